@@ -26,6 +26,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/pipe-cd/pipe/pkg/admin"
+	"github.com/pipe-cd/pipe/pkg/app/ops/deploymentchaincontroller"
 	"github.com/pipe-cd/pipe/pkg/app/ops/firestoreindexensurer"
 	"github.com/pipe-cd/pipe/pkg/app/ops/handler"
 	"github.com/pipe-cd/pipe/pkg/app/ops/insightcollector"
@@ -160,6 +161,14 @@ func (s *ops) run(ctx context.Context, t cli.Telemetry) error {
 		})
 	}
 
+	// Start running deployment chain controller.
+	{
+		controller := deploymentchaincontroller.NewDeploymentChainController(ds, t.Logger)
+		group.Go(func() error {
+			return controller.Run(ctx)
+		})
+	}
+
 	// Start running planpreview output cleaner.
 	{
 		cleaner := planpreviewoutputcleaner.NewCleaner(fs, t.Logger)
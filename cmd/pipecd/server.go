@@ -17,7 +17,9 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	jwtgo "github.com/golang-jwt/jwt"
@@ -83,8 +85,9 @@ type server struct {
 	keyFile        string
 	insecureCookie bool
 
-	encryptionKeyFile string
-	configFile        string
+	encryptionKeyFile      string
+	configFile             string
+	slackSigningSecretFile string
 
 	enableGRPCReflection bool
 }
@@ -125,6 +128,7 @@ func NewServerCommand() *cobra.Command {
 	cmd.MarkFlagRequired("encryption-key-file")
 	cmd.Flags().StringVar(&s.configFile, "config-file", s.configFile, "The path to the configuration file.")
 	cmd.MarkFlagRequired("config-file")
+	cmd.Flags().StringVar(&s.slackSigningSecretFile, "slack-signing-secret-file", s.slackSigningSecretFile, "The path to the file containing the signing secret of the Slack app used to approve the stages from Slack.")
 
 	// For debugging early in development
 	cmd.Flags().BoolVar(&s.enableGRPCReflection, "enable-grpc-reflection", s.enableGRPCReflection, "Whether to enable the reflection service or not.")
@@ -199,7 +203,7 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 				datastore.NewPipedStore(ds),
 				t.Logger,
 			)
-			service = grpcapi.NewPipedAPI(ctx, ds, sls, alss, las, cmds, statCache, cmdOutputStore, t.Logger)
+			service = grpcapi.NewPipedAPI(ctx, ds, sls, alss, las, cmds, statCache, rd, cmdOutputStore, t.Logger)
 			opts    = []rpc.Option{
 				rpc.WithPort(s.pipedAPIPort),
 				rpc.WithGracePeriod(s.gracePeriod),
@@ -305,6 +309,16 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 			return err
 		}
 
+		var slackSigningSecret string
+		if s.slackSigningSecretFile != "" {
+			data, err := ioutil.ReadFile(s.slackSigningSecretFile)
+			if err != nil {
+				t.Logger.Error("failed to read slack signing secret file", zap.Error(err))
+				return err
+			}
+			slackSigningSecret = strings.TrimSpace(string(data))
+		}
+
 		h := httpapi.NewHandler(
 			signer,
 			s.staticDir,
@@ -315,6 +329,10 @@ func (s *server) run(ctx context.Context, t cli.Telemetry) error {
 			cfg.SharedSSOConfigMap(),
 			datastore.NewProjectStore(ds),
 			!s.insecureCookie,
+			slackSigningSecret,
+			datastore.NewDeploymentStore(ds),
+			cmds,
+			cfg,
 			t.Logger,
 		)
 		httpServer := &http.Server{
@@ -12,10 +12,16 @@ require (
 	github.com/aws/aws-sdk-go-v2/config v1.1.1
 	github.com/aws/aws-sdk-go-v2/credentials v1.1.1
 	github.com/aws/aws-sdk-go-v2/internal/ini v1.0.0 // indirect
+	github.com/aws/aws-sdk-go-v2/service/cloudformation v1.5.1
+	github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs v1.4.0
+	github.com/aws/aws-sdk-go-v2/service/codedeploy v1.3.1
 	github.com/aws/aws-sdk-go-v2/service/ecs v1.1.1
 	github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2 v1.3.1
 	github.com/aws/aws-sdk-go-v2/service/lambda v1.1.1
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.2.0
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.3.1
+	github.com/aws/aws-sdk-go-v2/service/sns v1.5.0
+	github.com/aws/smithy-go v1.4.0
 	github.com/creasty/defaults v1.5.1
 	github.com/envoyproxy/protoc-gen-validate v0.1.0
 	github.com/fsouza/fake-gcs-server v1.21.0
@@ -322,6 +322,111 @@ func (a *API) GetDeployment(ctx context.Context, req *apiservice.GetDeploymentRe
 	}, nil
 }
 
+func (a *API) PauseDeployment(ctx context.Context, req *apiservice.PauseDeploymentRequest) (*apiservice.PauseDeploymentResponse, error) {
+	cmdID, err := a.addPauseCommand(ctx, req.DeploymentId, true)
+	if err != nil {
+		return nil, err
+	}
+	return &apiservice.PauseDeploymentResponse{
+		CommandId: cmdID,
+	}, nil
+}
+
+func (a *API) ResumeDeployment(ctx context.Context, req *apiservice.ResumeDeploymentRequest) (*apiservice.ResumeDeploymentResponse, error) {
+	cmdID, err := a.addPauseCommand(ctx, req.DeploymentId, false)
+	if err != nil {
+		return nil, err
+	}
+	return &apiservice.ResumeDeploymentResponse{
+		CommandId: cmdID,
+	}, nil
+}
+
+// addPauseCommand adds a command to pause or resume the given deployment.
+func (a *API) addPauseCommand(ctx context.Context, deploymentID string, pause bool) (string, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return "", err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, deploymentID, a.logger)
+	if err != nil {
+		return "", err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return "", status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+	if err := validateDeploymentPausable(deployment, pause); err != nil {
+		return "", err
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deployment.Id,
+		Commander:     key.Id,
+	}
+	if pause {
+		cmd.Type = model.Command_PAUSE_DEPLOYMENT
+		cmd.PauseDeployment = &model.Command_PauseDeployment{
+			DeploymentId: deployment.Id,
+		}
+	} else {
+		cmd.Type = model.Command_RESUME_DEPLOYMENT
+		cmd.ResumeDeployment = &model.Command_ResumeDeployment{
+			DeploymentId: deployment.Id,
+		}
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return "", err
+	}
+	return cmd.Id, nil
+}
+
+func (a *API) SkipStage(ctx context.Context, req *apiservice.SkipStageRequest) (*apiservice.SkipStageResponse, error) {
+	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.ProjectId != deployment.ProjectId {
+		return nil, status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+	if err := validateStageSkippable(deployment, req.StageId); err != nil {
+		return nil, err
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deployment.Id,
+		StageId:       req.StageId,
+		Type:          model.Command_SKIP_STAGE,
+		Commander:     key.Id,
+		SkipStage: &model.Command_SkipStage{
+			DeploymentId: deployment.Id,
+			StageId:      req.StageId,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+
+	return &apiservice.SkipStageResponse{
+		CommandId: cmd.Id,
+	}, nil
+}
+
 func (a *API) GetCommand(ctx context.Context, req *apiservice.GetCommandRequest) (*apiservice.GetCommandResponse, error) {
 	_, err := requireAPIKey(ctx, model.APIKey_READ_ONLY, a.logger)
 	if err != nil {
@@ -352,6 +457,21 @@ func (a *API) DisablePiped(ctx context.Context, req *apiservice.DisablePipedRequ
 	return &apiservice.DisablePipedResponse{}, nil
 }
 
+// UpgradePiped sets the version the given pipeds should be upgraded to.
+// Each piped drains its in-flight deployments and restarts itself with that version.
+func (a *API) UpgradePiped(ctx context.Context, req *apiservice.UpgradePipedRequest) (*apiservice.UpgradePipedResponse, error) {
+	now := time.Now().Unix()
+	for _, id := range req.PipedIds {
+		updater := func(ctx context.Context, pipedID string) error {
+			return a.pipedStore.UpdatePiped(ctx, pipedID, datastore.PipedDesiredVersionUpdater(req.Version, now))
+		}
+		if err := a.updatePiped(ctx, id, updater); err != nil {
+			return nil, err
+		}
+	}
+	return &apiservice.UpgradePipedResponse{}, nil
+}
+
 func (a *API) updatePiped(ctx context.Context, pipedID string, updater func(context.Context, string) error) error {
 	key, err := requireAPIKey(ctx, model.APIKey_READ_WRITE, a.logger)
 	if err != nil {
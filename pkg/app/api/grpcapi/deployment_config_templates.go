@@ -47,9 +47,14 @@ var (
 		},
 	}
 
-	terraformDeploymentConfigTemplates  = []*webservice.DeploymentConfigTemplate{}
-	crossplaneDeploymentConfigTemplates = []*webservice.DeploymentConfigTemplate{}
-	lambdaDeploymentConfigTemplates     = []*webservice.DeploymentConfigTemplate{}
-	cloudrunDeploymentConfigTemplates   = []*webservice.DeploymentConfigTemplate{}
-	ecsDeploymentConfigTemplates        = []*webservice.DeploymentConfigTemplate{}
+	terraformDeploymentConfigTemplates      = []*webservice.DeploymentConfigTemplate{}
+	crossplaneDeploymentConfigTemplates     = []*webservice.DeploymentConfigTemplate{}
+	lambdaDeploymentConfigTemplates         = []*webservice.DeploymentConfigTemplate{}
+	cloudrunDeploymentConfigTemplates       = []*webservice.DeploymentConfigTemplate{}
+	ecsDeploymentConfigTemplates            = []*webservice.DeploymentConfigTemplate{}
+	appengineDeploymentConfigTemplates      = []*webservice.DeploymentConfigTemplate{}
+	cloudformationDeploymentConfigTemplates = []*webservice.DeploymentConfigTemplate{}
+	nomadDeploymentConfigTemplates          = []*webservice.DeploymentConfigTemplate{}
+	azureFunctionsDeploymentConfigTemplates = []*webservice.DeploymentConfigTemplate{}
+	customSyncDeploymentConfigTemplates     = []*webservice.DeploymentConfigTemplate{}
 )
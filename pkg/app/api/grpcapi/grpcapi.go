@@ -155,3 +155,45 @@ func getEnvironment(ctx context.Context, store datastore.EnvironmentStore, id st
 
 	return env, nil
 }
+
+// validateStageSkippable checks whether the given stage of the deployment can be skipped by a user.
+// Only the stage of the running deployment which has not been completed yet can be skipped.
+// The stage declaring the required outputs can not be skipped since the later stages
+// consuming them would fail or behave unexpectedly without them.
+func validateStageSkippable(d *model.Deployment, stageID string) error {
+	if model.IsCompletedDeployment(d.Status) {
+		return status.Error(codes.FailedPrecondition, "Could not skip the stage because the deployment was already completed")
+	}
+	for _, s := range d.Stages {
+		if s.Id != stageID {
+			continue
+		}
+		if !s.Visible || s.Name == model.StageRollback.String() {
+			return status.Error(codes.FailedPrecondition, "Could not skip the stage because it is not a stage of the pipeline")
+		}
+		if model.IsCompletedStage(s.Status) {
+			return status.Error(codes.FailedPrecondition, "Could not skip the stage because it was already completed")
+		}
+		for _, o := range s.Outputs {
+			if o.Required {
+				return status.Errorf(codes.FailedPrecondition, "Could not skip the stage because its required output %s may be used by the later stages", o.Name)
+			}
+		}
+		return nil
+	}
+	return status.Error(codes.FailedPrecondition, "The stage was not found in the deployment")
+}
+
+// validateDeploymentPausable checks whether the given deployment can be paused or resumed by a user.
+func validateDeploymentPausable(d *model.Deployment, pause bool) error {
+	if model.IsCompletedDeployment(d.Status) {
+		return status.Error(codes.FailedPrecondition, "Could not pause or resume the deployment because it was already completed")
+	}
+	if pause && d.IsPaused() {
+		return status.Error(codes.FailedPrecondition, "The deployment was already paused")
+	}
+	if !pause && !d.IsPaused() {
+		return status.Error(codes.FailedPrecondition, "The deployment is not paused")
+	}
+	return nil
+}
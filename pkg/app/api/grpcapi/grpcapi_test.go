@@ -0,0 +1,203 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestValidateStageSkippable(t *testing.T) {
+	deployment := &model.Deployment{
+		Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+		Stages: []*model.PipelineStage{
+			{
+				Id:      "stage-1",
+				Name:    model.StageWait.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_SUCCESS,
+			},
+			{
+				Id:      "stage-2",
+				Name:    model.StageAnalysis.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_RUNNING,
+			},
+			{
+				Id:      "stage-3",
+				Name:    model.StageWaitApproval.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_NOT_STARTED_YET,
+			},
+			{
+				Id:      "stage-4",
+				Name:    model.StageScriptRun.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_NOT_STARTED_YET,
+				Outputs: []*model.StageOutput{
+					{Name: "Version", Type: model.StageOutputType_STAGE_OUTPUT_STRING},
+				},
+			},
+			{
+				Id:      "stage-5",
+				Name:    model.StageTerraformPlan.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_NOT_STARTED_YET,
+				Outputs: model.BuiltinStageOutputs(model.StageTerraformPlan),
+			},
+			{
+				Id:     "stage-rollback",
+				Name:   model.StageRollback.String(),
+				Status: model.StageStatus_STAGE_NOT_STARTED_YET,
+			},
+		},
+	}
+	completed := &model.Deployment{
+		Status: model.DeploymentStatus_DEPLOYMENT_CANCELLED,
+		Stages: []*model.PipelineStage{
+			{
+				Id:      "stage-1",
+				Name:    model.StageWait.String(),
+				Visible: true,
+				Status:  model.StageStatus_STAGE_NOT_STARTED_YET,
+			},
+		},
+	}
+
+	testcases := []struct {
+		name       string
+		deployment *model.Deployment
+		stageID    string
+		wantErr    bool
+	}{
+		{
+			name:       "running stage",
+			deployment: deployment,
+			stageID:    "stage-2",
+		},
+		{
+			name:       "pending stage",
+			deployment: deployment,
+			stageID:    "stage-3",
+		},
+		{
+			name:       "completed stage",
+			deployment: deployment,
+			stageID:    "stage-1",
+			wantErr:    true,
+		},
+		{
+			name:       "stage with optional outputs",
+			deployment: deployment,
+			stageID:    "stage-4",
+		},
+		{
+			name:       "stage with required outputs",
+			deployment: deployment,
+			stageID:    "stage-5",
+			wantErr:    true,
+		},
+		{
+			name:       "rollback stage",
+			deployment: deployment,
+			stageID:    "stage-rollback",
+			wantErr:    true,
+		},
+		{
+			name:       "unknown stage",
+			deployment: deployment,
+			stageID:    "stage-unknown",
+			wantErr:    true,
+		},
+		{
+			name:       "completed deployment",
+			deployment: completed,
+			stageID:    "stage-1",
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateStageSkippable(tc.deployment, tc.stageID)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestValidateDeploymentPausable(t *testing.T) {
+	running := &model.Deployment{
+		Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+	}
+	paused := &model.Deployment{
+		Status: model.DeploymentStatus_DEPLOYMENT_RUNNING,
+		Metadata: map[string]string{
+			model.MetadataKeyPausedBy: "user",
+		},
+	}
+	completed := &model.Deployment{
+		Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+	}
+
+	testcases := []struct {
+		name       string
+		deployment *model.Deployment
+		pause      bool
+		wantErr    bool
+	}{
+		{
+			name:       "pause running deployment",
+			deployment: running,
+			pause:      true,
+		},
+		{
+			name:       "pause paused deployment",
+			deployment: paused,
+			pause:      true,
+			wantErr:    true,
+		},
+		{
+			name:       "resume paused deployment",
+			deployment: paused,
+		},
+		{
+			name:       "resume running deployment",
+			deployment: running,
+			wantErr:    true,
+		},
+		{
+			name:       "pause completed deployment",
+			deployment: completed,
+			pause:      true,
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateDeploymentPausable(tc.deployment, tc.pause)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+			}
+		})
+	}
+}
@@ -18,8 +18,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sort"
 	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -32,9 +34,11 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/api/stagelogstore"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
+	"github.com/pipe-cd/pipe/pkg/cache/rediscache"
 	"github.com/pipe-cd/pipe/pkg/datastore"
 	"github.com/pipe-cd/pipe/pkg/filestore"
 	"github.com/pipe-cd/pipe/pkg/model"
+	"github.com/pipe-cd/pipe/pkg/redis"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
 )
 
@@ -42,6 +46,7 @@ import (
 type PipedAPI struct {
 	applicationStore          datastore.ApplicationStore
 	deploymentStore           datastore.DeploymentStore
+	deploymentChainStore      datastore.DeploymentChainStore
 	environmentStore          datastore.EnvironmentStore
 	pipedStore                datastore.PipedStore
 	projectStore              datastore.ProjectStore
@@ -56,15 +61,19 @@ type PipedAPI struct {
 	deploymentPipedCache cache.Cache
 	envProjectCache      cache.Cache
 	pipedStatCache       cache.Cache
+	// Returns the cache of the given piped which maps from the replica id
+	// to the last heartbeat of the replica.
+	pipedReplicaCache func(pipedID string) cache.Cache
 
 	logger *zap.Logger
 }
 
 // NewPipedAPI creates a new PipedAPI instance.
-func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.Store, alss applicationlivestatestore.Store, las analysisresultstore.Store, cs commandstore.Store, hc cache.Cache, cop commandOutputPutter, logger *zap.Logger) *PipedAPI {
+func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.Store, alss applicationlivestatestore.Store, las analysisresultstore.Store, cs commandstore.Store, hc cache.Cache, rd redis.Redis, cop commandOutputPutter, logger *zap.Logger) *PipedAPI {
 	a := &PipedAPI{
 		applicationStore:          datastore.NewApplicationStore(ds),
 		deploymentStore:           datastore.NewDeploymentStore(ds),
+		deploymentChainStore:      datastore.NewDeploymentChainStore(ds),
 		environmentStore:          datastore.NewEnvironmentStore(ds),
 		pipedStore:                datastore.NewPipedStore(ds),
 		projectStore:              datastore.NewProjectStore(ds),
@@ -78,7 +87,10 @@ func NewPipedAPI(ctx context.Context, ds datastore.DataStore, sls stagelogstore.
 		deploymentPipedCache:      memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		envProjectCache:           memorycache.NewTTLCache(ctx, 24*time.Hour, 3*time.Hour),
 		pipedStatCache:            hc,
-		logger:                    logger.Named("piped-api"),
+		pipedReplicaCache: func(pipedID string) cache.Cache {
+			return rediscache.NewHashCache(rd, pipedReplicaHashKey(pipedID))
+		},
+		logger: logger.Named("piped-api"),
 	}
 	return a
 }
@@ -152,6 +164,103 @@ func (a *PipedAPI) ReportPipedMeta(ctx context.Context, req *pipedservice.Report
 	return &pipedservice.ReportPipedMetaResponse{}, nil
 }
 
+// GetDesiredVersion returns the version piped should be upgraded to
+// and the latest reported upgrade status.
+func (a *PipedAPI) GetDesiredVersion(ctx context.Context, req *pipedservice.GetDesiredVersionRequest) (*pipedservice.GetDesiredVersionResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	piped, err := getPiped(ctx, a.pipedStore, pipedID, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	return &pipedservice.GetDesiredVersionResponse{
+		DesiredVersion: piped.DesiredVersion,
+		UpgradeStatus:  piped.UpgradeStatus,
+	}, nil
+}
+
+// ReportUpgradeStatus is used to report the progress of upgrading piped to the desired version.
+func (a *PipedAPI) ReportUpgradeStatus(ctx context.Context, req *pipedservice.ReportUpgradeStatusRequest) (*pipedservice.ReportUpgradeStatusResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.pipedStore.UpdatePiped(ctx, pipedID, datastore.PipedUpgradeStatusUpdater(req.UpgradeStatus)); err != nil {
+		switch err {
+		case datastore.ErrNotFound:
+			return nil, status.Error(codes.InvalidArgument, "piped is not found")
+		case datastore.ErrInvalidArgument:
+			return nil, status.Error(codes.InvalidArgument, "invalid value for update")
+		default:
+			a.logger.Error("failed to update the piped upgrade status",
+				zap.String("piped-id", pipedID),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "failed to update the piped upgrade status")
+		}
+	}
+	return &pipedservice.ReportUpgradeStatusResponse{}, nil
+}
+
+// ReportReplicaHeartbeat is periodically sent by each replica of piped running with sharding
+// to report that it is still alive. The last heartbeats of all replicas are returned.
+func (a *PipedAPI) ReportReplicaHeartbeat(ctx context.Context, req *pipedservice.ReportReplicaHeartbeatRequest) (*pipedservice.ReportReplicaHeartbeatResponse, error) {
+	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	val, err := json.Marshal(&pipedservice.PipedReplica{
+		Id:             req.ReplicaId,
+		ApplicationIds: req.ApplicationIds,
+		HeartbeatAt:    now.Unix(),
+	})
+	if err != nil {
+		a.logger.Error("failed to encode the replica heartbeat",
+			zap.String("piped-id", pipedID),
+			zap.String("replica-id", req.ReplicaId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to encode the replica heartbeat")
+	}
+	replicaCache := a.pipedReplicaCache(pipedID)
+	if err := replicaCache.Put(req.ReplicaId, val); err != nil {
+		a.logger.Error("failed to store the replica heartbeat",
+			zap.String("piped-id", pipedID),
+			zap.String("replica-id", req.ReplicaId),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to store the replica heartbeat")
+	}
+
+	entries, err := replicaCache.GetAll()
+	if err != nil {
+		a.logger.Error("failed to get the replica heartbeats",
+			zap.String("piped-id", pipedID),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.Internal, "failed to get the replica heartbeats")
+	}
+	replicas, staled := filterPipedReplicas(entries, now)
+	for _, id := range staled {
+		if err := replicaCache.Delete(id); err != nil {
+			a.logger.Warn("failed to delete the staled replica heartbeat",
+				zap.String("piped-id", pipedID),
+				zap.String("replica-id", id),
+				zap.Error(err),
+			)
+		}
+	}
+	return &pipedservice.ReportReplicaHeartbeatResponse{
+		Replicas: replicas,
+	}, nil
+}
+
 // GetEnvironment finds and returns the environment for the specified ID.
 func (a *PipedAPI) GetEnvironment(ctx context.Context, req *pipedservice.GetEnvironmentRequest) (*pipedservice.GetEnvironmentResponse, error) {
 	projectID, _, _, err := rpcauth.ExtractPipedToken(ctx)
@@ -484,6 +593,126 @@ func (a *PipedAPI) ReportDeploymentCompleted(ctx context.Context, req *pipedserv
 	return &pipedservice.ReportDeploymentCompletedResponse{}, nil
 }
 
+// CreateDeploymentChain is sent by piped after a deployment was completed successfully
+// to deploy the matched applications as the next block of the deployment chain.
+// The deployment starts a new chain whose ID is same with the deployment ID
+// when it does not belong to any chain yet.
+func (a *PipedAPI) CreateDeploymentChain(ctx context.Context, req *pipedservice.CreateDeploymentChainRequest) (*pipedservice.CreateDeploymentChainResponse, error) {
+	projectID, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToPiped(ctx, req.DeploymentId, pipedID); err != nil {
+		return nil, err
+	}
+
+	d, err := a.deploymentStore.GetDeployment(ctx, req.DeploymentId)
+	if err != nil {
+		a.logger.Error("failed to get deployment", zap.String("deployment-id", req.DeploymentId), zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to get deployment")
+	}
+	if d.Status != model.DeploymentStatus_DEPLOYMENT_SUCCESS {
+		return nil, status.Error(codes.FailedPrecondition, "only the successfully completed deployment can trigger the deployment chain")
+	}
+
+	opts := datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "ProjectId",
+				Operator: datastore.OperatorEqual,
+				Value:    projectID,
+			},
+			{
+				Field:    "Disabled",
+				Operator: datastore.OperatorEqual,
+				Value:    false,
+			},
+		},
+	}
+	apps, _, err := a.applicationStore.ListApplications(ctx, opts)
+	if err != nil {
+		a.logger.Error("failed to fetch applications", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to fetch applications")
+	}
+	nodes := matchDeploymentChainApplications(apps, req.Matchers)
+	if len(nodes) == 0 {
+		return nil, status.Error(codes.NotFound, "no application matched the deployment chain")
+	}
+
+	var (
+		chainID    = d.DeploymentChainId
+		blockIndex = int(d.DeploymentChainBlockIndex) + 1
+		addedIDs   []string
+	)
+	if chainID == "" {
+		chainID = d.Id
+	}
+	addNodes := func(c *model.DeploymentChain) error {
+		if c.Status == model.DeploymentChainStatus_DEPLOYMENT_CHAIN_FAILURE {
+			return errDeploymentChainFailed
+		}
+		added, err := c.AddBlockNodes(blockIndex, nodes, req.Outputs)
+		if err != nil {
+			return err
+		}
+		addedIDs = make([]string, 0, len(added))
+		for _, n := range added {
+			addedIDs = append(addedIDs, n.ApplicationId)
+		}
+		return nil
+	}
+
+	err = a.deploymentChainStore.UpdateDeploymentChain(ctx, chainID, addNodes)
+	if errors.Is(err, datastore.ErrNotFound) && chainID == d.Id {
+		chain := &model.DeploymentChain{
+			Id:                chainID,
+			ProjectId:         projectID,
+			FirstDeploymentId: d.Id,
+			Blocks: []*model.ChainBlock{
+				{
+					Nodes: []*model.ChainNode{
+						{
+							ApplicationId:   d.ApplicationId,
+							ApplicationName: d.ApplicationName,
+							DeploymentId:    d.Id,
+							Status:          d.Status,
+						},
+					},
+					StartedAt: d.CreatedAt,
+				},
+			},
+			Status: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+		}
+		if err = addNodes(chain); err == nil {
+			if len(addedIDs) == 0 {
+				return nil, status.Error(codes.NotFound, "no application other than the deployed one matched the deployment chain")
+			}
+			err = a.deploymentChainStore.AddDeploymentChain(ctx, chain)
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, errDeploymentChainFailed):
+			return nil, status.Error(codes.FailedPrecondition, "the deployment chain has already failed")
+		case errors.Is(err, datastore.ErrNotFound):
+			return nil, status.Error(codes.NotFound, "the deployment chain is not found")
+		case errors.Is(err, datastore.ErrAlreadyExists):
+			return nil, status.Error(codes.AlreadyExists, "the deployment chain is being created")
+		default:
+			a.logger.Error("failed to add the applications to the deployment chain",
+				zap.String("deployment-chain-id", chainID),
+				zap.Error(err),
+			)
+			return nil, status.Error(codes.Internal, "failed to add the applications to the deployment chain")
+		}
+	}
+
+	return &pipedservice.CreateDeploymentChainResponse{
+		DeploymentChainId: chainID,
+		ApplicationIds:    addedIDs,
+	}, nil
+}
+
 // SaveDeploymentMetadata used by piped to persist the metadata of a specific deployment.
 func (a *PipedAPI) SaveDeploymentMetadata(ctx context.Context, req *pipedservice.SaveDeploymentMetadataRequest) (*pipedservice.SaveDeploymentMetadataResponse, error) {
 	_, pipedID, _, err := rpcauth.ExtractPipedToken(ctx)
@@ -494,7 +723,9 @@ func (a *PipedAPI) SaveDeploymentMetadata(ctx context.Context, req *pipedservice
 		return nil, err
 	}
 
-	err = a.deploymentStore.PutDeploymentMetadata(ctx, req.DeploymentId, req.Metadata)
+	// The large values compacted by piped are stored after being expanded
+	// to be readable by the web console and the other consumers.
+	err = a.deploymentStore.PutDeploymentMetadata(ctx, req.DeploymentId, model.ExpandMetadata(req.Metadata))
 	if errors.Is(err, datastore.ErrNotFound) {
 		return nil, status.Error(codes.InvalidArgument, "deployment is not found")
 	}
@@ -519,7 +750,7 @@ func (a *PipedAPI) SaveStageMetadata(ctx context.Context, req *pipedservice.Save
 		return nil, err
 	}
 
-	err = a.deploymentStore.PutDeploymentStageMetadata(ctx, req.DeploymentId, req.StageId, req.Metadata)
+	err = a.deploymentStore.PutDeploymentStageMetadata(ctx, req.DeploymentId, req.StageId, model.ExpandMetadata(req.Metadata))
 	if err != nil {
 		switch errors.Unwrap(err) {
 		case datastore.ErrNotFound:
@@ -591,7 +822,7 @@ func (a *PipedAPI) ReportStageStatusChanged(ctx context.Context, req *pipedservi
 		return nil, err
 	}
 
-	updater := datastore.StageStatusChangedUpdater(req.StageId, req.Status, req.StatusReason, req.Requires, req.Visible, req.RetriedCount, req.CompletedAt)
+	updater := datastore.StageStatusChangedUpdater(req.StageId, req.Status, req.StatusReason, req.Requires, req.Visible, req.RetriedCount, req.ActualDuration, req.CompletedAt)
 	err = a.deploymentStore.UpdateDeployment(ctx, req.DeploymentId, updater)
 	if err != nil {
 		switch err {
@@ -847,6 +1078,36 @@ func (a *PipedAPI) ListEvents(ctx context.Context, req *pipedservice.ListEventsR
 	}, nil
 }
 
+// RegisterEvent registers a new event published by a deployment
+// to trigger the event watchers of all pipeds in the project.
+func (a *PipedAPI) RegisterEvent(ctx context.Context, req *pipedservice.RegisterEventRequest) (*pipedservice.RegisterEventResponse, error) {
+	projectID, _, _, err := rpcauth.ExtractPipedToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := uuid.New().String()
+
+	err = a.eventStore.AddEvent(ctx, model.Event{
+		Id:        id,
+		Name:      req.Name,
+		Data:      req.Data,
+		Labels:    req.Labels,
+		EventKey:  model.MakeEventKey(req.Name, req.Labels),
+		ProjectId: projectID,
+	})
+	if errors.Is(err, datastore.ErrAlreadyExists) {
+		return nil, status.Error(codes.AlreadyExists, "the event already exists")
+	}
+	if err != nil {
+		a.logger.Error("failed to register event", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to register event")
+	}
+
+	return &pipedservice.RegisterEventResponse{
+		EventId: id,
+	}, nil
+}
+
 func (a *PipedAPI) GetLatestAnalysisResult(ctx context.Context, req *pipedservice.GetLatestAnalysisResultRequest) (*pipedservice.GetLatestAnalysisResultResponse, error) {
 	projectID, _, _, err := rpcauth.ExtractPipedToken(ctx)
 	if err != nil {
@@ -966,3 +1227,83 @@ func (a *PipedAPI) validateEnvBelongsToProject(ctx context.Context, envID, proje
 	}
 	return nil
 }
+
+var errDeploymentChainFailed = errors.New("deployment chain has already failed")
+
+// matchDeploymentChainApplications returns the nodes of the applications
+// matched with any of the given matchers.
+func matchDeploymentChainApplications(apps []*model.Application, matchers []*pipedservice.DeploymentChainApplicationMatcher) []*model.ChainNode {
+	nodes := make([]*model.ChainNode, 0)
+	for _, app := range apps {
+		if app.Deleted {
+			continue
+		}
+		for _, m := range matchers {
+			if !matchDeploymentChainApplication(app, m) {
+				continue
+			}
+			nodes = append(nodes, &model.ChainNode{
+				ApplicationId:   app.Id,
+				ApplicationName: app.Name,
+				Status:          model.DeploymentStatus_DEPLOYMENT_PENDING,
+			})
+			break
+		}
+	}
+	return nodes
+}
+
+func matchDeploymentChainApplication(app *model.Application, m *pipedservice.DeploymentChainApplicationMatcher) bool {
+	// A matcher without any condition matches nothing.
+	if m.Name == "" && len(m.Labels) == 0 {
+		return false
+	}
+	if m.Name != "" && m.Name != app.Name {
+		return false
+	}
+	for k, v := range m.Labels {
+		if app.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// pipedReplicaStaledTimeout is how long the heartbeat of a replica is kept since it was reported.
+const pipedReplicaStaledTimeout = time.Hour
+
+// pipedReplicaHashKey returns the key of the redis hash
+// holding the heartbeats of the replicas of the given piped.
+func pipedReplicaHashKey(pipedID string) string {
+	return "HASHKEY:PIPED:REPLICAS:" + pipedID
+}
+
+// filterPipedReplicas returns the last heartbeats of the replicas
+// and the ids of the replicas whose heartbeat is staled or broken and should be deleted.
+func filterPipedReplicas(entries map[string]interface{}, now time.Time) ([]*pipedservice.PipedReplica, []string) {
+	var (
+		replicas = make([]*pipedservice.PipedReplica, 0)
+		staled   []string
+	)
+	for k, v := range entries {
+		value, ok := v.([]byte)
+		if !ok {
+			staled = append(staled, k)
+			continue
+		}
+		r := &pipedservice.PipedReplica{}
+		if err := json.Unmarshal(value, r); err != nil {
+			staled = append(staled, k)
+			continue
+		}
+		if now.Sub(time.Unix(r.HeartbeatAt, 0)) > pipedReplicaStaledTimeout {
+			staled = append(staled, k)
+			continue
+		}
+		replicas = append(replicas, r)
+	}
+	sort.Slice(replicas, func(i, j int) bool {
+		return replicas[i].Id < replicas[j].Id
+	})
+	return replicas, staled
+}
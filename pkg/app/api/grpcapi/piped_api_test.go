@@ -17,11 +17,14 @@ package grpcapi
 import (
 	"context"
 	"errors"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
 	"github.com/pipe-cd/pipe/pkg/datastore"
@@ -304,3 +307,76 @@ func TestValidateEnvBelongsToProject(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterPipedReplicas(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	entries := map[string]interface{}{
+		"replica-1": []byte(`{"id":"replica-1","heartbeat_at":1599999900}`),
+		"replica-0": []byte(`{"id":"replica-0","application_ids":["app-1"],"heartbeat_at":1599999990}`),
+		"replica-2": []byte(`{"id":"replica-2","heartbeat_at":1599990000}`),
+		"replica-3": []byte(`invalid`),
+		"replica-4": "not bytes",
+	}
+
+	replicas, staled := filterPipedReplicas(entries, now)
+	assert.Equal(t, []*pipedservice.PipedReplica{
+		{Id: "replica-0", ApplicationIds: []string{"app-1"}, HeartbeatAt: 1599999990},
+		{Id: "replica-1", HeartbeatAt: 1599999900},
+	}, replicas)
+
+	sort.Strings(staled)
+	assert.Equal(t, []string{"replica-2", "replica-3", "replica-4"}, staled)
+}
+
+func TestMatchDeploymentChainApplications(t *testing.T) {
+	apps := []*model.Application{
+		{Id: "app-1", Name: "frontend", Labels: map[string]string{"env": "prod", "team": "web"}},
+		{Id: "app-2", Name: "frontend", Labels: map[string]string{"env": "staging", "team": "web"}},
+		{Id: "app-3", Name: "backend", Labels: map[string]string{"env": "prod"}},
+		{Id: "app-4", Name: "backend", Labels: map[string]string{"env": "prod"}, Deleted: true},
+	}
+	testcases := []struct {
+		name     string
+		matchers []*pipedservice.DeploymentChainApplicationMatcher
+		want     []string
+	}{
+		{
+			name:     "empty matcher matches nothing",
+			matchers: []*pipedservice.DeploymentChainApplicationMatcher{{}},
+			want:     []string{},
+		},
+		{
+			name: "match by name",
+			matchers: []*pipedservice.DeploymentChainApplicationMatcher{
+				{Name: "frontend"},
+			},
+			want: []string{"app-1", "app-2"},
+		},
+		{
+			name: "match by name and labels",
+			matchers: []*pipedservice.DeploymentChainApplicationMatcher{
+				{Name: "frontend", Labels: map[string]string{"env": "prod"}},
+			},
+			want: []string{"app-1"},
+		},
+		{
+			name: "multiple matchers match the same application only once",
+			matchers: []*pipedservice.DeploymentChainApplicationMatcher{
+				{Labels: map[string]string{"env": "prod"}},
+				{Name: "frontend", Labels: map[string]string{"team": "web"}},
+			},
+			want: []string{"app-1", "app-2", "app-3"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes := matchDeploymentChainApplications(apps, tc.matchers)
+			got := make([]string, 0, len(nodes))
+			for _, n := range nodes {
+				assert.Equal(t, model.DeploymentStatus_DEPLOYMENT_PENDING, n.Status)
+				got = append(got, n.ApplicationId)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
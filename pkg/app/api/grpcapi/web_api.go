@@ -624,6 +624,7 @@ func (a *WebAPI) AddApplication(ctx context.Context, req *webservice.AddApplicat
 		Kind:          req.Kind,
 		CloudProvider: req.CloudProvider,
 		Description:   req.Description,
+		Labels:        req.Labels,
 	}
 	err = a.applicationStore.AddApplication(ctx, &app)
 	if errors.Is(err, datastore.ErrAlreadyExists) {
@@ -646,6 +647,7 @@ func (a *WebAPI) UpdateApplication(ctx context.Context, req *webservice.UpdateAp
 		app.PipedId = req.PipedId
 		app.Kind = req.Kind
 		app.CloudProvider = req.CloudProvider
+		app.Labels = req.Labels
 		return nil
 	}
 
@@ -1179,6 +1181,70 @@ func (a *WebAPI) CancelDeployment(ctx context.Context, req *webservice.CancelDep
 	}, nil
 }
 
+func (a *WebAPI) PauseDeployment(ctx context.Context, req *webservice.PauseDeploymentRequest) (*webservice.PauseDeploymentResponse, error) {
+	cmdID, err := a.addPauseCommand(ctx, req.DeploymentId, true)
+	if err != nil {
+		return nil, err
+	}
+	return &webservice.PauseDeploymentResponse{
+		CommandId: cmdID,
+	}, nil
+}
+
+func (a *WebAPI) ResumeDeployment(ctx context.Context, req *webservice.ResumeDeploymentRequest) (*webservice.ResumeDeploymentResponse, error) {
+	cmdID, err := a.addPauseCommand(ctx, req.DeploymentId, false)
+	if err != nil {
+		return nil, err
+	}
+	return &webservice.ResumeDeploymentResponse{
+		CommandId: cmdID,
+	}, nil
+}
+
+// addPauseCommand adds a command to pause or resume the given deployment.
+func (a *WebAPI) addPauseCommand(ctx context.Context, deploymentID string, pause bool) (string, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return "", err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, deploymentID, a.logger)
+	if err != nil {
+		return "", err
+	}
+	if claims.Role.ProjectId != deployment.ProjectId {
+		return "", status.Error(codes.InvalidArgument, "Requested deployment does not belong to your project")
+	}
+	if err := validateDeploymentPausable(deployment, pause); err != nil {
+		return "", err
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deploymentID,
+		Commander:     claims.Subject,
+	}
+	if pause {
+		cmd.Type = model.Command_PAUSE_DEPLOYMENT
+		cmd.PauseDeployment = &model.Command_PauseDeployment{
+			DeploymentId: deploymentID,
+		}
+	} else {
+		cmd.Type = model.Command_RESUME_DEPLOYMENT
+		cmd.ResumeDeployment = &model.Command_ResumeDeployment{
+			DeploymentId: deploymentID,
+		}
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return "", err
+	}
+	return cmd.Id, nil
+}
+
 func (a *WebAPI) ApproveStage(ctx context.Context, req *webservice.ApproveStageRequest) (*webservice.ApproveStageResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
@@ -1225,6 +1291,48 @@ func (a *WebAPI) ApproveStage(ctx context.Context, req *webservice.ApproveStageR
 	}, nil
 }
 
+func (a *WebAPI) SkipStage(ctx context.Context, req *webservice.SkipStageRequest) (*webservice.SkipStageResponse, error) {
+	claims, err := rpcauth.ExtractClaims(ctx)
+	if err != nil {
+		a.logger.Error("failed to authenticate the current user", zap.Error(err))
+		return nil, err
+	}
+
+	deployment, err := getDeployment(ctx, a.deploymentStore, req.DeploymentId, a.logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.validateDeploymentBelongsToProject(ctx, req.DeploymentId, claims.Role.ProjectId); err != nil {
+		return nil, err
+	}
+	if err := validateStageSkippable(deployment, req.StageId); err != nil {
+		return nil, err
+	}
+
+	commandID := uuid.New().String()
+	cmd := model.Command{
+		Id:            commandID,
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  req.DeploymentId,
+		StageId:       req.StageId,
+		Type:          model.Command_SKIP_STAGE,
+		Commander:     claims.Subject,
+		SkipStage: &model.Command_SkipStage{
+			DeploymentId: req.DeploymentId,
+			StageId:      req.StageId,
+		},
+	}
+	if err := addCommand(ctx, a.commandStore, &cmd, a.logger); err != nil {
+		return nil, err
+	}
+
+	return &webservice.SkipStageResponse{
+		CommandId: commandID,
+	}, nil
+}
+
 func (a *WebAPI) GetApplicationLiveState(ctx context.Context, req *webservice.GetApplicationLiveStateRequest) (*webservice.GetApplicationLiveStateResponse, error) {
 	claims, err := rpcauth.ExtractClaims(ctx)
 	if err != nil {
@@ -1457,6 +1565,16 @@ func (a *WebAPI) ListDeploymentConfigTemplates(ctx context.Context, req *webserv
 		templates = cloudrunDeploymentConfigTemplates
 	case model.ApplicationKind_ECS:
 		templates = ecsDeploymentConfigTemplates
+	case model.ApplicationKind_APPENGINE:
+		templates = appengineDeploymentConfigTemplates
+	case model.ApplicationKind_CLOUDFORMATION:
+		templates = cloudformationDeploymentConfigTemplates
+	case model.ApplicationKind_NOMAD:
+		templates = nomadDeploymentConfigTemplates
+	case model.ApplicationKind_AZURE_FUNCTIONS:
+		templates = azureFunctionsDeploymentConfigTemplates
+	case model.ApplicationKind_CUSTOM_SYNC:
+		templates = customSyncDeploymentConfigTemplates
 	default:
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Unknown application kind %v", app.Kind))
 	}
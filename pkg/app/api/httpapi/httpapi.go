@@ -38,6 +38,10 @@ func NewHandler(
 	sharedSSOConfigs map[string]*model.ProjectSSOConfig,
 	projectGetter projectGetter,
 	secureCookie bool,
+	slackSigningSecret string,
+	deploymentGetter deploymentGetter,
+	commandAdder commandAdder,
+	slackApproverFinder slackApproverFinder,
 	logger *zap.Logger,
 ) http.Handler {
 	mux := http.NewServeMux()
@@ -76,5 +80,10 @@ func NewHandler(
 	register(callbackPath, http.HandlerFunc(a.handleCallback))
 	register(logoutPath, http.HandlerFunc(a.handleLogout))
 
+	// The approvals from Slack are accepted only when the signing secret of the Slack app was given.
+	if slackSigningSecret != "" {
+		register(slackInteractionPath, newSlackInteractionHandler(slackSigningSecret, deploymentGetter, commandAdder, slackApproverFinder, logger))
+	}
+
 	return mux
 }
@@ -0,0 +1,267 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// slackInteractionPath is the request URL configured in the interactivity settings of the Slack app.
+	slackInteractionPath = "/slack/interactions"
+
+	slackSignatureHeader  = "X-Slack-Signature"
+	slackTimestampHeader  = "X-Slack-Request-Timestamp"
+	slackSignatureVersion = "v0"
+	// The requests older than this are rejected to prevent the replay attacks.
+	slackMaxRequestAge = 5 * time.Minute
+	slackMaxBodySize   = 1024 * 1024
+
+	// approversMetadataKey is the key of the stage metadata containing the comma separated approvers.
+	approversMetadataKey = "Approvers"
+)
+
+type deploymentGetter interface {
+	GetDeployment(ctx context.Context, id string) (*model.Deployment, error)
+}
+
+type commandAdder interface {
+	AddCommand(ctx context.Context, cmd *model.Command) error
+}
+
+type slackApproverFinder interface {
+	FindSlackApprover(projectID, slackUserID string) (config.ControlPlaneSlackApprover, bool)
+}
+
+// slackInteractionHandler handles the clicks on the approval buttons
+// of the Slack messages sent by the piped notifiers.
+// https://api.slack.com/legacy/interactive-messages
+type slackInteractionHandler struct {
+	signingSecret    []byte
+	deploymentGetter deploymentGetter
+	commandAdder     commandAdder
+	approverFinder   slackApproverFinder
+	nowFunc          func() time.Time
+	logger           *zap.Logger
+}
+
+func newSlackInteractionHandler(signingSecret string, dg deploymentGetter, ca commandAdder, af slackApproverFinder, logger *zap.Logger) *slackInteractionHandler {
+	return &slackInteractionHandler{
+		signingSecret:    []byte(signingSecret),
+		deploymentGetter: dg,
+		commandAdder:     ca,
+		approverFinder:   af,
+		nowFunc:          time.Now,
+		logger:           logger.Named("slack-interaction-handler"),
+	}
+}
+
+type slackInteraction struct {
+	Type       string `json:"type"`
+	CallbackID string `json:"callback_id"`
+	Actions    []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"actions"`
+	User struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+// slackInteractionResponse is posted to the channel as a new message
+// while keeping the original one.
+type slackInteractionResponse struct {
+	ResponseType    string `json:"response_type"`
+	ReplaceOriginal bool   `json:"replace_original"`
+	Text            string `json:"text"`
+}
+
+func (h *slackInteractionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, slackMaxBodySize))
+	if err != nil {
+		http.Error(w, "Unable to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.verify(r.Header, body); err != nil {
+		h.logger.Warn("received an unverified slack interaction", zap.Error(err))
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "Malformed request body", http.StatusBadRequest)
+		return
+	}
+	var in slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &in); err != nil {
+		http.Error(w, "Malformed payload", http.StatusBadRequest)
+		return
+	}
+	if in.CallbackID != model.SlackApprovalCallbackID || len(in.Actions) == 0 {
+		http.Error(w, "Unsupported interaction", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	text, err := h.handleApproval(ctx, in)
+	if err != nil {
+		h.logger.Error("failed to handle slack interaction", zap.Error(err))
+		text = fmt.Sprintf("Failed to handle the request: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slackInteractionResponse{
+		ResponseType:    "in_channel",
+		ReplaceOriginal: false,
+		Text:            text,
+	})
+}
+
+// handleApproval adds the command to approve or reject the stage
+// and returns the message to be posted to the channel.
+func (h *slackInteractionHandler) handleApproval(ctx context.Context, in slackInteraction) (string, error) {
+	action := in.Actions[0]
+	deploymentID, stageID, err := model.ParseSlackApprovalValue(action.Value)
+	if err != nil {
+		return "", err
+	}
+
+	deployment, err := h.deploymentGetter.GetDeployment(ctx, deploymentID)
+	if err != nil {
+		return "", fmt.Errorf("unable to find deployment %s", deploymentID)
+	}
+	var stage *model.PipelineStage
+	for _, s := range deployment.Stages {
+		if s.Id == stageID {
+			stage = s
+			break
+		}
+	}
+	if stage == nil {
+		return "", fmt.Errorf("stage %s was not found in the deployment", stageID)
+	}
+	if stage.Name != model.StageWaitApproval.String() {
+		return "", fmt.Errorf("stage %s is not a %s stage", stageID, model.StageWaitApproval)
+	}
+	if model.IsCompletedStage(stage.Status) {
+		return "", fmt.Errorf("stage %s was already completed", stageID)
+	}
+	// Only the Slack users mapped to the PipeCD users in the control plane configuration can approve.
+	// They are identified by the immutable IDs since the user names can be changed by anyone.
+	approver, ok := h.approverFinder.FindSlackApprover(deployment.ProjectId, in.User.ID)
+	if !ok {
+		return "", fmt.Errorf("slack user %s is not allowed to approve the stages of project %s", in.User.ID, deployment.ProjectId)
+	}
+	// The mapped users must be listed in the approvers of the stage if specified.
+	if approvers := stage.Metadata[approversMetadataKey]; approvers != "" && !containsUser(approvers, approver.Username) {
+		return "", fmt.Errorf("%s is not allowed to approve stage %s", approver.Username, stageID)
+	}
+
+	cmd := model.Command{
+		Id:            uuid.New().String(),
+		PipedId:       deployment.PipedId,
+		ApplicationId: deployment.ApplicationId,
+		ProjectId:     deployment.ProjectId,
+		DeploymentId:  deploymentID,
+		StageId:       stageID,
+		Commander:     approver.Username,
+	}
+	var verb string
+	switch action.Name {
+	case model.SlackApprovalActionApprove:
+		cmd.Type = model.Command_APPROVE_STAGE
+		cmd.ApproveStage = &model.Command_ApproveStage{
+			DeploymentId: deploymentID,
+			StageId:      stageID,
+		}
+		verb = "approved"
+	case model.SlackApprovalActionReject:
+		cmd.Type = model.Command_REJECT_STAGE
+		cmd.RejectStage = &model.Command_RejectStage{
+			DeploymentId: deploymentID,
+			StageId:      stageID,
+		}
+		verb = "rejected"
+	default:
+		return "", fmt.Errorf("unsupported action %s", action.Name)
+	}
+
+	if err := h.commandAdder.AddCommand(ctx, &cmd); err != nil {
+		return "", fmt.Errorf("unable to add command: %w", err)
+	}
+	return fmt.Sprintf("<@%s> %s the deployment for %q", in.User.ID, verb, deployment.ApplicationName), nil
+}
+
+func containsUser(users, user string) bool {
+	for _, u := range strings.Split(users, ",") {
+		if strings.TrimSpace(u) == user {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks the signature of the request signed by Slack with the signing secret.
+// https://api.slack.com/authentication/verifying-requests-from-slack
+func (h *slackInteractionHandler) verify(header http.Header, body []byte) error {
+	ts := header.Get(slackTimestampHeader)
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", ts)
+	}
+	if age := h.nowFunc().Sub(time.Unix(unix, 0)); age > slackMaxRequestAge || age < -slackMaxRequestAge {
+		return fmt.Errorf("request timestamp %s is too old", ts)
+	}
+
+	want := makeSlackSignature(h.signingSecret, ts, body)
+	if !hmac.Equal([]byte(want), []byte(header.Get(slackSignatureHeader))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+func makeSlackSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s:%s:", slackSignatureVersion, timestamp)
+	mac.Write(body)
+	return slackSignatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeDeploymentGetter struct {
+	deployment *model.Deployment
+}
+
+func (f *fakeDeploymentGetter) GetDeployment(_ context.Context, _ string) (*model.Deployment, error) {
+	return f.deployment, nil
+}
+
+type fakeCommandAdder struct {
+	commands []*model.Command
+}
+
+func (f *fakeCommandAdder) AddCommand(_ context.Context, cmd *model.Command) error {
+	f.commands = append(f.commands, cmd)
+	return nil
+}
+
+type fakeSlackApproverFinder struct {
+	approvers []config.ControlPlaneSlackApprover
+}
+
+func (f *fakeSlackApproverFinder) FindSlackApprover(projectID, slackUserID string) (config.ControlPlaneSlackApprover, bool) {
+	for _, a := range f.approvers {
+		if a.ProjectID == projectID && a.SlackUserID == slackUserID {
+			return a, true
+		}
+	}
+	return config.ControlPlaneSlackApprover{}, false
+}
+
+func TestSlackInteractionHandler(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	deployment := &model.Deployment{
+		Id:              "deployment-1",
+		ApplicationId:   "app-1",
+		ApplicationName: "demo",
+		PipedId:         "piped-1",
+		ProjectId:       "project-1",
+		Stages: []*model.PipelineStage{
+			{Id: "stage-1", Name: "WAIT_APPROVAL", Status: model.StageStatus_STAGE_RUNNING},
+			{Id: "stage-2", Name: "WAIT_APPROVAL", Status: model.StageStatus_STAGE_SUCCESS},
+			{Id: "stage-3", Name: "WAIT_APPROVAL", Status: model.StageStatus_STAGE_RUNNING, Metadata: map[string]string{"Approvers": "bob,carol"}},
+			{Id: "stage-4", Name: "K8S_SYNC", Status: model.StageStatus_STAGE_RUNNING},
+			{Id: "stage-5", Name: "WAIT_APPROVAL", Status: model.StageStatus_STAGE_RUNNING, Metadata: map[string]string{"Approvers": "alice,bob"}},
+		},
+	}
+	approvers := &fakeSlackApproverFinder{
+		approvers: []config.ControlPlaneSlackApprover{
+			{SlackUserID: "U1", ProjectID: "project-1", Username: "alice"},
+			{SlackUserID: "U2", ProjectID: "project-2", Username: "dave"},
+		},
+	}
+
+	testcases := []struct {
+		name        string
+		action      string
+		value       string
+		userID      string
+		signature   string
+		timestamp   time.Time
+		wantStatus  int
+		wantText    string
+		wantCommand bool
+		wantType    model.Command_Type
+		wantStageID string
+	}{
+		{
+			name:        "approved",
+			action:      model.SlackApprovalActionApprove,
+			value:       model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			timestamp:   now,
+			wantStatus:  http.StatusOK,
+			wantText:    `<@U1> approved the deployment for "demo"`,
+			wantCommand: true,
+			wantType:    model.Command_APPROVE_STAGE,
+			wantStageID: "stage-1",
+		},
+		{
+			name:        "rejected",
+			action:      model.SlackApprovalActionReject,
+			value:       model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			timestamp:   now,
+			wantStatus:  http.StatusOK,
+			wantText:    `<@U1> rejected the deployment for "demo"`,
+			wantCommand: true,
+			wantType:    model.Command_REJECT_STAGE,
+			wantStageID: "stage-1",
+		},
+		{
+			name:        "approved by listed approver",
+			action:      model.SlackApprovalActionApprove,
+			value:       model.MakeSlackApprovalValue("deployment-1", "stage-5"),
+			timestamp:   now,
+			wantStatus:  http.StatusOK,
+			wantText:    `<@U1> approved the deployment for "demo"`,
+			wantCommand: true,
+			wantType:    model.Command_APPROVE_STAGE,
+			wantStageID: "stage-5",
+		},
+		{
+			name:       "stage was already completed",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-2"),
+			timestamp:  now,
+			wantStatus: http.StatusOK,
+			wantText:   "Failed to handle the request: stage stage-2 was already completed",
+		},
+		{
+			name:       "user is not an approver",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-3"),
+			timestamp:  now,
+			wantStatus: http.StatusOK,
+			wantText:   "Failed to handle the request: alice is not allowed to approve stage stage-3",
+		},
+		{
+			name:       "slack user is not mapped",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			userID:     "U3",
+			timestamp:  now,
+			wantStatus: http.StatusOK,
+			wantText:   "Failed to handle the request: slack user U3 is not allowed to approve the stages of project project-1",
+		},
+		{
+			name:       "slack user is mapped in another project",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			userID:     "U2",
+			timestamp:  now,
+			wantStatus: http.StatusOK,
+			wantText:   "Failed to handle the request: slack user U2 is not allowed to approve the stages of project project-1",
+		},
+		{
+			name:       "not a wait approval stage",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-4"),
+			timestamp:  now,
+			wantStatus: http.StatusOK,
+			wantText:   "Failed to handle the request: stage stage-4 is not a WAIT_APPROVAL stage",
+		},
+		{
+			name:       "invalid signature",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			signature:  "v0=invalid",
+			timestamp:  now,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "too old request",
+			action:     model.SlackApprovalActionApprove,
+			value:      model.MakeSlackApprovalValue("deployment-1", "stage-1"),
+			timestamp:  now.Add(-10 * time.Minute),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			commands := &fakeCommandAdder{}
+			h := newSlackInteractionHandler("secret", &fakeDeploymentGetter{deployment: deployment}, commands, approvers, zap.NewNop())
+			h.nowFunc = func() time.Time { return now }
+
+			userID := tc.userID
+			if userID == "" {
+				userID = "U1"
+			}
+			// The user name is set to the approver's one to make sure it is not trusted.
+			payload := `{"type":"interactive_message","callback_id":"wait_approval","actions":[{"name":"` + tc.action + `","value":"` + tc.value + `"}],"user":{"id":"` + userID + `","name":"bob"}}`
+			body := url.Values{"payload": {payload}}.Encode()
+			ts := strconv.FormatInt(tc.timestamp.Unix(), 10)
+			signature := tc.signature
+			if signature == "" {
+				signature = makeSlackSignature([]byte("secret"), ts, []byte(body))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, slackInteractionPath, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set(slackTimestampHeader, ts)
+			req.Header.Set(slackSignatureHeader, signature)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantStatus == http.StatusOK {
+				var resp slackInteractionResponse
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				assert.Equal(t, tc.wantText, resp.Text)
+			}
+			if !tc.wantCommand {
+				assert.Empty(t, commands.commands)
+				return
+			}
+			require.Len(t, commands.commands, 1)
+			cmd := commands.commands[0]
+			assert.Equal(t, tc.wantType, cmd.Type)
+			assert.Equal(t, "alice", cmd.Commander)
+			assert.Equal(t, "piped-1", cmd.PipedId)
+			assert.Equal(t, tc.wantStageID, cmd.StageId)
+		})
+	}
+}
@@ -93,8 +93,14 @@ func (a *authorizer) Authorize(method string, r model.Role) bool {
 		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/CancelDeployment":
 		return isAdmin(r) || isEditor(r)
+	case "/pipe.api.service.webservice.WebService/PauseDeployment":
+		return isAdmin(r) || isEditor(r)
+	case "/pipe.api.service.webservice.WebService/ResumeDeployment":
+		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/ApproveStage":
 		return isAdmin(r) || isEditor(r)
+	case "/pipe.api.service.webservice.WebService/SkipStage":
+		return isAdmin(r) || isEditor(r)
 	case "/pipe.api.service.webservice.WebService/GenerateApplicationSealedSecret":
 		return isAdmin(r) || isEditor(r)
 
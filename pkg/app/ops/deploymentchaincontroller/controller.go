@@ -0,0 +1,321 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package deploymentchaincontroller provides a control-plane component
+// that advances the running deployment chains by requesting the pipeds
+// to sync the applications of the next block once the previous block has succeeded.
+package deploymentchaincontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/datastore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+var interval = 30 * time.Second
+
+const triggeredDeploymentIDKey = "TriggeredDeploymentID"
+
+type deploymentChainStore interface {
+	ListDeploymentChains(ctx context.Context, opts datastore.ListOptions) ([]*model.DeploymentChain, error)
+	UpdateDeploymentChain(ctx context.Context, id string, updater func(c *model.DeploymentChain) error) error
+}
+
+type commandStore interface {
+	AddCommand(ctx context.Context, cmd *model.Command) error
+	GetCommand(ctx context.Context, id string) (*model.Command, error)
+}
+
+type deploymentGetter interface {
+	GetDeployment(ctx context.Context, id string) (*model.Deployment, error)
+}
+
+type applicationGetter interface {
+	GetApplication(ctx context.Context, id string) (*model.Application, error)
+}
+
+type DeploymentChainController struct {
+	chainStore       deploymentChainStore
+	commandStore     commandStore
+	deploymentStore  deploymentGetter
+	applicationStore applicationGetter
+	nowFunc          func() time.Time
+	logger           *zap.Logger
+}
+
+func NewDeploymentChainController(
+	ds datastore.DataStore,
+	logger *zap.Logger,
+) *DeploymentChainController {
+	return &DeploymentChainController{
+		chainStore:       datastore.NewDeploymentChainStore(ds),
+		commandStore:     datastore.NewCommandStore(ds),
+		deploymentStore:  datastore.NewDeploymentStore(ds),
+		applicationStore: datastore.NewApplicationStore(ds),
+		nowFunc:          time.Now,
+		logger:           logger.Named("deployment-chain-controller"),
+	}
+}
+
+func (c *DeploymentChainController) Run(ctx context.Context) error {
+	c.logger.Info("start running DeploymentChainController")
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("deploymentChainController has been stopped")
+			return nil
+
+		case <-t.C:
+			c.syncChains(ctx)
+		}
+	}
+}
+
+func (c *DeploymentChainController) syncChains(ctx context.Context) {
+	opts := datastore.ListOptions{
+		Filters: []datastore.ListFilter{
+			{
+				Field:    "Status",
+				Operator: datastore.OperatorEqual,
+				Value:    model.DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+			},
+		},
+	}
+	chains, err := c.chainStore.ListDeploymentChains(ctx, opts)
+	if err != nil {
+		c.logger.Error("failed to list running deployment chains", zap.Error(err))
+		return
+	}
+
+	for _, chain := range chains {
+		if err := c.syncChain(ctx, chain); err != nil {
+			c.logger.Error("failed to sync deployment chain",
+				zap.String("id", chain.Id),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// syncChain updates the given chain by the latest states of its commands and deployments
+// and then sends the sync commands for the applications of its current block.
+func (c *DeploymentChainController) syncChain(ctx context.Context, chain *model.DeploymentChain) error {
+	commands, deployments, err := c.fetchNodeStates(ctx, chain)
+	if err != nil {
+		return err
+	}
+
+	var (
+		now        = c.nowFunc()
+		blockIndex int
+		nodes      []*model.ChainNode
+		inputs     map[string]string
+	)
+	// The chain may have been changed since it was listed
+	// so the update is applied to the latest one.
+	err = c.chainStore.UpdateDeploymentChain(ctx, chain.Id, func(latest *model.DeploymentChain) error {
+		blockIndex, nodes = progress(latest, commands, deployments, uuid.NewString, now)
+		if len(nodes) > 0 {
+			inputs = latest.Blocks[blockIndex].Inputs
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update deployment chain: %w", err)
+	}
+
+	for _, n := range nodes {
+		if err := c.sendSyncCommand(ctx, chain, blockIndex, inputs, n); err != nil {
+			c.logger.Error("failed to send sync command of deployment chain",
+				zap.String("id", chain.Id),
+				zap.String("application-id", n.ApplicationId),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// fetchNodeStates returns the commands and the deployments referred by the nodes
+// of the given chain which have not been completed yet.
+// The commands which do not exist are not contained in the returned map.
+func (c *DeploymentChainController) fetchNodeStates(ctx context.Context, chain *model.DeploymentChain) (map[string]*model.Command, map[string]*model.Deployment, error) {
+	var (
+		commands    = make(map[string]*model.Command)
+		deployments = make(map[string]*model.Deployment)
+	)
+	for _, b := range chain.Blocks {
+		for _, n := range b.Nodes {
+			if model.IsCompletedDeployment(n.Status) {
+				continue
+			}
+			if n.DeploymentId != "" {
+				d, err := c.deploymentStore.GetDeployment(ctx, n.DeploymentId)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to get deployment %s: %w", n.DeploymentId, err)
+				}
+				deployments[d.Id] = d
+				continue
+			}
+			if n.CommandId == "" {
+				continue
+			}
+			cmd, err := c.commandStore.GetCommand(ctx, n.CommandId)
+			if errors.Is(err, datastore.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get command %s: %w", n.CommandId, err)
+			}
+			commands[cmd.Id] = cmd
+			if id := cmd.Metadata[triggeredDeploymentIDKey]; id != "" {
+				d, err := c.deploymentStore.GetDeployment(ctx, id)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to get deployment %s: %w", id, err)
+				}
+				deployments[d.Id] = d
+			}
+		}
+	}
+	return commands, deployments, nil
+}
+
+func (c *DeploymentChainController) sendSyncCommand(ctx context.Context, chain *model.DeploymentChain, blockIndex int, inputs map[string]string, node *model.ChainNode) error {
+	app, err := c.applicationStore.GetApplication(ctx, node.ApplicationId)
+	if err != nil {
+		return fmt.Errorf("failed to get application: %w", err)
+	}
+	cmd := &model.Command{
+		Id:            node.CommandId,
+		PipedId:       app.PipedId,
+		ApplicationId: app.Id,
+		ProjectId:     app.ProjectId,
+		Type:          model.Command_SYNC_APPLICATION,
+		SyncApplication: &model.Command_SyncApplication{
+			ApplicationId: app.Id,
+			SyncStrategy:  model.SyncStrategy_AUTO,
+			DeploymentChain: &model.DeploymentChainRef{
+				ChainId:    chain.Id,
+				BlockIndex: uint32(blockIndex),
+				Inputs:     inputs,
+			},
+		},
+	}
+	err = c.commandStore.AddCommand(ctx, cmd)
+	if errors.Is(err, datastore.ErrAlreadyExists) {
+		return nil
+	}
+	return err
+}
+
+// progress updates the nodes of the given chain by the given states of their commands and deployments
+// and then decides the status of the chain.
+// It returns the current block index and its nodes whose sync commands should be sent.
+// The command IDs of those nodes are generated by newID when they have not been assigned yet.
+func progress(
+	chain *model.DeploymentChain,
+	commands map[string]*model.Command,
+	deployments map[string]*model.Deployment,
+	newID func() string,
+	now time.Time,
+) (int, []*model.ChainNode) {
+	for i, b := range chain.Blocks {
+		var (
+			succeeded = true
+			failed    *model.ChainNode
+		)
+		for _, n := range b.Nodes {
+			updateNode(n, commands, deployments)
+			switch n.Status {
+			case model.DeploymentStatus_DEPLOYMENT_SUCCESS:
+			case model.DeploymentStatus_DEPLOYMENT_FAILURE, model.DeploymentStatus_DEPLOYMENT_CANCELLED:
+				succeeded = false
+				if failed == nil {
+					failed = n
+				}
+			default:
+				succeeded = false
+			}
+		}
+
+		if failed != nil {
+			chain.Status = model.DeploymentChainStatus_DEPLOYMENT_CHAIN_FAILURE
+			chain.StatusReason = fmt.Sprintf("The deployment of application %s in block %d was not completed successfully", failed.ApplicationName, i)
+			chain.CompletedAt = now.Unix()
+			return i, nil
+		}
+		if succeeded {
+			continue
+		}
+
+		// This is the current block since all previous ones have succeeded.
+		if b.StartedAt == 0 {
+			b.StartedAt = now.Unix()
+		}
+		var nodes []*model.ChainNode
+		for _, n := range b.Nodes {
+			if n.DeploymentId != "" {
+				continue
+			}
+			// The command was not sent yet or failed to be stored.
+			if n.CommandId == "" {
+				n.CommandId = newID()
+				nodes = append(nodes, n)
+				continue
+			}
+			if _, ok := commands[n.CommandId]; !ok {
+				nodes = append(nodes, n)
+			}
+		}
+		return i, nodes
+	}
+
+	chain.Status = model.DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS
+	chain.StatusReason = "All deployments of the chain have been completed successfully"
+	chain.CompletedAt = now.Unix()
+	return len(chain.Blocks) - 1, nil
+}
+
+func updateNode(n *model.ChainNode, commands map[string]*model.Command, deployments map[string]*model.Deployment) {
+	if model.IsCompletedDeployment(n.Status) {
+		return
+	}
+	if n.DeploymentId == "" && n.CommandId != "" {
+		cmd, ok := commands[n.CommandId]
+		if !ok {
+			return
+		}
+		switch cmd.Status {
+		case model.CommandStatus_COMMAND_SUCCEEDED:
+			n.DeploymentId = cmd.Metadata[triggeredDeploymentIDKey]
+		case model.CommandStatus_COMMAND_FAILED, model.CommandStatus_COMMAND_TIMEOUT:
+			n.Status = model.DeploymentStatus_DEPLOYMENT_FAILURE
+			return
+		}
+	}
+	if d, ok := deployments[n.DeploymentId]; ok {
+		n.Status = d.Status
+	}
+}
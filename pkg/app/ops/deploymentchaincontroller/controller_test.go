@@ -0,0 +1,170 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploymentchaincontroller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestProgress(t *testing.T) {
+	now := time.Unix(100, 0)
+	newChain := func(current ...*model.ChainNode) *model.DeploymentChain {
+		return &model.DeploymentChain{
+			Blocks: []*model.ChainBlock{
+				{
+					Nodes: []*model.ChainNode{
+						{ApplicationId: "app-a", DeploymentId: "deployment-a", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS},
+					},
+					StartedAt: 10,
+				},
+				{Nodes: current},
+			},
+		}
+	}
+	testcases := []struct {
+		name        string
+		chain       *model.DeploymentChain
+		commands    map[string]*model.Command
+		deployments map[string]*model.Deployment
+		wantIndex   int
+		wantSend    []string
+		wantStatus  model.DeploymentChainStatus
+		wantNodes   []*model.ChainNode
+	}{
+		{
+			name: "send commands for the new block",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b"},
+				&model.ChainNode{ApplicationId: "app-c"},
+			),
+			wantIndex:  1,
+			wantSend:   []string{"app-b", "app-c"},
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", CommandId: "command-1"},
+				{ApplicationId: "app-c", CommandId: "command-2"},
+			},
+		},
+		{
+			name: "resend the command failed to be stored",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b", CommandId: "command-b"},
+				&model.ChainNode{ApplicationId: "app-c", CommandId: "command-c"},
+			),
+			commands: map[string]*model.Command{
+				"command-b": {Id: "command-b", Status: model.CommandStatus_COMMAND_NOT_HANDLED_YET},
+			},
+			wantIndex:  1,
+			wantSend:   []string{"app-c"},
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", CommandId: "command-b"},
+				{ApplicationId: "app-c", CommandId: "command-c"},
+			},
+		},
+		{
+			name: "update the nodes by the triggered deployments",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b", CommandId: "command-b"},
+				&model.ChainNode{ApplicationId: "app-c", DeploymentId: "deployment-c", Status: model.DeploymentStatus_DEPLOYMENT_RUNNING},
+			),
+			commands: map[string]*model.Command{
+				"command-b": {
+					Id:       "command-b",
+					Status:   model.CommandStatus_COMMAND_SUCCEEDED,
+					Metadata: map[string]string{triggeredDeploymentIDKey: "deployment-b"},
+				},
+			},
+			deployments: map[string]*model.Deployment{
+				"deployment-b": {Id: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS},
+				"deployment-c": {Id: "deployment-c", Status: model.DeploymentStatus_DEPLOYMENT_RUNNING},
+			},
+			wantIndex:  1,
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", CommandId: "command-b", DeploymentId: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS},
+				{ApplicationId: "app-c", DeploymentId: "deployment-c", Status: model.DeploymentStatus_DEPLOYMENT_RUNNING},
+			},
+		},
+		{
+			name: "fail when the command was timed out",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b", CommandId: "command-b"},
+			),
+			commands: map[string]*model.Command{
+				"command-b": {Id: "command-b", Status: model.CommandStatus_COMMAND_TIMEOUT},
+			},
+			wantIndex:  1,
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_FAILURE,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", CommandId: "command-b", Status: model.DeploymentStatus_DEPLOYMENT_FAILURE},
+			},
+		},
+		{
+			name: "fail when a deployment was cancelled",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b", DeploymentId: "deployment-b"},
+			),
+			deployments: map[string]*model.Deployment{
+				"deployment-b": {Id: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_CANCELLED},
+			},
+			wantIndex:  1,
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_FAILURE,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", DeploymentId: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_CANCELLED},
+			},
+		},
+		{
+			name: "succeed when all blocks have succeeded",
+			chain: newChain(
+				&model.ChainNode{ApplicationId: "app-b", DeploymentId: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS},
+			),
+			wantIndex:  1,
+			wantStatus: model.DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS,
+			wantNodes: []*model.ChainNode{
+				{ApplicationId: "app-b", DeploymentId: "deployment-b", Status: model.DeploymentStatus_DEPLOYMENT_SUCCESS},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var count int
+			newID := func() string {
+				count++
+				return fmt.Sprintf("command-%d", count)
+			}
+			index, nodes := progress(tc.chain, tc.commands, tc.deployments, newID, now)
+			assert.Equal(t, tc.wantIndex, index)
+
+			send := make([]string, 0, len(nodes))
+			for _, n := range nodes {
+				send = append(send, n.ApplicationId)
+			}
+			if tc.wantSend == nil {
+				tc.wantSend = []string{}
+			}
+			assert.Equal(t, tc.wantSend, send)
+			assert.Equal(t, tc.wantStatus, tc.chain.Status)
+			assert.Equal(t, tc.wantNodes, tc.chain.Blocks[1].Nodes)
+			assert.Equal(t, int64(10), tc.chain.Blocks[0].StartedAt)
+		})
+	}
+}
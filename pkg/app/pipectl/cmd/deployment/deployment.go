@@ -34,6 +34,9 @@ func NewCommand() *cobra.Command {
 	}
 
 	cmd.AddCommand(newWaitStatusCommand(c))
+	cmd.AddCommand(newSkipStageCommand(c))
+	cmd.AddCommand(newPauseCommand(c))
+	cmd.AddCommand(newResumeCommand(c))
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
 
@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type resume struct {
+	root *command
+
+	deploymentID string
+	stdout       io.Writer
+}
+
+func newResumeCommand(root *command) *cobra.Command {
+	c := &resume{
+		root:   root,
+		stdout: os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused deployment.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringVar(&c.deploymentID, "deployment-id", c.deploymentID, "The deployment ID.")
+	cmd.MarkFlagRequired("deployment-id")
+
+	return cmd
+}
+
+func (c *resume) run(ctx context.Context, t cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	req := &apiservice.ResumeDeploymentRequest{
+		DeploymentId: c.deploymentID,
+	}
+	resp, err := cli.ResumeDeployment(ctx, req)
+	if err != nil {
+		fmt.Fprintf(c.stdout, "Failed to request resuming deployment %s (%v)\n", c.deploymentID, err)
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Successfully requested resuming deployment %s (command: %s)\n", c.deploymentID, resp.CommandId)
+	return nil
+}
@@ -36,6 +36,7 @@ func NewCommand() *cobra.Command {
 	cmd.AddCommand(
 		newEnableCommand(c),
 		newDisableCommand(c),
+		newUpgradeCommand(c),
 	)
 
 	c.clientOptions.RegisterPersistentFlags(cmd)
@@ -0,0 +1,76 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package piped
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/apiservice"
+	"github.com/pipe-cd/pipe/pkg/cli"
+)
+
+type upgrade struct {
+	root *command
+
+	pipedIDs []string
+	version  string
+	stdout   io.Writer
+}
+
+func newUpgradeCommand(root *command) *cobra.Command {
+	c := &upgrade{
+		root:   root,
+		stdout: os.Stdout,
+	}
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrade the given Pipeds to a specific version.",
+		RunE:  cli.WithContext(c.run),
+	}
+
+	cmd.Flags().StringSliceVar(&c.pipedIDs, "piped-id", c.pipedIDs, "The Piped ID. This flag can be specified multiple times.")
+	cmd.Flags().StringVar(&c.version, "version", c.version, "The semantic version Pipeds should be upgraded to, e.g. v0.10.0.")
+	cmd.MarkFlagRequired("piped-id")
+	cmd.MarkFlagRequired("version")
+
+	return cmd
+}
+
+func (c *upgrade) run(ctx context.Context, t cli.Telemetry) error {
+	cli, err := c.root.clientOptions.NewClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %w", err)
+	}
+	defer cli.Close()
+
+	ids := strings.Join(c.pipedIDs, ", ")
+	req := &apiservice.UpgradePipedRequest{
+		PipedIds: c.pipedIDs,
+		Version:  c.version,
+	}
+	if _, err := cli.UpgradePiped(ctx, req); err != nil {
+		fmt.Fprintf(c.stdout, "Failed to request upgrading Pipeds %s to %s (%v)\n", ids, c.version, err)
+		return err
+	}
+
+	fmt.Fprintf(c.stdout, "Successfully requested upgrading Pipeds %s to %s\n", ids, c.version)
+	return nil
+}
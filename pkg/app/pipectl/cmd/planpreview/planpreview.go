@@ -256,7 +256,7 @@ type ApplicationInfo struct {
 	EnvID                string
 	EnvName              string
 	EnvURL               string
-	ApplicationKind      string // KUBERNETES, TERRAFORM, CLOUDRUN, LAMBDA, ECS
+	ApplicationKind      string // KUBERNETES, TERRAFORM, CLOUDRUN, LAMBDA, ECS, APPENGINE, CLOUDFORMATION, NOMAD, AZURE_FUNCTIONS, CUSTOM_SYNC
 	ApplicationDirectory string
 }
 
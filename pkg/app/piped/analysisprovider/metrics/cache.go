@@ -0,0 +1,146 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// resultCache is an in-memory store of the query results shared among
+// all providers created in the process, so that the analyzers running
+// the same query at the same time send only one request to the provider.
+type resultCache struct {
+	entries map[string]cacheEntry
+	mu      sync.Mutex
+	group   *singleflight.Group
+	nowFunc func() time.Time
+}
+
+type cacheEntry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+var defaultResultCache = newResultCache()
+
+func newResultCache() *resultCache {
+	return &resultCache{
+		entries: make(map[string]cacheEntry),
+		group:   &singleflight.Group{},
+		nowFunc: time.Now,
+	}
+}
+
+// getOrQuery returns the cached value of the given key if it has not expired yet.
+// Otherwise it runs the given query and caches the result for the ttl.
+// Errors are never cached.
+func (c *resultCache) getOrQuery(key string, ttl time.Duration, query func() (interface{}, error)) (interface{}, error) {
+	now := c.nowFunc()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && now.Before(e.expireAt) {
+		return e.value, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		v, err := query()
+		if err != nil {
+			return nil, err
+		}
+		c.set(key, v, ttl)
+		return v, nil
+	})
+	return v, err
+}
+
+func (c *resultCache) set(key string, value interface{}, ttl time.Duration) {
+	now := c.nowFunc()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Remove the expired entries to keep the cache small.
+	for k, e := range c.entries {
+		if !now.Before(e.expireAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{
+		value:    value,
+		expireAt: now.Add(ttl),
+	}
+}
+
+type cachedProvider struct {
+	Provider
+	name  string
+	ttl   time.Duration
+	cache *resultCache
+}
+
+type evaluateResult struct {
+	expected bool
+	reason   string
+}
+
+// NewCachedProvider wraps the given provider to reuse the results of the same queries for the ttl.
+// The results are keyed by the provider name, the query and the length of the query range
+// since the ranges given by the analyzers always end at the time of querying.
+func NewCachedProvider(name string, provider Provider, ttl time.Duration) Provider {
+	return &cachedProvider{
+		Provider: provider,
+		name:     name,
+		ttl:      ttl,
+		cache:    defaultResultCache,
+	}
+}
+
+func (p *cachedProvider) Evaluate(ctx context.Context, query string, queryRange QueryRange, evaluator Evaluator) (bool, string, error) {
+	if err := queryRange.Validate(); err != nil {
+		return false, "", err
+	}
+	key := fmt.Sprintf("evaluate/%s/%s/%s/%s", p.name, queryRange.To.Sub(queryRange.From), evaluator, query)
+	v, err := p.cache.getOrQuery(key, p.ttl, func() (interface{}, error) {
+		expected, reason, err := p.Provider.Evaluate(ctx, query, queryRange, evaluator)
+		if err != nil {
+			return nil, err
+		}
+		return evaluateResult{expected: expected, reason: reason}, nil
+	})
+	if err != nil {
+		return false, "", err
+	}
+	r := v.(evaluateResult)
+	return r.expected, r.reason, nil
+}
+
+func (p *cachedProvider) QueryPoints(ctx context.Context, query string, queryRange QueryRange) ([]DataPoint, error) {
+	if err := queryRange.Validate(); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("points/%s/%s/%s", p.name, queryRange.To.Sub(queryRange.From), query)
+	v, err := p.cache.getOrQuery(key, p.ttl, func() (interface{}, error) {
+		return p.Provider.QueryPoints(ctx, query, queryRange)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]DataPoint), nil
+}
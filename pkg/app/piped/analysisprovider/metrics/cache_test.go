@@ -0,0 +1,148 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	points  []DataPoint
+	err     error
+	queries int
+}
+
+func (f *fakeProvider) Type() string {
+	return "FAKE"
+}
+
+func (f *fakeProvider) Evaluate(_ context.Context, _ string, _ QueryRange, evaluator Evaluator) (bool, string, error) {
+	f.queries++
+	if f.err != nil {
+		return false, "", f.err
+	}
+	return evaluator.InRange(f.points[0].Value), "evaluated", nil
+}
+
+func (f *fakeProvider) QueryPoints(_ context.Context, _ string, _ QueryRange) ([]DataPoint, error) {
+	f.queries++
+	return f.points, f.err
+}
+
+type fakeEvaluator struct{}
+
+func (fakeEvaluator) InRange(value float64) bool {
+	return value < 10
+}
+
+func (fakeEvaluator) String() string {
+	return "<= 10"
+}
+
+func TestCachedProviderQueryPoints(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name        string
+		queryRanges []QueryRange
+		queries     []string
+		advance     time.Duration
+		err         error
+		wantQueries int
+	}{
+		{
+			name:        "same query within ttl",
+			queries:     []string{"up", "up"},
+			queryRanges: []QueryRange{{From: now.Add(-time.Minute), To: now}, {From: now, To: now.Add(time.Minute)}},
+			wantQueries: 1,
+		},
+		{
+			name:        "different queries",
+			queries:     []string{"up", "down"},
+			queryRanges: []QueryRange{{From: now.Add(-time.Minute), To: now}, {From: now.Add(-time.Minute), To: now}},
+			wantQueries: 2,
+		},
+		{
+			name:        "different range length",
+			queries:     []string{"up", "up"},
+			queryRanges: []QueryRange{{From: now.Add(-time.Minute), To: now}, {From: now.Add(-time.Hour), To: now}},
+			wantQueries: 2,
+		},
+		{
+			name:        "expired",
+			queries:     []string{"up", "up"},
+			queryRanges: []QueryRange{{From: now.Add(-time.Minute), To: now}, {From: now.Add(-time.Minute), To: now}},
+			advance:     time.Minute,
+			wantQueries: 2,
+		},
+		{
+			name:        "errors are not cached",
+			queries:     []string{"up", "up"},
+			queryRanges: []QueryRange{{From: now.Add(-time.Minute), To: now}, {From: now.Add(-time.Minute), To: now}},
+			err:         ErrNoDataFound,
+			wantQueries: 2,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := now
+			cache := newResultCache()
+			cache.nowFunc = func() time.Time { return current }
+			fake := &fakeProvider{points: []DataPoint{{Timestamp: now.Unix(), Value: 1}}, err: tc.err}
+			p := &cachedProvider{Provider: fake, name: "prometheus", ttl: 30 * time.Second, cache: cache}
+
+			for i := range tc.queries {
+				points, err := p.QueryPoints(context.Background(), tc.queries[i], tc.queryRanges[i])
+				if tc.err != nil {
+					assert.True(t, errors.Is(err, tc.err))
+				} else {
+					require.NoError(t, err)
+					assert.Equal(t, fake.points, points)
+				}
+				current = current.Add(tc.advance)
+			}
+			assert.Equal(t, tc.wantQueries, fake.queries)
+		})
+	}
+}
+
+func TestCachedProviderEvaluate(t *testing.T) {
+	now := time.Now()
+	cache := newResultCache()
+	fake := &fakeProvider{points: []DataPoint{{Value: 1}}}
+	queryRange := QueryRange{From: now.Add(-time.Minute), To: now}
+
+	// The providers sharing the same name share the cached results.
+	p1 := &cachedProvider{Provider: fake, name: "prometheus", ttl: time.Minute, cache: cache}
+	p2 := &cachedProvider{Provider: fake, name: "prometheus", ttl: time.Minute, cache: cache}
+	for _, p := range []*cachedProvider{p1, p2} {
+		expected, reason, err := p.Evaluate(context.Background(), "up", queryRange, fakeEvaluator{})
+		require.NoError(t, err)
+		assert.True(t, expected)
+		assert.Equal(t, "evaluated", reason)
+	}
+	assert.Equal(t, 1, fake.queries)
+
+	// Another provider does not use them.
+	p3 := &cachedProvider{Provider: fake, name: "datadog", ttl: time.Minute, cache: cache}
+	_, _, err := p3.Evaluate(context.Background(), "up", queryRange, fakeEvaluator{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.queries)
+}
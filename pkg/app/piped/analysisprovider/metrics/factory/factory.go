@@ -23,6 +23,7 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/datadog"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/pixie"
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/prometheus"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -74,6 +75,23 @@ func NewProvider(analysisTempCfg *config.TemplatableAnalysisMetrics, providerCfg
 			options = append(options, datadog.WithAddress(cfg.Address))
 		}
 		return datadog.NewProvider(apiKey, applicationKey, options...)
+	case model.AnalysisProviderPixie:
+		cfg := providerCfg.PixieConfig
+		a, err := ioutil.ReadFile(cfg.APIKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the api-key file: %w", err)
+		}
+		options := []pixie.Option{
+			pixie.WithLogger(logger),
+			pixie.WithTimeout(analysisTempCfg.Timeout.Duration()),
+		}
+		if cfg.CloudAddress != "" {
+			options = append(options, pixie.WithCloudAddress(cfg.CloudAddress))
+		}
+		if cfg.CLIPath != "" {
+			options = append(options, pixie.WithCLIPath(cfg.CLIPath))
+		}
+		return pixie.NewProvider(cfg.ClusterID, strings.TrimSpace(string(a)), options...)
 	default:
 		return nil, fmt.Errorf("any of providers config not found")
 	}
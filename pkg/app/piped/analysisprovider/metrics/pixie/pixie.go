@@ -0,0 +1,232 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pixie
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+const (
+	ProviderType   = "Pixie"
+	defaultCLIPath = "px"
+	defaultTimeout = 30 * time.Second
+
+	// The columns of the output table of the PxL script used for analysis.
+	valueColumn = "value"
+	timeColumn  = "time_"
+)
+
+// Provider runs PxL scripts on a Pixie-enabled cluster through the px CLI
+// to obtain the golden signals collected by eBPF, such as latency and error rate.
+// The script must output a table having the "value" column,
+// and optionally the "time_" column used to filter rows by the query range.
+type Provider struct {
+	runScript func(ctx context.Context, script string) ([]byte, error)
+
+	cliPath      string
+	cloudAddress string
+	clusterID    string
+	apiKey       string
+	timeout      time.Duration
+	logger       *zap.Logger
+}
+
+func NewProvider(clusterID, apiKey string, opts ...Option) (*Provider, error) {
+	if clusterID == "" {
+		return nil, fmt.Errorf("cluster-id is required")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("api-key is required")
+	}
+
+	p := &Provider{
+		cliPath:   defaultCLIPath,
+		clusterID: clusterID,
+		apiKey:    apiKey,
+		timeout:   defaultTimeout,
+		logger:    zap.NewNop(),
+	}
+	p.runScript = p.runScriptByCLI
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+type Option func(*Provider)
+
+func WithCLIPath(path string) Option {
+	return func(p *Provider) {
+		p.cliPath = path
+	}
+}
+
+func WithCloudAddress(address string) Option {
+	return func(p *Provider) {
+		p.cloudAddress = address
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(p *Provider) {
+		p.logger = logger.Named("pixie-provider")
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+func (p *Provider) Type() string {
+	return ProviderType
+}
+
+// Evaluate runs the given PxL script, then checks if all values
+// of the output rows within the query range are expected.
+func (p *Provider) Evaluate(ctx context.Context, query string, queryRange metrics.QueryRange, evaluator metrics.Evaluator) (bool, string, error) {
+	points, err := p.QueryPoints(ctx, query, queryRange)
+	if err != nil {
+		return false, "", err
+	}
+	return evaluate(evaluator, points)
+}
+
+// evaluate checks if all data points are within the expected range.
+func evaluate(evaluator metrics.Evaluator, points []metrics.DataPoint) (bool, string, error) {
+	if len(points) == 0 {
+		return false, "", fmt.Errorf("no data points found within the queried range: %w", metrics.ErrNoDataFound)
+	}
+	for _, point := range points {
+		if !evaluator.InRange(point.Value) {
+			reason := fmt.Sprintf("found a value (%g) that is out of the expected range (%s)", point.Value, evaluator)
+			return false, reason, nil
+		}
+	}
+	reason := fmt.Sprintf("all values are within the expected range (%s)", evaluator)
+	return true, reason, nil
+}
+
+// QueryPoints runs the given PxL script and gives back the values of its output rows within the given range.
+func (p *Provider) QueryPoints(ctx context.Context, query string, queryRange metrics.QueryRange) ([]metrics.DataPoint, error) {
+	if err := queryRange.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	out, err := p.runScript(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the PxL script: %w", err)
+	}
+	points, err := parseRows(out, queryRange)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("no rows found within the queried range: %w", metrics.ErrNoDataFound)
+	}
+	return points, nil
+}
+
+func (p *Provider) runScriptByCLI(ctx context.Context, script string) ([]byte, error) {
+	args := []string{"run", "--cluster", p.clusterID, "--output", "json", "--filename", "-"}
+	cmd := exec.CommandContext(ctx, p.cliPath, args...)
+	cmd.Stdin = strings.NewReader(script)
+	cmd.Env = append(os.Environ(), "PX_API_KEY="+p.apiKey)
+	if p.cloudAddress != "" {
+		cmd.Env = append(cmd.Env, "PX_CLOUD_ADDR="+p.cloudAddress)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		p.logger.Info("failed to run px command", zap.String("stderr", stderr.String()), zap.Error(err))
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseRows converts the JSON lines printed by the px CLI into data points.
+// The rows whose time is out of the given range are ignored.
+func parseRows(data []byte, queryRange metrics.QueryRange) ([]metrics.DataPoint, error) {
+	var (
+		points  []metrics.DataPoint
+		scanner = bufio.NewScanner(bytes.NewReader(data))
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var row map[string]interface{}
+		if err := json.Unmarshal(line, &row); err != nil {
+			return nil, fmt.Errorf("invalid output row: %w", err)
+		}
+		raw, ok := row[valueColumn]
+		if !ok {
+			return nil, fmt.Errorf("invalid output row: no %q column found", valueColumn)
+		}
+		value, ok := raw.(float64)
+		if !ok {
+			return nil, fmt.Errorf("invalid output row: %q column must be a number but got %v", valueColumn, raw)
+		}
+
+		point := metrics.DataPoint{Value: value}
+		if raw, ok := row[timeColumn]; ok {
+			t, err := parseTime(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid output row: %w", err)
+			}
+			if t.Before(queryRange.From) || t.After(queryRange.To) {
+				continue
+			}
+			point.Timestamp = t.Unix()
+		}
+		points = append(points, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the output rows: %w", err)
+	}
+	return points, nil
+}
+
+// parseTime accepts both an RFC3339 string and nanoseconds since the epoch.
+func parseTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	case float64:
+		return time.Unix(0, int64(t)), nil
+	default:
+		return time.Time{}, fmt.Errorf("unexpected %q column: %v", timeColumn, v)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pixie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+type fakeEvaluator struct {
+	expected bool
+}
+
+func (f *fakeEvaluator) InRange(_ float64) bool {
+	return f.expected
+}
+
+func (f *fakeEvaluator) String() string {
+	return ""
+}
+
+func TestProviderQueryPoints(t *testing.T) {
+	queryRange := metrics.QueryRange{
+		From: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2021, time.January, 1, 0, 5, 0, 0, time.UTC),
+	}
+	testcases := []struct {
+		name      string
+		output    string
+		runErr    error
+		want      []metrics.DataPoint
+		wantErr   bool
+		errNoData bool
+	}{
+		{
+			name:    "script failed",
+			runErr:  fmt.Errorf("failed"),
+			wantErr: true,
+		},
+		{
+			name:      "no rows",
+			output:    "\n",
+			wantErr:   true,
+			errNoData: true,
+		},
+		{
+			name:    "no value column",
+			output:  `{"_tableName":"output","latency":1}`,
+			wantErr: true,
+		},
+		{
+			name:    "non-number value",
+			output:  `{"_tableName":"output","value":"1"}`,
+			wantErr: true,
+		},
+		{
+			name: "rows without time",
+			output: `{"_tableName":"output","value":0.1}
+{"_tableName":"output","value":0.2}
+`,
+			want: []metrics.DataPoint{
+				{Value: 0.1},
+				{Value: 0.2},
+			},
+		},
+		{
+			name: "rows out of range are ignored",
+			output: `{"_tableName":"output","time_":"2020-12-31T23:59:00Z","value":0.1}
+{"_tableName":"output","time_":"2021-01-01T00:01:00Z","value":0.2}
+{"_tableName":"output","time_":1609459380000000000,"value":0.3}
+`,
+			want: []metrics.DataPoint{
+				{Timestamp: 1609459260, Value: 0.2},
+				{Timestamp: 1609459380, Value: 0.3},
+			},
+		},
+		{
+			name:      "all rows out of range",
+			output:    `{"_tableName":"output","time_":"2020-12-31T23:59:00Z","value":0.1}`,
+			wantErr:   true,
+			errNoData: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := Provider{
+				runScript: func(_ context.Context, _ string) ([]byte, error) {
+					return []byte(tc.output), tc.runErr
+				},
+				timeout: defaultTimeout,
+				logger:  zap.NewNop(),
+			}
+			got, err := provider.QueryPoints(context.Background(), "script", queryRange)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.errNoData, errors.Is(err, metrics.ErrNoDataFound))
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	testcases := []struct {
+		name      string
+		evaluator metrics.Evaluator
+		points    []metrics.DataPoint
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "no data points found",
+			evaluator: &fakeEvaluator{},
+			wantErr:   true,
+		},
+		{
+			name:      "out of range",
+			evaluator: &fakeEvaluator{expected: false},
+			points:    []metrics.DataPoint{{Value: 1}},
+			want:      false,
+		},
+		{
+			name:      "within the range",
+			evaluator: &fakeEvaluator{expected: true},
+			points:    []metrics.DataPoint{{Value: 1}},
+			want:      true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := evaluate(tc.evaluator, tc.points)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package changesummary generates a read-only summary of the changes
+// made to an application since its last deployed commit
+// to give the approvers of the deployment its context.
+package changesummary
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/pipe/pkg/diff"
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+// MetadataKey is the key of the deployment metadata storing the rendered summary.
+const MetadataKey = "ChangeSummary"
+
+const (
+	maxRenderedFiles      = 50
+	maxRenderedValueLines = 100
+)
+
+var (
+	// imageRegex matches the container image specified in YAML, JSON and HCL files,
+	// e.g. `image: nginx:1.21`, `"image": "nginx:1.21"`, `image = "nginx:1.21"`.
+	imageRegex = regexp.MustCompile(`(?m)^[ \t]*-?[ \t]*"?image"?[ \t]*[:=][ \t]*["']?([^"'\s,{}\[\]]+)["']?`)
+	// valuesFileRegex matches the values files of Helm charts, e.g. values.yaml, values-prod.yml.
+	valuesFileRegex = regexp.MustCompile(`^values[^/]*\.ya?ml$`)
+)
+
+type gitRepo interface {
+	DiffFiles(ctx context.Context, from, to string, paths ...string) ([]git.FileChange, error)
+	ShowFile(ctx context.Context, commit, path string) ([]byte, error)
+}
+
+// Summary represents the changes made to an application between two commits.
+type Summary struct {
+	From string
+	To   string
+	// The touched files. Their paths are relative to the application directory.
+	Files  []git.FileChange
+	Images []ImageChange
+	Values []ValuesChange
+}
+
+// ImageChange represents a container image whose tags were changed.
+type ImageChange struct {
+	Name string
+	// The tags used in the from commit. Empty means the image was added.
+	From []string
+	// The tags used in the to commit. Empty means the image was removed.
+	To []string
+}
+
+// ValuesChange represents the difference of a values file.
+type ValuesChange struct {
+	Path string
+	Diff string
+}
+
+// Generate builds the summary of the changes made to the application placing at appPath
+// between the given commits. The appPath is relative to the repository root.
+// Only the committed contents are read, so the secrets decrypted by piped are never included.
+func Generate(ctx context.Context, repo gitRepo, appPath, from, to string) (*Summary, error) {
+	appPath = path.Clean(appPath)
+	var paths []string
+	if appPath != "." {
+		paths = append(paths, appPath)
+	}
+	changes, err := repo.DiffFiles(ctx, from, to, paths...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the changed files: %w", err)
+	}
+
+	var (
+		s = &Summary{
+			From:  from,
+			To:    to,
+			Files: make([]git.FileChange, 0, len(changes)),
+		}
+		fromImages = make(map[string]map[string]struct{})
+		toImages   = make(map[string]map[string]struct{})
+	)
+	for _, c := range changes {
+		relPath := strings.TrimPrefix(c.Path, appPath+"/")
+		if appPath == "." {
+			relPath = c.Path
+		}
+		s.Files = append(s.Files, git.FileChange{Status: c.Status, Path: relPath})
+
+		var fromContent, toContent []byte
+		if c.Status != git.FileAdded {
+			if fromContent, err = repo.ShowFile(ctx, from, c.Path); err != nil {
+				return nil, fmt.Errorf("failed to read %s at %s: %w", c.Path, from, err)
+			}
+		}
+		if c.Status != git.FileDeleted {
+			if toContent, err = repo.ShowFile(ctx, to, c.Path); err != nil {
+				return nil, fmt.Errorf("failed to read %s at %s: %w", c.Path, to, err)
+			}
+		}
+		collectImages(fromContent, fromImages)
+		collectImages(toContent, toImages)
+
+		if c.Status == git.FileModified && valuesFileRegex.MatchString(path.Base(c.Path)) {
+			d, err := diffValues(fromContent, toContent)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare values file %s: %w", c.Path, err)
+			}
+			if d != "" {
+				s.Values = append(s.Values, ValuesChange{Path: relPath, Diff: d})
+			}
+		}
+	}
+	s.Images = diffImages(fromImages, toImages)
+	return s, nil
+}
+
+// Render returns the text of the summary to be shown in the deployment.
+func (s *Summary) Render() string {
+	var b strings.Builder
+	if len(s.Files) == 0 {
+		fmt.Fprintf(&b, "No files of the application were changed since the last deployed commit %s\n", shortHash(s.From))
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%d files of the application were changed since the last deployed commit %s:\n", len(s.Files), shortHash(s.From))
+	for i, f := range s.Files {
+		if i == maxRenderedFiles {
+			fmt.Fprintf(&b, "  ... and %d more files\n", len(s.Files)-maxRenderedFiles)
+			break
+		}
+		fmt.Fprintf(&b, "  %s %s\n", f.Status, f.Path)
+	}
+
+	if len(s.Images) > 0 {
+		b.WriteString("Image changes:\n")
+		for _, i := range s.Images {
+			fmt.Fprintf(&b, "  %s: %s -> %s\n", i.Name, renderTags(i.From), renderTags(i.To))
+		}
+	}
+
+	for _, v := range s.Values {
+		fmt.Fprintf(&b, "Values changes in %s:\n", v.Path)
+		lines := strings.Split(strings.TrimRight(v.Diff, "\n"), "\n")
+		for i, l := range lines {
+			if i == maxRenderedValueLines {
+				fmt.Fprintf(&b, "  ... and %d more lines\n", len(lines)-maxRenderedValueLines)
+				break
+			}
+			fmt.Fprintf(&b, "  %s\n", l)
+		}
+	}
+	return b.String()
+}
+
+func collectImages(content []byte, images map[string]map[string]struct{}) {
+	for _, m := range imageRegex.FindAllSubmatch(content, -1) {
+		name, tag := parseImage(string(m[1]))
+		if _, ok := images[name]; !ok {
+			images[name] = make(map[string]struct{})
+		}
+		images[name][tag] = struct{}{}
+	}
+}
+
+// parseImage splits the given image reference into its name and its tag or digest.
+func parseImage(image string) (name, tag string) {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+func diffImages(from, to map[string]map[string]struct{}) []ImageChange {
+	names := make(map[string]struct{}, len(from)+len(to))
+	for n := range from {
+		names[n] = struct{}{}
+	}
+	for n := range to {
+		names[n] = struct{}{}
+	}
+
+	changes := make([]ImageChange, 0)
+	for n := range names {
+		fromTags, toTags := sortedKeys(from[n]), sortedKeys(to[n])
+		if strings.Join(fromTags, ",") == strings.Join(toTags, ",") {
+			continue
+		}
+		changes = append(changes, ImageChange{Name: n, From: fromTags, To: toTags})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+func diffValues(from, to []byte) (string, error) {
+	var x, y map[string]interface{}
+	if err := yaml.Unmarshal(from, &x); err != nil {
+		return "", err
+	}
+	if err := yaml.Unmarshal(to, &y); err != nil {
+		return "", err
+	}
+	result, err := diff.DiffUnstructureds(unstructured.Unstructured{Object: x}, unstructured.Unstructured{Object: y}, diff.WithEquateEmpty())
+	if err != nil {
+		return "", err
+	}
+	return diff.NewRenderer().Render(result.Nodes()), nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func renderTags(tags []string) string {
+	if len(tags) == 0 {
+		return "(none)"
+	}
+	return strings.Join(tags, ", ")
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changesummary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+type fakeRepo struct {
+	changes []git.FileChange
+	// map[commit]map[path]content
+	files map[string]map[string]string
+}
+
+func (r *fakeRepo) DiffFiles(_ context.Context, _, _ string, paths ...string) ([]git.FileChange, error) {
+	out := make([]git.FileChange, 0, len(r.changes))
+	for _, c := range r.changes {
+		if len(paths) > 0 && !strings.HasPrefix(c.Path, paths[0]+"/") {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (r *fakeRepo) ShowFile(_ context.Context, commit, path string) ([]byte, error) {
+	content, ok := r.files[commit][path]
+	if !ok {
+		return nil, fmt.Errorf("%s does not exist at %s", path, commit)
+	}
+	return []byte(content), nil
+}
+
+func TestGenerate(t *testing.T) {
+	repo := &fakeRepo{
+		changes: []git.FileChange{
+			{Status: git.FileModified, Path: "apps/demo/deployment.yaml"},
+			{Status: git.FileModified, Path: "apps/demo/values.yaml"},
+			{Status: git.FileAdded, Path: "apps/demo/job.nomad"},
+			{Status: git.FileDeleted, Path: "apps/demo/old.yaml"},
+			{Status: git.FileModified, Path: "apps/other/deployment.yaml"},
+		},
+		files: map[string]map[string]string{
+			"0123456789": {
+				"apps/demo/deployment.yaml": "spec:\n  containers:\n  - name: app\n    image: gcr.io/pipecd/demo:v0.1.0\n  - name: proxy\n    image: envoyproxy/envoy:v1.18.0\n",
+				"apps/demo/values.yaml":     "replicaCount: 2\nresources:\n  limits:\n    cpu: 100m\n",
+				"apps/demo/old.yaml":        "image: busybox\n",
+			},
+			"abcdefghij": {
+				"apps/demo/deployment.yaml": "spec:\n  containers:\n  - name: app\n    image: gcr.io/pipecd/demo:v0.2.0\n  - name: proxy\n    image: envoyproxy/envoy:v1.18.0\n",
+				"apps/demo/values.yaml":     "replicaCount: 3\nresources:\n  limits:\n    cpu: 100m\n",
+				"apps/demo/job.nomad":       "task \"worker\" {\n  config {\n    image = \"gcr.io/pipecd/worker@sha256:abc\"\n  }\n}\n",
+			},
+		},
+	}
+
+	s, err := Generate(context.Background(), repo, "apps/demo/", "0123456789", "abcdefghij")
+	require.NoError(t, err)
+
+	assert.Equal(t, []git.FileChange{
+		{Status: git.FileModified, Path: "deployment.yaml"},
+		{Status: git.FileModified, Path: "values.yaml"},
+		{Status: git.FileAdded, Path: "job.nomad"},
+		{Status: git.FileDeleted, Path: "old.yaml"},
+	}, s.Files)
+	assert.Equal(t, []ImageChange{
+		{Name: "busybox", From: []string{"latest"}, To: []string{}},
+		{Name: "gcr.io/pipecd/demo", From: []string{"v0.1.0"}, To: []string{"v0.2.0"}},
+		{Name: "gcr.io/pipecd/worker", From: []string{}, To: []string{"sha256:abc"}},
+	}, s.Images)
+	require.Len(t, s.Values, 1)
+	assert.Equal(t, "values.yaml", s.Values[0].Path)
+	assert.Equal(t, "#replicaCount\n- replicaCount: 2\n+ replicaCount: 3\n\n", s.Values[0].Diff)
+
+	expected := `4 files of the application were changed since the last deployed commit 0123456:
+  M deployment.yaml
+  M values.yaml
+  A job.nomad
+  D old.yaml
+Image changes:
+  busybox: latest -> (none)
+  gcr.io/pipecd/demo: v0.1.0 -> v0.2.0
+  gcr.io/pipecd/worker: (none) -> sha256:abc
+Values changes in values.yaml:
+  #replicaCount
+  - replicaCount: 2
+  + replicaCount: 3
+`
+	assert.Equal(t, expected, s.Render())
+}
+
+func TestParseImage(t *testing.T) {
+	testcases := []struct {
+		image    string
+		wantName string
+		wantTag  string
+	}{
+		{image: "nginx", wantName: "nginx", wantTag: "latest"},
+		{image: "nginx:1.21", wantName: "nginx", wantTag: "1.21"},
+		{image: "localhost:5000/app", wantName: "localhost:5000/app", wantTag: "latest"},
+		{image: "localhost:5000/app:v1", wantName: "localhost:5000/app", wantTag: "v1"},
+		{image: "gcr.io/app@sha256:abc", wantName: "gcr.io/app", wantTag: "sha256:abc"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.image, func(t *testing.T) {
+			name, tag := parseImage(tc.image)
+			assert.Equal(t, tc.wantName, name)
+			assert.Equal(t, tc.wantTag, tag)
+		})
+	}
+}
+
+func TestRenderNoChanges(t *testing.T) {
+	s := &Summary{From: "0123456789", To: "abcdefghij"}
+	assert.Equal(t, "No files of the application were changed since the last deployed commit 0123456\n", s.Render())
+}
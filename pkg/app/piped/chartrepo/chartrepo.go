@@ -18,7 +18,9 @@ package chartrepo
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
@@ -26,6 +28,14 @@ import (
 	"github.com/pipe-cd/pipe/pkg/config"
 )
 
+const (
+	// OCIEnv is the environment variable required by helm to handle OCI registries.
+	// This is needed for the helm versions older than 3.8.0.
+	OCIEnv = "HELM_EXPERIMENTAL_OCI=1"
+
+	ociScheme = "oci://"
+)
+
 var updateGroup = &singleflight.Group{}
 
 type registry interface {
@@ -43,6 +53,13 @@ func Add(ctx context.Context, repos []config.HelmChartRepository, reg registry,
 	}
 
 	for _, repo := range repos {
+		if repo.IsOCI() {
+			if err := login(ctx, helm, repo, logger); err != nil {
+				return err
+			}
+			continue
+		}
+
 		args := []string{"repo", "add", repo.Name, repo.Address}
 		if repo.Insecure {
 			args = append(args, "--insecure-skip-tls-verify")
@@ -60,6 +77,65 @@ func Add(ctx context.Context, repos []config.HelmChartRepository, reg registry,
 	return nil
 }
 
+// login authenticates to the OCI registry of the given repository.
+// Nothing will be done when the repository does not require any credentials.
+// The repository using the credentials of a cloud provider is logged in by CredentialsRefresher.
+// https://helm.sh/docs/topics/registries/
+// helm registry login ghcr.io --username my-username --password-stdin
+func login(ctx context.Context, helm string, repo config.HelmChartRepository, logger *zap.Logger) error {
+	if repo.Username == "" && repo.Password == "" {
+		return nil
+	}
+	if err := loginRegistry(ctx, helm, repo, repo.Username, repo.Password); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("successfully logged in to OCI registry of chart repository: %s", repo.Name))
+	return nil
+}
+
+func loginRegistry(ctx context.Context, helm string, repo config.HelmChartRepository, username, password string) error {
+	args := []string{"registry", "login", OCIRegistryHost(repo.Address), "--username", username, "--password-stdin"}
+	if repo.Insecure {
+		args = append(args, "--insecure")
+	}
+	cmd := exec.CommandContext(ctx, helm, args...)
+	cmd.Env = append(os.Environ(), OCIEnv)
+	cmd.Stdin = strings.NewReader(password)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to login to OCI registry of chart repository %s: %s (%w)", repo.Name, string(out), err)
+	}
+	return nil
+}
+
+// OCIRegistryHost returns the host part of the given OCI registry address.
+// e.g. oci://ghcr.io/org/charts -> ghcr.io
+func OCIRegistryHost(address string) string {
+	address = strings.TrimPrefix(address, ociScheme)
+	if i := strings.Index(address, "/"); i >= 0 {
+		return address[:i]
+	}
+	return address
+}
+
+// OCIChartReference returns the reference to the given chart stored in an OCI registry.
+// e.g. oci://ghcr.io/org/charts, foo -> oci://ghcr.io/org/charts/foo
+func OCIChartReference(address, chart string) string {
+	address = strings.TrimSuffix(strings.TrimPrefix(address, ociScheme), "/")
+	return fmt.Sprintf("%s%s/%s", ociScheme, address, chart)
+}
+
+// HasHTTPRepository checks whether the given list contains at least one HTTP repository
+// which should be updated by "helm repo update" command.
+func HasHTTPRepository(repos []config.HelmChartRepository) bool {
+	for _, repo := range repos {
+		if !repo.IsOCI() {
+			return true
+		}
+	}
+	return false
+}
+
 func Update(ctx context.Context, reg registry, logger *zap.Logger) error {
 	_, err, _ := updateGroup.Do("update", func() (interface{}, error) {
 		return nil, update(ctx, reg, logger)
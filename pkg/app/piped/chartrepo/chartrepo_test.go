@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOCIRegistryHost(t *testing.T) {
+	testcases := []struct {
+		address  string
+		expected string
+	}{
+		{
+			address:  "oci://ghcr.io/org/charts",
+			expected: "ghcr.io",
+		},
+		{
+			address:  "ghcr.io/org/charts",
+			expected: "ghcr.io",
+		},
+		{
+			address:  "oci://localhost:5000",
+			expected: "localhost:5000",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.address, func(t *testing.T) {
+			got := OCIRegistryHost(tc.address)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestOCIChartReference(t *testing.T) {
+	testcases := []struct {
+		address  string
+		chart    string
+		expected string
+	}{
+		{
+			address:  "oci://ghcr.io/org/charts",
+			chart:    "foo",
+			expected: "oci://ghcr.io/org/charts/foo",
+		},
+		{
+			address:  "ghcr.io/org/charts/",
+			chart:    "foo",
+			expected: "oci://ghcr.io/org/charts/foo",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.address, func(t *testing.T) {
+			got := OCIChartReference(tc.address, tc.chart)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
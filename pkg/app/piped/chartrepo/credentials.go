@@ -0,0 +1,162 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// How long before the expiry the registry token should be refreshed.
+	credentialsRefreshMargin = 10 * time.Minute
+	// How long to wait before retrying after failing to refresh the registry token.
+	credentialsRetryInterval = time.Minute
+	// How long the registry token is assumed to be valid when its expiry is unknown.
+	defaultCredentialsLifetime = time.Hour
+)
+
+// registryCredentials is a short-lived credentials to log in to an OCI registry.
+type registryCredentials struct {
+	Username string
+	Password string
+	Expiry   time.Time
+}
+
+// credentialsIssuer issues the credentials of an OCI registry by using the credentials of a cloud provider.
+type credentialsIssuer interface {
+	Issue(ctx context.Context) (registryCredentials, error)
+}
+
+func newCredentialsIssuer(cp config.PipedCloudProvider, registryHost string) (credentialsIssuer, error) {
+	switch cp.Type {
+	case model.CloudProviderECS:
+		cfg := cp.ECSConfig
+		return newECRCredentialsIssuer(registryHost, cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile), nil
+	case model.CloudProviderLambda:
+		cfg := cp.LambdaConfig
+		return newECRCredentialsIssuer(registryHost, cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile), nil
+	case model.CloudProviderCloudRun:
+		return newGARCredentialsIssuer(cp.CloudRunConfig.CredentialsFile), nil
+	default:
+		return nil, fmt.Errorf("cloud provider %s of type %s cannot be used to log in to OCI registries", cp.Name, cp.Type)
+	}
+}
+
+type credentialsTarget struct {
+	repo   config.HelmChartRepository
+	issuer credentialsIssuer
+}
+
+// CredentialsRefresher logs in to the OCI registries of the chart repositories
+// by using the credentials of the configured cloud providers,
+// and keeps logging in again before the issued tokens expire.
+type CredentialsRefresher struct {
+	targets []credentialsTarget
+	reg     registry
+	logger  *zap.Logger
+}
+
+// NewCredentialsRefresher returns a refresher for all chart repositories having credentialsProvider in the given piped config.
+func NewCredentialsRefresher(cfg *config.PipedSpec, reg registry, logger *zap.Logger) (*CredentialsRefresher, error) {
+	r := &CredentialsRefresher{
+		reg:    reg,
+		logger: logger.Named("chart-repository-credentials"),
+	}
+	for _, repo := range cfg.ChartRepositories {
+		if repo.CredentialsProvider == "" {
+			continue
+		}
+		cp, ok := cfg.FindRegistryCredentialsProvider(repo.CredentialsProvider)
+		if !ok {
+			return nil, fmt.Errorf("cloud provider %s for chart repository %s was not found", repo.CredentialsProvider, repo.Name)
+		}
+		issuer, err := newCredentialsIssuer(cp, OCIRegistryHost(repo.Address))
+		if err != nil {
+			return nil, err
+		}
+		r.targets = append(r.targets, credentialsTarget{
+			repo:   repo,
+			issuer: issuer,
+		})
+	}
+	return r, nil
+}
+
+// IsEmpty checks whether there is no chart repository to be logged in.
+func (r *CredentialsRefresher) IsEmpty() bool {
+	return len(r.targets) == 0
+}
+
+// Login logs in to all OCI registries once and returns the earliest time to refresh.
+func (r *CredentialsRefresher) Login(ctx context.Context) (time.Time, error) {
+	helm, _, err := r.reg.Helm(ctx, "")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to find helm to login to registries (%w)", err)
+	}
+
+	var next time.Time
+	for _, t := range r.targets {
+		creds, err := t.issuer.Issue(ctx)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to issue credentials for chart repository %s: %w", t.repo.Name, err)
+		}
+		if creds.Expiry.IsZero() {
+			creds.Expiry = time.Now().Add(defaultCredentialsLifetime)
+		}
+		if err := loginRegistry(ctx, helm, t.repo, creds.Username, creds.Password); err != nil {
+			return time.Time{}, err
+		}
+		r.logger.Info(fmt.Sprintf("successfully logged in to OCI registry of chart repository: %s", t.repo.Name),
+			zap.Time("expiry", creds.Expiry),
+		)
+		if refreshAt := creds.Expiry.Add(-credentialsRefreshMargin); next.IsZero() || refreshAt.Before(next) {
+			next = refreshAt
+		}
+	}
+	return next, nil
+}
+
+// Run keeps logging in to all OCI registries before the issued tokens expire
+// until the given context is done.
+func (r *CredentialsRefresher) Run(ctx context.Context, next time.Time) error {
+	r.logger.Info("start running chart repository credentials refresher")
+	for {
+		wait := time.Until(next)
+		if wait < credentialsRetryInterval {
+			wait = credentialsRetryInterval
+		}
+		select {
+		case <-ctx.Done():
+			r.logger.Info("chart repository credentials refresher has been stopped")
+			return nil
+		case <-time.After(wait):
+		}
+
+		refreshAt, err := r.Login(ctx)
+		if err != nil {
+			r.logger.Error("failed to refresh chart repository credentials", zap.Error(err))
+			next = time.Now().Add(credentialsRetryInterval)
+			continue
+		}
+		next = refreshAt
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+const (
+	ecrGetAuthorizationTokenTarget = "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken"
+	ecrRequestTimeout              = 30 * time.Second
+)
+
+// ecrCredentialsIssuer issues the credentials of Amazon ECR
+// by calling GetAuthorizationToken API with the AWS credentials.
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_GetAuthorizationToken.html
+type ecrCredentialsIssuer struct {
+	region          string
+	profile         string
+	credentialsFile string
+	roleARN         string
+	tokenFile       string
+	client          *http.Client
+}
+
+func newECRCredentialsIssuer(registryHost, region, profile, credentialsFile, roleARN, tokenFile string) *ecrCredentialsIssuer {
+	if r := ecrRegistryRegion(registryHost); r != "" {
+		region = r
+	}
+	return &ecrCredentialsIssuer{
+		region:          region,
+		profile:         profile,
+		credentialsFile: credentialsFile,
+		roleARN:         roleARN,
+		tokenFile:       tokenFile,
+		client:          &http.Client{Timeout: ecrRequestTimeout},
+	}
+}
+
+// ecrRegistryRegion returns the region of the given ECR registry host.
+// e.g. 123456789012.dkr.ecr.us-west-2.amazonaws.com -> us-west-2
+func ecrRegistryRegion(host string) string {
+	parts := strings.Split(host, ".")
+	if len(parts) < 6 || parts[1] != "dkr" || parts[2] != "ecr" {
+		return ""
+	}
+	return parts[3]
+}
+
+type ecrAuthorizationTokenResponse struct {
+	AuthorizationData []struct {
+		AuthorizationToken string  `json:"authorizationToken"`
+		ExpiresAt          float64 `json:"expiresAt"`
+	} `json:"authorizationData"`
+}
+
+func (i *ecrCredentialsIssuer) Issue(ctx context.Context) (registryCredentials, error) {
+	if i.region == "" {
+		return registryCredentials{}, fmt.Errorf("region is required to get ECR authorization token")
+	}
+
+	optFns := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(i.region)}
+	if i.credentialsFile != "" {
+		optFns = append(optFns, awsconfig.WithSharedCredentialsFiles([]string{i.credentialsFile}))
+	}
+	if i.profile != "" {
+		optFns = append(optFns, awsconfig.WithSharedConfigProfile(i.profile))
+	}
+	if i.tokenFile != "" && i.roleARN != "" {
+		optFns = append(optFns, awsconfig.WithWebIdentityRoleCredentialOptions(func(v *stscreds.WebIdentityRoleOptions) {
+			v.RoleARN = i.roleARN
+			v.TokenRetriever = stscreds.IdentityTokenFile(i.tokenFile)
+		}))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	body := []byte("{}")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://api.ecr.%s.amazonaws.com/", i.region), bytes.NewReader(body))
+	if err != nil {
+		return registryCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", ecrGetAuthorizationTokenTarget)
+	hash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "ecr", i.region, time.Now()); err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to sign ECR request: %w", err)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to get ECR authorization token: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return registryCredentials{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return registryCredentials{}, fmt.Errorf("unexpected HTTP status code from ECR: %d (%s)", resp.StatusCode, string(data))
+	}
+	return parseECRAuthorizationToken(data)
+}
+
+// parseECRAuthorizationToken extracts the credentials from the response of GetAuthorizationToken API.
+// The token is a base64 encoded string of "AWS:<password>".
+func parseECRAuthorizationToken(data []byte) (registryCredentials, error) {
+	var resp ecrAuthorizationTokenResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to parse ECR authorization token: %w", err)
+	}
+	if len(resp.AuthorizationData) == 0 {
+		return registryCredentials{}, fmt.Errorf("no ECR authorization data was returned")
+	}
+	d := resp.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(d.AuthorizationToken)
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to decode ECR authorization token: %w", err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return registryCredentials{}, fmt.Errorf("malformed ECR authorization token")
+	}
+	return registryCredentials{
+		Username: parts[0],
+		Password: parts[1],
+		Expiry:   time.Unix(int64(d.ExpiresAt), 0),
+	}, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECRRegistryRegion(t *testing.T) {
+	testcases := []struct {
+		host     string
+		expected string
+	}{
+		{
+			host:     "123456789012.dkr.ecr.us-west-2.amazonaws.com",
+			expected: "us-west-2",
+		},
+		{
+			host:     "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn",
+			expected: "cn-north-1",
+		},
+		{
+			host:     "ghcr.io",
+			expected: "",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.host, func(t *testing.T) {
+			got := ecrRegistryRegion(tc.host)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestParseECRAuthorizationToken(t *testing.T) {
+	testcases := []struct {
+		name        string
+		data        string
+		expected    registryCredentials
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			// base64("AWS:password")
+			data: `{"authorizationData":[{"authorizationToken":"QVdTOnBhc3N3b3Jk","expiresAt":1.6E9,"proxyEndpoint":"https://123456789012.dkr.ecr.us-west-2.amazonaws.com"}]}`,
+			expected: registryCredentials{
+				Username: "AWS",
+				Password: "password",
+				Expiry:   time.Unix(1600000000, 0),
+			},
+		},
+		{
+			name:        "no authorization data",
+			data:        `{"authorizationData":[]}`,
+			expectedErr: true,
+		},
+		{
+			name:        "malformed token",
+			data:        `{"authorizationData":[{"authorizationToken":"QVdT","expiresAt":1.6E9}]}`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseECRAuthorizationToken([]byte(tc.data))
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartrepo
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// The username used to log in to Google Artifact Registry with an access token.
+	garAccessTokenUsername = "oauth2accesstoken"
+	garScope               = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// garCredentialsIssuer issues the credentials of Google Artifact Registry
+// by using an OAuth2 access token of the service account.
+// https://cloud.google.com/artifact-registry/docs/helm/authentication
+type garCredentialsIssuer struct {
+	credentialsFile string
+}
+
+func newGARCredentialsIssuer(credentialsFile string) *garCredentialsIssuer {
+	return &garCredentialsIssuer{
+		credentialsFile: credentialsFile,
+	}
+}
+
+func (i *garCredentialsIssuer) Issue(ctx context.Context) (registryCredentials, error) {
+	var (
+		creds *google.Credentials
+		err   error
+	)
+	// The default credentials are used when no file was specified.
+	if i.credentialsFile != "" {
+		data, e := ioutil.ReadFile(i.credentialsFile)
+		if e != nil {
+			return registryCredentials{}, fmt.Errorf("failed to read credentials file: %w", e)
+		}
+		creds, err = google.CredentialsFromJSON(ctx, data, garScope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, garScope)
+	}
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to load GCP credentials: %w", err)
+	}
+
+	token, err := creds.TokenSource.Token()
+	if err != nil {
+		return registryCredentials{}, fmt.Errorf("failed to get GCP access token: %w", err)
+	}
+	return registryCredentials{
+		Username: garAccessTokenUsername,
+		Password: token.AccessToken,
+		Expiry:   token.Expiry,
+	}, nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/api/appengine/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	DefaultVersionManifestFilename = "version.yaml"
+)
+
+var (
+	ErrVersionNotFound = errors.New("not found")
+)
+
+type Version appengine.Version
+
+type Client interface {
+	// CreateVersion deploys the given version to the service without routing any traffic to it.
+	CreateVersion(ctx context.Context, service string, version *Version) error
+	GetVersion(ctx context.Context, service, id string) (*Version, error)
+	// GetAllocations returns the fractions of traffic routed to each version of the service.
+	GetAllocations(ctx context.Context, service string) (map[string]float64, error)
+	// MigrateTraffic routes the traffic to the versions by the given fractions.
+	MigrateTraffic(ctx context.Context, service, shardBy string, allocations map[string]float64) error
+}
+
+type Registry interface {
+	Client(ctx context.Context, name string, cfg *config.CloudProviderAppEngineConfig, logger *zap.Logger) (Client, error)
+}
+
+// LoadVersionManifest loads the App Engine Version resource placing in the application directory.
+func LoadVersionManifest(appDir, versionFilename string) (*Version, error) {
+	if versionFilename == "" {
+		versionFilename = DefaultVersionManifestFilename
+	}
+	path := filepath.Join(appDir, versionFilename)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseVersionManifest(data)
+}
+
+func parseVersionManifest(data []byte) (*Version, error) {
+	var v Version
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if v.Runtime == "" {
+		return nil, fmt.Errorf("runtime is required in version manifest")
+	}
+	if v.Deployment == nil {
+		return nil, fmt.Errorf("deployment is required in version manifest")
+	}
+	return &v, nil
+}
+
+// DecideVersionID returns the id of the version deployed from the given commit.
+func DecideVersionID(commit string) (string, error) {
+	if len(commit) < 7 {
+		return "", fmt.Errorf("commit hash %q is too short", commit)
+	}
+	return strings.ToLower(commit[:7]), nil
+}
+
+// FindImageTag returns the tag of the container image deployed by the given version.
+// Only the versions of the flexible environment deployed from a container image have it.
+func FindImageTag(v *Version) (string, error) {
+	if v.Deployment == nil || v.Deployment.Container == nil || v.Deployment.Container.Image == "" {
+		return "", fmt.Errorf("container image was not found")
+	}
+	image := v.Deployment.Container.Image
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:], nil
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[i+1:], nil
+	}
+	return "latest", nil
+}
+
+// MakeAllocations returns the fractions of traffic routing the given percentage to the canary version
+// and the rest to the primary version.
+func MakeAllocations(primary, canary string, canaryPercent int) map[string]float64 {
+	if primary == "" || primary == canary || canaryPercent >= 100 {
+		return map[string]float64{canary: 1}
+	}
+	if canaryPercent <= 0 {
+		return map[string]float64{primary: 1}
+	}
+	return map[string]float64{
+		primary: float64(100-canaryPercent) / 100,
+		canary:  float64(canaryPercent) / 100,
+	}
+}
+
+var defaultRegistry = &registry{
+	clients:  make(map[string]Client),
+	newGroup: &singleflight.Group{},
+}
+
+func DefaultRegistry() Registry {
+	return defaultRegistry
+}
+
+type registry struct {
+	clients  map[string]Client
+	mu       sync.RWMutex
+	newGroup *singleflight.Group
+}
+
+func (r *registry) Client(ctx context.Context, name string, cfg *config.CloudProviderAppEngineConfig, logger *zap.Logger) (Client, error) {
+	r.mu.RLock()
+	client, ok := r.clients[name]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
+		return newClient(ctx, cfg.Project, cfg.CredentialsFile, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client = c.(Client)
+	r.mu.Lock()
+	r.clients[name] = client
+	r.mu.Unlock()
+
+	return client, nil
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/appengine/v1"
+)
+
+func TestParseVersionManifest(t *testing.T) {
+	testcases := []struct {
+		name     string
+		manifest string
+		expected *Version
+		wantErr  bool
+	}{
+		{
+			name: "flexible environment",
+			manifest: `
+runtime: custom
+env: flex
+deployment:
+  container:
+    image: gcr.io/demo-project/demoapp:v1.0.0
+envVariables:
+  FOO: bar
+`,
+			expected: &Version{
+				Runtime: "custom",
+				Env:     "flex",
+				Deployment: &appengine.Deployment{
+					Container: &appengine.ContainerInfo{Image: "gcr.io/demo-project/demoapp:v1.0.0"},
+				},
+				EnvVariables: map[string]string{"FOO": "bar"},
+			},
+		},
+		{
+			name: "standard environment",
+			manifest: `
+runtime: go113
+deployment:
+  zip:
+    sourceUrl: https://storage.googleapis.com/demo-bucket/demoapp.zip
+`,
+			expected: &Version{
+				Runtime: "go113",
+				Deployment: &appengine.Deployment{
+					Zip: &appengine.ZipInfo{SourceUrl: "https://storage.googleapis.com/demo-bucket/demoapp.zip"},
+				},
+			},
+		},
+		{
+			name: "missing runtime",
+			manifest: `
+deployment:
+  container:
+    image: gcr.io/demo-project/demoapp:v1.0.0
+`,
+			wantErr: true,
+		},
+		{
+			name:     "missing deployment",
+			manifest: "runtime: go113",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := parseVersionManifest([]byte(tc.manifest))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestFindImageTag(t *testing.T) {
+	testcases := []struct {
+		name     string
+		image    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "tagged image",
+			image:    "gcr.io/demo-project/demoapp:v1.0.0",
+			expected: "v1.0.0",
+		},
+		{
+			name:     "image with digest",
+			image:    "gcr.io/demo-project/demoapp@sha256:abc",
+			expected: "sha256:abc",
+		},
+		{
+			name:     "registry with port",
+			image:    "localhost:5000/demoapp",
+			expected: "latest",
+		},
+		{
+			name:    "no container",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &Version{Deployment: &appengine.Deployment{}}
+			if tc.image != "" {
+				v.Deployment.Container = &appengine.ContainerInfo{Image: tc.image}
+			}
+			tag, err := FindImageTag(v)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, tag)
+		})
+	}
+}
+
+func TestDecideVersionID(t *testing.T) {
+	id, err := DecideVersionID("A1B2C3D4E5F6")
+	require.NoError(t, err)
+	assert.Equal(t, "a1b2c3d", id)
+
+	_, err = DecideVersionID("a1b2")
+	assert.Error(t, err)
+}
+
+func TestMakeAllocations(t *testing.T) {
+	testcases := []struct {
+		name          string
+		primary       string
+		canary        string
+		canaryPercent int
+		expected      map[string]float64
+	}{
+		{
+			name:          "split",
+			primary:       "v1",
+			canary:        "v2",
+			canaryPercent: 10,
+			expected:      map[string]float64{"v1": 0.9, "v2": 0.1},
+		},
+		{
+			name:          "all to canary",
+			primary:       "v1",
+			canary:        "v2",
+			canaryPercent: 100,
+			expected:      map[string]float64{"v2": 1},
+		},
+		{
+			name:          "all to primary",
+			primary:       "v1",
+			canary:        "v2",
+			canaryPercent: 0,
+			expected:      map[string]float64{"v1": 1},
+		},
+		{
+			name:          "same version",
+			primary:       "v1",
+			canary:        "v1",
+			canaryPercent: 50,
+			expected:      map[string]float64{"v1": 1},
+		},
+		{
+			name:          "no primary",
+			canary:        "v2",
+			canaryPercent: 50,
+			expected:      map[string]float64{"v2": 1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := MakeAllocations(tc.primary, tc.canary, tc.canaryPercent)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	assert.Equal(t, "abc-123", operationID("apps/demo-project/operations/abc-123"))
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/api/appengine/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// How often to check whether the operation has been done.
+const operationPollingInterval = 5 * time.Second
+
+type client struct {
+	projectID string
+	client    *appengine.APIService
+	logger    *zap.Logger
+}
+
+func newClient(ctx context.Context, projectID, credentialsFile string, logger *zap.Logger) (*client, error) {
+	c := &client{
+		projectID: projectID,
+		logger:    logger.Named("appengine"),
+	}
+
+	var options []option.ClientOption
+	if len(credentialsFile) > 0 {
+		data, err := ioutil.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read credentials file (%w)", err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	}
+
+	appengineClient, err := appengine.NewService(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	c.client = appengineClient
+
+	return c, nil
+}
+
+func (c *client) CreateVersion(ctx context.Context, service string, version *Version) error {
+	call := c.client.Apps.Services.Versions.Create(c.projectID, service, (*appengine.Version)(version))
+	call.Context(ctx)
+
+	op, err := call.Do()
+	if err != nil {
+		if e, ok := err.(*googleapi.Error); ok {
+			return fmt.Errorf("failed to create version: code=%d, message=%s, details=%s", e.Code, e.Message, e.Details)
+		}
+		return err
+	}
+	return c.waitOperation(ctx, op)
+}
+
+func (c *client) GetVersion(ctx context.Context, service, id string) (*Version, error) {
+	call := c.client.Apps.Services.Versions.Get(c.projectID, service, id)
+	call.Context(ctx)
+
+	version, err := call.Do()
+	if err != nil {
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil, ErrVersionNotFound
+		}
+		return nil, err
+	}
+	return (*Version)(version), nil
+}
+
+func (c *client) GetAllocations(ctx context.Context, service string) (map[string]float64, error) {
+	call := c.client.Apps.Services.Get(c.projectID, service)
+	call.Context(ctx)
+
+	svc, err := call.Do()
+	if err != nil {
+		return nil, err
+	}
+	if svc.Split == nil {
+		return nil, nil
+	}
+	return svc.Split.Allocations, nil
+}
+
+func (c *client) MigrateTraffic(ctx context.Context, service, shardBy string, allocations map[string]float64) error {
+	svc := &appengine.Service{
+		Split: &appengine.TrafficSplit{
+			ShardBy:     shardBy,
+			Allocations: allocations,
+		},
+	}
+	call := c.client.Apps.Services.Patch(c.projectID, service, svc).UpdateMask("split")
+	call.Context(ctx)
+
+	op, err := call.Do()
+	if err != nil {
+		if e, ok := err.(*googleapi.Error); ok {
+			return fmt.Errorf("failed to migrate traffic: code=%d, message=%s, details=%s", e.Code, e.Message, e.Details)
+		}
+		return err
+	}
+	return c.waitOperation(ctx, op)
+}
+
+// waitOperation blocks until the given long-running operation has been done.
+func (c *client) waitOperation(ctx context.Context, op *appengine.Operation) error {
+	ticker := time.NewTicker(operationPollingInterval)
+	defer ticker.Stop()
+
+	for !op.Done {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		call := c.client.Apps.Operations.Get(c.projectID, operationID(op.Name))
+		call.Context(ctx)
+
+		var err error
+		if op, err = call.Do(); err != nil {
+			return fmt.Errorf("failed to get operation: %w", err)
+		}
+	}
+	if op.Error != nil {
+		return fmt.Errorf("operation %s failed: code=%d, message=%s", op.Name, op.Error.Code, op.Error.Message)
+	}
+	return nil
+}
+
+// operationID returns the id of the operation named as apps/{app}/operations/{id}.
+func operationID(name string) string {
+	return name[strings.LastIndex(name, "/")+1:]
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// RunFromPackageSetting is the app setting specifying the package the app runs from.
+	RunFromPackageSetting = "WEBSITE_RUN_FROM_PACKAGE"
+	// SlotStateRunning is the state of the slot which is serving requests.
+	SlotStateRunning = "Running"
+)
+
+var ErrSlotNotFound = errors.New("slot not found")
+
+// Client is wrapper of Azure Resource Manager API for App Service.
+// Both Function Apps and Web Apps are App Service sites.
+type Client interface {
+	// GetSlot returns the given deployment slot of the site.
+	GetSlot(ctx context.Context, site Site, slot string) (*Slot, error)
+	// GetAppSettings returns all app settings of the given slot.
+	GetAppSettings(ctx context.Context, site Site, slot string) (map[string]string, error)
+	// UpdateAppSettings replaces all app settings of the given slot.
+	// The slot is restarted by App Service to apply them.
+	UpdateAppSettings(ctx context.Context, site Site, slot string, settings map[string]string) error
+	// SwapSlot swaps the given slot with the production slot and waits until it completes.
+	SwapSlot(ctx context.Context, site Site, slot string) error
+}
+
+// Registry holds a pool of Azure clients.
+type Registry interface {
+	Client(name string, cfg *config.CloudProviderAzureConfig, logger *zap.Logger) (Client, error)
+}
+
+// Site identifies an App Service site such as Function App or Web App.
+type Site struct {
+	ResourceGroup string
+	Name          string
+}
+
+// Slot represents a deployment slot of a site.
+type Slot struct {
+	Name            string
+	State           string
+	DefaultHostName string
+}
+
+// MakeAppSettings returns the app settings to run the given package
+// by merging the given settings into the current ones.
+func MakeAppSettings(current, settings map[string]string, packageURL string) map[string]string {
+	out := make(map[string]string, len(current)+len(settings)+1)
+	for k, v := range current {
+		out[k] = v
+	}
+	for k, v := range settings {
+		out[k] = v
+	}
+	out[RunFromPackageSetting] = packageURL
+	return out
+}
+
+// FindVersion returns the name of the package file without its extension as the version.
+// e.g. https://example.blob.core.windows.net/packages/hello-v1.0.0.zip -> hello-v1.0.0
+func FindVersion(packageURL string) (string, error) {
+	u, err := url.Parse(packageURL)
+	if err != nil {
+		return "", err
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		return "", errors.New("package file name was not found")
+	}
+	return strings.TrimSuffix(name, path.Ext(name)), nil
+}
+
+type registry struct {
+	clients  map[string]Client
+	mu       sync.RWMutex
+	newGroup *singleflight.Group
+}
+
+func (r *registry) Client(name string, cfg *config.CloudProviderAzureConfig, logger *zap.Logger) (Client, error) {
+	r.mu.RLock()
+	client, ok := r.clients[name]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
+		return newClient(cfg, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client = c.(Client)
+	r.mu.Lock()
+	r.clients[name] = client
+	r.mu.Unlock()
+
+	return client, nil
+}
+
+var defaultRegistry = &registry{
+	clients:  make(map[string]Client),
+	newGroup: &singleflight.Group{},
+}
+
+// DefaultRegistry returns a pool of Azure clients.
+func DefaultRegistry() Registry {
+	return defaultRegistry
+}
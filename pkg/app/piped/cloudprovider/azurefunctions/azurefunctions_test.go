@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeAppSettings(t *testing.T) {
+	current := map[string]string{
+		"FUNCTIONS_WORKER_RUNTIME": "node",
+		"LOG_LEVEL":                "info",
+		RunFromPackageSetting:      "https://example.com/hello-v1.zip",
+	}
+	settings := map[string]string{
+		"LOG_LEVEL": "debug",
+	}
+	want := map[string]string{
+		"FUNCTIONS_WORKER_RUNTIME": "node",
+		"LOG_LEVEL":                "debug",
+		RunFromPackageSetting:      "https://example.com/hello-v2.zip",
+	}
+	got := MakeAppSettings(current, settings, "https://example.com/hello-v2.zip")
+	assert.Equal(t, want, got)
+	assert.Equal(t, "info", current["LOG_LEVEL"])
+}
+
+func TestFindVersion(t *testing.T) {
+	testcases := []struct {
+		name       string
+		packageURL string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "zip package",
+			packageURL: "https://pipecd.blob.core.windows.net/packages/hello-v1.0.0.zip",
+			want:       "hello-v1.0.0",
+		},
+		{
+			name:       "package with SAS token",
+			packageURL: "https://pipecd.blob.core.windows.net/packages/hello-v1.0.0.zip?sv=2020-08-04&sig=abc",
+			want:       "hello-v1.0.0",
+		},
+		{
+			name:       "no file name",
+			packageURL: "https://pipecd.blob.core.windows.net",
+			wantErr:    true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FindVersion(tc.packageURL)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
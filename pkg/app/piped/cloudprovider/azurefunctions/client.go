@@ -0,0 +1,298 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	managementEndpoint = "https://management.azure.com"
+	managementScope    = "https://management.azure.com/.default"
+	apiVersion         = "2021-02-01"
+
+	// The endpoint of Azure Instance Metadata Service issuing the tokens of managed identity.
+	managedIdentityEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+)
+
+// How often to check the state of a long running operation such as slot swap.
+var operationPollInterval = 5 * time.Second
+
+// client calls Azure Resource Manager API directly
+// since there is no Azure SDK in the dependencies.
+type client struct {
+	endpoint       string
+	subscriptionID string
+	httpClient     *http.Client
+	logger         *zap.Logger
+}
+
+func newClient(cfg *config.CloudProviderAzureConfig, logger *zap.Logger) (Client, error) {
+	if cfg.SubscriptionID == "" {
+		return nil, fmt.Errorf("subscriptionId is required")
+	}
+
+	var ts oauth2.TokenSource
+	if cfg.ClientSecretFile != "" {
+		data, err := ioutil.ReadFile(cfg.ClientSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client secret file (%w)", err)
+		}
+		cc := &clientcredentials.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: strings.TrimSpace(string(data)),
+			TokenURL:     fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", cfg.TenantID),
+			Scopes:       []string{managementScope},
+		}
+		ts = cc.TokenSource(context.Background())
+	} else {
+		ts = oauth2.ReuseTokenSource(nil, &managedIdentityTokenSource{
+			clientID:   cfg.ClientID,
+			httpClient: &http.Client{Timeout: 30 * time.Second},
+		})
+	}
+
+	return &client{
+		endpoint:       managementEndpoint,
+		subscriptionID: cfg.SubscriptionID,
+		httpClient:     oauth2.NewClient(context.Background(), ts),
+		logger:         logger.Named("azure"),
+	}, nil
+}
+
+// managedIdentityTokenSource issues the token of the managed identity
+// assigned to the Azure resource running piped.
+type managedIdentityTokenSource struct {
+	// The client ID of the user-assigned identity.
+	// Empty means the system-assigned one.
+	clientID   string
+	httpClient *http.Client
+}
+
+func (s *managedIdentityTokenSource) Token() (*oauth2.Token, error) {
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	query.Set("resource", managementEndpoint+"/")
+	if s.clientID != "" {
+		query.Set("client_id", s.clientID)
+	}
+	req, err := http.NewRequest(http.MethodGet, managedIdentityEndpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token of managed identity: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get token of managed identity: unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	expiresOn, err := strconv.ParseInt(out.ExpiresOn, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("malformed expiration of token %q", out.ExpiresOn)
+	}
+	return &oauth2.Token{
+		AccessToken: out.AccessToken,
+		TokenType:   out.TokenType,
+		Expiry:      time.Unix(expiresOn, 0),
+	}, nil
+}
+
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *client) GetSlot(ctx context.Context, site Site, slot string) (*Slot, error) {
+	var out struct {
+		Name       string
+		Properties struct {
+			State           string `json:"state"`
+			DefaultHostName string `json:"defaultHostName"`
+		}
+	}
+	_, err := c.call(ctx, http.MethodGet, c.slotURL(site, slot), nil, &out)
+	if e, ok := err.(*apiError); ok && e.StatusCode == http.StatusNotFound {
+		return nil, ErrSlotNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slot %s: %w", slot, err)
+	}
+	return &Slot{
+		Name:            slot,
+		State:           out.Properties.State,
+		DefaultHostName: out.Properties.DefaultHostName,
+	}, nil
+}
+
+func (c *client) GetAppSettings(ctx context.Context, site Site, slot string) (map[string]string, error) {
+	var out struct {
+		Properties map[string]string `json:"properties"`
+	}
+	if _, err := c.call(ctx, http.MethodPost, c.slotURL(site, slot)+"/config/appsettings/list", nil, &out); err != nil {
+		return nil, fmt.Errorf("failed to get app settings of slot %s: %w", slot, err)
+	}
+	if out.Properties == nil {
+		return map[string]string{}, nil
+	}
+	return out.Properties, nil
+}
+
+func (c *client) UpdateAppSettings(ctx context.Context, site Site, slot string, settings map[string]string) error {
+	in := map[string]interface{}{
+		"properties": settings,
+	}
+	if _, err := c.call(ctx, http.MethodPut, c.slotURL(site, slot)+"/config/appsettings", in, nil); err != nil {
+		return fmt.Errorf("failed to update app settings of slot %s: %w", slot, err)
+	}
+	return nil
+}
+
+func (c *client) SwapSlot(ctx context.Context, site Site, slot string) error {
+	in := map[string]interface{}{
+		"targetSlot":   config.AzureFunctionsProductionSlot,
+		"preserveVnet": true,
+	}
+	resp, err := c.call(ctx, http.MethodPost, c.slotURL(site, slot)+"/slotsswap", in, nil)
+	if err != nil {
+		return fmt.Errorf("failed to swap slot %s: %w", slot, err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		return nil
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return fmt.Errorf("missing location of swap operation of slot %s", slot)
+	}
+	if err := c.waitOperation(ctx, location); err != nil {
+		return fmt.Errorf("failed to swap slot %s: %w", slot, err)
+	}
+	return nil
+}
+
+// waitOperation blocks until the long running operation at the given location completes.
+// The operation is still running while its location returns 202 Accepted.
+func (c *client) waitOperation(ctx context.Context, location string) error {
+	ticker := time.NewTicker(operationPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		resp, err := c.call(ctx, http.MethodGet, location, nil, nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusAccepted {
+			return nil
+		}
+	}
+}
+
+func (c *client) slotURL(site Site, slot string) string {
+	u := fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Web/sites/%s",
+		c.endpoint,
+		url.PathEscape(c.subscriptionID),
+		url.PathEscape(site.ResourceGroup),
+		url.PathEscape(site.Name),
+	)
+	if slot != "" && slot != config.AzureFunctionsProductionSlot {
+		u += "/slots/" + url.PathEscape(slot)
+	}
+	return u
+}
+
+// call sends a request to the given URL and decodes its response into the output.
+// The api-version is added to the URL unless it already has one.
+func (c *client) call(ctx context.Context, method, rawURL string, input, output interface{}) (*http.Response, error) {
+	var body bytes.Buffer
+	if input != nil {
+		if err := json.NewEncoder(&body).Encode(input); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	if query.Get("api-version") == "" {
+		query.Set("api-version", apiVersion)
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &apiError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if output != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, output); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const sitePath = "/subscriptions/sub/resourceGroups/pipecd/providers/Microsoft.Web/sites/hello"
+
+func newTestClient(server *httptest.Server) *client {
+	return &client{
+		endpoint:       server.URL,
+		subscriptionID: "sub",
+		httpClient:     http.DefaultClient,
+		logger:         zap.NewNop(),
+	}
+}
+
+func TestClientAppSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, apiVersion, r.URL.Query().Get("api-version"))
+		switch r.URL.Path {
+		case sitePath + "/config/appsettings/list":
+			assert.Equal(t, http.MethodPost, r.Method)
+			w.Write([]byte(`{"properties": {"LOG_LEVEL": "info"}}`))
+		case sitePath + "/slots/staging/config/appsettings":
+			assert.Equal(t, http.MethodPut, r.Method)
+			var in struct {
+				Properties map[string]string `json:"properties"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			assert.Equal(t, map[string]string{"LOG_LEVEL": "debug"}, in.Properties)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	c := newTestClient(server)
+	site := Site{ResourceGroup: "pipecd", Name: "hello"}
+
+	settings, err := c.GetAppSettings(context.Background(), site, "production")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info"}, settings)
+
+	err = c.UpdateAppSettings(context.Background(), site, "staging", map[string]string{"LOG_LEVEL": "debug"})
+	require.NoError(t, err)
+}
+
+func TestClientSwapSlot(t *testing.T) {
+	operationPollInterval = time.Millisecond
+
+	var polls int
+	server := httptest.NewServer(nil)
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case sitePath + "/slots/staging/slotsswap":
+			var in map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+			assert.Equal(t, "production", in["targetSlot"])
+			w.Header().Set("Location", server.URL+"/operations/swap?api-version=2019-08-01")
+			w.WriteHeader(http.StatusAccepted)
+		case "/operations/swap":
+			assert.Equal(t, "2019-08-01", r.URL.Query().Get("api-version"))
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+	defer server.Close()
+
+	c := newTestClient(server)
+	err := c.SwapSlot(context.Background(), Site{ResourceGroup: "pipecd", Name: "hello"}, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, 3, polls)
+}
+
+func TestClientGetSlotNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server)
+	_, err := c.GetSlot(context.Background(), Site{ResourceGroup: "pipecd", Name: "hello"}, "staging")
+	assert.Equal(t, ErrSlotNotFound, err)
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/smithy-go"
+	"go.uber.org/zap"
+)
+
+type client struct {
+	client *cloudformation.Client
+	logger *zap.Logger
+}
+
+func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logger *zap.Logger) (Client, error) {
+	if region == "" {
+		return nil, fmt.Errorf("region is required field")
+	}
+
+	optFns := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if credentialsFile != "" {
+		optFns = append(optFns, config.WithSharedCredentialsFiles([]string{credentialsFile}))
+	}
+	if profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(profile))
+	}
+	if tokenPath != "" && roleARN != "" {
+		optFns = append(optFns, config.WithWebIdentityRoleCredentialOptions(func(v *stscreds.WebIdentityRoleOptions) {
+			v.RoleARN = roleARN
+			v.TokenRetriever = stscreds.IdentityTokenFile(tokenPath)
+		}))
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background(), optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config to create cloudformation client: %w", err)
+	}
+
+	return &client{
+		client: cloudformation.NewFromConfig(cfg),
+		logger: logger.Named("cloudformation"),
+	}, nil
+}
+
+func (c *client) GetStack(ctx context.Context, stackName string) (*Stack, error) {
+	output, err := c.client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		if isStackNotFoundError(err) {
+			return nil, ErrStackNotFound
+		}
+		return nil, fmt.Errorf("failed to describe stack %s: %w", stackName, err)
+	}
+	if len(output.Stacks) == 0 {
+		return nil, ErrStackNotFound
+	}
+	s := output.Stacks[0]
+	return &Stack{
+		Name:         aws.ToString(s.StackName),
+		Status:       string(s.StackStatus),
+		StatusReason: aws.ToString(s.StackStatusReason),
+	}, nil
+}
+
+func (c *client) CreateChangeSet(ctx context.Context, in ChangeSetInput) error {
+	input := &cloudformation.CreateChangeSetInput{
+		StackName:     aws.String(in.StackName),
+		ChangeSetName: aws.String(in.ChangeSetName),
+		ChangeSetType: types.ChangeSetType(in.ChangeSetType),
+		TemplateBody:  aws.String(in.TemplateBody),
+	}
+	if in.Description != "" {
+		input.Description = aws.String(in.Description)
+	}
+	for _, k := range sortedKeys(in.Parameters) {
+		input.Parameters = append(input.Parameters, types.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(in.Parameters[k]),
+		})
+	}
+	for _, c := range in.Capabilities {
+		input.Capabilities = append(input.Capabilities, types.Capability(c))
+	}
+	for _, k := range sortedKeys(in.Tags) {
+		input.Tags = append(input.Tags, types.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(in.Tags[k]),
+		})
+	}
+
+	if _, err := c.client.CreateChangeSet(ctx, input); err != nil {
+		return fmt.Errorf("failed to create change set %s of stack %s: %w", in.ChangeSetName, in.StackName, err)
+	}
+	return nil
+}
+
+func (c *client) GetChangeSet(ctx context.Context, stackName, changeSetName string) (*ChangeSet, error) {
+	var cs *ChangeSet
+	input := &cloudformation.DescribeChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	}
+	for {
+		output, err := c.client.DescribeChangeSet(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe change set %s of stack %s: %w", changeSetName, stackName, err)
+		}
+
+		if cs == nil {
+			cs = &ChangeSet{
+				Name:            aws.ToString(output.ChangeSetName),
+				Status:          string(output.Status),
+				StatusReason:    aws.ToString(output.StatusReason),
+				ExecutionStatus: string(output.ExecutionStatus),
+			}
+		}
+		for _, ch := range output.Changes {
+			rc := ch.ResourceChange
+			if rc == nil {
+				continue
+			}
+			cs.Changes = append(cs.Changes, ResourceChange{
+				Action:            string(rc.Action),
+				LogicalResourceID: aws.ToString(rc.LogicalResourceId),
+				ResourceType:      aws.ToString(rc.ResourceType),
+				Replacement:       string(rc.Replacement),
+			})
+		}
+		if aws.ToString(output.NextToken) == "" {
+			return cs, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+func (c *client) ExecuteChangeSet(ctx context.Context, stackName, changeSetName string) error {
+	_, err := c.client.ExecuteChangeSet(ctx, &cloudformation.ExecuteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute change set %s of stack %s: %w", changeSetName, stackName, err)
+	}
+	return nil
+}
+
+func (c *client) DeleteChangeSet(ctx context.Context, stackName, changeSetName string) error {
+	_, err := c.client.DeleteChangeSet(ctx, &cloudformation.DeleteChangeSetInput{
+		StackName:     aws.String(stackName),
+		ChangeSetName: aws.String(changeSetName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete change set %s of stack %s: %w", changeSetName, stackName, err)
+	}
+	return nil
+}
+
+func (c *client) CancelUpdateStack(ctx context.Context, stackName string) error {
+	_, err := c.client.CancelUpdateStack(ctx, &cloudformation.CancelUpdateStackInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel update of stack %s: %w", stackName, err)
+	}
+	return nil
+}
+
+// isStackNotFoundError returns true when the given error is returned for a missing stack.
+// CloudFormation has no dedicated error type for it.
+func isStackNotFoundError(err error) bool {
+	var aerr smithy.APIError
+	return errors.As(err, &aerr) && aerr.ErrorCode() == "ValidationError" && strings.Contains(aerr.ErrorMessage(), "does not exist")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseForm())
+
+		switch r.PostForm.Get("Action") {
+		case "DescribeStacks":
+			if r.PostForm.Get("StackName") != "app" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`<ErrorResponse><Error><Code>ValidationError</Code><Message>Stack with id missing does not exist</Message></Error></ErrorResponse>`))
+				return
+			}
+			w.Write([]byte(`<DescribeStacksResponse><DescribeStacksResult><Stacks><member><StackName>app</StackName><StackStatus>UPDATE_COMPLETE</StackStatus></member></Stacks></DescribeStacksResult></DescribeStacksResponse>`))
+		case "CreateChangeSet":
+			assert.Equal(t, "CREATE", r.PostForm.Get("ChangeSetType"))
+			assert.Equal(t, "Env", r.PostForm.Get("Parameters.member.1.ParameterKey"))
+			assert.Equal(t, "dev", r.PostForm.Get("Parameters.member.1.ParameterValue"))
+			assert.Equal(t, CapabilityAutoExpand, r.PostForm.Get("Capabilities.member.1"))
+			w.Write([]byte(`<CreateChangeSetResponse><CreateChangeSetResult><Id>id</Id></CreateChangeSetResult></CreateChangeSetResponse>`))
+		case "DescribeChangeSet":
+			if r.PostForm.Get("NextToken") == "" {
+				w.Write([]byte(`<DescribeChangeSetResponse><DescribeChangeSetResult><ChangeSetName>cs</ChangeSetName><Status>CREATE_COMPLETE</Status><ExecutionStatus>AVAILABLE</ExecutionStatus><Changes><member><ResourceChange><Action>Add</Action><LogicalResourceId>Function</LogicalResourceId><ResourceType>AWS::Lambda::Function</ResourceType></ResourceChange></member></Changes><NextToken>next</NextToken></DescribeChangeSetResult></DescribeChangeSetResponse>`))
+				return
+			}
+			w.Write([]byte(`<DescribeChangeSetResponse><DescribeChangeSetResult><ChangeSetName>cs</ChangeSetName><Status>CREATE_COMPLETE</Status><ExecutionStatus>AVAILABLE</ExecutionStatus><Changes><member><ResourceChange><Action>Modify</Action><LogicalResourceId>Role</LogicalResourceId><ResourceType>AWS::IAM::Role</ResourceType><Replacement>True</Replacement></ResourceChange></member></Changes></DescribeChangeSetResult></DescribeChangeSetResponse>`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	c := &client{
+		client: cloudformation.New(cloudformation.Options{
+			Region:      "ap-northeast-1",
+			Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+			EndpointResolver: cloudformation.EndpointResolverFunc(func(_ string, _ cloudformation.EndpointResolverOptions) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: server.URL}, nil
+			}),
+			Retryer:    aws.NopRetryer{},
+			HTTPClient: server.Client(),
+		}),
+	}
+	ctx := context.Background()
+
+	stack, err := c.GetStack(ctx, "app")
+	require.NoError(t, err)
+	assert.Equal(t, &Stack{Name: "app", Status: "UPDATE_COMPLETE"}, stack)
+
+	_, err = c.GetStack(ctx, "missing")
+	assert.Equal(t, ErrStackNotFound, err)
+
+	err = c.CreateChangeSet(ctx, ChangeSetInput{
+		StackName:     "app",
+		ChangeSetName: "cs",
+		ChangeSetType: ChangeSetTypeCreate,
+		TemplateBody:  "Resources: {}",
+		Parameters:    map[string]string{"Env": "dev"},
+		Capabilities:  []string{CapabilityAutoExpand},
+	})
+	require.NoError(t, err)
+
+	cs, err := c.GetChangeSet(ctx, "app", "cs")
+	require.NoError(t, err)
+	assert.True(t, cs.IsExecutable())
+	assert.Equal(t, "1 add, 0 modify, 1 replace, 0 remove", cs.Summary())
+
+	err = c.ExecuteChangeSet(ctx, "app", "cs")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "StatusCode: 500")
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	DefaultTemplateFilename = "template.yaml"
+
+	// The maximum size of the template passed in the request body.
+	// The larger template must be uploaded to S3 before deploying.
+	maxTemplateBodySize = 51200
+
+	// The capability required to deploy the template containing macros such as SAM transform.
+	CapabilityAutoExpand = "CAPABILITY_AUTO_EXPAND"
+
+	samTransform = "AWS::Serverless-2016-10-31"
+)
+
+// The types of change set.
+const (
+	ChangeSetTypeCreate = "CREATE"
+	ChangeSetTypeUpdate = "UPDATE"
+)
+
+// The statuses of stack and change set used by piped.
+const (
+	StackStatusReviewInProgress       = "REVIEW_IN_PROGRESS"
+	StackStatusRollbackComplete       = "ROLLBACK_COMPLETE"
+	StackStatusUpdateInProgress       = "UPDATE_IN_PROGRESS"
+	StackStatusUpdateRollbackComplete = "UPDATE_ROLLBACK_COMPLETE"
+
+	ChangeSetStatusCreateComplete = "CREATE_COMPLETE"
+	ChangeSetStatusFailed         = "FAILED"
+
+	ChangeSetExecutionStatusAvailable = "AVAILABLE"
+)
+
+var ErrStackNotFound = errors.New("stack not found")
+
+// Client is wrapper of CloudFormation client.
+type Client interface {
+	GetStack(ctx context.Context, stackName string) (*Stack, error)
+	CreateChangeSet(ctx context.Context, in ChangeSetInput) error
+	GetChangeSet(ctx context.Context, stackName, changeSetName string) (*ChangeSet, error)
+	ExecuteChangeSet(ctx context.Context, stackName, changeSetName string) error
+	DeleteChangeSet(ctx context.Context, stackName, changeSetName string) error
+	CancelUpdateStack(ctx context.Context, stackName string) error
+}
+
+// Registry holds a pool of aws client wrappers.
+type Registry interface {
+	Client(name string, cfg *config.CloudProviderCloudFormationConfig, logger *zap.Logger) (Client, error)
+}
+
+// Stack represents the current state of a CloudFormation stack.
+type Stack struct {
+	Name         string
+	Status       string
+	StatusReason string
+}
+
+// IsInProgress returns whether an operation is still running on the stack.
+func (s *Stack) IsInProgress() bool {
+	return strings.HasSuffix(s.Status, "_IN_PROGRESS")
+}
+
+// IsRolledBack returns whether the last operation on the stack has been rolled back.
+func (s *Stack) IsRolledBack() bool {
+	return s.Status == StackStatusRollbackComplete || s.Status == StackStatusUpdateRollbackComplete
+}
+
+// IsFailed returns whether the last operation on the stack has failed without being rolled back.
+func (s *Stack) IsFailed() bool {
+	return strings.HasSuffix(s.Status, "_FAILED")
+}
+
+// ChangeSetInput contains the values to create a change set.
+type ChangeSetInput struct {
+	StackName     string
+	ChangeSetName string
+	// One of CREATE or UPDATE.
+	ChangeSetType string
+	TemplateBody  string
+	Parameters    map[string]string
+	Capabilities  []string
+	Tags          map[string]string
+	Description   string
+}
+
+// ChangeSet represents a change set and the resource changes contained in it.
+type ChangeSet struct {
+	Name            string
+	Status          string
+	StatusReason    string
+	ExecutionStatus string
+	Changes         []ResourceChange
+}
+
+// HasNoChanges returns whether the change set failed to be created
+// because the template and the parameters are the same with the current stack.
+func (c *ChangeSet) HasNoChanges() bool {
+	if c.Status != ChangeSetStatusFailed {
+		return false
+	}
+	return strings.Contains(c.StatusReason, "didn't contain changes") || strings.Contains(c.StatusReason, "No updates are to be performed")
+}
+
+// IsExecutable returns whether the change set can be executed.
+func (c *ChangeSet) IsExecutable() bool {
+	return c.Status == ChangeSetStatusCreateComplete && c.ExecutionStatus == ChangeSetExecutionStatusAvailable
+}
+
+// Summary returns the counts of the resource changes grouped by their actions.
+func (c *ChangeSet) Summary() string {
+	var adds, modifies, removes, replaces int
+	for _, rc := range c.Changes {
+		switch rc.Action {
+		case "Add":
+			adds++
+		case "Modify":
+			if rc.Replacement == "True" {
+				replaces++
+			} else {
+				modifies++
+			}
+		case "Remove":
+			removes++
+		}
+	}
+	return fmt.Sprintf("%d add, %d modify, %d replace, %d remove", adds, modifies, replaces, removes)
+}
+
+// ResourceChange represents a change of a resource contained in a change set.
+type ResourceChange struct {
+	// One of Add, Modify, Remove, Import or Dynamic.
+	Action            string
+	LogicalResourceID string
+	ResourceType      string
+	// Whether the resource will be recreated, one of True, False or Conditional.
+	Replacement string
+}
+
+func (r ResourceChange) String() string {
+	if r.Action == "Modify" && r.Replacement != "" && r.Replacement != "False" {
+		return fmt.Sprintf("%s %s (%s) with replacement %s", r.Action, r.LogicalResourceID, r.ResourceType, r.Replacement)
+	}
+	return fmt.Sprintf("%s %s (%s)", r.Action, r.LogicalResourceID, r.ResourceType)
+}
+
+// LoadTemplate returns the content of the template file placing in the application directory.
+func LoadTemplate(appDir, templateFilename string) (string, error) {
+	if templateFilename == "" {
+		templateFilename = DefaultTemplateFilename
+	}
+	path := filepath.Join(appDir, templateFilename)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxTemplateBodySize {
+		return "", fmt.Errorf("template %s is %d bytes which exceeds the maximum size %d bytes", templateFilename, len(data), maxTemplateBodySize)
+	}
+	return string(data), nil
+}
+
+// IsSAMTemplate returns whether the given template uses the SAM transform.
+func IsSAMTemplate(template string) bool {
+	return strings.Contains(template, samTransform)
+}
+
+// DecideCapabilities returns the capabilities to acknowledge for deploying the given template.
+func DecideCapabilities(template string, capabilities []string) []string {
+	if !IsSAMTemplate(template) {
+		return capabilities
+	}
+	for _, c := range capabilities {
+		if c == CapabilityAutoExpand {
+			return capabilities
+		}
+	}
+	return append(append([]string(nil), capabilities...), CapabilityAutoExpand)
+}
+
+// MakeChangeSetName returns the name of the change set created by the given deployment.
+// The name must begin with a letter and contain only alphanumeric characters and hyphens.
+func MakeChangeSetName(prefix, deploymentID string) string {
+	return fmt.Sprintf("pipecd-%s-%s", prefix, deploymentID)
+}
+
+type registry struct {
+	clients  map[string]Client
+	mu       sync.RWMutex
+	newGroup *singleflight.Group
+}
+
+func (r *registry) Client(name string, cfg *config.CloudProviderCloudFormationConfig, logger *zap.Logger) (Client, error) {
+	r.mu.RLock()
+	client, ok := r.clients[name]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
+		return newClient(cfg.Region, cfg.Profile, cfg.CredentialsFile, cfg.RoleARN, cfg.TokenFile, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client = c.(Client)
+	r.mu.Lock()
+	r.clients[name] = client
+	r.mu.Unlock()
+
+	return client, nil
+}
+
+var defaultRegistry = &registry{
+	clients:  make(map[string]Client),
+	newGroup: &singleflight.Group{},
+}
+
+// DefaultRegistry returns a pool of aws clients and a mutex associated with it.
+func DefaultRegistry() Registry {
+	return defaultRegistry
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeSet(t *testing.T) {
+	testcases := []struct {
+		name             string
+		changeSet        ChangeSet
+		expectedNoChange bool
+		expectedSummary  string
+	}{
+		{
+			name: "has changes",
+			changeSet: ChangeSet{
+				Status: ChangeSetStatusCreateComplete,
+				Changes: []ResourceChange{
+					{Action: "Add"},
+					{Action: "Modify", Replacement: "False"},
+					{Action: "Modify", Replacement: "True"},
+					{Action: "Modify", Replacement: "Conditional"},
+					{Action: "Remove"},
+				},
+			},
+			expectedSummary: "1 add, 2 modify, 1 replace, 1 remove",
+		},
+		{
+			name: "no changes",
+			changeSet: ChangeSet{
+				Status:       ChangeSetStatusFailed,
+				StatusReason: "The submitted information didn't contain changes. Submit different information to create a change set.",
+			},
+			expectedNoChange: true,
+			expectedSummary:  "0 add, 0 modify, 0 replace, 0 remove",
+		},
+		{
+			name: "failed by invalid template",
+			changeSet: ChangeSet{
+				Status:       ChangeSetStatusFailed,
+				StatusReason: "Template format error: Unresolved resource dependencies",
+			},
+			expectedSummary: "0 add, 0 modify, 0 replace, 0 remove",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedNoChange, tc.changeSet.HasNoChanges())
+			assert.Equal(t, tc.expectedSummary, tc.changeSet.Summary())
+		})
+	}
+}
+
+func TestDecideCapabilities(t *testing.T) {
+	samTemplate := "Transform: AWS::Serverless-2016-10-31\nResources: {}\n"
+	testcases := []struct {
+		name         string
+		template     string
+		capabilities []string
+		expected     []string
+	}{
+		{
+			name:         "plain template",
+			template:     "Resources: {}\n",
+			capabilities: []string{"CAPABILITY_IAM"},
+			expected:     []string{"CAPABILITY_IAM"},
+		},
+		{
+			name:         "sam template",
+			template:     samTemplate,
+			capabilities: []string{"CAPABILITY_IAM"},
+			expected:     []string{"CAPABILITY_IAM", CapabilityAutoExpand},
+		},
+		{
+			name:         "sam template with auto expand",
+			template:     samTemplate,
+			capabilities: []string{CapabilityAutoExpand},
+			expected:     []string{CapabilityAutoExpand},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := DecideCapabilities(tc.template, tc.capabilities)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
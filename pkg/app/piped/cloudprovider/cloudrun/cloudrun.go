@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"go.uber.org/zap"
@@ -44,6 +45,7 @@ type (
 type Client interface {
 	Create(ctx context.Context, sm ServiceManifest) (*Service, error)
 	Update(ctx context.Context, sm ServiceManifest) (*Service, error)
+	GetService(ctx context.Context, serviceName string) (*Service, error)
 	GetRevision(ctx context.Context, name string) (*Revision, error)
 }
 
@@ -51,6 +53,24 @@ type Registry interface {
 	Client(ctx context.Context, name string, cfg *config.CloudProviderCloudRunConfig, logger *zap.Logger) (Client, error)
 }
 
+// PreviewURL returns the URL to access the revision with the given tag of the service.
+// The URL is built from the service URL when it has not been reported in the traffic status yet.
+func PreviewURL(svc *Service, tag string) (string, bool) {
+	if svc == nil || svc.Status == nil || tag == "" {
+		return "", false
+	}
+	for _, t := range svc.Status.Traffic {
+		if t != nil && t.Tag == tag && t.Url != "" {
+			return t.Url, true
+		}
+	}
+	const scheme = "https://"
+	if !strings.HasPrefix(svc.Status.Url, scheme) {
+		return "", false
+	}
+	return scheme + tag + "---" + strings.TrimPrefix(svc.Status.Url, scheme), true
+}
+
 func LoadServiceManifest(appDir, serviceFilename string) (ServiceManifest, error) {
 	if serviceFilename == "" {
 		serviceFilename = DefaultServiceManifestFilename
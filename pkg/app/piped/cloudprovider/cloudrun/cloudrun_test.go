@@ -0,0 +1,79 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/run/v1"
+)
+
+func TestPreviewURL(t *testing.T) {
+	testcases := []struct {
+		name     string
+		svc      *Service
+		tag      string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "reported in traffic status",
+			tag:      "canary",
+			expected: "https://canary---helloworld-abcdefg-an.a.run.app",
+			ok:       true,
+			svc: &Service{
+				Status: &run.ServiceStatus{
+					Url: "https://helloworld-abcdefg-an.a.run.app",
+					Traffic: []*run.TrafficTarget{
+						{RevisionName: "helloworld-v010-1234567", Percent: 20, Tag: "canary", Url: "https://canary---helloworld-abcdefg-an.a.run.app"},
+						{RevisionName: "helloworld-v009-abcdefg", Percent: 80},
+					},
+				},
+			},
+		},
+		{
+			name:     "built from service url",
+			tag:      "canary",
+			expected: "https://canary---helloworld-abcdefg-an.a.run.app",
+			ok:       true,
+			svc: &Service{
+				Status: &run.ServiceStatus{
+					Url: "https://helloworld-abcdefg-an.a.run.app",
+				},
+			},
+		},
+		{
+			name: "no status",
+			tag:  "canary",
+			svc:  &Service{},
+		},
+		{
+			name: "no tag",
+			svc: &Service{
+				Status: &run.ServiceStatus{
+					Url: "https://helloworld-abcdefg-an.a.run.app",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := PreviewURL(tc.svc, tc.tag)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
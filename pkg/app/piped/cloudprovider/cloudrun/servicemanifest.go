@@ -15,6 +15,7 @@
 package cloudrun
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"strings"
@@ -36,6 +37,9 @@ func (m ServiceManifest) SetRevision(name string) error {
 type RevisionTraffic struct {
 	RevisionName string `json:"revisionName"`
 	Percent      int    `json:"percent"`
+	// The revision can be accessed at its own URL with this tag
+	// regardless of the traffic percentage.
+	Tag string `json:"tag,omitempty"`
 }
 
 func (m ServiceManifest) UpdateTraffic(revisions []RevisionTraffic) error {
@@ -51,6 +55,28 @@ func (m ServiceManifest) UpdateTraffic(revisions []RevisionTraffic) error {
 	return unstructured.SetNestedSlice(m.u.Object, items, "spec", "traffic")
 }
 
+// Traffic returns the traffic percentages of revisions configured in the manifest.
+func (m ServiceManifest) Traffic() ([]RevisionTraffic, error) {
+	items, _, err := unstructured.NestedSlice(m.u.Object, "spec", "traffic")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get traffic from object: %w", err)
+	}
+
+	revisions := make([]RevisionTraffic, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected type of traffic item: %T", item)
+		}
+		var r RevisionTraffic
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &r); err != nil {
+			return nil, fmt.Errorf("unable to convert traffic item: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
 func (m ServiceManifest) UpdateAllTraffic(revision string) error {
 	return m.UpdateTraffic([]RevisionTraffic{
 		{
@@ -60,6 +86,18 @@ func (m ServiceManifest) UpdateAllTraffic(revision string) error {
 	})
 }
 
+// RevisionSpec returns the spec of the revision template of the service.
+func (m ServiceManifest) RevisionSpec() (map[string]interface{}, error) {
+	spec, ok, err := unstructured.NestedMap(m.u.Object, "spec", "template", "spec")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get spec.template.spec from object: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("spec.template.spec was missing")
+	}
+	return spec, nil
+}
+
 func (m ServiceManifest) YamlBytes() ([]byte, error) {
 	return yaml.Marshal(m.u)
 }
@@ -84,6 +122,15 @@ func ParseServiceManifest(data []byte) (ServiceManifest, error) {
 	}, nil
 }
 
+// ParseService converts the given live service into the manifest.
+func ParseService(svc *Service) (ServiceManifest, error) {
+	data, err := json.Marshal(svc)
+	if err != nil {
+		return ServiceManifest{}, err
+	}
+	return ParseServiceManifest(data)
+}
+
 func DecideRevisionName(sm ServiceManifest, commit string) (string, error) {
 	tag, err := FindImageTag(sm)
 	if err != nil {
@@ -13,3 +13,71 @@
 // limitations under the License.
 
 package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/run/v1"
+)
+
+func TestServiceManifestTraffic(t *testing.T) {
+	sm, err := ParseServiceManifest([]byte(`
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.1.0
+`))
+	require.NoError(t, err)
+
+	traffic, err := sm.Traffic()
+	require.NoError(t, err)
+	assert.Empty(t, traffic)
+
+	expected := []RevisionTraffic{
+		{RevisionName: "helloworld-v010-1234567", Percent: 80},
+		{RevisionName: "helloworld-v009-abcdefg", Percent: 20, Tag: "previous"},
+	}
+	require.NoError(t, sm.UpdateTraffic(expected))
+
+	traffic, err = sm.Traffic()
+	require.NoError(t, err)
+	assert.Equal(t, expected, traffic)
+}
+
+func TestParseServiceRevisionSpec(t *testing.T) {
+	svc := &Service{
+		ApiVersion: "serving.knative.dev/v1",
+		Kind:       "Service",
+		Metadata:   &run.ObjectMeta{Name: "helloworld"},
+		Spec: &run.ServiceSpec{
+			Template: &run.RevisionTemplate{
+				Spec: &run.RevisionSpec{
+					ContainerConcurrency: 80,
+					Containers: []*run.Container{
+						{Image: "gcr.io/pipecd/helloworld:v0.1.0"},
+					},
+				},
+			},
+		},
+	}
+	sm, err := ParseService(svc)
+	require.NoError(t, err)
+	assert.Equal(t, "helloworld", sm.Name)
+
+	spec, err := sm.RevisionSpec()
+	require.NoError(t, err)
+	expected := map[string]interface{}{
+		"containerConcurrency": int64(80),
+		"containers": []interface{}{
+			map[string]interface{}{"image": "gcr.io/pipecd/helloworld:v0.1.0"},
+		},
+	}
+	assert.Equal(t, expected, spec)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
@@ -32,9 +33,10 @@ import (
 )
 
 type client struct {
-	ecsClient *ecs.Client
-	elbClient *elasticloadbalancingv2.Client
-	logger    *zap.Logger
+	ecsClient        *ecs.Client
+	elbClient        *elasticloadbalancingv2.Client
+	codeDeployClient *codedeploy.Client
+	logger           *zap.Logger
 }
 
 func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logger *zap.Logger) (Client, error) {
@@ -66,6 +68,7 @@ func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logg
 	}
 	c.ecsClient = ecs.NewFromConfig(cfg)
 	c.elbClient = elasticloadbalancingv2.NewFromConfig(cfg)
+	c.codeDeployClient = codedeploy.NewFromConfig(cfg)
 
 	return c, nil
 }
@@ -219,6 +222,22 @@ func (c *client) UpdateServicePrimaryTaskSet(ctx context.Context, service types.
 	return output.TaskSet, nil
 }
 
+func (c *client) GetTaskSet(ctx context.Context, service types.Service, taskSetArn string) (*types.TaskSet, error) {
+	input := &ecs.DescribeTaskSetsInput{
+		Cluster:  service.ClusterArn,
+		Service:  service.ServiceArn,
+		TaskSets: []string{taskSetArn},
+	}
+	output, err := c.ecsClient.DescribeTaskSets(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ECS task set %s: %w", taskSetArn, err)
+	}
+	if len(output.TaskSets) == 0 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return &output.TaskSets[0], nil
+}
+
 func (c *client) ServiceExists(ctx context.Context, clusterName string, serviceName string) (bool, error) {
 	input := &ecs.DescribeServicesInput{
 		Cluster:  aws.String(clusterName),
@@ -309,3 +328,17 @@ func (c *client) ModifyListener(ctx context.Context, listenerArn string, routing
 	_, err := c.elbClient.ModifyListener(ctx, input)
 	return err
 }
+
+func (c *client) GetListenerWeights(ctx context.Context, listenerArn string) (RoutingTrafficConfig, error) {
+	input := &elasticloadbalancingv2.DescribeListenersInput{
+		ListenerArns: []string{listenerArn},
+	}
+	output, err := c.elbClient.DescribeListeners(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Listeners) == 0 {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return makeRoutingTrafficConfig(output.Listeners[0].DefaultActions), nil
+}
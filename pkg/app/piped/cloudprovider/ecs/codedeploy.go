@@ -0,0 +1,159 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
+	cdtypes "github.com/aws/aws-sdk-go-v2/service/codedeploy/types"
+)
+
+// The statuses of CodeDeploy deployment.
+const (
+	CodeDeployStatusCreated    = string(cdtypes.DeploymentStatusCreated)
+	CodeDeployStatusQueued     = string(cdtypes.DeploymentStatusQueued)
+	CodeDeployStatusInProgress = string(cdtypes.DeploymentStatusInProgress)
+	CodeDeployStatusBaking     = string(cdtypes.DeploymentStatusBaking)
+	CodeDeployStatusReady      = string(cdtypes.DeploymentStatusReady)
+	CodeDeployStatusSucceeded  = string(cdtypes.DeploymentStatusSucceeded)
+	CodeDeployStatusFailed     = string(cdtypes.DeploymentStatusFailed)
+	CodeDeployStatusStopped    = string(cdtypes.DeploymentStatusStopped)
+)
+
+// IsCodeDeployDeploymentCompleted returns whether the deployment with the given status has been completed.
+func IsCodeDeployDeploymentCompleted(status string) bool {
+	switch status {
+	case CodeDeployStatusSucceeded, CodeDeployStatusFailed, CodeDeployStatusStopped:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) CreateDeployment(ctx context.Context, applicationName, deploymentGroupName, deploymentConfigName, appSpec string) (string, error) {
+	input := &codedeploy.CreateDeploymentInput{
+		ApplicationName:     aws.String(applicationName),
+		DeploymentGroupName: aws.String(deploymentGroupName),
+		Revision: &cdtypes.RevisionLocation{
+			RevisionType: cdtypes.RevisionLocationTypeAppSpecContent,
+			AppSpecContent: &cdtypes.AppSpecContent{
+				Content: aws.String(appSpec),
+			},
+		},
+	}
+	if deploymentConfigName != "" {
+		input.DeploymentConfigName = aws.String(deploymentConfigName)
+	}
+	output, err := c.codeDeployClient.CreateDeployment(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CodeDeploy deployment of %s/%s: %w", applicationName, deploymentGroupName, err)
+	}
+	return aws.ToString(output.DeploymentId), nil
+}
+
+func (c *client) GetDeployment(ctx context.Context, deploymentID string) (*CodeDeployDeployment, error) {
+	output, err := c.codeDeployClient.GetDeployment(ctx, &codedeploy.GetDeploymentInput{
+		DeploymentId: aws.String(deploymentID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CodeDeploy deployment %s: %w", deploymentID, err)
+	}
+	d := &CodeDeployDeployment{
+		ID: deploymentID,
+	}
+	if info := output.DeploymentInfo; info != nil {
+		d.Status = string(info.Status)
+		if e := info.ErrorInformation; e != nil {
+			d.ErrorMessage = fmt.Sprintf("%s: %s", e.Code, aws.ToString(e.Message))
+		}
+	}
+	return d, nil
+}
+
+func (c *client) StopDeployment(ctx context.Context, deploymentID string, autoRollback bool) error {
+	_, err := c.codeDeployClient.StopDeployment(ctx, &codedeploy.StopDeploymentInput{
+		DeploymentId:        aws.String(deploymentID),
+		AutoRollbackEnabled: aws.Bool(autoRollback),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stop CodeDeploy deployment %s: %w", deploymentID, err)
+	}
+	return nil
+}
+
+// CodeDeployDeployment represents the state of a CodeDeploy deployment.
+type CodeDeployDeployment struct {
+	ID     string
+	Status string
+	// The reason why the deployment failed.
+	ErrorMessage string
+}
+
+type appSpec struct {
+	Version   json.Number                  `json:"version"`
+	Resources []map[string]appSpecResource `json:"Resources"`
+}
+
+type appSpecResource struct {
+	Type       string                    `json:"Type"`
+	Properties appSpecResourceProperties `json:"Properties"`
+}
+
+type appSpecResourceProperties struct {
+	TaskDefinition   string                  `json:"TaskDefinition"`
+	LoadBalancerInfo appSpecLoadBalancerInfo `json:"LoadBalancerInfo"`
+}
+
+type appSpecLoadBalancerInfo struct {
+	ContainerName string `json:"ContainerName"`
+	ContainerPort int32  `json:"ContainerPort"`
+}
+
+// MakeCodeDeployAppSpec returns the AppSpec content of CodeDeploy deployment
+// replacing the task definition of the ECS service with the given one.
+func MakeCodeDeployAppSpec(taskDefinitionArn, containerName string, containerPort int32) (string, error) {
+	if taskDefinitionArn == "" {
+		return "", fmt.Errorf("task definition arn is required")
+	}
+	if containerName == "" || containerPort == 0 {
+		return "", fmt.Errorf("container name and port of the target group are required")
+	}
+	spec := appSpec{
+		Version: json.Number("0.0"),
+		Resources: []map[string]appSpecResource{
+			{
+				"TargetService": {
+					Type: "AWS::ECS::Service",
+					Properties: appSpecResourceProperties{
+						TaskDefinition: taskDefinitionArn,
+						LoadBalancerInfo: appSpecLoadBalancerInfo{
+							ContainerName: containerName,
+							ContainerPort: containerPort,
+						},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
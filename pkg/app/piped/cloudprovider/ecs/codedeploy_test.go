@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/codedeploy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeCodeDeployAppSpec(t *testing.T) {
+	testcases := []struct {
+		name          string
+		taskDef       string
+		containerName string
+		containerPort int32
+		expected      string
+		expectedErr   bool
+	}{
+		{
+			name:          "valid",
+			taskDef:       "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/simple:2",
+			containerName: "web",
+			containerPort: 80,
+			expected:      `{"version":0.0,"Resources":[{"TargetService":{"Type":"AWS::ECS::Service","Properties":{"TaskDefinition":"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/simple:2","LoadBalancerInfo":{"ContainerName":"web","ContainerPort":80}}}}]}`,
+		},
+		{
+			name:          "missing task definition",
+			containerName: "web",
+			containerPort: 80,
+			expectedErr:   true,
+		},
+		{
+			name:        "missing container",
+			taskDef:     "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/simple:2",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := MakeCodeDeployAppSpec(tc.taskDef, tc.containerName, tc.containerPort)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestCodeDeployDeployment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var input struct {
+			DeploymentID string `json:"deploymentId"`
+		}
+		require.NoError(t, json.Unmarshal(body, &input))
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "CodeDeploy_20141006.GetDeployment":
+			if input.DeploymentID != "d-123" {
+				w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"DeploymentDoesNotExistException","message":"not found"}`))
+				return
+			}
+			w.Write([]byte(`{"deploymentInfo":{"status":"Failed","errorInformation":{"code":"HEALTH_CONSTRAINTS","message":"unhealthy"}}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	c := &client{
+		codeDeployClient: codedeploy.New(codedeploy.Options{
+			Region:      "ap-northeast-1",
+			Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+			EndpointResolver: codedeploy.EndpointResolverFunc(func(_ string, _ codedeploy.EndpointResolverOptions) (aws.Endpoint, error) {
+				return aws.Endpoint{URL: server.URL}, nil
+			}),
+			Retryer:    aws.NopRetryer{},
+			HTTPClient: server.Client(),
+		}),
+	}
+	ctx := context.Background()
+
+	d, err := c.GetDeployment(ctx, "d-123")
+	require.NoError(t, err)
+	assert.Equal(t, &CodeDeployDeployment{
+		ID:           "d-123",
+		Status:       CodeDeployStatusFailed,
+		ErrorMessage: "HEALTH_CONSTRAINTS: unhealthy",
+	}, d)
+
+	_, err = c.GetDeployment(ctx, "d-456")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DeploymentDoesNotExistException")
+
+	err = c.StopDeployment(ctx, "d-123", true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "StatusCode: 500")
+}
@@ -30,6 +30,7 @@ import (
 type Client interface {
 	ECS
 	ELB
+	CodeDeploy
 }
 
 type ECS interface {
@@ -41,11 +42,19 @@ type ECS interface {
 	CreateTaskSet(ctx context.Context, service types.Service, taskDefinition types.TaskDefinition, targetGroup types.LoadBalancer, scale int) (*types.TaskSet, error)
 	DeleteTaskSet(ctx context.Context, service types.Service, taskSetArn string) error
 	UpdateServicePrimaryTaskSet(ctx context.Context, service types.Service, taskSet types.TaskSet) (*types.TaskSet, error)
+	GetTaskSet(ctx context.Context, service types.Service, taskSetArn string) (*types.TaskSet, error)
 }
 
 type ELB interface {
 	GetListener(ctx context.Context, targetGroup types.LoadBalancer) (string, error)
 	ModifyListener(ctx context.Context, listenerArn string, routingTrafficCfg RoutingTrafficConfig) error
+	GetListenerWeights(ctx context.Context, listenerArn string) (RoutingTrafficConfig, error)
+}
+
+type CodeDeploy interface {
+	CreateDeployment(ctx context.Context, applicationName, deploymentGroupName, deploymentConfigName, appSpec string) (string, error)
+	GetDeployment(ctx context.Context, deploymentID string) (*CodeDeployDeployment, error)
+	StopDeployment(ctx context.Context, deploymentID string, autoRollback bool) error
 }
 
 // Registry holds a pool of aws client wrappers.
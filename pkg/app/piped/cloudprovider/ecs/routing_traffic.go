@@ -14,9 +14,56 @@
 
 package ecs
 
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
 type RoutingTrafficConfig []targetGroupWeight
 
 type targetGroupWeight struct {
 	TargetGroupArn string
 	Weight         int
 }
+
+// Weight returns the weight of the given target group.
+// Zero is returned when the target group is not contained.
+func (c RoutingTrafficConfig) Weight(targetGroupArn string) int {
+	for _, w := range c {
+		if w.TargetGroupArn == targetGroupArn {
+			return w.Weight
+		}
+	}
+	return 0
+}
+
+// makeRoutingTrafficConfig returns the weights of the target groups forwarded by the given listener actions.
+func makeRoutingTrafficConfig(actions []elbtypes.Action) RoutingTrafficConfig {
+	var cfg RoutingTrafficConfig
+	for _, action := range actions {
+		if action.Type != elbtypes.ActionTypeEnumForward {
+			continue
+		}
+		if action.ForwardConfig == nil || len(action.ForwardConfig.TargetGroups) == 0 {
+			if action.TargetGroupArn != nil {
+				cfg = append(cfg, targetGroupWeight{
+					TargetGroupArn: *action.TargetGroupArn,
+					Weight:         100,
+				})
+			}
+			continue
+		}
+		for _, tg := range action.ForwardConfig.TargetGroups {
+			// The weight is 1 when it is not specified.
+			weight := 1
+			if tg.Weight != nil {
+				weight = int(*tg.Weight)
+			}
+			cfg = append(cfg, targetGroupWeight{
+				TargetGroupArn: aws.ToString(tg.TargetGroupArn),
+				Weight:         weight,
+			})
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeRoutingTrafficConfig(t *testing.T) {
+	testcases := []struct {
+		name     string
+		actions  []elbtypes.Action
+		expected RoutingTrafficConfig
+	}{
+		{
+			name: "forward to single target group",
+			actions: []elbtypes.Action{
+				{
+					Type:           elbtypes.ActionTypeEnumForward,
+					TargetGroupArn: aws.String("blue"),
+				},
+			},
+			expected: RoutingTrafficConfig{
+				{TargetGroupArn: "blue", Weight: 100},
+			},
+		},
+		{
+			name: "forward to weighted target groups",
+			actions: []elbtypes.Action{
+				{
+					Type: elbtypes.ActionTypeEnumForward,
+					ForwardConfig: &elbtypes.ForwardActionConfig{
+						TargetGroups: []elbtypes.TargetGroupTuple{
+							{TargetGroupArn: aws.String("blue"), Weight: aws.Int32(0)},
+							{TargetGroupArn: aws.String("green"), Weight: aws.Int32(100)},
+						},
+					},
+				},
+			},
+			expected: RoutingTrafficConfig{
+				{TargetGroupArn: "blue", Weight: 0},
+				{TargetGroupArn: "green", Weight: 100},
+			},
+		},
+		{
+			name: "weight is not specified",
+			actions: []elbtypes.Action{
+				{
+					Type: elbtypes.ActionTypeEnumForward,
+					ForwardConfig: &elbtypes.ForwardActionConfig{
+						TargetGroups: []elbtypes.TargetGroupTuple{
+							{TargetGroupArn: aws.String("blue")},
+						},
+					},
+				},
+			},
+			expected: RoutingTrafficConfig{
+				{TargetGroupArn: "blue", Weight: 1},
+			},
+		},
+		{
+			name: "not forward action",
+			actions: []elbtypes.Action{
+				{
+					Type: elbtypes.ActionTypeEnumFixedResponse,
+				},
+			},
+			expected: nil,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeRoutingTrafficConfig(tc.actions)
+			assert.Equal(t, tc.expected, got)
+			for _, w := range tc.expected {
+				assert.Equal(t, w.Weight, got.Weight(w.TargetGroupArn))
+			}
+			assert.Equal(t, 0, got.Weight("unknown"))
+		})
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// countPodsByArchitecture returns the number of the given running pods
+// grouped by the CPU architecture of the nodes they are scheduled on.
+// The pods not scheduled yet or scheduled on unknown nodes are ignored.
+func countPodsByArchitecture(pods, nodes []Manifest) (map[string]int, error) {
+	archs := make(map[string]string, len(nodes))
+	for _, m := range nodes {
+		node := &corev1.Node{}
+		if err := m.ConvertToStructuredObject(node); err != nil {
+			return nil, err
+		}
+		arch := node.Labels[corev1.LabelArchStable]
+		if arch == "" {
+			arch = node.Status.NodeInfo.Architecture
+		}
+		if arch != "" {
+			archs[node.Name] = arch
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, m := range pods {
+		pod := &corev1.Pod{}
+		if err := m.ConvertToStructuredObject(pod); err != nil {
+			return nil, err
+		}
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if arch, ok := archs[pod.Spec.NodeName]; ok {
+			counts[arch]++
+		}
+	}
+	return counts, nil
+}
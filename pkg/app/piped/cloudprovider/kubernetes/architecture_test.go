@@ -0,0 +1,101 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountPodsByArchitecture(t *testing.T) {
+	nodes, err := ParseManifests(`
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-amd64
+  labels:
+    kubernetes.io/arch: amd64
+---
+apiVersion: v1
+kind: Node
+metadata:
+  name: node-arm64
+status:
+  nodeInfo:
+    architecture: arm64
+`)
+	require.NoError(t, err)
+
+	pods, err := ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-1
+spec:
+  nodeName: node-amd64
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-2
+spec:
+  nodeName: node-arm64
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pod-3
+spec:
+  nodeName: node-arm64
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: pending
+status:
+  phase: Pending
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: terminated
+spec:
+  nodeName: node-amd64
+status:
+  phase: Succeeded
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: unknown-node
+spec:
+  nodeName: removed
+status:
+  phase: Running
+`)
+	require.NoError(t, err)
+
+	got, err := countPodsByArchitecture(pods, nodes)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"amd64": 1, "arm64": 2}, got)
+}
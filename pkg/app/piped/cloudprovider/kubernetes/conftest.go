@@ -0,0 +1,141 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Conftest runs the OPA/Rego policies against the manifests by using conftest.
+type Conftest struct {
+	version  string
+	execPath string
+}
+
+func NewConftest(version, path string) *Conftest {
+	return &Conftest{
+		version:  version,
+		execPath: path,
+	}
+}
+
+// PolicyViolation represents a message reported by a policy for a manifest.
+type PolicyViolation struct {
+	// The resource that violated the policy.
+	Key ResourceKey
+	// The namespace of the policy reporting this violation.
+	Namespace string
+	Message   string
+	// Whether this was reported as a warning instead of a failure.
+	Warning bool
+}
+
+type conftestResult struct {
+	Filename  string `json:"filename"`
+	Namespace string `json:"namespace"`
+	Warnings  []struct {
+		Msg string `json:"msg"`
+	} `json:"warnings"`
+	Failures []struct {
+		Msg string `json:"msg"`
+	} `json:"failures"`
+}
+
+// Test checks the given manifests against the policies placed in the given directories
+// and returns all reported violations.
+// An empty namespaces list means using the default namespace of conftest.
+func (c *Conftest) Test(ctx context.Context, policies, namespaces []string, manifests []Manifest) ([]PolicyViolation, error) {
+	if len(manifests) == 0 {
+		return nil, nil
+	}
+
+	dir, err := ioutil.TempDir("", "conftest")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// Each manifest is written into its own file
+	// to be able to know which resource violated the policies.
+	files := make([]string, 0, len(manifests))
+	keys := make(map[string]ResourceKey, len(manifests))
+	for i, m := range manifests {
+		data, err := m.YamlBytes()
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("%d.yaml", i))
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return nil, err
+		}
+		files = append(files, path)
+		keys[path] = m.Key
+	}
+
+	cmd := exec.CommandContext(ctx, c.execPath, conftestArgs(policies, namespaces, files)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// conftest exits with a non-zero code when there are some failures
+	// so the output is parsed before checking the error.
+	runErr := cmd.Run()
+	var results []conftestResult
+	if err := json.Unmarshal(stdout.Bytes(), &results); err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("failed to run conftest: %s (%v)", stderr.String(), runErr)
+		}
+		return nil, fmt.Errorf("failed to parse conftest output: %w", err)
+	}
+
+	var violations []PolicyViolation
+	for _, r := range results {
+		for _, w := range r.Warnings {
+			violations = append(violations, PolicyViolation{
+				Key:       keys[r.Filename],
+				Namespace: r.Namespace,
+				Message:   w.Msg,
+				Warning:   true,
+			})
+		}
+		for _, f := range r.Failures {
+			violations = append(violations, PolicyViolation{
+				Key:       keys[r.Filename],
+				Namespace: r.Namespace,
+				Message:   f.Msg,
+			})
+		}
+	}
+	return violations, nil
+}
+
+func conftestArgs(policies, namespaces, files []string) []string {
+	args := make([]string, 0, 4+2*len(policies)+2*len(namespaces)+len(files))
+	args = append(args, "test", "--no-color", "--output=json")
+	for _, p := range policies {
+		args = append(args, "--policy", p)
+	}
+	for _, ns := range namespaces {
+		args = append(args, "--namespace", ns)
+	}
+	return append(args, files...)
+}
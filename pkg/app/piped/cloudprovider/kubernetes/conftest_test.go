@@ -0,0 +1,57 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConftestArgs(t *testing.T) {
+	testcases := []struct {
+		name       string
+		policies   []string
+		namespaces []string
+		files      []string
+		expected   []string
+	}{
+		{
+			name:     "default namespace",
+			policies: []string{"policy"},
+			files:    []string{"0.yaml", "1.yaml"},
+			expected: []string{"test", "--no-color", "--output=json", "--policy", "policy", "0.yaml", "1.yaml"},
+		},
+		{
+			name:       "multiple policies and namespaces",
+			policies:   []string{"policy", "/shared/policy"},
+			namespaces: []string{"main", "security"},
+			files:      []string{"0.yaml"},
+			expected: []string{
+				"test", "--no-color", "--output=json",
+				"--policy", "policy", "--policy", "/shared/policy",
+				"--namespace", "main", "--namespace", "security",
+				"0.yaml",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := conftestArgs(tc.policies, tc.namespaces, tc.files)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Cosign verifies the signatures and attestations of the container images by using cosign.
+type Cosign struct {
+	version  string
+	execPath string
+}
+
+func NewCosign(version, path string) *Cosign {
+	return &Cosign{
+		version:  version,
+		execPath: path,
+	}
+}
+
+// CosignSigner represents who must have signed the image.
+// Either the public key or the keyless identity is specified.
+type CosignSigner struct {
+	// The path to the public key file or the KMS URI.
+	Key string
+	// The OIDC issuer and the subject of the keyless identity.
+	Issuer  string
+	Subject string
+}
+
+func (s CosignSigner) String() string {
+	if s.Key != "" {
+		return fmt.Sprintf("key %s", s.Key)
+	}
+	return fmt.Sprintf("identity %s (issued by %s)", s.Subject, s.Issuer)
+}
+
+// Verify checks whether the given image was signed by the given signer.
+func (c *Cosign) Verify(ctx context.Context, image string, signer CosignSigner) error {
+	return c.run(ctx, signer, cosignArgs("verify", image, signer, "")...)
+}
+
+// VerifyAttestation checks whether the given image has an attestation
+// of the given predicate type signed by the given signer.
+func (c *Cosign) VerifyAttestation(ctx context.Context, image string, signer CosignSigner, predicateType string) error {
+	return c.run(ctx, signer, cosignArgs("verify-attestation", image, signer, predicateType)...)
+}
+
+func (c *Cosign) run(ctx context.Context, signer CosignSigner, args ...string) error {
+	cmd := exec.CommandContext(ctx, c.execPath, args...)
+	if signer.Key == "" {
+		// The keyless verification is still experimental in cosign v1.
+		cmd.Env = append(os.Environ(), "COSIGN_EXPERIMENTAL=1")
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s (%w)", msg, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func cosignArgs(command, image string, signer CosignSigner, predicateType string) []string {
+	args := []string{command}
+	if signer.Key != "" {
+		args = append(args, "--key", signer.Key)
+	} else {
+		args = append(args, "--certificate-identity", signer.Subject, "--certificate-oidc-issuer", signer.Issuer)
+	}
+	if predicateType != "" {
+		args = append(args, "--type", predicateType)
+	}
+	return append(args, image)
+}
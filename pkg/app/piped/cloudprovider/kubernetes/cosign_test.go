@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCosignArgs(t *testing.T) {
+	testcases := []struct {
+		name          string
+		command       string
+		signer        CosignSigner
+		predicateType string
+		expected      []string
+	}{
+		{
+			name:     "verify with key",
+			command:  "verify",
+			signer:   CosignSigner{Key: "cosign.pub"},
+			expected: []string{"verify", "--key", "cosign.pub", "gcr.io/pipecd/helloworld:v0.1.0"},
+		},
+		{
+			name:    "verify with keyless identity",
+			command: "verify",
+			signer: CosignSigner{
+				Issuer:  "https://accounts.google.com",
+				Subject: "release@pipecd.dev",
+			},
+			expected: []string{
+				"verify",
+				"--certificate-identity", "release@pipecd.dev",
+				"--certificate-oidc-issuer", "https://accounts.google.com",
+				"gcr.io/pipecd/helloworld:v0.1.0",
+			},
+		},
+		{
+			name:          "verify attestation",
+			command:       "verify-attestation",
+			signer:        CosignSigner{Key: "gcpkms://projects/pipecd/keys/cosign"},
+			predicateType: "spdx",
+			expected: []string{
+				"verify-attestation",
+				"--key", "gcpkms://projects/pipecd/keys/cosign",
+				"--type", "spdx",
+				"gcr.io/pipecd/helloworld:v0.1.0",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := cosignArgs(tc.command, "gcr.io/pipecd/helloworld:v0.1.0", tc.signer, tc.predicateType)
+			assert.Equal(t, tc.expected, args)
+		})
+	}
+}
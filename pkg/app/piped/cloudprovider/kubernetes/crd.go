@@ -0,0 +1,70 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	crdStatusPollInterval = 2 * time.Second
+)
+
+// waitForEstablished polls the CustomResourceDefinition returned by the given get function
+// until it has been established or the context is done.
+func waitForEstablished(ctx context.Context, get func() (Manifest, error), interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m, err := get()
+		if err != nil {
+			return err
+		}
+		established, err := isCRDEstablished(m)
+		if established || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for custom resource definition to be established: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// isCRDEstablished returns whether the given CustomResourceDefinition
+// has the Established condition so that its custom resources can be served.
+func isCRDEstablished(m Manifest) (bool, error) {
+	conditions, _, err := unstructured.NestedSlice(m.u.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if cond["type"] == "Established" && cond["status"] == "True" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
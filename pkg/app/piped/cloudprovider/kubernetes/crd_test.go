@@ -0,0 +1,111 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeCRDManifest(t *testing.T, status string) Manifest {
+	t.Helper()
+	data := `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+`
+	if status != "" {
+		data += "status:\n" + status
+	}
+	manifests, err := ParseManifests(data)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	return manifests[0]
+}
+
+func TestIsCRDEstablished(t *testing.T) {
+	testcases := []struct {
+		name     string
+		status   string
+		expected bool
+	}{
+		{
+			name:     "no status",
+			expected: false,
+		},
+		{
+			name: "names accepted only",
+			status: `  conditions:
+  - type: NamesAccepted
+    status: "True"
+  - type: Established
+    status: "False"
+`,
+			expected: false,
+		},
+		{
+			name: "established",
+			status: `  conditions:
+  - type: NamesAccepted
+    status: "True"
+  - type: Established
+    status: "True"
+`,
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			established, err := isCRDEstablished(makeCRDManifest(t, tc.status))
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, established)
+		})
+	}
+}
+
+func TestWaitForEstablished(t *testing.T) {
+	pending := makeCRDManifest(t, "")
+	established := makeCRDManifest(t, "  conditions:\n  - type: Established\n    status: \"True\"\n")
+
+	t.Run("established after polling", func(t *testing.T) {
+		var calls int
+		get := func() (Manifest, error) {
+			calls++
+			if calls < 3 {
+				return pending, nil
+			}
+			return established, nil
+		}
+		err := waitForEstablished(context.Background(), get, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		get := func() (Manifest, error) {
+			return pending, nil
+		}
+		err := waitForEstablished(ctx, get, time.Hour)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
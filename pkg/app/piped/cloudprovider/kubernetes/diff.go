@@ -16,12 +16,14 @@ package kubernetes
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"sort"
 	"strings"
 
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/diff"
 )
 
@@ -82,10 +84,31 @@ type DiffRenderOptions struct {
 	// Zero means rendering all.
 	MaxChangedManifests int
 	// If true, use "diff" command to render.
+	// This is used by the unified format only.
 	UseDiffCommand bool
+	// The format of the rendered diff.
+	// Empty means the unified format.
+	Format config.K8sDiffFormat
 }
 
+type diffRenderFunc func(r *DiffListResult, opt DiffRenderOptions) string
+
+var diffRenderers = map[config.K8sDiffFormat]diffRenderFunc{
+	config.K8sDiffFormatUnified:   renderUnified,
+	config.K8sDiffFormatJSONPatch: renderJSONPatch,
+	config.K8sDiffFormatMarkdown:  renderMarkdown,
+}
+
+// Render renders the diff list result in the format specified by the given options.
 func (r *DiffListResult) Render(opt DiffRenderOptions) string {
+	render, ok := diffRenderers[opt.Format]
+	if !ok {
+		render = renderUnified
+	}
+	return render(r, opt)
+}
+
+func renderUnified(r *DiffListResult, opt DiffRenderOptions) string {
 	var b strings.Builder
 	index := 0
 	for _, delete := range r.Deletes {
@@ -97,27 +120,12 @@ func (r *DiffListResult) Render(opt DiffRenderOptions) string {
 		b.WriteString(fmt.Sprintf("+ %d. %s\n\n", index, add.Key.ReadableString()))
 	}
 
-	maxPrintDiffs := len(r.Changes)
-	if opt.MaxChangedManifests != 0 && opt.MaxChangedManifests < maxPrintDiffs {
-		maxPrintDiffs = opt.MaxChangedManifests
-	}
+	maxPrintDiffs := opt.maxPrintDiffs(len(r.Changes))
 
 	var prints = 0
 	for _, change := range r.Changes {
 		key := change.Old.Key
-		opts := []diff.RenderOption{
-			diff.WithLeftPadding(1),
-		}
-
-		needMaskValue := false
-		if opt.MaskSecret && key.IsSecret() {
-			opts = append(opts, diff.WithMaskPath("data"))
-			needMaskValue = true
-		} else if opt.MaskConfigMap && key.IsConfigMap() {
-			opts = append(opts, diff.WithMaskPath("data"))
-			needMaskValue = true
-		}
-		renderer := diff.NewRenderer(opts...)
+		renderer, needMaskValue := opt.newRenderer(key, diff.WithLeftPadding(1))
 
 		index++
 		b.WriteString(fmt.Sprintf("# %d. %s\n\n", index, key.ReadableString()))
@@ -151,6 +159,178 @@ func (r *DiffListResult) Render(opt DiffRenderOptions) string {
 	return b.String()
 }
 
+type jsonPatchManifest struct {
+	Action   string                `json:"action"`
+	Resource string                `json:"resource,omitempty"`
+	Patches  []diff.PatchOperation `json:"patches,omitempty"`
+	// The number of the changed manifests omitted due to MaxChangedManifests.
+	// This is set by the trailing entry whose action is "omit" only.
+	Omitted int `json:"omitted,omitempty"`
+}
+
+func renderJSONPatch(r *DiffListResult, opt DiffRenderOptions) string {
+	manifests := make([]jsonPatchManifest, 0, len(r.Deletes)+len(r.Adds)+len(r.Changes))
+	for _, delete := range r.Deletes {
+		manifests = append(manifests, jsonPatchManifest{
+			Action:   "delete",
+			Resource: delete.Key.ReadableString(),
+		})
+	}
+	for _, add := range r.Adds {
+		manifests = append(manifests, jsonPatchManifest{
+			Action:   "add",
+			Resource: add.Key.ReadableString(),
+		})
+	}
+
+	maxPrintDiffs := opt.maxPrintDiffs(len(r.Changes))
+	for i, change := range r.Changes {
+		if i >= maxPrintDiffs {
+			break
+		}
+		key := change.Old.Key
+		renderer, _ := opt.newRenderer(key)
+		manifests = append(manifests, jsonPatchManifest{
+			Action:   "change",
+			Resource: key.ReadableString(),
+			Patches:  renderer.RenderPatchOperations(change.Diff.Nodes()),
+		})
+	}
+	if maxPrintDiffs < len(r.Changes) {
+		manifests = append(manifests, jsonPatchManifest{
+			Action:  "omit",
+			Omitted: len(r.Changes) - maxPrintDiffs,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifests, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("An error occurred while rendering diff (%v)", err)
+	}
+	return string(data)
+}
+
+func renderMarkdown(r *DiffListResult, opt DiffRenderOptions) string {
+	var b strings.Builder
+	if len(r.Deletes)+len(r.Adds) > 0 {
+		b.WriteString("| Action | Resource |\n")
+		b.WriteString("| --- | --- |\n")
+		for _, delete := range r.Deletes {
+			b.WriteString(fmt.Sprintf("| delete | `%s` |\n", delete.Key.ReadableString()))
+		}
+		for _, add := range r.Adds {
+			b.WriteString(fmt.Sprintf("| add | `%s` |\n", add.Key.ReadableString()))
+		}
+		b.WriteString("\n")
+	}
+
+	maxPrintDiffs := opt.maxPrintDiffs(len(r.Changes))
+
+	var prints = 0
+	for _, change := range r.Changes {
+		key := change.Old.Key
+		renderer, _ := opt.newRenderer(key)
+
+		b.WriteString(fmt.Sprintf("#### %s\n\n", key.ReadableString()))
+		b.WriteString(renderer.RenderMarkdownTable(change.Diff.Nodes()))
+		b.WriteString("\n")
+
+		prints++
+		if prints >= maxPrintDiffs {
+			break
+		}
+	}
+
+	if prints < len(r.Changes) {
+		b.WriteString(fmt.Sprintf("_... (omitted %d other changed manifests)_\n", len(r.Changes)-prints))
+	}
+
+	return b.String()
+}
+
+func (opt DiffRenderOptions) maxPrintDiffs(numChanges int) int {
+	if opt.MaxChangedManifests != 0 && opt.MaxChangedManifests < numChanges {
+		return opt.MaxChangedManifests
+	}
+	return numChanges
+}
+
+// newRenderer returns a renderer for the given resource key
+// and a boolean indicating whether its values must be masked.
+func (opt DiffRenderOptions) newRenderer(key ResourceKey, opts ...diff.RenderOption) (*diff.Renderer, bool) {
+	needMaskValue := false
+	if opt.MaskSecret && key.IsSecret() {
+		opts = append(opts, diff.WithMaskPath("data"))
+		needMaskValue = true
+	} else if opt.MaskConfigMap && key.IsConfigMap() {
+		opts = append(opts, diff.WithMaskPath("data"))
+		needMaskValue = true
+	}
+	return diff.NewRenderer(opts...), needMaskValue
+}
+
+// DiffSummary is a concise and serializable summary of a DiffListResult.
+type DiffSummary struct {
+	Adds    []string            `json:"adds,omitempty"`
+	Deletes []string            `json:"deletes,omitempty"`
+	Changes []DiffSummaryChange `json:"changes,omitempty"`
+}
+
+type DiffSummaryChange struct {
+	Resource string `json:"resource"`
+	// Number of the changed fields.
+	NumFields int `json:"numFields"`
+}
+
+// Summary returns the summary of the diff list result.
+func (r *DiffListResult) Summary() DiffSummary {
+	s := DiffSummary{}
+	for _, add := range r.Adds {
+		s.Adds = append(s.Adds, add.Key.ReadableString())
+	}
+	for _, delete := range r.Deletes {
+		s.Deletes = append(s.Deletes, delete.Key.ReadableString())
+	}
+	for _, change := range r.Changes {
+		s.Changes = append(s.Changes, DiffSummaryChange{
+			Resource:  change.Old.Key.ReadableString(),
+			NumFields: change.Diff.NumNodes(),
+		})
+	}
+	return s
+}
+
+func (s DiffSummary) String() string {
+	return fmt.Sprintf("%d added manifests, %d changed manifests, %d deleted manifests", len(s.Adds), len(s.Changes), len(s.Deletes))
+}
+
+// Render returns a concise text of the summary that lists the changed resources
+// under its counts. Zero maxResources means listing all of them.
+func (s DiffSummary) Render(maxResources int) string {
+	lines := make([]string, 0, len(s.Adds)+len(s.Changes)+len(s.Deletes))
+	for _, add := range s.Adds {
+		lines = append(lines, fmt.Sprintf("+ %s", add))
+	}
+	for _, change := range s.Changes {
+		lines = append(lines, fmt.Sprintf("~ %s (%d fields)", change.Resource, change.NumFields))
+	}
+	for _, delete := range s.Deletes {
+		lines = append(lines, fmt.Sprintf("- %s", delete))
+	}
+
+	var b strings.Builder
+	b.WriteString(s.String())
+	for i, line := range lines {
+		if maxResources > 0 && i == maxResources {
+			b.WriteString(fmt.Sprintf("\n... (%d more)", len(lines)-maxResources))
+			break
+		}
+		b.WriteString("\n")
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
 func diffByCommand(command string, old, new Manifest) ([]byte, error) {
 	oldBytes, err := old.YamlBytes()
 	if err != nil {
@@ -15,10 +15,13 @@
 package kubernetes
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 func TestGroupManifests(t *testing.T) {
@@ -184,3 +187,119 @@ func TestDiffByCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestDiffListRender(t *testing.T) {
+	manifests, err := LoadManifestsFromYAMLFile("testdata/diff_by_command.yaml")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(manifests))
+
+	added := Manifest{Key: ResourceKey{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "simple"}}
+	result, err := DiffList(manifests[:1], []Manifest{manifests[1], added})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(result.Changes))
+
+	testcases := []struct {
+		name     string
+		opt      DiffRenderOptions
+		expected string
+	}{
+		{
+			name: "json patch",
+			opt:  DiffRenderOptions{Format: config.K8sDiffFormatJSONPatch},
+			expected: `[
+  {
+    "action": "add",
+    "resource": "name=\"simple\", kind=\"Service\", namespace=\"default\", apiVersion=\"v1\""
+  },
+  {
+    "action": "change",
+    "resource": "name=\"simple\", kind=\"Deployment\", namespace=\"default\", apiVersion=\"apps/v1\"",
+    "patches": [
+      {
+        "op": "replace",
+        "path": "/spec/replicas",
+        "value": 3
+      },
+      {
+        "op": "replace",
+        "path": "/spec/template/spec/containers/0/args/1",
+        "value": "d"
+      },
+      {
+        "op": "replace",
+        "path": "/spec/template/spec/containers/0/args/2",
+        "value": "b"
+      },
+      {
+        "op": "add",
+        "path": "/spec/template/spec/containers/0/args/3",
+        "value": "c"
+      },
+      {
+        "op": "replace",
+        "path": "/spec/template/spec/containers/1/args/1",
+        "value": "zz"
+      },
+      {
+        "op": "remove",
+        "path": "/spec/template/spec/containers/1/args/2"
+      }
+    ]
+  }
+]`,
+		},
+		{
+			name: "markdown",
+			opt:  DiffRenderOptions{Format: config.K8sDiffFormatMarkdown},
+			expected: "| Action | Resource |\n" +
+				"| --- | --- |\n" +
+				"| add | `name=\"simple\", kind=\"Service\", namespace=\"default\", apiVersion=\"v1\"` |\n" +
+				"\n" +
+				"#### name=\"simple\", kind=\"Deployment\", namespace=\"default\", apiVersion=\"apps/v1\"\n" +
+				"\n" +
+				"| Path | Old | New |\n" +
+				"| --- | --- | --- |\n" +
+				"| `spec.replicas` | 2 | 3 |\n" +
+				"| `spec.template.spec.containers.0.args.1` | b | d |\n" +
+				"| `spec.template.spec.containers.0.args.2` | c | b |\n" +
+				"| `spec.template.spec.containers.0.args.3` |  | c |\n" +
+				"| `spec.template.spec.containers.1.args.1` | yy | zz |\n" +
+				"| `spec.template.spec.containers.1.args.2` | zz |  |\n" +
+				"\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := result.Render(tc.opt)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+
+	omitted := &DiffListResult{
+		Changes: []DiffListChange{result.Changes[0], result.Changes[0], result.Changes[0]},
+	}
+	got := omitted.Render(DiffRenderOptions{Format: config.K8sDiffFormatJSONPatch, MaxChangedManifests: 1})
+	var manifests []jsonPatchManifest
+	require.NoError(t, json.Unmarshal([]byte(got), &manifests))
+	require.Equal(t, 2, len(manifests))
+	assert.Equal(t, "change", manifests[0].Action)
+	assert.Equal(t, jsonPatchManifest{Action: "omit", Omitted: 2}, manifests[1])
+
+	summary := result.Summary()
+	assert.Equal(t, "1 added manifests, 1 changed manifests, 0 deleted manifests", summary.String())
+	assert.Equal(t, []DiffSummaryChange{
+		{
+			Resource:  `name="simple", kind="Deployment", namespace="default", apiVersion="apps/v1"`,
+			NumFields: 6,
+		},
+	}, summary.Changes)
+	assert.Equal(t, "1 added manifests, 1 changed manifests, 0 deleted manifests\n"+
+		"+ name=\"simple\", kind=\"Service\", namespace=\"default\", apiVersion=\"v1\"\n"+
+		"~ name=\"simple\", kind=\"Deployment\", namespace=\"default\", apiVersion=\"apps/v1\" (6 fields)",
+		summary.Render(0))
+	assert.Equal(t, "1 added manifests, 1 changed manifests, 0 deleted manifests\n"+
+		"+ name=\"simple\", kind=\"Service\", namespace=\"default\", apiVersion=\"v1\"\n"+
+		"... (1 more)",
+		summary.Render(1))
+}
@@ -0,0 +1,111 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/jsonpath"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// HealthChecker determines the health status of the resources
+// by evaluating the health checks configured for their kinds.
+// This enables to show the health status of the custom resources
+// managed by operators such as Argo Rollouts or cert-manager.
+type HealthChecker struct {
+	checks map[APIVersionKind][]healthCondition
+}
+
+type healthCondition struct {
+	cfg      config.KubernetesResourceHealthCondition
+	jsonPath *jsonpath.JSONPath
+}
+
+// NewHealthChecker parses the JSONPath templates of the given health checks.
+func NewHealthChecker(checks []config.KubernetesResourceHealthCheck) (*HealthChecker, error) {
+	c := &HealthChecker{
+		checks: make(map[APIVersionKind][]healthCondition, len(checks)),
+	}
+	for _, check := range checks {
+		k := APIVersionKind{
+			APIVersion: check.APIVersion,
+			Kind:       check.Kind,
+		}
+		for _, cond := range check.Conditions {
+			j := jsonpath.New(check.Kind).AllowMissingKeys(true)
+			if err := j.Parse(cond.JSONPath); err != nil {
+				return nil, fmt.Errorf("invalid jsonPath %q of health check for %s/%s: %w", cond.JSONPath, check.APIVersion, check.Kind, err)
+			}
+			c.checks[k] = append(c.checks[k], healthCondition{
+				cfg:      cond,
+				jsonPath: j,
+			})
+		}
+	}
+	return c, nil
+}
+
+// Check returns the health status of the given resource.
+// The returned boolean is false when no health check was configured for its kind.
+func (c *HealthChecker) Check(key ResourceKey, obj *unstructured.Unstructured) (model.KubernetesResourceState_HealthStatus, string, bool) {
+	if c == nil {
+		return model.KubernetesResourceState_UNKNOWN, "", false
+	}
+	conds, ok := c.checks[APIVersionKind{APIVersion: key.APIVersion, Kind: key.Kind}]
+	if !ok {
+		return model.KubernetesResourceState_UNKNOWN, "", false
+	}
+
+	for _, cond := range conds {
+		var b bytes.Buffer
+		if err := cond.jsonPath.Execute(&b, obj.Object); err != nil {
+			return model.KubernetesResourceState_UNKNOWN, fmt.Sprintf("Unable to evaluate jsonPath %s: %v", cond.cfg.JSONPath, err), true
+		}
+		value := strings.TrimSpace(b.String())
+		if !cond.matches(value) {
+			continue
+		}
+
+		status := model.KubernetesResourceState_OTHER
+		if cond.cfg.Healthy {
+			status = model.KubernetesResourceState_HEALTHY
+		}
+		desc := cond.cfg.Description
+		if desc == "" {
+			desc = fmt.Sprintf("%s is %q", cond.cfg.JSONPath, value)
+		}
+		return status, desc, true
+	}
+
+	return model.KubernetesResourceState_OTHER, "None of the configured health conditions matched", true
+}
+
+func (c healthCondition) matches(value string) bool {
+	if len(c.cfg.Values) == 0 {
+		return value != ""
+	}
+	for _, v := range c.cfg.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
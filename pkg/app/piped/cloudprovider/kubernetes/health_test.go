@@ -0,0 +1,162 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestHealthChecker(t *testing.T) {
+	checker, err := NewHealthChecker([]config.KubernetesResourceHealthCheck{
+		{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       "Rollout",
+			Conditions: []config.KubernetesResourceHealthCondition{
+				{
+					JSONPath: "{.status.phase}",
+					Values:   []string{"Healthy"},
+					Healthy:  true,
+				},
+				{
+					JSONPath: "{.status.message}",
+				},
+			},
+		},
+		{
+			APIVersion: "cert-manager.io/v1",
+			Kind:       "Certificate",
+			Conditions: []config.KubernetesResourceHealthCondition{
+				{
+					JSONPath:    `{.status.conditions[?(@.type=="Ready")].status}`,
+					Values:      []string{"True"},
+					Healthy:     true,
+					Description: "Certificate is ready",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name           string
+		manifest       string
+		expectedStatus model.KubernetesResourceState_HealthStatus
+		expectedDesc   string
+		expectedOK     bool
+	}{
+		{
+			name: "healthy rollout",
+			manifest: `
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: simple
+status:
+  phase: Healthy
+`,
+			expectedStatus: model.KubernetesResourceState_HEALTHY,
+			expectedDesc:   `{.status.phase} is "Healthy"`,
+			expectedOK:     true,
+		},
+		{
+			name: "paused rollout",
+			manifest: `
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: simple
+status:
+  phase: Paused
+  message: CanaryPauseStep
+`,
+			expectedStatus: model.KubernetesResourceState_OTHER,
+			expectedDesc:   `{.status.message} is "CanaryPauseStep"`,
+			expectedOK:     true,
+		},
+		{
+			name: "ready certificate",
+			manifest: `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: simple
+status:
+  conditions:
+  - type: Issuing
+    status: "False"
+  - type: Ready
+    status: "True"
+`,
+			expectedStatus: model.KubernetesResourceState_HEALTHY,
+			expectedDesc:   "Certificate is ready",
+			expectedOK:     true,
+		},
+		{
+			name: "certificate without status",
+			manifest: `
+apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: simple
+`,
+			expectedStatus: model.KubernetesResourceState_OTHER,
+			expectedDesc:   "None of the configured health conditions matched",
+			expectedOK:     true,
+		},
+		{
+			name: "no health check for the kind",
+			manifest: `
+apiVersion: kafka.strimzi.io/v1beta2
+kind: Kafka
+metadata:
+  name: simple
+`,
+			expectedStatus: model.KubernetesResourceState_UNKNOWN,
+			expectedOK:     false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var obj unstructured.Unstructured
+			require.NoError(t, yaml.Unmarshal([]byte(tc.manifest), &obj))
+
+			status, desc, ok := checker.Check(MakeResourceKey(&obj), &obj)
+			assert.Equal(t, tc.expectedOK, ok)
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedDesc, desc)
+		})
+	}
+}
+
+func TestNewHealthCheckerInvalidJSONPath(t *testing.T) {
+	_, err := NewHealthChecker([]config.KubernetesResourceHealthCheck{
+		{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       "Rollout",
+			Conditions: []config.KubernetesResourceHealthCondition{
+				{JSONPath: "{.status.phase"},
+			},
+		},
+	})
+	assert.Error(t, err)
+}
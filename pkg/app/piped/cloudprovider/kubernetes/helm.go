@@ -22,6 +22,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"go.uber.org/zap"
@@ -62,14 +63,7 @@ func (c *Helm) TemplateLocalChart(ctx context.Context, appName, appDir, namespac
 		args = append(args, fmt.Sprintf("--namespace=%s", namespace))
 	}
 
-	if opts != nil {
-		for _, v := range opts.ValueFiles {
-			args = append(args, "-f", v)
-		}
-		for k, v := range opts.SetFiles {
-			args = append(args, "--set-file", fmt.Sprintf("%s=%s", k, v))
-		}
-	}
+	args = append(args, helmValueArgs(opts)...)
 
 	var stdout, stderr bytes.Buffer
 	cmd := exec.CommandContext(ctx, c.execPath, args...)
@@ -122,6 +116,8 @@ type helmRemoteChart struct {
 	Name       string
 	Version    string
 	Insecure   bool
+	// The address of OCI registry when the repository is an OCI one.
+	OCIRegistry string
 }
 
 func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespace string, chart helmRemoteChart, opts *config.InputHelmOptions) (string, error) {
@@ -130,11 +126,16 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 		releaseName = opts.ReleaseName
 	}
 
+	chartRef := fmt.Sprintf("%s/%s", chart.Repository, chart.Name)
+	if chart.OCIRegistry != "" {
+		chartRef = chartrepo.OCIChartReference(chart.OCIRegistry, chart.Name)
+	}
+
 	args := []string{
 		"template",
 		"--no-hooks",
 		releaseName,
-		fmt.Sprintf("%s/%s", chart.Repository, chart.Name),
+		chartRef,
 		fmt.Sprintf("--version=%s", chart.Version),
 	}
 
@@ -146,14 +147,7 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 		args = append(args, fmt.Sprintf("--namespace=%s", namespace))
 	}
 
-	if opts != nil {
-		for _, v := range opts.ValueFiles {
-			args = append(args, "-f", v)
-		}
-		for k, v := range opts.SetFiles {
-			args = append(args, "--set-file", fmt.Sprintf("%s=%s", k, v))
-		}
-	}
+	args = append(args, helmValueArgs(opts)...)
 
 	c.logger.Info(fmt.Sprintf("start templating a chart from Helm repository for application %s", appName),
 		zap.Any("args", args),
@@ -165,6 +159,9 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 		cmd.Dir = appDir
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
+		if chart.OCIRegistry != "" {
+			cmd.Env = append(os.Environ(), chartrepo.OCIEnv)
+		}
 
 		if err := cmd.Run(); err != nil {
 			return stdout.String(), fmt.Errorf("%w: %s", err, stderr.String())
@@ -177,7 +174,9 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 		return out, nil
 	}
 
-	if !strings.Contains(err.Error(), "helm repo update") {
+	// Updating is not needed for OCI registries
+	// because the chart is always pulled from the registry.
+	if chart.OCIRegistry != "" || !strings.Contains(err.Error(), "helm repo update") {
 		return "", err
 	}
 
@@ -188,3 +187,29 @@ func (c *Helm) TemplateRemoteChart(ctx context.Context, appName, appDir, namespa
 	}
 	return executor()
 }
+
+// helmValueArgs returns the arguments for specifying values to helm command.
+func helmValueArgs(opts *config.InputHelmOptions) []string {
+	if opts == nil {
+		return nil
+	}
+
+	var args []string
+	for _, v := range opts.ValueFiles {
+		args = append(args, "-f", v)
+	}
+	for k, v := range opts.SetFiles {
+		args = append(args, "--set-file", fmt.Sprintf("%s=%s", k, v))
+	}
+
+	// Sort the keys to make the arguments deterministic.
+	keys := make([]string, 0, len(opts.SetValues))
+	for k := range opts.SetValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--set", fmt.Sprintf("%s=%s", k, opts.SetValues[k]))
+	}
+	return args
+}
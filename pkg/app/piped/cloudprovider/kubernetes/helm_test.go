@@ -24,6 +24,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 func TestTemplateLocalChart(t *testing.T) {
@@ -73,3 +74,42 @@ func TestTemplateLocalChart_WithNamespace(t *testing.T) {
 		require.Equal(t, namespace, metadata["namespace"])
 	}
 }
+
+func TestHelmValueArgs(t *testing.T) {
+	testcases := []struct {
+		name     string
+		opts     *config.InputHelmOptions
+		expected []string
+	}{
+		{
+			name: "nil options",
+		},
+		{
+			name: "value files and set values",
+			opts: &config.InputHelmOptions{
+				ValueFiles: []string{"values.yaml", "values-canary.yaml"},
+				SetFiles: map[string]string{
+					"config": "config.yaml",
+				},
+				SetValues: map[string]string{
+					"replicaCount": "1",
+					"image.tag":    "v1.0.0",
+				},
+			},
+			expected: []string{
+				"-f", "values.yaml",
+				"-f", "values-canary.yaml",
+				"--set-file", "config=config.yaml",
+				"--set", "image.tag=v1.0.0",
+				"--set", "replicaCount=1",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := helmValueArgs(tc.opts)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
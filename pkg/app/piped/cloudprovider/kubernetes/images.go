@@ -0,0 +1,71 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// podSpecFields returns the fields to the pod spec of the given kind of workload.
+// Nil is returned for the kinds not running any container.
+func podSpecFields(kind string) []string {
+	switch kind {
+	case KindPod:
+		return []string{"spec"}
+	case KindDeployment, KindStatefulSet, KindDaemonSet, KindReplicaSet, KindJob:
+		return []string{"spec", "template", "spec"}
+	case KindCronJob:
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return nil
+	}
+}
+
+// FindContainerImages returns all unique images referenced by the containers,
+// init containers and ephemeral containers of the given manifests in sorted order.
+func FindContainerImages(manifests []Manifest) []string {
+	m := make(map[string]struct{})
+	for _, manifest := range manifests {
+		fields := podSpecFields(manifest.Key.Kind)
+		if fields == nil {
+			continue
+		}
+		spec, ok, err := unstructured.NestedMap(manifest.u.Object, fields...)
+		if err != nil || !ok {
+			continue
+		}
+		for _, name := range []string{"initContainers", "containers", "ephemeralContainers"} {
+			containers, _, _ := unstructured.NestedSlice(spec, name)
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if image, _, _ := unstructured.NestedString(container, "image"); image != "" {
+					m[image] = struct{}{}
+				}
+			}
+		}
+	}
+
+	images := make([]string, 0, len(m))
+	for image := range m {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindContainerImages(t *testing.T) {
+	data := `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: gcr.io/pipecd/init:v0.1.0
+      containers:
+      - name: helloworld
+        image: gcr.io/pipecd/helloworld:v0.1.0
+      - name: sidecar
+        image: gcr.io/pipecd/sidecar:v0.1.0
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cleanup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: cleanup
+            image: gcr.io/pipecd/cleanup:v0.1.0
+          - name: sidecar
+            image: gcr.io/pipecd/sidecar:v0.1.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+`
+	manifests, err := ParseManifests(data)
+	require.NoError(t, err)
+
+	images := FindContainerImages(manifests)
+	expected := []string{
+		"gcr.io/pipecd/cleanup:v0.1.0",
+		"gcr.io/pipecd/helloworld:v0.1.0",
+		"gcr.io/pipecd/init:v0.1.0",
+		"gcr.io/pipecd/sidecar:v0.1.0",
+	}
+	assert.Equal(t, expected, images)
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	jobStatusPollInterval = 5 * time.Second
+)
+
+// waitForJob polls the Job returned by the given get function
+// until it has been finished or the context is done.
+func waitForJob(ctx context.Context, get func() (Manifest, error), interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m, err := get()
+		if err != nil {
+			return err
+		}
+		finished, err := isJobFinished(m)
+		if finished || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for job: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// isJobFinished returns whether the given Job has been finished.
+// ErrJobFailed is returned when it has finished with a failure.
+func isJobFinished(m Manifest) (bool, error) {
+	job := &batchv1.Job{}
+	if err := m.ConvertToStructuredObject(job); err != nil {
+		return false, err
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return true, fmt.Errorf("%w: %s (%s)", ErrJobFailed, c.Reason, c.Message)
+		}
+	}
+	return false, nil
+}
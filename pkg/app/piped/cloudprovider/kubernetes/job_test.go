@@ -0,0 +1,139 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeJobManifest(t *testing.T, status string) Manifest {
+	t.Helper()
+	data := `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+`
+	if status != "" {
+		data += "status:\n" + status
+	}
+	manifests, err := ParseManifests(data)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	return manifests[0]
+}
+
+func TestIsJobFinished(t *testing.T) {
+	testcases := []struct {
+		name             string
+		status           string
+		expectedFinished bool
+		expectedErr      error
+	}{
+		{
+			name:             "no status",
+			expectedFinished: false,
+		},
+		{
+			name: "running",
+			status: `  active: 1
+`,
+			expectedFinished: false,
+		},
+		{
+			name: "completed",
+			status: `  succeeded: 1
+  conditions:
+  - type: Complete
+    status: "True"
+`,
+			expectedFinished: true,
+		},
+		{
+			name: "failed",
+			status: `  failed: 1
+  conditions:
+  - type: Failed
+    status: "True"
+    reason: BackoffLimitExceeded
+    message: Job has reached the specified backoff limit
+`,
+			expectedFinished: true,
+			expectedErr:      ErrJobFailed,
+		},
+		{
+			name: "condition is not true",
+			status: `  conditions:
+  - type: Complete
+    status: "False"
+`,
+			expectedFinished: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			finished, err := isJobFinished(makeJobManifest(t, tc.status))
+			assert.Equal(t, tc.expectedFinished, finished)
+			if tc.expectedErr != nil {
+				assert.True(t, errors.Is(err, tc.expectedErr))
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	running := makeJobManifest(t, "  active: 1\n")
+	completed := makeJobManifest(t, "  conditions:\n  - type: Complete\n    status: \"True\"\n")
+
+	t.Run("completed after polling", func(t *testing.T) {
+		var calls int
+		get := func() (Manifest, error) {
+			calls++
+			if calls < 3 {
+				return running, nil
+			}
+			return completed, nil
+		}
+		err := waitForJob(context.Background(), get, time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("failed to get", func(t *testing.T) {
+		get := func() (Manifest, error) {
+			return Manifest{}, ErrNotFound
+		}
+		err := waitForJob(context.Background(), get, time.Millisecond)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		get := func() (Manifest, error) {
+			return running, nil
+		}
+		err := waitForJob(ctx, get, time.Hour)
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
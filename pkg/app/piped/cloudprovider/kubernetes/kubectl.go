@@ -17,19 +17,33 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/rest"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/kubernetesmetrics"
 )
 
+const (
+	// The default name of the field manager used while doing server-side apply.
+	defaultFieldManager = "piped"
+	// How long to wait for a pod to be running before streaming its logs.
+	logsPodRunningTimeout = "5m"
+)
+
 type Kubectl struct {
 	version  string
 	execPath string
 	config   *rest.Config
+	// The path to the kubeconfig file and the master URL of the cluster
+	// to run commands against. Empty means the default one.
+	kubeConfigPath string
+	masterURL      string
 }
 
 func NewKubectl(version, path string) *Kubectl {
@@ -39,7 +53,17 @@ func NewKubectl(version, path string) *Kubectl {
 	}
 }
 
-func (c *Kubectl) Apply(ctx context.Context, namespace string, manifest Manifest) (err error) {
+func (c *Kubectl) Apply(ctx context.Context, namespace string, manifest Manifest) error {
+	return c.apply(ctx, applyArgs("apply", namespace, false, "", false), manifest)
+}
+
+// ApplyServerSide applies the given manifest by using server-side apply
+// to let the API server track the fields managed by the given field manager.
+func (c *Kubectl) ApplyServerSide(ctx context.Context, namespace string, manifest Manifest, fieldManager string, forceConflicts bool) error {
+	return c.apply(ctx, applyArgs("apply", namespace, true, fieldManager, forceConflicts), manifest)
+}
+
+func (c *Kubectl) apply(ctx context.Context, args []string, manifest Manifest) (err error) {
 	defer func() {
 		kubernetesmetrics.IncKubectlCallsCounter(
 			c.version,
@@ -53,19 +77,131 @@ func (c *Kubectl) Apply(ctx context.Context, namespace string, manifest Manifest
 		return err
 	}
 
-	args := make([]string, 0, 5)
+	cmd := c.command(ctx, args...)
+	r := bytes.NewReader(data)
+	cmd.Stdin = r
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if isWebhookUnavailable(string(out)) {
+			return fmt.Errorf("failed to apply: %s, (%w), %v", string(out), ErrWebhookUnavailable, err)
+		}
+		return fmt.Errorf("failed to apply: %s (%v)", string(out), err)
+	}
+	return nil
+}
+
+// Diff returns the difference between the given manifest and the live resource in the cluster.
+// An empty string is returned if there is no difference.
+func (c *Kubectl) Diff(ctx context.Context, namespace string, manifest Manifest, serverSide bool, fieldManager string, forceConflicts bool) (diff string, err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelDiffCommand,
+			err == nil,
+		)
+	}()
+
+	data, err := manifest.YamlBytes()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := c.command(ctx, applyArgs("diff", namespace, serverSide, fieldManager, forceConflicts)...)
+	cmd.Stdin = bytes.NewReader(data)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// kubectl diff exits with 1 when there are some differences.
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err == nil || (errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return stdout.String(), nil
+	}
+	return "", fmt.Errorf("failed to diff: %s (%v)", stderr.String(), err)
+}
+
+// Validate sends the given manifest to the API server in the dry-run mode
+// to check it against the schemas and the admission controllers of the cluster
+// without persisting anything.
+func (c *Kubectl) Validate(ctx context.Context, namespace string, manifest Manifest, serverSide bool, fieldManager string, forceConflicts bool) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelValidateCommand,
+			err == nil,
+		)
+	}()
+
+	data, err := manifest.YamlBytes()
+	if err != nil {
+		return err
+	}
+
+	cmd := c.command(ctx, validateArgs(namespace, serverSide, fieldManager, forceConflicts)...)
+	cmd.Stdin = bytes.NewReader(data)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to validate: %s (%v)", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// validateArgs builds the arguments of the apply command
+// running in the server-side dry-run mode.
+func validateArgs(namespace string, serverSide bool, fieldManager string, forceConflicts bool) []string {
+	args := applyArgs("apply", namespace, serverSide, fieldManager, forceConflicts)
+	// Insert the dry-run flags before the trailing "-f -".
+	n := len(args) - 2
+	return append(args[:n:n], "--dry-run=server", "--validate=true", "-f", "-")
+}
+
+// applyArgs builds the arguments of the given subcommand
+// which accepts the same flags with apply, e.g. apply, diff.
+func applyArgs(subcommand, namespace string, serverSide bool, fieldManager string, forceConflicts bool) []string {
+	args := make([]string, 0, 8)
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
-	args = append(args, "apply", "-f", "-")
+	args = append(args, subcommand)
+	if serverSide {
+		if fieldManager == "" {
+			fieldManager = defaultFieldManager
+		}
+		args = append(args, "--server-side", fmt.Sprintf("--field-manager=%s", fieldManager))
+		if forceConflicts {
+			args = append(args, "--force-conflicts")
+		}
+	}
+	return append(args, "-f", "-")
+}
 
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
-	r := bytes.NewReader(data)
-	cmd.Stdin = r
+// RolloutStatus blocks until the rollout of the given resource has been completed.
+func (c *Kubectl) RolloutStatus(ctx context.Context, namespace string, r ResourceKey) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelRolloutCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 6)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "rollout", "status", fmt.Sprintf("%s/%s", r.Kind, r.Name), "--watch=true")
 
+	cmd := c.command(ctx, args...)
 	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return fmt.Errorf("failed to wait for rollout: %w", ctx.Err())
+	}
 	if err != nil {
-		return fmt.Errorf("failed to apply: %s (%v)", string(out), err)
+		return fmt.Errorf("failed to wait for rollout: %s (%v)", string(out), err)
 	}
 	return nil
 }
@@ -85,7 +221,7 @@ func (c *Kubectl) Delete(ctx context.Context, namespace string, r ResourceKey) (
 	}
 	args = append(args, "delete", r.Kind, r.Name)
 
-	cmd := exec.CommandContext(ctx, c.execPath, args...)
+	cmd := c.command(ctx, args...)
 	out, err := cmd.CombinedOutput()
 
 	if strings.Contains(string(out), "(NotFound)") {
@@ -96,3 +232,219 @@ func (c *Kubectl) Delete(ctx context.Context, namespace string, r ResourceKey) (
 	}
 	return nil
 }
+
+// Create creates the given manifest.
+// An error wrapping errAlreadyExists is returned when the resource already exists.
+func (c *Kubectl) Create(ctx context.Context, namespace string, manifest Manifest) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelCreateCommand,
+			err == nil,
+		)
+	}()
+
+	out, err := c.runWithManifest(ctx, "create", namespace, manifest)
+	if err != nil {
+		if strings.Contains(string(out), "(AlreadyExists)") {
+			return fmt.Errorf("failed to create: %s, (%w), %v", string(out), errAlreadyExists, err)
+		}
+		return fmt.Errorf("failed to create: %s (%v)", string(out), err)
+	}
+	return nil
+}
+
+// Replace replaces the live resource with the given manifest.
+// An error wrapping errConflict is returned when the resource was modified
+// since the resourceVersion of the given manifest.
+func (c *Kubectl) Replace(ctx context.Context, namespace string, manifest Manifest) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelReplaceCommand,
+			err == nil,
+		)
+	}()
+
+	out, err := c.runWithManifest(ctx, "replace", namespace, manifest)
+	if err != nil {
+		if strings.Contains(string(out), "(Conflict)") {
+			return fmt.Errorf("failed to replace: %s, (%w), %v", string(out), errConflict, err)
+		}
+		return fmt.Errorf("failed to replace: %s (%v)", string(out), err)
+	}
+	return nil
+}
+
+func (c *Kubectl) runWithManifest(ctx context.Context, command, namespace string, manifest Manifest) ([]byte, error) {
+	data, err := manifest.YamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, 5)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, command, "-f", "-")
+
+	cmd := c.command(ctx, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	return cmd.CombinedOutput()
+}
+
+// GetNamespaces returns the namespaces matching the given label selector.
+func (c *Kubectl) GetNamespaces(ctx context.Context, selector string) ([]Manifest, error) {
+	return c.getList(ctx, "", "namespaces", selector)
+}
+
+// GetPods returns the pods in the given namespace matching the given label selector.
+func (c *Kubectl) GetPods(ctx context.Context, namespace, selector string) ([]Manifest, error) {
+	return c.getList(ctx, namespace, "pods", selector)
+}
+
+// GetNodes returns all nodes of the cluster.
+func (c *Kubectl) GetNodes(ctx context.Context) ([]Manifest, error) {
+	return c.getList(ctx, "", "nodes", "")
+}
+
+func (c *Kubectl) getList(ctx context.Context, namespace, resource, selector string) (manifests []Manifest, err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelGetCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 7)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "get", resource, "-o", "json")
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := c.command(ctx, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to get %s: %s (%v)", resource, stderr.String(), err)
+	}
+
+	var list unstructured.UnstructuredList
+	if err := list.UnmarshalJSON(stdout.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", resource, err)
+	}
+	manifests = make([]Manifest, 0, len(list.Items))
+	for i := range list.Items {
+		obj := &list.Items[i]
+		manifests = append(manifests, MakeManifest(MakeResourceKey(obj), obj))
+	}
+	return manifests, nil
+}
+
+// CreateNamespace creates a namespace with the given name.
+func (c *Kubectl) CreateNamespace(ctx context.Context, name string) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelCreateCommand,
+			err == nil,
+		)
+	}()
+
+	out, err := c.command(ctx, "create", "namespace", name).CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "(AlreadyExists)") {
+			return nil
+		}
+		return fmt.Errorf("failed to create namespace: %s (%v)", string(out), err)
+	}
+	return nil
+}
+
+// Get returns the live manifest of the given resource.
+func (c *Kubectl) Get(ctx context.Context, namespace string, r ResourceKey) (m Manifest, err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelGetCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 6)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args, "get", r.Kind, r.Name, "-o", "json")
+
+	var stdout, stderr bytes.Buffer
+	cmd := c.command(ctx, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "(NotFound)") {
+			return Manifest{}, fmt.Errorf("failed to get: %s, (%w), %v", stderr.String(), ErrNotFound, err)
+		}
+		return Manifest{}, fmt.Errorf("failed to get: %s (%v)", stderr.String(), err)
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(stdout.Bytes()); err != nil {
+		return Manifest{}, fmt.Errorf("failed to parse %s: %w", r.ReadableString(), err)
+	}
+	return MakeManifest(MakeResourceKey(obj), obj), nil
+}
+
+// Logs writes the logs of all containers of a pod selected by the given resource into the given writer.
+// It keeps following the logs until the containers have been terminated.
+func (c *Kubectl) Logs(ctx context.Context, namespace string, r ResourceKey, w io.Writer) (err error) {
+	defer func() {
+		kubernetesmetrics.IncKubectlCallsCounter(
+			c.version,
+			kubernetesmetrics.LabelLogsCommand,
+			err == nil,
+		)
+	}()
+
+	args := make([]string, 0, 7)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	args = append(args,
+		"logs",
+		fmt.Sprintf("%s/%s", r.Kind, r.Name),
+		"--all-containers=true",
+		"--follow=true",
+		fmt.Sprintf("--pod-running-timeout=%s", logsPodRunningTimeout),
+	)
+
+	var stderr bytes.Buffer
+	cmd := c.command(ctx, args...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("failed to stream logs: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to stream logs: %s (%v)", stderr.String(), err)
+	}
+	return nil
+}
+
+// command returns the command to run kubectl with the given arguments
+// against the configured cluster.
+func (c *Kubectl) command(ctx context.Context, args ...string) *exec.Cmd {
+	cargs := make([]string, 0, len(args)+4)
+	if c.kubeConfigPath != "" {
+		cargs = append(cargs, "--kubeconfig", c.kubeConfigPath)
+	}
+	if c.masterURL != "" {
+		cargs = append(cargs, "--server", c.masterURL)
+	}
+	return exec.CommandContext(ctx, c.execPath, append(cargs, args...)...)
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyArgs(t *testing.T) {
+	testcases := []struct {
+		name           string
+		subcommand     string
+		namespace      string
+		serverSide     bool
+		fieldManager   string
+		forceConflicts bool
+		expected       []string
+	}{
+		{
+			name:     "client-side apply",
+			expected: []string{"apply", "-f", "-"},
+		},
+		{
+			name:      "client-side apply with namespace",
+			namespace: "ns",
+			expected:  []string{"-n", "ns", "apply", "-f", "-"},
+		},
+		{
+			name:       "server-side apply with default field manager",
+			serverSide: true,
+			expected:   []string{"apply", "--server-side", "--field-manager=piped", "-f", "-"},
+		},
+		{
+			name:           "server-side apply with forcing conflicts",
+			namespace:      "ns",
+			serverSide:     true,
+			fieldManager:   "custom",
+			forceConflicts: true,
+			expected:       []string{"-n", "ns", "apply", "--server-side", "--field-manager=custom", "--force-conflicts", "-f", "-"},
+		},
+		{
+			name:       "server-side diff",
+			subcommand: "diff",
+			namespace:  "ns",
+			serverSide: true,
+			expected:   []string{"-n", "ns", "diff", "--server-side", "--field-manager=piped", "-f", "-"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			subcommand := tc.subcommand
+			if subcommand == "" {
+				subcommand = "apply"
+			}
+			got := applyArgs(subcommand, tc.namespace, tc.serverSide, tc.fieldManager, tc.forceConflicts)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestValidateArgs(t *testing.T) {
+	testcases := []struct {
+		name           string
+		namespace      string
+		serverSide     bool
+		fieldManager   string
+		forceConflicts bool
+		expected       []string
+	}{
+		{
+			name:     "client-side apply",
+			expected: []string{"apply", "--dry-run=server", "--validate=true", "-f", "-"},
+		},
+		{
+			name:           "server-side apply",
+			namespace:      "ns",
+			serverSide:     true,
+			forceConflicts: true,
+			expected:       []string{"-n", "ns", "apply", "--server-side", "--field-manager=piped", "--force-conflicts", "--dry-run=server", "--validate=true", "-f", "-"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := validateArgs(tc.namespace, tc.serverSide, tc.fieldManager, tc.forceConflicts)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestKubectlCommand(t *testing.T) {
+	testcases := []struct {
+		name     string
+		kubectl  *Kubectl
+		expected []string
+	}{
+		{
+			name:     "default cluster",
+			kubectl:  NewKubectl("1.18.2", "kubectl"),
+			expected: []string{"kubectl", "apply", "-f", "-"},
+		},
+		{
+			name: "specified cluster",
+			kubectl: &Kubectl{
+				execPath:       "kubectl",
+				kubeConfigPath: "/etc/kube/config",
+				masterURL:      "https://cluster.dev",
+			},
+			expected: []string{"kubectl", "--kubeconfig", "/etc/kube/config", "--server", "https://cluster.dev", "apply", "-f", "-"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := tc.kubectl.command(context.Background(), "apply", "-f", "-")
+			assert.Equal(t, tc.expected, cmd.Args)
+		})
+	}
+}
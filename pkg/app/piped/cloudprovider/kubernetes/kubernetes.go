@@ -18,19 +18,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/backoff"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/git"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
+	// ErrWebhookUnavailable is returned when a manifest could not be applied
+	// because the admission webhook handling it was not reachable.
+	ErrWebhookUnavailable = errors.New("admission webhook unavailable")
+	// ErrJobFailed is returned when a Job has reached its backoff limit or deadline.
+	ErrJobFailed = errors.New("job failed")
 )
 
 const (
@@ -42,6 +51,7 @@ const (
 	LabelOriginalAPIVersion   = "pipecd.dev/original-api-version"   // The api version defined in git configuration. e.g. apps/v1
 	LabelIgnoreDriftDirection = "pipecd.dev/ignore-drift-detection" // Whether the drift detection should ignore this resource.
 	AnnotationConfigHash      = "pipecd.dev/config-hash"            // The hash value of all mouting config resources.
+	AnnotationSyncWave        = "pipecd.dev/sync-wave"              // The wave number in which this resource is applied. Lower ones are applied first.
 	ManagedByPiped            = "piped"
 	IgnoreDriftDetectionTrue  = "true"
 
@@ -73,6 +83,31 @@ type Applier interface {
 	ApplyManifest(ctx context.Context, manifest Manifest) error
 	// Delete deletes the given resource from Kubernetes cluster.
 	Delete(ctx context.Context, key ResourceKey) error
+	// DiffManifest returns the changes applying the given manifest would make to the cluster.
+	DiffManifest(ctx context.Context, manifest Manifest) (string, error)
+	// ValidateManifest checks whether the given manifest would be accepted by the cluster
+	// without applying it.
+	ValidateManifest(ctx context.Context, manifest Manifest) error
+	// ListNamespaces returns the namespaces matching the given label selector.
+	ListNamespaces(ctx context.Context, selector string) ([]Manifest, error)
+	// CountPodsByArchitecture returns the number of running pods matching the given label selector
+	// in the namespace of the given workload, grouped by the CPU architecture of their nodes.
+	CountPodsByArchitecture(ctx context.Context, key ResourceKey, selector string) (map[string]int, error)
+	// WaitForRollout blocks until the rollout of the given resource has been completed.
+	WaitForRollout(ctx context.Context, key ResourceKey) error
+	// WaitForJob blocks until the given Job has been finished.
+	// ErrJobFailed is returned when the Job has failed.
+	WaitForJob(ctx context.Context, key ResourceKey) error
+	// WaitForEstablished blocks until the given CustomResourceDefinition has been established.
+	WaitForEstablished(ctx context.Context, key ResourceKey) error
+	// TryAcquireLease acquires or renews the given Lease for the given holder.
+	// An error wrapping ErrLeaseHeld is returned when it is held by another holder.
+	TryAcquireLease(ctx context.Context, key ResourceKey, holder string, duration time.Duration) error
+	// ReleaseLease deletes the given Lease if it is held by the given holder.
+	ReleaseLease(ctx context.Context, key ResourceKey, holder string) error
+	// StreamLogs writes the logs of the pod of the given resource into the given writer
+	// until its containers have been terminated.
+	StreamLogs(ctx context.Context, key ResourceKey, w io.Writer) error
 }
 
 type gitClient interface {
@@ -92,6 +127,9 @@ type provider struct {
 	configFileName string
 	input          config.KubernetesDeploymentInput
 	logger         *zap.Logger
+	// The cluster where manifests are applied to.
+	// Nil means the default one of kubectl.
+	cluster *config.CloudProviderKubernetesConfig
 
 	kubectl          *Kubectl
 	kustomize        *Kustomize
@@ -99,6 +137,10 @@ type provider struct {
 	templatingMethod TemplatingMethod
 	initOnce         sync.Once
 	initErr          error
+
+	// Whether the namespace specified in the input has been ensured to exist.
+	namespaceEnsured bool
+	namespaceMu      sync.Mutex
 }
 
 func initSharedGitClient(logger *zap.Logger) error {
@@ -120,6 +162,20 @@ func NewProvider(appName, appDir, repoDir, configFileName string, input config.K
 	}
 }
 
+// NewProviderForCluster returns a provider which applies manifests
+// to the cluster of the given Kubernetes cloud provider.
+func NewProviderForCluster(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, cluster config.CloudProviderKubernetesConfig, logger *zap.Logger) Provider {
+	return &provider{
+		appName:        appName,
+		appDir:         appDir,
+		repoDir:        repoDir,
+		configFileName: configFileName,
+		input:          input,
+		cluster:        &cluster,
+		logger:         logger.Named("kubernetes-provider"),
+	}
+}
+
 func NewManifestLoader(appName, appDir, repoDir, configFileName string, input config.KubernetesDeploymentInput, logger *zap.Logger) ManifestLoader {
 	return NewProvider(appName, appDir, repoDir, configFileName, input, logger)
 }
@@ -137,6 +193,10 @@ func (p *provider) init(ctx context.Context) {
 	if p.initErr != nil {
 		return
 	}
+	if p.cluster != nil {
+		p.kubectl.kubeConfigPath = p.cluster.KubeConfigPath
+		p.kubectl.masterURL = p.cluster.MasterURL
+	}
 
 	switch p.templatingMethod {
 	case TemplatingMethodHelm:
@@ -174,10 +234,11 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 
 		case p.input.HelmChart.Repository != "":
 			chart := helmRemoteChart{
-				Repository: p.input.HelmChart.Repository,
-				Name:       p.input.HelmChart.Name,
-				Version:    p.input.HelmChart.Version,
-				Insecure:   p.input.HelmChart.Insecure,
+				Repository:  p.input.HelmChart.Repository,
+				Name:        p.input.HelmChart.Name,
+				Version:     p.input.HelmChart.Version,
+				Insecure:    p.input.HelmChart.Insecure,
+				OCIRegistry: p.input.HelmChart.OCIRegistry,
 			}
 			data, err = p.helm.TemplateRemoteChart(ctx,
 				p.appName,
@@ -203,7 +264,7 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 
 	case TemplatingMethodKustomize:
 		var data string
-		data, err = p.kustomize.Template(ctx, p.appName, p.appDir, p.input.KustomizeOptions)
+		data, err = p.kustomize.Template(ctx, p.appName, p.appDir, p.input.KustomizeOverlay, p.input.KustomizeOptions, p.input.KustomizeBuildOptions)
 		if err != nil {
 			err = fmt.Errorf("unable to run kustomize template: %w", err)
 			return
@@ -217,9 +278,52 @@ func (p *provider) LoadManifests(ctx context.Context) (manifests []Manifest, err
 		err = fmt.Errorf("unsupport templating method %v", p.templatingMethod)
 	}
 
+	if err == nil && p.input.EnforceNamespace {
+		manifests = enforceNamespace(manifests, p.input.Namespace)
+	}
 	return
 }
 
+// enforceNamespace overrides the namespace of all namespaced manifests with the given one.
+// Cluster-scoped manifests which have no namespace are kept as is.
+func enforceNamespace(manifests []Manifest, namespace string) []Manifest {
+	out := make([]Manifest, 0, len(manifests))
+	for _, m := range manifests {
+		out = append(out, m.DuplicateWithNamespace(namespace))
+	}
+	return out
+}
+
+// ensureNamespace creates the namespace specified in the input if it does not exist yet.
+func (p *provider) ensureNamespace(ctx context.Context) error {
+	p.namespaceMu.Lock()
+	defer p.namespaceMu.Unlock()
+
+	if p.namespaceEnsured {
+		return nil
+	}
+
+	key := ResourceKey{
+		APIVersion: "v1",
+		Kind:       KindNamespace,
+		Name:       p.input.Namespace,
+	}
+	_, err := p.kubectl.Get(ctx, "", key)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNotFound):
+		if err := p.kubectl.CreateNamespace(ctx, p.input.Namespace); err != nil {
+			return err
+		}
+		p.logger.Info(fmt.Sprintf("created namespace %s", p.input.Namespace))
+	default:
+		return err
+	}
+
+	p.namespaceEnsured = true
+	return nil
+}
+
 // Apply does applying application manifests by using the tool specified in Input.
 func (p *provider) Apply(ctx context.Context) error {
 	return nil
@@ -232,7 +336,209 @@ func (p *provider) ApplyManifest(ctx context.Context, manifest Manifest) error {
 		return p.initErr
 	}
 
-	return p.kubectl.Apply(ctx, p.getNamespaceToRun(manifest.Key), manifest)
+	if p.input.AutoCreateNamespace {
+		if err := p.ensureNamespace(ctx); err != nil {
+			return err
+		}
+	}
+
+	namespace := p.getNamespaceToRun(manifest.Key)
+	apply := func() error {
+		if ssa := p.input.ServerSideApply; ssa != nil {
+			return p.kubectl.ApplyServerSide(ctx, namespace, manifest, ssa.FieldManager, ssa.ForceConflicts)
+		}
+		return p.kubectl.Apply(ctx, namespace, manifest)
+	}
+
+	timeout := p.input.WebhookRetryTimeout()
+	if timeout <= 0 {
+		return apply()
+	}
+	b := backoff.NewExponential(webhookRetryBaseInterval, webhookRetryMaxInterval)
+	return retryOnWebhookUnavailable(ctx, timeout, b, apply, p.logger.With(zap.String("manifest", manifest.Key.ReadableString())))
+}
+
+// DiffManifest returns the changes applying the given manifest would make to the cluster.
+func (p *provider) DiffManifest(ctx context.Context, manifest Manifest) (string, error) {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return "", p.initErr
+	}
+
+	namespace := p.getNamespaceToRun(manifest.Key)
+	if ssa := p.input.ServerSideApply; ssa != nil {
+		return p.kubectl.Diff(ctx, namespace, manifest, true, ssa.FieldManager, ssa.ForceConflicts)
+	}
+	return p.kubectl.Diff(ctx, namespace, manifest, false, "", false)
+}
+
+// ValidateManifest checks whether the given manifest would be accepted by the cluster
+// without applying it.
+func (p *provider) ValidateManifest(ctx context.Context, manifest Manifest) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	namespace := p.getNamespaceToRun(manifest.Key)
+	if ssa := p.input.ServerSideApply; ssa != nil {
+		return p.kubectl.Validate(ctx, namespace, manifest, true, ssa.FieldManager, ssa.ForceConflicts)
+	}
+	return p.kubectl.Validate(ctx, namespace, manifest, false, "", false)
+}
+
+// ListNamespaces returns the namespaces matching the given label selector.
+func (p *provider) ListNamespaces(ctx context.Context, selector string) ([]Manifest, error) {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return nil, p.initErr
+	}
+
+	return p.kubectl.GetNamespaces(ctx, selector)
+}
+
+// CountPodsByArchitecture returns the number of running pods matching the given label selector
+// in the namespace of the given workload, grouped by the CPU architecture of their nodes.
+func (p *provider) CountPodsByArchitecture(ctx context.Context, key ResourceKey, selector string) (map[string]int, error) {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return nil, p.initErr
+	}
+
+	pods, err := p.kubectl.GetPods(ctx, p.getNamespaceToRun(key), selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) == 0 {
+		return map[string]int{}, nil
+	}
+	nodes, err := p.kubectl.GetNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return countPodsByArchitecture(pods, nodes)
+}
+
+// WaitForRollout blocks until the rollout of the given resource has been completed.
+func (p *provider) WaitForRollout(ctx context.Context, k ResourceKey) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	return p.kubectl.RolloutStatus(ctx, p.getNamespaceToRun(k), k)
+}
+
+// WaitForJob blocks until the given Job has been finished.
+// ErrJobFailed is returned when the Job has failed.
+func (p *provider) WaitForJob(ctx context.Context, k ResourceKey) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	namespace := p.getNamespaceToRun(k)
+	get := func() (Manifest, error) {
+		return p.kubectl.Get(ctx, namespace, k)
+	}
+	return waitForJob(ctx, get, jobStatusPollInterval)
+}
+
+// WaitForEstablished blocks until the given CustomResourceDefinition has been established.
+func (p *provider) WaitForEstablished(ctx context.Context, k ResourceKey) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	get := func() (Manifest, error) {
+		return p.kubectl.Get(ctx, "", k)
+	}
+	return waitForEstablished(ctx, get, crdStatusPollInterval)
+}
+
+// TryAcquireLease acquires or renews the given Lease for the given holder.
+// An error wrapping ErrLeaseHeld is returned when it is held by another holder.
+// Since the Lease is replaced with its resourceVersion,
+// only one of the holders trying to acquire it at the same time can succeed.
+func (p *provider) TryAcquireLease(ctx context.Context, k ResourceKey, holder string, duration time.Duration) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	now := time.Now()
+	lease := &coordinationv1.Lease{}
+	m, err := p.kubectl.Get(ctx, k.Namespace, k)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		holdLease(lease, holder, duration, now)
+		m, err = makeLeaseManifest(k, lease)
+		if err != nil {
+			return err
+		}
+		err = p.kubectl.Create(ctx, k.Namespace, m)
+		if errors.Is(err, errAlreadyExists) {
+			return fmt.Errorf("%w: created by another holder just now", ErrLeaseHeld)
+		}
+		return err
+	case err != nil:
+		return err
+	}
+
+	if err := m.ConvertToStructuredObject(lease); err != nil {
+		return fmt.Errorf("failed to parse lease %s: %w", k.Name, err)
+	}
+	if !isLeaseAvailable(lease, holder, now) {
+		return fmt.Errorf("%w: held by %s", ErrLeaseHeld, leaseHolder(lease))
+	}
+	holdLease(lease, holder, duration, now)
+	if m, err = makeLeaseManifest(k, lease); err != nil {
+		return err
+	}
+	err = p.kubectl.Replace(ctx, k.Namespace, m)
+	if errors.Is(err, errConflict) {
+		return fmt.Errorf("%w: acquired by another holder just now", ErrLeaseHeld)
+	}
+	return err
+}
+
+// ReleaseLease deletes the given Lease if it is held by the given holder.
+func (p *provider) ReleaseLease(ctx context.Context, k ResourceKey, holder string) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	m, err := p.kubectl.Get(ctx, k.Namespace, k)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	lease := &coordinationv1.Lease{}
+	if err := m.ConvertToStructuredObject(lease); err != nil {
+		return fmt.Errorf("failed to parse lease %s: %w", k.Name, err)
+	}
+	if leaseHolder(lease) != holder {
+		return nil
+	}
+	if err := p.kubectl.Delete(ctx, k.Namespace, k); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// StreamLogs writes the logs of the pod of the given resource into the given writer
+// until its containers have been terminated.
+func (p *provider) StreamLogs(ctx context.Context, k ResourceKey, w io.Writer) error {
+	p.initOnce.Do(func() { p.init(ctx) })
+	if p.initErr != nil {
+		return p.initErr
+	}
+
+	return p.kubectl.Logs(ctx, p.getNamespaceToRun(k), k, w)
 }
 
 // Delete deletes the given resource from Kubernetes cluster.
@@ -291,7 +597,7 @@ func determineTemplatingMethod(input config.KubernetesDeploymentInput, appDirPat
 	if input.HelmChart != nil {
 		return TemplatingMethodHelm
 	}
-	if _, err := os.Stat(filepath.Join(appDirPath, kustomizationFileName)); err == nil {
+	if _, err := os.Stat(filepath.Join(appDirPath, input.KustomizeOverlay, kustomizationFileName)); err == nil {
 		return TemplatingMethodKustomize
 	}
 	return TemplatingMethodNone
@@ -19,6 +19,8 @@ import (
 	"os"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
@@ -32,3 +34,40 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(m.Run())
 }
+
+func TestEnforceNamespace(t *testing.T) {
+	manifests, err := ParseManifests(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: preview
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+  namespace: default
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: simple
+`)
+	require.NoError(t, err)
+	require.Len(t, manifests, 3)
+
+	got := enforceNamespace(manifests, "preview-feature-a")
+	require.Len(t, got, 3)
+
+	assert.Equal(t, "", got[0].Key.Namespace)
+	assert.Equal(t, "", got[0].u.GetNamespace())
+
+	assert.Equal(t, "preview-feature-a", got[1].Key.Namespace)
+	assert.Equal(t, "preview-feature-a", got[1].u.GetNamespace())
+
+	assert.Equal(t, "", got[2].Key.Namespace)
+	assert.Equal(t, "", got[2].u.GetNamespace())
+
+	// The original manifests must not be changed.
+	assert.Equal(t, "default", manifests[1].u.GetNamespace())
+}
@@ -34,8 +34,15 @@ const (
 type ToolCommand string
 
 const (
-	LabelApplyCommand  ToolCommand = "apply"
-	LabelDeleteCommand ToolCommand = "delete"
+	LabelApplyCommand    ToolCommand = "apply"
+	LabelDeleteCommand   ToolCommand = "delete"
+	LabelDiffCommand     ToolCommand = "diff"
+	LabelRolloutCommand  ToolCommand = "rollout"
+	LabelValidateCommand ToolCommand = "validate"
+	LabelGetCommand      ToolCommand = "get"
+	LabelLogsCommand     ToolCommand = "logs"
+	LabelCreateCommand   ToolCommand = "create"
+	LabelReplaceCommand  ToolCommand = "replace"
 )
 
 type CommandOutput string
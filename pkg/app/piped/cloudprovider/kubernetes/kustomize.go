@@ -19,10 +19,16 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
+const defaultKustomizeMajorVersion = 3
+
 type Kustomize struct {
 	version  string
 	execPath string
@@ -37,10 +43,23 @@ func NewKustomize(version, path string, logger *zap.Logger) *Kustomize {
 	}
 }
 
-func (c *Kustomize) Template(ctx context.Context, appName, appDir string, opts map[string]string) (string, error) {
+// Template runs "kustomize build" for the given overlay directory relative to appDir.
+// Empty overlay means appDir itself will be built.
+func (c *Kustomize) Template(ctx context.Context, appName, appDir, overlay string, opts map[string]string, buildOpts *config.InputKustomizeBuildOptions) (string, error) {
+	if overlay == "" {
+		overlay = "."
+	}
 	args := []string{
 		"build",
-		".",
+		overlay,
+	}
+
+	if buildOpts != nil {
+		flags, err := c.buildFlags(buildOpts)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, flags...)
 	}
 
 	for k, v := range opts {
@@ -65,3 +84,49 @@ func (c *Kustomize) Template(ctx context.Context, appName, appDir string, opts m
 	}
 	return stdout.String(), nil
 }
+
+// buildFlags returns the flags for the given build options.
+// Kustomize v4 renamed the flags from snake_case to kebab-case.
+func (c *Kustomize) buildFlags(opts *config.InputKustomizeBuildOptions) ([]string, error) {
+	major := c.majorVersion()
+	flag := func(name string) string {
+		if major < 4 {
+			name = strings.ReplaceAll(name, "-", "_")
+		}
+		return "--" + name
+	}
+
+	var flags []string
+	if opts.EnableHelm {
+		if major < 4 {
+			return nil, fmt.Errorf("enableHelm requires kustomize v4.1.0 or later but %s is used", c.displayVersion())
+		}
+		flags = append(flags, flag("enable-helm"))
+	}
+	if opts.LoadRestrictor != "" {
+		flags = append(flags, flag("load-restrictor"), opts.LoadRestrictor)
+	}
+	if opts.EnableAlphaPlugins {
+		flags = append(flags, flag("enable-alpha-plugins"))
+	}
+	return flags, nil
+}
+
+func (c *Kustomize) majorVersion() int {
+	v := strings.TrimPrefix(c.version, "v")
+	if v == "" {
+		return defaultKustomizeMajorVersion
+	}
+	major, err := strconv.Atoi(strings.SplitN(v, ".", 2)[0])
+	if err != nil {
+		return defaultKustomizeMajorVersion
+	}
+	return major
+}
+
+func (c *Kustomize) displayVersion() string {
+	if c.version == "" {
+		return "the default version"
+	}
+	return c.version
+}
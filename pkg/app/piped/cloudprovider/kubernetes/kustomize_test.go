@@ -23,6 +23,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 func TestKustomizeTemplate(t *testing.T) {
@@ -36,9 +37,55 @@ func TestKustomizeTemplate(t *testing.T) {
 	require.NoError(t, err)
 
 	kustomize := NewKustomize("", kustomizePath, zap.NewNop())
-	out, err := kustomize.Template(ctx, appName, appDir, map[string]string{
+	out, err := kustomize.Template(ctx, appName, appDir, "", map[string]string{
 		"load_restrictor": "LoadRestrictionsNone",
-	})
+	}, nil)
 	require.NoError(t, err)
 	assert.True(t, len(out) > 0)
 }
+
+func TestKustomizeBuildFlags(t *testing.T) {
+	testcases := []struct {
+		name     string
+		version  string
+		opts     *config.InputKustomizeBuildOptions
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:    "default version",
+			version: "",
+			opts: &config.InputKustomizeBuildOptions{
+				LoadRestrictor:     "LoadRestrictionsNone",
+				EnableAlphaPlugins: true,
+			},
+			expected: []string{"--load_restrictor", "LoadRestrictionsNone", "--enable_alpha_plugins"},
+		},
+		{
+			name:    "v4",
+			version: "4.1.2",
+			opts: &config.InputKustomizeBuildOptions{
+				EnableHelm:         true,
+				LoadRestrictor:     "LoadRestrictionsNone",
+				EnableAlphaPlugins: true,
+			},
+			expected: []string{"--enable-helm", "--load-restrictor", "LoadRestrictionsNone", "--enable-alpha-plugins"},
+		},
+		{
+			name:    "enable helm is not supported by v3",
+			version: "3.8.1",
+			opts: &config.InputKustomizeBuildOptions{
+				EnableHelm: true,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			kustomize := NewKustomize(tc.version, "", zap.NewNop())
+			flags, err := kustomize.buildFlags(tc.opts)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, flags)
+		})
+	}
+}
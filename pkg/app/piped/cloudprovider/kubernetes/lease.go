@@ -0,0 +1,107 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	KindLease = "Lease"
+
+	leaseAPIVersion = "coordination.k8s.io/v1"
+)
+
+var (
+	// ErrLeaseHeld is returned when the Lease is held by another holder and has not expired yet.
+	ErrLeaseHeld = errors.New("lease is held by another holder")
+
+	errAlreadyExists = errors.New("already exists")
+	errConflict      = errors.New("conflict")
+)
+
+// MakeLeaseKey returns the key of the Lease object with the given name.
+func MakeLeaseKey(namespace, name string) ResourceKey {
+	return ResourceKey{
+		APIVersion: leaseAPIVersion,
+		Kind:       KindLease,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// isLeaseAvailable returns whether the given Lease can be acquired by the given holder.
+// It is available when it is not held by anyone, is already held by the holder, or has expired.
+func isLeaseAvailable(lease *coordinationv1.Lease, holder string, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || *spec.HolderIdentity == holder {
+		return true
+	}
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+	return now.After(expiry)
+}
+
+// leaseHolder returns the current holder of the given Lease.
+func leaseHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// holdLease updates the given Lease to be held by the given holder.
+// The acquire time is updated only when the holder was changed.
+func holdLease(lease *coordinationv1.Lease, holder string, duration time.Duration, now time.Time) {
+	var (
+		seconds = int32(duration / time.Second)
+		t       = metav1.NewMicroTime(now)
+	)
+	if leaseHolder(lease) != holder {
+		lease.Spec.AcquireTime = &t
+		if lease.Spec.HolderIdentity != nil {
+			transitions := int32(1)
+			if lease.Spec.LeaseTransitions != nil {
+				transitions = *lease.Spec.LeaseTransitions + 1
+			}
+			lease.Spec.LeaseTransitions = &transitions
+		}
+		lease.Spec.HolderIdentity = &holder
+	}
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &t
+}
+
+func makeLeaseManifest(key ResourceKey, lease *coordinationv1.Lease) (Manifest, error) {
+	lease.APIVersion = leaseAPIVersion
+	lease.Kind = KindLease
+	lease.Namespace = key.Namespace
+	lease.Name = key.Name
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(lease)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to convert lease %s: %w", key.Name, err)
+	}
+	return MakeManifest(key, &unstructured.Unstructured{Object: obj}), nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsLeaseAvailable(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	makeLease := func(holder string, renewed time.Time, seconds int32) *coordinationv1.Lease {
+		t := metav1.NewMicroTime(renewed)
+		return &coordinationv1.Lease{
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				RenewTime:            &t,
+				LeaseDurationSeconds: &seconds,
+			},
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		lease    *coordinationv1.Lease
+		expected bool
+	}{
+		{
+			name:     "not held by anyone",
+			lease:    &coordinationv1.Lease{},
+			expected: true,
+		},
+		{
+			name:     "released",
+			lease:    makeLease("", now, 60),
+			expected: true,
+		},
+		{
+			name:     "held by the same holder",
+			lease:    makeLease("piped-1/deployment-1", now, 60),
+			expected: true,
+		},
+		{
+			name:     "held by another holder",
+			lease:    makeLease("piped-2/deployment-2", now.Add(-30*time.Second), 60),
+			expected: false,
+		},
+		{
+			name:     "expired",
+			lease:    makeLease("piped-2/deployment-2", now.Add(-90*time.Second), 60),
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := isLeaseAvailable(tc.lease, "piped-1/deployment-1", now)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestHoldLease(t *testing.T) {
+	var (
+		key   = MakeLeaseKey("default", "pipecd-lock")
+		lease = &coordinationv1.Lease{}
+		now   = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	// Acquire a new lease.
+	holdLease(lease, "piped-1/deployment-1", time.Minute, now)
+	assert.Equal(t, "piped-1/deployment-1", leaseHolder(lease))
+	assert.Equal(t, int32(60), *lease.Spec.LeaseDurationSeconds)
+	assert.True(t, lease.Spec.AcquireTime.Time.Equal(now))
+	assert.True(t, lease.Spec.RenewTime.Time.Equal(now))
+	assert.Nil(t, lease.Spec.LeaseTransitions)
+
+	// Renew by the same holder.
+	renewed := now.Add(20 * time.Second)
+	holdLease(lease, "piped-1/deployment-1", time.Minute, renewed)
+	assert.True(t, lease.Spec.AcquireTime.Time.Equal(now))
+	assert.True(t, lease.Spec.RenewTime.Time.Equal(renewed))
+	assert.Nil(t, lease.Spec.LeaseTransitions)
+
+	// Taken over by another holder.
+	taken := now.Add(2 * time.Minute)
+	holdLease(lease, "piped-2/deployment-2", time.Minute, taken)
+	assert.Equal(t, "piped-2/deployment-2", leaseHolder(lease))
+	assert.True(t, lease.Spec.AcquireTime.Time.Equal(taken))
+	assert.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+
+	m, err := makeLeaseManifest(key, lease)
+	require.NoError(t, err)
+	assert.Equal(t, key, m.Key)
+	assert.Equal(t, "coordination.k8s.io/v1", m.u.GetAPIVersion())
+	assert.Equal(t, "Lease", m.u.GetKind())
+	assert.Equal(t, "pipecd-lock", m.u.GetName())
+	assert.Equal(t, "default", m.u.GetNamespace())
+}
@@ -53,6 +53,22 @@ func (m Manifest) Duplicate(name string) Manifest {
 	}
 }
 
+// DuplicateWithNamespace returns a copy of the manifest whose namespace was replaced by the given one.
+// The manifest not specifying its namespace is kept as is.
+func (m Manifest) DuplicateWithNamespace(namespace string) Manifest {
+	u := m.u.DeepCopy()
+	key := m.Key
+	if u.GetNamespace() != "" {
+		u.SetNamespace(namespace)
+		key.Namespace = namespace
+	}
+
+	return Manifest{
+		Key: key,
+		u:   u,
+	}
+}
+
 func (m Manifest) YamlBytes() ([]byte, error) {
 	return yaml.Marshal(m.u)
 }
@@ -77,10 +93,30 @@ func (m Manifest) AddAnnotations(annotations map[string]string) {
 	m.u.SetAnnotations(annos)
 }
 
+func (m Manifest) AddLabels(labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	ls := m.u.GetLabels()
+	if ls != nil {
+		for k, v := range labels {
+			ls[k] = v
+		}
+	} else {
+		ls = labels
+	}
+	m.u.SetLabels(ls)
+}
+
 func (m Manifest) GetAnnotations() map[string]string {
 	return m.u.GetAnnotations()
 }
 
+func (m Manifest) GetLabels() map[string]string {
+	return m.u.GetLabels()
+}
+
 func (m Manifest) GetNestedStringMap(fields ...string) (map[string]string, error) {
 	sm, _, err := unstructured.NestedStringMap(m.u.Object, fields...)
 	if err != nil {
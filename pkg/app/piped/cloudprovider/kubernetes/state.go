@@ -32,13 +32,18 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
-func MakeKubernetesResourceState(uid string, key ResourceKey, obj *unstructured.Unstructured, now time.Time) model.KubernetesResourceState {
+// MakeKubernetesResourceState builds the state of the given resource.
+// The given health checker is prioritized over the built-in ones to determine the health status.
+func MakeKubernetesResourceState(uid string, key ResourceKey, obj *unstructured.Unstructured, healthChecker *HealthChecker, now time.Time) model.KubernetesResourceState {
 	var (
-		owners       = obj.GetOwnerReferences()
-		ownerIDs     = make([]string, 0, len(owners))
-		creationTime = obj.GetCreationTimestamp()
-		status, desc = determineResourceHealth(key, obj)
+		owners           = obj.GetOwnerReferences()
+		ownerIDs         = make([]string, 0, len(owners))
+		creationTime     = obj.GetCreationTimestamp()
+		status, desc, ok = healthChecker.Check(key, obj)
 	)
+	if !ok {
+		status, desc = determineResourceHealth(key, obj)
+	}
 
 	for _, owner := range owners {
 		ownerIDs = append(ownerIDs, string(owner.UID))
@@ -0,0 +1,68 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/backoff"
+)
+
+const (
+	webhookRetryBaseInterval = 2 * time.Second
+	webhookRetryMaxInterval  = 20 * time.Second
+)
+
+// The API server reports this when it was unable to call an admission webhook,
+// e.g. timed out, no endpoints or connection refused.
+// The requests denied by the webhooks are reported as "admission webhook ... denied the request" instead.
+var webhookUnavailableRegex = regexp.MustCompile(`failed calling (admission )?webhook`)
+
+func isWebhookUnavailable(out string) bool {
+	return webhookUnavailableRegex.MatchString(out)
+}
+
+// retryOnWebhookUnavailable calls the given apply function
+// and retries it with the given backoff while it is failing with ErrWebhookUnavailable
+// until the given timeout has been elapsed.
+// The other errors are returned immediately.
+func retryOnWebhookUnavailable(ctx context.Context, timeout time.Duration, b backoff.Backoff, apply func() error, logger *zap.Logger) error {
+	var (
+		deadline = time.Now().Add(timeout)
+		retry    = backoff.NewRetry(math.MaxInt32, b)
+		err      error
+	)
+	for retry.WaitNext(ctx) {
+		err = apply()
+		if !errors.Is(err, ErrWebhookUnavailable) {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("gave up applying after retrying for %v: %w", timeout, err)
+		}
+		logger.Info("admission webhook was unavailable, retrying to apply", zap.Int("calls", retry.Calls()), zap.Error(err))
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
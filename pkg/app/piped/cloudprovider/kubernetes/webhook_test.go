@@ -0,0 +1,119 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/backoff"
+)
+
+func TestIsWebhookUnavailable(t *testing.T) {
+	testcases := []struct {
+		name     string
+		out      string
+		expected bool
+	}{
+		{
+			name:     "timed out",
+			out:      `Error from server (InternalError): error when creating "STDIN": Internal error occurred: failed calling webhook "validate.example.com": Post "https://webhook.default.svc:443/validate?timeout=10s": context deadline exceeded`,
+			expected: true,
+		},
+		{
+			name:     "no endpoints",
+			out:      `Error from server (InternalError): error when applying patch: Internal error occurred: failed calling admission webhook "validate.example.com": no endpoints available for service "webhook"`,
+			expected: true,
+		},
+		{
+			name:     "denied by webhook",
+			out:      `Error from server: error when creating "STDIN": admission webhook "validate.example.com" denied the request: image tag must not be latest`,
+			expected: false,
+		},
+		{
+			name:     "invalid manifest",
+			out:      `error: error validating "STDIN": error validating data: ValidationError(Deployment.spec): unknown field "replica"`,
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isWebhookUnavailable(tc.out))
+		})
+	}
+}
+
+func TestRetryOnWebhookUnavailable(t *testing.T) {
+	webhookErr := fmt.Errorf("failed to apply: (%w)", ErrWebhookUnavailable)
+	otherErr := errors.New("invalid manifest")
+
+	testcases := []struct {
+		name          string
+		errs          []error
+		timeout       time.Duration
+		expectedCalls int
+		expectedErr   error
+	}{
+		{
+			name:          "succeeded at first",
+			errs:          []error{nil},
+			timeout:       time.Minute,
+			expectedCalls: 1,
+		},
+		{
+			name:          "succeeded after webhook became available",
+			errs:          []error{webhookErr, webhookErr, nil},
+			timeout:       time.Minute,
+			expectedCalls: 3,
+		},
+		{
+			name:          "other error is not retried",
+			errs:          []error{webhookErr, otherErr, nil},
+			timeout:       time.Minute,
+			expectedCalls: 2,
+			expectedErr:   otherErr,
+		},
+		{
+			name:          "gave up after timeout",
+			errs:          []error{webhookErr, nil},
+			timeout:       time.Nanosecond,
+			expectedCalls: 1,
+			expectedErr:   ErrWebhookUnavailable,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			apply := func() error {
+				err := tc.errs[calls]
+				calls++
+				return err
+			}
+			err := retryOnWebhookUnavailable(context.Background(), tc.timeout, backoff.NewConstant(0), apply, zap.NewNop())
+			assert.Equal(t, tc.expectedCalls, calls)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tc.expectedErr))
+		})
+	}
+}
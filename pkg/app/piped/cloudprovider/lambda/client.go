@@ -15,9 +15,13 @@
 package lambda
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"time"
 
@@ -26,6 +30,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/backoff"
@@ -44,6 +49,8 @@ var ErrNotFound = errors.New("lambda resource not found")
 
 type client struct {
 	client *lambda.Client
+	// Used to upload and compute the hash of the zip archives of function code.
+	s3     *s3.Client
 	logger *zap.Logger
 }
 
@@ -87,6 +94,7 @@ func newClient(region, profile, credentialsFile, roleARN, tokenPath string, logg
 		return nil, fmt.Errorf("failed to load config to create lambda client: %w", err)
 	}
 	c.client = lambda.NewFromConfig(cfg)
+	c.s3 = s3.NewFromConfig(cfg)
 
 	return c, nil
 }
@@ -109,36 +117,131 @@ func (c *client) IsFunctionExist(ctx context.Context, name string) (bool, error)
 
 func (c *client) CreateFunction(ctx context.Context, fm FunctionManifest) error {
 	input := &lambda.CreateFunctionInput{
-		Code: &types.FunctionCode{
-			ImageUri: aws.String(fm.Spec.ImageURI),
-		},
-		PackageType:  types.PackageType("Image"),
 		Role:         aws.String(fm.Spec.Role),
 		FunctionName: aws.String(fm.Spec.Name),
+		MemorySize:   aws.Int32(fm.Spec.Memory),
+		Timeout:      aws.Int32(fm.Spec.Timeout),
 		Tags:         fm.Spec.Tags,
 		Environment: &types.Environment{
 			Variables: fm.Spec.Environments,
 		},
 	}
+	if fm.Spec.IsZipPackage() {
+		input.PackageType = types.PackageTypeZip
+		input.Code = &types.FunctionCode{
+			S3Bucket:        aws.String(fm.Spec.S3Bucket),
+			S3Key:           aws.String(fm.Spec.S3Key),
+			S3ObjectVersion: optionalString(fm.Spec.S3ObjectVersion),
+		}
+		input.Runtime = types.Runtime(fm.Spec.Runtime)
+		input.Handler = aws.String(fm.Spec.Handler)
+	} else {
+		input.PackageType = types.PackageTypeImage
+		input.Code = &types.FunctionCode{
+			ImageUri: aws.String(fm.Spec.ImageURI),
+		}
+	}
 	_, err := c.client.CreateFunction(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to create Lambda function %s: %w", fm.Spec.Name, err)
 	}
-	return nil
+	return c.updateConcurrency(ctx, fm)
 }
 
 func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error {
 	// Update function code.
 	codeInput := &lambda.UpdateFunctionCodeInput{
 		FunctionName: aws.String(fm.Spec.Name),
-		ImageUri:     aws.String(fm.Spec.ImageURI),
 	}
-	_, err := c.client.UpdateFunctionCode(ctx, codeInput)
+	if fm.Spec.IsZipPackage() {
+		codeInput.S3Bucket = aws.String(fm.Spec.S3Bucket)
+		codeInput.S3Key = aws.String(fm.Spec.S3Key)
+		codeInput.S3ObjectVersion = optionalString(fm.Spec.S3ObjectVersion)
+	} else {
+		codeInput.ImageUri = aws.String(fm.Spec.ImageURI)
+	}
+
+	unchanged, err := c.isCodeUnchanged(ctx, fm)
 	if err != nil {
+		return err
+	}
+	if unchanged {
+		c.logger.Info(fmt.Sprintf("skip updating the code of Lambda function %s since it is unchanged", fm.Spec.Name))
+	} else if _, err := c.client.UpdateFunctionCode(ctx, codeInput); err != nil {
 		return fmt.Errorf("failed to update function code for Lambda function %s: %w", fm.Spec.Name, err)
 	}
 
-	// Update function configuration.
+	return c.UpdateFunctionConfiguration(ctx, fm)
+}
+
+// isCodeUnchanged returns whether the zip archive of the given function has the same hash with the live one.
+// It always returns false for a container image since its code is updated by pulling the image again.
+func (c *client) isCodeUnchanged(ctx context.Context, fm FunctionManifest) (bool, error) {
+	if !fm.Spec.IsZipPackage() {
+		return false, nil
+	}
+	live, err := c.GetFunction(ctx, fm.Spec.Name)
+	if err != nil {
+		return false, err
+	}
+	desired := fm.Spec
+	if desired.CodeSHA256, err = c.GetCodeSHA256(ctx, fm); err != nil {
+		return false, err
+	}
+	return IsCodeUnchanged(live, desired), nil
+}
+
+// GetCodeSHA256 returns the base64-encoded SHA256 hash of the zip archive of the given function
+// in the same format with the CodeSha256 of the deployed function.
+// An empty string is returned for a container image.
+func (c *client) GetCodeSHA256(ctx context.Context, fm FunctionManifest) (string, error) {
+	if !fm.Spec.IsZipPackage() {
+		return "", nil
+	}
+	out, err := c.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String(fm.Spec.S3Bucket),
+		Key:       aws.String(fm.Spec.S3Key),
+		VersionId: optionalString(fm.Spec.S3ObjectVersion),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the zip archive s3://%s/%s of Lambda function %s: %w", fm.Spec.S3Bucket, fm.Spec.S3Key, fm.Spec.Name, err)
+	}
+	defer out.Body.Close()
+	return computeCodeSHA256(out.Body)
+}
+
+// UploadPackage uploads the given zip archive to the given S3 location.
+func (c *client) UploadPackage(ctx context.Context, bucket, key string, data []byte) error {
+	_, err := c.s3.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload the zip archive to s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func computeCodeSHA256(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to compute the hash of the zip archive: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// UpdateFunctionConfiguration updates the configuration of the function
+// such as memory, timeout, environment variables, concurrency and tags without touching its code.
+func (c *client) UpdateFunctionConfiguration(ctx context.Context, fm FunctionManifest) error {
+	var err error
 	retry := backoff.NewRetry(RequestRetryTime, backoff.NewConstant(RetryIntervalDuration))
 	updateFunctionConfigurationSucceed := false
 	for retry.WaitNext(ctx) {
@@ -150,6 +253,11 @@ func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error
 				Variables: fm.Spec.Environments,
 			},
 		}
+		// The runtime and the handler are configurable only for a zip archive.
+		if fm.Spec.IsZipPackage() {
+			configInput.Runtime = types.Runtime(fm.Spec.Runtime)
+			configInput.Handler = aws.String(fm.Spec.Handler)
+		}
 		_, err = c.client.UpdateFunctionConfiguration(ctx, configInput)
 		if err != nil {
 			c.logger.Error("Failed to update function configuration")
@@ -162,10 +270,67 @@ func (c *client) UpdateFunction(ctx context.Context, fm FunctionManifest) error
 		return fmt.Errorf("failed to update configuration for Lambda function %s: %w", fm.Spec.Name, err)
 	}
 
+	if err := c.updateConcurrency(ctx, fm); err != nil {
+		return err
+	}
+
 	// Tag/Untag function if necessary.
 	return c.updateTagsConfig(ctx, fm)
 }
 
+// GetFunction returns the live state of the given function as a manifest spec.
+// ErrNotFound is returned in case the function is not existed.
+func (c *client) GetFunction(ctx context.Context, name string) (FunctionManifestSpec, error) {
+	input := &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	}
+	out, err := c.client.GetFunction(ctx, input)
+	if err != nil {
+		var nfe *types.ResourceNotFoundException
+		if errors.As(err, &nfe) {
+			return FunctionManifestSpec{}, ErrNotFound
+		}
+		return FunctionManifestSpec{}, fmt.Errorf("failed to get Lambda function %s: %w", name, err)
+	}
+
+	spec := FunctionManifestSpec{
+		Name: name,
+		Tags: out.Tags,
+	}
+	if code := out.Code; code != nil {
+		spec.ImageURI = aws.ToString(code.ImageUri)
+	}
+	if cfg := out.Configuration; cfg != nil {
+		spec.Role = aws.ToString(cfg.Role)
+		spec.CodeSHA256 = aws.ToString(cfg.CodeSha256)
+		spec.Runtime = string(cfg.Runtime)
+		spec.Handler = aws.ToString(cfg.Handler)
+		spec.Memory = aws.ToInt32(cfg.MemorySize)
+		spec.Timeout = aws.ToInt32(cfg.Timeout)
+		if cfg.Environment != nil {
+			spec.Environments = cfg.Environment.Variables
+		}
+	}
+	if out.Concurrency != nil {
+		spec.ReservedConcurrency = out.Concurrency.ReservedConcurrentExecutions
+	}
+	return spec, nil
+}
+
+func (c *client) updateConcurrency(ctx context.Context, fm FunctionManifest) error {
+	if fm.Spec.ReservedConcurrency == nil {
+		return nil
+	}
+	input := &lambda.PutFunctionConcurrencyInput{
+		FunctionName:                 aws.String(fm.Spec.Name),
+		ReservedConcurrentExecutions: fm.Spec.ReservedConcurrency,
+	}
+	if _, err := c.client.PutFunctionConcurrency(ctx, input); err != nil {
+		return fmt.Errorf("failed to update reserved concurrency for Lambda function %s: %w", fm.Spec.Name, err)
+	}
+	return nil
+}
+
 func (c *client) PublishFunction(ctx context.Context, fm FunctionManifest) (string, error) {
 	input := &lambda.PublishVersionInput{
 		FunctionName: aws.String(fm.Spec.Name),
@@ -266,12 +431,14 @@ func (c *client) UpdateTrafficConfig(ctx context.Context, fm FunctionManifest, r
 		FunctionVersion: aws.String(primary.Version),
 	}
 
-	if secondary, ok := routingTraffic[TrafficSecondaryVersionKeyName]; ok {
-		routingTrafficMap := make(map[string]float64)
+	// The routing config is always given to clear the previous secondary version
+	// once the primary version has received all traffic.
+	routingTrafficMap := make(map[string]float64)
+	if secondary, ok := routingTraffic[TrafficSecondaryVersionKeyName]; ok && secondary.Version != primary.Version && secondary.Percent > 0 {
 		routingTrafficMap[secondary.Version] = precentToPercentage(secondary.Percent)
-		input.RoutingConfig = &types.AliasRoutingConfiguration{
-			AdditionalVersionWeights: routingTrafficMap,
-		}
+	}
+	input.RoutingConfig = &types.AliasRoutingConfiguration{
+		AdditionalVersionWeights: routingTrafficMap,
 	}
 
 	_, err := c.client.UpdateAlias(ctx, input)
@@ -17,9 +17,13 @@ package lambda
 import (
 	"fmt"
 	"io/ioutil"
+	"path"
+	"sort"
 	"strings"
 
 	"sigs.k8s.io/yaml"
+
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 const (
@@ -52,22 +56,54 @@ func (fm *FunctionManifest) validate() error {
 }
 
 // FunctionManifestSpec contains configuration for LambdaFunction.
+// The code of the function is deployed from either a container image or a zip archive stored in S3.
 type FunctionManifestSpec struct {
-	Name         string            `json:"name"`
-	Role         string            `json:"role"`
-	ImageURI     string            `json:"image"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// The URI of the container image in ECR.
+	ImageURI string `json:"image,omitempty"`
+	// The S3 bucket and the key of the zip archive of the function code.
+	S3Bucket string `json:"s3Bucket,omitempty"`
+	S3Key    string `json:"s3Key,omitempty"`
+	// The version of the S3 object.
+	// Empty means the latest version.
+	S3ObjectVersion string `json:"s3ObjectVersion,omitempty"`
+	// The runtime and the handler of the function.
+	// Required only when deploying from a zip archive.
+	Runtime      string            `json:"runtime,omitempty"`
+	Handler      string            `json:"handler,omitempty"`
 	Memory       int32             `json:"memory"`
 	Timeout      int32             `json:"timeout"`
 	Tags         map[string]string `json:"tags,omitempty"`
 	Environments map[string]string `json:"environments,omitempty"`
+	// The environment variables whose values were encrypted by the secret management of piped.
+	// They are decrypted and merged into the environment variables while syncing
+	// so that the plaintext is never stored in Git.
+	EncryptedEnvironments map[string]string `json:"encryptedEnvironments,omitempty"`
+	// The number of simultaneous executions to reserve for the function.
+	// Empty means the reserved concurrency will not be changed.
+	ReservedConcurrency *int32 `json:"reservedConcurrency,omitempty"`
+	// The base64-encoded SHA256 hash of the code.
+	// This is not a part of the manifest but is filled while comparing with the live function.
+	CodeSHA256 string `json:"-"`
+}
+
+// IsZipPackage returns whether the function code is deployed from a zip archive stored in S3.
+func (fmp FunctionManifestSpec) IsZipPackage() bool {
+	return fmp.ImageURI == ""
 }
 
 func (fmp FunctionManifestSpec) validate() error {
 	if len(fmp.Name) == 0 {
 		return fmt.Errorf("lambda function is missing")
 	}
-	if len(fmp.ImageURI) == 0 {
-		return fmt.Errorf("image uri is missing")
+	switch {
+	case fmp.ImageURI != "" && (fmp.S3Bucket != "" || fmp.S3Key != ""):
+		return fmt.Errorf("only one of image and s3 zip archive can be specified")
+	case fmp.ImageURI == "" && (fmp.S3Bucket == "" || fmp.S3Key == ""):
+		return fmt.Errorf("either image uri or s3Bucket and s3Key is required")
+	case fmp.IsZipPackage() && (fmp.Runtime == "" || fmp.Handler == ""):
+		return fmt.Errorf("runtime and handler are required to deploy from a zip archive")
 	}
 	if len(fmp.Role) == 0 {
 		return fmt.Errorf("role is missing")
@@ -78,6 +114,11 @@ func (fmp FunctionManifestSpec) validate() error {
 	if fmp.Timeout < timeoutLowerLimit || fmp.Timeout > timeoutUpperLimit {
 		return fmt.Errorf("timeout is missing or out of range")
 	}
+	for k := range fmp.EncryptedEnvironments {
+		if _, ok := fmp.Environments[k]; ok {
+			return fmt.Errorf("environment variable %s must not be specified in both environments and encryptedEnvironments", k)
+		}
+	}
 	return nil
 }
 
@@ -100,9 +141,55 @@ func parseFunctionManifest(data []byte) (FunctionManifest, error) {
 	return obj, nil
 }
 
+func parsePackagedFunctionManifest(data []byte, pkg config.LambdaPackage, commit string) (FunctionManifest, error) {
+	var obj FunctionManifest
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return FunctionManifest{}, err
+	}
+	if obj.Spec.ImageURI != "" || obj.Spec.S3Bucket != "" || obj.Spec.S3Key != "" {
+		return FunctionManifest{}, fmt.Errorf("image and s3 zip archive must not be specified while packaging the source code")
+	}
+	obj.Spec.S3Bucket = pkg.S3Bucket
+	obj.Spec.S3Key = MakePackageKey(pkg.S3KeyPrefix, obj.Spec.Name, commit)
+	if err := obj.validate(); err != nil {
+		return FunctionManifest{}, err
+	}
+	return obj, nil
+}
+
+type secretDecrypter interface {
+	Decrypt(string) (string, error)
+}
+
+// DecryptEnvironments decrypts the encrypted environment variables of the given manifest
+// and merges them into its environment variables.
+func DecryptEnvironments(fm *FunctionManifest, dcr secretDecrypter) error {
+	if len(fm.Spec.EncryptedEnvironments) == 0 {
+		return nil
+	}
+	if dcr == nil {
+		return fmt.Errorf("unable to decrypt encryptedEnvironments because no secret management was configured in piped")
+	}
+
+	envs := make(map[string]string, len(fm.Spec.Environments)+len(fm.Spec.EncryptedEnvironments))
+	for k, v := range fm.Spec.Environments {
+		envs[k] = v
+	}
+	for k, v := range fm.Spec.EncryptedEnvironments {
+		ds, err := dcr.Decrypt(v)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt environment variable %s (%w)", k, err)
+		}
+		envs[k] = ds
+	}
+	fm.Spec.Environments = envs
+	fm.Spec.EncryptedEnvironments = nil
+	return nil
+}
+
 // DecideRevisionName returns revision name to apply.
 func DecideRevisionName(fm FunctionManifest, commit string) (string, error) {
-	tag, err := FindImageTag(fm)
+	tag, err := FindArtifactVersion(fm)
 	if err != nil {
 		return "", err
 	}
@@ -114,6 +201,18 @@ func DecideRevisionName(fm FunctionManifest, commit string) (string, error) {
 	return fmt.Sprintf("%s-%s-%s", fm.Spec.Name, tag, commit), nil
 }
 
+// FindArtifactVersion returns the version of the code of the given LambdaFunction manifest.
+// It is the image tag for a container image, or the object version or the base name of the key for a zip archive.
+func FindArtifactVersion(fm FunctionManifest) (string, error) {
+	if !fm.Spec.IsZipPackage() {
+		return FindImageTag(fm)
+	}
+	if fm.Spec.S3ObjectVersion != "" {
+		return fm.Spec.S3ObjectVersion, nil
+	}
+	return strings.TrimSuffix(path.Base(fm.Spec.S3Key), ".zip"), nil
+}
+
 // FindImageTag parses image tag from given LambdaFunction manifest.
 func FindImageTag(fm FunctionManifest) (string, error) {
 	name, tag := parseContainerImage(fm.Spec.ImageURI)
@@ -132,3 +231,113 @@ func parseContainerImage(image string) (name, tag string) {
 	name = paths[len(paths)-1]
 	return
 }
+
+// IsConfigurationOnlyChange reports whether the desired function differs from the live one
+// only in its configuration (role, memory, timeout, runtime, handler, environment variables, concurrency and tags)
+// while its code is unchanged.
+// The code of a zip archive is compared by its hash, so CodeSHA256 of both specs must be filled.
+func IsConfigurationOnlyChange(live, desired FunctionManifestSpec) bool {
+	if !IsCodeUnchanged(live, desired) {
+		return false
+	}
+	if live.Role != desired.Role || live.Memory != desired.Memory || live.Timeout != desired.Timeout {
+		return true
+	}
+	if desired.IsZipPackage() && (live.Runtime != desired.Runtime || live.Handler != desired.Handler) {
+		return true
+	}
+	if !equalStringMap(live.Environments, desired.Environments) || !equalStringMap(live.Tags, desired.Tags) {
+		return true
+	}
+	if c := desired.ReservedConcurrency; c != nil && (live.ReservedConcurrency == nil || *live.ReservedConcurrency != *c) {
+		return true
+	}
+	return false
+}
+
+// IsCodeUnchanged reports whether the desired function has the same code with the live one.
+// The container images are compared by their URIs while the zip archives are compared by their hashes.
+func IsCodeUnchanged(live, desired FunctionManifestSpec) bool {
+	if live.IsZipPackage() != desired.IsZipPackage() {
+		return false
+	}
+	if !desired.IsZipPackage() {
+		return live.ImageURI == desired.ImageURI
+	}
+	return desired.CodeSHA256 != "" && live.CodeSHA256 == desired.CodeSHA256
+}
+
+// DiffFunctionSpec returns the human readable differences between the live function and the desired one.
+// The values of the environment variables are not included since they might be secrets.
+// The code of a zip archive is compared by its hash, so CodeSHA256 of both specs must be filled.
+func DiffFunctionSpec(live, desired FunctionManifestSpec) []string {
+	var diffs []string
+	if !IsCodeUnchanged(live, desired) {
+		if desired.IsZipPackage() {
+			diffs = append(diffs, fmt.Sprintf("code: the hash %q of s3://%s/%s was expected but got %q", desired.CodeSHA256, desired.S3Bucket, desired.S3Key, live.CodeSHA256))
+		} else {
+			diffs = append(diffs, fmt.Sprintf("image: %q was expected but got %q", desired.ImageURI, live.ImageURI))
+		}
+	}
+	if live.Role != desired.Role {
+		diffs = append(diffs, fmt.Sprintf("role: %q was expected but got %q", desired.Role, live.Role))
+	}
+	if live.Memory != desired.Memory {
+		diffs = append(diffs, fmt.Sprintf("memory: %d was expected but got %d", desired.Memory, live.Memory))
+	}
+	if live.Timeout != desired.Timeout {
+		diffs = append(diffs, fmt.Sprintf("timeout: %d was expected but got %d", desired.Timeout, live.Timeout))
+	}
+	if desired.IsZipPackage() {
+		if live.Runtime != desired.Runtime {
+			diffs = append(diffs, fmt.Sprintf("runtime: %q was expected but got %q", desired.Runtime, live.Runtime))
+		}
+		if live.Handler != desired.Handler {
+			diffs = append(diffs, fmt.Sprintf("handler: %q was expected but got %q", desired.Handler, live.Handler))
+		}
+	}
+	if keys := diffStringMapKeys(live.Environments, desired.Environments); len(keys) > 0 {
+		diffs = append(diffs, fmt.Sprintf("environments: the values of %s are different", strings.Join(keys, ", ")))
+	}
+	if keys := diffStringMapKeys(live.Tags, desired.Tags); len(keys) > 0 {
+		diffs = append(diffs, fmt.Sprintf("tags: the values of %s are different", strings.Join(keys, ", ")))
+	}
+	if c := desired.ReservedConcurrency; c != nil {
+		switch {
+		case live.ReservedConcurrency == nil:
+			diffs = append(diffs, fmt.Sprintf("reservedConcurrency: %d was expected but got none", *c))
+		case *live.ReservedConcurrency != *c:
+			diffs = append(diffs, fmt.Sprintf("reservedConcurrency: %d was expected but got %d", *c, *live.ReservedConcurrency))
+		}
+	}
+	return diffs
+}
+
+// diffStringMapKeys returns the sorted keys whose values are different between the given maps.
+func diffStringMapKeys(a, b map[string]string) []string {
+	var keys []string
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			keys = append(keys, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func equalStringMap(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
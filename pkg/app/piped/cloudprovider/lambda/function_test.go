@@ -15,6 +15,8 @@
 package lambda
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -90,6 +92,98 @@ func TestparseFunctionManifest(t *testing.T) {
 	  "timeout": 1000,
 	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"
   }
+}`,
+			wantSpec: FunctionManifest{},
+			wantErr:  true,
+		},
+		{
+			name: "correct config for LambdaFunction deployed from zip archive",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "s3Bucket": "pipecd-sample-lambda",
+	  "s3Key": "sample-function-code",
+	  "s3ObjectVersion": "xyz",
+	  "runtime": "nodejs14.x",
+	  "handler": "app.lambdaHandler"
+  }
+}`,
+			wantSpec: FunctionManifest{
+				Kind:       "LambdaFunction",
+				APIVersion: "pipecd.dev/v1beta1",
+				Spec: FunctionManifestSpec{
+					Name:            "SimpleFunction",
+					Role:            "arn:aws:iam::xxxxx:role/lambda-role",
+					Memory:          128,
+					Timeout:         5,
+					S3Bucket:        "pipecd-sample-lambda",
+					S3Key:           "sample-function-code",
+					S3ObjectVersion: "xyz",
+					Runtime:         "nodejs14.x",
+					Handler:         "app.lambdaHandler",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "both image and zip archive were specified",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+	  "s3Bucket": "pipecd-sample-lambda",
+	  "s3Key": "sample-function-code"
+  }
+}`,
+			wantSpec: FunctionManifest{},
+			wantErr:  true,
+		},
+		{
+			name: "missing runtime for zip archive",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "s3Bucket": "pipecd-sample-lambda",
+	  "s3Key": "sample-function-code",
+	  "handler": "app.lambdaHandler"
+  }
+}`,
+			wantSpec: FunctionManifest{},
+			wantErr:  true,
+		},
+		{
+			name: "environment variable was specified in both environments and encryptedEnvironments",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+	  "environments": {
+		  "DB_PASSWORD": "password"
+	  },
+	  "encryptedEnvironments": {
+		  "DB_PASSWORD": "encrypted-password"
+	  }
+  }
 }`,
 			wantSpec: FunctionManifest{},
 			wantErr:  true,
@@ -103,3 +197,293 @@ func TestparseFunctionManifest(t *testing.T) {
 		})
 	}
 }
+
+func TestIsConfigurationOnlyChange(t *testing.T) {
+	concurrency := func(v int32) *int32 { return &v }
+	live := FunctionManifestSpec{
+		Name:                "SimpleFunction",
+		Role:                "arn:aws:iam::xxxxx:role/lambda-role",
+		ImageURI:            "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+		Memory:              128,
+		Timeout:             5,
+		Environments:        map[string]string{"FOO": "bar"},
+		ReservedConcurrency: concurrency(10),
+	}
+	testcases := []struct {
+		name     string
+		modify   func(s *FunctionManifestSpec)
+		expected bool
+	}{
+		{
+			name:     "no change",
+			modify:   func(s *FunctionManifestSpec) {},
+			expected: false,
+		},
+		{
+			name: "image was changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.ImageURI = "ecr.region.amazonaws.com/lambda-simple-function:v0.0.2"
+			},
+			expected: false,
+		},
+		{
+			name: "memory was changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.Memory = 256
+			},
+			expected: true,
+		},
+		{
+			name: "environment variables were changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.Environments = map[string]string{"FOO": "baz"}
+			},
+			expected: true,
+		},
+		{
+			name: "concurrency was changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.ReservedConcurrency = concurrency(20)
+			},
+			expected: true,
+		},
+		{
+			name: "concurrency was not specified",
+			modify: func(s *FunctionManifestSpec) {
+				s.ReservedConcurrency = nil
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			desired := live
+			tc.modify(&desired)
+			assert.Equal(t, tc.expected, IsConfigurationOnlyChange(live, desired))
+		})
+	}
+}
+
+func TestDiffFunctionSpec(t *testing.T) {
+	concurrency := func(v int32) *int32 { return &v }
+	live := FunctionManifestSpec{
+		Name:                "SimpleFunction",
+		Role:                "arn:aws:iam::xxxxx:role/lambda-role",
+		ImageURI:            "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+		Memory:              128,
+		Timeout:             5,
+		Environments:        map[string]string{"FOO": "bar", "BAZ": "qux"},
+		ReservedConcurrency: concurrency(10),
+	}
+	testcases := []struct {
+		name     string
+		modify   func(s *FunctionManifestSpec)
+		expected []string
+	}{
+		{
+			name:   "no change",
+			modify: func(s *FunctionManifestSpec) {},
+		},
+		{
+			name: "image and memory were changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.ImageURI = "ecr.region.amazonaws.com/lambda-simple-function:v0.0.2"
+				s.Memory = 256
+			},
+			expected: []string{
+				`image: "ecr.region.amazonaws.com/lambda-simple-function:v0.0.2" was expected but got "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"`,
+				"memory: 256 was expected but got 128",
+			},
+		},
+		{
+			name: "environment variables were changed",
+			modify: func(s *FunctionManifestSpec) {
+				s.Environments = map[string]string{"FOO": "secret", "NEW": "value"}
+			},
+			expected: []string{
+				"environments: the values of BAZ, FOO, NEW are different",
+			},
+		},
+		{
+			name: "concurrency was not specified",
+			modify: func(s *FunctionManifestSpec) {
+				s.ReservedConcurrency = nil
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			desired := live
+			tc.modify(&desired)
+			assert.Equal(t, tc.expected, DiffFunctionSpec(live, desired))
+		})
+	}
+}
+
+func TestIsCodeUnchanged(t *testing.T) {
+	zip := FunctionManifestSpec{
+		S3Bucket:   "pipecd-sample-lambda",
+		S3Key:      "sample-function-code",
+		Runtime:    "nodejs14.x",
+		Handler:    "app.lambdaHandler",
+		CodeSHA256: "hash",
+	}
+	image := FunctionManifestSpec{
+		ImageURI: "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+	}
+	testcases := []struct {
+		name     string
+		live     FunctionManifestSpec
+		desired  func() FunctionManifestSpec
+		expected bool
+	}{
+		{
+			name:     "same image",
+			live:     image,
+			desired:  func() FunctionManifestSpec { return image },
+			expected: true,
+		},
+		{
+			name: "different image",
+			live: image,
+			desired: func() FunctionManifestSpec {
+				s := image
+				s.ImageURI = "ecr.region.amazonaws.com/lambda-simple-function:v0.0.2"
+				return s
+			},
+			expected: false,
+		},
+		{
+			name:     "same zip archive hash",
+			live:     zip,
+			desired:  func() FunctionManifestSpec { return zip },
+			expected: true,
+		},
+		{
+			name: "different zip archive hash",
+			live: zip,
+			desired: func() FunctionManifestSpec {
+				s := zip
+				s.CodeSHA256 = "new-hash"
+				return s
+			},
+			expected: false,
+		},
+		{
+			name: "unknown zip archive hash",
+			live: zip,
+			desired: func() FunctionManifestSpec {
+				s := zip
+				s.CodeSHA256 = ""
+				return s
+			},
+			expected: false,
+		},
+		{
+			name:     "package type was changed",
+			live:     image,
+			desired:  func() FunctionManifestSpec { return zip },
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsCodeUnchanged(tc.live, tc.desired()))
+		})
+	}
+}
+
+func TestFindArtifactVersion(t *testing.T) {
+	testcases := []struct {
+		name     string
+		spec     FunctionManifestSpec
+		expected string
+	}{
+		{
+			name:     "image tag",
+			spec:     FunctionManifestSpec{ImageURI: "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"},
+			expected: "v0.0.1",
+		},
+		{
+			name:     "s3 object version",
+			spec:     FunctionManifestSpec{S3Bucket: "bucket", S3Key: "simple/v0.0.1.zip", S3ObjectVersion: "xyz"},
+			expected: "xyz",
+		},
+		{
+			name:     "s3 key",
+			spec:     FunctionManifestSpec{S3Bucket: "bucket", S3Key: "simple/v0.0.1.zip"},
+			expected: "v0.0.1",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			version, err := FindArtifactVersion(FunctionManifest{Spec: tc.spec})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, version)
+		})
+	}
+}
+
+type fakeSecretDecrypter struct{}
+
+func (d fakeSecretDecrypter) Decrypt(text string) (string, error) {
+	if !strings.HasPrefix(text, "encrypted-") {
+		return "", fmt.Errorf("malformed encrypted text")
+	}
+	return strings.TrimPrefix(text, "encrypted-"), nil
+}
+
+func TestDecryptEnvironments(t *testing.T) {
+	testcases := []struct {
+		name      string
+		spec      FunctionManifestSpec
+		decrypter secretDecrypter
+		expected  map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "no encrypted environment variable",
+			spec: FunctionManifestSpec{
+				Environments: map[string]string{"FOO": "bar"},
+			},
+			expected: map[string]string{"FOO": "bar"},
+		},
+		{
+			name: "merged into environment variables",
+			spec: FunctionManifestSpec{
+				Environments:          map[string]string{"FOO": "bar"},
+				EncryptedEnvironments: map[string]string{"DB_PASSWORD": "encrypted-password"},
+			},
+			decrypter: fakeSecretDecrypter{},
+			expected:  map[string]string{"FOO": "bar", "DB_PASSWORD": "password"},
+		},
+		{
+			name: "no secret management",
+			spec: FunctionManifestSpec{
+				EncryptedEnvironments: map[string]string{"DB_PASSWORD": "encrypted-password"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "failed to decrypt",
+			spec: FunctionManifestSpec{
+				EncryptedEnvironments: map[string]string{"DB_PASSWORD": "password"},
+			},
+			decrypter: fakeSecretDecrypter{},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm := FunctionManifest{Spec: tc.spec}
+			err := DecryptEnvironments(&fm, tc.decrypter)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, fm.Spec.Environments)
+			assert.Empty(t, fm.Spec.EncryptedEnvironments)
+		})
+	}
+}
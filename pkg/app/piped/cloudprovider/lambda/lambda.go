@@ -16,6 +16,7 @@ package lambda
 
 import (
 	"context"
+	"io/ioutil"
 	"path/filepath"
 	"sync"
 
@@ -30,6 +31,10 @@ type Client interface {
 	IsFunctionExist(ctx context.Context, name string) (bool, error)
 	CreateFunction(ctx context.Context, fm FunctionManifest) error
 	UpdateFunction(ctx context.Context, fm FunctionManifest) error
+	UpdateFunctionConfiguration(ctx context.Context, fm FunctionManifest) error
+	GetFunction(ctx context.Context, name string) (FunctionManifestSpec, error)
+	GetCodeSHA256(ctx context.Context, fm FunctionManifest) (string, error)
+	UploadPackage(ctx context.Context, bucket, key string, data []byte) error
 	PublishFunction(ctx context.Context, fm FunctionManifest) (version string, err error)
 	GetTrafficConfig(ctx context.Context, fm FunctionManifest) (routingTrafficCfg RoutingTrafficConfig, err error)
 	CreateTrafficConfig(ctx context.Context, fm FunctionManifest, version string) error
@@ -47,6 +52,17 @@ func LoadFunctionManifest(appDir, functionManifestFilename string) (FunctionMani
 	return loadFunctionManifest(path)
 }
 
+// LoadPackagedFunctionManifest returns FunctionManifest object from a given Function config manifest file
+// whose code is the zip archive packaged from the source code at the given commit.
+func LoadPackagedFunctionManifest(appDir, functionManifestFilename string, pkg config.LambdaPackage, commit string) (FunctionManifest, error) {
+	path := filepath.Join(appDir, functionManifestFilename)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return FunctionManifest{}, err
+	}
+	return parsePackagedFunctionManifest(data, pkg, commit)
+}
+
 type registry struct {
 	clients  map[string]Client
 	mu       sync.RWMutex
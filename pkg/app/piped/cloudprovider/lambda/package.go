@@ -0,0 +1,95 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// The modification time set to all files in the zip archive.
+// A fixed value is used so that the same source code always produces the same archive,
+// and its hash can be used to know whether the code was changed.
+var packageModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MakePackageKey returns the S3 key of the zip archive packaged from the source code at the given commit.
+func MakePackageKey(prefix, functionName, commit string) string {
+	return path.Join(prefix, functionName, commit+".zip")
+}
+
+// PackageSource zips all files under the given directory.
+// The paths in the archive are relative to the directory.
+func PackageSource(dir string) ([]byte, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find source directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("source %s is not a directory", dir)
+	}
+
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	// Walk visits the files in lexical order, so the order in the archive is stable.
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir || !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Modified = packageModTime
+		if info.IsDir() {
+			header.Name += "/"
+			_, err = w.CreateHeader(header)
+			return err
+		}
+		header.Method = zip.Deflate
+
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to package source directory %s: %w", dir, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to package source directory %s: %w", dir, err)
+	}
+	return buf.Bytes(), nil
+}
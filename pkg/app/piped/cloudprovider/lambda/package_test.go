@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestPackageSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lambda-package")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte("exports.handler = async () => {}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "lib", "util.js"), []byte("module.exports = {}"), 0644))
+
+	data, err := PackageSource(dir)
+	require.NoError(t, err)
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	names := make([]string, 0, len(r.File))
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"app.js", "lib/", "lib/util.js"}, names)
+
+	// The same source code must produce the same archive regardless of the modification time.
+	future := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "app.js"), future, future))
+	again, err := PackageSource(dir)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	_, err = PackageSource(filepath.Join(dir, "not-found"))
+	assert.Error(t, err)
+}
+
+func TestParsePackagedFunctionManifest(t *testing.T) {
+	pkg := config.LambdaPackage{
+		Source:      "src",
+		S3Bucket:    "pipecd-lambda-packages",
+		S3KeyPrefix: "simple",
+	}
+	testcases := []struct {
+		name     string
+		data     string
+		wantSpec FunctionManifestSpec
+		wantErr  bool
+	}{
+		{
+			name: "code location is filled",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "runtime": "nodejs14.x",
+	  "handler": "app.handler"
+  }
+}`,
+			wantSpec: FunctionManifestSpec{
+				Name:     "SimpleFunction",
+				Role:     "arn:aws:iam::xxxxx:role/lambda-role",
+				Memory:   128,
+				Timeout:  5,
+				Runtime:  "nodejs14.x",
+				Handler:  "app.handler",
+				S3Bucket: "pipecd-lambda-packages",
+				S3Key:    "simple/SimpleFunction/0123456789.zip",
+			},
+		},
+		{
+			name: "image was specified",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "image": "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1"
+  }
+}`,
+			wantErr: true,
+		},
+		{
+			name: "missing handler",
+			data: `{
+  "apiVersion": "pipecd.dev/v1beta1",
+  "kind": "LambdaFunction",
+  "spec": {
+	  "name": "SimpleFunction",
+	  "role": "arn:aws:iam::xxxxx:role/lambda-role",
+	  "memory": 128,
+	  "timeout": 5,
+	  "runtime": "nodejs14.x"
+  }
+}`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fm, err := parsePackagedFunctionManifest([]byte(tc.data), pkg, "0123456789")
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantSpec, fm.Spec)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const tokenHeader = "X-Nomad-Token"
+
+// client calls Nomad HTTP API directly
+// since there is no Nomad API client in the dependencies.
+type client struct {
+	address    string
+	region     string
+	token      string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+func newClient(address, region, tokenFile string, logger *zap.Logger) (Client, error) {
+	if address == "" {
+		address = DefaultAddress
+	}
+	c := &client{
+		address:    strings.TrimSuffix(address, "/"),
+		region:     region,
+		httpClient: http.DefaultClient,
+		logger:     logger.Named("nomad"),
+	}
+	if tokenFile != "" {
+		data, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read token file (%w)", err)
+		}
+		c.token = strings.TrimSpace(string(data))
+	}
+	return c, nil
+}
+
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *client) ParseJob(ctx context.Context, hcl string) (Job, error) {
+	in := map[string]interface{}{
+		"JobHCL":       hcl,
+		"Canonicalize": true,
+	}
+	var job Job
+	if err := c.call(ctx, http.MethodPost, "/v1/jobs/parse", "", in, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse job: %w", err)
+	}
+	return job, nil
+}
+
+func (c *client) PlanJob(ctx context.Context, job Job) (*PlanResult, error) {
+	in := map[string]interface{}{
+		"Job":  job,
+		"Diff": true,
+	}
+	out := &PlanResult{}
+	path := fmt.Sprintf("/v1/job/%s/plan", url.PathEscape(job.ID()))
+	if err := c.call(ctx, http.MethodPost, path, job.Namespace(), in, out); err != nil {
+		return nil, fmt.Errorf("failed to plan job: %w", err)
+	}
+	return out, nil
+}
+
+func (c *client) RegisterJob(ctx context.Context, job Job) (uint64, error) {
+	in := map[string]interface{}{
+		"Job": job,
+	}
+	var out struct {
+		EvalID         string
+		JobModifyIndex uint64
+		Warnings       string
+	}
+	if err := c.call(ctx, http.MethodPost, "/v1/jobs", job.Namespace(), in, &out); err != nil {
+		return 0, fmt.Errorf("failed to register job: %w", err)
+	}
+	if out.Warnings != "" {
+		c.logger.Warn("job was registered with warnings", zap.String("job", job.ID()), zap.String("warnings", out.Warnings))
+	}
+	return out.JobModifyIndex, nil
+}
+
+func (c *client) LatestDeployment(ctx context.Context, namespace, jobID string) (*Deployment, error) {
+	var d *Deployment
+	path := fmt.Sprintf("/v1/job/%s/deployment", url.PathEscape(jobID))
+	err := c.call(ctx, http.MethodGet, path, namespace, nil, &d)
+	if e, ok := err.(*apiError); ok && e.StatusCode == http.StatusNotFound {
+		return nil, ErrDeploymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Nomad returns null when the job has no deployment.
+	if d == nil {
+		return nil, ErrDeploymentNotFound
+	}
+	return d, nil
+}
+
+func (c *client) PromoteDeployment(ctx context.Context, namespace, deploymentID string) error {
+	in := map[string]interface{}{
+		"DeploymentID": deploymentID,
+		"All":          true,
+	}
+	path := fmt.Sprintf("/v1/deployment/promote/%s", url.PathEscape(deploymentID))
+	if err := c.call(ctx, http.MethodPost, path, namespace, in, nil); err != nil {
+		return fmt.Errorf("failed to promote deployment: %w", err)
+	}
+	return nil
+}
+
+func (c *client) FailDeployment(ctx context.Context, namespace, deploymentID string) error {
+	path := fmt.Sprintf("/v1/deployment/fail/%s", url.PathEscape(deploymentID))
+	if err := c.call(ctx, http.MethodPost, path, namespace, nil, nil); err != nil {
+		return fmt.Errorf("failed to fail deployment: %w", err)
+	}
+	return nil
+}
+
+func (c *client) call(ctx context.Context, method, path, namespace string, input, output interface{}) error {
+	var body bytes.Buffer
+	if input != nil {
+		if err := json.NewEncoder(&body).Encode(input); err != nil {
+			return err
+		}
+	}
+
+	query := url.Values{}
+	if namespace != "" {
+		query.Set("namespace", namespace)
+	}
+	if c.region != "" {
+		query.Set("region", c.region)
+	}
+	u := c.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &apiError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if output == nil {
+		return nil
+	}
+	return json.Unmarshal(data, output)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestClientRegisterJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/jobs", r.URL.Path)
+		assert.Equal(t, "apps", r.URL.Query().Get("namespace"))
+		assert.Equal(t, "asia", r.URL.Query().Get("region"))
+		assert.Equal(t, "secret", r.Header.Get(tokenHeader))
+
+		var in struct{ Job Job }
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		assert.Equal(t, "web", in.Job.ID())
+
+		w.Write([]byte(`{"EvalID": "eval", "JobModifyIndex": 42}`))
+	}))
+	defer server.Close()
+
+	c := &client{
+		address:    server.URL,
+		region:     "asia",
+		token:      "secret",
+		httpClient: http.DefaultClient,
+		logger:     zap.NewNop(),
+	}
+	index, err := c.RegisterJob(context.Background(), Job{"ID": "web", "Namespace": "apps"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), index)
+}
+
+func TestClientLatestDeployment(t *testing.T) {
+	testcases := []struct {
+		name    string
+		status  int
+		body    string
+		want    *Deployment
+		wantErr error
+	}{
+		{
+			name:   "found",
+			status: http.StatusOK,
+			body:   `{"ID": "d1", "JobID": "web", "JobModifyIndex": 42, "Status": "running"}`,
+			want:   &Deployment{ID: "d1", JobID: "web", JobModifyIndex: 42, Status: DeploymentStatusRunning},
+		},
+		{
+			name:    "job has no deployment",
+			status:  http.StatusOK,
+			body:    `null`,
+			wantErr: ErrDeploymentNotFound,
+		},
+		{
+			name:    "job not found",
+			status:  http.StatusNotFound,
+			body:    `job not found`,
+			wantErr: ErrDeploymentNotFound,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/job/web/deployment", r.URL.Path)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			c := &client{address: server.URL, httpClient: http.DefaultClient, logger: zap.NewNop()}
+			got, err := c.LatestDeployment(context.Background(), "", "web")
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
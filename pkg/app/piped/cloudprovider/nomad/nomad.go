@@ -0,0 +1,271 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	DefaultJobFilename = "job.nomad"
+	DefaultAddress     = "http://127.0.0.1:4646"
+)
+
+// The statuses of deployment.
+const (
+	DeploymentStatusRunning    = "running"
+	DeploymentStatusPaused     = "paused"
+	DeploymentStatusFailed     = "failed"
+	DeploymentStatusSuccessful = "successful"
+	DeploymentStatusCancelled  = "cancelled"
+)
+
+var (
+	ErrDeploymentNotFound = errors.New("deployment not found")
+
+	imageRegex = regexp.MustCompile(`"?image"?\s*[=:]\s*"([^"]+)"`)
+)
+
+// Client is wrapper of Nomad HTTP API.
+type Client interface {
+	// ParseJob converts the given HCL job specification into the JSON one.
+	ParseJob(ctx context.Context, hcl string) (Job, error)
+	// PlanJob shows the changes will be made by running the given job.
+	PlanJob(ctx context.Context, job Job) (*PlanResult, error)
+	// RegisterJob runs the given job and returns the modify index of the registered job.
+	RegisterJob(ctx context.Context, job Job) (uint64, error)
+	// LatestDeployment returns the most recent deployment of the given job.
+	LatestDeployment(ctx context.Context, namespace, jobID string) (*Deployment, error)
+	// PromoteDeployment promotes the canaries of all task groups in the given deployment.
+	PromoteDeployment(ctx context.Context, namespace, deploymentID string) error
+	// FailDeployment marks the given deployment as failed to stop placing the new allocations.
+	FailDeployment(ctx context.Context, namespace, deploymentID string) error
+}
+
+// Registry holds a pool of Nomad clients.
+type Registry interface {
+	Client(name string, cfg *config.CloudProviderNomadConfig, logger *zap.Logger) (Client, error)
+}
+
+// Job is the Nomad job in the JSON format of the HTTP API.
+// It is kept as a map to pass through the fields piped does not care about.
+type Job map[string]interface{}
+
+// ID returns the ID of the job.
+func (j Job) ID() string {
+	id, _ := j["ID"].(string)
+	return id
+}
+
+// Namespace returns the namespace of the job.
+func (j Job) Namespace() string {
+	ns, _ := j["Namespace"].(string)
+	return ns
+}
+
+// PlanResult represents the result of planning a job.
+type PlanResult struct {
+	JobModifyIndex uint64
+	Annotations    *PlanAnnotations
+	Diff           *JobDiff
+	FailedTGAllocs map[string]interface{}
+	Warnings       string
+}
+
+// JobDiff represents the difference between the running job and the planned one.
+type JobDiff struct {
+	// The type of the difference. One of Added, Deleted, Edited or None.
+	Type string
+}
+
+// PlanAnnotations contains the updates desired for each task group.
+type PlanAnnotations struct {
+	DesiredTGUpdates map[string]*DesiredUpdates
+}
+
+// DesiredUpdates represents the numbers of the allocations changed in a task group.
+type DesiredUpdates struct {
+	Ignore            uint64
+	Place             uint64
+	Migrate           uint64
+	Stop              uint64
+	InPlaceUpdate     uint64
+	DestructiveUpdate uint64
+	Canary            uint64
+}
+
+// HasNoChanges returns whether the planned job is same as the running one.
+func (p *PlanResult) HasNoChanges() bool {
+	return p.Diff != nil && p.Diff.Type == "None"
+}
+
+// Summary returns the changes of the allocations for each task group.
+func (p *PlanResult) Summary() []string {
+	if p.Annotations == nil {
+		return nil
+	}
+	groups := make([]string, 0, len(p.Annotations.DesiredTGUpdates))
+	for g := range p.Annotations.DesiredTGUpdates {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	out := make([]string, 0, len(groups))
+	for _, g := range groups {
+		u := p.Annotations.DesiredTGUpdates[g]
+		out = append(out, fmt.Sprintf("%s: %d place, %d in-place update, %d destructive update, %d canary, %d stop, %d ignore",
+			g, u.Place, u.InPlaceUpdate, u.DestructiveUpdate, u.Canary, u.Stop, u.Ignore))
+	}
+	return out
+}
+
+// Deployment represents a Nomad deployment created by registering a job with the update stanza.
+type Deployment struct {
+	ID                string
+	JobID             string
+	JobVersion        uint64
+	JobModifyIndex    uint64
+	Status            string
+	StatusDescription string
+	TaskGroups        map[string]*DeploymentState
+}
+
+// DeploymentState represents the state of a task group in a deployment.
+type DeploymentState struct {
+	AutoPromote     bool
+	Promoted        bool
+	DesiredCanaries int
+	DesiredTotal    int
+	PlacedCanaries  []string
+	PlacedAllocs    int
+	HealthyAllocs   int
+	UnhealthyAllocs int
+}
+
+// IsTerminal returns whether the deployment will not make any progress anymore.
+func (d *Deployment) IsTerminal() bool {
+	switch d.Status {
+	case DeploymentStatusSuccessful, DeploymentStatusFailed, DeploymentStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// RequiresPromotion returns whether any task group has canaries waiting for being promoted manually.
+func (d *Deployment) RequiresPromotion() bool {
+	for _, s := range d.TaskGroups {
+		if s.DesiredCanaries > 0 && !s.Promoted && !s.AutoPromote {
+			return true
+		}
+	}
+	return false
+}
+
+// CanariesHealthy returns whether all canaries desired in the deployment are healthy.
+// Before being promoted, the healthy allocations of a task group are its canaries.
+func (d *Deployment) CanariesHealthy() bool {
+	for _, s := range d.TaskGroups {
+		if s.DesiredCanaries == 0 || s.Promoted {
+			continue
+		}
+		if s.HealthyAllocs < s.DesiredCanaries {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadJob loads the job specification placing in the application directory.
+// HCL job specification is returned as is to be parsed by Nomad
+// since its syntax depends on the version of the Nomad cluster.
+func LoadJob(appDir, jobFilename string) (data []byte, isJSON bool, err error) {
+	if jobFilename == "" {
+		jobFilename = DefaultJobFilename
+	}
+	path := filepath.Join(appDir, jobFilename)
+	data, err = ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, strings.EqualFold(filepath.Ext(jobFilename), ".json"), nil
+}
+
+// FindImageTag returns the tag of the first container image specified in the given job specification.
+func FindImageTag(data []byte) (string, error) {
+	m := imageRegex.FindSubmatch(data)
+	if m == nil {
+		return "", fmt.Errorf("container image was not found")
+	}
+	image := string(m[1])
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:], nil
+	}
+	if i := strings.LastIndex(image, ":"); i >= 0 && !strings.Contains(image[i:], "/") {
+		return image[i+1:], nil
+	}
+	return "latest", nil
+}
+
+type registry struct {
+	clients  map[string]Client
+	mu       sync.RWMutex
+	newGroup *singleflight.Group
+}
+
+func (r *registry) Client(name string, cfg *config.CloudProviderNomadConfig, logger *zap.Logger) (Client, error) {
+	r.mu.RLock()
+	client, ok := r.clients[name]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	c, err, _ := r.newGroup.Do(name, func() (interface{}, error) {
+		return newClient(cfg.Address, cfg.Region, cfg.TokenFile, logger)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client = c.(Client)
+	r.mu.Lock()
+	r.clients[name] = client
+	r.mu.Unlock()
+
+	return client, nil
+}
+
+var defaultRegistry = &registry{
+	clients:  make(map[string]Client),
+	newGroup: &singleflight.Group{},
+}
+
+// DefaultRegistry returns a pool of Nomad clients.
+func DefaultRegistry() Registry {
+	return defaultRegistry
+}
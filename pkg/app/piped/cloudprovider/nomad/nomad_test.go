@@ -0,0 +1,157 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindImageTag(t *testing.T) {
+	testcases := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "hcl job",
+			data: `
+job "web" {
+  group "web" {
+    task "server" {
+      driver = "docker"
+      config {
+        image = "gcr.io/pipecd/web:v1.2.0"
+      }
+    }
+  }
+}`,
+			want: "v1.2.0",
+		},
+		{
+			name: "json job",
+			data: `{"Job": {"TaskGroups": [{"Tasks": [{"Config": {"image": "pipecd/web:v0.1.0"}}]}]}}`,
+			want: "v0.1.0",
+		},
+		{
+			name: "image with digest",
+			data: `image = "pipecd/web@sha256:abcd"`,
+			want: "sha256:abcd",
+		},
+		{
+			name: "image from registry with port and no tag",
+			data: `image = "localhost:5000/pipecd/web"`,
+			want: "latest",
+		},
+		{
+			name:    "no image",
+			data:    `command = "/bin/server"`,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := FindImageTag([]byte(tc.data))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDeploymentCanaries(t *testing.T) {
+	testcases := []struct {
+		name                  string
+		deployment            *Deployment
+		wantRequiresPromotion bool
+		wantCanariesHealthy   bool
+	}{
+		{
+			name: "no canary",
+			deployment: &Deployment{
+				TaskGroups: map[string]*DeploymentState{
+					"web": {DesiredTotal: 3, HealthyAllocs: 1},
+				},
+			},
+			wantCanariesHealthy: true,
+		},
+		{
+			name: "canaries are being placed",
+			deployment: &Deployment{
+				TaskGroups: map[string]*DeploymentState{
+					"web": {DesiredCanaries: 2, DesiredTotal: 3, HealthyAllocs: 1},
+				},
+			},
+			wantRequiresPromotion: true,
+		},
+		{
+			name: "canaries are healthy",
+			deployment: &Deployment{
+				TaskGroups: map[string]*DeploymentState{
+					"web": {DesiredCanaries: 2, DesiredTotal: 3, HealthyAllocs: 2},
+					"api": {DesiredTotal: 1},
+				},
+			},
+			wantRequiresPromotion: true,
+			wantCanariesHealthy:   true,
+		},
+		{
+			name: "canaries are promoted automatically",
+			deployment: &Deployment{
+				TaskGroups: map[string]*DeploymentState{
+					"web": {AutoPromote: true, DesiredCanaries: 1, DesiredTotal: 3},
+				},
+			},
+			wantRequiresPromotion: false,
+		},
+		{
+			name: "canaries were promoted",
+			deployment: &Deployment{
+				TaskGroups: map[string]*DeploymentState{
+					"web": {Promoted: true, DesiredCanaries: 1, DesiredTotal: 3, HealthyAllocs: 0},
+				},
+			},
+			wantCanariesHealthy: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantRequiresPromotion, tc.deployment.RequiresPromotion())
+			assert.Equal(t, tc.wantCanariesHealthy, tc.deployment.CanariesHealthy())
+		})
+	}
+}
+
+func TestPlanResultSummary(t *testing.T) {
+	p := &PlanResult{
+		Annotations: &PlanAnnotations{
+			DesiredTGUpdates: map[string]*DesiredUpdates{
+				"web": {Canary: 1, Ignore: 3},
+				"api": {DestructiveUpdate: 2},
+			},
+		},
+	}
+	want := []string{
+		"api: 0 place, 0 in-place update, 2 destructive update, 0 canary, 0 stop, 0 ignore",
+		"web: 0 place, 0 in-place update, 0 destructive update, 1 canary, 0 stop, 3 ignore",
+	}
+	assert.Equal(t, want, p.Summary())
+	assert.Nil(t, (&PlanResult{}).Summary())
+
+	assert.True(t, (&PlanResult{Diff: &JobDiff{Type: "None"}}).HasNoChanges())
+	assert.False(t, (&PlanResult{Diff: &JobDiff{Type: "Edited"}}).HasNoChanges())
+	assert.False(t, (&PlanResult{}).HasNoChanges())
+}
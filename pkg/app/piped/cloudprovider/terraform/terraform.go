@@ -120,12 +120,19 @@ type PlanResult struct {
 	Adds     int
 	Changes  int
 	Destroys int
+	// Whether terraform detected that the resources have been changed outside of terraform.
+	Drifted bool
 }
 
 func (r PlanResult) NoChanges() bool {
 	return r.Adds == 0 && r.Changes == 0 && r.Destroys == 0
 }
 
+// Summary returns the numbers of changes in the same format as terraform shows.
+func (r PlanResult) Summary() string {
+	return fmt.Sprintf("%d to add, %d to change, %d to destroy", r.Adds, r.Changes, r.Destroys)
+}
+
 func GetExitCode(err error) int {
 	if err == nil {
 		return 0
@@ -156,7 +163,7 @@ func (t *Terraform) Plan(ctx context.Context, w io.Writer) (PlanResult, error) {
 	err := cmd.Run()
 	switch GetExitCode(err) {
 	case 0:
-		return PlanResult{}, nil
+		return PlanResult{Drifted: isDrifted(buf.String(), !t.options.noColor)}, nil
 	case 2:
 		return parsePlanResult(buf.String(), !t.options.noColor)
 	default:
@@ -180,6 +187,7 @@ func (t *Terraform) makeCommonCommandArgs() (args []string) {
 var (
 	planHasChangeRegex = regexp.MustCompile(`(?m)^Plan: (\d+) to add, (\d+) to change, (\d+) to destroy.$`)
 	planNoChangesRegex = regexp.MustCompile(`(?m)^No changes. Infrastructure is up-to-date.$`)
+	planDriftedRegex   = regexp.MustCompile(`(?m)^Note: Objects have changed outside of Terraform$`)
 )
 
 // Borrowed from https://github.com/acarl005/stripansi
@@ -212,6 +220,8 @@ func parsePlanResult(out string, ansiIncluded bool) (PlanResult, error) {
 		out = stripAnsiCodes(out)
 	}
 
+	drifted := planDriftedRegex.MatchString(out)
+
 	if s := planHasChangeRegex.FindStringSubmatch(out); len(s) == 4 {
 		adds, changes, destroys, err := parseNums(s[1], s[2], s[3])
 		if err == nil {
@@ -219,17 +229,25 @@ func parsePlanResult(out string, ansiIncluded bool) (PlanResult, error) {
 				Adds:     adds,
 				Changes:  changes,
 				Destroys: destroys,
+				Drifted:  drifted,
 			}, nil
 		}
 	}
 
 	if s := planNoChangesRegex.FindStringSubmatch(out); len(s) > 0 {
-		return PlanResult{}, nil
+		return PlanResult{Drifted: drifted}, nil
 	}
 
 	return PlanResult{}, fmt.Errorf("unable to parse plan output")
 }
 
+func isDrifted(out string, ansiIncluded bool) bool {
+	if ansiIncluded {
+		out = stripAnsiCodes(out)
+	}
+	return planDriftedRegex.MatchString(out)
+}
+
 func (t *Terraform) Apply(ctx context.Context, w io.Writer) error {
 	args := []string{
 		"apply",
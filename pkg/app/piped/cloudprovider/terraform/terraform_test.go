@@ -13,3 +13,73 @@
 // limitations under the License.
 
 package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlanResult(t *testing.T) {
+	testcases := []struct {
+		name     string
+		out      string
+		expected PlanResult
+		wantErr  bool
+	}{
+		{
+			name: "has changes",
+			out: `
+An execution plan has been generated and is shown below.
+
+Plan: 1 to add, 2 to change, 3 to destroy.
+`,
+			expected: PlanResult{Adds: 1, Changes: 2, Destroys: 3},
+		},
+		{
+			name: "has changes with drift",
+			out: `
+Note: Objects have changed outside of Terraform
+
+Terraform detected the following changes made outside of Terraform since the
+last "terraform apply":
+
+Plan: 0 to add, 1 to change, 0 to destroy.
+`,
+			expected: PlanResult{Changes: 1, Drifted: true},
+		},
+		{
+			name: "no changes",
+			out: `
+No changes. Infrastructure is up-to-date.
+`,
+			expected: PlanResult{},
+		},
+		{
+			name:     "ansi codes included",
+			out:      "\u001b[1mPlan:\u001b[0m 1 to add, 0 to change, 0 to destroy.\n",
+			expected: PlanResult{Adds: 1},
+		},
+		{
+			name:    "unexpected output",
+			out:     "Error: Invalid reference",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parsePlanResult(tc.out, true)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestPlanResultSummary(t *testing.T) {
+	r := PlanResult{Adds: 1, Changes: 2, Destroys: 3}
+	require.Equal(t, "1 to add, 2 to change, 3 to destroy", r.Summary())
+}
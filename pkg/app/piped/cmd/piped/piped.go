@@ -18,8 +18,10 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path"
@@ -46,30 +48,38 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/eventstore"
 	"github.com/pipe-cd/pipe/pkg/app/piped/chartrepo"
 	k8scloudprovidermetrics "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/kubernetesmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/configreloader"
 	"github.com/pipe-cd/pipe/pkg/app/piped/controller"
 	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector"
 	"github.com/pipe-cd/pipe/pkg/app/piped/eventwatcher"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis/analysismetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/plugin"
+	executorregistry "github.com/pipe-cd/pipe/pkg/app/piped/executor/registry"
 	"github.com/pipe-cd/pipe/pkg/app/piped/livestatereporter"
 	"github.com/pipe-cd/pipe/pkg/app/piped/livestatestore"
 	k8slivestatestoremetrics "github.com/pipe-cd/pipe/pkg/app/piped/livestatestore/kubernetes/kubernetesmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/logsink"
 	"github.com/pipe-cd/pipe/pkg/app/piped/notifier"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planpreview"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planpreview/planpreviewmetrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/sharder"
+	"github.com/pipe-cd/pipe/pkg/app/piped/sourcedecrypter"
 	"github.com/pipe-cd/pipe/pkg/app/piped/statsreporter"
 	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
 	"github.com/pipe-cd/pipe/pkg/app/piped/trigger"
+	"github.com/pipe-cd/pipe/pkg/app/piped/upgrader"
 	"github.com/pipe-cd/pipe/pkg/cache/memorycache"
 	"github.com/pipe-cd/pipe/pkg/cli"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/crypto"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
+	"github.com/pipe-cd/pipe/pkg/oci"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcauth"
 	"github.com/pipe-cd/pipe/pkg/rpc/rpcclient"
 	"github.com/pipe-cd/pipe/pkg/version"
 
-	// Import to preload all built-in executors to the default registry.
-	_ "github.com/pipe-cd/pipe/pkg/app/piped/executor/registry"
 	// Import to preload all planners to the default registry.
 	_ "github.com/pipe-cd/pipe/pkg/app/piped/planner/registry"
 )
@@ -78,14 +88,21 @@ type piped struct {
 	configFile      string
 	configData      string
 	configGCPSecret string
+	// How often to reload the configuration from the file or the GCP secret.
+	configReloadInterval time.Duration
 
 	insecure                             bool
 	certFile                             string
 	adminPort                            int
 	toolsDir                             string
+	toolsGCInterval                      time.Duration
+	toolsMaxVersions                     int
+	toolsMaxTotalSizeMB                  int64
+	toolsMaxUnusedDuration               time.Duration
 	enableDefaultKubernetesCloudProvider bool
 	gracePeriod                          time.Duration
 	addLoginUserToPasswd                 bool
+	replicaID                            string
 }
 
 func NewCommand() *cobra.Command {
@@ -94,9 +111,10 @@ func NewCommand() *cobra.Command {
 		panic(fmt.Sprintf("failed to detect the current user's home directory: %v", err))
 	}
 	p := &piped{
-		adminPort:   9085,
-		toolsDir:    path.Join(home, ".piped", "tools"),
-		gracePeriod: 30 * time.Second,
+		adminPort:       9085,
+		toolsDir:        path.Join(home, ".piped", "tools"),
+		toolsGCInterval: time.Hour,
+		gracePeriod:     30 * time.Second,
 	}
 	cmd := &cobra.Command{
 		Use:   "piped",
@@ -107,20 +125,48 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().StringVar(&p.configFile, "config-file", p.configFile, "The path to the configuration file.")
 	cmd.Flags().StringVar(&p.configData, "config-data", p.configData, "The configuration data in YAML/JSON format.")
 	cmd.Flags().StringVar(&p.configGCPSecret, "config-gcp-secret", p.configGCPSecret, "The resource ID of secret that contains Piped config and be stored in GCP SecretManager.")
+	cmd.Flags().DurationVar(&p.configReloadInterval, "config-reload-interval", p.configReloadInterval, "How often to reload the repositories, analysis providers and notifications from the configuration file or the GCP secret without restarting. Zero means disabled.")
 
 	cmd.Flags().BoolVar(&p.insecure, "insecure", p.insecure, "Whether disabling transport security while connecting to control-plane.")
 	cmd.Flags().StringVar(&p.certFile, "cert-file", p.certFile, "The path to the TLS certificate file.")
 	cmd.Flags().IntVar(&p.adminPort, "admin-port", p.adminPort, "The port number used to run a HTTP server for admin tasks such as metrics, healthz.")
 
-	cmd.Flags().StringVar(&p.toolsDir, "tools-dir", p.toolsDir, "The path to directory where to install needed tools such as kubectl, helm, kustomize.")
+	cmd.Flags().StringVar(&p.toolsDir, "tools-dir", p.toolsDir, "The path to directory where to install needed tools such as kubectl, helm, kustomize. It can be shared by multiple pipeds.")
+	cmd.Flags().DurationVar(&p.toolsGCInterval, "tools-gc-interval", p.toolsGCInterval, "How often to remove the unused tool versions from the tools directory.")
+	cmd.Flags().IntVar(&p.toolsMaxVersions, "tools-max-versions", p.toolsMaxVersions, "Maximum number of versions should be kept for each tool. Zero means unlimited.")
+	cmd.Flags().Int64Var(&p.toolsMaxTotalSizeMB, "tools-max-total-size-mb", p.toolsMaxTotalSizeMB, "Maximum total size in megabytes of the tools directory. Zero means unlimited.")
+	cmd.Flags().DurationVar(&p.toolsMaxUnusedDuration, "tools-max-unused-duration", p.toolsMaxUnusedDuration, "The tool versions that have not been used in this duration will be removed. Zero means unlimited.")
 	cmd.Flags().BoolVar(&p.enableDefaultKubernetesCloudProvider, "enable-default-kubernetes-cloud-provider", p.enableDefaultKubernetesCloudProvider, "Whether the default kubernetes provider is enabled or not.")
 	cmd.Flags().BoolVar(&p.addLoginUserToPasswd, "add-login-user-to-passwd", p.addLoginUserToPasswd, "Whether to add login user to $HOME/passwd. This is typically for applications running as a random user ID.")
 	cmd.Flags().DurationVar(&p.gracePeriod, "grace-period", p.gracePeriod, "How long to wait for graceful shutdown.")
+	cmd.Flags().StringVar(&p.replicaID, "replica-id", p.replicaID, "The unique ID of this replica among the ones sharing the same piped ID. Default is the hostname. This is used only when sharding is configured.")
 
 	return cmd
 }
 
-func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
+func (p *piped) run(ctx context.Context, t cli.Telemetry) error {
+	// Restart with the upgraded binary only after all components
+	// have been stopped and cleaned up by runComponents.
+	run := func() (string, error) {
+		var upgradedBinary string
+		err := p.runComponents(ctx, t, &upgradedBinary)
+		return upgradedBinary, err
+	}
+	restart := func(binaryPath string) error {
+		t.Logger.Info(fmt.Sprintf("restarting piped with %s", binaryPath))
+		if err := upgrader.Restart(binaryPath); err != nil {
+			t.Logger.Error("failed to restart piped with the upgraded binary", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	return upgrader.RunWithRestart(run, restart)
+}
+
+// runComponents runs all components of piped until the given context is done
+// or one of them stops. The path of the binary to restart with is set to
+// upgradedBinary when the upgrader has downloaded the desired version.
+func (p *piped) runComponents(ctx context.Context, t cli.Telemetry, upgradedBinary *string) error {
 	group, ctx := errgroup.WithContext(ctx)
 	if p.addLoginUserToPasswd {
 		if err := p.insertLoginUserToPasswd(ctx); err != nil {
@@ -148,6 +194,14 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		return notifier.Run(ctx)
 	})
 
+	// Initialize the sinks to stream the stage logs to the external logging systems.
+	// They are run by the log persister of the deployment controller.
+	logSink, err := logsink.NewStreamer(cfg, t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to initialize log sinks", zap.Error(err))
+		return err
+	}
+
 	// Configure SSH config if needed.
 	if cfg.Git.ShouldConfigureSSHConfig() {
 		if err := git.AddSSHConfig(cfg.Git); err != nil {
@@ -158,11 +212,58 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 	}
 
 	// Initialize default tool registry.
-	if err := toolregistry.InitDefaultRegistry(p.toolsDir, t.Logger); err != nil {
+	if err := toolregistry.InitDefaultRegistry(p.toolsDir, t.Logger, toolRegistryOptions(cfg.Tools)...); err != nil {
 		t.Logger.Error("failed to initialize default tool registry", zap.Error(err))
 		return err
 	}
 
+	// Start installing the configured tools in advance.
+	if len(cfg.Tools.Preinstall) > 0 {
+		tools := make([]toolregistry.Tool, 0, len(cfg.Tools.Preinstall))
+		for _, tool := range cfg.Tools.Preinstall {
+			tools = append(tools, toolregistry.Tool{Name: tool.Name, Version: tool.Version})
+		}
+		group.Go(func() error {
+			return toolregistry.Preinstall(ctx, tools)
+		})
+	}
+
+	// Make the key material for sops available if configured.
+	if cfg.Sops != nil {
+		if err := sourcedecrypter.ConfigureSops(*cfg.Sops); err != nil {
+			t.Logger.Error("failed to configure sops", zap.Error(err))
+			return err
+		}
+		t.Logger.Info("successfully configured sops")
+	}
+
+	// Make the secrets stored in Vault available if configured.
+	if cfg.Vault != nil {
+		if err := sourcedecrypter.ConfigureVault(*cfg.Vault); err != nil {
+			t.Logger.Error("failed to configure vault", zap.Error(err))
+			return err
+		}
+		t.Logger.Info("successfully configured vault")
+	}
+
+	// Configure the client used to pull the application manifests from OCI registries.
+	if len(cfg.OCIRegistries) > 0 {
+		opts, err := ociClientOptions(cfg.OCIRegistries)
+		if err != nil {
+			t.Logger.Error("failed to configure OCI registries", zap.Error(err))
+			return err
+		}
+		oci.InitDefaultClient(append(opts, oci.WithLogger(t.Logger))...)
+		t.Logger.Info("successfully configured OCI registries")
+	}
+
+	// Start removing the unused tool versions if configured.
+	if policy := p.toolsGCPolicy(); !policy.IsEmpty() {
+		group.Go(func() error {
+			return toolregistry.RunGC(ctx, policy, p.toolsGCInterval)
+		})
+	}
+
 	// Add configured Helm chart repositories.
 	if len(cfg.ChartRepositories) > 0 {
 		reg := toolregistry.DefaultRegistry()
@@ -170,7 +271,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			t.Logger.Error("failed to add configured chart repositories", zap.Error(err))
 			return err
 		}
-		if len(cfg.ChartRepositories) > 0 {
+		if chartrepo.HasHTTPRepository(cfg.ChartRepositories) {
 			if err := chartrepo.Update(ctx, reg, t.Logger); err != nil {
 				t.Logger.Error("failed to update Helm chart repositories", zap.Error(err))
 				return err
@@ -178,6 +279,23 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		}
 	}
 
+	// Log in to the OCI registries of chart repositories by using the credentials of cloud providers.
+	refresher, err := chartrepo.NewCredentialsRefresher(cfg, toolregistry.DefaultRegistry(), t.Logger)
+	if err != nil {
+		t.Logger.Error("failed to create chart repository credentials refresher", zap.Error(err))
+		return err
+	}
+	if !refresher.IsEmpty() {
+		next, err := refresher.Login(ctx)
+		if err != nil {
+			t.Logger.Error("failed to log in to chart repositories by cloud provider credentials", zap.Error(err))
+			return err
+		}
+		group.Go(func() error {
+			return refresher.Run(ctx, next)
+		})
+	}
+
 	pipedKey, err := cfg.LoadPipedKey()
 	if err != nil {
 		t.Logger.Error("failed to load piped key", zap.Error(err))
@@ -197,6 +315,22 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		return err
 	}
 
+	// Start reloading the configuration to apply its changes without restarting.
+	// The components looking up the configuration every time, such as the executors
+	// finding the analysis providers, use the reloaded one automatically.
+	if p.configReloadInterval > 0 && p.configData == "" {
+		r := configreloader.NewReloader(cfg, p.loadConfig, p.configReloadInterval, t.Logger)
+		r.Register("notifier", func(_ context.Context, cfg *config.PipedSpec) error {
+			return notifier.Reload(cfg)
+		})
+		r.Register("piped-meta", func(ctx context.Context, cfg *config.PipedSpec) error {
+			return p.sendPipedMeta(ctx, apiClient, cfg, t.Logger)
+		})
+		group.Go(func() error {
+			return r.Run(ctx)
+		})
+	}
+
 	// Start running admin server.
 	{
 		var (
@@ -211,6 +345,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			w.Write([]byte("ok"))
 		})
 		admin.Handle("/metrics", t.PrometheusMetricsHandlerFor(registry))
+		admin.Handle("/analysis/evaluate", analysis.NewEvaluationHandler(cfg, t.Logger))
 
 		group.Go(func() error {
 			return admin.Run(ctx)
@@ -226,8 +361,15 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		})
 	}
 
+	// Prepare the credentials of the repositories configured individually.
+	gitOptions, err := gitClientOptions(cfg.Repositories)
+	if err != nil {
+		t.Logger.Error("failed to configure credentials of git repositories", zap.Error(err))
+		return err
+	}
+
 	// Initialize git client.
-	gitClient, err := git.NewClient(cfg.Git.Username, cfg.Git.Email, t.Logger)
+	gitClient, err := git.NewClient(cfg.Git.Username, cfg.Git.Email, t.Logger, gitOptions...)
 	if err != nil {
 		t.Logger.Error("failed to initialize git client", zap.Error(err))
 		return err
@@ -287,6 +429,27 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		eventGetter = store.Getter()
 	}
 
+	// Start sharding the applications across the replicas of this piped.
+	// The components except plan-preview see only the applications handled by this replica.
+	allApplicationLister := applicationLister
+	if cfg.Sharding != nil {
+		replicaID := p.replicaID
+		if replicaID == "" {
+			if replicaID, err = os.Hostname(); err != nil {
+				t.Logger.Error("failed to get hostname as the replica ID", zap.Error(err))
+				return err
+			}
+		}
+		s := sharder.NewSharder(apiClient, applicationLister, deploymentLister, cfg.Sharding, replicaID, t.Logger)
+		group.Go(func() error {
+			return s.Run(ctx)
+		})
+		applicationLister = s.ApplicationLister(applicationLister)
+		deploymentLister = s.DeploymentLister(deploymentLister)
+		commandLister = s.CommandLister(commandLister)
+		eventGetter = s.EventGetter(eventGetter)
+	}
+
 	analysisResultStore := analysisresultstore.NewStore(apiClient, t.Logger)
 
 	// Create memory caches.
@@ -333,6 +496,22 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		})
 	}
 
+	// Register the stages provided by the executor plugins
+	// before the deployment controller starts executing them.
+	for _, pc := range cfg.ExecutorPlugins {
+		ep, err := plugin.Connect(ctx, pc, t.Logger)
+		if err != nil {
+			t.Logger.Error("failed to connect to executor plugin", zap.String("plugin", pc.Name), zap.Error(err))
+			return err
+		}
+		defer ep.Close()
+
+		if err := executorregistry.RegisterPlugin(ep); err != nil {
+			t.Logger.Error("failed to register the stages of executor plugin", zap.String("plugin", pc.Name), zap.Error(err))
+			return err
+		}
+	}
+
 	// Start running deployment controller.
 	{
 		c := controller.NewController(
@@ -345,6 +524,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			livestatestore.LiveResourceLister{Getter: liveStateGetter},
 			analysisResultStore,
 			notifier,
+			logSink,
 			decrypter,
 			cfg,
 			appManifestsCache,
@@ -355,6 +535,19 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		group.Go(func() error {
 			return c.Run(ctx)
 		})
+
+		// Start upgrading piped to the version desired by the control plane.
+		// The in-flight deployments are drained by the controller before restarting.
+		if cfg.Upgrade != nil {
+			u := upgrader.NewUpgrader(apiClient, c, cfg.Upgrade, version.Get().Version, t.Logger)
+			group.Go(func() error {
+				err := u.Run(ctx)
+				if errors.Is(err, upgrader.ErrRestartRequired) {
+					*upgradedBinary = u.BinaryPath()
+				}
+				return err
+			})
+		}
 	}
 
 	// Start running deployment trigger.
@@ -380,6 +573,26 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 		group.Go(func() error {
 			return tr.Run(ctx)
 		})
+
+		// Start receiving the push events to trigger the deployments immediately.
+		if wc := cfg.TriggerWebhook; wc != nil {
+			data, err := os.ReadFile(wc.SecretFile)
+			if err != nil {
+				t.Logger.Error("failed to read secret of trigger webhook", zap.Error(err))
+				return err
+			}
+			// Anyone could sign the payloads if the secret was empty.
+			secret := bytes.TrimSpace(data)
+			if len(secret) == 0 {
+				t.Logger.Error("secret of trigger webhook must not be empty", zap.String("secret-file", wc.SecretFile))
+				return fmt.Errorf("secret of trigger webhook must not be empty")
+			}
+			server := admin.NewAdmin(wc.ListenPort(), p.gracePeriod, t.Logger.Named("trigger-webhook"))
+			server.Handle("/webhook", tr.WebhookHandler(secret))
+			group.Go(func() error {
+				return server.Run(ctx)
+			})
+		}
 	}
 
 	// Start running event watcher.
@@ -399,7 +612,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 	{
 		// Initialize a dedicated git client for plan-preview feature.
 		// Basically, this feature is an utility so it should not share any resource with the main components of piped.
-		gc, err := git.NewClient(cfg.Git.Username, cfg.Git.Email, t.Logger)
+		gc, err := git.NewClient(cfg.Git.Username, cfg.Git.Email, t.Logger, gitOptions...)
 		if err != nil {
 			t.Logger.Error("failed to initialize git client for plan-preview", zap.Error(err))
 			return err
@@ -416,7 +629,7 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 			gc,
 			apiClient,
 			commandLister,
-			applicationLister,
+			allApplicationLister,
 			environmentStore,
 			lastTriggeredCommitGetter,
 			decrypter,
@@ -434,6 +647,9 @@ func (p *piped) run(ctx context.Context, t cli.Telemetry) (runErr error) {
 	// could trigger the finish of piped.
 	// This ensures that all components are good or no one.
 	if err := group.Wait(); err != nil {
+		if errors.Is(err, upgrader.ErrRestartRequired) {
+			return nil
+		}
 		t.Logger.Error("failed while running", zap.Error(err))
 		return err
 	}
@@ -473,6 +689,81 @@ func (p *piped) createAPIClient(ctx context.Context, address, projectID, pipedID
 	return client, nil
 }
 
+// toolsGCPolicy returns the policy for removing the installed tools from the flags.
+func (p *piped) toolsGCPolicy() toolregistry.GCPolicy {
+	return toolregistry.GCPolicy{
+		MaxVersionsPerTool: p.toolsMaxVersions,
+		MaxTotalSize:       p.toolsMaxTotalSizeMB * 1024 * 1024,
+		MaxUnusedDuration:  p.toolsMaxUnusedDuration,
+	}
+}
+
+// toolRegistryOptions converts the tools configuration into the options of the tool registry.
+// The proxy was already validated while loading the configuration.
+func toolRegistryOptions(cfg config.PipedTools) []toolregistry.Option {
+	var opts []toolregistry.Option
+	if cfg.Proxy != "" {
+		if proxy, err := url.Parse(cfg.Proxy); err == nil {
+			opts = append(opts, toolregistry.WithHTTPProxy(proxy))
+		}
+	}
+	for _, m := range cfg.Mirrors {
+		opts = append(opts, toolregistry.WithMirror(m.Name, m.URL))
+	}
+	for _, c := range cfg.Checksums {
+		opts = append(opts, toolregistry.WithChecksum(c.Name, c.Version, c.SHA256))
+	}
+	for _, d := range cfg.Definitions {
+		opts = append(opts, toolregistry.WithTool(d.Name, d.Version, d.URL))
+		if d.SHA256 != "" {
+			opts = append(opts, toolregistry.WithChecksum(d.Name, d.Version, d.SHA256))
+		}
+	}
+	return opts
+}
+
+// gitClientOptions converts the credentials of the repositories into the options of the git client.
+// The token source of a GitHub App is shared by all git clients to reuse the issued tokens.
+func gitClientOptions(repos []config.PipedRepository) ([]git.Option, error) {
+	var opts []git.Option
+	for _, r := range repos {
+		if r.SSHKeyFile != "" {
+			opts = append(opts, git.WithSSHKeyFile(r.RepoID, r.SSHKeyFile))
+		}
+		if app := r.GitHubApp; app != nil {
+			key, err := os.ReadFile(app.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read private key of GitHub App for repository %s (%w)", r.RepoID, err)
+			}
+			ts, err := git.NewGitHubAppTokenSource(app.AppID, app.InstallationID, key, app.APIURL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid GitHub App for repository %s (%w)", r.RepoID, err)
+			}
+			opts = append(opts, git.WithTokenSource(r.RepoID, ts))
+		}
+	}
+	return opts, nil
+}
+
+// ociClientOptions converts the registries configuration into the options of the OCI client.
+func ociClientOptions(registries []config.PipedOCIRegistry) ([]oci.Option, error) {
+	var opts []oci.Option
+	for _, r := range registries {
+		if r.Insecure {
+			opts = append(opts, oci.WithInsecureRegistry(r.Address))
+		}
+		if r.PasswordFile == "" {
+			continue
+		}
+		password, err := os.ReadFile(r.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read password file of OCI registry %s (%w)", r.Address, err)
+		}
+		opts = append(opts, oci.WithBasicAuth(r.Address, r.Username, strings.TrimSpace(string(password))))
+	}
+	return opts, nil
+}
+
 // loadConfig reads the Piped configuration data from the specified source.
 func (p *piped) loadConfig(ctx context.Context) (*config.PipedSpec, error) {
 	if p.configFile != "" && p.configGCPSecret != "" {
@@ -555,8 +846,9 @@ func (p *piped) initializeSecretDecrypter(cfg *config.PipedSpec) (crypto.Decrypt
 }
 
 func (p *piped) sendPipedMeta(ctx context.Context, client pipedservice.Client, cfg *config.PipedSpec, logger *zap.Logger) error {
-	repos := make([]*model.ApplicationGitRepository, 0, len(cfg.Repositories))
-	for _, r := range cfg.Repositories {
+	configuredRepos := cfg.GetRepositories()
+	repos := make([]*model.ApplicationGitRepository, 0, len(configuredRepos))
+	for _, r := range configuredRepos {
 		repos = append(repos, &model.ApplicationGitRepository{
 			Id:     r.RepoID,
 			Remote: r.Remote,
@@ -702,6 +994,7 @@ func registerMetrics(pipedID, projectID string) *prometheus.Registry {
 	k8scloudprovidermetrics.Register(wrapped)
 	k8slivestatestoremetrics.Register(wrapped)
 	planpreviewmetrics.Register(wrapped)
+	analysismetrics.Register(wrapped)
 
 	return r
 }
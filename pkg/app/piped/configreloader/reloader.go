@@ -0,0 +1,130 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configreloader provides a piped component
+// that periodically reloads the piped configuration from its source
+// to apply the changes without restarting piped.
+package configreloader
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// LoadFunc loads the latest piped configuration from its source.
+type LoadFunc func(ctx context.Context) (*config.PipedSpec, error)
+
+// Handler applies the reloaded configuration to a piped component.
+// The given configuration is the one shared by all components.
+type Handler func(ctx context.Context, cfg *config.PipedSpec) error
+
+type namedHandler struct {
+	name    string
+	handler Handler
+}
+
+type Reloader struct {
+	config   *config.PipedSpec
+	load     LoadFunc
+	interval time.Duration
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	handlers []namedHandler
+	// Whether the loaded configuration had any change requiring restart last time.
+	restartRequired bool
+}
+
+func NewReloader(cfg *config.PipedSpec, load LoadFunc, interval time.Duration, logger *zap.Logger) *Reloader {
+	return &Reloader{
+		config:   cfg,
+		load:     load,
+		interval: interval,
+		logger:   logger.Named("config-reloader"),
+	}
+}
+
+// Register adds a handler called with the configuration every time it has been changed.
+// The components looking up the configuration every time they use it do not need any handler.
+func (r *Reloader) Register(name string, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, namedHandler{name: name, handler: h})
+}
+
+func (r *Reloader) Run(ctx context.Context) error {
+	r.logger.Info("start running config reloader", zap.Duration("interval", r.interval))
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ctx.Done():
+			break L
+
+		case <-ticker.C:
+			r.reload(ctx)
+		}
+	}
+
+	r.logger.Info("config reloader has been stopped")
+	return nil
+}
+
+// reload loads the configuration and applies it if it has been changed.
+// The current configuration keeps being used if the loaded one is invalid.
+func (r *Reloader) reload(ctx context.Context) {
+	cfg, err := r.load(ctx)
+	if err != nil {
+		r.logger.Error("failed to load piped configuration, keep using the current one", zap.Error(err))
+		return
+	}
+
+	restart, err := r.config.RequiresRestart(cfg)
+	if err != nil {
+		r.logger.Error("failed to compare piped configuration", zap.Error(err))
+		return
+	}
+	if restart && !r.restartRequired {
+		r.logger.Warn("piped configuration has the changes which are applied only after restarting piped")
+	}
+	r.restartRequired = restart
+
+	changed := r.config.Reload(cfg)
+	if len(changed) == 0 {
+		return
+	}
+	r.logger.Info("reloaded piped configuration", zap.String("changed", strings.Join(changed, ",")))
+
+	r.mu.Lock()
+	handlers := r.handlers
+	r.mu.Unlock()
+
+	for _, h := range handlers {
+		if err := h.handler(ctx, r.config); err != nil {
+			r.logger.Error("failed to apply the reloaded piped configuration",
+				zap.String("component", h.name),
+				zap.Error(err),
+			)
+		}
+	}
+}
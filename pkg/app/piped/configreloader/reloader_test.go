@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configreloader
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestReloaderReload(t *testing.T) {
+	cfg := &config.PipedSpec{
+		PipedID: "piped-id",
+		Repositories: []config.PipedRepository{
+			{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "master"},
+		},
+	}
+
+	var (
+		next     *config.PipedSpec
+		loadErr  error
+		reloaded int
+	)
+	load := func(_ context.Context) (*config.PipedSpec, error) {
+		return next, loadErr
+	}
+	r := NewReloader(cfg, load, 0, zap.NewNop())
+	r.Register("test", func(_ context.Context, c *config.PipedSpec) error {
+		assert.Same(t, cfg, c)
+		reloaded++
+		return nil
+	})
+	ctx := context.Background()
+
+	// Nothing has been changed.
+	next = &config.PipedSpec{
+		PipedID:      "piped-id",
+		Repositories: cfg.GetRepositories(),
+	}
+	r.reload(ctx)
+	assert.Equal(t, 0, reloaded)
+
+	// A repository has been added.
+	next = &config.PipedSpec{
+		PipedID: "piped-id",
+		Repositories: []config.PipedRepository{
+			{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "master"},
+			{RepoID: "repo-2", Remote: "git@github.com:org/repo-2.git", Branch: "main"},
+		},
+	}
+	r.reload(ctx)
+	assert.Equal(t, 1, reloaded)
+	assert.Len(t, cfg.GetRepositories(), 2)
+
+	// The current configuration is kept if failed to load.
+	next, loadErr = nil, errors.New("invalid configuration")
+	r.reload(ctx)
+	assert.Equal(t, 1, reloaded)
+	assert.Len(t, cfg.GetRepositories(), 2)
+
+	// The changes requiring restart are not applied.
+	next, loadErr = &config.PipedSpec{
+		PipedID:      "new-piped-id",
+		Repositories: cfg.GetRepositories(),
+	}, nil
+	r.reload(ctx)
+	assert.Equal(t, 1, reloaded)
+	assert.True(t, r.restartRequired)
+	assert.Equal(t, "piped-id", cfg.PipedID)
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// concurrencyPolicy returns the concurrency policy recorded in the given deployment when it was triggered.
+func concurrencyPolicy(d *model.Deployment) config.ConcurrencyPolicy {
+	if p := d.Metadata[model.MetadataKeyConcurrencyPolicy]; p != "" {
+		return config.ConcurrencyPolicy(p)
+	}
+	return config.ConcurrencyPolicyQueue
+}
+
+// selectPendingDeployment chooses the deployment to be planned next from the pending deployments of an application,
+// and returns the ones superseded by it which should be skipped.
+// The concurrency policy of the most recently triggered one is used since it reflects the latest configuration.
+func selectPendingDeployment(pendings []*model.Deployment) (target *model.Deployment, superseded []*model.Deployment) {
+	if len(pendings) == 0 {
+		return nil, nil
+	}
+
+	oldest, newest := pendings[0], pendings[0]
+	for _, d := range pendings[1:] {
+		if d.TriggerBefore(oldest) {
+			oldest = d
+		}
+		if newest.TriggerBefore(d) {
+			newest = d
+		}
+	}
+
+	switch concurrencyPolicy(newest) {
+	case config.ConcurrencyPolicyCancelInProgress, config.ConcurrencyPolicySkipIntermediate:
+		for _, d := range pendings {
+			if d != newest {
+				superseded = append(superseded, d)
+			}
+		}
+		return newest, superseded
+	default:
+		return oldest, nil
+	}
+}
+
+// shouldCancelInProgress reports whether the planning or running deployment
+// should be cancelled because it was superseded by the given pending one.
+func shouldCancelInProgress(inProgress, pending *model.Deployment) bool {
+	if concurrencyPolicy(pending) != config.ConcurrencyPolicyCancelInProgress {
+		return false
+	}
+	return inProgress.Id != pending.Id && inProgress.TriggerBefore(pending)
+}
+
+// supersedingCommander returns the name shown as the one who cancelled the superseded deployments.
+func supersedingCommander(newer *model.Deployment) string {
+	return fmt.Sprintf("the newer deployment %s", newer.Id)
+}
+
+// supersededCancelCommand builds a command to cancel the given deployment
+// in the same way as the one sent from the web page.
+func supersededCancelCommand(d, newer *model.Deployment) model.ReportableCommand {
+	return model.ReportableCommand{
+		Command: &model.Command{
+			PipedId:       d.PipedId,
+			ApplicationId: d.ApplicationId,
+			DeploymentId:  d.Id,
+			Commander:     supersedingCommander(newer),
+			Type:          model.Command_CANCEL_DEPLOYMENT,
+			CancelDeployment: &model.Command_CancelDeployment{
+				DeploymentId: d.Id,
+			},
+		},
+		// There is nothing to report since this command was not sent via control-plane.
+		Report: func(context.Context, model.CommandStatus, map[string]string, []byte) error {
+			return nil
+		},
+	}
+}
+
+// skipSupersededDeployment marks the given pending deployment as cancelled without planning it
+// since the newer deployment of the same application will be deployed instead.
+func (c *controller) skipSupersededDeployment(ctx context.Context, d, newer *model.Deployment) {
+	var (
+		err error
+		now = time.Now()
+		req = &pipedservice.ReportDeploymentCompletedRequest{
+			DeploymentId:  d.Id,
+			Status:        model.DeploymentStatus_DEPLOYMENT_CANCELLED,
+			StatusReason:  fmt.Sprintf("Skipped because it was superseded by the newer deployment %s", newer.Id),
+			StageStatuses: nil,
+			CompletedAt:   now.Unix(),
+		}
+		retry  = pipedservice.NewRetry(3)
+		logger = c.logger.With(
+			zap.String("deployment", d.Id),
+			zap.String("app", d.ApplicationId),
+			zap.String("newer-deployment", newer.Id),
+		)
+	)
+
+	for retry.WaitNext(ctx) {
+		if _, err = c.apiClient.ReportDeploymentCompleted(ctx, req); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		logger.Error("failed to skip the superseded deployment", zap.Error(err))
+		return
+	}
+	logger.Info("skipped the superseded deployment")
+	c.donePlanners[d.Id] = now
+
+	// We only need the environment name
+	// so the returned error can be ignorable.
+	var envName string
+	if env, err := c.environmentLister.Get(ctx, d.EnvId); err == nil {
+		envName = env.Name
+	}
+	c.notifier.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_CANCELLED,
+		Metadata: &model.NotificationEventDeploymentCancelled{
+			Deployment: d,
+			EnvName:    envName,
+			Commander:  supersedingCommander(newer),
+		},
+	})
+}
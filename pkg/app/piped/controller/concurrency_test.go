@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func newPendingDeployment(id string, commitCreatedAt int64, policy config.ConcurrencyPolicy) *model.Deployment {
+	d := &model.Deployment{
+		Id:            id,
+		ApplicationId: "app",
+		Trigger: &model.DeploymentTrigger{
+			Commit: &model.Commit{
+				CreatedAt: commitCreatedAt,
+			},
+			Timestamp: commitCreatedAt,
+		},
+	}
+	if policy != "" {
+		d.Metadata = map[string]string{
+			model.MetadataKeyConcurrencyPolicy: string(policy),
+		}
+	}
+	return d
+}
+
+func TestSelectPendingDeployment(t *testing.T) {
+	testcases := []struct {
+		name           string
+		pendings       []*model.Deployment
+		wantTarget     string
+		wantSuperseded []string
+	}{
+		{
+			name:       "no policy means queue",
+			pendings:   []*model.Deployment{newPendingDeployment("d2", 2, ""), newPendingDeployment("d1", 1, ""), newPendingDeployment("d3", 3, "")},
+			wantTarget: "d1",
+		},
+		{
+			name:       "queue",
+			pendings:   []*model.Deployment{newPendingDeployment("d2", 2, config.ConcurrencyPolicyQueue), newPendingDeployment("d1", 1, config.ConcurrencyPolicyQueue)},
+			wantTarget: "d1",
+		},
+		{
+			name:           "skip intermediate",
+			pendings:       []*model.Deployment{newPendingDeployment("d2", 2, ""), newPendingDeployment("d3", 3, config.ConcurrencyPolicySkipIntermediate), newPendingDeployment("d1", 1, "")},
+			wantTarget:     "d3",
+			wantSuperseded: []string{"d2", "d1"},
+		},
+		{
+			name:           "cancel in progress",
+			pendings:       []*model.Deployment{newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress), newPendingDeployment("d2", 2, config.ConcurrencyPolicyCancelInProgress)},
+			wantTarget:     "d2",
+			wantSuperseded: []string{"d1"},
+		},
+		{
+			name:       "policy of the newest one is used",
+			pendings:   []*model.Deployment{newPendingDeployment("d1", 1, config.ConcurrencyPolicySkipIntermediate), newPendingDeployment("d2", 2, config.ConcurrencyPolicyQueue)},
+			wantTarget: "d1",
+		},
+		{
+			name:       "single pending",
+			pendings:   []*model.Deployment{newPendingDeployment("d1", 1, config.ConcurrencyPolicySkipIntermediate)},
+			wantTarget: "d1",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, superseded := selectPendingDeployment(tc.pendings)
+			assert.Equal(t, tc.wantTarget, target.Id)
+			ids := make([]string, 0, len(superseded))
+			for _, d := range superseded {
+				ids = append(ids, d.Id)
+			}
+			assert.ElementsMatch(t, tc.wantSuperseded, ids)
+		})
+	}
+}
+
+func TestShouldCancelInProgress(t *testing.T) {
+	testcases := []struct {
+		name       string
+		inProgress *model.Deployment
+		pending    *model.Deployment
+		expected   bool
+	}{
+		{
+			name:       "superseded by the newer one",
+			inProgress: newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress),
+			pending:    newPendingDeployment("d2", 2, config.ConcurrencyPolicyCancelInProgress),
+			expected:   true,
+		},
+		{
+			name:       "newer one is not configured to cancel",
+			inProgress: newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress),
+			pending:    newPendingDeployment("d2", 2, config.ConcurrencyPolicySkipIntermediate),
+			expected:   false,
+		},
+		{
+			name:       "pending one is older",
+			inProgress: newPendingDeployment("d2", 2, ""),
+			pending:    newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress),
+			expected:   false,
+		},
+		{
+			name:       "same deployment",
+			inProgress: newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress),
+			pending:    newPendingDeployment("d1", 1, config.ConcurrencyPolicyCancelInProgress),
+			expected:   false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := shouldCancelInProgress(tc.inProgress, tc.pending)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
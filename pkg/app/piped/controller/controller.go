@@ -28,6 +28,7 @@ import (
 	"sync"
 	"time"
 
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -48,6 +49,7 @@ type apiClient interface {
 	ReportDeploymentPlanned(ctx context.Context, req *pipedservice.ReportDeploymentPlannedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentPlannedResponse, error)
 	ReportDeploymentStatusChanged(ctx context.Context, req *pipedservice.ReportDeploymentStatusChangedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentStatusChangedResponse, error)
 	ReportDeploymentCompleted(ctx context.Context, req *pipedservice.ReportDeploymentCompletedRequest, opts ...grpc.CallOption) (*pipedservice.ReportDeploymentCompletedResponse, error)
+	CreateDeploymentChain(ctx context.Context, req *pipedservice.CreateDeploymentChainRequest, opts ...grpc.CallOption) (*pipedservice.CreateDeploymentChainResponse, error)
 	SaveDeploymentMetadata(ctx context.Context, req *pipedservice.SaveDeploymentMetadataRequest, opts ...grpc.CallOption) (*pipedservice.SaveDeploymentMetadataResponse, error)
 	ReportApplicationMostRecentDeployment(ctx context.Context, req *pipedservice.ReportApplicationMostRecentDeploymentRequest, opts ...grpc.CallOption) (*pipedservice.ReportApplicationMostRecentDeploymentResponse, error)
 
@@ -55,10 +57,13 @@ type apiClient interface {
 	SaveStageMetadata(ctx context.Context, req *pipedservice.SaveStageMetadataRequest, opts ...grpc.CallOption) (*pipedservice.SaveStageMetadataResponse, error)
 	ReportStageLogs(ctx context.Context, req *pipedservice.ReportStageLogsRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsResponse, error)
 	ReportStageLogsFromLastCheckpoint(ctx context.Context, in *pipedservice.ReportStageLogsFromLastCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error)
+
+	RegisterEvent(ctx context.Context, req *pipedservice.RegisterEventRequest, opts ...grpc.CallOption) (*pipedservice.RegisterEventResponse, error)
 }
 
 type gitClient interface {
 	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+	Worktree(ctx context.Context, repoID, remote, revision, destination string) (git.Repo, error)
 }
 
 type deploymentLister interface {
@@ -82,6 +87,7 @@ type environmentLister interface {
 
 type liveResourceLister interface {
 	ListKubernetesAppLiveResources(cloudProvider, appID string) ([]provider.Manifest, bool)
+	ListKubernetesAppLiveDependedResources(cloudProvider, appID string) ([]provider.Manifest, bool)
 }
 
 type analysisResultStore interface {
@@ -99,6 +105,11 @@ type secretDecrypter interface {
 
 type DeploymentController interface {
 	Run(ctx context.Context) error
+	// Drain stops planning the new deployments and blocks until
+	// all deployments being planned or running have been completed.
+	Drain(ctx context.Context) error
+	// Undrain resumes planning the new deployments.
+	Undrain()
 }
 
 var (
@@ -120,6 +131,7 @@ type controller struct {
 	pipedConfig         *config.PipedSpec
 	appManifestsCache   cache.Cache
 	logPersister        logpersister.Persister
+	stageBudgetTracker  *stageBudgetTracker
 
 	// Map from application ID to the planner
 	// of a pending deployment of that application.
@@ -139,6 +151,10 @@ type controller struct {
 	mostRecentlySuccessfulCommits map[string]string
 	// WaitGroup for waiting the completions of all planners, schedulers.
 	wg sync.WaitGroup
+	// Whether the new deployments should not be planned.
+	draining atomic.Bool
+	// Whether there was no planner and scheduler at the last sync.
+	idle atomic.Bool
 
 	workspaceDir string
 	syncInternal time.Duration
@@ -157,6 +173,7 @@ func NewController(
 	liveResourceLister liveResourceLister,
 	analysisResultStore analysisResultStore,
 	notifier notifier,
+	logSink logpersister.Sink,
 	sd secretDecrypter,
 	pipedConfig *config.PipedSpec,
 	appManifestsCache cache.Cache,
@@ -165,7 +182,7 @@ func NewController(
 ) DeploymentController {
 
 	var (
-		lp = logpersister.NewPersister(apiClient, logger)
+		lp = logpersister.NewPersister(apiClient, logSink, logger)
 		lg = logger.Named("controller")
 	)
 	return &controller{
@@ -182,6 +199,7 @@ func NewController(
 		appManifestsCache:   appManifestsCache,
 		pipedConfig:         pipedConfig,
 		logPersister:        lp,
+		stageBudgetTracker:  newStageBudgetTracker(),
 
 		planners:                      make(map[string]*planner),
 		donePlanners:                  make(map[string]time.Time),
@@ -238,6 +256,7 @@ L:
 			c.syncSchedulers(ctx)
 			c.syncPlanners(ctx)
 			c.checkCommands()
+			c.idle.Store(len(c.planners) == 0 && len(c.schedulers) == 0)
 		}
 	}
 
@@ -289,6 +308,37 @@ func (c *controller) checkCommands() {
 	}
 }
 
+// Drain stops planning the new deployments and blocks until
+// all deployments being planned or running have been completed.
+func (c *controller) Drain(ctx context.Context) error {
+	c.logger.Info("start draining the in-flight deployments")
+	c.draining.Store(true)
+	// Wait for the next sync to see the planners and schedulers
+	// which were started before draining.
+	c.idle.Store(false)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if c.idle.Load() {
+				c.logger.Info("all in-flight deployments have been completed")
+				return nil
+			}
+		}
+	}
+}
+
+// Undrain resumes planning the new deployments.
+func (c *controller) Undrain() {
+	c.draining.Store(false)
+	c.logger.Info("resumed planning the new deployments")
+}
+
 // syncPlanners adds new planner for newly PENDING deployments.
 func (c *controller) syncPlanners(ctx context.Context) error {
 	// Remove stale planners from the recently completed list.
@@ -323,6 +373,12 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 		}
 	}
 
+	// The pending deployments are left to be planned after draining
+	// or by the next piped, e.g. the upgraded one.
+	if c.draining.Load() {
+		return nil
+	}
+
 	// Add missing planners.
 	pendings := c.deploymentLister.ListPendings()
 	if len(pendings) == 0 {
@@ -333,9 +389,8 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 		zap.Int("count", len(c.planners)),
 	)
 
-	pendingByApp := make(map[string]*model.Deployment, len(pendings))
+	pendingsByApp := make(map[string][]*model.Deployment, len(pendings))
 	for _, d := range pendings {
-		appID := d.ApplicationId
 		// Ignore already processed one.
 		if _, ok := c.donePlanners[d.Id]; ok {
 			c.logger.Info("ignore planning because it was already processed",
@@ -344,8 +399,27 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 			)
 			continue
 		}
+		pendingsByApp[d.ApplicationId] = append(pendingsByApp[d.ApplicationId], d)
+	}
+
+	for appID, ds := range pendingsByApp {
+		// Choose the PENDING deployment of the application to plan
+		// based on its concurrency policy, and skip the superseded ones.
+		d, superseded := selectPendingDeployment(ds)
+		for _, sd := range superseded {
+			c.skipSupersededDeployment(ctx, sd, d)
+		}
+
 		// For each application, only one deployment can be planned at the same time.
 		if p, ok := c.planners[appID]; ok {
+			if shouldCancelInProgress(p.deployment, d) {
+				p.Cancel(supersededCancelCommand(p.deployment, d))
+				c.logger.Info("cancelled the planning deployment because it was superseded",
+					zap.String("deployment", d.Id),
+					zap.String("app", d.ApplicationId),
+					zap.String("cancelled-deployment", p.deployment.Id),
+				)
+			}
 			c.logger.Info("temporarily skip planning because another deployment is planning",
 				zap.String("deployment", d.Id),
 				zap.String("app", d.ApplicationId),
@@ -355,6 +429,14 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 		}
 		// If this application is deploying, no other deployments can be added to plan.
 		if s, ok := c.schedulers[appID]; ok {
+			if shouldCancelInProgress(s.deployment, d) {
+				s.Cancel(supersededCancelCommand(s.deployment, d))
+				c.logger.Info("cancelled the running deployment because it was superseded",
+					zap.String("deployment", d.Id),
+					zap.String("app", d.ApplicationId),
+					zap.String("cancelled-deployment", s.deployment.Id),
+				)
+			}
 			c.logger.Info("temporarily skip planning because another deployment is running",
 				zap.String("deployment", d.Id),
 				zap.String("app", d.ApplicationId),
@@ -362,14 +444,7 @@ func (c *controller) syncPlanners(ctx context.Context) error {
 			)
 			continue
 		}
-		// Choose the oldest PENDING deployment of the application to plan.
-		if pre, ok := pendingByApp[appID]; ok && !d.TriggerBefore(pre) {
-			continue
-		}
-		pendingByApp[appID] = d
-	}
 
-	for appID, d := range pendingByApp {
 		planner, err := c.startNewPlanner(ctx, d)
 		if err != nil {
 			c.logger.Error("failed to start a new planner",
@@ -594,6 +669,7 @@ func (c *controller) startNewScheduler(ctx context.Context, d *model.Deployment)
 		c.secretDecrypter,
 		c.pipedConfig,
 		c.appManifestsCache,
+		c.stageBudgetTracker,
 		c.logger,
 	)
 
@@ -654,6 +730,10 @@ func (l appLiveResourceLister) ListKubernetesResources() ([]provider.Manifest, b
 	return l.lister.ListKubernetesAppLiveResources(l.cloudProvider, l.appID)
 }
 
+func (l appLiveResourceLister) ListKubernetesDependedResources() ([]provider.Manifest, bool) {
+	return l.lister.ListKubernetesAppLiveDependedResources(l.cloudProvider, l.appID)
+}
+
 func reportApplicationDeployingStatus(ctx context.Context, c apiClient, appID string, deploying bool) error {
 	var (
 		err   error
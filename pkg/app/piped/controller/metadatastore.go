@@ -16,40 +16,180 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+const (
+	// The changes made within this interval are saved together by a single call.
+	metadataFlushInterval = 2 * time.Second
+	// The maximum time to save the pending changes.
+	metadataFlushTimeout = 30 * time.Second
+	// The values larger than this are compacted before being saved.
+	metadataCompactionThreshold = 4 * 1024
+	// The maximum size of a value after being compacted.
+	maxMetadataValueSize = 64 * 1024
+)
+
+// metadataStore keeps the metadata of the deployment and its stages in memory
+// and saves the changes to the control plane in batches.
 type metadataStore struct {
 	apiClient     apiClient
 	deployment    *model.Deployment
 	metadata      sync.Map // map[key-string]string
 	stageMetadata sync.Map // map[stage-id-string]map[string]string
+	flushInterval time.Duration
+	logger        *zap.Logger
+
+	// Guards the fields below.
+	mu            sync.Mutex
+	metadataDirty bool
+	dirtyStages   map[string]struct{}
+	flushTimer    *time.Timer
+
+	// Serializes the flushes to save the changes in order.
+	flushMu sync.Mutex
 }
 
-func NewMetadataStore(apiClient apiClient, d *model.Deployment) *metadataStore {
+func NewMetadataStore(apiClient apiClient, d *model.Deployment, logger *zap.Logger) *metadataStore {
 	s := &metadataStore{
 		apiClient:     apiClient,
 		deployment:    d,
 		metadata:      sync.Map{},
 		stageMetadata: sync.Map{},
+		flushInterval: metadataFlushInterval,
+		dirtyStages:   make(map[string]struct{}),
+		logger:        logger.Named("metadata-store"),
 	}
 	// Store shared metadata of deployment.
-	for k, v := range d.Metadata {
+	for k, v := range model.ExpandMetadata(d.Metadata) {
 		s.metadata.Store(k, v)
 	}
 	// Store metadata of all stages.
 	for _, stage := range d.Stages {
-		s.stageMetadata.Store(stage.Id, stage.Metadata)
+		s.stageMetadata.Store(stage.Id, model.ExpandMetadata(stage.Metadata))
 	}
 	return s
 }
 
+// Set stores the given value and saves it with the next batch.
+// An error is returned when the value is too large to be saved.
+// The failure of saving is returned by the next Flush instead,
+// which is called by the scheduler before completing the stage.
 func (s *metadataStore) Set(ctx context.Context, key, value string) error {
+	if _, err := compactMetadataValue(key, value); err != nil {
+		return err
+	}
 	s.metadata.Store(key, value)
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadataDirty = true
+	s.scheduleFlushLocked()
+	return nil
+}
+
+func (s *metadataStore) Get(key string) (string, bool) {
+	if value, ok := s.metadata.Load(key); ok {
+		return value.(string), true
+	}
+	return "", false
+}
+
+// SetStageMetadata stores the metadata of the given stage and saves it with the next batch.
+// An error is returned when one of the values is too large to be saved.
+// Same as Set, the failure of saving is returned by the next Flush.
+func (s *metadataStore) SetStageMetadata(ctx context.Context, stageID string, metadata map[string]string) error {
+	if _, err := compactMetadata(metadata); err != nil {
+		return err
+	}
+	s.stageMetadata.Store(stageID, metadata)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirtyStages[stageID] = struct{}{}
+	s.scheduleFlushLocked()
+	return nil
+}
+
+func (s *metadataStore) GetStageMetadata(stageID string) (map[string]string, bool) {
+	if metadata, ok := s.stageMetadata.Load(stageID); ok {
+		return metadata.(map[string]string), true
+	}
+	return nil, false
+}
+
+// Flush saves all pending changes immediately instead of waiting for the next batch.
+// The changes failed to be saved are kept pending to be retried by the next flush.
+func (s *metadataStore) Flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+	metadataDirty := s.metadataDirty
+	dirtyStages := s.dirtyStages
+	s.metadataDirty = false
+	s.dirtyStages = make(map[string]struct{})
+	s.mu.Unlock()
+
+	var (
+		failedMetadata bool
+		failedStages   = make(map[string]struct{})
+		lastErr        error
+	)
+	if metadataDirty {
+		if err := s.saveMetadata(ctx); err != nil {
+			failedMetadata = true
+			lastErr = err
+		}
+	}
+	for stageID := range dirtyStages {
+		if err := s.saveStageMetadata(ctx, stageID); err != nil {
+			failedStages[stageID] = struct{}{}
+			lastErr = err
+		}
+	}
+	if lastErr == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if failedMetadata {
+		s.metadataDirty = true
+	}
+	for stageID := range failedStages {
+		s.dirtyStages[stageID] = struct{}{}
+	}
+	return lastErr
+}
+
+// scheduleFlushLocked starts the timer to save the pending changes
+// unless it was already started. The caller must hold s.mu.
+func (s *metadataStore) scheduleFlushLocked() {
+	if s.flushTimer != nil {
+		return
+	}
+	s.flushTimer = time.AfterFunc(s.flushInterval, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metadataFlushTimeout)
+		defer cancel()
+		if err := s.Flush(ctx); err != nil {
+			s.logger.Error("failed to save metadata", zap.Error(err))
+		}
+	})
+}
+
+func (s *metadataStore) saveMetadata(ctx context.Context) error {
 	metadata := make(map[string]string)
 	s.metadata.Range(func(key, value interface{}) bool {
 		var (
@@ -59,35 +199,63 @@ func (s *metadataStore) Set(ctx context.Context, key, value string) error {
 		metadata[k] = v
 		return true
 	})
+	compacted, err := compactMetadata(metadata)
+	if err != nil {
+		return err
+	}
 
-	_, err := s.apiClient.SaveDeploymentMetadata(ctx, &pipedservice.SaveDeploymentMetadataRequest{
+	_, err = s.apiClient.SaveDeploymentMetadata(ctx, &pipedservice.SaveDeploymentMetadataRequest{
 		DeploymentId: s.deployment.Id,
-		Metadata:     metadata,
+		Metadata:     compacted,
 	})
 	return err
 }
 
-func (s *metadataStore) Get(key string) (string, bool) {
-	if value, ok := s.metadata.Load(key); ok {
-		return value.(string), true
+func (s *metadataStore) saveStageMetadata(ctx context.Context, stageID string) error {
+	metadata, _ := s.GetStageMetadata(stageID)
+	compacted, err := compactMetadata(metadata)
+	if err != nil {
+		return err
 	}
-	return "", false
-}
-
-func (s *metadataStore) SetStageMetadata(ctx context.Context, stageID string, metadata map[string]string) error {
-	s.stageMetadata.Store(stageID, metadata)
 
-	_, err := s.apiClient.SaveStageMetadata(ctx, &pipedservice.SaveStageMetadataRequest{
+	_, err = s.apiClient.SaveStageMetadata(ctx, &pipedservice.SaveStageMetadataRequest{
 		DeploymentId: s.deployment.Id,
 		StageId:      stageID,
-		Metadata:     metadata,
+		Metadata:     compacted,
 	})
 	return err
 }
 
-func (s *metadataStore) GetStageMetadata(stageID string) (map[string]string, bool) {
-	if metadata, ok := s.stageMetadata.Load(stageID); ok {
-		return metadata.(map[string]string), true
+// compactMetadata returns a copy of the given metadata whose large values are compacted.
+func compactMetadata(metadata map[string]string) (map[string]string, error) {
+	compacted := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		c, err := compactMetadataValue(k, v)
+		if err != nil {
+			return nil, err
+		}
+		compacted[k] = c
 	}
-	return nil, false
+	return compacted, nil
+}
+
+// compactMetadataValue compacts the value larger than metadataCompactionThreshold.
+// The compacted values are expanded by the control plane before being stored.
+// An error is returned when the value is still larger than maxMetadataValueSize after being compacted.
+func compactMetadataValue(key, value string) (string, error) {
+	if len(value) <= metadataCompactionThreshold {
+		return value, nil
+	}
+
+	compacted, err := model.CompactMetadataValue(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to compact metadata %s: %w", key, err)
+	}
+	if len(compacted) >= len(value) {
+		compacted = value
+	}
+	if len(compacted) > maxMetadataValueSize {
+		return "", fmt.Errorf("metadata %s is too large: %d bytes after compaction exceeds the limit of %d bytes", key, len(compacted), maxMetadataValueSize)
+	}
+	return compacted, nil
 }
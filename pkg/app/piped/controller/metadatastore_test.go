@@ -0,0 +1,170 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func randomString(n int) string {
+	r := rand.New(rand.NewSource(1))
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b)
+}
+
+func TestCompactMetadataValue(t *testing.T) {
+	testcases := []struct {
+		name      string
+		value     string
+		compacted bool
+		expectErr bool
+	}{
+		{
+			name:  "small value",
+			value: "value",
+		},
+		{
+			name:      "large value",
+			value:     strings.Repeat("resource-", 1000),
+			compacted: true,
+		},
+		{
+			name:  "large value not reduced by compaction",
+			value: randomString(metadataCompactionThreshold + 1),
+		},
+		{
+			name:      "too large value",
+			value:     randomString(maxMetadataValueSize + 1),
+			expectErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := compactMetadataValue("key", tc.value)
+			assert.Equal(t, tc.expectErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.compacted, strings.HasPrefix(got, model.CompactedMetadataValuePrefix))
+			assert.LessOrEqual(t, len(got), len(tc.value))
+
+			expanded, err := model.ExpandMetadataValue(got)
+			require.NoError(t, err)
+			assert.Equal(t, tc.value, expanded)
+		})
+	}
+}
+
+type fakeMetadataAPIClient struct {
+	apiClient
+
+	mu                 sync.Mutex
+	err                error
+	deploymentMetadata []map[string]string
+	stageMetadata      map[string][]map[string]string
+}
+
+func (c *fakeMetadataAPIClient) SaveDeploymentMetadata(_ context.Context, req *pipedservice.SaveDeploymentMetadataRequest, _ ...grpc.CallOption) (*pipedservice.SaveDeploymentMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.deploymentMetadata = append(c.deploymentMetadata, req.Metadata)
+	return &pipedservice.SaveDeploymentMetadataResponse{}, nil
+}
+
+func (c *fakeMetadataAPIClient) SaveStageMetadata(_ context.Context, req *pipedservice.SaveStageMetadataRequest, _ ...grpc.CallOption) (*pipedservice.SaveStageMetadataResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.stageMetadata[req.StageId] = append(c.stageMetadata[req.StageId], req.Metadata)
+	return &pipedservice.SaveStageMetadataResponse{}, nil
+}
+
+func TestMetadataStoreFlush(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = &fakeMetadataAPIClient{stageMetadata: make(map[string][]map[string]string)}
+		large  = strings.Repeat("resource-", 1000)
+		d      = &model.Deployment{
+			Id:       "deployment-1",
+			Metadata: map[string]string{"existing": "value"},
+			Stages:   []*model.PipelineStage{{Id: "stage-1"}},
+		}
+	)
+	s := NewMetadataStore(client, d, zap.NewNop())
+	s.flushInterval = time.Hour
+
+	// The changes are saved together by the flush.
+	require.NoError(t, s.Set(ctx, "key-1", "value-1"))
+	require.NoError(t, s.Set(ctx, "key-2", large))
+	require.NoError(t, s.SetStageMetadata(ctx, "stage-1", map[string]string{"key": "value"}))
+	assert.Empty(t, client.deploymentMetadata)
+	assert.Empty(t, client.stageMetadata)
+
+	require.NoError(t, s.Flush(ctx))
+	require.Len(t, client.deploymentMetadata, 1)
+	assert.Equal(t, "value-1", client.deploymentMetadata[0]["key-1"])
+	assert.Equal(t, "value", client.deploymentMetadata[0]["existing"])
+	assert.True(t, strings.HasPrefix(client.deploymentMetadata[0]["key-2"], model.CompactedMetadataValuePrefix))
+	assert.Equal(t, []map[string]string{{"key": "value"}}, client.stageMetadata["stage-1"])
+
+	// The values are kept expanded in the store.
+	v, ok := s.Get("key-2")
+	assert.True(t, ok)
+	assert.Equal(t, large, v)
+
+	// Nothing is saved when there are no changes.
+	require.NoError(t, s.Flush(ctx))
+	assert.Len(t, client.deploymentMetadata, 1)
+
+	// The too large value is rejected without being stored.
+	assert.Error(t, s.Set(ctx, "key-3", randomString(maxMetadataValueSize+1)))
+	_, ok = s.Get("key-3")
+	assert.False(t, ok)
+
+	// The changes failed to be saved are retried by the next flush.
+	client.err = errors.New("unavailable")
+	require.NoError(t, s.SetStageMetadata(ctx, "stage-1", map[string]string{"key": "updated"}))
+	assert.Error(t, s.Flush(ctx))
+	client.err = nil
+	require.NoError(t, s.Flush(ctx))
+	assert.Equal(t, []map[string]string{{"key": "value"}, {"key": "updated"}}, client.stageMetadata["stage-1"])
+
+	// The compacted values saved by the previous piped are restored.
+	d.Metadata = client.deploymentMetadata[0]
+	s = NewMetadataStore(client, d, zap.NewNop())
+	v, ok = s.Get("key-2")
+	assert.True(t, ok)
+	assert.Equal(t, large, v)
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// analysisFailurePolicy returns the onFailure policy configured in the pipeline
+// when the deployment failed at the given ANALYSIS stage.
+// Nil is returned when the policy does not apply, e.g. the deployment was cancelled,
+// and in that case the deployment should be rolled back as usual.
+func analysisFailurePolicy(cfg config.GenericDeploymentSpec, failedStage *model.PipelineStage, status model.DeploymentStatus) *config.PipelineOnFailure {
+	if status != model.DeploymentStatus_DEPLOYMENT_FAILURE {
+		return nil
+	}
+	if failedStage == nil || failedStage.Name != model.StageAnalysis.String() {
+		return nil
+	}
+	if cfg.Pipeline == nil {
+		return nil
+	}
+	return cfg.Pipeline.OnFailure
+}
+
+// pausedRollbackExecutor pauses the deployment until a human approves the rollback
+// and then executes the wrapped rollback executor.
+// The wrapped one never waits for another approval since rollbackApproval
+// of the deployment input is rejected when the pause action is configured.
+type pausedRollbackExecutor struct {
+	rollback executor.Executor
+	input    executor.Input
+	approval *config.RollbackApproval
+}
+
+func (e *pausedRollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	if ok, status := executor.WaitRollbackApproval(sig, e.input, e.approval); !ok {
+		return status
+	}
+	return e.rollback.Execute(sig)
+}
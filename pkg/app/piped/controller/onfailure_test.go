@@ -0,0 +1,73 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAnalysisFailurePolicy(t *testing.T) {
+	var (
+		onFailure = &config.PipelineOnFailure{Action: config.PipelineOnFailureActionPause}
+		cfg       = config.GenericDeploymentSpec{
+			Pipeline: &config.DeploymentPipeline{OnFailure: onFailure},
+		}
+		analysis = &model.PipelineStage{Id: "stage-1", Name: model.StageAnalysis.String()}
+		wait     = &model.PipelineStage{Id: "stage-0", Name: model.StageWait.String()}
+	)
+	testcases := []struct {
+		name        string
+		cfg         config.GenericDeploymentSpec
+		failedStage *model.PipelineStage
+		status      model.DeploymentStatus
+		expected    *config.PipelineOnFailure
+	}{
+		{
+			name:        "failed at analysis stage",
+			cfg:         cfg,
+			failedStage: analysis,
+			status:      model.DeploymentStatus_DEPLOYMENT_FAILURE,
+			expected:    onFailure,
+		},
+		{
+			name:        "failed at non-analysis stage",
+			cfg:         cfg,
+			failedStage: wait,
+			status:      model.DeploymentStatus_DEPLOYMENT_FAILURE,
+		},
+		{
+			name:        "cancelled while running analysis stage",
+			cfg:         cfg,
+			failedStage: analysis,
+			status:      model.DeploymentStatus_DEPLOYMENT_CANCELLED,
+		},
+		{
+			name:        "no pipeline",
+			failedStage: analysis,
+			status:      model.DeploymentStatus_DEPLOYMENT_FAILURE,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := analysisFailurePolicy(tc.cfg, tc.failedStage, tc.status)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// pauseCommandCheckInterval is the interval to check whether the paused deployment was resumed.
+const pauseCommandCheckInterval = 5 * time.Second
+
+// filterPauseCommands returns the commands pausing or resuming the given deployment
+// in the order they were listed.
+func filterPauseCommands(commands []model.ReportableCommand, deploymentID string) []model.ReportableCommand {
+	out := make([]model.ReportableCommand, 0, len(commands))
+	for _, cmd := range commands {
+		if cmd.DeploymentId != deploymentID {
+			continue
+		}
+		if cmd.GetPauseDeployment() == nil && cmd.GetResumeDeployment() == nil {
+			continue
+		}
+		out = append(out, cmd)
+	}
+	return out
+}
+
+// isPaused returns whether the deployment is being paused.
+func (s *scheduler) isPaused() bool {
+	pausedBy, _ := s.metadataStore.Get(model.MetadataKeyPausedBy)
+	return pausedBy != ""
+}
+
+// handlePauseCommands applies the pause and resume commands of the deployment
+// and persists the paused state into the deployment metadata
+// so that the deployment is still paused after piped restarts.
+func (s *scheduler) handlePauseCommands(ctx context.Context, nextStage *model.PipelineStage) {
+	commands := filterPauseCommands(s.commandLister.ListDeploymentCommands(), s.deployment.Id)
+	for _, cmd := range commands {
+		var pausedBy, desc string
+		if cmd.GetPauseDeployment() != nil {
+			pausedBy = cmd.Commander
+			desc = fmt.Sprintf("Paused by %s before executing stage %s", cmd.Commander, nextStage.Id)
+		} else {
+			desc = fmt.Sprintf("Resumed by %s", cmd.Commander)
+		}
+
+		status := model.CommandStatus_COMMAND_SUCCEEDED
+		if err := s.savePausedBy(ctx, pausedBy); err != nil {
+			s.logger.Error("failed to save the paused state of deployment", zap.Error(err))
+			status = model.CommandStatus_COMMAND_FAILED
+		} else if err := s.reportDeploymentStatusChanged(ctx, model.DeploymentStatus_DEPLOYMENT_RUNNING, desc); err != nil {
+			s.logger.Error("failed to report deployment status", zap.Error(err))
+		}
+		if err := cmd.Report(ctx, status, nil, nil); err != nil {
+			s.logger.Error("failed to report command status", zap.Error(err))
+		}
+	}
+}
+
+// savePausedBy saves the paused state of the deployment immediately
+// instead of waiting for the next batch of the metadata store.
+// The previous state is restored when it could not be saved
+// to not pause or resume the deployment by the failed command.
+func (s *scheduler) savePausedBy(ctx context.Context, pausedBy string) error {
+	prev, _ := s.metadataStore.Get(model.MetadataKeyPausedBy)
+	if err := s.metadataStore.Set(ctx, model.MetadataKeyPausedBy, pausedBy); err != nil {
+		return err
+	}
+	if err := s.flushMetadata(); err != nil {
+		if e := s.metadataStore.Set(ctx, model.MetadataKeyPausedBy, prev); e != nil {
+			s.logger.Error("failed to restore the paused state of deployment", zap.Error(e))
+		}
+		return err
+	}
+	return nil
+}
+
+// waitWhilePaused blocks until the paused deployment is resumed.
+// It returns false when the deployment was cancelled or the scheduler was asked to stop
+// before being resumed. The cancel command is returned in the former case.
+func (s *scheduler) waitWhilePaused(ctx context.Context, nextStage *model.PipelineStage) (*model.ReportableCommand, bool) {
+	ticker := time.NewTicker(pauseCommandCheckInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("deployment is paused", zap.String("next-stage-id", nextStage.Id))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+
+		case cmd := <-s.cancelledCh:
+			return cmd, false
+
+		case <-ticker.C:
+			s.handlePauseCommands(ctx, nextStage)
+			if !s.isPaused() {
+				s.logger.Info("deployment was resumed", zap.String("next-stage-id", nextStage.Id))
+				return nil, true
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestFilterPauseCommands(t *testing.T) {
+	commands := []model.ReportableCommand{
+		{
+			Command: &model.Command{
+				Id:           "pause",
+				DeploymentId: "deployment-1",
+				Type:         model.Command_PAUSE_DEPLOYMENT,
+				PauseDeployment: &model.Command_PauseDeployment{
+					DeploymentId: "deployment-1",
+				},
+			},
+		},
+		{
+			Command: &model.Command{
+				Id:           "cancel",
+				DeploymentId: "deployment-1",
+				Type:         model.Command_CANCEL_DEPLOYMENT,
+				CancelDeployment: &model.Command_CancelDeployment{
+					DeploymentId: "deployment-1",
+				},
+			},
+		},
+		{
+			Command: &model.Command{
+				Id:           "pause-other",
+				DeploymentId: "deployment-2",
+				Type:         model.Command_PAUSE_DEPLOYMENT,
+				PauseDeployment: &model.Command_PauseDeployment{
+					DeploymentId: "deployment-2",
+				},
+			},
+		},
+		{
+			Command: &model.Command{
+				Id:           "resume",
+				DeploymentId: "deployment-1",
+				Type:         model.Command_RESUME_DEPLOYMENT,
+				ResumeDeployment: &model.Command_ResumeDeployment{
+					DeploymentId: "deployment-1",
+				},
+			},
+		},
+	}
+
+	testcases := []struct {
+		name         string
+		deploymentID string
+		expected     []string
+	}{
+		{
+			name:         "pause and resume in order",
+			deploymentID: "deployment-1",
+			expected:     []string{"pause", "resume"},
+		},
+		{
+			name:         "only pause",
+			deploymentID: "deployment-2",
+			expected:     []string{"pause-other"},
+		},
+		{
+			name:         "no command",
+			deploymentID: "deployment-3",
+			expected:     []string{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := filterPauseCommands(commands, tc.deploymentID)
+			ids := make([]string, 0, len(got))
+			for _, cmd := range got {
+				ids = append(ids, cmd.Id)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestSavePausedBy(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		client = &fakeMetadataAPIClient{stageMetadata: make(map[string][]map[string]string)}
+		d      = &model.Deployment{Id: "deployment-1"}
+	)
+	s := &scheduler{
+		metadataStore: NewMetadataStore(client, d, zap.NewNop()),
+		logger:        zap.NewNop(),
+	}
+	s.metadataStore.flushInterval = time.Hour
+
+	// The paused state is saved without waiting for the next batch.
+	require.NoError(t, s.savePausedBy(ctx, "user"))
+	assert.True(t, s.isPaused())
+	require.Len(t, client.deploymentMetadata, 1)
+	assert.Equal(t, "user", client.deploymentMetadata[0][model.MetadataKeyPausedBy])
+
+	// The deployment is kept paused when the resume could not be saved.
+	client.err = errors.New("unavailable")
+	assert.Error(t, s.savePausedBy(ctx, ""))
+	assert.True(t, s.isPaused())
+}
@@ -24,6 +24,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/changesummary"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	pln "github.com/pipe-cd/pipe/pkg/app/piped/planner"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/registry"
@@ -189,6 +190,10 @@ func (p *planner) Run(ctx context.Context) error {
 		)
 	}
 
+	if p.lastSuccessfulCommitHash != "" && p.lastSuccessfulCommitHash != p.deployment.Trigger.Commit.Hash {
+		in.Changes = p.summarizeChanges(ctx, repoCfg)
+	}
+
 	out, err := planner.Plan(ctx, in)
 
 	// If the deployment was already cancelled, we ignore the plan result.
@@ -208,10 +213,53 @@ func (p *planner) Run(ctx context.Context) error {
 		return p.reportDeploymentFailed(ctx, fmt.Sprintf("Unable to plan the deployment (%v)", err))
 	}
 
+	if in.Changes != nil {
+		p.saveChangeSummary(ctx, in.Changes)
+	}
+
+	if eta := estimatedPipelineSummary(out.Stages); eta != "" {
+		out.Summary = fmt.Sprintf("%s (%s)", out.Summary, eta)
+	}
+
 	p.doneDeploymentStatus = model.DeploymentStatus_DEPLOYMENT_PLANNED
 	return p.reportDeploymentPlanned(ctx, p.lastSuccessfulCommitHash, out)
 }
 
+// summarizeChanges summarizes the changes made to the application since the last deployed commit.
+// Nil is returned when failed since the summary is not required to plan the deployment.
+func (p *planner) summarizeChanges(ctx context.Context, repoCfg config.PipedRepository) *changesummary.Summary {
+	repo, err := p.gitClient.Clone(ctx, repoCfg.RepoID, repoCfg.Remote, repoCfg.Branch, filepath.Join(p.workingDir, "change-summary"))
+	if err != nil {
+		p.logger.Warn("unable to clone repository to summarize the changes", zap.Error(err))
+		return nil
+	}
+	defer repo.Clean()
+
+	summary, err := changesummary.Generate(ctx, repo, p.deployment.GitPath.Path, p.lastSuccessfulCommitHash, p.deployment.Trigger.Commit.Hash)
+	if err != nil {
+		p.logger.Warn("unable to summarize the changes since the last deployed commit", zap.Error(err))
+		return nil
+	}
+	return summary
+}
+
+// saveChangeSummary saves the given summary of the changes into the deployment metadata.
+// Failing to save the summary does not fail the deployment since it is just for information.
+func (p *planner) saveChangeSummary(ctx context.Context, summary *changesummary.Summary) {
+	metadata := make(map[string]string, len(p.deployment.Metadata)+1)
+	for k, v := range p.deployment.Metadata {
+		metadata[k] = v
+	}
+	metadata[changesummary.MetadataKey] = summary.Render()
+	_, err := p.apiClient.SaveDeploymentMetadata(ctx, &pipedservice.SaveDeploymentMetadataRequest{
+		DeploymentId: p.deployment.Id,
+		Metadata:     metadata,
+	})
+	if err != nil {
+		p.logger.Warn("unable to save the change summary to the deployment metadata", zap.Error(err))
+	}
+}
+
 func (p *planner) reportDeploymentPlanned(ctx context.Context, runningCommitHash string, out pln.Output) error {
 	var (
 		err   error
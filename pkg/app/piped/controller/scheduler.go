@@ -19,10 +19,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
@@ -35,6 +39,9 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+// skipStageCheckInterval is the interval to check whether the running stage was asked to be skipped.
+const skipStageCheckInterval = 5 * time.Second
+
 // scheduler is a dedicated object for a specific deployment of a single application.
 type scheduler struct {
 	// Readonly deployment model.
@@ -54,6 +61,7 @@ type scheduler struct {
 	secretDecrypter     secretDecrypter
 	pipedConfig         *config.PipedSpec
 	appManifestsCache   cache.Cache
+	stageBudgetTracker  *stageBudgetTracker
 	logger              *zap.Logger
 
 	targetDSP  deploysource.Provider
@@ -65,6 +73,9 @@ type scheduler struct {
 	// We may need a mutex for this field in the future
 	// when the stages can be executed concurrently.
 	stageStatuses           map[string]model.StageStatus
+	stageStatusReasons      map[string]string
+	stageRetriedCounts      map[string]int32
+	stageWarnings           []string
 	genericDeploymentConfig config.GenericDeploymentSpec
 
 	done                 atomic.Bool
@@ -73,7 +84,8 @@ type scheduler struct {
 	cancelled            bool
 	cancelledCh          chan *model.ReportableCommand
 
-	nowFunc func() time.Time
+	skipCheckInterval time.Duration
+	nowFunc           func() time.Time
 }
 
 func newScheduler(
@@ -91,6 +103,7 @@ func newScheduler(
 	sd secretDecrypter,
 	pipedConfig *config.PipedSpec,
 	appManifestsCache cache.Cache,
+	sbt *stageBudgetTracker,
 	logger *zap.Logger,
 ) *scheduler {
 
@@ -115,21 +128,26 @@ func newScheduler(
 		liveResourceLister:   liveResourceLister,
 		analysisResultStore:  analysisResultStore,
 		logPersister:         lp,
-		metadataStore:        NewMetadataStore(apiClient, d),
+		metadataStore:        NewMetadataStore(apiClient, d, logger),
 		notifier:             notifier,
 		secretDecrypter:      sd,
 		pipedConfig:          pipedConfig,
 		appManifestsCache:    appManifestsCache,
+		stageBudgetTracker:   sbt,
 		doneDeploymentStatus: d.Status,
 		cancelledCh:          make(chan *model.ReportableCommand, 1),
 		logger:               logger,
+		skipCheckInterval:    skipStageCheckInterval,
 		nowFunc:              time.Now,
 	}
 
 	// Initialize the map of current status of all stages.
 	s.stageStatuses = make(map[string]model.StageStatus, len(d.Stages))
+	s.stageStatusReasons = make(map[string]string)
+	s.stageRetriedCounts = make(map[string]int32, len(d.Stages))
 	for _, stage := range d.Stages {
 		s.stageStatuses[stage.Id] = stage.Status
+		s.stageRetriedCounts[stage.Id] = stage.RetriedCount
 	}
 
 	return s
@@ -259,14 +277,18 @@ func (s *scheduler) Run(ctx context.Context) error {
 	}
 	s.genericDeploymentConfig = ds.GenericDeploymentConfig
 
-	timer := time.NewTimer(s.genericDeploymentConfig.Timeout.Duration())
+	var (
+		timeout  = s.genericDeploymentConfig.Timeout.Duration()
+		deadline = time.Now().Add(timeout)
+		timer    = time.NewTimer(timeout)
+	)
 	defer timer.Stop()
 
 	// Iterate all the stages and execute the uncompleted ones.
 	for i, ps := range s.deployment.Stages {
 		lastStage = s.deployment.Stages[i]
 
-		if ps.Status == model.StageStatus_STAGE_SUCCESS {
+		if model.IsPassedStage(ps.Status) {
 			continue
 		}
 		if !ps.Visible || ps.Name == model.StageRollback.String() {
@@ -285,43 +307,47 @@ func (s *scheduler) Run(ctx context.Context) error {
 			break
 		}
 
-		var (
-			result       model.StageStatus
-			sig, handler = executor.NewStopSignal()
-			doneCh       = make(chan struct{})
-		)
-
-		go func() {
-			result = s.executeStage(sig, *ps, func(in executor.Input) (executor.Executor, bool) {
-				return s.executorRegistry.Executor(model.Stage(ps.Name), in)
-			})
-			close(doneCh)
-		}()
-
-		select {
-		case <-ctx.Done():
-			handler.Terminate()
-			<-doneCh
-
-		case <-timer.C:
-			handler.Timeout()
-			<-doneCh
-
-		case cmd := <-s.cancelledCh:
+		// The deployment is held before executing the next stage while it is paused.
+		// The paused duration is not counted in the deployment timeout.
+		s.handlePauseCommands(ctx, ps)
+		if s.isPaused() {
+			if !timer.Stop() {
+				<-timer.C
+			}
+			pausedAt := time.Now()
+			cmd, resumed := s.waitWhilePaused(ctx, ps)
 			if cmd != nil {
 				cancelCommand = cmd
 				cancelCommander = cmd.Commander
-				handler.Cancel()
-				<-doneCh
+				deploymentStatus = model.DeploymentStatus_DEPLOYMENT_CANCELLED
+				statusReason = fmt.Sprintf("Cancelled by %s while the deployment was paused before executing stage %s", cancelCommander, ps.Id)
+				break
+			}
+			if !resumed {
+				s.logger.Info("stop scheduler because of termination signal while the deployment was paused", zap.String("stage-id", ps.Id))
+				return nil
 			}
+			deadline = deadline.Add(time.Since(pausedAt))
+			timer.Reset(time.Until(deadline))
+		}
 
-		case <-doneCh:
-			break
+		// The stage was skipped by a user before starting.
+		if cmd, ok := s.findSkipStageCommand(ps.Id); ok {
+			s.skipStage(ctx, ps, cmd)
+			continue
+		}
+
+		result, sig, cmd := s.runStage(ctx, ps, deadline, timer.C, func(in executor.Input) (executor.Executor, bool) {
+			return s.executorRegistry.Executor(model.Stage(ps.Name), in)
+		})
+		if cmd != nil {
+			cancelCommand = cmd
+			cancelCommander = cmd.Commander
 		}
 
 		// If all operations of the stage were completed successfully
-		// handle the next stage.
-		if result == model.StageStatus_STAGE_SUCCESS {
+		// or the stage was skipped by a user, handle the next stage.
+		if model.IsPassedStage(result) {
 			continue
 		}
 
@@ -339,6 +365,9 @@ func (s *scheduler) Run(ctx context.Context) error {
 				statusReason = fmt.Sprintf("Timed out while executing stage %s", ps.Id)
 			} else {
 				statusReason = fmt.Sprintf("Failed while executing stage %s", ps.Id)
+				if reason := s.stageStatusReasons[ps.Id]; reason != "" {
+					statusReason = fmt.Sprintf("%s (%s)", statusReason, reason)
+				}
 			}
 			break
 		}
@@ -364,6 +393,20 @@ func (s *scheduler) Run(ctx context.Context) error {
 				return err
 			}
 
+			// Apply the onFailure policy of the pipeline when an ANALYSIS stage failed.
+			onFailure := analysisFailurePolicy(s.genericDeploymentConfig, lastStage, deploymentStatus)
+			if onFailure != nil && onFailure.Action == config.PipelineOnFailureActionRollbackAndNotify {
+				s.notifier.Notify(model.NotificationEvent{
+					Type: model.NotificationEventType_EVENT_DEPLOYMENT_INCIDENT,
+					Metadata: &model.NotificationEventDeploymentIncident{
+						Deployment: s.deployment,
+						EnvName:    s.envName,
+						StageId:    lastStage.Id,
+						Reason:     statusReason,
+					},
+				})
+			}
+
 			// Start running rollback stage.
 			var (
 				sig, handler = executor.NewStopSignal()
@@ -373,7 +416,15 @@ func (s *scheduler) Run(ctx context.Context) error {
 				rbs := *stage
 				rbs.Requires = []string{lastStage.Id}
 				s.executeStage(sig, rbs, func(in executor.Input) (executor.Executor, bool) {
-					return s.executorRegistry.RollbackExecutor(s.deployment.Kind, in)
+					ex, ok := s.executorRegistry.RollbackExecutor(s.deployment.Kind, in)
+					if ok && onFailure != nil && onFailure.Action == config.PipelineOnFailureActionPause {
+						ex = &pausedRollbackExecutor{
+							rollback: ex,
+							input:    in,
+							approval: &onFailure.Approval,
+						}
+					}
+					return ex, ok
 				})
 				close(doneCh)
 			}()
@@ -390,10 +441,19 @@ func (s *scheduler) Run(ctx context.Context) error {
 		}
 	}
 
+	if deploymentStatus == model.DeploymentStatus_DEPLOYMENT_SUCCESS && len(s.stageWarnings) > 0 {
+		statusReason = fmt.Sprintf("The deployment was completed successfully with %d warnings", len(s.stageWarnings))
+	}
+
 	if model.IsCompletedDeployment(deploymentStatus) {
 		err := s.reportDeploymentCompleted(ctx, deploymentStatus, statusReason, cancelCommander)
 		if err == nil && deploymentStatus == model.DeploymentStatus_DEPLOYMENT_SUCCESS {
 			s.reportMostRecentlySuccessfulDeployment(ctx)
+			if ps := s.genericDeploymentConfig.PostSync; ps != nil && ps.Chain != nil {
+				if err := s.createDeploymentChain(ctx, ps.Chain); err != nil {
+					s.logger.Error("failed to create deployment chain", zap.Error(err))
+				}
+			}
 		}
 	}
 
@@ -406,12 +466,76 @@ func (s *scheduler) Run(ctx context.Context) error {
 	return nil
 }
 
+// runStage executes the given stage until it is completed or stopped due to
+// the deployment timeout, the cancellation, the skip command of the stage or the termination of piped.
+// The command cancelled the deployment is returned if the deployment was cancelled while running the stage.
+func (s *scheduler) runStage(ctx context.Context, ps *model.PipelineStage, deadline time.Time, timeoutCh <-chan time.Time, executorFactory func(executor.Input) (executor.Executor, bool)) (model.StageStatus, executor.StopSignal, *model.ReportableCommand) {
+	var (
+		result        model.StageStatus
+		cancelCommand *model.ReportableCommand
+		skipCommand   *model.ReportableCommand
+		sig, handler  = executor.NewStopSignalWithDeadline(deadline)
+		doneCh        = make(chan struct{})
+		skipTicker    = time.NewTicker(s.skipCheckInterval)
+	)
+	defer skipTicker.Stop()
+
+	go func() {
+		result = s.executeStage(sig, *ps, executorFactory)
+		close(doneCh)
+	}()
+
+L:
+	for {
+		select {
+		case <-ctx.Done():
+			handler.Terminate()
+			<-doneCh
+			break L
+
+		case <-timeoutCh:
+			handler.Timeout()
+			<-doneCh
+			break L
+
+		case cmd := <-s.cancelledCh:
+			if cmd != nil {
+				cancelCommand = cmd
+				handler.Cancel()
+				<-doneCh
+			}
+			break L
+
+		case <-skipTicker.C:
+			cmd, ok := s.findSkipStageCommand(ps.Id)
+			if !ok {
+				continue
+			}
+			skipCommand = cmd
+			s.logger.Info("skipping the running stage", zap.String("stage-id", ps.Id), zap.String("commander", cmd.Commander))
+			handler.Skip()
+			<-doneCh
+			break L
+
+		case <-doneCh:
+			break L
+		}
+	}
+
+	if skipCommand != nil {
+		if err := skipCommand.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
+			s.logger.Error("failed to report command status", zap.Error(err))
+		}
+	}
+	return result, sig, cancelCommand
+}
+
 // executeStage finds the executor for the given stage and execute.
 func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage, executorFactory func(executor.Input) (executor.Executor, bool)) (finalStatus model.StageStatus) {
 	var (
 		ctx            = sig.Context()
 		originalStatus = ps.Status
-		lp             = s.logPersister.StageLogPersister(s.deployment.Id, ps.Id)
+		lp             = s.logPersister.StageLogPersister(s.deployment, ps.Id)
 	)
 	defer func() {
 		// When the piped has been terminated (PS kill) while the stage is still running
@@ -424,7 +548,7 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 
 	// Update stage status to RUNNING if needed.
 	if model.CanUpdateStageStatus(ps.Status, model.StageStatus_STAGE_RUNNING) {
-		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_RUNNING, ps.Requires); err != nil {
+		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_RUNNING, "", ps.Requires, 0); err != nil {
 			return model.StageStatus_STAGE_FAILURE
 		}
 		originalStatus = model.StageStatus_STAGE_RUNNING
@@ -433,7 +557,8 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 	// Check the existence of the specified cloud provider.
 	if !s.pipedConfig.HasCloudProvider(s.deployment.CloudProvider, s.deployment.CloudProviderType()) {
 		lp.Errorf("This piped is not having the specified cloud provider in this deployment: %v", s.deployment.CloudProvider)
-		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, ps.Requires); err != nil {
+		reason := executor.StatusReason(executor.NewUserError("cloud provider %s was not found in this piped", s.deployment.CloudProvider))
+		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, reason, ps.Requires, 0); err != nil {
 			s.logger.Error("failed to report stage status", zap.Error(err))
 		}
 		return model.StageStatus_STAGE_FAILURE
@@ -450,7 +575,8 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 
 	if !stageConfigFound {
 		lp.Error("Unable to find the stage configuration")
-		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, ps.Requires); err != nil {
+		reason := executor.StatusReason(executor.NewUserError("stage configuration was not found"))
+		if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, reason, ps.Requires, 0); err != nil {
 			s.logger.Error("failed to report stage status", zap.Error(err))
 		}
 		return model.StageStatus_STAGE_FAILURE
@@ -459,7 +585,8 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 	app, ok := s.applicationLister.Get(s.deployment.ApplicationId)
 	if !ok {
 		lp.Errorf("Application %s for this deployment was not found (Maybe it was disabled).", s.deployment.ApplicationId)
-		s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, ps.Requires)
+		reason := executor.StatusReason(executor.NewUserError("application %s was not found", s.deployment.ApplicationId))
+		s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, reason, ps.Requires, 0)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -477,11 +604,13 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 		store:         s.analysisResultStore,
 		applicationID: app.Id,
 	}
+	reporter := &stageResultReporter{}
 	input := executor.Input{
 		Stage:                 &ps,
 		StageConfig:           stageConfig,
 		Deployment:            s.deployment,
 		Application:           app,
+		EnvName:               s.envName,
 		PipedConfig:           s.pipedConfig,
 		TargetDSP:             s.targetDSP,
 		RunningDSP:            s.runningDSP,
@@ -491,6 +620,11 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 		AppManifestsCache:     s.appManifestsCache,
 		AppLiveResourceLister: alrLister,
 		AnalysisResultStore:   aStore,
+		ErrorReporter:         reporter,
+		WarningReporter:       reporter,
+		Notifier:              s.notifier,
+		EventRegisterer:       eventRegisterer{apiClient: s.apiClient},
+		SecretDecrypter:       s.secretDecrypter,
 		Logger:                s.logger,
 	}
 
@@ -499,22 +633,103 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 	if !ok {
 		err := fmt.Errorf("no registered executor for stage %s", ps.Name)
 		lp.Error(err.Error())
-		s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, ps.Requires)
+		s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_FAILURE, executor.StatusReason(err), ps.Requires, 0)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	// Start running executor.
-	status := ex.Execute(sig)
+	// The failed stage is executed again by a new executor while its retries remain.
+	var (
+		startTime = s.nowFunc()
+		status    model.StageStatus
+	)
+	for {
+		status = s.executeWithTimeout(sig, ex, stageConfig.Timeout.Duration(), lp, reporter)
+		if status != model.StageStatus_STAGE_FAILURE || sig.Signal() != executor.StopSignalNone {
+			break
+		}
+		if !s.waitForRetry(sig, &ps, stageConfig.Retries, reporter.Err(), lp) {
+			// The deployment may have been asked to stop while waiting for the retry.
+			if sig.Signal() != executor.StopSignalNone {
+				status = executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+			}
+			break
+		}
+		reporter = &stageResultReporter{}
+		input.ErrorReporter = reporter
+		input.WarningReporter = reporter
+		ex, _ = executorFactory(input)
+	}
+	duration := s.nowFunc().Sub(startTime)
+
+	// The executor may not know how to handle the skip signal,
+	// so the stage is marked as skipped regardless of the status it returned.
+	if sig.Signal() == executor.StopSignalSkip {
+		lp.Info("The stage was skipped by a user")
+		status = executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+	}
+
+	// The metadata changed by the stage is saved before reporting the stage status
+	// to make it visible together with the status. The later stages and the restarted piped
+	// rely on the metadata, so the stage is failed when it could not be saved.
+	if err := s.flushMetadata(); err != nil && model.IsSuccessfulStage(status) {
+		lp.Errorf("Failed to save the metadata of the stage (%v)", err)
+		reporter.ReportError(fmt.Errorf("failed to save metadata: %w", err))
+		status = model.StageStatus_STAGE_FAILURE
+	}
+
+	// The later stages may depend on the outputs, so the stage
+	// having not published its required outputs is failed here.
+	if model.IsSuccessfulStage(status) && len(ps.Outputs) > 0 {
+		metadata, _ := s.metadataStore.GetStageMetadata(ps.Id)
+		if err := ps.CheckOutputs(metadata); err != nil {
+			lp.Errorf("The stage did not publish its outputs as declared (%v)", err)
+			reporter.ReportError(executor.NewUserError("invalid outputs: %w", err))
+			status = model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	if model.IsSuccessfulStage(status) {
+		s.checkStageBudget(&ps, duration, lp, reporter)
+	}
+
+	// The successful stage reporting some warnings is marked as completed with warnings.
+	warnings := reporter.Warnings()
+	if status == model.StageStatus_STAGE_SUCCESS && len(warnings) > 0 {
+		status = model.StageStatus_STAGE_SUCCESS_WITH_WARNINGS
+	}
 
 	// Commit deployment state status in the following cases:
 	// - Apply state successfully.
 	// - State was canceled while running (cancel via Controlpane).
 	// - Apply state failed but not because of terminating piped process.
-	if status == model.StageStatus_STAGE_SUCCESS ||
+	if model.IsPassedStage(status) ||
 		status == model.StageStatus_STAGE_CANCELLED ||
 		(status == model.StageStatus_STAGE_FAILURE && !sig.Terminated()) {
 
-		s.reportStageStatus(ctx, ps.Id, status, ps.Requires)
+		var reason string
+		if status == model.StageStatus_STAGE_SUCCESS_WITH_WARNINGS {
+			reason = warningsStatusReason(warnings)
+			for _, w := range warnings {
+				s.stageWarnings = append(s.stageWarnings, fmt.Sprintf("%s: %s", ps.Name, w))
+			}
+		}
+		reportCtx := ctx
+		if status == model.StageStatus_STAGE_SKIPPED {
+			reason = "Skipped by a user while running"
+			// The stage context has been cancelled by the skip signal
+			// but the deployment continues, so the status must still be reported.
+			reportCtx = context.Background()
+		}
+		if err := reporter.Err(); err != nil && status == model.StageStatus_STAGE_FAILURE {
+			reason = executor.StatusReason(err)
+			s.logger.Info("stage failed",
+				zap.String("stage-name", ps.Name),
+				zap.String("error-kind", string(executor.ClassifyError(err))),
+				zap.Error(err),
+			)
+		}
+		s.reportStageStatus(reportCtx, ps.Id, status, reason, ps.Requires, duration)
 		return status
 	}
 
@@ -523,23 +738,158 @@ func (s *scheduler) executeStage(sig executor.StopSignal, ps model.PipelineStage
 	return originalStatus
 }
 
-func (s *scheduler) reportStageStatus(ctx context.Context, stageID string, status model.StageStatus, requires []string) error {
+// flushMetadata saves the pending changes of the metadata without waiting for the next batch.
+// The stage context is not used since the stage may have been stopped.
+func (s *scheduler) flushMetadata() error {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataFlushTimeout)
+	defer cancel()
+	if err := s.metadataStore.Flush(ctx); err != nil {
+		s.logger.Error("failed to save metadata", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// executeWithTimeout executes the given executor and asks it to stop
+// when the stage has not been completed within the given timeout.
+// The stop signal of the deployment is forwarded to the executor.
+func (s *scheduler) executeWithTimeout(sig executor.StopSignal, ex executor.Executor, timeout time.Duration, lp executor.LogPersister, er executor.ErrorReporter) model.StageStatus {
+	if timeout <= 0 {
+		return ex.Execute(sig)
+	}
+
+	deadline := s.nowFunc().Add(timeout)
+	if d, ok := sig.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	var (
+		status            model.StageStatus
+		stageSig, handler = executor.NewStopSignalWithDeadline(deadline)
+		doneCh            = make(chan struct{})
+		timer             = time.NewTimer(timeout)
+	)
+	defer timer.Stop()
+
+	go func() {
+		status = ex.Execute(stageSig)
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+		break
+
+	case <-sig.Ch():
+		switch sig.Signal() {
+		case executor.StopSignalCancel:
+			handler.Cancel()
+		case executor.StopSignalTimeout:
+			handler.Timeout()
+		case executor.StopSignalSkip:
+			handler.Skip()
+		default:
+			handler.Terminate()
+		}
+		<-doneCh
+
+	case <-timer.C:
+		handler.Timeout()
+		<-doneCh
+		lp.Errorf("The stage was not completed within its timeout %v", timeout)
+		er.ReportError(executor.NewSystemError("stage timed out after %v", timeout))
+	}
+	return status
+}
+
+// waitForRetry decides whether the failed stage should be retried by its retry policy
+// and waits for the backoff. It returns false when the stage should not be retried
+// or the deployment was asked to stop while waiting.
+func (s *scheduler) waitForRetry(sig executor.StopSignal, ps *model.PipelineStage, retries *config.StageRetries, err error, lp executor.LogPersister) bool {
+	retried := s.stageRetriedCounts[ps.Id]
+	if retries == nil || int(retried) >= retries.Limit {
+		return false
+	}
+	if err != nil && !executor.IsRetryable(err) {
+		lp.Info("The stage is not retried because it failed due to the configuration")
+		return false
+	}
+
+	backoff := retries.GetBackoff(int(retried))
+	lp.Infof("Retrying the stage in %v (%d/%d)", backoff, retried+1, retries.Limit)
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-sig.Ch():
+		return false
+	}
+
+	s.stageRetriedCounts[ps.Id] = retried + 1
+	reason := fmt.Sprintf("Retrying %d/%d", retried+1, retries.Limit)
+	if err != nil {
+		reason = fmt.Sprintf("%s after failure (%s)", reason, executor.StatusReason(err))
+	}
+	if err := s.reportStageStatus(sig.Context(), ps.Id, model.StageStatus_STAGE_RUNNING, reason, ps.Requires, 0); err != nil {
+		s.logger.Error("failed to report stage status", zap.Error(err))
+	}
+	// The reason of the retry must not be used as the reason of the deployment status.
+	delete(s.stageStatusReasons, ps.Id)
+	return true
+}
+
+// findSkipStageCommand returns the command asking to skip the given stage.
+func (s *scheduler) findSkipStageCommand(stageID string) (*model.ReportableCommand, bool) {
+	commands := s.commandLister.ListStageCommands(s.deployment.Id, stageID)
+	for i := range commands {
+		if commands[i].GetSkipStage() != nil {
+			return &commands[i], true
+		}
+	}
+	return nil, false
+}
+
+// skipStage marks the given stage which has not been started yet as skipped
+// and reports the given command as handled.
+func (s *scheduler) skipStage(ctx context.Context, ps *model.PipelineStage, cmd *model.ReportableCommand) {
+	lp := s.logPersister.StageLogPersister(s.deployment, ps.Id)
+	lp.Infof("The stage was skipped by %s before starting", cmd.Commander)
+	lp.Complete(time.Minute)
+
+	reason := fmt.Sprintf("Skipped by %s", cmd.Commander)
+	if err := s.reportStageStatus(ctx, ps.Id, model.StageStatus_STAGE_SKIPPED, reason, ps.Requires, 0); err != nil {
+		s.logger.Error("failed to report stage status", zap.Error(err))
+	}
+	if err := cmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
+		s.logger.Error("failed to report command status", zap.Error(err))
+	}
+}
+
+// reportStageStatus reports the status of the given stage.
+// Zero actualDuration means the stage has not been completed yet.
+func (s *scheduler) reportStageStatus(ctx context.Context, stageID string, status model.StageStatus, reason string, requires []string, actualDuration time.Duration) error {
 	var (
 		err error
 		now = s.nowFunc()
 		req = &pipedservice.ReportStageStatusChangedRequest{
-			DeploymentId: s.deployment.Id,
-			StageId:      stageID,
-			Status:       status,
-			Requires:     requires,
-			Visible:      true,
-			CompletedAt:  now.Unix(),
+			DeploymentId:   s.deployment.Id,
+			StageId:        stageID,
+			Status:         status,
+			StatusReason:   reason,
+			Requires:       requires,
+			Visible:        true,
+			RetriedCount:   s.stageRetriedCounts[stageID],
+			ActualDuration: int64(actualDuration.Seconds()),
+			CompletedAt:    now.Unix(),
 		}
 		retry = pipedservice.NewRetry(10)
 	)
 
 	// Update stage status at local.
 	s.stageStatuses[stageID] = status
+	if reason != "" {
+		s.stageStatusReasons[stageID] = reason
+	}
 
 	// Update stage status on the remote.
 	for retry.WaitNext(ctx) {
@@ -597,6 +947,7 @@ func (s *scheduler) reportDeploymentCompleted(ctx context.Context, status model.
 				Metadata: &model.NotificationEventDeploymentSucceeded{
 					Deployment: s.deployment,
 					EnvName:    s.envName,
+					Warnings:   s.stageWarnings,
 				},
 			})
 
@@ -661,6 +1012,50 @@ func (s *scheduler) reportMostRecentlySuccessfulDeployment(ctx context.Context)
 	return err
 }
 
+// createDeploymentChain requests the control-plane to deploy the chained applications
+// as the next block of the deployment chain this deployment belongs to.
+func (s *scheduler) createDeploymentChain(ctx context.Context, cfg *config.DeploymentChain) error {
+	matchers := make([]*pipedservice.DeploymentChainApplicationMatcher, 0, len(cfg.Applications))
+	for _, a := range cfg.Applications {
+		matchers = append(matchers, &pipedservice.DeploymentChainApplicationMatcher{
+			Name:   a.Name,
+			Labels: a.Labels,
+		})
+	}
+	outputs := make(map[string]string, len(cfg.Outputs))
+	for _, key := range cfg.Outputs {
+		if value, ok := s.metadataStore.Get(key); ok {
+			outputs[key] = value
+		}
+	}
+
+	var (
+		err  error
+		resp *pipedservice.CreateDeploymentChainResponse
+		req  = &pipedservice.CreateDeploymentChainRequest{
+			DeploymentId: s.deployment.Id,
+			Matchers:     matchers,
+			Outputs:      outputs,
+		}
+		retry = pipedservice.NewRetry(10)
+	)
+	for retry.WaitNext(ctx) {
+		resp, err = s.apiClient.CreateDeploymentChain(ctx, req)
+		if err == nil {
+			s.logger.Info(fmt.Sprintf("added %d applications to deployment chain %s", len(resp.ApplicationIds), resp.DeploymentChainId),
+				zap.Strings("application-ids", resp.ApplicationIds),
+			)
+			return nil
+		}
+		// Retrying does not help when no application was matched or the chain has already failed.
+		if c := status.Code(err); c == codes.NotFound || c == codes.FailedPrecondition {
+			return err
+		}
+		err = fmt.Errorf("failed to create deployment chain: %w", err)
+	}
+	return err
+}
+
 type stageCommandLister struct {
 	lister       commandLister
 	deploymentID string
@@ -683,3 +1078,91 @@ func (a appAnalysisResultStore) GetLatestAnalysisResult(ctx context.Context) (*m
 func (a appAnalysisResultStore) PutLatestAnalysisResult(ctx context.Context, analysisResult *model.AnalysisResult) error {
 	return a.store.PutLatestAnalysisResult(ctx, a.applicationID, analysisResult)
 }
+
+// eventRegisterer registers the events published by the stages to the control-plane.
+type eventRegisterer struct {
+	apiClient apiClient
+}
+
+func (r eventRegisterer) RegisterEvent(ctx context.Context, name, data string, labels map[string]string) (string, error) {
+	var (
+		err   error
+		resp  *pipedservice.RegisterEventResponse
+		retry = pipedservice.NewRetry(3)
+		req   = &pipedservice.RegisterEventRequest{
+			Name:   name,
+			Data:   data,
+			Labels: labels,
+		}
+	)
+
+	for retry.WaitNext(ctx) {
+		if resp, err = r.apiClient.RegisterEvent(ctx, req); err == nil {
+			return resp.EventId, nil
+		}
+		if !pipedservice.Retriable(err) {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+// stageResultReporter keeps the last error and all warnings reported by the executor
+// to be used as the reason of the completed stage.
+type stageResultReporter struct {
+	mu       sync.Mutex
+	err      error
+	warnings []string
+}
+
+func (r *stageResultReporter) ReportError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+func (r *stageResultReporter) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *stageResultReporter) ReportWarning(warning string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, warning)
+}
+
+func (r *stageResultReporter) Warnings() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.warnings...)
+}
+
+// checkStageBudget compares the actual duration of the given stage with its estimated duration
+// and reports a warning when the stage has exceeded it in the recent deployments consecutively.
+func (s *scheduler) checkStageBudget(ps *model.PipelineStage, duration time.Duration, lp executor.LogPersister, wr executor.WarningReporter) {
+	if ps.EstimatedDuration <= 0 {
+		return
+	}
+	estimated := time.Duration(ps.EstimatedDuration) * time.Second
+	overruns := s.stageBudgetTracker.observe(s.deployment.ApplicationId, ps, duration)
+	if overruns == 0 {
+		return
+	}
+	lp.Log(model.LogSeverity_WARNING, "This stage took longer than its estimated duration", map[string]interface{}{
+		"duration":          duration.Round(time.Second),
+		"estimatedDuration": estimated,
+		"overruns":          overruns,
+	})
+	if overruns >= stageBudgetExceededThreshold {
+		wr.ReportWarning(fmt.Sprintf("exceeded its estimated duration %v in the last %d deployments", estimated, overruns))
+	}
+}
+
+func warningsStatusReason(warnings []string) string {
+	if len(warnings) == 1 {
+		return fmt.Sprintf("Completed with a warning: %s", warnings[0])
+	}
+	return fmt.Sprintf("Completed with %d warnings: %s", len(warnings), strings.Join(warnings, "; "))
+}
@@ -0,0 +1,611 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/logpersister"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deadlineRecordingExecutor struct {
+	deadline time.Time
+}
+
+func (e *deadlineRecordingExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	e.deadline, _ = sig.Deadline()
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func TestExecuteWithTimeoutDeadline(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	testcases := []struct {
+		name           string
+		parentDeadline time.Time
+		want           time.Time
+	}{
+		{
+			name: "deadline from the stage timeout",
+			want: now.Add(10 * time.Minute),
+		},
+		{
+			name:           "earlier deadline of the deployment",
+			parentDeadline: now.Add(5 * time.Minute),
+			want:           now.Add(5 * time.Minute),
+		},
+		{
+			name:           "later deadline of the deployment",
+			parentDeadline: now.Add(time.Hour),
+			want:           now.Add(10 * time.Minute),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &scheduler{
+				nowFunc: func() time.Time { return now },
+			}
+			// Zero deadline means the deployment has no deadline.
+			sig, _ := executor.NewStopSignalWithDeadline(tc.parentDeadline)
+			ex := &deadlineRecordingExecutor{}
+
+			status := s.executeWithTimeout(sig, ex, 10*time.Minute, nil, nil)
+			assert.Equal(t, model.StageStatus_STAGE_SUCCESS, status)
+			assert.Equal(t, tc.want, ex.deadline)
+		})
+	}
+}
+
+type fakeStageStatusAPIClient struct {
+	apiClient
+
+	mu      sync.Mutex
+	reports []*pipedservice.ReportStageStatusChangedRequest
+}
+
+func (c *fakeStageStatusAPIClient) ReportStageStatusChanged(_ context.Context, req *pipedservice.ReportStageStatusChangedRequest, _ ...grpc.CallOption) (*pipedservice.ReportStageStatusChangedResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reports = append(c.reports, req)
+	return &pipedservice.ReportStageStatusChangedResponse{}, nil
+}
+
+type fakePersister struct {
+	// Called with the logs written by Infof of all stages.
+	onInfo func(log string)
+}
+
+func (p *fakePersister) Run(_ context.Context) error {
+	return nil
+}
+
+func (p *fakePersister) StageLogPersister(_ *model.Deployment, _ string) logpersister.StageLogPersister {
+	return &fakeStageLogPersister{onInfo: p.onInfo}
+}
+
+type fakeStageLogPersister struct {
+	executortest.FakeLogPersister
+	onInfo func(log string)
+}
+
+func (l *fakeStageLogPersister) Infof(format string, a ...interface{}) {
+	if l.onInfo != nil {
+		l.onInfo(fmt.Sprintf(format, a...))
+	}
+}
+
+func (l *fakeStageLogPersister) Complete(_ time.Duration) error {
+	return nil
+}
+
+type fakeApplicationLister struct{}
+
+func (l fakeApplicationLister) Get(id string) (*model.Application, bool) {
+	return &model.Application{Id: id}, true
+}
+
+// stageResult is the result returned by a fake executor.
+type stageResult struct {
+	status model.StageStatus
+	err    error
+}
+
+type fakeExecutor struct {
+	result   stageResult
+	reporter executor.ErrorReporter
+}
+
+func (e *fakeExecutor) Execute(_ executor.StopSignal) model.StageStatus {
+	if e.result.err != nil {
+		e.reporter.ReportError(e.result.err)
+	}
+	return e.result.status
+}
+
+// fakeExecutorFactory creates the executors returning the given results in order.
+type fakeExecutorFactory struct {
+	results []stageResult
+	created int
+}
+
+func (f *fakeExecutorFactory) newExecutor(in executor.Input) (executor.Executor, bool) {
+	r := f.results[len(f.results)-1]
+	if f.created < len(f.results) {
+		r = f.results[f.created]
+	}
+	f.created++
+	return &fakeExecutor{result: r, reporter: in.ErrorReporter}, true
+}
+
+func newTestScheduler(client apiClient, lp logpersister.Persister, stage *model.PipelineStage, retries *config.StageRetries) *scheduler {
+	d := &model.Deployment{
+		Id:            "deployment-1",
+		ApplicationId: "app-1",
+		Kind:          model.ApplicationKind_KUBERNETES,
+		CloudProvider: "kubernetes-default",
+		Stages:        []*model.PipelineStage{stage},
+	}
+	pipedConfig := &config.PipedSpec{
+		CloudProviders: []config.PipedCloudProvider{
+			{Name: "kubernetes-default", Type: model.CloudProviderKubernetes},
+		},
+	}
+	s := newScheduler(d, "", "", client, nil, nil, fakeApplicationLister{}, nil, nil, lp, nil, nil, pipedConfig, nil, nil, zap.NewNop())
+	s.genericDeploymentConfig = config.GenericDeploymentSpec{
+		Pipeline: &config.DeploymentPipeline{
+			Stages: []config.PipelineStage{
+				{Name: model.StageWait, Retries: retries},
+			},
+		},
+	}
+	return s
+}
+
+func TestExecuteStageRetry(t *testing.T) {
+	var (
+		systemErr = executor.NewSystemError("connection refused")
+		userErr   = executor.NewUserError("invalid template")
+	)
+	testcases := []struct {
+		name    string
+		retries *config.StageRetries
+		// The retried count restored from the previous piped.
+		retriedCount int32
+		results      []stageResult
+		// Whether the deployment is cancelled while waiting for the retry.
+		cancelOnRetry bool
+
+		expectedStatus       model.StageStatus
+		expectedCreated      int
+		expectedRetriedCount int32
+		expectedReason       string
+	}{
+		{
+			name: "retried after failure",
+			retries: &config.StageRetries{
+				Limit:   2,
+				Backoff: config.Duration(time.Millisecond),
+			},
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: systemErr},
+				{status: model.StageStatus_STAGE_SUCCESS},
+			},
+			expectedStatus:       model.StageStatus_STAGE_SUCCESS,
+			expectedCreated:      2,
+			expectedRetriedCount: 1,
+		},
+		{
+			name: "failed after the retries were exhausted",
+			retries: &config.StageRetries{
+				Limit:   1,
+				Backoff: config.Duration(time.Millisecond),
+			},
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: systemErr},
+			},
+			expectedStatus:       model.StageStatus_STAGE_FAILURE,
+			expectedCreated:      2,
+			expectedRetriedCount: 1,
+			expectedReason:       "System error: connection refused",
+		},
+		{
+			name: "not retried on user error",
+			retries: &config.StageRetries{
+				Limit:   2,
+				Backoff: config.Duration(time.Millisecond),
+			},
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: userErr},
+			},
+			expectedStatus:  model.StageStatus_STAGE_FAILURE,
+			expectedCreated: 1,
+			expectedReason:  "Configuration error: invalid template",
+		},
+		{
+			name: "not retried without retry policy",
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: systemErr},
+			},
+			expectedStatus:  model.StageStatus_STAGE_FAILURE,
+			expectedCreated: 1,
+			expectedReason:  "System error: connection refused",
+		},
+		{
+			name: "retried count restored from the previous piped",
+			retries: &config.StageRetries{
+				Limit:   2,
+				Backoff: config.Duration(time.Millisecond),
+			},
+			retriedCount: 1,
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: systemErr},
+				{status: model.StageStatus_STAGE_SUCCESS},
+			},
+			expectedStatus:       model.StageStatus_STAGE_SUCCESS,
+			expectedCreated:      2,
+			expectedRetriedCount: 2,
+		},
+		{
+			name: "cancelled while waiting for the retry",
+			retries: &config.StageRetries{
+				Limit:   2,
+				Backoff: config.Duration(time.Hour),
+			},
+			results: []stageResult{
+				{status: model.StageStatus_STAGE_FAILURE, err: systemErr},
+			},
+			cancelOnRetry:   true,
+			expectedStatus:  model.StageStatus_STAGE_CANCELLED,
+			expectedCreated: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				client = &fakeStageStatusAPIClient{}
+				lp     = &fakePersister{}
+				stage  = &model.PipelineStage{
+					Id:           "stage-1",
+					Name:         model.StageWait.String(),
+					RetriedCount: tc.retriedCount,
+				}
+				factory     = &fakeExecutorFactory{results: tc.results}
+				sig, handle = executor.NewStopSignal()
+			)
+			if tc.cancelOnRetry {
+				lp.onInfo = func(log string) {
+					if strings.HasPrefix(log, "Retrying the stage") {
+						handle.Cancel()
+					}
+				}
+			}
+			s := newTestScheduler(client, lp, stage, tc.retries)
+
+			status := s.executeStage(sig, *stage, factory.newExecutor)
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedStatus, s.stageStatuses[stage.Id])
+			assert.Equal(t, tc.expectedCreated, factory.created)
+			assert.Equal(t, tc.expectedRetriedCount, s.stageRetriedCounts[stage.Id])
+
+			// The stage status reported to the control-plane carries the retried count
+			// so that the restarted piped can resume the retries.
+			if tc.cancelOnRetry {
+				return
+			}
+			last := client.reports[len(client.reports)-1]
+			assert.Equal(t, tc.expectedStatus, last.Status)
+			assert.Equal(t, tc.expectedRetriedCount, last.RetriedCount)
+			assert.Equal(t, tc.expectedReason, last.StatusReason)
+		})
+	}
+}
+
+func TestWaitForRetry(t *testing.T) {
+	retries := &config.StageRetries{
+		Limit:   2,
+		Backoff: config.Duration(time.Millisecond),
+	}
+	testcases := []struct {
+		name         string
+		retries      *config.StageRetries
+		retriedCount int32
+		err          error
+		stopped      bool
+
+		expected             bool
+		expectedRetriedCount int32
+		expectedReason       string
+	}{
+		{
+			name:                 "retried",
+			retries:              retries,
+			err:                  executor.NewSystemError("timed out"),
+			expected:             true,
+			expectedRetriedCount: 1,
+			expectedReason:       "Retrying 1/2 after failure (System error: timed out)",
+		},
+		{
+			name:                 "retried without error",
+			retries:              retries,
+			retriedCount:         1,
+			expected:             true,
+			expectedRetriedCount: 2,
+			expectedReason:       "Retrying 2/2",
+		},
+		{
+			name:                 "limit reached",
+			retries:              retries,
+			retriedCount:         2,
+			expectedRetriedCount: 2,
+		},
+		{
+			name:    "no retry policy",
+			retries: nil,
+		},
+		{
+			name:    "non-retryable error",
+			retries: retries,
+			err:     executor.NewUserError("missing field"),
+		},
+		{
+			name: "stopped while waiting",
+			retries: &config.StageRetries{
+				Limit:   2,
+				Backoff: config.Duration(time.Hour),
+			},
+			stopped: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				client = &fakeStageStatusAPIClient{}
+				stage  = &model.PipelineStage{
+					Id:           "stage-1",
+					Name:         model.StageWait.String(),
+					RetriedCount: tc.retriedCount,
+				}
+				sig, handle = executor.NewStopSignal()
+			)
+			if tc.stopped {
+				handle.Terminate()
+			}
+			s := newTestScheduler(client, &fakePersister{}, stage, tc.retries)
+
+			got := s.waitForRetry(sig, stage, tc.retries, tc.err, &fakeStageLogPersister{})
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expectedRetriedCount, s.stageRetriedCounts[stage.Id])
+			if !tc.expected {
+				assert.Empty(t, client.reports)
+				return
+			}
+			// The retry is reported as running with the reason
+			// but the reason is not kept for the deployment status.
+			assert.Len(t, client.reports, 1)
+			assert.Equal(t, model.StageStatus_STAGE_RUNNING, client.reports[0].Status)
+			assert.Equal(t, tc.expectedReason, client.reports[0].StatusReason)
+			assert.Equal(t, tc.expectedRetriedCount, client.reports[0].RetriedCount)
+			assert.NotContains(t, s.stageStatusReasons, stage.Id)
+		})
+	}
+}
+
+type fakeCommandLister struct {
+	mu       sync.Mutex
+	commands []model.ReportableCommand
+}
+
+func (l *fakeCommandLister) ListDeploymentCommands() []model.ReportableCommand {
+	return nil
+}
+
+func (l *fakeCommandLister) ListStageCommands(deploymentID, stageID string) []model.ReportableCommand {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var commands []model.ReportableCommand
+	for _, cmd := range l.commands {
+		if cmd.DeploymentId == deploymentID && cmd.StageId == stageID {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+func (l *fakeCommandLister) add(cmd model.ReportableCommand) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.commands = append(l.commands, cmd)
+}
+
+// commandReports records the statuses reported for the commands keyed by their ids.
+type commandReports struct {
+	mu       sync.Mutex
+	statuses map[string]model.CommandStatus
+}
+
+func (r *commandReports) newCommand(cmd *model.Command) model.ReportableCommand {
+	return model.ReportableCommand{
+		Command: cmd,
+		Report: func(_ context.Context, status model.CommandStatus, _ map[string]string, _ []byte) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.statuses[cmd.Id] = status
+			return nil
+		},
+	}
+}
+
+func (r *commandReports) get(id string) (model.CommandStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status, ok := r.statuses[id]
+	return status, ok
+}
+
+func newSkipStageCommand(id, stageID string) *model.Command {
+	return &model.Command{
+		Id:           id,
+		DeploymentId: "deployment-1",
+		StageId:      stageID,
+		Commander:    "user",
+		Type:         model.Command_SKIP_STAGE,
+		SkipStage: &model.Command_SkipStage{
+			DeploymentId: "deployment-1",
+			StageId:      stageID,
+		},
+	}
+}
+
+func TestSkipPendingStage(t *testing.T) {
+	var (
+		client  = &fakeStageStatusAPIClient{}
+		reports = &commandReports{statuses: make(map[string]model.CommandStatus)}
+		lister  = &fakeCommandLister{}
+		stage   = &model.PipelineStage{
+			Id:   "stage-1",
+			Name: model.StageWait.String(),
+		}
+	)
+	lister.add(reports.newCommand(&model.Command{
+		Id:           "cancel",
+		DeploymentId: "deployment-1",
+		StageId:      "stage-1",
+		Type:         model.Command_CANCEL_DEPLOYMENT,
+		CancelDeployment: &model.Command_CancelDeployment{
+			DeploymentId: "deployment-1",
+		},
+	}))
+	lister.add(reports.newCommand(newSkipStageCommand("skip", "stage-1")))
+	s := newTestScheduler(client, &fakePersister{}, stage, nil)
+	s.commandLister = lister
+
+	_, ok := s.findSkipStageCommand("stage-2")
+	assert.False(t, ok)
+
+	cmd, ok := s.findSkipStageCommand("stage-1")
+	require.True(t, ok)
+	assert.Equal(t, "skip", cmd.Id)
+
+	s.skipStage(context.Background(), stage, cmd)
+	assert.Equal(t, model.StageStatus_STAGE_SKIPPED, s.stageStatuses[stage.Id])
+	require.Len(t, client.reports, 1)
+	assert.Equal(t, model.StageStatus_STAGE_SKIPPED, client.reports[0].Status)
+	assert.Equal(t, "Skipped by user", client.reports[0].StatusReason)
+
+	status, ok := reports.get("skip")
+	assert.True(t, ok)
+	assert.Equal(t, model.CommandStatus_COMMAND_SUCCEEDED, status)
+	_, ok = reports.get("cancel")
+	assert.False(t, ok)
+}
+
+// blockingExecutor runs until it is asked to stop.
+type blockingExecutor struct {
+	started chan struct{}
+}
+
+func (e *blockingExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	close(e.started)
+	<-sig.Ch()
+	return executor.DetermineStageStatus(sig.Signal(), model.StageStatus_STAGE_RUNNING, model.StageStatus_STAGE_FAILURE)
+}
+
+func TestRunStage(t *testing.T) {
+	testcases := []struct {
+		name string
+		// Called once the executor has started.
+		onStarted func(s *scheduler, lister *fakeCommandLister, reports *commandReports)
+
+		expectedStatus       model.StageStatus
+		expectedReason       string
+		expectedCancelled    bool
+		expectedSkipReported bool
+	}{
+		{
+			name: "skipped while running",
+			onStarted: func(s *scheduler, lister *fakeCommandLister, reports *commandReports) {
+				lister.add(reports.newCommand(newSkipStageCommand("skip", "stage-1")))
+			},
+			expectedStatus:       model.StageStatus_STAGE_SKIPPED,
+			expectedReason:       "Skipped by a user while running",
+			expectedSkipReported: true,
+		},
+		{
+			name: "skip command of another stage",
+			onStarted: func(s *scheduler, lister *fakeCommandLister, reports *commandReports) {
+				lister.add(reports.newCommand(newSkipStageCommand("skip", "stage-2")))
+				// Wait for the ticks checking the skip command before cancelling.
+				time.Sleep(10 * time.Millisecond)
+				s.Cancel(reports.newCommand(&model.Command{Id: "cancel", Commander: "user"}))
+			},
+			expectedStatus:    model.StageStatus_STAGE_CANCELLED,
+			expectedCancelled: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				client  = &fakeStageStatusAPIClient{}
+				reports = &commandReports{statuses: make(map[string]model.CommandStatus)}
+				lister  = &fakeCommandLister{}
+				stage   = &model.PipelineStage{
+					Id:   "stage-1",
+					Name: model.StageWait.String(),
+				}
+				ex = &blockingExecutor{started: make(chan struct{})}
+			)
+			s := newTestScheduler(client, &fakePersister{}, stage, nil)
+			s.commandLister = lister
+			s.skipCheckInterval = time.Millisecond
+
+			go func() {
+				<-ex.started
+				tc.onStarted(s, lister, reports)
+			}()
+			status, sig, cancelCommand := s.runStage(context.Background(), stage, time.Time{}, nil, func(_ executor.Input) (executor.Executor, bool) {
+				return ex, true
+			})
+			assert.Equal(t, tc.expectedStatus, status)
+			assert.Equal(t, tc.expectedCancelled, cancelCommand != nil)
+			assert.Equal(t, tc.expectedStatus, s.stageStatuses[stage.Id])
+			if tc.expectedReason != "" {
+				assert.Equal(t, executor.StopSignalSkip, sig.Signal())
+				last := client.reports[len(client.reports)-1]
+				assert.Equal(t, tc.expectedStatus, last.Status)
+				assert.Equal(t, tc.expectedReason, last.StatusReason)
+			}
+
+			status, ok := reports.get("skip")
+			assert.Equal(t, tc.expectedSkipReported, ok)
+			if ok {
+				assert.Equal(t, model.CommandStatus_COMMAND_SUCCEEDED, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// How many consecutive deployments a stage must exceed its estimated duration
+// to be reported as consistently exceeding it.
+const stageBudgetExceededThreshold = 3
+
+// stageBudgetTracker counts how many times in a row each stage of each application
+// has exceeded its estimated duration.
+// The counts are kept in memory so they are reset when piped restarts.
+type stageBudgetTracker struct {
+	mu sync.Mutex
+	// Map from application ID and stage ID to the number of consecutive overruns.
+	overruns map[string]int
+}
+
+func newStageBudgetTracker() *stageBudgetTracker {
+	return &stageBudgetTracker{
+		overruns: make(map[string]int),
+	}
+}
+
+// observe records the actual duration of the given stage
+// and returns the number of consecutive deployments in which the stage exceeded its estimated duration.
+func (t *stageBudgetTracker) observe(appID string, stage *model.PipelineStage, actual time.Duration) int {
+	if stage.EstimatedDuration <= 0 {
+		return 0
+	}
+	key := fmt.Sprintf("%s/%s", appID, stage.Id)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if actual <= time.Duration(stage.EstimatedDuration)*time.Second {
+		delete(t.overruns, key)
+		return 0
+	}
+	t.overruns[key]++
+	return t.overruns[key]
+}
+
+// estimatedPipelineSummary returns the summary of the estimated duration of the given stages.
+// Empty is returned when none of the visible stages declared its estimated duration.
+func estimatedPipelineSummary(stages []*model.PipelineStage) string {
+	var (
+		total              time.Duration
+		estimated, visible int
+	)
+	for _, s := range stages {
+		if !s.Visible {
+			continue
+		}
+		visible++
+		if s.EstimatedDuration > 0 {
+			estimated++
+			total += time.Duration(s.EstimatedDuration) * time.Second
+		}
+	}
+	switch {
+	case estimated == 0:
+		return ""
+	case estimated < visible:
+		return fmt.Sprintf("ETA %v for %d of %d stages", total, estimated, visible)
+	default:
+		return fmt.Sprintf("ETA %v", total)
+	}
+}
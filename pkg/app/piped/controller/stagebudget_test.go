@@ -0,0 +1,81 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestStageBudgetTracker(t *testing.T) {
+	var (
+		tracker = newStageBudgetTracker()
+		stage   = &model.PipelineStage{Id: "stage-0", EstimatedDuration: 60}
+	)
+
+	assert.Equal(t, 1, tracker.observe("app-1", stage, 2*time.Minute))
+	assert.Equal(t, 2, tracker.observe("app-1", stage, 2*time.Minute))
+	// The counts are kept per application.
+	assert.Equal(t, 1, tracker.observe("app-2", stage, 2*time.Minute))
+	// Completing within the estimated duration resets the count.
+	assert.Equal(t, 0, tracker.observe("app-1", stage, time.Minute))
+	assert.Equal(t, 1, tracker.observe("app-1", stage, 2*time.Minute))
+	// The stage without estimated duration is never counted.
+	assert.Equal(t, 0, tracker.observe("app-1", &model.PipelineStage{Id: "stage-1"}, time.Hour))
+}
+
+func TestEstimatedPipelineSummary(t *testing.T) {
+	testcases := []struct {
+		name     string
+		stages   []*model.PipelineStage
+		expected string
+	}{
+		{
+			name: "no estimated stage",
+			stages: []*model.PipelineStage{
+				{Id: "stage-0", Visible: true},
+			},
+			expected: "",
+		},
+		{
+			name: "all stages are estimated",
+			stages: []*model.PipelineStage{
+				{Id: "stage-0", Visible: true, EstimatedDuration: 60},
+				{Id: "stage-1", Visible: true, EstimatedDuration: 600},
+				{Id: "rollback", Visible: false},
+			},
+			expected: "ETA 11m0s",
+		},
+		{
+			name: "some stages are estimated",
+			stages: []*model.PipelineStage{
+				{Id: "stage-0", Visible: true, EstimatedDuration: 300},
+				{Id: "stage-1", Visible: true},
+				{Id: "stage-2", Visible: true, EstimatedDuration: 300},
+			},
+			expected: "ETA 10m0s for 2 of 3 stages",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := estimatedPipelineSummary(tc.stages)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/pipe-cd/pipe/pkg/app/piped/sourcedecrypter"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
+	"github.com/pipe-cd/pipe/pkg/oci"
 )
 
 type DeploySource struct {
@@ -46,6 +47,10 @@ type secretDecrypter interface {
 	Decrypt(string) (string, error)
 }
 
+type ociPuller interface {
+	Pull(ctx context.Context, reference, dest string) (string, error)
+}
+
 type provider struct {
 	workingDir      string
 	cloner          SourceCloner
@@ -53,6 +58,7 @@ type provider struct {
 	revision        string
 	appGitPath      model.ApplicationGitPath
 	secretDecrypter secretDecrypter
+	ociPuller       ociPuller
 
 	done    bool
 	source  *DeploySource
@@ -75,6 +81,7 @@ func NewProvider(
 		revision:        cloner.Revision(),
 		appGitPath:      appGitPath,
 		secretDecrypter: sd,
+		ociPuller:       oci.DefaultClient(),
 	}
 }
 
@@ -171,6 +178,17 @@ func (p *provider) prepare(ctx context.Context, lw io.Writer) (*DeploySource, er
 	}
 	fmt.Fprintln(lw, "Successfully loaded the deployment configuration file")
 
+	// Pull the application manifests from the OCI artifact if configured.
+	// The pulled files overwrite the ones committed in the application directory.
+	if o := gdc.OCISource; o != nil {
+		digest, err := p.ociPuller.Pull(ctx, o.Reference, appDir)
+		if err != nil {
+			fmt.Fprintf(lw, "Unable to pull the OCI artifact %s (%v)\n", o.Reference, err)
+			return nil, err
+		}
+		fmt.Fprintf(lw, "Successfully pulled the OCI artifact %s (%s)\n", o.Reference, digest)
+	}
+
 	// Decrypt the sealed secrets if needed.
 	if len(gdc.SealedSecrets) > 0 && p.secretDecrypter != nil {
 		if err := sourcedecrypter.DecryptSealedSecrets(appDir, gdc.SealedSecrets, p.secretDecrypter); err != nil {
@@ -186,6 +204,20 @@ func (p *provider) prepare(ctx context.Context, lw io.Writer) (*DeploySource, er
 		}
 		fmt.Fprintf(lw, "Successfully decrypted secrets: %v\n", gdc.Encryption.DecryptionTargets)
 	}
+	if gdc.Sops != nil && len(gdc.Sops.DecryptionTargets) > 0 {
+		if err := sourcedecrypter.DecryptSopsFiles(ctx, appDir, *gdc.Sops); err != nil {
+			fmt.Fprintf(lw, "Unable to decrypt the files encrypted by sops (%v)\n", err)
+			return nil, err
+		}
+		fmt.Fprintf(lw, "Successfully decrypted files encrypted by sops: %v\n", gdc.Sops.DecryptionTargets)
+	}
+	if gdc.ExternalSecrets != nil && len(gdc.ExternalSecrets.Targets) > 0 {
+		if err := sourcedecrypter.ResolveExternalSecrets(ctx, appDir, *gdc.ExternalSecrets); err != nil {
+			fmt.Fprintf(lw, "Unable to resolve external secrets (%v)\n", err)
+			return nil, err
+		}
+		fmt.Fprintf(lw, "Successfully resolved external secrets: %v\n", gdc.ExternalSecrets.Targets)
+	}
 
 	return &DeploySource{
 		RepoDir:                 repoDir,
@@ -13,3 +13,119 @@
 // limitations under the License.
 
 package deploysource
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeSourceCloner struct {
+	files map[string]string
+}
+
+func (c *fakeSourceCloner) Clone(_ context.Context, dest string) error {
+	for name, content := range c.files {
+		path := filepath.Join(dest, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fakeSourceCloner) Revision() string {
+	return "revision"
+}
+
+func (c *fakeSourceCloner) RevisionName() string {
+	return "target"
+}
+
+type fakeOCIPuller struct {
+	files     map[string]string
+	err       error
+	reference string
+}
+
+func (p *fakeOCIPuller) Pull(_ context.Context, reference, dest string) (string, error) {
+	p.reference = reference
+	if p.err != nil {
+		return "", p.err
+	}
+	for name, content := range p.files {
+		if err := ioutil.WriteFile(filepath.Join(dest, name), []byte(content), 0644); err != nil {
+			return "", err
+		}
+	}
+	return "sha256:digest", nil
+}
+
+func TestPrepareWithOCISource(t *testing.T) {
+	const appConfig = `
+apiVersion: pipecd.dev/v1beta1
+kind: KubernetesApp
+spec:
+  ociSource:
+    reference: ghcr.io/org/manifests:v1.0.0
+`
+	testcases := []struct {
+		name      string
+		pullErr   error
+		wantFiles map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "artifact files overwrite the committed ones",
+			wantFiles: map[string]string{
+				"deployment.yaml": "pulled",
+				"service.yaml":    "committed",
+			},
+		},
+		{
+			name:    "failed to pull",
+			pullErr: errors.New("unauthorized"),
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cloner := &fakeSourceCloner{
+				files: map[string]string{
+					"app/.pipe.yaml":      appConfig,
+					"app/deployment.yaml": "committed",
+					"app/service.yaml":    "committed",
+				},
+			}
+			puller := &fakeOCIPuller{
+				files: map[string]string{"deployment.yaml": "pulled"},
+				err:   tc.pullErr,
+			}
+			p := NewProvider(t.TempDir(), cloner, model.ApplicationGitPath{Path: "app"}, nil).(*provider)
+			p.ociPuller = puller
+
+			ds, err := p.GetReadOnly(context.Background(), ioutil.Discard)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, "ghcr.io/org/manifests:v1.0.0", puller.reference)
+			if err != nil {
+				return
+			}
+			for name, content := range tc.wantFiles {
+				data, err := ioutil.ReadFile(filepath.Join(ds.AppDir, name))
+				require.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+		})
+	}
+}
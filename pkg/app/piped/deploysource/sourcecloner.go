@@ -28,7 +28,7 @@ type SourceCloner interface {
 }
 
 type gitClient interface {
-	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+	Worktree(ctx context.Context, repoID, remote, revision, destination string) (git.Repo, error)
 }
 
 func NewGitSourceCloner(gc gitClient, cfg config.PipedRepository, revisionName, revision string) SourceCloner {
@@ -55,15 +55,11 @@ func (d *gitSourceCloner) RevisionName() string {
 	return d.revisionName
 }
 
+// Clone creates a working tree of the revision at the given destination
+// instead of cloning the whole repository since the deploy source is only read.
 func (d *gitSourceCloner) Clone(ctx context.Context, dest string) error {
-	repo, err := d.gc.Clone(ctx, d.cfg.RepoID, d.cfg.Remote, d.cfg.Branch, dest)
-	if err != nil {
-		return err
-	}
-	if err := repo.Checkout(ctx, d.revision); err != nil {
-		return err
-	}
-	return nil
+	_, err := d.gc.Worktree(ctx, d.cfg.RepoID, d.cfg.Remote, d.revision, dest)
+	return err
 }
 
 type localSourceCloner struct {
@@ -1,4 +1,4 @@
-// Copyright 2020 The PipeCD Authors.
+// Copyright 2021 The PipeCD Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -13,3 +13,350 @@
 // limitations under the License.
 
 package cloudrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/sourcedecrypter"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/diff"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type applicationLister interface {
+	ListByCloudProvider(name string) []*model.Application
+}
+
+type gitClient interface {
+	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+}
+
+type secretDecrypter interface {
+	Decrypt(string) (string, error)
+}
+
+type reporter interface {
+	ReportApplicationSyncState(ctx context.Context, appID string, state model.ApplicationSyncState) error
+}
+
+type detector struct {
+	provider        config.PipedCloudProvider
+	appLister       applicationLister
+	gitClient       gitClient
+	reporter        reporter
+	interval        time.Duration
+	config          *config.PipedSpec
+	secretDecrypter secretDecrypter
+	logger          *zap.Logger
+
+	gitRepos map[string]git.Repo
+}
+
+func NewDetector(
+	cp config.PipedCloudProvider,
+	appLister applicationLister,
+	gitClient gitClient,
+	reporter reporter,
+	cfg *config.PipedSpec,
+	sd secretDecrypter,
+	logger *zap.Logger,
+) *detector {
+
+	logger = logger.Named("cloudrun-detector").With(
+		zap.String("cloud-provider", cp.Name),
+	)
+	return &detector{
+		provider:        cp,
+		appLister:       appLister,
+		gitClient:       gitClient,
+		reporter:        reporter,
+		interval:        time.Minute,
+		config:          cfg,
+		secretDecrypter: sd,
+		gitRepos:        make(map[string]git.Repo),
+		logger:          logger,
+	}
+}
+
+func (d *detector) Run(ctx context.Context) error {
+	d.logger.Info("start running drift detector for cloudrun applications")
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ticker.C:
+			d.check(ctx)
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
+	d.logger.Info("drift detector for cloudrun applications has been stopped")
+	return nil
+}
+
+func (d *detector) check(ctx context.Context) error {
+	client, err := provider.DefaultRegistry().Client(ctx, d.provider.Name, d.provider.CloudRunConfig, d.logger)
+	if err != nil {
+		d.logger.Error("failed to create cloudrun client", zap.Error(err))
+		return err
+	}
+
+	appsByRepo := d.listGroupedApplication()
+
+	for repoID, apps := range appsByRepo {
+		gitRepo, ok := d.gitRepos[repoID]
+		if !ok {
+			// Clone repository for the first time.
+			repoCfg, ok := d.config.GetRepository(repoID)
+			if !ok {
+				d.logger.Error(fmt.Sprintf("repository %s was not found in piped configuration", repoID))
+				continue
+			}
+			gr, err := d.gitClient.Clone(ctx, repoID, repoCfg.Remote, repoCfg.Branch, "")
+			if err != nil {
+				d.logger.Error("failed to clone repository",
+					zap.String("repo-id", repoID),
+					zap.Error(err),
+				)
+				continue
+			}
+			gitRepo = gr
+			d.gitRepos[repoID] = gitRepo
+		}
+
+		// Fetch the latest commit to compare the states.
+		branch := gitRepo.GetClonedBranch()
+		if err := gitRepo.Pull(ctx, branch); err != nil {
+			d.logger.Error("failed to update repository branch",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Get the head commit of the repository.
+		headCommit, err := gitRepo.GetLatestCommit(ctx)
+		if err != nil {
+			d.logger.Error("failed to get head commit hash",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Start checking all applications in this repository.
+		for _, app := range apps {
+			if err := d.checkApplication(ctx, client, app, gitRepo, headCommit); err != nil {
+				d.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *detector) checkApplication(ctx context.Context, client provider.Client, app *model.Application, repo git.Repo, headCommit git.Commit) error {
+	cfg, err := d.loadDeploymentConfiguration(repo.GetPath(), app)
+	if err != nil {
+		return fmt.Errorf("failed to load deployment configuration: %w", err)
+	}
+
+	headManifest, err := d.loadHeadServiceManifest(ctx, app, cfg, repo)
+	if err != nil {
+		return err
+	}
+
+	svc, err := client.GetService(ctx, headManifest.Name)
+	if errors.Is(err, provider.ErrServiceNotFound) {
+		state := makeOutOfSyncState(fmt.Sprintf("Service %s was not found", headManifest.Name), "", headCommit.Hash)
+		return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
+	}
+	if err != nil {
+		return err
+	}
+	liveManifest, err := provider.ParseService(svc)
+	if err != nil {
+		return err
+	}
+
+	ignoredPaths := revisionSpecIgnoredPaths(cfg.CloudRunDeploymentSpec.DriftDetection)
+	result, err := diffRevisionSpec(headManifest, liveManifest, ignoredPaths...)
+	if err != nil {
+		return err
+	}
+	d.logger.Info(fmt.Sprintf("application %s has %d differences in the revision spec at commit %s", app.Id, result.NumNodes(), headCommit.Hash))
+
+	if !result.HasDiff() {
+		return d.reporter.ReportApplicationSyncState(ctx, app.Id, makeSyncedState())
+	}
+	details := diff.NewRenderer(diff.WithLeftPadding(1)).Render(result.Nodes())
+	shortReason := fmt.Sprintf("There are %d differences in the revision spec of service %s", result.NumNodes(), headManifest.Name)
+	state := makeOutOfSyncState(shortReason, details, headCommit.Hash)
+	return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
+}
+
+func (d *detector) loadHeadServiceManifest(ctx context.Context, app *model.Application, cfg *config.Config, repo git.Repo) (provider.ServiceManifest, error) {
+	var (
+		appDir = filepath.Join(repo.GetPath(), app.GitPath.Path)
+		gds    = cfg.CloudRunDeploymentSpec.GenericDeploymentSpec
+	)
+
+	var (
+		shouldDecryptSecrets   = d.secretDecrypter != nil && gds.Encryption != nil
+		shouldDecryptSopsFiles = gds.Sops != nil && len(gds.Sops.DecryptionTargets) > 0
+	)
+
+	if shouldDecryptSecrets || shouldDecryptSopsFiles {
+		// We have to copy repository into another directory because
+		// decrypting the secrets might change the git repository.
+		dir, err := ioutil.TempDir("", "detector-git-decrypt")
+		if err != nil {
+			return provider.ServiceManifest{}, fmt.Errorf("failed to prepare a temporary directory for git repository (%w)", err)
+		}
+		defer os.RemoveAll(dir)
+
+		repo, err = repo.Copy(filepath.Join(dir, "repo"))
+		if err != nil {
+			return provider.ServiceManifest{}, fmt.Errorf("failed to copy the cloned git repository (%w)", err)
+		}
+		appDir = filepath.Join(repo.GetPath(), app.GitPath.Path)
+
+		if shouldDecryptSecrets {
+			if err := sourcedecrypter.DecryptSecrets(appDir, *gds.Encryption, d.secretDecrypter); err != nil {
+				return provider.ServiceManifest{}, fmt.Errorf("failed to decrypt secrets (%w)", err)
+			}
+		}
+		if shouldDecryptSopsFiles {
+			if err := sourcedecrypter.DecryptSopsFiles(ctx, appDir, *gds.Sops); err != nil {
+				return provider.ServiceManifest{}, fmt.Errorf("failed to decrypt files encrypted by sops (%w)", err)
+			}
+		}
+	}
+
+	sm, err := provider.LoadServiceManifest(appDir, cfg.CloudRunDeploymentSpec.Input.ServiceManifestFile)
+	if err != nil {
+		return provider.ServiceManifest{}, fmt.Errorf("failed to load service manifest: %w", err)
+	}
+	return sm, nil
+}
+
+// listGroupedApplication retrieves all applications those should be handled by this director
+// and then groups them by repoID.
+func (d *detector) listGroupedApplication() map[string][]*model.Application {
+	var (
+		apps = d.appLister.ListByCloudProvider(d.provider.Name)
+		m    = make(map[string][]*model.Application)
+	)
+	for _, app := range apps {
+		repoID := app.GitPath.Repo.Id
+		m[repoID] = append(m[repoID], app)
+	}
+	return m
+}
+
+func (d *detector) loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.Config, error) {
+	path := filepath.Join(repoPath, app.GitPath.GetDeploymentConfigFilePath())
+	cfg, err := config.LoadFromYAML(path)
+	if err != nil {
+		return nil, err
+	}
+	if appKind, ok := config.ToApplicationKind(cfg.Kind); !ok || appKind != app.Kind {
+		return nil, fmt.Errorf("application in deployment configuration file is not match, got: %s, expected: %s", appKind, app.Kind)
+	}
+	if cfg.CloudRunDeploymentSpec == nil {
+		return nil, fmt.Errorf("missing CloudRun spec field in deployment configuration")
+	}
+	return cfg, nil
+}
+
+func (d *detector) ProviderName() string {
+	return d.provider.Name
+}
+
+// diffRevisionSpec compares the revision spec defined in Git with the live one.
+// The fields which are not defined in Git are ignored since Cloud Run fills them by the default values.
+// The given ignored paths are relative to the revision spec.
+func diffRevisionSpec(head, live provider.ServiceManifest, ignoredPaths ...string) (*diff.Result, error) {
+	headSpec, err := head.RevisionSpec()
+	if err != nil {
+		return nil, err
+	}
+	liveSpec, err := live.RevisionSpec()
+	if err != nil {
+		return nil, err
+	}
+	return diff.DiffUnstructureds(
+		unstructured.Unstructured{Object: headSpec},
+		unstructured.Unstructured{Object: liveSpec},
+		diff.WithEquateEmpty(),
+		diff.WithIgnoreAddingMapKeys(),
+		diff.WithCompareNumberAndNumericString(),
+		diff.WithIgnoredPaths(ignoredPaths...),
+	)
+}
+
+// revisionSpecIgnoredPaths returns the configured paths to be ignored under the revision spec
+// by trimming the spec.template.spec prefix since only the revision spec is compared.
+func revisionSpecIgnoredPaths(dd *config.DriftDetection) []string {
+	const prefix = "spec.template.spec."
+	if dd == nil {
+		return nil
+	}
+	var paths []string
+	for _, f := range dd.IgnoreFields {
+		for _, p := range f.Paths {
+			if strings.HasPrefix(p, prefix) {
+				paths = append(paths, strings.TrimPrefix(p, prefix))
+			}
+		}
+	}
+	return paths
+}
+
+func makeSyncedState() model.ApplicationSyncState {
+	return model.ApplicationSyncState{
+		Status:      model.ApplicationSyncStatus_SYNCED,
+		ShortReason: "",
+		Reason:      "",
+		Timestamp:   time.Now().Unix(),
+	}
+}
+
+func makeOutOfSyncState(shortReason, details, commit string) model.ApplicationSyncState {
+	if len(commit) >= 7 {
+		commit = commit[:7]
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Diff between the defined state in Git at commit %s and actual state in Cloud Run:\n\n", commit))
+	if details == "" {
+		details = shortReason
+	} else {
+		b.WriteString("--- Expected\n+++ Actual\n\n")
+	}
+	b.WriteString(details)
+
+	return model.ApplicationSyncState{
+		Status:      model.ApplicationSyncStatus_OUT_OF_SYNC,
+		ShortReason: shortReason,
+		Reason:      b.String(),
+		Timestamp:   time.Now().Unix(),
+	}
+}
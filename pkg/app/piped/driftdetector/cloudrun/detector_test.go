@@ -0,0 +1,134 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestRevisionSpecIgnoredPaths(t *testing.T) {
+	dd := &config.DriftDetection{
+		IgnoreFields: []config.DriftDetectionIgnoreField{
+			{
+				Paths: []string{
+					"spec.template.spec.containers.*.image",
+					"metadata.annotations",
+				},
+			},
+		},
+	}
+	assert.Equal(t, []string{"containers.*.image"}, revisionSpecIgnoredPaths(dd))
+	assert.Nil(t, revisionSpecIgnoredPaths(nil))
+}
+
+func TestDiffRevisionSpec(t *testing.T) {
+	head, err := provider.ParseServiceManifest([]byte(`
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.1.0
+        args:
+        - server
+`))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name         string
+		live         string
+		ignoredPaths []string
+		expected     int
+	}{
+		{
+			name: "the fields filled by cloud run are ignored",
+			live: `
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    metadata:
+      name: helloworld-v010-1234567
+    spec:
+      containerConcurrency: 80
+      timeoutSeconds: 300
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.1.0
+        args:
+        - server
+        resources:
+          limits:
+            cpu: 1000m
+`,
+			expected: 0,
+		},
+		{
+			name: "image was changed manually",
+			live: `
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.2.0
+        args:
+        - server
+`,
+			expected: 1,
+		},
+		{
+			name: "image was changed at the ignored path",
+			live: `
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.2.0
+        args:
+        - server
+`,
+			ignoredPaths: []string{"containers.*.image"},
+			expected:     0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			live, err := provider.ParseServiceManifest([]byte(tc.live))
+			require.NoError(t, err)
+
+			result, err := diffRevisionSpec(head, live, tc.ignoredPaths...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result.NumNodes())
+		})
+	}
+}
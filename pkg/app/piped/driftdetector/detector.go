@@ -28,7 +28,10 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/driftdetector/terraform"
 	"github.com/pipe-cd/pipe/pkg/app/piped/livestatestore"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -111,6 +114,39 @@ func NewDetector(
 				logger,
 			))
 
+		case model.CloudProviderLambda:
+			d.detectors = append(d.detectors, lambda.NewDetector(
+				cp,
+				appLister,
+				gitClient,
+				d,
+				cfg,
+				sd,
+				logger,
+			))
+
+		case model.CloudProviderCloudRun:
+			d.detectors = append(d.detectors, cloudrun.NewDetector(
+				cp,
+				appLister,
+				gitClient,
+				d,
+				cfg,
+				sd,
+				logger,
+			))
+
+		case model.CloudProviderTerraform:
+			d.detectors = append(d.detectors, terraform.NewDetector(
+				cp,
+				appLister,
+				gitClient,
+				d,
+				cfg,
+				sd,
+				logger,
+			))
+
 		default:
 		}
 	}
@@ -121,7 +157,8 @@ func NewDetector(
 func (d *detector) Run(ctx context.Context) error {
 	group, ctx := errgroup.WithContext(ctx)
 
-	for i, detector := range d.detectors {
+	for i := range d.detectors {
+		detector := d.detectors[i]
 		// Avoid starting all detectors at the same time to reduce the API call burst.
 		time.Sleep(time.Duration(i) * 10 * time.Second)
 		d.logger.Info(fmt.Sprintf("starting drift detector for cloud provider: %s", detector.ProviderName()))
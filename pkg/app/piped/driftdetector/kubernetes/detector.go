@@ -175,8 +175,13 @@ func (d *detector) check(ctx context.Context) error {
 }
 
 func (d *detector) checkApplication(ctx context.Context, app *model.Application, repo git.Repo, headCommit git.Commit) error {
+	cfg, err := d.loadDeploymentConfiguration(repo.GetPath(), app)
+	if err != nil {
+		return fmt.Errorf("failed to load deployment configuration: %w", err)
+	}
+
 	watchingResourceKinds := d.stateGetter.GetWatchingResourceKinds()
-	headManifests, err := d.loadHeadManifests(ctx, app, repo, headCommit, watchingResourceKinds)
+	headManifests, err := d.loadHeadManifests(ctx, app, cfg, repo, headCommit, watchingResourceKinds)
 	if err != nil {
 		return err
 	}
@@ -187,17 +192,23 @@ func (d *detector) checkApplication(ctx context.Context, app *model.Application,
 	liveManifests = filterIgnoringManifests(liveManifests)
 	d.logger.Info(fmt.Sprintf("application %s has %d live manifests", app.Id, len(liveManifests)))
 
-	result, err := provider.DiffList(
-		headManifests,
-		liveManifests,
+	opts := []diff.Option{
 		diff.WithEquateEmpty(),
 		diff.WithIgnoreAddingMapKeys(),
 		diff.WithCompareNumberAndNumericString(),
-	)
+	}
+	result, err := provider.DiffList(headManifests, liveManifests, opts...)
 	if err != nil {
 		return err
 	}
 
+	if dd := cfg.KubernetesDeploymentSpec.DriftDetection; dd != nil && len(dd.IgnoreFields) > 0 {
+		result, err = ignoreFields(result, dd.IgnoreFields, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
 	state := makeSyncState(result, headCommit.Hash)
 	if state.Status == model.ApplicationSyncStatus_SYNCED {
 		return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
@@ -206,7 +217,7 @@ func (d *detector) checkApplication(ctx context.Context, app *model.Application,
 	return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
 }
 
-func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application, repo git.Repo, headCommit git.Commit, watchingResourceKinds []provider.APIVersionKind) ([]provider.Manifest, error) {
+func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application, cfg *config.Config, repo git.Repo, headCommit git.Commit, watchingResourceKinds []provider.APIVersionKind) ([]provider.Manifest, error) {
 	var (
 		manifestCache = provider.AppManifestsCache{
 			AppID:  app.Id,
@@ -220,11 +231,6 @@ func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application
 	manifests, ok := manifestCache.Get(headCommit.Hash)
 	if !ok {
 		// When the manifests were not in the cache we have to load them.
-		cfg, err := d.loadDeploymentConfiguration(repoDir, app)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load deployment configuration: %w", err)
-		}
-
 		gds, ok := cfg.GetGenericDeployment()
 		if !ok {
 			return nil, fmt.Errorf("unsupport application kind %s", cfg.Kind)
@@ -233,9 +239,10 @@ func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application
 		var (
 			shouldDecryptSealedSecrets = d.secretDecrypter != nil && len(gds.SealedSecrets) > 0
 			shouldDecryptSecrets       = d.secretDecrypter != nil && gds.Encryption != nil
+			shouldDecryptSopsFiles     = gds.Sops != nil && len(gds.Sops.DecryptionTargets) > 0
 		)
 
-		if shouldDecryptSealedSecrets || shouldDecryptSecrets {
+		if shouldDecryptSealedSecrets || shouldDecryptSecrets || shouldDecryptSopsFiles {
 			// We have to copy repository into another directory because
 			// decrypting the sealed secrets might change the git repository.
 			dir, err := ioutil.TempDir("", "detector-git-decrypt")
@@ -261,9 +268,15 @@ func (d *detector) loadHeadManifests(ctx context.Context, app *model.Application
 					return nil, fmt.Errorf("failed to decrypt secrets (%w)", err)
 				}
 			}
+			if shouldDecryptSopsFiles {
+				if err := sourcedecrypter.DecryptSopsFiles(ctx, appDir, *gds.Sops); err != nil {
+					return nil, fmt.Errorf("failed to decrypt files encrypted by sops (%w)", err)
+				}
+			}
 		}
 
 		loader := provider.NewManifestLoader(app.Name, appDir, repoDir, app.GitPath.ConfigFilename, cfg.KubernetesDeploymentSpec.Input, d.logger)
+		var err error
 		manifests, err = loader.LoadManifests(ctx)
 		if err != nil {
 			err = fmt.Errorf("failed to load new manifests: %w", err)
@@ -323,6 +336,7 @@ func (d *detector) loadDeploymentConfiguration(repoPath string, app *model.Appli
 		chartRepoName := cfg.KubernetesDeploymentSpec.Input.HelmChart.Repository
 		if chartRepoName != "" {
 			cfg.KubernetesDeploymentSpec.Input.HelmChart.Insecure = d.config.IsInsecureChartRepository(chartRepoName)
+			cfg.KubernetesDeploymentSpec.Input.HelmChart.OCIRegistry = d.config.GetOCIChartRegistry(chartRepoName)
 		}
 	}
 
@@ -345,6 +359,51 @@ func filterIgnoringManifests(manifests []provider.Manifest) []provider.Manifest
 	return out
 }
 
+// ignoreFields drops the differences at the fields configured to be ignored
+// and removes the changed manifests those have no remaining difference.
+func ignoreFields(r *provider.DiffListResult, rules []config.DriftDetectionIgnoreField, opts ...diff.Option) (*provider.DiffListResult, error) {
+	out := &provider.DiffListResult{
+		Adds:    r.Adds,
+		Deletes: r.Deletes,
+		Changes: make([]provider.DiffListChange, 0, len(r.Changes)),
+	}
+	for _, c := range r.Changes {
+		paths := ignoredPaths(rules, c.Old.Key)
+		if len(paths) == 0 {
+			out.Changes = append(out.Changes, c)
+			continue
+		}
+		result, err := provider.Diff(c.Old, c.New, append(opts, diff.WithIgnoredPaths(paths...))...)
+		if err != nil {
+			return nil, err
+		}
+		if !result.HasDiff() {
+			continue
+		}
+		out.Changes = append(out.Changes, provider.DiffListChange{
+			Old:  c.Old,
+			New:  c.New,
+			Diff: result,
+		})
+	}
+	return out, nil
+}
+
+// ignoredPaths returns the paths of the given resource configured to be ignored.
+func ignoredPaths(rules []config.DriftDetectionIgnoreField, key provider.ResourceKey) []string {
+	var paths []string
+	for _, r := range rules {
+		if r.Kind != "" && r.Kind != key.Kind {
+			continue
+		}
+		if r.Name != "" && r.Name != key.Name {
+			continue
+		}
+		paths = append(paths, r.Paths...)
+	}
+	return paths
+}
+
 func makeSyncState(r *provider.DiffListResult, commit string) model.ApplicationSyncState {
 	if r.NoChange() {
 		return model.ApplicationSyncState{
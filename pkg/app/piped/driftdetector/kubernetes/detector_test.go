@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/diff"
+)
+
+func TestIgnoreFields(t *testing.T) {
+	const (
+		head = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: helloworld
+        image: gcr.io/pipecd/helloworld:v0.1.0
+`
+		live = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  replicas: 5
+  template:
+    spec:
+      containers:
+      - name: helloworld
+        image: gcr.io/pipecd/helloworld:v0.2.0
+`
+	)
+	headManifests, err := provider.ParseManifests(head)
+	require.NoError(t, err)
+	liveManifests, err := provider.ParseManifests(live)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name         string
+		rules        []config.DriftDetectionIgnoreField
+		expectedDiff []string
+	}{
+		{
+			name: "no matching rule",
+			rules: []config.DriftDetectionIgnoreField{
+				{
+					Kind:  "Deployment",
+					Name:  "another",
+					Paths: []string{"spec.replicas"},
+				},
+			},
+			expectedDiff: []string{
+				"spec.replicas",
+				"spec.template.spec.containers.0.image",
+			},
+		},
+		{
+			name: "ignore a part of the changed fields",
+			rules: []config.DriftDetectionIgnoreField{
+				{
+					Kind:  "Deployment",
+					Paths: []string{"spec.replicas"},
+				},
+			},
+			expectedDiff: []string{
+				"spec.template.spec.containers.0.image",
+			},
+		},
+		{
+			name: "ignore all changed fields",
+			rules: []config.DriftDetectionIgnoreField{
+				{
+					Paths: []string{"spec.replicas"},
+				},
+				{
+					Name:  "simple",
+					Paths: []string{"spec.template.spec.containers.*.image"},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := provider.DiffList(headManifests, liveManifests)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(result.Changes))
+
+			result, err = ignoreFields(result, tc.rules, diff.WithEquateEmpty())
+			require.NoError(t, err)
+			if len(tc.expectedDiff) == 0 {
+				assert.True(t, result.NoChange())
+				return
+			}
+			require.Equal(t, 1, len(result.Changes))
+
+			paths := make([]string, 0, result.Changes[0].Diff.NumNodes())
+			for _, n := range result.Changes[0].Diff.Nodes() {
+				paths = append(paths, n.PathString)
+			}
+			assert.Equal(t, tc.expectedDiff, paths)
+		})
+	}
+}
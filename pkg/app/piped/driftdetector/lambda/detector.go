@@ -1,4 +1,4 @@
-// Copyright 2020 The PipeCD Authors.
+// Copyright 2021 The PipeCD Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -13,3 +13,301 @@
 // limitations under the License.
 
 package lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type applicationLister interface {
+	ListByCloudProvider(name string) []*model.Application
+}
+
+type gitClient interface {
+	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+}
+
+type secretDecrypter interface {
+	Decrypt(string) (string, error)
+}
+
+type reporter interface {
+	ReportApplicationSyncState(ctx context.Context, appID string, state model.ApplicationSyncState) error
+}
+
+type detector struct {
+	provider        config.PipedCloudProvider
+	appLister       applicationLister
+	gitClient       gitClient
+	reporter        reporter
+	interval        time.Duration
+	config          *config.PipedSpec
+	secretDecrypter secretDecrypter
+	logger          *zap.Logger
+
+	gitRepos map[string]git.Repo
+}
+
+func NewDetector(
+	cp config.PipedCloudProvider,
+	appLister applicationLister,
+	gitClient gitClient,
+	reporter reporter,
+	cfg *config.PipedSpec,
+	sd secretDecrypter,
+	logger *zap.Logger,
+) *detector {
+
+	logger = logger.Named("lambda-detector").With(
+		zap.String("cloud-provider", cp.Name),
+	)
+	return &detector{
+		provider:        cp,
+		appLister:       appLister,
+		gitClient:       gitClient,
+		reporter:        reporter,
+		interval:        time.Minute,
+		config:          cfg,
+		secretDecrypter: sd,
+		gitRepos:        make(map[string]git.Repo),
+		logger:          logger,
+	}
+}
+
+func (d *detector) Run(ctx context.Context) error {
+	d.logger.Info("start running drift detector for lambda applications")
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ticker.C:
+			d.check(ctx)
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
+	d.logger.Info("drift detector for lambda applications has been stopped")
+	return nil
+}
+
+func (d *detector) check(ctx context.Context) error {
+	client, err := provider.DefaultRegistry().Client(d.provider.Name, d.provider.LambdaConfig, d.logger)
+	if err != nil {
+		d.logger.Error("failed to create lambda client", zap.Error(err))
+		return err
+	}
+
+	appsByRepo := d.listGroupedApplication()
+
+	for repoID, apps := range appsByRepo {
+		gitRepo, ok := d.gitRepos[repoID]
+		if !ok {
+			// Clone repository for the first time.
+			repoCfg, ok := d.config.GetRepository(repoID)
+			if !ok {
+				d.logger.Error(fmt.Sprintf("repository %s was not found in piped configuration", repoID))
+				continue
+			}
+			gr, err := d.gitClient.Clone(ctx, repoID, repoCfg.Remote, repoCfg.Branch, "")
+			if err != nil {
+				d.logger.Error("failed to clone repository",
+					zap.String("repo-id", repoID),
+					zap.Error(err),
+				)
+				continue
+			}
+			gitRepo = gr
+			d.gitRepos[repoID] = gitRepo
+		}
+
+		// Fetch the latest commit to compare the states.
+		branch := gitRepo.GetClonedBranch()
+		if err := gitRepo.Pull(ctx, branch); err != nil {
+			d.logger.Error("failed to update repository branch",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Get the head commit of the repository.
+		headCommit, err := gitRepo.GetLatestCommit(ctx)
+		if err != nil {
+			d.logger.Error("failed to get head commit hash",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Start checking all applications in this repository.
+		for _, app := range apps {
+			if err := d.checkApplication(ctx, client, app, gitRepo, headCommit); err != nil {
+				d.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *detector) checkApplication(ctx context.Context, client provider.Client, app *model.Application, repo git.Repo, headCommit git.Commit) error {
+	fm, err := d.loadHeadFunctionManifest(app, repo, headCommit)
+	if err != nil {
+		return err
+	}
+	desired := fm.Spec
+
+	live, err := client.GetFunction(ctx, desired.Name)
+	if errors.Is(err, provider.ErrNotFound) {
+		diffs := []string{fmt.Sprintf("function %s was not found", desired.Name)}
+		return d.reporter.ReportApplicationSyncState(ctx, app.Id, makeSyncState(diffs, headCommit.Hash))
+	}
+	if err != nil {
+		return err
+	}
+
+	// The hash of the zip archive is compared with the live one to know whether its code was changed.
+	if desired.IsZipPackage() {
+		if desired.CodeSHA256, err = client.GetCodeSHA256(ctx, fm); err != nil {
+			return err
+		}
+	}
+	diffs := provider.DiffFunctionSpec(live, desired)
+
+	trafficCfg, err := client.GetTrafficConfig(ctx, fm)
+	switch {
+	case errors.Is(err, provider.ErrNotFound):
+		diffs = append(diffs, fmt.Sprintf("alias of function %s was not found", desired.Name))
+	case err != nil:
+		return err
+	default:
+		if diff, ok := diffTrafficConfig(trafficCfg); ok {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	d.logger.Info(fmt.Sprintf("application %s has %d differences at commit %s", app.Id, len(diffs), headCommit.Hash))
+	return d.reporter.ReportApplicationSyncState(ctx, app.Id, makeSyncState(diffs, headCommit.Hash))
+}
+
+func (d *detector) loadHeadFunctionManifest(app *model.Application, repo git.Repo, headCommit git.Commit) (provider.FunctionManifest, error) {
+	var (
+		repoDir = repo.GetPath()
+		appDir  = filepath.Join(repoDir, app.GitPath.Path)
+	)
+
+	cfg, err := d.loadDeploymentConfiguration(repoDir, app)
+	if err != nil {
+		return provider.FunctionManifest{}, fmt.Errorf("failed to load deployment configuration: %w", err)
+	}
+	input := cfg.LambdaDeploymentSpec.Input
+
+	var fm provider.FunctionManifest
+	// The code packaged by piped is stored at the location decided by the commit.
+	if pkg := input.Package; pkg != nil {
+		fm, err = provider.LoadPackagedFunctionManifest(appDir, input.FunctionManifestFile, *pkg, headCommit.Hash)
+	} else {
+		fm, err = provider.LoadFunctionManifest(appDir, input.FunctionManifestFile)
+	}
+	if err != nil {
+		return provider.FunctionManifest{}, fmt.Errorf("failed to load function manifest: %w", err)
+	}
+
+	if err := provider.DecryptEnvironments(&fm, d.secretDecrypter); err != nil {
+		return provider.FunctionManifest{}, fmt.Errorf("failed to decrypt the environment variables: %w", err)
+	}
+	return fm, nil
+}
+
+// listGroupedApplication retrieves all applications those should be handled by this director
+// and then groups them by repoID.
+func (d *detector) listGroupedApplication() map[string][]*model.Application {
+	var (
+		apps = d.appLister.ListByCloudProvider(d.provider.Name)
+		m    = make(map[string][]*model.Application)
+	)
+	for _, app := range apps {
+		repoID := app.GitPath.Repo.Id
+		m[repoID] = append(m[repoID], app)
+	}
+	return m
+}
+
+func (d *detector) loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.Config, error) {
+	path := filepath.Join(repoPath, app.GitPath.GetDeploymentConfigFilePath())
+	cfg, err := config.LoadFromYAML(path)
+	if err != nil {
+		return nil, err
+	}
+	if appKind, ok := config.ToApplicationKind(cfg.Kind); !ok || appKind != app.Kind {
+		return nil, fmt.Errorf("application in deployment configuration file is not match, got: %s, expected: %s", appKind, app.Kind)
+	}
+	if cfg.LambdaDeploymentSpec == nil {
+		return nil, fmt.Errorf("missing Lambda spec field in deployment configuration")
+	}
+	return cfg, nil
+}
+
+func (d *detector) ProviderName() string {
+	return d.provider.Name
+}
+
+// diffTrafficConfig reports the alias splitting the traffic between two versions
+// since all traffic is routed to the primary version after completing a deployment.
+func diffTrafficConfig(cfg provider.RoutingTrafficConfig) (string, bool) {
+	secondary, ok := cfg[provider.TrafficSecondaryVersionKeyName]
+	if !ok || secondary.Percent == 0 {
+		return "", false
+	}
+	primary := cfg[provider.TrafficPrimaryVersionKeyName]
+	return fmt.Sprintf("alias: all traffic was expected to be routed to a single version but got %g%% to version %s and %g%% to version %s",
+		primary.Percent, primary.Version, secondary.Percent, secondary.Version), true
+}
+
+func makeSyncState(diffs []string, commit string) model.ApplicationSyncState {
+	if len(diffs) == 0 {
+		return model.ApplicationSyncState{
+			Status:      model.ApplicationSyncStatus_SYNCED,
+			ShortReason: "",
+			Reason:      "",
+			Timestamp:   time.Now().Unix(),
+		}
+	}
+
+	shortReason := fmt.Sprintf("There are %d differences in the function", len(diffs))
+	if len(commit) >= 7 {
+		commit = commit[:7]
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Diff between the defined state in Git at commit %s and actual state in AWS Lambda:\n\n", commit))
+	for _, diff := range diffs {
+		b.WriteString("- ")
+		b.WriteString(diff)
+		b.WriteString("\n")
+	}
+
+	return model.ApplicationSyncState{
+		Status:      model.ApplicationSyncStatus_OUT_OF_SYNC,
+		ShortReason: shortReason,
+		Reason:      b.String(),
+		Timestamp:   time.Now().Unix(),
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+)
+
+func TestDiffTrafficConfig(t *testing.T) {
+	testcases := []struct {
+		name     string
+		cfg      provider.RoutingTrafficConfig
+		expected string
+		diff     bool
+	}{
+		{
+			name: "all traffic is routed to primary",
+			cfg: provider.RoutingTrafficConfig{
+				provider.TrafficPrimaryVersionKeyName: {Version: "2", Percent: 100},
+			},
+		},
+		{
+			name: "secondary has no traffic",
+			cfg: provider.RoutingTrafficConfig{
+				provider.TrafficPrimaryVersionKeyName:   {Version: "2", Percent: 100},
+				provider.TrafficSecondaryVersionKeyName: {Version: "1", Percent: 0},
+			},
+		},
+		{
+			name: "traffic is split",
+			cfg: provider.RoutingTrafficConfig{
+				provider.TrafficPrimaryVersionKeyName:   {Version: "2", Percent: 80},
+				provider.TrafficSecondaryVersionKeyName: {Version: "3", Percent: 20},
+			},
+			expected: "alias: all traffic was expected to be routed to a single version but got 80% to version 2 and 20% to version 3",
+			diff:     true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, diff := diffTrafficConfig(tc.cfg)
+			assert.Equal(t, tc.diff, diff)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020 The PipeCD Authors.
+// Copyright 2021 The PipeCD Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -13,3 +13,293 @@
 // limitations under the License.
 
 package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/terraform"
+	"github.com/pipe-cd/pipe/pkg/app/piped/sourcedecrypter"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type applicationLister interface {
+	ListByCloudProvider(name string) []*model.Application
+}
+
+type gitClient interface {
+	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+}
+
+type secretDecrypter interface {
+	Decrypt(string) (string, error)
+}
+
+type reporter interface {
+	ReportApplicationSyncState(ctx context.Context, appID string, state model.ApplicationSyncState) error
+}
+
+type detector struct {
+	provider        config.PipedCloudProvider
+	appLister       applicationLister
+	gitClient       gitClient
+	reporter        reporter
+	interval        time.Duration
+	config          *config.PipedSpec
+	secretDecrypter secretDecrypter
+	logger          *zap.Logger
+
+	gitRepos map[string]git.Repo
+}
+
+func NewDetector(
+	cp config.PipedCloudProvider,
+	appLister applicationLister,
+	gitClient gitClient,
+	reporter reporter,
+	cfg *config.PipedSpec,
+	sd secretDecrypter,
+	logger *zap.Logger,
+) *detector {
+
+	logger = logger.Named("terraform-detector").With(
+		zap.String("cloud-provider", cp.Name),
+	)
+	return &detector{
+		provider:  cp,
+		appLister: appLister,
+		gitClient: gitClient,
+		reporter:  reporter,
+		// Running terraform plan is much heavier than fetching the live state
+		// from the other cloud providers, so the applications are checked less frequently.
+		interval:        10 * time.Minute,
+		config:          cfg,
+		secretDecrypter: sd,
+		gitRepos:        make(map[string]git.Repo),
+		logger:          logger,
+	}
+}
+
+func (d *detector) Run(ctx context.Context) error {
+	d.logger.Info("start running drift detector for terraform applications")
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ticker.C:
+			d.check(ctx)
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
+	d.logger.Info("drift detector for terraform applications has been stopped")
+	return nil
+}
+
+func (d *detector) check(ctx context.Context) error {
+	appsByRepo := d.listGroupedApplication()
+
+	for repoID, apps := range appsByRepo {
+		gitRepo, ok := d.gitRepos[repoID]
+		if !ok {
+			// Clone repository for the first time.
+			repoCfg, ok := d.config.GetRepository(repoID)
+			if !ok {
+				d.logger.Error(fmt.Sprintf("repository %s was not found in piped configuration", repoID))
+				continue
+			}
+			gr, err := d.gitClient.Clone(ctx, repoID, repoCfg.Remote, repoCfg.Branch, "")
+			if err != nil {
+				d.logger.Error("failed to clone repository",
+					zap.String("repo-id", repoID),
+					zap.Error(err),
+				)
+				continue
+			}
+			gitRepo = gr
+			d.gitRepos[repoID] = gitRepo
+		}
+
+		// Fetch the latest commit to compare the states.
+		branch := gitRepo.GetClonedBranch()
+		if err := gitRepo.Pull(ctx, branch); err != nil {
+			d.logger.Error("failed to update repository branch",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Get the head commit of the repository.
+		headCommit, err := gitRepo.GetLatestCommit(ctx)
+		if err != nil {
+			d.logger.Error("failed to get head commit hash",
+				zap.String("repo-id", repoID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		// Start checking all applications in this repository.
+		for _, app := range apps {
+			if err := d.checkApplication(ctx, app, gitRepo, headCommit); err != nil {
+				d.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *detector) checkApplication(ctx context.Context, app *model.Application, repo git.Repo, headCommit git.Commit) error {
+	cfg, err := d.loadDeploymentConfiguration(repo.GetPath(), app)
+	if err != nil {
+		return fmt.Errorf("failed to load deployment configuration: %w", err)
+	}
+	gds := cfg.TerraformDeploymentSpec.GenericDeploymentSpec
+	input := cfg.TerraformDeploymentSpec.Input
+
+	// We have to copy repository into another directory because
+	// terraform init and decrypting the secrets change the application directory.
+	dir, err := ioutil.TempDir("", "detector-terraform")
+	if err != nil {
+		return fmt.Errorf("failed to prepare a temporary directory for git repository (%w)", err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err = repo.Copy(filepath.Join(dir, "repo"))
+	if err != nil {
+		return fmt.Errorf("failed to copy the cloned git repository (%w)", err)
+	}
+	appDir := filepath.Join(repo.GetPath(), app.GitPath.Path)
+
+	if d.secretDecrypter != nil && gds.Encryption != nil {
+		if err := sourcedecrypter.DecryptSecrets(appDir, *gds.Encryption, d.secretDecrypter); err != nil {
+			return fmt.Errorf("failed to decrypt secrets (%w)", err)
+		}
+	}
+	if gds.Sops != nil && len(gds.Sops.DecryptionTargets) > 0 {
+		if err := sourcedecrypter.DecryptSopsFiles(ctx, appDir, *gds.Sops); err != nil {
+			return fmt.Errorf("failed to decrypt files encrypted by sops (%w)", err)
+		}
+	}
+
+	terraformPath, installed, err := toolregistry.DefaultRegistry().Terraform(ctx, input.TerraformVersion)
+	if err != nil {
+		return fmt.Errorf("unable to find the specified terraform version %q (%w)", input.TerraformVersion, err)
+	}
+	if installed {
+		d.logger.Info(fmt.Sprintf("terraform %q has just been installed to %q because of no pre-installed binary for that version", input.TerraformVersion, terraformPath))
+	}
+
+	vars := make([]string, 0, len(d.provider.TerraformConfig.Vars)+len(input.Vars))
+	vars = append(vars, d.provider.TerraformConfig.Vars...)
+	vars = append(vars, input.Vars...)
+
+	executor := provider.NewTerraform(
+		terraformPath,
+		appDir,
+		provider.WithoutColor(),
+		provider.WithVars(vars),
+		provider.WithVarFiles(input.VarFiles),
+	)
+
+	var buf bytes.Buffer
+	if err := executor.Init(ctx, &buf); err != nil {
+		return fmt.Errorf("failed while executing terraform init (%w): %s", err, buf.String())
+	}
+	if ws := input.Workspace; ws != "" {
+		if err := executor.SelectWorkspace(ctx, ws); err != nil {
+			return fmt.Errorf("failed to select workspace %q (%w)", ws, err)
+		}
+	}
+
+	buf.Reset()
+	result, err := executor.Plan(ctx, &buf)
+	if err != nil {
+		return fmt.Errorf("failed while executing terraform plan (%w): %s", err, buf.String())
+	}
+	d.logger.Info(fmt.Sprintf("application %s has %s at commit %s", app.Id, result.Summary(), headCommit.Hash))
+
+	state := makeSyncState(result, buf.String(), headCommit.Hash)
+	return d.reporter.ReportApplicationSyncState(ctx, app.Id, state)
+}
+
+// listGroupedApplication retrieves all applications those should be handled by this director
+// and then groups them by repoID.
+func (d *detector) listGroupedApplication() map[string][]*model.Application {
+	var (
+		apps = d.appLister.ListByCloudProvider(d.provider.Name)
+		m    = make(map[string][]*model.Application)
+	)
+	for _, app := range apps {
+		repoID := app.GitPath.Repo.Id
+		m[repoID] = append(m[repoID], app)
+	}
+	return m
+}
+
+func (d *detector) loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.Config, error) {
+	path := filepath.Join(repoPath, app.GitPath.GetDeploymentConfigFilePath())
+	cfg, err := config.LoadFromYAML(path)
+	if err != nil {
+		return nil, err
+	}
+	if appKind, ok := config.ToApplicationKind(cfg.Kind); !ok || appKind != app.Kind {
+		return nil, fmt.Errorf("application in deployment configuration file is not match, got: %s, expected: %s", appKind, app.Kind)
+	}
+	if cfg.TerraformDeploymentSpec == nil {
+		return nil, fmt.Errorf("missing Terraform spec field in deployment configuration")
+	}
+	return cfg, nil
+}
+
+func (d *detector) ProviderName() string {
+	return d.provider.Name
+}
+
+// makeSyncState builds the sync state from the result of terraform plan.
+// The resources changed outside of terraform without any changes to apply are considered synced
+// since the plan only refreshes the state for them.
+func makeSyncState(r provider.PlanResult, planOutput, commit string) model.ApplicationSyncState {
+	if r.NoChanges() {
+		return model.ApplicationSyncState{
+			Status:      model.ApplicationSyncStatus_SYNCED,
+			ShortReason: "",
+			Reason:      "",
+			Timestamp:   time.Now().Unix(),
+		}
+	}
+
+	shortReason := fmt.Sprintf("There are changes to be applied by terraform (%s)", r.Summary())
+	if len(commit) >= 7 {
+		commit = commit[:7]
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Diff between the defined state in Git at commit %s and actual state reported by terraform plan:\n\n", commit))
+	b.WriteString(planOutput)
+
+	return model.ApplicationSyncState{
+		Status:      model.ApplicationSyncStatus_OUT_OF_SYNC,
+		ShortReason: shortReason,
+		Reason:      b.String(),
+		Timestamp:   time.Now().Unix(),
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"text/template"
 	"time"
 
@@ -43,6 +44,10 @@ type Executor struct {
 	config              *config.Config
 	startTime           time.Time
 	previousElapsedTime time.Duration
+	// The result of the analyses performed in this execution.
+	result *model.AnalysisResult
+	// The commit author who requested to skip the analysis by the commit message directive.
+	skipRequester string
 }
 
 type registerer interface {
@@ -60,18 +65,31 @@ func Register(r registerer) {
 
 // templateArgs allows deployment-specific data to be embedded in the analysis template.
 // NOTE: Changing its fields will force users to change the template definition.
+// Use config.MigrateAnalysisTemplateArgs to migrate the templates when renaming.
 type templateArgs struct {
-	App struct {
-		Name string
-		Env  string
-	}
-	K8s struct {
-		Namespace string
-	}
+	BuiltInArgs templateBuiltInArgs
+	// Deprecated: Use BuiltInArgs.App instead.
+	App templateAppArgs
+	// Deprecated: Use BuiltInArgs.K8s instead.
+	K8s templateK8sArgs
 	// User-defined custom args.
 	Args map[string]string
 }
 
+type templateBuiltInArgs struct {
+	App templateAppArgs
+	K8s templateK8sArgs
+}
+
+type templateAppArgs struct {
+	Name string
+	Env  string
+}
+
+type templateK8sArgs struct {
+	Namespace string
+}
+
 // Execute spawns and runs multiple analyzer that run a query at the regular time.
 // Any on of those fail then the stage ends with failure.
 func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
@@ -80,12 +98,14 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	options := e.StageConfig.AnalysisStageOptions
 	if options == nil {
 		e.Logger.Error("missing analysis configuration for ANALYSIS stage")
+		e.ReportError(executor.NewUserError("missing analysis configuration for ANALYSIS stage"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	ds, err := e.RunningDSP.Get(ctx, e.LogPersister)
 	if err != nil {
 		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 	e.repoDir = ds.RepoDir
@@ -97,6 +117,12 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		templateCfg = &config.AnalysisTemplateSpec{}
 	} else if err != nil {
 		e.LogPersister.Error(err.Error())
+		e.ReportError(executor.NewUserError("failed to load analysis template: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if err := verifyExamples(templateCfg); err != nil {
+		e.LogPersister.Error(err.Error())
+		e.ReportError(executor.NewUserError("invalid analysis template: %w", err))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -108,10 +134,40 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	}
 	defer e.saveElapsedTime(ctx)
 
+	if deadline, ok := sig.Deadline(); ok && time.Until(deadline) < timeout {
+		e.LogPersister.Infof("The deployment will time out at %s before the analysis completes", deadline.Format(time.RFC3339))
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if genericCfg, ok := e.config.GetGenericDeployment(); ok {
+		m := genericCfg.CommitMatcher.SkipAnalysis
+		matched, err := matchSkipAnalysisDirective(m, e.Deployment.Trigger)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to check the skip-analysis directive (%v)", err)
+			e.ReportError(executor.NewUserError("invalid commitMatcher.skipAnalysis: %w", err))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if matched {
+			author := e.Deployment.Trigger.Commit.Author
+			if m.IsAllowedAuthor(author) {
+				e.skipRequester = author
+				e.LogPersister.Infof("Skipping analysis was requested by %s, the analysis will be performed in report-only mode", author)
+			} else {
+				e.LogPersister.Infof("The skip-analysis directive was ignored because %s is not allowed to use it", author)
+			}
+		}
+	}
+
+	excluded := e.newPreemptionExcluder(options.PreemptionTolerance)
+	profile, hasProfile := options.StrictnessProfiles[e.EnvName]
+	if hasProfile {
+		e.LogPersister.Infof("Applying the strictness profile for environment %s", e.EnvName)
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
+	analyzers := make([]*analyzer, 0, len(options.Metrics)+len(options.Logs)+len(options.Https)+len(options.KubernetesPodFailures)+len(options.KubernetesPods)+len(options.ConsumerLags))
 
 	// Run analyses with metrics providers.
 	for i := range options.Metrics {
@@ -119,8 +175,14 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		analyzer, err := e.newAnalyzerForMetrics(i, &options.Metrics[i], templateCfg)
 		if err != nil {
 			e.LogPersister.Errorf("Failed to spawn analyzer for %s: %v", options.Metrics[i].Provider, err)
+			e.ReportError(err)
 			return model.StageStatus_STAGE_FAILURE
 		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
 		eg.Go(func() error {
 			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
 			return analyzer.run(ctx)
@@ -131,8 +193,14 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		analyzer, err := e.newAnalyzerForLog(i, &options.Logs[i], templateCfg)
 		if err != nil {
 			e.LogPersister.Errorf("Failed to spawn analyzer for %s: %v", options.Logs[i].Provider, err)
+			e.ReportError(err)
 			return model.StageStatus_STAGE_FAILURE
 		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
 		eg.Go(func() error {
 			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
 			return analyzer.run(ctx)
@@ -143,49 +211,209 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 		analyzer, err := e.newAnalyzerForHTTP(i, &options.Https[i], templateCfg)
 		if err != nil {
 			e.LogPersister.Errorf("Failed to spawn analyzer for HTTP: %v", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
+		eg.Go(func() error {
+			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
+			return analyzer.run(ctx)
+		})
+	}
+
+	// Run analyses with the failures observed on the pods.
+	for i := range options.KubernetesPodFailures {
+		analyzer, err := e.newAnalyzerForKubernetesPodFailures(i, &options.KubernetesPodFailures[i])
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn analyzer for Kubernetes pod failures: %v", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
+		eg.Go(func() error {
+			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
+			return analyzer.run(ctx)
+		})
+	}
+
+	// Run analyses with the readiness and the restarts of the pods.
+	for i := range options.KubernetesPods {
+		analyzer, err := e.newAnalyzerForKubernetesPods(i, &options.KubernetesPods[i])
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn analyzer for Kubernetes pods: %v", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
+		eg.Go(func() error {
+			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
+			return analyzer.run(ctx)
+		})
+	}
+
+	// Run analyses with the lag of the message-queue consumers.
+	for i := range options.ConsumerLags {
+		analyzer, err := e.newAnalyzerForConsumerLag(i, &options.ConsumerLags[i])
+		if err != nil {
+			e.LogPersister.Errorf("Failed to spawn analyzer for consumer lag: %v", err)
+			e.ReportError(err)
 			return model.StageStatus_STAGE_FAILURE
 		}
+		analyzer.excluded = excluded
+		if hasProfile {
+			applyStrictnessProfile(analyzer, &profile)
+		}
+		analyzers = append(analyzers, analyzer)
 		eg.Go(func() error {
 			e.LogPersister.Infof("[%s] Start analysis for %s", analyzer.id, analyzer.providerType)
 			return analyzer.run(ctx)
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
+	err = eg.Wait()
+	e.result = buildAnalysisResult(e.startTime, time.Now(), analyzers)
+	// The elapsed time is saved to resume the analysis from the middle after restarting.
+	if sig.Reason() == executor.StopReasonPipedRestart {
+		e.LogPersister.Infof("Analysis was interrupted because piped is shutting down, it will be resumed after %v elapsed", time.Since(e.startTime)+e.previousElapsedTime)
+	}
+	if err != nil && e.skipRequester != "" {
+		e.LogPersister.Infof("Analysis failed but it is reported only because skipping analysis was requested by %s: %s", e.skipRequester, err.Error())
+		e.ReportWarning("analysis failed in report-only mode: %v", err)
+		e.notifyFailure(err, true)
+		return executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_SUCCESS)
+	}
+	if err != nil && hasProfile && profile.ReportOnly {
+		e.LogPersister.Infof("Analysis failed but it is reported only because of the strictness profile for environment %s: %s", e.EnvName, err.Error())
+		e.ReportWarning("analysis failed in report-only mode: %v", err)
+		e.notifyFailure(err, true)
+		return executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_SUCCESS)
+	}
+	if err != nil {
 		e.LogPersister.Errorf("Analysis failed: %s", err.Error())
+		e.notifyFailure(err, false)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	status := executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_SUCCESS)
-	if status != model.StageStatus_STAGE_SUCCESS {
+	if !model.IsSuccessfulStage(status) {
 		return status
 	}
 
+	for _, a := range analyzers {
+		if a.skippedCount == 0 {
+			continue
+		}
+		total := a.successCount + a.failureCount + a.skippedCount
+		e.ReportWarning("%d of %d evaluations of %s were skipped", a.skippedCount, total, a.id)
+	}
+
 	e.LogPersister.Success("All analyses were successful")
-	err = e.AnalysisResultStore.PutLatestAnalysisResult(ctx, &model.AnalysisResult{
-		StartTime: e.startTime.Unix(),
+	e.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_ANALYSIS_SUCCEEDED,
+		Metadata: &model.NotificationEventAnalysisSucceeded{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			StageId:    e.Stage.Id,
+			Result:     e.result,
+		},
 	})
+	err = e.AnalysisResultStore.PutLatestAnalysisResult(ctx, e.result)
 	if err != nil {
 		e.Logger.Error("failed to send the analysis metadata")
 	}
 	return status
 }
 
-const elapsedTimeKey = "elapsedTime"
+// notifyFailure sends the verdict of the failed analysis to the notification receivers.
+func (e *Executor) notifyFailure(err error, reportOnly bool) {
+	e.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_ANALYSIS_FAILED,
+		Metadata: &model.NotificationEventAnalysisFailed{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			StageId:    e.Stage.Id,
+			Result:     e.result,
+			Reason:     err.Error(),
+			ReportOnly: reportOnly,
+		},
+	})
+}
+
+const (
+	elapsedTimeKey    = "elapsedTime"
+	analysisResultKey = "analysisResult"
+	skipAnalysisKey   = "skipAnalysis"
+)
+
+func buildAnalysisResult(startTime, endTime time.Time, analyzers []*analyzer) *model.AnalysisResult {
+	result := &model.AnalysisResult{
+		StartTime: startTime.Unix(),
+		EndTime:   endTime.Unix(),
+		Summaries: make([]*model.AnalysisSummary, 0, len(analyzers)),
+	}
+	for _, a := range analyzers {
+		result.Summaries = append(result.Summaries, a.summary())
+	}
+	return result
+}
 
 // saveElapsedTime stores the elapsed time of analysis stage into metadata persister.
 // The analysis stage can be restarted from the middle even if it ends unexpectedly,
 // that's why count should be stored.
+// The analysis result is also stored to let it be seen from the deployment.
+// The requester of skipping analysis is also stored for audit.
 func (e *Executor) saveElapsedTime(ctx context.Context) {
 	elapsedTime := time.Since(e.startTime) + e.previousElapsedTime
 	metadata := map[string]string{
 		elapsedTimeKey: elapsedTime.String(),
 	}
+	if e.skipRequester != "" {
+		metadata[skipAnalysisKey] = fmt.Sprintf("requested by %s in commit %s", e.skipRequester, e.Deployment.Trigger.Commit.Hash)
+	}
+	if e.result != nil {
+		data, err := json.Marshal(e.result)
+		if err != nil {
+			e.Logger.Error("failed to marshal analysis result", zap.Error(err))
+		} else {
+			metadata[analysisResultKey] = string(data)
+		}
+	}
 	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
 		e.Logger.Error("failed to store metadata", zap.Error(err))
 	}
 }
 
+// ResetElapsedTime removes the elapsed time saved by the analysis performed in the given stage
+// to let the next analysis performed in the same stage start from the beginning.
+func ResetElapsedTime(ctx context.Context, store executor.MetadataStore, stageID string) error {
+	ori, ok := store.GetStageMetadata(stageID)
+	if !ok {
+		return nil
+	}
+	if _, ok := ori[elapsedTimeKey]; !ok {
+		return nil
+	}
+	metadata := make(map[string]string, len(ori))
+	for k, v := range ori {
+		if k != elapsedTimeKey {
+			metadata[k] = v
+		}
+	}
+	return store.SetStageMetadata(ctx, stageID, metadata)
+}
+
 // retrievePreviousElapsedTime sets the elapsed time of analysis stage by decoding metadata.
 func (e *Executor) retrievePreviousElapsedTime() time.Duration {
 	metadata, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id)
@@ -204,25 +432,77 @@ func (e *Executor) retrievePreviousElapsedTime() time.Duration {
 	return et
 }
 
+// matchSkipAnalysisDirective returns whether the message of the triggered commit
+// has the skip-analysis directive configured by the given matcher.
+// The directive is ignored when the deployment was triggered by a command.
+func matchSkipAnalysisDirective(m *config.SkipAnalysisMatcher, trigger *model.DeploymentTrigger) (bool, error) {
+	if m == nil || trigger == nil || trigger.Commit == nil || trigger.Commander != "" {
+		return false, nil
+	}
+	r, err := regexp.Compile(m.Pattern)
+	if err != nil {
+		return false, err
+	}
+	return r.MatchString(trigger.Commit.Message), nil
+}
+
+// applyStrictnessProfile overrides the values of the given analyzer by the given profile.
+func applyStrictnessProfile(a *analyzer, profile *config.AnalysisStrictnessProfile) {
+	if profile.FailureLimit != nil {
+		a.failureLimit = *profile.FailureLimit
+	}
+}
+
+// newPreemptionExcluder returns a function to exclude the evaluations
+// performed within the tolerance window after a pod preemption.
+// Nil is returned if it is not configured or the application is not a Kubernetes one.
+func (e *Executor) newPreemptionExcluder(tolerance *config.AnalysisPreemptionTolerance) func(time.Time) (bool, string) {
+	if tolerance == nil {
+		return nil
+	}
+	if e.config.Kind != config.KindKubernetesApp {
+		e.LogPersister.Info("The preemption tolerance is ignored because it is only supported for Kubernetes application")
+		return nil
+	}
+	d := &preemptionDetector{
+		lister:  e.AppLiveResourceLister,
+		variant: tolerance.Variant,
+		window:  tolerance.Window.Duration(),
+	}
+	return d.excluded
+}
+
 func (e *Executor) newAnalyzerForMetrics(i int, templatable *config.TemplatableAnalysisMetrics, templateCfg *config.AnalysisTemplateSpec) (*analyzer, error) {
 	cfg, err := e.getMetricsConfig(templatable, templateCfg, templatable.Template.Args)
 	if err != nil {
 		return nil, err
 	}
-	provider, err := e.newMetricsProvider(cfg.Provider, templatable)
+	provider, err := e.newMetricsProvider(cfg.Provider, templatable, cfg.DisableCache)
 	if err != nil {
 		return nil, err
 	}
 	id := fmt.Sprintf("metrics-%d", i)
-	runner := func(ctx context.Context, query string) (bool, string, error) {
-		now := time.Now()
-		queryRange := metrics.QueryRange{
-			From: now.Add(-cfg.Interval.Duration()),
-			To:   now,
+	newRunner := func(provider metrics.Provider) evaluator {
+		return func(ctx context.Context, query string) (bool, string, error) {
+			now := time.Now()
+			queryRange := metrics.QueryRange{
+				From: now.Add(-cfg.Interval.Duration()),
+				To:   now,
+			}
+			return provider.Evaluate(ctx, query, queryRange, &cfg.Expected)
 		}
-		return provider.Evaluate(ctx, query, queryRange, &cfg.Expected)
 	}
-	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
+	runner := newRunner(provider)
+	if cfg.SecondaryProvider != "" {
+		secondary, err := e.newMetricsProvider(cfg.SecondaryProvider, templatable, cfg.DisableCache)
+		if err != nil {
+			return nil, err
+		}
+		runner = crossCheck(runner, newRunner(secondary), cfg.SecondaryProvider, cfg.SecondaryQueryOrDefault())
+	}
+	a := newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister)
+	e.setProviderInfo(a, cfg.Provider)
+	return a, nil
 }
 
 func (e *Executor) newAnalyzerForLog(i int, templatable *config.TemplatableAnalysisLog, templateCfg *config.AnalysisTemplateSpec) (*analyzer, error) {
@@ -238,7 +518,18 @@ func (e *Executor) newAnalyzerForLog(i int, templatable *config.TemplatableAnaly
 	runner := func(ctx context.Context, query string) (bool, string, error) {
 		return provider.Evaluate(ctx, query)
 	}
-	return newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
+	a := newAnalyzer(id, provider.Type(), cfg.Query, runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister)
+	e.setProviderInfo(a, cfg.Provider)
+	return a, nil
+}
+
+// setProviderInfo sets the information of the analysis provider configured in piped
+// to let the analyzer report the metrics and the slow queries for each provider.
+func (e *Executor) setProviderInfo(a *analyzer, providerName string) {
+	a.providerName = providerName
+	if cfg, ok := e.PipedConfig.GetAnalysisProvider(providerName); ok {
+		a.slowQueryThreshold = cfg.SlowQueryThreshold.Duration()
+	}
 }
 
 func (e *Executor) newAnalyzerForHTTP(i int, templatable *config.TemplatableAnalysisHTTP, templateCfg *config.AnalysisTemplateSpec) (*analyzer, error) {
@@ -254,22 +545,88 @@ func (e *Executor) newAnalyzerForHTTP(i int, templatable *config.TemplatableAnal
 	return newAnalyzer(id, provider.Type(), "", runner, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister), nil
 }
 
-func (e *Executor) newMetricsProvider(providerName string, templatable *config.TemplatableAnalysisMetrics) (metrics.Provider, error) {
+func (e *Executor) newAnalyzerForKubernetesPodFailures(i int, cfg *config.AnalysisKubernetesPodFailures) (*analyzer, error) {
+	if e.config.Kind != config.KindKubernetesApp {
+		return nil, executor.NewUserError("kubernetesPodFailures analysis is only supported for Kubernetes application")
+	}
+	evaluator := &kubernetesPodFailuresEvaluator{
+		lister:    e.AppLiveResourceLister,
+		cfg:       cfg,
+		startTime: e.startTime,
+	}
+	id := fmt.Sprintf("kubernetes-pod-failures-%d", i)
+	return newAnalyzer(id, kubernetesPodFailuresProviderType, "", evaluator.evaluate, time.Duration(cfg.Interval), cfg.FailureLimit, false, e.Logger, e.LogPersister), nil
+}
+
+func (e *Executor) newAnalyzerForKubernetesPods(i int, cfg *config.AnalysisKubernetesPods) (*analyzer, error) {
+	if e.config.Kind != config.KindKubernetesApp {
+		return nil, executor.NewUserError("kubernetesPods analysis is only supported for Kubernetes application")
+	}
+	evaluator, err := newKubernetesPodsEvaluator(e.AppLiveResourceLister, cfg, e.startTime)
+	if err != nil {
+		return nil, err
+	}
+	id := fmt.Sprintf("kubernetes-pods-%d", i)
+	return newAnalyzer(id, kubernetesPodsProviderType, "", evaluator.evaluate, time.Duration(cfg.Interval), cfg.FailureLimit, false, e.Logger, e.LogPersister), nil
+}
+
+func (e *Executor) newAnalyzerForConsumerLag(i int, cfg *config.AnalysisConsumerLag) (*analyzer, error) {
+	providerCfg, ok := e.PipedConfig.GetAnalysisProvider(cfg.Provider)
+	if !ok {
+		return nil, executor.NewUserError("unknown provider name %s", cfg.Provider)
+	}
+	queryTemplate, err := consumerLagQueryTemplate(cfg, providerCfg.Type)
+	if err != nil {
+		return nil, executor.NewUserError("invalid consumerLags configuration: %w", err)
+	}
+	canaryQuery, err := renderConsumerLagQuery(queryTemplate, cfg.CanaryConsumer)
+	if err != nil {
+		return nil, executor.NewUserError("failed to render query for canary consumer: %w", err)
+	}
+	baselineQuery, err := renderConsumerLagQuery(queryTemplate, cfg.BaselineConsumer)
+	if err != nil {
+		return nil, executor.NewUserError("failed to render query for baseline consumer: %w", err)
+	}
+	templatable := &config.TemplatableAnalysisMetrics{
+		AnalysisMetrics: config.AnalysisMetrics{Timeout: cfg.Timeout},
+	}
+	provider, err := e.newMetricsProvider(cfg.Provider, templatable, false)
+	if err != nil {
+		return nil, err
+	}
+	evaluator := &consumerLagEvaluator{
+		provider:      provider,
+		cfg:           cfg,
+		canaryQuery:   canaryQuery,
+		baselineQuery: baselineQuery,
+	}
+	id := fmt.Sprintf("consumer-lag-%d", i)
+	a := newAnalyzer(id, consumerLagProviderType, canaryQuery, evaluator.evaluate, time.Duration(cfg.Interval), cfg.FailureLimit, cfg.SkipOnNoData, e.Logger, e.LogPersister)
+	e.setProviderInfo(a, cfg.Provider)
+	return a, nil
+}
+
+// newMetricsProvider creates the provider of the given name.
+// The query results are cached when the provider is configured to do so unless disableCache is true.
+func (e *Executor) newMetricsProvider(providerName string, templatable *config.TemplatableAnalysisMetrics, disableCache bool) (metrics.Provider, error) {
 	cfg, ok := e.PipedConfig.GetAnalysisProvider(providerName)
 	if !ok {
-		return nil, fmt.Errorf("unknown provider name %s", providerName)
+		return nil, executor.NewUserError("unknown provider name %s", providerName)
 	}
 	provider, err := metricsfactory.NewProvider(templatable, &cfg, e.Logger)
 	if err != nil {
 		return nil, err
 	}
+	if ttl := cfg.CacheTTL.Duration(); ttl > 0 && !disableCache {
+		return metrics.NewCachedProvider(providerName, provider, ttl), nil
+	}
 	return provider, nil
 }
 
 func (e *Executor) newLogProvider(providerName string) (log.Provider, error) {
 	cfg, ok := e.PipedConfig.GetAnalysisProvider(providerName)
 	if !ok {
-		return nil, fmt.Errorf("unknown provider name %s", providerName)
+		return nil, executor.NewUserError("unknown provider name %s", providerName)
 	}
 	provider, err := logfactory.NewProvider(&cfg, e.Logger)
 	if err != nil {
@@ -285,7 +642,7 @@ func (e *Executor) getMetricsConfig(templatableCfg *config.TemplatableAnalysisMe
 	if name == "" {
 		cfg := &templatableCfg.AnalysisMetrics
 		if err := cfg.Validate(); err != nil {
-			return nil, fmt.Errorf("invalid metrics configuration: %w", err)
+			return nil, executor.NewUserError("invalid metrics configuration: %w", err)
 		}
 		return cfg, nil
 	}
@@ -297,10 +654,10 @@ func (e *Executor) getMetricsConfig(templatableCfg *config.TemplatableAnalysisMe
 	}
 	cfg, ok := templateCfg.Metrics[name]
 	if !ok {
-		return nil, fmt.Errorf("analysis template %s not found despite template specified", name)
+		return nil, executor.NewUserError("analysis template %s not found despite template specified", name)
 	}
 	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid metrics configuration: %w", err)
+		return nil, executor.NewUserError("invalid metrics configuration: %w", err)
 	}
 	return &cfg, nil
 }
@@ -320,7 +677,7 @@ func (e *Executor) getLogConfig(templatableCfg *config.TemplatableAnalysisLog, t
 	}
 	cfg, ok := templateCfg.Logs[name]
 	if !ok {
-		return nil, fmt.Errorf("analysis template %s not found despite template specified", name)
+		return nil, executor.NewUserError("analysis template %s not found despite template specified", name)
 	}
 	return &cfg, nil
 }
@@ -340,46 +697,55 @@ func (e *Executor) getHTTPConfig(templatableCfg *config.TemplatableAnalysisHTTP,
 	}
 	cfg, ok := templateCfg.HTTPs[name]
 	if !ok {
-		return nil, fmt.Errorf("analysis template %s not found despite template specified", name)
+		return nil, executor.NewUserError("analysis template %s not found despite template specified", name)
 	}
 	return &cfg, nil
 }
 
 // render returns a new AnalysisTemplateSpec, where deployment-specific arguments populated.
-//
-// TODO: Change Template Args reference name
-//   Use .BuiltInArgs.App.Name instead of .App.Name
-//   Besides, we'd prefer to keep the variables for variant as is.
+// The deprecated args (.App.* and .K8s.*) are still populated to keep the existing templates working.
 func (e *Executor) render(templateCfg config.AnalysisTemplateSpec, customArgs map[string]string) (*config.AnalysisTemplateSpec, error) {
-	args := templateArgs{
-		Args: customArgs,
-		App: struct {
-			Name string
-			Env  string
-			// TODO: Populate Env
-		}{Name: e.Application.Name, Env: ""},
+	builtIn := templateBuiltInArgs{
+		App: templateAppArgs{Name: e.Application.Name, Env: e.EnvName},
 	}
 	if e.config.Kind == config.KindKubernetesApp {
 		namespace := "default"
 		if n := e.config.KubernetesDeploymentSpec.Input.Namespace; n != "" {
 			namespace = n
 		}
-		args.K8s = struct{ Namespace string }{Namespace: namespace}
+		builtIn.K8s = templateK8sArgs{Namespace: namespace}
+	}
+	newCfg, deprecated, err := renderTemplate(templateCfg, builtIn, customArgs)
+	if deprecated {
+		e.LogPersister.Info("The analysis template is using the deprecated args .App and .K8s, please use .BuiltInArgs.App and .BuiltInArgs.K8s instead")
+	}
+	return newCfg, err
+}
+
+// renderTemplate applies the given built-in and custom args to the template.
+// The returned bool reports whether the template is using the deprecated args.
+func renderTemplate(templateCfg config.AnalysisTemplateSpec, builtIn templateBuiltInArgs, customArgs map[string]string) (*config.AnalysisTemplateSpec, bool, error) {
+	args := templateArgs{
+		BuiltInArgs: builtIn,
+		App:         builtIn.App,
+		K8s:         builtIn.K8s,
+		Args:        customArgs,
 	}
 
 	cfg, err := json.Marshal(templateCfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal json: %w", err)
+		return nil, false, fmt.Errorf("failed to marshal json: %w", err)
 	}
+	_, deprecated := config.MigrateAnalysisTemplateArgs(cfg)
 	t, err := template.New("AnalysisTemplate").Parse(string(cfg))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse text: %w", err)
+		return nil, deprecated, executor.NewUserError("failed to parse text: %w", err)
 	}
 	b := new(bytes.Buffer)
 	if err := t.Execute(b, args); err != nil {
-		return nil, fmt.Errorf("failed to apply template: %w", err)
+		return nil, deprecated, executor.NewUserError("failed to apply template: %w", err)
 	}
 	newCfg := &config.AnalysisTemplateSpec{}
 	err = json.Unmarshal(b.Bytes(), newCfg)
-	return newCfg, err
+	return newCfg, deprecated, err
 }
@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestMatchSkipAnalysisDirective(t *testing.T) {
+	matcher := &config.SkipAnalysisMatcher{
+		Pattern:        `\[skip-analysis\]`,
+		AllowedAuthors: []string{"foo"},
+	}
+	testcases := []struct {
+		name        string
+		matcher     *config.SkipAnalysisMatcher
+		trigger     *model.DeploymentTrigger
+		expected    bool
+		expectedErr bool
+	}{
+		{
+			name:    "no matcher",
+			trigger: &model.DeploymentTrigger{Commit: &model.Commit{Message: "fix [skip-analysis]"}},
+		},
+		{
+			name:    "not matched",
+			matcher: matcher,
+			trigger: &model.DeploymentTrigger{Commit: &model.Commit{Message: "fix"}},
+		},
+		{
+			name:     "matched",
+			matcher:  matcher,
+			trigger:  &model.DeploymentTrigger{Commit: &model.Commit{Message: "fix [skip-analysis]"}},
+			expected: true,
+		},
+		{
+			name:    "triggered by command",
+			matcher: matcher,
+			trigger: &model.DeploymentTrigger{Commit: &model.Commit{Message: "fix [skip-analysis]"}, Commander: "bar"},
+		},
+		{
+			name:        "invalid pattern",
+			matcher:     &config.SkipAnalysisMatcher{Pattern: "[skip-analysis"},
+			trigger:     &model.DeploymentTrigger{Commit: &model.Commit{Message: "fix"}},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			matched, err := matchSkipAnalysisDirective(tc.matcher, tc.trigger)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, matched)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysismetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	providerTypeKey = "provider_type"
+	providerKey     = "provider"
+	statusKey       = "status"
+)
+
+type Status string
+
+const (
+	StatusSuccess Status = "success"
+	StatusFailure Status = "failure"
+)
+
+var (
+	inflightQueries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "analysis_provider_inflight_queries",
+			Help: "Number of queries currently being run against the analysis providers.",
+		},
+		[]string{providerTypeKey, providerKey},
+	)
+
+	queryDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "analysis_provider_query_duration_seconds",
+			Help:    "Histogram of the seconds taken to run queries against the analysis providers.",
+			Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{providerTypeKey, providerKey, statusKey},
+	)
+
+	slowQueriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analysis_provider_slow_queries_total",
+			Help: "Total number of queries exceeding the slow query threshold of the analysis providers.",
+		},
+		[]string{providerTypeKey, providerKey},
+	)
+)
+
+func StartedQuery(providerType, provider string) {
+	inflightQueries.With(prometheus.Labels{
+		providerTypeKey: providerType,
+		providerKey:     provider,
+	}).Inc()
+}
+
+func FinishedQuery(providerType, provider string, s Status, d time.Duration) {
+	inflightQueries.With(prometheus.Labels{
+		providerTypeKey: providerType,
+		providerKey:     provider,
+	}).Dec()
+
+	queryDurationSeconds.With(prometheus.Labels{
+		providerTypeKey: providerType,
+		providerKey:     provider,
+		statusKey:       string(s),
+	}).Observe(d.Seconds())
+}
+
+func SlowQuery(providerType, provider string) {
+	slowQueriesTotal.With(prometheus.Labels{
+		providerTypeKey: providerType,
+		providerKey:     provider,
+	}).Inc()
+}
+
+func Register(r prometheus.Registerer) {
+	r.MustRegister(
+		inflightQueries,
+		queryDurationSeconds,
+		slowQueriesTotal,
+	)
+}
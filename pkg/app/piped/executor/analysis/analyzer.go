@@ -10,6 +10,8 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis/analysismetrics"
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 // analyzer contains a query for an analysis provider.
@@ -22,6 +24,19 @@ type analyzer struct {
 	// The analysis will fail, if this value is exceeded,
 	failureLimit int
 	skipOnNoData bool
+	// Optional function to check whether the evaluation at the given time
+	// should be excluded from the analysis, e.g. right after pod preemptions.
+	excluded func(now time.Time) (bool, string)
+	// The name of the analysis provider configured in piped.
+	providerName string
+	// The queries taking longer than this are logged as slow queries.
+	// Zero means no slow query is logged.
+	slowQueryThreshold time.Duration
+
+	// The numbers of evaluation results that used to build the summary.
+	successCount int
+	failureCount int
+	skippedCount int
 
 	logger       *zap.Logger
 	logPersister executor.LogPersister
@@ -62,17 +77,24 @@ func (a *analyzer) run(ctx context.Context) error {
 	ticker := time.NewTicker(a.interval)
 	defer ticker.Stop()
 
-	failureCount := 0
 	for {
 		select {
-		case <-ticker.C:
-			expected, reason, err := a.evaluate(ctx, a.query)
+		case now := <-ticker.C:
+			if a.excluded != nil {
+				if ok, reason := a.excluded(now); ok {
+					a.logPersister.Infof("[%s] The query result evaluation was skipped because %s. Performed query: %q", a.id, reason, a.query)
+					a.skippedCount++
+					continue
+				}
+			}
+			expected, reason, err := a.evaluateWithMetrics(ctx)
 			// Ignore parent's context deadline exceeded error, and return immediately.
 			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
 				return nil
 			}
 			if errors.Is(err, metrics.ErrNoDataFound) && a.skipOnNoData {
 				a.logPersister.Infof("[%s] The query result evaluation was skipped because \"skipOnNoData\" is true even though no data returned. Reason: %v. Performed query: %q", a.id, err, a.query)
+				a.skippedCount++
 				continue
 			}
 			if err != nil {
@@ -81,12 +103,13 @@ func (a *analyzer) run(ctx context.Context) error {
 
 			if expected {
 				a.logPersister.Successf("[%s] The query result is expected one. Reason: %s. Performed query: %q", a.id, reason, a.query)
+				a.successCount++
 				continue
 			}
 
 			a.logPersister.Errorf("[%s] The query result is unexpected. Reason: %s. Performed query: %q", a.id, reason, a.query)
-			failureCount++
-			if failureCount > a.failureLimit {
+			a.failureCount++
+			if a.failureCount > a.failureLimit {
 				return fmt.Errorf("analysis '%s' failed because the failure number exceeded the failure limit (%d)", a.id, a.failureLimit)
 			}
 		case <-ctx.Done():
@@ -94,3 +117,43 @@ func (a *analyzer) run(ctx context.Context) error {
 		}
 	}
 }
+
+// evaluateWithMetrics evaluates the query while recording its latency
+// and reporting it as a slow query when it exceeds the threshold.
+func (a *analyzer) evaluateWithMetrics(ctx context.Context) (bool, string, error) {
+	analysismetrics.StartedQuery(a.providerType, a.providerName)
+	start := time.Now()
+	expected, reason, err := a.evaluate(ctx, a.query)
+	elapsed := time.Since(start)
+
+	status := analysismetrics.StatusSuccess
+	if err != nil {
+		status = analysismetrics.StatusFailure
+	}
+	analysismetrics.FinishedQuery(a.providerType, a.providerName, status, elapsed)
+
+	if a.slowQueryThreshold > 0 && elapsed > a.slowQueryThreshold {
+		analysismetrics.SlowQuery(a.providerType, a.providerName)
+		a.logPersister.Infof("[%s] The query took %v which exceeded the slow query threshold %v of provider %s. Performed query: %q", a.id, elapsed, a.slowQueryThreshold, a.providerName, a.query)
+		a.logger.Warn("slow analysis query",
+			zap.String("provider", a.providerName),
+			zap.String("query", a.query),
+			zap.Duration("elapsed", elapsed),
+		)
+	}
+	return expected, reason, err
+}
+
+// summary returns the summary of the evaluations performed so far.
+// It must be called after the analysis has been finished.
+func (a *analyzer) summary() *model.AnalysisSummary {
+	return &model.AnalysisSummary{
+		Id:           a.id,
+		ProviderType: a.providerType,
+		Query:        a.query,
+		Passed:       a.failureCount <= a.failureLimit,
+		SuccessCount: int32(a.successCount),
+		FailureCount: int32(a.failureCount),
+		SkippedCount: int32(a.skippedCount),
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAnalyzerSummary(t *testing.T) {
+	results := []struct {
+		expected bool
+		err      error
+	}{
+		{expected: true},
+		{expected: false},
+		{err: metrics.ErrNoDataFound},
+		{expected: true},
+		{expected: false},
+	}
+	evaluations := 0
+	evaluate := func(_ context.Context, _ string) (bool, string, error) {
+		r := results[evaluations%len(results)]
+		evaluations++
+		return r.expected, "", r.err
+	}
+
+	a := newAnalyzer("metrics-0", "PROMETHEUS", "query", evaluate, time.Millisecond, 1, true, zap.NewNop(), &executortest.FakeLogPersister{})
+	err := a.run(context.Background())
+	assert.Error(t, err)
+
+	expected := &model.AnalysisSummary{
+		Id:           "metrics-0",
+		ProviderType: "PROMETHEUS",
+		Query:        "query",
+		Passed:       false,
+		SuccessCount: 2,
+		FailureCount: 2,
+		SkippedCount: 1,
+	}
+	assert.Equal(t, expected, a.summary())
+
+	startTime := time.Unix(100, 0)
+	endTime := time.Unix(200, 0)
+	result := buildAnalysisResult(startTime, endTime, []*analyzer{a})
+	assert.Equal(t, &model.AnalysisResult{
+		StartTime: 100,
+		EndTime:   200,
+		Summaries: []*model.AnalysisSummary{expected},
+	}, result)
+}
+
+type infoRecordingLogPersister struct {
+	executortest.FakeLogPersister
+	infos []string
+}
+
+func (l *infoRecordingLogPersister) Infof(format string, a ...interface{}) {
+	l.infos = append(l.infos, fmt.Sprintf(format, a...))
+}
+
+func TestAnalyzerSlowQuery(t *testing.T) {
+	testcases := []struct {
+		name      string
+		threshold time.Duration
+		expected  int
+	}{
+		{
+			name:     "no threshold",
+			expected: 0,
+		},
+		{
+			name:      "exceeded threshold",
+			threshold: time.Millisecond,
+			expected:  1,
+		},
+		{
+			name:      "within threshold",
+			threshold: time.Hour,
+			expected:  0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			evaluate := func(_ context.Context, _ string) (bool, string, error) {
+				time.Sleep(5 * time.Millisecond)
+				return true, "", nil
+			}
+			lp := &infoRecordingLogPersister{}
+			a := newAnalyzer("metrics-0", "PROMETHEUS", "query", evaluate, time.Millisecond, 0, false, zap.NewNop(), lp)
+			a.providerName = "prometheus-dev"
+			a.slowQueryThreshold = tc.threshold
+
+			expected, _, err := a.evaluateWithMetrics(context.Background())
+			require.NoError(t, err)
+			assert.True(t, expected)
+			assert.Equal(t, tc.expected, len(lp.infos))
+		})
+	}
+}
+
+func TestApplyStrictnessProfile(t *testing.T) {
+	failureLimit := 5
+	testcases := []struct {
+		name     string
+		profile  config.AnalysisStrictnessProfile
+		expected int
+	}{
+		{
+			name:     "failure limit overridden",
+			profile:  config.AnalysisStrictnessProfile{FailureLimit: &failureLimit},
+			expected: 5,
+		},
+		{
+			name:     "failure limit kept",
+			profile:  config.AnalysisStrictnessProfile{ReportOnly: true},
+			expected: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newAnalyzer("metrics-0", "PROMETHEUS", "query", nil, time.Minute, 1, false, zap.NewNop(), &executortest.FakeLogPersister{})
+			applyStrictnessProfile(a, &tc.profile)
+			assert.Equal(t, tc.expected, a.failureLimit)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const consumerLagProviderType = "CONSUMER_LAG"
+
+// builtInConsumerLagQueries are the query templates returning the lag of a consumer
+// keyed by the analysis provider type and the message queue.
+// They assume the metrics exported by the widely used exporters and integrations:
+//   - Prometheus: kafka_exporter and the CloudWatch exporter
+//   - Datadog: the Kafka consumer and the Amazon SQS integrations
+var builtInConsumerLagQueries = map[model.AnalysisProviderType]map[string]string{
+	model.AnalysisProviderPrometheus: {
+		config.AnalysisConsumerLagSourceKafka: `sum(kafka_consumergroup_lag{consumergroup="{{ .Consumer }}"})`,
+		config.AnalysisConsumerLagSourceSQS:   `sum(aws_sqs_approximate_number_of_messages_visible_average{queue_name="{{ .Consumer }}"})`,
+	},
+	model.AnalysisProviderDatadog: {
+		config.AnalysisConsumerLagSourceKafka: `sum:kafka.consumer_lag{consumer_group:{{ .Consumer }}}`,
+		config.AnalysisConsumerLagSourceSQS:   `avg:aws.sqs.approximate_number_of_messages_visible{queuename:{{ .Consumer }}}`,
+	},
+}
+
+// consumerLagQueryTemplate returns the query template of the given config.
+// The built-in one for the provider is used when no query is configured.
+func consumerLagQueryTemplate(cfg *config.AnalysisConsumerLag, providerType model.AnalysisProviderType) (string, error) {
+	if cfg.Query != "" {
+		return cfg.Query, nil
+	}
+	q, ok := builtInConsumerLagQueries[providerType][cfg.Source]
+	if !ok {
+		return "", fmt.Errorf("no built-in query of %s consumer lag for %s provider, please specify \"query\" instead", cfg.Source, providerType)
+	}
+	return q, nil
+}
+
+// renderConsumerLagQuery applies the given consumer to the query template.
+func renderConsumerLagQuery(queryTemplate, consumer string) (string, error) {
+	t, err := template.New("ConsumerLagQuery").Parse(queryTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse query template: %w", err)
+	}
+	b := new(bytes.Buffer)
+	args := struct{ Consumer string }{Consumer: consumer}
+	if err := t.Execute(b, args); err != nil {
+		return "", fmt.Errorf("failed to apply template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// consumerLagEvaluator compares the average lag of the canary consumer
+// with the one of the baseline consumer in the last interval.
+type consumerLagEvaluator struct {
+	provider      metrics.Provider
+	cfg           *config.AnalysisConsumerLag
+	canaryQuery   string
+	baselineQuery string
+}
+
+func (c *consumerLagEvaluator) evaluate(ctx context.Context, _ string) (bool, string, error) {
+	now := time.Now()
+	queryRange := metrics.QueryRange{
+		From: now.Add(-c.cfg.Interval.Duration()),
+		To:   now,
+	}
+	canary, err := c.averageLag(ctx, c.canaryQuery, queryRange)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to fetch the lag of canary consumer %s: %w", c.cfg.CanaryConsumer, err)
+	}
+	baseline, err := c.averageLag(ctx, c.baselineQuery, queryRange)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to fetch the lag of baseline consumer %s: %w", c.cfg.BaselineConsumer, err)
+	}
+
+	if canary <= c.cfg.LagTolerance {
+		return true, fmt.Sprintf("the canary lag %g is within the tolerance %g", canary, c.cfg.LagTolerance), nil
+	}
+	if max := baseline * c.cfg.MaxLagRatio; canary > max {
+		return false, fmt.Sprintf("the canary lag %g exceeded %g times the baseline lag %g", canary, c.cfg.MaxLagRatio, baseline), nil
+	}
+	return true, fmt.Sprintf("the canary lag %g is within %g times the baseline lag %g", canary, c.cfg.MaxLagRatio, baseline), nil
+}
+
+func (c *consumerLagEvaluator) averageLag(ctx context.Context, query string, queryRange metrics.QueryRange) (float64, error) {
+	points, err := c.provider.QueryPoints(ctx, query, queryRange)
+	if err != nil {
+		return 0, err
+	}
+	if len(points) == 0 {
+		return 0, metrics.ErrNoDataFound
+	}
+	var sum float64
+	for i := range points {
+		sum += points[i].Value
+	}
+	return sum / float64(len(points)), nil
+}
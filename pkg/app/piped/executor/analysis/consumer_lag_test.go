@@ -0,0 +1,143 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestConsumerLagQueryTemplate(t *testing.T) {
+	testcases := []struct {
+		name         string
+		cfg          config.AnalysisConsumerLag
+		providerType model.AnalysisProviderType
+		consumer     string
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "built-in kafka query for prometheus",
+			cfg:          config.AnalysisConsumerLag{Source: config.AnalysisConsumerLagSourceKafka},
+			providerType: model.AnalysisProviderPrometheus,
+			consumer:     "orders-canary",
+			want:         `sum(kafka_consumergroup_lag{consumergroup="orders-canary"})`,
+		},
+		{
+			name:         "built-in sqs query for datadog",
+			cfg:          config.AnalysisConsumerLag{Source: config.AnalysisConsumerLagSourceSQS},
+			providerType: model.AnalysisProviderDatadog,
+			consumer:     "orders-canary",
+			want:         `avg:aws.sqs.approximate_number_of_messages_visible{queuename:orders-canary}`,
+		},
+		{
+			name: "custom query takes precedence",
+			cfg: config.AnalysisConsumerLag{
+				Source: config.AnalysisConsumerLagSourceKafka,
+				Query:  `max(lag{group="{{ .Consumer }}"})`,
+			},
+			providerType: model.AnalysisProviderPrometheus,
+			consumer:     "orders-baseline",
+			want:         `max(lag{group="orders-baseline"})`,
+		},
+		{
+			name:         "no built-in query for the provider",
+			cfg:          config.AnalysisConsumerLag{Source: config.AnalysisConsumerLagSourceKafka},
+			providerType: model.AnalysisProviderPixie,
+			wantErr:      true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := consumerLagQueryTemplate(&tc.cfg, tc.providerType)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			got, err := renderConsumerLagQuery(tmpl, tc.consumer)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestConsumerLagEvaluator(t *testing.T) {
+	testcases := []struct {
+		name         string
+		points       map[string][]metrics.DataPoint
+		lagTolerance float64
+		wantExpected bool
+		wantNoData   bool
+	}{
+		{
+			name: "canary lag is close to baseline",
+			points: map[string][]metrics.DataPoint{
+				"canary":   {{Value: 100}, {Value: 140}},
+				"baseline": {{Value: 100}, {Value: 100}},
+			},
+			wantExpected: true,
+		},
+		{
+			name: "canary lag exceeded the ratio",
+			points: map[string][]metrics.DataPoint{
+				"canary":   {{Value: 300}, {Value: 500}},
+				"baseline": {{Value: 100}, {Value: 100}},
+			},
+			wantExpected: false,
+		},
+		{
+			name: "canary lag is within the tolerance",
+			points: map[string][]metrics.DataPoint{
+				"canary":   {{Value: 8}},
+				"baseline": {{Value: 0}},
+			},
+			lagTolerance: 10,
+			wantExpected: true,
+		},
+		{
+			name: "baseline consumer has no data",
+			points: map[string][]metrics.DataPoint{
+				"canary": {{Value: 8}},
+			},
+			wantNoData: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &consumerLagEvaluator{
+				provider: &fakeVariantMetricsProvider{points: tc.points},
+				cfg: &config.AnalysisConsumerLag{
+					Interval:     config.Duration(time.Minute),
+					MaxLagRatio:  1.5,
+					LagTolerance: tc.lagTolerance,
+				},
+				canaryQuery:   "canary",
+				baselineQuery: "baseline",
+			}
+			expected, _, err := e.evaluate(context.Background(), "")
+			assert.Equal(t, tc.wantNoData, errors.Is(err, metrics.ErrNoDataFound))
+			assert.Equal(t, tc.wantExpected, expected)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+)
+
+// crossCheck returns an evaluator which considers the query result as expected
+// only when both the primary and the secondary providers agree with it.
+// The secondary provider is queried only when the result of the primary one is expected.
+func crossCheck(primary, secondary evaluator, secondaryProvider, secondaryQuery string) evaluator {
+	return func(ctx context.Context, query string) (bool, string, error) {
+		expected, reason, err := primary(ctx, query)
+		if err != nil || !expected {
+			return expected, reason, err
+		}
+		secondaryExpected, secondaryReason, err := secondary(ctx, secondaryQuery)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to run query %q against secondary provider %s: %w", secondaryQuery, secondaryProvider, err)
+		}
+		if !secondaryExpected {
+			return false, fmt.Sprintf("secondary provider %s disagreed: %s", secondaryProvider, secondaryReason), nil
+		}
+		return true, fmt.Sprintf("%s, and secondary provider %s agreed: %s", reason, secondaryProvider, secondaryReason), nil
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+type fakeEvaluation struct {
+	expected bool
+	err      error
+	queries  []string
+}
+
+func (f *fakeEvaluation) evaluate(_ context.Context, query string) (bool, string, error) {
+	f.queries = append(f.queries, query)
+	return f.expected, "reason", f.err
+}
+
+func TestCrossCheck(t *testing.T) {
+	testcases := []struct {
+		name              string
+		primary           fakeEvaluation
+		secondary         fakeEvaluation
+		wantExpected      bool
+		wantNoData        bool
+		wantSecondaryCall bool
+	}{
+		{
+			name:              "both providers agreed",
+			primary:           fakeEvaluation{expected: true},
+			secondary:         fakeEvaluation{expected: true},
+			wantExpected:      true,
+			wantSecondaryCall: true,
+		},
+		{
+			name:              "secondary provider disagreed",
+			primary:           fakeEvaluation{expected: true},
+			secondary:         fakeEvaluation{expected: false},
+			wantExpected:      false,
+			wantSecondaryCall: true,
+		},
+		{
+			name:              "secondary provider has no data",
+			primary:           fakeEvaluation{expected: true},
+			secondary:         fakeEvaluation{err: metrics.ErrNoDataFound},
+			wantExpected:      false,
+			wantNoData:        true,
+			wantSecondaryCall: true,
+		},
+		{
+			name:              "primary provider is unexpected",
+			primary:           fakeEvaluation{expected: false},
+			secondary:         fakeEvaluation{expected: true},
+			wantExpected:      false,
+			wantSecondaryCall: false,
+		},
+		{
+			name:              "primary provider failed",
+			primary:           fakeEvaluation{err: errors.New("unavailable")},
+			secondary:         fakeEvaluation{expected: true},
+			wantExpected:      false,
+			wantSecondaryCall: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			evaluate := crossCheck(tc.primary.evaluate, tc.secondary.evaluate, "secondary", "secondary-query")
+			expected, _, err := evaluate(context.Background(), "query")
+			assert.Equal(t, tc.wantExpected, expected)
+			assert.Equal(t, tc.wantNoData, errors.Is(err, metrics.ErrNoDataFound))
+			assert.Equal(t, []string{"query"}, tc.primary.queries)
+			if tc.wantSecondaryCall {
+				assert.Equal(t, []string{"secondary-query"}, tc.secondary.queries)
+			} else {
+				assert.Empty(t, tc.secondary.queries)
+			}
+		})
+	}
+}
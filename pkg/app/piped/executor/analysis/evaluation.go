@@ -0,0 +1,240 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	httpprovider "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/http"
+	logfactory "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/log/factory"
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	metricsfactory "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/factory"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// The kinds of the analysis template entry.
+const (
+	EvaluationKindMetrics = "metrics"
+	EvaluationKindLog     = "log"
+	EvaluationKindHTTP    = "http"
+)
+
+// EvaluationInput specifies an entry of the analysis template to be evaluated.
+type EvaluationInput struct {
+	// The kind of the template entry. One of metrics, log and http.
+	Kind string
+	// The name of the template entry.
+	Name string
+	// The custom args used to render the template.
+	Args map[string]string
+	// The application name used as .BuiltInArgs.App.Name.
+	AppName string
+	// The namespace used as .BuiltInArgs.K8s.Namespace.
+	K8sNamespace string
+}
+
+// EvaluationResult is the result of a single evaluation of an analysis template entry.
+type EvaluationResult struct {
+	// Whether the result of the query is expected one.
+	Expected bool `json:"expected"`
+	// Whether the evaluation was skipped because no data was returned
+	// while skipOnNoData was enabled.
+	Skipped bool   `json:"skipped"`
+	Reason  string `json:"reason"`
+	// The rendered query or the URL for http.
+	Query string `json:"query"`
+	// The data points returned by the metrics provider.
+	// Only available for metrics.
+	DataPoints []metrics.DataPoint `json:"dataPoints,omitempty"`
+}
+
+// Evaluate renders the specified entry of the given analysis template and evaluates it
+// once immediately by using the analysis providers configured in the given piped config.
+// This does the same evaluation as the ANALYSIS stage does at each interval,
+// so that it can be used to check the queries and thresholds without triggering deployments.
+func Evaluate(ctx context.Context, templateCfg *config.AnalysisTemplateSpec, in EvaluationInput, pipedCfg *config.PipedSpec, logger *zap.Logger) (*EvaluationResult, error) {
+	builtIn := templateBuiltInArgs{
+		App: templateAppArgs{Name: in.AppName},
+		K8s: templateK8sArgs{Namespace: in.K8sNamespace},
+	}
+	rendered, _, err := renderTemplate(*templateCfg, builtIn, in.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch in.Kind {
+	case EvaluationKindMetrics:
+		cfg, ok := rendered.Metrics[in.Name]
+		if !ok {
+			return nil, executor.NewUserError("metrics template %s not found", in.Name)
+		}
+		if err := cfg.Validate(); err != nil {
+			return nil, executor.NewUserError("invalid metrics configuration: %w", err)
+		}
+		return evaluateMetrics(ctx, &cfg, pipedCfg, logger)
+
+	case EvaluationKindLog:
+		cfg, ok := rendered.Logs[in.Name]
+		if !ok {
+			return nil, executor.NewUserError("log template %s not found", in.Name)
+		}
+		providerCfg, ok := pipedCfg.GetAnalysisProvider(cfg.Provider)
+		if !ok {
+			return nil, executor.NewUserError("unknown provider name %s", cfg.Provider)
+		}
+		provider, err := logfactory.NewProvider(&providerCfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		expected, reason, err := provider.Evaluate(ctx, cfg.Query)
+		return newEvaluationResult(cfg.Query, expected, reason, err, cfg.SkipOnNoData), nil
+
+	case EvaluationKindHTTP:
+		cfg, ok := rendered.HTTPs[in.Name]
+		if !ok {
+			return nil, executor.NewUserError("http template %s not found", in.Name)
+		}
+		provider := httpprovider.NewProvider(time.Duration(cfg.Timeout))
+		expected, reason, err := provider.Run(ctx, &cfg)
+		return newEvaluationResult(cfg.URL, expected, reason, err, cfg.SkipOnNoData), nil
+
+	default:
+		return nil, executor.NewUserError("unknown kind %q given", in.Kind)
+	}
+}
+
+// evaluateMetrics runs the query over the last interval and returns the verdict with the data points.
+func evaluateMetrics(ctx context.Context, cfg *config.AnalysisMetrics, pipedCfg *config.PipedSpec, logger *zap.Logger) (*EvaluationResult, error) {
+	providerCfg, ok := pipedCfg.GetAnalysisProvider(cfg.Provider)
+	if !ok {
+		return nil, executor.NewUserError("unknown provider name %s", cfg.Provider)
+	}
+	provider, err := metricsfactory.NewProvider(&config.TemplatableAnalysisMetrics{AnalysisMetrics: *cfg}, &providerCfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	queryRange := metrics.QueryRange{
+		From: now.Add(-cfg.Interval.Duration()),
+		To:   now,
+	}
+	expected, reason, err := provider.Evaluate(ctx, cfg.Query, queryRange, &cfg.Expected)
+	result := newEvaluationResult(cfg.Query, expected, reason, err, cfg.SkipOnNoData)
+	if err != nil {
+		return result, nil
+	}
+
+	points, err := provider.QueryPoints(ctx, cfg.Query, queryRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data points: %w", err)
+	}
+	result.DataPoints = points
+	return result, nil
+}
+
+// newEvaluationResult builds the result in the same way as the analyzer does,
+// the error returned by the provider is considered as an unexpected result.
+func newEvaluationResult(query string, expected bool, reason string, err error, skipOnNoData bool) *EvaluationResult {
+	result := &EvaluationResult{
+		Expected: expected,
+		Reason:   reason,
+		Query:    query,
+	}
+	if errors.Is(err, metrics.ErrNoDataFound) && skipOnNoData {
+		result.Skipped = true
+		result.Reason = err.Error()
+		return result
+	}
+	if err != nil {
+		result.Expected = false
+		result.Reason = fmt.Sprintf("failed to run query: %s", err.Error())
+	}
+	return result
+}
+
+// NewEvaluationHandler returns a http handler to evaluate an entry of the analysis template
+// given as the request body. The entry and args are specified by the query parameters:
+// - kind: metrics, log or http
+// - name: the name of the template entry
+// - app: the value of .BuiltInArgs.App.Name
+// - namespace: the value of .BuiltInArgs.K8s.Namespace
+// - arg: a custom arg formatted as key=value, can be specified multiple times
+func NewEvaluationHandler(pipedCfg *config.PipedSpec, logger *zap.Logger) http.Handler {
+	logger = logger.Named("analysis-evaluation")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST method is allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		cfg, err := config.DecodeYAML(data)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode analysis template: %v", err), http.StatusBadRequest)
+			return
+		}
+		if cfg.Kind != config.KindAnalysisTemplate {
+			http.Error(w, fmt.Sprintf("expected kind %s but got %s", config.KindAnalysisTemplate, cfg.Kind), http.StatusBadRequest)
+			return
+		}
+
+		q := r.URL.Query()
+		in := EvaluationInput{
+			Kind:         q.Get("kind"),
+			Name:         q.Get("name"),
+			AppName:      q.Get("app"),
+			K8sNamespace: q.Get("namespace"),
+			Args:         make(map[string]string, len(q["arg"])),
+		}
+		for _, arg := range q["arg"] {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				http.Error(w, fmt.Sprintf("malformed arg %q, must be formatted as key=value", arg), http.StatusBadRequest)
+				return
+			}
+			in.Args[parts[0]] = parts[1]
+		}
+
+		result, err := Evaluate(r.Context(), cfg.AnalysisTemplateSpec, in, pipedCfg, logger)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if executor.ClassifyError(err) == executor.ErrorKindUser {
+				code = http.StatusBadRequest
+			}
+			logger.Info("failed to evaluate analysis template", zap.Error(err))
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			logger.Error("failed to write evaluation result", zap.Error(err))
+		}
+	})
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestEvaluateHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	templateCfg := &config.AnalysisTemplateSpec{
+		HTTPs: map[string]config.AnalysisHTTP{
+			"health": {
+				URL:          server.URL + "/{{ .Args.path }}",
+				Method:       "GET",
+				ExpectedCode: http.StatusOK,
+			},
+		},
+	}
+	testcases := []struct {
+		name        string
+		input       EvaluationInput
+		expected    *EvaluationResult
+		expectedErr bool
+	}{
+		{
+			name: "expected",
+			input: EvaluationInput{
+				Kind: EvaluationKindHTTP,
+				Name: "health",
+				Args: map[string]string{"path": "ok"},
+			},
+			expected: &EvaluationResult{
+				Expected: true,
+				Query:    server.URL + "/ok",
+			},
+		},
+		{
+			name: "unexpected",
+			input: EvaluationInput{
+				Kind: EvaluationKindHTTP,
+				Name: "health",
+				Args: map[string]string{"path": "ng"},
+			},
+			expected: &EvaluationResult{
+				Expected: false,
+				Reason:   "failed to run query: unexpected status code 500",
+				Query:    server.URL + "/ng",
+			},
+		},
+		{
+			name: "template not found",
+			input: EvaluationInput{
+				Kind: EvaluationKindHTTP,
+				Name: "unknown",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unknown kind",
+			input: EvaluationInput{
+				Kind: "unknown",
+				Name: "health",
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Evaluate(context.Background(), templateCfg, tc.input, &config.PipedSpec{}, zap.NewNop())
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestEvaluationHandler(t *testing.T) {
+	const template = `
+apiVersion: pipecd.dev/v1beta1
+kind: AnalysisTemplate
+spec:
+  metrics:
+    error_rate:
+      provider: unknown-provider
+      interval: 1m
+      expected:
+        max: 0
+      query: rate(errors{app="{{ .BuiltInArgs.App.Name }}"}[1m])
+`
+	handler := NewEvaluationHandler(&config.PipedSpec{}, zap.NewNop())
+
+	testcases := []struct {
+		name         string
+		method       string
+		url          string
+		body         string
+		expectedCode int
+	}{
+		{
+			name:         "method not allowed",
+			method:       http.MethodGet,
+			url:          "/analysis/evaluate",
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "not an analysis template",
+			method:       http.MethodPost,
+			url:          "/analysis/evaluate?kind=metrics&name=error_rate",
+			body:         "apiVersion: pipecd.dev/v1beta1\nkind: KubernetesApp\nspec: {}\n",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "malformed arg",
+			method:       http.MethodPost,
+			url:          "/analysis/evaluate?kind=metrics&name=error_rate&arg=foo",
+			body:         template,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown provider",
+			method:       http.MethodPost,
+			url:          "/analysis/evaluate?kind=metrics&name=error_rate&app=demo&arg=foo=bar",
+			body:         template,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.expectedCode, rec.Code, rec.Body.String())
+		})
+	}
+}
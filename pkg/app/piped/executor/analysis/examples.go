@@ -0,0 +1,89 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// verifyExamples evaluates the examples of every metrics in the given analysis template
+// by using the same threshold or deviation logic as the analysis does.
+// It works as a self-test of the template, an error listing all examples
+// whose verdicts were not the expected ones is returned.
+func verifyExamples(templateCfg *config.AnalysisTemplateSpec) error {
+	names := make([]string, 0, len(templateCfg.Metrics))
+	for name := range templateCfg.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, name := range names {
+		cfg := templateCfg.Metrics[name]
+		for i, example := range cfg.Examples {
+			exampleName := example.Name
+			if exampleName == "" {
+				exampleName = fmt.Sprintf("#%d", i)
+			}
+			verdict, err := evaluateExample(&cfg, example)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("metrics %s example %s: %v", name, exampleName, err))
+				continue
+			}
+			if verdict != example.Verdict {
+				failures = append(failures, fmt.Sprintf("metrics %s example %s: expected %s but got %s", name, exampleName, example.Verdict, verdict))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d example(s) of the analysis template failed: %s", len(failures), strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// evaluateExample returns the verdict of the given example evaluated by the strategy of the given metrics.
+// An error is returned when the example could not be evaluated due to the misconfiguration of the metrics.
+func evaluateExample(cfg *config.AnalysisMetrics, example config.AnalysisMetricsExample) (string, error) {
+	switch cfg.StrategyOrDefault() {
+	case config.AnalysisStrategyThreshold:
+		if err := cfg.Expected.Validate(); err != nil {
+			return "", fmt.Errorf("\"expected\" is required to analyze with the THRESHOLD strategy")
+		}
+		for _, v := range example.Values {
+			if !cfg.Expected.InRange(v) {
+				return config.AnalysisVerdictFail, nil
+			}
+		}
+		return config.AnalysisVerdictPass, nil
+
+	case config.AnalysisStrategyPrevious, config.AnalysisStrategyCanaryBaseline, config.AnalysisStrategyCanaryPrimary:
+		deviation := cfg.Deviation
+		if deviation == "" {
+			deviation = config.AnalysisDeviationEither
+		}
+		// Same as the analysis, the failure of the comparison is considered as a deviation.
+		if err := compare(example.Values, example.ControlValues, deviation); err != nil {
+			return config.AnalysisVerdictFail, nil
+		}
+		return config.AnalysisVerdictPass, nil
+
+	default:
+		return "", fmt.Errorf("unknown strategy %q given", cfg.Strategy)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestVerifyExamples(t *testing.T) {
+	testcases := []struct {
+		name    string
+		metrics map[string]config.AnalysisMetrics
+		wantErr bool
+	}{
+		{
+			name: "no example",
+			metrics: map[string]config.AnalysisMetrics{
+				"error_rate": {
+					Expected: config.AnalysisExpected{Max: floatToPointer(0.1)},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "threshold examples matched",
+			metrics: map[string]config.AnalysisMetrics{
+				"error_rate": {
+					Expected: config.AnalysisExpected{Max: floatToPointer(0.1)},
+					Examples: []config.AnalysisMetricsExample{
+						{Name: "healthy", Values: []float64{0.01, 0.05, 0.1}, Verdict: config.AnalysisVerdictPass},
+						{Name: "spike", Values: []float64{0.01, 0.2}, Verdict: config.AnalysisVerdictFail},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "threshold example unmatched",
+			metrics: map[string]config.AnalysisMetrics{
+				"error_rate": {
+					Expected: config.AnalysisExpected{Max: floatToPointer(0.1)},
+					Examples: []config.AnalysisMetricsExample{
+						{Name: "spike", Values: []float64{0.01, 0.2}, Verdict: config.AnalysisVerdictPass},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "threshold without expected range",
+			metrics: map[string]config.AnalysisMetrics{
+				"error_rate": {
+					Examples: []config.AnalysisMetricsExample{
+						{Values: []float64{0.01}, Verdict: config.AnalysisVerdictPass},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "deviation examples matched",
+			metrics: map[string]config.AnalysisMetrics{
+				"latency": {
+					Strategy:  config.AnalysisStrategyCanaryBaseline,
+					Deviation: config.AnalysisDeviationHigh,
+					Examples: []config.AnalysisMetricsExample{
+						{
+							Name:          "same",
+							Values:        []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+							ControlValues: []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+							Verdict:       config.AnalysisVerdictPass,
+						},
+						{
+							Name:          "higher",
+							Values:        []float64{1.1, 1.2, 1.3, 1.4, 1.5},
+							ControlValues: []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+							Verdict:       config.AnalysisVerdictFail,
+						},
+						{
+							Name:          "lower",
+							Values:        []float64{0.01, 0.02, 0.03, 0.04, 0.05},
+							ControlValues: []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+							Verdict:       config.AnalysisVerdictPass,
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "deviation example unmatched",
+			metrics: map[string]config.AnalysisMetrics{
+				"latency": {
+					Strategy:  config.AnalysisStrategyPrevious,
+					Deviation: config.AnalysisDeviationEither,
+					Examples: []config.AnalysisMetricsExample{
+						{
+							Name:          "lower",
+							Values:        []float64{0.01, 0.02, 0.03, 0.04, 0.05},
+							ControlValues: []float64{0.1, 0.2, 0.3, 0.4, 0.5},
+							Verdict:       config.AnalysisVerdictPass,
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifyExamples(&config.AnalysisTemplateSpec{Metrics: tc.metrics})
+			assert.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const kubernetesPodFailuresProviderType = "KUBERNETES_POD_FAILURES"
+
+// kubernetesPodFailuresEvaluator checks the statuses of the pods of the configured variant
+// and reports unexpected when the number of failures exceeds its threshold.
+// The failures are read from the pod statuses instead of the Kubernetes Events.
+type kubernetesPodFailuresEvaluator struct {
+	lister    executor.AppLiveResourceLister
+	cfg       *config.AnalysisKubernetesPodFailures
+	startTime time.Time
+}
+
+func (k *kubernetesPodFailuresEvaluator) evaluate(_ context.Context, _ string) (bool, string, error) {
+	manifests, ok := k.lister.ListKubernetesDependedResources()
+	if !ok {
+		return false, "", fmt.Errorf("unable to list the live pods of the application")
+	}
+	o := observePods(manifests, k.cfg.Variant, k.startTime)
+
+	var exceeded []string
+	if o.crashLoopBackOff > k.cfg.MaxCrashLoopBackOff {
+		exceeded = append(exceeded, fmt.Sprintf("%d containers in %s (max %d)", o.crashLoopBackOff, reasonCrashLoopBackOff, k.cfg.MaxCrashLoopBackOff))
+	}
+	if o.oomKilled > k.cfg.MaxOOMKilled {
+		exceeded = append(exceeded, fmt.Sprintf("%d containers %s (max %d)", o.oomKilled, reasonOOMKilled, k.cfg.MaxOOMKilled))
+	}
+	if o.failedScheduling > k.cfg.MaxFailedScheduling {
+		exceeded = append(exceeded, fmt.Sprintf("%d pods failed to be scheduled (max %d)", o.failedScheduling, k.cfg.MaxFailedScheduling))
+	}
+	if len(exceeded) > 0 {
+		return false, fmt.Sprintf("found %s among %d pods of %s variant", strings.Join(exceeded, ", "), o.pods, k.cfg.Variant), nil
+	}
+	return true, fmt.Sprintf("no failure exceeded its threshold among %d pods of %s variant", o.pods, k.cfg.Variant), nil
+}
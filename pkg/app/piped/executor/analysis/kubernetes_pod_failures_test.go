@@ -0,0 +1,134 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestKubernetesPodFailuresEvaluator(t *testing.T) {
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	manifests, err := provider.ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-crash-looping
+  labels:
+    pipecd.dev/variant: canary
+status:
+  containerStatuses:
+  - name: app
+    state:
+      waiting:
+        reason: CrashLoopBackOff
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-06-01T00:05:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-oom-killed-before
+  labels:
+    pipecd.dev/variant: canary
+status:
+  containerStatuses:
+  - name: app
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-05-31T23:00:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-unschedulable
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Pending
+  conditions:
+  - type: PodScheduled
+    status: "False"
+    reason: Unschedulable
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: primary-crash-looping
+  labels:
+    pipecd.dev/variant: primary
+status:
+  containerStatuses:
+  - name: app
+    state:
+      waiting:
+        reason: CrashLoopBackOff
+`)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		cfg      config.AnalysisKubernetesPodFailures
+		expected bool
+	}{
+		{
+			name: "no failure is allowed",
+			cfg: config.AnalysisKubernetesPodFailures{
+				Variant: "canary",
+			},
+			expected: false,
+		},
+		{
+			name: "all failures are within thresholds",
+			cfg: config.AnalysisKubernetesPodFailures{
+				Variant:             "canary",
+				MaxCrashLoopBackOff: 1,
+				MaxOOMKilled:        1,
+				MaxFailedScheduling: 1,
+			},
+			expected: true,
+		},
+		{
+			name: "other variant has no failure",
+			cfg: config.AnalysisKubernetesPodFailures{
+				Variant:             "primary",
+				MaxCrashLoopBackOff: 1,
+			},
+			expected: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &kubernetesPodFailuresEvaluator{
+				lister:    fakeLiveResourceLister{depended: manifests},
+				cfg:       &tc.cfg,
+				startTime: startTime,
+			}
+			expected, reason, err := e.evaluate(context.Background(), "")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, expected, reason)
+		})
+	}
+}
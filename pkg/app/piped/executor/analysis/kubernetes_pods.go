@@ -0,0 +1,90 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const kubernetesPodsProviderType = "KUBERNETES_PODS"
+
+// kubernetesPodsEvaluator checks the readiness and the restarts of the pods of the configured variant
+// since the analysis started, and reports unexpected when any of them exceeds its threshold.
+type kubernetesPodsEvaluator struct {
+	lister    executor.AppLiveResourceLister
+	cfg       *config.AnalysisKubernetesPods
+	startTime time.Time
+	// The restart counts observed when the analysis started.
+	// The containers not found here are considered as started during the analysis.
+	baseline map[string]int32
+}
+
+// newKubernetesPodsEvaluator returns an evaluator whose restart baseline
+// is taken from the pods observed at the given analysis start time.
+func newKubernetesPodsEvaluator(lister executor.AppLiveResourceLister, cfg *config.AnalysisKubernetesPods, startTime time.Time) (*kubernetesPodsEvaluator, error) {
+	manifests, ok := lister.ListKubernetesDependedResources()
+	if !ok {
+		return nil, fmt.Errorf("unable to list the live pods of the application when the analysis started")
+	}
+	return &kubernetesPodsEvaluator{
+		lister:    lister,
+		cfg:       cfg,
+		startTime: startTime,
+		baseline:  observePods(manifests, cfg.Variant, startTime).restarts,
+	}, nil
+}
+
+func (k *kubernetesPodsEvaluator) evaluate(_ context.Context, _ string) (bool, string, error) {
+	manifests, ok := k.lister.ListKubernetesDependedResources()
+	if !ok {
+		return false, "", fmt.Errorf("unable to list the live pods of the application")
+	}
+	o := observePods(manifests, k.cfg.Variant, k.startTime)
+	if o.pods == 0 {
+		return false, "", fmt.Errorf("no pod of %s variant was found", k.cfg.Variant)
+	}
+
+	var exceeded []string
+	if readyPercentage := o.ready * 100 / o.pods; readyPercentage < k.cfg.MinReadyPercentage.Int() {
+		exceeded = append(exceeded, fmt.Sprintf("%d%% pods ready (min %d%%)", readyPercentage, k.cfg.MinReadyPercentage.Int()))
+	}
+	if restarts := countRestarts(k.baseline, o.restarts); restarts > k.cfg.MaxRestarts {
+		exceeded = append(exceeded, fmt.Sprintf("%d container restarts (max %d)", restarts, k.cfg.MaxRestarts))
+	}
+	if o.oomKilled > k.cfg.MaxOOMKilled {
+		exceeded = append(exceeded, fmt.Sprintf("%d containers %s (max %d)", o.oomKilled, reasonOOMKilled, k.cfg.MaxOOMKilled))
+	}
+	if len(exceeded) > 0 {
+		return false, fmt.Sprintf("found %s among %d pods of %s variant since the analysis started", strings.Join(exceeded, ", "), o.pods, k.cfg.Variant), nil
+	}
+	return true, fmt.Sprintf("%d of %d pods of %s variant are ready and no restart exceeded its threshold", o.ready, o.pods, k.cfg.Variant), nil
+}
+
+// countRestarts returns the total number of restarts increased from the baseline.
+func countRestarts(baseline, current map[string]int32) int {
+	var total int
+	for k, c := range current {
+		if d := c - baseline[k]; d > 0 {
+			total += int(d)
+		}
+	}
+	return total
+}
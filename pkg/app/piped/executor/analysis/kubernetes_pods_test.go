@@ -0,0 +1,177 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestKubernetesPodsEvaluator(t *testing.T) {
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	before, err := provider.ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-1
+  labels:
+    pipecd.dev/variant: canary
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+  containerStatuses:
+  - name: app
+    restartCount: 3
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-05-31T23:00:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: primary-1
+  labels:
+    pipecd.dev/variant: primary
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+`)
+	require.NoError(t, err)
+	after, err := provider.ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-1
+  labels:
+    pipecd.dev/variant: canary
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+  containerStatuses:
+  - name: app
+    restartCount: 4
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-06-01T00:05:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-2
+  labels:
+    pipecd.dev/variant: canary
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+  containerStatuses:
+  - name: app
+    restartCount: 1
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: primary-1
+  labels:
+    pipecd.dev/variant: primary
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+`)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		cfg      config.AnalysisKubernetesPods
+		expected bool
+	}{
+		{
+			name: "all pods must be ready",
+			cfg: config.AnalysisKubernetesPods{
+				Variant:            "canary",
+				MinReadyPercentage: config.Percentage{Number: 100},
+				MaxRestarts:        2,
+				MaxOOMKilled:       1,
+			},
+			expected: false,
+		},
+		{
+			name: "restarts before the analysis are not counted",
+			cfg: config.AnalysisKubernetesPods{
+				Variant:            "canary",
+				MinReadyPercentage: config.Percentage{Number: 50},
+				MaxRestarts:        2,
+				MaxOOMKilled:       1,
+			},
+			expected: true,
+		},
+		{
+			name: "no restart is allowed",
+			cfg: config.AnalysisKubernetesPods{
+				Variant:            "canary",
+				MinReadyPercentage: config.Percentage{Number: 50},
+				MaxOOMKilled:       1,
+			},
+			expected: false,
+		},
+		{
+			name: "no OOMKilled is allowed",
+			cfg: config.AnalysisKubernetesPods{
+				Variant:            "canary",
+				MinReadyPercentage: config.Percentage{Number: 50},
+				MaxRestarts:        2,
+			},
+			expected: false,
+		},
+		{
+			name: "other variant is not ready",
+			cfg: config.AnalysisKubernetesPods{
+				Variant:            "primary",
+				MinReadyPercentage: config.Percentage{Number: 1},
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := newKubernetesPodsEvaluator(fakeLiveResourceLister{depended: before}, &tc.cfg, startTime)
+			require.NoError(t, err)
+			e.lister = fakeLiveResourceLister{depended: after}
+			expected, reason, err := e.evaluate(context.Background(), "")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, expected, reason)
+		})
+	}
+
+	t.Run("no pod found", func(t *testing.T) {
+		e, err := newKubernetesPodsEvaluator(fakeLiveResourceLister{}, &config.AnalysisKubernetesPods{Variant: "canary"}, startTime)
+		require.NoError(t, err)
+		_, _, err = e.evaluate(context.Background(), "")
+		assert.Error(t, err)
+	})
+}
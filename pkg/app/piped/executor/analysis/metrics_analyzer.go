@@ -71,26 +71,17 @@ func (a *metricsAnalyzer) run(ctx context.Context) error {
 	for {
 		select {
 		case <-ticker.C:
-			var (
-				expected bool
-				err      error
-			)
-			switch a.cfg.Strategy {
-			case config.AnalysisStrategyThreshold:
-				expected, err = a.analyzeWithThreshold(ctx)
-			case config.AnalysisStrategyPrevious:
-				var firstDeploy bool
-				expected, firstDeploy, err = a.analyzeWithPrevious(ctx)
-				if firstDeploy {
-					a.logPersister.Info("[%s] PreviousAnalysis cannot be executed because this seems to be the first deployment, so it is considered as a success")
-					return nil
+			expected, firstDeploy, err := a.analyze(ctx)
+			var cerr *controlNotFoundError
+			if errors.As(err, &cerr) && a.cfg.ControlFallback != "" {
+				if err := a.switchToControlFallback(cerr.variant); err != nil {
+					return err
 				}
-			case config.AnalysisStrategyCanaryBaseline:
-				expected, err = a.analyzeWithCanaryBaseline(ctx)
-			case config.AnalysisStrategyCanaryPrimary:
-				expected, err = a.analyzeWithCanaryPrimary(ctx)
-			default:
-				return fmt.Errorf("unknown strategy %q given", a.cfg.Strategy)
+				expected, firstDeploy, err = a.analyze(ctx)
+			}
+			if firstDeploy {
+				a.logPersister.Info("[%s] PreviousAnalysis cannot be executed because this seems to be the first deployment, so it is considered as a success")
+				return nil
 			}
 			// Ignore parent's context deadline exceeded error, and return immediately.
 			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
@@ -117,6 +108,79 @@ func (a *metricsAnalyzer) run(ctx context.Context) error {
 	}
 }
 
+// analyze runs the query with the configured strategy once.
+// firstDeploy is true when it could not be analyzed with the PREVIOUS strategy
+// because there is no previous deployment.
+func (a *metricsAnalyzer) analyze(ctx context.Context) (expected, firstDeploy bool, err error) {
+	switch a.cfg.Strategy {
+	case config.AnalysisStrategyThreshold:
+		expected, err = a.analyzeWithThreshold(ctx)
+	case config.AnalysisStrategyPrevious:
+		expected, firstDeploy, err = a.analyzeWithPrevious(ctx)
+	case config.AnalysisStrategyCanaryBaseline:
+		expected, err = a.analyzeWithCanaryBaseline(ctx)
+	case config.AnalysisStrategyCanaryPrimary:
+		expected, err = a.analyzeWithCanaryPrimary(ctx)
+	default:
+		err = fmt.Errorf("unknown strategy %q given", a.cfg.Strategy)
+	}
+	return
+}
+
+// switchToControlFallback makes the analyzer use the fallback strategy for the rest of the analysis
+// since the given control variant is no longer available to be compared with.
+// The fallback strategy is evaluated against the query of the canary variant.
+func (a *metricsAnalyzer) switchToControlFallback(controlVariant string) error {
+	canaryQuery, err := a.renderQuery(a.cfg.Query, a.cfg.CanaryArgs, canaryVariantName)
+	if err != nil {
+		return fmt.Errorf("failed to render query template for Canary: %w", err)
+	}
+	a.logPersister.Infof("[%s] No data points of the %s variant were found, the analysis continues with the %s strategy for the canary variant", a.id, controlVariant, a.cfg.ControlFallback)
+	a.cfg.Strategy = a.cfg.ControlFallback
+	a.cfg.Query = canaryQuery
+	a.cfg.ControlFallback = ""
+	return nil
+}
+
+// controlNotFoundError indicates that the control variant of the comparison has no data,
+// e.g. it was evicted or scaled down in the middle of the analysis.
+type controlNotFoundError struct {
+	variant string
+	// The error returned by the provider. Nil when it returned no data points without error.
+	err error
+}
+
+func (e *controlNotFoundError) Error() string {
+	if e.err != nil {
+		return fmt.Sprintf("no data points of the %s variant found: %v", e.variant, e.err)
+	}
+	return fmt.Sprintf("no data points of the %s variant found", e.variant)
+}
+
+func (e *controlNotFoundError) Unwrap() error {
+	return e.err
+}
+
+// queryControlValues returns the values of the given control variant.
+// controlNotFoundError is returned when there is no data of the variant.
+func (a *metricsAnalyzer) queryControlValues(ctx context.Context, query string, queryRange metrics.QueryRange, variant string) ([]float64, error) {
+	points, err := a.provider.QueryPoints(ctx, query, queryRange)
+	if errors.Is(err, metrics.ErrNoDataFound) {
+		return nil, &controlNotFoundError{variant: variant, err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, &controlNotFoundError{variant: variant}
+	}
+	values := make([]float64, 0, len(points))
+	for i := range points {
+		values = append(values, points[i].Value)
+	}
+	return values, nil
+}
+
 // analyzeWithThreshold returns false if any data point is out of the prediction range.
 // Return an error if the evaluation could not be executed normally.
 func (a *metricsAnalyzer) analyzeWithThreshold(ctx context.Context) (bool, error) {
@@ -225,14 +289,10 @@ func (a *metricsAnalyzer) analyzeWithCanaryBaseline(ctx context.Context) (bool,
 	for i := range canaryPoints {
 		canaryValues = append(canaryValues, canaryPoints[i].Value)
 	}
-	baselinePoints, err := a.provider.QueryPoints(ctx, baselineQuery, queryRange)
+	baselineValues, err := a.queryControlValues(ctx, baselineQuery, queryRange, baselineVariantName)
 	if err != nil {
 		return false, fmt.Errorf("failed to run query to fetch metrics for the Baseline variant: %w", err)
 	}
-	baselineValues := make([]float64, 0, len(baselinePoints))
-	for i := range baselinePoints {
-		baselineValues = append(baselineValues, baselinePoints[i].Value)
-	}
 
 	if err := compare(canaryValues, baselineValues, a.cfg.Deviation); err != nil {
 		a.logPersister.Errorf("[%s] Failed because %v. Performed query for canary: %q. Performed query for baseline: %q", a.id, err, canaryQuery, baselineQuery)
@@ -266,14 +326,10 @@ func (a *metricsAnalyzer) analyzeWithCanaryPrimary(ctx context.Context) (bool, e
 	for i := range canaryPoints {
 		canaryValues = append(canaryValues, canaryPoints[i].Value)
 	}
-	primaryPoints, err := a.provider.QueryPoints(ctx, primaryQuery, queryRange)
+	primaryValues, err := a.queryControlValues(ctx, primaryQuery, queryRange, primaryVariantName)
 	if err != nil {
 		return false, fmt.Errorf("failed to run query to fetch metrics for the Primary variant: %w", err)
 	}
-	primaryValues := make([]float64, 0, len(primaryPoints))
-	for i := range primaryPoints {
-		primaryValues = append(primaryValues, primaryPoints[i].Value)
-	}
 	if err := compare(canaryValues, primaryValues, a.cfg.Deviation); err != nil {
 		a.logPersister.Errorf("[%s] Failed because %v. Performed query for canary: %q. Performed query for primary: %q", a.id, err, canaryQuery, primaryQuery)
 		return false, nil
@@ -16,13 +16,16 @@ package analysis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/pipe-cd/pipe/pkg/config"
 )
 
@@ -39,16 +42,6 @@ func (f *fakeMetricsProvider) QueryPoints(_ context.Context, _ string, _ metrics
 	return f.points, f.err
 }
 
-type fakeLogPersister struct{}
-
-func (l *fakeLogPersister) Write(_ []byte) (int, error)         { return 0, nil }
-func (l *fakeLogPersister) Info(_ string)                       {}
-func (l *fakeLogPersister) Infof(_ string, _ ...interface{})    {}
-func (l *fakeLogPersister) Success(_ string)                    {}
-func (l *fakeLogPersister) Successf(_ string, _ ...interface{}) {}
-func (l *fakeLogPersister) Error(_ string)                      {}
-func (l *fakeLogPersister) Errorf(_ string, _ ...interface{})   {}
-
 func floatToPointer(n float64) *float64 { return &n }
 
 func Test_metricsAnalyzer_analyzeWithThreshold(t *testing.T) {
@@ -131,7 +124,7 @@ func Test_metricsAnalyzer_analyzeWithThreshold(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			tc.metricsAnalyzer.logger = zap.NewNop()
-			tc.metricsAnalyzer.logPersister = &fakeLogPersister{}
+			tc.metricsAnalyzer.logPersister = &executortest.FakeLogPersister{}
 			got, err := tc.metricsAnalyzer.analyzeWithThreshold(context.Background())
 			assert.Equal(t, tc.wantErr, err != nil)
 			assert.Equal(t, tc.want, got)
@@ -212,3 +205,94 @@ func Test_compare(t *testing.T) {
 		})
 	}
 }
+
+type fakeVariantMetricsProvider struct {
+	fakeMetricsProvider
+	// The data points keyed by query.
+	points map[string][]metrics.DataPoint
+}
+
+func (f *fakeVariantMetricsProvider) QueryPoints(_ context.Context, query string, _ metrics.QueryRange) ([]metrics.DataPoint, error) {
+	points, ok := f.points[query]
+	if !ok {
+		return nil, fmt.Errorf("no series: %w", metrics.ErrNoDataFound)
+	}
+	return points, nil
+}
+
+func Test_metricsAnalyzer_switchToControlFallback(t *testing.T) {
+	testcases := []struct {
+		name          string
+		strategy      string
+		points        map[string][]metrics.DataPoint
+		wantNoControl bool
+		wantVariant   string
+		wantExpected  bool
+	}{
+		{
+			name:     "baseline disappeared",
+			strategy: config.AnalysisStrategyCanaryBaseline,
+			points: map[string][]metrics.DataPoint{
+				"canary": {{Value: 0.01}},
+			},
+			wantNoControl: true,
+			wantVariant:   "baseline",
+			wantExpected:  true,
+		},
+		{
+			name:     "primary has no data point",
+			strategy: config.AnalysisStrategyCanaryPrimary,
+			points: map[string][]metrics.DataPoint{
+				"canary":  {{Value: 0.5}},
+				"primary": {},
+			},
+			wantNoControl: true,
+			wantVariant:   "primary",
+			wantExpected:  false,
+		},
+		{
+			name:     "baseline is available",
+			strategy: config.AnalysisStrategyCanaryBaseline,
+			points: map[string][]metrics.DataPoint{
+				"canary":   {{Value: 0.01}},
+				"baseline": {{Value: 0.01}},
+			},
+			wantNoControl: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &metricsAnalyzer{
+				id: "id",
+				cfg: config.AnalysisMetrics{
+					Strategy:        tc.strategy,
+					Query:           "{{ .BuiltInArgs.Variant.Name }}",
+					Interval:        config.Duration(time.Minute),
+					Deviation:       config.AnalysisDeviationEither,
+					Expected:        config.AnalysisExpected{Max: floatToPointer(0.1)},
+					ControlFallback: config.AnalysisStrategyThreshold,
+				},
+				provider:     &fakeVariantMetricsProvider{points: tc.points},
+				logger:       zap.NewNop(),
+				logPersister: &executortest.FakeLogPersister{},
+			}
+			_, _, err := a.analyze(context.Background())
+			var cerr *controlNotFoundError
+			assert.Equal(t, tc.wantNoControl, errors.As(err, &cerr))
+			if !tc.wantNoControl {
+				return
+			}
+			assert.Equal(t, tc.wantVariant, cerr.variant)
+
+			err = a.switchToControlFallback(cerr.variant)
+			assert.NoError(t, err)
+			assert.Equal(t, config.AnalysisStrategyThreshold, a.cfg.Strategy)
+			assert.Equal(t, "canary", a.cfg.Query)
+			assert.Empty(t, a.cfg.ControlFallback)
+
+			expected, _, err := a.analyze(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantExpected, expected)
+		})
+	}
+}
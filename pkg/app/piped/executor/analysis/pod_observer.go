@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+const (
+	variantLabel = "pipecd.dev/variant"
+
+	reasonCrashLoopBackOff = "CrashLoopBackOff"
+	reasonOOMKilled        = "OOMKilled"
+)
+
+// podObservation represents the state of the pods of a variant observed in a check.
+// It is shared by the analyses based on the pod statuses.
+type podObservation struct {
+	pods  int
+	ready int
+	// The restart counts keyed by pod and container name.
+	restarts map[string]int32
+	// The containers terminated due to OOMKilled since the given time.
+	oomKilled        int
+	crashLoopBackOff int
+	failedScheduling int
+}
+
+// observePods returns the state of the pods of the given variant.
+// Only the OOMKilled terminations finished after the given time are counted.
+func observePods(manifests []provider.Manifest, variant string, since time.Time) podObservation {
+	o := podObservation{
+		restarts: make(map[string]int32),
+	}
+	for _, m := range manifests {
+		if m.Key.Kind != provider.KindPod {
+			continue
+		}
+		pod := &corev1.Pod{}
+		if err := m.ConvertToStructuredObject(pod); err != nil {
+			continue
+		}
+		if pod.Labels[variantLabel] != variant {
+			continue
+		}
+		o.pods++
+
+		for _, c := range pod.Status.Conditions {
+			switch {
+			case c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue:
+				o.ready++
+			case c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable:
+				o.failedScheduling++
+			}
+		}
+		for _, s := range pod.Status.ContainerStatuses {
+			o.restarts[pod.Name+"/"+s.Name] = s.RestartCount
+			if w := s.State.Waiting; w != nil && w.Reason == reasonCrashLoopBackOff {
+				o.crashLoopBackOff++
+			}
+			if isOOMKilledSince(s.State, since) || isOOMKilledSince(s.LastTerminationState, since) {
+				o.oomKilled++
+			}
+		}
+	}
+	return o
+}
+
+func isOOMKilledSince(s corev1.ContainerState, since time.Time) bool {
+	return s.Terminated != nil && s.Terminated.Reason == reasonOOMKilled && !s.Terminated.FinishedAt.Time.Before(since)
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestObservePods(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-crash-looping
+  labels:
+    pipecd.dev/variant: canary
+status:
+  conditions:
+  - type: Ready
+    status: "False"
+  containerStatuses:
+  - name: app
+    restartCount: 4
+    state:
+      waiting:
+        reason: CrashLoopBackOff
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-06-01T00:05:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-ready
+  labels:
+    pipecd.dev/variant: canary
+status:
+  conditions:
+  - type: Ready
+    status: "True"
+  containerStatuses:
+  - name: app
+    restartCount: 1
+    lastState:
+      terminated:
+        reason: OOMKilled
+        finishedAt: "2021-05-31T23:00:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: canary-unschedulable
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Pending
+  conditions:
+  - type: PodScheduled
+    status: "False"
+    reason: Unschedulable
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: primary-crash-looping
+  labels:
+    pipecd.dev/variant: primary
+status:
+  containerStatuses:
+  - name: app
+    state:
+      waiting:
+        reason: CrashLoopBackOff
+`)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name     string
+		variant  string
+		since    time.Time
+		expected podObservation
+	}{
+		{
+			name:    "OOMKilled before the given time is not counted",
+			variant: "canary",
+			since:   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+			expected: podObservation{
+				pods:             3,
+				ready:            1,
+				restarts:         map[string]int32{"canary-crash-looping/app": 4, "canary-ready/app": 1},
+				oomKilled:        1,
+				crashLoopBackOff: 1,
+				failedScheduling: 1,
+			},
+		},
+		{
+			name:    "all OOMKilled are counted",
+			variant: "canary",
+			expected: podObservation{
+				pods:             3,
+				ready:            1,
+				restarts:         map[string]int32{"canary-crash-looping/app": 4, "canary-ready/app": 1},
+				oomKilled:        2,
+				crashLoopBackOff: 1,
+				failedScheduling: 1,
+			},
+		},
+		{
+			name:    "other variant",
+			variant: "primary",
+			expected: podObservation{
+				pods:             1,
+				restarts:         map[string]int32{"primary-crash-looping/app": 0},
+				crashLoopBackOff: 1,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := observePods(manifests, tc.variant, tc.since)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
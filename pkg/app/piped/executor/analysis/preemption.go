@@ -0,0 +1,156 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+)
+
+// The pod condition type added by Kubernetes when a pod is about to be
+// terminated due to a disruption such as preemption or node shutdown.
+const podConditionDisruptionTarget corev1.PodConditionType = "DisruptionTarget"
+
+var (
+	preemptedPodReasons = map[string]struct{}{
+		"Evicted":      {},
+		"Preempting":   {},
+		"Shutdown":     {},
+		"NodeShutdown": {},
+		"Terminated":   {},
+	}
+	disruptionReasons = map[string]struct{}{
+		"PreemptionByScheduler":  {},
+		"TerminationByKubelet":   {},
+		"DeletionByTaintManager": {},
+	}
+)
+
+// preemptionDetector checks whether any pod of the variant
+// was preempted or evicted within the configured window.
+// It is shared by all analyzers of the stage so it must be goroutine-safe.
+type preemptionDetector struct {
+	lister  executor.AppLiveResourceLister
+	variant string
+	window  time.Duration
+
+	mu sync.Mutex
+	// The pods of the variant observed in the previous check keyed by name.
+	// Nil means no check has been done yet.
+	pods map[string]struct{}
+	// The most recent preemption detected so far.
+	// It is remembered since the preempted pod may disappear in the next checks.
+	latest preemption
+}
+
+type preemption struct {
+	pod string
+	at  time.Time
+}
+
+// excluded returns true with the reason when the evaluation at the given time
+// should be excluded because it falls into the window after a pod preemption.
+func (d *preemptionDetector) excluded(now time.Time) (bool, string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if manifests, ok := d.lister.ListKubernetesDependedResources(); ok {
+		d.observe(manifests)
+	}
+	if d.latest.at.IsZero() || now.Sub(d.latest.at) > d.window {
+		return false, ""
+	}
+	return true, fmt.Sprintf("pod %s was preempted at %s", d.latest.pod, d.latest.at.Format(time.RFC3339))
+}
+
+// observe updates the latest preemption by looking at the given manifests.
+// Besides the pods marked as preempted, a pod disappeared since the previous check
+// is treated as preempted at the time when its replacement was created,
+// since the preempted pods may be deleted before they are observed.
+func (d *preemptionDetector) observe(manifests []provider.Manifest) {
+	var (
+		pods        = make(map[string]struct{})
+		replacement preemption
+	)
+	for _, m := range manifests {
+		if m.Key.Kind != provider.KindPod {
+			continue
+		}
+		pod := &corev1.Pod{}
+		if err := m.ConvertToStructuredObject(pod); err != nil {
+			continue
+		}
+		if pod.Labels[variantLabel] != d.variant {
+			continue
+		}
+		pods[pod.Name] = struct{}{}
+
+		if at, ok := podPreemptionTime(pod); ok {
+			d.update(preemption{pod: pod.Name, at: at})
+		}
+		if _, ok := d.pods[pod.Name]; !ok && pod.CreationTimestamp.Time.After(replacement.at) {
+			replacement = preemption{pod: pod.Name, at: pod.CreationTimestamp.Time}
+		}
+	}
+
+	if d.pods != nil && !replacement.at.IsZero() {
+		for name := range d.pods {
+			if _, ok := pods[name]; !ok {
+				d.update(preemption{pod: name, at: replacement.at})
+				break
+			}
+		}
+	}
+	d.pods = pods
+}
+
+func (d *preemptionDetector) update(p preemption) {
+	if p.at.After(d.latest.at) {
+		d.latest = p
+	}
+}
+
+// podPreemptionTime returns the time when the given pod was preempted.
+// The second returned value is false if the pod was not preempted.
+func podPreemptionTime(pod *corev1.Pod) (time.Time, bool) {
+	for _, c := range pod.Status.Conditions {
+		if c.Type != podConditionDisruptionTarget || c.Status != corev1.ConditionTrue {
+			continue
+		}
+		if _, ok := disruptionReasons[c.Reason]; ok {
+			return c.LastTransitionTime.Time, true
+		}
+	}
+
+	if _, ok := preemptedPodReasons[pod.Status.Reason]; !ok {
+		return time.Time{}, false
+	}
+	var at time.Time
+	for _, s := range pod.Status.ContainerStatuses {
+		if t := s.State.Terminated; t != nil && t.FinishedAt.Time.After(at) {
+			at = t.FinishedAt.Time
+		}
+	}
+	if at.IsZero() && pod.DeletionTimestamp != nil {
+		at = pod.DeletionTimestamp.Time
+	}
+	return at, !at.IsZero()
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+type fakeLiveResourceLister struct {
+	depended []provider.Manifest
+}
+
+func (l fakeLiveResourceLister) ListKubernetesResources() ([]provider.Manifest, bool) {
+	return nil, true
+}
+
+func (l fakeLiveResourceLister) ListKubernetesDependedResources() ([]provider.Manifest, bool) {
+	return l.depended, true
+}
+
+func TestPreemptionDetector(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: running
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: preempted-by-scheduler
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Failed
+  conditions:
+  - type: DisruptionTarget
+    status: "True"
+    reason: PreemptionByScheduler
+    lastTransitionTime: "2021-06-01T10:00:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: evicted
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Failed
+  reason: Evicted
+  containerStatuses:
+  - name: app
+    state:
+      terminated:
+        exitCode: 137
+        finishedAt: "2021-06-01T10:02:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: oom-killed
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Failed
+  reason: OOMKilled
+  containerStatuses:
+  - name: app
+    state:
+      terminated:
+        exitCode: 137
+        finishedAt: "2021-06-01T10:05:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: primary-evicted
+  labels:
+    pipecd.dev/variant: primary
+status:
+  phase: Failed
+  reason: Evicted
+  containerStatuses:
+  - name: app
+    state:
+      terminated:
+        exitCode: 137
+        finishedAt: "2021-06-01T10:04:00Z"
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: replacement
+  creationTimestamp: "2021-06-01T10:10:00Z"
+  labels:
+    pipecd.dev/variant: canary
+status:
+  phase: Running
+`)
+	require.NoError(t, err)
+	var (
+		running     = manifests[0]
+		preempted   = manifests[1:3]
+		oomKilled   = manifests[3]
+		primary     = manifests[4]
+		replacement = manifests[5]
+	)
+
+	testcases := []struct {
+		name string
+		// The manifests listed at each check.
+		checks         [][]provider.Manifest
+		now            time.Time
+		expected       bool
+		expectedReason string
+	}{
+		{
+			name:           "within the window after the latest preemption",
+			checks:         [][]provider.Manifest{append([]provider.Manifest{running, oomKilled}, preempted...)},
+			now:            time.Date(2021, 6, 1, 10, 6, 0, 0, time.UTC),
+			expected:       true,
+			expectedReason: "pod evicted was preempted at 2021-06-01T10:02:00Z",
+		},
+		{
+			name:     "after the window",
+			checks:   [][]provider.Manifest{append([]provider.Manifest{running, oomKilled}, preempted...)},
+			now:      time.Date(2021, 6, 1, 10, 8, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name:     "pod of another variant",
+			checks:   [][]provider.Manifest{{running, primary}},
+			now:      time.Date(2021, 6, 1, 10, 6, 0, 0, time.UTC),
+			expected: false,
+		},
+		{
+			name: "preempted pod disappeared in the next check",
+			checks: [][]provider.Manifest{
+				append([]provider.Manifest{running}, preempted...),
+				{running},
+			},
+			now:            time.Date(2021, 6, 1, 10, 6, 0, 0, time.UTC),
+			expected:       true,
+			expectedReason: "pod evicted was preempted at 2021-06-01T10:02:00Z",
+		},
+		{
+			name: "pod replaced between checks",
+			checks: [][]provider.Manifest{
+				{running, oomKilled},
+				{running, replacement},
+			},
+			now:            time.Date(2021, 6, 1, 10, 12, 0, 0, time.UTC),
+			expected:       true,
+			expectedReason: "pod oom-killed was preempted at 2021-06-01T10:10:00Z",
+		},
+		{
+			name: "new pod in the first check",
+			checks: [][]provider.Manifest{
+				{running, replacement},
+			},
+			now:      time.Date(2021, 6, 1, 10, 12, 0, 0, time.UTC),
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := &fakeLiveResourceLister{}
+			d := &preemptionDetector{
+				lister:  lister,
+				variant: "canary",
+				window:  5 * time.Minute,
+			}
+			var (
+				excluded bool
+				reason   string
+			)
+			for _, manifests := range tc.checks {
+				lister.depended = manifests
+				excluded, reason = d.excluded(tc.now)
+			}
+			assert.Equal(t, tc.expected, excluded)
+			assert.Equal(t, tc.expectedReason, reason)
+		})
+	}
+}
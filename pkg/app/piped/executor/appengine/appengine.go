@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"context"
+	"sort"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/appengine"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageAppEngineSync, f)
+	r.Register(model.StageAppEngineDeployVersion, f)
+	r.Register(model.StageAppEngineMigrateTraffic, f)
+
+	r.RegisterRollback(model.ApplicationKind_APPENGINE, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func loadVersionManifest(in *executor.Input, versionManifestFile string, ds *deploysource.DeploySource) (*provider.Version, bool) {
+	in.LogPersister.Infof("Loading version manifest at commit %s", ds.Revision)
+
+	version, err := provider.LoadVersionManifest(ds.AppDir, versionManifestFile)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load version manifest (%v)", err)
+		return nil, false
+	}
+
+	in.LogPersister.Infof("Successfully loaded the version manifest at commit %s", ds.Revision)
+	return version, true
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderAppEngineConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderAppEngine)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.AppEngineConfig
+	found = true
+	return
+}
+
+func decideVersionID(commit string, lp executor.LogPersister) (id string, ok bool) {
+	var err error
+	id, err = provider.DecideVersionID(commit)
+	if err != nil {
+		lp.Errorf("Unable to decide version id for the commit %s (%v)", commit, err)
+		return
+	}
+
+	ok = true
+	return
+}
+
+// deployVersion creates the given version of the service unless it has already been deployed.
+// The created version receives no traffic until it is migrated.
+func deployVersion(ctx context.Context, client provider.Client, service, id string, version *provider.Version, lp executor.LogPersister) bool {
+	_, err := client.GetVersion(ctx, service, id)
+	if err == nil {
+		lp.Infof("Version %s of service %s was already deployed", id, service)
+		return true
+	}
+	if err != provider.ErrVersionNotFound {
+		lp.Errorf("Failed while checking the existence of version %s (%v)", id, err)
+		return false
+	}
+
+	lp.Infof("Start deploying version %s of service %s, this may take a few minutes", id, service)
+	version.Id = id
+	if err := client.CreateVersion(ctx, service, version); err != nil {
+		lp.Errorf("Failed to deploy version %s (%v)", id, err)
+		return false
+	}
+
+	lp.Infof("Successfully deployed version %s of service %s", id, service)
+	return true
+}
+
+func migrateTraffic(ctx context.Context, client provider.Client, service, shardBy string, allocations map[string]float64, lp executor.LogPersister) bool {
+	versions := make([]string, 0, len(allocations))
+	for v := range allocations {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	lp.Infof("Start migrating traffic of service %s as below:", service)
+	for _, v := range versions {
+		lp.Infof("  %s: %.0f%%", v, allocations[v]*100)
+	}
+
+	if err := client.MigrateTraffic(ctx, service, shardBy, allocations); err != nil {
+		lp.Errorf("Failed to migrate traffic (%v)", err)
+		return false
+	}
+
+	lp.Successf("Successfully migrated traffic of service %s", service)
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/appengine"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+)
+
+type fakeClient struct {
+	provider.Client
+	versions  map[string]*provider.Version
+	getErr    error
+	createErr error
+}
+
+func (c *fakeClient) GetVersion(_ context.Context, _, id string) (*provider.Version, error) {
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	v, ok := c.versions[id]
+	if !ok {
+		return nil, provider.ErrVersionNotFound
+	}
+	return v, nil
+}
+
+func (c *fakeClient) CreateVersion(_ context.Context, _ string, v *provider.Version) error {
+	if c.createErr != nil {
+		return c.createErr
+	}
+	c.versions[v.Id] = v
+	return nil
+}
+
+func TestDeployVersion(t *testing.T) {
+	testcases := []struct {
+		name        string
+		client      *fakeClient
+		expected    bool
+		wantCreated bool
+	}{
+		{
+			name:        "create new version",
+			client:      &fakeClient{versions: map[string]*provider.Version{}},
+			expected:    true,
+			wantCreated: true,
+		},
+		{
+			name: "version already exists",
+			client: &fakeClient{versions: map[string]*provider.Version{
+				"a1b2c3d": {Id: "a1b2c3d", Runtime: "go113"},
+			}},
+			expected: true,
+		},
+		{
+			name: "failed to get version",
+			client: &fakeClient{
+				versions: map[string]*provider.Version{},
+				getErr:   errors.New("permission denied"),
+			},
+			expected: false,
+		},
+		{
+			name: "failed to create version",
+			client: &fakeClient{
+				versions:  map[string]*provider.Version{},
+				createErr: errors.New("invalid runtime"),
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			version := &provider.Version{Runtime: "go114"}
+			got := deployVersion(context.Background(), tc.client, "default", "a1b2c3d", version, &executortest.FakeLogPersister{})
+			assert.Equal(t, tc.expected, got)
+			if tc.wantCreated {
+				assert.Equal(t, version, tc.client.versions["a1b2c3d"])
+				assert.Equal(t, "a1b2c3d", version.Id)
+			}
+		})
+	}
+}
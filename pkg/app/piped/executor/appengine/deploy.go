@@ -0,0 +1,168 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appengine
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/appengine"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	migratePercentageMetadataKey = "migrate-percentage"
+)
+
+type deployExecutor struct {
+	executor.Input
+
+	deploySource *deploysource.DeploySource
+	deployCfg    *config.AppEngineDeploymentSpec
+	client       provider.Client
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.deploySource = ds
+	e.deployCfg = ds.DeploymentConfig.AppEngineDeploymentSpec
+	if e.deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing AppEngineDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing AppEngineDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cpName, cpCfg, found := findCloudProvider(&e.Input)
+	if !found {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.client, err = provider.DefaultRegistry().Client(ctx, cpName, cpCfg, e.Logger)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to create App Engine client for the provider (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageAppEngineSync:
+		status = e.ensureSync(ctx)
+
+	case model.StageAppEngineDeployVersion:
+		status = e.ensureDeployVersion(ctx)
+
+	case model.StageAppEngineMigrateTraffic:
+		status = e.ensureMigrateTraffic(ctx)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for appengine application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	id, ok := e.deployTargetVersion(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	allocations := map[string]float64{id: 1}
+	if !migrateTraffic(ctx, e.client, e.deployCfg.Input.Service, e.deployCfg.Input.ShardBy, allocations, e.LogPersister) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureDeployVersion(ctx context.Context) model.StageStatus {
+	if _, ok := e.deployTargetVersion(ctx); !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureMigrateTraffic(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.AppEngineMigrateTrafficStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	metadata := map[string]string{
+		migratePercentageMetadataKey: strconv.FormatInt(int64(options.Percent.Int()), 10),
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save migrating percentage to metadata", zap.Error(err))
+	}
+
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	runningID, ok := decideVersionID(e.Deployment.RunningCommitHash, e.LogPersister)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The new version is deployed here when no APPENGINE_DEPLOY_VERSION stage was executed before.
+	id, ok := e.deployTargetVersion(ctx)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	allocations := provider.MakeAllocations(runningID, id, options.Percent.Int())
+	if !migrateTraffic(ctx, e.client, e.deployCfg.Input.Service, e.deployCfg.Input.ShardBy, allocations, e.LogPersister) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// deployTargetVersion deploys the version at the target commit and returns its id.
+func (e *deployExecutor) deployTargetVersion(ctx context.Context) (string, bool) {
+	version, ok := loadVersionManifest(&e.Input, e.deployCfg.Input.VersionManifestFile, e.deploySource)
+	if !ok {
+		return "", false
+	}
+
+	id, ok := decideVersionID(e.Deployment.Trigger.Commit.Hash, e.LogPersister)
+	if !ok {
+		return "", false
+	}
+
+	if !deployVersion(ctx, e.client, e.deployCfg.Input.Service, id, version, e.LogPersister) {
+		return "", false
+	}
+	return id, true
+}
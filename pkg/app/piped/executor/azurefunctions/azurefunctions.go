@@ -0,0 +1,155 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"context"
+	"fmt"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/azurefunctions"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageAzureFunctionsSync, f)
+	r.Register(model.StageAzureFunctionsCanaryRollout, f)
+	r.Register(model.StageAzureFunctionsPromote, f)
+
+	r.RegisterRollback(model.ApplicationKind_AZURE_FUNCTIONS, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderAzureConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderAzure)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.AzureConfig
+	found = true
+	return
+}
+
+func loadClient(in *executor.Input) (provider.Client, bool) {
+	name, cfg, found := findCloudProvider(in)
+	if !found {
+		return nil, false
+	}
+	client, err := provider.DefaultRegistry().Client(name, cfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create Azure client for the provider %s (%v)", name, err)
+		return nil, false
+	}
+	return client, true
+}
+
+func siteOf(cfg config.AzureFunctionsDeploymentInput) provider.Site {
+	return provider.Site{
+		ResourceGroup: cfg.ResourceGroup,
+		Name:          cfg.AppName,
+	}
+}
+
+// runningPackage returns the package the given slot is running from.
+func runningPackage(ctx context.Context, client provider.Client, site provider.Site, slot string) (string, error) {
+	settings, err := client.GetAppSettings(ctx, site, slot)
+	if err != nil {
+		return "", err
+	}
+	return settings[provider.RunFromPackageSetting], nil
+}
+
+// deployToSlot makes the given slot run the package with the app settings in the given config.
+// The production slot is not affected until the slot is swapped with it.
+func deployToSlot(ctx context.Context, client provider.Client, lp executor.LogPersister, cfg config.AzureFunctionsDeploymentInput) error {
+	site := siteOf(cfg)
+	if _, err := client.GetSlot(ctx, site, cfg.Slot); err != nil {
+		if err == provider.ErrSlotNotFound {
+			return executor.NewUserError("slot %s of app %s was not found, it must be created in advance", cfg.Slot, cfg.AppName)
+		}
+		return err
+	}
+
+	current, err := client.GetAppSettings(ctx, site, cfg.Slot)
+	if err != nil {
+		return err
+	}
+	lp.Infof("Start deploying package %s to slot %s of app %s", cfg.PackageURL, cfg.Slot, cfg.AppName)
+	settings := provider.MakeAppSettings(current, cfg.AppSettings, cfg.PackageURL)
+	if err := client.UpdateAppSettings(ctx, site, cfg.Slot, settings); err != nil {
+		return err
+	}
+
+	slot, err := client.GetSlot(ctx, site, cfg.Slot)
+	if err != nil {
+		return err
+	}
+	if slot.State != provider.SlotStateRunning {
+		return fmt.Errorf("slot %s is %s", cfg.Slot, slot.State)
+	}
+	lp.Infof("Successfully deployed package to slot %s, it is available at https://%s", cfg.Slot, slot.DefaultHostName)
+	return nil
+}
+
+// swapSlot swaps the slot in the given config with the production slot.
+// App Service warms up the slot before routing the production traffic to it.
+func swapSlot(ctx context.Context, client provider.Client, lp executor.LogPersister, cfg config.AzureFunctionsDeploymentInput) error {
+	lp.Infof("Start swapping slot %s with the production slot of app %s, this may take a few minutes", cfg.Slot, cfg.AppName)
+	if err := client.SwapSlot(ctx, siteOf(cfg), cfg.Slot); err != nil {
+		return err
+	}
+	lp.Infof("Successfully swapped slot %s with the production slot", cfg.Slot)
+	return nil
+}
+
+// syncPackage deploys the package in the given config to the slot and swaps it with the production slot
+// unless the production slot is already running the package.
+func syncPackage(ctx context.Context, client provider.Client, lp executor.LogPersister, cfg config.AzureFunctionsDeploymentInput) error {
+	current, err := runningPackage(ctx, client, siteOf(cfg), config.AzureFunctionsProductionSlot)
+	if err != nil {
+		return err
+	}
+	if current == cfg.PackageURL {
+		lp.Infof("The production slot of app %s is already running package %s", cfg.AppName, cfg.PackageURL)
+		return nil
+	}
+
+	if err := deployToSlot(ctx, client, lp, cfg); err != nil {
+		return err
+	}
+	return swapSlot(ctx, client, lp, cfg)
+}
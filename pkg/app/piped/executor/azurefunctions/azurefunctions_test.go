@@ -0,0 +1,126 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/azurefunctions"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type fakeClient struct {
+	provider.Client
+
+	// App settings keyed by slot name.
+	settings map[string]map[string]string
+	swapped  []string
+}
+
+func (c *fakeClient) GetSlot(_ context.Context, _ provider.Site, slot string) (*provider.Slot, error) {
+	if _, ok := c.settings[slot]; !ok {
+		return nil, provider.ErrSlotNotFound
+	}
+	return &provider.Slot{Name: slot, State: provider.SlotStateRunning, DefaultHostName: "hello-" + slot + ".azurewebsites.net"}, nil
+}
+
+func (c *fakeClient) GetAppSettings(_ context.Context, _ provider.Site, slot string) (map[string]string, error) {
+	return c.settings[slot], nil
+}
+
+func (c *fakeClient) UpdateAppSettings(_ context.Context, _ provider.Site, slot string, settings map[string]string) error {
+	c.settings[slot] = settings
+	return nil
+}
+
+func (c *fakeClient) SwapSlot(_ context.Context, _ provider.Site, slot string) error {
+	prod := config.AzureFunctionsProductionSlot
+	c.settings[prod], c.settings[slot] = c.settings[slot], c.settings[prod]
+	c.swapped = append(c.swapped, slot)
+	return nil
+}
+
+func TestSyncPackage(t *testing.T) {
+	const (
+		oldPackage = "https://pipecd.blob.core.windows.net/packages/hello-v1.zip"
+		newPackage = "https://pipecd.blob.core.windows.net/packages/hello-v2.zip"
+	)
+	cfg := config.AzureFunctionsDeploymentInput{
+		ResourceGroup: "pipecd",
+		AppName:       "hello",
+		Slot:          "staging",
+		PackageURL:    newPackage,
+		AppSettings:   map[string]string{"LOG_LEVEL": "debug"},
+	}
+
+	testcases := []struct {
+		name        string
+		client      *fakeClient
+		wantSwapped []string
+		wantProd    map[string]string
+		wantErr     error
+	}{
+		{
+			name: "deploy and swap",
+			client: &fakeClient{settings: map[string]map[string]string{
+				"production": {provider.RunFromPackageSetting: oldPackage},
+				"staging":    {"LOG_LEVEL": "info"},
+			}},
+			wantSwapped: []string{"staging"},
+			wantProd: map[string]string{
+				provider.RunFromPackageSetting: newPackage,
+				"LOG_LEVEL":                    "debug",
+			},
+		},
+		{
+			name: "production slot is already running the package",
+			client: &fakeClient{settings: map[string]map[string]string{
+				"production": {provider.RunFromPackageSetting: newPackage},
+				"staging":    {},
+			}},
+			wantProd: map[string]string{provider.RunFromPackageSetting: newPackage},
+		},
+		{
+			name: "slot does not exist",
+			client: &fakeClient{settings: map[string]map[string]string{
+				"production": {provider.RunFromPackageSetting: oldPackage},
+			}},
+			wantProd: map[string]string{provider.RunFromPackageSetting: oldPackage},
+			wantErr:  executor.NewUserError("slot staging of app hello was not found, it must be created in advance"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := syncPackage(context.Background(), tc.client, &executortest.FakeLogPersister{}, cfg)
+			if tc.wantErr != nil {
+				require.Error(t, err)
+				assert.Equal(t, tc.wantErr.Error(), err.Error())
+				var ue *executor.Error
+				assert.True(t, errors.As(err, &ue))
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantSwapped, tc.client.swapped)
+			assert.Equal(t, tc.wantProd, tc.client.settings["production"])
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurefunctions
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/azurefunctions"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+
+	deployCfg *config.AzureFunctionsDeploymentSpec
+	client    provider.Client
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.deployCfg = ds.DeploymentConfig.AzureFunctionsDeploymentSpec
+	if e.deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing AzureFunctionsDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing AzureFunctionsDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var ok bool
+	if e.client, ok = loadClient(&e.Input); !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageAzureFunctionsSync:
+		status = e.ensureSync(ctx)
+
+	case model.StageAzureFunctionsCanaryRollout:
+		status = e.ensureCanaryRollout(ctx)
+
+	case model.StageAzureFunctionsPromote:
+		status = e.ensurePromote(ctx)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for azure functions application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	if err := syncPackage(ctx, e.client, e.LogPersister, e.deployCfg.Input); err != nil {
+		e.ReportError(err)
+		e.LogPersister.Errorf("Failed to sync app %s (%v)", e.deployCfg.Input.AppName, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully synced app %s", e.deployCfg.Input.AppName)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// ensureCanaryRollout deploys the package to the slot without affecting the production slot.
+func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageStatus {
+	if err := deployToSlot(ctx, e.client, e.LogPersister, e.deployCfg.Input); err != nil {
+		e.ReportError(err)
+		e.LogPersister.Errorf("Failed to deploy package to slot %s (%v)", e.deployCfg.Input.Slot, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// ensurePromote swaps the slot with the production slot.
+// The package is deployed to the slot here when no AZURE_FUNCTIONS_CANARY_ROLLOUT stage was executed before.
+func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
+	cfg := e.deployCfg.Input
+	current, err := runningPackage(ctx, e.client, siteOf(cfg), cfg.Slot)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get the package of slot %s (%v)", cfg.Slot, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if current != cfg.PackageURL {
+		if err := deployToSlot(ctx, e.client, e.LogPersister, cfg); err != nil {
+			e.ReportError(err)
+			e.LogPersister.Errorf("Failed to deploy package to slot %s (%v)", cfg.Slot, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	if err := swapSlot(ctx, e.client, e.LogPersister, cfg); err != nil {
+		e.LogPersister.Errorf("Failed to promote slot %s (%v)", cfg.Slot, err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully promoted package %s to the production slot", cfg.PackageURL)
+	return model.StageStatus_STAGE_SUCCESS
+}
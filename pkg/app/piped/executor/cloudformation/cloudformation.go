@@ -0,0 +1,236 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudformation"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// Whether a change set has been executed on the stack by this deployment.
+	stackUpdatedMetadataKey = "stack-updated"
+)
+
+var (
+	// How often to check the state of the change set or the stack.
+	pollInterval = 10 * time.Second
+	// How long to wait for the change set to be created.
+	changeSetCreationTimeout = 10 * time.Minute
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageCloudFormationSync, f)
+	r.Register(model.StageCloudFormationPlan, f)
+	r.Register(model.StageCloudFormationApply, f)
+
+	r.RegisterRollback(model.ApplicationKind_CLOUDFORMATION, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderCloudFormationConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderCloudFormation)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.CloudFormationConfig
+	found = true
+	return
+}
+
+func loadClient(in *executor.Input) (provider.Client, bool) {
+	name, cfg, found := findCloudProvider(in)
+	if !found {
+		return nil, false
+	}
+	client, err := provider.DefaultRegistry().Client(name, cfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create CloudFormation client for the provider %s (%v)", name, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// makeChangeSetInput loads the template at the given deploy source
+// and returns the input to create a change set from it.
+func makeChangeSetInput(in *executor.Input, ds *deploysource.DeploySource, cfg config.CloudFormationDeploymentInput, changeSetName string) (provider.ChangeSetInput, bool) {
+	in.LogPersister.Infof("Loading template at commit %s", ds.Revision)
+	template, err := provider.LoadTemplate(ds.AppDir, cfg.TemplateFile)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load template (%v)", err)
+		return provider.ChangeSetInput{}, false
+	}
+	if provider.IsSAMTemplate(template) {
+		in.LogPersister.Info("Detected SAM template, it will be transformed by CloudFormation")
+	}
+	return provider.ChangeSetInput{
+		StackName:     cfg.StackName,
+		ChangeSetName: changeSetName,
+		TemplateBody:  template,
+		Parameters:    cfg.Parameters,
+		Capabilities:  provider.DecideCapabilities(template, cfg.Capabilities),
+		Tags:          cfg.Tags,
+		Description:   fmt.Sprintf("Created by PipeCD deployment %s at commit %s", in.Deployment.Id, ds.Revision),
+	}, true
+}
+
+// createChangeSet creates a change set of the stack and waits until its changes have been computed.
+// The change set containing no change is deleted and returned with its status.
+func createChangeSet(ctx context.Context, client provider.Client, lp executor.LogPersister, in provider.ChangeSetInput) (*provider.ChangeSet, error) {
+	stack, err := client.GetStack(ctx, in.StackName)
+	switch {
+	case errors.Is(err, provider.ErrStackNotFound):
+		in.ChangeSetType = provider.ChangeSetTypeCreate
+	case err != nil:
+		return nil, err
+	case stack.Status == provider.StackStatusReviewInProgress:
+		// The stack was reserved by a CREATE change set which has not been executed yet.
+		in.ChangeSetType = provider.ChangeSetTypeCreate
+	case stack.Status == provider.StackStatusRollbackComplete:
+		return nil, executor.NewUserError("stack %s failed to be created and must be deleted before deploying again", in.StackName)
+	case stack.IsInProgress():
+		return nil, fmt.Errorf("stack %s is %s by another operation", in.StackName, stack.Status)
+	default:
+		in.ChangeSetType = provider.ChangeSetTypeUpdate
+	}
+
+	if err := client.CreateChangeSet(ctx, in); err != nil {
+		return nil, err
+	}
+	lp.Infof("Created %s change set %s of stack %s, waiting for its changes to be computed", in.ChangeSetType, in.ChangeSetName, in.StackName)
+
+	cs, err := waitChangeSetCreated(ctx, client, in.StackName, in.ChangeSetName)
+	if err != nil {
+		return nil, err
+	}
+	if cs.HasNoChanges() {
+		if err := client.DeleteChangeSet(ctx, in.StackName, in.ChangeSetName); err != nil {
+			lp.Errorf("Failed to delete change set %s containing no change (%v)", in.ChangeSetName, err)
+		}
+		return cs, nil
+	}
+	if cs.Status != provider.ChangeSetStatusCreateComplete {
+		return nil, fmt.Errorf("change set %s was %s: %s", in.ChangeSetName, cs.Status, cs.StatusReason)
+	}
+	for _, c := range cs.Changes {
+		lp.Info(c.String())
+	}
+	return cs, nil
+}
+
+// waitChangeSetCreated blocks until the given change set has been created or failed.
+func waitChangeSetCreated(ctx context.Context, client provider.Client, stackName, changeSetName string) (*provider.ChangeSet, error) {
+	ctx, cancel := context.WithTimeout(ctx, changeSetCreationTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		cs, err := client.GetChangeSet(ctx, stackName, changeSetName)
+		if err != nil {
+			return nil, err
+		}
+		if cs.Status == provider.ChangeSetStatusCreateComplete || cs.Status == provider.ChangeSetStatusFailed {
+			return cs, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("change set %s was not created: %w", changeSetName, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// executeChangeSet executes the given change set and waits until the stack has been updated.
+// An error is returned when the update failed, CloudFormation rolls it back by itself in that case.
+func executeChangeSet(ctx context.Context, in *executor.Input, client provider.Client, stackName, changeSetName string) error {
+	if err := client.ExecuteChangeSet(ctx, stackName, changeSetName); err != nil {
+		return err
+	}
+	// Record that the stack has been touched before waiting
+	// so that the rollback can revert it even if piped stopped in the middle.
+	if err := in.MetadataStore.Set(ctx, stackUpdatedMetadataKey, "true"); err != nil {
+		in.LogPersister.Errorf("Unable to store the execution of change set to metadata store (%v)", err)
+	}
+	in.LogPersister.Infof("Executed change set %s, waiting for stack %s to be updated", changeSetName, stackName)
+
+	stack, err := waitStackOperation(ctx, client, in.LogPersister, stackName)
+	if err != nil {
+		return err
+	}
+	if stack.IsRolledBack() || stack.IsFailed() {
+		return fmt.Errorf("stack %s was %s: %s", stackName, stack.Status, stack.StatusReason)
+	}
+	return nil
+}
+
+// waitStackOperation blocks until no operation is running on the given stack.
+// The timeout is left to the stage timeout since the operation can not be stopped without rolling it back.
+func waitStackOperation(ctx context.Context, client provider.Client, lp executor.LogPersister, stackName string) (*provider.Stack, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var status string
+	for {
+		stack, err := client.GetStack(ctx, stackName)
+		if err != nil {
+			return nil, err
+		}
+		if !stack.IsInProgress() {
+			return stack, nil
+		}
+		if stack.Status != status {
+			lp.Infof("Stack %s is %s", stackName, stack.Status)
+			status = stack.Status
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("stack %s is still %s: %w", stackName, stack.Status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
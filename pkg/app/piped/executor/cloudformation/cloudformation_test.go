@@ -0,0 +1,229 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudformation"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeMetadataStore struct {
+	stages map[string]map[string]string
+}
+
+func (m *fakeMetadataStore) Get(_ string) (string, bool)              { return "", false }
+func (m *fakeMetadataStore) Set(_ context.Context, _, _ string) error { return nil }
+func (m *fakeMetadataStore) GetStageMetadata(id string) (map[string]string, bool) {
+	md, ok := m.stages[id]
+	return md, ok
+}
+func (m *fakeMetadataStore) SetStageMetadata(_ context.Context, _ string, _ map[string]string) error {
+	return nil
+}
+
+type fakeClient struct {
+	provider.Client
+
+	stack      *provider.Stack
+	changeSets []*provider.ChangeSet
+
+	createdType string
+	deleted     []string
+}
+
+func (c *fakeClient) GetStack(_ context.Context, _ string) (*provider.Stack, error) {
+	if c.stack == nil {
+		return nil, provider.ErrStackNotFound
+	}
+	return c.stack, nil
+}
+
+func (c *fakeClient) CreateChangeSet(_ context.Context, in provider.ChangeSetInput) error {
+	c.createdType = in.ChangeSetType
+	return nil
+}
+
+func (c *fakeClient) GetChangeSet(_ context.Context, _, _ string) (*provider.ChangeSet, error) {
+	cs := c.changeSets[0]
+	if len(c.changeSets) > 1 {
+		c.changeSets = c.changeSets[1:]
+	}
+	return cs, nil
+}
+
+func (c *fakeClient) DeleteChangeSet(_ context.Context, _, name string) error {
+	c.deleted = append(c.deleted, name)
+	return nil
+}
+
+func TestCreateChangeSet(t *testing.T) {
+	pollInterval = time.Millisecond
+
+	var (
+		pending = &provider.ChangeSet{Status: "CREATE_PENDING"}
+		created = &provider.ChangeSet{
+			Status:          provider.ChangeSetStatusCreateComplete,
+			ExecutionStatus: provider.ChangeSetExecutionStatusAvailable,
+			Changes: []provider.ResourceChange{
+				{Action: "Add", LogicalResourceID: "Function", ResourceType: "AWS::Lambda::Function"},
+			},
+		}
+		noChanges = &provider.ChangeSet{
+			Status:       provider.ChangeSetStatusFailed,
+			StatusReason: "The submitted information didn't contain changes. Submit different information to create a change set.",
+		}
+	)
+
+	testcases := []struct {
+		name        string
+		client      *fakeClient
+		wantType    string
+		wantDeleted bool
+		wantErr     bool
+	}{
+		{
+			name: "create new stack",
+			client: &fakeClient{
+				changeSets: []*provider.ChangeSet{pending, created},
+			},
+			wantType: provider.ChangeSetTypeCreate,
+		},
+		{
+			name: "update existing stack",
+			client: &fakeClient{
+				stack:      &provider.Stack{Status: "UPDATE_COMPLETE"},
+				changeSets: []*provider.ChangeSet{created},
+			},
+			wantType: provider.ChangeSetTypeUpdate,
+		},
+		{
+			name: "stack reserved by unexecuted change set",
+			client: &fakeClient{
+				stack:      &provider.Stack{Status: provider.StackStatusReviewInProgress},
+				changeSets: []*provider.ChangeSet{created},
+			},
+			wantType: provider.ChangeSetTypeCreate,
+		},
+		{
+			name: "no changes",
+			client: &fakeClient{
+				stack:      &provider.Stack{Status: "UPDATE_COMPLETE"},
+				changeSets: []*provider.ChangeSet{noChanges},
+			},
+			wantType:    provider.ChangeSetTypeUpdate,
+			wantDeleted: true,
+		},
+		{
+			name: "failed to be created",
+			client: &fakeClient{
+				stack:      &provider.Stack{Status: "UPDATE_COMPLETE"},
+				changeSets: []*provider.ChangeSet{{Status: provider.ChangeSetStatusFailed, StatusReason: "Template error"}},
+			},
+			wantType: provider.ChangeSetTypeUpdate,
+			wantErr:  true,
+		},
+		{
+			name: "stack in rollback complete",
+			client: &fakeClient{
+				stack: &provider.Stack{Status: provider.StackStatusRollbackComplete},
+			},
+			wantErr: true,
+		},
+		{
+			name: "stack in progress",
+			client: &fakeClient{
+				stack: &provider.Stack{Status: provider.StackStatusUpdateInProgress},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := provider.ChangeSetInput{StackName: "stack", ChangeSetName: "change-set"}
+			_, err := createChangeSet(context.Background(), tc.client, &executortest.FakeLogPersister{}, in)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantType, tc.client.createdType)
+			if tc.wantDeleted {
+				require.Equal(t, []string{"change-set"}, tc.client.deleted)
+			} else {
+				assert.Empty(t, tc.client.deleted)
+			}
+		})
+	}
+}
+
+func TestFindPlannedChangeSet(t *testing.T) {
+	stages := []*model.PipelineStage{
+		{Id: "plan-1", Name: model.StageCloudFormationPlan.String(), Index: 0},
+		{Id: "approval", Name: model.StageWaitApproval.String(), Index: 1},
+		{Id: "apply-1", Name: model.StageCloudFormationApply.String(), Index: 2},
+		{Id: "plan-2", Name: model.StageCloudFormationPlan.String(), Index: 3},
+		{Id: "apply-2", Name: model.StageCloudFormationApply.String(), Index: 4},
+	}
+	testcases := []struct {
+		name      string
+		stage     *model.PipelineStage
+		metadata  map[string]map[string]string
+		want      string
+		wantFound bool
+	}{
+		{
+			name:  "no plan stage result",
+			stage: stages[2],
+		},
+		{
+			name:  "planned before",
+			stage: stages[2],
+			metadata: map[string]map[string]string{
+				"plan-1": {changeSetNameMetadataKey: "pipecd-plan-0-deployment"},
+			},
+			want:      "pipecd-plan-0-deployment",
+			wantFound: true,
+		},
+		{
+			name:  "use the last plan stage",
+			stage: stages[4],
+			metadata: map[string]map[string]string{
+				"plan-1": {changeSetNameMetadataKey: "pipecd-plan-0-deployment"},
+				"plan-2": {changeSetNameMetadataKey: "pipecd-plan-3-deployment"},
+			},
+			want:      "pipecd-plan-3-deployment",
+			wantFound: true,
+		},
+		{
+			name:  "plan stage after the current stage",
+			stage: stages[2],
+			metadata: map[string]map[string]string{
+				"plan-2": {changeSetNameMetadataKey: "pipecd-plan-3-deployment"},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeMetadataStore{stages: tc.metadata}
+			got, found := findPlannedChangeSet(stages, tc.stage.Index, store)
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.want, got[changeSetNameMetadataKey])
+		})
+	}
+}
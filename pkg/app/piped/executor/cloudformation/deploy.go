@@ -0,0 +1,223 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudformation"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// The name of the change set created by CLOUDFORMATION_PLAN stage.
+	changeSetNameMetadataKey = "change-set-name"
+	// The summary of the changes contained in the change set.
+	planSummaryMetadataKey = "plan-summary"
+
+	noChangesSummary = "No changes"
+)
+
+type deployExecutor struct {
+	executor.Input
+
+	client    provider.Client
+	ds        *deploysource.DeploySource
+	deployCfg *config.CloudFormationDeploymentSpec
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.deployCfg = ds.DeploymentConfig.CloudFormationDeploymentSpec
+	if e.deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing CloudFormationDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CloudFormationDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.ds = ds
+
+	var ok bool
+	e.client, ok = loadClient(&e.Input)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageCloudFormationSync:
+		status = e.ensureSync(ctx)
+
+	case model.StageCloudFormationPlan:
+		status = e.ensurePlan(ctx)
+
+	case model.StageCloudFormationApply:
+		status = e.ensureApply(ctx)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for cloudformation application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	name := provider.MakeChangeSetName(fmt.Sprintf("sync-%d", e.Stage.Index), e.Deployment.Id)
+	return e.createAndExecuteChangeSet(ctx, name)
+}
+
+func (e *deployExecutor) ensurePlan(ctx context.Context) model.StageStatus {
+	name := provider.MakeChangeSetName(fmt.Sprintf("plan-%d", e.Stage.Index), e.Deployment.Id)
+	in, ok := makeChangeSetInput(&e.Input, e.ds, e.deployCfg.Input, name)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cs, err := createChangeSet(ctx, e.client, e.LogPersister, in)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create change set (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	summary := noChangesSummary
+	if !cs.HasNoChanges() {
+		summary = cs.Summary()
+	}
+	metadata := map[string]string{
+		changeSetNameMetadataKey: name,
+		planSummaryMetadataKey:   summary,
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to store the change set to metadata store", zap.Error(err))
+	}
+
+	if cs.HasNoChanges() {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	e.LogPersister.Successf("Detected %s. They will be applied by executing change set %s", summary, name)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensureApply(ctx context.Context) model.StageStatus {
+	planned, ok := e.plannedChangeSet()
+	if !ok {
+		e.LogPersister.Infof("No change set was created by %s stage, a new one will be created and executed", model.StageCloudFormationPlan)
+		name := provider.MakeChangeSetName(fmt.Sprintf("apply-%d", e.Stage.Index), e.Deployment.Id)
+		return e.createAndExecuteChangeSet(ctx, name)
+	}
+	if planned[planSummaryMetadataKey] == noChangesSummary {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	var (
+		stackName = e.deployCfg.Input.StackName
+		name      = planned[changeSetNameMetadataKey]
+	)
+	// Make sure that the change set reviewed at the CLOUDFORMATION_PLAN stage
+	// is still valid, e.g. the stack was not updated by others after approving it.
+	cs, err := e.client.GetChangeSet(ctx, stackName, name)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get change set %s (%v)", name, err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !cs.IsExecutable() {
+		e.LogPersister.Errorf("Change set %s is not executable (status: %s, execution status: %s)", name, cs.Status, cs.ExecutionStatus)
+		e.ReportError(executor.NewUserError("change set %s is no longer executable, the stack might have been changed since %s stage", name, model.StageCloudFormationPlan))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Infof("Applying %s", cs.Summary())
+	if err := executeChangeSet(ctx, &e.Input, e.client, stackName, name); err != nil {
+		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully applied changes")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) createAndExecuteChangeSet(ctx context.Context, name string) model.StageStatus {
+	in, ok := makeChangeSetInput(&e.Input, e.ds, e.deployCfg.Input, name)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cs, err := createChangeSet(ctx, e.client, e.LogPersister, in)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create change set (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if cs.HasNoChanges() {
+		e.LogPersister.Success("No changes to apply")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Detected %s. Those changes will be applied automatically.", cs.Summary())
+	if err := executeChangeSet(ctx, &e.Input, e.client, in.StackName, name); err != nil {
+		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully applied changes")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// plannedChangeSet returns the metadata of the change set created by
+// the last CLOUDFORMATION_PLAN stage executed before the current stage.
+func (e *deployExecutor) plannedChangeSet() (map[string]string, bool) {
+	return findPlannedChangeSet(e.Deployment.Stages, e.Stage.Index, e.MetadataStore)
+}
+
+// findPlannedChangeSet returns the metadata of the change set created by
+// the last CLOUDFORMATION_PLAN stage before the given index.
+func findPlannedChangeSet(stages []*model.PipelineStage, before int32, store executor.MetadataStore) (map[string]string, bool) {
+	var planned map[string]string
+	for _, s := range stages {
+		if s.Name != model.StageCloudFormationPlan.String() || s.Index >= before {
+			continue
+		}
+		metadata, ok := store.GetStageMetadata(s.Id)
+		if !ok {
+			continue
+		}
+		if _, ok := metadata[changeSetNameMetadataKey]; ok {
+			planned = metadata
+		}
+	}
+	return planned, planned != nil
+}
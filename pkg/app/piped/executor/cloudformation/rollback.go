@@ -0,0 +1,152 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudformation
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudformation"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type rollbackExecutor struct {
+	executor.Input
+}
+
+func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageRollback:
+		status = e.ensureRollback(ctx)
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for cloudformation application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+	targetDS, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	deployCfg := targetDS.DeploymentConfig.CloudFormationDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing CloudFormationDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CloudFormationDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	stackName := deployCfg.Input.StackName
+
+	client, ok := loadClient(&e.Input)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The change set created by CLOUDFORMATION_PLAN stage is no longer needed.
+	if planned, ok := findPlannedChangeSet(e.Deployment.Stages, e.Stage.Index, e.MetadataStore); ok {
+		if err := client.DeleteChangeSet(ctx, stackName, planned[changeSetNameMetadataKey]); err != nil {
+			e.LogPersister.Infof("Unable to delete the planned change set, it might have been executed or deleted (%v)", err)
+		}
+	}
+
+	if updated, _ := e.MetadataStore.Get(stackUpdatedMetadataKey); updated != "true" {
+		e.LogPersister.Success("No change set was executed by this deployment, nothing to roll back")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	stack, err := client.GetStack(ctx, stackName)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get stack %s (%v)", stackName, err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The update is still running since piped was stopped or the stage was cancelled while waiting for it.
+	if stack.Status == provider.StackStatusUpdateInProgress {
+		e.LogPersister.Infof("Cancelling the running update of stack %s", stackName)
+		if err := client.CancelUpdateStack(ctx, stackName); err != nil {
+			e.LogPersister.Errorf("Failed to cancel the update (%v)", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+	if stack.IsInProgress() {
+		if stack, err = waitStackOperation(ctx, client, e.LogPersister, stackName); err != nil {
+			e.LogPersister.Errorf("Failed while waiting for stack %s (%v)", stackName, err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+
+	// CloudFormation has already reverted the failed update by itself.
+	if stack.IsRolledBack() {
+		e.LogPersister.Successf("Stack %s has been rolled back by CloudFormation (%s)", stackName, stack.Status)
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	// Not rollback in case this is the first deployment.
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
+		e.ReportError(executor.NewUserError("stack %s was created by the first deployment and must be deleted or fixed manually", stackName))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	runningCfg := runningDS.DeploymentConfig.CloudFormationDeploymentSpec
+	if runningCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing CloudFormationDeploymentSpec")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Infof("Start rolling back stack %s to the state defined at commit %s", stackName, e.Deployment.RunningCommitHash)
+	name := provider.MakeChangeSetName("rollback", e.Deployment.Id)
+	in, ok := makeChangeSetInput(&e.Input, runningDS, runningCfg.Input, name)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	in.StackName = stackName
+
+	cs, err := createChangeSet(ctx, client, e.LogPersister, in)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create change set for rolling back (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if cs.HasNoChanges() {
+		e.LogPersister.Success("Stack is already in the state defined at the running commit")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	if err := executeChangeSet(ctx, &e.Input, client, stackName, name); err != nil {
+		e.LogPersister.Errorf("Failed to roll back stack %s (%v)", stackName, err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully rolled back stack %s", stackName)
+	return model.StageStatus_STAGE_SUCCESS
+}
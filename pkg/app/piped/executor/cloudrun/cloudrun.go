@@ -103,35 +103,40 @@ func configureServiceManifest(sm provider.ServiceManifest, revision string, traf
 
 	lp.Info("Successfully prepared service manifest with traffic percentages as below:")
 	for _, t := range traffics {
+		if t.Tag != "" {
+			lp.Infof("  %s: %d (tag: %s)", t.RevisionName, t.Percent, t.Tag)
+			continue
+		}
 		lp.Infof("  %s: %d", t.RevisionName, t.Percent)
 	}
 
 	return true
 }
 
-func apply(ctx context.Context, client provider.Client, sm provider.ServiceManifest, lp executor.LogPersister) bool {
+func apply(ctx context.Context, client provider.Client, sm provider.ServiceManifest, lp executor.LogPersister) (*provider.Service, bool) {
 	lp.Info("Start applying the service manifest")
 
-	_, err := client.Update(ctx, sm)
+	svc, err := client.Update(ctx, sm)
 	if err == nil {
 		lp.Infof("Successfully updated the service %s", sm.Name)
-		return true
+		return svc, true
 	}
 
 	if err != provider.ErrServiceNotFound {
 		lp.Errorf("Failed to update the service %s (%v)", sm.Name, err)
-		return false
+		return nil, false
 	}
 
 	lp.Infof("Service %s was not found, a new service will be created", sm.Name)
 
-	if _, err := client.Create(ctx, sm); err != nil {
+	svc, err = client.Create(ctx, sm)
+	if err != nil {
 		lp.Errorf("Failed to create the service %s (%v)", sm.Name, err)
-		return false
+		return nil, false
 	}
 
 	lp.Infof("Successfully created the service %s", sm.Name)
-	return true
+	return svc, true
 }
 
 func revisionExists(ctx context.Context, client provider.Client, revisionName string, lp executor.LogPersister) (bool, error) {
@@ -21,13 +21,17 @@ import (
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 
 	"go.uber.org/zap"
 )
 
-const promotePercentageMetadataKey = "promote-percentage"
+const (
+	promotePercentageMetadataKey = "promote-percentage"
+	previewURLMetadataKey        = "preview-url"
+)
 
 type deployExecutor struct {
 	executor.Input
@@ -49,6 +53,7 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	e.deployCfg = ds.DeploymentConfig.CloudRunDeploymentSpec
 	if e.deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing CloudRunDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CloudRunDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -104,7 +109,7 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	if !apply(ctx, e.client, sm, e.LogPersister) {
+	if _, ok := apply(ctx, e.client, sm, e.LogPersister); !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -115,6 +120,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 	options := e.StageConfig.CloudRunPromoteStageOptions
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
 	metadata := map[string]string{
@@ -163,17 +169,6 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	traffics := []provider.RevisionTraffic{
-		{
-			RevisionName: revision,
-			Percent:      options.Percent.Int(),
-		},
-		{
-			RevisionName: lastDeployedRevision,
-			Percent:      100 - options.Percent.Int(),
-		},
-	}
-
 	exist, err := revisionExists(ctx, e.client, revision, e.LogPersister)
 	if err != nil {
 		return model.StageStatus_STAGE_FAILURE
@@ -185,13 +180,29 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 		e.LogPersister.Infof("Revision %s was already registered", revision)
 	}
 
-	if !configureServiceManifest(sm, newRevision, traffics, e.LogPersister) {
-		return model.StageStatus_STAGE_FAILURE
+	router := &revisionRouter{
+		client:          e.client,
+		sm:              sm,
+		newRevision:     newRevision,
+		primaryRevision: lastDeployedRevision,
+		canaryRevision:  revision,
+		canaryTag:       e.deployCfg.Input.PreviewTag,
+		logPersister:    e.LogPersister,
 	}
-
-	if !apply(ctx, e.client, sm, e.LogPersister) {
+	weights := trafficrouting.Weights{
+		Primary: 100 - options.Percent.Int(),
+		Canary:  options.Percent.Int(),
+	}
+	if err := router.SetWeights(ctx, weights); err != nil {
+		e.LogPersister.Errorf("Failed to update traffic routing (%v)", err)
 		return model.StageStatus_STAGE_FAILURE
 	}
+	if router.previewURL != "" {
+		metadata[previewURLMetadataKey] = router.previewURL
+		if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+			e.Logger.Error("failed to save preview url to metadata", zap.Error(err))
+		}
+	}
 
 	// TODO: Wait to ensure the traffic was fully configured.
 	return model.StageStatus_STAGE_SUCCESS
@@ -74,6 +74,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	deployCfg := runningDS.DeploymentConfig.CloudRunDeploymentSpec
 	if deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing CloudRunDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CloudRunDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -97,7 +98,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	if !apply(ctx, e.client, sm, e.LogPersister) {
+	if _, ok := apply(ctx, e.client, sm, e.LogPersister); !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
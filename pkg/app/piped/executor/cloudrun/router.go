@@ -0,0 +1,107 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+)
+
+var errTrafficRoutingFailed = errors.New("failed to update traffic routing, see the logs for details")
+
+// revisionRouter implements trafficrouting.Router for Cloud Run services
+// by splitting traffic between the revisions of PRIMARY and CANARY variants.
+type revisionRouter struct {
+	client provider.Client
+	sm     provider.ServiceManifest
+	// The revision name will be set to the service manifest
+	// while applying. Empty means the revision was already registered.
+	newRevision     string
+	primaryRevision string
+	canaryRevision  string
+	// The tag given to the CANARY revision to preview it.
+	// Empty means no tag is given.
+	canaryTag    string
+	logPersister executor.LogPersister
+	// The URL to preview the CANARY revision, set after the weights were applied.
+	previewURL string
+}
+
+// SetWeights does not support BASELINE variant.
+func (r *revisionRouter) SetWeights(ctx context.Context, weights trafficrouting.Weights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	if weights.Baseline > 0 {
+		return fmt.Errorf("BASELINE variant: %w", trafficrouting.ErrUnsupported)
+	}
+
+	traffics := []provider.RevisionTraffic{
+		{
+			RevisionName: r.canaryRevision,
+			Percent:      weights.Canary,
+			Tag:          r.canaryTag,
+		},
+		{
+			RevisionName: r.primaryRevision,
+			Percent:      weights.Primary,
+		},
+	}
+	if !configureServiceManifest(r.sm, r.newRevision, traffics, r.logPersister) {
+		return errTrafficRoutingFailed
+	}
+	svc, ok := apply(ctx, r.client, r.sm, r.logPersister)
+	if !ok {
+		return errTrafficRoutingFailed
+	}
+	if url, ok := provider.PreviewURL(svc, r.canaryTag); ok {
+		r.previewURL = url
+		r.logPersister.Infof("The revision %s can be previewed at %s", r.canaryRevision, url)
+	}
+	return nil
+}
+
+// GetWeights returns the weights configured in the service manifest.
+// All traffic is considered to be routed to PRIMARY variant if nothing was configured.
+func (r *revisionRouter) GetWeights(_ context.Context) (trafficrouting.Weights, error) {
+	traffics, err := r.sm.Traffic()
+	if err != nil {
+		return trafficrouting.Weights{}, err
+	}
+	if len(traffics) == 0 {
+		return trafficrouting.Weights{Primary: 100}, nil
+	}
+
+	var weights trafficrouting.Weights
+	for _, t := range traffics {
+		switch t.RevisionName {
+		case r.primaryRevision:
+			weights.Primary += t.Percent
+		case r.canaryRevision:
+			weights.Canary += t.Percent
+		}
+	}
+	return weights, weights.Validate()
+}
+
+// RouteByHeader is not supported because Cloud Run routes traffic only by percentages and tags.
+func (r *revisionRouter) RouteByHeader(_ context.Context, _ trafficrouting.HeaderRoute) error {
+	return trafficrouting.ErrUnsupported
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudrun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/run/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+)
+
+type fakeClient struct {
+	provider.Client
+	applied []provider.ServiceManifest
+}
+
+func (c *fakeClient) Update(_ context.Context, sm provider.ServiceManifest) (*provider.Service, error) {
+	c.applied = append(c.applied, sm)
+	return &provider.Service{
+		Status: &run.ServiceStatus{
+			Url: "https://helloworld-abcdefg-an.a.run.app",
+		},
+	}, nil
+}
+
+func TestRevisionRouterSetWeights(t *testing.T) {
+	testcases := []struct {
+		name               string
+		canaryTag          string
+		expectedTraffic    []provider.RevisionTraffic
+		expectedPreviewURL string
+	}{
+		{
+			name:      "with preview tag",
+			canaryTag: "canary",
+			expectedTraffic: []provider.RevisionTraffic{
+				{RevisionName: "helloworld-v010-1234567", Percent: 20, Tag: "canary"},
+				{RevisionName: "helloworld-v009-abcdefg", Percent: 80},
+			},
+			expectedPreviewURL: "https://canary---helloworld-abcdefg-an.a.run.app",
+		},
+		{
+			name: "without preview tag",
+			expectedTraffic: []provider.RevisionTraffic{
+				{RevisionName: "helloworld-v010-1234567", Percent: 20},
+				{RevisionName: "helloworld-v009-abcdefg", Percent: 80},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm, err := provider.ParseServiceManifest([]byte(`
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/pipecd/helloworld:v0.1.0
+`))
+			require.NoError(t, err)
+
+			client := &fakeClient{}
+			r := &revisionRouter{
+				client:          client,
+				sm:              sm,
+				newRevision:     "helloworld-v010-1234567",
+				primaryRevision: "helloworld-v009-abcdefg",
+				canaryRevision:  "helloworld-v010-1234567",
+				canaryTag:       tc.canaryTag,
+				logPersister:    &executortest.FakeLogPersister{},
+			}
+			err = r.SetWeights(context.Background(), trafficrouting.Weights{Primary: 80, Canary: 20})
+			require.NoError(t, err)
+			require.Len(t, client.applied, 1)
+
+			traffic, err := client.applied[0].Traffic()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedTraffic, traffic)
+			assert.Equal(t, tc.expectedPreviewURL, r.previewURL)
+		})
+	}
+}
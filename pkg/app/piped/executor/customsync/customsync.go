@@ -0,0 +1,226 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customsync
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The values of PIPECD_SYNC_ACTION telling the command what to do.
+const (
+	syncActionSync     = "sync"
+	syncActionRollback = "rollback"
+)
+
+// passEnvs are the environment variables of piped passed to the command.
+// The others are not passed to not leak the credentials of piped.
+var passEnvs = []string{"PATH", "HOME"}
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageCustomSync, f)
+
+	r.RegisterRollback(model.ApplicationKind_CUSTOM_SYNC, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderCustomSyncConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderCustomSync)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.CustomSyncConfig
+	found = true
+	return
+}
+
+// resolveCommand returns the path to the command of the given cloud provider
+// by installing it into the tool registry if needed.
+func resolveCommand(ctx context.Context, lp executor.LogPersister, cfg *config.CloudProviderCustomSyncConfig) (string, bool) {
+	path, installed, err := toolregistry.DefaultRegistry().Custom(ctx, cfg.Command, cfg.Version, cfg.DownloadURL)
+	if installed {
+		lp.Infof("%s %s has just been installed because of no pre-installed binary for that version", cfg.Command, cfg.Version)
+	}
+	if err != nil {
+		lp.Errorf("Unable to find the command %s %s (%v)", cfg.Command, cfg.Version, err)
+		return "", false
+	}
+	return path, true
+}
+
+// command is an invocation of the custom sync command for a deploy source.
+type command struct {
+	path   string
+	args   []string
+	action string
+	ds     *deploysource.DeploySource
+	input  config.CustomSyncDeploymentInput
+	cpEnv  map[string]string
+}
+
+// run runs the command and reports whether it was completed successfully.
+// Its stdout and stderr are streamed to the stage logs line by line.
+func (c *command) run(ctx context.Context, in *executor.Input) bool {
+	if c.input.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.input.Timeout.Duration())
+		defer cancel()
+	}
+
+	in.LogPersister.Infof("Running %s with action %s at commit %s", c.path, c.action, c.ds.Revision)
+	code, err := runCommand(ctx, c.path, c.args, c.ds.AppDir, c.env(in), in.LogPersister)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to run the custom sync command (%v)", err)
+		in.ReportError(fmt.Errorf("failed to run the custom sync command: %w", err))
+		return false
+	}
+	if code != 0 {
+		in.LogPersister.Errorf("The custom sync command exited with code %d", code)
+		in.ReportError(fmt.Errorf("the custom sync command exited with code %d", code))
+		return false
+	}
+	return true
+}
+
+// env returns the environment variables of the command.
+// The ones of the application take precedence over the ones of the cloud provider,
+// and the deployment context is exposed with the PIPECD_ prefix at last.
+func (c *command) env(in *executor.Input) []string {
+	env := make([]string, 0, len(passEnvs)+len(c.cpEnv)+len(c.input.Env)+9)
+	for _, name := range passEnvs {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	env = appendSorted(env, c.cpEnv)
+	env = appendSorted(env, c.input.Env)
+
+	return append(env,
+		"PIPECD_SYNC_ACTION="+c.action,
+		"PIPECD_DEPLOYMENT_ID="+in.Deployment.Id,
+		"PIPECD_APPLICATION_ID="+in.Deployment.ApplicationId,
+		"PIPECD_APPLICATION_NAME="+in.Deployment.ApplicationName,
+		"PIPECD_ENV_NAME="+in.EnvName,
+		"PIPECD_COMMIT_HASH="+c.ds.Revision,
+		"PIPECD_RUNNING_COMMIT_HASH="+in.Deployment.RunningCommitHash,
+		"PIPECD_STAGE_ID="+in.Stage.Id,
+		"PIPECD_APP_DIR="+c.ds.AppDir,
+	)
+}
+
+func appendSorted(env []string, vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
+}
+
+type lineLogger interface {
+	Info(log string)
+	Error(log string)
+}
+
+// runCommand runs the given command and streams its stdout and stderr
+// to the logger line by line. The exit code is returned if the command was run.
+func runCommand(ctx context.Context, path string, args []string, dir string, env []string, logger lineLogger) (int, error) {
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamLines(stdout, logger.Info)
+	}()
+	go func() {
+		defer wg.Done()
+		streamLines(stderr, logger.Error)
+	}()
+	// All output must be read before calling Wait since it closes the pipes.
+	wg.Wait()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+func streamLines(r io.Reader, log func(string)) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		log(s.Text())
+	}
+	// Drain the rest to not block the command when a line was too long.
+	io.Copy(ioutil.Discard, r)
+}
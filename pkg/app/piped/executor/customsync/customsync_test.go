@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customsync
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeLineLogger struct {
+	mu     sync.Mutex
+	infos  []string
+	errors []string
+}
+
+func (l *fakeLineLogger) Info(log string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, log)
+}
+
+func (l *fakeLineLogger) Error(log string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, log)
+}
+
+func TestRunCommand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deployer")
+	script := "#!/bin/sh\necho \"$PIPECD_SYNC_ACTION $1\"\necho warning >&2\nexit \"$2\"\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0755))
+
+	testcases := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+	}{
+		{
+			name:         "succeeded",
+			args:         []string{"app.yaml", "0"},
+			wantExitCode: 0,
+		},
+		{
+			name:         "failed",
+			args:         []string{"app.yaml", "1"},
+			wantExitCode: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &fakeLineLogger{}
+			code, err := runCommand(context.Background(), path, tc.args, dir, []string{"PIPECD_SYNC_ACTION=sync"}, logger)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantExitCode, code)
+			assert.Equal(t, []string{"sync app.yaml"}, logger.infos)
+			assert.Equal(t, []string{"warning"}, logger.errors)
+		})
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	os.Setenv("CUSTOM_SYNC_TEST_SECRET", "secret")
+	defer os.Unsetenv("CUSTOM_SYNC_TEST_SECRET")
+
+	in := &executor.Input{
+		Stage: &model.PipelineStage{Id: "stage-id"},
+		Deployment: &model.Deployment{
+			Id:                "deployment-id",
+			ApplicationId:     "app-id",
+			ApplicationName:   "app",
+			RunningCommitHash: "running-hash",
+		},
+		EnvName: "dev",
+	}
+	c := &command{
+		action: syncActionRollback,
+		ds: &deploysource.DeploySource{
+			AppDir:   "/app",
+			Revision: "target-hash",
+		},
+		input: config.CustomSyncDeploymentInput{
+			Env: map[string]string{"TARGET": "app", "REGION": "asia"},
+		},
+		cpEnv: map[string]string{"TARGET": "provider", "TOKEN_FILE": "/etc/token"},
+	}
+	got := c.env(in)
+
+	assert.NotContains(t, got, "CUSTOM_SYNC_TEST_SECRET=secret")
+	assert.Equal(t, []string{
+		"TARGET=provider",
+		"TOKEN_FILE=/etc/token",
+		"REGION=asia",
+		"TARGET=app",
+		"PIPECD_SYNC_ACTION=rollback",
+		"PIPECD_DEPLOYMENT_ID=deployment-id",
+		"PIPECD_APPLICATION_ID=app-id",
+		"PIPECD_APPLICATION_NAME=app",
+		"PIPECD_ENV_NAME=dev",
+		"PIPECD_COMMIT_HASH=target-hash",
+		"PIPECD_RUNNING_COMMIT_HASH=running-hash",
+		"PIPECD_STAGE_ID=stage-id",
+		"PIPECD_APP_DIR=/app",
+	}, got[len(got)-13:])
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customsync
+
+import (
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+	)
+
+	_, cpCfg, found := findCloudProvider(&e.Input)
+	if !found {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The command may write files into the application directory, so it is run in a copy.
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.CustomSyncDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing CustomSyncDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CustomSyncDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// The predefined stage of quick sync has no options, so the ones for quick sync are used.
+	opts := e.StageConfig.CustomSyncStageOptions
+	if opts == nil {
+		opts = &deployCfg.QuickSync
+	}
+	args := deployCfg.Input.Args
+	if len(opts.Args) > 0 {
+		args = opts.Args
+	}
+
+	path, ok := resolveCommand(ctx, e.LogPersister, cpCfg)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cmd := &command{
+		path:   path,
+		args:   args,
+		action: syncActionSync,
+		ds:     ds,
+		input:  deployCfg.Input,
+		cpEnv:  cpCfg.Env,
+	}
+	status := model.StageStatus_STAGE_FAILURE
+	if cmd.run(ctx, &e.Input) {
+		e.LogPersister.Successf("Successfully synced the application at commit %s", ds.Revision)
+		status = model.StageStatus_STAGE_SUCCESS
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
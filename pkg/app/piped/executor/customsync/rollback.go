@@ -0,0 +1,94 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package customsync
+
+import (
+	"context"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type rollbackExecutor struct {
+	executor.Input
+}
+
+func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	_, cpCfg, found := findCloudProvider(&e.Input)
+	if !found {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageRollback:
+		status = e.ensureRollback(ctx, cpCfg)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for custom sync application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+// ensureRollback runs the command at the last deployed commit
+// to let it bring the application back to that state.
+func (e *rollbackExecutor) ensureRollback(ctx context.Context, cpCfg *config.CloudProviderCustomSyncConfig) model.StageStatus {
+	// There is nothing to do if this is the first deployment.
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	runningDS, err := e.RunningDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := runningDS.DeploymentConfig.CustomSyncDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing CustomSyncDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing CustomSyncDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	path, ok := resolveCommand(ctx, e.LogPersister, cpCfg)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	cmd := &command{
+		path:   path,
+		args:   deployCfg.Input.Args,
+		action: syncActionRollback,
+		ds:     runningDS,
+		input:  deployCfg.Input,
+		cpEnv:  cpCfg.Env,
+	}
+	if !cmd.run(ctx, &e.Input) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully rolled back the application to commit %s", e.Deployment.RunningCommitHash)
+	return model.StageStatus_STAGE_SUCCESS
+}
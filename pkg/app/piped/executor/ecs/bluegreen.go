@@ -0,0 +1,291 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider"
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ecs"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	codeDeployDeploymentIDKeyName = "codedeploy-deployment-id"
+)
+
+// How often to check the state of the new task set or the CodeDeploy deployment.
+var blueGreenPollInterval = 10 * time.Second
+
+// blueGreenSync deploys the given task definition by the configured blue/green controller.
+func blueGreenSync(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderECSConfig, bg *config.ECSBlueGreen, taskDefinition types.TaskDefinition, serviceDefinition types.Service, primary, canary *types.LoadBalancer) bool {
+	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create ECS client for the provider %s: %v", cloudProviderName, err)
+		return false
+	}
+
+	in.LogPersister.Infof("Start applying the ECS task definition")
+	td, err := applyTaskDefinition(ctx, client, taskDefinition)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to register ECS task definition of family %s: %v", *taskDefinition.Family, err)
+		return false
+	}
+
+	if bg.Controller == config.ECSBlueGreenControllerCodeDeploy {
+		store := func(id string) error {
+			return in.MetadataStore.Set(ctx, codeDeployDeploymentIDKeyName, id)
+		}
+		if err := deployByCodeDeploy(ctx, client, in.LogPersister, bg, *td, *primary, store); err != nil {
+			in.LogPersister.Errorf("Failed to deploy ECS service %s by CodeDeploy: %v", *serviceDefinition.ServiceName, err)
+			return false
+		}
+		in.LogPersister.Infof("Successfully deployed the task definition of family %s to ECS service %s by CodeDeploy", *taskDefinition.Family, *serviceDefinition.ServiceName)
+		return true
+	}
+
+	in.LogPersister.Infof("Start applying the ECS service definition")
+	service, err := applyServiceDefinition(ctx, client, serviceDefinition)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to apply service %s: %v", *serviceDefinition.ServiceName, err)
+		return false
+	}
+
+	if canary == nil {
+		in.LogPersister.Error("Canary target group is required to switch the traffic in blue/green deployment")
+		return false
+	}
+	if err := switchTargetGroups(ctx, client, in.LogPersister, bg.Timeout.Duration(), *service, *td, *primary, *canary); err != nil {
+		in.LogPersister.Errorf("Failed to deploy ECS service %s by switching target groups: %v", *serviceDefinition.ServiceName, err)
+		return false
+	}
+	in.LogPersister.Infof("Successfully applied the service definition and the task definition for ECS service %s and task definition of family %s", *serviceDefinition.ServiceName, *taskDefinition.Family)
+	return true
+}
+
+// switchTargetGroups starts a task set of the given task definition in the target group not receiving the traffic,
+// and once it became stable, switches all traffic to that target group and makes the task set PRIMARY.
+func switchTargetGroups(ctx context.Context, client provider.Client, lp executor.LogPersister, timeout time.Duration, service types.Service, td types.TaskDefinition, primary, canary types.LoadBalancer) error {
+	listenerArn, err := client.GetListener(ctx, primary)
+	if err != nil {
+		return fmt.Errorf("failed to get listener of target group %s: %w", *primary.TargetGroupArn, err)
+	}
+	weights, err := client.GetListenerWeights(ctx, listenerArn)
+	if err != nil {
+		return fmt.Errorf("failed to get weights of listener %s: %w", listenerArn, err)
+	}
+	live, idle, err := decideBlueGreenTargetGroups(weights, primary, canary)
+	if err != nil {
+		return err
+	}
+	lp.Infof("Target group %s is receiving the traffic, the new task set will be started in target group %s", *live.TargetGroupArn, *idle.TargetGroupArn)
+
+	prevPrimaryTaskSet, err := client.GetPrimaryTaskSet(ctx, service)
+	// Ignore error in case it's not found error, the prevPrimaryTaskSet doesn't exist for newly created Service.
+	if err != nil && !errors.Is(err, cloudprovider.ErrNotFound) {
+		return err
+	}
+
+	taskSet, err := client.CreateTaskSet(ctx, service, td, idle, 100)
+	if err != nil {
+		return err
+	}
+	lp.Infof("Waiting for the task set %s to become stable", *taskSet.TaskSetArn)
+	if err := waitTaskSetStable(ctx, client, service, *taskSet.TaskSetArn, timeout); err != nil {
+		// The traffic has not been switched yet, so just remove the new task set.
+		if derr := client.DeleteTaskSet(ctx, service, *taskSet.TaskSetArn); derr != nil {
+			lp.Errorf("Failed to remove the unstable task set %s: %v", *taskSet.TaskSetArn, derr)
+		}
+		return err
+	}
+
+	routingTrafficCfg := provider.RoutingTrafficConfig{
+		{
+			TargetGroupArn: *idle.TargetGroupArn,
+			Weight:         100,
+		},
+		{
+			TargetGroupArn: *live.TargetGroupArn,
+			Weight:         0,
+		},
+	}
+	if err := client.ModifyListener(ctx, listenerArn, routingTrafficCfg); err != nil {
+		return fmt.Errorf("failed to switch the traffic to target group %s: %w", *idle.TargetGroupArn, err)
+	}
+	lp.Infof("Switched all traffic to target group %s", *idle.TargetGroupArn)
+
+	if _, err = client.UpdateServicePrimaryTaskSet(ctx, service, *taskSet); err != nil {
+		return err
+	}
+	if prevPrimaryTaskSet != nil {
+		if err = client.DeleteTaskSet(ctx, service, *prevPrimaryTaskSet.TaskSetArn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decideBlueGreenTargetGroups returns the target group receiving the traffic and the other one.
+func decideBlueGreenTargetGroups(weights provider.RoutingTrafficConfig, primary, canary types.LoadBalancer) (live, idle types.LoadBalancer, err error) {
+	var (
+		primaryWeight = weights.Weight(aws.ToString(primary.TargetGroupArn))
+		canaryWeight  = weights.Weight(aws.ToString(canary.TargetGroupArn))
+	)
+	switch {
+	case primaryWeight == 0 && canaryWeight == 0:
+		err = fmt.Errorf("neither target group %s nor %s is receiving the traffic", aws.ToString(primary.TargetGroupArn), aws.ToString(canary.TargetGroupArn))
+	case primaryWeight == canaryWeight:
+		err = fmt.Errorf("unable to decide the live target group since both target groups are receiving the same traffic")
+	case primaryWeight > canaryWeight:
+		live, idle = primary, canary
+	default:
+		live, idle = canary, primary
+	}
+	return
+}
+
+// waitTaskSetStable blocks until the given task set reaches the steady state.
+func waitTaskSetStable(ctx context.Context, client provider.Client, service types.Service, taskSetArn string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(blueGreenPollInterval)
+	defer ticker.Stop()
+
+	for {
+		taskSet, err := client.GetTaskSet(ctx, service, taskSetArn)
+		if err != nil {
+			return err
+		}
+		if taskSet.StabilityStatus == types.StabilityStatusSteadyState {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("task set %s did not become stable: %w", taskSetArn, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// deployByCodeDeploy creates a CodeDeploy deployment replacing the task definition of the service
+// and waits for it to complete. The deployment is stopped and rolled back when it did not complete in time.
+func deployByCodeDeploy(ctx context.Context, client provider.Client, lp executor.LogPersister, bg *config.ECSBlueGreen, td types.TaskDefinition, primary types.LoadBalancer, storeID func(string) error) error {
+	appSpec, err := provider.MakeCodeDeployAppSpec(aws.ToString(td.TaskDefinitionArn), aws.ToString(primary.ContainerName), aws.ToInt32(primary.ContainerPort))
+	if err != nil {
+		return err
+	}
+
+	cd := bg.CodeDeploy
+	id, err := client.CreateDeployment(ctx, cd.ApplicationName, cd.DeploymentGroupName, cd.DeploymentConfigName, appSpec)
+	if err != nil {
+		return err
+	}
+	lp.Infof("Created CodeDeploy deployment %s", id)
+	// The deployment id is used to stop the deployment while rolling back.
+	if err := storeID(id); err != nil {
+		lp.Errorf("Unable to store CodeDeploy deployment id to metadata store: %v", err)
+	}
+
+	d, err := waitCodeDeployDeployment(ctx, client, lp, id, bg.Timeout.Duration())
+	if err != nil {
+		if serr := client.StopDeployment(context.Background(), id, true); serr != nil {
+			lp.Errorf("Failed to stop CodeDeploy deployment %s: %v", id, serr)
+		}
+		return err
+	}
+	if d.Status != provider.CodeDeployStatusSucceeded {
+		return fmt.Errorf("CodeDeploy deployment %s was %s: %s", id, d.Status, d.ErrorMessage)
+	}
+	return nil
+}
+
+// waitCodeDeployDeployment blocks until the given CodeDeploy deployment has been completed.
+func waitCodeDeployDeployment(ctx context.Context, client provider.Client, lp executor.LogPersister, id string, timeout time.Duration) (*provider.CodeDeployDeployment, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(blueGreenPollInterval)
+	defer ticker.Stop()
+
+	var status string
+	for {
+		d, err := client.GetDeployment(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if provider.IsCodeDeployDeploymentCompleted(d.Status) {
+			return d, nil
+		}
+		if d.Status != status {
+			lp.Infof("CodeDeploy deployment %s is %s", id, d.Status)
+			status = d.Status
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("CodeDeploy deployment %s did not complete: %w", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// blueGreenRollback reverts the changes made by blue/green deployment.
+// An uncompleted CodeDeploy deployment is stopped to let CodeDeploy roll it back,
+// otherwise the running task definition is deployed again in the same way.
+func blueGreenRollback(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderECSConfig, bg *config.ECSBlueGreen, taskDefinition types.TaskDefinition, serviceDefinition types.Service, primary, canary *types.LoadBalancer) bool {
+	if bg.Controller != config.ECSBlueGreenControllerCodeDeploy {
+		return blueGreenSync(ctx, in, cloudProviderName, cloudProviderCfg, bg, taskDefinition, serviceDefinition, primary, canary)
+	}
+
+	id, ok := in.MetadataStore.Get(codeDeployDeploymentIDKeyName)
+	if !ok {
+		return blueGreenSync(ctx, in, cloudProviderName, cloudProviderCfg, bg, taskDefinition, serviceDefinition, primary, canary)
+	}
+
+	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create ECS client for the provider %s: %v", cloudProviderName, err)
+		return false
+	}
+	d, err := client.GetDeployment(ctx, id)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to get CodeDeploy deployment %s: %v", id, err)
+		return false
+	}
+	if provider.IsCodeDeployDeploymentCompleted(d.Status) {
+		return blueGreenSync(ctx, in, cloudProviderName, cloudProviderCfg, bg, taskDefinition, serviceDefinition, primary, canary)
+	}
+
+	in.LogPersister.Infof("Stopping CodeDeploy deployment %s to roll it back", id)
+	if err := client.StopDeployment(ctx, id, true); err != nil {
+		in.LogPersister.Errorf("Failed to stop CodeDeploy deployment %s: %v", id, err)
+		return false
+	}
+	if _, err := waitCodeDeployDeployment(ctx, client, in.LogPersister, id, bg.Timeout.Duration()); err != nil {
+		in.LogPersister.Errorf("Failed to roll back CodeDeploy deployment %s: %v", id, err)
+		return false
+	}
+	in.LogPersister.Infof("Rolled back CodeDeploy deployment %s", id)
+	return true
+}
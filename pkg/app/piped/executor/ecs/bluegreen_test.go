@@ -0,0 +1,274 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider"
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ecs"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type fakeClient struct {
+	provider.Client
+
+	weights         provider.RoutingTrafficConfig
+	primary         *types.TaskSet
+	stable          bool
+	deployments     []string
+	createdTaskSet  string
+	deletedTaskSets []string
+	switched        provider.RoutingTrafficConfig
+	primaryUpdated  string
+	stopped         []string
+}
+
+func (c *fakeClient) GetListener(_ context.Context, _ types.LoadBalancer) (string, error) {
+	return "listener", nil
+}
+
+func (c *fakeClient) GetListenerWeights(_ context.Context, _ string) (provider.RoutingTrafficConfig, error) {
+	return c.weights, nil
+}
+
+func (c *fakeClient) ModifyListener(_ context.Context, _ string, cfg provider.RoutingTrafficConfig) error {
+	c.switched = cfg
+	return nil
+}
+
+func (c *fakeClient) GetPrimaryTaskSet(_ context.Context, _ types.Service) (*types.TaskSet, error) {
+	if c.primary == nil {
+		return nil, cloudprovider.ErrNotFound
+	}
+	return c.primary, nil
+}
+
+func (c *fakeClient) CreateTaskSet(_ context.Context, _ types.Service, _ types.TaskDefinition, targetGroup types.LoadBalancer, _ int) (*types.TaskSet, error) {
+	c.createdTaskSet = "taskset-" + *targetGroup.TargetGroupArn
+	return &types.TaskSet{TaskSetArn: aws.String(c.createdTaskSet)}, nil
+}
+
+func (c *fakeClient) GetTaskSet(_ context.Context, _ types.Service, taskSetArn string) (*types.TaskSet, error) {
+	ts := &types.TaskSet{TaskSetArn: aws.String(taskSetArn), StabilityStatus: types.StabilityStatusStabilizing}
+	if c.stable {
+		ts.StabilityStatus = types.StabilityStatusSteadyState
+	}
+	return ts, nil
+}
+
+func (c *fakeClient) DeleteTaskSet(_ context.Context, _ types.Service, taskSetArn string) error {
+	c.deletedTaskSets = append(c.deletedTaskSets, taskSetArn)
+	return nil
+}
+
+func (c *fakeClient) UpdateServicePrimaryTaskSet(_ context.Context, _ types.Service, taskSet types.TaskSet) (*types.TaskSet, error) {
+	c.primaryUpdated = *taskSet.TaskSetArn
+	return &taskSet, nil
+}
+
+func (c *fakeClient) CreateDeployment(_ context.Context, _, _, _, _ string) (string, error) {
+	return "d-123", nil
+}
+
+// GetDeployment returns the statuses in the given order, the last one is kept returning.
+func (c *fakeClient) GetDeployment(_ context.Context, id string) (*provider.CodeDeployDeployment, error) {
+	status := c.deployments[0]
+	if len(c.deployments) > 1 {
+		c.deployments = c.deployments[1:]
+	}
+	return &provider.CodeDeployDeployment{ID: id, Status: status}, nil
+}
+
+func (c *fakeClient) StopDeployment(_ context.Context, id string, _ bool) error {
+	c.stopped = append(c.stopped, id)
+	return nil
+}
+
+func makeTargetGroup(arn string) types.LoadBalancer {
+	return types.LoadBalancer{
+		TargetGroupArn: aws.String(arn),
+		ContainerName:  aws.String("web"),
+		ContainerPort:  aws.Int32(80),
+	}
+}
+
+func TestDecideBlueGreenTargetGroups(t *testing.T) {
+	blue, green := makeTargetGroup("blue"), makeTargetGroup("green")
+	testcases := []struct {
+		name         string
+		weights      provider.RoutingTrafficConfig
+		expectedLive string
+		expectedErr  bool
+	}{
+		{
+			name:         "primary is live",
+			weights:      provider.RoutingTrafficConfig{{TargetGroupArn: "blue", Weight: 100}},
+			expectedLive: "blue",
+		},
+		{
+			name: "canary is live",
+			weights: provider.RoutingTrafficConfig{
+				{TargetGroupArn: "blue", Weight: 0},
+				{TargetGroupArn: "green", Weight: 100},
+			},
+			expectedLive: "green",
+		},
+		{
+			name:        "no traffic",
+			weights:     provider.RoutingTrafficConfig{{TargetGroupArn: "other", Weight: 100}},
+			expectedErr: true,
+		},
+		{
+			name: "same traffic",
+			weights: provider.RoutingTrafficConfig{
+				{TargetGroupArn: "blue", Weight: 50},
+				{TargetGroupArn: "green", Weight: 50},
+			},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			live, idle, err := decideBlueGreenTargetGroups(tc.weights, blue, green)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedLive, *live.TargetGroupArn)
+			assert.NotEqual(t, *live.TargetGroupArn, *idle.TargetGroupArn)
+		})
+	}
+}
+
+func TestSwitchTargetGroups(t *testing.T) {
+	blueGreenPollInterval = time.Millisecond
+	var (
+		ctx     = context.Background()
+		lp      = &executortest.FakeLogPersister{}
+		blue    = makeTargetGroup("blue")
+		green   = makeTargetGroup("green")
+		weights = provider.RoutingTrafficConfig{
+			{TargetGroupArn: "blue", Weight: 100},
+			{TargetGroupArn: "green", Weight: 0},
+		}
+	)
+
+	t.Run("switch to idle target group", func(t *testing.T) {
+		client := &fakeClient{
+			weights: weights,
+			primary: &types.TaskSet{TaskSetArn: aws.String("taskset-blue")},
+			stable:  true,
+		}
+		err := switchTargetGroups(ctx, client, lp, time.Second, types.Service{}, types.TaskDefinition{}, blue, green)
+		require.NoError(t, err)
+		assert.Equal(t, "taskset-green", client.createdTaskSet)
+		assert.Equal(t, provider.RoutingTrafficConfig{
+			{TargetGroupArn: "green", Weight: 100},
+			{TargetGroupArn: "blue", Weight: 0},
+		}, client.switched)
+		assert.Equal(t, "taskset-green", client.primaryUpdated)
+		assert.Equal(t, []string{"taskset-blue"}, client.deletedTaskSets)
+	})
+
+	t.Run("new task set does not become stable", func(t *testing.T) {
+		client := &fakeClient{
+			weights: weights,
+			primary: &types.TaskSet{TaskSetArn: aws.String("taskset-blue")},
+		}
+		err := switchTargetGroups(ctx, client, lp, 10*time.Millisecond, types.Service{}, types.TaskDefinition{}, blue, green)
+		require.Error(t, err)
+		assert.Nil(t, client.switched)
+		assert.Empty(t, client.primaryUpdated)
+		assert.Equal(t, []string{"taskset-green"}, client.deletedTaskSets)
+	})
+}
+
+func TestDeployByCodeDeploy(t *testing.T) {
+	blueGreenPollInterval = time.Millisecond
+	var (
+		ctx = context.Background()
+		lp  = &executortest.FakeLogPersister{}
+		td  = types.TaskDefinition{TaskDefinitionArn: aws.String("arn:aws:ecs:task-definition/simple:2")}
+		bg  = &config.ECSBlueGreen{
+			Controller: config.ECSBlueGreenControllerCodeDeploy,
+			Timeout:    config.Duration(time.Second),
+			CodeDeploy: &config.ECSCodeDeploy{
+				ApplicationName:     "simple",
+				DeploymentGroupName: "simple-group",
+			},
+		}
+	)
+
+	testcases := []struct {
+		name            string
+		deployments     []string
+		timeout         time.Duration
+		expectedStopped []string
+		expectedErr     bool
+	}{
+		{
+			name:        "succeeded",
+			deployments: []string{provider.CodeDeployStatusInProgress, provider.CodeDeployStatusSucceeded},
+		},
+		{
+			name:        "failed",
+			deployments: []string{provider.CodeDeployStatusInProgress, provider.CodeDeployStatusFailed},
+			expectedErr: true,
+		},
+		{
+			name:            "timed out",
+			deployments:     []string{provider.CodeDeployStatusInProgress},
+			timeout:         10 * time.Millisecond,
+			expectedStopped: []string{"d-123"},
+			expectedErr:     true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := *bg
+			if tc.timeout > 0 {
+				cfg.Timeout = config.Duration(tc.timeout)
+			}
+			client := &fakeClient{deployments: tc.deployments}
+			var stored string
+			err := deployByCodeDeploy(ctx, client, lp, &cfg, td, makeTargetGroup("blue"), func(id string) error {
+				stored = id
+				return nil
+			})
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Equal(t, "d-123", stored)
+			assert.Equal(t, tc.expectedStopped, client.stopped)
+		})
+	}
+
+	t.Run("missing container", func(t *testing.T) {
+		client := &fakeClient{}
+		err := deployByCodeDeploy(ctx, client, lp, bg, td, types.LoadBalancer{TargetGroupArn: aws.String("blue")}, func(string) error {
+			return errors.New("should not be called")
+		})
+		assert.Error(t, err)
+	})
+}
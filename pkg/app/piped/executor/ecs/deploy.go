@@ -44,6 +44,7 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	e.deployCfg = ds.DeploymentConfig.ECSDeploymentSpec
 	if e.deployCfg == nil {
 		e.LogPersister.Errorf("Malformed deployment configuration: missing ECSDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing ECSDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -87,11 +88,18 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	primary, _, ok := loadTargetGroups(&e.Input, e.deployCfg, e.deploySource)
+	primary, canary, ok := loadTargetGroups(&e.Input, e.deployCfg, e.deploySource)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if bg := e.deployCfg.Input.BlueGreen; bg != nil {
+		if !blueGreenSync(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, bg, taskDefinition, servicedefinition, primary, canary) {
+			return model.StageStatus_STAGE_FAILURE
+		}
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
 	if !sync(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, taskDefinition, servicedefinition, *primary) {
 		return model.StageStatus_STAGE_FAILURE
 	}
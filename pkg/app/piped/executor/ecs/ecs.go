@@ -27,6 +27,7 @@ import (
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ecs"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 
@@ -249,6 +250,7 @@ func rollout(ctx context.Context, in *executor.Input, cloudProviderName string,
 		options := in.StageConfig.ECSCanaryRolloutStageOptions
 		if options == nil {
 			in.LogPersister.Errorf("Malformed configuration for stage %s", in.Stage.Name)
+			in.ReportError(executor.NewUserError("malformed configuration for stage %s", in.Stage.Name))
 			return false
 		}
 
@@ -327,19 +329,10 @@ func routing(ctx context.Context, in *executor.Input, cloudProviderName string,
 	options := in.StageConfig.ECSTrafficRoutingStageOptions
 	if options == nil {
 		in.LogPersister.Errorf("Malformed configuration for stage %s", in.Stage.Name)
+		in.ReportError(executor.NewUserError("malformed configuration for stage %s", in.Stage.Name))
 		return false
 	}
 	primary, canary := options.Percentage()
-	routingTrafficCfg := provider.RoutingTrafficConfig{
-		{
-			TargetGroupArn: *primaryTargetGroup.TargetGroupArn,
-			Weight:         primary,
-		},
-		{
-			TargetGroupArn: *canaryTargetGroup.TargetGroupArn,
-			Weight:         canary,
-		},
-	}
 
 	metadata := map[string]string{
 		trafficRoutePrimaryMetadataKey: strconv.FormatInt(int64(primary), 10),
@@ -349,13 +342,16 @@ func routing(ctx context.Context, in *executor.Input, cloudProviderName string,
 		in.Logger.Error("Failed to store traffic routing config to metadata store", zap.Error(err))
 	}
 
-	currListenerArn, err := client.GetListener(ctx, primaryTargetGroup)
-	if err != nil {
-		in.LogPersister.Errorf("Failed to get current active listener: %v", err)
-		return false
+	router := &listenerRouter{
+		client:             client,
+		primaryTargetGroup: primaryTargetGroup,
+		canaryTargetGroup:  canaryTargetGroup,
 	}
-
-	if err := client.ModifyListener(ctx, currListenerArn, routingTrafficCfg); err != nil {
+	weights := trafficrouting.Weights{
+		Primary: primary,
+		Canary:  canary,
+	}
+	if err := router.SetWeights(ctx, weights); err != nil {
 		in.LogPersister.Errorf("Failed to routing traffic to CANARY variant: %v", err)
 		return false
 	}
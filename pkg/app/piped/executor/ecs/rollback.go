@@ -65,6 +65,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	deployCfg := runningDS.DeploymentConfig.ECSDeploymentSpec
 	if deployCfg == nil {
 		e.LogPersister.Errorf("Malformed deployment configuration: missing ECSDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing ECSDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -82,11 +83,18 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	primary, _, ok := loadTargetGroups(&e.Input, deployCfg, runningDS)
+	primary, canary, ok := loadTargetGroups(&e.Input, deployCfg, runningDS)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if bg := deployCfg.Input.BlueGreen; bg != nil {
+		if !blueGreenRollback(ctx, &e.Input, cloudProviderName, cloudProviderCfg, bg, taskDefinition, serviceDefinition, primary, canary) {
+			return model.StageStatus_STAGE_FAILURE
+		}
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
 	if !rollback(ctx, &e.Input, cloudProviderName, cloudProviderCfg, taskDefinition, serviceDefinition, *primary) {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -0,0 +1,84 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/ecs"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+)
+
+// listenerRouter implements trafficrouting.Router for ECS services
+// by modifying the weights of the target groups in the listener of PRIMARY target group.
+type listenerRouter struct {
+	client             provider.Client
+	primaryTargetGroup types.LoadBalancer
+	canaryTargetGroup  types.LoadBalancer
+}
+
+// SetWeights does not support BASELINE variant.
+func (r *listenerRouter) SetWeights(ctx context.Context, weights trafficrouting.Weights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	if weights.Baseline > 0 {
+		return fmt.Errorf("BASELINE variant: %w", trafficrouting.ErrUnsupported)
+	}
+
+	routingTrafficCfg := provider.RoutingTrafficConfig{
+		{
+			TargetGroupArn: *r.primaryTargetGroup.TargetGroupArn,
+			Weight:         weights.Primary,
+		},
+		{
+			TargetGroupArn: *r.canaryTargetGroup.TargetGroupArn,
+			Weight:         weights.Canary,
+		},
+	}
+
+	currListenerArn, err := r.client.GetListener(ctx, r.primaryTargetGroup)
+	if err != nil {
+		return fmt.Errorf("failed to get current active listener: %w", err)
+	}
+	if err := r.client.ModifyListener(ctx, currListenerArn, routingTrafficCfg); err != nil {
+		return fmt.Errorf("failed to modify listener: %w", err)
+	}
+	return nil
+}
+
+// GetWeights returns the weights of the target groups in the listener of PRIMARY target group.
+func (r *listenerRouter) GetWeights(ctx context.Context) (trafficrouting.Weights, error) {
+	currListenerArn, err := r.client.GetListener(ctx, r.primaryTargetGroup)
+	if err != nil {
+		return trafficrouting.Weights{}, fmt.Errorf("failed to get current active listener: %w", err)
+	}
+	cfg, err := r.client.GetListenerWeights(ctx, currListenerArn)
+	if err != nil {
+		return trafficrouting.Weights{}, fmt.Errorf("failed to get listener weights: %w", err)
+	}
+	return trafficrouting.Weights{
+		Primary: cfg.Weight(*r.primaryTargetGroup.TargetGroupArn),
+		Canary:  cfg.Weight(*r.canaryTargetGroup.TargetGroupArn),
+	}, nil
+}
+
+// RouteByHeader is not supported yet.
+func (r *listenerRouter) RouteByHeader(_ context.Context, _ trafficrouting.HeaderRoute) error {
+	return trafficrouting.ErrUnsupported
+}
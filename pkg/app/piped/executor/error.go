@@ -0,0 +1,107 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorKind represents the origin of an error occurred while executing a stage.
+type ErrorKind string
+
+const (
+	// ErrorKindUser is used for the errors caused by the user configuration,
+	// such as a malformed template or a missing cloud provider.
+	// Retrying the stage without changing the configuration does not help.
+	ErrorKindUser ErrorKind = "USER"
+	// ErrorKindSystem is used for the system or transient errors,
+	// such as network failures or API throttling.
+	ErrorKindSystem ErrorKind = "SYSTEM"
+)
+
+// Error is an error with its classification.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// NewUserError returns an error caused by the user configuration.
+func NewUserError(format string, a ...interface{}) *Error {
+	return &Error{
+		Kind: ErrorKindUser,
+		Err:  fmt.Errorf(format, a...),
+	}
+}
+
+// NewSystemError returns a system or transient error.
+func NewSystemError(format string, a ...interface{}) *Error {
+	return &Error{
+		Kind: ErrorKindSystem,
+		Err:  fmt.Errorf(format, a...),
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the kind of the given error.
+// The kind of the wrapped Error or gRPC status is used if exists,
+// otherwise the error is considered as a system error.
+func ClassifyError(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if s, ok := e.(interface{ GRPCStatus() *status.Status }); ok {
+			return classifyGRPCCode(s.GRPCStatus().Code())
+		}
+	}
+	return ErrorKindSystem
+}
+
+func classifyGRPCCode(code codes.Code) ErrorKind {
+	switch code {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied, codes.FailedPrecondition, codes.OutOfRange, codes.Unauthenticated:
+		return ErrorKindUser
+	default:
+		return ErrorKindSystem
+	}
+}
+
+// IsRetryable reports whether retrying the stage may resolve the given error.
+func IsRetryable(err error) bool {
+	return ClassifyError(err) == ErrorKindSystem
+}
+
+// StatusReason returns the human-readable description of the given error
+// that should be used as the reason of the failed stage.
+func StatusReason(err error) string {
+	switch ClassifyError(err) {
+	case ErrorKindUser:
+		return fmt.Sprintf("Configuration error: %v", err)
+	default:
+		return fmt.Sprintf("System error: %v", err)
+	}
+}
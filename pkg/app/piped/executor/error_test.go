@@ -0,0 +1,74 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClassifyError(t *testing.T) {
+	testcases := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{
+			name:     "user error",
+			err:      NewUserError("unknown provider name %s", "prometheus"),
+			expected: ErrorKindUser,
+		},
+		{
+			name:     "wrapped user error",
+			err:      fmt.Errorf("failed to spawn analyzer: %w", NewUserError("analysis template %s not found", "foo")),
+			expected: ErrorKindUser,
+		},
+		{
+			name:     "system error",
+			err:      NewSystemError("failed to connect"),
+			expected: ErrorKindSystem,
+		},
+		{
+			name:     "unclassified error",
+			err:      fmt.Errorf("connection reset by peer"),
+			expected: ErrorKindSystem,
+		},
+		{
+			name:     "invalid argument status",
+			err:      fmt.Errorf("failed to call api: %w", status.Error(codes.InvalidArgument, "invalid")),
+			expected: ErrorKindUser,
+		},
+		{
+			name:     "resource exhausted status",
+			err:      status.Error(codes.ResourceExhausted, "throttled"),
+			expected: ErrorKindSystem,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ClassifyError(tc.err))
+			assert.Equal(t, tc.expected == ErrorKindSystem, IsRetryable(tc.err))
+		})
+	}
+}
+
+func TestStatusReason(t *testing.T) {
+	assert.Equal(t, "Configuration error: unknown provider name foo", StatusReason(NewUserError("unknown provider name %s", "foo")))
+	assert.Equal(t, "System error: timeout", StatusReason(fmt.Errorf("timeout")))
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventpublish
+
+import (
+	"fmt"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type Executor struct {
+	executor.Input
+}
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+}
+
+// Register registers this executor factory into a given registerer.
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageEventPublish, f)
+}
+
+// Execute registers the configured event to the control-plane
+// to let the event watchers of the downstream applications handle it.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	opts := e.StageConfig.EventPublishStageOptions
+	if opts == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if e.EventRegisterer == nil {
+		e.LogPersister.Error("Unable to publish the event because no event registerer was given")
+		e.ReportError(fmt.Errorf("no event registerer was given"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	data, err := executor.RenderTemplate("data", opts.Data, e.TemplateArgs())
+	if err != nil {
+		e.LogPersister.Errorf("Failed to render the event data (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Infof("Publishing event %s with data %q and labels %v", opts.Name, data, opts.Labels)
+	id, err := e.EventRegisterer.RegisterEvent(sig.Context(), opts.Name, data, opts.Labels)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to publish event %s (%v)", opts.Name, err)
+		e.ReportError(fmt.Errorf("failed to publish event %s: %w", opts.Name, err))
+		return executor.DetermineStageStatus(sig.Signal(), e.Stage.Status, model.StageStatus_STAGE_FAILURE)
+	}
+
+	e.LogPersister.Successf("Successfully published event %s (id: %s)", opts.Name, id)
+	return model.StageStatus_STAGE_SUCCESS
+}
@@ -0,0 +1,107 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventpublish
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeEventRegisterer struct {
+	name   string
+	data   string
+	labels map[string]string
+	err    error
+}
+
+func (r *fakeEventRegisterer) RegisterEvent(_ context.Context, name, data string, labels map[string]string) (string, error) {
+	r.name, r.data, r.labels = name, data, labels
+	return "event-id", r.err
+}
+
+func TestExecute(t *testing.T) {
+	testcases := []struct {
+		name       string
+		opts       *config.EventPublishStageOptions
+		err        error
+		wantStatus model.StageStatus
+		wantData   string
+	}{
+		{
+			name: "published",
+			opts: &config.EventPublishStageOptions{
+				Name:   "helloworld-deployed",
+				Data:   "{{ .Deployment.Version }}",
+				Labels: map[string]string{"env": "dev"},
+			},
+			wantStatus: model.StageStatus_STAGE_SUCCESS,
+			wantData:   "v0.1.0",
+		},
+		{
+			name: "failed to render data",
+			opts: &config.EventPublishStageOptions{
+				Name: "helloworld-deployed",
+				Data: "{{ .Deployment.Image }}",
+			},
+			wantStatus: model.StageStatus_STAGE_FAILURE,
+		},
+		{
+			name: "failed to register",
+			opts: &config.EventPublishStageOptions{
+				Name: "helloworld-deployed",
+				Data: "{{ .Commit.Hash }}",
+			},
+			err:        errors.New("unavailable"),
+			wantStatus: model.StageStatus_STAGE_FAILURE,
+			wantData:   "abc123",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &fakeEventRegisterer{err: tc.err}
+			e := &Executor{
+				Input: executor.Input{
+					Stage:       &model.PipelineStage{Id: "stage-1", Name: model.StageEventPublish.String()},
+					StageConfig: config.PipelineStage{EventPublishStageOptions: tc.opts},
+					Deployment: &model.Deployment{
+						Id:      "deployment-1",
+						Version: "v0.1.0",
+						Trigger: &model.DeploymentTrigger{
+							Commit: &model.Commit{Hash: "abc123"},
+						},
+					},
+					LogPersister:    &executortest.FakeLogPersister{},
+					EventRegisterer: r,
+				},
+			}
+			sig, _ := executor.NewStopSignal()
+			status := e.Execute(sig)
+			assert.Equal(t, tc.wantStatus, status)
+			assert.Equal(t, tc.wantData, r.data)
+			if tc.wantData != "" {
+				assert.Equal(t, tc.opts.Name, r.name)
+				assert.Equal(t, tc.opts.Labels, r.labels)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ package executor
 
 import (
 	"context"
+	"fmt"
 
 	"go.uber.org/zap"
 
@@ -40,10 +41,19 @@ type LogPersister interface {
 	Infof(format string, a ...interface{})
 	Success(log string)
 	Successf(format string, a ...interface{})
+	Warning(log string)
+	Warningf(format string, a ...interface{})
 	Error(log string)
 	Errorf(format string, a ...interface{})
+	// Log appends the log of the given severity along with the structured fields
+	// which are shown next to the log instead of being formatted into it.
+	Log(severity model.LogSeverity, log string, fields map[string]interface{})
 }
 
+// DiffSummaryMetadataKey is the key of the deployment metadata storing
+// the concise summary of the changes detected by a diff stage such as K8S_DIFF.
+const DiffSummaryMetadataKey = "DiffSummary"
+
 type MetadataStore interface {
 	Get(key string) (string, bool)
 	Set(ctx context.Context, key, value string) error
@@ -58,6 +68,7 @@ type CommandLister interface {
 
 type AppLiveResourceLister interface {
 	ListKubernetesResources() ([]provider.Manifest, bool)
+	ListKubernetesDependedResources() ([]provider.Manifest, bool)
 }
 
 type AnalysisResultStore interface {
@@ -65,12 +76,39 @@ type AnalysisResultStore interface {
 	PutLatestAnalysisResult(ctx context.Context, analysisResult *model.AnalysisResult) error
 }
 
+type ErrorReporter interface {
+	// ReportError records the error that caused the stage to fail.
+	ReportError(err error)
+}
+
+type WarningReporter interface {
+	// ReportWarning records a non-critical problem that did not fail the stage.
+	ReportWarning(warning string)
+}
+
+type Notifier interface {
+	// Notify sends the given event to the configured notification receivers.
+	Notify(event model.NotificationEvent)
+}
+
+type EventRegisterer interface {
+	// RegisterEvent registers a new event to be handled by the event watchers of all pipeds in the project.
+	// It returns the id of the registered event.
+	RegisterEvent(ctx context.Context, name, data string, labels map[string]string) (string, error)
+}
+
+type SecretDecrypter interface {
+	// Decrypt returns the plaintext of the given text encrypted by the secret management of piped.
+	Decrypt(string) (string, error)
+}
+
 type Input struct {
 	Stage       *model.PipelineStage
 	StageConfig config.PipelineStage
 	// Readonly deployment model.
 	Deployment            *model.Deployment
 	Application           *model.Application
+	EnvName               string
 	PipedConfig           *config.PipedSpec
 	TargetDSP             deploysource.Provider
 	RunningDSP            deploysource.Provider
@@ -80,7 +118,43 @@ type Input struct {
 	AppManifestsCache     cache.Cache
 	AppLiveResourceLister AppLiveResourceLister
 	AnalysisResultStore   AnalysisResultStore
-	Logger                *zap.Logger
+	ErrorReporter         ErrorReporter
+	WarningReporter       WarningReporter
+	Notifier              Notifier
+	EventRegisterer       EventRegisterer
+	// Nil when no secret management was configured in piped.
+	SecretDecrypter SecretDecrypter
+	Logger          *zap.Logger
+}
+
+// ReportError records the given error to be used as the reason of the failed stage.
+func (in Input) ReportError(err error) {
+	if in.ErrorReporter != nil {
+		in.ErrorReporter.ReportError(err)
+	}
+}
+
+// ReportWarning records a non-critical problem found while executing the stage.
+// The successful stage having some warnings is completed as STAGE_SUCCESS_WITH_WARNINGS.
+func (in Input) ReportWarning(format string, a ...interface{}) {
+	if in.WarningReporter != nil {
+		in.WarningReporter.ReportWarning(fmt.Sprintf(format, a...))
+	}
+}
+
+// Notify sends the given event to the notification receivers of piped.
+func (in Input) Notify(event model.NotificationEvent) {
+	if in.Notifier != nil {
+		in.Notifier.Notify(event)
+	}
+}
+
+// IsFeatureEnabled returns whether the given feature is enabled by piped for the deployment.
+func (in Input) IsFeatureEnabled(feature string) bool {
+	if in.PipedConfig == nil {
+		return false
+	}
+	return in.PipedConfig.IsFeatureEnabled(feature, in.Deployment.ApplicationName, in.Deployment.Id)
 }
 
 func DetermineStageStatus(sig StopSignalType, ori, got model.StageStatus) model.StageStatus {
@@ -93,6 +167,8 @@ func DetermineStageStatus(sig StopSignalType, ori, got model.StageStatus) model.
 		return model.StageStatus_STAGE_CANCELLED
 	case StopSignalTimeout:
 		return model.StageStatus_STAGE_FAILURE
+	case StopSignalSkip:
+		return model.StageStatus_STAGE_SKIPPED
 	}
 	return model.StageStatus_STAGE_FAILURE
 }
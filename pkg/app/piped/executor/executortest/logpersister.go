@@ -0,0 +1,34 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package executortest provides the fakes for testing the stage executors.
+package executortest
+
+import (
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// FakeLogPersister is a LogPersister discarding all given logs.
+type FakeLogPersister struct{}
+
+func (l *FakeLogPersister) Write(_ []byte) (int, error)                                 { return 0, nil }
+func (l *FakeLogPersister) Info(_ string)                                               {}
+func (l *FakeLogPersister) Infof(_ string, _ ...interface{})                            {}
+func (l *FakeLogPersister) Success(_ string)                                            {}
+func (l *FakeLogPersister) Successf(_ string, _ ...interface{})                         {}
+func (l *FakeLogPersister) Warning(_ string)                                            {}
+func (l *FakeLogPersister) Warningf(_ string, _ ...interface{})                         {}
+func (l *FakeLogPersister) Error(_ string)                                              {}
+func (l *FakeLogPersister) Errorf(_ string, _ ...interface{})                           {}
+func (l *FakeLogPersister) Log(_ model.LogSeverity, _ string, _ map[string]interface{}) {}
@@ -0,0 +1,85 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"encoding/json"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+const albActionAnnotationPrefix = "alb.ingress.kubernetes.io/actions."
+
+// albAction represents the forward action of AWS Load Balancer Controller
+// configured via alb.ingress.kubernetes.io/actions.<action> annotation.
+type albAction struct {
+	Type          string           `json:"type"`
+	ForwardConfig albForwardConfig `json:"forwardConfig"`
+}
+
+type albForwardConfig struct {
+	TargetGroups []albTargetGroup `json:"targetGroups"`
+}
+
+type albTargetGroup struct {
+	ServiceName string `json:"serviceName"`
+	ServicePort string `json:"servicePort"`
+	Weight      int    `json:"weight"`
+}
+
+// generateALBIngressManifest sets the forward action with the weighted target groups
+// for the Services of variants into the given Ingress.
+// The Ingress is expected to route traffic to the action by using
+// the action name as the service name and use-annotation as the port name.
+func generateALBIngressManifest(m provider.Manifest, action, serviceName, servicePort string, canaryPercent, baselinePercent int) (provider.Manifest, error) {
+	// Because the loaded manifests are read-only
+	// so we duplicate them to avoid updating the shared manifests data in cache.
+	m = duplicateManifest(m, "")
+
+	primaryPercent := 100 - canaryPercent - baselinePercent
+	targetGroups := []albTargetGroup{
+		{
+			ServiceName: serviceName,
+			ServicePort: servicePort,
+			Weight:      primaryPercent,
+		},
+	}
+	if canaryPercent > 0 {
+		targetGroups = append(targetGroups, albTargetGroup{
+			ServiceName: makeSuffixedName(serviceName, canaryVariant),
+			ServicePort: servicePort,
+			Weight:      canaryPercent,
+		})
+	}
+	if baselinePercent > 0 {
+		targetGroups = append(targetGroups, albTargetGroup{
+			ServiceName: makeSuffixedName(serviceName, baselineVariant),
+			ServicePort: servicePort,
+			Weight:      baselinePercent,
+		})
+	}
+
+	data, err := json.Marshal(albAction{
+		Type:          "forward",
+		ForwardConfig: albForwardConfig{TargetGroups: targetGroups},
+	})
+	if err != nil {
+		return m, err
+	}
+	m.AddAnnotations(map[string]string{
+		albActionAnnotationPrefix + action: string(data),
+	})
+	return m, nil
+}
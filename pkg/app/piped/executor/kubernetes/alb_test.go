@@ -0,0 +1,80 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestGenerateALBIngressManifest(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: helloworld
+  annotations:
+    alb.ingress.kubernetes.io/scheme: internet-facing
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: forward-helloworld
+            port:
+              name: use-annotation
+`)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(manifests))
+
+	testcases := []struct {
+		name            string
+		canaryPercent   int
+		baselinePercent int
+		expected        string
+	}{
+		{
+			name:          "canary only",
+			canaryPercent: 30,
+			expected:      `{"type":"forward","forwardConfig":{"targetGroups":[{"serviceName":"helloworld","servicePort":"80","weight":70},{"serviceName":"helloworld-canary","servicePort":"80","weight":30}]}}`,
+		},
+		{
+			name:            "canary and baseline",
+			canaryPercent:   20,
+			baselinePercent: 20,
+			expected:        `{"type":"forward","forwardConfig":{"targetGroups":[{"serviceName":"helloworld","servicePort":"80","weight":60},{"serviceName":"helloworld-canary","servicePort":"80","weight":20},{"serviceName":"helloworld-baseline","servicePort":"80","weight":20}]}}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			generated, err := generateALBIngressManifest(manifests[0], "forward-helloworld", "helloworld", "80", tc.canaryPercent, tc.baselinePercent)
+			require.NoError(t, err)
+
+			annotations := generated.GetAnnotations()
+			assert.Equal(t, tc.expected, annotations["alb.ingress.kubernetes.io/actions.forward-helloworld"])
+			assert.Equal(t, "internet-facing", annotations["alb.ingress.kubernetes.io/scheme"])
+
+			// The original manifest must not be changed.
+			assert.Equal(t, 1, len(manifests[0].GetAnnotations()))
+		})
+	}
+}
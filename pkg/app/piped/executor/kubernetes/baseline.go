@@ -37,12 +37,23 @@ func (e *deployExecutor) ensureBaselineRollout(ctx context.Context) model.StageS
 	)
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	// Load running manifests at the most successful deployed commit.
-	e.LogPersister.Infof("Loading running manifests at commit %s for handling", runningCommit)
-	manifests, err := e.loadRunningManifests(ctx)
+	// They are rendered again when helm values were specified for BASELINE variant.
+	var (
+		manifests []provider.Manifest
+		err       error
+	)
+	if options.HelmValues != nil {
+		e.LogPersister.Infof("Loading running manifests at commit %s with the helm values for BASELINE variant", runningCommit)
+		manifests, err = e.loadManifestsWithHelmValues(ctx, e.RunningDSP, options.HelmValues)
+	} else {
+		e.LogPersister.Infof("Loading running manifests at commit %s for handling", runningCommit)
+		manifests, err = e.loadRunningManifests(ctx)
+	}
 	if err != nil {
 		e.LogPersister.Errorf("Failed while loading running manifests (%v)", err)
 		return model.StageStatus_STAGE_FAILURE
@@ -60,6 +71,8 @@ func (e *deployExecutor) ensureBaselineRollout(ctx context.Context) model.StageS
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	e.commonMetadata.apply(baselineManifests)
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		baselineManifests,
@@ -84,6 +97,7 @@ func (e *deployExecutor) ensureBaselineRollout(ctx context.Context) model.StageS
 	// Start rolling out the resources for BASELINE variant.
 	e.LogPersister.Info("Start rolling out BASELINE variant...")
 	if err := applyManifests(ctx, e.provider, baselineManifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
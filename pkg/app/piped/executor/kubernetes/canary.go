@@ -30,23 +30,35 @@ const (
 	addedCanaryResourcesMetadataKey = "canary-resources"
 )
 
-func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageStatus {
+func (e *deployExecutor) ensureCanaryRollout(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
 	options := e.StageConfig.K8sCanaryRolloutStageOptions
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
 	// Load the manifests at the triggered commit.
-	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
-	manifests, err := loadManifests(
-		ctx,
-		e.Deployment.ApplicationId,
-		e.commit,
-		e.AppManifestsCache,
-		e.provider,
-		e.Logger,
+	// They are rendered again when helm values were specified for CANARY variant.
+	var (
+		manifests []provider.Manifest
+		err       error
 	)
+	if options.HelmValues != nil {
+		e.LogPersister.Infof("Loading manifests at commit %s with the helm values for CANARY variant", e.commit)
+		manifests, err = e.loadManifestsWithHelmValues(ctx, e.TargetDSP, options.HelmValues)
+	} else {
+		e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+		manifests, err = loadManifests(
+			ctx,
+			e.Deployment.ApplicationId,
+			e.commit,
+			e.AppManifestsCache,
+			e.provider,
+			e.Logger,
+		)
+	}
 	if err != nil {
 		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
 		return model.StageStatus_STAGE_FAILURE
@@ -68,13 +80,31 @@ func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageSta
 		}
 	}
 
+	if len(options.Steps) > 0 {
+		return e.rolloutCanarySteps(sig, manifests, *options)
+	}
+
 	// Find and generate workload & service manifests for CANARY variant.
-	canaryManifests, err := e.generateCanaryManifests(manifests, *options)
+	canaryManifests, err := e.generateCanaryManifests(ctx, manifests, *options)
 	if err != nil {
 		e.LogPersister.Errorf("Unable to generate manifests for CANARY variant (%v)", err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if !e.rolloutCanaryManifests(ctx, canaryManifests) {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully rolled out CANARY variant")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// rolloutCanaryManifests applies the given manifests of CANARY variant
+// after storing their keys for cleaning later.
+// The result is logged and false is returned if failed.
+func (e *deployExecutor) rolloutCanaryManifests(ctx context.Context, canaryManifests []provider.Manifest) bool {
+	e.commonMetadata.apply(canaryManifests)
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		canaryManifests,
@@ -90,20 +120,18 @@ func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageSta
 		addedResources = append(addedResources, m.Key.String())
 	}
 	metadata := strings.Join(addedResources, ",")
-	err = e.MetadataStore.Set(ctx, addedCanaryResourcesMetadataKey, metadata)
-	if err != nil {
+	if err := e.MetadataStore.Set(ctx, addedCanaryResourcesMetadataKey, metadata); err != nil {
 		e.LogPersister.Errorf("Unable to save deployment metadata (%v)", err)
-		return model.StageStatus_STAGE_FAILURE
+		return false
 	}
 
 	// Start rolling out the resources for CANARY variant.
 	e.LogPersister.Info("Start rolling out CANARY variant...")
 	if err := applyManifests(ctx, e.provider, canaryManifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
-		return model.StageStatus_STAGE_FAILURE
+		e.ReportError(err)
+		return false
 	}
-
-	e.LogPersister.Success("Successfully rolled out CANARY variant")
-	return model.StageStatus_STAGE_SUCCESS
+	return true
 }
 
 func (e *deployExecutor) ensureCanaryClean(ctx context.Context) model.StageStatus {
@@ -121,7 +149,7 @@ func (e *deployExecutor) ensureCanaryClean(ctx context.Context) model.StageStatu
 	return model.StageStatus_STAGE_SUCCESS
 }
 
-func (e *deployExecutor) generateCanaryManifests(manifests []provider.Manifest, opts config.K8sCanaryRolloutStageOptions) ([]provider.Manifest, error) {
+func (e *deployExecutor) generateCanaryManifests(ctx context.Context, manifests []provider.Manifest, opts config.K8sCanaryRolloutStageOptions) ([]provider.Manifest, error) {
 	suffix := canaryVariant
 	if opts.Suffix != "" {
 		suffix = opts.Suffix
@@ -164,25 +192,121 @@ func (e *deployExecutor) generateCanaryManifests(manifests []provider.Manifest,
 
 	// Generate new workload manifests for CANARY variant.
 	// The generated ones will mount to the new ConfigMaps and Secrets.
-	replicasCalculator := func(cur *int32) int32 {
-		if cur == nil {
-			return 1
+	// Each workload is generated with its own replicas when configured.
+	if err := checkCanaryWorkloads(workloads, opts.Workloads); err != nil {
+		return nil, err
+	}
+	generatedWorkloads := make([]provider.Manifest, 0, len(workloads))
+	for _, w := range workloads {
+		replicas := opts.Replicas
+		if cfg, ok := findCanaryWorkload(w.Key, opts.Workloads); ok {
+			if cfg.Exclude {
+				continue
+			}
+			if cfg.Replicas.Number != 0 {
+				replicas = cfg.Replicas
+			}
+		}
+		replicasCalculator := func(cur *int32) int32 {
+			if cur == nil {
+				return 1
+			}
+			num := replicas.Calculate(int(*cur), 1)
+			return int32(num)
 		}
-		num := opts.Replicas.Calculate(int(*cur), 1)
-		return int32(num)
+		// We don't need to duplicate the workload manifests
+		// because generateVariantWorkloadManifests function is already making a duplicate while decoding.
+		generated, err := generateVariantWorkloadManifests([]provider.Manifest{w}, configMaps, secrets, canaryVariant, suffix, replicasCalculator)
+		if err != nil {
+			return nil, err
+		}
+		if a := opts.Architecture; a != nil && a.MirrorPrimary {
+			if generated, err = e.mirrorPrimaryArchitectures(ctx, w, generated); err != nil {
+				return nil, err
+			}
+		}
+		generatedWorkloads = append(generatedWorkloads, generated...)
+	}
+	if len(generatedWorkloads) == 0 {
+		return nil, fmt.Errorf("all workloads were excluded from CANARY variant")
 	}
-	// We don't need to duplicate the workload manifests
-	// because generateVariantWorkloadManifests function is already making a duplicate while decoding.
-	// workloads = duplicateManifests(workloads, suffix)
-	generatedWorkloads, err := generateVariantWorkloadManifests(workloads, configMaps, secrets, canaryVariant, suffix, replicasCalculator)
+
+	// Inject the scheduling constraints to place CANARY pods on the specified nodes.
+	generatedWorkloads, err := applyNodePlacement(generatedWorkloads, opts.NodePlacement)
 	if err != nil {
 		return nil, err
 	}
+	if a := opts.Architecture; a != nil && a.Pin != "" {
+		if generatedWorkloads, err = pinArchitecture(generatedWorkloads, a.Pin); err != nil {
+			return nil, err
+		}
+	}
 	canaryManifests = append(canaryManifests, generatedWorkloads...)
 
 	return canaryManifests, nil
 }
 
+// mirrorPrimaryArchitectures spreads the pods of the given CANARY workloads over the CPU architectures
+// in the same ratio as the running pods of PRIMARY variant of the given workload,
+// because skewed placement in a mixed cluster makes the comparison between the variants meaningless.
+func (e *deployExecutor) mirrorPrimaryArchitectures(ctx context.Context, primary provider.Manifest, canaries []provider.Manifest) ([]provider.Manifest, error) {
+	selector, err := primaryPodSelector(primary)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := e.provider.CountPodsByArchitecture(ctx, primary.Key, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the architectures of PRIMARY pods of %s: %w", primary.Key.ReadableString(), err)
+	}
+	if len(counts) == 0 {
+		e.LogPersister.Infof("No running PRIMARY pod of %s was found, its CANARY pods will be placed without architecture constraint", primary.Key.ReadableString())
+		return canaries, nil
+	}
+	e.LogPersister.Infof("Placing CANARY pods of %s to mirror the architectures of PRIMARY pods %v", primary.Key.ReadableString(), counts)
+
+	out := make([]provider.Manifest, 0, len(canaries))
+	for _, c := range canaries {
+		mirrored, err := mirrorArchitectures(c, counts)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mirrored...)
+	}
+	return out, nil
+}
+
+// findCanaryWorkload returns the configuration for CANARY variant of the given workload.
+func findCanaryWorkload(key provider.ResourceKey, workloads []config.K8sCanaryWorkload) (config.K8sCanaryWorkload, bool) {
+	for _, w := range workloads {
+		kind := provider.KindDeployment
+		if w.Kind != "" {
+			kind = w.Kind
+		}
+		if key.Kind == kind && key.Name == w.Name {
+			return w, true
+		}
+	}
+	return config.K8sCanaryWorkload{}, false
+}
+
+// checkCanaryWorkloads returns an error if any configured workload was not found
+// to avoid silently rolling out the workloads with unintended replicas due to a typo.
+func checkCanaryWorkloads(manifests []provider.Manifest, workloads []config.K8sCanaryWorkload) error {
+	for _, w := range workloads {
+		found := false
+		for _, m := range manifests {
+			if _, ok := findCanaryWorkload(m.Key, []config.K8sCanaryWorkload{w}); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unable to find workload %s configured for CANARY variant", w.Name)
+		}
+	}
+	return nil
+}
+
 func removeCanaryResources(ctx context.Context, applier provider.Applier, resources []string, lp executor.LogPersister) error {
 	if len(resources) == 0 {
 		return nil
@@ -21,12 +21,15 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -53,7 +56,7 @@ func TestEnsureCanaryRollout(t *testing.T) {
 						},
 					},
 					Stage:        &model.PipelineStage{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Logger:       zap.NewNop(),
 				},
 			},
@@ -68,7 +71,7 @@ func TestEnsureCanaryRollout(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sCanaryRolloutStageOptions: &config.K8sCanaryRolloutStageOptions{},
@@ -97,7 +100,7 @@ func TestEnsureCanaryRollout(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sCanaryRolloutStageOptions: &config.K8sCanaryRolloutStageOptions{},
@@ -127,7 +130,7 @@ func TestEnsureCanaryRollout(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sCanaryRolloutStageOptions: &config.K8sCanaryRolloutStageOptions{},
@@ -176,7 +179,7 @@ func TestEnsureCanaryRollout(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sCanaryRolloutStageOptions: &config.K8sCanaryRolloutStageOptions{},
@@ -218,9 +221,141 @@ func TestEnsureCanaryRollout(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
-			got := tc.executor.ensureCanaryRollout(ctx)
+			sig, _ := executor.NewStopSignal()
+			got := tc.executor.ensureCanaryRollout(sig)
 			assert.Equal(t, tc.want, got)
 		})
 	}
 }
+
+func TestGenerateCanaryManifestsForMultipleWorkloads(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 10
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: gcr.io/pipecd/web:v0.2.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 10
+  selector:
+    matchLabels:
+      app: api
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      containers:
+      - name: api
+        image: gcr.io/pipecd/api:v0.2.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: worker
+  template:
+    metadata:
+      labels:
+        app: worker
+    spec:
+      containers:
+      - name: worker
+        image: gcr.io/pipecd/worker:v0.2.0
+`)
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name             string
+		workloads        []config.K8sCanaryWorkload
+		expectedReplicas map[string]int32
+		wantErr          bool
+	}{
+		{
+			name: "same replicas for all workloads",
+			expectedReplicas: map[string]int32{
+				"web-canary":    1,
+				"api-canary":    1,
+				"worker-canary": 1,
+			},
+		},
+		{
+			name: "distinct replicas and exclusion",
+			workloads: []config.K8sCanaryWorkload{
+				{
+					Name:     "web",
+					Replicas: config.Replicas{Number: 50, IsPercentage: true},
+				},
+				{
+					Name:    "worker",
+					Exclude: true,
+				},
+			},
+			expectedReplicas: map[string]int32{
+				"web-canary": 5,
+				"api-canary": 1,
+			},
+		},
+		{
+			name: "all workloads were excluded",
+			workloads: []config.K8sCanaryWorkload{
+				{Name: "web", Exclude: true},
+				{Name: "api", Exclude: true},
+				{Name: "worker", Exclude: true},
+			},
+			wantErr: true,
+		},
+		{
+			name: "configured workload was not found",
+			workloads: []config.K8sCanaryWorkload{
+				{Name: "unknown", Exclude: true},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &deployExecutor{
+				deployCfg: &config.KubernetesDeploymentSpec{},
+			}
+			got, err := e.generateCanaryManifests(context.Background(), manifests, config.K8sCanaryRolloutStageOptions{
+				Replicas:  config.Replicas{Number: 1},
+				Workloads: tc.workloads,
+			})
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			replicas := make(map[string]int32, len(got))
+			for _, m := range got {
+				d := &appsv1.Deployment{}
+				require.NoError(t, m.ConvertToStructuredObject(d))
+				replicas[d.Name] = *d.Spec.Replicas
+			}
+			assert.Equal(t, tc.expectedReplicas, replicas)
+		})
+	}
+}
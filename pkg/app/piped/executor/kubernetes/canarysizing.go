@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+	metricsfactory "github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics/factory"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// decideCanaryPercentage returns the percentage of traffic to be routed to the canary variant
+// from the recent request rate of the application queried through the analysis provider.
+func (e *deployExecutor) decideCanaryPercentage(ctx context.Context, sizing *config.K8sCanarySizing) (int, error) {
+	providerCfg, ok := e.PipedConfig.GetAnalysisProvider(sizing.Provider)
+	if !ok {
+		return 0, fmt.Errorf("unknown analysis provider %s", sizing.Provider)
+	}
+	provider, err := metricsfactory.NewProvider(&config.TemplatableAnalysisMetrics{
+		AnalysisMetrics: config.AnalysisMetrics{
+			Provider: sizing.Provider,
+			Query:    sizing.Query,
+			Timeout:  sizing.Timeout,
+		},
+	}, &providerCfg, e.Logger)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	points, err := provider.QueryPoints(ctx, sizing.Query, metrics.QueryRange{
+		From: now.Add(-sizing.Window.Duration()),
+		To:   now,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query the request rate: %w", err)
+	}
+	rps, err := averageRequestRate(points)
+	if err != nil {
+		return 0, err
+	}
+
+	percent, capped := computeCanaryPercentage(rps, sizing.MinCanaryRPS, sizing.MaxCanary.Int())
+	if capped {
+		e.LogPersister.Infof("The canary variant would receive %.2f requests per second which is less than the minimum %.2f because the percentage was capped to %d%%",
+			rps*float64(percent)/100, sizing.MinCanaryRPS, percent)
+	}
+	e.LogPersister.Infof("Decided %d%% traffic to the canary variant based on the request rate %.2f per second in the last %v",
+		percent, rps, sizing.Window.Duration())
+	return percent, nil
+}
+
+// averageRequestRate returns the average of the given request rates.
+// An error is returned when there is no traffic to decide the percentage.
+func averageRequestRate(points []metrics.DataPoint) (float64, error) {
+	if len(points) == 0 {
+		return 0, metrics.ErrNoDataFound
+	}
+	var sum float64
+	for _, p := range points {
+		sum += p.Value
+	}
+	rps := sum / float64(len(points))
+	if rps <= 0 || math.IsNaN(rps) || math.IsInf(rps, 0) {
+		return 0, fmt.Errorf("invalid request rate %v", rps)
+	}
+	return rps, nil
+}
+
+// computeCanaryPercentage returns the minimum percentage of the given total request rate
+// to route at least minRPS to the canary variant.
+// The returned bool is true when the percentage was capped by maxPercent.
+func computeCanaryPercentage(totalRPS, minRPS float64, maxPercent int) (int, bool) {
+	percent := int(math.Ceil(minRPS / totalRPS * 100))
+	if percent < 1 {
+		percent = 1
+	}
+	if percent > maxPercent {
+		return maxPercent, true
+	}
+	return percent, false
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/analysisprovider/metrics"
+)
+
+func TestComputeCanaryPercentage(t *testing.T) {
+	testcases := []struct {
+		name           string
+		totalRPS       float64
+		minRPS         float64
+		maxPercent     int
+		expected       int
+		expectedCapped bool
+	}{
+		{
+			name:       "exact percentage",
+			totalRPS:   1000,
+			minRPS:     100,
+			maxPercent: 50,
+			expected:   10,
+		},
+		{
+			name:       "rounded up",
+			totalRPS:   3000,
+			minRPS:     100,
+			maxPercent: 50,
+			expected:   4,
+		},
+		{
+			name:       "at least one percent",
+			totalRPS:   100000,
+			minRPS:     1,
+			maxPercent: 50,
+			expected:   1,
+		},
+		{
+			name:           "capped by max",
+			totalRPS:       150,
+			minRPS:         100,
+			maxPercent:     50,
+			expected:       50,
+			expectedCapped: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, capped := computeCanaryPercentage(tc.totalRPS, tc.minRPS, tc.maxPercent)
+			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expectedCapped, capped)
+		})
+	}
+}
+
+func TestAverageRequestRate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		points   []metrics.DataPoint
+		expected float64
+		wantErr  bool
+	}{
+		{
+			name:    "no data",
+			wantErr: true,
+		},
+		{
+			name:    "no traffic",
+			points:  []metrics.DataPoint{{Value: 0}, {Value: 0}},
+			wantErr: true,
+		},
+		{
+			name:     "average",
+			points:   []metrics.DataPoint{{Value: 100}, {Value: 200}, {Value: 300}},
+			expected: 200,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := averageRequestRate(tc.points)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// The prefix of the deployment metadata key storing the number of completed canary steps.
+	// The stage ID is appended since multiple K8S_CANARY_ROLLOUT stages can be configured.
+	canaryStepMetadataKeyPrefix = "canary-completed-steps"
+)
+
+// rolloutCanarySteps gradually rolls out CANARY variant through the configured steps.
+// Each step updates the replicas and the traffic of CANARY variant,
+// waits for the specified duration and then runs the analysis if configured.
+// The completed steps are saved to restart from the middle after piped was restarted.
+func (e *deployExecutor) rolloutCanarySteps(sig executor.StopSignal, manifests []provider.Manifest, options config.K8sCanaryRolloutStageOptions) model.StageStatus {
+	var (
+		ctx   = sig.Context()
+		steps = options.Steps
+		start = e.retrieveCompletedCanarySteps()
+	)
+	if start > 0 {
+		e.LogPersister.Infof("Restarting from step %d because %d steps have been completed", start+1, start)
+	}
+
+	for i := start; i < len(steps); i++ {
+		step := steps[i]
+		e.LogPersister.Infof("[step %d/%d] Start rolling out CANARY variant with replicas=%s", i+1, len(steps), step.Replicas)
+
+		opts := options
+		opts.Replicas = step.Replicas
+		canaryManifests, err := e.generateCanaryManifests(ctx, manifests, opts)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to generate manifests for CANARY variant (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if !e.rolloutCanaryManifests(ctx, canaryManifests) {
+			return model.StageStatus_STAGE_FAILURE
+		}
+
+		if canaryPercent := step.Traffic.Int(); canaryPercent > 0 {
+			weights := trafficrouting.Weights{
+				Primary: 100 - canaryPercent,
+				Canary:  canaryPercent,
+			}
+			if err := e.newTrafficRouter(manifests).SetWeights(ctx, weights); err != nil {
+				e.LogPersister.Errorf("[step %d/%d] Failed to update traffic routing (%v)", i+1, len(steps), err)
+				return model.StageStatus_STAGE_FAILURE
+			}
+		}
+
+		if step.Wait > 0 {
+			e.LogPersister.Infof("[step %d/%d] Waiting for %v...", i+1, len(steps), step.Wait.Duration())
+			if !waitFor(ctx, step.Wait.Duration()) {
+				return model.StageStatus_STAGE_FAILURE
+			}
+		}
+
+		if step.Analysis != nil {
+			e.LogPersister.Infof("[step %d/%d] Start analyzing CANARY variant", i+1, len(steps))
+			if status := e.analyzeCanaryStep(sig, step.Analysis); !model.IsSuccessfulStage(status) {
+				return status
+			}
+			if err := analysis.ResetElapsedTime(ctx, e.MetadataStore, e.Stage.Id); err != nil {
+				e.Logger.Error("failed to reset the elapsed time of analysis", zap.Error(err))
+			}
+		}
+
+		e.saveCompletedCanarySteps(ctx, i+1)
+		e.LogPersister.Successf("[step %d/%d] Successfully completed", i+1, len(steps))
+	}
+
+	e.LogPersister.Success("Successfully rolled out CANARY variant through all steps")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// analyzeCanaryStep runs the given analysis as a part of the current stage.
+func (e *deployExecutor) analyzeCanaryStep(sig executor.StopSignal, opts *config.AnalysisStageOptions) model.StageStatus {
+	in := e.Input
+	in.StageConfig = config.PipelineStage{
+		Name:                 model.StageAnalysis,
+		Timeout:              e.StageConfig.Timeout,
+		AnalysisStageOptions: opts,
+	}
+	return e.newAnalysisExecutor(in).Execute(sig)
+}
+
+// waitFor blocks for the given duration.
+// It returns false if the context was done before that.
+func waitFor(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (e *deployExecutor) canaryStepMetadataKey() string {
+	return fmt.Sprintf("%s-%s", canaryStepMetadataKeyPrefix, e.Stage.Id)
+}
+
+func (e *deployExecutor) retrieveCompletedCanarySteps() int {
+	value, ok := e.MetadataStore.Get(e.canaryStepMetadataKey())
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.Logger.Error("unexpected number of completed canary steps was stored", zap.String("stored-value", value), zap.Error(err))
+		return 0
+	}
+	return n
+}
+
+func (e *deployExecutor) saveCompletedCanarySteps(ctx context.Context, n int) {
+	if err := e.MetadataStore.Set(ctx, e.canaryStepMetadataKey(), strconv.Itoa(n)); err != nil {
+		e.Logger.Error("failed to save the number of completed canary steps", zap.Error(err))
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type mapMetadataStore struct {
+	fakeMetadataStore
+	values map[string]string
+}
+
+func (m *mapMetadataStore) Get(key string) (string, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+func (m *mapMetadataStore) Set(_ context.Context, key, value string) error {
+	m.values[key] = value
+	return nil
+}
+
+type fakeAnalysisExecutor struct {
+	status model.StageStatus
+	calls  *int
+}
+
+func (e *fakeAnalysisExecutor) Execute(_ executor.StopSignal) model.StageStatus {
+	*e.calls++
+	return e.status
+}
+
+func TestRolloutCanarySteps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manifests := []provider.Manifest{
+		provider.MakeManifest(provider.ResourceKey{
+			APIVersion: "apps/v1",
+			Kind:       provider.KindDeployment,
+		}, &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"spec": map[string]interface{}{
+					"replicas": int64(10),
+					"selector": map[string]interface{}{
+						"matchLabels": map[string]interface{}{"app": "foo"},
+					},
+				},
+			},
+		}),
+	}
+	steps := []config.K8sCanaryRolloutStep{
+		{Replicas: config.Replicas{Number: 10, IsPercentage: true}},
+		{
+			Replicas: config.Replicas{Number: 50, IsPercentage: true},
+			Analysis: &config.AnalysisStageOptions{},
+		},
+		{Replicas: config.Replicas{Number: 100, IsPercentage: true}},
+	}
+
+	testcases := []struct {
+		name              string
+		completedSteps    string
+		analysisStatus    model.StageStatus
+		expectedApplies   int
+		expectedAnalyses  int
+		expectedCompleted string
+		want              model.StageStatus
+	}{
+		{
+			name:              "all steps succeeded",
+			analysisStatus:    model.StageStatus_STAGE_SUCCESS,
+			expectedApplies:   3,
+			expectedAnalyses:  1,
+			expectedCompleted: "3",
+			want:              model.StageStatus_STAGE_SUCCESS,
+		},
+		{
+			name:              "stopped at the failed analysis",
+			analysisStatus:    model.StageStatus_STAGE_FAILURE,
+			expectedApplies:   2,
+			expectedAnalyses:  1,
+			expectedCompleted: "1",
+			want:              model.StageStatus_STAGE_FAILURE,
+		},
+		{
+			name:              "restarted from the middle",
+			completedSteps:    "2",
+			analysisStatus:    model.StageStatus_STAGE_SUCCESS,
+			expectedApplies:   1,
+			expectedAnalyses:  0,
+			expectedCompleted: "3",
+			want:              model.StageStatus_STAGE_SUCCESS,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &mapMetadataStore{values: map[string]string{}}
+			if tc.completedSteps != "" {
+				store.values["canary-completed-steps-stage-id"] = tc.completedSteps
+			}
+			p := providertest.NewMockProvider(ctrl)
+			p.EXPECT().ApplyManifest(gomock.Any(), gomock.Any()).Return(nil).Times(tc.expectedApplies)
+
+			var analyses int
+			e := &deployExecutor{
+				Input: executor.Input{
+					Deployment:    &model.Deployment{},
+					Stage:         &model.PipelineStage{Id: "stage-id"},
+					LogPersister:  &executortest.FakeLogPersister{},
+					MetadataStore: store,
+					PipedConfig:   &config.PipedSpec{},
+					Logger:        zap.NewNop(),
+				},
+				provider:  p,
+				deployCfg: &config.KubernetesDeploymentSpec{},
+				newAnalysisExecutor: func(in executor.Input) executor.Executor {
+					assert.Equal(t, model.StageAnalysis, in.StageConfig.Name)
+					return &fakeAnalysisExecutor{status: tc.analysisStatus, calls: &analyses}
+				},
+			}
+
+			sig, _ := executor.NewStopSignal()
+			got := e.rolloutCanarySteps(sig, manifests, config.K8sCanaryRolloutStageOptions{Steps: steps})
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.expectedAnalyses, analyses)
+			assert.Equal(t, tc.expectedCompleted, store.values["canary-completed-steps-stage-id"])
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	maxLabelValueLength = 63
+)
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// commonMetadataArgs allows the deployment metadata
+// to be embedded in the values of the common labels and annotations.
+type commonMetadataArgs struct {
+	App struct {
+		ID   string
+		Name string
+	}
+	Deployment struct {
+		ID string
+	}
+	Commit struct {
+		Hash   string
+		Author string
+		Branch string
+	}
+	Trigger struct {
+		Commander string
+	}
+}
+
+// commonMetadata contains the rendered labels and annotations
+// which should be added to all applying manifests.
+type commonMetadata struct {
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// renderCommonMetadata renders the configured common labels and annotations for the given deployment.
+// The commit args are always of the triggered commit, even while rolling back.
+func renderCommonMetadata(cfg *config.K8sCommonMetadata, d *model.Deployment) (commonMetadata, error) {
+	var m commonMetadata
+	if cfg == nil {
+		return m, nil
+	}
+
+	var args commonMetadataArgs
+	args.App.ID = d.ApplicationId
+	args.App.Name = d.ApplicationName
+	args.Deployment.ID = d.Id
+	if t := d.Trigger; t != nil {
+		args.Trigger.Commander = t.Commander
+		if c := t.Commit; c != nil {
+			args.Commit.Hash = c.Hash
+			args.Commit.Author = c.Author
+			args.Commit.Branch = c.Branch
+		}
+	}
+
+	if len(cfg.Labels) > 0 {
+		m.labels = make(map[string]string, len(cfg.Labels))
+		for k, v := range cfg.Labels {
+			value, err := renderTemplate(k, v, args)
+			if err != nil {
+				return m, fmt.Errorf("unable to render label %q: %w", k, err)
+			}
+			m.labels[k] = sanitizeLabelValue(value)
+		}
+	}
+	if len(cfg.Annotations) > 0 {
+		m.annotations = make(map[string]string, len(cfg.Annotations))
+		for k, v := range cfg.Annotations {
+			value, err := renderTemplate(k, v, args)
+			if err != nil {
+				return m, fmt.Errorf("unable to render annotation %q: %w", k, err)
+			}
+			m.annotations[k] = value
+		}
+	}
+	return m, nil
+}
+
+// apply adds the common labels and annotations to the given manifests.
+// This must be called before adding the builtin annotations to not override them.
+func (m commonMetadata) apply(manifests []provider.Manifest) {
+	for i := range manifests {
+		manifests[i].AddLabels(m.labels)
+		manifests[i].AddAnnotations(m.annotations)
+	}
+}
+
+// sanitizeLabelValue converts the given string to be a valid label value
+// by replacing the invalid characters and trimming it.
+func sanitizeLabelValue(s string) string {
+	s = invalidLabelValueChars.ReplaceAllString(s, "-")
+	if len(s) > maxLabelValueLength {
+		s = s[:maxLabelValueLength]
+	}
+	return strings.TrimFunc(s, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestRenderCommonMetadata(t *testing.T) {
+	deployment := &model.Deployment{
+		Id:              "deployment-id",
+		ApplicationId:   "app-id",
+		ApplicationName: "simple",
+		Trigger: &model.DeploymentTrigger{
+			Commit: &model.Commit{
+				Hash:   "commit-hash",
+				Author: "John Doe",
+				Branch: "feature/foo",
+			},
+			Commander: "user",
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		cfg      *config.K8sCommonMetadata
+		expected commonMetadata
+		wantErr  bool
+	}{
+		{
+			name: "nil config",
+		},
+		{
+			name: "labels and annotations",
+			cfg: &config.K8sCommonMetadata{
+				Labels: map[string]string{
+					"team":        "payment",
+					"app":         "{{ .App.Name }}",
+					"author":      "{{ .Commit.Author }}",
+					"branch":      "{{ .Commit.Branch }}",
+					"commit-hash": "{{ .Commit.Hash }}",
+				},
+				Annotations: map[string]string{
+					"example.com/author":     "{{ .Commit.Author }}",
+					"example.com/deployment": "{{ .Deployment.ID }} by {{ .Trigger.Commander }}",
+				},
+			},
+			expected: commonMetadata{
+				labels: map[string]string{
+					"team":        "payment",
+					"app":         "simple",
+					"author":      "John-Doe",
+					"branch":      "feature-foo",
+					"commit-hash": "commit-hash",
+				},
+				annotations: map[string]string{
+					"example.com/author":     "John Doe",
+					"example.com/deployment": "deployment-id by user",
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			cfg: &config.K8sCommonMetadata{
+				Labels: map[string]string{
+					"env": "{{ .Env.Name }}",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := renderCommonMetadata(tc.cfg, deployment)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestSanitizeLabelValue(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "valid value",
+			input:    "v1.2.3_rc-1",
+			expected: "v1.2.3_rc-1",
+		},
+		{
+			name:     "invalid characters",
+			input:    "John Doe <john@example.com>",
+			expected: "John-Doe-john-example.com",
+		},
+		{
+			name:     "too long value",
+			input:    strings.Repeat("a", 62) + "-b",
+			expected: strings.Repeat("a", 62),
+		},
+		{
+			name:     "empty value",
+			input:    "",
+			expected: "",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, sanitizeLabelValue(tc.input))
+		})
+	}
+}
+
+func TestApplyCommonMetadata(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+  labels:
+    app: simple
+  annotations:
+    example.com/owner: old
+spec:
+  replicas: 1
+`)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(manifests))
+
+	m := commonMetadata{
+		labels: map[string]string{
+			"team": "payment",
+		},
+		annotations: map[string]string{
+			"example.com/owner": "new",
+		},
+	}
+	m.apply(manifests)
+
+	assert.Equal(t, map[string]string{"app": "simple", "team": "payment"}, manifests[0].GetLabels())
+	assert.Equal(t, map[string]string{"example.com/owner": "new"}, manifests[0].GetAnnotations())
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/diff"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	diffSummaryMetadataKey = "diff-summary"
+	diffFormatMetadataKey  = "diff-format"
+	kubectlDiffMetadataKey = "kubectl-diff"
+
+	// The maximum size of the kubectl diff output stored in the stage metadata.
+	maxKubectlDiffMetadataSize = 16 * 1024
+	// The maximum number of resources listed in the diff summary for notifications.
+	maxDiffSummaryResources = 10
+)
+
+func (e *deployExecutor) ensureDiff(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sDiffStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Load the manifests at the triggered commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+	newManifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(newManifests))
+
+	// Load the manifests at the running commit.
+	// There is nothing to compare with if this is the first deployment.
+	var oldManifests []provider.Manifest
+	if e.Deployment.RunningCommitHash != "" {
+		e.LogPersister.Infof("Loading manifests at running commit %s for handling", e.Deployment.RunningCommitHash)
+		oldManifests, err = e.loadRunningManifests(ctx)
+		if err != nil {
+			e.LogPersister.Errorf("Failed while loading running manifests (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Successf("Successfully loaded %d manifests", len(oldManifests))
+	}
+
+	result, err := provider.DiffList(
+		oldManifests,
+		newManifests,
+		diff.WithEquateEmpty(),
+		diff.WithCompareNumberAndNumericString(),
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while comparing manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	format := options.Format
+	if format == "" {
+		format = config.K8sDiffFormatUnified
+	}
+	summary := result.Summary()
+	e.saveDiffMetadata(ctx, summary, format)
+
+	if result.NoChange() {
+		e.LogPersister.Success("No changes were detected")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Infof("Detected %s", summary.String())
+	e.LogPersister.Info(result.Render(provider.DiffRenderOptions{
+		MaskSecret:          true,
+		MaxChangedManifests: options.MaxChangedManifests,
+		Format:              format,
+	}))
+
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// saveDiffMetadata stores the structured summary of the diff into the stage metadata
+// and its rendered text into the deployment metadata to be embedded into the notifications.
+func (e *deployExecutor) saveDiffMetadata(ctx context.Context, summary provider.DiffSummary, format config.K8sDiffFormat) {
+	if err := e.MetadataStore.Set(ctx, executor.DiffSummaryMetadataKey, summary.Render(maxDiffSummaryResources)); err != nil {
+		e.Logger.Error("failed to save diff summary to deployment metadata", zap.Error(err))
+	}
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		e.Logger.Error("failed to marshal diff summary", zap.Error(err))
+		return
+	}
+	metadata := map[string]string{
+		diffSummaryMetadataKey: string(data),
+		diffFormatMetadataKey:  string(format),
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save diff summary to metadata", zap.Error(err))
+	}
+}
+
+// saveKubectlDiffMetadata stores the output of kubectl diff into the stage metadata
+// to let the operators see exactly what will be changed in the cluster.
+func (e *deployExecutor) saveKubectlDiffMetadata(ctx context.Context, out string) {
+	if len(out) > maxKubectlDiffMetadataSize {
+		out = out[:maxKubectlDiffMetadataSize] + "\n... (truncated)"
+	}
+	metadata := map[string]string{
+		kubectlDiffMetadataKey: out,
+	}
+	if ori, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
+		for k, v := range ori {
+			if _, ok := metadata[k]; !ok {
+				metadata[k] = v
+			}
+		}
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save kubectl diff to metadata", zap.Error(err))
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func findHTTPRouteManifests(manifests []provider.Manifest, ref config.K8sResourceReference) ([]provider.Manifest, error) {
+	const (
+		gatewayAPIVersionPrefix = "gateway.networking.k8s.io/"
+		httpRouteKind           = "HTTPRoute"
+	)
+
+	if ref.Kind != "" && ref.Kind != httpRouteKind {
+		return nil, fmt.Errorf("support only %q kind for HTTPRoute reference", httpRouteKind)
+	}
+
+	var out []provider.Manifest
+	for _, m := range manifests {
+		if !strings.HasPrefix(m.Key.APIVersion, gatewayAPIVersionPrefix) {
+			continue
+		}
+		if m.Key.Kind != httpRouteKind {
+			continue
+		}
+		if ref.Name != "" && m.Key.Name != ref.Name {
+			continue
+		}
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+// generateHTTPRouteManifest splits the weight of the backendRefs for the given service
+// among the Services of variants in all rules of the given HTTPRoute.
+// The weights of the backendRefs for the other services are kept as is
+// and the rest of 100 is split by the given percentages.
+func generateHTTPRouteManifest(m provider.Manifest, serviceName string, canaryPercent, baselinePercent int) (provider.Manifest, error) {
+	// Because the loaded manifests are read-only
+	// so we duplicate them to avoid updating the shared manifests data in cache.
+	m = duplicateManifest(m, "")
+
+	spec, err := m.GetNestedMap("spec")
+	if err != nil {
+		return m, err
+	}
+	rules, _ := spec["rules"].([]interface{})
+
+	var edited int
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		refs, _ := rule["backendRefs"].([]interface{})
+
+		var (
+			serviceRef     map[string]interface{}
+			otherRefs      = make([]interface{}, 0, len(refs))
+			otherRefWeight int64
+		)
+		for _, ref := range refs {
+			b, ok := ref.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			kind, _ := b["kind"].(string)
+			name, _ := b["name"].(string)
+			if (kind == "" || kind == provider.KindService) && name == serviceName {
+				if serviceRef == nil {
+					serviceRef = b
+				}
+				continue
+			}
+			otherRefs = append(otherRefs, b)
+			otherRefWeight += backendRefWeight(b)
+		}
+		if serviceRef == nil {
+			continue
+		}
+
+		var (
+			variantsWeight = 100 - otherRefWeight
+			canaryWeight   = int64(canaryPercent) * variantsWeight / 100
+			baselineWeight = int64(baselinePercent) * variantsWeight / 100
+			primaryWeight  = variantsWeight - canaryWeight - baselineWeight
+			newRefs        = make([]interface{}, 0, len(otherRefs)+3)
+		)
+		if variantsWeight < 0 {
+			return m, fmt.Errorf("the total weight of the other backends in HTTPRoute %s exceeds 100", m.Key.Name)
+		}
+
+		newRefs = append(newRefs, variantBackendRef(serviceRef, serviceName, primaryWeight))
+		if canaryWeight > 0 {
+			newRefs = append(newRefs, variantBackendRef(serviceRef, makeSuffixedName(serviceName, canaryVariant), canaryWeight))
+		}
+		if baselineWeight > 0 {
+			newRefs = append(newRefs, variantBackendRef(serviceRef, makeSuffixedName(serviceName, baselineVariant), baselineWeight))
+		}
+		newRefs = append(newRefs, otherRefs...)
+		rule["backendRefs"] = newRefs
+		edited++
+	}
+	if edited == 0 {
+		return m, fmt.Errorf("no rule of HTTPRoute %s routes traffic to service %s", m.Key.Name, serviceName)
+	}
+
+	if err := m.SetStructuredSpec(spec); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// backendRefWeight returns the weight of the given backendRef.
+// As defined by Gateway API, the weight is 1 when not specified.
+func backendRefWeight(ref map[string]interface{}) int64 {
+	switch w := ref["weight"].(type) {
+	case int64:
+		return w
+	case float64:
+		return int64(w)
+	default:
+		return 1
+	}
+}
+
+// variantBackendRef returns a copy of the given backendRef pointing at the given service with the weight.
+func variantBackendRef(ref map[string]interface{}, serviceName string, weight int64) map[string]interface{} {
+	out := make(map[string]interface{}, len(ref)+1)
+	for k, v := range ref {
+		out[k] = v
+	}
+	out["name"] = serviceName
+	out["weight"] = weight
+	return out
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestGenerateHTTPRouteManifest(t *testing.T) {
+	testcases := []struct {
+		name        string
+		manifest    string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "split weights among variants",
+			manifest: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  parentRefs:
+  - name: gateway
+  rules:
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /
+    backendRefs:
+    - name: helloworld
+      port: 80
+    - name: legacy
+      port: 80
+      weight: 20
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /static
+    backendRefs:
+    - name: static
+      port: 80
+`,
+			expected: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  parentRefs:
+  - name: gateway
+  rules:
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /
+    backendRefs:
+    - name: helloworld
+      port: 80
+      weight: 40
+    - name: helloworld-canary
+      port: 80
+      weight: 24
+    - name: helloworld-baseline
+      port: 80
+      weight: 16
+    - name: legacy
+      port: 80
+      weight: 20
+  - matches:
+    - path:
+        type: PathPrefix
+        value: /static
+    backendRefs:
+    - name: static
+      port: 80
+`,
+		},
+		{
+			name: "no rule for the service",
+			manifest: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  rules:
+  - backendRefs:
+    - name: other
+      port: 80
+`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifest)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(manifests))
+
+			generated, err := generateHTTPRouteManifest(manifests[0], "helloworld", 30, 20)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expectedManifests, err := provider.ParseManifests(tc.expected)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(expectedManifests))
+
+			expected, err := expectedManifests[0].YamlBytes()
+			require.NoError(t, err)
+			got, err := generated.YamlBytes()
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(got))
+		})
+	}
+}
+
+func TestFindHTTPRouteManifests(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: helloworld
+---
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: first
+---
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: second
+`)
+	require.NoError(t, err)
+
+	found, err := findHTTPRouteManifests(manifests, config.K8sResourceReference{})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(found))
+	assert.Equal(t, "first", found[0].Key.Name)
+
+	found, err = findHTTPRouteManifests(manifests, config.K8sResourceReference{Name: "second"})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, "second", found[0].Key.Name)
+
+	_, err = findHTTPRouteManifests(manifests, config.K8sResourceReference{Kind: "Ingress"})
+	assert.Error(t, err)
+}
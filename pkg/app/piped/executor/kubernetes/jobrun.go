@@ -0,0 +1,231 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	jobVariant = "job"
+	// The container name of the generated Job.
+	jobContainerName = "job"
+	// The maximum length of the Job name to be usable as the job-name label of its pods.
+	maxJobNameLength = 63
+	// The length of the deployment ID used as the suffix of the Job name.
+	jobNameSuffixLength = 8
+	// How long to wait for the logs to be fully streamed after the Job has been finished.
+	jobLogsGracePeriod = 30 * time.Second
+	// The metadata key for storing the name of the applied Job.
+	jobNameMetadataKey = "job-name"
+)
+
+func (e *deployExecutor) ensureJobRun(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sJobRunStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	name := makeJobName(options.Name, e.Deployment.ApplicationName, e.Deployment.Id)
+
+	var (
+		job provider.Manifest
+		err error
+	)
+	if options.Manifest != "" {
+		path := filepath.Join(e.appDir, options.Manifest)
+		e.LogPersister.Infof("Loading Job manifest from %s", options.Manifest)
+		job, err = loadJobManifest(path, name)
+	} else {
+		e.LogPersister.Infof("Generating Job manifest to run %s", options.Container.Image)
+		job, err = generateJobManifest(name, *options)
+	}
+	if err != nil {
+		e.LogPersister.Errorf("Unable to prepare the Job manifest (%v)", err)
+		e.ReportError(executor.NewUserError("unable to prepare the job manifest: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	manifests := []provider.Manifest{job}
+	e.commonMetadata.apply(manifests)
+	addBuiltinAnnontations(
+		manifests,
+		jobVariant,
+		e.commit,
+		e.PipedConfig.PipedID,
+		e.Deployment.ApplicationId,
+	)
+	// The Job is not a part of the application manifests in Git.
+	job.AddAnnotations(map[string]string{
+		provider.LabelIgnoreDriftDirection: provider.IgnoreDriftDetectionTrue,
+	})
+
+	if err := applyManifests(ctx, e.provider, manifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.saveJobMetadata(ctx, job.Key.Name)
+
+	e.LogPersister.Infof("Waiting for Job %s to be finished", job.Key.Name)
+	if err := e.runJob(ctx, job.Key); err != nil {
+		if errors.Is(err, provider.ErrJobFailed) {
+			e.LogPersister.Errorf("Job %s failed (%v)", job.Key.Name, err)
+			e.ReportError(executor.NewUserError("job %s failed: %w", job.Key.Name, err))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Errorf("Unable to wait for Job %s to be finished (%v)", job.Key.Name, err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Job %s has been completed successfully", job.Key.Name)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// runJob streams the logs of the given Job into the stage log
+// while waiting for it to be finished.
+// The failure of streaming is only logged since the logs are not
+// essential to determine the result of the Job.
+func (e *deployExecutor) runJob(ctx context.Context, key provider.ResourceKey) error {
+	logsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := e.provider.StreamLogs(logsCtx, key, e.LogPersister); err != nil && logsCtx.Err() == nil {
+			e.LogPersister.Infof("Unable to stream the logs of Job %s (%v)", key.Name, err)
+		}
+	}()
+
+	err := e.provider.WaitForJob(ctx, key)
+
+	select {
+	case <-done:
+	case <-time.After(jobLogsGracePeriod):
+		cancel()
+		<-done
+	}
+	return err
+}
+
+func (e *deployExecutor) saveJobMetadata(ctx context.Context, name string) {
+	metadata := map[string]string{}
+	if m, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
+		for k, v := range m {
+			metadata[k] = v
+		}
+	}
+	metadata[jobNameMetadataKey] = name
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.LogPersister.Errorf("Unable to save the Job name to the stage metadata (%v)", err)
+	}
+}
+
+// makeJobName returns a name unique to the given deployment
+// since a Job cannot be updated once it has been created.
+func makeJobName(name, appName, deploymentID string) string {
+	if name == "" {
+		name = appName + "-job"
+	}
+	suffix := deploymentID
+	if len(suffix) > jobNameSuffixLength {
+		suffix = suffix[:jobNameSuffixLength]
+	}
+	suffix = sanitizeName(suffix)
+
+	name = sanitizeName(name)
+	if max := maxJobNameLength - len(suffix) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-")
+	}
+	return makeSuffixedName(name, suffix)
+}
+
+// loadJobManifest loads the Job manifest from the given file
+// and renames it to the given name.
+func loadJobManifest(path, name string) (provider.Manifest, error) {
+	manifests, err := provider.LoadManifestsFromYAMLFile(path)
+	if err != nil {
+		return provider.Manifest{}, err
+	}
+	if len(manifests) != 1 {
+		return provider.Manifest{}, fmt.Errorf("the manifest file must contain exactly one manifest, got %d", len(manifests))
+	}
+	m := manifests[0]
+	if m.Key.Kind != provider.KindJob {
+		return provider.Manifest{}, fmt.Errorf("the manifest must be a Job, got %s", m.Key.Kind)
+	}
+	return m.Duplicate(name), nil
+}
+
+// generateJobManifest generates the Job running the container specified in the given options.
+func generateJobManifest(name string, opts config.K8sJobRunStageOptions) (provider.Manifest, error) {
+	c := opts.Container
+	envNames := make([]string, 0, len(c.Env))
+	for k := range c.Env {
+		envNames = append(envNames, k)
+	}
+	sort.Strings(envNames)
+	env := make([]corev1.EnvVar, 0, len(envNames))
+	for _, k := range envNames {
+		env = append(env, corev1.EnvVar{Name: k, Value: c.Env[k]})
+	}
+
+	backoffLimit := int32(opts.BackoffLimit)
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       provider.KindJob,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:    jobContainerName,
+							Image:   c.Image,
+							Command: c.Command,
+							Args:    c.Args,
+							Env:     env,
+						},
+					},
+				},
+			},
+		},
+	}
+	return provider.ParseFromStructuredObject(job)
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestMakeJobName(t *testing.T) {
+	testcases := []struct {
+		name         string
+		jobName      string
+		appName      string
+		deploymentID string
+		expected     string
+	}{
+		{
+			name:         "default name",
+			appName:      "simple",
+			deploymentID: "0d4e5a7c-3f2b-4c1d-9e8f-7a6b5c4d3e2f",
+			expected:     "simple-job-0d4e5a7c",
+		},
+		{
+			name:         "specified name",
+			jobName:      "migrate",
+			appName:      "simple",
+			deploymentID: "0d4e5a7c-3f2b-4c1d-9e8f-7a6b5c4d3e2f",
+			expected:     "migrate-0d4e5a7c",
+		},
+		{
+			name:         "sanitized name",
+			appName:      "Simple_App",
+			deploymentID: "ABC",
+			expected:     "simple-app-job-abc",
+		},
+		{
+			name:         "too long name",
+			jobName:      strings.Repeat("a", 70),
+			deploymentID: "0d4e5a7c-3f2b-4c1d-9e8f-7a6b5c4d3e2f",
+			expected:     strings.Repeat("a", 54) + "-0d4e5a7c",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := makeJobName(tc.jobName, tc.appName, tc.deploymentID)
+			assert.Equal(t, tc.expected, got)
+			assert.LessOrEqual(t, len(got), maxJobNameLength)
+		})
+	}
+}
+
+func TestLoadJobManifest(t *testing.T) {
+	job, err := loadJobManifest("testdata/job.yaml", "migrate-0d4e5a7c")
+	require.NoError(t, err)
+	assert.Equal(t, provider.KindJob, job.Key.Kind)
+	assert.Equal(t, "migrate-0d4e5a7c", job.Key.Name)
+
+	_, err = loadJobManifest("testdata/services.yaml", "migrate-0d4e5a7c")
+	assert.Error(t, err)
+}
+
+func TestGenerateJobManifest(t *testing.T) {
+	opts := config.K8sJobRunStageOptions{
+		BackoffLimit: 2,
+		Container: &config.K8sJobContainer{
+			Image:   "gcr.io/pipecd/migrate:v0.1.0",
+			Command: []string{"/bin/migrate"},
+			Args:    []string{"up"},
+			Env: map[string]string{
+				"DB_NAME": "app",
+				"DB_HOST": "db.example.com",
+			},
+		},
+	}
+	job, err := generateJobManifest("migrate-0d4e5a7c", opts)
+	require.NoError(t, err)
+
+	expected := `apiVersion: batch/v1
+kind: Job
+metadata:
+  creationTimestamp: null
+  name: migrate-0d4e5a7c
+spec:
+  backoffLimit: 2
+  template:
+    metadata:
+      creationTimestamp: null
+    spec:
+      containers:
+      - args:
+        - up
+        command:
+        - /bin/migrate
+        env:
+        - name: DB_HOST
+          value: db.example.com
+        - name: DB_NAME
+          value: app
+        image: gcr.io/pipecd/migrate:v0.1.0
+        name: job
+        resources: {}
+      restartPolicy: Never
+status: {}
+`
+	data, err := job.YamlBytes()
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+	assert.Equal(t, provider.KindJob, job.Key.Kind)
+	assert.Equal(t, "migrate-0d4e5a7c", job.Key.Name)
+}
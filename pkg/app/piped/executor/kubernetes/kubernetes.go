@@ -26,7 +26,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -41,8 +43,15 @@ type deployExecutor struct {
 	executor.Input
 
 	commit    string
+	appDir    string
 	deployCfg *config.KubernetesDeploymentSpec
 	provider  provider.Provider
+	// Used to run the analyses between the steps of canary rollout.
+	newAnalysisExecutor executor.Factory
+	// Available only while executing the preview stages.
+	previewArgs previewArgs
+	// The labels and annotations added to all applying manifests.
+	commonMetadata commonMetadata
 }
 
 type registerer interface {
@@ -55,6 +64,11 @@ func Register(r registerer) {
 	f := func(in executor.Input) executor.Executor {
 		return &deployExecutor{
 			Input: in,
+			newAnalysisExecutor: func(in executor.Input) executor.Executor {
+				return &analysis.Executor{
+					Input: in,
+				}
+			},
 		}
 	}
 
@@ -65,6 +79,12 @@ func Register(r registerer) {
 	r.Register(model.StageK8sBaselineRollout, f)
 	r.Register(model.StageK8sBaselineClean, f)
 	r.Register(model.StageK8sTrafficRouting, f)
+	r.Register(model.StageK8sDiff, f)
+	r.Register(model.StageK8sValidate, f)
+	r.Register(model.StageK8sVerifyImages, f)
+	r.Register(model.StageK8sPreviewRollout, f)
+	r.Register(model.StageK8sPreviewClean, f)
+	r.Register(model.StageK8sJobRun, f)
 
 	r.RegisterRollback(model.ApplicationKind_KUBERNETES, func(in executor.Input) executor.Executor {
 		return &rollbackExecutor{
@@ -80,12 +100,22 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
 	if err != nil {
 		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	e.appDir = ds.AppDir
 	e.deployCfg = ds.DeploymentConfig.KubernetesDeploymentSpec
 	if e.deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing KubernetesDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing KubernetesDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.commonMetadata, err = renderCommonMetadata(e.deployCfg.CommonMetadata, e.Deployment)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to render the common metadata (%v)", err)
+		e.ReportError(executor.NewUserError("unable to render the common metadata: %w", err))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -93,15 +123,71 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		chartRepoName := e.deployCfg.Input.HelmChart.Repository
 		if chartRepoName != "" {
 			e.deployCfg.Input.HelmChart.Insecure = e.PipedConfig.IsInsecureChartRepository(chartRepoName)
+			e.deployCfg.Input.HelmChart.OCIRegistry = e.PipedConfig.GetOCIChartRegistry(chartRepoName)
+		}
+	}
+
+	if overlay := e.deployCfg.Input.StageKustomizeOverlay(e.Stage.Name); overlay != e.deployCfg.Input.KustomizeOverlay {
+		// Copy the deployment configuration to not affect the other stages.
+		cfg := *e.deployCfg
+		cfg.Input.KustomizeOverlay = overlay
+		e.deployCfg = &cfg
+		// The manifests built from the stage overlay must not be shared with the other stages.
+		e.AppManifestsCache = nil
+		e.LogPersister.Infof("Using kustomize overlay %s for this stage", overlay)
+	}
+
+	if e.deployCfg.Input.ServerSideApply == nil && e.IsFeatureEnabled(config.FeatureK8sServerSideApply) {
+		cfg := *e.deployCfg
+		cfg.Input.ServerSideApply = &config.K8sServerSideApply{}
+		e.deployCfg = &cfg
+		e.LogPersister.Infof("Applying manifests with server-side apply since %s feature is enabled", config.FeatureK8sServerSideApply)
+	}
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageK8sPreviewRollout, model.StageK8sPreviewClean:
+		if err := e.preparePreview(); err != nil {
+			e.LogPersister.Errorf("Unable to determine the preview namespace (%v)", err)
+			e.ReportError(executor.NewUserError("unable to determine the preview namespace: %w", err))
+			return model.StageStatus_STAGE_FAILURE
 		}
+		e.LogPersister.Infof("Using %q namespace for the preview environment", e.previewArgs.Namespace)
 	}
 
 	e.provider = provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, e.deployCfg.Input, e.Logger)
+	var multiCluster *multiClusterProvider
+	if mc := e.deployCfg.Input.MultiCluster; mc != nil {
+		newProvider := func(cluster config.CloudProviderKubernetesConfig) provider.Provider {
+			return provider.NewProviderForCluster(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, e.deployCfg.Input, cluster, e.Logger)
+		}
+		multiCluster, err = newMultiClusterProvider(e.provider, mc, e.PipedConfig, newProvider)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to prepare the target clusters (%v)", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.provider = multiCluster
+		e.LogPersister.Infof("Manifests will be applied to %d clusters: %s", len(mc.CloudProviders), strings.Join(mc.CloudProviders, ", "))
+	}
 	e.Logger.Info("start executing kubernetes stage",
 		zap.String("stage-name", e.Stage.Name),
 		zap.String("app-dir", ds.AppDir),
 	)
 
+	// Acquire the lock before changing the cluster to not race with the other pipeds sharing it.
+	if l := e.deployCfg.Input.Lock; l != nil {
+		if _, ok := lockFreeStages[model.Stage(e.Stage.Name)]; !ok {
+			lock := newDeploymentLock(e.provider, l, e.deployCfg.Input.Namespace, lockHolder(e.PipedConfig.PipedID, e.Deployment.Id), e.Logger)
+			e.LogPersister.Infof("Acquiring lock %s in namespace %s", lock.key.Name, lock.key.Namespace)
+			if err := lock.acquire(ctx, l.WaitTimeout.Duration(), e.LogPersister); err != nil {
+				e.LogPersister.Errorf("Unable to acquire the lock (%v)", err)
+				return model.StageStatus_STAGE_FAILURE
+			}
+			e.LogPersister.Successf("Successfully acquired lock %s", lock.key.Name)
+			defer lock.release(e.LogPersister)
+		}
+	}
+
 	var (
 		originalStatus = e.Stage.Status
 		status         model.StageStatus
@@ -115,7 +201,7 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 		status = e.ensurePrimaryRollout(ctx)
 
 	case model.StageK8sCanaryRollout:
-		status = e.ensureCanaryRollout(ctx)
+		status = e.ensureCanaryRollout(sig)
 
 	case model.StageK8sCanaryClean:
 		status = e.ensureCanaryClean(ctx)
@@ -129,11 +215,33 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	case model.StageK8sTrafficRouting:
 		status = e.ensureTrafficRouting(ctx)
 
+	case model.StageK8sDiff:
+		status = e.ensureDiff(ctx)
+
+	case model.StageK8sValidate:
+		status = e.ensureValidate(ctx)
+
+	case model.StageK8sVerifyImages:
+		status = e.ensureVerifyImages(ctx)
+
+	case model.StageK8sPreviewRollout:
+		status = e.ensurePreviewRollout(ctx)
+
+	case model.StageK8sPreviewClean:
+		status = e.ensurePreviewClean(ctx)
+
+	case model.StageK8sJobRun:
+		status = e.ensureJobRun(ctx)
+
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for kubernetes application", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if multiCluster != nil {
+		multiCluster.reportStatuses(ctx, e.Input)
+	}
+
 	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
 }
 
@@ -166,6 +274,30 @@ func (e *deployExecutor) loadRunningManifests(ctx context.Context) (manifests []
 	return loadManifests(ctx, e.Deployment.ApplicationId, commit, e.AppManifestsCache, loader, e.Logger)
 }
 
+// loadManifestsWithHelmValues renders the manifests of the given deploy source
+// with the given helm values merged on top of the application ones.
+// The rendered manifests are not cached since they are specific to a stage.
+func (e *deployExecutor) loadManifestsWithHelmValues(ctx context.Context, dsp deploysource.Provider, values *config.K8sHelmValueOverrides) ([]provider.Manifest, error) {
+	ds, err := dsp.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare deploy source (%v)", err)
+		return nil, err
+	}
+
+	input := e.deployCfg.Input
+	input.HelmOptions = input.HelmOptions.WithOverrides(values)
+
+	loader := provider.NewManifestLoader(
+		e.Deployment.ApplicationName,
+		ds.AppDir,
+		ds.RepoDir,
+		e.Deployment.GitPath.ConfigFilename,
+		input,
+		e.Logger,
+	)
+	return loader.LoadManifests(ctx)
+}
+
 type manifestsLoadFunc struct {
 	loadFunc func(context.Context) ([]provider.Manifest, error)
 }
@@ -174,7 +306,12 @@ func (l *manifestsLoadFunc) LoadManifests(ctx context.Context) ([]provider.Manif
 	return l.loadFunc(ctx)
 }
 
+// loadManifests loads the manifests at the given commit by using the cache if available.
+// A nil manifestsCache means the manifests should always be loaded by the loader.
 func loadManifests(ctx context.Context, appID, commit string, manifestsCache cache.Cache, loader provider.ManifestLoader, logger *zap.Logger) (manifests []provider.Manifest, err error) {
+	if manifestsCache == nil {
+		return loader.LoadManifests(ctx)
+	}
 	cache := provider.AppManifestsCache{
 		AppID:  appID,
 		Cache:  manifestsCache,
@@ -214,17 +351,156 @@ func applyManifests(ctx context.Context, applier provider.Applier, manifests []p
 	} else {
 		lp.Infof("Start applying %d manifests to %q namespace", len(manifests), namespace)
 	}
+	waves, err := groupManifestsByWave(manifests)
+	if err != nil {
+		lp.Errorf("Unable to determine the order to apply manifests (%v)", err)
+		return err
+	}
+	for _, w := range waves {
+		if len(waves) > 1 {
+			lp.Infof("Start applying %d manifests of sync wave %d", len(w.manifests), w.wave)
+		}
+		var crds []provider.ResourceKey
+		for _, m := range w.manifests {
+			// The custom resources must not be applied until their definitions have been established.
+			if len(crds) > 0 && m.Key.Kind != provider.KindCustomResourceDefinition {
+				if err := waitForCRDsEstablished(ctx, applier, crds, lp); err != nil {
+					return err
+				}
+				crds = nil
+			}
+			if err := applier.ApplyManifest(ctx, m); err != nil {
+				lp.Errorf("Failed to apply manifest: %s (%v)", m.Key.ReadableString(), err)
+				return err
+			}
+			lp.Successf("- applied manifest: %s", m.Key.ReadableString())
+			if m.Key.Kind == provider.KindCustomResourceDefinition {
+				crds = append(crds, m.Key)
+			}
+		}
+		// Also wait before going to the next wave which may contain their custom resources.
+		if len(crds) > 0 {
+			if err := waitForCRDsEstablished(ctx, applier, crds, lp); err != nil {
+				return err
+			}
+		}
+	}
+	lp.Successf("Successfully applied %d manifests", len(manifests))
+	return nil
+}
+
+// defaultWaitForReadyKinds is the list of workload kinds waited for by default.
+var defaultWaitForReadyKinds = []string{
+	provider.KindDeployment,
+	provider.KindStatefulSet,
+	provider.KindDaemonSet,
+}
+
+// waitForReady blocks until the rollout of all workloads of the configured kinds
+// in the given manifests has been completed, or the configured timeout is exceeded.
+func waitForReady(ctx context.Context, applier provider.Applier, manifests []provider.Manifest, cfg *config.K8sWaitForReady, lp executor.LogPersister) error {
+	kinds := cfg.Kinds
+	if len(kinds) == 0 {
+		kinds = defaultWaitForReadyKinds
+	}
+	targets := make([]provider.Manifest, 0, len(manifests))
 	for _, m := range manifests {
-		if err := applier.ApplyManifest(ctx, m); err != nil {
-			lp.Errorf("Failed to apply manifest: %s (%v)", m.Key.ReadableString(), err)
+		for _, k := range kinds {
+			if m.Key.Kind == k {
+				targets = append(targets, m)
+				break
+			}
+		}
+	}
+	if len(targets) == 0 {
+		lp.Info("There are no workloads to wait for")
+		return nil
+	}
+
+	if timeout := cfg.Timeout.Duration(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		lp.Infof("Waiting for %d workloads to be ready (timeout: %v)", len(targets), timeout)
+	} else {
+		lp.Infof("Waiting for %d workloads to be ready", len(targets))
+	}
+
+	for _, m := range targets {
+		if err := applier.WaitForRollout(ctx, m.Key); err != nil {
+			lp.Errorf("- workload %s did not become ready (%v)", m.Key.ReadableString(), err)
 			return err
 		}
-		lp.Successf("- applied manifest: %s", m.Key.ReadableString())
+		lp.Successf("- workload %s is ready", m.Key.ReadableString())
 	}
-	lp.Successf("Successfully applied %d manifests", len(manifests))
+	lp.Successf("All %d workloads are ready", len(targets))
 	return nil
 }
 
+// diffManifests returns the combined output of kubectl diff for the given manifests
+// and the number of manifests that will change the cluster.
+func diffManifests(ctx context.Context, applier provider.Applier, manifests []provider.Manifest, lp executor.LogPersister) (string, int, error) {
+	var (
+		b       strings.Builder
+		changed int
+	)
+	for _, m := range manifests {
+		out, err := applier.DiffManifest(ctx, m)
+		if err != nil {
+			lp.Errorf("Failed to diff manifest: %s (%v)", m.Key.ReadableString(), err)
+			return "", 0, err
+		}
+		if out == "" {
+			continue
+		}
+		changed++
+		b.WriteString(out)
+	}
+	return b.String(), changed, nil
+}
+
+// defaultPruneProtectedKinds is the list of resource kinds never removed while pruning by default
+// because removing them may cause losing data or other resources.
+var defaultPruneProtectedKinds = []string{
+	provider.KindNamespace,
+	provider.KindPersistentVolume,
+	provider.KindPersistentVolumeClaim,
+	provider.KindCustomResourceDefinition,
+}
+
+// excludeProtectedResources returns the resources whose kinds are not protected from pruning.
+func excludeProtectedResources(resources []provider.ResourceKey, protectedKinds []string, lp executor.LogPersister) []provider.ResourceKey {
+	if len(protectedKinds) == 0 {
+		protectedKinds = defaultPruneProtectedKinds
+	}
+	protected := make(map[string]struct{}, len(protectedKinds))
+	for _, k := range protectedKinds {
+		protected[k] = struct{}{}
+	}
+
+	out := make([]provider.ResourceKey, 0, len(resources))
+	for _, k := range resources {
+		if _, ok := protected[k.Kind]; ok {
+			lp.Infof("- resource %s was not removed because its kind is protected from pruning", k.ReadableString())
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// filterManagedByPiped returns the manifests marked as being managed by piped.
+func filterManagedByPiped(manifests []provider.Manifest) []provider.Manifest {
+	out := make([]provider.Manifest, 0, len(manifests))
+	for _, m := range manifests {
+		if m.GetAnnotations()[provider.LabelManagedBy] != provider.ManagedByPiped {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
 func deleteResources(ctx context.Context, applier provider.Applier, resources []provider.ResourceKey, lp executor.LogPersister) error {
 	resourcesLen := len(resources)
 	if resourcesLen == 0 {
@@ -312,6 +588,25 @@ func findWorkloadManifests(manifests []provider.Manifest, refs []config.K8sResou
 	return workloads
 }
 
+// excludeManifests returns the manifests that are not included in the excludes list.
+func excludeManifests(manifests, excludes []provider.Manifest) []provider.Manifest {
+	if len(excludes) == 0 {
+		return manifests
+	}
+	keys := make(map[provider.ResourceKey]struct{}, len(excludes))
+	for _, m := range excludes {
+		keys[m.Key] = struct{}{}
+	}
+	out := make([]provider.Manifest, 0, len(manifests))
+	for _, m := range manifests {
+		if _, ok := keys[m.Key]; ok {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
 func duplicateManifests(manifests []provider.Manifest, nameSuffix string) []provider.Manifest {
 	out := make([]provider.Manifest, 0, len(manifests))
 	for _, m := range manifests {
@@ -26,19 +26,10 @@ import (
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/pipe-cd/pipe/pkg/config"
 )
 
-type fakeLogPersister struct{}
-
-func (l *fakeLogPersister) Write(_ []byte) (int, error)         { return 0, nil }
-func (l *fakeLogPersister) Info(_ string)                       {}
-func (l *fakeLogPersister) Infof(_ string, _ ...interface{})    {}
-func (l *fakeLogPersister) Success(_ string)                    {}
-func (l *fakeLogPersister) Successf(_ string, _ ...interface{}) {}
-func (l *fakeLogPersister) Error(_ string)                      {}
-func (l *fakeLogPersister) Errorf(_ string, _ ...interface{})   {}
-
 type fakeMetadataStore struct{}
 
 func (m *fakeMetadataStore) Get(_ string) (string, bool)                         { return "", false }
@@ -320,7 +311,7 @@ func TestDeleteResources(t *testing.T) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			err := deleteResources(ctx, tc.provider, tc.resources, &fakeLogPersister{})
+			err := deleteResources(ctx, tc.provider, tc.resources, &executortest.FakeLogPersister{})
 			assert.Equal(t, tc.wantErr, err != nil)
 		})
 	}
@@ -804,3 +795,88 @@ func TestPatchManifests(t *testing.T) {
 		})
 	}
 }
+
+func TestExcludeManifests(t *testing.T) {
+	var (
+		deployment = provider.Manifest{
+			Key: provider.ResourceKey{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "foo",
+			},
+		}
+		service = provider.Manifest{
+			Key: provider.ResourceKey{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Name:       "foo",
+			},
+		}
+	)
+	testcases := []struct {
+		name      string
+		manifests []provider.Manifest
+		excludes  []provider.Manifest
+		want      []provider.Manifest
+	}{
+		{
+			name:      "nothing to exclude",
+			manifests: []provider.Manifest{deployment, service},
+			want:      []provider.Manifest{deployment, service},
+		},
+		{
+			name:      "exclude one manifest",
+			manifests: []provider.Manifest{deployment, service},
+			excludes:  []provider.Manifest{service},
+			want:      []provider.Manifest{deployment},
+		},
+		{
+			name:      "exclude all manifests",
+			manifests: []provider.Manifest{deployment, service},
+			excludes:  []provider.Manifest{deployment, service},
+			want:      []provider.Manifest{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := excludeManifests(tc.manifests, tc.excludes)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExcludeProtectedResources(t *testing.T) {
+	var (
+		deployment = provider.ResourceKey{APIVersion: "apps/v1", Kind: provider.KindDeployment, Name: "foo"}
+		pvc        = provider.ResourceKey{APIVersion: "v1", Kind: provider.KindPersistentVolumeClaim, Name: "foo"}
+		namespace  = provider.ResourceKey{APIVersion: "v1", Kind: provider.KindNamespace, Name: "foo"}
+	)
+	testcases := []struct {
+		name           string
+		resources      []provider.ResourceKey
+		protectedKinds []string
+		want           []provider.ResourceKey
+	}{
+		{
+			name:      "default protected kinds",
+			resources: []provider.ResourceKey{deployment, pvc, namespace},
+			want:      []provider.ResourceKey{deployment},
+		},
+		{
+			name:           "configured protected kinds",
+			resources:      []provider.ResourceKey{deployment, pvc, namespace},
+			protectedKinds: []string{provider.KindDeployment},
+			want:           []provider.ResourceKey{pvc, namespace},
+		},
+		{
+			name: "no resource",
+			want: []provider.ResourceKey{},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := excludeProtectedResources(tc.resources, tc.protectedKinds, &executortest.FakeLogPersister{})
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
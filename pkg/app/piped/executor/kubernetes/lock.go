@@ -0,0 +1,159 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// How often to retry acquiring the lock held by another deployment.
+	lockRetryInterval = 5 * time.Second
+	// How long to wait for releasing the lock after the stage has been finished.
+	lockReleaseTimeout = 30 * time.Second
+)
+
+// lockFreeStages are the stages not changing the cluster
+// so they can be executed without acquiring the lock.
+var lockFreeStages = map[model.Stage]struct{}{
+	model.StageK8sDiff:         {},
+	model.StageK8sValidate:     {},
+	model.StageK8sVerifyImages: {},
+}
+
+// deploymentLock is a lock backed by a Lease object in the cluster.
+// It is held by a deployment while running its stages changing the cluster
+// so that the other pipeds sharing the cluster do not touch the same resources at the same time.
+type deploymentLock struct {
+	applier  provider.Applier
+	key      provider.ResourceKey
+	holder   string
+	duration time.Duration
+	// How often to retry acquiring the lock held by another deployment.
+	retryInterval time.Duration
+	logger        *zap.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newDeploymentLock(applier provider.Applier, cfg *config.K8sDeploymentLock, namespace, holder string, logger *zap.Logger) *deploymentLock {
+	if cfg.Namespace != "" {
+		namespace = cfg.Namespace
+	}
+	if namespace == "" {
+		namespace = provider.DefaultNamespace
+	}
+	return &deploymentLock{
+		applier:       applier,
+		key:           provider.MakeLeaseKey(namespace, cfg.Name),
+		holder:        holder,
+		duration:      cfg.LeaseDuration.Duration(),
+		retryInterval: lockRetryInterval,
+		logger:        logger.With(zap.String("lock", cfg.Name), zap.String("namespace", namespace)),
+	}
+}
+
+// lockHolder returns the identity of the given deployment holding the lock.
+// Since the lock is re-acquired by every stage, it is unique to the deployment instead of the stage.
+func lockHolder(pipedID, deploymentID string) string {
+	return fmt.Sprintf("%s/%s", pipedID, deploymentID)
+}
+
+// acquire blocks until the lock has been acquired, the given timeout was exceeded or the context is done.
+// Zero timeout means waiting until the context is done.
+// Once acquired, the lock keeps being renewed in background until release is called.
+func (l *deploymentLock) acquire(ctx context.Context, timeout time.Duration, lp executor.LogPersister) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		err := l.applier.TryAcquireLease(ctx, l.key, l.holder, l.duration)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, provider.ErrLeaseHeld) {
+			return fmt.Errorf("failed to acquire lock %s: %w", l.key.Name, err)
+		}
+		lp.Infof("Waiting for lock %s in namespace %s (%v)", l.key.Name, l.key.Namespace, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to acquire lock %s: %w", l.key.Name, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	renewCtx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		l.renew(renewCtx)
+	}()
+	return nil
+}
+
+// renew keeps renewing the acquired lock until the context is done.
+// The lock is renewed three times within its duration to tolerate temporary failures.
+func (l *deploymentLock) renew(ctx context.Context) {
+	ticker := time.NewTicker(l.duration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := l.applier.TryAcquireLease(ctx, l.key, l.holder, l.duration); err != nil {
+			l.logger.Error("failed to renew the deployment lock", zap.Error(err))
+		}
+	}
+}
+
+// release stops renewing the lock and releases it.
+func (l *deploymentLock) release(lp executor.LogPersister) {
+	if l.cancel != nil {
+		l.cancel()
+		l.wg.Wait()
+	}
+
+	// The stage context may have been cancelled already.
+	ctx, cancel := context.WithTimeout(context.Background(), lockReleaseTimeout)
+	defer cancel()
+	if err := l.applier.ReleaseLease(ctx, l.key, l.holder); err != nil {
+		lp.Errorf("Failed to release lock %s, it will be released after %v (%v)", l.key.Name, l.duration, err)
+		return
+	}
+	lp.Infof("Released lock %s", l.key.Name)
+}
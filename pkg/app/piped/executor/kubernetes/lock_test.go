@@ -0,0 +1,113 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestNewDeploymentLock(t *testing.T) {
+	testcases := []struct {
+		name      string
+		cfg       config.K8sDeploymentLock
+		namespace string
+		expected  provider.ResourceKey
+	}{
+		{
+			name:     "default namespace",
+			cfg:      config.K8sDeploymentLock{Name: "pipecd-lock"},
+			expected: provider.MakeLeaseKey("default", "pipecd-lock"),
+		},
+		{
+			name:      "application namespace",
+			cfg:       config.K8sDeploymentLock{Name: "pipecd-lock"},
+			namespace: "shared",
+			expected:  provider.MakeLeaseKey("shared", "pipecd-lock"),
+		},
+		{
+			name:      "configured namespace",
+			cfg:       config.K8sDeploymentLock{Name: "pipecd-lock", Namespace: "locks"},
+			namespace: "shared",
+			expected:  provider.MakeLeaseKey("locks", "pipecd-lock"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := newDeploymentLock(nil, &tc.cfg, tc.namespace, "piped/deployment", zap.NewNop())
+			assert.Equal(t, tc.expected, l.key)
+		})
+	}
+}
+
+func TestDeploymentLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	key := provider.MakeLeaseKey("default", "pipecd-lock")
+	newLock := func(p provider.Provider) *deploymentLock {
+		return &deploymentLock{
+			applier:       p,
+			key:           key,
+			holder:        "piped/deployment",
+			duration:      time.Minute,
+			retryInterval: time.Millisecond,
+			logger:        zap.NewNop(),
+		}
+	}
+
+	t.Run("acquired after being released by another holder", func(t *testing.T) {
+		p := providertest.NewMockProvider(ctrl)
+		gomock.InOrder(
+			p.EXPECT().TryAcquireLease(gomock.Any(), key, "piped/deployment", time.Minute).Return(fmt.Errorf("%w: held by other", provider.ErrLeaseHeld)).Times(2),
+			p.EXPECT().TryAcquireLease(gomock.Any(), key, "piped/deployment", time.Minute).Return(nil),
+			p.EXPECT().ReleaseLease(gomock.Any(), key, "piped/deployment").Return(nil),
+		)
+
+		l := newLock(p)
+		require.NoError(t, l.acquire(context.Background(), 0, &executortest.FakeLogPersister{}))
+		l.release(&executortest.FakeLogPersister{})
+	})
+
+	t.Run("timed out while waiting", func(t *testing.T) {
+		p := providertest.NewMockProvider(ctrl)
+		p.EXPECT().TryAcquireLease(gomock.Any(), key, "piped/deployment", time.Minute).Return(provider.ErrLeaseHeld).MinTimes(1)
+
+		l := newLock(p)
+		err := l.acquire(context.Background(), 20*time.Millisecond, &executortest.FakeLogPersister{})
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+
+	t.Run("unexpected error", func(t *testing.T) {
+		p := providertest.NewMockProvider(ctrl)
+		p.EXPECT().TryAcquireLease(gomock.Any(), key, "piped/deployment", time.Minute).Return(errors.New("forbidden"))
+
+		l := newLock(p)
+		assert.Error(t, l.acquire(context.Background(), 0, &executortest.FakeLogPersister{}))
+	})
+}
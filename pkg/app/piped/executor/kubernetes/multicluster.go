@@ -0,0 +1,358 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	clusterStatusesMetadataKey = "cluster-statuses"
+
+	clusterStatusSuccess = "SUCCESS"
+	clusterStatusFailure = "FAILURE"
+	clusterStatusSkipped = "SKIPPED"
+)
+
+type cluster struct {
+	name    string
+	applier provider.Applier
+}
+
+// multiClusterProvider loads manifests once and applies them to all of the configured clusters.
+// The result of each cluster is recorded to be reported after the stage.
+type multiClusterProvider struct {
+	provider.ManifestLoader
+	clusters []cluster
+	parallel bool
+
+	mu       sync.Mutex
+	statuses map[string]string
+}
+
+// newMultiClusterProvider returns a provider for all Kubernetes cloud providers configured in the given config.
+func newMultiClusterProvider(loader provider.ManifestLoader, cfg *config.K8sMultiCluster, pipedCfg *config.PipedSpec, newProvider func(config.CloudProviderKubernetesConfig) provider.Provider) (*multiClusterProvider, error) {
+	p := &multiClusterProvider{
+		ManifestLoader: loader,
+		clusters:       make([]cluster, 0, len(cfg.CloudProviders)),
+		parallel:       cfg.Parallel,
+		statuses:       make(map[string]string, len(cfg.CloudProviders)),
+	}
+	for _, name := range cfg.CloudProviders {
+		cp, ok := pipedCfg.FindCloudProvider(name, model.CloudProviderKubernetes)
+		if !ok {
+			return nil, executor.NewUserError("kubernetes cloud provider %s was not found in the piped configuration", name)
+		}
+		var clusterCfg config.CloudProviderKubernetesConfig
+		if cp.KubernetesConfig != nil {
+			clusterCfg = *cp.KubernetesConfig
+		}
+		p.clusters = append(p.clusters, cluster{
+			name:    name,
+			applier: newProvider(clusterCfg),
+		})
+		p.statuses[name] = clusterStatusSuccess
+	}
+	return p, nil
+}
+
+func (p *multiClusterProvider) Apply(ctx context.Context) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.Apply(ctx)
+	})
+}
+
+func (p *multiClusterProvider) ApplyManifest(ctx context.Context, manifest provider.Manifest) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.ApplyManifest(ctx, manifest)
+	})
+}
+
+// Delete deletes the given resource from all clusters.
+// ErrNotFound is returned only when the resource was not found in any cluster.
+func (p *multiClusterProvider) Delete(ctx context.Context, key provider.ResourceKey) error {
+	var (
+		mu       sync.Mutex
+		notFound int
+	)
+	err := p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		err := a.Delete(ctx, key)
+		if errors.Is(err, provider.ErrNotFound) {
+			mu.Lock()
+			notFound++
+			mu.Unlock()
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if notFound == len(p.clusters) {
+		return fmt.Errorf("resource %s was not found in any cluster: %w", key.ReadableString(), provider.ErrNotFound)
+	}
+	return nil
+}
+
+// DiffManifest returns the combined diffs of all clusters
+// where each diff is prefixed by the cluster name.
+func (p *multiClusterProvider) DiffManifest(ctx context.Context, manifest provider.Manifest) (string, error) {
+	var (
+		mu    sync.Mutex
+		diffs = make(map[string]string, len(p.clusters))
+	)
+	err := p.runNamed(ctx, func(ctx context.Context, name string, a provider.Applier) error {
+		out, err := a.DiffManifest(ctx, manifest)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		diffs[name] = out
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, c := range p.clusters {
+		if out := diffs[c.name]; out != "" {
+			fmt.Fprintf(&b, "# cluster: %s\n%s", c.name, out)
+		}
+	}
+	return b.String(), nil
+}
+
+func (p *multiClusterProvider) ValidateManifest(ctx context.Context, manifest provider.Manifest) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.ValidateManifest(ctx, manifest)
+	})
+}
+
+// ListNamespaces returns the namespaces found in any cluster.
+// The ones having the same name in multiple clusters are returned once.
+func (p *multiClusterProvider) ListNamespaces(ctx context.Context, selector string) ([]provider.Manifest, error) {
+	var (
+		mu         sync.Mutex
+		namespaces = make(map[string]provider.Manifest)
+	)
+	err := p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		ns, err := a.ListNamespaces(ctx, selector)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, n := range ns {
+			namespaces[n.Key.Name] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]provider.Manifest, 0, len(namespaces))
+	for _, n := range namespaces {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key.Name < out[j].Key.Name
+	})
+	return out, nil
+}
+
+// CountPodsByArchitecture returns the sum of the pod counts of all clusters.
+func (p *multiClusterProvider) CountPodsByArchitecture(ctx context.Context, key provider.ResourceKey, selector string) (map[string]int, error) {
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]int)
+	)
+	err := p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		c, err := a.CountPodsByArchitecture(ctx, key, selector)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for arch, n := range c {
+			counts[arch] += n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+func (p *multiClusterProvider) WaitForRollout(ctx context.Context, key provider.ResourceKey) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.WaitForRollout(ctx, key)
+	})
+}
+
+func (p *multiClusterProvider) WaitForJob(ctx context.Context, key provider.ResourceKey) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.WaitForJob(ctx, key)
+	})
+}
+
+func (p *multiClusterProvider) WaitForEstablished(ctx context.Context, key provider.ResourceKey) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.WaitForEstablished(ctx, key)
+	})
+}
+
+// TryAcquireLease acquires the given Lease in all clusters one by one.
+// When failed in any cluster, the ones acquired in the other clusters are released
+// so that no partially acquired lock is left.
+// The cluster statuses are not updated because failing to acquire a lock is not a failure of the cluster.
+func (p *multiClusterProvider) TryAcquireLease(ctx context.Context, key provider.ResourceKey, holder string, duration time.Duration) error {
+	for i, c := range p.clusters {
+		if err := c.applier.TryAcquireLease(ctx, key, holder, duration); err != nil {
+			for _, a := range p.clusters[:i] {
+				if e := a.applier.ReleaseLease(ctx, key, holder); e != nil {
+					return fmt.Errorf("cluster %s: %w (failed to release the acquired one in cluster %s: %v)", c.name, err, a.name, e)
+				}
+			}
+			return fmt.Errorf("cluster %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// ReleaseLease releases the given Lease in all clusters.
+func (p *multiClusterProvider) ReleaseLease(ctx context.Context, key provider.ResourceKey, holder string) error {
+	var errs []string
+	for _, c := range p.clusters {
+		if err := c.applier.ReleaseLease(ctx, key, holder); err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", c.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to release lease: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// StreamLogs writes the logs of all clusters into the given writer.
+// The logs may be interleaved when running in parallel.
+func (p *multiClusterProvider) StreamLogs(ctx context.Context, key provider.ResourceKey, w io.Writer) error {
+	return p.run(ctx, func(ctx context.Context, a provider.Applier) error {
+		return a.StreamLogs(ctx, key, w)
+	})
+}
+
+func (p *multiClusterProvider) run(ctx context.Context, f func(context.Context, provider.Applier) error) error {
+	return p.runNamed(ctx, func(ctx context.Context, _ string, a provider.Applier) error {
+		return f(ctx, a)
+	})
+}
+
+// runNamed runs the given function for all clusters sequentially or in parallel.
+// In the sequential mode, the remaining clusters are skipped after a failure.
+func (p *multiClusterProvider) runNamed(ctx context.Context, f func(context.Context, string, provider.Applier) error) error {
+	if !p.parallel {
+		for i, c := range p.clusters {
+			if err := f(ctx, c.name, c.applier); err != nil {
+				p.setFailure(c.name)
+				for _, r := range p.clusters[i+1:] {
+					p.setSkipped(r.name)
+				}
+				return fmt.Errorf("cluster %s: %w", c.name, err)
+			}
+		}
+		return nil
+	}
+
+	var eg errgroup.Group
+	for _, c := range p.clusters {
+		c := c
+		eg.Go(func() error {
+			if err := f(ctx, c.name, c.applier); err != nil {
+				p.setFailure(c.name)
+				return fmt.Errorf("cluster %s: %w", c.name, err)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (p *multiClusterProvider) setFailure(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[name] = clusterStatusFailure
+}
+
+func (p *multiClusterProvider) setSkipped(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.statuses[name] != clusterStatusFailure {
+		p.statuses[name] = clusterStatusSkipped
+	}
+}
+
+// reportStatuses writes the status of each cluster to the stage log
+// and stores them into the stage metadata.
+func (p *multiClusterProvider) reportStatuses(ctx context.Context, in executor.Input) {
+	p.mu.Lock()
+	statuses := make(map[string]string, len(p.statuses))
+	for k, v := range p.statuses {
+		statuses[k] = v
+	}
+	p.mu.Unlock()
+
+	for _, c := range p.clusters {
+		in.LogPersister.Infof("Cluster %s: %s", c.name, statuses[c.name])
+	}
+
+	data, err := json.Marshal(statuses)
+	if err != nil {
+		in.Logger.Error("failed to marshal cluster statuses", zap.Error(err))
+		return
+	}
+	metadata := map[string]string{
+		clusterStatusesMetadataKey: string(data),
+	}
+	if ori, ok := in.MetadataStore.GetStageMetadata(in.Stage.Id); ok {
+		for k, v := range ori {
+			if _, ok := metadata[k]; !ok {
+				metadata[k] = v
+			}
+		}
+	}
+	if err := in.MetadataStore.SetStageMetadata(ctx, in.Stage.Id, metadata); err != nil {
+		in.Logger.Error("failed to save cluster statuses to metadata", zap.Error(err))
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestNewMultiClusterProvider(t *testing.T) {
+	pipedCfg := &config.PipedSpec{
+		CloudProviders: []config.PipedCloudProvider{
+			{
+				Name:             "cluster-1",
+				Type:             model.CloudProviderKubernetes,
+				KubernetesConfig: &config.CloudProviderKubernetesConfig{KubeConfigPath: "/etc/kube/cluster-1"},
+			},
+			{
+				Name:             "cluster-2",
+				Type:             model.CloudProviderKubernetes,
+				KubernetesConfig: &config.CloudProviderKubernetesConfig{KubeConfigPath: "/etc/kube/cluster-2"},
+			},
+		},
+	}
+
+	var paths []string
+	newProvider := func(c config.CloudProviderKubernetesConfig) provider.Provider {
+		paths = append(paths, c.KubeConfigPath)
+		return nil
+	}
+
+	p, err := newMultiClusterProvider(nil, &config.K8sMultiCluster{CloudProviders: []string{"cluster-2", "cluster-1"}}, pipedCfg, newProvider)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(p.clusters))
+	assert.Equal(t, []string{"/etc/kube/cluster-2", "/etc/kube/cluster-1"}, paths)
+
+	_, err = newMultiClusterProvider(nil, &config.K8sMultiCluster{CloudProviders: []string{"cluster-1", "cluster-3"}}, pipedCfg, newProvider)
+	assert.Error(t, err)
+}
+
+func TestMultiClusterProviderApply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testcases := []struct {
+		name             string
+		parallel         bool
+		results          []error
+		expectedStatuses map[string]string
+		wantErr          bool
+	}{
+		{
+			name:     "all clusters succeeded",
+			parallel: false,
+			results:  []error{nil, nil, nil},
+			expectedStatuses: map[string]string{
+				"cluster-0": clusterStatusSuccess,
+				"cluster-1": clusterStatusSuccess,
+				"cluster-2": clusterStatusSuccess,
+			},
+		},
+		{
+			name:     "remaining clusters are skipped after a failure",
+			parallel: false,
+			results:  []error{nil, fmt.Errorf("error"), nil},
+			expectedStatuses: map[string]string{
+				"cluster-0": clusterStatusSuccess,
+				"cluster-1": clusterStatusFailure,
+				"cluster-2": clusterStatusSkipped,
+			},
+			wantErr: true,
+		},
+		{
+			name:     "all clusters are handled in parallel",
+			parallel: true,
+			results:  []error{fmt.Errorf("error"), nil, nil},
+			expectedStatuses: map[string]string{
+				"cluster-0": clusterStatusFailure,
+				"cluster-1": clusterStatusSuccess,
+				"cluster-2": clusterStatusSuccess,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &multiClusterProvider{
+				parallel: tc.parallel,
+				statuses: make(map[string]string, len(tc.results)),
+			}
+			for i, r := range tc.results {
+				name := fmt.Sprintf("cluster-%d", i)
+				a := providertest.NewMockProvider(ctrl)
+				if tc.expectedStatuses[name] != clusterStatusSkipped {
+					a.EXPECT().Apply(gomock.Any()).Return(r)
+				}
+				p.clusters = append(p.clusters, cluster{name: name, applier: a})
+				p.statuses[name] = clusterStatusSuccess
+			}
+
+			err := p.Apply(context.Background())
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.expectedStatuses, p.statuses)
+		})
+	}
+}
+
+func TestMultiClusterProviderDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	key := provider.ResourceKey{
+		APIVersion: "apps/v1",
+		Kind:       provider.KindDeployment,
+		Namespace:  "default",
+		Name:       "simple",
+	}
+	newProvider := func(results ...error) *multiClusterProvider {
+		p := &multiClusterProvider{
+			statuses: make(map[string]string, len(results)),
+		}
+		for i, r := range results {
+			a := providertest.NewMockProvider(ctrl)
+			a.EXPECT().Delete(gomock.Any(), key).Return(r)
+			p.clusters = append(p.clusters, cluster{name: fmt.Sprintf("cluster-%d", i), applier: a})
+		}
+		return p
+	}
+
+	err := newProvider(provider.ErrNotFound, nil).Delete(context.Background(), key)
+	assert.NoError(t, err)
+
+	err = newProvider(provider.ErrNotFound, provider.ErrNotFound).Delete(context.Background(), key)
+	assert.True(t, errors.Is(err, provider.ErrNotFound))
+}
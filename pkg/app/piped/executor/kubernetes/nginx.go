@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	nginxCanaryAnnotation       = "nginx.ingress.kubernetes.io/canary"
+	nginxCanaryWeightAnnotation = "nginx.ingress.kubernetes.io/canary-weight"
+
+	nginxCanaryByHeaderAnnotation      = "nginx.ingress.kubernetes.io/canary-by-header"
+	nginxCanaryByHeaderValueAnnotation = "nginx.ingress.kubernetes.io/canary-by-header-value"
+)
+
+// updateNginxCanaryIngress routes the given percentage of traffic to CANARY variant
+// by applying a canary Ingress, a copy of the given Ingress pointing at the CANARY Service,
+// with the canary annotations of NGINX Ingress Controller.
+// The requests having the header of the given route are also routed to CANARY variant if specified.
+// The canary Ingress is removed when no traffic should be routed to CANARY variant.
+// The result is logged and false is returned if failed.
+func (e *deployExecutor) updateNginxCanaryIngress(ctx context.Context, manifests []provider.Manifest, ingress provider.Manifest, canaryPercent, baselinePercent int, headerRoute *trafficrouting.HeaderRoute) bool {
+	if baselinePercent > 0 {
+		e.LogPersister.Errorf("Traffic routing by NGINX Ingress does not support BASELINE variant (baseline=%d)", baselinePercent)
+		return false
+	}
+
+	if canaryPercent == 0 && headerRoute == nil {
+		key := duplicateManifest(ingress, canaryVariant).Key
+		e.LogPersister.Infof("Start removing the canary Ingress %s to route all traffic to PRIMARY variant", key.ReadableString())
+		if err := deleteResources(ctx, e.provider, []provider.ResourceKey{key}, e.LogPersister); err != nil {
+			e.ReportError(err)
+			return false
+		}
+		return true
+	}
+
+	nginxCfg := e.deployCfg.TrafficRouting.Nginx
+	if nginxCfg == nil {
+		nginxCfg = &config.NginxTrafficRouting{}
+	}
+	serviceName := findServiceName(manifests, e.deployCfg.Service.Name)
+	if serviceName == "" {
+		e.LogPersister.Error("Unable to find any service manifests for traffic routing by NGINX Ingress")
+		return false
+	}
+	canaryServiceName := nginxCfg.CanaryService
+	if canaryServiceName == "" {
+		canaryServiceName = makeSuffixedName(serviceName, canaryVariant)
+	}
+
+	canaryIngress, err := generateNginxCanaryIngressManifest(ingress, serviceName, canaryServiceName, canaryPercent)
+	if err != nil {
+		e.LogPersister.Errorf("Unable generate canary Ingress manifest: (%v)", err)
+		return false
+	}
+	if headerRoute != nil {
+		e.LogPersister.Infof("Requests having the header %s will be routed to CANARY variant", headerRoute)
+		canaryIngress.AddAnnotations(map[string]string{
+			nginxCanaryByHeaderAnnotation:      headerRoute.Name,
+			nginxCanaryByHeaderValueAnnotation: headerRoute.Value,
+		})
+	}
+
+	e.commonMetadata.apply([]provider.Manifest{canaryIngress})
+
+	// Add builtin annotations for tracking application live state.
+	addBuiltinAnnontations(
+		[]provider.Manifest{canaryIngress},
+		canaryVariant,
+		e.commit,
+		e.PipedConfig.PipedID,
+		e.Deployment.ApplicationId,
+	)
+
+	e.LogPersister.Infof("Start updating traffic routing to be percentages: primary=%d, canary=%d", 100-canaryPercent, canaryPercent)
+	if err := applyManifests(ctx, e.provider, []provider.Manifest{canaryIngress}, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
+		return false
+	}
+	return true
+}
+
+// generateNginxCanaryIngressManifest returns a copy of the given Ingress
+// whose backends for the given service are replaced with the canary service
+// and marked as a canary Ingress receiving the given percentage of traffic.
+// Both networking.k8s.io/v1 and the older Ingress APIs are supported.
+func generateNginxCanaryIngressManifest(ingress provider.Manifest, serviceName, canaryServiceName string, canaryPercent int) (provider.Manifest, error) {
+	// Because the loaded manifests are read-only
+	// so we duplicate them to avoid updating the shared manifests data in cache.
+	m := duplicateManifest(ingress, canaryVariant)
+
+	spec, err := m.GetSpec()
+	if err != nil {
+		return m, err
+	}
+	// The spec of the duplicated manifest is updated in place.
+	if replaceIngressBackendService(spec, serviceName, canaryServiceName) == 0 {
+		return m, fmt.Errorf("no backend of Ingress %s routes traffic to service %s", ingress.Key.Name, serviceName)
+	}
+
+	m.AddAnnotations(map[string]string{
+		nginxCanaryAnnotation:       "true",
+		nginxCanaryWeightAnnotation: strconv.Itoa(canaryPercent),
+	})
+	return m, nil
+}
+
+// replaceIngressBackendService replaces the service name of all backends
+// found in the given unstructured Ingress spec and returns the number of replaced ones.
+func replaceIngressBackendService(obj interface{}, from, to string) int {
+	var count int
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		// networking.k8s.io/v1beta1 and extensions/v1beta1.
+		if name, ok := o["serviceName"].(string); ok && name == from {
+			o["serviceName"] = to
+			count++
+		}
+		// networking.k8s.io/v1.
+		if service, ok := o["service"].(map[string]interface{}); ok {
+			if name, ok := service["name"].(string); ok && name == from {
+				service["name"] = to
+				count++
+			}
+		}
+		for k, v := range o {
+			if k == "service" {
+				continue
+			}
+			count += replaceIngressBackendService(v, from, to)
+		}
+	case []interface{}:
+		for _, v := range o {
+			count += replaceIngressBackendService(v, from, to)
+		}
+	}
+	return count
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestGenerateNginxCanaryIngressManifest(t *testing.T) {
+	testcases := []struct {
+		name        string
+		manifest    string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "networking.k8s.io/v1",
+			manifest: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: helloworld
+spec:
+  rules:
+  - host: helloworld.example.com
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: helloworld
+            port:
+              number: 80
+      - path: /static
+        pathType: Prefix
+        backend:
+          service:
+            name: static
+            port:
+              number: 80
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: helloworld-canary
+  annotations:
+    nginx.ingress.kubernetes.io/canary: "true"
+    nginx.ingress.kubernetes.io/canary-weight: "20"
+spec:
+  rules:
+  - host: helloworld.example.com
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: helloworld-canary
+            port:
+              number: 80
+      - path: /static
+        pathType: Prefix
+        backend:
+          service:
+            name: static
+            port:
+              number: 80
+`,
+		},
+		{
+			name: "networking.k8s.io/v1beta1",
+			manifest: `
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: helloworld
+spec:
+  backend:
+    serviceName: helloworld
+    servicePort: 80
+`,
+			expected: `
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: helloworld-canary
+  annotations:
+    nginx.ingress.kubernetes.io/canary: "true"
+    nginx.ingress.kubernetes.io/canary-weight: "20"
+spec:
+  backend:
+    serviceName: helloworld-canary
+    servicePort: 80
+`,
+		},
+		{
+			name: "no backend for the service",
+			manifest: `
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: helloworld
+spec:
+  defaultBackend:
+    service:
+      name: other
+      port:
+        number: 80
+`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifest)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(manifests))
+
+			generated, err := generateNginxCanaryIngressManifest(manifests[0], "helloworld", "helloworld-canary", 20)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expectedManifests, err := provider.ParseManifests(tc.expected)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(expectedManifests))
+
+			expected, err := expectedManifests[0].YamlBytes()
+			require.NoError(t, err)
+			got, err := generated.YamlBytes()
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(got))
+
+			// The original manifest must not be changed.
+			assert.Equal(t, "helloworld", manifests[0].Key.Name)
+			assert.Empty(t, manifests[0].GetAnnotations())
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// applyNodePlacement injects the given scheduling constraints
+// into the pod template of the given workload manifests.
+func applyNodePlacement(workloads []provider.Manifest, placement *config.K8sNodePlacement) ([]provider.Manifest, error) {
+	if placement == nil {
+		return workloads, nil
+	}
+
+	var affinity *corev1.Affinity
+	if len(placement.Affinity) > 0 {
+		affinity = &corev1.Affinity{}
+		if err := json.Unmarshal(placement.Affinity, affinity); err != nil {
+			return nil, fmt.Errorf("invalid affinity of node placement: %w", err)
+		}
+	}
+
+	tolerations := make([]corev1.Toleration, 0, len(placement.Tolerations))
+	for _, t := range placement.Tolerations {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:               t.Key,
+			Operator:          corev1.TolerationOperator(t.Operator),
+			Value:             t.Value,
+			Effect:            corev1.TaintEffect(t.Effect),
+			TolerationSeconds: t.TolerationSeconds,
+		})
+	}
+
+	updatePodSpec := func(spec *corev1.PodSpec) {
+		if len(placement.NodeSelector) > 0 {
+			if spec.NodeSelector == nil {
+				spec.NodeSelector = make(map[string]string, len(placement.NodeSelector))
+			}
+			for k, v := range placement.NodeSelector {
+				spec.NodeSelector[k] = v
+			}
+		}
+		spec.Tolerations = append(spec.Tolerations, tolerations...)
+		if affinity != nil {
+			spec.Affinity = affinity.DeepCopy()
+		}
+	}
+
+	manifests := make([]provider.Manifest, 0, len(workloads))
+	for _, m := range workloads {
+		switch m.Key.Kind {
+		case provider.KindDeployment:
+			d := &appsv1.Deployment{}
+			if err := m.ConvertToStructuredObject(d); err != nil {
+				return nil, err
+			}
+			updatePodSpec(&d.Spec.Template.Spec)
+			manifest, err := provider.ParseFromStructuredObject(d)
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, manifest)
+
+		default:
+			return nil, fmt.Errorf("unsupported workload kind %s", m.Key.Kind)
+		}
+	}
+	return manifests, nil
+}
+
+// The label given to the workloads generated for each CPU architecture
+// to prevent their selectors from overlapping with each other.
+const architectureLabel = "pipecd.dev/architecture"
+
+// pinArchitecture places the pods of the given workload manifests
+// on the nodes of the given CPU architecture.
+func pinArchitecture(workloads []provider.Manifest, arch string) ([]provider.Manifest, error) {
+	return applyNodePlacement(workloads, &config.K8sNodePlacement{
+		NodeSelector: map[string]string{corev1.LabelArchStable: arch},
+	})
+}
+
+// primaryPodSelector returns the label selector matching the pods of PRIMARY variant of the given workload.
+// The pods of the other variants are excluded explicitly since they also have the labels of the original workload.
+func primaryPodSelector(workload provider.Manifest) (string, error) {
+	matchLabels, err := workload.GetNestedStringMap("spec", "selector", "matchLabels")
+	if err != nil {
+		return "", err
+	}
+	requirements := make([]string, 0, len(matchLabels)+2)
+	for k, v := range matchLabels {
+		if k == variantLabel {
+			continue
+		}
+		requirements = append(requirements, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(requirements)
+	requirements = append(requirements,
+		fmt.Sprintf("%s!=%s", variantLabel, canaryVariant),
+		fmt.Sprintf("%s!=%s", variantLabel, baselineVariant),
+	)
+	return strings.Join(requirements, ","), nil
+}
+
+// mirrorArchitectures splits the given CANARY workload into one workload per CPU architecture
+// so that its pods are spread over the architectures in the same ratio as the given PRIMARY pods.
+// A workload is generated even for the architecture receiving no replica
+// to keep the names of the generated workloads stable while changing the replicas.
+func mirrorArchitectures(workload provider.Manifest, primaryPods map[string]int) ([]provider.Manifest, error) {
+	if len(primaryPods) == 0 {
+		return []provider.Manifest{workload}, nil
+	}
+	if workload.Key.Kind != provider.KindDeployment {
+		return nil, fmt.Errorf("unsupported workload kind %s", workload.Key.Kind)
+	}
+
+	d := &appsv1.Deployment{}
+	if err := workload.ConvertToStructuredObject(d); err != nil {
+		return nil, err
+	}
+	var replicas int32 = 1
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	allocations := distributeReplicas(replicas, primaryPods)
+	archs := make([]string, 0, len(allocations))
+	for arch := range allocations {
+		archs = append(archs, arch)
+	}
+	sort.Strings(archs)
+
+	manifests := make([]provider.Manifest, 0, len(archs))
+	for _, arch := range archs {
+		ad := d.DeepCopy()
+		ad.Name = makeSuffixedName(d.Name, arch)
+		n := allocations[arch]
+		ad.Spec.Replicas = &n
+		ad.Spec.Selector = metav1.AddLabelToSelector(ad.Spec.Selector, architectureLabel, arch)
+		if ad.Spec.Template.Labels == nil {
+			ad.Spec.Template.Labels = map[string]string{}
+		}
+		ad.Spec.Template.Labels[architectureLabel] = arch
+		if ad.Spec.Template.Spec.NodeSelector == nil {
+			ad.Spec.Template.Spec.NodeSelector = map[string]string{}
+		}
+		ad.Spec.Template.Spec.NodeSelector[corev1.LabelArchStable] = arch
+
+		manifest, err := provider.ParseFromStructuredObject(ad)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// distributeReplicas allocates the given number of replicas to the architectures
+// proportionally to the given pod counts by using the largest remainder method.
+func distributeReplicas(replicas int32, counts map[string]int) map[string]int32 {
+	var total int
+	archs := make([]string, 0, len(counts))
+	for arch, n := range counts {
+		total += n
+		archs = append(archs, arch)
+	}
+	sort.Strings(archs)
+
+	allocations := make(map[string]int32, len(counts))
+	if total == 0 {
+		return allocations
+	}
+
+	remainders := make(map[string]int, len(counts))
+	var allocated int32
+	for _, arch := range archs {
+		product := int(replicas) * counts[arch]
+		allocations[arch] = int32(product / total)
+		remainders[arch] = product % total
+		allocated += allocations[arch]
+	}
+
+	// Give the rest one by one to the architectures having larger remainders.
+	sort.SliceStable(archs, func(i, j int) bool {
+		return remainders[archs[i]] > remainders[archs[j]]
+	})
+	for i := 0; allocated < replicas; i++ {
+		allocations[archs[i%len(archs)]]++
+		allocated++
+	}
+	return allocations
+}
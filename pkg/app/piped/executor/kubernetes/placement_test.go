@@ -0,0 +1,212 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestApplyNodePlacement(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple-canary
+spec:
+  template:
+    spec:
+      nodeSelector:
+        kubernetes.io/os: linux
+      tolerations:
+      - key: dedicated
+        operator: Exists
+      containers:
+      - name: helloworld
+        image: gcr.io/pipecd/helloworld:v0.1.0
+`)
+	require.NoError(t, err)
+
+	seconds := int64(30)
+	placement := &config.K8sNodePlacement{
+		NodeSelector: map[string]string{
+			"cloud.google.com/gke-spot": "true",
+		},
+		Tolerations: []config.K8sToleration{
+			{
+				Key:               "cloud.google.com/gke-spot",
+				Operator:          "Equal",
+				Value:             "true",
+				Effect:            "NoSchedule",
+				TolerationSeconds: &seconds,
+			},
+		},
+		Affinity: json.RawMessage(`{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"pool","operator":"In","values":["spot"]}]}]}}}`),
+	}
+
+	got, err := applyNodePlacement(manifests, placement)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(got))
+
+	d := &appsv1.Deployment{}
+	require.NoError(t, got[0].ConvertToStructuredObject(d))
+	spec := d.Spec.Template.Spec
+
+	assert.Equal(t, map[string]string{
+		"kubernetes.io/os":          "linux",
+		"cloud.google.com/gke-spot": "true",
+	}, spec.NodeSelector)
+	assert.Equal(t, []corev1.Toleration{
+		{
+			Key:      "dedicated",
+			Operator: corev1.TolerationOpExists,
+		},
+		{
+			Key:               "cloud.google.com/gke-spot",
+			Operator:          corev1.TolerationOpEqual,
+			Value:             "true",
+			Effect:            corev1.TaintEffectNoSchedule,
+			TolerationSeconds: &seconds,
+		},
+	}, spec.Tolerations)
+	require.NotNil(t, spec.Affinity)
+	require.NotNil(t, spec.Affinity.NodeAffinity)
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	require.Equal(t, 1, len(terms))
+	assert.Equal(t, []string{"spot"}, terms[0].MatchExpressions[0].Values)
+
+	// The original manifests must not be changed.
+	original := &appsv1.Deployment{}
+	require.NoError(t, manifests[0].ConvertToStructuredObject(original))
+	assert.Equal(t, 1, len(original.Spec.Template.Spec.Tolerations))
+	assert.Nil(t, original.Spec.Template.Spec.Affinity)
+
+	_, err = applyNodePlacement(manifests, &config.K8sNodePlacement{Affinity: json.RawMessage(`"invalid"`)})
+	assert.Error(t, err)
+}
+
+func TestPrimaryPodSelector(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  selector:
+    matchLabels:
+      app: simple
+      pipecd.dev/variant: primary
+      tier: web
+`)
+	require.NoError(t, err)
+
+	got, err := primaryPodSelector(manifests[0])
+	require.NoError(t, err)
+	assert.Equal(t, "app=simple,tier=web,pipecd.dev/variant!=canary,pipecd.dev/variant!=baseline", got)
+}
+
+func TestMirrorArchitectures(t *testing.T) {
+	manifests, err := provider.ParseManifests(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: simple-canary
+spec:
+  replicas: 3
+  selector:
+    matchLabels:
+      app: simple
+      pipecd.dev/variant: canary
+  template:
+    metadata:
+      labels:
+        app: simple
+        pipecd.dev/variant: canary
+    spec:
+      containers:
+      - name: helloworld
+        image: gcr.io/pipecd/helloworld:v0.1.0
+`)
+	require.NoError(t, err)
+
+	got, err := mirrorArchitectures(manifests[0], map[string]int{"amd64": 8, "arm64": 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(got))
+
+	expected := map[string]int32{"amd64": 2, "arm64": 1}
+	for i, arch := range []string{"amd64", "arm64"} {
+		d := &appsv1.Deployment{}
+		require.NoError(t, got[i].ConvertToStructuredObject(d))
+		assert.Equal(t, "simple-canary-"+arch, d.Name)
+		assert.Equal(t, expected[arch], *d.Spec.Replicas)
+		assert.Equal(t, arch, d.Spec.Selector.MatchLabels[architectureLabel])
+		assert.Equal(t, "canary", d.Spec.Selector.MatchLabels[variantLabel])
+		assert.Equal(t, arch, d.Spec.Template.Labels[architectureLabel])
+		assert.Equal(t, map[string]string{corev1.LabelArchStable: arch}, d.Spec.Template.Spec.NodeSelector)
+	}
+
+	// The workload is not changed when no PRIMARY pod is running.
+	got, err = mirrorArchitectures(manifests[0], map[string]int{})
+	require.NoError(t, err)
+	assert.Equal(t, manifests, got)
+}
+
+func TestDistributeReplicas(t *testing.T) {
+	testcases := []struct {
+		name     string
+		replicas int32
+		counts   map[string]int
+		expected map[string]int32
+	}{
+		{
+			name:     "single architecture",
+			replicas: 2,
+			counts:   map[string]int{"arm64": 5},
+			expected: map[string]int32{"arm64": 2},
+		},
+		{
+			name:     "divisible",
+			replicas: 4,
+			counts:   map[string]int{"amd64": 6, "arm64": 2},
+			expected: map[string]int32{"amd64": 3, "arm64": 1},
+		},
+		{
+			name:     "largest remainder",
+			replicas: 3,
+			counts:   map[string]int{"amd64": 5, "arm64": 5},
+			expected: map[string]int32{"amd64": 2, "arm64": 1},
+		},
+		{
+			name:     "fewer replicas than architectures",
+			replicas: 1,
+			counts:   map[string]int{"amd64": 1, "arm64": 3},
+			expected: map[string]int32{"amd64": 0, "arm64": 1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := distributeReplicas(tc.replicas, tc.counts)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	// The labels added to the preview namespaces to find them later.
+	previewOfLabel  = "pipecd.dev/preview-of"  // The application the preview environment belongs to.
+	previewKeyLabel = "pipecd.dev/preview-key" // The branch or pull request the preview environment was deployed for.
+	// The unix time after which the preview environment can be removed.
+	previewExpiresAtAnnotation = "pipecd.dev/preview-expires-at"
+
+	previewNamespaceMetadataKey = "preview-namespace"
+	previewURLMetadataKey       = "preview-url"
+
+	maxNamespaceLength = 63
+)
+
+var invalidNamespaceChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// previewArgs allows the branch or pull request specific data
+// to be embedded in the templates of the preview namespace and URL.
+type previewArgs struct {
+	App         previewAppArgs
+	Branch      string
+	PullRequest int64
+	CommitHash  string
+	// "pr-<number>" for pull requests, otherwise the branch name.
+	Key string
+	// The generated namespace. Available only in the URL template.
+	Namespace string
+}
+
+type previewAppArgs struct {
+	Name string
+}
+
+func (e *deployExecutor) newPreviewArgs() previewArgs {
+	commit := e.Deployment.Trigger.Commit
+	key := commit.Branch
+	if commit.PullRequest > 0 {
+		key = fmt.Sprintf("pr-%d", commit.PullRequest)
+	}
+	return previewArgs{
+		App: previewAppArgs{
+			Name: e.Deployment.ApplicationName,
+		},
+		Branch:      commit.Branch,
+		PullRequest: commit.PullRequest,
+		CommitHash:  commit.Hash,
+		Key:         sanitizeName(key),
+	}
+}
+
+// preparePreview makes the current stage deploy into the preview namespace
+// generated for the triggered branch or pull request.
+func (e *deployExecutor) preparePreview() error {
+	preview := e.deployCfg.Preview
+	if preview == nil {
+		preview = &config.K8sPreview{}
+	}
+
+	args := e.newPreviewArgs()
+	namespace, err := renderTemplate("namespace", preview.NamespaceTemplate(), args)
+	if err != nil {
+		return err
+	}
+	namespace = sanitizeName(namespace)
+	if namespace == "" {
+		return fmt.Errorf("the preview namespace rendered from %q was empty", preview.NamespaceTemplate())
+	}
+
+	// Copy the deployment configuration to not affect the other stages.
+	cfg := *e.deployCfg
+	cfg.Input.Namespace = namespace
+	cfg.Preview = preview
+	e.deployCfg = &cfg
+	// The manifests rendered for the preview namespace must not be shared with the other stages.
+	e.AppManifestsCache = nil
+	e.previewArgs = args
+	e.previewArgs.Namespace = namespace
+	return nil
+}
+
+func (e *deployExecutor) ensurePreviewRollout(ctx context.Context) model.StageStatus {
+	namespace := e.previewArgs.Namespace
+
+	// Load the manifests at the triggered commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	if len(manifests) == 0 {
+		e.LogPersister.Error("This application has no Kubernetes manifests to handle")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	previewManifests := make([]provider.Manifest, 0, len(manifests)+1)
+	previewManifests = append(previewManifests, e.generatePreviewNamespaceManifest(namespace, time.Now()))
+	for _, m := range manifests {
+		// The namespace of preview environment is generated by piped.
+		if m.Key.Kind == provider.KindNamespace {
+			continue
+		}
+		previewManifests = append(previewManifests, m.DuplicateWithNamespace(namespace))
+	}
+	e.commonMetadata.apply(previewManifests)
+
+	// Add the annotations for tracking the preview resources.
+	// The application annotation is not added to keep them away from the live state of the application.
+	for _, m := range previewManifests {
+		m.AddAnnotations(map[string]string{
+			provider.LabelManagedBy:          provider.ManagedByPiped,
+			provider.LabelPiped:              e.PipedConfig.PipedID,
+			previewOfLabel:                   e.Deployment.ApplicationId,
+			provider.LabelOriginalAPIVersion: m.Key.APIVersion,
+			provider.LabelResourceKey:        m.Key.String(),
+			provider.LabelCommitHash:         e.commit,
+		})
+	}
+
+	if err := applyManifests(ctx, e.provider, previewManifests, namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	url, err := renderTemplate("url", e.deployCfg.Preview.URL, e.previewArgs)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to render the preview URL (%v)", err)
+		e.ReportError(executor.NewUserError("unable to render the preview URL: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.savePreviewMetadata(ctx, namespace, url)
+
+	e.removeStalePreviews(ctx, namespace, time.Now())
+
+	if url != "" {
+		e.LogPersister.Successf("Successfully rolled out the preview environment into %q namespace. It is available at %s", namespace, url)
+	} else {
+		e.LogPersister.Successf("Successfully rolled out the preview environment into %q namespace", namespace)
+	}
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) ensurePreviewClean(ctx context.Context) model.StageStatus {
+	namespace := e.previewArgs.Namespace
+	e.LogPersister.Infof("Start removing the preview environment in %q namespace", namespace)
+
+	err := e.provider.Delete(ctx, previewNamespaceKey(namespace))
+	if errors.Is(err, provider.ErrNotFound) {
+		e.LogPersister.Infof("The preview environment in %q namespace was already removed", namespace)
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	if err != nil {
+		e.LogPersister.Errorf("Unable to remove the preview environment (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully removed the preview environment in %q namespace", namespace)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+func (e *deployExecutor) generatePreviewNamespaceManifest(namespace string, now time.Time) provider.Manifest {
+	metadata := map[string]interface{}{
+		"name": namespace,
+		"labels": map[string]interface{}{
+			previewOfLabel:  e.Deployment.ApplicationId,
+			previewKeyLabel: e.previewArgs.Key,
+		},
+	}
+	if ttl := e.deployCfg.Preview.TTL.Duration(); ttl > 0 {
+		metadata["annotations"] = map[string]interface{}{
+			previewExpiresAtAnnotation: strconv.FormatInt(now.Add(ttl).Unix(), 10),
+		}
+	}
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       provider.KindNamespace,
+			"metadata":   metadata,
+		},
+	}
+	return provider.MakeManifest(previewNamespaceKey(namespace), u)
+}
+
+// removeStalePreviews removes the preview environments of this application
+// which were superseded by the given one or have been expired.
+// The failures are only logged since they must not fail the current rollout.
+func (e *deployExecutor) removeStalePreviews(ctx context.Context, current string, now time.Time) {
+	selector := fmt.Sprintf("%s=%s", previewOfLabel, e.Deployment.ApplicationId)
+	namespaces, err := e.provider.ListNamespaces(ctx, selector)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to list the preview environments to remove the stale ones (%v)", err)
+		return
+	}
+
+	for _, ns := range findStalePreviews(namespaces, current, e.previewArgs.Key, now) {
+		if err := e.provider.Delete(ctx, ns.Key); err != nil && !errors.Is(err, provider.ErrNotFound) {
+			e.LogPersister.Errorf("Unable to remove the stale preview environment in %q namespace (%v)", ns.Key.Name, err)
+			continue
+		}
+		e.LogPersister.Infof("- removed the stale preview environment in %q namespace", ns.Key.Name)
+	}
+}
+
+// findStalePreviews returns the preview namespaces deployed for the same key with the current one,
+// or having been expired.
+func findStalePreviews(namespaces []provider.Manifest, current, key string, now time.Time) []provider.Manifest {
+	var stale []provider.Manifest
+	for _, ns := range namespaces {
+		if ns.Key.Name == current {
+			continue
+		}
+		if ns.GetLabels()[previewKeyLabel] == key {
+			stale = append(stale, ns)
+			continue
+		}
+		v, ok := ns.GetAnnotations()[previewExpiresAtAnnotation]
+		if !ok {
+			continue
+		}
+		expiresAt, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		if now.Unix() >= expiresAt {
+			stale = append(stale, ns)
+		}
+	}
+	return stale
+}
+
+func (e *deployExecutor) savePreviewMetadata(ctx context.Context, namespace, url string) {
+	metadata := map[string]string{
+		previewNamespaceMetadataKey: namespace,
+	}
+	if url != "" {
+		metadata[previewURLMetadataKey] = url
+	}
+	if ori, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
+		for k, v := range ori {
+			if _, ok := metadata[k]; !ok {
+				metadata[k] = v
+			}
+		}
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to save preview metadata", zap.Error(err))
+	}
+}
+
+func previewNamespaceKey(namespace string) provider.ResourceKey {
+	return provider.ResourceKey{
+		APIVersion: "v1",
+		Kind:       provider.KindNamespace,
+		Name:       namespace,
+	}
+}
+
+func renderTemplate(name, text string, args interface{}) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, args); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// sanitizeName converts the given string to be usable as
+// a namespace name and a label value.
+func sanitizeName(s string) string {
+	s = invalidNamespaceChars.ReplaceAllString(strings.ToLower(s), "-")
+	s = strings.Trim(s, "-")
+	if len(s) > maxNamespaceLength {
+		s = strings.TrimRight(s[:maxNamespaceLength], "-")
+	}
+	return s
+}
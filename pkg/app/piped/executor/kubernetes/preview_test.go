@@ -0,0 +1,185 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestSanitizeName(t *testing.T) {
+	testcases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "valid name",
+			input:    "simple-pr-10",
+			expected: "simple-pr-10",
+		},
+		{
+			name:     "branch name containing invalid characters",
+			input:    "Feature/Add_New-API",
+			expected: "feature-add-new-api",
+		},
+		{
+			name:     "leading and trailing invalid characters",
+			input:    "--/feature/-",
+			expected: "feature",
+		},
+		{
+			name:     "too long name",
+			input:    strings.Repeat("a", 62) + "-b",
+			expected: strings.Repeat("a", 62),
+		},
+		{
+			name:     "no valid character",
+			input:    "//",
+			expected: "",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, sanitizeName(tc.input))
+		})
+	}
+}
+
+func TestPreparePreview(t *testing.T) {
+	testcases := []struct {
+		name              string
+		commit            *model.Commit
+		preview           *config.K8sPreview
+		expectedNamespace string
+		expectedKey       string
+		wantErr           bool
+	}{
+		{
+			name: "default namespace for pull request",
+			commit: &model.Commit{
+				Branch:      "feature/foo",
+				PullRequest: 10,
+			},
+			expectedNamespace: "simple-pr-10",
+			expectedKey:       "pr-10",
+		},
+		{
+			name: "default namespace for branch",
+			commit: &model.Commit{
+				Branch: "feature/foo",
+			},
+			preview:           &config.K8sPreview{},
+			expectedNamespace: "simple-feature-foo",
+			expectedKey:       "feature-foo",
+		},
+		{
+			name: "custom namespace",
+			commit: &model.Commit{
+				Branch: "Feature/Foo",
+			},
+			preview: &config.K8sPreview{
+				Namespace: "preview-{{ .Branch }}",
+			},
+			expectedNamespace: "preview-feature-foo",
+			expectedKey:       "feature-foo",
+		},
+		{
+			name: "empty namespace",
+			commit: &model.Commit{
+				Branch: "feature/foo",
+			},
+			preview: &config.K8sPreview{
+				Namespace: "{{ if .PullRequest }}preview-{{ .Key }}{{ end }}",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &deployExecutor{
+				Input: executor.Input{
+					Deployment: &model.Deployment{
+						ApplicationName: "simple",
+						Trigger: &model.DeploymentTrigger{
+							Commit: tc.commit,
+						},
+					},
+				},
+				deployCfg: &config.KubernetesDeploymentSpec{
+					Input: config.KubernetesDeploymentInput{
+						Namespace: "default",
+					},
+					Preview: tc.preview,
+				},
+			}
+			original := e.deployCfg
+
+			err := e.preparePreview()
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNamespace, e.previewArgs.Namespace)
+			assert.Equal(t, tc.expectedKey, e.previewArgs.Key)
+			assert.Equal(t, tc.expectedNamespace, e.deployCfg.Input.Namespace)
+			assert.Equal(t, "default", original.Input.Namespace)
+		})
+	}
+}
+
+func TestFindStalePreviews(t *testing.T) {
+	now := time.Unix(1000, 0)
+	makeNamespace := func(name, key, expiresAt string) provider.Manifest {
+		u := &unstructured.Unstructured{}
+		u.SetName(name)
+		u.SetLabels(map[string]string{
+			previewKeyLabel: key,
+		})
+		if expiresAt != "" {
+			u.SetAnnotations(map[string]string{
+				previewExpiresAtAnnotation: expiresAt,
+			})
+		}
+		return provider.MakeManifest(previewNamespaceKey(name), u)
+	}
+	namespaces := []provider.Manifest{
+		makeNamespace("simple-feature-foo", "feature-foo", "2000"),
+		makeNamespace("preview-feature-foo", "feature-foo", ""),
+		makeNamespace("simple-pr-1", "pr-1", "999"),
+		makeNamespace("simple-pr-2", "pr-2", "1000"),
+		makeNamespace("simple-pr-3", "pr-3", "1001"),
+		makeNamespace("simple-pr-4", "pr-4", ""),
+		makeNamespace("simple-pr-5", "pr-5", "invalid"),
+	}
+
+	stale := findStalePreviews(namespaces, "simple-feature-foo", "feature-foo", now)
+	names := make([]string, 0, len(stale))
+	for _, ns := range stale {
+		names = append(names, ns.Key.Name)
+	}
+	assert.Equal(t, []string{"preview-feature-foo", "simple-pr-1", "simple-pr-2"}, names)
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -32,6 +33,7 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 	options := e.StageConfig.K8sPrimaryRolloutStageOptions
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -55,21 +57,20 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 	routingMethod := config.DetermineKubernetesTrafficRoutingMethod(e.deployCfg.TrafficRouting)
 
 	switch routingMethod {
-	// In case of routing by Pod selector,
+	// In case of routing by Pod selector or NGINX Ingress,
 	// all manifests can be used as primary manifests.
-	case config.KubernetesTrafficRoutingMethodPodSelector:
+	case config.KubernetesTrafficRoutingMethodPodSelector, config.KubernetesTrafficRoutingMethodNginx:
 		primaryManifests = manifests
 
-	// In case of routing by Istio,
-	// VirtualService manifest will be used to manipulate the traffic ratio.
+	// In case of routing by Istio, SMI, Gateway API or AWS Load Balancer Controller,
+	// VirtualService, TrafficSplit, HTTPRoute or Ingress manifest will be used to manipulate the traffic ratio.
 	// Other manifests can be used as primary manifests.
-	case config.KubernetesTrafficRoutingMethodIstio:
-		// Firstly, find the VirtualService manifests.
-		istioCfg := e.deployCfg.TrafficRouting.Istio
-		if istioCfg == nil {
-			istioCfg = &config.IstioTrafficRouting{}
-		}
-		trafficRoutingManifests, err := findIstioVirtualServiceManifests(manifests, istioCfg.VirtualService)
+	case config.KubernetesTrafficRoutingMethodIstio,
+		config.KubernetesTrafficRoutingMethodSMI,
+		config.KubernetesTrafficRoutingMethodGateway,
+		config.KubernetesTrafficRoutingMethodALB:
+		// Firstly, find the traffic routing manifests.
+		trafficRoutingManifests, err := findTrafficRoutingManifests(manifests, e.deployCfg.Service.Name, e.deployCfg.TrafficRouting)
 		if err != nil {
 			e.LogPersister.Errorf("Failed while finding traffic routing manifest: (%v)", err)
 			return model.StageStatus_STAGE_FAILURE
@@ -115,6 +116,8 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 	}
 	e.LogPersister.Successf("Successfully generated %d manifests for PRIMARY variant", len(primaryManifests))
 
+	e.commonMetadata.apply(primaryManifests)
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		primaryManifests,
@@ -130,15 +133,40 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Preview the changes to the cluster before applying.
+	if options.PreviewDiff || options.DiffOnly {
+		e.LogPersister.Info("Start previewing the changes by kubectl diff")
+		out, changed, err := diffManifests(ctx, e.provider, primaryManifests, e.LogPersister)
+		if err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.saveKubectlDiffMetadata(ctx, out)
+		if changed == 0 {
+			e.LogPersister.Success("No changes will be made to the cluster")
+		} else {
+			e.LogPersister.Infof("%d manifests will change the cluster:\n%s", changed, out)
+		}
+		if options.DiffOnly {
+			e.LogPersister.Info("Applying was skipped because diffOnly was configured")
+			return model.StageStatus_STAGE_SUCCESS
+		}
+	}
+
 	// Start applying all manifests to add or update running resources.
 	e.LogPersister.Info("Start rolling out PRIMARY variant...")
 	if err := applyManifests(ctx, e.provider, primaryManifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
+	if options.WaitForReady != nil {
+		if err := waitForReady(ctx, e.provider, primaryManifests, options.WaitForReady, e.LogPersister); err != nil {
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
 	e.LogPersister.Success("Successfully rolled out PRIMARY variant")
 
-	if !options.Prune {
-		e.LogPersister.Info("Resource GC was skipped because sync.prune was not configured")
+	if !options.Prune && !e.deployCfg.Input.Prune {
+		e.LogPersister.Info("Resource GC was skipped because neither prune nor input.prune was configured")
 		return model.StageStatus_STAGE_SUCCESS
 	}
 
@@ -162,6 +190,7 @@ func (e *deployExecutor) ensurePrimaryRollout(ctx context.Context) model.StageSt
 	}
 
 	removeKeys := findRemoveManifests(runningManifests, manifests, e.deployCfg.Input.Namespace)
+	removeKeys = excludeProtectedResources(removeKeys, e.deployCfg.Input.PruneProtectedKinds, e.LogPersister)
 	if len(removeKeys) == 0 {
 		e.LogPersister.Info("There are no live resources should be removed")
 		return model.StageStatus_STAGE_SUCCESS
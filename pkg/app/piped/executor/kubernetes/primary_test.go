@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -27,6 +28,7 @@ import (
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -53,7 +55,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 						},
 					},
 					Stage:        &model.PipelineStage{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Logger:       zap.NewNop(),
 				},
 			},
@@ -68,7 +70,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{},
@@ -98,7 +100,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
@@ -129,6 +131,95 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 				deployCfg: &config.KubernetesDeploymentSpec{},
 			},
 		},
+		{
+			name: "only preview the changes by kubectl diff",
+			want: model.StageStatus_STAGE_SUCCESS,
+			executor: &deployExecutor{
+				Input: executor.Input{
+					Deployment: &model.Deployment{
+						Trigger: &model.DeploymentTrigger{
+							Commit: &model.Commit{},
+						},
+					},
+					PipedConfig:   &config.PipedSpec{},
+					LogPersister:  &executortest.FakeLogPersister{},
+					MetadataStore: &fakeMetadataStore{},
+					Stage:         &model.PipelineStage{},
+					StageConfig: config.PipelineStage{
+						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
+							DiffOnly: true,
+						},
+					},
+					AppManifestsCache: func() cache.Cache {
+						c := cachetest.NewMockCache(ctrl)
+						c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
+						c.EXPECT().Put(gomock.Any(), gomock.Any()).Return(nil)
+						return c
+					}(),
+					Logger: zap.NewNop(),
+				},
+				provider: func() provider.Provider {
+					p := providertest.NewMockProvider(ctrl)
+					p.EXPECT().LoadManifests(gomock.Any()).Return([]provider.Manifest{
+						provider.MakeManifest(provider.ResourceKey{
+							APIVersion: "apps/v1",
+							Kind:       provider.KindDeployment,
+						}, &unstructured.Unstructured{
+							Object: map[string]interface{}{"spec": map[string]interface{}{}},
+						}),
+					}, nil)
+					p.EXPECT().DiffManifest(gomock.Any(), gomock.Any()).Return("-  replicas: 1\n+  replicas: 2\n", nil)
+					p.EXPECT().ApplyManifest(gomock.Any(), gomock.Any()).Times(0)
+					return p
+				}(),
+				deployCfg: &config.KubernetesDeploymentSpec{},
+			},
+		},
+		{
+			name: "failed to wait for the workload to be ready",
+			want: model.StageStatus_STAGE_FAILURE,
+			executor: &deployExecutor{
+				Input: executor.Input{
+					Deployment: &model.Deployment{
+						Trigger: &model.DeploymentTrigger{
+							Commit: &model.Commit{},
+						},
+					},
+					PipedConfig:  &config.PipedSpec{},
+					LogPersister: &executortest.FakeLogPersister{},
+					Stage:        &model.PipelineStage{},
+					StageConfig: config.PipelineStage{
+						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
+							WaitForReady: &config.K8sWaitForReady{
+								Timeout: config.Duration(time.Minute),
+							},
+						},
+					},
+					AppManifestsCache: func() cache.Cache {
+						c := cachetest.NewMockCache(ctrl)
+						c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
+						c.EXPECT().Put(gomock.Any(), gomock.Any()).Return(nil)
+						return c
+					}(),
+					Logger: zap.NewNop(),
+				},
+				provider: func() provider.Provider {
+					p := providertest.NewMockProvider(ctrl)
+					p.EXPECT().LoadManifests(gomock.Any()).Return([]provider.Manifest{
+						provider.MakeManifest(provider.ResourceKey{
+							APIVersion: "apps/v1",
+							Kind:       provider.KindDeployment,
+						}, &unstructured.Unstructured{
+							Object: map[string]interface{}{"spec": map[string]interface{}{}},
+						}),
+					}, nil)
+					p.EXPECT().ApplyManifest(gomock.Any(), gomock.Any()).Return(nil)
+					p.EXPECT().WaitForRollout(gomock.Any(), gomock.Any()).Return(context.DeadlineExceeded)
+					return p
+				}(),
+				deployCfg: &config.KubernetesDeploymentSpec{},
+			},
+		},
 		{
 			name: "successfully apply two manifests",
 			want: model.StageStatus_STAGE_SUCCESS,
@@ -140,7 +231,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
@@ -195,7 +286,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
@@ -240,7 +331,7 @@ func TestEnsurePrimaryRollout(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					Stage:        &model.PipelineStage{},
 					StageConfig: config.PipelineStage{
 						K8sPrimaryRolloutStageOptions: &config.K8sPrimaryRolloutStageOptions{
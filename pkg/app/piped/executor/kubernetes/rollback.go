@@ -15,13 +15,14 @@
 package kubernetes
 
 import (
-	"context"
 	"strings"
 
 	"go.uber.org/zap"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -31,14 +32,13 @@ type rollbackExecutor struct {
 
 func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	var (
-		ctx            = sig.Context()
 		originalStatus = e.Stage.Status
 		status         model.StageStatus
 	)
 
 	switch model.Stage(e.Stage.Name) {
 	case model.StageRollback:
-		status = e.ensureRollback(ctx)
+		status = e.ensureRollback(sig)
 
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for kubernetes application", e.Stage.Name)
@@ -48,13 +48,20 @@ func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
 }
 
-func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+func (e *rollbackExecutor) ensureRollback(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+
 	// There is nothing to do if this is the first deployment.
 	if e.Deployment.RunningCommitHash == "" {
 		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Wait for an approval before reverting the changes if it was configured.
+	if ok, status := e.waitRollbackApproval(sig); !ok {
+		return status
+	}
+
 	ds, err := e.RunningDSP.Get(ctx, e.LogPersister)
 	if err != nil {
 		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
@@ -64,6 +71,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	deployCfg := ds.DeploymentConfig.KubernetesDeploymentSpec
 	if deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing KubernetesDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing KubernetesDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -71,15 +79,44 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		chartRepoName := deployCfg.Input.HelmChart.Repository
 		if chartRepoName != "" {
 			deployCfg.Input.HelmChart.Insecure = e.PipedConfig.IsInsecureChartRepository(chartRepoName)
+			deployCfg.Input.HelmChart.OCIRegistry = e.PipedConfig.GetOCIChartRegistry(chartRepoName)
 		}
 	}
 
-	p := provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, e.Logger)
+	if deployCfg.Input.ServerSideApply == nil && e.IsFeatureEnabled(config.FeatureK8sServerSideApply) {
+		deployCfg.Input.ServerSideApply = &config.K8sServerSideApply{}
+		e.LogPersister.Infof("Applying manifests with server-side apply since %s feature is enabled", config.FeatureK8sServerSideApply)
+	}
+
+	var p provider.Provider = provider.NewProvider(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, e.Logger)
+	if mc := deployCfg.Input.MultiCluster; mc != nil {
+		newProvider := func(cluster config.CloudProviderKubernetesConfig) provider.Provider {
+			return provider.NewProviderForCluster(e.Deployment.ApplicationName, ds.AppDir, ds.RepoDir, e.Deployment.GitPath.ConfigFilename, deployCfg.Input, cluster, e.Logger)
+		}
+		multiCluster, err := newMultiClusterProvider(p, mc, e.PipedConfig, newProvider)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to prepare the target clusters (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		defer multiCluster.reportStatuses(ctx, e.Input)
+		p = multiCluster
+	}
 	e.Logger.Info("start executing kubernetes stage",
 		zap.String("stage-name", e.Stage.Name),
 		zap.String("app-dir", ds.AppDir),
 	)
 
+	if l := deployCfg.Input.Lock; l != nil {
+		lock := newDeploymentLock(p, l, deployCfg.Input.Namespace, lockHolder(e.PipedConfig.PipedID, e.Deployment.Id), e.Logger)
+		e.LogPersister.Infof("Acquiring lock %s in namespace %s", lock.key.Name, lock.key.Namespace)
+		if err := lock.acquire(ctx, l.WaitTimeout.Duration(), e.LogPersister); err != nil {
+			e.LogPersister.Errorf("Unable to acquire the lock (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Successf("Successfully acquired lock %s", lock.key.Name)
+		defer lock.release(e.LogPersister)
+	}
+
 	// Firstly, we reapply all manifests at running commit
 	// to revert PRIMARY resources and TRAFFIC ROUTING resources.
 
@@ -108,6 +145,13 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		}
 	}
 
+	commonMetadata, err := renderCommonMetadata(deployCfg.CommonMetadata, e.Deployment)
+	if err != nil {
+		e.LogPersister.Errorf("Unable to render the common metadata (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	commonMetadata.apply(manifests)
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		manifests,
@@ -123,11 +167,54 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Separate the traffic routing manifests from the others
+	// because traffic must be routed back to PRIMARY variant
+	// only after all of its resources have been reverted.
+	trafficRoutingManifests, err := findTrafficRoutingManifests(manifests, deployCfg.Service.Name, deployCfg.TrafficRouting)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while finding traffic routing manifest: (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	primaryManifests := excludeManifests(manifests, trafficRoutingManifests)
+
 	// Start applying all manifests to add or update running resources.
-	if err := applyManifests(ctx, p, manifests, deployCfg.Input.Namespace, e.LogPersister); err != nil {
+	e.LogPersister.Info("Start rolling back PRIMARY variant to the running commit")
+	if err := applyManifests(ctx, p, primaryManifests, deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Restore the traffic routing manifests at the running commit
+	// updated to route all traffic to PRIMARY variant again.
+	if len(trafficRoutingManifests) > 0 {
+		e.LogPersister.Info("Start restoring traffic routing to send 100% of traffic to PRIMARY variant")
+		serviceName := findServiceName(manifests, deployCfg.Service.Name)
+		trafficRoutingManifest, err := generateTrafficRoutingManifest(trafficRoutingManifests[0], serviceName, 100, 0, 0, deployCfg.TrafficRouting)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to generate traffic routing manifest: (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		trafficRoutingManifests[0] = trafficRoutingManifest
+		if err := applyManifests(ctx, p, trafficRoutingManifests, deployCfg.Input.Namespace, e.LogPersister); err != nil {
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		// The canary Ingress is not a part of the manifests at the running commit
+		// so it must be removed explicitly.
+		if config.DetermineKubernetesTrafficRoutingMethod(deployCfg.TrafficRouting) == config.KubernetesTrafficRoutingMethodNginx {
+			key := duplicateManifest(trafficRoutingManifests[0], canaryVariant).Key
+			if err := deleteResources(ctx, p, []provider.ResourceKey{key}, e.LogPersister); err != nil {
+				e.ReportError(err)
+				return model.StageStatus_STAGE_FAILURE
+			}
+		}
+		weights := trafficrouting.Weights{Primary: 100}
+		saveTrafficRoutingMetadata(ctx, &e.Input, weights.Primary, weights.Canary, weights.Baseline)
+		if err := e.MetadataStore.Set(ctx, trafficWeightsMetadataKey, weights.String()); err != nil {
+			e.Logger.Error("failed to save traffic weights to metadata", zap.Error(err))
+		}
+	}
+
 	var errs []error
 
 	// Next we delete all resources of CANARY variant.
@@ -151,5 +238,24 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	if len(errs) > 0 {
 		return model.StageStatus_STAGE_FAILURE
 	}
+
+	e.LogPersister.Successf("Successfully rolled back to the running commit %s", e.Deployment.RunningCommitHash)
 	return model.StageStatus_STAGE_SUCCESS
 }
+
+func (e *rollbackExecutor) waitRollbackApproval(sig executor.StopSignal) (bool, model.StageStatus) {
+	ds, err := e.TargetDSP.GetReadOnly(sig.Context(), e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return false, model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.KubernetesDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing KubernetesDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing KubernetesDeploymentSpec"))
+		return false, model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.WaitRollbackApproval(sig, e.Input, deployCfg.Input.RollbackApproval)
+}
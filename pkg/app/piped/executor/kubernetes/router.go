@@ -0,0 +1,216 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// The deployment metadata keys storing the state of the traffic router
+	// since it must be kept across the stages.
+	trafficWeightsMetadataKey     = "traffic-weights"
+	trafficHeaderRouteMetadataKey = "traffic-header-route"
+
+	headerRouteName = "pipecd-header-route"
+)
+
+var errTrafficRoutingFailed = errors.New("failed to update traffic routing, see the logs for details")
+
+// trafficRouter implements trafficrouting.Router for Kubernetes applications
+// by applying the traffic routing manifest generated from the ones at the target commit
+// with the method specified in the deployment configuration.
+type trafficRouter struct {
+	e         *deployExecutor
+	manifests []provider.Manifest
+}
+
+func (e *deployExecutor) newTrafficRouter(manifests []provider.Manifest) trafficrouting.Router {
+	return &trafficRouter{
+		e:         e,
+		manifests: manifests,
+	}
+}
+
+func (r *trafficRouter) SetWeights(ctx context.Context, weights trafficrouting.Weights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	saveTrafficRoutingMetadata(ctx, &r.e.Input, weights.Primary, weights.Canary, weights.Baseline)
+
+	if !r.e.updateTrafficRouting(ctx, r.manifests, weights.Primary, weights.Canary, weights.Baseline) {
+		return errTrafficRoutingFailed
+	}
+	if err := r.e.MetadataStore.Set(ctx, trafficWeightsMetadataKey, weights.String()); err != nil {
+		r.e.Logger.Error("failed to save traffic weights to metadata", zap.Error(err))
+	}
+	return nil
+}
+
+// GetWeights returns the weights set by the last SetWeights of this deployment.
+// All traffic is considered to be routed to PRIMARY variant before that.
+func (r *trafficRouter) GetWeights(_ context.Context) (trafficrouting.Weights, error) {
+	value, ok := r.e.MetadataStore.Get(trafficWeightsMetadataKey)
+	if !ok {
+		return trafficrouting.Weights{Primary: 100}, nil
+	}
+	return trafficrouting.ParseWeights(value)
+}
+
+// RouteByHeader is supported by Istio, NGINX Ingress and Gateway API methods.
+// The route is kept while updating the weights until the end of the deployment.
+func (r *trafficRouter) RouteByHeader(ctx context.Context, route trafficrouting.HeaderRoute) error {
+	if err := route.Validate(); err != nil {
+		return err
+	}
+	switch config.DetermineKubernetesTrafficRoutingMethod(r.e.deployCfg.TrafficRouting) {
+	case config.KubernetesTrafficRoutingMethodIstio,
+		config.KubernetesTrafficRoutingMethodNginx,
+		config.KubernetesTrafficRoutingMethodGateway:
+		break
+	default:
+		return trafficrouting.ErrUnsupported
+	}
+
+	if err := r.e.MetadataStore.Set(ctx, trafficHeaderRouteMetadataKey, route.String()); err != nil {
+		return fmt.Errorf("failed to save the header route to metadata: %w", err)
+	}
+	weights, err := r.GetWeights(ctx)
+	if err != nil {
+		return err
+	}
+	if !r.e.updateTrafficRouting(ctx, r.manifests, weights.Primary, weights.Canary, weights.Baseline) {
+		return errTrafficRoutingFailed
+	}
+	return nil
+}
+
+// addHeaderRoute adds a route sending all requests having the given header to CANARY variant
+// into the given traffic routing manifest. The manifest is updated in place.
+func addHeaderRoute(m provider.Manifest, serviceName string, route trafficrouting.HeaderRoute, cfg *config.KubernetesTrafficRouting) (provider.Manifest, error) {
+	switch config.DetermineKubernetesTrafficRoutingMethod(cfg) {
+	case config.KubernetesTrafficRoutingMethodIstio:
+		istioConfig := cfg.Istio
+		if istioConfig == nil {
+			istioConfig = &config.IstioTrafficRouting{}
+		}
+		return addVirtualServiceHeaderRoute(m, istioConfig.Host, route)
+
+	case config.KubernetesTrafficRoutingMethodGateway:
+		return addHTTPRouteHeaderRoute(m, serviceName, route)
+
+	default:
+		return m, trafficrouting.ErrUnsupported
+	}
+}
+
+// addVirtualServiceHeaderRoute prepends an http route matching the given header
+// to the CANARY subset of the given host.
+// The unstructured spec is used to support all API versions of VirtualService.
+func addVirtualServiceHeaderRoute(m provider.Manifest, host string, route trafficrouting.HeaderRoute) (provider.Manifest, error) {
+	spec, err := m.GetNestedMap("spec")
+	if err != nil {
+		return m, err
+	}
+	routes, _ := spec["http"].([]interface{})
+
+	headerRoute := map[string]interface{}{
+		"name": headerRouteName,
+		"match": []interface{}{
+			map[string]interface{}{
+				"headers": map[string]interface{}{
+					route.Name: map[string]interface{}{
+						"exact": route.Value,
+					},
+				},
+			},
+		},
+		"route": []interface{}{
+			map[string]interface{}{
+				"destination": map[string]interface{}{
+					"host":   host,
+					"subset": canaryVariant,
+				},
+			},
+		},
+	}
+	spec["http"] = append([]interface{}{headerRoute}, routes...)
+
+	if err := m.SetStructuredSpec(spec); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// addHTTPRouteHeaderRoute prepends a rule matching the given header
+// to the CANARY Service of the given service to the given HTTPRoute.
+func addHTTPRouteHeaderRoute(m provider.Manifest, serviceName string, route trafficrouting.HeaderRoute) (provider.Manifest, error) {
+	spec, err := m.GetNestedMap("spec")
+	if err != nil {
+		return m, err
+	}
+	rules, _ := spec["rules"].([]interface{})
+
+	// Find the backendRef of the service to copy its port and namespace.
+	var serviceRef map[string]interface{}
+	for _, r := range rules {
+		rule, _ := r.(map[string]interface{})
+		refs, _ := rule["backendRefs"].([]interface{})
+		for _, ref := range refs {
+			b, _ := ref.(map[string]interface{})
+			kind, _ := b["kind"].(string)
+			name, _ := b["name"].(string)
+			if (kind == "" || kind == provider.KindService) && name == serviceName {
+				serviceRef = b
+				break
+			}
+		}
+		if serviceRef != nil {
+			break
+		}
+	}
+	if serviceRef == nil {
+		return m, fmt.Errorf("no rule of HTTPRoute %s routes traffic to service %s", m.Key.Name, serviceName)
+	}
+
+	canaryRef := variantBackendRef(serviceRef, makeSuffixedName(serviceName, canaryVariant), 1)
+	headerRule := map[string]interface{}{
+		"matches": []interface{}{
+			map[string]interface{}{
+				"headers": []interface{}{
+					map[string]interface{}{
+						"name":  route.Name,
+						"value": route.Value,
+					},
+				},
+			},
+		},
+		"backendRefs": []interface{}{canaryRef},
+	}
+	spec["rules"] = append([]interface{}{headerRule}, rules...)
+
+	if err := m.SetStructuredSpec(spec); err != nil {
+		return m, err
+	}
+	return m, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestAddHeaderRoute(t *testing.T) {
+	route := trafficrouting.HeaderRoute{Name: "x-canary", Value: "always"}
+	testcases := []struct {
+		name        string
+		manifest    string
+		cfg         *config.KubernetesTrafficRouting
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "istio",
+			manifest: `
+apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: helloworld
+spec:
+  hosts:
+  - helloworld
+  http:
+  - route:
+    - destination:
+        host: helloworld
+        subset: primary
+`,
+			cfg: &config.KubernetesTrafficRouting{
+				Method: config.KubernetesTrafficRoutingMethodIstio,
+				Istio: &config.IstioTrafficRouting{
+					Host: "helloworld",
+				},
+			},
+			expected: `
+apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: helloworld
+spec:
+  hosts:
+  - helloworld
+  http:
+  - name: pipecd-header-route
+    match:
+    - headers:
+        x-canary:
+          exact: always
+    route:
+    - destination:
+        host: helloworld
+        subset: canary
+  - route:
+    - destination:
+        host: helloworld
+        subset: primary
+`,
+		},
+		{
+			name: "gateway",
+			manifest: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  rules:
+  - backendRefs:
+    - name: helloworld
+      port: 80
+`,
+			cfg: &config.KubernetesTrafficRouting{
+				Method: config.KubernetesTrafficRoutingMethodGateway,
+			},
+			expected: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  rules:
+  - matches:
+    - headers:
+      - name: x-canary
+        value: always
+    backendRefs:
+    - name: helloworld-canary
+      port: 80
+      weight: 1
+  - backendRefs:
+    - name: helloworld
+      port: 80
+`,
+		},
+		{
+			name: "gateway without the service",
+			manifest: `
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: HTTPRoute
+metadata:
+  name: helloworld
+spec:
+  rules:
+  - backendRefs:
+    - name: other
+      port: 80
+`,
+			cfg: &config.KubernetesTrafficRouting{
+				Method: config.KubernetesTrafficRoutingMethodGateway,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported method",
+			manifest: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: helloworld
+spec:
+  selector:
+    app: helloworld
+`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifest)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(manifests))
+
+			generated, err := addHeaderRoute(manifests[0], "helloworld", route, tc.cfg)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expectedManifests, err := provider.ParseManifests(tc.expected)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(expectedManifests))
+
+			expected, err := expectedManifests[0].YamlBytes()
+			require.NoError(t, err)
+			got, err := generated.YamlBytes()
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(got))
+		})
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func findTrafficSplitManifests(manifests []provider.Manifest, ref config.K8sResourceReference) ([]provider.Manifest, error) {
+	const (
+		smiSplitAPIVersionPrefix = "split.smi-spec.io/"
+		trafficSplitKind         = "TrafficSplit"
+	)
+
+	if ref.Kind != "" && ref.Kind != trafficSplitKind {
+		return nil, fmt.Errorf("support only %q kind for TrafficSplit reference", trafficSplitKind)
+	}
+
+	var out []provider.Manifest
+	for _, m := range manifests {
+		if !strings.HasPrefix(m.Key.APIVersion, smiSplitAPIVersionPrefix) {
+			continue
+		}
+		if m.Key.Kind != trafficSplitKind {
+			continue
+		}
+		if ref.Name != "" && m.Key.Name != ref.Name {
+			continue
+		}
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+// generateTrafficSplitManifest splits the weight of the backend for the given service
+// among the Services of variants in the given TrafficSplit.
+// The weights of the backends for the other services are kept as is
+// and the rest of 100 is split by the given percentages.
+// Only the integer weights introduced by v1alpha2 are supported.
+func generateTrafficSplitManifest(m provider.Manifest, serviceName string, canaryPercent, baselinePercent int) (provider.Manifest, error) {
+	// Because the loaded manifests are read-only
+	// so we duplicate them to avoid updating the shared manifests data in cache.
+	m = duplicateManifest(m, "")
+
+	spec, err := m.GetNestedMap("spec")
+	if err != nil {
+		return m, err
+	}
+	backends, _ := spec["backends"].([]interface{})
+
+	var (
+		found              bool
+		otherBackends      = make([]interface{}, 0, len(backends))
+		otherBackendWeight int64
+	)
+	for _, b := range backends {
+		backend, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _ := backend["service"].(string); name == serviceName {
+			found = true
+			continue
+		}
+		weight, ok := trafficSplitBackendWeight(backend)
+		if !ok {
+			return m, fmt.Errorf("weight of backend in TrafficSplit %s must be an integer", m.Key.Name)
+		}
+		otherBackends = append(otherBackends, backend)
+		otherBackendWeight += weight
+	}
+	if !found {
+		return m, fmt.Errorf("no backend of TrafficSplit %s routes traffic to service %s", m.Key.Name, serviceName)
+	}
+
+	var (
+		variantsWeight = 100 - otherBackendWeight
+		canaryWeight   = int64(canaryPercent) * variantsWeight / 100
+		baselineWeight = int64(baselinePercent) * variantsWeight / 100
+		primaryWeight  = variantsWeight - canaryWeight - baselineWeight
+		newBackends    = make([]interface{}, 0, len(otherBackends)+3)
+	)
+	if variantsWeight < 0 {
+		return m, fmt.Errorf("the total weight of the other backends in TrafficSplit %s exceeds 100", m.Key.Name)
+	}
+
+	newBackends = append(newBackends, map[string]interface{}{
+		"service": serviceName,
+		"weight":  primaryWeight,
+	})
+	if canaryWeight > 0 {
+		newBackends = append(newBackends, map[string]interface{}{
+			"service": makeSuffixedName(serviceName, canaryVariant),
+			"weight":  canaryWeight,
+		})
+	}
+	if baselineWeight > 0 {
+		newBackends = append(newBackends, map[string]interface{}{
+			"service": makeSuffixedName(serviceName, baselineVariant),
+			"weight":  baselineWeight,
+		})
+	}
+	newBackends = append(newBackends, otherBackends...)
+	spec["backends"] = newBackends
+
+	if err := m.SetStructuredSpec(spec); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// trafficSplitBackendWeight returns the weight of the given TrafficSplit backend.
+// False is returned if it is not an integer, e.g. the quantity used by v1alpha1.
+func trafficSplitBackendWeight(backend map[string]interface{}) (int64, bool) {
+	switch w := backend["weight"].(type) {
+	case int64:
+		return w, true
+	case float64:
+		return int64(w), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestGenerateTrafficSplitManifest(t *testing.T) {
+	testcases := []struct {
+		name        string
+		manifest    string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			name: "split weights among variants",
+			manifest: `
+apiVersion: split.smi-spec.io/v1alpha2
+kind: TrafficSplit
+metadata:
+  name: helloworld
+spec:
+  service: helloworld-root
+  backends:
+  - service: helloworld
+    weight: 80
+  - service: legacy
+    weight: 20
+`,
+			expected: `
+apiVersion: split.smi-spec.io/v1alpha2
+kind: TrafficSplit
+metadata:
+  name: helloworld
+spec:
+  service: helloworld-root
+  backends:
+  - service: helloworld
+    weight: 40
+  - service: helloworld-canary
+    weight: 24
+  - service: helloworld-baseline
+    weight: 16
+  - service: legacy
+    weight: 20
+`,
+		},
+		{
+			name: "no backend for the service",
+			manifest: `
+apiVersion: split.smi-spec.io/v1alpha2
+kind: TrafficSplit
+metadata:
+  name: helloworld
+spec:
+  service: helloworld-root
+  backends:
+  - service: other
+    weight: 100
+`,
+			expectedErr: true,
+		},
+		{
+			name: "quantity weight",
+			manifest: `
+apiVersion: split.smi-spec.io/v1alpha1
+kind: TrafficSplit
+metadata:
+  name: helloworld
+spec:
+  service: helloworld-root
+  backends:
+  - service: helloworld
+    weight: 900m
+  - service: legacy
+    weight: 100m
+`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifest)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(manifests))
+
+			generated, err := generateTrafficSplitManifest(manifests[0], "helloworld", 30, 20)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			expectedManifests, err := provider.ParseManifests(tc.expected)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(expectedManifests))
+
+			expected, err := expectedManifests[0].YamlBytes()
+			require.NoError(t, err)
+			got, err := generated.YamlBytes()
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(got))
+		})
+	}
+}
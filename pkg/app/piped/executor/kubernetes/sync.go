@@ -55,6 +55,8 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 		}
 	}
 
+	e.commonMetadata.apply(manifests)
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		manifests,
@@ -72,11 +74,12 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 
 	// Start applying all manifests to add or update running resources.
 	if err := applyManifests(ctx, e.provider, manifests, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
+		e.ReportError(err)
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	if !e.deployCfg.QuickSync.Prune {
-		e.LogPersister.Info("Resource GC was skipped because sync.prune was not configured")
+	if !e.deployCfg.QuickSync.Prune && !e.deployCfg.Input.Prune {
+		e.LogPersister.Info("Resource GC was skipped because neither sync.prune nor input.prune was configured")
 		return model.StageStatus_STAGE_SUCCESS
 	}
 
@@ -99,7 +102,8 @@ func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_SUCCESS
 	}
 
-	removeKeys := findRemoveResources(manifests, liveResources)
+	removeKeys := findRemoveResources(manifests, filterManagedByPiped(liveResources))
+	removeKeys = excludeProtectedResources(removeKeys, e.deployCfg.Input.PruneProtectedKinds, e.LogPersister)
 	if len(removeKeys) == 0 {
 		e.LogPersister.Info("There are no live resources should be removed")
 		return model.StageStatus_STAGE_SUCCESS
@@ -27,6 +27,7 @@ import (
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/cache/cachetest"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -52,7 +53,7 @@ func TestEnsureSync(t *testing.T) {
 							Commit: &model.Commit{},
 						},
 					},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					AppManifestsCache: func() cache.Cache {
 						c := cachetest.NewMockCache(ctrl)
 						c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
@@ -78,7 +79,7 @@ func TestEnsureSync(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					AppManifestsCache: func() cache.Cache {
 						c := cachetest.NewMockCache(ctrl)
 						c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
@@ -118,7 +119,7 @@ func TestEnsureSync(t *testing.T) {
 						},
 					},
 					PipedConfig:  &config.PipedSpec{},
-					LogPersister: &fakeLogPersister{},
+					LogPersister: &executortest.FakeLogPersister{},
 					AppManifestsCache: func() cache.Cache {
 						c := cachetest.NewMockCache(ctrl)
 						c.EXPECT().Get(gomock.Any()).Return(nil, fmt.Errorf("not found"))
@@ -26,6 +26,8 @@ import (
 	istiov1beta1 "istio.io/api/networking/v1beta1"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -43,9 +45,9 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 	)
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
-	method := config.DetermineKubernetesTrafficRoutingMethod(e.deployCfg.TrafficRouting)
 
 	// Load the manifests at the triggered commit.
 	e.LogPersister.Infof("Loading manifests at commit %s for handling", commitHash)
@@ -70,13 +72,51 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 
 	// Decide traffic routing percentage for all variants.
 	primaryPercent, canaryPercent, baselinePercent := options.Percentages()
-	e.saveTrafficRoutingMetadata(ctx, primaryPercent, canaryPercent, baselinePercent)
+	if sizing := options.CanarySizing; sizing != nil {
+		percent, err := e.decideCanaryPercentage(ctx, sizing)
+		switch {
+		case err == nil:
+			canaryPercent = percent
+			// The baseline variant receives the same traffic to be compared with the canary variant.
+			if baselinePercent > 0 {
+				if canaryPercent > 50 {
+					canaryPercent = 50
+				}
+				baselinePercent = canaryPercent
+			}
+			primaryPercent = 100 - canaryPercent - baselinePercent
+		case canaryPercent > 0:
+			e.LogPersister.Infof("Unable to decide the canary percentage from the request rate, the configured %d%% is used instead (%v)", canaryPercent, err)
+		default:
+			e.LogPersister.Errorf("Unable to decide the canary percentage from the request rate (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+	weights := trafficrouting.Weights{
+		Primary:  primaryPercent,
+		Canary:   canaryPercent,
+		Baseline: baselinePercent,
+	}
+	if err := e.newTrafficRouter(manifests).SetWeights(ctx, weights); err != nil {
+		e.LogPersister.Errorf("Failed to update traffic routing (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Success("Successfully updated traffic routing")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// updateTrafficRouting generates the traffic routing manifest for the given percentages
+// and applies it to the cluster.
+// The result is logged and false is returned if failed.
+func (e *deployExecutor) updateTrafficRouting(ctx context.Context, manifests []provider.Manifest, primaryPercent, canaryPercent, baselinePercent int) bool {
+	method := config.DetermineKubernetesTrafficRoutingMethod(e.deployCfg.TrafficRouting)
 
 	// Find traffic routing manifests.
 	trafficRoutingManifests, err := findTrafficRoutingManifests(manifests, e.deployCfg.Service.Name, e.deployCfg.TrafficRouting)
 	if err != nil {
 		e.LogPersister.Errorf("Failed while finding traffic routing manifest: (%v)", err)
-		return model.StageStatus_STAGE_FAILURE
+		return false
 	}
 
 	switch len(trafficRoutingManifests) {
@@ -84,7 +124,7 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 		break
 	case 0:
 		e.LogPersister.Errorf("Unable to find any traffic routing manifests")
-		return model.StageStatus_STAGE_FAILURE
+		return false
 	default:
 		e.LogPersister.Infof(
 			"Detected %d traffic routing manifests but only the first one (%s) will be used",
@@ -94,6 +134,23 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 	}
 	trafficRoutingManifest := trafficRoutingManifests[0]
 
+	// Load the header route configured through the traffic router if any.
+	var headerRoute *trafficrouting.HeaderRoute
+	if value, ok := e.MetadataStore.Get(trafficHeaderRouteMetadataKey); ok {
+		route, err := trafficrouting.ParseHeaderRoute(value)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to load the header route from metadata (%v)", err)
+			return false
+		}
+		headerRoute = &route
+	}
+
+	// In case we are routing by NGINX Ingress, the Ingress itself is kept as is
+	// and a canary Ingress is applied alongside it instead.
+	if method == config.KubernetesTrafficRoutingMethodNginx {
+		return e.updateNginxCanaryIngress(ctx, manifests, trafficRoutingManifest, canaryPercent, baselinePercent, headerRoute)
+	}
+
 	// In case we are routing by PodSelector, the service manifest must contain variantLabel inside its selector.
 	if method == config.KubernetesTrafficRoutingMethodPodSelector {
 		if err := checkVariantSelectorInService(trafficRoutingManifest, primaryVariant); err != nil {
@@ -102,12 +159,14 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 				trafficRoutingManifest.Key.ReadableString(),
 				err,
 			)
-			return model.StageStatus_STAGE_FAILURE
+			return false
 		}
 	}
 
-	trafficRoutingManifest, err = e.generateTrafficRoutingManifest(
+	serviceName := findServiceName(manifests, e.deployCfg.Service.Name)
+	trafficRoutingManifest, err = generateTrafficRoutingManifest(
 		trafficRoutingManifest,
+		serviceName,
 		primaryPercent,
 		canaryPercent,
 		baselinePercent,
@@ -115,14 +174,24 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 	)
 	if err != nil {
 		e.LogPersister.Errorf("Unable generate traffic routing manifest: (%v)", err)
-		return model.StageStatus_STAGE_FAILURE
+		return false
+	}
+
+	if headerRoute != nil {
+		trafficRoutingManifest, err = addHeaderRoute(trafficRoutingManifest, serviceName, *headerRoute, e.deployCfg.TrafficRouting)
+		if err != nil {
+			e.LogPersister.Errorf("Unable to add the header route %s to traffic routing manifest: (%v)", headerRoute, err)
+			return false
+		}
 	}
 
+	e.commonMetadata.apply([]provider.Manifest{trafficRoutingManifest})
+
 	// Add builtin annotations for tracking application live state.
 	addBuiltinAnnontations(
 		[]provider.Manifest{trafficRoutingManifest},
 		primaryVariant,
-		commitHash,
+		e.commit,
 		e.PipedConfig.PipedID,
 		e.Deployment.ApplicationId,
 	)
@@ -133,11 +202,11 @@ func (e *deployExecutor) ensureTrafficRouting(ctx context.Context) model.StageSt
 		baselinePercent,
 	)
 	if err := applyManifests(ctx, e.provider, []provider.Manifest{trafficRoutingManifest}, e.deployCfg.Input.Namespace, e.LogPersister); err != nil {
-		return model.StageStatus_STAGE_FAILURE
+		e.ReportError(err)
+		return false
 	}
 
-	e.LogPersister.Success("Successfully updated traffic routing")
-	return model.StageStatus_STAGE_SUCCESS
+	return true
 }
 
 func findTrafficRoutingManifests(manifests []provider.Manifest, serviceName string, cfg *config.KubernetesTrafficRouting) ([]provider.Manifest, error) {
@@ -154,12 +223,40 @@ func findTrafficRoutingManifests(manifests []provider.Manifest, serviceName stri
 		}
 		return findIstioVirtualServiceManifests(manifests, istioConfig.VirtualService)
 
+	case config.KubernetesTrafficRoutingMethodSMI:
+		smiConfig := cfg.SMI
+		if smiConfig == nil {
+			smiConfig = &config.SMITrafficRouting{}
+		}
+		return findTrafficSplitManifests(manifests, smiConfig.TrafficSplit)
+
+	case config.KubernetesTrafficRoutingMethodNginx:
+		nginxConfig := cfg.Nginx
+		if nginxConfig == nil {
+			nginxConfig = &config.NginxTrafficRouting{}
+		}
+		return findIngressManifests(manifests, nginxConfig.Ingress)
+
+	case config.KubernetesTrafficRoutingMethodGateway:
+		gatewayConfig := cfg.Gateway
+		if gatewayConfig == nil {
+			gatewayConfig = &config.GatewayTrafficRouting{}
+		}
+		return findHTTPRouteManifests(manifests, gatewayConfig.HTTPRoute)
+
+	case config.KubernetesTrafficRoutingMethodALB:
+		albConfig := cfg.ALB
+		if albConfig == nil {
+			albConfig = &config.ALBTrafficRouting{}
+		}
+		return findIngressManifests(manifests, albConfig.Ingress)
+
 	default:
 		return nil, fmt.Errorf("unsupport traffic routing method %v", method)
 	}
 }
 
-func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manifest, primaryPercent, canaryPercent, baselinePercent int, cfg *config.KubernetesTrafficRouting) (provider.Manifest, error) {
+func generateTrafficRoutingManifest(manifest provider.Manifest, serviceName string, primaryPercent, canaryPercent, baselinePercent int, cfg *config.KubernetesTrafficRouting) (provider.Manifest, error) {
 	// Because the loaded manifests are read-only
 	// so we duplicate them to avoid updating the shared manifests data in cache.
 	manifest = duplicateManifest(manifest, "")
@@ -167,7 +264,10 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 	// When all traffic should be routed to primary variant
 	// we do not need to change the traffic manifest
 	// just copy and return the one specified in the target commit.
-	if primaryPercent == 100 {
+	// Since the Service selects the pods of all variants, the ones routing by PodSelector
+	// and Istio are still updated to route traffic to PRIMARY variant explicitly.
+	method := config.DetermineKubernetesTrafficRoutingMethod(cfg)
+	if primaryPercent == 100 && method != config.KubernetesTrafficRoutingMethodPodSelector && method != config.KubernetesTrafficRoutingMethodIstio {
 		return manifest, nil
 	}
 
@@ -183,6 +283,31 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 		return generateVirtualServiceManifest(manifest, istioConfig.Host, istioConfig.EditableRoutes, int32(canaryPercent), int32(baselinePercent))
 	}
 
+	if cfg != nil && cfg.Method == config.KubernetesTrafficRoutingMethodSMI {
+		if serviceName == "" {
+			return manifest, fmt.Errorf("unable to find any service manifests for traffic routing by SMI")
+		}
+		return generateTrafficSplitManifest(manifest, serviceName, canaryPercent, baselinePercent)
+	}
+
+	if cfg != nil && cfg.Method == config.KubernetesTrafficRoutingMethodGateway {
+		if serviceName == "" {
+			return manifest, fmt.Errorf("unable to find any service manifests for traffic routing by Gateway API")
+		}
+		return generateHTTPRouteManifest(manifest, serviceName, canaryPercent, baselinePercent)
+	}
+
+	if cfg != nil && cfg.Method == config.KubernetesTrafficRoutingMethodALB {
+		if serviceName == "" {
+			return manifest, fmt.Errorf("unable to find any service manifests for traffic routing by AWS Load Balancer Controller")
+		}
+		action := cfg.ALB.Action
+		if action == "" {
+			action = serviceName
+		}
+		return generateALBIngressManifest(manifest, action, serviceName, cfg.ALB.ServicePort, canaryPercent, baselinePercent)
+	}
+
 	// Determine which variant will receive 100% percent of traffic.
 	var variant string
 	switch {
@@ -201,14 +326,37 @@ func (e *deployExecutor) generateTrafficRoutingManifest(manifest provider.Manife
 	return manifest, nil
 }
 
-func (e *deployExecutor) saveTrafficRoutingMetadata(ctx context.Context, primary, canary, baseline int) {
+// findServiceName returns the given name of the application Service,
+// or the name of the first Service manifest if it was not specified.
+// Empty is returned if no Service manifest was found.
+func findServiceName(manifests []provider.Manifest, name string) string {
+	if name != "" {
+		return name
+	}
+	services := findManifests(provider.KindService, "", manifests)
+	if len(services) == 0 {
+		return ""
+	}
+	return services[0].Key.Name
+}
+
+func findIngressManifests(manifests []provider.Manifest, ref config.K8sResourceReference) ([]provider.Manifest, error) {
+	if ref.Kind != "" && ref.Kind != provider.KindIngress {
+		return nil, fmt.Errorf("support only %q kind for Ingress reference", provider.KindIngress)
+	}
+	return findManifests(provider.KindIngress, ref.Name, manifests), nil
+}
+
+// saveTrafficRoutingMetadata saves the traffic routing percentages
+// into the metadata of the stage being executed by the given input.
+func saveTrafficRoutingMetadata(ctx context.Context, in *executor.Input, primary, canary, baseline int) {
 	metadata := map[string]string{
 		primaryMetadataKey:  strconv.FormatInt(int64(primary), 10),
 		canaryMetadataKey:   strconv.FormatInt(int64(canary), 10),
 		baselineMetadataKey: strconv.FormatInt(int64(baseline), 10),
 	}
-	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
-		e.Logger.Error("failed to save traffic routing percentages to metadata", zap.Error(err))
+	if err := in.MetadataStore.SetStageMetadata(ctx, in.Stage.Id, metadata); err != nil {
+		in.Logger.Error("failed to save traffic routing percentages to metadata", zap.Error(err))
 	}
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/config"
 )
 
 func TestGenerateVirtualServiceManifest(t *testing.T) {
@@ -66,6 +67,111 @@ func TestGenerateVirtualServiceManifest(t *testing.T) {
 	}
 }
 
+func TestGenerateTrafficRoutingManifestToPrimary(t *testing.T) {
+	testcases := []struct {
+		name     string
+		manifest string
+		cfg      *config.KubernetesTrafficRouting
+		expected string
+	}{
+		{
+			name: "pod selector",
+			manifest: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+`,
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: simple
+spec:
+  selector:
+    app: simple
+    pipecd.dev/variant: primary
+`,
+		},
+		{
+			name: "istio",
+			manifest: `
+apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: simple
+spec:
+  hosts:
+  - simple
+  http:
+  - route:
+    - destination:
+        host: simple
+`,
+			cfg: &config.KubernetesTrafficRouting{
+				Method: config.KubernetesTrafficRoutingMethodIstio,
+				Istio:  &config.IstioTrafficRouting{Host: "simple"},
+			},
+			expected: `apiVersion: networking.istio.io/v1beta1
+kind: VirtualService
+metadata:
+  name: simple
+spec:
+  hosts:
+  - simple
+  http:
+  - route:
+    - destination:
+        host: simple
+        subset: primary
+      weight: 100
+`,
+		},
+		{
+			name: "smi",
+			manifest: `
+apiVersion: split.smi-spec.io/v1alpha2
+kind: TrafficSplit
+metadata:
+  name: simple
+spec:
+  service: simple
+  backends:
+  - service: simple-primary
+    weight: 100
+`,
+			cfg: &config.KubernetesTrafficRouting{
+				Method: config.KubernetesTrafficRoutingMethodSMI,
+			},
+			expected: `apiVersion: split.smi-spec.io/v1alpha2
+kind: TrafficSplit
+metadata:
+  name: simple
+spec:
+  backends:
+  - service: simple-primary
+    weight: 100
+  service: simple
+`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifest)
+			require.NoError(t, err)
+			require.Equal(t, 1, len(manifests))
+
+			generated, err := generateTrafficRoutingManifest(manifests[0], "simple", 100, 0, 0, tc.cfg)
+			require.NoError(t, err)
+			got, err := generated.YamlBytes()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(got))
+		})
+	}
+}
+
 func TestCheckVariantSelectorInService(t *testing.T) {
 	testcases := []struct {
 		name     string
@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"path/filepath"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (e *deployExecutor) ensureValidate(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sValidateStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Load the manifests at the triggered commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	var invalid int
+	if !options.SkipSchemaValidation {
+		e.LogPersister.Infof("Start validating %d manifests against the schemas of the cluster", len(manifests))
+		invalid = validateManifests(ctx, e.provider, manifests, e.LogPersister)
+		if invalid > 0 {
+			e.LogPersister.Errorf("%d manifests were rejected by the cluster", invalid)
+		} else {
+			e.LogPersister.Success("All manifests were accepted by the cluster")
+		}
+	}
+
+	var result policyCheckResult
+	if len(options.Policies) > 0 {
+		conftest, ok := findConftest(ctx, options.ConftestVersion, e.LogPersister)
+		if !ok {
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Infof("Start checking %d manifests against the policies", len(manifests))
+		result, err = checkPolicies(ctx, conftest, e.policyDirs(options.Policies), options.PolicyNamespaces, manifests, e.LogPersister)
+		if err != nil {
+			e.LogPersister.Errorf("Failed while checking the policies (%v)", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		switch {
+		case result.failures > 0:
+			e.LogPersister.Errorf("Detected %d policy failures and %d warnings", result.failures, result.warnings)
+		case result.warnings > 0:
+			e.LogPersister.Infof("Detected %d policy warnings", result.warnings)
+		default:
+			e.LogPersister.Success("All manifests complied with the policies")
+		}
+	}
+
+	if invalid > 0 {
+		e.ReportError(executor.NewUserError("%d manifests were rejected by the cluster", invalid))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if result.failures > 0 {
+		e.ReportError(executor.NewUserError("%d policy failures were detected", result.failures))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if result.warnings > 0 && options.FailOnWarning {
+		e.ReportError(executor.NewUserError("%d policy warnings were detected while failOnWarning was enabled", result.warnings))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if result.warnings > 0 {
+		e.ReportWarning("%d policy warnings were detected", result.warnings)
+	}
+
+	e.LogPersister.Success("Successfully validated all manifests")
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// policyDirs returns the given policy directories
+// where the relative ones are resolved from the application directory.
+func (e *deployExecutor) policyDirs(policies []string) []string {
+	dirs := make([]string, 0, len(policies))
+	for _, p := range policies {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(e.appDir, p)
+		}
+		dirs = append(dirs, p)
+	}
+	return dirs
+}
+
+// validateManifests validates all given manifests against the cluster
+// and returns the number of rejected ones.
+func validateManifests(ctx context.Context, applier provider.Applier, manifests []provider.Manifest, lp executor.LogPersister) int {
+	var invalid int
+	for _, m := range manifests {
+		if err := applier.ValidateManifest(ctx, m); err != nil {
+			lp.Errorf("- manifest %s is invalid (%v)", m.Key.ReadableString(), err)
+			invalid++
+			continue
+		}
+		lp.Infof("- manifest %s is valid", m.Key.ReadableString())
+	}
+	return invalid
+}
+
+type policyChecker interface {
+	Test(ctx context.Context, policies, namespaces []string, manifests []provider.Manifest) ([]provider.PolicyViolation, error)
+}
+
+type policyCheckResult struct {
+	failures int
+	warnings int
+}
+
+func checkPolicies(ctx context.Context, checker policyChecker, policies, namespaces []string, manifests []provider.Manifest, lp executor.LogPersister) (policyCheckResult, error) {
+	var result policyCheckResult
+	violations, err := checker.Test(ctx, policies, namespaces, manifests)
+	if err != nil {
+		return result, err
+	}
+
+	for _, v := range violations {
+		if v.Warning {
+			lp.Infof("- [WARN] %s: %s (%s)", v.Key.ReadableString(), v.Message, v.Namespace)
+			result.warnings++
+			continue
+		}
+		lp.Errorf("- [FAIL] %s: %s (%s)", v.Key.ReadableString(), v.Message, v.Namespace)
+		result.failures++
+	}
+	return result, nil
+}
+
+func findConftest(ctx context.Context, version string, lp executor.LogPersister) (*provider.Conftest, bool) {
+	path, installed, err := toolregistry.DefaultRegistry().Conftest(ctx, version)
+	if err != nil {
+		lp.Errorf("Unable to find required conftest %q (%v)", version, err)
+		return nil, false
+	}
+	if installed {
+		lp.Infof("Conftest %q has just been installed to %q because of no pre-installed binary for that version", version, path)
+	}
+	return provider.NewConftest(version, path), true
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes/providertest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+)
+
+func TestValidateManifests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	manifests := []provider.Manifest{
+		{Key: provider.ResourceKey{Kind: provider.KindDeployment, Name: "valid"}},
+		{Key: provider.ResourceKey{Kind: provider.KindDeployment, Name: "invalid"}},
+		{Key: provider.ResourceKey{Kind: provider.KindService, Name: "valid"}},
+	}
+
+	p := providertest.NewMockProvider(ctrl)
+	p.EXPECT().ValidateManifest(gomock.Any(), manifests[0]).Return(nil)
+	p.EXPECT().ValidateManifest(gomock.Any(), manifests[1]).Return(fmt.Errorf("unknown field"))
+	p.EXPECT().ValidateManifest(gomock.Any(), manifests[2]).Return(nil)
+
+	invalid := validateManifests(context.Background(), p, manifests, &executortest.FakeLogPersister{})
+	assert.Equal(t, 1, invalid)
+}
+
+type fakePolicyChecker struct {
+	violations []provider.PolicyViolation
+	err        error
+}
+
+func (c *fakePolicyChecker) Test(_ context.Context, _, _ []string, _ []provider.Manifest) ([]provider.PolicyViolation, error) {
+	return c.violations, c.err
+}
+
+func TestCheckPolicies(t *testing.T) {
+	testcases := []struct {
+		name     string
+		checker  *fakePolicyChecker
+		expected policyCheckResult
+		wantErr  bool
+	}{
+		{
+			name:    "no violation",
+			checker: &fakePolicyChecker{},
+		},
+		{
+			name: "failures and warnings",
+			checker: &fakePolicyChecker{
+				violations: []provider.PolicyViolation{
+					{Message: "containers must not run as root"},
+					{Message: "resource limits should be set", Warning: true},
+					{Message: "image tag must not be latest"},
+				},
+			},
+			expected: policyCheckResult{failures: 2, warnings: 1},
+		},
+		{
+			name:    "failed to run checker",
+			checker: &fakePolicyChecker{err: fmt.Errorf("error")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := checkPolicies(context.Background(), tc.checker, []string{"policy"}, nil, nil, &executortest.FakeLogPersister{})
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
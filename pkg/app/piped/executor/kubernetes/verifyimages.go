@@ -0,0 +1,188 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func (e *deployExecutor) ensureVerifyImages(ctx context.Context) model.StageStatus {
+	options := e.StageConfig.K8sVerifyImagesStageOptions
+	if options == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Load the manifests at the triggered commit.
+	e.LogPersister.Infof("Loading manifests at commit %s for handling", e.commit)
+	manifests, err := loadManifests(
+		ctx,
+		e.Deployment.ApplicationId,
+		e.commit,
+		e.AppManifestsCache,
+		e.provider,
+		e.Logger,
+	)
+	if err != nil {
+		e.LogPersister.Errorf("Failed while loading manifests (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	e.LogPersister.Successf("Successfully loaded %d manifests", len(manifests))
+
+	images := excludeImages(provider.FindContainerImages(manifests), options.ExcludeImages)
+	if len(images) == 0 {
+		e.LogPersister.Info("There is no image to verify")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	cosign, ok := findCosign(ctx, options.CosignVersion, e.LogPersister)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Infof("Start verifying %d images", len(images))
+	failed := verifyImages(ctx, cosign, images, e.cosignSigners(options), options.Attestations, e.LogPersister)
+	if len(failed) > 0 {
+		e.LogPersister.Errorf("%d of %d images failed the verification", len(failed), len(images))
+		e.ReportError(executor.NewUserError("%d images failed the verification: %s", len(failed), strings.Join(failed, ", ")))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully verified all %d images", len(images))
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// cosignSigners returns the signers configured in the given options
+// where the relative key paths are resolved from the application directory.
+func (e *deployExecutor) cosignSigners(options *config.K8sVerifyImagesStageOptions) []provider.CosignSigner {
+	signers := make([]provider.CosignSigner, 0, len(options.Keys)+len(options.Identities))
+	for _, k := range options.Keys {
+		// The KMS keys are referred by URIs such as "gcpkms://...".
+		if !strings.Contains(k, "://") && !filepath.IsAbs(k) {
+			k = filepath.Join(e.appDir, k)
+		}
+		signers = append(signers, provider.CosignSigner{Key: k})
+	}
+	for _, id := range options.Identities {
+		signers = append(signers, provider.CosignSigner{
+			Issuer:  id.Issuer,
+			Subject: id.Subject,
+		})
+	}
+	return signers
+}
+
+// excludeImages returns the given images except the ones starting with any of the given prefixes.
+func excludeImages(images, prefixes []string) []string {
+	if len(prefixes) == 0 {
+		return images
+	}
+	out := make([]string, 0, len(images))
+	for _, image := range images {
+		excluded := false
+		for _, p := range prefixes {
+			if strings.HasPrefix(image, p) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			out = append(out, image)
+		}
+	}
+	return out
+}
+
+type imageVerifier interface {
+	Verify(ctx context.Context, image string, signer provider.CosignSigner) error
+	VerifyAttestation(ctx context.Context, image string, signer provider.CosignSigner, predicateType string) error
+}
+
+// verifyImages verifies all given images and returns the ones failed the verification.
+// The detail of every image is written into the log.
+func verifyImages(ctx context.Context, verifier imageVerifier, images []string, signers []provider.CosignSigner, attestations []string, lp executor.LogPersister) []string {
+	var failed []string
+	for _, image := range images {
+		reasons := verifyImage(ctx, verifier, image, signers, attestations)
+		if len(reasons) > 0 {
+			lp.Errorf("- image %s failed the verification", image)
+			for _, r := range reasons {
+				lp.Errorf("  - %s", r)
+			}
+			failed = append(failed, image)
+			continue
+		}
+		lp.Infof("- image %s was verified", image)
+	}
+	return failed
+}
+
+// verifyImage returns the reasons why the given image was not verified.
+// The signature and each type of the attestations are required to be verified by at least one of the given signers.
+func verifyImage(ctx context.Context, verifier imageVerifier, image string, signers []provider.CosignSigner, attestations []string) []string {
+	var reasons []string
+	errs := make([]string, 0, len(signers))
+	verified := false
+	for _, s := range signers {
+		err := verifier.Verify(ctx, image, s)
+		if err == nil {
+			verified = true
+			break
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", s, err))
+	}
+	if !verified {
+		reasons = append(reasons, fmt.Sprintf("no valid signature was found (%s)", strings.Join(errs, "; ")))
+	}
+
+	for _, a := range attestations {
+		errs = errs[:0]
+		verified = false
+		for _, s := range signers {
+			err := verifier.VerifyAttestation(ctx, image, s, a)
+			if err == nil {
+				verified = true
+				break
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", s, err))
+		}
+		if !verified {
+			reasons = append(reasons, fmt.Sprintf("no valid %s attestation was found (%s)", a, strings.Join(errs, "; ")))
+		}
+	}
+	return reasons
+}
+
+func findCosign(ctx context.Context, version string, lp executor.LogPersister) (*provider.Cosign, bool) {
+	path, installed, err := toolregistry.DefaultRegistry().Cosign(ctx, version)
+	if err != nil {
+		lp.Errorf("Unable to find required cosign %q (%v)", version, err)
+		return nil, false
+	}
+	if installed {
+		lp.Infof("Cosign %q has just been installed to %q because of no pre-installed binary for that version", version, path)
+	}
+	return provider.NewCosign(version, path), true
+}
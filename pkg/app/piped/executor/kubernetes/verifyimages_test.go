@@ -0,0 +1,124 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+)
+
+func TestExcludeImages(t *testing.T) {
+	images := []string{
+		"gcr.io/pipecd/helloworld:v0.1.0",
+		"gcr.io/pipecd/sidecar:v0.1.0",
+		"docker.io/library/busybox:1.33",
+	}
+	assert.Equal(t, images, excludeImages(images, nil))
+	assert.Equal(t, []string{"gcr.io/pipecd/helloworld:v0.1.0"}, excludeImages(images, []string{"docker.io/", "gcr.io/pipecd/sidecar"}))
+}
+
+type fakeImageVerifier struct {
+	// Map from image to the signers signed it.
+	signatures map[string][]string
+	// Map from image to the predicate types of the attestations signed by the signers.
+	attestations map[string]map[string][]string
+}
+
+func (v *fakeImageVerifier) Verify(_ context.Context, image string, signer provider.CosignSigner) error {
+	return findSigner(v.signatures[image], signer)
+}
+
+func (v *fakeImageVerifier) VerifyAttestation(_ context.Context, image string, signer provider.CosignSigner, predicateType string) error {
+	return findSigner(v.attestations[image][predicateType], signer)
+}
+
+func findSigner(signers []string, signer provider.CosignSigner) error {
+	for _, s := range signers {
+		if s == signer.String() {
+			return nil
+		}
+	}
+	return fmt.Errorf("no matching signatures")
+}
+
+func TestVerifyImages(t *testing.T) {
+	var (
+		key      = provider.CosignSigner{Key: "cosign.pub"}
+		identity = provider.CosignSigner{Issuer: "https://accounts.google.com", Subject: "release@pipecd.dev"}
+		verifier = &fakeImageVerifier{
+			signatures: map[string][]string{
+				"signed-by-key":      {key.String()},
+				"signed-by-identity": {identity.String()},
+				"with-sbom":          {key.String()},
+			},
+			attestations: map[string]map[string][]string{
+				"with-sbom": {"spdx": {identity.String()}},
+			},
+		}
+	)
+
+	testcases := []struct {
+		name         string
+		images       []string
+		signers      []provider.CosignSigner
+		attestations []string
+		expected     []string
+	}{
+		{
+			name:    "verified by any signer",
+			images:  []string{"signed-by-key", "signed-by-identity"},
+			signers: []provider.CosignSigner{key, identity},
+		},
+		{
+			name:     "no valid signature",
+			images:   []string{"signed-by-key", "signed-by-identity", "unsigned"},
+			signers:  []provider.CosignSigner{key},
+			expected: []string{"signed-by-identity", "unsigned"},
+		},
+		{
+			name:         "missing attestation",
+			images:       []string{"signed-by-key", "with-sbom"},
+			signers:      []provider.CosignSigner{key, identity},
+			attestations: []string{"spdx"},
+			expected:     []string{"signed-by-key"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			failed := verifyImages(context.Background(), verifier, tc.images, tc.signers, tc.attestations, &executortest.FakeLogPersister{})
+			assert.Equal(t, tc.expected, failed)
+		})
+	}
+}
+
+func TestVerifyImage(t *testing.T) {
+	var (
+		key      = provider.CosignSigner{Key: "cosign.pub"}
+		verifier = &fakeImageVerifier{}
+	)
+	reasons := verifyImage(context.Background(), verifier, "unsigned", []provider.CosignSigner{key}, []string{"spdx"})
+	expected := []string{
+		"no valid signature was found (key cosign.pub: no matching signatures)",
+		"no valid spdx attestation was found (key cosign.pub: no matching signatures)",
+	}
+	assert.Equal(t, expected, reasons)
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+)
+
+const (
+	// How long to wait for the applied CustomResourceDefinitions to be established
+	// before applying the remaining manifests.
+	crdEstablishedTimeout = 2 * time.Minute
+)
+
+// kindApplyOrders represents the order in which the resources of the same wave are applied.
+// The kinds not listed here are applied last, after the ones they may depend on.
+var kindApplyOrders = map[string]int{
+	provider.KindNamespace:                0,
+	provider.KindCustomResourceDefinition: 1,
+	provider.KindServiceAccount:           2,
+	provider.KindSecret:                   2,
+	provider.KindConfigMap:                2,
+	provider.KindPersistentVolume:         2,
+	provider.KindPersistentVolumeClaim:    2,
+	provider.KindClusterRole:              2,
+	provider.KindClusterRoleBinding:       2,
+	provider.KindRole:                     2,
+	provider.KindRoleBinding:              2,
+	provider.KindService:                  3,
+}
+
+const defaultKindApplyOrder = 4
+
+// manifestWave is a group of manifests annotated with the same sync wave.
+type manifestWave struct {
+	wave      int
+	manifests []provider.Manifest
+}
+
+// groupManifestsByWave sorts the given manifests in the order to be applied
+// and groups them by the wave number annotated with pipecd.dev/sync-wave.
+// The waves are ordered from the lowest number, the default one is 0.
+// In each wave, namespaces and CRDs are followed by the resources depending on them.
+func groupManifestsByWave(manifests []provider.Manifest) ([]manifestWave, error) {
+	type entry struct {
+		wave     int
+		order    int
+		manifest provider.Manifest
+	}
+	entries := make([]entry, 0, len(manifests))
+	for _, m := range manifests {
+		wave, err := syncWave(m)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{
+			wave:     wave,
+			order:    kindApplyOrder(m.Key),
+			manifest: m,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].wave != entries[j].wave {
+			return entries[i].wave < entries[j].wave
+		}
+		return entries[i].order < entries[j].order
+	})
+
+	var waves []manifestWave
+	for _, e := range entries {
+		if len(waves) == 0 || waves[len(waves)-1].wave != e.wave {
+			waves = append(waves, manifestWave{wave: e.wave})
+		}
+		last := &waves[len(waves)-1]
+		last.manifests = append(last.manifests, e.manifest)
+	}
+	return waves, nil
+}
+
+func syncWave(m provider.Manifest) (int, error) {
+	v, ok := m.GetAnnotations()[provider.AnnotationSyncWave]
+	if !ok {
+		return 0, nil
+	}
+	wave, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation %q of %s: must be an integer", provider.AnnotationSyncWave, v, m.Key.ReadableString())
+	}
+	return wave, nil
+}
+
+func kindApplyOrder(k provider.ResourceKey) int {
+	if !provider.IsKubernetesBuiltInResource(k.APIVersion) {
+		return defaultKindApplyOrder
+	}
+	if order, ok := kindApplyOrders[k.Kind]; ok {
+		return order
+	}
+	return defaultKindApplyOrder
+}
+
+// waitForCRDsEstablished blocks until all given CustomResourceDefinitions have been established
+// so that their custom resources can be applied.
+func waitForCRDsEstablished(ctx context.Context, applier provider.Applier, keys []provider.ResourceKey, lp executor.LogPersister) error {
+	lp.Infof("Waiting for %d custom resource definitions to be established", len(keys))
+	ctx, cancel := context.WithTimeout(ctx, crdEstablishedTimeout)
+	defer cancel()
+
+	for _, k := range keys {
+		if err := applier.WaitForEstablished(ctx, k); err != nil {
+			lp.Errorf("Failed while waiting for custom resource definition %s to be established (%v)", k.Name, err)
+			return err
+		}
+	}
+	lp.Successf("All %d custom resource definitions have been established", len(keys))
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/kubernetes"
+)
+
+func TestGroupManifestsByWave(t *testing.T) {
+	testcases := []struct {
+		name        string
+		manifests   string
+		expected    [][]string
+		expectedErr bool
+	}{
+		{
+			name: "ordered by kind",
+			manifests: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: foos.example.com
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: app
+`,
+			expected: [][]string{
+				{"Namespace", "CustomResourceDefinition", "ConfigMap", "Service", "Deployment", "Foo"},
+			},
+		},
+		{
+			name: "grouped by wave",
+			manifests: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    pipecd.dev/sync-wave: "1"
+---
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: foo
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    pipecd.dev/sync-wave: "-1"
+`,
+			expected: [][]string{
+				{"Job"},
+				{"Foo"},
+				{"Deployment"},
+			},
+		},
+		{
+			name: "custom resource of built-in kind name",
+			manifests: `
+apiVersion: example.com/v1
+kind: Namespace
+metadata:
+  name: foo
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+`,
+			expected: [][]string{
+				{"Secret", "Namespace"},
+			},
+		},
+		{
+			name: "invalid wave",
+			manifests: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    pipecd.dev/sync-wave: first
+`,
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifests, err := provider.ParseManifests(tc.manifests)
+			require.NoError(t, err)
+
+			waves, err := groupManifestsByWave(manifests)
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			got := make([][]string, 0, len(waves))
+			for _, w := range waves {
+				kinds := make([]string, 0, len(w.manifests))
+				for _, m := range w.manifests {
+					kinds = append(kinds, m.Key.Kind)
+				}
+				got = append(got, kinds)
+			}
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"strconv"
 
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -49,6 +50,7 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	e.deployCfg = ds.DeploymentConfig.LambdaDeploymentSpec
 	if e.deployCfg == nil {
 		e.LogPersister.Errorf("Malformed deployment configuration: missing LambdaDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing LambdaDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -78,13 +80,28 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
 }
 
+// loadFunctionManifest loads the function manifest to be deployed
+// and uploads the package of its source code when the packaging was configured.
+func (e *deployExecutor) loadFunctionManifest(ctx context.Context) (provider.FunctionManifest, bool) {
+	fm, ok := loadFunctionManifest(&e.Input, e.deployCfg.Input, e.deploySource)
+	if !ok {
+		return fm, false
+	}
+	if pkg := e.deployCfg.Input.Package; pkg != nil {
+		if !uploadPackage(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, pkg, e.deploySource, fm) {
+			return fm, false
+		}
+	}
+	return fm, true
+}
+
 func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
-	fm, ok := loadFunctionManifest(&e.Input, e.deployCfg.Input.FunctionManifestFile, e.deploySource)
+	fm, ok := e.loadFunctionManifest(ctx)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	if !sync(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, fm) {
+	if !sync(ctx, &e.Input, e.cloudProviderName, e.cloudProviderCfg, fm, e.deployCfg.Input.ConfigurationOnly) {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -95,6 +112,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 	options := e.StageConfig.LambdaPromoteStageOptions
 	if options == nil {
 		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
 		return model.StageStatus_STAGE_FAILURE
 	}
 	metadata := map[string]string{
@@ -104,7 +122,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 		e.Logger.Error("failed to save routing percentages to metadata", zap.Error(err))
 	}
 
-	fm, ok := loadFunctionManifest(&e.Input, e.deployCfg.Input.FunctionManifestFile, e.deploySource)
+	fm, ok := e.loadFunctionManifest(ctx)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -117,7 +135,7 @@ func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
 }
 
 func (e *deployExecutor) ensureRollout(ctx context.Context) model.StageStatus {
-	fm, ok := loadFunctionManifest(&e.Input, e.deployCfg.Input.FunctionManifestFile, e.deploySource)
+	fm, ok := e.loadFunctionManifest(ctx)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -18,11 +18,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
 	"github.com/pipe-cd/pipe/pkg/backoff"
 	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
@@ -68,20 +70,58 @@ func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProvid
 	return
 }
 
-func loadFunctionManifest(in *executor.Input, functionManifestFile string, ds *deploysource.DeploySource) (provider.FunctionManifest, bool) {
+func loadFunctionManifest(in *executor.Input, input config.LambdaDeploymentInput, ds *deploysource.DeploySource) (provider.FunctionManifest, bool) {
 	in.LogPersister.Infof("Loading service manifest at commit %s", ds.Revision)
 
-	fm, err := provider.LoadFunctionManifest(ds.AppDir, functionManifestFile)
+	var (
+		fm  provider.FunctionManifest
+		err error
+	)
+	// The code packaged by piped is stored at the location decided by the commit.
+	if pkg := input.Package; pkg != nil {
+		fm, err = provider.LoadPackagedFunctionManifest(ds.AppDir, input.FunctionManifestFile, *pkg, ds.Revision)
+	} else {
+		fm, err = provider.LoadFunctionManifest(ds.AppDir, input.FunctionManifestFile)
+	}
 	if err != nil {
 		in.LogPersister.Errorf("Failed to load lambda function manifest (%v)", err)
 		return provider.FunctionManifest{}, false
 	}
+	if n := len(fm.Spec.EncryptedEnvironments); n > 0 {
+		if err := provider.DecryptEnvironments(&fm, in.SecretDecrypter); err != nil {
+			in.LogPersister.Errorf("Failed to decrypt the environment variables of lambda function manifest (%v)", err)
+			return provider.FunctionManifest{}, false
+		}
+		in.LogPersister.Infof("Successfully decrypted %d environment variables", n)
+	}
 
 	in.LogPersister.Infof("Successfully loaded the lambda function manifest at commit %s", ds.Revision)
 	return fm, true
 }
 
-func sync(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, fm provider.FunctionManifest) bool {
+// uploadPackage packages the source code into a zip archive and uploads it to the location specified in the given manifest.
+func uploadPackage(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, pkg *config.LambdaPackage, ds *deploysource.DeploySource, fm provider.FunctionManifest) bool {
+	in.LogPersister.Infof("Packaging the source code at %s", pkg.Source)
+	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create Lambda client for the provider %s: %v", cloudProviderName, err)
+		return false
+	}
+
+	data, err := provider.PackageSource(filepath.Join(ds.AppDir, pkg.Source))
+	if err != nil {
+		in.LogPersister.Errorf("Failed to package the source code of Lambda function %s: %v", fm.Spec.Name, err)
+		return false
+	}
+	if err := client.UploadPackage(ctx, fm.Spec.S3Bucket, fm.Spec.S3Key, data); err != nil {
+		in.LogPersister.Errorf("Failed to upload the package of Lambda function %s: %v", fm.Spec.Name, err)
+		return false
+	}
+	in.LogPersister.Successf("Successfully uploaded the package (%d bytes) to s3://%s/%s", len(data), fm.Spec.S3Bucket, fm.Spec.S3Key)
+	return true
+}
+
+func sync(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, fm provider.FunctionManifest, configurationOnly bool) bool {
 	in.LogPersister.Infof("Start applying the lambda function manifest")
 	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
 	if err != nil {
@@ -89,6 +129,16 @@ func sync(ctx context.Context, in *executor.Input, cloudProviderName string, clo
 		return false
 	}
 
+	if configurationOnly {
+		updated, ok := syncConfiguration(ctx, in, client, fm)
+		if !ok {
+			return false
+		}
+		if updated {
+			return true
+		}
+	}
+
 	// Build and publish new version of Lambda function.
 	version, ok := build(ctx, in, client, fm)
 	if !ok {
@@ -139,6 +189,38 @@ func sync(ctx context.Context, in *executor.Input, cloudProviderName string, clo
 	return true
 }
 
+// syncConfiguration updates only the configuration of the function when its code was not changed.
+// The returned updated is true when the configuration was updated and no new version should be published.
+func syncConfiguration(ctx context.Context, in *executor.Input, client provider.Client, fm provider.FunctionManifest) (updated, ok bool) {
+	live, err := client.GetFunction(ctx, fm.Spec.Name)
+	if errors.Is(err, provider.ErrNotFound) {
+		return false, true
+	}
+	if err != nil {
+		in.LogPersister.Errorf("Unable to get the live state of Lambda function %s: %v", fm.Spec.Name, err)
+		return false, false
+	}
+	desired := fm.Spec
+	// The hash of the zip archive is compared with the live one to know whether its code was changed.
+	if desired.IsZipPackage() {
+		if desired.CodeSHA256, err = client.GetCodeSHA256(ctx, fm); err != nil {
+			in.LogPersister.Errorf("Unable to get the code hash of Lambda function %s: %v", fm.Spec.Name, err)
+			return false, false
+		}
+	}
+	if !provider.IsConfigurationOnlyChange(live, desired) {
+		return false, true
+	}
+
+	in.LogPersister.Infof("Only the configuration of Lambda function %s was changed, updating it without publishing a new version", fm.Spec.Name)
+	if err := client.UpdateFunctionConfiguration(ctx, fm); err != nil {
+		in.LogPersister.Errorf("Failed to update the configuration of Lambda function %s: %v", fm.Spec.Name, err)
+		return false, false
+	}
+	in.LogPersister.Successf("Successfully updated the configuration of Lambda function %s", fm.Spec.Name)
+	return true, true
+}
+
 func rollout(ctx context.Context, in *executor.Input, cloudProviderName string, cloudProviderCfg *config.CloudProviderLambdaConfig, fm provider.FunctionManifest) bool {
 	in.LogPersister.Infof("Start rolling out the lambda function: %s", fm.Spec.Name)
 	client, err := provider.DefaultRegistry().Client(cloudProviderName, cloudProviderCfg, in.Logger)
@@ -187,16 +269,27 @@ func promote(ctx context.Context, in *executor.Input, cloudProviderName string,
 		return false
 	}
 
+	// Use the version published by the previous rollout or promote stage,
+	// so that the successive promote stages shift the traffic of the same version.
 	rolloutVersionKeyName := fmt.Sprintf("%s-rollout", fm.Spec.Name)
 	version, ok := in.MetadataStore.Get(rolloutVersionKeyName)
 	if !ok {
-		in.LogPersister.Errorf("Unable to prepare version to promote for Lambda function %s: Not found", fm.Spec.Name)
-		return false
+		in.LogPersister.Infof("No version of Lambda function %s was rolled out by the previous stages, a new version will be published", fm.Spec.Name)
+		version, ok = build(ctx, in, client, fm)
+		if !ok {
+			in.LogPersister.Errorf("Failed to build new version for Lambda function %s", fm.Spec.Name)
+			return false
+		}
+		if err := in.MetadataStore.Set(ctx, rolloutVersionKeyName, version); err != nil {
+			in.LogPersister.Errorf("Failed to update latest version name to metadata store for Lambda function %s: %v", fm.Spec.Name, err)
+			return false
+		}
 	}
 
 	options := in.StageConfig.LambdaPromoteStageOptions
 	if options == nil {
 		in.LogPersister.Errorf("Malformed configuration for stage %s", in.Stage.Name)
+		in.ReportError(executor.NewUserError("malformed configuration for stage %s", in.Stage.Name))
 		return false
 	}
 
@@ -219,25 +312,34 @@ func promote(ctx context.Context, in *executor.Input, cloudProviderName string,
 		return false
 	}
 
-	// Update traffic to the new lambda version.
-	if !configureTrafficRouting(trafficCfg, version, options.Percent.Int()) {
-		in.LogPersister.Errorf("Failed to prepare traffic routing for Lambda function %s", fm.Spec.Name)
-		return false
+	// Store the traffic config before the first promotion for rollback if necessary.
+	originalTrafficKeyName := fmt.Sprintf("original-traffic-%s", in.Deployment.RunningCommitHash)
+	if _, ok := in.MetadataStore.Get(originalTrafficKeyName); !ok {
+		originalTrafficCfg, err := trafficCfg.Encode()
+		if err != nil {
+			in.LogPersister.Errorf("Unable to store current traffic config for rollback: encode failed: %v", err)
+			return false
+		}
+		if err := in.MetadataStore.Set(ctx, originalTrafficKeyName, originalTrafficCfg); err != nil {
+			in.LogPersister.Errorf("Unable to store current traffic config for rollback: %v", err)
+			return false
+		}
 	}
 
-	// Store promote traffic config for rollback if necessary.
-	promoteTrafficCfgData, err := trafficCfg.Encode()
-	if err != nil {
-		in.LogPersister.Errorf("Unable to store current traffic config for rollback: encode failed: %v", err)
-		return false
+	// Update traffic to the new lambda version.
+	// The promote traffic config is stored for rollback if necessary.
+	router := &aliasRouter{
+		client:                client,
+		fm:                    fm,
+		version:               version,
+		metadataStore:         in.MetadataStore,
+		trafficCfgMetadataKey: fmt.Sprintf("latest-promote-traffic-%s", in.Deployment.RunningCommitHash),
 	}
-	promoteTrafficKeyName := fmt.Sprintf("latest-promote-traffic-%s", in.Deployment.RunningCommitHash)
-	if err := in.MetadataStore.Set(ctx, promoteTrafficKeyName, promoteTrafficCfgData); err != nil {
-		in.LogPersister.Errorf("Unable to store promote traffic config for rollback: %v", err)
-		return false
+	weights := trafficrouting.Weights{
+		Primary: 100 - options.Percent.Int(),
+		Canary:  options.Percent.Int(),
 	}
-
-	if err = client.UpdateTrafficConfig(ctx, fm, trafficCfg); err != nil {
+	if err := router.SetWeights(ctx, weights); err != nil {
 		in.LogPersister.Errorf("Failed to update traffic routing for Lambda function %s (version: %s): %v", fm.Spec.Name, version, err)
 		return false
 	}
@@ -257,6 +359,11 @@ func configureTrafficRouting(trafficCfg provider.RoutingTrafficConfig, version s
 		Version: version,
 		Percent: float64(percent),
 	}
+	// No secondary version is needed once the new version has received all traffic.
+	if percent >= 100 {
+		delete(trafficCfg, provider.TrafficSecondaryVersionKeyName)
+		return true
+	}
 	// Make the current primary version as new secondary version in case it's not the latest built version by rollout stage.
 	if primary.Version != version {
 		trafficCfg[provider.TrafficSecondaryVersionKeyName] = provider.VersionTraffic{
@@ -15,9 +15,12 @@
 package lambda
 
 import (
+	"context"
 	"testing"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -88,6 +91,20 @@ func TestConfigureTrafficRouting(t *testing.T) {
 			},
 			out: true,
 		},
+		{
+			name:    "configure successfully in case successive promotion of the current primary",
+			version: "2",
+			percent: 50,
+			primary: &provider.VersionTraffic{
+				Version: "2",
+				Percent: 10,
+			},
+			secondary: &provider.VersionTraffic{
+				Version: "1",
+				Percent: 90,
+			},
+			out: true,
+		},
 		{
 			name:    "configure successfully in case new primary is the same as current primary",
 			version: "2",
@@ -132,10 +149,122 @@ func TestConfigureTrafficRouting(t *testing.T) {
 			if primary, ok := trafficCfg[provider.TrafficPrimaryVersionKeyName]; ok {
 				assert.Equal(t, tc.version, primary.Version)
 				assert.Equal(t, float64(tc.percent), primary.Percent)
-				if secondary, ok := trafficCfg[provider.TrafficSecondaryVersionKeyName]; ok {
+				secondary, ok := trafficCfg[provider.TrafficSecondaryVersionKeyName]
+				if tc.percent == 100 {
+					// The secondary version must be removed once the new version receives all traffic.
+					assert.False(t, ok)
+				} else if ok {
 					assert.Equal(t, float64(100-tc.percent), secondary.Percent)
 				}
 			}
 		})
 	}
 }
+
+type fakeClient struct {
+	provider.Client
+	live                provider.FunctionManifestSpec
+	liveErr             error
+	codeSHA256          string
+	configurationUpdate int
+}
+
+func (c *fakeClient) GetCodeSHA256(_ context.Context, _ provider.FunctionManifest) (string, error) {
+	return c.codeSHA256, nil
+}
+
+func (c *fakeClient) GetFunction(_ context.Context, _ string) (provider.FunctionManifestSpec, error) {
+	return c.live, c.liveErr
+}
+
+func (c *fakeClient) UpdateFunctionConfiguration(_ context.Context, _ provider.FunctionManifest) error {
+	c.configurationUpdate++
+	return nil
+}
+
+func TestSyncConfiguration(t *testing.T) {
+	live := provider.FunctionManifestSpec{
+		Name:     "simple",
+		ImageURI: "ecr.region.amazonaws.com/lambda-simple-function:v0.0.1",
+		Role:     "arn:aws:iam::76xxxxxxx:role/lambda-role",
+		Memory:   512,
+		Timeout:  30,
+	}
+	zipLive := provider.FunctionManifestSpec{
+		Name:       "simple",
+		S3Bucket:   "pipecd-lambda",
+		S3Key:      "simple/v0.0.1.zip",
+		Runtime:    "go1.x",
+		Handler:    "main",
+		Role:       "arn:aws:iam::76xxxxxxx:role/lambda-role",
+		Memory:     512,
+		Timeout:    30,
+		CodeSHA256: "current-hash",
+	}
+	testcases := []struct {
+		name                        string
+		client                      *fakeClient
+		desired                     provider.FunctionManifestSpec
+		expectedUpdated             bool
+		expectedConfigurationUpdate int
+	}{
+		{
+			name:    "function not found",
+			client:  &fakeClient{liveErr: provider.ErrNotFound},
+			desired: live,
+		},
+		{
+			name:   "code was changed",
+			client: &fakeClient{live: live},
+			desired: func() provider.FunctionManifestSpec {
+				s := live
+				s.ImageURI = "ecr.region.amazonaws.com/lambda-simple-function:v0.0.2"
+				s.Memory = 1024
+				return s
+			}(),
+		},
+		{
+			name:   "only configuration was changed",
+			client: &fakeClient{live: live},
+			desired: func() provider.FunctionManifestSpec {
+				s := live
+				s.Environments = map[string]string{"FOO": "bar"}
+				return s
+			}(),
+			expectedUpdated:             true,
+			expectedConfigurationUpdate: 1,
+		},
+		{
+			name:   "zip archive was changed",
+			client: &fakeClient{live: zipLive, codeSHA256: "new-hash"},
+			desired: func() provider.FunctionManifestSpec {
+				s := zipLive
+				s.CodeSHA256 = ""
+				s.Environments = map[string]string{"FOO": "bar"}
+				return s
+			}(),
+		},
+		{
+			name:   "only configuration of zip archive was changed",
+			client: &fakeClient{live: zipLive, codeSHA256: zipLive.CodeSHA256},
+			desired: func() provider.FunctionManifestSpec {
+				s := zipLive
+				s.CodeSHA256 = ""
+				s.Environments = map[string]string{"FOO": "bar"}
+				return s
+			}(),
+			expectedUpdated:             true,
+			expectedConfigurationUpdate: 1,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := &executor.Input{LogPersister: &executortest.FakeLogPersister{}}
+			fm := provider.FunctionManifest{Spec: tc.desired}
+			updated, ok := syncConfiguration(context.Background(), in, tc.client, fm)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expectedUpdated, updated)
+			assert.Equal(t, tc.expectedConfigurationUpdate, tc.client.configurationUpdate)
+		})
+	}
+}
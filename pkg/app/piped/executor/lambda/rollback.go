@@ -30,14 +30,13 @@ type rollbackExecutor struct {
 
 func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	var (
-		ctx            = sig.Context()
 		originalStatus = e.Stage.Status
 		status         model.StageStatus
 	)
 
 	switch model.Stage(e.Stage.Name) {
 	case model.StageRollback:
-		status = e.ensureRollback(ctx)
+		status = e.ensureRollback(sig)
 	default:
 		e.LogPersister.Errorf("Unsupported stage %s for lambda application", e.Stage.Name)
 		return model.StageStatus_STAGE_FAILURE
@@ -46,13 +45,20 @@ func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
 }
 
-func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+func (e *rollbackExecutor) ensureRollback(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+
 	// Not rollback in case this is the first deployment.
 	if e.Deployment.RunningCommitHash == "" {
 		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Wait for an approval before reverting the changes if it was configured.
+	if ok, status := e.waitRollbackApproval(sig); !ok {
+		return status
+	}
+
 	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
 	if err != nil {
 		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
@@ -62,6 +68,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	deployCfg := runningDS.DeploymentConfig.LambdaDeploymentSpec
 	if deployCfg == nil {
 		e.LogPersister.Errorf("Malformed deployment configuration: missing LambdaDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing LambdaDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -70,7 +77,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
-	fm, ok := loadFunctionManifest(&e.Input, deployCfg.Input.FunctionManifestFile, runningDS)
+	fm, ok := loadFunctionManifest(&e.Input, deployCfg.Input, runningDS)
 	if !ok {
 		return model.StageStatus_STAGE_FAILURE
 	}
@@ -145,7 +152,7 @@ func rollback(ctx context.Context, in *executor.Input, cloudProviderName string,
 			return false
 		}
 
-		// Update promoted traffic config by add 0% SECONDARY for reset remote promoted version config.
+		// Route all traffic back to the original PRIMARY version and clear the SECONDARY one added by the promotion.
 		if !configureTrafficRouting(promotedTrafficCfg, primary.Version, 100) {
 			in.LogPersister.Errorf("Unable to prepare traffic config to rollback Lambda function %s: can not reset promoted version", fm.Spec.Name)
 			return false
@@ -161,3 +168,20 @@ func rollback(ctx context.Context, in *executor.Input, cloudProviderName string,
 		return false
 	}
 }
+
+func (e *rollbackExecutor) waitRollbackApproval(sig executor.StopSignal) (bool, model.StageStatus) {
+	ds, err := e.TargetDSP.GetReadOnly(sig.Context(), e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return false, model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := ds.DeploymentConfig.LambdaDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Errorf("Malformed deployment configuration: missing LambdaDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing LambdaDeploymentSpec"))
+		return false, model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.WaitRollbackApproval(sig, e.Input, deployCfg.Input.RollbackApproval)
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lambda
+
+import (
+	"context"
+	"fmt"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/trafficrouting"
+)
+
+// aliasRouter implements trafficrouting.Router for Lambda functions
+// by shifting the traffic of the function alias to the version built by rollout stage.
+// That version is considered as CANARY variant and the others as PRIMARY variant.
+type aliasRouter struct {
+	client        provider.Client
+	fm            provider.FunctionManifest
+	version       string
+	metadataStore executor.MetadataStore
+	// The metadata key where the traffic config is saved before being applied
+	// so that it can be restored by rollback. Empty means not saving.
+	trafficCfgMetadataKey string
+}
+
+// SetWeights does not support BASELINE variant.
+func (r *aliasRouter) SetWeights(ctx context.Context, weights trafficrouting.Weights) error {
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+	if weights.Baseline > 0 {
+		return fmt.Errorf("BASELINE variant: %w", trafficrouting.ErrUnsupported)
+	}
+
+	trafficCfg, err := r.client.GetTrafficConfig(ctx, r.fm)
+	if err != nil {
+		return fmt.Errorf("failed to get traffic config: %w", err)
+	}
+	if !configureTrafficRouting(trafficCfg, r.version, weights.Canary) {
+		return fmt.Errorf("missing primary version in traffic config")
+	}
+
+	if r.trafficCfgMetadataKey != "" {
+		data, err := trafficCfg.Encode()
+		if err != nil {
+			return fmt.Errorf("failed to encode traffic config: %w", err)
+		}
+		if err := r.metadataStore.Set(ctx, r.trafficCfgMetadataKey, data); err != nil {
+			return fmt.Errorf("failed to store traffic config: %w", err)
+		}
+	}
+
+	if err := r.client.UpdateTrafficConfig(ctx, r.fm, trafficCfg); err != nil {
+		return fmt.Errorf("failed to update traffic config: %w", err)
+	}
+	return nil
+}
+
+func (r *aliasRouter) GetWeights(ctx context.Context) (trafficrouting.Weights, error) {
+	trafficCfg, err := r.client.GetTrafficConfig(ctx, r.fm)
+	if err != nil {
+		return trafficrouting.Weights{}, fmt.Errorf("failed to get traffic config: %w", err)
+	}
+
+	var weights trafficrouting.Weights
+	for _, t := range trafficCfg {
+		if t.Version == r.version {
+			weights.Canary += int(t.Percent)
+			continue
+		}
+		weights.Primary += int(t.Percent)
+	}
+	return weights, weights.Validate()
+}
+
+// RouteByHeader is not supported because Lambda aliases route traffic only by weights.
+func (r *aliasRouter) RouteByHeader(_ context.Context, _ trafficrouting.HeaderRoute) error {
+	return trafficrouting.ErrUnsupported
+}
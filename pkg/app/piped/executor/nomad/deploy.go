@@ -0,0 +1,150 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/nomad"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type deployExecutor struct {
+	executor.Input
+
+	deployCfg *config.NomadDeploymentSpec
+	client    provider.Client
+	job       provider.Job
+}
+
+func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	ctx := sig.Context()
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.deployCfg = ds.DeploymentConfig.NomadDeploymentSpec
+	if e.deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing NomadDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing NomadDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var ok bool
+	if e.client, ok = loadClient(&e.Input); !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if e.job, ok = loadJob(ctx, &e.Input, e.client, e.deployCfg.Input, ds); !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var (
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageNomadSync:
+		status = e.ensureSync(ctx)
+
+	case model.StageNomadCanaryRollout:
+		status = e.ensureCanaryRollout(ctx)
+
+	case model.StageNomadPromote:
+		status = e.ensurePromote(ctx)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for nomad application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *deployExecutor) ensureSync(ctx context.Context) model.StageStatus {
+	if err := syncJob(ctx, e.client, e.LogPersister, e.job); err != nil {
+		e.LogPersister.Errorf("Failed to sync job %s (%v)", e.job.ID(), err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully synced job %s", e.job.ID())
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// ensureCanaryRollout runs the job and waits until its canaries become healthy.
+// The number of canaries is decided by the update stanza of the job.
+func (e *deployExecutor) ensureCanaryRollout(ctx context.Context) model.StageStatus {
+	index, changed, err := runJob(ctx, e.client, e.LogPersister, e.job)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to run job %s (%v)", e.job.ID(), err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if !changed || !isServiceJob(e.job) {
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Info("Waiting for the canaries to become healthy")
+	d, err := waitDeployment(ctx, e.client, e.LogPersister, e.job, index, func(d *provider.Deployment) bool {
+		return d.RequiresPromotion() && d.CanariesHealthy()
+	})
+	if err != nil {
+		e.LogPersister.Errorf("Failed while waiting for the canaries (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if d.IsTerminal() {
+		if err := checkSuccessful(d); err != nil {
+			e.LogPersister.Errorf("Failed to roll out canaries (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Info("No canary was configured in the update stanza, the job was fully rolled out")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+
+	e.LogPersister.Successf("Successfully rolled out canaries of job %s in deployment %s", e.job.ID(), d.ID)
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// ensurePromote promotes the canaries placed by the latest deployment of the job.
+func (e *deployExecutor) ensurePromote(ctx context.Context) model.StageStatus {
+	d, err := e.client.LatestDeployment(ctx, e.job.Namespace(), e.job.ID())
+	if err != nil {
+		e.LogPersister.Errorf("Failed to get the latest deployment of job %s (%v)", e.job.ID(), err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if d.IsTerminal() {
+		if err := checkSuccessful(d); err != nil {
+			e.LogPersister.Errorf("Unable to promote the deployment (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Infof("Deployment %s has already completed, there is nothing to promote", d.ID)
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	if !d.RequiresPromotion() {
+		e.LogPersister.Errorf("Deployment %s has no canary to promote", d.ID)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if err := promoteDeployment(ctx, e.client, e.LogPersister, e.job, d); err != nil {
+		e.LogPersister.Errorf("Failed to promote canaries (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully promoted canaries of job %s", e.job.ID())
+	return model.StageStatus_STAGE_SUCCESS
+}
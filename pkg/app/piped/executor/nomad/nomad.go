@@ -0,0 +1,246 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/nomad"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// How often to check the state of the deployment.
+var pollInterval = 5 * time.Second
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+	RegisterRollback(kind model.ApplicationKind, f executor.Factory) error
+}
+
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &deployExecutor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageNomadSync, f)
+	r.Register(model.StageNomadCanaryRollout, f)
+	r.Register(model.StageNomadPromote, f)
+
+	r.RegisterRollback(model.ApplicationKind_NOMAD, func(in executor.Input) executor.Executor {
+		return &rollbackExecutor{
+			Input: in,
+		}
+	})
+}
+
+func findCloudProvider(in *executor.Input) (name string, cfg *config.CloudProviderNomadConfig, found bool) {
+	name = in.Application.CloudProvider
+	if name == "" {
+		in.LogPersister.Error("Missing the CloudProvider name in the application configuration")
+		return
+	}
+
+	cp, ok := in.PipedConfig.FindCloudProvider(name, model.CloudProviderNomad)
+	if !ok {
+		in.LogPersister.Errorf("The specified cloud provider %q was not found in piped configuration", name)
+		return
+	}
+
+	cfg = cp.NomadConfig
+	found = true
+	return
+}
+
+func loadClient(in *executor.Input) (provider.Client, bool) {
+	name, cfg, found := findCloudProvider(in)
+	if !found {
+		return nil, false
+	}
+	client, err := provider.DefaultRegistry().Client(name, cfg, in.Logger)
+	if err != nil {
+		in.LogPersister.Errorf("Unable to create Nomad client for the provider %s (%v)", name, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// loadJob loads the job specification at the given deploy source.
+// HCL specification is converted into JSON by Nomad.
+func loadJob(ctx context.Context, in *executor.Input, client provider.Client, cfg config.NomadDeploymentInput, ds *deploysource.DeploySource) (provider.Job, bool) {
+	in.LogPersister.Infof("Loading job file at commit %s", ds.Revision)
+	data, isJSON, err := provider.LoadJob(ds.AppDir, cfg.JobFile)
+	if err != nil {
+		in.LogPersister.Errorf("Failed to load job file (%v)", err)
+		return nil, false
+	}
+
+	var job provider.Job
+	if isJSON {
+		job, err = decodeJSONJob(data)
+	} else {
+		job, err = client.ParseJob(ctx, string(data))
+	}
+	if err != nil {
+		in.LogPersister.Errorf("Failed to parse job file (%v)", err)
+		return nil, false
+	}
+	if job.ID() == "" {
+		in.LogPersister.Error("Malformed job file: missing job ID")
+		return nil, false
+	}
+	if cfg.Namespace != "" {
+		job["Namespace"] = cfg.Namespace
+	}
+
+	in.LogPersister.Infof("Successfully loaded job %s at commit %s", job.ID(), ds.Revision)
+	return job, true
+}
+
+// decodeJSONJob decodes the given JSON job specification
+// which can be either the output of "nomad job run -output" or the bare job.
+func decodeJSONJob(data []byte) (provider.Job, error) {
+	var wrapped struct {
+		Job provider.Job
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, err
+	}
+	if wrapped.Job != nil {
+		return wrapped.Job, nil
+	}
+	var job provider.Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// runJob plans the given job and registers it when it has some changes.
+// The modify index of the registered job is returned to find the deployment created by it.
+func runJob(ctx context.Context, client provider.Client, lp executor.LogPersister, job provider.Job) (index uint64, changed bool, err error) {
+	plan, err := client.PlanJob(ctx, job)
+	if err != nil {
+		return 0, false, err
+	}
+	if plan.HasNoChanges() {
+		lp.Infof("Job %s is already up to date", job.ID())
+		return 0, false, nil
+	}
+	lp.Infof("Planned changes of job %s:", job.ID())
+	for _, s := range plan.Summary() {
+		lp.Infof("  %s", s)
+	}
+	if plan.Warnings != "" {
+		lp.Infof("Warnings: %s", plan.Warnings)
+	}
+
+	index, err = client.RegisterJob(ctx, job)
+	if err != nil {
+		return 0, false, err
+	}
+	lp.Infof("Registered job %s, its modify index is %d", job.ID(), index)
+	return index, true, nil
+}
+
+// isServiceJob returns whether Nomad creates a deployment for the given job.
+// Only service jobs are deployed with the update stanza.
+func isServiceJob(job provider.Job) bool {
+	t, _ := job["Type"].(string)
+	return t == "" || t == "service"
+}
+
+// waitDeployment blocks until the deployment created by registering the job at the given index
+// satisfies the given condition or becomes terminal.
+// The timeout is left to the stage timeout.
+func waitDeployment(ctx context.Context, client provider.Client, lp executor.LogPersister, job provider.Job, index uint64, cond func(*provider.Deployment) bool) (*provider.Deployment, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var desc string
+	for {
+		d, err := client.LatestDeployment(ctx, job.Namespace(), job.ID())
+		switch {
+		case errors.Is(err, provider.ErrDeploymentNotFound):
+			// The deployment has not been created yet.
+		case err != nil:
+			return nil, err
+		case d.JobModifyIndex < index:
+			// This is the deployment of the previous job.
+		case d.IsTerminal() || cond(d):
+			return d, nil
+		case d.StatusDescription != desc:
+			lp.Infof("Deployment %s is %s: %s", d.ID, d.Status, d.StatusDescription)
+			desc = d.StatusDescription
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("deployment of job %s did not complete: %w", job.ID(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// promoteDeployment promotes the canaries of the given deployment and waits until it completes.
+func promoteDeployment(ctx context.Context, client provider.Client, lp executor.LogPersister, job provider.Job, d *provider.Deployment) error {
+	if err := client.PromoteDeployment(ctx, job.Namespace(), d.ID); err != nil {
+		return err
+	}
+	lp.Infof("Promoted canaries of deployment %s, waiting for it to complete", d.ID)
+
+	d, err := waitDeployment(ctx, client, lp, job, d.JobModifyIndex, func(*provider.Deployment) bool { return false })
+	if err != nil {
+		return err
+	}
+	return checkSuccessful(d)
+}
+
+// syncJob runs the given job and promotes its canaries if any.
+func syncJob(ctx context.Context, client provider.Client, lp executor.LogPersister, job provider.Job) error {
+	index, changed, err := runJob(ctx, client, lp, job)
+	if err != nil || !changed {
+		return err
+	}
+	if !isServiceJob(job) {
+		return nil
+	}
+
+	lp.Info("Waiting for the deployment to complete")
+	d, err := waitDeployment(ctx, client, lp, job, index, func(d *provider.Deployment) bool {
+		return d.RequiresPromotion() && d.CanariesHealthy()
+	})
+	if err != nil {
+		return err
+	}
+	if d.IsTerminal() {
+		return checkSuccessful(d)
+	}
+	return promoteDeployment(ctx, client, lp, job, d)
+}
+
+func checkSuccessful(d *provider.Deployment) error {
+	if d.Status != provider.DeploymentStatusSuccessful {
+		return fmt.Errorf("deployment %s was %s: %s", d.ID, d.Status, d.StatusDescription)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/nomad"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+)
+
+type fakeClient struct {
+	provider.Client
+
+	plan        *provider.PlanResult
+	deployments []*provider.Deployment
+
+	registered bool
+	promoted   []string
+}
+
+func (c *fakeClient) PlanJob(_ context.Context, _ provider.Job) (*provider.PlanResult, error) {
+	return c.plan, nil
+}
+
+func (c *fakeClient) RegisterJob(_ context.Context, _ provider.Job) (uint64, error) {
+	c.registered = true
+	return 10, nil
+}
+
+func (c *fakeClient) LatestDeployment(_ context.Context, _, _ string) (*provider.Deployment, error) {
+	if len(c.deployments) == 0 {
+		return nil, provider.ErrDeploymentNotFound
+	}
+	d := c.deployments[0]
+	if len(c.deployments) > 1 {
+		c.deployments = c.deployments[1:]
+	}
+	return d, nil
+}
+
+func (c *fakeClient) PromoteDeployment(_ context.Context, _, id string) error {
+	c.promoted = append(c.promoted, id)
+	return nil
+}
+
+func TestSyncJob(t *testing.T) {
+	pollInterval = time.Millisecond
+
+	var (
+		changed  = &provider.PlanResult{Diff: &provider.JobDiff{Type: "Edited"}}
+		previous = &provider.Deployment{ID: "previous", JobModifyIndex: 5, Status: provider.DeploymentStatusSuccessful}
+		canaries = map[string]*provider.DeploymentState{
+			"web": {DesiredCanaries: 1, DesiredTotal: 3, HealthyAllocs: 1},
+		}
+		promoted = map[string]*provider.DeploymentState{
+			"web": {Promoted: true, DesiredCanaries: 1, DesiredTotal: 3, HealthyAllocs: 3},
+		}
+	)
+
+	testcases := []struct {
+		name           string
+		job            provider.Job
+		client         *fakeClient
+		wantRegistered bool
+		wantPromoted   []string
+		wantErr        bool
+	}{
+		{
+			name: "no changes",
+			job:  provider.Job{"ID": "web"},
+			client: &fakeClient{
+				plan: &provider.PlanResult{Diff: &provider.JobDiff{Type: "None"}},
+			},
+		},
+		{
+			name: "batch job",
+			job:  provider.Job{"ID": "web", "Type": "batch"},
+			client: &fakeClient{
+				plan: changed,
+			},
+			wantRegistered: true,
+		},
+		{
+			name: "rolling update",
+			job:  provider.Job{"ID": "web"},
+			client: &fakeClient{
+				plan: changed,
+				deployments: []*provider.Deployment{
+					previous,
+					{ID: "new", JobModifyIndex: 10, Status: provider.DeploymentStatusRunning},
+					{ID: "new", JobModifyIndex: 10, Status: provider.DeploymentStatusSuccessful},
+				},
+			},
+			wantRegistered: true,
+		},
+		{
+			name: "promote canaries",
+			job:  provider.Job{"ID": "web"},
+			client: &fakeClient{
+				plan: changed,
+				deployments: []*provider.Deployment{
+					{ID: "new", JobModifyIndex: 10, Status: provider.DeploymentStatusRunning, TaskGroups: canaries},
+					{ID: "new", JobModifyIndex: 10, Status: provider.DeploymentStatusSuccessful, TaskGroups: promoted},
+				},
+			},
+			wantRegistered: true,
+			wantPromoted:   []string{"new"},
+		},
+		{
+			name: "deployment failed",
+			job:  provider.Job{"ID": "web"},
+			client: &fakeClient{
+				plan: changed,
+				deployments: []*provider.Deployment{
+					{ID: "new", JobModifyIndex: 10, Status: provider.DeploymentStatusFailed, StatusDescription: "Failed due to unhealthy allocations"},
+				},
+			},
+			wantRegistered: true,
+			wantErr:        true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := syncJob(context.Background(), tc.client, &executortest.FakeLogPersister{}, tc.job)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantRegistered, tc.client.registered)
+			assert.Equal(t, tc.wantPromoted, tc.client.promoted)
+		})
+	}
+}
+
+func TestDecodeJSONJob(t *testing.T) {
+	testcases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "wrapped job",
+			data: `{"Job": {"ID": "web", "Type": "service"}}`,
+		},
+		{
+			name: "bare job",
+			data: `{"ID": "web", "Type": "service"}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, err := decodeJSONJob([]byte(tc.data))
+			require.NoError(t, err)
+			assert.Equal(t, "web", job.ID())
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nomad
+
+import (
+	"context"
+
+	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/nomad"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type rollbackExecutor struct {
+	executor.Input
+	client provider.Client
+}
+
+func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+		status         model.StageStatus
+	)
+
+	var ok bool
+	if e.client, ok = loadClient(&e.Input); !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	switch model.Stage(e.Stage.Name) {
+	case model.StageRollback:
+		status = e.ensureRollback(ctx)
+
+	default:
+		e.LogPersister.Errorf("Unsupported stage %s for nomad application", e.Stage.Name)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+}
+
+func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
+	// There is nothing to do if this is the first deployment.
+	if e.Deployment.RunningCommitHash == "" {
+		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	runningDS, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare running deploy source data (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	deployCfg := runningDS.DeploymentConfig.NomadDeploymentSpec
+	if deployCfg == nil {
+		e.LogPersister.Error("Malformed deployment configuration: missing NomadDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing NomadDeploymentSpec"))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	job, ok := loadJob(ctx, &e.Input, e.client, deployCfg.Input, runningDS)
+	if !ok {
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	// Stop the in-progress deployment from placing more allocations of the new job.
+	d, err := e.client.LatestDeployment(ctx, job.Namespace(), job.ID())
+	switch {
+	case err == provider.ErrDeploymentNotFound:
+	case err != nil:
+		e.LogPersister.Errorf("Failed to get the latest deployment of job %s (%v)", job.ID(), err)
+		return model.StageStatus_STAGE_FAILURE
+	case !d.IsTerminal():
+		if err := e.client.FailDeployment(ctx, job.Namespace(), d.ID); err != nil {
+			e.LogPersister.Errorf("Failed to stop deployment %s (%v)", d.ID, err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		e.LogPersister.Infof("Stopped the in-progress deployment %s", d.ID)
+	}
+
+	if err := syncJob(ctx, e.client, e.LogPersister, job); err != nil {
+		e.LogPersister.Errorf("Failed to rollback job %s (%v)", job.ID(), err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Successf("Successfully rolled back job %s to commit %s", job.ID(), e.Deployment.RunningCommitHash)
+	return model.StageStatus_STAGE_SUCCESS
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type Executor struct {
+	executor.Input
+}
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+}
+
+// Register registers this executor factory into a given registerer.
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageNotify, f)
+}
+
+// Execute renders the configured message and sends it to the notification receivers of piped.
+// Sending is done asynchronously by the notifier so the stage does not wait for the receivers.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	opts := e.StageConfig.NotifyStageOptions
+	if opts == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	if err := checkReceivers(e.PipedConfig, opts.Receivers); err != nil {
+		e.LogPersister.Error(err.Error())
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	msg, err := executor.RenderTemplate("message", opts.Message, e.TemplateArgs())
+	if err != nil {
+		e.LogPersister.Errorf("Failed to render the message (%v)", err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_MESSAGE,
+		Metadata: &model.NotificationEventDeploymentMessage{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			StageId:    e.Stage.Id,
+			Message:    msg,
+			Receivers:  opts.Receivers,
+		},
+	})
+
+	if len(opts.Receivers) > 0 {
+		e.LogPersister.Successf("Sent the message to %v: %s", opts.Receivers, msg)
+	} else {
+		e.LogPersister.Successf("Sent the message to the receivers of the matching notification routes: %s", msg)
+	}
+	return model.StageStatus_STAGE_SUCCESS
+}
+
+// checkReceivers ensures that all specified receivers are configured in piped.
+func checkReceivers(cfg *config.PipedSpec, receivers []string) error {
+	if len(receivers) == 0 {
+		return nil
+	}
+	configured := make(map[string]struct{})
+	if cfg != nil {
+		for _, r := range cfg.GetNotifications().Receivers {
+			configured[r.Name] = struct{}{}
+		}
+	}
+	for _, r := range receivers {
+		if _, ok := configured[r]; !ok {
+			return executor.NewUserError("notification receiver %s is not configured in piped", r)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestCheckReceivers(t *testing.T) {
+	cfg := &config.PipedSpec{
+		Notifications: config.Notifications{
+			Receivers: []config.NotificationReceiver{
+				{Name: "dev-slack"},
+			},
+		},
+	}
+	testcases := []struct {
+		name      string
+		cfg       *config.PipedSpec
+		receivers []string
+		wantErr   bool
+	}{
+		{
+			name: "no receivers",
+			cfg:  nil,
+		},
+		{
+			name:      "configured receiver",
+			cfg:       cfg,
+			receivers: []string{"dev-slack"},
+		},
+		{
+			name:      "unknown receiver",
+			cfg:       cfg,
+			receivers: []string{"dev-slack", "prod-slack"},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkReceivers(tc.cfg, tc.receivers)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
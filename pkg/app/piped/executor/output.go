@@ -0,0 +1,53 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// PublishOutputs stores the given values of the outputs declared by the stage
+// into its metadata to make them available in the templates of the later stages.
+// The returned error is a user error when the values do not follow the declarations.
+func (in Input) PublishOutputs(ctx context.Context, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	for name, v := range values {
+		o, ok := in.Stage.FindOutput(name)
+		if !ok {
+			return NewUserError("output %s is not declared by stage %s", name, in.Stage.Name)
+		}
+		if _, err := o.ParseValue(v); err != nil {
+			return NewUserError("invalid output of stage %s: %w", in.Stage.Name, err)
+		}
+	}
+
+	current, _ := in.MetadataStore.GetStageMetadata(in.Stage.Id)
+	metadata := make(map[string]string, len(current)+len(values))
+	for k, v := range current {
+		metadata[k] = v
+	}
+	for name, v := range values {
+		metadata[model.StageOutputMetadataKey(name)] = v
+	}
+	if err := in.MetadataStore.SetStageMetadata(ctx, in.Stage.Id, metadata); err != nil {
+		return fmt.Errorf("failed to store the outputs of stage %s: %w", in.Stage.Name, err)
+	}
+	return nil
+}
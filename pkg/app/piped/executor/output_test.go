@@ -0,0 +1,122 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeMetadataStore struct {
+	stages map[string]map[string]string
+}
+
+func (s *fakeMetadataStore) Get(_ string) (string, bool)              { return "", false }
+func (s *fakeMetadataStore) Set(_ context.Context, _, _ string) error { return nil }
+
+func (s *fakeMetadataStore) GetStageMetadata(id string) (map[string]string, bool) {
+	m, ok := s.stages[id]
+	return m, ok
+}
+
+func (s *fakeMetadataStore) SetStageMetadata(_ context.Context, id string, metadata map[string]string) error {
+	s.stages[id] = metadata
+	return nil
+}
+
+func TestPublishOutputs(t *testing.T) {
+	stage := &model.PipelineStage{
+		Id:   "plan",
+		Name: "SCRIPT_RUN",
+		Outputs: []*model.StageOutput{
+			{Name: "Endpoint", Type: model.StageOutputType_STAGE_OUTPUT_STRING},
+			{Name: "Replicas", Type: model.StageOutputType_STAGE_OUTPUT_NUMBER},
+		},
+	}
+	testcases := []struct {
+		name         string
+		values       map[string]string
+		wantMetadata map[string]string
+		wantErr      bool
+	}{
+		{
+			name:   "declared outputs",
+			values: map[string]string{"Endpoint": "https://example.com", "Replicas": "3"},
+			wantMetadata: map[string]string{
+				"existing":        "value",
+				"Output.Endpoint": "https://example.com",
+				"Output.Replicas": "3",
+			},
+		},
+		{
+			name:         "undeclared output",
+			values:       map[string]string{"Version": "v1"},
+			wantMetadata: map[string]string{"existing": "value"},
+			wantErr:      true,
+		},
+		{
+			name:         "value does not match the type",
+			values:       map[string]string{"Replicas": "three"},
+			wantMetadata: map[string]string{"existing": "value"},
+			wantErr:      true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &fakeMetadataStore{
+				stages: map[string]map[string]string{
+					"plan": {"existing": "value"},
+				},
+			}
+			in := Input{
+				Stage:         stage,
+				MetadataStore: store,
+			}
+			err := in.PublishOutputs(context.Background(), tc.values)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantMetadata, store.stages["plan"])
+		})
+	}
+}
+
+func TestStageOutputValues(t *testing.T) {
+	in := Input{
+		Deployment: &model.Deployment{
+			Stages: []*model.PipelineStage{
+				{
+					Id:      "plan",
+					Outputs: []*model.StageOutput{{Name: "HasChanges", Type: model.StageOutputType_STAGE_OUTPUT_BOOL}},
+				},
+				{
+					Id: "wait",
+				},
+			},
+		},
+		MetadataStore: &fakeMetadataStore{
+			stages: map[string]map[string]string{
+				"plan": {"Output.HasChanges": "true"},
+				"wait": {"Output.HasChanges": "true"},
+			},
+		},
+	}
+	got, err := RenderTemplate("message", "changed: {{ .Outputs.plan.HasChanges }}", in.TemplateArgs())
+	assert.NoError(t, err)
+	assert.Equal(t, "changed: true", got)
+	assert.Equal(t, map[string]map[string]interface{}{"plan": {"HasChanges": true}}, in.stageOutputValues())
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/plugin/pluginservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type executeStageClient interface {
+	ExecuteStage(ctx context.Context, in *pluginservice.ExecuteStageRequest, opts ...grpc.CallOption) (pluginservice.ExecutorPlugin_ExecuteStageClient, error)
+}
+
+// Executor executes a stage by calling the plugin providing it.
+type Executor struct {
+	executor.Input
+	plugin string
+	client executeStageClient
+}
+
+// Execute sends the stage to the plugin and streams back its logs and metadata
+// until the plugin reports the final status of the stage.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		ctx            = sig.Context()
+		originalStatus = e.Stage.Status
+	)
+
+	req, err := e.buildRequest(ctx, sig)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare the deploy source data (%v)", err)
+		e.ReportError(fmt.Errorf("failed to prepare the deploy source data: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	e.LogPersister.Infof("Executing %s stage by executor plugin %s", e.Stage.Name, e.plugin)
+	status, err := e.execute(ctx, req)
+	if sig.Signal() != executor.StopSignalNone {
+		// The call was cancelled by the stop signal.
+		return executor.DetermineStageStatus(sig.Signal(), originalStatus, status)
+	}
+	if err != nil {
+		e.LogPersister.Errorf("Failed to execute the stage by executor plugin %s (%v)", e.plugin, err)
+		e.ReportError(err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	return status
+}
+
+func (e *Executor) buildRequest(ctx context.Context, sig executor.StopSignal) (*pluginservice.ExecuteStageRequest, error) {
+	req := &pluginservice.ExecuteStageRequest{
+		Stage:        e.Stage,
+		StageOptions: e.StageConfig.PluginStageOptions,
+		Deployment:   e.Deployment,
+	}
+	if deadline, ok := sig.Deadline(); ok {
+		req.Deadline = deadline.Unix()
+	}
+
+	ds, err := e.TargetDSP.GetReadOnly(ctx, e.LogPersister)
+	if err != nil {
+		return nil, err
+	}
+	req.TargetAppDir = ds.AppDir
+
+	if e.RunningDSP != nil {
+		ds, err := e.RunningDSP.GetReadOnly(ctx, e.LogPersister)
+		if err != nil {
+			return nil, err
+		}
+		req.RunningAppDir = ds.AppDir
+	}
+	return req, nil
+}
+
+// execute calls the plugin and returns the status sent by it.
+func (e *Executor) execute(ctx context.Context, req *pluginservice.ExecuteStageRequest) (model.StageStatus, error) {
+	stream, err := e.client.ExecuteStage(ctx, req)
+	if err != nil {
+		return model.StageStatus_STAGE_FAILURE, fmt.Errorf("failed to call executor plugin %s: %w", e.plugin, err)
+	}
+
+	var (
+		status = model.StageStatus_STAGE_NOT_STARTED_YET
+		reason string
+	)
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return model.StageStatus_STAGE_FAILURE, fmt.Errorf("failed to receive from executor plugin %s: %w", e.plugin, err)
+		}
+
+		if resp.Log != "" {
+			e.writeLog(resp)
+		}
+		if len(resp.Metadata) > 0 {
+			if err := e.saveMetadata(ctx, resp.Metadata); err != nil {
+				e.Logger.Error("failed to save the stage metadata sent by executor plugin",
+					zap.String("plugin", e.plugin),
+					zap.Error(err),
+				)
+			}
+		}
+		if resp.Status != model.StageStatus_STAGE_NOT_STARTED_YET {
+			status, reason = resp.Status, resp.Error
+		}
+	}
+
+	switch status {
+	case model.StageStatus_STAGE_SUCCESS:
+		return status, nil
+	case model.StageStatus_STAGE_SUCCESS_WITH_WARNINGS:
+		if reason == "" {
+			reason = fmt.Sprintf("executor plugin %s reported warnings", e.plugin)
+		}
+		e.ReportWarning("%s", reason)
+		return model.StageStatus_STAGE_SUCCESS, nil
+	case model.StageStatus_STAGE_FAILURE:
+		if reason != "" {
+			e.ReportError(errors.New(reason))
+		}
+		return status, nil
+	case model.StageStatus_STAGE_NOT_STARTED_YET:
+		return model.StageStatus_STAGE_FAILURE, fmt.Errorf("executor plugin %s finished without reporting the stage status", e.plugin)
+	default:
+		return model.StageStatus_STAGE_FAILURE, fmt.Errorf("executor plugin %s reported unsupported stage status %s", e.plugin, status)
+	}
+}
+
+// writeLog appends the log sent by the plugin with its severity and fields.
+// The malformed fields are dropped to keep the log itself.
+func (e *Executor) writeLog(resp *pluginservice.ExecuteStageResponse) {
+	var fields map[string]interface{}
+	if resp.LogFields != "" {
+		if err := json.Unmarshal([]byte(resp.LogFields), &fields); err != nil {
+			e.Logger.Warn("ignored the malformed log fields sent by executor plugin",
+				zap.String("plugin", e.plugin),
+				zap.Error(err),
+			)
+		}
+	}
+	e.LogPersister.Log(resp.LogSeverity, resp.Log, fields)
+}
+
+// saveMetadata merges the given metadata into the current stage metadata.
+func (e *Executor) saveMetadata(ctx context.Context, metadata map[string]string) error {
+	current, _ := e.MetadataStore.GetStageMetadata(e.Stage.Id)
+	merged := make(map[string]string, len(current)+len(metadata))
+	for k, v := range current {
+		merged[k] = v
+	}
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	return e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, merged)
+}
@@ -0,0 +1,177 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/plugin/pluginservice"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeMetadataStore struct {
+	executor.MetadataStore
+	metadata map[string]string
+}
+
+func (s *fakeMetadataStore) GetStageMetadata(_ string) (map[string]string, bool) {
+	return s.metadata, s.metadata != nil
+}
+
+func (s *fakeMetadataStore) SetStageMetadata(_ context.Context, _ string, metadata map[string]string) error {
+	s.metadata = metadata
+	return nil
+}
+
+type fakeStream struct {
+	grpc.ClientStream
+	responses []*pluginservice.ExecuteStageResponse
+	err       error
+}
+
+func (s *fakeStream) Recv() (*pluginservice.ExecuteStageResponse, error) {
+	if len(s.responses) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+type fakeClient struct {
+	stream *fakeStream
+}
+
+func (c *fakeClient) ExecuteStage(_ context.Context, _ *pluginservice.ExecuteStageRequest, _ ...grpc.CallOption) (pluginservice.ExecutorPlugin_ExecuteStageClient, error) {
+	return c.stream, nil
+}
+
+func TestParseStages(t *testing.T) {
+	testcases := []struct {
+		name    string
+		names   []string
+		want    []model.Stage
+		wantErr bool
+	}{
+		{
+			name:  "plugin stages",
+			names: []string{"PLUGIN_MAINFRAME_DEPLOY", "PLUGIN_MAINFRAME_SWITCH"},
+			want:  []model.Stage{"PLUGIN_MAINFRAME_DEPLOY", "PLUGIN_MAINFRAME_SWITCH"},
+		},
+		{
+			name:    "stage without the prefix",
+			names:   []string{"PLUGIN_MAINFRAME_DEPLOY", "K8S_SYNC"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseStages(tc.names)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	testcases := []struct {
+		name         string
+		responses    []*pluginservice.ExecuteStageResponse
+		recvErr      error
+		want         model.StageStatus
+		wantErr      bool
+		wantMetadata map[string]string
+	}{
+		{
+			name: "succeeded with metadata",
+			responses: []*pluginservice.ExecuteStageResponse{
+				{Log: "deploying", Metadata: map[string]string{"job": "1"}},
+				{Log: "deployed", LogSeverity: model.LogSeverity_SUCCESS, Metadata: map[string]string{"version": "2"}},
+				{Status: model.StageStatus_STAGE_SUCCESS},
+			},
+			want:         model.StageStatus_STAGE_SUCCESS,
+			wantMetadata: map[string]string{"job": "1", "version": "2"},
+		},
+		{
+			name: "failed",
+			responses: []*pluginservice.ExecuteStageResponse{
+				{Status: model.StageStatus_STAGE_FAILURE, Error: "job failed"},
+			},
+			want: model.StageStatus_STAGE_FAILURE,
+		},
+		{
+			name: "succeeded with warnings",
+			responses: []*pluginservice.ExecuteStageResponse{
+				{Status: model.StageStatus_STAGE_SUCCESS_WITH_WARNINGS, Error: "slow job"},
+			},
+			want: model.StageStatus_STAGE_SUCCESS,
+		},
+		{
+			name: "finished without status",
+			responses: []*pluginservice.ExecuteStageResponse{
+				{Log: "deploying"},
+			},
+			want:    model.StageStatus_STAGE_FAILURE,
+			wantErr: true,
+		},
+		{
+			name:    "connection lost",
+			recvErr: errors.New("connection reset"),
+			want:    model.StageStatus_STAGE_FAILURE,
+			wantErr: true,
+		},
+		{
+			name: "unsupported status",
+			responses: []*pluginservice.ExecuteStageResponse{
+				{Status: model.StageStatus_STAGE_CANCELLED},
+			},
+			want:    model.StageStatus_STAGE_FAILURE,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ms := &fakeMetadataStore{}
+			e := &Executor{
+				Input: executor.Input{
+					Stage:         &model.PipelineStage{Id: "stage-1"},
+					LogPersister:  &executortest.FakeLogPersister{},
+					MetadataStore: ms,
+					Logger:        zap.NewNop(),
+				},
+				plugin: "mainframe",
+				client: &fakeClient{
+					stream: &fakeStream{responses: tc.responses, err: tc.recvErr},
+				},
+			}
+			got, err := e.execute(context.Background(), &pluginservice.ExecuteStageRequest{})
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantMetadata, ms.metadata)
+		})
+	}
+}
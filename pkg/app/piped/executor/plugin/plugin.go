@@ -0,0 +1,125 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin provides the executors of the stages
+// served by the external processes implementing the ExecutorPlugin gRPC service.
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/plugin/pluginservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+	"github.com/pipe-cd/pipe/pkg/rpc/rpcclient"
+)
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+}
+
+// Plugin is a connection to an executor plugin.
+type Plugin struct {
+	name   string
+	client pluginservice.Client
+	stages []model.Stage
+	logger *zap.Logger
+}
+
+// Connect connects to the given executor plugin and lists the stages provided by it.
+func Connect(ctx context.Context, cfg config.PipedExecutorPlugin, logger *zap.Logger) (*Plugin, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.GetConnectTimeout())
+	defer cancel()
+
+	options := []rpcclient.DialOption{
+		rpcclient.WithBlock(),
+	}
+	if cfg.CertFile != "" {
+		options = append(options, rpcclient.WithTLS(cfg.CertFile))
+	} else {
+		options = append(options, rpcclient.WithInsecure())
+	}
+	client, err := pluginservice.NewClient(ctx, cfg.Address, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to executor plugin %s: %w", cfg.Name, err)
+	}
+
+	resp, err := client.ListStages(ctx, &pluginservice.ListStagesRequest{})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to list the stages of executor plugin %s: %w", cfg.Name, err)
+	}
+	stages, err := parseStages(resp.Stages)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("invalid stages of executor plugin %s: %w", cfg.Name, err)
+	}
+
+	return &Plugin{
+		name:   cfg.Name,
+		client: client,
+		stages: stages,
+		logger: logger.Named("executor-plugin").With(zap.String("plugin", cfg.Name)),
+	}, nil
+}
+
+func parseStages(names []string) ([]model.Stage, error) {
+	stages := make([]model.Stage, 0, len(names))
+	for _, name := range names {
+		stage := model.Stage(name)
+		if !stage.IsPlugin() {
+			return nil, fmt.Errorf("stage name %q must start with %s", name, model.PluginStagePrefix)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+// Stages returns the stages provided by the plugin.
+func (p *Plugin) Stages() []model.Stage {
+	return p.stages
+}
+
+// Register registers the executor factories of all stages provided by the plugin.
+// It fails if any of them has already been registered, e.g. by another plugin.
+func (p *Plugin) Register(r registerer) error {
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input:  in,
+			plugin: p.name,
+			client: p.client,
+		}
+	}
+	for _, stage := range p.stages {
+		if err := r.Register(stage, f); err != nil {
+			return err
+		}
+		p.logger.Info(fmt.Sprintf("registered %s stage", stage))
+	}
+	return nil
+}
+
+// Close closes the connection to the plugin.
+func (p *Plugin) Close() error {
+	return p.client.Close()
+}
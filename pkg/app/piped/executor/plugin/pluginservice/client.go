@@ -0,0 +1,48 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginservice
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/rpc/rpcclient"
+)
+
+type Client interface {
+	ExecutorPluginClient
+	Close() error
+}
+
+type client struct {
+	ExecutorPluginClient
+	conn *grpc.ClientConn
+}
+
+func NewClient(ctx context.Context, addr string, opts ...rpcclient.DialOption) (Client, error) {
+	conn, err := rpcclient.DialContext(ctx, addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		ExecutorPluginClient: NewExecutorPluginClient(conn),
+		conn:                 conn,
+	}, nil
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
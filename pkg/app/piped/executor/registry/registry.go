@@ -20,10 +20,19 @@ import (
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/analysis"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/appengine"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/azurefunctions"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/cloudformation"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/customsync"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/ecs"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/eventpublish"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/nomad"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/notify"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/plugin"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/scriptrun"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/terraform"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/wait"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor/waitapproval"
@@ -94,14 +103,28 @@ func DefaultRegistry() Registry {
 	return defaultRegistry
 }
 
+// RegisterPlugin registers the stages provided by the given executor plugin
+// to the default registry.
+func RegisterPlugin(p *plugin.Plugin) error {
+	return p.Register(defaultRegistry)
+}
+
 // init registers all built-in executors to the default registry.
 func init() {
 	analysis.Register(defaultRegistry)
+	appengine.Register(defaultRegistry)
+	azurefunctions.Register(defaultRegistry)
+	cloudformation.Register(defaultRegistry)
 	cloudrun.Register(defaultRegistry)
+	customsync.Register(defaultRegistry)
 	kubernetes.Register(defaultRegistry)
 	lambda.Register(defaultRegistry)
+	nomad.Register(defaultRegistry)
 	terraform.Register(defaultRegistry)
 	ecs.Register(defaultRegistry)
 	wait.Register(defaultRegistry)
 	waitapproval.Register(defaultRegistry)
+	scriptrun.Register(defaultRegistry)
+	notify.Register(defaultRegistry)
+	eventpublish.Register(defaultRegistry)
 }
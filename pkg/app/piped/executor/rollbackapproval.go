@@ -0,0 +1,131 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	rollbackApprovedByKey = "ApprovedBy"
+)
+
+// WaitRollbackApproval blocks the rollback stage until receiving an approval
+// when the deployment failed at an ANALYSIS stage and the approval was configured.
+// It returns true when the rollback should be executed,
+// otherwise the returned status should be used as the result of the rollback stage.
+func WaitRollbackApproval(sig StopSignal, in Input, cfg *config.RollbackApproval) (bool, model.StageStatus) {
+	if cfg == nil || !failedAtAnalysisStage(in) {
+		return true, model.StageStatus_STAGE_RUNNING
+	}
+
+	// The rollback might have been approved already,
+	// e.g. by the onFailure policy of the pipeline or before piped was restarted.
+	if md, ok := in.MetadataStore.GetStageMetadata(in.Stage.Id); ok && md[rollbackApprovedByKey] != "" {
+		in.LogPersister.Infof("The rollback was already approved by %s", md[rollbackApprovedByKey])
+		return true, model.StageStatus_STAGE_RUNNING
+	}
+
+	var (
+		ctx     = sig.Context()
+		timeout = cfg.Timeout.Duration()
+		ticker  = time.NewTicker(5 * time.Second)
+		timer   = time.NewTimer(timeout)
+	)
+	defer ticker.Stop()
+	defer timer.Stop()
+
+	in.LogPersister.Info("The ANALYSIS stage failed. Waiting for an approval before executing the rollback...")
+	for {
+		select {
+		case <-ticker.C:
+			if commander, ok := checkRollbackApproval(ctx, in); ok {
+				in.LogPersister.Infof("Got an approval from %s to execute the rollback", commander)
+				return true, model.StageStatus_STAGE_RUNNING
+			}
+
+		case s := <-sig.Ch():
+			switch s {
+			case StopSignalCancel:
+				return false, model.StageStatus_STAGE_CANCELLED
+			case StopSignalTerminate:
+				return false, in.Stage.Status
+			default:
+				return false, model.StageStatus_STAGE_FAILURE
+			}
+
+		case <-timer.C:
+			if cfg.DefaultAction == config.RollbackApprovalDefaultActionSkip {
+				in.LogPersister.Infof("Timed out %v while waiting for an approval. The rollback was skipped", timeout)
+				return false, model.StageStatus_STAGE_SUCCESS
+			}
+			in.LogPersister.Infof("Timed out %v while waiting for an approval. Start executing the rollback", timeout)
+			return true, model.StageStatus_STAGE_RUNNING
+		}
+	}
+}
+
+// failedAtAnalysisStage checks whether the stage
+// that the rollback stage is depending on is an ANALYSIS stage.
+func failedAtAnalysisStage(in Input) bool {
+	if len(in.Stage.Requires) == 0 {
+		return false
+	}
+	for _, s := range in.Deployment.Stages {
+		if s.Id == in.Stage.Requires[0] {
+			return s.Name == model.StageAnalysis.String()
+		}
+	}
+	return false
+}
+
+func checkRollbackApproval(ctx context.Context, in Input) (string, bool) {
+	var approveCmd *model.ReportableCommand
+	commands := in.CommandLister.ListCommands()
+
+	for i, cmd := range commands {
+		if cmd.GetApproveStage() != nil {
+			approveCmd = &commands[i]
+			break
+		}
+	}
+	if approveCmd == nil {
+		return "", false
+	}
+
+	metadata := map[string]string{
+		rollbackApprovedByKey: approveCmd.Commander,
+	}
+	if ori, ok := in.MetadataStore.GetStageMetadata(in.Stage.Id); ok {
+		for k, v := range ori {
+			metadata[k] = v
+		}
+	}
+	if err := in.MetadataStore.SetStageMetadata(ctx, in.Stage.Id, metadata); err != nil {
+		in.LogPersister.Errorf("Unable to save approver information to deployment, %v", err)
+		return "", false
+	}
+
+	if err := approveCmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
+		in.Logger.Error("failed to report handled command", zap.Error(err))
+	}
+	return approveCmd.Commander, true
+}
@@ -0,0 +1,382 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scriptrun
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const defaultShell = "/bin/sh"
+
+// outputFileEnv is the environment variable holding the path to the file
+// where the script writes the outputs of the stage as "name=value" lines.
+const outputFileEnv = "PIPECD_OUTPUT_FILE"
+
+// defaultPassEnvs are the environment variables of piped always passed to the scripts.
+var defaultPassEnvs = []string{"PATH", "HOME"}
+
+type Executor struct {
+	executor.Input
+}
+
+type registerer interface {
+	Register(stage model.Stage, f executor.Factory) error
+}
+
+// Register registers this executor factory into a given registerer.
+func Register(r registerer) {
+	f := func(in executor.Input) executor.Executor {
+		return &Executor{
+			Input: in,
+		}
+	}
+	r.Register(model.StageScriptRun, f)
+}
+
+type lineLogger interface {
+	Info(log string)
+	Error(log string)
+}
+
+type toolResolver interface {
+	Tool(ctx context.Context, name string) (string, bool, error)
+}
+
+type result struct {
+	exitCode int
+	err      error
+}
+
+// Execute runs the user-defined script in the application directory
+// and decides the stage status by its exit code.
+func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
+	var (
+		originalStatus = e.Stage.Status
+		opts           = e.StageConfig.ScriptRunStageOptions
+	)
+	if opts == nil {
+		e.LogPersister.Errorf("Malformed configuration for stage %s", e.Stage.Name)
+		e.ReportError(executor.NewUserError("malformed configuration for stage %s", e.Stage.Name))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	var scriptRun config.PipedScriptRun
+	if e.PipedConfig != nil {
+		scriptRun = e.PipedConfig.ScriptRun
+	}
+	if !scriptRun.IsAllowed(e.Deployment.ApplicationName) {
+		e.LogPersister.Errorf("SCRIPT_RUN stage is not allowed for application %s by the piped configuration", e.Deployment.ApplicationName)
+		e.ReportError(executor.NewUserError("SCRIPT_RUN stage is not allowed for application %s, enable it in scriptRun of the piped configuration", e.Deployment.ApplicationName))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	ctx, cancel := context.WithCancel(sig.Context())
+	defer cancel()
+
+	// The script may modify the files, so it is run in a copy of the deploy source.
+	ds, err := e.TargetDSP.Get(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to prepare target deploy source data (%v)", err)
+		e.ReportError(fmt.Errorf("failed to prepare target deploy source data: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+
+	shell := scriptRun.Shell
+	if shell == "" {
+		shell = defaultShell
+	}
+	env := e.buildEnv(scriptRun.PassEnvs, opts.Env)
+
+	if len(opts.Tools) > 0 {
+		toolDir, err := ioutil.TempDir("", "script-run-tools")
+		if err != nil {
+			e.LogPersister.Errorf("Failed to create a directory for the tools (%v)", err)
+			e.ReportError(fmt.Errorf("failed to create a directory for the tools: %w", err))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		defer os.RemoveAll(toolDir)
+
+		if err := linkTools(ctx, toolregistry.DefaultRegistry(), opts.Tools, toolDir, e.LogPersister); err != nil {
+			e.ReportError(fmt.Errorf("failed to prepare the tools: %w", err))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		env = prependPath(env, toolDir)
+	}
+
+	// The script publishes the outputs of the stage by writing them into this file.
+	outputFile, err := ioutil.TempFile("", "script-run-output")
+	if err != nil {
+		e.LogPersister.Errorf("Failed to create a file for the outputs (%v)", err)
+		e.ReportError(fmt.Errorf("failed to create a file for the outputs: %w", err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	outputFile.Close()
+	defer os.Remove(outputFile.Name())
+	env = append(env, outputFileEnv+"="+outputFile.Name())
+
+	e.LogPersister.Infof("Running the script by %s", shell)
+	resultCh := make(chan result, 1)
+	go func() {
+		code, err := runScript(ctx, shell, opts.Run, ds.AppDir, env, e.LogPersister)
+		resultCh <- result{exitCode: code, err: err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		status := e.decideStatus(r, opts.WarningExitCodes)
+		if status != model.StageStatus_STAGE_SUCCESS {
+			return status
+		}
+		if err := e.publishOutputs(ctx, outputFile.Name()); err != nil {
+			e.LogPersister.Errorf("Failed to publish the outputs of the script (%v)", err)
+			e.ReportError(err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		return status
+
+	case s := <-sig.Ch():
+		// Kill the running script and wait for its output to be flushed.
+		cancel()
+		<-resultCh
+		switch s {
+		case executor.StopSignalCancel:
+			return model.StageStatus_STAGE_CANCELLED
+		case executor.StopSignalTerminate:
+			return originalStatus
+		case executor.StopSignalSkip:
+			return model.StageStatus_STAGE_SKIPPED
+		default:
+			return model.StageStatus_STAGE_FAILURE
+		}
+	}
+}
+
+func (e *Executor) decideStatus(r result, warningExitCodes []int) model.StageStatus {
+	if r.err != nil {
+		e.LogPersister.Errorf("Failed to run the script (%v)", r.err)
+		e.ReportError(fmt.Errorf("failed to run the script: %w", r.err))
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if r.exitCode == 0 {
+		e.LogPersister.Success("The script has been completed successfully")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	for _, c := range warningExitCodes {
+		if c == r.exitCode {
+			e.LogPersister.Warningf("The script exited with code %d which is configured as a warning", r.exitCode)
+			e.ReportWarning("the script exited with code %d", r.exitCode)
+			return model.StageStatus_STAGE_SUCCESS
+		}
+	}
+	e.LogPersister.Errorf("The script exited with code %d", r.exitCode)
+	e.ReportError(executor.NewUserError("the script exited with code %d", r.exitCode))
+	return model.StageStatus_STAGE_FAILURE
+}
+
+// publishOutputs publishes the outputs written by the script into the given file.
+func (e *Executor) publishOutputs(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open the output file: %w", err)
+	}
+	defer f.Close()
+
+	outputs, err := parseOutputs(f)
+	if err != nil {
+		return err
+	}
+	if len(outputs) == 0 {
+		return nil
+	}
+	fields := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		fields[name] = value
+	}
+	e.LogPersister.Log(model.LogSeverity_INFO, "Publishing the outputs of the script", fields)
+	return e.PublishOutputs(ctx, outputs)
+}
+
+// parseOutputs parses the "name=value" lines written by the script.
+// Empty lines are ignored and the later value wins when a name appears more than once.
+func parseOutputs(r io.Reader) (map[string]string, error) {
+	var (
+		outputs = make(map[string]string)
+		s       = bufio.NewScanner(r)
+		line    = 0
+	)
+	for s.Scan() {
+		line++
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		name, value, ok := cutOutput(text)
+		if !ok {
+			return nil, executor.NewUserError("malformed output at line %d of %s, it must be in the name=value format", line, outputFileEnv)
+		}
+		outputs[name] = value
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the output file: %w", err)
+	}
+	return outputs, nil
+}
+
+func cutOutput(text string) (string, string, bool) {
+	i := strings.Index(text, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+}
+
+// buildEnv returns the environment variables of the script.
+// Only the allowed variables of piped are passed through to not leak its credentials,
+// and the deployment context is exposed with the PIPECD_ prefix.
+func (e *Executor) buildEnv(passEnvs []string, userEnv map[string]string) []string {
+	env := make([]string, 0, len(defaultPassEnvs)+len(passEnvs)+len(userEnv)+6)
+	for _, names := range [][]string{defaultPassEnvs, passEnvs} {
+		for _, name := range names {
+			if v, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+v)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(userEnv))
+	for k := range userEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+userEnv[k])
+	}
+
+	// The deployment context is added at last to take precedence over the others.
+	return append(env,
+		"PIPECD_DEPLOYMENT_ID="+e.Deployment.Id,
+		"PIPECD_APPLICATION_ID="+e.Deployment.ApplicationId,
+		"PIPECD_APPLICATION_NAME="+e.Deployment.ApplicationName,
+		"PIPECD_ENV_NAME="+e.EnvName,
+		"PIPECD_COMMIT_HASH="+e.Deployment.Trigger.Commit.Hash,
+		"PIPECD_STAGE_ID="+e.Stage.Id,
+	)
+}
+
+// linkTools resolves the given tools and links them into the given directory
+// by their names to let the script run them as commands.
+func linkTools(ctx context.Context, resolver toolResolver, names []string, dir string, lp executor.LogPersister) error {
+	for _, name := range names {
+		path, installed, err := resolver.Tool(ctx, name)
+		if err != nil {
+			lp.Errorf("Unable to find required tool %s (%v)", name, err)
+			return err
+		}
+		if installed {
+			lp.Infof("Tool %s has just been installed to %q because of no pre-installed binary", name, path)
+		}
+		if err := os.Symlink(path, filepath.Join(dir, name)); err != nil {
+			lp.Errorf("Failed to link tool %s (%v)", name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// prependPath adds the given directory to the head of PATH in the given environment variables.
+func prependPath(env []string, dir string) []string {
+	out := make([]string, 0, len(env)+1)
+	found := false
+	for _, e := range env {
+		if strings.HasPrefix(e, "PATH=") {
+			e = "PATH=" + dir + string(os.PathListSeparator) + strings.TrimPrefix(e, "PATH=")
+			found = true
+		}
+		out = append(out, e)
+	}
+	if !found {
+		out = append(out, "PATH="+dir)
+	}
+	return out
+}
+
+// runScript runs the given script by the shell and streams its stdout and stderr
+// to the logger line by line. The exit code is returned if the script was run.
+func runScript(ctx context.Context, shell, script, dir string, env []string, logger lineLogger) (int, error) {
+	cmd := exec.CommandContext(ctx, shell, "-c", script)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		streamLines(stdout, logger.Info)
+	}()
+	go func() {
+		defer wg.Done()
+		streamLines(stderr, logger.Error)
+	}()
+	// All output must be read before calling Wait since it closes the pipes.
+	wg.Wait()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+func streamLines(r io.Reader, log func(string)) {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		log(s.Text())
+	}
+	// Drain the rest to not block the script when a line was too long.
+	io.Copy(ioutil.Discard, r)
+}
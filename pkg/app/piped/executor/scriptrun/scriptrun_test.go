@@ -0,0 +1,239 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scriptrun
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor/executortest"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeLineLogger struct {
+	mu     sync.Mutex
+	infos  []string
+	errors []string
+}
+
+func (l *fakeLineLogger) Info(log string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, log)
+}
+
+func (l *fakeLineLogger) Error(log string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, log)
+}
+
+type fakeToolResolver struct {
+	paths map[string]string
+}
+
+func (r *fakeToolResolver) Tool(_ context.Context, name string) (string, bool, error) {
+	path, ok := r.paths[name]
+	if !ok {
+		return "", false, fmt.Errorf("tool %s not found", name)
+	}
+	return path, false, nil
+}
+
+func TestRunScript(t *testing.T) {
+	testcases := []struct {
+		name         string
+		script       string
+		env          []string
+		wantExitCode int
+		wantInfos    []string
+		wantErrors   []string
+	}{
+		{
+			name:         "successful script",
+			script:       "echo foo; echo bar",
+			wantExitCode: 0,
+			wantInfos:    []string{"foo", "bar"},
+		},
+		{
+			name:         "failed script writing to stderr",
+			script:       "echo failed >&2; exit 3",
+			wantExitCode: 3,
+			wantErrors:   []string{"failed"},
+		},
+		{
+			name:         "environment variables",
+			script:       `echo "$PIPECD_APPLICATION_NAME"`,
+			env:          []string{"PIPECD_APPLICATION_NAME=demo"},
+			wantExitCode: 0,
+			wantInfos:    []string{"demo"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			logger := &fakeLineLogger{}
+			code, err := runScript(context.Background(), defaultShell, tc.script, t.TempDir(), tc.env, logger)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantExitCode, code)
+			assert.Equal(t, tc.wantInfos, logger.infos)
+			assert.Equal(t, tc.wantErrors, logger.errors)
+		})
+	}
+}
+
+func TestBuildEnv(t *testing.T) {
+	os.Setenv("SCRIPT_RUN_TEST_PASSED", "passed")
+	os.Setenv("SCRIPT_RUN_TEST_NOT_PASSED", "not-passed")
+	defer os.Unsetenv("SCRIPT_RUN_TEST_PASSED")
+	defer os.Unsetenv("SCRIPT_RUN_TEST_NOT_PASSED")
+
+	e := &Executor{
+		Input: executor.Input{
+			Stage: &model.PipelineStage{Id: "stage-id"},
+			Deployment: &model.Deployment{
+				Id:              "deployment-id",
+				ApplicationId:   "app-id",
+				ApplicationName: "app",
+				Trigger: &model.DeploymentTrigger{
+					Commit: &model.Commit{Hash: "commit-hash"},
+				},
+			},
+			EnvName: "dev",
+		},
+	}
+	got := e.buildEnv(
+		[]string{"SCRIPT_RUN_TEST_PASSED"},
+		map[string]string{"B": "b", "A": "a", "PIPECD_ENV_NAME": "overridden"},
+	)
+
+	assert.Contains(t, got, "SCRIPT_RUN_TEST_PASSED=passed")
+	assert.NotContains(t, got, "SCRIPT_RUN_TEST_NOT_PASSED=not-passed")
+	assert.Equal(t, []string{
+		"A=a",
+		"B=b",
+		"PIPECD_ENV_NAME=overridden",
+		"PIPECD_DEPLOYMENT_ID=deployment-id",
+		"PIPECD_APPLICATION_ID=app-id",
+		"PIPECD_APPLICATION_NAME=app",
+		"PIPECD_ENV_NAME=dev",
+		"PIPECD_COMMIT_HASH=commit-hash",
+		"PIPECD_STAGE_ID=stage-id",
+	}, got[len(got)-9:])
+}
+
+func TestLinkTools(t *testing.T) {
+	binDir := t.TempDir()
+	yq := filepath.Join(binDir, "yq-4.9.6")
+	require.NoError(t, ioutil.WriteFile(yq, []byte("#!/bin/sh\necho yq\n"), 0755))
+	resolver := &fakeToolResolver{paths: map[string]string{"yq": yq}}
+
+	toolDir := t.TempDir()
+	require.NoError(t, linkTools(context.Background(), resolver, []string{"yq"}, toolDir, &executortest.FakeLogPersister{}))
+
+	// The tool can be run by its name.
+	logger := &fakeLineLogger{}
+	env := prependPath([]string{"PATH=/usr/bin:/bin"}, toolDir)
+	code, err := runScript(context.Background(), defaultShell, "yq", t.TempDir(), env, logger)
+	require.NoError(t, err)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, []string{"yq"}, logger.infos)
+
+	err = linkTools(context.Background(), resolver, []string{"unknown"}, t.TempDir(), &executortest.FakeLogPersister{})
+	assert.Error(t, err)
+}
+
+func TestPrependPath(t *testing.T) {
+	testcases := []struct {
+		name string
+		env  []string
+		want []string
+	}{
+		{
+			name: "no PATH",
+			env:  []string{"HOME=/home/piped"},
+			want: []string{"HOME=/home/piped", "PATH=/tools"},
+		},
+		{
+			name: "PATH is given",
+			env:  []string{"PATH=/usr/bin", "HOME=/home/piped"},
+			want: []string{"PATH=/tools:/usr/bin", "HOME=/home/piped"},
+		},
+		{
+			name: "PATH is overridden by the user",
+			env:  []string{"PATH=/usr/bin", "PATH=/opt/bin"},
+			want: []string{"PATH=/tools:/usr/bin", "PATH=/tools:/opt/bin"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, prependPath(tc.env, "/tools"))
+		})
+	}
+}
+
+func TestParseOutputs(t *testing.T) {
+	testcases := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "no outputs",
+			content: "",
+			want:    map[string]string{},
+		},
+		{
+			name:    "multiple outputs",
+			content: "Endpoint=https://example.com/?a=b\n\nReplicas = 3\nReplicas=4\n",
+			want: map[string]string{
+				"Endpoint": "https://example.com/?a=b",
+				"Replicas": "4",
+			},
+		},
+		{
+			name:    "empty value",
+			content: "Endpoint=",
+			want:    map[string]string{"Endpoint": ""},
+		},
+		{
+			name:    "missing name",
+			content: "=value",
+			wantErr: true,
+		},
+		{
+			name:    "missing separator",
+			content: "Endpoint",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseOutputs(strings.NewReader(tc.content))
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
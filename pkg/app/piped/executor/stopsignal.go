@@ -16,6 +16,7 @@ package executor
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/atomic"
 )
@@ -32,37 +33,87 @@ const (
 	// StopSignalTimeout means the executor should stop its execution
 	// because of timeout.
 	StopSignalTimeout StopSignalType = "timeout"
+	// StopSignalSkip means the executor should stop its execution
+	// because the stage was skipped by a user.
+	StopSignalSkip StopSignalType = "skip"
 	// StopSignalNone means the excutor can be continuously executed.
 	StopSignalNone StopSignalType = "none"
 )
 
+// StopReason describes why the executor was asked to stop
+// so that it can decide whether to clean up its resources or leave them to be resumed.
+type StopReason string
+
+const (
+	// StopReasonNone means the executor has not been asked to stop.
+	StopReasonNone StopReason = ""
+	// StopReasonCancelledByUser means the deployment was cancelled by a user.
+	// The executor should clean up the resources it created.
+	StopReasonCancelledByUser StopReason = "cancelled-by-user"
+	// StopReasonTimeout means the deployment exceeded its timeout.
+	// The executor should clean up the resources it created.
+	StopReasonTimeout StopReason = "timeout"
+	// StopReasonPipedRestart means the piped is shutting down, e.g. for restarting.
+	// The stage will be executed again after restarting,
+	// so the executor should checkpoint its state and leave the resources to be resumed.
+	StopReasonPipedRestart StopReason = "piped-restart"
+	// StopReasonSkippedByUser means the stage was skipped by a user.
+	// The executor should leave the resources as they are
+	// because the next stages will be executed on top of them.
+	StopReasonSkippedByUser StopReason = "skipped-by-user"
+)
+
+var stopReasons = map[StopSignalType]StopReason{
+	StopSignalNone:      StopReasonNone,
+	StopSignalCancel:    StopReasonCancelledByUser,
+	StopSignalTimeout:   StopReasonTimeout,
+	StopSignalTerminate: StopReasonPipedRestart,
+	StopSignalSkip:      StopReasonSkippedByUser,
+}
+
 type StopSignal interface {
 	Context() context.Context
 	Ch() <-chan StopSignalType
 	Signal() StopSignalType
 	Terminated() bool
+	// Reason returns why the executor was asked to stop.
+	// StopReasonNone is returned while it can be continuously executed.
+	Reason() StopReason
+	// Deadline returns the time when the executor will be asked to stop because of timeout.
+	// The returned bool is false when no deadline was set.
+	Deadline() (time.Time, bool)
 }
 
 type StopSignalHandler interface {
 	Cancel()
 	Timeout()
 	Terminate()
+	Skip()
 }
 
 type stopSignal struct {
-	ctx    context.Context
-	cancel func()
-	ch     chan StopSignalType
-	signal *atomic.String
+	ctx      context.Context
+	cancel   func()
+	ch       chan StopSignalType
+	signal   *atomic.String
+	deadline time.Time
 }
 
 func NewStopSignal() (StopSignal, StopSignalHandler) {
+	return NewStopSignalWithDeadline(time.Time{})
+}
+
+// NewStopSignalWithDeadline returns a stop signal telling the executor
+// the time when the handler will be called to time out.
+// Zero deadline means no deadline.
+func NewStopSignalWithDeadline(deadline time.Time) (StopSignal, StopSignalHandler) {
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &stopSignal{
-		ctx:    ctx,
-		cancel: cancel,
-		ch:     make(chan StopSignalType, 1),
-		signal: atomic.NewString(string(StopSignalNone)),
+		ctx:      ctx,
+		cancel:   cancel,
+		ch:       make(chan StopSignalType, 1),
+		signal:   atomic.NewString(string(StopSignalNone)),
+		deadline: deadline,
 	}
 	return s, s
 }
@@ -88,6 +139,13 @@ func (s *stopSignal) Terminate() {
 	close(s.ch)
 }
 
+func (s *stopSignal) Skip() {
+	s.signal.Store(string(StopSignalSkip))
+	s.cancel()
+	s.ch <- StopSignalSkip
+	close(s.ch)
+}
+
 func (s *stopSignal) Context() context.Context {
 	return s.ctx
 }
@@ -105,3 +163,11 @@ func (s *stopSignal) Terminated() bool {
 	value := s.signal.Load()
 	return StopSignalType(value) == StopSignalTerminate
 }
+
+func (s *stopSignal) Reason() StopReason {
+	return stopReasons[s.Signal()]
+}
+
+func (s *stopSignal) Deadline() (time.Time, bool) {
+	return s.deadline, !s.deadline.IsZero()
+}
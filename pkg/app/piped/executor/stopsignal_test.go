@@ -0,0 +1,75 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStopSignalReason(t *testing.T) {
+	testcases := []struct {
+		name     string
+		stop     func(h StopSignalHandler)
+		expected StopReason
+	}{
+		{
+			name:     "not stopped",
+			stop:     func(h StopSignalHandler) {},
+			expected: StopReasonNone,
+		},
+		{
+			name:     "cancelled",
+			stop:     func(h StopSignalHandler) { h.Cancel() },
+			expected: StopReasonCancelledByUser,
+		},
+		{
+			name:     "timed out",
+			stop:     func(h StopSignalHandler) { h.Timeout() },
+			expected: StopReasonTimeout,
+		},
+		{
+			name:     "terminated",
+			stop:     func(h StopSignalHandler) { h.Terminate() },
+			expected: StopReasonPipedRestart,
+		},
+		{
+			name:     "skipped",
+			stop:     func(h StopSignalHandler) { h.Skip() },
+			expected: StopReasonSkippedByUser,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sig, handler := NewStopSignal()
+			tc.stop(handler)
+			assert.Equal(t, tc.expected, sig.Reason())
+		})
+	}
+}
+
+func TestStopSignalDeadline(t *testing.T) {
+	sig, _ := NewStopSignal()
+	_, ok := sig.Deadline()
+	assert.False(t, ok)
+
+	deadline := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	sig, _ = NewStopSignalWithDeadline(deadline)
+	got, ok := sig.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, deadline, got)
+}
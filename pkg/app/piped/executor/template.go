@@ -0,0 +1,122 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// TemplateArgs allows deployment-specific data to be embedded in the text
+// configured in the stages such as the message of NOTIFY stage.
+// NOTE: Changing its fields will force users to change their configurations.
+type TemplateArgs struct {
+	App        TemplateAppArgs
+	Deployment TemplateDeploymentArgs
+	Commit     TemplateCommitArgs
+	Stage      TemplateStageArgs
+	// The values of the outputs published by the stages keyed by the stage ID and the output name,
+	// e.g. {{ .Outputs.plan.HasChanges }} or {{ index .Outputs "stage-0" "HasChanges" }}.
+	Outputs map[string]map[string]interface{}
+}
+
+type TemplateAppArgs struct {
+	ID   string
+	Name string
+	Env  string
+}
+
+type TemplateDeploymentArgs struct {
+	ID      string
+	Summary string
+	Version string
+}
+
+type TemplateCommitArgs struct {
+	Hash    string
+	Message string
+	Author  string
+	Branch  string
+}
+
+type TemplateStageArgs struct {
+	ID   string
+	Name string
+}
+
+// TemplateArgs returns the args of the deployment and the stage being executed.
+func (in Input) TemplateArgs() TemplateArgs {
+	args := TemplateArgs{
+		App: TemplateAppArgs{
+			ID:   in.Deployment.ApplicationId,
+			Name: in.Deployment.ApplicationName,
+			Env:  in.EnvName,
+		},
+		Deployment: TemplateDeploymentArgs{
+			ID:      in.Deployment.Id,
+			Summary: in.Deployment.Summary,
+			Version: in.Deployment.Version,
+		},
+	}
+	if c := in.Deployment.Trigger.GetCommit(); c != nil {
+		args.Commit = TemplateCommitArgs{
+			Hash:    c.Hash,
+			Message: c.Message,
+			Author:  c.Author,
+			Branch:  c.Branch,
+		}
+	}
+	if in.Stage != nil {
+		args.Stage = TemplateStageArgs{
+			ID:   in.Stage.Id,
+			Name: in.Stage.Name,
+		}
+	}
+	args.Outputs = in.stageOutputValues()
+	return args
+}
+
+// stageOutputValues returns the values of the outputs published by the stages of the deployment.
+func (in Input) stageOutputValues() map[string]map[string]interface{} {
+	values := make(map[string]map[string]interface{})
+	if in.MetadataStore == nil {
+		return values
+	}
+	for _, s := range in.Deployment.Stages {
+		if len(s.Outputs) == 0 {
+			continue
+		}
+		metadata, ok := in.MetadataStore.GetStageMetadata(s.Id)
+		if !ok {
+			continue
+		}
+		values[s.Id] = s.OutputValues(metadata)
+	}
+	return values
+}
+
+// RenderTemplate applies the given args to the text template.
+// The returned error is a user error since the template is written by the users.
+func RenderTemplate(name, text string, args TemplateArgs) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", NewUserError("failed to parse %s template: %w", name, err)
+	}
+	b := new(bytes.Buffer)
+	if err := t.Execute(b, args); err != nil {
+		return "", NewUserError("failed to apply %s template: %w", name, err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	args := TemplateArgs{
+		App:    TemplateAppArgs{Name: "demo", Env: "prod"},
+		Commit: TemplateCommitArgs{Hash: "abc123"},
+	}
+	testcases := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "plain text",
+			text: "canary is live at 25%, please verify",
+			want: "canary is live at 25%, please verify",
+		},
+		{
+			name: "deployment args",
+			text: "canary of {{ .App.Name }} in {{ .App.Env }} is live at {{ .Commit.Hash }}",
+			want: "canary of demo in prod is live at abc123",
+		},
+		{
+			name:    "unknown field",
+			text:    "{{ .App.Namespace }}",
+			wantErr: true,
+		},
+		{
+			name:    "malformed template",
+			text:    "{{ .App.Name",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RenderTemplate("message", tc.text, args)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
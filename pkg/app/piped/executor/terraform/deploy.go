@@ -16,6 +16,9 @@ package terraform
 
 import (
 	"context"
+	"strconv"
+
+	"go.uber.org/zap"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/terraform"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
@@ -23,6 +26,15 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+const (
+	// The summary of the changes detected by TERRAFORM_PLAN stage.
+	planSummaryMetadataKey = "plan-summary"
+	// Whether TERRAFORM_PLAN stage detected the changes made outside of terraform.
+	driftedMetadataKey = "drifted"
+
+	noChangesSummary = "No changes"
+)
+
 type deployExecutor struct {
 	executor.Input
 
@@ -49,6 +61,7 @@ func (e *deployExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 	e.deployCfg = ds.DeploymentConfig.TerraformDeploymentSpec
 	if e.deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing TerraformDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing TerraformDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -158,6 +171,27 @@ func (e *deployExecutor) ensurePlan(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	if planResult.Drifted {
+		e.LogPersister.Warning("Detected the changes made outside of terraform, they are shown in the plan output above")
+		e.ReportWarning("resources have been changed outside of terraform since the last apply")
+	}
+	metadata := map[string]string{
+		planSummaryMetadataKey: planSummary(planResult),
+		driftedMetadataKey:     strconv.FormatBool(planResult.Drifted),
+	}
+	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
+		e.Logger.Error("failed to store the plan result to metadata store", zap.Error(err))
+	}
+	outputs := map[string]string{
+		"HasChanges": strconv.FormatBool(!planResult.NoChanges()),
+		"Adds":       strconv.Itoa(planResult.Adds),
+		"Changes":    strconv.Itoa(planResult.Changes),
+		"Destroys":   strconv.Itoa(planResult.Destroys),
+	}
+	if err := e.PublishOutputs(ctx, outputs); err != nil {
+		e.Logger.Error("failed to publish the plan result as the stage outputs", zap.Error(err))
+	}
+
 	if planResult.NoChanges() {
 		e.LogPersister.Success("No changes to apply")
 		return model.StageStatus_STAGE_SUCCESS
@@ -188,6 +222,25 @@ func (e *deployExecutor) ensureApply(ctx context.Context) model.StageStatus {
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	// Make sure that the changes to be applied are the same with the ones
+	// reviewed at the TERRAFORM_PLAN stage, e.g. before approving them.
+	if planned, ok := e.plannedSummary(); ok {
+		planResult, err := cmd.Plan(ctx, e.LogPersister)
+		if err != nil {
+			e.LogPersister.Errorf("Failed to plan (%v)", err)
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if summary := planSummary(planResult); summary != planned {
+			e.LogPersister.Errorf("The changes to apply (%s) are different from the ones planned at %s stage (%s)", summary, model.StageTerraformPlan, planned)
+			e.ReportError(executor.NewUserError("the changes to apply were changed since %s stage, the resources might have been changed outside of this deployment", model.StageTerraformPlan))
+			return model.StageStatus_STAGE_FAILURE
+		}
+		if planResult.NoChanges() {
+			e.LogPersister.Success("No changes to apply")
+			return model.StageStatus_STAGE_SUCCESS
+		}
+	}
+
 	if err := cmd.Apply(ctx, e.LogPersister); err != nil {
 		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
 		return model.StageStatus_STAGE_FAILURE
@@ -196,3 +249,30 @@ func (e *deployExecutor) ensureApply(ctx context.Context) model.StageStatus {
 	e.LogPersister.Success("Successfully applied changes")
 	return model.StageStatus_STAGE_SUCCESS
 }
+
+// plannedSummary returns the summary of the changes detected by
+// the last TERRAFORM_PLAN stage executed before the current stage.
+func (e *deployExecutor) plannedSummary() (string, bool) {
+	var summary string
+	var found bool
+	for _, s := range e.Deployment.Stages {
+		if s.Name != model.StageTerraformPlan.String() || s.Index >= e.Stage.Index {
+			continue
+		}
+		metadata, ok := e.MetadataStore.GetStageMetadata(s.Id)
+		if !ok {
+			continue
+		}
+		if v, ok := metadata[planSummaryMetadataKey]; ok {
+			summary, found = v, true
+		}
+	}
+	return summary, found
+}
+
+func planSummary(r provider.PlanResult) string {
+	if r.NoChanges() {
+		return noChangesSummary
+	}
+	return r.Summary()
+}
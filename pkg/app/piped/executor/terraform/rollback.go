@@ -46,9 +46,10 @@ func (e *rollbackExecutor) Execute(sig executor.StopSignal) model.StageStatus {
 }
 
 func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus {
-	// There is nothing to do if this is the first deployment.
+	// Terraform has no state to go back to if this is the first deployment.
 	if e.Deployment.RunningCommitHash == "" {
 		e.LogPersister.Errorf("Unable to determine the last deployed commit to rollback. It seems this is the first deployment.")
+		e.ReportError(executor.NewUserError("unable to rollback automatically because there is no previously deployed commit, the applied changes must be reverted manually"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -66,6 +67,7 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 	deployCfg := ds.DeploymentConfig.TerraformDeploymentSpec
 	if deployCfg == nil {
 		e.LogPersister.Error("Malformed deployment configuration: missing TerraformDeploymentSpec")
+		e.ReportError(executor.NewUserError("malformed deployment configuration: missing TerraformDeploymentSpec"))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -99,8 +101,24 @@ func (e *rollbackExecutor) ensureRollback(ctx context.Context) model.StageStatus
 		return model.StageStatus_STAGE_FAILURE
 	}
 
+	planResult, err := cmd.Plan(ctx, e.LogPersister)
+	if err != nil {
+		e.LogPersister.Errorf("Failed to plan (%v)", err)
+		return model.StageStatus_STAGE_FAILURE
+	}
+	if planResult.NoChanges() {
+		e.LogPersister.Success("No changes to rollback")
+		return model.StageStatus_STAGE_SUCCESS
+	}
+	// Re-applying the previous configuration can not restore the data of the destroyed resources.
+	if planResult.Destroys > 0 {
+		e.ReportWarning("rolling back destroys %d resources, the data stored in them can not be restored", planResult.Destroys)
+	}
+	e.LogPersister.Infof("Rolling back %s", planResult.Summary())
+
 	if err := cmd.Apply(ctx, e.LogPersister); err != nil {
 		e.LogPersister.Errorf("Failed to apply changes (%v)", err)
+		e.ReportError(executor.NewUserError("failed to rollback, the resources might be partially changed and must be fixed manually: %w", err))
 		return model.StageStatus_STAGE_FAILURE
 	}
 
@@ -13,3 +13,89 @@
 // limitations under the License.
 
 package terraform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeMetadataStore struct {
+	stages map[string]map[string]string
+}
+
+func (m *fakeMetadataStore) Get(_ string) (string, bool)              { return "", false }
+func (m *fakeMetadataStore) Set(_ context.Context, _, _ string) error { return nil }
+func (m *fakeMetadataStore) GetStageMetadata(id string) (map[string]string, bool) {
+	md, ok := m.stages[id]
+	return md, ok
+}
+func (m *fakeMetadataStore) SetStageMetadata(_ context.Context, _ string, _ map[string]string) error {
+	return nil
+}
+
+func TestPlannedSummary(t *testing.T) {
+	stages := []*model.PipelineStage{
+		{Id: "plan-1", Name: model.StageTerraformPlan.String(), Index: 0},
+		{Id: "approval", Name: model.StageWaitApproval.String(), Index: 1},
+		{Id: "apply-1", Name: model.StageTerraformApply.String(), Index: 2},
+		{Id: "plan-2", Name: model.StageTerraformPlan.String(), Index: 3},
+		{Id: "apply-2", Name: model.StageTerraformApply.String(), Index: 4},
+	}
+	testcases := []struct {
+		name      string
+		stage     *model.PipelineStage
+		metadata  map[string]map[string]string
+		want      string
+		wantFound bool
+	}{
+		{
+			name:  "no plan stage result",
+			stage: stages[2],
+		},
+		{
+			name:  "planned before",
+			stage: stages[2],
+			metadata: map[string]map[string]string{
+				"plan-1": {planSummaryMetadataKey: "1 to add, 0 to change, 0 to destroy"},
+			},
+			want:      "1 to add, 0 to change, 0 to destroy",
+			wantFound: true,
+		},
+		{
+			name:  "use the last plan stage",
+			stage: stages[4],
+			metadata: map[string]map[string]string{
+				"plan-1": {planSummaryMetadataKey: "1 to add, 0 to change, 0 to destroy"},
+				"plan-2": {planSummaryMetadataKey: noChangesSummary},
+			},
+			want:      noChangesSummary,
+			wantFound: true,
+		},
+		{
+			name:  "plan stage after the current stage",
+			stage: stages[2],
+			metadata: map[string]map[string]string{
+				"plan-2": {planSummaryMetadataKey: noChangesSummary},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &deployExecutor{
+				Input: executor.Input{
+					Stage:         tc.stage,
+					Deployment:    &model.Deployment{Stages: stages},
+					MetadataStore: &fakeMetadataStore{stages: tc.metadata},
+				},
+			}
+			got, found := e.plannedSummary()
+			assert.Equal(t, tc.wantFound, found)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
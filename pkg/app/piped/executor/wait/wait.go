@@ -98,6 +98,8 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 				return model.StageStatus_STAGE_CANCELLED
 			case executor.StopSignalTerminate:
 				return originalStatus
+			case executor.StopSignalSkip:
+				return model.StageStatus_STAGE_SKIPPED
 			default:
 				return model.StageStatus_STAGE_FAILURE
 			}
@@ -20,12 +20,14 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/pipe-cd/pipe/pkg/app/piped/changesummary"
 	"github.com/pipe-cd/pipe/pkg/app/piped/executor"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 const (
 	approvedByKey = "ApprovedBy"
+	rejectedByKey = "RejectedBy"
 )
 
 type Executor struct {
@@ -57,12 +59,39 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 	timeout := e.StageConfig.WaitApprovalStageOptions.Timeout.Duration()
 	timer := time.NewTimer(timeout)
 
+	// Show what is going to be deployed to help the approvers decide.
+	if summary, ok := e.MetadataStore.Get(changesummary.MetadataKey); ok {
+		e.LogPersister.Info(summary)
+	}
+	// Let the approvers see the detected changes in the notification as well.
+	diffSummary, _ := e.MetadataStore.Get(executor.DiffSummaryMetadataKey)
 	e.LogPersister.Info("Waiting for an approval...")
+	e.Notify(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_WAIT_APPROVAL,
+		Metadata: &model.NotificationEventDeploymentWaitApproval{
+			Deployment: e.Deployment,
+			EnvName:    e.EnvName,
+			StageId:    e.Stage.Id,
+			Summary:    diffSummary,
+		},
+	})
 	for {
 		select {
 		case <-ticker.C:
+			if commander, ok := e.checkRejection(ctx); ok {
+				e.LogPersister.Errorf("Got a rejection from %s", commander)
+				return model.StageStatus_STAGE_FAILURE
+			}
 			if commander, ok := e.checkApproval(ctx); ok {
 				e.LogPersister.Infof("Got an approval from %s", commander)
+				e.Notify(model.NotificationEvent{
+					Type: model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED,
+					Metadata: &model.NotificationEventDeploymentApproved{
+						Deployment: e.Deployment,
+						EnvName:    e.EnvName,
+						Approver:   commander,
+					},
+				})
 				return model.StageStatus_STAGE_SUCCESS
 			}
 
@@ -72,6 +101,8 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 				return model.StageStatus_STAGE_CANCELLED
 			case executor.StopSignalTerminate:
 				return originalStatus
+			case executor.StopSignalSkip:
+				return model.StageStatus_STAGE_SKIPPED
 			default:
 				return model.StageStatus_STAGE_FAILURE
 			}
@@ -83,21 +114,35 @@ func (e *Executor) Execute(sig executor.StopSignal) model.StageStatus {
 }
 
 func (e *Executor) checkApproval(ctx context.Context) (string, bool) {
-	var approveCmd *model.ReportableCommand
+	return e.checkCommand(ctx, approvedByKey, func(cmd *model.ReportableCommand) bool {
+		return cmd.GetApproveStage() != nil
+	})
+}
+
+func (e *Executor) checkRejection(ctx context.Context) (string, bool) {
+	return e.checkCommand(ctx, rejectedByKey, func(cmd *model.ReportableCommand) bool {
+		return cmd.GetRejectStage() != nil
+	})
+}
+
+// checkCommand finds the first command matching the given function
+// and saves its commander into the stage metadata with the given key.
+func (e *Executor) checkCommand(ctx context.Context, commanderKey string, match func(*model.ReportableCommand) bool) (string, bool) {
+	var matchedCmd *model.ReportableCommand
 	commands := e.CommandLister.ListCommands()
 
-	for i, cmd := range commands {
-		if cmd.GetApproveStage() != nil {
-			approveCmd = &commands[i]
+	for i := range commands {
+		if match(&commands[i]) {
+			matchedCmd = &commands[i]
 			break
 		}
 	}
-	if approveCmd == nil {
+	if matchedCmd == nil {
 		return "", false
 	}
 
 	metadata := map[string]string{
-		approvedByKey: approveCmd.Commander,
+		commanderKey: matchedCmd.Commander,
 	}
 	if ori, ok := e.MetadataStore.GetStageMetadata(e.Stage.Id); ok {
 		for k, v := range ori {
@@ -105,12 +150,12 @@ func (e *Executor) checkApproval(ctx context.Context) (string, bool) {
 		}
 	}
 	if err := e.MetadataStore.SetStageMetadata(ctx, e.Stage.Id, metadata); err != nil {
-		e.LogPersister.Errorf("Unabled to save approver information to deployment, %v", err)
+		e.LogPersister.Errorf("Unabled to save commander information to deployment, %v", err)
 		return "", false
 	}
 
-	if err := approveCmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
+	if err := matchedCmd.Report(ctx, model.CommandStatus_COMMAND_SUCCEEDED, nil, nil); err != nil {
 		e.Logger.Error("failed to report handled command", zap.Error(err))
 	}
-	return approveCmd.Commander, true
+	return matchedCmd.Commander, true
 }
@@ -29,6 +29,7 @@ type appNodes struct {
 	appID         string
 	managingNodes map[string]node
 	dependedNodes map[string]node
+	healthChecker *provider.HealthChecker
 	version       model.ApplicationLiveStateVersion
 	mu            sync.RWMutex
 }
@@ -59,7 +60,7 @@ func (a *appNodes) addManagingResource(uid string, key provider.ResourceKey, obj
 		appID:        a.appID,
 		key:          key,
 		unstructured: obj,
-		state:        provider.MakeKubernetesResourceState(uid, key, obj, now),
+		state:        provider.MakeKubernetesResourceState(uid, key, obj, a.healthChecker, now),
 	}
 
 	a.mu.Lock()
@@ -113,7 +114,7 @@ func (a *appNodes) addDependedResource(uid string, key provider.ResourceKey, obj
 		appID:        a.appID,
 		key:          key,
 		unstructured: obj,
-		state:        provider.MakeKubernetesResourceState(uid, key, obj, now),
+		state:        provider.MakeKubernetesResourceState(uid, key, obj, a.healthChecker, now),
 	}
 
 	a.mu.Lock()
@@ -168,6 +169,13 @@ func (a *appNodes) getManagingNodes() map[string]node {
 	return a.managingNodes
 }
 
+func (a *appNodes) getDependedNodes() map[string]node {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.dependedNodes
+}
+
 func (a *appNodes) getNodes() (map[string]node, model.ApplicationLiveStateVersion) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -46,6 +46,7 @@ type Getter interface {
 
 	GetWatchingResourceKinds() []provider.APIVersionKind
 	GetAppLiveManifests(appID string) []provider.Manifest
+	GetAppLiveDependedManifests(appID string) []provider.Manifest
 
 	WaitForReady(ctx context.Context, timeout time.Duration) error
 }
@@ -94,6 +95,13 @@ func (s *Store) Run(ctx context.Context) error {
 		return err
 	}
 
+	s.store.healthChecker, err = provider.NewHealthChecker(s.config.AppStateInformer.HealthChecks)
+	if err != nil {
+		s.logger.Error("failed to build health checker", zap.Error(err))
+		s.firstSyncedCh <- err
+		return err
+	}
+
 	stopCh := make(chan struct{})
 	rf := reflector{
 		config:      s.config,
@@ -150,3 +158,7 @@ func (s *Store) GetWatchingResourceKinds() []provider.APIVersionKind {
 func (s *Store) GetAppLiveManifests(appID string) []provider.Manifest {
 	return s.store.GetAppLiveManifests(appID)
 }
+
+func (s *Store) GetAppLiveDependedManifests(appID string) []provider.Manifest {
+	return s.store.GetAppLiveDependedManifests(appID)
+}
@@ -41,6 +41,9 @@ type store struct {
 	// so this is used to determine the application of a depended resource.
 	resources map[string]appResource
 	mu        sync.RWMutex
+	// Used to determine the health status of the resources
+	// by the health checks configured for their kinds.
+	healthChecker *provider.HealthChecker
 
 	events         []model.KubernetesResourceStateEvent
 	iterators      map[int]int
@@ -120,6 +123,7 @@ func (s *store) addResource(obj *unstructured.Unstructured, appID string) {
 				appID:         appID,
 				managingNodes: make(map[string]node),
 				dependedNodes: make(map[string]node),
+				healthChecker: s.healthChecker,
 				version: model.ApplicationLiveStateVersion{
 					Timestamp: now.Unix(),
 				},
@@ -315,6 +319,22 @@ func (s *store) GetAppLiveManifests(appID string) []provider.Manifest {
 	return manifests
 }
 
+func (s *store) GetAppLiveDependedManifests(appID string) []provider.Manifest {
+	s.mu.RLock()
+	app, ok := s.apps[appID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	nodes := app.getDependedNodes()
+	manifests := make([]provider.Manifest, 0, len(nodes))
+	for i := range nodes {
+		manifests = append(manifests, nodes[i].Manifest())
+	}
+	return manifests
+}
+
 func (s *store) addEvent(event model.KubernetesResourceStateEvent) {
 	s.eventMu.Lock()
 	defer s.eventMu.Unlock()
@@ -207,3 +207,11 @@ func (g LiveResourceLister) ListKubernetesAppLiveResources(cloudProvider, appID
 	}
 	return kg.GetAppLiveManifests(appID), true
 }
+
+func (g LiveResourceLister) ListKubernetesAppLiveDependedResources(cloudProvider, appID string) ([]provider.Manifest, bool) {
+	kg, ok := g.KubernetesGetter(cloudProvider)
+	if !ok {
+		return nil, false
+	}
+	return kg.GetAppLiveDependedManifests(appID), true
+}
@@ -27,6 +27,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/app/piped/logsink"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -35,9 +36,16 @@ type apiClient interface {
 	ReportStageLogsFromLastCheckpoint(ctx context.Context, in *pipedservice.ReportStageLogsFromLastCheckpointRequest, opts ...grpc.CallOption) (*pipedservice.ReportStageLogsFromLastCheckpointResponse, error)
 }
 
+// Sink streams the log blocks to the external logging systems
+// in addition to the control plane.
+type Sink interface {
+	Run(ctx context.Context) error
+	Send(e logsink.Entry)
+}
+
 type Persister interface {
 	Run(ctx context.Context) error
-	StageLogPersister(deploymentID, stageID string) StageLogPersister
+	StageLogPersister(d *model.Deployment, stageID string) StageLogPersister
 }
 
 type StageLogPersister interface {
@@ -46,8 +54,11 @@ type StageLogPersister interface {
 	Infof(format string, a ...interface{})
 	Success(log string)
 	Successf(format string, a ...interface{})
+	Warning(log string)
+	Warningf(format string, a ...interface{})
 	Error(log string)
 	Errorf(format string, a ...interface{})
+	Log(severity model.LogSeverity, log string, fields map[string]interface{})
 	Complete(timeout time.Duration) error
 }
 
@@ -58,6 +69,7 @@ type key struct {
 
 type persister struct {
 	apiClient       apiClient
+	sink            Sink
 	stagePersisters sync.Map
 
 	flushInterval           time.Duration
@@ -69,9 +81,11 @@ type persister struct {
 
 // NewPersister creates a new persister instance for saving the stage logs into server's storage.
 // This controls how many concurent api calls should be executed and when to flush the logs.
-func NewPersister(apiClient apiClient, logger *zap.Logger) *persister {
+// The log blocks are also sent to the given sink unless it is nil.
+func NewPersister(apiClient apiClient, sink Sink, logger *zap.Logger) *persister {
 	return &persister{
 		apiClient:               apiClient,
+		sink:                    sink,
 		flushInterval:           5 * time.Second,
 		checkpointFlushInterval: 2 * time.Minute,
 		stalePeriod:             time.Minute,
@@ -83,6 +97,17 @@ func NewPersister(apiClient apiClient, logger *zap.Logger) *persister {
 // Run starts running workers to flush logs to server.
 func (p *persister) Run(ctx context.Context) error {
 	p.logger.Info("start running log persister")
+
+	// The sink sends its buffered log blocks by itself while stopping.
+	sinkStoppedCh := make(chan error, 1)
+	if p.sink != nil {
+		go func() {
+			sinkStoppedCh <- p.sink.Run(ctx)
+		}()
+	} else {
+		sinkStoppedCh <- nil
+	}
+
 	ticker := time.NewTicker(p.flushInterval)
 	defer ticker.Stop()
 
@@ -102,22 +127,39 @@ L:
 	defer cancel()
 	p.flushAll(ctx)
 
+	if err := <-sinkStoppedCh; err != nil {
+		p.logger.Error("failed to stop log sink", zap.Error(err))
+	}
+
 	p.logger.Info("log persister has been stopped")
 	return nil
 }
 
 // StageLogPersister creates a child persister instance for a specific stage.
-func (p *persister) StageLogPersister(deploymentID, stageID string) StageLogPersister {
+func (p *persister) StageLogPersister(d *model.Deployment, stageID string) StageLogPersister {
 	k := key{
-		DeploymentID: deploymentID,
+		DeploymentID: d.Id,
 		StageID:      stageID,
 	}
 	logger := p.logger.With(
-		zap.String("deployment-id", deploymentID),
+		zap.String("deployment-id", d.Id),
 		zap.String("stage-id", stageID),
 	)
+	entry := logsink.Entry{
+		ApplicationID:   d.ApplicationId,
+		ApplicationName: d.ApplicationName,
+		DeploymentID:    d.Id,
+		StageID:         stageID,
+	}
+	for _, s := range d.Stages {
+		if s.Id == stageID {
+			entry.StageName = s.Name
+			break
+		}
+	}
 	sp := &stageLogPersister{
 		key:                     k,
+		entry:                   entry,
 		curLogIndex:             time.Now().Unix(),
 		doneCh:                  make(chan struct{}),
 		checkpointFlushInterval: p.checkpointFlushInterval,
@@ -25,6 +25,7 @@ import (
 	"google.golang.org/grpc"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/model"
 )
 
 type fakeAPIClient struct {
@@ -52,7 +53,7 @@ func (c *fakeAPIClient) NumberOfReportStageLogsFromLastCheckpoint() int {
 
 func TestPersister(t *testing.T) {
 	apiClient := &fakeAPIClient{}
-	p := NewPersister(apiClient, zap.NewNop())
+	p := NewPersister(apiClient, nil, zap.NewNop())
 	p.stalePeriod = 0
 
 	flushes, deletes := p.flush(context.TODO())
@@ -66,8 +67,8 @@ func TestPersister(t *testing.T) {
 	require.Equal(t, 0, apiClient.NumberOfReportStageLogsFromLastCheckpoint())
 	assert.Equal(t, 0, num)
 
-	sp1 := p.StageLogPersister("deployment-1", "stage-1")
-	p.StageLogPersister("deployment-2", "stage-2")
+	sp1 := p.StageLogPersister(&model.Deployment{Id: "deployment-1"}, "stage-1")
+	p.StageLogPersister(&model.Deployment{Id: "deployment-2"}, "stage-2")
 
 	num = p.flushAll(context.TODO())
 	require.Equal(t, 0, apiClient.NumberOfReportStageLogs())
@@ -16,6 +16,7 @@ package logpersister
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 
+	"github.com/pipe-cd/pipe/pkg/app/piped/logsink"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -41,13 +43,15 @@ type stageLogPersister struct {
 	done                    atomic.Bool
 	doneCh                  chan struct{}
 
+	// The entry sent to the sink without the log block.
+	entry                   logsink.Entry
 	checkpointFlushInterval time.Duration
 	persister               *persister
 	logger                  *zap.Logger
 }
 
 // append appends a new log block.
-func (sp *stageLogPersister) append(log string, s model.LogSeverity) {
+func (sp *stageLogPersister) append(log string, s model.LogSeverity, fields map[string]interface{}) {
 	now := time.Now()
 
 	// We also send the error logs to the local logger.
@@ -59,12 +63,21 @@ func (sp *stageLogPersister) append(log string, s model.LogSeverity) {
 	defer sp.mu.Unlock()
 
 	sp.curLogIndex++
-	sp.blocks = append(sp.blocks, &model.LogBlock{
+	block := &model.LogBlock{
 		Index:     sp.curLogIndex,
 		Log:       log,
 		Severity:  s,
+		Fields:    encodeLogFields(fields),
 		CreatedAt: now.Unix(),
-	})
+	}
+	sp.blocks = append(sp.blocks, block)
+
+	// Send while holding the lock to keep the order of the log blocks.
+	if sink := sp.persister.sink; sink != nil {
+		e := sp.entry
+		e.Block = block
+		sink.Send(e)
+	}
 }
 
 // Write appends a new INFO log block.
@@ -75,32 +88,47 @@ func (sp *stageLogPersister) Write(log []byte) (int, error) {
 
 // Info appends a new INFO log block.
 func (sp *stageLogPersister) Info(log string) {
-	sp.append(log, model.LogSeverity_INFO)
+	sp.append(log, model.LogSeverity_INFO, nil)
 }
 
 // Infof formats and appends a new INFO log block.
 func (sp *stageLogPersister) Infof(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_INFO)
+	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_INFO, nil)
 }
 
 // Success appends a new SUCCESS log block.
 func (sp *stageLogPersister) Success(log string) {
-	sp.append(log, model.LogSeverity_SUCCESS)
+	sp.append(log, model.LogSeverity_SUCCESS, nil)
 }
 
 // Successf formats and appends a new SUCCESS log block.
 func (sp *stageLogPersister) Successf(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS)
+	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_SUCCESS, nil)
+}
+
+// Warning appends a new WARNING log block.
+func (sp *stageLogPersister) Warning(log string) {
+	sp.append(log, model.LogSeverity_WARNING, nil)
+}
+
+// Warningf formats and appends a new WARNING log block.
+func (sp *stageLogPersister) Warningf(format string, a ...interface{}) {
+	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_WARNING, nil)
 }
 
 // Error appends a new ERROR log block.
 func (sp *stageLogPersister) Error(log string) {
-	sp.append(log, model.LogSeverity_ERROR)
+	sp.append(log, model.LogSeverity_ERROR, nil)
 }
 
 // Errorf formats and appends a new ERROR log block.
 func (sp *stageLogPersister) Errorf(format string, a ...interface{}) {
-	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_ERROR)
+	sp.append(fmt.Sprintf(format, a...), model.LogSeverity_ERROR, nil)
+}
+
+// Log appends a new log block of the given severity with the structured fields.
+func (sp *stageLogPersister) Log(severity model.LogSeverity, log string, fields map[string]interface{}) {
+	sp.append(log, severity, fields)
 }
 
 // Complete marks the completion of logging for this stage.
@@ -207,3 +235,30 @@ func (sp *stageLogPersister) flushFromLastCheckpoint(ctx context.Context) (err e
 	sp.sentIndex = 0
 	return nil
 }
+
+// encodeLogFields encodes the given fields into a JSON object.
+// The errors and the values implementing fmt.Stringer are encoded as their strings,
+// and the values unable to be encoded as JSON are formatted by fmt.
+func encodeLogFields(fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	values := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		switch tv := v.(type) {
+		case error:
+			v = tv.Error()
+		case fmt.Stringer:
+			v = tv.String()
+		}
+		if _, err := json.Marshal(v); err != nil {
+			v = fmt.Sprintf("%v", v)
+		}
+		values[k] = v
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logpersister
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/logsink"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestEncodeLogFields(t *testing.T) {
+	testcases := []struct {
+		name     string
+		fields   map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "no fields",
+			expected: "",
+		},
+		{
+			name: "json values",
+			fields: map[string]interface{}{
+				"name":     "canary",
+				"replicas": 3,
+				"ready":    true,
+				"labels":   map[string]string{"app": "simple"},
+			},
+			expected: `{"labels":{"app":"simple"},"name":"canary","ready":true,"replicas":3}`,
+		},
+		{
+			name: "error and stringer",
+			fields: map[string]interface{}{
+				"error":   errors.New("connection refused"),
+				"timeout": 90 * time.Second,
+			},
+			expected: `{"error":"connection refused","timeout":"1m30s"}`,
+		},
+		{
+			name: "value unable to be encoded",
+			fields: map[string]interface{}{
+				"impedance": complex(1, 2),
+			},
+			expected: `{"impedance":"(1+2i)"}`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := encodeLogFields(tc.fields)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestStageLogPersisterLog(t *testing.T) {
+	p := NewPersister(&fakeAPIClient{}, nil, zap.NewNop())
+	sp := p.StageLogPersister(&model.Deployment{Id: "deployment-1"}, "stage-1").(*stageLogPersister)
+
+	sp.Info("applying manifests")
+	sp.Warningf("%d resources were changed outside of this deployment", 2)
+	sp.Log(model.LogSeverity_ERROR, "failed to apply manifest", map[string]interface{}{"kind": "Deployment"})
+
+	require.Len(t, sp.blocks, 3)
+	assert.Equal(t, model.LogSeverity_INFO, sp.blocks[0].Severity)
+	assert.Equal(t, "", sp.blocks[0].Fields)
+	assert.Equal(t, model.LogSeverity_WARNING, sp.blocks[1].Severity)
+	assert.Equal(t, "2 resources were changed outside of this deployment", sp.blocks[1].Log)
+	assert.Equal(t, model.LogSeverity_ERROR, sp.blocks[2].Severity)
+	assert.Equal(t, `{"kind":"Deployment"}`, sp.blocks[2].Fields)
+}
+
+type fakeSink struct {
+	entries []logsink.Entry
+}
+
+func (s *fakeSink) Run(_ context.Context) error {
+	return nil
+}
+
+func (s *fakeSink) Send(e logsink.Entry) {
+	s.entries = append(s.entries, e)
+}
+
+func TestStageLogPersisterSendToSink(t *testing.T) {
+	sink := &fakeSink{}
+	p := NewPersister(&fakeAPIClient{}, sink, zap.NewNop())
+	d := &model.Deployment{
+		Id:              "deployment-1",
+		ApplicationId:   "app-1",
+		ApplicationName: "app",
+		Stages: []*model.PipelineStage{
+			{Id: "stage-1", Name: "K8S_SYNC"},
+		},
+	}
+	sp := p.StageLogPersister(d, "stage-1").(*stageLogPersister)
+
+	sp.Info("applying manifests")
+	sp.Success("applied manifests")
+
+	require.Len(t, sink.entries, 2)
+	for i, e := range sink.entries {
+		assert.Equal(t, "app-1", e.ApplicationID)
+		assert.Equal(t, "app", e.ApplicationName)
+		assert.Equal(t, "deployment-1", e.DeploymentID)
+		assert.Equal(t, "stage-1", e.StageID)
+		assert.Equal(t, "K8S_SYNC", e.StageName)
+		assert.Same(t, sp.blocks[i], e.Block)
+	}
+}
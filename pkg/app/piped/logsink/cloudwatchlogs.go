@@ -0,0 +1,182 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	// The limits of a PutLogEvents request.
+	// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_PutLogEvents.html
+	cloudWatchLogsMaxBatchEvents = 10000
+	cloudWatchLogsMaxBatchBytes  = 1048576
+	// The size counted for each event in addition to its message.
+	cloudWatchLogsEventOverhead = 26
+
+	// The number of the log streams remembered as created.
+	// The stream of each stage is no longer written after the stage is completed,
+	// so the least recently used ones are forgotten.
+	cloudWatchLogsStreamCacheSize = 1000
+)
+
+type cloudWatchLogsClient interface {
+	CreateLogStream(ctx context.Context, params *cloudwatchlogs.CreateLogStreamInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error)
+	PutLogEvents(ctx context.Context, params *cloudwatchlogs.PutLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error)
+}
+
+// cloudWatchLogsSink puts the entries of each stage into its own log stream of Amazon CloudWatch Logs.
+type cloudWatchLogsSink struct {
+	client   cloudWatchLogsClient
+	logGroup string
+	// The log streams which were created already.
+	streams *lru.Cache
+}
+
+func newCloudWatchLogsSink(cfg config.LogSinkCloudWatchLogsConfig, httpClient *http.Client) (*cloudWatchLogsSink, error) {
+	optFns := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.Region)}
+	if cfg.CredentialsFile != "" {
+		optFns = append(optFns, awsconfig.WithSharedCredentialsFiles([]string{cfg.CredentialsFile}))
+	}
+	if cfg.Profile != "" {
+		optFns = append(optFns, awsconfig.WithSharedConfigProfile(cfg.Profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config to create cloudwatch logs sink: %w", err)
+	}
+	client := cloudwatchlogs.NewFromConfig(awsCfg, func(o *cloudwatchlogs.Options) {
+		o.HTTPClient = httpClient
+	})
+	return newCloudWatchLogsSinkWithClient(client, cfg.LogGroup)
+}
+
+func newCloudWatchLogsSinkWithClient(client cloudWatchLogsClient, logGroup string) (*cloudWatchLogsSink, error) {
+	streams, err := lru.New(cloudWatchLogsStreamCacheSize)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudWatchLogsSink{
+		client:   client,
+		logGroup: logGroup,
+		streams:  streams,
+	}, nil
+}
+
+// cloudWatchLogsBatch is the entries sent by a PutLogEvents request.
+type cloudWatchLogsBatch struct {
+	stream  string
+	entries []Entry
+	events  []types.InputLogEvent
+	size    int
+}
+
+// Write sends the entries by one request per log stream, splitting the ones exceeding the limits of a request.
+// Since the requests succeeded are not undone, the entries of the failed request
+// and the later ones are returned as a partialWriteError to be retried.
+func (s *cloudWatchLogsSink) Write(ctx context.Context, entries []Entry) error {
+	batches, err := makeCloudWatchLogsBatches(entries)
+	if err != nil {
+		return err
+	}
+
+	for i, b := range batches {
+		err := s.ensureLogStream(ctx, b.stream)
+		if err == nil {
+			_, err = s.client.PutLogEvents(ctx, &cloudwatchlogs.PutLogEventsInput{
+				LogGroupName:  aws.String(s.logGroup),
+				LogStreamName: aws.String(b.stream),
+				LogEvents:     b.events,
+			})
+		}
+		if err == nil {
+			continue
+		}
+		if i == 0 {
+			return err
+		}
+		unwritten := make([]Entry, 0, len(entries))
+		for _, b := range batches[i:] {
+			unwritten = append(unwritten, b.entries...)
+		}
+		return &partialWriteError{unwritten: unwritten, err: err}
+	}
+	return nil
+}
+
+// makeCloudWatchLogsBatches groups the given entries by the log stream of their stage
+// and splits each group into the batches not exceeding the limits of a request.
+func makeCloudWatchLogsBatches(entries []Entry) ([]*cloudWatchLogsBatch, error) {
+	var (
+		batches = make([]*cloudWatchLogsBatch, 0)
+		last    = make(map[string]*cloudWatchLogsBatch)
+	)
+	for _, e := range entries {
+		msg, err := json.Marshal(e.record())
+		if err != nil {
+			return nil, err
+		}
+		var (
+			stream = fmt.Sprintf("%s/%s", e.DeploymentID, e.StageID)
+			size   = len(msg) + cloudWatchLogsEventOverhead
+		)
+		b, ok := last[stream]
+		if !ok || len(b.events) >= cloudWatchLogsMaxBatchEvents || b.size+size > cloudWatchLogsMaxBatchBytes {
+			b = &cloudWatchLogsBatch{stream: stream}
+			batches = append(batches, b)
+			last[stream] = b
+		}
+		b.entries = append(b.entries, e)
+		b.events = append(b.events, types.InputLogEvent{
+			Timestamp: aws.Int64(e.time().UnixNano() / int64(time.Millisecond)),
+			Message:   aws.String(string(msg)),
+		})
+		b.size += size
+	}
+	return batches, nil
+}
+
+func (s *cloudWatchLogsSink) ensureLogStream(ctx context.Context, name string) error {
+	if s.streams.Contains(name) {
+		return nil
+	}
+	_, err := s.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(s.logGroup),
+		LogStreamName: aws.String(name),
+	})
+	var aee *types.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &aee) {
+		return fmt.Errorf("failed to create log stream %s: %w", name, err)
+	}
+	s.streams.Add(name, struct{}{})
+	return nil
+}
+
+func (s *cloudWatchLogsSink) Close() error {
+	return nil
+}
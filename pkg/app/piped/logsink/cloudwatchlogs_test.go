@@ -0,0 +1,159 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeCloudWatchLogsClient struct {
+	// The streams created before piped was restarted.
+	existing map[string]bool
+	// The number of the next PutLogEvents requests to be failed for each stream.
+	failures map[string]int
+	actions  []string
+	events   map[string][]string
+}
+
+func (c *fakeCloudWatchLogsClient) CreateLogStream(_ context.Context, in *cloudwatchlogs.CreateLogStreamInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.CreateLogStreamOutput, error) {
+	c.actions = append(c.actions, "CreateLogStream "+*in.LogStreamName)
+	if c.existing[*in.LogStreamName] {
+		return nil, &types.ResourceAlreadyExistsException{Message: aws.String("The specified log stream already exists")}
+	}
+	return &cloudwatchlogs.CreateLogStreamOutput{}, nil
+}
+
+func (c *fakeCloudWatchLogsClient) PutLogEvents(_ context.Context, in *cloudwatchlogs.PutLogEventsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutLogEventsOutput, error) {
+	c.actions = append(c.actions, "PutLogEvents "+*in.LogStreamName)
+	if c.failures[*in.LogStreamName] > 0 {
+		c.failures[*in.LogStreamName]--
+		return nil, &types.ServiceUnavailableException{Message: aws.String("unavailable")}
+	}
+	for _, e := range in.LogEvents {
+		c.events[*in.LogStreamName] = append(c.events[*in.LogStreamName], *e.Message)
+	}
+	return &cloudwatchlogs.PutLogEventsOutput{}, nil
+}
+
+func TestCloudWatchLogsSinkWrite(t *testing.T) {
+	client := &fakeCloudWatchLogsClient{
+		existing: map[string]bool{"deployment-1/stage-1": true},
+		events:   make(map[string][]string),
+	}
+	s, err := newCloudWatchLogsSinkWithClient(client, "pipecd")
+	require.NoError(t, err)
+
+	entries := []Entry{
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: "first"}},
+		{DeploymentID: "deployment-1", StageID: "stage-2", Block: &model.LogBlock{Log: "second"}},
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: "third"}},
+	}
+	require.NoError(t, s.Write(context.Background(), entries))
+	// The log streams are created only once.
+	require.NoError(t, s.Write(context.Background(), entries[:1]))
+
+	assert.Equal(t, []string{
+		"CreateLogStream deployment-1/stage-1",
+		"PutLogEvents deployment-1/stage-1",
+		"CreateLogStream deployment-1/stage-2",
+		"PutLogEvents deployment-1/stage-2",
+		"PutLogEvents deployment-1/stage-1",
+	}, client.actions)
+	assert.Len(t, client.events["deployment-1/stage-1"], 3)
+	assert.Len(t, client.events["deployment-1/stage-2"], 1)
+}
+
+func TestMakeCloudWatchLogsBatches(t *testing.T) {
+	entries := make([]Entry, 0, cloudWatchLogsMaxBatchEvents+1)
+	for i := 0; i < cloudWatchLogsMaxBatchEvents+1; i++ {
+		entries = append(entries, Entry{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: "log"}})
+	}
+	batches, err := makeCloudWatchLogsBatches(entries)
+	require.NoError(t, err)
+	require.Greater(t, len(batches), 1)
+	var num int
+	for _, b := range batches {
+		assert.LessOrEqual(t, len(b.events), cloudWatchLogsMaxBatchEvents)
+		assert.LessOrEqual(t, b.size, cloudWatchLogsMaxBatchBytes)
+		num += len(b.events)
+	}
+	assert.Equal(t, len(entries), num)
+
+	large := strings.Repeat("x", 300*1024)
+	entries = []Entry{
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: large}},
+		{DeploymentID: "deployment-1", StageID: "stage-2", Block: &model.LogBlock{Log: large}},
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: large}},
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: large}},
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: large}},
+	}
+	batches, err = makeCloudWatchLogsBatches(entries)
+	require.NoError(t, err)
+	require.Len(t, batches, 3)
+	assert.Equal(t, "deployment-1/stage-1", batches[0].stream)
+	assert.Len(t, batches[0].events, 3)
+	assert.Equal(t, "deployment-1/stage-2", batches[1].stream)
+	assert.Len(t, batches[1].events, 1)
+	assert.Equal(t, "deployment-1/stage-1", batches[2].stream)
+	assert.Len(t, batches[2].events, 1)
+	for _, b := range batches {
+		assert.LessOrEqual(t, b.size, cloudWatchLogsMaxBatchBytes)
+	}
+}
+
+func TestCloudWatchLogsSinkRetriesOnlyUnwrittenEntries(t *testing.T) {
+	interval := retryBaseInterval
+	retryBaseInterval = time.Millisecond
+	defer func() {
+		retryBaseInterval = interval
+	}()
+
+	client := &fakeCloudWatchLogsClient{
+		failures: map[string]int{"deployment-1/stage-2": 1},
+		events:   make(map[string][]string),
+	}
+	s, err := newCloudWatchLogsSinkWithClient(client, "pipecd")
+	require.NoError(t, err)
+
+	entries := []Entry{
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Log: "first"}},
+		{DeploymentID: "deployment-1", StageID: "stage-2", Block: &model.LogBlock{Log: "second"}},
+	}
+	err = s.Write(context.Background(), entries)
+	var perr *partialWriteError
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, entries[1:], perr.unwritten)
+
+	// The worker retries the entries of the second stage only.
+	client.failures["deployment-1/stage-2"] = 1
+	client.events = make(map[string][]string)
+	w := newWorker("cloudwatch", s, zap.NewNop())
+	w.write(context.Background(), entries)
+	assert.Len(t, client.events["deployment-1/stage-1"], 1)
+	assert.Len(t, client.events["deployment-1/stage-2"], 1)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logsink")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "stages.log")
+	entries := []Entry{
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Index: 1, Log: "first"}},
+		{DeploymentID: "deployment-1", StageID: "stage-1", Block: &model.LogBlock{Index: 2, Log: "second"}},
+	}
+
+	// The logs are appended to the existing file when piped was restarted.
+	for i := 0; i < 2; i++ {
+		s, err := newFileSink(config.LogSinkFileConfig{Path: path})
+		require.NoError(t, err)
+		require.NoError(t, s.Write(context.Background(), entries))
+		require.NoError(t, s.Close())
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var got []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &r))
+		assert.Equal(t, "deployment-1", r.DeploymentID)
+		got = append(got, r.Message)
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"first", "second", "first", "second"}, got)
+}
@@ -0,0 +1,306 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logsink provides a piped component
+// that streams the stage logs to the external logging systems
+// in addition to the control plane for the long-term retention and searching.
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/backoff"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	defaultBufferSize    = 1000
+	defaultBatchSize     = 500
+	defaultFlushInterval = 5 * time.Second
+	defaultGracePeriod   = 30 * time.Second
+	maxRetries           = 3
+)
+
+var (
+	retryBaseInterval = time.Second
+	retryMaxInterval  = 10 * time.Second
+)
+
+// Entry is a log block of a stage with the information
+// to find out where it was written.
+type Entry struct {
+	PipedID         string
+	ProjectID       string
+	ApplicationID   string
+	ApplicationName string
+	DeploymentID    string
+	StageID         string
+	StageName       string
+	Block           *model.LogBlock
+}
+
+// record is the structured form of an entry written to the sinks.
+type record struct {
+	Time            string          `json:"time"`
+	Severity        string          `json:"severity"`
+	Message         string          `json:"message"`
+	Fields          json.RawMessage `json:"fields,omitempty"`
+	Index           int64           `json:"index"`
+	PipedID         string          `json:"pipedId"`
+	ProjectID       string          `json:"projectId"`
+	ApplicationID   string          `json:"applicationId"`
+	ApplicationName string          `json:"applicationName"`
+	DeploymentID    string          `json:"deploymentId"`
+	StageID         string          `json:"stageId"`
+	StageName       string          `json:"stageName"`
+}
+
+func (e Entry) time() time.Time {
+	return time.Unix(e.Block.CreatedAt, 0).UTC()
+}
+
+func (e Entry) record() record {
+	r := record{
+		Time:            e.time().Format(time.RFC3339),
+		Severity:        e.Block.Severity.String(),
+		Message:         e.Block.Log,
+		Index:           e.Block.Index,
+		PipedID:         e.PipedID,
+		ProjectID:       e.ProjectID,
+		ApplicationID:   e.ApplicationID,
+		ApplicationName: e.ApplicationName,
+		DeploymentID:    e.DeploymentID,
+		StageID:         e.StageID,
+		StageName:       e.StageName,
+	}
+	if f := e.Block.Fields; f != "" && json.Valid([]byte(f)) {
+		r.Fields = json.RawMessage(f)
+	}
+	return r
+}
+
+// sink writes the entries to an external logging system.
+type sink interface {
+	// Write sends the given entries in a batch.
+	// The entries of the same stage are given in the order they were written.
+	Write(ctx context.Context, entries []Entry) error
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// partialWriteError is returned by the sink which wrote only some of the entries.
+// Only the unwritten ones are retried to not write the others twice.
+type partialWriteError struct {
+	unwritten []Entry
+	err       error
+}
+
+func (e *partialWriteError) Error() string {
+	return fmt.Sprintf("failed to write %d log entries: %v", len(e.unwritten), e.err)
+}
+
+func (e *partialWriteError) Unwrap() error {
+	return e.err
+}
+
+// Streamer streams the entries to all configured sinks.
+// Each sink has its own buffer so a slow sink does not delay the others.
+type Streamer struct {
+	pipedID   string
+	projectID string
+	workers   []*worker
+	logger    *zap.Logger
+}
+
+// NewStreamer creates a new streamer for the sinks configured in the given piped configuration.
+func NewStreamer(cfg *config.PipedSpec, logger *zap.Logger) (*Streamer, error) {
+	s := &Streamer{
+		pipedID:   cfg.PipedID,
+		projectID: cfg.ProjectID,
+		workers:   make([]*worker, 0, len(cfg.LogSinks)),
+		logger:    logger.Named("log-sink"),
+	}
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	for _, c := range cfg.LogSinks {
+		var (
+			sk  sink
+			err error
+		)
+		switch c.Type {
+		case config.LogSinkTypeCloudWatchLogs:
+			sk, err = newCloudWatchLogsSink(*c.CloudWatchLogs, httpClient)
+		case config.LogSinkTypeStackdriver:
+			sk, err = newStackdriverSink(*c.Stackdriver)
+		case config.LogSinkTypeLoki:
+			sk, err = newLokiSink(*c.Loki, httpClient)
+		case config.LogSinkTypeFile:
+			sk, err = newFileSink(*c.File)
+		default:
+			err = fmt.Errorf("unsupported log sink type: %s", c.Type)
+		}
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to create log sink %s: %w", c.Name, err)
+		}
+		s.workers = append(s.workers, newWorker(c.Name, sk, s.logger))
+	}
+	return s, nil
+}
+
+// Run starts sending the entries to the sinks until the given context is done.
+// The buffered entries are sent before returning.
+func (s *Streamer) Run(ctx context.Context) error {
+	s.logger.Info(fmt.Sprintf("start running log streamer with %d sinks", len(s.workers)))
+
+	var wg sync.WaitGroup
+	for _, w := range s.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			w.run(ctx)
+		}(w)
+	}
+	wg.Wait()
+
+	s.logger.Info("log streamer has been stopped")
+	return nil
+}
+
+// Send enqueues the given entry to all sinks.
+// The entry is dropped instead of blocking the caller when the buffer of a sink is full.
+func (s *Streamer) Send(e Entry) {
+	e.PipedID = s.pipedID
+	e.ProjectID = s.projectID
+	for _, w := range s.workers {
+		select {
+		case w.entryCh <- e:
+		default:
+			w.dropped.Inc()
+		}
+	}
+}
+
+func (s *Streamer) close() {
+	for _, w := range s.workers {
+		if err := w.sink.Close(); err != nil {
+			w.logger.Error("failed to close log sink", zap.Error(err))
+		}
+	}
+}
+
+type worker struct {
+	name    string
+	sink    sink
+	entryCh chan Entry
+	dropped atomic.Int64
+
+	batchSize     int
+	flushInterval time.Duration
+	gracePeriod   time.Duration
+	logger        *zap.Logger
+}
+
+func newWorker(name string, sk sink, logger *zap.Logger) *worker {
+	return &worker{
+		name:          name,
+		sink:          sk,
+		entryCh:       make(chan Entry, defaultBufferSize),
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		gracePeriod:   defaultGracePeriod,
+		logger:        logger.With(zap.String("sink", name)),
+	}
+}
+
+func (w *worker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, w.batchSize)
+L:
+	for {
+		select {
+		case e := <-w.entryCh:
+			batch = append(batch, e)
+			if len(batch) >= w.batchSize {
+				w.write(ctx, batch)
+				batch = make([]Entry, 0, w.batchSize)
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.write(ctx, batch)
+				batch = make([]Entry, 0, w.batchSize)
+			}
+
+		case <-ctx.Done():
+			break L
+		}
+	}
+
+	// Send all remaining entries before stopping.
+	ctx, cancel := context.WithTimeout(context.Background(), w.gracePeriod)
+	defer cancel()
+	for drained := false; !drained; {
+		select {
+		case e := <-w.entryCh:
+			batch = append(batch, e)
+		default:
+			drained = true
+		}
+	}
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > w.batchSize {
+			n = w.batchSize
+		}
+		w.write(ctx, batch[:n])
+		batch = batch[n:]
+	}
+
+	if err := w.sink.Close(); err != nil {
+		w.logger.Error("failed to close log sink", zap.Error(err))
+	}
+}
+
+func (w *worker) write(ctx context.Context, entries []Entry) {
+	if n := w.dropped.Swap(0); n > 0 {
+		w.logger.Warn(fmt.Sprintf("dropped %d log entries because the buffer was full", n))
+	}
+
+	retry := backoff.NewRetry(maxRetries, backoff.NewExponential(retryBaseInterval, retryMaxInterval))
+	_, err := retry.Do(ctx, func() (interface{}, error) {
+		err := w.sink.Write(ctx, entries)
+		var perr *partialWriteError
+		if errors.As(err, &perr) {
+			entries = perr.unwritten
+		}
+		return nil, err
+	})
+	if err != nil {
+		w.logger.Error(fmt.Sprintf("unable to write %d log entries", len(entries)), zap.Error(err))
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestEntryRecord(t *testing.T) {
+	testcases := []struct {
+		name   string
+		fields string
+		want   json.RawMessage
+	}{
+		{
+			name: "no fields",
+		},
+		{
+			name:   "valid fields",
+			fields: `{"duration":"5m0s"}`,
+			want:   json.RawMessage(`{"duration":"5m0s"}`),
+		},
+		{
+			name:   "malformed fields",
+			fields: `{"duration":`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Entry{
+				PipedID:         "piped-1",
+				ApplicationName: "app-1",
+				DeploymentID:    "deployment-1",
+				StageID:         "stage-1",
+				StageName:       "K8S_SYNC",
+				Block: &model.LogBlock{
+					Index:     1,
+					Log:       "hello",
+					Severity:  model.LogSeverity_WARNING,
+					Fields:    tc.fields,
+					CreatedAt: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Unix(),
+				},
+			}
+			r := e.record()
+			assert.Equal(t, "2021-06-01T00:00:00Z", r.Time)
+			assert.Equal(t, "WARNING", r.Severity)
+			assert.Equal(t, "hello", r.Message)
+			assert.Equal(t, tc.want, r.Fields)
+
+			_, err := json.Marshal(r)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+type fakeSink struct {
+	mu      sync.Mutex
+	entries []Entry
+	closed  bool
+}
+
+func (s *fakeSink) Write(_ context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestStreamer(t *testing.T) {
+	sk := &fakeSink{}
+	w := newWorker("fake", sk, zap.NewNop())
+	w.batchSize = 2
+	w.flushInterval = time.Hour
+	s := &Streamer{
+		pipedID:   "piped-1",
+		projectID: "project-1",
+		workers:   []*worker{w},
+		logger:    zap.NewNop(),
+	}
+
+	for i := 0; i < 5; i++ {
+		s.Send(Entry{
+			DeploymentID: "deployment-1",
+			StageID:      "stage-1",
+			Block:        &model.LogBlock{Index: int64(i)},
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	doneCh := make(chan error)
+	go func() {
+		doneCh <- s.Run(ctx)
+	}()
+
+	// The full batches are written without waiting for the flush interval.
+	require.Eventually(t, func() bool {
+		sk.mu.Lock()
+		defer sk.mu.Unlock()
+		return len(sk.entries) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	// The remaining entries are written before stopping.
+	cancel()
+	require.NoError(t, <-doneCh)
+
+	require.Len(t, sk.entries, 5)
+	for i, e := range sk.entries {
+		assert.Equal(t, int64(i), e.Block.Index)
+		assert.Equal(t, "piped-1", e.PipedID)
+		assert.Equal(t, "project-1", e.ProjectID)
+	}
+	assert.True(t, sk.closed)
+}
+
+func TestStreamerSendDropsWhenBufferIsFull(t *testing.T) {
+	w := newWorker("fake", &fakeSink{}, zap.NewNop())
+	w.entryCh = make(chan Entry, 1)
+	s := &Streamer{
+		workers: []*worker{w},
+		logger:  zap.NewNop(),
+	}
+
+	s.Send(Entry{Block: &model.LogBlock{}})
+	s.Send(Entry{Block: &model.LogBlock{}})
+
+	assert.Len(t, w.entryCh, 1)
+	assert.Equal(t, int64(1), w.dropped.Load())
+}
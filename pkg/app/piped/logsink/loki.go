@@ -0,0 +1,135 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const lokiPushPath = "/loki/api/v1/push"
+
+// lokiSink pushes the entries through the HTTP API of Loki.
+// https://grafana.com/docs/loki/latest/api/#post-lokiapiv1push
+type lokiSink struct {
+	endpoint   string
+	tenantID   string
+	username   string
+	password   string
+	labels     map[string]string
+	httpClient *http.Client
+}
+
+func newLokiSink(cfg config.LogSinkLokiConfig, httpClient *http.Client) (*lokiSink, error) {
+	s := &lokiSink{
+		endpoint:   strings.TrimSuffix(cfg.Address, "/") + lokiPushPath,
+		tenantID:   cfg.TenantID,
+		labels:     cfg.Labels,
+		httpClient: httpClient,
+	}
+	if cfg.UsernameFile != "" {
+		username, err := ioutil.ReadFile(cfg.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read username file: %w", err)
+		}
+		password, err := ioutil.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+		s.username = strings.TrimSpace(string(username))
+		s.password = strings.TrimSpace(string(password))
+	}
+	return s, nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSink) Write(ctx context.Context, entries []Entry) error {
+	// The labels are kept in low cardinality as recommended by Loki,
+	// the identifiers of the deployment and the stage are in the log lines.
+	var (
+		streams = make([]*lokiStream, 0)
+		indexes = make(map[string]int)
+	)
+	for _, e := range entries {
+		severity := strings.ToLower(e.Block.Severity.String())
+		key := strings.Join([]string{e.ApplicationName, e.StageName, severity}, "\x00")
+		i, ok := indexes[key]
+		if !ok {
+			labels := make(map[string]string, len(s.labels)+4)
+			for k, v := range s.labels {
+				labels[k] = v
+			}
+			labels["piped"] = e.PipedID
+			labels["application"] = e.ApplicationName
+			labels["stage"] = e.StageName
+			labels["severity"] = severity
+
+			i = len(streams)
+			indexes[key] = i
+			streams = append(streams, &lokiStream{Stream: labels})
+		}
+
+		line, err := json.Marshal(e.record())
+		if err != nil {
+			return err
+		}
+		ts := strconv.FormatInt(e.time().UnixNano(), 10)
+		streams[i].Values = append(streams[i].Values, [2]string{ts, string(line)})
+	}
+
+	body, err := json.Marshal(map[string][]*lokiStream{"streams": streams})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenantID)
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		out, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (s *lokiSink) Close() error {
+	return nil
+}
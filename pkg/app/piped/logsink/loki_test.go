@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestLokiSinkWrite(t *testing.T) {
+	var (
+		gotTenant string
+		gotBody   struct {
+			Streams []lokiStream `json:"streams"`
+		}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != lokiPushPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotTenant = r.Header.Get("X-Scope-OrgID")
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &gotBody); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := &lokiSink{
+		endpoint:   server.URL + lokiPushPath,
+		tenantID:   "team-a",
+		labels:     map[string]string{"cluster": "prod", "stage": "overridden"},
+		httpClient: server.Client(),
+	}
+	entries := []Entry{
+		{PipedID: "piped-1", ApplicationName: "app-1", StageName: "K8S_SYNC", Block: &model.LogBlock{Log: "first", Severity: model.LogSeverity_INFO, CreatedAt: 1}},
+		{PipedID: "piped-1", ApplicationName: "app-1", StageName: "K8S_SYNC", Block: &model.LogBlock{Log: "failed", Severity: model.LogSeverity_ERROR, CreatedAt: 2}},
+		{PipedID: "piped-1", ApplicationName: "app-1", StageName: "K8S_SYNC", Block: &model.LogBlock{Log: "second", Severity: model.LogSeverity_INFO, CreatedAt: 3}},
+	}
+	require.NoError(t, s.Write(context.Background(), entries))
+
+	assert.Equal(t, "team-a", gotTenant)
+	require.Len(t, gotBody.Streams, 2)
+	assert.Equal(t, map[string]string{
+		"cluster":     "prod",
+		"piped":       "piped-1",
+		"application": "app-1",
+		"stage":       "K8S_SYNC",
+		"severity":    "info",
+	}, gotBody.Streams[0].Stream)
+	require.Len(t, gotBody.Streams[0].Values, 2)
+	assert.Equal(t, "1000000000", gotBody.Streams[0].Values[0][0])
+	assert.Equal(t, "3000000000", gotBody.Streams[0].Values[1][0])
+	assert.Equal(t, "error", gotBody.Streams[1].Stream["severity"])
+
+	var r record
+	require.NoError(t, json.Unmarshal([]byte(gotBody.Streams[1].Values[0][1]), &r))
+	assert.Equal(t, "failed", r.Message)
+}
+
+func TestLokiSinkWriteError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("entry too far behind"))
+	}))
+	defer server.Close()
+
+	s := &lokiSink{
+		endpoint:   server.URL + lokiPushPath,
+		httpClient: server.Client(),
+	}
+	err := s.Write(context.Background(), []Entry{{Block: &model.LogBlock{Log: "hello"}}})
+	assert.EqualError(t, err, "unexpected status code 400: entry too far behind")
+}
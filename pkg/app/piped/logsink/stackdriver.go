@@ -0,0 +1,121 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/logging/v2"
+	"google.golang.org/api/option"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The maximum length of time to write a batch of entries
+// since the client of Cloud Logging does not share the HTTP client of the other sinks.
+const stackdriverWriteTimeout = 10 * time.Second
+
+// stackdriverSink writes the entries to Cloud Logging.
+type stackdriverSink struct {
+	project string
+	logName string
+	client  *logging.Service
+}
+
+func newStackdriverSink(cfg config.LogSinkStackdriverConfig) (*stackdriverSink, error) {
+	// The default credentials are used when no file was specified.
+	options := []option.ClientOption{
+		option.WithScopes(logging.LoggingWriteScope),
+	}
+	if cfg.CredentialsFile != "" {
+		data, err := ioutil.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file: %w", err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	}
+	client, err := logging.NewService(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloud Logging client: %w", err)
+	}
+
+	return &stackdriverSink{
+		project: cfg.Project,
+		logName: fmt.Sprintf("projects/%s/logs/%s", cfg.Project, url.PathEscape(cfg.GetLogName())),
+		client:  client,
+	}, nil
+}
+
+func (s *stackdriverSink) Write(ctx context.Context, entries []Entry) error {
+	in := &logging.WriteLogEntriesRequest{
+		LogName: s.logName,
+		Resource: &logging.MonitoredResource{
+			Type:   "global",
+			Labels: map[string]string{"project_id": s.project},
+		},
+		Entries: make([]*logging.LogEntry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		payload, err := json.Marshal(e.record())
+		if err != nil {
+			return err
+		}
+		in.Entries = append(in.Entries, &logging.LogEntry{
+			Timestamp: e.time().Format(time.RFC3339),
+			Severity:  stackdriverSeverity(e.Block.Severity),
+			// The labels are indexed to find the logs of a deployment quickly.
+			Labels: map[string]string{
+				"piped_id":       e.PipedID,
+				"application_id": e.ApplicationID,
+				"deployment_id":  e.DeploymentID,
+				"stage_id":       e.StageID,
+			},
+			JsonPayload: googleapi.RawMessage(payload),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, stackdriverWriteTimeout)
+	defer cancel()
+	_, err := s.client.Entries.Write(in).Context(ctx).Do()
+	return err
+}
+
+func (s *stackdriverSink) Close() error {
+	return nil
+}
+
+// stackdriverSeverity returns the severity of Cloud Logging for the given severity.
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogSeverity
+func stackdriverSeverity(s model.LogSeverity) string {
+	switch s {
+	case model.LogSeverity_INFO:
+		return "INFO"
+	case model.LogSeverity_SUCCESS:
+		return "NOTICE"
+	case model.LogSeverity_WARNING:
+		return "WARNING"
+	case model.LogSeverity_ERROR:
+		return "ERROR"
+	default:
+		return "DEFAULT"
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/backoff"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	eventBusSpecVersion = "1.0"
+	eventBusContentType = "application/json"
+	eventBusMaxRetries  = 3
+)
+
+var (
+	eventBusRetryBaseInterval = time.Second
+	eventBusRetryMaxInterval  = 10 * time.Second
+)
+
+// eventBusMessage is the structured event published to the event bus.
+// It follows the JSON format of CloudEvents v1.0 to be consumed by the common tools.
+// https://github.com/cloudevents/spec/blob/v1.0/json-format.md
+type eventBusMessage struct {
+	SpecVersion     string `json:"specversion"`
+	ID              string `json:"id"`
+	Source          string `json:"source"`
+	Type            string `json:"type"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+	// The extension attributes to filter the events without decoding the data.
+	Group   string `json:"group"`
+	AppID   string `json:"appid,omitempty"`
+	AppName string `json:"appname,omitempty"`
+	EnvName string `json:"envname,omitempty"`
+	// The metadata of the event.
+	Data json.RawMessage `json:"data"`
+}
+
+// attributes returns the attributes of the message used by the event bus to filter the messages.
+func (m *eventBusMessage) attributes() map[string]string {
+	attrs := map[string]string{
+		"type":  m.Type,
+		"group": m.Group,
+	}
+	if m.AppName != "" {
+		attrs["appname"] = m.AppName
+	}
+	if m.EnvName != "" {
+		attrs["envname"] = m.EnvName
+	}
+	return attrs
+}
+
+// eventPublisher publishes the messages to an event bus.
+type eventPublisher interface {
+	// Publish sends the given message.
+	// The messages having the same key are expected to be ordered if the event bus supports.
+	Publish(ctx context.Context, key string, msg *eventBusMessage) error
+}
+
+type eventBus struct {
+	name      string
+	pipedID   string
+	publisher eventPublisher
+	eventCh   chan model.NotificationEvent
+	logger    *zap.Logger
+}
+
+func newEventBusSender(name string, cfg config.NotificationReceiverEventBus, pipedID string, logger *zap.Logger) (*eventBus, error) {
+	var (
+		httpClient = &http.Client{
+			Timeout: 10 * time.Second,
+		}
+		publisher eventPublisher
+		err       error
+	)
+	switch cfg.Type {
+	case config.EventBusTypeKafka:
+		publisher, err = newKafkaPublisher(*cfg.Kafka, httpClient)
+	case config.EventBusTypeSNS:
+		publisher, err = newSNSPublisher(*cfg.SNS, httpClient)
+	case config.EventBusTypePubSub:
+		publisher, err = newPubSubPublisher(*cfg.PubSub)
+	default:
+		err = fmt.Errorf("unsupported event bus type: %s", cfg.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the publisher of event bus %s: %w", name, err)
+	}
+
+	return &eventBus{
+		name:      name,
+		pipedID:   pipedID,
+		publisher: publisher,
+		eventCh:   make(chan model.NotificationEvent, 100),
+		logger:    logger.Named("eventbus").With(zap.String("receiver", name)),
+	}, nil
+}
+
+func (e *eventBus) Run(ctx context.Context) error {
+	for {
+		select {
+		case event, ok := <-e.eventCh:
+			if ok {
+				e.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (e *eventBus) Notify(event model.NotificationEvent) {
+	e.eventCh <- event
+}
+
+func (e *eventBus) Close(ctx context.Context) {
+	close(e.eventCh)
+
+	// Publish all remaining events.
+	for {
+		select {
+		case event, ok := <-e.eventCh:
+			if !ok {
+				return
+			}
+			e.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *eventBus) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	msg, err := buildEventMessage(event, e.pipedID, time.Now())
+	if err != nil {
+		e.logger.Error(fmt.Sprintf("unable to build message for event %s: %v", event.Type.String(), err))
+		return
+	}
+
+	retry := backoff.NewRetry(eventBusMaxRetries, backoff.NewExponential(eventBusRetryBaseInterval, eventBusRetryMaxInterval))
+	_, err = retry.Do(ctx, func() (interface{}, error) {
+		return nil, e.publisher.Publish(ctx, msg.AppID, msg)
+	})
+	if err != nil {
+		e.logger.Error(fmt.Sprintf("unable to publish event %s to event bus: %v", event.Type.String(), err))
+	}
+}
+
+// buildEventMessage converts the given event into the structured message
+// shared by the event bus and the webhook receivers.
+func buildEventMessage(event model.NotificationEvent, pipedID string, now time.Time) (*eventBusMessage, error) {
+	data, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &eventBusMessage{
+		SpecVersion:     eventBusSpecVersion,
+		ID:              uuid.New().String(),
+		Source:          fmt.Sprintf("pipecd/piped/%s", pipedID),
+		Type:            event.Type.String(),
+		Time:            now.UTC().Format(time.RFC3339Nano),
+		DataContentType: eventBusContentType,
+		Group:           event.Group().String(),
+		Data:            data,
+	}
+	if md, ok := event.Metadata.(appNameMetadata); ok {
+		msg.AppName = md.GetAppName()
+	}
+	if md, ok := event.Metadata.(envNameMetadata); ok {
+		msg.EnvName = md.GetEnvName()
+	}
+	switch md := event.Metadata.(type) {
+	case interface{ GetDeployment() *model.Deployment }:
+		msg.AppID = md.GetDeployment().GetApplicationId()
+	case interface{ GetApplication() *model.Application }:
+		msg.AppID = md.GetApplication().GetId()
+	}
+	return msg, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const kafkaRESTContentType = "application/vnd.kafka.json.v2+json"
+
+// kafkaPublisher produces the messages through the Kafka REST Proxy
+// since there is no Kafka client in the dependencies.
+// https://docs.confluent.io/platform/current/kafka-rest/api.html#post--topics-(string-topic_name)
+type kafkaPublisher struct {
+	endpoint   string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+func newKafkaPublisher(cfg config.EventBusKafkaConfig, httpClient *http.Client) (*kafkaPublisher, error) {
+	p := &kafkaPublisher{
+		endpoint:   fmt.Sprintf("%s/topics/%s", strings.TrimSuffix(cfg.RESTProxyAddress, "/"), url.PathEscape(cfg.Topic)),
+		httpClient: httpClient,
+	}
+	if cfg.UsernameFile != "" {
+		data, err := ioutil.ReadFile(cfg.UsernameFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read username file (%w)", err)
+		}
+		p.username = strings.TrimSpace(string(data))
+	}
+	if cfg.PasswordFile != "" {
+		data, err := ioutil.ReadFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read password file (%w)", err)
+		}
+		p.password = strings.TrimSpace(string(data))
+	}
+	return p, nil
+}
+
+type kafkaRecord struct {
+	Key   string           `json:"key,omitempty"`
+	Value *eventBusMessage `json:"value"`
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, key string, msg *eventBusMessage) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: key, Value: msg}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaRESTContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	if p.username != "" || p.password != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	// The REST Proxy responds 200 even if some records failed to be produced.
+	var out struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("failed to decode the response: %w", err)
+	}
+	for _, o := range out.Offsets {
+		if o.ErrorCode != nil {
+			return fmt.Errorf("failed to produce the record (%d): %s", *o.ErrorCode, o.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// The maximum length of time to publish a message to Cloud Pub/Sub
+// since its client does not share the HTTP client of the other publishers.
+const pubsubPublishTimeout = 10 * time.Second
+
+// pubsubPublisher publishes the messages to a topic of Cloud Pub/Sub.
+type pubsubPublisher struct {
+	topics *pubsub.ProjectsTopicsService
+	topic  string
+}
+
+func newPubSubPublisher(cfg config.EventBusPubSubConfig) (*pubsubPublisher, error) {
+	// The default credentials are used when no file was specified.
+	options := []option.ClientOption{
+		option.WithScopes(pubsub.PubsubScope),
+	}
+	if cfg.CredentialsFile != "" {
+		data, err := ioutil.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials file: %w", err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	}
+	client, err := pubsub.NewService(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pubsub client: %w", err)
+	}
+
+	return &pubsubPublisher{
+		topics: client.Projects.Topics,
+		topic:  fmt.Sprintf("projects/%s/topics/%s", cfg.Project, cfg.Topic),
+	}, nil
+}
+
+func (p *pubsubPublisher) Publish(ctx context.Context, _ string, msg *eventBusMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	// The ordering key is not set since it is rejected unless the message ordering is enabled.
+	in := &pubsub.PublishRequest{
+		Messages: []*pubsub.PubsubMessage{{
+			Data:       base64.StdEncoding.EncodeToString(data),
+			Attributes: msg.attributes(),
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pubsubPublishTimeout)
+	defer cancel()
+	_, err = p.topics.Publish(p.topic, in).Context(ctx).Do()
+	return err
+}
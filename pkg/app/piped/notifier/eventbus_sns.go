@@ -0,0 +1,89 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+type snsClient interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// snsPublisher publishes the messages to a topic of Amazon SNS.
+type snsPublisher struct {
+	client   snsClient
+	topicARN string
+}
+
+func newSNSPublisher(cfg config.EventBusSNSConfig, httpClient *http.Client) (*snsPublisher, error) {
+	optFns := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(cfg.Region)}
+	if cfg.CredentialsFile != "" {
+		optFns = append(optFns, awsconfig.WithSharedCredentialsFiles([]string{cfg.CredentialsFile}))
+	}
+	if cfg.Profile != "" {
+		optFns = append(optFns, awsconfig.WithSharedConfigProfile(cfg.Profile))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config to create sns publisher: %w", err)
+	}
+
+	return &snsPublisher{
+		client: sns.NewFromConfig(awsCfg, func(o *sns.Options) {
+			o.HTTPClient = httpClient
+		}),
+		topicARN: cfg.TopicARN,
+	}, nil
+}
+
+func (p *snsPublisher) Publish(ctx context.Context, key string, msg *eventBusMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	in := &sns.PublishInput{
+		TopicArn: aws.String(p.topicARN),
+		Message:  aws.String(string(data)),
+		// Set the attributes to allow filtering the messages by subscription filter policies.
+		MessageAttributes: make(map[string]types.MessageAttributeValue),
+	}
+	for name, value := range msg.attributes() {
+		in.MessageAttributes[name] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	// The messages are ordered by application in FIFO topics.
+	if strings.HasSuffix(p.topicARN, ".fifo") && key != "" {
+		in.MessageGroupId = aws.String(key)
+		in.MessageDeduplicationId = aws.String(msg.ID)
+	}
+
+	_, err = p.client.Publish(ctx, in)
+	return err
+}
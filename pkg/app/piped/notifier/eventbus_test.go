@@ -0,0 +1,251 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/api/pubsub/v1"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildEventMessage(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name        string
+		event       model.NotificationEvent
+		wantGroup   string
+		wantAppID   string
+		wantAppName string
+		wantEnvName string
+	}{
+		{
+			name: "deployment event",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
+				Metadata: &model.NotificationEventDeploymentSucceeded{
+					Deployment: &model.Deployment{ApplicationId: "app-1", ApplicationName: "demo"},
+					EnvName:    "prod",
+				},
+			},
+			wantGroup:   "EVENT_DEPLOYMENT",
+			wantAppID:   "app-1",
+			wantAppName: "demo",
+			wantEnvName: "prod",
+		},
+		{
+			name: "analysis event",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_ANALYSIS_FAILED,
+				Metadata: &model.NotificationEventAnalysisFailed{
+					Deployment: &model.Deployment{ApplicationId: "app-1", ApplicationName: "demo"},
+					EnvName:    "prod",
+					StageId:    "stage-1",
+					Reason:     "error rate exceeded",
+				},
+			},
+			wantGroup:   "EVENT_ANALYSIS",
+			wantAppID:   "app-1",
+			wantAppName: "demo",
+			wantEnvName: "prod",
+		},
+		{
+			name: "piped event",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_PIPED_STARTED,
+				Metadata: &model.NotificationEventPipedStarted{
+					Id: "piped-1",
+				},
+			},
+			wantGroup: "EVENT_PIPED",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, err := buildEventMessage(tc.event, "piped-1", now)
+			require.NoError(t, err)
+			assert.Equal(t, "1.0", msg.SpecVersion)
+			assert.NotEmpty(t, msg.ID)
+			assert.Equal(t, "pipecd/piped/piped-1", msg.Source)
+			assert.Equal(t, tc.event.Type.String(), msg.Type)
+			assert.Equal(t, "2021-06-01T00:00:00Z", msg.Time)
+			assert.Equal(t, tc.wantGroup, msg.Group)
+			assert.Equal(t, tc.wantAppID, msg.AppID)
+			assert.Equal(t, tc.wantAppName, msg.AppName)
+			assert.Equal(t, tc.wantEnvName, msg.EnvName)
+
+			data, err := json.Marshal(tc.event.Metadata)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(data), string(msg.Data))
+		})
+	}
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/pipecd-events", r.URL.Path)
+		assert.Equal(t, kafkaRESTContentType, r.Header.Get("Content-Type"))
+
+		var in struct {
+			Records []struct {
+				Key   string          `json:"key"`
+				Value eventBusMessage `json:"value"`
+			} `json:"records"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		require.Len(t, in.Records, 1)
+		assert.Equal(t, "app-1", in.Records[0].Key)
+
+		if in.Records[0].Value.Type == "EVENT_DEPLOYMENT_FAILED" {
+			w.Write([]byte(`{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"Schema not found"}]}`))
+			return
+		}
+		w.Write([]byte(`{"offsets":[{"partition":0,"offset":1}]}`))
+	}))
+	defer server.Close()
+
+	p, err := newKafkaPublisher(config.EventBusKafkaConfig{
+		RESTProxyAddress: server.URL + "/",
+		Topic:            "pipecd-events",
+	}, server.Client())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = p.Publish(ctx, "app-1", &eventBusMessage{Type: "EVENT_DEPLOYMENT_SUCCEEDED", Data: json.RawMessage(`{}`)})
+	assert.NoError(t, err)
+
+	err = p.Publish(ctx, "app-1", &eventBusMessage{Type: "EVENT_DEPLOYMENT_FAILED", Data: json.RawMessage(`{}`)})
+	assert.Error(t, err)
+}
+
+type fakeSNSClient struct {
+	inputs []*sns.PublishInput
+}
+
+func (c *fakeSNSClient) Publish(_ context.Context, params *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	if aws.ToString(params.TopicArn) == "arn:aws:sns:ap-northeast-1:123456789012:missing" {
+		return nil, &types.NotFoundException{Message: aws.String("Topic does not exist")}
+	}
+	c.inputs = append(c.inputs, params)
+	return &sns.PublishOutput{MessageId: aws.String("1")}, nil
+}
+
+func TestSNSPublisher(t *testing.T) {
+	msg := &eventBusMessage{
+		ID:    "id-1",
+		Type:  "EVENT_DEPLOYMENT_TRIGGERED",
+		Group: "EVENT_DEPLOYMENT",
+		Data:  json.RawMessage(`{}`),
+	}
+	testcases := []struct {
+		name      string
+		topicARN  string
+		wantGroup string
+		wantErr   bool
+	}{
+		{
+			name:     "standard topic",
+			topicARN: "arn:aws:sns:ap-northeast-1:123456789012:events",
+		},
+		{
+			name:      "fifo topic",
+			topicARN:  "arn:aws:sns:ap-northeast-1:123456789012:events.fifo",
+			wantGroup: "app-1",
+		},
+		{
+			name:     "missing topic",
+			topicARN: "arn:aws:sns:ap-northeast-1:123456789012:missing",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeSNSClient{}
+			p := &snsPublisher{
+				client:   client,
+				topicARN: tc.topicARN,
+			}
+			err := p.Publish(context.Background(), "app-1", msg)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if tc.wantErr {
+				return
+			}
+
+			require.Len(t, client.inputs, 1)
+			in := client.inputs[0]
+			assert.Equal(t, tc.topicARN, aws.ToString(in.TopicArn))
+			assert.Equal(t, tc.wantGroup, aws.ToString(in.MessageGroupId))
+			assert.Equal(t, map[string]types.MessageAttributeValue{
+				"type":  {DataType: aws.String("String"), StringValue: aws.String("EVENT_DEPLOYMENT_TRIGGERED")},
+				"group": {DataType: aws.String("String"), StringValue: aws.String("EVENT_DEPLOYMENT")},
+			}, in.MessageAttributes)
+
+			var got eventBusMessage
+			require.NoError(t, json.Unmarshal([]byte(aws.ToString(in.Message)), &got))
+			assert.Equal(t, "EVENT_DEPLOYMENT_TRIGGERED", got.Type)
+		})
+	}
+}
+
+func TestPubSubPublisher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/pipecd/topics/events:publish", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var in pubsub.PublishRequest
+		require.NoError(t, json.Unmarshal(body, &in))
+		require.Len(t, in.Messages, 1)
+		assert.Equal(t, map[string]string{"type": "EVENT_ANALYSIS_SUCCEEDED", "group": "EVENT_ANALYSIS", "appname": "demo"}, in.Messages[0].Attributes)
+
+		data, err := base64.StdEncoding.DecodeString(in.Messages[0].Data)
+		require.NoError(t, err)
+		var msg eventBusMessage
+		require.NoError(t, json.Unmarshal(data, &msg))
+		assert.Equal(t, "EVENT_ANALYSIS_SUCCEEDED", msg.Type)
+		w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer server.Close()
+
+	client, err := pubsub.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+	p := &pubsubPublisher{
+		topics: client.Projects.Topics,
+		topic:  "projects/pipecd/topics/events",
+	}
+	err = p.Publish(context.Background(), "app-1", &eventBusMessage{
+		Type:    "EVENT_ANALYSIS_SUCCEEDED",
+		Group:   "EVENT_ANALYSIS",
+		AppName: "demo",
+		Data:    json.RawMessage(`{}`),
+	})
+	assert.NoError(t, err)
+}
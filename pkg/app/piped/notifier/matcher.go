@@ -28,6 +28,7 @@ type matcher struct {
 	ignoreApps   map[string]struct{}
 	envs         map[string]struct{}
 	ignoreEnvs   map[string]struct{}
+	labels       map[string]string
 }
 
 func newMatcher(cfg config.NotificationRoute) *matcher {
@@ -40,6 +41,7 @@ func newMatcher(cfg config.NotificationRoute) *matcher {
 		ignoreApps:   makeStringMap(cfg.IgnoreApps, ""),
 		envs:         makeStringMap(cfg.Envs, ""),
 		ignoreEnvs:   makeStringMap(cfg.IgnoreEnvs, ""),
+		labels:       cfg.Labels,
 	}
 }
 
@@ -47,6 +49,10 @@ type appNameMetadata interface {
 	GetAppName() string
 }
 
+type appLabelsMetadata interface {
+	GetAppLabels() map[string]string
+}
+
 type envNameMetadata interface {
 	GetEnvName() string
 }
@@ -95,6 +101,16 @@ func (m *matcher) Match(event model.NotificationEvent) bool {
 			return false
 		}
 	}
+	// The events not related to any application such as PIPED_STARTED
+	// are not filtered by the labels.
+	if md, ok := event.Metadata.(appLabelsMetadata); ok && len(m.labels) > 0 {
+		labels := md.GetAppLabels()
+		for k, v := range m.labels {
+			if labels[k] != v {
+				return false
+			}
+		}
+	}
 
 	return true
 }
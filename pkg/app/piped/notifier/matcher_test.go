@@ -160,6 +160,47 @@ func TestMatch(t *testing.T) {
 				}: true,
 			},
 		},
+		{
+			name: "filter by labels",
+			config: config.NotificationRoute{
+				Labels: map[string]string{
+					"team": "payment",
+				},
+			},
+			matchings: map[model.NotificationEvent]bool{
+				{
+					Type: model.NotificationEventType_EVENT_DEPLOYMENT_WAIT_APPROVAL,
+					Metadata: &model.NotificationEventDeploymentWaitApproval{
+						Deployment: &model.Deployment{
+							Labels: map[string]string{
+								"team": "payment",
+								"tier": "backend",
+							},
+						},
+					},
+				}: true,
+				{
+					Type: model.NotificationEventType_EVENT_APPLICATION_OUT_OF_SYNC,
+					Metadata: &model.NotificationEventApplicationOutOfSync{
+						Application: &model.Application{
+							Labels: map[string]string{
+								"team": "search",
+							},
+						},
+					},
+				}: false,
+				{
+					Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+					Metadata: &model.NotificationEventDeploymentTriggered{
+						Deployment: &model.Deployment{},
+					},
+				}: false,
+				{
+					Type:     model.NotificationEventType_EVENT_PIPED_STARTED,
+					Metadata: &model.NotificationEventPipedStarted{},
+				}: true,
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -19,6 +19,8 @@ package notifier
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
@@ -32,10 +34,19 @@ import (
 
 type Notifier struct {
 	config      *config.PipedSpec
-	handlers    []handler
 	gracePeriod time.Duration
 	closed      atomic.Bool
 	logger      *zap.Logger
+
+	// Guards the fields below which are replaced by reloading the configuration.
+	mu        sync.RWMutex
+	handlers  []handler
+	senders   map[string]sender
+	receivers map[string]config.NotificationReceiver
+	// Starts running the given sender until the returned function is called.
+	// This is set only while the notifier is running.
+	runSender func(s sender) context.CancelFunc
+	cancels   map[string]context.CancelFunc
 }
 
 type handler struct {
@@ -50,53 +61,159 @@ type sender interface {
 }
 
 func NewNotifier(cfg *config.PipedSpec, logger *zap.Logger) (*Notifier, error) {
-	logger = logger.Named("notifier")
-	receivers := make(map[string]config.NotificationReceiver, len(cfg.Notifications.Receivers))
-	for _, r := range cfg.Notifications.Receivers {
-		receivers[r.Name] = r
+	n := &Notifier{
+		config:      cfg,
+		gracePeriod: 10 * time.Second,
+		logger:      logger.Named("notifier"),
+		cancels:     make(map[string]context.CancelFunc),
+	}
+	if err := n.Reload(cfg); err != nil {
+		return nil, err
 	}
+	return n, nil
+}
 
-	handlers := make([]handler, 0, len(cfg.Notifications.Routes))
-	for _, route := range cfg.Notifications.Routes {
-		receiver, ok := receivers[route.Receiver]
-		if !ok {
-			return nil, fmt.Errorf("missing receiver %s that is used in route %s", route.Receiver, route.Name)
-		}
+// Reload applies the notification routes and receivers of the given configuration.
+// The senders of the unchanged receivers keep running without losing their events
+// while the ones of the changed or removed receivers are closed.
+func (n *Notifier) Reload(cfg *config.PipedSpec) error {
+	notifications := cfg.GetNotifications()
 
-		var sd sender
-		switch {
-		case receiver.Slack != nil:
-			sd = newSlackSender(receiver.Name, *receiver.Slack, cfg.WebAddress, logger)
-		case receiver.Webhook != nil:
-			sd = newWebhookSender(receiver.Name, *receiver.Webhook, logger)
-		default:
+	n.mu.RLock()
+	curSenders, curReceivers := n.senders, n.receivers
+	n.mu.RUnlock()
+
+	// Each receiver has only one sender shared by all routes using it
+	// to let the events be sent to the receiver without any route as well.
+	var (
+		senders   = make(map[string]sender, len(notifications.Receivers))
+		receivers = make(map[string]config.NotificationReceiver, len(notifications.Receivers))
+		added     []string
+	)
+	for _, receiver := range notifications.Receivers {
+		if sd, ok := curSenders[receiver.Name]; ok && reflect.DeepEqual(curReceivers[receiver.Name], receiver) {
+			senders[receiver.Name] = sd
+			receivers[receiver.Name] = receiver
+			continue
+		}
+		sd, err := newSender(receiver, cfg, n.logger)
+		if err != nil {
+			n.closeSenders(added, senders)
+			return err
+		}
+		if sd == nil {
 			continue
 		}
+		senders[receiver.Name] = sd
+		receivers[receiver.Name] = receiver
+		added = append(added, receiver.Name)
+	}
 
+	handlers := make([]handler, 0, len(notifications.Routes))
+	for _, route := range notifications.Routes {
+		if !hasReceiver(notifications.Receivers, route.Receiver) {
+			n.closeSenders(added, senders)
+			return fmt.Errorf("missing receiver %s that is used in route %s", route.Receiver, route.Name)
+		}
+		sd, ok := senders[route.Receiver]
+		if !ok {
+			continue
+		}
 		handlers = append(handlers, handler{
 			matcher: newMatcher(route),
 			sender:  sd,
 		})
 	}
 
-	return &Notifier{
-		config:      cfg,
-		handlers:    handlers,
-		gracePeriod: 10 * time.Second,
-		logger:      logger,
-	}, nil
+	n.mu.Lock()
+	var (
+		stale       []sender
+		staleCancel []context.CancelFunc
+	)
+	for name, sd := range n.senders {
+		if senders[name] == sd {
+			continue
+		}
+		stale = append(stale, sd)
+		if cancel, ok := n.cancels[name]; ok {
+			staleCancel = append(staleCancel, cancel)
+			delete(n.cancels, name)
+		}
+	}
+	n.handlers, n.senders, n.receivers = handlers, senders, receivers
+	if n.runSender != nil {
+		for _, name := range added {
+			n.cancels[name] = n.runSender(senders[name])
+		}
+	}
+	n.mu.Unlock()
+
+	// No event is sent to the stale senders from this time
+	// so they can be closed after sending their remaining events.
+	for _, cancel := range staleCancel {
+		cancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), n.gracePeriod)
+	defer cancel()
+	for _, sd := range stale {
+		sd.Close(ctx)
+	}
+	return nil
+}
+
+// newSender returns nil if the given receiver has no supported configuration.
+func newSender(receiver config.NotificationReceiver, cfg *config.PipedSpec, logger *zap.Logger) (sender, error) {
+	switch {
+	case receiver.Slack != nil:
+		return newSlackSender(receiver.Name, *receiver.Slack, cfg.WebAddress, logger), nil
+	case receiver.Teams != nil:
+		return newTeamsSender(receiver.Name, *receiver.Teams, cfg.WebAddress, logger), nil
+	case receiver.PagerDuty != nil:
+		return newPagerDutySender(receiver.Name, *receiver.PagerDuty, cfg.WebAddress, logger)
+	case receiver.Webhook != nil:
+		return newWebhookSender(receiver.Name, *receiver.Webhook, cfg.PipedID, logger)
+	case receiver.EventBus != nil:
+		return newEventBusSender(receiver.Name, *receiver.EventBus, cfg.PipedID, logger)
+	default:
+		return nil, nil
+	}
+}
+
+// closeSenders closes the given senders which have not been started yet.
+func (n *Notifier) closeSenders(names []string, senders map[string]sender) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.gracePeriod)
+	defer cancel()
+	for _, name := range names {
+		senders[name].Close(ctx)
+	}
+}
+
+func hasReceiver(receivers []config.NotificationReceiver, name string) bool {
+	for _, r := range receivers {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
 }
 
 func (n *Notifier) Run(ctx context.Context) error {
 	group, ctx := errgroup.WithContext(ctx)
 
-	// Start running all senders.
-	for i := range n.handlers {
-		sender := n.handlers[i].sender
+	// Start running all senders including the ones added by reloading later.
+	n.mu.Lock()
+	n.runSender = func(s sender) context.CancelFunc {
+		ctx, cancel := context.WithCancel(ctx)
 		group.Go(func() error {
-			return sender.Run(ctx)
+			return s.Run(ctx)
 		})
+		return cancel
+	}
+	for name, sender := range n.senders {
+		n.cancels[name] = n.runSender(sender)
 	}
+	numSenders := len(n.senders)
+	n.mu.Unlock()
 
 	// Send the PIPED_STARTED event.
 	n.Notify(model.NotificationEvent{
@@ -107,7 +224,10 @@ func (n *Notifier) Run(ctx context.Context) error {
 		},
 	})
 
-	n.logger.Info(fmt.Sprintf("all %d notifiers have been started", len(n.handlers)))
+	n.logger.Info(fmt.Sprintf("all %d notifiers have been started", numSenders))
+
+	// Keep running even if there is no sender since they can be added by reloading.
+	<-ctx.Done()
 	if err := group.Wait(); err != nil {
 		n.logger.Error("failed while running", zap.Error(err))
 		return err
@@ -124,15 +244,19 @@ func (n *Notifier) Run(ctx context.Context) error {
 
 	// Mark to ignore all incoming events from this time and close all senders.
 	n.closed.Store(true)
+	n.mu.Lock()
+	n.runSender = nil
+	senders := n.senders
+	n.mu.Unlock()
+
 	ctx, cancel := context.WithTimeout(context.Background(), n.gracePeriod)
 	defer cancel()
 
-	for i := range n.handlers {
-		sender := n.handlers[i].sender
+	for _, sender := range senders {
 		sender.Close(ctx)
 	}
 
-	n.logger.Info(fmt.Sprintf("all %d notifiers have been stopped", len(n.handlers)))
+	n.logger.Info(fmt.Sprintf("all %d notifiers have been stopped", len(senders)))
 	return nil
 }
 
@@ -141,6 +265,21 @@ func (n *Notifier) Notify(event model.NotificationEvent) {
 		n.logger.Warn("ignore an event because notifier is already closed", zap.String("type", event.Type.String()))
 		return
 	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	// The message sent by NOTIFY stage goes to its receivers directly without being routed.
+	if md, ok := event.Metadata.(*model.NotificationEventDeploymentMessage); ok && len(md.Receivers) > 0 {
+		for _, r := range md.Receivers {
+			sender, ok := n.senders[r]
+			if !ok {
+				n.logger.Warn("ignore an event sent to the unknown receiver", zap.String("type", event.Type.String()), zap.String("receiver", r))
+				continue
+			}
+			sender.Notify(event)
+		}
+		return
+	}
 	for _, h := range n.handlers {
 		if !h.matcher.Match(event) {
 			continue
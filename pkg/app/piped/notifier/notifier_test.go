@@ -0,0 +1,171 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeSender struct {
+	events []model.NotificationEvent
+}
+
+func (s *fakeSender) Run(_ context.Context) error {
+	return nil
+}
+
+func (s *fakeSender) Notify(event model.NotificationEvent) {
+	s.events = append(s.events, event)
+}
+
+func (s *fakeSender) Close(_ context.Context) {
+}
+
+func TestNotifierNotify(t *testing.T) {
+	deployment := &model.Deployment{ApplicationName: "demo"}
+	testcases := []struct {
+		name  string
+		event model.NotificationEvent
+		want  map[string]int
+	}{
+		{
+			name: "routed event",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+				Metadata: &model.NotificationEventDeploymentTriggered{
+					Deployment: deployment,
+					EnvName:    "dev",
+				},
+			},
+			want: map[string]int{"dev-slack": 1, "ci-webhook": 0},
+		},
+		{
+			name: "message without receivers is routed",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_MESSAGE,
+				Metadata: &model.NotificationEventDeploymentMessage{
+					Deployment: deployment,
+					EnvName:    "dev",
+					Message:    "hello",
+				},
+			},
+			want: map[string]int{"dev-slack": 1, "ci-webhook": 0},
+		},
+		{
+			name: "message is sent to its receivers directly",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_MESSAGE,
+				Metadata: &model.NotificationEventDeploymentMessage{
+					Deployment: deployment,
+					EnvName:    "dev",
+					Message:    "hello",
+					Receivers:  []string{"ci-webhook", "unknown"},
+				},
+			},
+			want: map[string]int{"dev-slack": 0, "ci-webhook": 1},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			senders := map[string]*fakeSender{
+				"dev-slack":  {},
+				"ci-webhook": {},
+			}
+			n := &Notifier{
+				handlers: []handler{
+					{
+						matcher: newMatcher(config.NotificationRoute{Envs: []string{"dev"}}),
+						sender:  senders["dev-slack"],
+					},
+				},
+				senders: map[string]sender{
+					"dev-slack":  senders["dev-slack"],
+					"ci-webhook": senders["ci-webhook"],
+				},
+				logger: zap.NewNop(),
+			}
+			n.Notify(tc.event)
+			for name, want := range tc.want {
+				assert.Len(t, senders[name].events, want, name)
+			}
+		})
+	}
+}
+
+func TestNotifierReload(t *testing.T) {
+	newConfig := func(routes []config.NotificationRoute, receivers []config.NotificationReceiver) *config.PipedSpec {
+		return &config.PipedSpec{
+			PipedID: "piped-id",
+			Notifications: config.Notifications{
+				Routes:    routes,
+				Receivers: receivers,
+			},
+		}
+	}
+	devSlack := config.NotificationReceiver{
+		Name:  "dev-slack",
+		Slack: &config.NotificationReceiverSlack{HookURL: "https://slack.com/dev"},
+	}
+
+	n, err := NewNotifier(newConfig(
+		[]config.NotificationRoute{
+			{Name: "dev", Envs: []string{"dev"}, Receiver: "dev-slack"},
+		},
+		[]config.NotificationReceiver{
+			devSlack,
+			{Name: "ci-webhook", Webhook: &config.NotificationReceiverWebhook{URL: "https://pipecd.dev/v1"}},
+		},
+	), zap.NewNop())
+	require.NoError(t, err)
+	require.Len(t, n.handlers, 1)
+	slackSender, webhookSender := n.senders["dev-slack"], n.senders["ci-webhook"]
+
+	err = n.Reload(newConfig(
+		[]config.NotificationRoute{
+			{Name: "dev", Envs: []string{"dev"}, Receiver: "dev-slack"},
+			{Name: "prod", Envs: []string{"prod"}, Receiver: "prod-teams"},
+		},
+		[]config.NotificationReceiver{
+			devSlack,
+			{Name: "ci-webhook", Webhook: &config.NotificationReceiverWebhook{URL: "https://pipecd.dev/v2"}},
+			{Name: "prod-teams", Teams: &config.NotificationReceiverTeams{HookURL: "https://teams.microsoft.com/prod"}},
+		},
+	))
+	require.NoError(t, err)
+	assert.Len(t, n.handlers, 2)
+	assert.Len(t, n.senders, 3)
+	// The sender of the unchanged receiver is kept.
+	assert.Same(t, slackSender, n.senders["dev-slack"])
+	assert.NotSame(t, webhookSender, n.senders["ci-webhook"])
+
+	// The current routes and receivers are kept if the new ones are invalid.
+	err = n.Reload(newConfig(
+		[]config.NotificationRoute{
+			{Name: "dev", Envs: []string{"dev"}, Receiver: "unknown"},
+		},
+		[]config.NotificationReceiver{devSlack},
+	))
+	require.Error(t, err)
+	assert.Len(t, n.handlers, 2)
+	assert.Len(t, n.senders, 3)
+}
@@ -0,0 +1,248 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/backoff"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+const (
+	pagerDutyEventsURL     = "https://events.pagerduty.com/v2/enqueue"
+	pagerDutyActionTrigger = "trigger"
+	pagerDutyActionResolve = "resolve"
+	// The maximum length of the summary accepted by PagerDuty.
+	pagerDutyMaxSummaryLength = 1024
+)
+
+type pagerDuty struct {
+	name       string
+	config     config.NotificationReceiverPagerDuty
+	routingKey string
+	eventsURL  string
+	webURL     string
+	httpClient *http.Client
+	eventCh    chan model.NotificationEvent
+	logger     *zap.Logger
+}
+
+func newPagerDutySender(name string, cfg config.NotificationReceiverPagerDuty, webURL string, logger *zap.Logger) (*pagerDuty, error) {
+	key, err := ioutil.ReadFile(cfg.RoutingKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read routing key file of pagerDuty %s (%w)", name, err)
+	}
+	if cfg.Severity == "" {
+		cfg.Severity = config.PagerDutySeverityError
+	}
+	return &pagerDuty{
+		name:       name,
+		config:     cfg,
+		routingKey: strings.TrimSpace(string(key)),
+		eventsURL:  pagerDutyEventsURL,
+		webURL:     strings.TrimRight(webURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		eventCh: make(chan model.NotificationEvent, 100),
+		logger:  logger.Named("pagerduty").With(zap.String("receiver", name)),
+	}, nil
+}
+
+func (p *pagerDuty) Run(ctx context.Context) error {
+	for {
+		select {
+		case event, ok := <-p.eventCh:
+			if ok {
+				p.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (p *pagerDuty) Notify(event model.NotificationEvent) {
+	p.eventCh <- event
+}
+
+func (p *pagerDuty) Close(ctx context.Context) {
+	close(p.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-p.eventCh:
+			if !ok {
+				return
+			}
+			p.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *pagerDuty) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	msg, ok := p.buildPagerDutyMessage(event, time.Now())
+	if !ok {
+		p.logger.Debug(fmt.Sprintf("ignore event %s", event.Type.String()))
+		return
+	}
+
+	retry := backoff.NewRetry(eventBusMaxRetries, backoff.NewExponential(eventBusRetryBaseInterval, eventBusRetryMaxInterval))
+	_, err := retry.Do(ctx, func() (interface{}, error) {
+		return nil, p.sendMessage(ctx, msg)
+	})
+	if err != nil {
+		p.logger.Error(fmt.Sprintf("unable to send event %s to pagerDuty: %v", event.Type.String(), err))
+	}
+}
+
+func (p *pagerDuty) sendMessage(ctx context.Context, msg *pagerDutyMessage) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(msg); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.eventsURL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from PagerDuty: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// buildPagerDutyMessage converts the given event into a PagerDuty event.
+// The failures of a deployment trigger an incident of its application,
+// and the incident is resolved once a deployment of the application succeeded.
+// Other events are ignored since they do not require any action.
+func (p *pagerDuty) buildPagerDutyMessage(event model.NotificationEvent, now time.Time) (*pagerDutyMessage, bool) {
+	var (
+		action, summary, reason string
+		severity                = p.config.Severity
+		deployment              *model.Deployment
+		envName                 string
+	)
+
+	switch md := event.Metadata.(type) {
+	case *model.NotificationEventDeploymentFailed:
+		action, deployment, envName, reason = pagerDutyActionTrigger, md.Deployment, md.EnvName, md.Reason
+		summary = fmt.Sprintf("Deployment for %q was failed", md.Deployment.ApplicationName)
+
+	case *model.NotificationEventDeploymentIncident:
+		action, deployment, envName, reason = pagerDutyActionTrigger, md.Deployment, md.EnvName, md.Reason
+		summary = fmt.Sprintf("Deployment for %q was rolled back because stage %s failed", md.Deployment.ApplicationName, md.StageId)
+
+	case *model.NotificationEventAnalysisFailed:
+		// The failures only reported do not stop the deployment.
+		if md.ReportOnly {
+			return nil, false
+		}
+		action, deployment, envName, reason = pagerDutyActionTrigger, md.Deployment, md.EnvName, md.Reason
+		summary = fmt.Sprintf("Analysis stage %s of deployment for %q was failed", md.StageId, md.Deployment.ApplicationName)
+
+	case *model.NotificationEventDeploymentSucceeded:
+		action, deployment, envName = pagerDutyActionResolve, md.Deployment, md.EnvName
+		summary = fmt.Sprintf("Deployment for %q was completed successfully", md.Deployment.ApplicationName)
+
+	default:
+		return nil, false
+	}
+
+	msg := &pagerDutyMessage{
+		RoutingKey:  p.routingKey,
+		EventAction: action,
+		// All incidents of an application are grouped into one
+		// to be resolved by its next successful deployment.
+		DedupKey: fmt.Sprintf("pipecd/%s", deployment.ApplicationId),
+	}
+	if action == pagerDutyActionResolve {
+		return msg, true
+	}
+
+	msg.Payload = &pagerDutyPayload{
+		Summary:   truncateText(summary, pagerDutyMaxSummaryLength-3),
+		Source:    fmt.Sprintf("pipecd/piped/%s", deployment.PipedId),
+		Severity:  severity,
+		Timestamp: now.UTC().Format(time.RFC3339),
+		Component: deployment.ApplicationName,
+		Group:     envName,
+		Class:     event.Type.String(),
+		CustomDetails: map[string]string{
+			"deployment":  deployment.Id,
+			"reason":      reason,
+			"kind":        strings.ToLower(deployment.Kind.String()),
+			"triggeredBy": deployment.TriggeredBy(),
+		},
+	}
+	if p.webURL != "" {
+		msg.Links = []pagerDutyLink{{
+			Href: p.webURL + "/deployments/" + deployment.Id,
+			Text: "View in PipeCD",
+		}}
+	}
+	return msg, true
+}
+
+// pagerDutyMessage is the event sent to PagerDuty Events API v2.
+// https://developer.pagerduty.com/docs/ZG9jOjExMDI5NTgw-events-api-v2-overview
+type pagerDutyMessage struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	Group         string            `json:"group,omitempty"`
+	Class         string            `json:"class,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
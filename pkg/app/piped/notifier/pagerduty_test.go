@@ -0,0 +1,126 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildPagerDutyMessage(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	deployment := &model.Deployment{
+		Id:              "deployment-1",
+		ApplicationId:   "app-1",
+		ApplicationName: "demo",
+		PipedId:         "piped-1",
+	}
+	p := &pagerDuty{
+		config:     config.NotificationReceiverPagerDuty{Severity: config.PagerDutySeverityCritical},
+		routingKey: "routing-key",
+		webURL:     "https://pipecd.dev",
+	}
+
+	testcases := []struct {
+		name   string
+		event  model.NotificationEvent
+		want   *pagerDutyMessage
+		wantOK bool
+	}{
+		{
+			name: "failed deployment triggers an incident",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_FAILED,
+				Metadata: &model.NotificationEventDeploymentFailed{
+					Deployment: deployment,
+					EnvName:    "prod",
+					Reason:     "timed out",
+				},
+			},
+			want: &pagerDutyMessage{
+				RoutingKey:  "routing-key",
+				EventAction: "trigger",
+				DedupKey:    "pipecd/app-1",
+				Payload: &pagerDutyPayload{
+					Summary:   `Deployment for "demo" was failed`,
+					Source:    "pipecd/piped/piped-1",
+					Severity:  "critical",
+					Timestamp: "2021-06-01T00:00:00Z",
+					Component: "demo",
+					Group:     "prod",
+					Class:     "EVENT_DEPLOYMENT_FAILED",
+					CustomDetails: map[string]string{
+						"deployment":  "deployment-1",
+						"reason":      "timed out",
+						"kind":        "kubernetes",
+						"triggeredBy": deployment.TriggeredBy(),
+					},
+				},
+				Links: []pagerDutyLink{{Href: "https://pipecd.dev/deployments/deployment-1", Text: "View in PipeCD"}},
+			},
+			wantOK: true,
+		},
+		{
+			name: "succeeded deployment resolves the incident",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED,
+				Metadata: &model.NotificationEventDeploymentSucceeded{
+					Deployment: deployment,
+					EnvName:    "prod",
+				},
+			},
+			want: &pagerDutyMessage{
+				RoutingKey:  "routing-key",
+				EventAction: "resolve",
+				DedupKey:    "pipecd/app-1",
+			},
+			wantOK: true,
+		},
+		{
+			name: "report-only analysis failure is ignored",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_ANALYSIS_FAILED,
+				Metadata: &model.NotificationEventAnalysisFailed{
+					Deployment: deployment,
+					EnvName:    "prod",
+					StageId:    "stage-1",
+					ReportOnly: true,
+				},
+			},
+		},
+		{
+			name: "other event is ignored",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED,
+				Metadata: &model.NotificationEventDeploymentTriggered{
+					Deployment: deployment,
+					EnvName:    "prod",
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := p.buildPagerDutyMessage(tc.event, now)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -170,10 +170,34 @@ func (s *slack) buildSlackMessage(event model.NotificationEvent, webURL string)
 		text = md.Summary
 		generateDeploymentEventData(md.Deployment, md.EnvName)
 
+	case model.NotificationEventType_EVENT_DEPLOYMENT_WAIT_APPROVAL:
+		md := event.Metadata.(*model.NotificationEventDeploymentWaitApproval)
+		title = fmt.Sprintf("Deployment for %q is waiting for an approval", md.Deployment.ApplicationName)
+		text = md.Summary
+		color = slackWarnColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+		if s.config.InteractiveApproval {
+			msg := makeSlackMessage(title, link, text, color, timestamp, fields...)
+			msg.Attachments[0].CallbackID = model.SlackApprovalCallbackID
+			msg.Attachments[0].Actions = makeSlackApprovalActions(md.Deployment.Id, md.StageId)
+			return msg, true
+		}
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED:
+		md := event.Metadata.(*model.NotificationEventDeploymentApproved)
+		title = fmt.Sprintf("Deployment for %q was approved", md.Deployment.ApplicationName)
+		text = fmt.Sprintf("Approved by %s", md.Approver)
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
 	case model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED:
 		md := event.Metadata.(*model.NotificationEventDeploymentSucceeded)
 		title = fmt.Sprintf("Deployment for %q was completed successfully", md.Deployment.ApplicationName)
 		color = slackSuccessColor
+		if len(md.Warnings) > 0 {
+			title = fmt.Sprintf("Deployment for %q was completed successfully with warnings", md.Deployment.ApplicationName)
+			text = strings.Join(md.Warnings, "\n")
+			color = slackWarnColor
+		}
 		generateDeploymentEventData(md.Deployment, md.EnvName)
 
 	case model.NotificationEventType_EVENT_DEPLOYMENT_FAILED:
@@ -190,6 +214,19 @@ func (s *slack) buildSlackMessage(event model.NotificationEvent, webURL string)
 		color = slackWarnColor
 		generateDeploymentEventData(md.Deployment, md.EnvName)
 
+	case model.NotificationEventType_EVENT_DEPLOYMENT_MESSAGE:
+		md := event.Metadata.(*model.NotificationEventDeploymentMessage)
+		title = fmt.Sprintf("Message from deployment for %q", md.Deployment.ApplicationName)
+		text = md.Message
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_INCIDENT:
+		md := event.Metadata.(*model.NotificationEventDeploymentIncident)
+		title = fmt.Sprintf("Deployment for %q was rolled back because stage %s failed", md.Deployment.ApplicationName, md.StageId)
+		text = md.Reason
+		color = slackErrorColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
 	case model.NotificationEventType_EVENT_PIPED_STARTED:
 		md := event.Metadata.(*model.NotificationEventPipedStarted)
 		title = "A piped has been started"
@@ -221,6 +258,25 @@ type slackAttachment struct {
 	Color     string       `json:"color,omitempty"`
 	Markdown  []string     `json:"mrkdwn_in,omitempty"`
 	Timestamp int64        `json:"ts,omitempty"`
+	// The fields for the interactive messages.
+	CallbackID string        `json:"callback_id,omitempty"`
+	Actions    []slackAction `json:"actions,omitempty"`
+}
+
+type slackAction struct {
+	Name    string              `json:"name"`
+	Text    string              `json:"text"`
+	Type    string              `json:"type"`
+	Value   string              `json:"value"`
+	Style   string              `json:"style,omitempty"`
+	Confirm *slackActionConfirm `json:"confirm,omitempty"`
+}
+
+type slackActionConfirm struct {
+	Title       string `json:"title"`
+	Text        string `json:"text"`
+	OkText      string `json:"ok_text"`
+	DismissText string `json:"dismiss_text"`
 }
 
 type slackField struct {
@@ -244,6 +300,34 @@ func truncateText(text string, max int) string {
 	return text[:max] + "..."
 }
 
+// makeSlackApprovalActions returns the buttons to approve or reject the given stage.
+// The clicks on them are sent to the control plane by Slack.
+func makeSlackApprovalActions(deploymentID, stageID string) []slackAction {
+	value := model.MakeSlackApprovalValue(deploymentID, stageID)
+	return []slackAction{
+		{
+			Name:  model.SlackApprovalActionApprove,
+			Text:  "Approve",
+			Type:  "button",
+			Value: value,
+			Style: "primary",
+		},
+		{
+			Name:  model.SlackApprovalActionReject,
+			Text:  "Reject",
+			Type:  "button",
+			Value: value,
+			Style: "danger",
+			Confirm: &slackActionConfirm{
+				Title:       "Reject the deployment?",
+				Text:        "The stage will be failed and the deployment will be rolled back if configured.",
+				OkText:      "Reject",
+				DismissText: "Cancel",
+			},
+		},
+	}
+}
+
 func makeSlackMessage(title, titleLink, text, color string, timestamp int64, fields ...slackField) slackMessage {
 	return slackMessage{
 		Username: slackUsername,
@@ -0,0 +1,306 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// The theme colors of Teams message cards.
+// They are same as the ones of Slack without the leading "#".
+var (
+	teamsInfoColor    = strings.TrimPrefix(slackInfoColor, "#")
+	teamsSuccessColor = strings.TrimPrefix(slackSuccessColor, "#")
+	teamsErrorColor   = strings.TrimPrefix(slackErrorColor, "#")
+	teamsWarnColor    = strings.TrimPrefix(slackWarnColor, "#")
+)
+
+type teams struct {
+	name       string
+	config     config.NotificationReceiverTeams
+	webURL     string
+	httpClient *http.Client
+	eventCh    chan model.NotificationEvent
+	logger     *zap.Logger
+}
+
+func newTeamsSender(name string, cfg config.NotificationReceiverTeams, webURL string, logger *zap.Logger) *teams {
+	return &teams{
+		name:   name,
+		config: cfg,
+		webURL: strings.TrimRight(webURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		eventCh: make(chan model.NotificationEvent, 100),
+		logger:  logger.Named("teams"),
+	}
+}
+
+func (t *teams) Run(ctx context.Context) error {
+	for {
+		select {
+		case event, ok := <-t.eventCh:
+			if ok {
+				t.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (t *teams) Notify(event model.NotificationEvent) {
+	t.eventCh <- event
+}
+
+func (t *teams) Close(ctx context.Context) {
+	close(t.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-t.eventCh:
+			if !ok {
+				return
+			}
+			t.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *teams) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	msg, ok := buildTeamsMessage(event, t.webURL)
+	if !ok {
+		t.logger.Info(fmt.Sprintf("ignore event %s", event.Type.String()))
+		return
+	}
+	if err := t.sendMessage(ctx, msg); err != nil {
+		t.logger.Error(fmt.Sprintf("unable to send notification to teams: %v", err))
+	}
+}
+
+func (t *teams) sendMessage(ctx context.Context, msg teamsMessage) error {
+	buf := &bytes.Buffer{}
+	if err := json.NewEncoder(buf).Encode(msg); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.HookURL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from Teams: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func buildTeamsMessage(event model.NotificationEvent, webURL string) (teamsMessage, bool) {
+	var (
+		title, link, text string
+		color             = teamsInfoColor
+		facts             []teamsFact
+	)
+
+	generateDeploymentEventData := func(d *model.Deployment, envName string) {
+		link = webURL + "/deployments/" + d.Id
+		facts = []teamsFact{
+			{"Env", envName},
+			{"Application", d.ApplicationName},
+			{"Kind", strings.ToLower(d.Kind.String())},
+			{"Deployment", d.Id},
+			{"Triggered By", d.TriggeredBy()},
+			{"Started At", time.Unix(d.CreatedAt, 0).UTC().Format(time.RFC3339)},
+		}
+	}
+	generatePipedEventData := func(id, version string) {
+		link = webURL + "/settings/piped"
+		facts = []teamsFact{
+			{"Id", id},
+			{"Version", version},
+		}
+	}
+
+	switch event.Type {
+	case model.NotificationEventType_EVENT_DEPLOYMENT_TRIGGERED:
+		md := event.Metadata.(*model.NotificationEventDeploymentTriggered)
+		title = fmt.Sprintf("Triggered a new deployment for %q", md.Deployment.ApplicationName)
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_PLANNED:
+		md := event.Metadata.(*model.NotificationEventDeploymentPlanned)
+		title = fmt.Sprintf("Deployment for %q was planned", md.Deployment.ApplicationName)
+		text = md.Summary
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_WAIT_APPROVAL:
+		md := event.Metadata.(*model.NotificationEventDeploymentWaitApproval)
+		title = fmt.Sprintf("Deployment for %q is waiting for an approval", md.Deployment.ApplicationName)
+		text = md.Summary
+		color = teamsWarnColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_APPROVED:
+		md := event.Metadata.(*model.NotificationEventDeploymentApproved)
+		title = fmt.Sprintf("Deployment for %q was approved", md.Deployment.ApplicationName)
+		text = fmt.Sprintf("Approved by %s", md.Approver)
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_SUCCEEDED:
+		md := event.Metadata.(*model.NotificationEventDeploymentSucceeded)
+		title = fmt.Sprintf("Deployment for %q was completed successfully", md.Deployment.ApplicationName)
+		color = teamsSuccessColor
+		if len(md.Warnings) > 0 {
+			title = fmt.Sprintf("Deployment for %q was completed successfully with warnings", md.Deployment.ApplicationName)
+			text = strings.Join(md.Warnings, "\n\n")
+			color = teamsWarnColor
+		}
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_FAILED:
+		md := event.Metadata.(*model.NotificationEventDeploymentFailed)
+		title = fmt.Sprintf("Deployment for %q was failed", md.Deployment.ApplicationName)
+		text = md.Reason
+		color = teamsErrorColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_CANCELLED:
+		md := event.Metadata.(*model.NotificationEventDeploymentCancelled)
+		title = fmt.Sprintf("Deployment for %q was cancelled", md.Deployment.ApplicationName)
+		text = fmt.Sprintf("Cancelled by %s", md.Commander)
+		color = teamsWarnColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_MESSAGE:
+		md := event.Metadata.(*model.NotificationEventDeploymentMessage)
+		title = fmt.Sprintf("Message from deployment for %q", md.Deployment.ApplicationName)
+		text = md.Message
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_DEPLOYMENT_INCIDENT:
+		md := event.Metadata.(*model.NotificationEventDeploymentIncident)
+		title = fmt.Sprintf("Deployment for %q was rolled back because stage %s failed", md.Deployment.ApplicationName, md.StageId)
+		text = md.Reason
+		color = teamsErrorColor
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_ANALYSIS_FAILED:
+		md := event.Metadata.(*model.NotificationEventAnalysisFailed)
+		title = fmt.Sprintf("Analysis stage %s of deployment for %q was failed", md.StageId, md.Deployment.ApplicationName)
+		text = md.Reason
+		color = teamsErrorColor
+		if md.ReportOnly {
+			color = teamsWarnColor
+		}
+		generateDeploymentEventData(md.Deployment, md.EnvName)
+
+	case model.NotificationEventType_EVENT_PIPED_STARTED:
+		md := event.Metadata.(*model.NotificationEventPipedStarted)
+		title = "A piped has been started"
+		generatePipedEventData(md.Id, md.Version)
+
+	case model.NotificationEventType_EVENT_PIPED_STOPPED:
+		md := event.Metadata.(*model.NotificationEventPipedStopped)
+		title = "A piped has been stopped"
+		generatePipedEventData(md.Id, md.Version)
+
+	default:
+		return teamsMessage{}, false
+	}
+
+	return makeTeamsMessage(title, link, text, color, facts...), true
+}
+
+// teamsMessage is the legacy actionable message card
+// accepted by the incoming webhooks of Microsoft Teams.
+// https://docs.microsoft.com/en-us/outlook/actionable-messages/message-card-reference
+type teamsMessage struct {
+	Type            string         `json:"@type"`
+	Context         string         `json:"@context"`
+	Summary         string         `json:"summary"`
+	ThemeColor      string         `json:"themeColor,omitempty"`
+	Title           string         `json:"title"`
+	Text            string         `json:"text,omitempty"`
+	Sections        []teamsSection `json:"sections,omitempty"`
+	PotentialAction []teamsAction  `json:"potentialAction,omitempty"`
+}
+
+type teamsSection struct {
+	Facts []teamsFact `json:"facts"`
+}
+
+type teamsFact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type teamsAction struct {
+	Type    string              `json:"@type"`
+	Name    string              `json:"name"`
+	Targets []teamsActionTarget `json:"targets"`
+}
+
+type teamsActionTarget struct {
+	OS  string `json:"os"`
+	URI string `json:"uri"`
+}
+
+func makeTeamsMessage(title, link, text, color string, facts ...teamsFact) teamsMessage {
+	msg := teamsMessage{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    title,
+		ThemeColor: color,
+		Title:      title,
+		Text:       text,
+	}
+	if len(facts) > 0 {
+		msg.Sections = []teamsSection{{Facts: facts}}
+	}
+	if link != "" {
+		msg.PotentialAction = []teamsAction{{
+			Type:    "OpenUri",
+			Name:    "View in PipeCD",
+			Targets: []teamsActionTarget{{OS: "default", URI: link}},
+		}}
+	}
+	return msg
+}
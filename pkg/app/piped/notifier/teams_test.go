@@ -0,0 +1,119 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildTeamsMessage(t *testing.T) {
+	testcases := []struct {
+		name   string
+		event  model.NotificationEvent
+		want   teamsMessage
+		wantOK bool
+	}{
+		{
+			name: "piped started",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_PIPED_STARTED,
+				Metadata: &model.NotificationEventPipedStarted{
+					Id:      "piped-1",
+					Version: "v0.10.0",
+				},
+			},
+			want: teamsMessage{
+				Type:       "MessageCard",
+				Context:    "https://schema.org/extensions",
+				Summary:    "A piped has been started",
+				ThemeColor: "222429",
+				Title:      "A piped has been started",
+				Sections: []teamsSection{{Facts: []teamsFact{
+					{"Id", "piped-1"},
+					{"Version", "v0.10.0"},
+				}}},
+				PotentialAction: []teamsAction{{
+					Type:    "OpenUri",
+					Name:    "View in PipeCD",
+					Targets: []teamsActionTarget{{OS: "default", URI: "https://pipecd.dev/settings/piped"}},
+				}},
+			},
+			wantOK: true,
+		},
+		{
+			name: "deployment waiting for an approval",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_DEPLOYMENT_WAIT_APPROVAL,
+				Metadata: &model.NotificationEventDeploymentWaitApproval{
+					Deployment: &model.Deployment{
+						Id:              "deployment-1",
+						ApplicationName: "app-1",
+						Kind:            model.ApplicationKind_KUBERNETES,
+						Trigger: &model.DeploymentTrigger{
+							Commander: "user-1",
+							Commit:    &model.Commit{},
+						},
+						CreatedAt: 1609459200,
+					},
+					EnvName: "dev",
+					StageId: "stage-1",
+					Summary: "1 added manifests, 0 changed manifests, 0 deleted manifests",
+				},
+			},
+			want: teamsMessage{
+				Type:       "MessageCard",
+				Context:    "https://schema.org/extensions",
+				Summary:    `Deployment for "app-1" is waiting for an approval`,
+				ThemeColor: teamsWarnColor,
+				Title:      `Deployment for "app-1" is waiting for an approval`,
+				Text:       "1 added manifests, 0 changed manifests, 0 deleted manifests",
+				Sections: []teamsSection{{Facts: []teamsFact{
+					{"Env", "dev"},
+					{"Application", "app-1"},
+					{"Kind", "kubernetes"},
+					{"Deployment", "deployment-1"},
+					{"Triggered By", "user-1"},
+					{"Started At", "2021-01-01T00:00:00Z"},
+				}}},
+				PotentialAction: []teamsAction{{
+					Type:    "OpenUri",
+					Name:    "View in PipeCD",
+					Targets: []teamsActionTarget{{OS: "default", URI: "https://pipecd.dev/deployments/deployment-1"}},
+				}},
+			},
+			wantOK: true,
+		},
+		{
+			name: "unsupported event",
+			event: model.NotificationEvent{
+				Type: model.NotificationEventType_EVENT_APPLICATION_SYNCED,
+				Metadata: &model.NotificationEventApplicationSynced{
+					Application: &model.Application{Id: "app-1"},
+				},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := buildTeamsMessage(tc.event, "https://pipecd.dev")
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
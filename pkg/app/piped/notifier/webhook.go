@@ -1,4 +1,4 @@
-// Copyright 2020 The PipeCD Authors.
+// Copyright 2021 The PipeCD Authors.
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -15,7 +15,19 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -23,26 +35,186 @@ import (
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
+const (
+	// webhookContentType is the content type of the structured mode of CloudEvents HTTP binding.
+	// https://github.com/cloudevents/spec/blob/v1.0/http-protocol-binding.md#32-structured-content-mode
+	webhookContentType = "application/cloudevents+json"
+	// webhookTemplateContentType is the content type of the payload rendered from the template.
+	webhookTemplateContentType = "application/json"
+	// webhookSignatureHeader is the header containing the HMAC-SHA256 signature of the request body.
+	webhookSignatureHeader = "X-PipeCD-Signature"
+)
+
 type webhook struct {
-	name   string
-	config config.NotificationReceiverWebhook
-	logger *zap.Logger
+	name       string
+	config     config.NotificationReceiverWebhook
+	pipedID    string
+	signingKey []byte
+	template   *template.Template
+	httpClient *http.Client
+	eventCh    chan model.NotificationEvent
+	logger     *zap.Logger
 }
 
-func newWebhookSender(name string, cfg config.NotificationReceiverWebhook, logger *zap.Logger) *webhook {
-	return &webhook{
-		name:   name,
-		config: cfg,
-		logger: logger.Named("webhook"),
+func newWebhookSender(name string, cfg config.NotificationReceiverWebhook, pipedID string, logger *zap.Logger) (*webhook, error) {
+	w := &webhook{
+		name:    name,
+		config:  cfg,
+		pipedID: pipedID,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		eventCh: make(chan model.NotificationEvent, 100),
+		logger:  logger.Named("webhook"),
+	}
+	if cfg.SigningKeyFile != "" {
+		key, err := ioutil.ReadFile(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read signing key file of webhook %s (%w)", name, err)
+		}
+		w.signingKey = bytes.TrimSpace(key)
 	}
+	if cfg.PayloadTemplate != "" {
+		t, err := template.New(name).Funcs(webhookTemplateFuncs).Parse(cfg.PayloadTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse payload template of webhook %s (%w)", name, err)
+		}
+		w.template = t
+	}
+	return w, nil
+}
+
+// webhookTemplateFuncs are the functions available in the payload template.
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes the given value to be embedded into the JSON payload safely.
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// webhookTemplateData is the data passed to the payload template.
+// It has the same fields as the CloudEvents message while its data is decoded.
+type webhookTemplateData struct {
+	ID      string
+	Source  string
+	Type    string
+	Time    string
+	Group   string
+	AppID   string
+	AppName string
+	EnvName string
+	Data    map[string]interface{}
 }
 
 func (s *webhook) Run(ctx context.Context) error {
-	return nil
+	for {
+		select {
+		case event, ok := <-s.eventCh:
+			if ok {
+				s.sendEvent(ctx, event)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
-func (s *webhook) Notify(_ model.NotificationEvent) {
+func (s *webhook) Notify(event model.NotificationEvent) {
+	s.eventCh <- event
 }
 
 func (s *webhook) Close(ctx context.Context) {
+	close(s.eventCh)
+
+	// Send all remaining events.
+	for {
+		select {
+		case event, ok := <-s.eventCh:
+			if !ok {
+				return
+			}
+			s.sendEvent(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *webhook) sendEvent(ctx context.Context, event model.NotificationEvent) {
+	msg, err := buildEventMessage(event, s.pipedID, time.Now())
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("unable to build message for event %s: %v", event.Type.String(), err))
+		return
+	}
+	body, contentType, err := s.buildPayload(msg)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("unable to build payload for event %s: %v", event.Type.String(), err))
+		return
+	}
+	if err := s.sendMessage(ctx, body, contentType); err != nil {
+		s.logger.Error(fmt.Sprintf("unable to send notification to webhook: %v", err))
+	}
+}
+
+// buildPayload returns the request body and its content type.
+// The CloudEvents message is sent as is unless the payload template is configured.
+func (s *webhook) buildPayload(msg *eventBusMessage) ([]byte, string, error) {
+	if s.template == nil {
+		data, err := json.Marshal(msg)
+		return data, webhookContentType, err
+	}
+
+	data := webhookTemplateData{
+		ID:      msg.ID,
+		Source:  msg.Source,
+		Type:    msg.Type,
+		Time:    msg.Time,
+		Group:   msg.Group,
+		AppID:   msg.AppID,
+		AppName: msg.AppName,
+		EnvName: msg.EnvName,
+	}
+	if err := json.Unmarshal(msg.Data, &data.Data); err != nil {
+		return nil, "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := s.template.Execute(buf, data); err != nil {
+		return nil, "", fmt.Errorf("failed to render payload template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, "", fmt.Errorf("payload template rendered an invalid JSON: %s", truncateText(buf.String(), 256))
+	}
+	return buf.Bytes(), webhookTemplateContentType, nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 signature of the given body.
+func sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *webhook) sendMessage(ctx context.Context, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(s.signingKey) > 0 {
+		req.Header.Set(webhookSignatureHeader, sign(s.signingKey, body))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+		return fmt.Errorf("%s from webhook: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
 }
@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestBuildEventPayload(t *testing.T) {
+	msg, err := buildEventMessage(model.NotificationEvent{
+		Type: model.NotificationEventType_EVENT_DEPLOYMENT_FAILED,
+		Metadata: &model.NotificationEventDeploymentFailed{
+			Deployment: &model.Deployment{ApplicationId: "app-1", ApplicationName: "demo"},
+			EnvName:    "prod",
+			Reason:     `quota "cpu" exceeded`,
+		},
+	}, "piped-1", time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name            string
+		template        string
+		wantBody        string
+		wantContentType string
+		wantErr         bool
+	}{
+		{
+			name:            "rendered from template",
+			template:        `{"text": {{ printf "%s of %s in %s" .Type .AppName .EnvName | json }}, "reason": {{ json .Data.reason }}}`,
+			wantBody:        `{"text": "EVENT_DEPLOYMENT_FAILED of demo in prod", "reason": "quota \"cpu\" exceeded"}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:     "rendered an invalid json",
+			template: `{"text": {{ .AppName }}}`,
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := newWebhookSender("test", config.NotificationReceiverWebhook{
+				URL:             "https://pipecd.dev/hook",
+				PayloadTemplate: tc.template,
+			}, "piped-1", zap.NewNop())
+			require.NoError(t, err)
+
+			body, contentType, err := w.buildPayload(msg)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.wantBody, string(body))
+			assert.Equal(t, tc.wantContentType, contentType)
+		})
+	}
+}
+
+func TestWebhookSendMessage(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "signing-key")
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("secret\n"), 0600))
+
+	var (
+		gotSignature   string
+		gotContentType string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-PipeCD-Signature")
+		gotContentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	w, err := newWebhookSender("test", config.NotificationReceiverWebhook{
+		URL:            server.URL,
+		SigningKeyFile: keyFile,
+	}, "piped-1", zap.NewNop())
+	require.NoError(t, err)
+
+	err = w.sendMessage(context.Background(), []byte(`{"type":"EVENT_PIPED_STARTED"}`), webhookContentType)
+	require.NoError(t, err)
+
+	// echo -n '{"type":"EVENT_PIPED_STARTED"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=c72ba2488e855e3021e5a4cb28198058b50e0adae485751cb97cb0cc0a3cbcec", gotSignature)
+	assert.Equal(t, "application/cloudevents+json", gotContentType)
+}
@@ -97,6 +97,23 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		return
 	}
 
+	// Decide the strategy by the planner rules matching the changes since the last successful deployment.
+	strategy, desc, matched, err := planner.DecideStrategyByRules(cfg.Planner.Rules, in.Changes)
+	if err != nil {
+		return
+	}
+	if matched {
+		out.SyncStrategy = strategy
+		if strategy == model.SyncStrategy_PIPELINE {
+			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Sync with pipeline to deploy image %s (%s)", out.Version, desc)
+		} else {
+			out.Stages = buildQuickSyncPipeline(cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Quick sync to deploy image %s and configure all traffic to it (%s)", out.Version, desc)
+		}
+		return
+	}
+
 	// This is the first time to deploy this application or it was unable to retrieve that value.
 	// We just do the quick sync.
 	if in.MostRecentSuccessfulCommitHash == "" {
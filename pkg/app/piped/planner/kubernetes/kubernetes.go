@@ -68,6 +68,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		chartRepoName := cfg.Input.HelmChart.Repository
 		if chartRepoName != "" {
 			cfg.Input.HelmChart.Insecure = in.PipedConfig.IsInsecureChartRepository(chartRepoName)
+			cfg.Input.HelmChart.OCIRegistry = in.PipedConfig.GetOCIChartRegistry(chartRepoName)
 		}
 	}
 
@@ -165,6 +166,23 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		}
 	}
 
+	// Decide the strategy by the planner rules matching the changes since the last successful deployment.
+	strategy, desc, matched, err := planner.DecideStrategyByRules(cfg.Planner.Rules, in.Changes)
+	if err != nil {
+		return
+	}
+	if matched {
+		out.SyncStrategy = strategy
+		if strategy == model.SyncStrategy_PIPELINE {
+			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Sync with the specified pipeline (%s)", desc)
+		} else {
+			out.Stages = buildQuickSyncPipeline(cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Quick sync by applying all manifests (%s)", desc)
+		}
+		return
+	}
+
 	// This is the first time to deploy this application
 	// or it was unable to retrieve that value.
 	// We just apply all manifests.
@@ -23,7 +23,9 @@ import (
 	"go.uber.org/zap"
 
 	provider "github.com/pipe-cd/pipe/pkg/app/piped/cloudprovider/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -55,7 +57,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 	}
 
 	// Determine application version from the manifest
-	if version, err := determineVersion(ds.AppDir, cfg.Input.FunctionManifestFile); err == nil {
+	if version, err := determineVersion(ds, cfg.Input); err == nil {
 		out.Version = version
 	} else {
 		out.Version = "unknown"
@@ -97,6 +99,23 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		return
 	}
 
+	// Decide the strategy by the planner rules matching the changes since the last successful deployment.
+	strategy, desc, matched, err := planner.DecideStrategyByRules(cfg.Planner.Rules, in.Changes)
+	if err != nil {
+		return
+	}
+	if matched {
+		out.SyncStrategy = strategy
+		if strategy == model.SyncStrategy_PIPELINE {
+			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Sync with pipeline to deploy image %s (%s)", out.Version, desc)
+		} else {
+			out.Stages = buildQuickSyncPipeline(cfg.Input.AutoRollback, time.Now())
+			out.Summary = fmt.Sprintf("Quick sync to deploy image %s and configure all traffic to it (%s)", out.Version, desc)
+		}
+		return
+	}
+
 	// If this is the first time to deploy this application or it was unable to retrieve last successful commit,
 	// we perform the quick sync strategy.
 	if in.MostRecentSuccessfulCommitHash == "" {
@@ -109,7 +128,7 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 	// Load service manifest at the last deployed commit to decide running version.
 	ds, err = in.RunningDSP.Get(ctx, ioutil.Discard)
 	if err == nil {
-		if lastVersion, e := determineVersion(ds.AppDir, cfg.Input.FunctionManifestFile); e == nil {
+		if lastVersion, e := determineVersion(ds, cfg.Input); e == nil {
 			out.SyncStrategy = model.SyncStrategy_PIPELINE
 			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, time.Now())
 			out.Summary = fmt.Sprintf("Sync with pipeline to update image from %s to %s", lastVersion, out.Version)
@@ -123,11 +142,19 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 	return
 }
 
-func determineVersion(appDir, functionManifestFile string) (string, error) {
-	fm, err := provider.LoadFunctionManifest(appDir, functionManifestFile)
+func determineVersion(ds *deploysource.DeploySource, input config.LambdaDeploymentInput) (string, error) {
+	var (
+		fm  provider.FunctionManifest
+		err error
+	)
+	if pkg := input.Package; pkg != nil {
+		fm, err = provider.LoadPackagedFunctionManifest(ds.AppDir, input.FunctionManifestFile, *pkg, ds.Revision)
+	} else {
+		fm, err = provider.LoadFunctionManifest(ds.AppDir, input.FunctionManifestFile)
+	}
 	if err != nil {
 		return "", err
 	}
 
-	return provider.FindImageTag(fm)
+	return provider.FindArtifactVersion(fm)
 }
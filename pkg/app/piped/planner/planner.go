@@ -25,6 +25,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/pipe-cd/pipe/pkg/app/piped/changesummary"
 	"github.com/pipe-cd/pipe/pkg/app/piped/deploysource"
 	"github.com/pipe-cd/pipe/pkg/cache"
 	"github.com/pipe-cd/pipe/pkg/config"
@@ -42,12 +43,15 @@ type Input struct {
 	GitPath                        model.ApplicationGitPath
 	Trigger                        model.DeploymentTrigger
 	MostRecentSuccessfulCommitHash string
-	PipedConfig                    *config.PipedSpec
-	TargetDSP                      deploysource.Provider
-	RunningDSP                     deploysource.Provider
-	AppManifestsCache              cache.Cache
-	RegexPool                      *regexpool.Pool
-	Logger                         *zap.Logger
+	// The changes made to the application since the most recent successful commit.
+	// Nil when they are unknown, e.g. for the first deployment.
+	Changes           *changesummary.Summary
+	PipedConfig       *config.PipedSpec
+	TargetDSP         deploysource.Provider
+	RunningDSP        deploysource.Provider
+	AppManifestsCache cache.Cache
+	RegexPool         *regexpool.Pool
+	Logger            *zap.Logger
 }
 
 type Output struct {
@@ -20,12 +20,17 @@ import (
 )
 
 const (
-	PredefinedStageK8sSync       = "K8sSync"
-	PredefinedStageTerraformSync = "TerraformSync"
-	PredefinedStageCloudRunSync  = "CloudRunSync"
-	PredefinedStageLambdaSync    = "LambdaSync"
-	PredefinedStageECSSync       = "ECSSync"
-	PredefinedStageRollback      = "Rollback"
+	PredefinedStageK8sSync            = "K8sSync"
+	PredefinedStageTerraformSync      = "TerraformSync"
+	PredefinedStageCloudRunSync       = "CloudRunSync"
+	PredefinedStageLambdaSync         = "LambdaSync"
+	PredefinedStageECSSync            = "ECSSync"
+	PredefinedStageAppEngineSync      = "AppEngineSync"
+	PredefinedStageCloudFormationSync = "CloudFormationSync"
+	PredefinedStageNomadSync          = "NomadSync"
+	PredefinedStageAzureFunctionsSync = "AzureFunctionsSync"
+	PredefinedStageCustomSync         = "CustomSync"
+	PredefinedStageRollback           = "Rollback"
 )
 
 var predefinedStages = map[string]config.PipelineStage{
@@ -54,6 +59,31 @@ var predefinedStages = map[string]config.PipelineStage{
 		Name: model.StageECSSync,
 		Desc: "Deploy the new version and configure all traffic to it",
 	},
+	PredefinedStageAppEngineSync: {
+		Id:   PredefinedStageAppEngineSync,
+		Name: model.StageAppEngineSync,
+		Desc: "Deploy the new version and migrate all traffic to it",
+	},
+	PredefinedStageCloudFormationSync: {
+		Id:   PredefinedStageCloudFormationSync,
+		Name: model.StageCloudFormationSync,
+		Desc: "Sync by creating a change set and executing it immediately",
+	},
+	PredefinedStageNomadSync: {
+		Id:   PredefinedStageNomadSync,
+		Name: model.StageNomadSync,
+		Desc: "Run the job and promote its canaries if any",
+	},
+	PredefinedStageAzureFunctionsSync: {
+		Id:   PredefinedStageAzureFunctionsSync,
+		Name: model.StageAzureFunctionsSync,
+		Desc: "Deploy the package to the staging slot and swap it with the production slot",
+	},
+	PredefinedStageCustomSync: {
+		Id:   PredefinedStageCustomSync,
+		Name: model.StageCustomSync,
+		Desc: "Deploy the application by running the custom sync command",
+	},
 	PredefinedStageRollback: {
 		Id:   PredefinedStageRollback,
 		Name: model.StageRollback,
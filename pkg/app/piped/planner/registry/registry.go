@@ -19,10 +19,15 @@ import (
 	"sync"
 
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/appengine"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/azurefunctions"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/cloudformation"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/cloudrun"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/customsync"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/ecs"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/kubernetes"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/lambda"
+	"github.com/pipe-cd/pipe/pkg/app/piped/planner/nomad"
 	"github.com/pipe-cd/pipe/pkg/app/piped/planner/terraform"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -68,9 +73,14 @@ func DefaultRegistry() Registry {
 
 // init registers all planners to the default registry.
 func init() {
+	appengine.Register(defaultRegistry)
+	azurefunctions.Register(defaultRegistry)
+	cloudformation.Register(defaultRegistry)
 	cloudrun.Register(defaultRegistry)
+	customsync.Register(defaultRegistry)
 	kubernetes.Register(defaultRegistry)
 	lambda.Register(defaultRegistry)
+	nomad.Register(defaultRegistry)
 	terraform.Register(defaultRegistry)
 	ecs.Register(defaultRegistry)
 }
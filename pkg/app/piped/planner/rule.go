@@ -0,0 +1,94 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/changesummary"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/filematcher"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// DecideStrategyByRules evaluates the given planner rules in order against the changes
+// made since the last successful deployment, and returns the sync strategy of the first matching one
+// with the description of why it matched.
+// False is returned when the changes are unknown or no rule matched.
+func DecideStrategyByRules(rules []config.DeploymentPlannerRule, changes *changesummary.Summary) (strategy model.SyncStrategy, desc string, matched bool, err error) {
+	if changes == nil {
+		return
+	}
+	for i, r := range rules {
+		var reasons []string
+		reasons, matched, err = matchRule(r, changes)
+		if err != nil {
+			err = fmt.Errorf("failed to evaluate planner rule %d: %w", i, err)
+			return
+		}
+		if !matched {
+			continue
+		}
+		strategy = model.SyncStrategy_QUICK_SYNC
+		if r.Strategy == config.PlannerRuleStrategyPipeline {
+			strategy = model.SyncStrategy_PIPELINE
+		}
+		desc = fmt.Sprintf("planner rule %d matched: %s", i, strings.Join(reasons, " and "))
+		return
+	}
+	return
+}
+
+// matchRule reports whether the given changes satisfy all conditions of the rule.
+func matchRule(r config.DeploymentPlannerRule, changes *changesummary.Summary) ([]string, bool, error) {
+	var reasons []string
+	switch r.Changes {
+	case config.PlannerRuleChangesImage:
+		if len(changes.Images) == 0 {
+			return nil, false, nil
+		}
+		reasons = append(reasons, "the container images were changed")
+	case config.PlannerRuleChangesConfig:
+		if len(changes.Files) == 0 || len(changes.Images) > 0 {
+			return nil, false, nil
+		}
+		reasons = append(reasons, "only the configuration was changed")
+	}
+
+	if len(r.Paths) > 0 {
+		matcher, err := filematcher.NewPatternMatcher(r.Paths)
+		if err != nil {
+			return nil, false, err
+		}
+		var matched string
+		for _, f := range changes.Files {
+			if matcher.Matches(f.Path) {
+				matched = f.Path
+				break
+			}
+		}
+		if matched == "" {
+			return nil, false, nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%s was changed", matched))
+	}
+
+	// A rule without any condition never matches.
+	if len(reasons) == 0 {
+		return nil, false, nil
+	}
+	return reasons, true, nil
+}
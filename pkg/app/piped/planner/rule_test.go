@@ -0,0 +1,127 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package planner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/changesummary"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestDecideStrategyByRules(t *testing.T) {
+	var (
+		imageChanges = &changesummary.Summary{
+			Files:  []git.FileChange{{Status: git.FileModified, Path: "deployment.yaml"}},
+			Images: []changesummary.ImageChange{{Name: "gcr.io/pipecd/helloworld", From: []string{"v0.1.0"}, To: []string{"v0.2.0"}}},
+		}
+		configChanges = &changesummary.Summary{
+			Files: []git.FileChange{{Status: git.FileModified, Path: "config/app.yaml"}},
+		}
+		rules = []config.DeploymentPlannerRule{
+			{
+				Strategy: config.PlannerRuleStrategyPipeline,
+				Changes:  config.PlannerRuleChangesImage,
+			},
+			{
+				Strategy: config.PlannerRuleStrategyPipeline,
+				Changes:  config.PlannerRuleChangesConfig,
+				Paths:    []string{"migrations/**"},
+			},
+			{
+				Strategy: config.PlannerRuleStrategyQuickSync,
+				Changes:  config.PlannerRuleChangesConfig,
+			},
+		}
+	)
+	testcases := []struct {
+		name         string
+		rules        []config.DeploymentPlannerRule
+		changes      *changesummary.Summary
+		wantStrategy model.SyncStrategy
+		wantDesc     string
+		wantMatched  bool
+		wantErr      bool
+	}{
+		{
+			name:    "unknown changes",
+			rules:   rules,
+			changes: nil,
+		},
+		{
+			name:         "image was changed",
+			rules:        rules,
+			changes:      imageChanges,
+			wantStrategy: model.SyncStrategy_PIPELINE,
+			wantDesc:     "planner rule 0 matched: the container images were changed",
+			wantMatched:  true,
+		},
+		{
+			name:  "config and migration were changed",
+			rules: rules,
+			changes: &changesummary.Summary{
+				Files: []git.FileChange{
+					{Status: git.FileModified, Path: "config/app.yaml"},
+					{Status: git.FileAdded, Path: "migrations/002_add_index.sql"},
+				},
+			},
+			wantStrategy: model.SyncStrategy_PIPELINE,
+			wantDesc:     "planner rule 1 matched: only the configuration was changed and migrations/002_add_index.sql was changed",
+			wantMatched:  true,
+		},
+		{
+			name:         "only config was changed",
+			rules:        rules,
+			changes:      configChanges,
+			wantStrategy: model.SyncStrategy_QUICK_SYNC,
+			wantDesc:     "planner rule 2 matched: only the configuration was changed",
+			wantMatched:  true,
+		},
+		{
+			name:    "no rule matched",
+			rules:   rules[:2],
+			changes: configChanges,
+		},
+		{
+			name:    "no file was changed",
+			rules:   rules,
+			changes: &changesummary.Summary{},
+		},
+		{
+			name: "invalid pattern",
+			rules: []config.DeploymentPlannerRule{
+				{
+					Strategy: config.PlannerRuleStrategyPipeline,
+					Paths:    []string{"[a-"},
+				},
+			},
+			changes: configChanges,
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy, desc, matched, err := DecideStrategyByRules(tc.rules, tc.changes)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantStrategy, strategy)
+			assert.Equal(t, tc.wantDesc, desc)
+			assert.Equal(t, tc.wantMatched, matched)
+		})
+	}
+}
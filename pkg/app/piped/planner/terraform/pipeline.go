@@ -78,15 +78,17 @@ func buildProgressivePipeline(pp *config.DeploymentPipeline, autoRollback bool,
 			id = fmt.Sprintf("stage-%d", i)
 		}
 		stage := &model.PipelineStage{
-			Id:         id,
-			Name:       s.Name.String(),
-			Desc:       s.Desc,
-			Index:      int32(i),
-			Predefined: false,
-			Visible:    true,
-			Status:     model.StageStatus_STAGE_NOT_STARTED_YET,
-			CreatedAt:  now.Unix(),
-			UpdatedAt:  now.Unix(),
+			Id:                id,
+			Name:              s.Name.String(),
+			Desc:              s.Desc,
+			Index:             int32(i),
+			Predefined:        false,
+			Visible:           true,
+			Status:            model.StageStatus_STAGE_NOT_STARTED_YET,
+			EstimatedDuration: int64(s.EstimatedDuration.Duration().Seconds()),
+			Outputs:           s.StageOutputs(),
+			CreatedAt:         now.Unix(),
+			UpdatedAt:         now.Unix(),
 		}
 		if preStageID != "" {
 			stage.Requires = []string{preStageID}
@@ -88,6 +88,23 @@ func (p *Planner) Plan(ctx context.Context, in planner.Input) (out planner.Outpu
 		return
 	}
 
+	// Decide the strategy by the planner rules matching the changes since the last successful deployment.
+	strategy, desc, matched, err := planner.DecideStrategyByRules(cfg.Planner.Rules, in.Changes)
+	if err != nil {
+		return
+	}
+	if matched {
+		out.SyncStrategy = strategy
+		if strategy == model.SyncStrategy_PIPELINE {
+			out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, now)
+			out.Summary = fmt.Sprintf("Sync with the specified progressive pipeline (%s)", desc)
+		} else {
+			out.Stages = buildQuickSyncPipeline(cfg.Input.AutoRollback, now)
+			out.Summary = fmt.Sprintf("Quick sync by automatically applying any detected changes (%s)", desc)
+		}
+		return
+	}
+
 	out.SyncStrategy = model.SyncStrategy_PIPELINE
 	out.Stages = buildProgressivePipeline(cfg.Pipeline, cfg.Input.AutoRollback, now)
 	out.Summary = "Sync with the specified progressive pipeline"
@@ -308,7 +308,7 @@ func (b *builder) cloneHeadCommit(ctx context.Context, headBranch, headCommit st
 func (b *builder) findTriggerApps(ctx context.Context, repo git.Repo, apps []*model.Application, headCommit string) (triggerApps []*model.Application, failedResults []*model.ApplicationPlanPreviewResult, err error) {
 	d := trigger.NewDeterminer(repo, headCommit, b.commitGetter, b.logger)
 	for _, app := range apps {
-		shouldTrigger, err := d.ShouldTrigger(ctx, app)
+		shouldTrigger, commit, err := d.ShouldTrigger(ctx, app)
 		if err != nil {
 			// We only need the environment name
 			// so the returned error can be ignorable.
@@ -323,7 +323,9 @@ func (b *builder) findTriggerApps(ctx context.Context, repo git.Repo, apps []*mo
 			continue
 		}
 
-		if shouldTrigger {
+		// The applications triggered by tags are not deployed at the head commit
+		// so they are not affected by the changes of this pull request.
+		if shouldTrigger && commit == headCommit {
 			triggerApps = append(triggerApps, app)
 		}
 	}
@@ -81,6 +81,7 @@ func WithLogger(l *zap.Logger) Option {
 
 type gitClient interface {
 	Clone(ctx context.Context, repoID, remote, branch, destination string) (git.Repo, error)
+	Worktree(ctx context.Context, repoID, remote, revision, destination string) (git.Repo, error)
 }
 
 type apiClient interface {
@@ -0,0 +1,163 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharder
+
+import (
+	"context"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/applicationstore"
+	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/commandstore"
+	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/deploymentstore"
+	"github.com/pipe-cd/pipe/pkg/app/piped/apistore/eventstore"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// ApplicationLister returns a lister listing only the applications handled by this replica.
+func (s *Sharder) ApplicationLister(l applicationstore.Lister) applicationstore.Lister {
+	return &shardedApplicationLister{lister: l, sharder: s}
+}
+
+// DeploymentLister returns a lister listing only the deployments of the applications handled by this replica.
+func (s *Sharder) DeploymentLister(l deploymentstore.Lister) deploymentstore.Lister {
+	return &shardedDeploymentLister{lister: l, sharder: s}
+}
+
+// CommandLister returns a lister listing only the commands should be handled by this replica.
+// The commands for building plan-preview are handled by the leader.
+func (s *Sharder) CommandLister(l commandstore.Lister) commandstore.Lister {
+	return &shardedCommandLister{lister: l, sharder: s}
+}
+
+// EventGetter returns a getter returning the events only to the leader
+// to not make multiple replicas update the same files.
+func (s *Sharder) EventGetter(g eventstore.Getter) eventstore.Getter {
+	return &shardedEventGetter{getter: g, sharder: s}
+}
+
+type shardedApplicationLister struct {
+	lister  applicationstore.Lister
+	sharder *Sharder
+}
+
+func (l *shardedApplicationLister) List() []*model.Application {
+	return l.filter(l.lister.List())
+}
+
+func (l *shardedApplicationLister) ListByCloudProvider(name string) []*model.Application {
+	return l.filter(l.lister.ListByCloudProvider(name))
+}
+
+func (l *shardedApplicationLister) Get(id string) (*model.Application, bool) {
+	if !l.sharder.Owns(id) {
+		return nil, false
+	}
+	return l.lister.Get(id)
+}
+
+func (l *shardedApplicationLister) filter(apps []*model.Application) []*model.Application {
+	out := make([]*model.Application, 0, len(apps))
+	for _, app := range apps {
+		if l.sharder.Owns(app.Id) {
+			out = append(out, app)
+		}
+	}
+	return out
+}
+
+type shardedDeploymentLister struct {
+	lister  deploymentstore.Lister
+	sharder *Sharder
+}
+
+func (l *shardedDeploymentLister) ListPendings() []*model.Deployment {
+	return l.filter(l.lister.ListPendings())
+}
+
+func (l *shardedDeploymentLister) ListPlanneds() []*model.Deployment {
+	return l.filter(l.lister.ListPlanneds())
+}
+
+func (l *shardedDeploymentLister) ListRunnings() []*model.Deployment {
+	return l.filter(l.lister.ListRunnings())
+}
+
+func (l *shardedDeploymentLister) ListAppHeadDeployments() map[string]*model.Deployment {
+	heads := l.lister.ListAppHeadDeployments()
+	out := make(map[string]*model.Deployment, len(heads))
+	for id, d := range heads {
+		if l.sharder.Owns(id) {
+			out[id] = d
+		}
+	}
+	return out
+}
+
+func (l *shardedDeploymentLister) filter(ds []*model.Deployment) []*model.Deployment {
+	out := make([]*model.Deployment, 0, len(ds))
+	for _, d := range ds {
+		if l.sharder.Owns(d.ApplicationId) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+type shardedCommandLister struct {
+	lister  commandstore.Lister
+	sharder *Sharder
+}
+
+func (l *shardedCommandLister) ListApplicationCommands() []model.ReportableCommand {
+	return l.filter(l.lister.ListApplicationCommands())
+}
+
+func (l *shardedCommandLister) ListDeploymentCommands() []model.ReportableCommand {
+	return l.filter(l.lister.ListDeploymentCommands())
+}
+
+// ListStageCommands returns the commands as is since they are looked up
+// only by the replica running the deployment.
+func (l *shardedCommandLister) ListStageCommands(deploymentID, stageID string) []model.ReportableCommand {
+	return l.lister.ListStageCommands(deploymentID, stageID)
+}
+
+func (l *shardedCommandLister) ListBuildPlanPreviewCommands() []model.ReportableCommand {
+	if !l.sharder.IsLeader() {
+		return nil
+	}
+	return l.lister.ListBuildPlanPreviewCommands()
+}
+
+func (l *shardedCommandLister) filter(cmds []model.ReportableCommand) []model.ReportableCommand {
+	out := make([]model.ReportableCommand, 0, len(cmds))
+	for _, cmd := range cmds {
+		if l.sharder.Owns(cmd.ApplicationId) {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+type shardedEventGetter struct {
+	getter  eventstore.Getter
+	sharder *Sharder
+}
+
+func (g *shardedEventGetter) GetLatest(ctx context.Context, name string, labels map[string]string) (*model.Event, bool) {
+	if !g.sharder.IsLeader() {
+		return nil, false
+	}
+	return g.getter.GetLatest(ctx, name, labels)
+}
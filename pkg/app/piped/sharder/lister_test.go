@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeStoreLister struct {
+	apps        []*model.Application
+	deployments []*model.Deployment
+	commands    []model.ReportableCommand
+}
+
+func (l *fakeStoreLister) List() []*model.Application {
+	return l.apps
+}
+
+func (l *fakeStoreLister) ListByCloudProvider(_ string) []*model.Application {
+	return l.apps
+}
+
+func (l *fakeStoreLister) Get(id string) (*model.Application, bool) {
+	for _, app := range l.apps {
+		if app.Id == id {
+			return app, true
+		}
+	}
+	return nil, false
+}
+
+func (l *fakeStoreLister) ListPendings() []*model.Deployment {
+	return l.deployments
+}
+
+func (l *fakeStoreLister) ListPlanneds() []*model.Deployment {
+	return nil
+}
+
+func (l *fakeStoreLister) ListRunnings() []*model.Deployment {
+	return nil
+}
+
+func (l *fakeStoreLister) ListAppHeadDeployments() map[string]*model.Deployment {
+	return nil
+}
+
+func (l *fakeStoreLister) ListApplicationCommands() []model.ReportableCommand {
+	return l.commands
+}
+
+func (l *fakeStoreLister) ListDeploymentCommands() []model.ReportableCommand {
+	return nil
+}
+
+func (l *fakeStoreLister) ListStageCommands(_, _ string) []model.ReportableCommand {
+	return nil
+}
+
+func (l *fakeStoreLister) ListBuildPlanPreviewCommands() []model.ReportableCommand {
+	return l.commands
+}
+
+func (l *fakeStoreLister) GetLatest(_ context.Context, _ string, _ map[string]string) (*model.Event, bool) {
+	return &model.Event{}, true
+}
+
+func TestShardedListers(t *testing.T) {
+	l := &fakeStoreLister{
+		apps: []*model.Application{
+			{Id: "app-1"},
+			{Id: "app-2"},
+		},
+		deployments: []*model.Deployment{
+			{Id: "deployment-1", ApplicationId: "app-1"},
+			{Id: "deployment-2", ApplicationId: "app-2"},
+		},
+		commands: []model.ReportableCommand{
+			{Command: &model.Command{Id: "command-1", ApplicationId: "app-1"}},
+			{Command: &model.Command{Id: "command-2", ApplicationId: "app-2"}},
+		},
+	}
+
+	testcases := []struct {
+		name      string
+		replicaID string
+		handling  []string
+		wantApps  []string
+		leader    bool
+	}{
+		{
+			name:      "leader",
+			replicaID: "replica-0",
+			handling:  []string{"app-1"},
+			wantApps:  []string{"app-1"},
+			leader:    true,
+		},
+		{
+			name:      "not leader",
+			replicaID: "replica-1",
+			handling:  []string{"app-2"},
+			wantApps:  []string{"app-2"},
+			leader:    false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSharder(tc.replicaID, nil, nil, &config.PipedSharding{})
+			s.replicas = []string{"replica-0", "replica-1"}
+			s.reportedAt = s.nowFunc()
+			for _, id := range tc.handling {
+				s.handling[id] = struct{}{}
+			}
+
+			var (
+				apps        []string
+				deployments []string
+				commands    []string
+			)
+			for _, app := range s.ApplicationLister(l).List() {
+				apps = append(apps, app.Id)
+			}
+			for _, d := range s.DeploymentLister(l).ListPendings() {
+				deployments = append(deployments, d.ApplicationId)
+			}
+			for _, cmd := range s.CommandLister(l).ListApplicationCommands() {
+				commands = append(commands, cmd.ApplicationId)
+			}
+			assert.Equal(t, tc.wantApps, apps)
+			assert.Equal(t, tc.wantApps, deployments)
+			assert.Equal(t, tc.wantApps, commands)
+
+			_, ok := s.ApplicationLister(l).Get(tc.wantApps[0])
+			assert.True(t, ok)
+			_, ok = s.ApplicationLister(l).Get("app-3")
+			assert.False(t, ok)
+
+			assert.Equal(t, tc.leader, len(s.CommandLister(l).ListBuildPlanPreviewCommands()) > 0)
+			_, ok = s.EventGetter(l).GetLatest(context.Background(), "event", nil)
+			assert.Equal(t, tc.leader, ok)
+		})
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sharder provides a piped component that shards the applications
+// across the replicas running with the same piped ID and key.
+//
+// Each replica periodically reports a heartbeat with the applications it is handling
+// to the control plane and receives the ones of the other replicas.
+// The applications are assigned to the live replicas explicitly or by rendezvous hashing,
+// and the applications of the replica stopped reporting heartbeats are handed over to the others.
+// To not run a deployment on multiple replicas, a replica keeps handling an application
+// until its in-flight deployment is completed, and an application is never taken
+// while another live replica is handling it.
+package sharder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type apiClient interface {
+	ReportReplicaHeartbeat(ctx context.Context, req *pipedservice.ReportReplicaHeartbeatRequest, opts ...grpc.CallOption) (*pipedservice.ReportReplicaHeartbeatResponse, error)
+}
+
+type applicationLister interface {
+	List() []*model.Application
+}
+
+type deploymentLister interface {
+	ListAppHeadDeployments() map[string]*model.Deployment
+}
+
+type Sharder struct {
+	apiClient         apiClient
+	applicationLister applicationLister
+	deploymentLister  deploymentLister
+	config            *config.PipedSharding
+	replicaID         string
+	// Map from application ID to the replica it is assigned to explicitly.
+	assignments map[string]string
+	nowFunc     func() time.Time
+
+	mu sync.RWMutex
+	// The IDs of the live replicas sorted in the alphabetical order.
+	replicas []string
+	// The IDs of the applications handled by this replica.
+	handling map[string]struct{}
+	// The last time the heartbeat of this replica was reported.
+	reportedAt time.Time
+
+	logger *zap.Logger
+}
+
+// NewSharder creates a new Sharder for the given replica.
+// The given listers must return all applications and deployments of the piped.
+func NewSharder(
+	apiClient apiClient,
+	applicationLister applicationLister,
+	deploymentLister deploymentLister,
+	cfg *config.PipedSharding,
+	replicaID string,
+	logger *zap.Logger,
+) *Sharder {
+
+	assignments := make(map[string]string)
+	for _, a := range cfg.Assignments {
+		for _, id := range a.ApplicationIDs {
+			assignments[id] = a.ReplicaID
+		}
+	}
+	return &Sharder{
+		apiClient:         apiClient,
+		applicationLister: applicationLister,
+		deploymentLister:  deploymentLister,
+		config:            cfg,
+		replicaID:         replicaID,
+		assignments:       assignments,
+		nowFunc:           time.Now,
+		handling:          make(map[string]struct{}),
+		logger:            logger.Named("sharder").With(zap.String("replica-id", replicaID)),
+	}
+}
+
+// Run reports the heartbeats and rebalances the applications
+// until the specified context has done.
+func (s *Sharder) Run(ctx context.Context) error {
+	s.logger.Info("start running sharder")
+
+	ticker := time.NewTicker(s.config.GetHeartbeatInterval())
+	defer ticker.Stop()
+
+	// Do first heartbeat without waiting the first ticker.
+	s.heartbeat(ctx)
+
+L:
+	for {
+		select {
+		case <-ctx.Done():
+			break L
+
+		case <-ticker.C:
+			s.heartbeat(ctx)
+		}
+	}
+
+	s.logger.Info("sharder has been stopped")
+	return nil
+}
+
+// Owns returns whether the given application should be handled by this replica.
+func (s *Sharder) Owns(appID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.isAlive() {
+		return false
+	}
+	_, ok := s.handling[appID]
+	return ok
+}
+
+// IsLeader returns whether this replica should handle the tasks
+// which are not related to a specific application such as the event watcher.
+func (s *Sharder) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.isAlive() && len(s.replicas) > 0 && s.replicas[0] == s.replicaID
+}
+
+// isAlive returns whether the other replicas consider this replica as alive.
+// This must be called while holding the lock.
+func (s *Sharder) isAlive() bool {
+	return !s.reportedAt.IsZero() && s.nowFunc().Sub(s.reportedAt) <= s.config.GetHeartbeatTimeout()
+}
+
+func (s *Sharder) heartbeat(ctx context.Context) {
+	s.mu.RLock()
+	handling := make([]string, 0, len(s.handling))
+	for id := range s.handling {
+		handling = append(handling, id)
+	}
+	s.mu.RUnlock()
+	sort.Strings(handling)
+
+	req := &pipedservice.ReportReplicaHeartbeatRequest{
+		ReplicaId:      s.replicaID,
+		ApplicationIds: handling,
+	}
+	resp, err := s.apiClient.ReportReplicaHeartbeat(ctx, req)
+	if err != nil {
+		s.logger.Error("failed to report heartbeat", zap.Error(err))
+
+		// The other replicas take over the applications of this replica
+		// once its heartbeat is timed out, so stop handling them as well.
+		s.mu.Lock()
+		if !s.isAlive() && len(s.handling) > 0 {
+			s.logger.Warn(fmt.Sprintf("stopped handling %d applications since heartbeat was timed out", len(s.handling)))
+			s.handling = make(map[string]struct{})
+		}
+		s.mu.Unlock()
+		return
+	}
+	s.rebalance(resp.Replicas)
+}
+
+// rebalance decides the applications this replica should handle
+// based on the last heartbeats of all replicas.
+func (s *Sharder) rebalance(replicas []*pipedservice.PipedReplica) {
+	// Use the heartbeat of this replica as the current time
+	// to not be affected by the clock skew with the control plane.
+	var now int64
+	for _, r := range replicas {
+		if r.Id == s.replicaID {
+			now = r.HeartbeatAt
+		}
+	}
+
+	var (
+		timeout = int64(s.config.GetHeartbeatTimeout().Seconds())
+		live    = make([]string, 0, len(replicas))
+		// Map from application ID to the other live replica handling it.
+		handledByOthers = make(map[string]string)
+	)
+	for _, r := range replicas {
+		if now-r.HeartbeatAt > timeout {
+			continue
+		}
+		live = append(live, r.Id)
+		if r.Id == s.replicaID {
+			continue
+		}
+		for _, id := range r.ApplicationIds {
+			handledByOthers[id] = r.Id
+		}
+	}
+	sort.Strings(live)
+
+	var (
+		apps  = s.applicationLister.List()
+		heads = s.deploymentLister.ListAppHeadDeployments()
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	handling := make(map[string]struct{}, len(s.handling))
+	for _, app := range apps {
+		var (
+			owner        = s.owner(app.Id, live)
+			_, deploying = heads[app.Id]
+			_, handled   = s.handling[app.Id]
+			other, taken = handledByOthers[app.Id]
+		)
+		switch {
+		case handled && taken:
+			// Both replicas handle the application due to the different views of the live replicas.
+			// Only the owner, or the one having the smaller ID if neither is the owner, keeps it.
+			if owner == s.replicaID || (owner != other && s.replicaID < other) {
+				handling[app.Id] = struct{}{}
+			}
+		case handled:
+			// Keep handling until its in-flight deployment is completed.
+			if owner == s.replicaID || deploying {
+				handling[app.Id] = struct{}{}
+			}
+		case owner == s.replicaID && !taken:
+			handling[app.Id] = struct{}{}
+		}
+	}
+
+	if !equalReplicas(s.replicas, live) {
+		s.logger.Info(fmt.Sprintf("live replicas were changed to %v", live))
+	}
+	if len(handling) != len(s.handling) {
+		s.logger.Info(fmt.Sprintf("handling %d of %d applications", len(handling), len(apps)))
+	}
+	s.replicas = live
+	s.handling = handling
+	s.reportedAt = s.nowFunc()
+}
+
+// owner returns the replica the given application should be assigned to.
+// The explicitly assigned replica is used while it is alive,
+// otherwise the one chosen by rendezvous hashing to minimize the moved applications
+// when the live replicas are changed.
+func (s *Sharder) owner(appID string, live []string) string {
+	if r, ok := s.assignments[appID]; ok {
+		for _, id := range live {
+			if id == r {
+				return r
+			}
+		}
+	}
+
+	var (
+		owner string
+		max   uint64
+	)
+	for _, id := range live {
+		h := sha256.Sum256([]byte(id + "/" + appID))
+		if v := binary.BigEndian.Uint64(h[:8]); owner == "" || v > max {
+			owner, max = id, v
+		}
+	}
+	return owner
+}
+
+func equalReplicas(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
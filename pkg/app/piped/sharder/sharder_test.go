@@ -0,0 +1,227 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sharder
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeAPIClient struct {
+	replicas []*pipedservice.PipedReplica
+	err      error
+}
+
+func (c *fakeAPIClient) ReportReplicaHeartbeat(_ context.Context, _ *pipedservice.ReportReplicaHeartbeatRequest, _ ...grpc.CallOption) (*pipedservice.ReportReplicaHeartbeatResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &pipedservice.ReportReplicaHeartbeatResponse{Replicas: c.replicas}, nil
+}
+
+type fakeApplicationLister struct {
+	apps []*model.Application
+}
+
+func (l *fakeApplicationLister) List() []*model.Application {
+	return l.apps
+}
+
+type fakeDeploymentLister struct {
+	heads map[string]*model.Deployment
+}
+
+func (l *fakeDeploymentLister) ListAppHeadDeployments() map[string]*model.Deployment {
+	return l.heads
+}
+
+func newTestSharder(replicaID string, apps []string, deploying []string, cfg *config.PipedSharding) *Sharder {
+	al := &fakeApplicationLister{}
+	for _, id := range apps {
+		al.apps = append(al.apps, &model.Application{Id: id})
+	}
+	dl := &fakeDeploymentLister{heads: make(map[string]*model.Deployment)}
+	for _, id := range deploying {
+		dl.heads[id] = &model.Deployment{ApplicationId: id}
+	}
+	return NewSharder(&fakeAPIClient{}, al, dl, cfg, replicaID, zap.NewNop())
+}
+
+func ownedApps(s *Sharder, apps []string) []string {
+	var out []string
+	for _, id := range apps {
+		if s.Owns(id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func TestRebalance(t *testing.T) {
+	const now = 1600000000
+	apps := []string{"app-1", "app-2", "app-3"}
+	cfg := &config.PipedSharding{
+		Assignments: []config.PipedShardAssignment{
+			{ReplicaID: "replica-1", ApplicationIDs: []string{"app-1"}},
+			{ReplicaID: "replica-0", ApplicationIDs: []string{"app-2", "app-3"}},
+		},
+	}
+
+	testcases := []struct {
+		name      string
+		handling  []string
+		deploying []string
+		replicas  []*pipedservice.PipedReplica
+		want      []string
+	}{
+		{
+			name: "the only replica handles all applications",
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+			},
+			want: apps,
+		},
+		{
+			name: "explicitly assigned applications",
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", HeartbeatAt: now},
+			},
+			want: []string{"app-2", "app-3"},
+		},
+		{
+			name: "take over the applications of the dead replica",
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", ApplicationIds: []string{"app-1"}, HeartbeatAt: now - 120},
+			},
+			want: apps,
+		},
+		{
+			name: "wait for the other replica to release the application",
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", ApplicationIds: []string{"app-2"}, HeartbeatAt: now},
+			},
+			want: []string{"app-3"},
+		},
+		{
+			name:     "release the application not assigned anymore",
+			handling: []string{"app-1", "app-2"},
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", HeartbeatAt: now},
+			},
+			want: []string{"app-2", "app-3"},
+		},
+		{
+			name:      "keep handling the application until its deployment is completed",
+			handling:  []string{"app-1", "app-2"},
+			deploying: []string{"app-1"},
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", HeartbeatAt: now},
+			},
+			want: apps,
+		},
+		{
+			name:     "only the owner keeps the application handled by both",
+			handling: []string{"app-1", "app-2"},
+			replicas: []*pipedservice.PipedReplica{
+				{Id: "replica-0", HeartbeatAt: now},
+				{Id: "replica-1", ApplicationIds: []string{"app-1", "app-2"}, HeartbeatAt: now},
+			},
+			want: []string{"app-2", "app-3"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestSharder("replica-0", apps, tc.deploying, cfg)
+			for _, id := range tc.handling {
+				s.handling[id] = struct{}{}
+			}
+			s.rebalance(tc.replicas)
+			assert.Equal(t, tc.want, ownedApps(s, apps))
+		})
+	}
+}
+
+func TestRebalanceShardsApplications(t *testing.T) {
+	var (
+		apps     = make([]string, 0, 100)
+		replicas = []*pipedservice.PipedReplica{
+			{Id: "replica-0", HeartbeatAt: 1600000000},
+			{Id: "replica-1", HeartbeatAt: 1600000000},
+			{Id: "replica-2", HeartbeatAt: 1600000000},
+		}
+		owners = make(map[string]string)
+	)
+	for i := 0; i < 100; i++ {
+		apps = append(apps, fmt.Sprintf("app-%d", i))
+	}
+	for _, r := range replicas {
+		s := newTestSharder(r.Id, apps, nil, &config.PipedSharding{})
+		s.rebalance(replicas)
+		owned := ownedApps(s, apps)
+		assert.NotEmpty(t, owned)
+		for _, id := range owned {
+			_, ok := owners[id]
+			assert.False(t, ok, "application %s must be handled by only one replica", id)
+			owners[id] = r.Id
+		}
+	}
+	assert.Len(t, owners, len(apps))
+}
+
+func TestHeartbeat(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	client := &fakeAPIClient{
+		replicas: []*pipedservice.PipedReplica{
+			{Id: "replica-0", HeartbeatAt: now.Unix()},
+		},
+	}
+	s := newTestSharder("replica-0", []string{"app-1"}, nil, &config.PipedSharding{})
+	s.apiClient = client
+	s.nowFunc = func() time.Time { return now }
+
+	assert.False(t, s.Owns("app-1"))
+	assert.False(t, s.IsLeader())
+
+	s.heartbeat(context.Background())
+	assert.True(t, s.Owns("app-1"))
+	assert.True(t, s.IsLeader())
+
+	// Stop handling the applications once the heartbeat was timed out.
+	client.err = fmt.Errorf("unavailable")
+	now = now.Add(30 * time.Second)
+	s.heartbeat(context.Background())
+	assert.True(t, s.Owns("app-1"))
+
+	now = now.Add(time.Minute)
+	s.heartbeat(context.Background())
+	assert.False(t, s.Owns("app-1"))
+	assert.False(t, s.IsLeader())
+	assert.Empty(t, s.handling)
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+const awsSecretsManagerReadTimeout = 10 * time.Second
+
+// defaultAWSSecretsManagerClient uses the default credentials of piped
+// such as the environment variables, the shared credentials file or the IAM role.
+var defaultAWSSecretsManagerClient = &awsSecretsManagerClient{}
+
+type awsSecretsManagerClient struct {
+	mu  sync.Mutex
+	cfg *aws.Config
+}
+
+// Read returns the value of the secret pointed by the given reference
+// in the form of "<arn or name>" or "<arn or name>#<key>".
+// The key is used to pick a value from the secret stored as a JSON object.
+func (c *awsSecretsManagerClient) Read(ctx context.Context, ref string) (string, error) {
+	id, key := splitSecretKey(ref)
+	cfg, err := c.loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	// The secret in another region can be read by specifying its ARN.
+	region := cfg.Region
+	if r := regionFromARN(id); r != "" {
+		region = r
+	}
+	if region == "" {
+		return "", fmt.Errorf("unable to determine the region of secret %s, use its ARN instead", id)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, awsSecretsManagerReadTimeout)
+	defer cancel()
+
+	client := secretsmanager.NewFromConfig(cfg, func(o *secretsmanager.Options) {
+		o.Region = region
+	})
+	output, err := client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret value of %s from aws secrets manager (%w)", id, err)
+	}
+	value := string(output.SecretBinary)
+	if output.SecretString != nil {
+		value = *output.SecretString
+	}
+	return pickSecretKey(value, key)
+}
+
+func (c *awsSecretsManagerClient) loadConfig(ctx context.Context) (aws.Config, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cfg != nil {
+		return *c.cfg, nil
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load config to access aws secrets manager (%w)", err)
+	}
+	c.cfg = &cfg
+	return cfg, nil
+}
+
+// regionFromARN returns the region part of the given ARN
+// such as "arn:aws:secretsmanager:us-west-2:123456789012:secret:name-AbCdEf".
+// Empty is returned if the given string is not an ARN.
+func regionFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 5)
+	if len(parts) != 5 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[3]
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionFromARN(t *testing.T) {
+	assert.Equal(t, "us-west-2", regionFromARN("arn:aws:secretsmanager:us-west-2:123456789012:secret:db-AbCdEf"))
+	assert.Equal(t, "", regionFromARN("prod/db"))
+}
+
+func TestAWSSecretsManagerClientRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.NotEmpty(t, r.Header.Get("Authorization"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var input struct {
+			SecretID string `json:"SecretId"`
+		}
+		require.NoError(t, json.Unmarshal(body, &input))
+
+		switch input.SecretID {
+		case "prod/db":
+			w.Write([]byte(`{"Name":"prod/db","SecretString":"{\"password\":\"foo\"}"}`))
+		case "arn:aws:secretsmanager:us-west-2:123456789012:secret:cert-AbCdEf":
+			// "bar" encoded in base64.
+			w.Write([]byte(`{"Name":"cert","SecretBinary":"YmFy"}`))
+		default:
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "whole string secret",
+			ref:  "prod/db",
+			want: `{"password":"foo"}`,
+		},
+		{
+			name: "key of string secret",
+			ref:  "prod/db#password",
+			want: "foo",
+		},
+		{
+			name: "binary secret in another region",
+			ref:  "arn:aws:secretsmanager:us-west-2:123456789012:secret:cert-AbCdEf",
+			want: "bar",
+		},
+		{
+			name:    "missing secret",
+			ref:     "prod/cache",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &awsSecretsManagerClient{
+				cfg: &aws.Config{
+					Region:      "ap-northeast-1",
+					Credentials: credentials.NewStaticCredentialsProvider("key", "secret", ""),
+					EndpointResolver: aws.EndpointResolverFunc(func(_, _ string) (aws.Endpoint, error) {
+						return aws.Endpoint{URL: server.URL}, nil
+					}),
+					HTTPClient: server.Client(),
+					Retryer: func() aws.Retryer {
+						return aws.NopRetryer{}
+					},
+				},
+			}
+			got, err := c.Read(context.Background(), tc.ref)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -49,30 +49,38 @@ func DecryptSecrets(appDir string, enc config.SecretEncryption, dcr secretDecryp
 	}
 
 	for _, t := range enc.DecryptionTargets {
-		targetPath := filepath.Join(appDir, t)
-		tmpl, err := template.ParseFiles(targetPath)
-		if err != nil {
-			return fmt.Errorf("failed to parse decryption target %s (%w)", t, err)
+		if err := renderTemplateFile(appDir, t, data); err != nil {
+			return err
 		}
+	}
 
-		// Return an error immediately if the target is using a nonexistent secret.
-		tmpl = tmpl.Option("missingkey=error")
+	return nil
+}
 
-		f, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return fmt.Errorf("failed to open decryption target %s (%w)", t, err)
-		}
+// renderTemplateFile renders the given target file in place with the given data.
+func renderTemplateFile(appDir, target string, data interface{}) error {
+	targetPath := filepath.Join(appDir, target)
+	tmpl, err := template.ParseFiles(targetPath)
+	if err != nil {
+		return fmt.Errorf("failed to parse decryption target %s (%w)", target, err)
+	}
 
-		if err := tmpl.Execute(f, data); err != nil {
-			f.Close()
-			return fmt.Errorf("failed to render decryption target %s (%w)", t, err)
-		}
+	// Return an error immediately if the target is using a nonexistent secret.
+	tmpl = tmpl.Option("missingkey=error")
 
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("failed to close decryption target %s (%w)", t, err)
-		}
+	f, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open decryption target %s (%w)", target, err)
 	}
 
+	if err := tmpl.Execute(f, data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to render decryption target %s (%w)", target, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close decryption target %s (%w)", target, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,143 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// ResolveExternalSecrets replaces the references to the external secrets
+// in the target files with the fetched secrets in place.
+// The targets are rendered as Go templates with the secretResolver as .secrets,
+// e.g. {{ .secrets.Vault "secret/payment/db#password" }}.
+func ResolveExternalSecrets(ctx context.Context, appDir string, s config.ExternalSecrets) error {
+	if len(s.Targets) == 0 {
+		return nil
+	}
+	data := map[string]interface{}{
+		"secrets": newSecretResolver(ctx),
+	}
+	for _, t := range s.Targets {
+		if err := renderTemplateFile(appDir, t, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type secretReader interface {
+	Read(ctx context.Context, ref string) (string, error)
+}
+
+// secretResolver fetches the secrets referenced from the templates.
+// The same secret referenced many times is fetched only once.
+type secretResolver struct {
+	ctx   context.Context
+	vault secretReader
+	aws   secretReader
+	gcp   secretReader
+	cache map[string]string
+}
+
+func newSecretResolver(ctx context.Context) *secretResolver {
+	r := &secretResolver{
+		ctx:   ctx,
+		aws:   defaultAWSSecretsManagerClient,
+		gcp:   defaultGCPSecretManagerClient,
+		cache: make(map[string]string),
+	}
+	// Avoid setting the typed nil pointer to the interface.
+	if defaultVaultClient != nil {
+		r.vault = defaultVaultClient
+	}
+	return r
+}
+
+// Vault returns the value of the Vault secret pointed by the given reference
+// in the form of "<mount>/<path>#<key>".
+func (r *secretResolver) Vault(ref string) (string, error) {
+	if r.vault == nil {
+		return "", fmt.Errorf("vault is not configured in piped")
+	}
+	return r.resolve("vault:"+ref, r.vault, ref)
+}
+
+// AWSSecretsManager returns the value of the AWS Secrets Manager secret
+// pointed by the given ARN or name, optionally followed by "#<key>".
+func (r *secretResolver) AWSSecretsManager(ref string) (string, error) {
+	return r.resolve("aws:"+ref, r.aws, ref)
+}
+
+// GCPSecretManager returns the value of the GCP Secret Manager secret
+// pointed by the given resource name, optionally followed by "#<key>".
+func (r *secretResolver) GCPSecretManager(ref string) (string, error) {
+	return r.resolve("gcp:"+ref, r.gcp, ref)
+}
+
+func (r *secretResolver) resolve(cacheKey string, reader secretReader, ref string) (string, error) {
+	if v, ok := r.cache[cacheKey]; ok {
+		return v, nil
+	}
+	v, err := reader.Read(r.ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	r.cache[cacheKey] = v
+	return v, nil
+}
+
+// splitSecretKey splits the given reference in the form of "<secret>#<key>".
+// Empty key is returned if the reference has no key.
+func splitSecretKey(ref string) (secret, key string) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// pickSecretKey returns the value of the given key of the secret stored as a JSON object.
+// The secret is returned as is if the key is empty.
+func pickSecretKey(secret, key string) (string, error) {
+	if key == "" {
+		return secret, nil
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("unable to pick key %s from the secret which is not a JSON object", key)
+	}
+	v, ok := values[key]
+	if !ok {
+		return "", fmt.Errorf("key %s was not found in the secret", key)
+	}
+	return secretValueString(v)
+}
+
+// secretValueString returns the given value as is if it is a string, or in JSON otherwise.
+func secretValueString(v interface{}) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
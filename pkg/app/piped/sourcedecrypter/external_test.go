@@ -0,0 +1,158 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSecretReader struct {
+	secrets map[string]string
+	reads   int
+}
+
+func (r *fakeSecretReader) Read(_ context.Context, ref string) (string, error) {
+	r.reads++
+	v, ok := r.secrets[ref]
+	if !ok {
+		return "", fmt.Errorf("secret %s was not found", ref)
+	}
+	return v, nil
+}
+
+func TestSecretResolver(t *testing.T) {
+	testcases := []struct {
+		name      string
+		source    string
+		want      string
+		wantReads int
+		wantErr   bool
+	}{
+		{
+			name:   "no reference",
+			source: "password: foo",
+			want:   "password: foo",
+		},
+		{
+			name:      "same secret is fetched once",
+			source:    `password: {{ .secrets.Vault "secret/db#password" }}, again: {{ .secrets.Vault "secret/db#password" }}`,
+			want:      "password: foo, again: foo",
+			wantReads: 1,
+		},
+		{
+			name:      "secrets in cloud secret managers",
+			source:    `aws: {{ .secrets.AWSSecretsManager "arn:aws:secretsmanager:us-west-2:123456789012:secret:db-AbCdEf" }}, gcp: {{ .secrets.GCPSecretManager "projects/p/secrets/db" }}`,
+			want:      "aws: bar, gcp: baz",
+			wantReads: 2,
+		},
+		{
+			name:      "missing secret",
+			source:    `password: {{ .secrets.Vault "secret/db#missing" }}`,
+			wantReads: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			appDir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(appDir, "secret.yaml"), []byte(tc.source), 0644))
+
+			reader := &fakeSecretReader{secrets: map[string]string{
+				"secret/db#password": "foo",
+				"arn:aws:secretsmanager:us-west-2:123456789012:secret:db-AbCdEf": "bar",
+				"projects/p/secrets/db": "baz",
+			}}
+			data := map[string]interface{}{
+				"secrets": &secretResolver{
+					ctx:   context.Background(),
+					vault: reader,
+					aws:   reader,
+					gcp:   reader,
+					cache: make(map[string]string),
+				},
+			}
+			err := renderTemplateFile(appDir, "secret.yaml", data)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantReads, reader.reads)
+			if err != nil {
+				return
+			}
+
+			got, err := os.ReadFile(filepath.Join(appDir, "secret.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestSecretResolverWithoutVault(t *testing.T) {
+	r := newSecretResolver(context.Background())
+	_, err := r.Vault("secret/db#password")
+	assert.Error(t, err)
+}
+
+func TestPickSecretKey(t *testing.T) {
+	testcases := []struct {
+		name    string
+		secret  string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "no key",
+			secret: `{"password":"foo"}`,
+			want:   `{"password":"foo"}`,
+		},
+		{
+			name:   "string value",
+			secret: `{"password":"foo","port":5432}`,
+			key:    "password",
+			want:   "foo",
+		},
+		{
+			name:   "non-string value",
+			secret: `{"password":"foo","port":5432}`,
+			key:    "port",
+			want:   "5432",
+		},
+		{
+			name:    "missing key",
+			secret:  `{"password":"foo"}`,
+			key:     "user",
+			wantErr: true,
+		},
+		{
+			name:    "not a JSON object",
+			secret:  "foo",
+			key:     "password",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := pickSecretKey(tc.secret, tc.key)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
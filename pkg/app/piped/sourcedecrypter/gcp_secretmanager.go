@@ -0,0 +1,92 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	secretmanagerpb "google.golang.org/genproto/googleapis/cloud/secretmanager/v1"
+)
+
+// defaultGCPSecretManagerClient uses the application default credentials of piped.
+var defaultGCPSecretManagerClient = &gcpSecretManagerClient{}
+
+// gcpSecretManagerClient reads the secrets from GCP Secret Manager.
+// The underlying client is created at the first read.
+type gcpSecretManagerClient struct {
+	mu     sync.Mutex
+	client *secretmanager.Client
+}
+
+// Read returns the value of the secret pointed by the given reference in the form of
+// "projects/<project>/secrets/<secret>[/versions/<version>]" optionally followed by "#<key>".
+// The latest version is used if no version is specified.
+// The key is used to pick a value from the secret stored as a JSON object.
+func (c *gcpSecretManagerClient) Read(ctx context.Context, ref string) (string, error) {
+	name, key := splitSecretKey(ref)
+	name, err := gcpSecretVersionName(name)
+	if err != nil {
+		return "", err
+	}
+	client, err := c.getClient()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to access %s of gcp secret manager (%w)", name, err)
+	}
+	return pickSecretKey(string(resp.Payload.Data), key)
+}
+
+func (c *gcpSecretManagerClient) getClient() (*secretmanager.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	// The client is shared by all deployments so it must not be bound to any of their contexts.
+	client, err := secretmanager.NewClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcp secret manager client (%w)", err)
+	}
+	c.client = client
+	return client, nil
+}
+
+// gcpSecretVersionName returns the resource name of the secret version pointed by the given name.
+func gcpSecretVersionName(name string) (string, error) {
+	invalid := fmt.Errorf("gcp secret %q must be in the form of projects/<project>/secrets/<secret>[/versions/<version>]", name)
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for _, p := range parts {
+		if p == "" {
+			return "", invalid
+		}
+	}
+	switch {
+	case len(parts) == 4 && parts[0] == "projects" && parts[2] == "secrets":
+		return strings.Join(append(parts, "versions", "latest"), "/"), nil
+	case len(parts) == 6 && parts[0] == "projects" && parts[2] == "secrets" && parts[4] == "versions":
+		return strings.Join(parts, "/"), nil
+	default:
+		return "", invalid
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGCPSecretVersionName(t *testing.T) {
+	testcases := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "projects/my-project/secrets/db",
+			want: "projects/my-project/secrets/db/versions/latest",
+		},
+		{
+			name: "projects/my-project/secrets/db/versions/3",
+			want: "projects/my-project/secrets/db/versions/3",
+		},
+		{
+			name:    "projects/my-project/secrets/",
+			wantErr: true,
+		},
+		{
+			name:    "db",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := gcpSecretVersionName(tc.name)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pipe-cd/pipe/pkg/app/piped/toolregistry"
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// The environment variables sops reads the age keys from.
+const (
+	sopsAgeKeyEnv     = "SOPS_AGE_KEY"
+	sopsAgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+)
+
+// sopsEnv contains the environment variables added to the sops processes.
+var sopsEnv []string
+
+// ConfigureSops makes the key material configured in piped available to sops.
+// The age keys are kept in memory and passed to the sops processes
+// through their environment variables instead of the ones of piped.
+// This must be called before decrypting any file.
+func ConfigureSops(cfg config.PipedSops) error {
+	if cfg.AgeKeyFile != "" {
+		sopsEnv = []string{sopsAgeKeyFileEnv + "=" + cfg.AgeKeyFile}
+		return nil
+	}
+
+	key, err := cfg.LoadAgeKey()
+	if err != nil {
+		return fmt.Errorf("failed to load age key for sops (%w)", err)
+	}
+	if key == nil {
+		return nil
+	}
+	sopsEnv = []string{sopsAgeKeyEnv + "=" + strings.TrimSpace(string(key))}
+	return nil
+}
+
+// DecryptSopsFiles decrypts the files encrypted by sops in place
+// by using the sops binary of the specified version.
+func DecryptSopsFiles(ctx context.Context, appDir string, d config.SopsDecryption) error {
+	if len(d.DecryptionTargets) == 0 {
+		return nil
+	}
+	sopsPath, _, err := toolregistry.DefaultRegistry().Sops(ctx, d.Version)
+	if err != nil {
+		return fmt.Errorf("no sops %s (%w)", d.Version, err)
+	}
+	return decryptSopsFiles(ctx, sopsPath, appDir, d.DecryptionTargets, sopsEnv)
+}
+
+func decryptSopsFiles(ctx context.Context, sopsPath, appDir string, targets, env []string) error {
+	for _, t := range targets {
+		var (
+			stderr     bytes.Buffer
+			targetPath = filepath.Join(appDir, t)
+			cmd        = exec.CommandContext(ctx, sopsPath, "--decrypt", "--in-place", targetPath)
+		)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to decrypt %s by sops: %s (%w)", t, strings.TrimSpace(stderr.String()), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+// fakeSops mimics "sops --decrypt --in-place <file>" by removing the ENC[] wrapper.
+// It fails if the age key is not given through the environment variable.
+const fakeSops = `#!/bin/sh
+set -e
+test "$SOPS_AGE_KEY" = "AGE-SECRET-KEY-1FOO" || { echo "no age key" >&2; exit 1; }
+test -f "$3" || { echo "file not found: $3" >&2; exit 1; }
+sed 's/ENC\[\(.*\)\]/\1/' "$3" > "$3.tmp"
+mv "$3.tmp" "$3"
+`
+
+func TestDecryptSopsFiles(t *testing.T) {
+	workspace := t.TempDir()
+
+	sopsPath := filepath.Join(workspace, "sops")
+	require.NoError(t, os.WriteFile(sopsPath, []byte(fakeSops), 0755))
+
+	appDir := filepath.Join(workspace, "app")
+	require.NoError(t, os.MkdirAll(filepath.Join(appDir, "secrets"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, "secrets", "db.yaml"), []byte("password: ENC[foo]\n"), 0644))
+
+	env := []string{"SOPS_AGE_KEY=AGE-SECRET-KEY-1FOO"}
+	err := decryptSopsFiles(context.Background(), sopsPath, appDir, []string{"secrets/db.yaml"}, env)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(appDir, "secrets", "db.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "password: foo\n", string(data))
+
+	err = decryptSopsFiles(context.Background(), sopsPath, appDir, []string{"missing.yaml"}, env)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+
+	err = decryptSopsFiles(context.Background(), sopsPath, appDir, []string{"secrets/db.yaml"}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no age key")
+}
+
+func TestConfigureSops(t *testing.T) {
+	defer func() { sopsEnv = nil }()
+
+	testcases := []struct {
+		name     string
+		cfg      config.PipedSops
+		expected []string
+	}{
+		{
+			name: "nothing configured",
+		},
+		{
+			name: "key file",
+			cfg: config.PipedSops{
+				AgeKeyFile: "/etc/piped-secret/sops-age-keys.txt",
+			},
+			expected: []string{"SOPS_AGE_KEY_FILE=/etc/piped-secret/sops-age-keys.txt"},
+		},
+		{
+			name: "key data",
+			cfg: config.PipedSops{
+				// "AGE-SECRET-KEY-1FOO\n" encoded in base64.
+				AgeKeyData: "QUdFLVNFQ1JFVC1LRVktMUZPTwo=",
+			},
+			expected: []string{"SOPS_AGE_KEY=AGE-SECRET-KEY-1FOO"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sopsEnv = nil
+			err := ConfigureSops(tc.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, sopsEnv)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+const (
+	vaultTokenHeader     = "X-Vault-Token"
+	vaultNamespaceHeader = "X-Vault-Namespace"
+)
+
+// defaultVaultClient is the client configured by ConfigureVault.
+var defaultVaultClient *vaultClient
+
+// ConfigureVault makes the secrets in the given Vault server
+// available to be referenced from the external secrets targets.
+func ConfigureVault(cfg config.PipedVault) error {
+	c, err := newVaultClient(cfg)
+	if err != nil {
+		return err
+	}
+	defaultVaultClient = c
+	return nil
+}
+
+// vaultClient reads the KV version 2 secrets through the HTTP API of Vault
+// since there is no Vault API client in the dependencies.
+type vaultClient struct {
+	config     config.PipedVault
+	address    string
+	httpClient *http.Client
+	nowFunc    func() time.Time
+
+	// The token got by logging in with the kubernetes auth method.
+	mu             sync.Mutex
+	token          string
+	tokenExpiresAt time.Time
+}
+
+func newVaultClient(cfg config.PipedVault) (*vaultClient, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CACertFile != "" {
+		cert, err := ioutil.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate file of vault (%w)", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(cert) {
+			return nil, fmt.Errorf("no valid CA certificate was found in %s", cfg.CACertFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &vaultClient{
+		config:  cfg,
+		address: strings.TrimRight(cfg.Address, "/"),
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   10 * time.Second,
+		},
+		nowFunc: time.Now,
+	}, nil
+}
+
+// Read returns the value of the key of the KV version 2 secret pointed by the given reference.
+// The reference is in the form of "<mount>/<path>#<key>", e.g. "secret/payment/db#password".
+func (c *vaultClient) Read(ctx context.Context, ref string) (string, error) {
+	mount, path, key, err := parseVaultSecretRef(ref)
+	if err != nil {
+		return "", err
+	}
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate with vault (%w)", err)
+	}
+
+	var out struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/v1/%s/data/%s", mount, path), token, nil, &out); err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s/%s (%w)", mount, path, err)
+	}
+	v, ok := out.Data.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s was not found in vault secret %s/%s", key, mount, path)
+	}
+	return secretValueString(v)
+}
+
+func (c *vaultClient) getToken(ctx context.Context) (string, error) {
+	if c.config.AuthMethod != config.VaultAuthMethodKubernetes {
+		// The token file is read every time to use the renewed token.
+		data, err := ioutil.ReadFile(c.config.TokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && c.nowFunc().Before(c.tokenExpiresAt) {
+		return c.token, nil
+	}
+
+	jwt, err := ioutil.ReadFile(c.config.GetKubernetesTokenFile())
+	if err != nil {
+		return "", fmt.Errorf("unable to read service account token (%w)", err)
+	}
+	in := map[string]string{
+		"role": c.config.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	var out struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	path := fmt.Sprintf("/v1/auth/%s/login", strings.Trim(c.config.GetKubernetesMountPath(), "/"))
+	if err := c.call(ctx, http.MethodPost, path, "", in, &out); err != nil {
+		return "", err
+	}
+
+	// Login again a bit before the token expires.
+	ttl := time.Duration(out.Auth.LeaseDuration) * time.Second
+	c.token = out.Auth.ClientToken
+	c.tokenExpiresAt = c.nowFunc().Add(ttl * 4 / 5)
+	return c.token, nil
+}
+
+func (c *vaultClient) call(ctx context.Context, method, path, token string, input, output interface{}) error {
+	var body bytes.Buffer
+	if input != nil {
+		if err := json.NewEncoder(&body).Encode(input); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+path, &body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set(vaultTokenHeader, token)
+	}
+	if c.config.Namespace != "" {
+		req.Header.Set(vaultNamespaceHeader, c.config.Namespace)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &e)
+		return fmt.Errorf("%s from vault: %s", resp.Status, strings.Join(e.Errors, ", "))
+	}
+	return json.Unmarshal(data, output)
+}
+
+// parseVaultSecretRef splits the given reference in the form of "<mount>/<path>#<key>".
+func parseVaultSecretRef(ref string) (mount, path, key string, err error) {
+	parts := strings.SplitN(ref, "#", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", "", "", fmt.Errorf("vault secret reference %q must be in the form of <mount>/<path>#<key>", ref)
+	}
+	key = parts[1]
+	paths := strings.SplitN(strings.Trim(parts[0], "/"), "/", 2)
+	if len(paths) != 2 || paths[0] == "" || paths[1] == "" {
+		return "", "", "", fmt.Errorf("vault secret reference %q must be in the form of <mount>/<path>#<key>", ref)
+	}
+	return paths[0], paths[1], key, nil
+}
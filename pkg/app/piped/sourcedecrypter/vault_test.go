@@ -0,0 +1,211 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sourcedecrypter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func TestParseVaultSecretRef(t *testing.T) {
+	testcases := []struct {
+		ref       string
+		wantMount string
+		wantPath  string
+		wantKey   string
+		wantErr   bool
+	}{
+		{
+			ref:       "secret/payment/db#password",
+			wantMount: "secret",
+			wantPath:  "payment/db",
+			wantKey:   "password",
+		},
+		{
+			ref:       "/kv/db/#user",
+			wantMount: "kv",
+			wantPath:  "db",
+			wantKey:   "user",
+		},
+		{
+			ref:     "secret/payment/db",
+			wantErr: true,
+		},
+		{
+			ref:     "secret/payment/db#",
+			wantErr: true,
+		},
+		{
+			ref:     "secret#password",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.ref, func(t *testing.T) {
+			mount, path, key, err := parseVaultSecretRef(tc.ref)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantMount, mount)
+			assert.Equal(t, tc.wantPath, path)
+			assert.Equal(t, tc.wantKey, key)
+		})
+	}
+}
+
+func newFakeVaultServer(t *testing.T, logins *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/kubernetes/login", func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		if in["role"] != "piped" || in["jwt"] != "service-account-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		*logins++
+		w.Write([]byte(`{"auth":{"client_token":"login-token","lease_duration":3600}}`))
+	})
+	mux.HandleFunc("/v1/secret/data/payment/db", func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(vaultTokenHeader)
+		if token != "static-token" && token != "login-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		assert.Equal(t, "team-a", r.Header.Get(vaultNamespaceHeader))
+		w.Write([]byte(`{"data":{"data":{"password":"foo","port":5432},"metadata":{"version":3}}}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestVaultClientRead(t *testing.T) {
+	var logins int
+	server := newFakeVaultServer(t, &logins)
+	defer server.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("static-token\n"), 0600))
+	jwtFile := filepath.Join(dir, "jwt")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("service-account-token"), 0600))
+
+	testcases := []struct {
+		name    string
+		cfg     config.PipedVault
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "string value with token auth",
+			cfg: config.PipedVault{
+				TokenFile: tokenFile,
+			},
+			ref:  "secret/payment/db#password",
+			want: "foo",
+		},
+		{
+			name: "non-string value with kubernetes auth",
+			cfg: config.PipedVault{
+				AuthMethod:          config.VaultAuthMethodKubernetes,
+				KubernetesRole:      "piped",
+				KubernetesTokenFile: jwtFile,
+			},
+			ref:  "secret/payment/db#port",
+			want: "5432",
+		},
+		{
+			name: "kubernetes auth with unknown role",
+			cfg: config.PipedVault{
+				AuthMethod:          config.VaultAuthMethodKubernetes,
+				KubernetesRole:      "unknown",
+				KubernetesTokenFile: jwtFile,
+			},
+			ref:     "secret/payment/db#password",
+			wantErr: true,
+		},
+		{
+			name: "missing key",
+			cfg: config.PipedVault{
+				TokenFile: tokenFile,
+			},
+			ref:     "secret/payment/db#user",
+			wantErr: true,
+		},
+		{
+			name: "missing secret",
+			cfg: config.PipedVault{
+				TokenFile: tokenFile,
+			},
+			ref:     "secret/payment/cache#password",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Address = server.URL
+			tc.cfg.Namespace = "team-a"
+			c, err := newVaultClient(tc.cfg)
+			require.NoError(t, err)
+
+			got, err := c.Read(context.Background(), tc.ref)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestVaultClientReusesLoginToken(t *testing.T) {
+	var logins int
+	server := newFakeVaultServer(t, &logins)
+	defer server.Close()
+
+	jwtFile := filepath.Join(t.TempDir(), "jwt")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("service-account-token"), 0600))
+
+	c, err := newVaultClient(config.PipedVault{
+		Address:             server.URL,
+		Namespace:           "team-a",
+		AuthMethod:          config.VaultAuthMethodKubernetes,
+		KubernetesRole:      "piped",
+		KubernetesTokenFile: jwtFile,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	c.nowFunc = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Read(context.Background(), "secret/payment/db#password")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, logins)
+
+	// Login again when the token is about to expire.
+	now = now.Add(50 * time.Minute)
+	_, err = c.Read(context.Background(), "secret/payment/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, 2, logins)
+}
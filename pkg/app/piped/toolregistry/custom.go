@@ -0,0 +1,96 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"text/template"
+
+	"go.uber.org/zap"
+)
+
+func (r *registry) Custom(ctx context.Context, tool, version, downloadURL string) (string, bool, error) {
+	name := toolName(tool, version)
+	path := filepath.Join(r.binDir, name)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		r.markUsed(name)
+		return path, false, nil
+	}
+	// The mirror takes precedence to be usable in the air-gapped environments.
+	if mirror, ok := r.mirrors[tool]; ok {
+		downloadURL = mirror
+	}
+	installed, err := r.installOnce(name, func() error {
+		if downloadURL == "" {
+			return fmt.Errorf("%s was not found in %s and no download URL was given", name, r.binDir)
+		}
+		return r.installCustom(ctx, tool, version, downloadURL)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.markUsed(name)
+	return path, installed, nil
+}
+
+// installCustom downloads the tool from the given URL template into the bin directory.
+// The file is downloaded into a temporary one first to not leave the broken tool.
+func (r *registry) installCustom(ctx context.Context, tool, version, downloadURL string) error {
+	name := toolName(tool, version)
+	url, err := renderDownloadURL(downloadURL, version)
+	if err != nil {
+		return fmt.Errorf("failed to install %s (%v)", name, err)
+	}
+
+	if err := r.download(ctx, url, filepath.Join(r.binDir, name), r.checksums[name]); err != nil {
+		r.logger.Error("failed to install custom tool",
+			zap.String("name", name),
+			zap.String("url", url),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install %s (%v)", name, err)
+	}
+
+	r.logger.Info("just installed custom tool", zap.String("name", name), zap.String("url", url))
+	return nil
+}
+
+func renderDownloadURL(tmpl, version string) (string, error) {
+	t, err := template.New("url").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid download URL: %w", err)
+	}
+	var (
+		buf  bytes.Buffer
+		data = map[string]string{
+			"Version": version,
+			"Os":      runtime.GOOS,
+			"Arch":    runtime.GOARCH,
+		}
+	)
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid download URL: %w", err)
+	}
+	return buf.String(), nil
+}
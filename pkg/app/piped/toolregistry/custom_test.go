@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+func TestRenderDownloadURL(t *testing.T) {
+	got, err := renderDownloadURL("https://example.com/{{ .Version }}/deployer_{{ .Os }}_{{ .Arch }}", "1.2.0")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1.2.0/deployer_"+runtime.GOOS+"_"+runtime.GOARCH, got)
+
+	_, err = renderDownloadURL("https://example.com/{{ .Unknown }}", "1.2.0")
+	assert.Error(t, err)
+}
+
+func TestCustom(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/1.2.0/deployer" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("#!/bin/sh\n"))
+	}))
+	defer server.Close()
+
+	binDir := t.TempDir()
+	r := &registry{
+		binDir: binDir,
+		versions: map[string]time.Time{
+			"preinstalled": time.Now(),
+		},
+		installGroup: &singleflight.Group{},
+		mirrors:      map[string]string{},
+		checksums:    map[string]string{},
+		definitions:  map[string]toolDefinition{},
+		httpClient:   http.DefaultClient,
+		logger:       zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	// The pre-installed one is used without downloading.
+	path, installed, err := r.Custom(ctx, "preinstalled", "", "")
+	require.NoError(t, err)
+	assert.False(t, installed)
+	assert.Equal(t, filepath.Join(binDir, "preinstalled"), path)
+
+	// Not installed and no URL to download from.
+	_, _, err = r.Custom(ctx, "deployer", "1.2.0", "")
+	assert.Error(t, err)
+
+	path, installed, err = r.Custom(ctx, "deployer", "1.2.0", server.URL+"/{{ .Version }}/deployer")
+	require.NoError(t, err)
+	assert.True(t, installed)
+	assert.Equal(t, filepath.Join(binDir, "deployer-1.2.0"), path)
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\n", string(data))
+
+	// The downloaded one is reused.
+	_, installed, err = r.Custom(ctx, "deployer", "1.2.0", server.URL+"/{{ .Version }}/deployer")
+	require.NoError(t, err)
+	assert.False(t, installed)
+	assert.Equal(t, 1, requests)
+
+	// The failed download does not leave any file.
+	_, _, err = r.Custom(ctx, "deployer", "2.0.0", server.URL+"/{{ .Version }}/deployer")
+	assert.Error(t, err)
+	tools, err := loadPreinstalledTool(binDir)
+	require.NoError(t, err)
+	assert.Len(t, tools, 1)
+}
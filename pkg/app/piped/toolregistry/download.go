@@ -0,0 +1,206 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Option configures how the registry downloads the tools.
+type Option func(*registry)
+
+// WithMirror makes the registry download the given tool from the given URL template
+// instead of the default location. The template can refer to
+// the version, the OS and the architecture as {{ .Version }}, {{ .Os }} and {{ .Arch }}.
+func WithMirror(tool, urlTemplate string) Option {
+	return func(r *registry) {
+		r.mirrors[tool] = urlTemplate
+	}
+}
+
+// WithChecksum makes the registry verify the SHA256 checksum of
+// the file downloaded for the given version of the tool.
+func WithChecksum(tool, version, sha256 string) Option {
+	return func(r *registry) {
+		r.checksums[toolName(tool, version)] = sha256
+	}
+}
+
+// WithTool declares the tool of the given name to be resolved by Tool.
+// It is downloaded from the given URL template in the same way as WithMirror.
+func WithTool(name, version, urlTemplate string) Option {
+	return func(r *registry) {
+		r.definitions[name] = toolDefinition{
+			version:     version,
+			urlTemplate: urlTemplate,
+		}
+	}
+}
+
+type toolDefinition struct {
+	version     string
+	urlTemplate string
+}
+
+// WithHTTPProxy makes the registry download the tools through the given proxy.
+func WithHTTPProxy(proxy *url.URL) Option {
+	return func(r *registry) {
+		r.httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyURL(proxy),
+			},
+		}
+	}
+}
+
+// Tool represents a version of a tool.
+type Tool struct {
+	Name string
+	// Empty means the default version of the tool.
+	Version string
+}
+
+// Preinstall installs the given tools into the default registry in advance
+// to not make the first deployments using them wait for downloading.
+// The failures are just logged since the tools can be installed again on demand.
+func Preinstall(ctx context.Context, tools []Tool) error {
+	if defaultRegistry == nil {
+		return fmt.Errorf("the default tool registry was not initialized")
+	}
+	for _, t := range tools {
+		if _, _, err := defaultRegistry.install(ctx, t.Name, t.Version); err != nil {
+			defaultRegistry.logger.Error("failed to pre-install tool",
+				zap.String("tool", t.Name),
+				zap.String("version", t.Version),
+				zap.Error(err),
+			)
+			continue
+		}
+		defaultRegistry.logger.Info("pre-installed tool", zap.String("tool", t.Name), zap.String("version", t.Version))
+	}
+	return nil
+}
+
+func (r *registry) Tool(ctx context.Context, name string) (string, bool, error) {
+	if d, ok := r.definitions[name]; ok {
+		return r.Custom(ctx, name, d.version, d.urlTemplate)
+	}
+	return r.install(ctx, name, "")
+}
+
+func (r *registry) install(ctx context.Context, name, version string) (string, bool, error) {
+	switch name {
+	case kubectlPrefix:
+		return r.Kubectl(ctx, version)
+	case kustomizePrefix:
+		return r.Kustomize(ctx, version)
+	case helmPrefix:
+		return r.Helm(ctx, version)
+	case terraformPrefix:
+		return r.Terraform(ctx, version)
+	case conftestPrefix:
+		return r.Conftest(ctx, version)
+	case cosignPrefix:
+		return r.Cosign(ctx, version)
+	case sopsPrefix:
+		return r.Sops(ctx, version)
+	}
+	if d, ok := r.definitions[name]; ok && (version == "" || version == d.version) {
+		return r.Custom(ctx, name, d.version, d.urlTemplate)
+	}
+	// Other tools can be installed only from their mirrors.
+	return r.Custom(ctx, name, version, r.mirrors[name])
+}
+
+// toolName returns the name of the file of the given tool version.
+func toolName(tool, version string) string {
+	if version == "" {
+		return tool
+	}
+	return fmt.Sprintf("%s-%s", tool, version)
+}
+
+// downloadTool downloads the release file of the given tool version into the working directory
+// from its mirror if configured, otherwise from the default location.
+func (r *registry) downloadTool(ctx context.Context, tool, version, workingDir string) (string, error) {
+	tmpl, ok := r.mirrors[tool]
+	if !ok {
+		tmpl = defaultDownloadURLs[tool]
+	}
+	url, err := renderDownloadURL(tmpl, version)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(workingDir, "download")
+	if err := r.download(ctx, url, path, r.checksums[toolName(tool, version)]); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return path, nil
+}
+
+// download writes the file of the given URL into the given path.
+// The file is downloaded into a temporary one first to not leave the broken file,
+// and its SHA256 checksum is verified when the expected one is given.
+func (r *registry) download(ctx context.Context, url, path, checksum string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	// The temporary file is hidden to not be loaded as a tool even if piped stopped while downloading.
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if checksum != "" {
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, checksum) {
+			return fmt.Errorf("checksum mismatch: expected %s but got %s", checksum, got)
+		}
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
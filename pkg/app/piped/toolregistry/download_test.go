@@ -0,0 +1,176 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+func TestDownload(t *testing.T) {
+	const content = "#!/bin/sh\n"
+	sum := sha256.Sum256([]byte(content))
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name     string
+		checksum string
+		wantErr  bool
+	}{
+		{
+			name: "no checksum",
+		},
+		{
+			name:     "matching checksum",
+			checksum: checksum,
+		},
+		{
+			name:     "checksum is case insensitive",
+			checksum: strings.ToUpper(checksum),
+		},
+		{
+			name:     "mismatching checksum",
+			checksum: "0000000000000000000000000000000000000000000000000000000000000000",
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &registry{httpClient: http.DefaultClient}
+			path := filepath.Join(t.TempDir(), "tool")
+			err := r.download(context.Background(), server.URL, path, tc.checksum)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				// The broken file must not be left.
+				assert.NoFileExists(t, path)
+				return
+			}
+			data, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, content, string(data))
+		})
+	}
+}
+
+func TestInstallFromMirror(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte("#!/bin/sh\n"))
+	}))
+	defer server.Close()
+
+	binDir := t.TempDir()
+	r := &registry{
+		binDir:       binDir,
+		versions:     map[string]time.Time{},
+		installGroup: &singleflight.Group{},
+		checksums:    map[string]string{},
+		mirrors:      map[string]string{},
+		definitions:  map[string]toolDefinition{},
+		httpClient:   http.DefaultClient,
+		logger:       zap.NewNop(),
+	}
+	WithMirror("deployer", server.URL+"/mirror/{{ .Version }}/deployer")(r)
+	ctx := context.Background()
+
+	// The mirror takes precedence over the given download URL.
+	path, installed, err := r.Custom(ctx, "deployer", "1.2.0", "https://example.com/{{ .Version }}/deployer")
+	require.NoError(t, err)
+	assert.True(t, installed)
+	assert.Equal(t, filepath.Join(binDir, "deployer-1.2.0"), path)
+
+	// The tools not having the built-in installer are installed from the mirror.
+	_, _, err = r.install(ctx, "deployer", "1.3.0")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(binDir, "deployer-1.3.0"))
+	assert.Equal(t, []string{"/mirror/1.2.0/deployer", "/mirror/1.3.0/deployer"}, paths)
+
+	// No location to download from.
+	_, _, err = r.install(ctx, "unknown", "1.0.0")
+	assert.Error(t, err)
+}
+
+func TestToolName(t *testing.T) {
+	assert.Equal(t, "kubectl", toolName("kubectl", ""))
+	assert.Equal(t, "kubectl-1.18.2", toolName("kubectl", "1.18.2"))
+}
+
+func TestTool(t *testing.T) {
+	const content = "#!/bin/sh\n"
+	sum := sha256.Sum256([]byte(content))
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	binDir := t.TempDir()
+	r := &registry{
+		binDir:       binDir,
+		versions:     map[string]time.Time{},
+		installGroup: &singleflight.Group{},
+		checksums:    map[string]string{},
+		mirrors:      map[string]string{},
+		definitions:  map[string]toolDefinition{},
+		httpClient:   http.DefaultClient,
+		logger:       zap.NewNop(),
+	}
+	WithTool("yq", "4.9.6", server.URL+"/yq/v{{ .Version }}/yq")(r)
+	WithChecksum("yq", "4.9.6", hex.EncodeToString(sum[:]))(r)
+	WithTool("jq", "1.6", server.URL+"/jq/{{ .Version }}/jq")(r)
+	WithChecksum("jq", "1.6", "0000000000000000000000000000000000000000000000000000000000000000")(r)
+	ctx := context.Background()
+
+	path, installed, err := r.Tool(ctx, "yq")
+	require.NoError(t, err)
+	assert.True(t, installed)
+	assert.Equal(t, filepath.Join(binDir, "yq-4.9.6"), path)
+
+	// The installed one is reused.
+	_, installed, err = r.Tool(ctx, "yq")
+	require.NoError(t, err)
+	assert.False(t, installed)
+	assert.Equal(t, []string{"/yq/v4.9.6/yq"}, paths)
+
+	// The checksum mismatched.
+	_, _, err = r.Tool(ctx, "jq")
+	assert.Error(t, err)
+	assert.NoFileExists(t, filepath.Join(binDir, "jq-1.6"))
+
+	// Neither declared nor built-in.
+	_, _, err = r.Tool(ctx, "unknown")
+	assert.Error(t, err)
+}
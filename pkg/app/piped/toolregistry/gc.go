@@ -0,0 +1,209 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// The tools used within this period are never removed
+	// since they might be executing or going to be executed.
+	gcProtectionPeriod = time.Hour
+	// How often the last used time is written into the tool file.
+	usageSyncPeriod = time.Minute
+)
+
+var toolPrefixes = []string{
+	kubectlPrefix,
+	kustomizePrefix,
+	helmPrefix,
+	terraformPrefix,
+	conftestPrefix,
+	cosignPrefix,
+	sopsPrefix,
+}
+
+// GCPolicy represents the conditions to remove the installed tool versions.
+// The default version of each tool such as "kubectl" is never removed.
+type GCPolicy struct {
+	// Maximum number of versions should be kept for each tool.
+	// The least recently used ones are removed first.
+	// Zero means unlimited.
+	MaxVersionsPerTool int
+	// Maximum total size in bytes of all tools in the bin directory.
+	// The least recently used versions are removed until satisfying it.
+	// Zero means unlimited.
+	MaxTotalSize int64
+	// The versions that have not been used in this duration are removed.
+	// Zero means unlimited.
+	MaxUnusedDuration time.Duration
+}
+
+// IsEmpty checks whether no condition was configured.
+func (p GCPolicy) IsEmpty() bool {
+	return p.MaxVersionsPerTool <= 0 && p.MaxTotalSize <= 0 && p.MaxUnusedDuration <= 0
+}
+
+// RunGC periodically removes the tool versions of the default registry
+// that are matching the given policy until the context is done.
+func RunGC(ctx context.Context, policy GCPolicy, interval time.Duration) error {
+	if defaultRegistry == nil {
+		return fmt.Errorf("the default tool registry was not initialized")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			removed, err := defaultRegistry.gc(policy, time.Now())
+			if err != nil {
+				defaultRegistry.logger.Error("failed to remove unused tools", zap.Error(err))
+				continue
+			}
+			if len(removed) > 0 {
+				defaultRegistry.logger.Info("successfully removed unused tools", zap.Strings("tools", removed))
+			}
+		}
+	}
+}
+
+type toolUsage struct {
+	name     string
+	tool     string
+	size     int64
+	lastUsed time.Time
+}
+
+// gc removes the tool versions matching the given policy
+// and returns the names of the removed ones.
+func (r *registry) gc(policy GCPolicy, now time.Time) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		usages    = make([]toolUsage, 0, len(r.versions))
+		totalSize int64
+	)
+	for name, lastUsed := range r.versions {
+		info, err := os.Stat(filepath.Join(r.binDir, name))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			// The tool file was removed by other than piped.
+			delete(r.versions, name)
+			continue
+		}
+		totalSize += info.Size()
+
+		tool, ok := versionedToolName(name)
+		if !ok {
+			continue
+		}
+		usages = append(usages, toolUsage{
+			name:     name,
+			tool:     tool,
+			size:     info.Size(),
+			lastUsed: lastUsed,
+		})
+	}
+
+	removes := selectGarbageTools(usages, totalSize, policy, now)
+	removed := make([]string, 0, len(removes))
+	for _, name := range removes {
+		if err := os.Remove(filepath.Join(r.binDir, name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		delete(r.versions, name)
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// selectGarbageTools returns the names of the tool versions should be removed
+// to satisfy the given policy.
+func selectGarbageTools(usages []toolUsage, totalSize int64, policy GCPolicy, now time.Time) []string {
+	// Sort by the last used time to handle the most recently used ones first.
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].lastUsed.After(usages[j].lastUsed)
+	})
+
+	var (
+		removes   = make([]string, 0)
+		remains   = make([]toolUsage, 0, len(usages))
+		perTool   = make(map[string]int)
+		removable = func(u toolUsage) bool {
+			return now.Sub(u.lastUsed) > gcProtectionPeriod
+		}
+	)
+	for _, u := range usages {
+		perTool[u.tool]++
+		if !removable(u) {
+			remains = append(remains, u)
+			continue
+		}
+		if policy.MaxUnusedDuration > 0 && now.Sub(u.lastUsed) > policy.MaxUnusedDuration {
+			removes = append(removes, u.name)
+			totalSize -= u.size
+			perTool[u.tool]--
+			continue
+		}
+		if policy.MaxVersionsPerTool > 0 && perTool[u.tool] > policy.MaxVersionsPerTool {
+			removes = append(removes, u.name)
+			totalSize -= u.size
+			perTool[u.tool]--
+			continue
+		}
+		remains = append(remains, u)
+	}
+
+	if policy.MaxTotalSize <= 0 {
+		return removes
+	}
+	// Remove the least recently used ones until the total size is satisfied.
+	for i := len(remains) - 1; i >= 0 && totalSize > policy.MaxTotalSize; i-- {
+		if !removable(remains[i]) {
+			continue
+		}
+		removes = append(removes, remains[i].name)
+		totalSize -= remains[i].size
+	}
+	return removes
+}
+
+// versionedToolName returns the tool name of the given versioned tool file name,
+// e.g. "kubectl" for "kubectl-1.18.2".
+// False is returned for the default version of tools and unknown files.
+func versionedToolName(name string) (string, bool) {
+	for _, prefix := range toolPrefixes {
+		if strings.HasPrefix(name, prefix+"-") {
+			return prefix, true
+		}
+	}
+	return "", false
+}
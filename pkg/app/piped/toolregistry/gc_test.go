@@ -0,0 +1,126 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSelectGarbageTools(t *testing.T) {
+	now := time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)
+	usages := []toolUsage{
+		{name: "kubectl-1.18.0", tool: "kubectl", size: 40, lastUsed: now.Add(-72 * time.Hour)},
+		{name: "kubectl-1.19.0", tool: "kubectl", size: 40, lastUsed: now.Add(-48 * time.Hour)},
+		{name: "kubectl-1.20.0", tool: "kubectl", size: 40, lastUsed: now.Add(-10 * time.Minute)},
+		{name: "helm-3.4.0", tool: "helm", size: 30, lastUsed: now.Add(-24 * time.Hour)},
+		{name: "helm-3.5.0", tool: "helm", size: 30, lastUsed: now.Add(-12 * time.Hour)},
+	}
+
+	testcases := []struct {
+		name      string
+		totalSize int64
+		policy    GCPolicy
+		expected  []string
+	}{
+		{
+			name:      "empty policy",
+			totalSize: 180,
+			expected:  []string{},
+		},
+		{
+			name:      "max versions per tool",
+			totalSize: 180,
+			policy: GCPolicy{
+				MaxVersionsPerTool: 1,
+			},
+			expected: []string{"helm-3.4.0", "kubectl-1.19.0", "kubectl-1.18.0"},
+		},
+		{
+			name:      "max unused duration",
+			totalSize: 180,
+			policy: GCPolicy{
+				MaxUnusedDuration: 36 * time.Hour,
+			},
+			expected: []string{"kubectl-1.19.0", "kubectl-1.18.0"},
+		},
+		{
+			name:      "max total size",
+			totalSize: 180,
+			policy: GCPolicy{
+				MaxTotalSize: 100,
+			},
+			expected: []string{"kubectl-1.18.0", "kubectl-1.19.0"},
+		},
+		{
+			name:      "recently used versions are protected",
+			totalSize: 180,
+			policy: GCPolicy{
+				MaxTotalSize: 10,
+			},
+			expected: []string{"kubectl-1.18.0", "kubectl-1.19.0", "helm-3.4.0", "helm-3.5.0"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := make([]toolUsage, len(usages))
+			copy(in, usages)
+			got := selectGarbageTools(in, tc.totalSize, tc.policy, now)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestRegistryGC(t *testing.T) {
+	binDir, err := ioutil.TempDir("", "toolregistry")
+	require.NoError(t, err)
+	defer os.RemoveAll(binDir)
+
+	now := time.Now()
+	versions := map[string]time.Time{
+		"kubectl":        now.Add(-48 * time.Hour),
+		"kubectl-1.18.0": now.Add(-48 * time.Hour),
+		"kubectl-1.19.0": now.Add(-2 * time.Hour),
+	}
+	for name := range versions {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(binDir, name), []byte("bin"), 0755))
+	}
+	// This one was removed by other than piped.
+	versions["helm-3.5.0"] = now
+
+	r := &registry{
+		binDir:   binDir,
+		versions: versions,
+		logger:   zap.NewNop(),
+	}
+	removed, err := r.gc(GCPolicy{MaxUnusedDuration: 24 * time.Hour}, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubectl-1.18.0"}, removed)
+
+	_, err = os.Stat(filepath.Join(binDir, "kubectl-1.18.0"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, map[string]time.Time{
+		"kubectl":        versions["kubectl"],
+		"kubectl-1.19.0": versions["kubectl-1.19.0"],
+	}, r.versions)
+}
@@ -31,6 +31,9 @@ const (
 	defaultKustomizeVersion = "3.8.1"
 	defaultHelmVersion      = "3.2.1"
 	defaultTerraformVersion = "0.13.0"
+	defaultConftestVersion  = "0.25.0"
+	defaultCosignVersion    = "1.13.1"
+	defaultSopsVersion      = "3.7.1"
 )
 
 var (
@@ -38,6 +41,9 @@ var (
 	kustomizeInstallScriptTmpl = template.Must(template.New("kustomize").Parse(kustomizeInstallScript))
 	helmInstallScriptTmpl      = template.Must(template.New("helm").Parse(helmInstallScript))
 	terraformInstallScriptTmpl = template.Must(template.New("terraform").Parse(terraformInstallScript))
+	conftestInstallScriptTmpl  = template.Must(template.New("conftest").Parse(conftestInstallScript))
+	cosignInstallScriptTmpl    = template.Must(template.New("cosign").Parse(cosignInstallScript))
+	sopsInstallScriptTmpl      = template.Must(template.New("sops").Parse(sopsInstallScript))
 )
 
 func (r *registry) installKubectl(ctx context.Context, version string) error {
@@ -52,13 +58,23 @@ func (r *registry) installKubectl(ctx context.Context, version string) error {
 		version = defaultKubectlVersion
 	}
 
+	downloaded, err := r.downloadTool(ctx, kubectlPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download kubectl",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install kubectl %s (%v)", version, err)
+	}
+
 	var (
 		buf  bytes.Buffer
 		data = map[string]interface{}{
-			"WorkingDir": workingDir,
-			"Version":    version,
-			"BinDir":     r.binDir,
-			"AsDefault":  asDefault,
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
 		}
 	)
 	if err := kubectlInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -99,13 +115,23 @@ func (r *registry) installKustomize(ctx context.Context, version string) error {
 		version = defaultKustomizeVersion
 	}
 
+	downloaded, err := r.downloadTool(ctx, kustomizePrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download kustomize",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install kustomize %s (%v)", version, err)
+	}
+
 	var (
 		buf  bytes.Buffer
 		data = map[string]interface{}{
-			"WorkingDir": workingDir,
-			"Version":    version,
-			"BinDir":     r.binDir,
-			"AsDefault":  asDefault,
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
 		}
 	)
 	if err := kustomizeInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -146,13 +172,23 @@ func (r *registry) installHelm(ctx context.Context, version string) error {
 		version = defaultHelmVersion
 	}
 
+	downloaded, err := r.downloadTool(ctx, helmPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download helm",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install helm %s (%v)", version, err)
+	}
+
 	var (
 		buf  bytes.Buffer
 		data = map[string]interface{}{
-			"WorkingDir": workingDir,
-			"Version":    version,
-			"BinDir":     r.binDir,
-			"AsDefault":  asDefault,
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
 		}
 	)
 	if err := helmInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -193,13 +229,23 @@ func (r *registry) installTerraform(ctx context.Context, version string) error {
 		version = defaultTerraformVersion
 	}
 
+	downloaded, err := r.downloadTool(ctx, terraformPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download terraform",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install terraform %s (%w)", version, err)
+	}
+
 	var (
 		buf  bytes.Buffer
 		data = map[string]interface{}{
-			"WorkingDir": workingDir,
-			"Version":    version,
-			"BinDir":     r.binDir,
-			"AsDefault":  asDefault,
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
 		}
 	)
 	if err := terraformInstallScriptTmpl.Execute(&buf, data); err != nil {
@@ -227,3 +273,174 @@ func (r *registry) installTerraform(ctx context.Context, version string) error {
 	r.logger.Info("just installed terraform", zap.String("version", version))
 	return nil
 }
+
+func (r *registry) installConftest(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "conftest-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultConftestVersion
+	}
+
+	downloaded, err := r.downloadTool(ctx, conftestPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download conftest",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install conftest %s (%w)", version, err)
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
+		}
+	)
+	if err := conftestInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render conftest install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install conftest %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install conftest",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install conftest %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed conftest", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installCosign(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "cosign-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultCosignVersion
+	}
+
+	downloaded, err := r.downloadTool(ctx, cosignPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download cosign",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cosign %s (%w)", version, err)
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
+		}
+	)
+	if err := cosignInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render cosign install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cosign %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install cosign",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install cosign %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed cosign", zap.String("version", version))
+	return nil
+}
+
+func (r *registry) installSops(ctx context.Context, version string) error {
+	workingDir, err := ioutil.TempDir("", "sops-install")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workingDir)
+
+	asDefault := version == ""
+	if asDefault {
+		version = defaultSopsVersion
+	}
+
+	downloaded, err := r.downloadTool(ctx, sopsPrefix, version, workingDir)
+	if err != nil {
+		r.logger.Error("failed to download sops",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install sops %s (%w)", version, err)
+	}
+
+	var (
+		buf  bytes.Buffer
+		data = map[string]interface{}{
+			"WorkingDir":     workingDir,
+			"Version":        version,
+			"BinDir":         r.binDir,
+			"AsDefault":      asDefault,
+			"DownloadedFile": downloaded,
+		}
+	)
+	if err := sopsInstallScriptTmpl.Execute(&buf, data); err != nil {
+		r.logger.Error("failed to render sops install script",
+			zap.String("version", version),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install sops %s (%w)", version, err)
+	}
+
+	var (
+		script = buf.String()
+		cmd    = exec.CommandContext(ctx, "/bin/sh", "-c", script)
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		r.logger.Error("failed to install sops",
+			zap.String("version", version),
+			zap.String("script", script),
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to install sops %s, %s (%w)", version, string(out), err)
+	}
+
+	r.logger.Info("just installed sops", zap.String("version", version))
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockDirName is the directory placing the lock files in the bin directory.
+// It is a hidden directory to not be loaded as a tool.
+const lockDirName = ".locks"
+
+// installOnce runs the given function to install the tool of the given name only once
+// even when the tool is requested at the same time. The concurrent requests
+// in this process are merged into one, and the installations by the other piped processes
+// sharing the same bin directory are serialized by a file lock.
+// False is returned when the tool was installed by another process while waiting for the lock.
+func (r *registry) installOnce(name string, install func() error) (bool, error) {
+	v, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
+		unlock, err := lockFile(filepath.Join(r.binDir, lockDirName, name+".lock"))
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+
+		if _, err := os.Stat(filepath.Join(r.binDir, name)); err == nil {
+			return false, nil
+		}
+		if err := install(); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return v.(bool), nil
+}
+
+// lockFile blocks until acquiring the exclusive lock of the given file
+// and returns the function to release it.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
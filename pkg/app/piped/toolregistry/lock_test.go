@@ -0,0 +1,110 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package toolregistry
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+func newTestRegistry(binDir string) *registry {
+	return &registry{
+		binDir:       binDir,
+		versions:     map[string]time.Time{},
+		installGroup: &singleflight.Group{},
+		logger:       zap.NewNop(),
+	}
+}
+
+func TestInstallOnce(t *testing.T) {
+	binDir := t.TempDir()
+	var (
+		calls   int32
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	install := func() error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return ioutil.WriteFile(filepath.Join(binDir, "kubectl-1.18.2"), []byte("#!/bin/sh\n"), 0755)
+	}
+
+	// The concurrent requests in the same process are merged into one.
+	r := newTestRegistry(binDir)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.installOnce("kubectl-1.18.2", install)
+			assert.NoError(t, err)
+		}()
+	}
+	<-started
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Another process sharing the bin directory does not install it again.
+	another := newTestRegistry(binDir)
+	ok, err := another.installOnce("kubectl-1.18.2", install)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// The lock files and the files being downloaded are not loaded as tools.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(binDir, ".helm-3.5.3-download123"), nil, 0644))
+	tools, err := loadPreinstalledTool(binDir)
+	require.NoError(t, err)
+	assert.Len(t, tools, 1)
+	assert.Contains(t, tools, "kubectl-1.18.2")
+}
+
+func TestLockFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks", "tool.lock")
+	unlock, err := lockFile(path)
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		unlock, err := lockFile(path)
+		require.NoError(t, err)
+		close(acquired)
+		unlock()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("the lock must not be acquired while being held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock was not acquired after being released")
+	}
+}
@@ -19,9 +19,12 @@ package toolregistry
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"golang.org/x/sync/singleflight"
@@ -33,6 +36,16 @@ type Registry interface {
 	Kustomize(ctx context.Context, version string) (string, bool, error)
 	Helm(ctx context.Context, version string) (string, bool, error)
 	Terraform(ctx context.Context, version string) (string, bool, error)
+	Conftest(ctx context.Context, version string) (string, bool, error)
+	Cosign(ctx context.Context, version string) (string, bool, error)
+	Sops(ctx context.Context, version string) (string, bool, error)
+	// Custom returns the path to the user-provided tool,
+	// downloading it from the given URL template when it was not installed yet.
+	Custom(ctx context.Context, name, version, downloadURL string) (string, bool, error)
+	// Tool returns the path to the tool of the given name declared by WithTool,
+	// or to the default version of the built-in one such as kubectl.
+	// The tool is installed when it was not installed yet.
+	Tool(ctx context.Context, name string) (string, bool, error)
 }
 
 var defaultRegistry *registry
@@ -44,7 +57,7 @@ func DefaultRegistry() Registry {
 
 // InitDefaultRegistry initializes the default registry.
 // This also preloads the pre-installed tools in the binDir.
-func InitDefaultRegistry(binDir string, logger *zap.Logger) error {
+func InitDefaultRegistry(binDir string, logger *zap.Logger, opts ...Option) error {
 	logger = logger.Named("tool-registry")
 	if err := os.MkdirAll(binDir, os.ModePerm); err != nil {
 		return err
@@ -56,18 +69,28 @@ func InitDefaultRegistry(binDir string, logger *zap.Logger) error {
 	}
 	logger.Info("successfully loaded the pre-installed tools", zap.Any("tools", tools))
 
-	defaultRegistry = &registry{
+	r := &registry{
 		binDir:       binDir,
 		versions:     tools,
 		installGroup: &singleflight.Group{},
+		mirrors:      make(map[string]string),
+		checksums:    make(map[string]string),
+		definitions:  make(map[string]toolDefinition),
+		httpClient:   http.DefaultClient,
 		logger:       logger,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	defaultRegistry = r
 
 	return nil
 }
 
-func loadPreinstalledTool(binDir string) (map[string]struct{}, error) {
-	tools := make(map[string]struct{})
+// loadPreinstalledTool returns the pre-installed tools with their last used time.
+// The modification time of the tool file is used as the last used time.
+func loadPreinstalledTool(binDir string) (map[string]time.Time, error) {
+	tools := make(map[string]time.Time)
 	err := filepath.Walk(binDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -82,7 +105,11 @@ func loadPreinstalledTool(binDir string) (map[string]struct{}, error) {
 			return nil
 		}
 		name := filepath.Base(path)
-		tools[name] = struct{}{}
+		// The hidden files are the ones being downloaded.
+		if strings.HasPrefix(name, ".") {
+			return nil
+		}
+		tools[name] = info.ModTime()
 		return nil
 	})
 	if err != nil {
@@ -96,14 +123,25 @@ const (
 	kustomizePrefix = "kustomize"
 	helmPrefix      = "helm"
 	terraformPrefix = "terraform"
+	conftestPrefix  = "conftest"
+	cosignPrefix    = "cosign"
+	sopsPrefix      = "sops"
 )
 
 type registry struct {
-	binDir       string
-	versions     map[string]struct{}
+	binDir string
+	// The installed tools with their last used time.
+	versions     map[string]time.Time
 	mu           sync.RWMutex
 	installGroup *singleflight.Group
-	logger       *zap.Logger
+	// The URL templates to download the tools from keyed by the tool name.
+	mirrors map[string]string
+	// The expected SHA256 checksums of the downloaded files keyed by the tool name with its version.
+	checksums map[string]string
+	// The tools declared by the user keyed by the tool name.
+	definitions map[string]toolDefinition
+	httpClient  *http.Client
+	logger      *zap.Logger
 }
 
 func (r *registry) Kubectl(ctx context.Context, version string) (string, bool, error) {
@@ -117,21 +155,19 @@ func (r *registry) Kubectl(ctx context.Context, version string) (string, bool, e
 	_, ok := r.versions[name]
 	r.mu.RUnlock()
 	if ok {
+		r.markUsed(name)
 		return path, false, nil
 	}
 
-	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
-		return nil, r.installKubectl(ctx, version)
+	installed, err := r.installOnce(name, func() error {
+		return r.installKubectl(ctx, version)
 	})
 	if err != nil {
 		return "", true, err
 	}
 
-	r.mu.Lock()
-	r.versions[name] = struct{}{}
-	r.mu.Unlock()
-
-	return path, true, nil
+	r.markUsed(name)
+	return path, installed, nil
 }
 
 func (r *registry) Kustomize(ctx context.Context, version string) (string, bool, error) {
@@ -145,21 +181,19 @@ func (r *registry) Kustomize(ctx context.Context, version string) (string, bool,
 	_, ok := r.versions[name]
 	r.mu.RUnlock()
 	if ok {
+		r.markUsed(name)
 		return path, false, nil
 	}
 
-	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
-		return nil, r.installKustomize(ctx, version)
+	installed, err := r.installOnce(name, func() error {
+		return r.installKustomize(ctx, version)
 	})
 	if err != nil {
 		return "", true, err
 	}
 
-	r.mu.Lock()
-	r.versions[name] = struct{}{}
-	r.mu.Unlock()
-
-	return path, true, nil
+	r.markUsed(name)
+	return path, installed, nil
 }
 
 func (r *registry) Helm(ctx context.Context, version string) (string, bool, error) {
@@ -173,21 +207,19 @@ func (r *registry) Helm(ctx context.Context, version string) (string, bool, erro
 	_, ok := r.versions[name]
 	r.mu.RUnlock()
 	if ok {
+		r.markUsed(name)
 		return path, false, nil
 	}
 
-	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
-		return nil, r.installHelm(ctx, version)
+	installed, err := r.installOnce(name, func() error {
+		return r.installHelm(ctx, version)
 	})
 	if err != nil {
 		return "", true, err
 	}
 
-	r.mu.Lock()
-	r.versions[name] = struct{}{}
-	r.mu.Unlock()
-
-	return path, true, nil
+	r.markUsed(name)
+	return path, installed, nil
 }
 
 func (r *registry) Terraform(ctx context.Context, version string) (string, bool, error) {
@@ -201,19 +233,114 @@ func (r *registry) Terraform(ctx context.Context, version string) (string, bool,
 	_, ok := r.versions[name]
 	r.mu.RUnlock()
 	if ok {
+		r.markUsed(name)
+		return path, false, nil
+	}
+
+	installed, err := r.installOnce(name, func() error {
+		return r.installTerraform(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.markUsed(name)
+	return path, installed, nil
+}
+
+func (r *registry) Conftest(ctx context.Context, version string) (string, bool, error) {
+	name := conftestPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", conftestPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		r.markUsed(name)
 		return path, false, nil
 	}
 
-	_, err, _ := r.installGroup.Do(name, func() (interface{}, error) {
-		return nil, r.installTerraform(ctx, version)
+	installed, err := r.installOnce(name, func() error {
+		return r.installConftest(ctx, version)
 	})
 	if err != nil {
 		return "", true, err
 	}
 
+	r.markUsed(name)
+	return path, installed, nil
+}
+
+func (r *registry) Cosign(ctx context.Context, version string) (string, bool, error) {
+	name := cosignPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", cosignPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		r.markUsed(name)
+		return path, false, nil
+	}
+
+	installed, err := r.installOnce(name, func() error {
+		return r.installCosign(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.markUsed(name)
+	return path, installed, nil
+}
+
+func (r *registry) Sops(ctx context.Context, version string) (string, bool, error) {
+	name := sopsPrefix
+	if version != "" {
+		name = fmt.Sprintf("%s-%s", sopsPrefix, version)
+	}
+	path := filepath.Join(r.binDir, name)
+
+	r.mu.RLock()
+	_, ok := r.versions[name]
+	r.mu.RUnlock()
+	if ok {
+		r.markUsed(name)
+		return path, false, nil
+	}
+
+	installed, err := r.installOnce(name, func() error {
+		return r.installSops(ctx, version)
+	})
+	if err != nil {
+		return "", true, err
+	}
+
+	r.markUsed(name)
+	return path, installed, nil
+}
+
+// markUsed records the current time as the last used time of the given tool.
+// The modification time of the tool file is also updated
+// to keep the last used time even if piped was restarted.
+func (r *registry) markUsed(name string) {
+	now := time.Now()
+
 	r.mu.Lock()
-	r.versions[name] = struct{}{}
+	last := r.versions[name]
+	r.versions[name] = now
 	r.mu.Unlock()
 
-	return path, true, nil
+	if now.Sub(last) < usageSyncPeriod {
+		return
+	}
+	if err := os.Chtimes(filepath.Join(r.binDir, name), now, now); err != nil {
+		r.logger.Warn("failed to update the last used time of tool", zap.String("tool", name), zap.Error(err))
+	}
 }
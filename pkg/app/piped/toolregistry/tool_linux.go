@@ -14,10 +14,19 @@
 
 package toolregistry
 
+// defaultDownloadURLs are the URL templates of the release files of the tools.
+var defaultDownloadURLs = map[string]string{
+	kubectlPrefix:   "https://storage.googleapis.com/kubernetes-release/release/v{{ .Version }}/bin/linux/amd64/kubectl",
+	kustomizePrefix: "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize/v{{ .Version }}/kustomize_v{{ .Version }}_linux_amd64.tar.gz",
+	helmPrefix:      "https://get.helm.sh/helm-v{{ .Version }}-linux-amd64.tar.gz",
+	terraformPrefix: "https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_linux_amd64.zip",
+	conftestPrefix:  "https://github.com/open-policy-agent/conftest/releases/download/v{{ .Version }}/conftest_{{ .Version }}_Linux_x86_64.tar.gz",
+	cosignPrefix:    "https://github.com/sigstore/cosign/releases/download/v{{ .Version }}/cosign-linux-amd64",
+	sopsPrefix:      "https://github.com/mozilla/sops/releases/download/v{{ .Version }}/sops-v{{ .Version }}.linux",
+}
+
 var kubectlInstallScript = `
-cd {{ .WorkingDir }}
-curl -LO https://storage.googleapis.com/kubernetes-release/release/v{{ .Version }}/bin/linux/amd64/kubectl
-mv kubectl {{ .BinDir }}/kubectl-{{ .Version }}
+mv {{ .DownloadedFile }} {{ .BinDir }}/kubectl-{{ .Version }}
 chmod +x {{ .BinDir }}/kubectl-{{ .Version }}
 {{ if .AsDefault }}
 cp -f {{ .BinDir }}/kubectl-{{ .Version }} {{ .BinDir }}/kubectl
@@ -26,7 +35,7 @@ cp -f {{ .BinDir }}/kubectl-{{ .Version }} {{ .BinDir }}/kubectl
 
 var kustomizeInstallScript = `
 cd {{ .WorkingDir }}
-curl -L https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize/v{{ .Version }}/kustomize_v{{ .Version }}_linux_amd64.tar.gz | tar xvz
+tar xvzf {{ .DownloadedFile }}
 mv kustomize {{ .BinDir }}/kustomize-{{ .Version }}
 chmod +x {{ .BinDir }}/kustomize-{{ .Version }}
 {{ if .AsDefault }}
@@ -36,7 +45,7 @@ cp -f {{ .BinDir }}/kustomize-{{ .Version }} {{ .BinDir }}/kustomize
 
 var helmInstallScript = `
 cd {{ .WorkingDir }}
-curl -L https://get.helm.sh/helm-v{{ .Version }}-linux-amd64.tar.gz | tar xvz
+tar xvzf {{ .DownloadedFile }}
 mv linux-amd64/helm {{ .BinDir }}/helm-{{ .Version }}
 chmod +x {{ .BinDir }}/helm-{{ .Version }}
 {{ if .AsDefault }}
@@ -46,10 +55,35 @@ cp -f {{ .BinDir }}/helm-{{ .Version }} {{ .BinDir }}/helm
 
 var terraformInstallScript = `
 cd {{ .WorkingDir }}
-curl https://releases.hashicorp.com/terraform/{{ .Version }}/terraform_{{ .Version }}_linux_amd64.zip -o terraform_{{ .Version }}_linux_amd64.zip
-unzip terraform_{{ .Version }}_linux_amd64.zip
+unzip {{ .DownloadedFile }}
 mv terraform {{ .BinDir }}/terraform-{{ .Version }}
 {{ if .AsDefault }}
 cp -f {{ .BinDir }}/terraform-{{ .Version }} {{ .BinDir }}/terraform
 {{ end }}
 `
+
+var conftestInstallScript = `
+cd {{ .WorkingDir }}
+tar xvzf {{ .DownloadedFile }}
+mv conftest {{ .BinDir }}/conftest-{{ .Version }}
+chmod +x {{ .BinDir }}/conftest-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/conftest-{{ .Version }} {{ .BinDir }}/conftest
+{{ end }}
+`
+
+var cosignInstallScript = `
+mv {{ .DownloadedFile }} {{ .BinDir }}/cosign-{{ .Version }}
+chmod +x {{ .BinDir }}/cosign-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/cosign-{{ .Version }} {{ .BinDir }}/cosign
+{{ end }}
+`
+
+var sopsInstallScript = `
+mv {{ .DownloadedFile }} {{ .BinDir }}/sops-{{ .Version }}
+chmod +x {{ .BinDir }}/sops-{{ .Version }}
+{{ if .AsDefault }}
+cp -f {{ .BinDir }}/sops-{{ .Version }} {{ .BinDir }}/sops
+{{ end }}
+`
@@ -0,0 +1,133 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trafficrouting provides a common interface to control how the traffic
+// is split between the variants of an application, so that the executors
+// of all platforms can do canary routing in the same way.
+// Each platform only needs to implement Router to get canary routing.
+package trafficrouting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned when the operation is not supported by the routing backend.
+var ErrUnsupported = errors.New("unsupported by the traffic routing backend")
+
+// Router controls the traffic routing of an application.
+// It is implemented by the routing backends such as Istio, SMI, Gateway API,
+// AWS Load Balancer and Cloud Run.
+type Router interface {
+	// SetWeights routes the traffic to the variants by the given percentages.
+	SetWeights(ctx context.Context, weights Weights) error
+	// GetWeights returns the percentages of traffic currently routed to the variants.
+	GetWeights(ctx context.Context) (Weights, error)
+	// RouteByHeader routes all requests having the given header to CANARY variant
+	// regardless of the weights. ErrUnsupported is returned if the backend can not do that.
+	RouteByHeader(ctx context.Context, route HeaderRoute) error
+}
+
+// Weights represents the percentages of traffic routed to each variant.
+type Weights struct {
+	Primary  int
+	Canary   int
+	Baseline int
+}
+
+// Validate checks whether all percentages are in range and their sum is 100.
+func (w Weights) Validate() error {
+	for _, p := range []int{w.Primary, w.Canary, w.Baseline} {
+		if p < 0 || p > 100 {
+			return fmt.Errorf("percentage must be in range [0, 100] (primary=%d, canary=%d, baseline=%d)", w.Primary, w.Canary, w.Baseline)
+		}
+	}
+	if sum := w.Primary + w.Canary + w.Baseline; sum != 100 {
+		return fmt.Errorf("sum of percentages must be 100 but got %d", sum)
+	}
+	return nil
+}
+
+// String returns the weights formatted as "primary=90,canary=10,baseline=0",
+// which can be parsed back by ParseWeights.
+func (w Weights) String() string {
+	return fmt.Sprintf("primary=%d,canary=%d,baseline=%d", w.Primary, w.Canary, w.Baseline)
+}
+
+// ParseWeights parses the weights formatted by Weights.String.
+func ParseWeights(s string) (Weights, error) {
+	var w Weights
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return w, fmt.Errorf("malformed weights %q", s)
+		}
+		p, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return w, fmt.Errorf("malformed percentage of %s: %w", kv[0], err)
+		}
+		switch kv[0] {
+		case "primary":
+			w.Primary = p
+		case "canary":
+			w.Canary = p
+		case "baseline":
+			w.Baseline = p
+		default:
+			return w, fmt.Errorf("unknown variant %q in weights", kv[0])
+		}
+	}
+	return w, w.Validate()
+}
+
+// HeaderRoute specifies the requests should be routed to CANARY variant.
+type HeaderRoute struct {
+	// The name of the header.
+	Name string
+	// The value the header must exactly match.
+	Value string
+}
+
+// Validate checks whether the route is well-formed.
+func (r HeaderRoute) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("header name must not be empty")
+	}
+	if r.Value == "" {
+		return fmt.Errorf("header value must not be empty")
+	}
+	if strings.Contains(r.Name, ":") {
+		return fmt.Errorf("header name must not contain colon")
+	}
+	return nil
+}
+
+// String returns the route formatted as "name:value",
+// which can be parsed back by ParseHeaderRoute.
+func (r HeaderRoute) String() string {
+	return r.Name + ":" + r.Value
+}
+
+// ParseHeaderRoute parses the route formatted by HeaderRoute.String.
+func ParseHeaderRoute(s string) (HeaderRoute, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return HeaderRoute{}, fmt.Errorf("malformed header route %q", s)
+	}
+	r := HeaderRoute{Name: parts[0], Value: parts[1]}
+	return r, r.Validate()
+}
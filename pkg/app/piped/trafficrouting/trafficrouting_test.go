@@ -0,0 +1,137 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficrouting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightsValidate(t *testing.T) {
+	testcases := []struct {
+		name        string
+		weights     Weights
+		expectedErr bool
+	}{
+		{
+			name:    "all primary",
+			weights: Weights{Primary: 100},
+		},
+		{
+			name:    "three variants",
+			weights: Weights{Primary: 80, Canary: 10, Baseline: 10},
+		},
+		{
+			name:        "sum is not 100",
+			weights:     Weights{Primary: 80, Canary: 10},
+			expectedErr: true,
+		},
+		{
+			name:        "negative percentage",
+			weights:     Weights{Primary: 110, Canary: -10},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.weights.Validate()
+			assert.Equal(t, tc.expectedErr, err != nil)
+		})
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	testcases := []struct {
+		name        string
+		value       string
+		expected    Weights
+		expectedErr bool
+	}{
+		{
+			name:     "formatted by String",
+			value:    Weights{Primary: 80, Canary: 10, Baseline: 10}.String(),
+			expected: Weights{Primary: 80, Canary: 10, Baseline: 10},
+		},
+		{
+			name:     "missing variant",
+			value:    "primary=70,canary=30",
+			expected: Weights{Primary: 70, Canary: 30},
+		},
+		{
+			name:        "malformed",
+			value:       "primary:100",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown variant",
+			value:       "primary=90,stable=10",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid sum",
+			value:       "primary=90",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseWeights(tc.value)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if !tc.expectedErr {
+				assert.Equal(t, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseHeaderRoute(t *testing.T) {
+	testcases := []struct {
+		name        string
+		value       string
+		expected    HeaderRoute
+		expectedErr bool
+	}{
+		{
+			name:     "formatted by String",
+			value:    HeaderRoute{Name: "x-canary", Value: "always"}.String(),
+			expected: HeaderRoute{Name: "x-canary", Value: "always"},
+		},
+		{
+			name:     "value containing colon",
+			value:    "x-user:id:1",
+			expected: HeaderRoute{Name: "x-user", Value: "id:1"},
+		},
+		{
+			name:        "missing value",
+			value:       "x-canary",
+			expectedErr: true,
+		},
+		{
+			name:        "empty value",
+			value:       "x-canary:",
+			expectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseHeaderRoute(tc.value)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if !tc.expectedErr {
+				assert.Equal(t, tc.expected, got)
+			}
+		})
+	}
+}
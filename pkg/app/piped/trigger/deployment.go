@@ -23,6 +23,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
 	"github.com/pipe-cd/pipe/pkg/git"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -34,12 +35,25 @@ func (t *Trigger) triggerDeployment(
 	commit git.Commit,
 	commander string,
 	syncStrategy model.SyncStrategy,
+	kind model.DeploymentTriggerKind,
+	chain *model.DeploymentChainRef,
 ) (deployment *model.Deployment, err error) {
-	deployment, err = buildDeployment(app, branch, commit, commander, syncStrategy, time.Now())
+	deployment, err = buildDeployment(app, branch, commit, commander, syncStrategy, kind, time.Now())
 	if err != nil {
 		return
 	}
 
+	// Record the concurrency policy of the application
+	// to let the controller handle its deployments as configured.
+	if policy := t.concurrencyPolicy(app); policy != "" {
+		deployment.Metadata = map[string]string{
+			model.MetadataKeyConcurrencyPolicy: string(policy),
+		}
+	}
+	if chain != nil {
+		setDeploymentChain(deployment, chain)
+	}
+
 	defer func() {
 		if err != nil {
 			return
@@ -77,6 +91,37 @@ func (t *Trigger) triggerDeployment(
 	return
 }
 
+// concurrencyPolicy returns the concurrency policy configured in the latest deployment configuration
+// of the given application, or an empty one when it could not be loaded.
+func (t *Trigger) concurrencyPolicy(app *model.Application) config.ConcurrencyPolicy {
+	repo, ok := t.gitRepos[app.GitPath.Repo.Id]
+	if !ok {
+		return ""
+	}
+	deployConfig, err := loadDeploymentConfiguration(repo.GetPath(), app)
+	if err != nil {
+		t.logger.Warn("unable to load deployment configuration to determine the concurrency policy", zap.String("app-id", app.Id), zap.Error(err))
+		return ""
+	}
+	return deployConfig.ConcurrencyPolicy
+}
+
+// setDeploymentChain records the block of the deployment chain that triggered the given deployment
+// and the inputs passed from the previous block into its metadata.
+func setDeploymentChain(d *model.Deployment, chain *model.DeploymentChainRef) {
+	d.DeploymentChainId = chain.ChainId
+	d.DeploymentChainBlockIndex = chain.BlockIndex
+	if len(chain.Inputs) == 0 {
+		return
+	}
+	if d.Metadata == nil {
+		d.Metadata = make(map[string]string, len(chain.Inputs))
+	}
+	for k, v := range chain.Inputs {
+		d.Metadata[model.MetadataKeyChainInputPrefix+k] = v
+	}
+}
+
 func (t *Trigger) reportMostRecentlyTriggeredDeployment(ctx context.Context, d *model.Deployment) error {
 	var (
 		err error
@@ -110,6 +155,7 @@ func buildDeployment(
 	commit git.Commit,
 	commander string,
 	syncStrategy model.SyncStrategy,
+	kind model.DeploymentTriggerKind,
 	now time.Time,
 ) (*model.Deployment, error) {
 	commitURL := ""
@@ -141,9 +187,11 @@ func buildDeployment(
 			Commander:    commander,
 			Timestamp:    now.Unix(),
 			SyncStrategy: syncStrategy,
+			Kind:         kind,
 		},
 		GitPath:       app.GitPath,
 		CloudProvider: app.CloudProvider,
+		Labels:        app.Labels,
 		Status:        model.DeploymentStatus_DEPLOYMENT_PENDING,
 		StatusReason:  "The deployment is waiting to be planned",
 		CreatedAt:     now.Unix(),
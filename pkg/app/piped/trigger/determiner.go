@@ -50,56 +50,87 @@ func NewDeterminer(repo git.Repo, targetCommit string, cg LastTriggeredCommitGet
 }
 
 // ShouldTrigger decides whether a given application should be triggered or not.
-func (d *Determiner) ShouldTrigger(ctx context.Context, app *model.Application) (bool, error) {
+// The returned commit is the one should be deployed when it should be triggered,
+// otherwise the one until which the application has been checked.
+func (d *Determiner) ShouldTrigger(ctx context.Context, app *model.Application) (bool, string, error) {
 	logger := d.logger.With(
 		zap.String("app", app.Name),
 		zap.String("app-id", app.Id),
 		zap.String("target-commit", d.targetCommit),
 	)
 
+	deployConfig, err := loadDeploymentConfiguration(d.repo.GetPath(), app)
+	if err != nil {
+		return false, "", err
+	}
+
+	// The application configured to be triggered by tags
+	// is deployed at the most recently tagged commit instead of the target commit.
+	targetCommit := d.targetCommit
+	tagTrigger := deployConfig.Trigger != nil && deployConfig.Trigger.Tag != ""
+	if tagTrigger {
+		tags, err := d.repo.ListTags(ctx, deployConfig.Trigger.Tag)
+		if err != nil {
+			logger.Error("failed to list tags", zap.Error(err))
+			return false, "", err
+		}
+		if len(tags) == 0 {
+			logger.Info(fmt.Sprintf("no tag matching %s was found", deployConfig.Trigger.Tag))
+			return false, "", nil
+		}
+		targetCommit = tags[0].CommitHash
+		logger = logger.With(zap.String("tag", tags[0].Name))
+	}
+
 	preCommit, err := d.commitGetter.Get(ctx, app.Id)
 	if err != nil {
 		logger.Error("failed to get last triggered commit", zap.Error(err))
-		return false, err
+		return false, "", err
 	}
 
 	// There is no previous deployment so we don't need to check anymore.
 	// Just do it.
 	if preCommit == "" {
 		logger.Info("no previously triggered deployment was found")
-		return true, nil
+		return true, targetCommit, nil
 	}
 
 	// Check whether the most recently applied one is the target commit or not.
 	// If so, nothing to do for this time.
-	if preCommit == d.targetCommit {
-		logger.Info(fmt.Sprintf("no update to sync for application, hash: %s", d.targetCommit))
-		return false, nil
+	if preCommit == targetCommit {
+		logger.Info(fmt.Sprintf("no update to sync for application, hash: %s", targetCommit))
+		return false, targetCommit, nil
+	}
+
+	// A new tag was pushed so the tagged commit should be deployed
+	// regardless of which files were changed.
+	if tagTrigger {
+		logger.Info("a new tag was found", zap.String("last-triggered-commit", preCommit))
+		return true, targetCommit, nil
 	}
 
 	// List the changed files between those two commits and
 	// determine whether this application was touch by those changed files.
-	changedFiles, err := d.repo.ChangedFiles(ctx, preCommit, d.targetCommit)
+	changedFiles, err := d.repo.ChangedFiles(ctx, preCommit, targetCommit)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
-	deployConfig, err := loadDeploymentConfiguration(d.repo.GetPath(), app)
-	if err != nil {
-		return false, err
+	var includes, excludes []string
+	if t := deployConfig.Trigger; t != nil {
+		includes, excludes = t.Includes, t.Excludes
 	}
-
-	touched, err := isTouchedByChangedFiles(app.GitPath.Path, deployConfig.TriggerPaths, changedFiles)
+	touched, err := isTouchedByChangedFiles(app.GitPath.Path, deployConfig.TriggerPaths, includes, excludes, changedFiles)
 	if err != nil {
-		return false, err
+		return false, "", err
 	}
 
 	if !touched {
 		logger.Info("application was not touched by any new commits", zap.String("last-triggered-commit", preCommit))
-		return false, nil
+		return false, targetCommit, nil
 	}
 
-	return true, nil
+	return true, targetCommit, nil
 }
 
 func loadDeploymentConfiguration(repoPath string, app *model.Application) (*config.GenericDeploymentSpec, error) {
@@ -127,17 +158,46 @@ func loadDeploymentConfiguration(repoPath string, app *model.Application) (*conf
 	return &spec, nil
 }
 
-func isTouchedByChangedFiles(appDir string, changes []string, changedFiles []string) (bool, error) {
-	if !strings.HasSuffix(appDir, "/") {
-		appDir += "/"
+// isTouchedByChangedFiles reports whether the application was touched by the given changed files.
+// The files inside the application directory are used to decide unless includes are given,
+// and the ones matching excludes are ignored.
+func isTouchedByChangedFiles(appDir string, changes, includes, excludes, changedFiles []string) (bool, error) {
+	if len(excludes) > 0 {
+		matcher, err := filematcher.NewPatternMatcher(excludes)
+		if err != nil {
+			return false, err
+		}
+		filtered := make([]string, 0, len(changedFiles))
+		for _, cf := range changedFiles {
+			if !matcher.Matches(cf) {
+				filtered = append(filtered, cf)
+			}
+		}
+		changedFiles = filtered
 	}
 
-	// If any files inside the application directory was changed
-	// this application is considered as touched.
-	for _, cf := range changedFiles {
-		if ok := strings.HasPrefix(cf, appDir); ok {
+	if len(includes) > 0 {
+		// If any changed files matches the specified "includes"
+		// this application is considered as touched.
+		matcher, err := filematcher.NewPatternMatcher(includes)
+		if err != nil {
+			return false, err
+		}
+		if matcher.MatchesAny(changedFiles) {
 			return true, nil
 		}
+	} else {
+		if !strings.HasSuffix(appDir, "/") {
+			appDir += "/"
+		}
+
+		// If any files inside the application directory was changed
+		// this application is considered as touched.
+		for _, cf := range changedFiles {
+			if ok := strings.HasPrefix(cf, appDir); ok {
+				return true, nil
+			}
+		}
 	}
 
 	// If any changed files matches the specified "changes"
@@ -25,6 +25,8 @@ func TestIsTouchedByChangedFiles(t *testing.T) {
 		name         string
 		appDir       string
 		changes      []string
+		includes     []string
+		excludes     []string
 		changedFiles []string
 		expected     bool
 	}{
@@ -67,11 +69,61 @@ func TestIsTouchedByChangedFiles(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name:   "not touched because of excludes",
+			appDir: "app/demo",
+			excludes: []string{
+				"**/*.md",
+			},
+			changedFiles: []string{
+				"app/demo/README.md",
+				"app/docs/guide.md",
+			},
+			expected: false,
+		},
+		{
+			name:   "touched in the includes",
+			appDir: "app/demo",
+			includes: []string{
+				"app/demo/manifests/**",
+				"charts/demo/**",
+			},
+			changedFiles: []string{
+				"app/hello.txt",
+				"charts/demo/values.yaml",
+			},
+			expected: true,
+		},
+		{
+			name:   "not touched because app dir is replaced by includes",
+			appDir: "app/demo",
+			includes: []string{
+				"app/demo/manifests/**",
+			},
+			changedFiles: []string{
+				"app/demo/scripts/test.sh",
+			},
+			expected: false,
+		},
+		{
+			name:   "excludes are prioritized over includes",
+			appDir: "app/demo",
+			includes: []string{
+				"app/demo/**",
+			},
+			excludes: []string{
+				"app/demo/docs",
+			},
+			changedFiles: []string{
+				"app/demo/docs/guide.md",
+			},
+			expected: false,
+		},
 	}
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := isTouchedByChangedFiles(tc.appDir, tc.changes, tc.changedFiles)
+			got, err := isTouchedByChangedFiles(tc.appDir, tc.changes, tc.includes, tc.excludes, tc.changedFiles)
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expected, got)
 		})
@@ -0,0 +1,57 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleChecker finds the applications whose schedules have come
+// by remembering the last time each application was checked.
+type scheduleChecker struct {
+	checkedAt map[string]time.Time
+}
+
+func newScheduleChecker() *scheduleChecker {
+	return &scheduleChecker{
+		checkedAt: make(map[string]time.Time),
+	}
+}
+
+// due reports whether any time of the given cron schedule has come
+// since the last check of the application.
+// The first check of an application is never due
+// so the schedules passed while piped was not running are not caught up.
+func (s *scheduleChecker) due(appID, schedule string, now time.Time) (bool, error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return false, err
+	}
+
+	last, ok := s.checkedAt[appID]
+	s.checkedAt[appID] = now
+	if !ok {
+		return false, nil
+	}
+	return !sched.Next(last).After(now), nil
+}
+
+// forget removes the application which has no schedule anymore
+// so that it will not be due immediately when a schedule is configured again.
+func (s *scheduleChecker) forget(appID string) {
+	delete(s.checkedAt, appID)
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleCheckerDue(t *testing.T) {
+	var (
+		s        = newScheduleChecker()
+		schedule = "0 3 * * *"
+		start    = time.Date(2021, 6, 1, 2, 58, 30, 0, time.UTC)
+	)
+
+	testcases := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		expected bool
+	}{
+		{
+			name:     "first check is never due",
+			schedule: schedule,
+			now:      start,
+			expected: false,
+		},
+		{
+			name:     "not come yet",
+			schedule: schedule,
+			now:      start.Add(time.Minute),
+			expected: false,
+		},
+		{
+			name:     "come since the last check",
+			schedule: schedule,
+			now:      start.Add(2 * time.Minute),
+			expected: true,
+		},
+		{
+			name:     "already triggered at the last check",
+			schedule: schedule,
+			now:      start.Add(3 * time.Minute),
+			expected: false,
+		},
+		{
+			name:     "come after a long interval",
+			schedule: schedule,
+			now:      start.Add(48 * time.Hour),
+			expected: true,
+		},
+		{
+			// 03:00 in Tokyo is 18:00 in UTC.
+			name:     "time zone is respected",
+			schedule: "CRON_TZ=Asia/Tokyo 0 3 * * *",
+			now:      start.Add(63*time.Hour + 32*time.Minute),
+			expected: true,
+		},
+	}
+	// The cases are run in order since each check depends on the previous one.
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := s.due("app", tc.schedule, tc.now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+
+	_, err := s.due("app", "0 3 * *", start)
+	assert.Error(t, err)
+
+	s.forget("app")
+	got, err := s.due("app", schedule, start.Add(72*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, got, "first check after forgetting is never due")
+}
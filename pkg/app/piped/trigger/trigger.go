@@ -20,6 +20,7 @@ package trigger
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"go.uber.org/zap"
@@ -34,7 +35,11 @@ import (
 
 const (
 	commandCheckInterval                = 10 * time.Second
+	scheduleCheckInterval               = time.Minute
 	defaultLastTriggeredCommitCacheSize = 500
+	// The maximum number of repositories waiting to be checked by webhook.
+	// The exceeded ones are checked at the next polling.
+	webhookQueueSize = 100
 )
 
 const (
@@ -77,7 +82,10 @@ type Trigger struct {
 	notifier          notifier
 	config            *config.PipedSpec
 	commitStore       *lastTriggeredCommitStore
+	scheduleChecker   *scheduleChecker
 	gitRepos          map[string]git.Repo
+	gitRepoConfigs    map[string]config.PipedRepository
+	webhookCh         chan string
 	gracePeriod       time.Duration
 	logger            *zap.Logger
 }
@@ -113,7 +121,10 @@ func NewTrigger(
 		notifier:          notifier,
 		config:            cfg,
 		commitStore:       commitStore,
-		gitRepos:          make(map[string]git.Repo, len(cfg.Repositories)),
+		scheduleChecker:   newScheduleChecker(),
+		gitRepos:          make(map[string]git.Repo),
+		gitRepoConfigs:    make(map[string]config.PipedRepository),
+		webhookCh:         make(chan string, webhookQueueSize),
 		gracePeriod:       gracePeriod,
 		logger:            logger.Named("trigger"),
 	}
@@ -127,17 +138,8 @@ func (t *Trigger) Run(ctx context.Context) error {
 	t.logger.Info("start running deployment trigger")
 
 	// Pre-clone to cache the registered git repositories.
-	t.gitRepos = make(map[string]git.Repo, len(t.config.Repositories))
-	for _, r := range t.config.Repositories {
-		repo, err := t.gitClient.Clone(ctx, r.RepoID, r.Remote, r.Branch, "")
-		if err != nil {
-			t.logger.Error("failed to clone repository",
-				zap.String("repo-id", r.RepoID),
-				zap.Error(err),
-			)
-			return err
-		}
-		t.gitRepos[r.RepoID] = repo
+	if err := t.syncRepositories(ctx); err != nil {
+		return err
 	}
 
 	commitTicker := time.NewTicker(time.Duration(t.config.SyncInterval))
@@ -146,6 +148,9 @@ func (t *Trigger) Run(ctx context.Context) error {
 	commandTicker := time.NewTicker(commandCheckInterval)
 	defer commandTicker.Stop()
 
+	scheduleTicker := time.NewTicker(scheduleCheckInterval)
+	defer scheduleTicker.Stop()
+
 L:
 	for {
 		select {
@@ -154,8 +159,16 @@ L:
 			t.checkNewCommands(ctx)
 
 		case <-commitTicker.C:
+			// The repositories may have been changed by reloading the configuration.
+			t.syncRepositories(ctx)
 			t.checkNewCommits(ctx)
 
+		case now := <-scheduleTicker.C:
+			t.checkSchedules(ctx, now)
+
+		case repoID := <-t.webhookCh:
+			t.checkRepositoryNewCommits(ctx, repoID, t.listApplications()[repoID])
+
 		case <-ctx.Done():
 			break L
 		}
@@ -169,6 +182,75 @@ func (t *Trigger) GetLastTriggeredCommitGetter() LastTriggeredCommitGetter {
 	return t.commitStore
 }
 
+// WebhookHandler returns the handler receiving the push events of the git repositories
+// to check their new commits without waiting for the next polling.
+// The payloads are verified by using the given secret.
+func (t *Trigger) WebhookHandler(secret []byte) http.Handler {
+	return &webhookHandler{
+		secret: secret,
+		repos:  t.config.GetRepositories,
+		notify: t.enqueueRepository,
+		logger: t.logger.Named("webhook"),
+	}
+}
+
+// syncRepositories clones the configured repositories which have not been cloned yet
+// and forgets the ones removed from the configuration.
+func (t *Trigger) syncRepositories(ctx context.Context) error {
+	repos := t.config.GetRepositories()
+	configured := make(map[string]struct{}, len(repos))
+
+	var firstErr error
+	for _, r := range repos {
+		configured[r.RepoID] = struct{}{}
+		if cur, ok := t.gitRepoConfigs[r.RepoID]; ok && cur.Remote == r.Remote && cur.Branch == r.Branch {
+			continue
+		}
+		repo, err := t.gitClient.Clone(ctx, r.RepoID, r.Remote, r.Branch, "")
+		if err != nil {
+			t.logger.Error("failed to clone repository",
+				zap.String("repo-id", r.RepoID),
+				zap.Error(err),
+			)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		t.removeRepository(r.RepoID)
+		t.gitRepos[r.RepoID] = repo
+		t.gitRepoConfigs[r.RepoID] = r
+	}
+
+	for id := range t.gitRepos {
+		if _, ok := configured[id]; !ok {
+			t.logger.Info("forget the repository removed from the configuration", zap.String("repo-id", id))
+			t.removeRepository(id)
+		}
+	}
+	return firstErr
+}
+
+func (t *Trigger) removeRepository(repoID string) {
+	repo, ok := t.gitRepos[repoID]
+	if !ok {
+		return
+	}
+	if err := repo.Clean(); err != nil {
+		t.logger.Warn("failed to clean repository", zap.String("repo-id", repoID), zap.Error(err))
+	}
+	delete(t.gitRepos, repoID)
+	delete(t.gitRepoConfigs, repoID)
+}
+
+func (t *Trigger) enqueueRepository(repoID string) {
+	select {
+	case t.webhookCh <- repoID:
+	default:
+		t.logger.Warn("too many repositories are waiting to be checked, it will be checked at the next polling", zap.String("repo-id", repoID))
+	}
+}
+
 func (t *Trigger) checkNewCommands(ctx context.Context) error {
 	commands := t.commandLister.ListApplicationCommands()
 
@@ -188,7 +270,7 @@ func (t *Trigger) checkNewCommands(ctx context.Context) error {
 			continue
 		}
 
-		d, err := t.syncApplication(ctx, app, cmd.Commander, syncCmd.SyncStrategy)
+		d, err := t.syncApplication(ctx, app, cmd.Commander, syncCmd.SyncStrategy, syncCmd.DeploymentChain)
 		if err != nil {
 			t.logger.Error("failed to sync application",
 				zap.String("app-id", app.Id),
@@ -223,37 +305,125 @@ func (t *Trigger) checkNewCommits(ctx context.Context) error {
 
 	// ENHANCEMENT: We may want to apply worker model here to run them concurrently.
 	for repoID, apps := range applications {
-		gitRepo, branch, headCommit, err := t.updateRepoToLatest(ctx, repoID)
+		t.checkRepositoryNewCommits(ctx, repoID, apps)
+	}
+
+	return nil
+}
+
+// checkRepositoryNewCommits triggers the deployments of the given applications
+// placed in the given repository if they were touched by the new commits.
+func (t *Trigger) checkRepositoryNewCommits(ctx context.Context, repoID string, apps []*model.Application) {
+	if len(apps) == 0 {
+		return
+	}
+	gitRepo, branch, headCommit, err := t.updateRepoToLatest(ctx, repoID)
+	if err != nil {
+		return
+	}
+	d := NewDeterminer(gitRepo, headCommit.Hash, t.commitStore, t.logger)
+
+	for _, app := range apps {
+		shouldTrigger, commitHash, err := d.ShouldTrigger(ctx, app)
 		if err != nil {
+			t.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
 			continue
 		}
-		d := NewDeterminer(gitRepo, headCommit.Hash, t.commitStore, t.logger)
 
+		if !shouldTrigger {
+			if commitHash != "" {
+				t.commitStore.Put(app.Id, commitHash)
+			}
+			continue
+		}
+
+		// The application triggered by tags may be deployed at an older commit than the head one.
+		commit := headCommit
+		if commitHash != headCommit.Hash {
+			if commit, err = getCommit(ctx, gitRepo, commitHash); err != nil {
+				t.logger.Error(fmt.Sprintf("failed to get commit %s of application: %s", commitHash, app.Id), zap.Error(err))
+				continue
+			}
+		}
+
+		// Build deployment model and send a request to API to create a new deployment.
+		t.logger.Info("application should be synced because of the new commit")
+		if _, err := t.triggerDeployment(ctx, app, branch, commit, "", model.SyncStrategy_AUTO, model.DeploymentTriggerKind_TRIGGER_COMMIT, nil); err != nil {
+			t.logger.Error(fmt.Sprintf("failed to trigger application: %s", app.Id), zap.Error(err))
+		}
+		t.commitStore.Put(app.Id, commit.Hash)
+	}
+}
+
+func getCommit(ctx context.Context, repo git.Repo, hash string) (git.Commit, error) {
+	// The revision range "<hash>^!" includes only the given commit.
+	commits, err := repo.ListCommits(ctx, hash+"^!")
+	if err != nil {
+		return git.Commit{}, err
+	}
+	if len(commits) != 1 {
+		return git.Commit{}, fmt.Errorf("commits must contain one item, got: %d", len(commits))
+	}
+	return commits[0], nil
+}
+
+// checkSchedules triggers the deployments of the applications whose schedules have come
+// to sync them to the last deployed commit again.
+func (t *Trigger) checkSchedules(ctx context.Context, now time.Time) {
+	for repoID, apps := range t.listApplications() {
+		gitRepo, ok := t.gitRepos[repoID]
+		if !ok {
+			continue
+		}
 		for _, app := range apps {
-			shouldTrigger, err := d.ShouldTrigger(ctx, app)
+			deployConfig, err := loadDeploymentConfiguration(gitRepo.GetPath(), app)
 			if err != nil {
-				t.logger.Error(fmt.Sprintf("failed to check application: %s", app.Id), zap.Error(err))
+				t.logger.Error(fmt.Sprintf("failed to load deployment configuration of application: %s", app.Id), zap.Error(err))
 				continue
 			}
-
-			if !shouldTrigger {
-				t.commitStore.Put(app.Id, headCommit.Hash)
+			if deployConfig.Trigger == nil || deployConfig.Trigger.Schedule == "" {
+				t.scheduleChecker.forget(app.Id)
 				continue
 			}
 
-			// Build deployment model and send a request to API to create a new deployment.
-			t.logger.Info("application should be synced because of the new commit")
-			if _, err := t.triggerDeployment(ctx, app, branch, headCommit, "", model.SyncStrategy_AUTO); err != nil {
-				t.logger.Error(fmt.Sprintf("failed to trigger application: %s", app.Id), zap.Error(err))
+			due, err := t.scheduleChecker.due(app.Id, deployConfig.Trigger.Schedule, now)
+			if err != nil {
+				t.logger.Error(fmt.Sprintf("invalid schedule of application: %s", app.Id), zap.Error(err))
+				continue
+			}
+			if !due {
+				continue
+			}
+			if err := t.syncScheduledApplication(ctx, gitRepo, app); err != nil {
+				t.logger.Error(fmt.Sprintf("failed to sync scheduled application: %s", app.Id), zap.Error(err))
 			}
-			t.commitStore.Put(app.Id, headCommit.Hash)
 		}
 	}
+}
 
-	return nil
+func (t *Trigger) syncScheduledApplication(ctx context.Context, gitRepo git.Repo, app *model.Application) error {
+	commitHash, err := t.commitStore.Get(ctx, app.Id)
+	if err != nil {
+		return err
+	}
+	// The application will be deployed by the new commits checking.
+	if commitHash == "" {
+		t.logger.Info(fmt.Sprintf("skipped the scheduled sync of application %s because it has never been deployed", app.Id))
+		return nil
+	}
+	commit, err := getCommit(ctx, gitRepo, commitHash)
+	if err != nil {
+		return err
+	}
+
+	t.logger.Info(fmt.Sprintf("application %s will be synced because of its schedule", app.Id),
+		zap.String("commit-hash", commit.Hash),
+	)
+	_, err = t.triggerDeployment(ctx, app, gitRepo.GetClonedBranch(), commit, "", model.SyncStrategy_AUTO, model.DeploymentTriggerKind_TRIGGER_SCHEDULED, nil)
+	return err
 }
 
-func (t *Trigger) syncApplication(ctx context.Context, app *model.Application, commander string, syncStrategy model.SyncStrategy) (*model.Deployment, error) {
+func (t *Trigger) syncApplication(ctx context.Context, app *model.Application, commander string, syncStrategy model.SyncStrategy, chain *model.DeploymentChainRef) (*model.Deployment, error) {
 	_, branch, headCommit, err := t.updateRepoToLatest(ctx, app.GitPath.Repo.Id)
 	if err != nil {
 		return nil, err
@@ -263,7 +433,7 @@ func (t *Trigger) syncApplication(ctx context.Context, app *model.Application, c
 	t.logger.Info(fmt.Sprintf("application %s will be synced because of a sync command", app.Id),
 		zap.String("head-commit", headCommit.Hash),
 	)
-	d, err := t.triggerDeployment(ctx, app, branch, headCommit, commander, syncStrategy)
+	d, err := t.triggerDeployment(ctx, app, branch, headCommit, commander, syncStrategy, model.DeploymentTriggerKind_TRIGGER_COMMAND, chain)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,94 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+type fakeRepo struct {
+	git.Repo
+	remote  string
+	cleaned bool
+}
+
+func (r *fakeRepo) Clean() error {
+	r.cleaned = true
+	return nil
+}
+
+type fakeGitClient struct {
+	clones int
+}
+
+func (c *fakeGitClient) Clone(_ context.Context, repoID, remote, branch, destination string) (git.Repo, error) {
+	if remote == "" {
+		return nil, errors.New("remote is required")
+	}
+	c.clones++
+	return &fakeRepo{remote: remote}, nil
+}
+
+func TestSyncRepositories(t *testing.T) {
+	cfg := &config.PipedSpec{
+		Repositories: []config.PipedRepository{
+			{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "master"},
+			{RepoID: "repo-2", Remote: "git@github.com:org/repo-2.git", Branch: "master"},
+		},
+	}
+	gc := &fakeGitClient{}
+	tr := &Trigger{
+		gitClient:      gc,
+		config:         cfg,
+		gitRepos:       make(map[string]git.Repo),
+		gitRepoConfigs: make(map[string]config.PipedRepository),
+		logger:         zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	require.NoError(t, tr.syncRepositories(ctx))
+	assert.Equal(t, 2, gc.clones)
+	repo1, repo2 := tr.gitRepos["repo-1"].(*fakeRepo), tr.gitRepos["repo-2"].(*fakeRepo)
+
+	// Nothing is cloned again if the repositories have not been changed.
+	require.NoError(t, tr.syncRepositories(ctx))
+	assert.Equal(t, 2, gc.clones)
+
+	// Reload the repositories: repo-1 is unchanged, repo-2 is removed and repo-3 is added.
+	cfg.Reload(&config.PipedSpec{
+		Repositories: []config.PipedRepository{
+			{RepoID: "repo-1", Remote: "git@github.com:org/repo-1.git", Branch: "master"},
+			{RepoID: "repo-3", Remote: "git@github.com:org/repo-3.git", Branch: "main"},
+			{RepoID: "repo-4"},
+		},
+	})
+	err := tr.syncRepositories(ctx)
+	require.Error(t, err)
+	assert.Equal(t, 3, gc.clones)
+	assert.Len(t, tr.gitRepos, 2)
+	assert.Same(t, repo1, tr.gitRepos["repo-1"])
+	assert.False(t, repo1.cleaned)
+	assert.True(t, repo2.cleaned)
+	assert.Equal(t, "git@github.com:org/repo-3.git", tr.gitRepos["repo-3"].(*fakeRepo).remote)
+}
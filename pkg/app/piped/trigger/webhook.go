@@ -0,0 +1,289 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/git"
+)
+
+const (
+	// The maximum size of the webhook payload, same as GitHub.
+	maxWebhookPayloadSize = 25 * 1024 * 1024
+
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
+// pushEvent represents a push to a git repository notified by webhook.
+type pushEvent struct {
+	// The URLs of the pushed repository.
+	remotes []string
+	// The names of the pushed branches.
+	branches []string
+	// Whether any tag was pushed.
+	tagged bool
+}
+
+// webhookHandler receives the push events from GitHub, GitLab and Bitbucket
+// to check the new commits of the pushed repositories immediately.
+type webhookHandler struct {
+	secret []byte
+	repos  func() []config.PipedRepository
+	notify func(repoID string)
+	logger *zap.Logger
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		event    *pushEvent
+		verified bool
+	)
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		verified = verifySignature(h.secret, payload, r.Header.Get("X-Hub-Signature-256"))
+		if verified && r.Header.Get("X-GitHub-Event") == "push" {
+			event, err = parseGitHubPushEvent(payload)
+		}
+	case r.Header.Get("X-Gitlab-Event") != "":
+		verified = verifyToken(h.secret, r.Header.Get("X-Gitlab-Token"))
+		if verified && r.Header.Get("X-Gitlab-Event") == "Push Hook" {
+			event, err = parseGitLabPushEvent(payload)
+		}
+	case r.Header.Get("X-Event-Key") != "":
+		verified = verifySignature(h.secret, payload, r.Header.Get("X-Hub-Signature"))
+		switch key := r.Header.Get("X-Event-Key"); {
+		case !verified:
+		case key == "repo:push":
+			event, err = parseBitbucketCloudPushEvent(payload)
+		case key == "repo:refs_changed":
+			event, err = parseBitbucketServerPushEvent(payload)
+		}
+	default:
+		http.Error(w, "unsupported webhook", http.StatusBadRequest)
+		return
+	}
+
+	if !verified {
+		h.logger.Warn("received a webhook with invalid signature", zap.String("remote-addr", r.RemoteAddr))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		h.logger.Warn("received an invalid webhook payload", zap.Error(err))
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	// The other events such as ping are just accepted.
+	if event == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	for _, repoID := range h.matchRepositories(event) {
+		h.logger.Info("received a push event of repository", zap.String("repo-id", repoID))
+		h.notify(repoID)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// matchRepositories returns the IDs of the repositories whose branches or tags were pushed.
+func (h *webhookHandler) matchRepositories(event *pushEvent) []string {
+	var ids []string
+	for _, repo := range h.repos() {
+		if !event.tagged && !containsString(event.branches, repo.Branch) {
+			continue
+		}
+		for _, remote := range event.remotes {
+			if git.IsSameRepository(repo.Remote, remote) {
+				ids = append(ids, repo.RepoID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// verifyToken verifies the token sent as is.
+// Both of the secret and the token must not be empty.
+func verifyToken(secret []byte, token string) bool {
+	if len(secret) == 0 || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare(secret, []byte(token)) == 1
+}
+
+// verifySignature verifies the HMAC-SHA256 signature of the payload
+// in the format of "sha256=<hex digest>".
+func verifySignature(secret, payload []byte, signature string) bool {
+	// Anyone can compute the signature with an empty secret.
+	if len(secret) == 0 {
+		return false
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func parseGitHubPushEvent(payload []byte) (*pushEvent, error) {
+	var p struct {
+		Ref        string `json:"ref"`
+		Repository struct {
+			CloneURL string `json:"clone_url"`
+			SSHURL   string `json:"ssh_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	return &pushEvent{
+		remotes:  []string{p.Repository.CloneURL, p.Repository.SSHURL},
+		branches: branchNames(p.Ref),
+		tagged:   strings.HasPrefix(p.Ref, tagRefPrefix),
+	}, nil
+}
+
+func parseGitLabPushEvent(payload []byte) (*pushEvent, error) {
+	var p struct {
+		Ref     string `json:"ref"`
+		Project struct {
+			GitHTTPURL string `json:"git_http_url"`
+			GitSSHURL  string `json:"git_ssh_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	return &pushEvent{
+		remotes:  []string{p.Project.GitHTTPURL, p.Project.GitSSHURL},
+		branches: branchNames(p.Ref),
+		tagged:   strings.HasPrefix(p.Ref, tagRefPrefix),
+	}, nil
+}
+
+func parseBitbucketCloudPushEvent(payload []byte) (*pushEvent, error) {
+	var p struct {
+		Push struct {
+			Changes []struct {
+				New *struct {
+					Type string `json:"type"`
+					Name string `json:"name"`
+				} `json:"new"`
+			} `json:"changes"`
+		} `json:"push"`
+		Repository struct {
+			Links struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	e := &pushEvent{
+		remotes: []string{p.Repository.Links.HTML.Href},
+	}
+	for _, c := range p.Push.Changes {
+		// The deleted branches have no new state.
+		if c.New == nil {
+			continue
+		}
+		switch c.New.Type {
+		case "branch":
+			e.branches = append(e.branches, c.New.Name)
+		case "tag":
+			e.tagged = true
+		}
+	}
+	return e, nil
+}
+
+func parseBitbucketServerPushEvent(payload []byte) (*pushEvent, error) {
+	var p struct {
+		Changes []struct {
+			RefID string `json:"refId"`
+			Type  string `json:"type"`
+		} `json:"changes"`
+		Repository struct {
+			Links struct {
+				Clone []struct {
+					Href string `json:"href"`
+				} `json:"clone"`
+			} `json:"links"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, err
+	}
+	e := &pushEvent{}
+	for _, c := range p.Repository.Links.Clone {
+		e.remotes = append(e.remotes, c.Href)
+	}
+	for _, c := range p.Changes {
+		if c.Type == "DELETE" {
+			continue
+		}
+		e.branches = append(e.branches, branchNames(c.RefID)...)
+		if strings.HasPrefix(c.RefID, tagRefPrefix) {
+			e.tagged = true
+		}
+	}
+	return e, nil
+}
+
+// branchNames returns the branch name of the given ref, or nothing if it is not a branch.
+func branchNames(ref string) []string {
+	if !strings.HasPrefix(ref, branchRefPrefix) {
+		return nil
+	}
+	return []string{strings.TrimPrefix(ref, branchRefPrefix)}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
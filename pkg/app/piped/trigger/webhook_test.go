@@ -0,0 +1,222 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trigger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/pipe-cd/pipe/pkg/config"
+)
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookHandler(t *testing.T) {
+	const (
+		secret        = "secret"
+		githubPayload = `{"ref":"refs/heads/master","repository":{"clone_url":"https://github.com/org/repo1.git","ssh_url":"git@github.com:org/repo1.git"}}`
+		githubTag     = `{"ref":"refs/tags/v1.0.0","repository":{"clone_url":"https://github.com/org/repo1.git","ssh_url":"git@github.com:org/repo1.git"}}`
+		gitlabPayload = `{"ref":"refs/heads/main","project":{"git_http_url":"https://gitlab.com/org/repo2.git","git_ssh_url":"git@gitlab.com:org/repo2.git"}}`
+		cloudPayload  = `{"push":{"changes":[{"new":{"type":"branch","name":"master"}},{"new":null}]},"repository":{"links":{"html":{"href":"https://bitbucket.org/org/repo3"}}}}`
+		serverPayload = `{"changes":[{"refId":"refs/heads/master","type":"UPDATE"}],"repository":{"links":{"clone":[{"href":"ssh://git@bitbucket.example.com:7999/org/repo4.git"}]}}}`
+	)
+	repos := []config.PipedRepository{
+		{RepoID: "repo1", Remote: "git@github.com:org/repo1.git", Branch: "master"},
+		{RepoID: "repo1-dev", Remote: "git@github.com:org/repo1.git", Branch: "dev"},
+		{RepoID: "repo2", Remote: "git@gitlab.com:org/repo2.git", Branch: "main"},
+		{RepoID: "repo3", Remote: "git@bitbucket.org:org/repo3.git", Branch: "master"},
+		{RepoID: "repo4", Remote: "ssh://git@bitbucket.example.com:7999/org/repo4.git", Branch: "master"},
+	}
+
+	testcases := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		payload string
+		// Whether the handler is created with an empty secret.
+		emptySecret bool
+		wantStatus  int
+		wantRepos   []string
+	}{
+		{
+			name: "github push",
+			headers: map[string]string{
+				"X-GitHub-Event":      "push",
+				"X-Hub-Signature-256": sign(secret, githubPayload),
+			},
+			payload:    githubPayload,
+			wantStatus: http.StatusAccepted,
+			wantRepos:  []string{"repo1"},
+		},
+		{
+			name: "github tag push",
+			headers: map[string]string{
+				"X-GitHub-Event":      "push",
+				"X-Hub-Signature-256": sign(secret, githubTag),
+			},
+			payload:    githubTag,
+			wantStatus: http.StatusAccepted,
+			wantRepos:  []string{"repo1", "repo1-dev"},
+		},
+		{
+			name: "github ping",
+			headers: map[string]string{
+				"X-GitHub-Event":      "ping",
+				"X-Hub-Signature-256": sign(secret, `{}`),
+			},
+			payload:    `{}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name: "github push with invalid signature",
+			headers: map[string]string{
+				"X-GitHub-Event":      "push",
+				"X-Hub-Signature-256": sign("wrong", githubPayload),
+			},
+			payload:    githubPayload,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "gitlab push",
+			headers: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": secret,
+			},
+			payload:    gitlabPayload,
+			wantStatus: http.StatusAccepted,
+			wantRepos:  []string{"repo2"},
+		},
+		{
+			name: "gitlab push with invalid token",
+			headers: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": "wrong",
+			},
+			payload:    gitlabPayload,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "gitlab push without token",
+			headers: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+			},
+			payload:    gitlabPayload,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "gitlab push with empty secret",
+			headers: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": "",
+			},
+			emptySecret: true,
+			payload:     gitlabPayload,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name: "github push with empty secret",
+			headers: map[string]string{
+				"X-GitHub-Event":      "push",
+				"X-Hub-Signature-256": sign("", githubPayload),
+			},
+			emptySecret: true,
+			payload:     githubPayload,
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name: "bitbucket cloud push",
+			headers: map[string]string{
+				"X-Event-Key":     "repo:push",
+				"X-Hub-Signature": sign(secret, cloudPayload),
+			},
+			payload:    cloudPayload,
+			wantStatus: http.StatusAccepted,
+			wantRepos:  []string{"repo3"},
+		},
+		{
+			name: "bitbucket server push",
+			headers: map[string]string{
+				"X-Event-Key":     "repo:refs_changed",
+				"X-Hub-Signature": sign(secret, serverPayload),
+			},
+			payload:    serverPayload,
+			wantStatus: http.StatusAccepted,
+			wantRepos:  []string{"repo4"},
+		},
+		{
+			name: "malformed payload",
+			headers: map[string]string{
+				"X-GitHub-Event":      "push",
+				"X-Hub-Signature-256": sign(secret, "{"),
+			},
+			payload:    "{",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown webhook",
+			payload:    githubPayload,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not post",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var notified []string
+			s := []byte(secret)
+			if tc.emptySecret {
+				s = nil
+			}
+			h := &webhookHandler{
+				secret: s,
+				repos: func() []config.PipedRepository {
+					return repos
+				},
+				notify: func(repoID string) {
+					notified = append(notified, repoID)
+				},
+				logger: zap.NewNop(),
+			}
+			method := tc.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, "/webhook", bytes.NewBufferString(tc.payload))
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantRepos, notified)
+		})
+	}
+}
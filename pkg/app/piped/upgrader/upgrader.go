@@ -0,0 +1,360 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package upgrader provides a piped component
+// that upgrades piped to the version desired by the control plane.
+// It stops planning the new deployments, waits for the in-flight ones to be completed,
+// downloads the binary of the desired version and asks piped to restart itself with it.
+package upgrader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+// ErrRestartRequired is returned by Run when the binary of the desired version
+// has been downloaded and piped must be restarted with it.
+var ErrRestartRequired = errors.New("piped must be restarted to complete the upgrade")
+
+// The semantic version piped can be upgraded to, e.g. v0.10.0 or v0.10.0-rc.1.
+// The desired version must match this since it is used in the download URLs and the binary path.
+var versionRegex = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?$`)
+
+type apiClient interface {
+	GetDesiredVersion(ctx context.Context, req *pipedservice.GetDesiredVersionRequest, opts ...grpc.CallOption) (*pipedservice.GetDesiredVersionResponse, error)
+	ReportUpgradeStatus(ctx context.Context, req *pipedservice.ReportUpgradeStatusRequest, opts ...grpc.CallOption) (*pipedservice.ReportUpgradeStatusResponse, error)
+}
+
+type drainer interface {
+	Drain(ctx context.Context) error
+	Undrain()
+}
+
+type Upgrader struct {
+	apiClient      apiClient
+	drainer        drainer
+	config         *config.PipedUpgrade
+	currentVersion string
+	httpClient     *http.Client
+	nowFunc        func() time.Time
+
+	// The desired version failed to upgrade to.
+	// It is not retried until another version is desired.
+	failedVersion string
+	// The path to the downloaded binary piped should restart with.
+	binaryPath string
+	logger     *zap.Logger
+}
+
+// NewUpgrader creates a new Upgrader for the piped running the given version.
+func NewUpgrader(apiClient apiClient, drainer drainer, cfg *config.PipedUpgrade, currentVersion string, logger *zap.Logger) *Upgrader {
+	return &Upgrader{
+		apiClient:      apiClient,
+		drainer:        drainer,
+		config:         cfg,
+		currentVersion: currentVersion,
+		httpClient:     http.DefaultClient,
+		nowFunc:        time.Now,
+		logger:         logger.Named("upgrader"),
+	}
+}
+
+// Run checks the desired version periodically until the specified context has done.
+// ErrRestartRequired is returned once the upgrade is ready.
+func (u *Upgrader) Run(ctx context.Context) error {
+	u.logger.Info("start running upgrader")
+
+	// Complete the upgrade which made piped restart.
+	u.reportRestarted(ctx)
+
+	ticker := time.NewTicker(u.config.GetCheckInterval())
+	defer ticker.Stop()
+
+L:
+	for {
+		select {
+		case <-ctx.Done():
+			break L
+
+		case <-ticker.C:
+			if u.check(ctx) {
+				u.logger.Info(fmt.Sprintf("piped is going to restart with %s", u.binaryPath))
+				return ErrRestartRequired
+			}
+		}
+	}
+
+	u.logger.Info("upgrader has been stopped")
+	return nil
+}
+
+// BinaryPath returns the path to the downloaded binary piped should restart with.
+func (u *Upgrader) BinaryPath() string {
+	return u.binaryPath
+}
+
+// Restart replaces the current process with the given binary
+// keeping the same arguments and environment variables.
+func Restart(binaryPath string) error {
+	args := append([]string{binaryPath}, os.Args[1:]...)
+	return syscall.Exec(binaryPath, args, os.Environ())
+}
+
+// RunWithRestart calls run and then restarts with the binary returned by it, if any.
+// Since the process is replaced only after run has returned, all the functions
+// deferred by run, such as closing connections and removing the working
+// directories, have been completed before restarting.
+func RunWithRestart(run func() (binaryPath string, err error), restart func(binaryPath string) error) error {
+	binaryPath, err := run()
+	if err != nil || binaryPath == "" {
+		return err
+	}
+	return restart(binaryPath)
+}
+
+// reportRestarted reports the result of the upgrade
+// when piped was restarted by the upgrader.
+func (u *Upgrader) reportRestarted(ctx context.Context) {
+	resp, err := u.apiClient.GetDesiredVersion(ctx, &pipedservice.GetDesiredVersionRequest{})
+	if err != nil {
+		u.logger.Error("failed to get the desired version", zap.Error(err))
+		return
+	}
+	s := resp.UpgradeStatus
+	if s == nil || s.State != model.PipedUpgradeState_UPGRADE_RESTARTING {
+		return
+	}
+	if s.Version == u.currentVersion {
+		u.logger.Info(fmt.Sprintf("successfully upgraded piped to %s", u.currentVersion))
+		u.report(ctx, s.Version, model.PipedUpgradeState_UPGRADE_SUCCEEDED, "")
+		return
+	}
+	u.report(ctx, s.Version, model.PipedUpgradeState_UPGRADE_FAILED,
+		fmt.Sprintf("piped was restarted with %s instead of %s", u.currentVersion, s.Version))
+}
+
+// check upgrades piped when the desired version is different from the running one.
+// It returns true when piped must be restarted to complete the upgrade.
+func (u *Upgrader) check(ctx context.Context) bool {
+	resp, err := u.apiClient.GetDesiredVersion(ctx, &pipedservice.GetDesiredVersionRequest{})
+	if err != nil {
+		u.logger.Error("failed to get the desired version", zap.Error(err))
+		return false
+	}
+	version := resp.DesiredVersion
+	if version == "" || version == u.currentVersion || version == u.failedVersion {
+		return false
+	}
+	if !versionRegex.MatchString(version) {
+		u.logger.Error("desired version is not a valid semantic version", zap.String("version", version))
+		u.failedVersion = version
+		u.report(ctx, version, model.PipedUpgradeState_UPGRADE_FAILED, fmt.Sprintf("%s is not a valid semantic version", version))
+		return false
+	}
+
+	u.logger.Info(fmt.Sprintf("start upgrading piped from %s to %s", u.currentVersion, version))
+	if err := u.upgrade(ctx, version); err != nil {
+		// Piped is being stopped, the upgrade will be retried after starting again.
+		if ctx.Err() != nil {
+			return false
+		}
+		u.logger.Error("failed to upgrade piped", zap.String("version", version), zap.Error(err))
+		u.failedVersion = version
+		u.report(ctx, version, model.PipedUpgradeState_UPGRADE_FAILED, err.Error())
+		return false
+	}
+	return true
+}
+
+func (u *Upgrader) upgrade(ctx context.Context, version string) error {
+	u.report(ctx, version, model.PipedUpgradeState_UPGRADE_DRAINING, "")
+	timeout := u.config.GetDrainTimeout()
+	dctx, cancel := context.WithTimeout(ctx, timeout)
+	err := u.drainer.Drain(dctx)
+	cancel()
+	if err != nil {
+		u.drainer.Undrain()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("the in-flight deployments were not completed in %v", timeout)
+	}
+
+	u.report(ctx, version, model.PipedUpgradeState_UPGRADE_DOWNLOADING, "")
+	path, err := u.download(ctx, version)
+	if err != nil {
+		u.drainer.Undrain()
+		return fmt.Errorf("failed to download piped %s: %w", version, err)
+	}
+	u.binaryPath = path
+
+	u.report(ctx, version, model.PipedUpgradeState_UPGRADE_RESTARTING, "")
+	return nil
+}
+
+// download writes the binary of the given version into the binary directory
+// and returns its path. The binary is not stored unless its checksum matches.
+func (u *Upgrader) download(ctx context.Context, version string) (string, error) {
+	binaryURL, err := renderURL(u.config.BinaryURL, version)
+	if err != nil {
+		return "", err
+	}
+	checksumURL, err := renderURL(u.config.ChecksumURL, version)
+	if err != nil {
+		return "", err
+	}
+	checksum, err := u.fetchChecksum(ctx, checksumURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum from %s: %w", checksumURL, err)
+	}
+
+	dir := u.config.BinaryDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	body, err := u.get(ctx, binaryURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", binaryURL, err)
+	}
+	defer body.Close()
+
+	// Write into a temporary file first to not leave the broken binary.
+	f, err := ioutil.TempFile(dir, ".piped-download")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), body); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, checksum) {
+		return "", fmt.Errorf("checksum mismatch: expected %s but got %s", checksum, got)
+	}
+	if err := os.Chmod(f.Name(), 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "piped-"+version)
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// fetchChecksum returns the checksum in the given file of the sha256sum format.
+// The first one is used when the file lists the checksums of multiple files.
+func (u *Upgrader) fetchChecksum(ctx context.Context, url string) (string, error) {
+	body, err := u.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", errors.New("checksum file is empty")
+	}
+	return fields[0], nil
+}
+
+func (u *Upgrader) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// report sends the upgrade status to the control plane.
+// The failures are just logged since they do not affect the upgrade.
+func (u *Upgrader) report(ctx context.Context, version string, state model.PipedUpgradeState, reason string) {
+	req := &pipedservice.ReportUpgradeStatusRequest{
+		UpgradeStatus: &model.PipedUpgradeStatus{
+			Version:   version,
+			State:     state,
+			Reason:    reason,
+			UpdatedAt: u.nowFunc().Unix(),
+		},
+	}
+	if _, err := u.apiClient.ReportUpgradeStatus(ctx, req); err != nil {
+		u.logger.Error("failed to report upgrade status",
+			zap.String("version", version),
+			zap.String("state", state.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+func renderURL(tmpl, version string) (string, error) {
+	t, err := template.New("url").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL template: %w", err)
+	}
+	var (
+		buf  bytes.Buffer
+		data = map[string]string{
+			"Version": version,
+			"Os":      runtime.GOOS,
+			"Arch":    runtime.GOARCH,
+		}
+	)
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("invalid URL template: %w", err)
+	}
+	return buf.String(), nil
+}
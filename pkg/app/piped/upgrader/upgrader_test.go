@@ -0,0 +1,318 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upgrader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/pipe-cd/pipe/pkg/app/api/service/pipedservice"
+	"github.com/pipe-cd/pipe/pkg/config"
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+type fakeAPIClient struct {
+	desiredVersion string
+	upgradeStatus  *model.PipedUpgradeStatus
+	reported       []model.PipedUpgradeState
+}
+
+func (c *fakeAPIClient) GetDesiredVersion(_ context.Context, _ *pipedservice.GetDesiredVersionRequest, _ ...grpc.CallOption) (*pipedservice.GetDesiredVersionResponse, error) {
+	return &pipedservice.GetDesiredVersionResponse{
+		DesiredVersion: c.desiredVersion,
+		UpgradeStatus:  c.upgradeStatus,
+	}, nil
+}
+
+func (c *fakeAPIClient) ReportUpgradeStatus(_ context.Context, req *pipedservice.ReportUpgradeStatusRequest, _ ...grpc.CallOption) (*pipedservice.ReportUpgradeStatusResponse, error) {
+	c.reported = append(c.reported, req.UpgradeStatus.State)
+	return &pipedservice.ReportUpgradeStatusResponse{}, nil
+}
+
+type fakeDrainer struct {
+	err      error
+	draining bool
+}
+
+func (d *fakeDrainer) Drain(_ context.Context) error {
+	d.draining = true
+	return d.err
+}
+
+func (d *fakeDrainer) Undrain() {
+	d.draining = false
+}
+
+func TestCheck(t *testing.T) {
+	const binary = "piped binary"
+	sum := sha256.Sum256([]byte(binary))
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v0.10.0/piped":
+			fmt.Fprint(w, binary)
+		case "/v0.10.0/piped.sha256", "/v0.11.0/piped.sha256":
+			fmt.Fprintf(w, "%s  piped\n", checksum)
+		case "/v0.10.0/piped.wrong-sha256":
+			fmt.Fprint(w, "0123456789abcdef  piped\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testcases := []struct {
+		name           string
+		desiredVersion string
+		checksumPath   string
+		drainErr       error
+		wantRestart    bool
+		wantDraining   bool
+		wantReported   []model.PipedUpgradeState
+	}{
+		{
+			name:           "no version is desired",
+			desiredVersion: "",
+		},
+		{
+			name:           "already running the desired version",
+			desiredVersion: "v0.9.0",
+		},
+		{
+			name:           "upgrade to the desired version",
+			desiredVersion: "v0.10.0",
+			wantRestart:    true,
+			wantDraining:   true,
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_DRAINING,
+				model.PipedUpgradeState_UPGRADE_DOWNLOADING,
+				model.PipedUpgradeState_UPGRADE_RESTARTING,
+			},
+		},
+		{
+			name:           "invalid version",
+			desiredVersion: "../../bin/v0.10.0",
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_FAILED,
+			},
+		},
+		{
+			name:           "deployments were not completed",
+			desiredVersion: "v0.10.0",
+			drainErr:       context.DeadlineExceeded,
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_DRAINING,
+				model.PipedUpgradeState_UPGRADE_FAILED,
+			},
+		},
+		{
+			name:           "checksum mismatch",
+			desiredVersion: "v0.10.0",
+			checksumPath:   "/{{ .Version }}/piped.wrong-sha256",
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_DRAINING,
+				model.PipedUpgradeState_UPGRADE_DOWNLOADING,
+				model.PipedUpgradeState_UPGRADE_FAILED,
+			},
+		},
+		{
+			name:           "checksum not found",
+			desiredVersion: "v0.10.0",
+			checksumPath:   "/{{ .Version }}/piped.sha512",
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_DRAINING,
+				model.PipedUpgradeState_UPGRADE_DOWNLOADING,
+				model.PipedUpgradeState_UPGRADE_FAILED,
+			},
+		},
+		{
+			name:           "binary not found",
+			desiredVersion: "v0.11.0",
+			wantReported: []model.PipedUpgradeState{
+				model.PipedUpgradeState_UPGRADE_DRAINING,
+				model.PipedUpgradeState_UPGRADE_DOWNLOADING,
+				model.PipedUpgradeState_UPGRADE_FAILED,
+			},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.PipedUpgrade{
+				BinaryURL:   server.URL + "/{{ .Version }}/piped",
+				ChecksumURL: server.URL + "/{{ .Version }}/piped.sha256",
+				BinaryDir:   dir,
+			}
+			if tc.checksumPath != "" {
+				cfg.ChecksumURL = server.URL + tc.checksumPath
+			}
+			client := &fakeAPIClient{desiredVersion: tc.desiredVersion}
+			drainer := &fakeDrainer{err: tc.drainErr}
+			u := NewUpgrader(client, drainer, cfg, "v0.9.0", zap.NewNop())
+
+			restart := u.check(context.Background())
+			assert.Equal(t, tc.wantRestart, restart)
+			assert.Equal(t, tc.wantDraining, drainer.draining)
+			assert.Equal(t, tc.wantReported, client.reported)
+			if !restart {
+				return
+			}
+
+			assert.Equal(t, filepath.Join(dir, "piped-v0.10.0"), u.BinaryPath())
+			data, err := ioutil.ReadFile(u.BinaryPath())
+			require.NoError(t, err)
+			assert.Equal(t, binary, string(data))
+			info, err := os.Stat(u.BinaryPath())
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+		})
+	}
+}
+
+func TestCheckDoesNotRetryFailedVersion(t *testing.T) {
+	client := &fakeAPIClient{desiredVersion: "v0.10.0"}
+	drainer := &fakeDrainer{err: errors.New("timeout")}
+	cfg := &config.PipedUpgrade{BinaryURL: "https://example.com/{{ .Version }}/piped"}
+	u := NewUpgrader(client, drainer, cfg, "v0.9.0", zap.NewNop())
+
+	assert.False(t, u.check(context.Background()))
+	assert.False(t, u.check(context.Background()))
+	assert.Equal(t, []model.PipedUpgradeState{
+		model.PipedUpgradeState_UPGRADE_DRAINING,
+		model.PipedUpgradeState_UPGRADE_FAILED,
+	}, client.reported)
+}
+
+func TestReportRestarted(t *testing.T) {
+	testcases := []struct {
+		name          string
+		upgradeStatus *model.PipedUpgradeStatus
+		wantReported  []model.PipedUpgradeState
+	}{
+		{
+			name: "no upgrade status",
+		},
+		{
+			name: "upgrade has been completed before",
+			upgradeStatus: &model.PipedUpgradeStatus{
+				Version: "v0.10.0",
+				State:   model.PipedUpgradeState_UPGRADE_SUCCEEDED,
+			},
+		},
+		{
+			name: "restarted with the desired version",
+			upgradeStatus: &model.PipedUpgradeStatus{
+				Version: "v0.10.0",
+				State:   model.PipedUpgradeState_UPGRADE_RESTARTING,
+			},
+			wantReported: []model.PipedUpgradeState{model.PipedUpgradeState_UPGRADE_SUCCEEDED},
+		},
+		{
+			name: "restarted with another version",
+			upgradeStatus: &model.PipedUpgradeStatus{
+				Version: "v0.11.0",
+				State:   model.PipedUpgradeState_UPGRADE_RESTARTING,
+			},
+			wantReported: []model.PipedUpgradeState{model.PipedUpgradeState_UPGRADE_FAILED},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &fakeAPIClient{upgradeStatus: tc.upgradeStatus}
+			u := NewUpgrader(client, &fakeDrainer{}, &config.PipedUpgrade{}, "v0.10.0", zap.NewNop())
+			u.reportRestarted(context.Background())
+			assert.Equal(t, tc.wantReported, client.reported)
+		})
+	}
+}
+
+func TestRenderURL(t *testing.T) {
+	got, err := renderURL("https://example.com/{{ .Version }}/piped_{{ .Os }}_{{ .Arch }}", "v0.10.0")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/v0.10.0/piped_"+runtime.GOOS+"_"+runtime.GOARCH, got)
+
+	_, err = renderURL("https://example.com/{{ .Unknown }}/piped", "v0.10.0")
+	assert.Error(t, err)
+}
+
+func TestRunWithRestart(t *testing.T) {
+	testcases := []struct {
+		name       string
+		binaryPath string
+		runErr     error
+		restartErr error
+		wantErr    error
+		wantCalls  []string
+	}{
+		{
+			name:      "stopped without upgrade",
+			wantCalls: []string{"run", "cleanup"},
+		},
+		{
+			name:       "restart after cleanup",
+			binaryPath: "/tmp/piped-v0.10.0",
+			wantCalls:  []string{"run", "cleanup", "restart /tmp/piped-v0.10.0"},
+		},
+		{
+			name:       "failed to restart",
+			binaryPath: "/tmp/piped-v0.10.0",
+			restartErr: errors.New("exec format error"),
+			wantErr:    errors.New("exec format error"),
+			wantCalls:  []string{"run", "cleanup", "restart /tmp/piped-v0.10.0"},
+		},
+		{
+			name:       "run failed",
+			binaryPath: "/tmp/piped-v0.10.0",
+			runErr:     errors.New("failed to start"),
+			wantErr:    errors.New("failed to start"),
+			wantCalls:  []string{"run", "cleanup"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			run := func() (string, error) {
+				defer func() {
+					calls = append(calls, "cleanup")
+				}()
+				calls = append(calls, "run")
+				return tc.binaryPath, tc.runErr
+			}
+			restart := func(binaryPath string) error {
+				calls = append(calls, "restart "+binaryPath)
+				return tc.restartErr
+			}
+			err := RunWithRestart(run, restart)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+}
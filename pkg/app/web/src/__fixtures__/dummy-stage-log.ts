@@ -1,13 +1,14 @@
 import { LogBlock, LogSeverity } from "~/modules/stage-logs";
 import { createRandTimes, randomWords } from "./utils";
 
-const logTimes = createRandTimes(3);
+const logTimes = createRandTimes(4);
 
 export const dummyLogBlock: LogBlock.AsObject = {
   index: 0,
   log: randomWords(8),
   severity: LogSeverity.SUCCESS,
   createdAt: logTimes[0].unix(),
+  fields: "",
 };
 
 export const dummyLogBlocks: LogBlock.AsObject[] = [
@@ -17,12 +18,21 @@ export const dummyLogBlocks: LogBlock.AsObject[] = [
     log: randomWords(8),
     severity: LogSeverity.INFO,
     createdAt: logTimes[1].unix(),
+    fields: "",
   },
   {
     index: 2,
     log: randomWords(8),
     severity: LogSeverity.ERROR,
     createdAt: logTimes[2].unix(),
+    fields: "",
+  },
+  {
+    index: 3,
+    log: randomWords(8),
+    severity: LogSeverity.WARNING,
+    createdAt: logTimes[3].unix(),
+    fields: JSON.stringify({ name: randomWords(1) }),
   },
 ];
 
@@ -32,5 +42,6 @@ export function createLogBlockFromObject(o: LogBlock.AsObject): LogBlock {
   block.setLog(o.log);
   block.setSeverity(o.severity);
   block.setCreatedAt(o.createdAt);
+  block.setFields(o.fields);
   return block;
 }
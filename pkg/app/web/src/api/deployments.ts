@@ -6,8 +6,14 @@ import {
   ListDeploymentsResponse,
   CancelDeploymentRequest,
   CancelDeploymentResponse,
+  PauseDeploymentRequest,
+  PauseDeploymentResponse,
+  ResumeDeploymentRequest,
+  ResumeDeploymentResponse,
   ApproveStageRequest,
   ApproveStageResponse,
+  SkipStageRequest,
+  SkipStageResponse,
 } from "pipe/pkg/app/web/api_client/service_pb";
 
 export const getDeployment = ({
@@ -54,6 +60,26 @@ export const cancelDeployment = ({
   return apiRequest(req, apiClient.cancelDeployment);
 };
 
+export const pauseDeployment = ({
+  deploymentId,
+}: PauseDeploymentRequest.AsObject): Promise<
+  PauseDeploymentResponse.AsObject
+> => {
+  const req = new PauseDeploymentRequest();
+  req.setDeploymentId(deploymentId);
+  return apiRequest(req, apiClient.pauseDeployment);
+};
+
+export const resumeDeployment = ({
+  deploymentId,
+}: ResumeDeploymentRequest.AsObject): Promise<
+  ResumeDeploymentResponse.AsObject
+> => {
+  const req = new ResumeDeploymentRequest();
+  req.setDeploymentId(deploymentId);
+  return apiRequest(req, apiClient.resumeDeployment);
+};
+
 export const approveStage = ({
   deploymentId,
   stageId,
@@ -63,3 +89,13 @@ export const approveStage = ({
   req.setStageId(stageId);
   return apiRequest(req, apiClient.approveStage);
 };
+
+export const skipStage = ({
+  deploymentId,
+  stageId,
+}: SkipStageRequest.AsObject): Promise<SkipStageResponse.AsObject> => {
+  const req = new SkipStageRequest();
+  req.setDeploymentId(deploymentId);
+  req.setStageId(stageId);
+  return apiRequest(req, apiClient.skipStage);
+};
@@ -1,6 +1,10 @@
 import userEvent from "@testing-library/user-event";
 import { MemoryRouter } from "react-router-dom";
-import { cancelDeployment, DeploymentStatus } from "~/modules/deployments";
+import {
+  cancelDeployment,
+  DeploymentStatus,
+  pauseDeployment,
+} from "~/modules/deployments";
 import { dummyDeployment } from "~/__fixtures__/dummy-deployment";
 import { dummyEnv } from "~/__fixtures__/dummy-environment";
 import { dummyPiped } from "~/__fixtures__/dummy-piped";
@@ -94,5 +98,21 @@ describe("DeploymentDetail", () => {
         },
       ]);
     });
+
+    it("dispatch pauseDeployment action if click pause button", () => {
+      store.clearActions();
+      userEvent.click(screen.getByRole("button", { name: "Pause" }));
+
+      expect(store.getActions()).toMatchObject([
+        {
+          type: pauseDeployment.pending.type,
+          meta: {
+            arg: {
+              deploymentId: dummyDeployment.id,
+            },
+          },
+        },
+      ]);
+    });
   });
 });
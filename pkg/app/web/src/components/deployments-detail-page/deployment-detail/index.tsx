@@ -1,5 +1,6 @@
 import {
   Box,
+  Button,
   CircularProgress,
   Link,
   makeStyles,
@@ -8,6 +9,8 @@ import {
 } from "@material-ui/core";
 import CancelIcon from "@material-ui/icons/Cancel";
 import OpenInNewIcon from "@material-ui/icons/OpenInNew";
+import PauseIcon from "@material-ui/icons/Pause";
+import PlayArrowIcon from "@material-ui/icons/PlayArrow";
 import dayjs from "dayjs";
 import { FC, memo } from "react";
 import { Link as RouterLink } from "react-router-dom";
@@ -21,7 +24,10 @@ import { useInterval } from "~/hooks/use-interval";
 import {
   cancelDeployment,
   Deployment,
+  isDeploymentPaused,
   isDeploymentRunning,
+  pauseDeployment,
+  resumeDeployment,
   selectById as selectDeploymentById,
   selectDeploymentIsCanceling,
 } from "~/modules/deployments";
@@ -49,11 +55,15 @@ const useStyles = makeStyles((theme) => ({
     flex: 1,
   },
   actionButtons: {
-    color: theme.palette.error.main,
+    display: "flex",
     position: "absolute",
     top: theme.spacing(2),
     right: theme.spacing(2),
   },
+  cancelButton: {
+    color: theme.palette.error.main,
+    marginLeft: theme.spacing(1),
+  },
   statusReason: {
     paddingTop: theme.spacing(1),
     paddingBottom: theme.spacing(1),
@@ -210,23 +220,42 @@ export const DeploymentDetail: FC<DeploymentDetailProps> = memo(
               </table>
             </div>
             {isDeploymentRunning(deployment.status) && (
-              <SplitButton
-                className={classes.actionButtons}
-                options={CANCEL_OPTIONS}
-                label="select merge strategy"
-                onClick={(index) => {
-                  dispatch(
-                    cancelDeployment({
-                      deploymentId,
-                      forceRollback: index === 1,
-                      forceNoRollback: index === 2,
-                    })
-                  );
-                }}
-                startIcon={<CancelIcon />}
-                loading={isCanceling}
-                disabled={isCanceling}
-              />
+              <div className={classes.actionButtons}>
+                {isDeploymentPaused(deployment) ? (
+                  <Button
+                    variant="outlined"
+                    startIcon={<PlayArrowIcon />}
+                    onClick={() => dispatch(resumeDeployment({ deploymentId }))}
+                  >
+                    Resume
+                  </Button>
+                ) : (
+                  <Button
+                    variant="outlined"
+                    startIcon={<PauseIcon />}
+                    onClick={() => dispatch(pauseDeployment({ deploymentId }))}
+                  >
+                    Pause
+                  </Button>
+                )}
+                <SplitButton
+                  className={classes.cancelButton}
+                  options={CANCEL_OPTIONS}
+                  label="select merge strategy"
+                  onClick={(index) => {
+                    dispatch(
+                      cancelDeployment({
+                        deploymentId,
+                        forceRollback: index === 1,
+                        forceNoRollback: index === 2,
+                      })
+                    );
+                  }}
+                  startIcon={<CancelIcon />}
+                  loading={isCanceling}
+                  disabled={isCanceling}
+                />
+              </div>
             )}
           </Box>
         </Box>
@@ -30,18 +30,21 @@ export default {
           logBlocks: [
             {
               createdAt: 0,
+              fields: "",
               index: 0,
               log: "HELLO",
               severity: LogSeverity.SUCCESS,
             },
             {
               createdAt: 0,
+              fields: "",
               index: 1,
               log: "ERROR",
               severity: LogSeverity.ERROR,
             },
             {
               createdAt: 0,
+              fields: "",
               index: 2,
               log: "INFO",
               severity: LogSeverity.INFO,
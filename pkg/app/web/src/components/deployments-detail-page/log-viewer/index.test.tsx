@@ -15,6 +15,7 @@ const dummyLog = {
   logBlocks: [
     {
       createdAt: 0,
+      fields: "",
       index: 0,
       log: "hello world",
       severity: LogSeverity.SUCCESS,
@@ -96,3 +97,49 @@ it("should dispatch clearActiveStage action if click `close log` button", () =>
     },
   ]);
 });
+
+it("should show only the logs of the selected severity", () => {
+  render(<LogViewer />, {
+    initialState: {
+      deployments: {
+        ids: [dummyDeployment.id],
+        entities: {
+          [dummyDeployment.id]: dummyDeployment,
+        },
+      },
+      activeStage: {
+        deploymentId: dummyDeployment.id,
+        name: dummyDeployment.stagesList[0].name,
+        stageId: dummyDeployment.stagesList[0].id,
+      },
+      stageLogs: {
+        [activeStageId]: {
+          ...dummyLog,
+          logBlocks: [
+            ...dummyLog.logBlocks,
+            {
+              createdAt: 0,
+              index: 1,
+              log: "resources were changed outside",
+              severity: LogSeverity.WARNING,
+              fields: JSON.stringify({ kind: "Deployment" }),
+            },
+          ],
+        },
+      },
+    },
+  });
+
+  expect(screen.queryByText("hello world")).toBeInTheDocument();
+  expect(screen.queryByText("kind=Deployment")).toBeInTheDocument();
+
+  userEvent.selectOptions(
+    screen.getByRole("combobox", { name: "filter logs by severity" }),
+    "Warning"
+  );
+
+  expect(screen.queryByText("hello world")).not.toBeInTheDocument();
+  expect(
+    screen.queryByText("resources were changed outside")
+  ).toBeInTheDocument();
+});
@@ -2,6 +2,7 @@ import {
   Divider,
   IconButton,
   makeStyles,
+  TextField,
   Toolbar,
   Typography,
 } from "@material-ui/core";
@@ -13,11 +14,29 @@ import { APP_HEADER_HEIGHT } from "~/components/header";
 import { useAppDispatch, useShallowEqualSelector } from "~/hooks/redux";
 import { clearActiveStage } from "~/modules/active-stage";
 import { isStageRunning, selectById, Stage } from "~/modules/deployments";
-import { selectStageLogById, StageLog } from "~/modules/stage-logs";
+import {
+  LogSeverity,
+  selectStageLogById,
+  StageLog,
+} from "~/modules/stage-logs";
 import { Log } from "./log";
 
 const INITIAL_HEIGHT = 400;
 const TOOLBAR_HEIGHT = 48;
+const ALL_SEVERITIES = "ALL";
+
+type SeverityFilter = LogSeverity | typeof ALL_SEVERITIES;
+
+const SEVERITY_FILTER_OPTIONS: Array<{
+  label: string;
+  value: SeverityFilter;
+}> = [
+  { label: "All", value: ALL_SEVERITIES },
+  { label: "Info", value: LogSeverity.INFO },
+  { label: "Success", value: LogSeverity.SUCCESS },
+  { label: "Warning", value: LogSeverity.WARNING },
+  { label: "Error", value: LogSeverity.ERROR },
+];
 
 function useActiveStageLog(): [Stage | null, StageLog | null] {
   return useShallowEqualSelector<[Stage | null, StageLog | null]>((state) => {
@@ -63,8 +82,12 @@ const useStyles = makeStyles((theme) => ({
   toolbarRight: {
     flex: 1,
     justifyContent: "flex-end",
+    alignItems: "center",
     display: "flex",
   },
+  severityFilter: {
+    marginRight: theme.spacing(1),
+  },
   stageName: {
     fontFamily: theme.typography.fontFamilyMono,
   },
@@ -95,6 +118,9 @@ export const LogViewer: FC = memo(function LogViewer() {
   const [activeStage, stageLog] = useActiveStageLog();
   const dispatch = useAppDispatch();
   const [handlePosY, setHandlePosY] = useState(maxHandlePosY - INITIAL_HEIGHT);
+  const [severityFilter, setSeverityFilter] = useState<SeverityFilter>(
+    ALL_SEVERITIES
+  );
   const logViewHeight = maxHandlePosY - handlePosY;
 
   const handleOnClickClose = (): void => {
@@ -118,6 +144,11 @@ export const LogViewer: FC = memo(function LogViewer() {
     return null;
   }
 
+  const logs =
+    severityFilter === ALL_SEVERITIES
+      ? stageLog.logBlocks
+      : stageLog.logBlocks.filter((log) => log.severity === severityFilter);
+
   return (
     <>
       <Draggable
@@ -143,6 +174,26 @@ export const LogViewer: FC = memo(function LogViewer() {
             </Typography>
           </div>
           <div className={classes.toolbarRight}>
+            <TextField
+              select
+              margin="dense"
+              value={severityFilter}
+              className={classes.severityFilter}
+              SelectProps={{ native: true }}
+              inputProps={{ "aria-label": "filter logs by severity" }}
+              onChange={(e) => {
+                const value = e.target.value;
+                setSeverityFilter(
+                  value === ALL_SEVERITIES ? ALL_SEVERITIES : Number(value)
+                );
+              }}
+            >
+              {SEVERITY_FILTER_OPTIONS.map((option) => (
+                <option key={option.label} value={option.value}>
+                  {option.label}
+                </option>
+              ))}
+            </TextField>
             <IconButton aria-label="close log" onClick={handleOnClickClose}>
               <Close />
             </IconButton>
@@ -151,7 +202,7 @@ export const LogViewer: FC = memo(function LogViewer() {
         <div className={classes.logContainer} style={{ height: logViewHeight }}>
           <Log
             loading={isStageRunning(activeStage.status)}
-            logs={stageLog.logBlocks}
+            logs={logs}
           />
         </div>
       </div>
@@ -1,5 +1,6 @@
 import { Box, makeStyles } from "@material-ui/core";
-import { Error } from "@material-ui/icons";
+import { Error, Warning } from "@material-ui/icons";
+import clsx from "clsx";
 import { FC } from "react";
 import {
   DEFAULT_BACKGROUND_COLOR,
@@ -10,6 +11,7 @@ import {
 } from "~/constants/term-colors";
 import { LogSeverity } from "~/modules/stage-logs";
 import { parseLog } from "~/utils/parse-log";
+import { parseLogFields } from "~/utils/parse-log-fields";
 import dayjs from "dayjs";
 
 const useStyles = makeStyles((theme) => ({
@@ -34,11 +36,20 @@ const useStyles = makeStyles((theme) => ({
     position: "absolute",
     marginLeft: theme.spacing(1),
   },
+  warningIcon: {
+    color: TERM_COLORS[3],
+  },
   timestamp: {
     color: DEFAULT_TEXT_COLOR,
     paddingRight: theme.spacing(1),
     opacity: 0.8,
   },
+  field: {
+    color: DEFAULT_TEXT_COLOR,
+    paddingLeft: theme.spacing(1),
+    opacity: 0.6,
+    whiteSpace: "pre-wrap",
+  },
 }));
 
 export interface LogLineProps {
@@ -46,6 +57,7 @@ export interface LogLineProps {
   body: string;
   severity: LogSeverity;
   createdAt: number;
+  fields?: string;
 }
 
 const TIMESTAMP_FORMAT = "YYYY-MM-DD HH:mm:ss Z";
@@ -55,6 +67,7 @@ export const LogLine: FC<LogLineProps> = ({
   lineNumber,
   severity,
   createdAt,
+  fields = "",
 }) => {
   const classes = useStyles();
 
@@ -63,6 +76,12 @@ export const LogLine: FC<LogLineProps> = ({
       {severity === LogSeverity.ERROR && (
         <Error color="error" fontSize="small" className={classes.icon} />
       )}
+      {severity === LogSeverity.WARNING && (
+        <Warning
+          fontSize="small"
+          className={clsx(classes.icon, classes.warningIcon)}
+        />
+      )}
       <span className={classes.lineNumber}>{lineNumber}</span>
       <span className={classes.timestamp}>{`[${dayjs(createdAt * 1000).format(
         TIMESTAMP_FORMAT
@@ -82,6 +101,11 @@ export const LogLine: FC<LogLineProps> = ({
             {cell.content.split("\\n").join("\n")}
           </span>
         ))}
+        {parseLogFields(fields).map(([key, value]) => (
+          <span key={`log-field-${key}`} className={classes.field}>
+            {`${key}=${value}`}
+          </span>
+        ))}
       </Box>
     </div>
   );
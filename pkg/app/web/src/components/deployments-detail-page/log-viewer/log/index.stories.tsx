@@ -26,21 +26,53 @@ Overview.args = {
     index: i,
     severity: LogSeverity.INFO,
     createdAt: 0,
+    fields: "",
   })),
   loading: false,
 };
 
 export const Severity = Template.bind({});
 Severity.args = {
-  logs: ["Hello, World", "Hello, World", "Hello, World"].map((v, i) => ({
-    log: v,
+  logs: [
+    LogSeverity.INFO,
+    LogSeverity.SUCCESS,
+    LogSeverity.WARNING,
+    LogSeverity.ERROR,
+  ].map((severity, i) => ({
+    log: "Hello, World",
     index: i,
-    severity: i,
+    severity,
     createdAt: 0,
+    fields: "",
   })),
   loading: false,
 };
 
+export const Fields = Template.bind({});
+Fields.args = {
+  logs: [
+    {
+      log: "Applying manifest",
+      index: 0,
+      severity: LogSeverity.INFO,
+      createdAt: 0,
+      fields: JSON.stringify({ kind: "Deployment", name: "simple" }),
+    },
+    {
+      log: "This stage took longer than its estimated duration",
+      index: 1,
+      severity: LogSeverity.WARNING,
+      createdAt: 0,
+      fields: JSON.stringify({
+        duration: "5m12s",
+        estimatedDuration: "3m0s",
+        overruns: 2,
+      }),
+    },
+  ],
+  loading: false,
+};
+
 export const Loading = Template.bind({});
 Loading.args = {
   logs: ["Hello, World", "Hello, World", "Hello, World", "Hello, World"].map(
@@ -49,6 +81,7 @@ Loading.args = {
       index: i,
       severity: LogSeverity.INFO,
       createdAt: 0,
+      fields: "",
     })
   ),
   loading: true,
@@ -71,6 +104,7 @@ WordWrap.args = {
     index: i,
     severity: LogSeverity.INFO,
     createdAt: 0,
+    fields: "",
   })),
   loading: false,
 };
@@ -91,6 +125,7 @@ AnsiCodes.args = {
     index: i,
     severity: LogSeverity.INFO,
     createdAt: 0,
+    fields: "",
   })),
   loading: false,
 };
@@ -122,6 +157,7 @@ AllColors.args = {
     index: i,
     severity: LogSeverity.INFO,
     createdAt: 0,
+    fields: "",
   })),
   loading: false,
 };
@@ -34,6 +34,7 @@ export const Log: FC<LogProps> = memo(function Log({ logs, loading }) {
           body={log.log}
           lineNumber={i + 1}
           createdAt={log.createdAt}
+          fields={log.fields}
         />
       ))}
       {loading && (
@@ -17,7 +17,9 @@ import {
   approveStage,
   Deployment,
   isDeploymentRunning,
+  isStageRunning,
   selectById,
+  skipStage,
   Stage,
   StageStatus,
 } from "~/modules/deployments";
@@ -26,6 +28,7 @@ import { ApprovalStage } from "./approval-stage";
 import { PipelineStage } from "./pipeline-stage";
 
 const WAIT_APPROVAL_NAME = "WAIT_APPROVAL";
+const ROLLBACK_NAME = "ROLLBACK";
 const STAGE_HEIGHT = 56;
 const APPROVED_STAGE_HEIGHT = 66;
 
@@ -157,6 +160,8 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
   );
   const [approveTargetId, setApproveTargetId] = useState<string | null>(null);
   const isOpenApproveDialog = Boolean(approveTargetId);
+  const [skipTargetId, setSkipTargetId] = useState<string | null>(null);
+  const isOpenSkipDialog = Boolean(skipTargetId);
 
   const defaultActiveStage = findDefaultActiveStage(deployment);
   const stages = createStagesForRendering(deployment);
@@ -204,6 +209,13 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
     }
   };
 
+  const handleSkip = (): void => {
+    if (skipTargetId) {
+      dispatch(skipStage({ deploymentId, stageId: skipTargetId }));
+      setSkipTargetId(null);
+    }
+  };
+
   return (
     <Box textAlign="center" overflow="scroll" className={classes.showScrollbar}>
       <Box display="inline-flex">
@@ -255,6 +267,13 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
                         active={isActive}
                         approver={approver}
                         isDeploymentRunning={isRunning}
+                        onSkip={
+                          isRunning &&
+                          isStageRunning(stage.status) &&
+                          stage.name !== ROLLBACK_NAME
+                            ? setSkipTargetId
+                            : undefined
+                        }
                       />
                     )}
                   </div>
@@ -283,6 +302,21 @@ export const Pipeline: FC<PipelineProps> = memo(function Pipeline({
             </Button>
           </DialogActions>
         </Dialog>
+
+        <Dialog open={isOpenSkipDialog} onClose={() => setSkipTargetId(null)}>
+          <DialogTitle>Skip stage</DialogTitle>
+          <DialogContent>
+            <DialogContentText>
+              {`The stage will be stopped and marked as skipped, then the next stages will be executed. To continue, click "SKIP".`}
+            </DialogContentText>
+          </DialogContent>
+          <DialogActions>
+            <Button onClick={() => setSkipTargetId(null)}>CANCEL</Button>
+            <Button color="primary" onClick={handleSkip}>
+              SKIP
+            </Button>
+          </DialogActions>
+        </Dialog>
       </Box>
     </Box>
   );
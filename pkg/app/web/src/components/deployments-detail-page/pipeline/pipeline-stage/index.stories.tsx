@@ -9,6 +9,9 @@ export default {
     onClick: {
       action: "onClick",
     },
+    onSkip: {
+      action: "onSkip",
+    },
   },
 };
 
@@ -79,3 +82,14 @@ PromotePercentage.args = {
   metadata: [["promote-percentage", "75"]],
   isDeploymentRunning: true,
 };
+
+export const Skippable = Template.bind({});
+Skippable.args = {
+  id: "stage-1",
+  status: StageStatus.STAGE_RUNNING,
+  name: "ANALYSIS",
+  active: false,
+  metadata: [],
+  isDeploymentRunning: true,
+  onSkip: () => undefined,
+};
@@ -1,4 +1,5 @@
-import { makeStyles, Paper, Typography } from "@material-ui/core";
+import { IconButton, makeStyles, Paper, Typography } from "@material-ui/core";
+import { SkipNext } from "@material-ui/icons";
 import clsx from "clsx";
 import { FC, memo } from "react";
 import { StageStatus } from "~/modules/deployments";
@@ -41,6 +42,10 @@ const useStyles = makeStyles((theme) => ({
     justifyContent: "flex-start",
     alignItems: "center",
   },
+  skipButton: {
+    marginLeft: "auto",
+    padding: theme.spacing(0.5),
+  },
   metadata: {
     color: theme.palette.text.secondary,
     marginLeft: theme.spacing(4),
@@ -57,6 +62,7 @@ export interface PipelineStageProps {
   approver?: string;
   metadata: [string, string][];
   onClick: (stageId: string, stageName: string) => void;
+  onSkip?: (stageId: string) => void;
 }
 
 const TRAFFIC_PERCENTAGE_META_KEY = {
@@ -115,6 +121,7 @@ export const PipelineStage: FC<PipelineStageProps> = memo(
     approver,
     metadata,
     isDeploymentRunning,
+    onSkip,
   }) {
     const classes = useStyles();
     const disabled =
@@ -146,6 +153,19 @@ export const PipelineStage: FC<PipelineStageProps> = memo(
               {name}
             </span>
           </Typography>
+          {onSkip && (
+            <IconButton
+              aria-label="Skip stage"
+              title="Skip this stage"
+              className={classes.skipButton}
+              onClick={(e) => {
+                e.stopPropagation();
+                onSkip(id);
+              }}
+            >
+              <SkipNext fontSize="small" />
+            </IconButton>
+          )}
         </div>
         {approver !== undefined ? (
           <div className={classes.metadata}>
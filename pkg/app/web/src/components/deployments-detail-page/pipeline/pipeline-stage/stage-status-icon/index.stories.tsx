@@ -23,5 +23,11 @@ NotStartedYet.args = { status: StageStatus.STAGE_NOT_STARTED_YET };
 export const Running = Template.bind({});
 Running.args = { status: StageStatus.STAGE_RUNNING };
 
+export const Skipped = Template.bind({});
+Skipped.args = { status: StageStatus.STAGE_SKIPPED };
+
 export const Success = Template.bind({});
 Success.args = { status: StageStatus.STAGE_SUCCESS };
+
+export const SuccessWithWarnings = Template.bind({});
+SuccessWithWarnings.args = { status: StageStatus.STAGE_SUCCESS_WITH_WARNINGS };
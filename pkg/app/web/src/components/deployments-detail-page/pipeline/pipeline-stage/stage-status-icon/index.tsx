@@ -4,7 +4,9 @@ import {
   CheckCircle,
   Error,
   IndeterminateCheckBox,
+  SkipNext,
   Stop,
+  Warning,
 } from "@material-ui/icons";
 import { FC } from "react";
 import { StageStatus } from "~/modules/deployments";
@@ -13,6 +15,9 @@ const useStyles = makeStyles((theme) => ({
   [StageStatus.STAGE_SUCCESS]: {
     color: theme.palette.success.main,
   },
+  [StageStatus.STAGE_SUCCESS_WITH_WARNINGS]: {
+    color: theme.palette.warning.main,
+  },
   [StageStatus.STAGE_RUNNING]: {
     color: theme.palette.info.main,
     animation: `$running 3s linear infinite`,
@@ -26,6 +31,9 @@ const useStyles = makeStyles((theme) => ({
   [StageStatus.STAGE_NOT_STARTED_YET]: {
     color: theme.palette.grey[500],
   },
+  [StageStatus.STAGE_SKIPPED]: {
+    color: theme.palette.grey[500],
+  },
   "@keyframes running": {
     "0%": {
       transform: "rotate(0deg)",
@@ -46,6 +54,8 @@ export const StageStatusIcon: FC<StageStatusIconProps> = ({ status }) => {
   switch (status) {
     case StageStatus.STAGE_SUCCESS:
       return <CheckCircle className={classes[status]} />;
+    case StageStatus.STAGE_SUCCESS_WITH_WARNINGS:
+      return <Warning className={classes[status]} />;
     case StageStatus.STAGE_FAILURE:
       return <Error className={classes[status]} />;
     case StageStatus.STAGE_CANCELLED:
@@ -54,5 +64,7 @@ export const StageStatusIcon: FC<StageStatusIconProps> = ({ status }) => {
       return <IndeterminateCheckBox className={classes[status]} />;
     case StageStatus.STAGE_RUNNING:
       return <Cached className={classes[status]} />;
+    case StageStatus.STAGE_SKIPPED:
+      return <SkipNext className={classes[status]} />;
   }
 };
@@ -6,6 +6,11 @@ export const APPLICATION_KIND_TEXT: Record<ApplicationKind, string> = {
   [ApplicationKind.LAMBDA]: "LAMBDA",
   [ApplicationKind.CLOUDRUN]: "CLOUDRUN",
   [ApplicationKind.ECS]: "ECS",
+  [ApplicationKind.APPENGINE]: "APPENGINE",
+  [ApplicationKind.CLOUDFORMATION]: "CLOUDFORMATION",
+  [ApplicationKind.NOMAD]: "NOMAD",
+  [ApplicationKind.AZURE_FUNCTIONS]: "AZURE_FUNCTIONS",
+  [ApplicationKind.CUSTOM_SYNC]: "CUSTOM_SYNC",
 };
 
 export const APPLICATION_KIND_BY_NAME: Record<string, ApplicationKind> = {
@@ -15,4 +20,13 @@ export const APPLICATION_KIND_BY_NAME: Record<string, ApplicationKind> = {
   [APPLICATION_KIND_TEXT[ApplicationKind.LAMBDA]]: ApplicationKind.LAMBDA,
   [APPLICATION_KIND_TEXT[ApplicationKind.CLOUDRUN]]: ApplicationKind.CLOUDRUN,
   [APPLICATION_KIND_TEXT[ApplicationKind.ECS]]: ApplicationKind.ECS,
+  [APPLICATION_KIND_TEXT[ApplicationKind.APPENGINE]]:
+    ApplicationKind.APPENGINE,
+  [APPLICATION_KIND_TEXT[ApplicationKind.CLOUDFORMATION]]:
+    ApplicationKind.CLOUDFORMATION,
+  [APPLICATION_KIND_TEXT[ApplicationKind.NOMAD]]: ApplicationKind.NOMAD,
+  [APPLICATION_KIND_TEXT[ApplicationKind.AZURE_FUNCTIONS]]:
+    ApplicationKind.AZURE_FUNCTIONS,
+  [APPLICATION_KIND_TEXT[ApplicationKind.CUSTOM_SYNC]]:
+    ApplicationKind.CUSTOM_SYNC,
 };
@@ -1 +1,2 @@
 export const METADATA_APPROVED_BY = "ApprovedBy";
+export const METADATA_PAUSED_BY = "PausedBy";
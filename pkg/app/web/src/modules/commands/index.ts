@@ -15,7 +15,10 @@ const METADATA_KEY = {
 
 export const COMMAND_TYPE_TEXT: Record<Command.Type, string> = {
   [Command.Type.APPROVE_STAGE]: "Approve Stage",
+  [Command.Type.SKIP_STAGE]: "Skip Stage",
   [Command.Type.CANCEL_DEPLOYMENT]: "Cancel Deployment",
+  [Command.Type.PAUSE_DEPLOYMENT]: "Pause Deployment",
+  [Command.Type.RESUME_DEPLOYMENT]: "Resume Deployment",
   [Command.Type.SYNC_APPLICATION]: "Sync Application",
   [Command.Type.UPDATE_APPLICATION_CONFIG]: "Update Application Config",
   [Command.Type.BUILD_PLAN_PREVIEW]: "Build Plan Preview",
@@ -16,6 +16,7 @@ import type { AppState } from "~/store";
 import { LoadingStatus } from "~/types/module";
 import { ListDeploymentsRequest } from "pipe/pkg/app/web/api_client/service_pb";
 import { ApplicationKind } from "../applications";
+import { METADATA_PAUSED_BY } from "~/constants/metadata-keys";
 
 export type Stage = Required<PipelineStage.AsObject>;
 export type DeploymentStatusKey = keyof typeof DeploymentStatus;
@@ -51,14 +52,27 @@ export const isDeploymentRunning = (
   }
 };
 
+export const isDeploymentPaused = (
+  deployment: Deployment.AsObject | undefined
+): boolean => {
+  if (!deployment) {
+    return false;
+  }
+  return deployment.metadataMap.some(
+    ([key, value]) => key === METADATA_PAUSED_BY && value !== ""
+  );
+};
+
 export const isStageRunning = (status: StageStatus): boolean => {
   switch (status) {
     case StageStatus.STAGE_NOT_STARTED_YET:
     case StageStatus.STAGE_RUNNING:
       return true;
     case StageStatus.STAGE_SUCCESS:
+    case StageStatus.STAGE_SUCCESS_WITH_WARNINGS:
     case StageStatus.STAGE_FAILURE:
     case StageStatus.STAGE_CANCELLED:
+    case StageStatus.STAGE_SKIPPED:
       return false;
   }
 };
@@ -154,6 +168,30 @@ export const approveStage = createAsyncThunk<
   await thunkAPI.dispatch(fetchCommand(commandId));
 });
 
+export const pauseDeployment = createAsyncThunk<
+  void,
+  { deploymentId: string }
+>("deployments/pause", async (props, thunkAPI) => {
+  const { commandId } = await deploymentsApi.pauseDeployment(props);
+  await thunkAPI.dispatch(fetchCommand(commandId));
+});
+
+export const resumeDeployment = createAsyncThunk<
+  void,
+  { deploymentId: string }
+>("deployments/resume", async (props, thunkAPI) => {
+  const { commandId } = await deploymentsApi.resumeDeployment(props);
+  await thunkAPI.dispatch(fetchCommand(commandId));
+});
+
+export const skipStage = createAsyncThunk<
+  void,
+  { deploymentId: string; stageId: string }
+>("deployments/skipStage", async (props, thunkAPI) => {
+  const { commandId } = await deploymentsApi.skipStage(props);
+  await thunkAPI.dispatch(fetchCommand(commandId));
+});
+
 export const cancelDeployment = createAsyncThunk<
   void,
   {
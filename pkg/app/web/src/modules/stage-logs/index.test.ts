@@ -180,7 +180,13 @@ describe("stageLogsSlice reducer", () => {
         stageId: "stage-1",
         deploymentId: "deployment-1",
         logBlocks: [
-          { createdAt: 0, index: 0, log: "log", severity: LogSeverity.SUCCESS },
+          {
+            createdAt: 0,
+            index: 0,
+            log: "log",
+            severity: LogSeverity.SUCCESS,
+            fields: "",
+          },
         ],
       };
       expect(
@@ -0,0 +1,16 @@
+import { parseLogFields } from "./parse-log-fields";
+
+test("parseLogFields", () => {
+  expect(parseLogFields("")).toEqual([]);
+  expect(parseLogFields("{")).toEqual([]);
+  expect(parseLogFields("[1, 2]")).toEqual([]);
+  expect(
+    parseLogFields(
+      JSON.stringify({ name: "simple", replicas: 3, labels: { app: "simple" } })
+    )
+  ).toEqual([
+    ["labels", '{"app":"simple"}'],
+    ["name", "simple"],
+    ["replicas", "3"],
+  ]);
+});
@@ -0,0 +1,25 @@
+/**
+ * Parses the structured fields of the log block encoded as a JSON object
+ * into the pairs of the key and the displayed value sorted by the key.
+ * The malformed fields are ignored.
+ */
+export const parseLogFields = (fields: string): Array<[string, string]> => {
+  if (!fields) {
+    return [];
+  }
+  let parsed: unknown;
+  try {
+    parsed = JSON.parse(fields);
+  } catch {
+    return [];
+  }
+  if (typeof parsed !== "object" || parsed === null || Array.isArray(parsed)) {
+    return [];
+  }
+  return Object.entries(parsed as Record<string, unknown>)
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([key, value]) => [
+      key,
+      typeof value === "string" ? value : JSON.stringify(value),
+    ]);
+};
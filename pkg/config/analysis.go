@@ -29,6 +29,12 @@ const (
 	AnalysisDeviationEither = "EITHER"
 	AnalysisDeviationHigh   = "HIGH"
 	AnalysisDeviationLow    = "LOW"
+
+	AnalysisVerdictPass = "PASS"
+	AnalysisVerdictFail = "FAIL"
+
+	AnalysisConsumerLagSourceKafka = "KAFKA"
+	AnalysisConsumerLagSourceSQS   = "SQS"
 )
 
 // AnalysisMetrics contains common configurable values for deployment analysis with metrics.
@@ -42,6 +48,14 @@ type AnalysisMetrics struct {
 	// A query performed against the Analysis Provider.
 	// Required field.
 	Query string `json:"query"`
+	// The unique name of the secondary provider defined in the Piped Configuration.
+	// When specified, the query result is considered as expected only when
+	// the results of both providers are expected, to protect the analysis
+	// against the ingestion gaps of one monitoring backend.
+	SecondaryProvider string `json:"secondaryProvider"`
+	// A query performed against the secondary provider.
+	// Defaults to the query.
+	SecondaryQuery string `json:"secondaryQuery"`
 	// The expected query result.
 	// Required field for the THRESHOLD strategy.
 	Expected AnalysisExpected `json:"expected"`
@@ -58,6 +72,10 @@ type AnalysisMetrics struct {
 	// How long after which the query times out.
 	// Default is 30s.
 	Timeout Duration `json:"timeout"`
+	// If true, the query is always sent to the provider
+	// even when the provider is configured to cache the results.
+	// Default is false.
+	DisableCache bool `json:"disableCache"`
 
 	// The stage fails on deviation in the specified direction. One of LOW or HIGH or EITHER is available.
 	// This can be used only for PREVIOUS, CANARY_BASELINE or CANARY_PRIMARY. Defaults to EITHER.
@@ -71,6 +89,14 @@ type AnalysisMetrics struct {
 	// The custom arguments to be populated for the Primary query.
 	// They can be reffered as {{ .VariantArgs.xxx }}.
 	PrimaryArgs map[string]string `json:"primaryArgs"`
+	// The strategy used instead when the control variant disappeared in the middle of the analysis,
+	// e.g. the baseline pods were evicted. One of THRESHOLD or PREVIOUS is available.
+	// This can be used only for CANARY_BASELINE or CANARY_PRIMARY. Empty means no fallback.
+	ControlFallback string `json:"controlFallback"`
+	// The example datasets with their expected verdicts.
+	// They are evaluated by the strategy of this metrics before running the analysis
+	// to make sure the threshold or the deviation works as intended.
+	Examples []AnalysisMetricsExample `json:"examples"`
 }
 
 func (m *AnalysisMetrics) Validate() error {
@@ -86,6 +112,107 @@ func (m *AnalysisMetrics) Validate() error {
 	if m.Deviation != AnalysisDeviationEither && m.Deviation != AnalysisDeviationHigh && m.Deviation != AnalysisDeviationLow {
 		return fmt.Errorf("\"deviation\" have to be one of %s, %s or %s", AnalysisDeviationEither, AnalysisDeviationHigh, AnalysisDeviationLow)
 	}
+	if err := m.validateSecondaryProvider(); err != nil {
+		return err
+	}
+	if err := m.validateControlFallback(); err != nil {
+		return err
+	}
+	return m.validateExamples()
+}
+
+func (m *AnalysisMetrics) validateSecondaryProvider() error {
+	if m.SecondaryProvider == "" {
+		if m.SecondaryQuery != "" {
+			return fmt.Errorf("\"secondaryQuery\" can be used only with \"secondaryProvider\"")
+		}
+		return nil
+	}
+	if m.SecondaryProvider == m.Provider {
+		return fmt.Errorf("\"secondaryProvider\" must be different from \"provider\"")
+	}
+	return nil
+}
+
+// SecondaryQueryOrDefault returns the query performed against the secondary provider.
+func (m *AnalysisMetrics) SecondaryQueryOrDefault() string {
+	if m.SecondaryQuery == "" {
+		return m.Query
+	}
+	return m.SecondaryQuery
+}
+
+func (m *AnalysisMetrics) validateControlFallback() error {
+	if m.ControlFallback == "" {
+		return nil
+	}
+	strategy := m.StrategyOrDefault()
+	if strategy != AnalysisStrategyCanaryBaseline && strategy != AnalysisStrategyCanaryPrimary {
+		return fmt.Errorf("\"controlFallback\" can be used only for %s or %s", AnalysisStrategyCanaryBaseline, AnalysisStrategyCanaryPrimary)
+	}
+	switch m.ControlFallback {
+	case AnalysisStrategyThreshold:
+		if err := m.Expected.Validate(); err != nil {
+			return fmt.Errorf("\"expected\" is required for the %s control fallback", AnalysisStrategyThreshold)
+		}
+	case AnalysisStrategyPrevious:
+	default:
+		return fmt.Errorf("\"controlFallback\" have to be one of %s or %s", AnalysisStrategyThreshold, AnalysisStrategyPrevious)
+	}
+	return nil
+}
+
+func (m *AnalysisMetrics) validateExamples() error {
+	strategy := m.StrategyOrDefault()
+	for i, e := range m.Examples {
+		if err := e.Validate(strategy); err != nil {
+			return fmt.Errorf("invalid example %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// StrategyOrDefault returns the strategy of the metrics.
+// The default value is not set to the entries of the analysis template
+// until they are rendered, so THRESHOLD is returned when it is empty.
+func (m *AnalysisMetrics) StrategyOrDefault() string {
+	if m.Strategy == "" {
+		return AnalysisStrategyThreshold
+	}
+	return m.Strategy
+}
+
+// AnalysisMetricsExample is an example dataset of the query result
+// used to test the analysis of the metrics.
+type AnalysisMetricsExample struct {
+	// The name of the example to be shown when it failed.
+	Name string `json:"name"`
+	// The values returned by the query.
+	// They are the values of the current deployment for PREVIOUS
+	// and the values of the canary variant for CANARY_BASELINE and CANARY_PRIMARY.
+	// Required field.
+	Values []float64 `json:"values"`
+	// The values to be compared with.
+	// They are the values of the previous deployment for PREVIOUS,
+	// the values of the baseline variant for CANARY_BASELINE
+	// and the values of the primary variant for CANARY_PRIMARY.
+	// Required field for the strategies other than THRESHOLD.
+	ControlValues []float64 `json:"controlValues"`
+	// The expected verdict of the analysis. One of PASS or FAIL is available.
+	// Required field.
+	Verdict string `json:"verdict"`
+}
+
+func (e *AnalysisMetricsExample) Validate(strategy string) error {
+	if len(e.Values) == 0 {
+		return fmt.Errorf("missing \"values\" field")
+	}
+	if strategy != AnalysisStrategyThreshold && len(e.ControlValues) == 0 {
+		return fmt.Errorf("\"controlValues\" is required for the %s strategy", strategy)
+	}
+	if e.Verdict != AnalysisVerdictPass && e.Verdict != AnalysisVerdictFail {
+		return fmt.Errorf("\"verdict\" have to be one of %s or %s", AnalysisVerdictPass, AnalysisVerdictFail)
+	}
 	return nil
 }
 
@@ -164,6 +291,143 @@ type AnalysisHTTP struct {
 	Timeout      Duration `json:"timeout"`
 }
 
+// AnalysisKubernetesPodFailures contains configurable values for deployment analysis
+// based on the failures observed on the statuses of the pods of a variant, e.g. crash loops.
+// They are caught even though they never reach the metrics backend.
+type AnalysisKubernetesPodFailures struct {
+	// The variant whose pods should be analyzed.
+	// Default is canary.
+	Variant string `json:"variant" default:"canary"`
+	// Check the pods at this intervals.
+	// Default is 1m.
+	Interval Duration `json:"interval" default:"1m"`
+	// Maximum number of failed checks before the analysis is considered as failure.
+	FailureLimit int `json:"failureLimit"`
+	// Maximum number of containers in CrashLoopBackOff state allowed in a check.
+	// Default is 0.
+	MaxCrashLoopBackOff int `json:"maxCrashLoopBackOff"`
+	// Maximum number of containers terminated due to OOMKilled since the analysis started.
+	// Default is 0.
+	MaxOOMKilled int `json:"maxOOMKilled"`
+	// Maximum number of pods failed to be scheduled allowed in a check.
+	// Default is 0.
+	MaxFailedScheduling int `json:"maxFailedScheduling"`
+}
+
+func (a *AnalysisKubernetesPodFailures) Validate() error {
+	if a.Interval <= 0 {
+		return fmt.Errorf("\"interval\" must be greater than zero")
+	}
+	if a.MaxCrashLoopBackOff < 0 || a.MaxOOMKilled < 0 || a.MaxFailedScheduling < 0 {
+		return fmt.Errorf("thresholds of kubernetesPodFailures analysis must not be negative")
+	}
+	return nil
+}
+
+// AnalysisKubernetesPods contains configurable values for deployment analysis
+// based on how the pods of a variant behave during the analysis, e.g. how many of them are ready.
+// It requires no analysis provider so it works even though no observability tool is set up.
+type AnalysisKubernetesPods struct {
+	// The variant whose pods should be analyzed.
+	// Default is canary.
+	Variant string `json:"variant" default:"canary"`
+	// Check the pods at this intervals.
+	// Default is 1m.
+	Interval Duration `json:"interval" default:"1m"`
+	// Maximum number of failed checks before the analysis is considered as failure.
+	FailureLimit int `json:"failureLimit"`
+	// Minimum percentage of the ready pods required in a check.
+	// Default is 100.
+	MinReadyPercentage Percentage `json:"minReadyPercentage" default:"100"`
+	// Maximum number of container restarts since the analysis started.
+	// Default is 0.
+	MaxRestarts int `json:"maxRestarts"`
+	// Maximum number of containers terminated due to OOMKilled since the analysis started.
+	// Default is 0.
+	MaxOOMKilled int `json:"maxOOMKilled"`
+}
+
+func (a *AnalysisKubernetesPods) Validate() error {
+	if a.Interval <= 0 {
+		return fmt.Errorf("\"interval\" must be greater than zero")
+	}
+	if p := a.MinReadyPercentage.Int(); p < 0 || p > 100 {
+		return fmt.Errorf("\"minReadyPercentage\" must be between 0 and 100")
+	}
+	if a.MaxRestarts < 0 || a.MaxOOMKilled < 0 {
+		return fmt.Errorf("thresholds of kubernetesPods analysis must not be negative")
+	}
+	return nil
+}
+
+// AnalysisConsumerLag contains configurable values for deployment analysis
+// based on the lag of the message-queue consumers, e.g. Kafka consumer groups or SQS queues.
+// It compares the lag of the canary consumer with the baseline one
+// since the queue-based services have no HTTP metrics to be analyzed.
+type AnalysisConsumerLag struct {
+	// The unique name of metrics provider defined in the Piped Configuration.
+	// Required field.
+	Provider string `json:"provider"`
+	// The message queue the consumers read from. One of KAFKA or SQS is available.
+	// It is used to build the query for the configured provider when no query is given.
+	Source string `json:"source"`
+	// A query returning the lag of a consumer. It can refer to the consumer as {{ .Consumer }}.
+	// Required field if no source is given.
+	Query string `json:"query"`
+	// The consumer of the canary variant, e.g. the Kafka consumer group or the SQS queue name.
+	// Required field.
+	CanaryConsumer string `json:"canaryConsumer"`
+	// The consumer of the baseline variant to be compared with.
+	// Required field.
+	BaselineConsumer string `json:"baselineConsumer"`
+	// Run the queries at this intervals.
+	// Default is 1m.
+	Interval Duration `json:"interval" default:"1m"`
+	// Maximum number of failed checks before the analysis is considered as failure.
+	FailureLimit int `json:"failureLimit"`
+	// If true, it considers as a success when no data returned from the analysis provider.
+	// Default is false.
+	SkipOnNoData bool `json:"skipOnNoData"`
+	// How long after which the query times out.
+	// Default is 30s.
+	Timeout Duration `json:"timeout"`
+	// Maximum ratio of the canary lag to the baseline lag.
+	// Default is 1.5.
+	MaxLagRatio float64 `json:"maxLagRatio" default:"1.5"`
+	// The canary lag up to this value is always accepted
+	// to avoid failing while both lags are close to zero.
+	// Default is 0.
+	LagTolerance float64 `json:"lagTolerance"`
+}
+
+func (a *AnalysisConsumerLag) Validate() error {
+	if a.Provider == "" {
+		return fmt.Errorf("missing \"provider\" field")
+	}
+	if a.Query == "" {
+		switch a.Source {
+		case AnalysisConsumerLagSourceKafka, AnalysisConsumerLagSourceSQS:
+		case "":
+			return fmt.Errorf("either \"source\" or \"query\" must be specified")
+		default:
+			return fmt.Errorf("\"source\" have to be one of %s or %s", AnalysisConsumerLagSourceKafka, AnalysisConsumerLagSourceSQS)
+		}
+	}
+	if a.CanaryConsumer == "" || a.BaselineConsumer == "" {
+		return fmt.Errorf("both \"canaryConsumer\" and \"baselineConsumer\" must be specified")
+	}
+	if a.Interval <= 0 {
+		return fmt.Errorf("\"interval\" must be greater than zero")
+	}
+	if a.MaxLagRatio <= 0 {
+		return fmt.Errorf("\"maxLagRatio\" must be greater than zero")
+	}
+	if a.LagTolerance < 0 {
+		return fmt.Errorf("\"lagTolerance\" must not be negative")
+	}
+	return nil
+}
+
 type AnalysisHeader struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
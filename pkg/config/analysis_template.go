@@ -19,6 +19,12 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+)
+
+var (
+	templateActionRegex        = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	deprecatedTemplateArgRegex = regexp.MustCompile(`(^|[^\w.)\]])\.(App|K8s)\b`)
 )
 
 type AnalysisTemplateSpec struct {
@@ -56,5 +62,30 @@ func LoadAnalysisTemplate(repoRoot string) (*AnalysisTemplateSpec, error) {
 }
 
 func (s *AnalysisTemplateSpec) Validate() error {
+	// Only the examples are validated here since the other fields
+	// may be populated by the template arguments while running the analysis.
+	for name, m := range s.Metrics {
+		if err := m.validateExamples(); err != nil {
+			return fmt.Errorf("metrics template %s: %w", name, err)
+		}
+	}
 	return nil
 }
+
+// MigrateAnalysisTemplateArgs rewrites the references to the deprecated
+// built-in args (.App.* and .K8s.*) in the given template text to their new
+// names under .BuiltInArgs (.BuiltInArgs.App.* and .BuiltInArgs.K8s.*).
+// Only the template actions are changed, the rest of the text is kept as is.
+// The returned bool is true when at least one reference was rewritten.
+// Migrating an already migrated text is a no-op.
+func MigrateAnalysisTemplateArgs(text []byte) ([]byte, bool) {
+	var migrated bool
+	out := templateActionRegex.ReplaceAllFunc(text, func(action []byte) []byte {
+		if !deprecatedTemplateArgRegex.Match(action) {
+			return action
+		}
+		migrated = true
+		return deprecatedTemplateArgRegex.ReplaceAll(action, []byte("${1}.BuiltInArgs.${2}"))
+	})
+	return out, migrated
+}
@@ -13,3 +13,58 @@
 // limitations under the License.
 
 package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateAnalysisTemplateArgs(t *testing.T) {
+	testcases := []struct {
+		name             string
+		text             string
+		expected         string
+		expectedMigrated bool
+	}{
+		{
+			name:     "no template action",
+			text:     `query: sum(rate(http_requests_total{job="app"}[1m]))`,
+			expected: `query: sum(rate(http_requests_total{job="app"}[1m]))`,
+		},
+		{
+			name:             "deprecated args",
+			text:             `job="{{ .App.Name }}", namespace="{{.K8s.Namespace}}"`,
+			expected:         `job="{{ .BuiltInArgs.App.Name }}", namespace="{{.BuiltInArgs.K8s.Namespace}}"`,
+			expectedMigrated: true,
+		},
+		{
+			name:             "root reference and pipeline",
+			text:             `{{ $.App.Name | printf "%s-canary" }} {{ if eq .App.Env "prod" }}prod{{ end }}`,
+			expected:         `{{ $.BuiltInArgs.App.Name | printf "%s-canary" }} {{ if eq .BuiltInArgs.App.Env "prod" }}prod{{ end }}`,
+			expectedMigrated: true,
+		},
+		{
+			name:     "already migrated",
+			text:     `job="{{ .BuiltInArgs.App.Name }}"`,
+			expected: `job="{{ .BuiltInArgs.App.Name }}"`,
+		},
+		{
+			name:     "custom and variant args are kept",
+			text:     `{{ .Args.App }} {{ .VariantArgs.App }} {{ .Application }} .App.Name`,
+			expected: `{{ .Args.App }} {{ .VariantArgs.App }} {{ .Application }} .App.Name`,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, migrated := MigrateAnalysisTemplateArgs([]byte(tc.text))
+			assert.Equal(t, tc.expected, string(got))
+			assert.Equal(t, tc.expectedMigrated, migrated)
+
+			// Migrating again must not change anything.
+			again, migrated := MigrateAnalysisTemplateArgs(got)
+			assert.Equal(t, tc.expected, string(again))
+			assert.False(t, migrated)
+		})
+	}
+}
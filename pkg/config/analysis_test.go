@@ -16,6 +16,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,6 +25,10 @@ func floatPointer(v float64) *float64 {
 	return &v
 }
 
+func intPointer(v int) *int {
+	return &v
+}
+
 func TestAnalysisExpectedString(t *testing.T) {
 	testcases := []struct {
 		name string
@@ -63,3 +68,184 @@ func TestAnalysisExpectedString(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalysisTemplateSpecValidateExamples(t *testing.T) {
+	testcases := []struct {
+		name    string
+		metrics AnalysisMetrics
+		wantErr bool
+	}{
+		{
+			name: "valid threshold example",
+			metrics: AnalysisMetrics{
+				Examples: []AnalysisMetricsExample{
+					{Values: []float64{0.1}, Verdict: AnalysisVerdictPass},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing values",
+			metrics: AnalysisMetrics{
+				Examples: []AnalysisMetricsExample{
+					{Verdict: AnalysisVerdictPass},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid verdict",
+			metrics: AnalysisMetrics{
+				Examples: []AnalysisMetricsExample{
+					{Values: []float64{0.1}, Verdict: "OK"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing control values for deviation",
+			metrics: AnalysisMetrics{
+				Strategy: AnalysisStrategyCanaryBaseline,
+				Examples: []AnalysisMetricsExample{
+					{Values: []float64{0.1}, Verdict: AnalysisVerdictFail},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid deviation example",
+			metrics: AnalysisMetrics{
+				Strategy: AnalysisStrategyPrevious,
+				Examples: []AnalysisMetricsExample{
+					{Values: []float64{0.1}, ControlValues: []float64{0.2}, Verdict: AnalysisVerdictFail},
+				},
+			},
+			wantErr: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &AnalysisTemplateSpec{
+				Metrics: map[string]AnalysisMetrics{"metrics": tc.metrics},
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAnalysisMetricsValidateControlFallback(t *testing.T) {
+	testcases := []struct {
+		name     string
+		strategy string
+		fallback string
+		expected AnalysisExpected
+		wantErr  bool
+	}{
+		{
+			name:     "no fallback",
+			strategy: AnalysisStrategyCanaryBaseline,
+			wantErr:  false,
+		},
+		{
+			name:     "threshold fallback",
+			strategy: AnalysisStrategyCanaryBaseline,
+			fallback: AnalysisStrategyThreshold,
+			expected: AnalysisExpected{Max: floatPointer(0.1)},
+			wantErr:  false,
+		},
+		{
+			name:     "threshold fallback without expected",
+			strategy: AnalysisStrategyCanaryPrimary,
+			fallback: AnalysisStrategyThreshold,
+			wantErr:  true,
+		},
+		{
+			name:     "previous fallback",
+			strategy: AnalysisStrategyCanaryPrimary,
+			fallback: AnalysisStrategyPrevious,
+			wantErr:  false,
+		},
+		{
+			name:     "unsupported fallback",
+			strategy: AnalysisStrategyCanaryBaseline,
+			fallback: AnalysisStrategyCanaryPrimary,
+			wantErr:  true,
+		},
+		{
+			name:     "no control variant",
+			strategy: AnalysisStrategyPrevious,
+			fallback: AnalysisStrategyThreshold,
+			expected: AnalysisExpected{Max: floatPointer(0.1)},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &AnalysisMetrics{
+				Strategy:        tc.strategy,
+				Provider:        "provider",
+				Query:           "query",
+				Interval:        Duration(time.Minute),
+				Deviation:       AnalysisDeviationEither,
+				Expected:        tc.expected,
+				ControlFallback: tc.fallback,
+			}
+			err := m.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestAnalysisMetricsValidateSecondaryProvider(t *testing.T) {
+	testcases := []struct {
+		name              string
+		secondaryProvider string
+		secondaryQuery    string
+		wantErr           bool
+		wantQuery         string
+	}{
+		{
+			name:      "no secondary provider",
+			wantQuery: "query",
+		},
+		{
+			name:              "secondary provider with the same query",
+			secondaryProvider: "secondary",
+			wantQuery:         "query",
+		},
+		{
+			name:              "secondary provider with its own query",
+			secondaryProvider: "secondary",
+			secondaryQuery:    "secondary-query",
+			wantQuery:         "secondary-query",
+		},
+		{
+			name:              "same provider",
+			secondaryProvider: "provider",
+			wantErr:           true,
+		},
+		{
+			name:           "secondary query without provider",
+			secondaryQuery: "secondary-query",
+			wantErr:        true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &AnalysisMetrics{
+				Provider:          "provider",
+				Query:             "query",
+				Interval:          Duration(time.Minute),
+				Deviation:         AnalysisDeviationEither,
+				SecondaryProvider: tc.secondaryProvider,
+				SecondaryQuery:    tc.secondaryQuery,
+			}
+			err := m.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.wantQuery, m.SecondaryQueryOrDefault())
+			}
+		})
+	}
+}
@@ -49,6 +49,17 @@ const (
 	KindCloudRunApp Kind = "CloudRunApp"
 	// KindECSApp represents deployment configuration for an AWS ECS.
 	KindECSApp Kind = "ECSApp"
+	// KindAppEngineApp represents deployment configuration for a Google App Engine application.
+	KindAppEngineApp Kind = "AppEngineApp"
+	// KindCloudFormationApp represents deployment configuration for an AWS CloudFormation stack.
+	KindCloudFormationApp Kind = "CloudFormationApp"
+	// KindNomadApp represents deployment configuration for a HashiCorp Nomad job.
+	KindNomadApp Kind = "NomadApp"
+	// KindAzureFunctionsApp represents deployment configuration for an Azure Functions application.
+	KindAzureFunctionsApp Kind = "AzureFunctionsApp"
+	// KindCustomSyncApp represents deployment configuration for an application
+	// deployed by the user-provided command.
+	KindCustomSyncApp Kind = "CustomSyncApp"
 	// KindSealedSecret represents a sealed secret.
 	KindSealedSecret Kind = "SealedSecret"
 )
@@ -78,11 +89,16 @@ type Config struct {
 	APIVersion string
 	spec       interface{}
 
-	KubernetesDeploymentSpec *KubernetesDeploymentSpec
-	TerraformDeploymentSpec  *TerraformDeploymentSpec
-	CloudRunDeploymentSpec   *CloudRunDeploymentSpec
-	LambdaDeploymentSpec     *LambdaDeploymentSpec
-	ECSDeploymentSpec        *ECSDeploymentSpec
+	KubernetesDeploymentSpec     *KubernetesDeploymentSpec
+	TerraformDeploymentSpec      *TerraformDeploymentSpec
+	CloudRunDeploymentSpec       *CloudRunDeploymentSpec
+	LambdaDeploymentSpec         *LambdaDeploymentSpec
+	ECSDeploymentSpec            *ECSDeploymentSpec
+	AppEngineDeploymentSpec      *AppEngineDeploymentSpec
+	CloudFormationDeploymentSpec *CloudFormationDeploymentSpec
+	NomadDeploymentSpec          *NomadDeploymentSpec
+	AzureFunctionsDeploymentSpec *AzureFunctionsDeploymentSpec
+	CustomSyncDeploymentSpec     *CustomSyncDeploymentSpec
 
 	PipedSpec            *PipedSpec
 	ControlPlaneSpec     *ControlPlaneSpec
@@ -123,6 +139,26 @@ func (c *Config) init(kind Kind, apiVersion string) error {
 		c.ECSDeploymentSpec = &ECSDeploymentSpec{}
 		c.spec = c.ECSDeploymentSpec
 
+	case KindAppEngineApp:
+		c.AppEngineDeploymentSpec = &AppEngineDeploymentSpec{}
+		c.spec = c.AppEngineDeploymentSpec
+
+	case KindCloudFormationApp:
+		c.CloudFormationDeploymentSpec = &CloudFormationDeploymentSpec{}
+		c.spec = c.CloudFormationDeploymentSpec
+
+	case KindNomadApp:
+		c.NomadDeploymentSpec = &NomadDeploymentSpec{}
+		c.spec = c.NomadDeploymentSpec
+
+	case KindAzureFunctionsApp:
+		c.AzureFunctionsDeploymentSpec = &AzureFunctionsDeploymentSpec{}
+		c.spec = c.AzureFunctionsDeploymentSpec
+
+	case KindCustomSyncApp:
+		c.CustomSyncDeploymentSpec = &CustomSyncDeploymentSpec{}
+		c.spec = c.CustomSyncDeploymentSpec
+
 	case KindPiped:
 		c.PipedSpec = &PipedSpec{}
 		c.spec = c.PipedSpec
@@ -242,6 +278,16 @@ func ToApplicationKind(k Kind) (model.ApplicationKind, bool) {
 		return model.ApplicationKind_CLOUDRUN, true
 	case KindECSApp:
 		return model.ApplicationKind_ECS, true
+	case KindAppEngineApp:
+		return model.ApplicationKind_APPENGINE, true
+	case KindCloudFormationApp:
+		return model.ApplicationKind_CLOUDFORMATION, true
+	case KindNomadApp:
+		return model.ApplicationKind_NOMAD, true
+	case KindAzureFunctionsApp:
+		return model.ApplicationKind_AZURE_FUNCTIONS, true
+	case KindCustomSyncApp:
+		return model.ApplicationKind_CUSTOM_SYNC, true
 	}
 	return model.ApplicationKind_KUBERNETES, false
 }
@@ -258,6 +304,16 @@ func (c *Config) GetGenericDeployment() (GenericDeploymentSpec, bool) {
 		return c.LambdaDeploymentSpec.GenericDeploymentSpec, true
 	case KindECSApp:
 		return c.ECSDeploymentSpec.GenericDeploymentSpec, true
+	case KindAppEngineApp:
+		return c.AppEngineDeploymentSpec.GenericDeploymentSpec, true
+	case KindCloudFormationApp:
+		return c.CloudFormationDeploymentSpec.GenericDeploymentSpec, true
+	case KindNomadApp:
+		return c.NomadDeploymentSpec.GenericDeploymentSpec, true
+	case KindAzureFunctionsApp:
+		return c.AzureFunctionsDeploymentSpec.GenericDeploymentSpec, true
+	case KindCustomSyncApp:
+		return c.CustomSyncDeploymentSpec.GenericDeploymentSpec, true
 	}
 	return GenericDeploymentSpec{}, false
 }
@@ -45,9 +45,17 @@ type ControlPlaneSpec struct {
 	Projects []ControlPlaneProject `json:"projects"`
 	// List of shared SSO configurations that can be used by any projects.
 	SharedSSOConfigs []SharedSSOConfig `json:"sharedSSOConfigs"`
+	// List of Slack users allowed to approve or reject the WAIT_APPROVAL stages
+	// by clicking the buttons of the Slack messages.
+	SlackApprovers []ControlPlaneSlackApprover `json:"slackApprovers"`
 }
 
 func (s *ControlPlaneSpec) Validate() error {
+	for _, a := range s.SlackApprovers {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -60,6 +68,30 @@ type ControlPlaneProject struct {
 	StaticAdmin ProjectStaticUser `json:"staticAdmin"`
 }
 
+type ControlPlaneSlackApprover struct {
+	// The ID of the Slack user, e.g. U012AB3CD.
+	// The user name is not used since it can be changed by the user.
+	SlackUserID string `json:"slackUserID"`
+	// The ID of the project whose stages the Slack user can approve.
+	ProjectID string `json:"projectID"`
+	// The PipeCD username the Slack user acts as.
+	// This is compared with the approvers of the stages and recorded as the approver.
+	Username string `json:"username"`
+}
+
+func (a ControlPlaneSlackApprover) Validate() error {
+	if a.SlackUserID == "" {
+		return fmt.Errorf("slackUserID of slack approver must be set")
+	}
+	if a.ProjectID == "" {
+		return fmt.Errorf("projectID of slack approver %s must be set", a.SlackUserID)
+	}
+	if a.Username == "" {
+		return fmt.Errorf("username of slack approver %s must be set", a.SlackUserID)
+	}
+	return nil
+}
+
 type ProjectStaticUser struct {
 	// The username string.
 	Username string `json:"username"`
@@ -117,6 +149,16 @@ func (s *ControlPlaneSpec) FindProject(id string) (ControlPlaneProject, bool) {
 	return ControlPlaneProject{}, false
 }
 
+// FindSlackApprover finds the Slack user allowed to approve the stages of the given project.
+func (s *ControlPlaneSpec) FindSlackApprover(projectID, slackUserID string) (ControlPlaneSlackApprover, bool) {
+	for i := range s.SlackApprovers {
+		if s.SlackApprovers[i].ProjectID == projectID && s.SlackApprovers[i].SlackUserID == slackUserID {
+			return s.SlackApprovers[i], true
+		}
+	}
+	return ControlPlaneSlackApprover{}, false
+}
+
 func (s *ControlPlaneSpec) ProjectMap() map[string]ControlPlaneProject {
 	m := make(map[string]ControlPlaneProject, len(s.Projects))
 	for i := range s.Projects {
@@ -115,3 +115,25 @@ func TestControlPlaneConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestFindSlackApprover(t *testing.T) {
+	spec := &ControlPlaneSpec{
+		SlackApprovers: []ControlPlaneSlackApprover{
+			{SlackUserID: "U1", ProjectID: "project-1", Username: "alice"},
+			{SlackUserID: "U1", ProjectID: "project-2", Username: "alice-2"},
+		},
+	}
+	require.NoError(t, spec.Validate())
+
+	approver, ok := spec.FindSlackApprover("project-2", "U1")
+	require.True(t, ok)
+	assert.Equal(t, "alice-2", approver.Username)
+
+	_, ok = spec.FindSlackApprover("project-3", "U1")
+	assert.False(t, ok)
+	_, ok = spec.FindSlackApprover("project-1", "U2")
+	assert.False(t, ok)
+
+	spec.SlackApprovers = append(spec.SlackApprovers, ControlPlaneSlackApprover{SlackUserID: "U2", ProjectID: "project-1"})
+	assert.Error(t, spec.Validate())
+}
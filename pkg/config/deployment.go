@@ -17,8 +17,15 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
+
+	"github.com/pipe-cd/pipe/pkg/filematcher"
 	"github.com/pipe-cd/pipe/pkg/model"
 )
 
@@ -39,29 +46,143 @@ type GenericDeploymentSpec struct {
 	// List of directories or files where their changes will trigger the deployment.
 	// Regular expression can be used.
 	TriggerPaths []string `json:"triggerPaths,omitempty"`
+	// Configuration to decide when the deployment is triggered.
+	Trigger *DeploymentTrigger `json:"trigger"`
+	// How to handle the deployments triggered while another deployment of the application
+	// is planning or running. Can be "queue", "cancel-in-progress" or "skip-intermediate".
+	// Default is "queue".
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy"`
 	// The maximum length of time to execute deployment before giving up.
 	// Default is 6h.
 	Timeout Duration `json:"timeout,omitempty" default:"6h"`
 	// List of encrypted secrets and targets that should be decoded before using.
 	Encryption *SecretEncryption `json:"encryption"`
+	// List of files encrypted by sops that should be decrypted before using.
+	Sops *SopsDecryption `json:"sops"`
+	// List of files referencing the secrets stored in the external secret managers
+	// such as Vault that should be resolved before using.
+	ExternalSecrets *ExternalSecrets `json:"externalSecrets"`
+	// The OCI artifact containing the application manifests.
+	// When specified, its files are pulled into the application directory
+	// instead of using the ones committed in Git.
+	OCISource *DeploymentOCISource `json:"ociSource"`
 	// Additional configuration used while sending notification to external services.
 	DeploymentNotification *DeploymentNotification `json:"notification"`
+	// Configuration used while detecting the configuration drift of the application.
+	DriftDetection *DriftDetection `json:"driftDetection"`
+	// Configuration used after the deployment was completed successfully.
+	PostSync *PostSync `json:"postSync"`
 }
 
 type DeploymentPlanner struct {
 	// Disable auto-detecting to use QUICK_SYNC or PROGRESSIVE_SYNC.
 	// Always use the speficied pipeline for all deployments.
 	AlwaysUsePipeline bool `json:"alwaysUsePipeline"`
+	// List of rules to decide the sync strategy by the changes made since the last successful deployment.
+	// The rules are evaluated in order and the first matching one is used.
+	// The strategy is auto-detected as before when no rule matched.
+	Rules []DeploymentPlannerRule `json:"rules"`
+}
+
+func (p *DeploymentPlanner) Validate() error {
+	for i := range p.Rules {
+		if err := p.Rules[i].Validate(); err != nil {
+			return fmt.Errorf("invalid planner.rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// DeploymentPlannerRule decides the sync strategy when the changes satisfy all of its conditions.
+type DeploymentPlannerRule struct {
+	// The sync strategy used when the rule matched. Either "pipeline" or "quickSync".
+	Strategy PlannerRuleStrategy `json:"strategy"`
+	// The kind of the changes. Either "image" or "config".
+	// "image" matches when any container image found in the changed files was changed,
+	// and "config" matches when the files were changed without changing any container image.
+	Changes PlannerRuleChanges `json:"changes"`
+	// List of glob patterns matching the changed files.
+	// The paths are relative to the application directory.
+	// This matches when any changed file matches any of them.
+	Paths []string `json:"paths"`
+}
+
+type PlannerRuleStrategy string
+
+const (
+	PlannerRuleStrategyPipeline  PlannerRuleStrategy = "pipeline"
+	PlannerRuleStrategyQuickSync PlannerRuleStrategy = "quickSync"
+)
+
+type PlannerRuleChanges string
+
+const (
+	PlannerRuleChangesImage  PlannerRuleChanges = "image"
+	PlannerRuleChangesConfig PlannerRuleChanges = "config"
+)
+
+func (r *DeploymentPlannerRule) Validate() error {
+	switch r.Strategy {
+	case PlannerRuleStrategyPipeline, PlannerRuleStrategyQuickSync:
+	default:
+		return fmt.Errorf("unsupported strategy %q", r.Strategy)
+	}
+	switch r.Changes {
+	case "", PlannerRuleChangesImage, PlannerRuleChangesConfig:
+	default:
+		return fmt.Errorf("unsupported changes %q", r.Changes)
+	}
+	if r.Changes == "" && len(r.Paths) == 0 {
+		return fmt.Errorf("either changes or paths must be set")
+	}
+	if _, err := filematcher.NewPatternMatcher(r.Paths); err != nil {
+		return fmt.Errorf("invalid paths: %w", err)
+	}
+	return nil
 }
 
 func (s *GenericDeploymentSpec) Validate() error {
 	if s.Pipeline != nil {
 		for _, stage := range s.Pipeline.Stages {
+			if stage.EstimatedDuration < 0 {
+				return fmt.Errorf("estimatedDuration of stage %s must not be negative", stage.Name)
+			}
+			if stage.Timeout < 0 {
+				return fmt.Errorf("timeout of stage %s must not be negative", stage.Name)
+			}
+			if stage.Retries != nil {
+				if err := stage.Retries.Validate(); err != nil {
+					return fmt.Errorf("invalid retries of stage %s: %w", stage.Name, err)
+				}
+			}
+			if err := stage.validateOutputs(); err != nil {
+				return fmt.Errorf("invalid outputs of stage %s: %w", stage.Name, err)
+			}
 			if stage.AnalysisStageOptions != nil {
 				if err := stage.AnalysisStageOptions.Validate(); err != nil {
 					return err
 				}
 			}
+			if stage.ScriptRunStageOptions != nil {
+				if err := stage.ScriptRunStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.NotifyStageOptions != nil {
+				if err := stage.NotifyStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.EventPublishStageOptions != nil {
+				if err := stage.EventPublishStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+		}
+		if f := s.Pipeline.OnFailure; f != nil {
+			if err := f.Validate(); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -71,6 +192,58 @@ func (s *GenericDeploymentSpec) Validate() error {
 		}
 	}
 
+	if sops := s.Sops; sops != nil {
+		if err := sops.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if es := s.ExternalSecrets; es != nil {
+		if err := es.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if o := s.OCISource; o != nil {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Planner.Validate(); err != nil {
+		return err
+	}
+
+	if t := s.Trigger; t != nil {
+		if err := t.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch s.ConcurrencyPolicy {
+	case "", ConcurrencyPolicyQueue, ConcurrencyPolicyCancelInProgress, ConcurrencyPolicySkipIntermediate:
+	default:
+		return fmt.Errorf("unsupported concurrencyPolicy %q", s.ConcurrencyPolicy)
+	}
+
+	if m := s.CommitMatcher.SkipAnalysis; m != nil {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if d := s.DriftDetection; d != nil {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if p := s.PostSync; p != nil {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -97,12 +270,57 @@ func (s GenericDeploymentSpec) HasStage(stage model.Stage) bool {
 	return false
 }
 
+// validateRollbackApproval validates the rollbackApproval of the deployment input.
+// It can not be used together with the pause action of onFailure
+// since both of them wait for an approval before executing the rollback.
+func (s GenericDeploymentSpec) validateRollbackApproval(a *RollbackApproval) error {
+	if s.Pipeline != nil && s.Pipeline.OnFailure != nil && s.Pipeline.OnFailure.Action == PipelineOnFailureActionPause {
+		return fmt.Errorf("rollbackApproval can not be used together with the pause action of onFailure")
+	}
+	return a.Validate()
+}
+
 // DeploymentCommitMatcher provides a way to decide how to deploy.
 type DeploymentCommitMatcher struct {
 	// It makes sure to perform syncing if the commit message matches this regular expression.
 	QuickSync string `json:"quickSync"`
 	// It makes sure to perform pipeline if the commit message matches this regular expression.
 	Pipeline string `json:"pipeline"`
+	// It makes all ANALYSIS stages report-only if the commit message matches the configured directive.
+	SkipAnalysis *SkipAnalysisMatcher `json:"skipAnalysis"`
+}
+
+// SkipAnalysisMatcher configures the commit message directive to skip the analysis
+// and who can use it.
+type SkipAnalysisMatcher struct {
+	// Regular expression to be matched with the commit message. e.g. "\[skip-analysis\]"
+	Pattern string `json:"pattern"`
+	// The list of commit authors allowed to use the directive.
+	// The directive in the commit by the others is ignored.
+	AllowedAuthors []string `json:"allowedAuthors"`
+}
+
+func (m *SkipAnalysisMatcher) Validate() error {
+	if m.Pattern == "" {
+		return fmt.Errorf("commitMatcher.skipAnalysis.pattern must not be empty")
+	}
+	if _, err := regexp.Compile(m.Pattern); err != nil {
+		return fmt.Errorf("commitMatcher.skipAnalysis.pattern is invalid: %w", err)
+	}
+	if len(m.AllowedAuthors) == 0 {
+		return fmt.Errorf("commitMatcher.skipAnalysis.allowedAuthors must not be empty")
+	}
+	return nil
+}
+
+// IsAllowedAuthor checks whether the given commit author is allowed to use the directive.
+func (m *SkipAnalysisMatcher) IsAllowedAuthor(author string) bool {
+	for _, a := range m.AllowedAuthors {
+		if a == author {
+			return true
+		}
+	}
+	return false
 }
 
 // DeploymentPipeline represents the way to deploy the application.
@@ -111,19 +329,71 @@ type DeploymentCommitMatcher struct {
 // - ConfigMaps, Secrets that are mounted as volumes or envs in the deployment.
 type DeploymentPipeline struct {
 	Stages []PipelineStage `json:"stages"`
+	// What should be done when an ANALYSIS stage of the pipeline failed.
+	// Empty means the deployment is rolled back immediately.
+	OnFailure *PipelineOnFailure `json:"onFailure"`
+}
+
+type PipelineOnFailureAction string
+
+const (
+	// PipelineOnFailureActionRollback rolls back the deployment immediately.
+	PipelineOnFailureActionRollback PipelineOnFailureAction = "rollback"
+	// PipelineOnFailureActionRollbackAndNotify rolls back the deployment
+	// and raises an incident by sending the DEPLOYMENT_INCIDENT notification event.
+	PipelineOnFailureActionRollbackAndNotify PipelineOnFailureAction = "rollback-and-notify"
+	// PipelineOnFailureActionPause pauses the deployment
+	// until a human approves the rollback.
+	PipelineOnFailureActionPause PipelineOnFailureAction = "pause"
+)
+
+// PipelineOnFailure represents the policy applied when an ANALYSIS stage failed.
+// It takes effect only when the rollback is enabled by autoRollback.
+type PipelineOnFailure struct {
+	// The action to take. Can be "rollback", "rollback-and-notify" or "pause".
+	// Defaults to "rollback".
+	Action PipelineOnFailureAction `json:"action" default:"rollback"`
+	// How to wait for the approval. Used only by the "pause" action.
+	Approval RollbackApproval `json:"approval"`
+}
+
+func (p *PipelineOnFailure) Validate() error {
+	switch p.Action {
+	case PipelineOnFailureActionRollback, PipelineOnFailureActionRollbackAndNotify:
+		return nil
+	case PipelineOnFailureActionPause:
+		return p.Approval.Validate()
+	default:
+		return fmt.Errorf("unsupported action %q for onFailure", p.Action)
+	}
 }
 
 // PipelineStage represents a single stage of a pipeline.
 // This is used as a generic struct for all stage type.
 type PipelineStage struct {
-	Id      string
-	Name    model.Stage
-	Desc    string
+	Id   string
+	Name model.Stage
+	Desc string
+	// The maximum length of time to execute this stage.
+	// It is applied to each attempt when the stage is retried.
+	// Empty means the stage can run until the deployment times out.
 	Timeout Duration
+	// How to retry this stage when it failed.
+	// Empty means the stage is never retried.
+	Retries *StageRetries
+	// How long this stage is expected to take.
+	// It is used to estimate the duration of the whole pipeline while planning
+	// and to detect the stage exceeding it repeatedly.
+	EstimatedDuration Duration
+	// The outputs published by this stage in addition to the built-in ones.
+	Outputs []StageOutput
 
 	WaitStageOptions         *WaitStageOptions
 	WaitApprovalStageOptions *WaitApprovalStageOptions
 	AnalysisStageOptions     *AnalysisStageOptions
+	ScriptRunStageOptions    *ScriptRunStageOptions
+	NotifyStageOptions       *NotifyStageOptions
+	EventPublishStageOptions *EventPublishStageOptions
 
 	K8sPrimaryRolloutStageOptions  *K8sPrimaryRolloutStageOptions
 	K8sCanaryRolloutStageOptions   *K8sCanaryRolloutStageOptions
@@ -131,6 +401,12 @@ type PipelineStage struct {
 	K8sBaselineRolloutStageOptions *K8sBaselineRolloutStageOptions
 	K8sBaselineCleanStageOptions   *K8sBaselineCleanStageOptions
 	K8sTrafficRoutingStageOptions  *K8sTrafficRoutingStageOptions
+	K8sDiffStageOptions            *K8sDiffStageOptions
+	K8sValidateStageOptions        *K8sValidateStageOptions
+	K8sVerifyImagesStageOptions    *K8sVerifyImagesStageOptions
+	K8sPreviewRolloutStageOptions  *K8sPreviewRolloutStageOptions
+	K8sPreviewCleanStageOptions    *K8sPreviewCleanStageOptions
+	K8sJobRunStageOptions          *K8sJobRunStageOptions
 
 	TerraformSyncStageOptions  *TerraformSyncStageOptions
 	TerraformPlanStageOptions  *TerraformPlanStageOptions
@@ -148,14 +424,102 @@ type PipelineStage struct {
 	ECSPrimaryRolloutStageOptions *ECSPrimaryRolloutStageOptions
 	ECSCanaryCleanStageOptions    *ECSCanaryCleanStageOptions
 	ECSTrafficRoutingStageOptions *ECSTrafficRoutingStageOptions
+
+	AppEngineSyncStageOptions           *AppEngineSyncStageOptions
+	AppEngineDeployVersionStageOptions  *AppEngineDeployVersionStageOptions
+	AppEngineMigrateTrafficStageOptions *AppEngineMigrateTrafficStageOptions
+
+	CloudFormationSyncStageOptions  *CloudFormationSyncStageOptions
+	CloudFormationPlanStageOptions  *CloudFormationPlanStageOptions
+	CloudFormationApplyStageOptions *CloudFormationApplyStageOptions
+
+	NomadSyncStageOptions          *NomadSyncStageOptions
+	NomadCanaryRolloutStageOptions *NomadCanaryRolloutStageOptions
+	NomadPromoteStageOptions       *NomadPromoteStageOptions
+
+	AzureFunctionsSyncStageOptions          *AzureFunctionsSyncStageOptions
+	AzureFunctionsCanaryRolloutStageOptions *AzureFunctionsCanaryRolloutStageOptions
+	AzureFunctionsPromoteStageOptions       *AzureFunctionsPromoteStageOptions
+
+	CustomSyncStageOptions *CustomSyncStageOptions
+
+	// The options of the stage provided by an executor plugin.
+	// They are passed to the plugin as is.
+	PluginStageOptions json.RawMessage
+}
+
+// StageOutput declares a value published by the stage
+// to be consumed by the templates of the later stages.
+type StageOutput struct {
+	// The name of the output. It must be a valid identifier to be referred in the templates.
+	Name string `json:"name"`
+	// The type of the value. Can be "string", "number" or "bool". Default is "string".
+	Type StageOutputType `json:"type"`
+	// Whether the stage fails when it was completed without publishing this output.
+	Required bool `json:"required"`
+}
+
+type StageOutputType string
+
+const (
+	StageOutputTypeString StageOutputType = "string"
+	StageOutputTypeNumber StageOutputType = "number"
+	StageOutputTypeBool   StageOutputType = "bool"
+)
+
+var stageOutputNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (s PipelineStage) validateOutputs() error {
+	names := make(map[string]struct{}, len(s.Outputs))
+	for _, o := range model.BuiltinStageOutputs(s.Name) {
+		names[o.Name] = struct{}{}
+	}
+	for _, o := range s.Outputs {
+		if !stageOutputNameRegex.MatchString(o.Name) {
+			return fmt.Errorf("name %q must consist of alphanumeric characters or '_' and not start with a digit", o.Name)
+		}
+		if _, ok := names[o.Name]; ok {
+			return fmt.Errorf("output %s is declared more than once", o.Name)
+		}
+		names[o.Name] = struct{}{}
+		switch o.Type {
+		case "", StageOutputTypeString, StageOutputTypeNumber, StageOutputTypeBool:
+		default:
+			return fmt.Errorf("unsupported type %q of output %s", o.Type, o.Name)
+		}
+	}
+	return nil
+}
+
+// StageOutputs returns the built-in outputs of the stage and the ones declared in the configuration.
+func (s PipelineStage) StageOutputs() []*model.StageOutput {
+	outputs := model.BuiltinStageOutputs(s.Name)
+	for _, o := range s.Outputs {
+		t := model.StageOutputType_STAGE_OUTPUT_STRING
+		switch o.Type {
+		case StageOutputTypeNumber:
+			t = model.StageOutputType_STAGE_OUTPUT_NUMBER
+		case StageOutputTypeBool:
+			t = model.StageOutputType_STAGE_OUTPUT_BOOL
+		}
+		outputs = append(outputs, &model.StageOutput{
+			Name:     o.Name,
+			Type:     t,
+			Required: o.Required,
+		})
+	}
+	return outputs
 }
 
 type genericPipelineStage struct {
-	Id      string          `json:"id"`
-	Name    model.Stage     `json:"name"`
-	Desc    string          `json:"desc,omitempty"`
-	Timeout Duration        `json:"timeout"`
-	With    json.RawMessage `json:"with"`
+	Id                string          `json:"id"`
+	Name              model.Stage     `json:"name"`
+	Desc              string          `json:"desc,omitempty"`
+	Timeout           Duration        `json:"timeout"`
+	Retries           *StageRetries   `json:"retries"`
+	EstimatedDuration Duration        `json:"estimatedDuration"`
+	Outputs           []StageOutput   `json:"outputs"`
+	With              json.RawMessage `json:"with"`
 }
 
 func (s *PipelineStage) UnmarshalJSON(data []byte) error {
@@ -168,6 +532,9 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 	s.Name = gs.Name
 	s.Desc = gs.Desc
 	s.Timeout = gs.Timeout
+	s.Retries = gs.Retries
+	s.EstimatedDuration = gs.EstimatedDuration
+	s.Outputs = gs.Outputs
 
 	switch s.Name {
 	case model.StageWait:
@@ -193,6 +560,21 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 				s.AnalysisStageOptions.Metrics[i].Timeout = defaultAnalysisQueryTimeout
 			}
 		}
+	case model.StageScriptRun:
+		s.ScriptRunStageOptions = &ScriptRunStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.ScriptRunStageOptions)
+		}
+	case model.StageNotify:
+		s.NotifyStageOptions = &NotifyStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.NotifyStageOptions)
+		}
+	case model.StageEventPublish:
+		s.EventPublishStageOptions = &EventPublishStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.EventPublishStageOptions)
+		}
 	case model.StageK8sPrimaryRollout:
 		s.K8sPrimaryRolloutStageOptions = &K8sPrimaryRolloutStageOptions{}
 		if len(gs.With) > 0 {
@@ -203,6 +585,16 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 		if len(gs.With) > 0 {
 			err = json.Unmarshal(gs.With, s.K8sCanaryRolloutStageOptions)
 		}
+		for _, step := range s.K8sCanaryRolloutStageOptions.Steps {
+			if step.Analysis == nil {
+				continue
+			}
+			for i := 0; i < len(step.Analysis.Metrics); i++ {
+				if step.Analysis.Metrics[i].Timeout <= 0 {
+					step.Analysis.Metrics[i].Timeout = defaultAnalysisQueryTimeout
+				}
+			}
+		}
 	case model.StageK8sCanaryClean:
 		s.K8sCanaryCleanStageOptions = &K8sCanaryCleanStageOptions{}
 		if len(gs.With) > 0 {
@@ -223,6 +615,36 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 		if len(gs.With) > 0 {
 			err = json.Unmarshal(gs.With, s.K8sTrafficRoutingStageOptions)
 		}
+	case model.StageK8sDiff:
+		s.K8sDiffStageOptions = &K8sDiffStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sDiffStageOptions)
+		}
+	case model.StageK8sValidate:
+		s.K8sValidateStageOptions = &K8sValidateStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sValidateStageOptions)
+		}
+	case model.StageK8sVerifyImages:
+		s.K8sVerifyImagesStageOptions = &K8sVerifyImagesStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sVerifyImagesStageOptions)
+		}
+	case model.StageK8sPreviewRollout:
+		s.K8sPreviewRolloutStageOptions = &K8sPreviewRolloutStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sPreviewRolloutStageOptions)
+		}
+	case model.StageK8sPreviewClean:
+		s.K8sPreviewCleanStageOptions = &K8sPreviewCleanStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sPreviewCleanStageOptions)
+		}
+	case model.StageK8sJobRun:
+		s.K8sJobRunStageOptions = &K8sJobRunStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.K8sJobRunStageOptions)
+		}
 
 	case model.StageTerraformSync:
 		s.TerraformSyncStageOptions = &TerraformSyncStageOptions{}
@@ -293,12 +715,142 @@ func (s *PipelineStage) UnmarshalJSON(data []byte) error {
 			err = json.Unmarshal(gs.With, s.ECSTrafficRoutingStageOptions)
 		}
 
+	case model.StageAppEngineSync:
+		s.AppEngineSyncStageOptions = &AppEngineSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AppEngineSyncStageOptions)
+		}
+	case model.StageAppEngineDeployVersion:
+		s.AppEngineDeployVersionStageOptions = &AppEngineDeployVersionStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AppEngineDeployVersionStageOptions)
+		}
+	case model.StageAppEngineMigrateTraffic:
+		s.AppEngineMigrateTrafficStageOptions = &AppEngineMigrateTrafficStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AppEngineMigrateTrafficStageOptions)
+		}
+
+	case model.StageCloudFormationSync:
+		s.CloudFormationSyncStageOptions = &CloudFormationSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CloudFormationSyncStageOptions)
+		}
+	case model.StageCloudFormationPlan:
+		s.CloudFormationPlanStageOptions = &CloudFormationPlanStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CloudFormationPlanStageOptions)
+		}
+	case model.StageCloudFormationApply:
+		s.CloudFormationApplyStageOptions = &CloudFormationApplyStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CloudFormationApplyStageOptions)
+		}
+
+	case model.StageNomadSync:
+		s.NomadSyncStageOptions = &NomadSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.NomadSyncStageOptions)
+		}
+	case model.StageNomadCanaryRollout:
+		s.NomadCanaryRolloutStageOptions = &NomadCanaryRolloutStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.NomadCanaryRolloutStageOptions)
+		}
+	case model.StageNomadPromote:
+		s.NomadPromoteStageOptions = &NomadPromoteStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.NomadPromoteStageOptions)
+		}
+
+	case model.StageAzureFunctionsSync:
+		s.AzureFunctionsSyncStageOptions = &AzureFunctionsSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AzureFunctionsSyncStageOptions)
+		}
+	case model.StageAzureFunctionsCanaryRollout:
+		s.AzureFunctionsCanaryRolloutStageOptions = &AzureFunctionsCanaryRolloutStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AzureFunctionsCanaryRolloutStageOptions)
+		}
+	case model.StageAzureFunctionsPromote:
+		s.AzureFunctionsPromoteStageOptions = &AzureFunctionsPromoteStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.AzureFunctionsPromoteStageOptions)
+		}
+
+	case model.StageCustomSync:
+		s.CustomSyncStageOptions = &CustomSyncStageOptions{}
+		if len(gs.With) > 0 {
+			err = json.Unmarshal(gs.With, s.CustomSyncStageOptions)
+		}
+
 	default:
+		if s.Name.IsPlugin() {
+			s.PluginStageOptions = gs.With
+			break
+		}
 		err = fmt.Errorf("unsupported stage name: %s", s.Name)
 	}
 	return err
 }
 
+const (
+	defaultStageRetryBackoff    = Duration(10 * time.Second)
+	defaultStageRetryMaxBackoff = Duration(5 * time.Minute)
+)
+
+// StageRetries represents how a failed stage is retried.
+// The failures caused by the wrong configuration are never retried
+// since retrying them without changing the configuration does not help.
+type StageRetries struct {
+	// The maximum number of retries.
+	Limit int `json:"limit"`
+	// How long to wait before the first retry.
+	// The wait is doubled for each retry.
+	// Default is 10s.
+	Backoff Duration `json:"backoff"`
+	// The maximum length of time to wait before a retry.
+	// Default is 5m.
+	MaxBackoff Duration `json:"maxBackoff"`
+}
+
+func (r *StageRetries) Validate() error {
+	if r.Limit < 0 {
+		return fmt.Errorf("limit must not be negative")
+	}
+	if r.Backoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	if r.MaxBackoff < 0 {
+		return fmt.Errorf("maxBackoff must not be negative")
+	}
+	return nil
+}
+
+// GetBackoff returns how long to wait before retrying the stage
+// which has already been retried the given number of times.
+func (r *StageRetries) GetBackoff(retried int) time.Duration {
+	var (
+		backoff    = r.Backoff
+		maxBackoff = r.MaxBackoff
+	)
+	if backoff == 0 {
+		backoff = defaultStageRetryBackoff
+	}
+	if maxBackoff == 0 {
+		maxBackoff = defaultStageRetryMaxBackoff
+	}
+	d := backoff.Duration()
+	for i := 0; i < retried && d < maxBackoff.Duration(); i++ {
+		d *= 2
+	}
+	if d > maxBackoff.Duration() {
+		return maxBackoff.Duration()
+	}
+	return d
+}
+
 // WaitStageOptions contains all configurable values for a WAIT stage.
 type WaitStageOptions struct {
 	Duration Duration `json:"duration"`
@@ -312,6 +864,90 @@ type WaitApprovalStageOptions struct {
 	Approvers []string `json:"approvers"`
 }
 
+// ScriptRunStageOptions contains all configurable values for a SCRIPT_RUN stage.
+type ScriptRunStageOptions struct {
+	// The script to run.
+	// It is run by the shell configured in piped in the application directory at the target commit.
+	Run string `json:"run"`
+	// The additional environment variables passed to the script.
+	Env map[string]string `json:"env"`
+	// The exit codes completing the stage with warnings instead of failing it.
+	WarningExitCodes []int `json:"warningExitCodes"`
+	// The names of the tools made available in the PATH of the script.
+	// They can be the tools defined in the piped configuration
+	// or the built-in ones such as kubectl and helm.
+	Tools []string `json:"tools"`
+}
+
+func (opts *ScriptRunStageOptions) Validate() error {
+	if strings.TrimSpace(opts.Run) == "" {
+		return fmt.Errorf("run of SCRIPT_RUN stage must not be empty")
+	}
+	for _, c := range opts.WarningExitCodes {
+		if c <= 0 || c > 255 {
+			return fmt.Errorf("warningExitCodes of SCRIPT_RUN stage must be between 1 and 255")
+		}
+	}
+	for _, t := range opts.Tools {
+		if t == "" {
+			return fmt.Errorf("tools of SCRIPT_RUN stage must not contain an empty name")
+		}
+	}
+	return nil
+}
+
+// NotifyStageOptions contains all configurable values for a NOTIFY stage.
+type NotifyStageOptions struct {
+	// The message to send.
+	// It is a Go template rendered with the information of the deployment
+	// such as {{ .App.Name }}, {{ .App.Env }} and {{ .Commit.Hash }}.
+	Message string `json:"message"`
+	// The names of the notification receivers configured in piped to send the message to.
+	// When empty, the message is sent as a DEPLOYMENT_MESSAGE event
+	// to the receivers of the matching notification routes.
+	Receivers []string `json:"receivers"`
+}
+
+func (opts *NotifyStageOptions) Validate() error {
+	if strings.TrimSpace(opts.Message) == "" {
+		return fmt.Errorf("message of NOTIFY stage must not be empty")
+	}
+	for _, r := range opts.Receivers {
+		if r == "" {
+			return fmt.Errorf("receivers of NOTIFY stage must not contain an empty name")
+		}
+	}
+	return nil
+}
+
+// EventPublishStageOptions contains all configurable values for an EVENT_PUBLISH stage.
+type EventPublishStageOptions struct {
+	// The name of the event to register.
+	Name string `json:"name"`
+	// The data of the event.
+	// It is a Go template rendered with the information of the deployment
+	// such as {{ .Commit.Hash }} and {{ .Deployment.Version }}.
+	Data string `json:"data"`
+	// The labels of the event.
+	// They must exactly match the labels of the event watcher to be handled.
+	Labels map[string]string `json:"labels"`
+}
+
+func (opts *EventPublishStageOptions) Validate() error {
+	if opts.Name == "" {
+		return fmt.Errorf("name of EVENT_PUBLISH stage must not be empty")
+	}
+	if strings.TrimSpace(opts.Data) == "" {
+		return fmt.Errorf("data of EVENT_PUBLISH stage must not be empty")
+	}
+	for k, v := range opts.Labels {
+		if k == "" || v == "" {
+			return fmt.Errorf("labels of EVENT_PUBLISH stage must not contain an empty key or value")
+		}
+	}
+	return nil
+}
+
 // AnalysisStageOptions contains all configurable values for a K8S_ANALYSIS stage.
 type AnalysisStageOptions struct {
 	// How long the analysis process should be executed.
@@ -322,15 +958,112 @@ type AnalysisStageOptions struct {
 	Metrics          []TemplatableAnalysisMetrics `json:"metrics"`
 	Logs             []TemplatableAnalysisLog     `json:"logs"`
 	Https            []TemplatableAnalysisHTTP    `json:"https"`
+	// Configuration for analysis by the failures observed on the pods.
+	// Available only for Kubernetes application.
+	KubernetesPodFailures []AnalysisKubernetesPodFailures `json:"kubernetesPodFailures"`
+	// Configuration for analysis by the readiness and the restarts of the pods during the analysis.
+	// Available only for Kubernetes application.
+	KubernetesPods []AnalysisKubernetesPods `json:"kubernetesPods"`
+	// Configuration for analysis by the lag of the canary message-queue consumer
+	// compared with the baseline one.
+	ConsumerLags []AnalysisConsumerLag `json:"consumerLags"`
+	// Configuration for excluding the evaluations performed
+	// while the application pods were being preempted or evicted.
+	// Empty means all evaluations are used.
+	PreemptionTolerance *AnalysisPreemptionTolerance `json:"preemptionTolerance"`
+	// Profiles to adjust the strictness of the analysis by the environment of the application.
+	// The profile keyed by the environment name is applied on top of the configured values.
+	// Empty means the configured values are used as is in all environments.
+	StrictnessProfiles map[string]AnalysisStrictnessProfile `json:"strictnessProfiles"`
+}
+
+// AnalysisStrictnessProfile contains the values overriding the analysis configuration
+// while deploying to a specific environment.
+type AnalysisStrictnessProfile struct {
+	// The number of failures allowed for all analyses, including the ones using templates.
+	// Empty means the configured failureLimit of each analysis is used.
+	FailureLimit *int `json:"failureLimit"`
+	// Whether the analysis failure should be reported as a warning
+	// instead of failing the stage.
+	ReportOnly bool `json:"reportOnly"`
+}
+
+func (p *AnalysisStrictnessProfile) Validate() error {
+	if p.FailureLimit != nil && *p.FailureLimit < 0 {
+		return fmt.Errorf("failureLimit of analysis strictness profile must not be negative")
+	}
+	return nil
+}
+
+// AnalysisPreemptionTolerance contains the configurable values for tolerating
+// the pod restarts caused by node preemption, e.g. running on spot instances.
+type AnalysisPreemptionTolerance struct {
+	// The variant whose pod preemptions should be tolerated.
+	// Default is canary.
+	Variant string `json:"variant" default:"canary"`
+	// How long the evaluations should be skipped after a pod was preempted.
+	// Default is 5m.
+	Window Duration `json:"window" default:"5m"`
 }
 
 func (a *AnalysisStageOptions) Validate() error {
 	if a.Duration == 0 {
 		return fmt.Errorf("the ANALYSIS stage requires duration field")
 	}
+	for i := range a.KubernetesPodFailures {
+		if err := a.KubernetesPodFailures[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range a.KubernetesPods {
+		if err := a.KubernetesPods[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range a.ConsumerLags {
+		if err := a.ConsumerLags[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for env, p := range a.StrictnessProfiles {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid strictness profile for environment %s: %w", env, err)
+		}
+	}
 	return nil
 }
 
+type RollbackApprovalDefaultAction string
+
+const (
+	// RollbackApprovalDefaultActionRollback executes the rollback
+	// when no approval was received before the timeout.
+	RollbackApprovalDefaultActionRollback RollbackApprovalDefaultAction = "rollback"
+	// RollbackApprovalDefaultActionSkip skips the rollback
+	// when no approval was received before the timeout.
+	RollbackApprovalDefaultActionSkip RollbackApprovalDefaultAction = "skip"
+)
+
+// RollbackApproval contains all configurable values for waiting a manual approval
+// before executing the rollback of a deployment whose ANALYSIS stage failed.
+type RollbackApproval struct {
+	// The maximum length of time to wait for an approval.
+	// Defaults to 6h.
+	Timeout Duration `json:"timeout" default:"6h"`
+	// What should be done when no approval was received before timeout.
+	// Can be "rollback" or "skip". Defaults to "rollback".
+	DefaultAction RollbackApprovalDefaultAction `json:"defaultAction" default:"rollback"`
+}
+
+func (a *RollbackApproval) Validate() error {
+	switch a.DefaultAction {
+	case RollbackApprovalDefaultActionRollback, RollbackApprovalDefaultActionSkip:
+		return nil
+	default:
+		return fmt.Errorf("unsupported defaultAction %q for rollbackApproval", a.DefaultAction)
+	}
+}
+
 type AnalysisTemplateRef struct {
 	Name string `json:"name"`
 	// TODO: Rename args to appArgs
@@ -385,6 +1118,132 @@ func (e *SecretEncryption) Validate() error {
 	return nil
 }
 
+// SopsDecryption contains the files encrypted by sops.
+// They are decrypted in place by using the key material configured in piped
+// before loading the application manifests.
+type SopsDecryption struct {
+	// List of files encrypted by sops to be decrypted.
+	// The paths are relative to the application directory.
+	DecryptionTargets []string `json:"decryptionTargets"`
+	// The version of sops used to decrypt.
+	// Empty means the version set as default in piped.
+	Version string `json:"version"`
+}
+
+func (d *SopsDecryption) Validate() error {
+	for _, t := range d.DecryptionTargets {
+		if t == "" {
+			return fmt.Errorf("decryptionTargets in sops must not contain an empty path")
+		}
+		if !isInsideAppDir(t) {
+			return fmt.Errorf("sops decryption target %s must be inside the application directory", t)
+		}
+	}
+	return nil
+}
+
+// ExternalSecrets contains the files referencing the secrets stored in the external secret managers.
+// The references like {{ .secrets.Vault "secret/app/db#password" }} in the files
+// are replaced in place by the secrets fetched with the credentials configured in piped
+// before loading the application manifests.
+type ExternalSecrets struct {
+	// List of files referencing the external secrets.
+	// The paths are relative to the application directory.
+	Targets []string `json:"targets"`
+}
+
+func (e *ExternalSecrets) Validate() error {
+	for _, t := range e.Targets {
+		if t == "" {
+			return fmt.Errorf("targets in externalSecrets must not contain an empty path")
+		}
+		if !isInsideAppDir(t) {
+			return fmt.Errorf("external secrets target %s must be inside the application directory", t)
+		}
+	}
+	return nil
+}
+
+// isInsideAppDir reports whether the given path relative to the application directory
+// does not point outside of it.
+func isInsideAppDir(path string) bool {
+	clean := filepath.Clean(path)
+	return !filepath.IsAbs(clean) && clean != ".." && !strings.HasPrefix(clean, ".."+string(filepath.Separator))
+}
+
+// DeploymentOCISource represents an OCI artifact published by CI
+// to be used as the source of the application manifests.
+type DeploymentOCISource struct {
+	// The reference to the artifact by tag or digest.
+	// e.g. ghcr.io/org/manifests:v1.0.0, ghcr.io/org/manifests@sha256:...
+	Reference string `json:"reference"`
+}
+
+func (s *DeploymentOCISource) Validate() error {
+	if s.Reference == "" {
+		return fmt.Errorf("reference of ociSource must be set")
+	}
+	return nil
+}
+
+// DeploymentTrigger represents the conditions to trigger a new deployment
+// instead of triggering for every commit touching the application directory.
+type DeploymentTrigger struct {
+	// List of glob patterns of the files whose changes will trigger the deployment.
+	// The paths are relative to the repository root.
+	// Default is all files inside the application directory.
+	Includes []string `json:"includes"`
+	// List of glob patterns of the files whose changes should not trigger the deployment.
+	// Excludes are prioritized over Includes.
+	Excludes []string `json:"excludes"`
+	// Glob pattern of the Git tag names, e.g. v*.
+	// When specified, the deployment is triggered only when a new tag matching it
+	// was pushed, and the tagged commit is deployed.
+	Tag string `json:"tag"`
+	// Cron expression to periodically sync the application to the last deployed commit,
+	// e.g. "0 3 * * *" to correct the configuration drift at 03:00 every day.
+	// The time zone can be specified by the CRON_TZ prefix, e.g. "CRON_TZ=Asia/Tokyo 0 3 * * *".
+	Schedule string `json:"schedule"`
+}
+
+func (t *DeploymentTrigger) Validate() error {
+	if _, err := filematcher.NewPatternMatcher(t.Includes); err != nil {
+		return fmt.Errorf("invalid trigger.includes: %w", err)
+	}
+	if _, err := filematcher.NewPatternMatcher(t.Excludes); err != nil {
+		return fmt.Errorf("invalid trigger.excludes: %w", err)
+	}
+	if t.Tag != "" {
+		if _, err := path.Match(t.Tag, ""); err != nil {
+			return fmt.Errorf("invalid trigger.tag: %w", err)
+		}
+		if len(t.Includes) > 0 || len(t.Excludes) > 0 {
+			return fmt.Errorf("trigger.tag can not be used together with trigger.includes or trigger.excludes")
+		}
+	}
+	if t.Schedule != "" {
+		if _, err := cron.ParseStandard(t.Schedule); err != nil {
+			return fmt.Errorf("invalid trigger.schedule: %w", err)
+		}
+	}
+	return nil
+}
+
+type ConcurrencyPolicy string
+
+const (
+	// ConcurrencyPolicyQueue deploys all triggered deployments one by one
+	// in the order they were triggered.
+	ConcurrencyPolicyQueue ConcurrencyPolicy = "queue"
+	// ConcurrencyPolicyCancelInProgress cancels the planning or running deployment
+	// as well as the queued ones when a newer deployment was triggered,
+	// so that only the newest one is deployed.
+	ConcurrencyPolicyCancelInProgress ConcurrencyPolicy = "cancel-in-progress"
+	// ConcurrencyPolicySkipIntermediate waits for the planning or running deployment
+	// and then deploys only the newest one of the queued deployments.
+	ConcurrencyPolicySkipIntermediate ConcurrencyPolicy = "skip-intermediate"
+)
+
 // DeploymentNotification represents the way to send to users.
 type DeploymentNotification struct {
 	// List of users to be notified for each event.
@@ -408,3 +1267,96 @@ func (n *NotificationMention) Validate() error {
 	}
 	return fmt.Errorf("event %q is incorrect as NotificationEventType", n.Event)
 }
+
+// DriftDetection represents the configuration used while detecting the configuration drift.
+type DriftDetection struct {
+	// List of fields whose differences should not be reported as a configuration drift.
+	// e.g. spec.replicas of a Deployment scaled by HorizontalPodAutoscaler.
+	IgnoreFields []DriftDetectionIgnoreField `json:"ignoreFields"`
+}
+
+func (d *DriftDetection) Validate() error {
+	for _, f := range d.IgnoreFields {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DriftDetectionIgnoreField represents the fields to be ignored
+// while comparing the resources defined in Git with the live ones.
+type DriftDetectionIgnoreField struct {
+	// The kind of the resources whose fields are ignored.
+	// Empty means all kinds. This is used by KUBERNETES application only.
+	Kind string `json:"kind"`
+	// The name of the resources whose fields are ignored.
+	// Empty means all names. This is used by KUBERNETES application only.
+	Name string `json:"name"`
+	// List of the dot-separated paths to the ignored fields, e.g. spec.replicas.
+	// All fields under the specified path are ignored as well.
+	// "*" matches any map key or slice index, e.g. spec.template.spec.containers.*.image,
+	// and a dot inside a map key can be escaped by backslash.
+	Paths []string `json:"paths"`
+}
+
+func (f *DriftDetectionIgnoreField) Validate() error {
+	if len(f.Paths) == 0 {
+		return fmt.Errorf("paths of driftDetection.ignoreFields must be set")
+	}
+	for _, p := range f.Paths {
+		if p == "" {
+			return fmt.Errorf("path of driftDetection.ignoreFields must not be empty")
+		}
+	}
+	return nil
+}
+
+// PostSync represents the configuration used after the deployment was completed successfully.
+type PostSync struct {
+	// The applications to be deployed as the next block of the deployment chain.
+	Chain *DeploymentChain `json:"chain"`
+}
+
+func (p *PostSync) Validate() error {
+	if p.Chain != nil {
+		return p.Chain.Validate()
+	}
+	return nil
+}
+
+// DeploymentChain represents the applications deployed by the control-plane
+// only after the deployment of this application was completed successfully.
+// The deployments of those applications can chain the other applications as well.
+type DeploymentChain struct {
+	// List of the matchers to find the applications to be deployed in the project.
+	Applications []DeploymentChainApplication `json:"applications"`
+	// List of the keys of the deployment metadata passed to the chained deployments.
+	// They can be found in their deployment metadata with "ChainInput." prefix.
+	Outputs []string `json:"outputs"`
+}
+
+func (c *DeploymentChain) Validate() error {
+	if len(c.Applications) == 0 {
+		return fmt.Errorf("applications of postSync.chain must be set")
+	}
+	for _, a := range c.Applications {
+		if a.Name == "" && len(a.Labels) == 0 {
+			return fmt.Errorf("either name or labels of postSync.chain.applications must be set")
+		}
+	}
+	for _, o := range c.Outputs {
+		if o == "" {
+			return fmt.Errorf("output of postSync.chain must not be empty")
+		}
+	}
+	return nil
+}
+
+// DeploymentChainApplication represents the matcher of the applications to be chained.
+type DeploymentChainApplication struct {
+	// The name of the applications.
+	Name string `json:"name"`
+	// The labels all of that the applications must have.
+	Labels map[string]string `json:"labels"`
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+)
+
+const (
+	AppEngineShardByIP     = "IP"
+	AppEngineShardByCookie = "COOKIE"
+	AppEngineShardByRandom = "RANDOM"
+)
+
+// AppEngineDeploymentSpec represents a deployment configuration for App Engine application.
+type AppEngineDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for App Engine deployment such as the service name...
+	Input AppEngineDeploymentInput `json:"input"`
+	// Configuration for quick sync.
+	QuickSync AppEngineSyncStageOptions `json:"quickSync"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *AppEngineDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	switch s.Input.ShardBy {
+	case AppEngineShardByIP, AppEngineShardByCookie, AppEngineShardByRandom:
+	default:
+		return fmt.Errorf("shardBy must be one of %s, %s or %s", AppEngineShardByIP, AppEngineShardByCookie, AppEngineShardByRandom)
+	}
+	return nil
+}
+
+type AppEngineDeploymentInput struct {
+	// The name of version manifest file placing in application directory.
+	// The manifest is the Version resource of App Engine Admin API in YAML format
+	// and both the standard and the flexible environments are supported.
+	// Default is version.yaml
+	VersionManifestFile string `json:"versionManifestFile"`
+	// The name of App Engine service to be deployed.
+	// Default is default.
+	Service string `json:"service" default:"default"`
+	// The method to split the traffic between the versions. One of IP, COOKIE or RANDOM is available.
+	// Default is RANDOM.
+	ShardBy string `json:"shardBy" default:"RANDOM"`
+	// Automatically reverts to the previous state when the deployment is failed.
+	// Default is true.
+	AutoRollback bool `json:"autoRollback" default:"true"`
+}
+
+// AppEngineSyncStageOptions contains all configurable values for a APPENGINE_SYNC stage.
+type AppEngineSyncStageOptions struct {
+}
+
+// AppEngineDeployVersionStageOptions contains all configurable values for a APPENGINE_DEPLOY_VERSION stage.
+type AppEngineDeployVersionStageOptions struct {
+}
+
+// AppEngineMigrateTrafficStageOptions contains all configurable values for a APPENGINE_MIGRATE_TRAFFIC stage.
+type AppEngineMigrateTrafficStageOptions struct {
+	// Percentage of traffic should be migrated to the new version.
+	Percent Percentage `json:"percent"`
+}
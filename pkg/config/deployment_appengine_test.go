@@ -0,0 +1,104 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAppEngineDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		expectedError      error
+	}{
+		{
+			fileName:           "testdata/application/appengine-app.yaml",
+			expectedKind:       KindAppEngineApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &AppEngineDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: AppEngineDeploymentInput{
+					Service:      "api",
+					ShardBy:      AppEngineShardByRandom,
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/appengine-app-canary.yaml",
+			expectedKind:       KindAppEngineApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &AppEngineDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                               model.StageAppEngineDeployVersion,
+								AppEngineDeployVersionStageOptions: &AppEngineDeployVersionStageOptions{},
+							},
+							{
+								Name: model.StageAppEngineMigrateTraffic,
+								AppEngineMigrateTrafficStageOptions: &AppEngineMigrateTrafficStageOptions{
+									Percent: Percentage{Number: 10},
+								},
+							},
+							{
+								Name: model.StageAppEngineMigrateTraffic,
+								AppEngineMigrateTrafficStageOptions: &AppEngineMigrateTrafficStageOptions{
+									Percent: Percentage{Number: 100},
+								},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: AppEngineDeploymentInput{
+					Service:      "default",
+					ShardBy:      AppEngineShardByIP,
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/appengine-app-invalid-shard-by.yaml",
+			expectedError: fmt.Errorf("shardBy must be one of IP, COOKIE or RANDOM"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+)
+
+// AzureFunctionsProductionSlot is the name of the slot serving the production traffic.
+const AzureFunctionsProductionSlot = "production"
+
+// AzureFunctionsDeploymentSpec represents a deployment configuration for Azure Functions application.
+type AzureFunctionsDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for Azure Functions deployment such as the app name, package URL...
+	Input AzureFunctionsDeploymentInput `json:"input"`
+	// Configuration for quick sync.
+	QuickSync AzureFunctionsSyncStageOptions `json:"quickSync"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *AzureFunctionsDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	if s.Input.ResourceGroup == "" {
+		return fmt.Errorf("resourceGroup is required")
+	}
+	if s.Input.AppName == "" {
+		return fmt.Errorf("appName is required")
+	}
+	if s.Input.PackageURL == "" {
+		return fmt.Errorf("packageUrl is required")
+	}
+	if s.Input.Slot == AzureFunctionsProductionSlot {
+		return fmt.Errorf("slot must not be the production slot")
+	}
+	return nil
+}
+
+type AzureFunctionsDeploymentInput struct {
+	// The name of the resource group containing the app.
+	ResourceGroup string `json:"resourceGroup"`
+	// The name of the Function App to be deployed.
+	// Web Apps can also be deployed since they share the same deployment mechanism.
+	AppName string `json:"appName"`
+	// The deployment slot where the package is deployed before swapping it with the production slot.
+	// Default is staging
+	Slot string `json:"slot" default:"staging"`
+	// The URL of the zip package to run the app from.
+	// The package must be uploaded in advance, e.g. to Azure Blob Storage.
+	PackageURL string `json:"packageUrl"`
+	// The app settings applied to the slot together with the package.
+	AppSettings map[string]string `json:"appSettings,omitempty"`
+	// Automatically reverts to the previous state when the deployment is failed.
+	// Default is true.
+	AutoRollback bool `json:"autoRollback" default:"true"`
+}
+
+// AzureFunctionsSyncStageOptions contains all configurable values for a AZURE_FUNCTIONS_SYNC stage.
+type AzureFunctionsSyncStageOptions struct {
+}
+
+// AzureFunctionsCanaryRolloutStageOptions contains all configurable values for a AZURE_FUNCTIONS_CANARY_ROLLOUT stage.
+type AzureFunctionsCanaryRolloutStageOptions struct {
+}
+
+// AzureFunctionsPromoteStageOptions contains all configurable values for a AZURE_FUNCTIONS_PROMOTE stage.
+type AzureFunctionsPromoteStageOptions struct {
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAzureFunctionsDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		expectedError      error
+	}{
+		{
+			fileName:           "testdata/application/azurefunctions-app.yaml",
+			expectedKind:       KindAzureFunctionsApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &AzureFunctionsDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: AzureFunctionsDeploymentInput{
+					ResourceGroup: "pipecd",
+					AppName:       "hello",
+					Slot:          "staging",
+					PackageURL:    "https://pipecd.blob.core.windows.net/packages/hello-v1.0.0.zip",
+					AppSettings: map[string]string{
+						"FUNCTIONS_WORKER_RUNTIME": "node",
+					},
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/azurefunctions-app-canary.yaml",
+			expectedKind:       KindAzureFunctionsApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &AzureFunctionsDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                                    model.StageAzureFunctionsCanaryRollout,
+								AzureFunctionsCanaryRolloutStageOptions: &AzureFunctionsCanaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageWaitApproval,
+								WaitApprovalStageOptions: &WaitApprovalStageOptions{
+									Timeout: defaultWaitApprovalTimeout,
+								},
+							},
+							{
+								Name:                              model.StageAzureFunctionsPromote,
+								AzureFunctionsPromoteStageOptions: &AzureFunctionsPromoteStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: AzureFunctionsDeploymentInput{
+					ResourceGroup: "pipecd",
+					AppName:       "hello",
+					Slot:          "canary",
+					PackageURL:    "https://pipecd.blob.core.windows.net/packages/hello-v1.0.0.zip",
+					AutoRollback:  true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/azurefunctions-app-production-slot.yaml",
+			expectedError: fmt.Errorf("slot must not be the production slot"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
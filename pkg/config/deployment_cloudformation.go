@@ -0,0 +1,70 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+)
+
+// CloudFormationDeploymentSpec represents a deployment configuration for CloudFormation application.
+type CloudFormationDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for CloudFormation deployment such as the stack name, template file...
+	Input CloudFormationDeploymentInput `json:"input"`
+	// Configuration for quick sync.
+	QuickSync CloudFormationSyncStageOptions `json:"quickSync"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *CloudFormationDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	if s.Input.StackName == "" {
+		return fmt.Errorf("stackName is required")
+	}
+	return nil
+}
+
+type CloudFormationDeploymentInput struct {
+	// The name of the stack to be deployed.
+	StackName string `json:"stackName"`
+	// The name of template file placing in application directory.
+	// Both CloudFormation and SAM templates are supported.
+	// Default is template.yaml
+	TemplateFile string `json:"templateFile"`
+	// The values of the template parameters keyed by the parameter name.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// The capabilities to acknowledge such as CAPABILITY_IAM.
+	// CAPABILITY_AUTO_EXPAND is always acknowledged for SAM templates.
+	Capabilities []string `json:"capabilities,omitempty"`
+	// The tags given to the stack and its resources.
+	Tags map[string]string `json:"tags,omitempty"`
+	// Automatically reverts all changes from all stages when one of them failed.
+	// Default is true.
+	AutoRollback bool `json:"autoRollback" default:"true"`
+}
+
+// CloudFormationSyncStageOptions contains all configurable values for a CLOUDFORMATION_SYNC stage.
+type CloudFormationSyncStageOptions struct {
+}
+
+// CloudFormationPlanStageOptions contains all configurable values for a CLOUDFORMATION_PLAN stage.
+type CloudFormationPlanStageOptions struct {
+}
+
+// CloudFormationApplyStageOptions contains all configurable values for a CLOUDFORMATION_APPLY stage.
+type CloudFormationApplyStageOptions struct {
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestCloudFormationDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		expectedError      error
+	}{
+		{
+			fileName:           "testdata/application/cloudformation-app.yaml",
+			expectedKind:       KindCloudFormationApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CloudFormationDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CloudFormationDeploymentInput{
+					StackName:    "hello",
+					Parameters:   map[string]string{"Environment": "dev"},
+					Capabilities: []string{"CAPABILITY_IAM"},
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/cloudformation-app-plan-apply.yaml",
+			expectedKind:       KindCloudFormationApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CloudFormationDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                           model.StageCloudFormationPlan,
+								CloudFormationPlanStageOptions: &CloudFormationPlanStageOptions{},
+							},
+							{
+								Name: model.StageWaitApproval,
+								WaitApprovalStageOptions: &WaitApprovalStageOptions{
+									Timeout: defaultWaitApprovalTimeout,
+								},
+							},
+							{
+								Name:                            model.StageCloudFormationApply,
+								CloudFormationApplyStageOptions: &CloudFormationApplyStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CloudFormationDeploymentInput{
+					StackName:    "hello",
+					TemplateFile: "sam.yaml",
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/cloudformation-app-missing-stack-name.yaml",
+			expectedError: fmt.Errorf("stackName is required"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
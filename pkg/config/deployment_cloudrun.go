@@ -14,6 +14,11 @@
 
 package config
 
+import (
+	"fmt"
+	"regexp"
+)
+
 // CloudRunDeploymentSpec represents a deployment configuration for CloudRun application.
 type CloudRunDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -28,9 +33,14 @@ func (s *CloudRunDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if tag := s.Input.PreviewTag; tag != "" && !cloudRunTagRegex.MatchString(tag) {
+		return fmt.Errorf("invalid previewTag %q: it must consist of lowercase letters, digits and hyphens, and start with a letter", tag)
+	}
 	return nil
 }
 
+var cloudRunTagRegex = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
 type CloudRunDeploymentInput struct {
 	// The name of service manifest file placing in application directory.
 	// Default is service.yaml
@@ -38,6 +48,10 @@ type CloudRunDeploymentInput struct {
 	// Automatically reverts to the previous state when the deployment is failed.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
+	// The tag given to the revision of the new version by CLOUDRUN_PROMOTE stage.
+	// The revision can be previewed at its own URL with this tag regardless of the traffic percentage.
+	// Empty means no tag is given.
+	PreviewTag string `json:"previewTag"`
 }
 
 // CloudRunSyncStageOptions contains all configurable values for a CLOUDRUN_SYNC stage.
@@ -15,6 +15,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -44,6 +45,25 @@ func TestCloudRunDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/cloudrun-app-preview-tag.yaml",
+			expectedKind:       KindCloudRunApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CloudRunDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CloudRunDeploymentInput{
+					AutoRollback: true,
+					PreviewTag:   "canary",
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/cloudrun-app-invalid-preview-tag.yaml",
+			expectedError: fmt.Errorf("invalid previewTag \"Canary_1\": it must consist of lowercase letters, digits and hyphens, and start with a letter"),
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.fileName, func(t *testing.T) {
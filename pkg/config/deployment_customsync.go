@@ -0,0 +1,59 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "fmt"
+
+// CustomSyncDeploymentSpec represents a deployment configuration for the application
+// deployed by the command provided by the CUSTOM_SYNC cloud provider.
+type CustomSyncDeploymentSpec struct {
+	GenericDeploymentSpec
+	// Input for the command such as its arguments, environment variables...
+	Input CustomSyncDeploymentInput `json:"input"`
+	// Configuration for quick sync.
+	QuickSync CustomSyncStageOptions `json:"quickSync"`
+}
+
+// Validate returns an error if any wrong configuration value was found.
+func (s *CustomSyncDeploymentSpec) Validate() error {
+	if err := s.GenericDeploymentSpec.Validate(); err != nil {
+		return err
+	}
+	if s.Input.Timeout < 0 {
+		return fmt.Errorf("input.timeout must be greater than or equal to 0")
+	}
+	return nil
+}
+
+type CustomSyncDeploymentInput struct {
+	// The arguments passed to the command.
+	Args []string `json:"args"`
+	// The additional environment variables passed to the command.
+	Env map[string]string `json:"env"`
+	// The maximum length of time to wait for the command to complete.
+	// Empty means the command is run until the stage times out.
+	Timeout Duration `json:"timeout"`
+	// Automatically reverts to the previous state when the deployment is failed.
+	// The command is run again at the last deployed commit with PIPECD_SYNC_ACTION=rollback.
+	// Default is true.
+	AutoRollback bool `json:"autoRollback" default:"true"`
+}
+
+// CustomSyncStageOptions contains all configurable values for a CUSTOM_SYNC stage.
+type CustomSyncStageOptions struct {
+	// The arguments passed to the command instead of the ones in input.
+	// Empty means the ones in input are used.
+	Args []string `json:"args"`
+}
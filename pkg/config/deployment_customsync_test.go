@@ -0,0 +1,161 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestCustomSyncDeploymentConfig(t *testing.T) {
+	testcases := []struct {
+		fileName           string
+		expectedKind       Kind
+		expectedAPIVersion string
+		expectedSpec       interface{}
+		expectedError      error
+	}{
+		{
+			fileName:           "testdata/application/customsync-app.yaml",
+			expectedKind:       KindCustomSyncApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CustomSyncDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CustomSyncDeploymentInput{
+					Args: []string{"deploy", "--manifest", "app.yaml"},
+					Env: map[string]string{
+						"TARGET": "production",
+					},
+					Timeout:      Duration(30 * time.Minute),
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/customsync-app-pipeline.yaml",
+			expectedKind:       KindCustomSyncApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CustomSyncDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageCustomSync,
+								CustomSyncStageOptions: &CustomSyncStageOptions{
+									Args: []string{"deploy", "--manifest", "app.yaml", "--canary"},
+								},
+							},
+							{
+								Name: model.StageWaitApproval,
+								WaitApprovalStageOptions: &WaitApprovalStageOptions{
+									Timeout: defaultWaitApprovalTimeout,
+								},
+							},
+							{
+								Name:                   model.StageCustomSync,
+								CustomSyncStageOptions: &CustomSyncStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CustomSyncDeploymentInput{
+					Args:         []string{"deploy", "--manifest", "app.yaml"},
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/customsync-app-plugin-stage.yaml",
+			expectedKind:       KindCustomSyncApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CustomSyncDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:               model.Stage("PLUGIN_MAINFRAME_DEPLOY"),
+								PluginStageOptions: json.RawMessage(`{"target":"blue"}`),
+							},
+							{
+								Name: model.Stage("PLUGIN_MAINFRAME_SWITCH"),
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CustomSyncDeploymentInput{
+					Args:         []string{"deploy", "--manifest", "app.yaml"},
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/customsync-app-stage-retries.yaml",
+			expectedKind:       KindCustomSyncApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &CustomSyncDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:    model.StageCustomSync,
+								Timeout: Duration(10 * time.Minute),
+								Retries: &StageRetries{
+									Limit:   3,
+									Backoff: Duration(30 * time.Second),
+								},
+								CustomSyncStageOptions: &CustomSyncStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: CustomSyncDeploymentInput{
+					Args:         []string{"deploy", "--manifest", "app.yaml"},
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/customsync-app-invalid-timeout.yaml",
+			expectedError: fmt.Errorf("input.timeout must be greater than or equal to 0"),
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			cfg, err := LoadFromYAML(tc.fileName)
+			require.Equal(t, tc.expectedError, err)
+			if err == nil {
+				assert.Equal(t, tc.expectedKind, cfg.Kind)
+				assert.Equal(t, tc.expectedAPIVersion, cfg.APIVersion)
+				assert.Equal(t, tc.expectedSpec, cfg.spec)
+			}
+		})
+	}
+}
@@ -14,7 +14,12 @@
 
 package config
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
 
 // ECSDeploymentSpec represents a deployment configuration for ECS application.
 type ECSDeploymentSpec struct {
@@ -30,6 +35,21 @@ func (s *ECSDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if bg := s.Input.BlueGreen; bg != nil {
+		if err := bg.Validate(); err != nil {
+			return err
+		}
+		if bg.Controller == ECSBlueGreenControllerNative && len(s.Input.TargetGroups.Canary) == 0 {
+			return fmt.Errorf("canary target group is required to switch the traffic in blue/green deployment")
+		}
+		if s.Pipeline != nil {
+			for _, stage := range s.Pipeline.Stages {
+				if _, ok := ecsBlueGreenUnsupportedStages[stage.Name]; ok {
+					return fmt.Errorf("%s stage can not be used in blue/green deployment, use ECS_SYNC stage instead", stage.Name)
+				}
+			}
+		}
+	}
 	return nil
 }
 
@@ -45,6 +65,79 @@ type ECSDeploymentInput struct {
 	// Automatically reverts all changes from all stages when one of them failed.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
+	// Configuration for the blue/green deployment done by ECS_SYNC stage.
+	// Empty means the task set is replaced in the same target group.
+	BlueGreen *ECSBlueGreen `json:"blueGreen"`
+}
+
+const (
+	// ECSBlueGreenControllerNative starts the new task set in the target group not receiving the traffic
+	// and swaps the target groups in the listener once the task set became stable.
+	ECSBlueGreenControllerNative = "NATIVE"
+	// ECSBlueGreenControllerCodeDeploy leaves the deployment to AWS CodeDeploy.
+	// The service must be created with CODE_DEPLOY deployment controller beforehand.
+	ECSBlueGreenControllerCodeDeploy = "CODE_DEPLOY"
+)
+
+// ecsBlueGreenUnsupportedStages are the stages controlling the task sets or the traffic by themselves
+// so they can not be used together with the blue/green deployment.
+var ecsBlueGreenUnsupportedStages = map[model.Stage]struct{}{
+	model.StageECSCanaryRollout:  {},
+	model.StageECSPrimaryRollout: {},
+	model.StageECSTrafficRouting: {},
+	model.StageECSCanaryClean:    {},
+}
+
+type ECSBlueGreen struct {
+	// Which controller switches the traffic to the new task set.
+	// Available values are NATIVE and CODE_DEPLOY. Default is NATIVE.
+	Controller string `json:"controller" default:"NATIVE"`
+	// How long to wait for the new task set to become stable
+	// or the CodeDeploy deployment to complete.
+	// Default is 30m.
+	Timeout Duration `json:"timeout" default:"30m"`
+	// Configuration for CODE_DEPLOY controller.
+	CodeDeploy *ECSCodeDeploy `json:"codeDeploy"`
+}
+
+func (bg *ECSBlueGreen) Validate() error {
+	switch bg.Controller {
+	case ECSBlueGreenControllerNative:
+	case ECSBlueGreenControllerCodeDeploy:
+		if bg.CodeDeploy == nil {
+			return fmt.Errorf("codeDeploy is required for %s controller", ECSBlueGreenControllerCodeDeploy)
+		}
+		if err := bg.CodeDeploy.Validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported blue/green controller %q", bg.Controller)
+	}
+	if bg.Timeout <= 0 {
+		return fmt.Errorf("timeout of blue/green deployment must be positive")
+	}
+	return nil
+}
+
+type ECSCodeDeploy struct {
+	// The name of the CodeDeploy application.
+	ApplicationName string `json:"applicationName"`
+	// The name of the deployment group bound to the ECS service.
+	DeploymentGroupName string `json:"deploymentGroupName"`
+	// The name of the deployment configuration deciding how the traffic is shifted,
+	// e.g. CodeDeployDefault.ECSCanary10Percent5Minutes.
+	// Empty means the one configured in the deployment group.
+	DeploymentConfigName string `json:"deploymentConfigName"`
+}
+
+func (c *ECSCodeDeploy) Validate() error {
+	if c.ApplicationName == "" {
+		return fmt.Errorf("codeDeploy.applicationName is required")
+	}
+	if c.DeploymentGroupName == "" {
+		return fmt.Errorf("codeDeploy.deploymentGroupName is required")
+	}
+	return nil
 }
 
 type ECSTargetGroups struct {
@@ -16,6 +16,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -51,6 +52,66 @@ func TestECSDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/ecs-app-blue-green.yaml",
+			expectedKind:       KindECSApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &ECSDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: ECSDeploymentInput{
+					ServiceDefinitionFile: "/path/to/servicedef.yaml",
+					TaskDefinitionFile:    "/path/to/taskdef.yaml",
+					TargetGroups: ECSTargetGroups{
+						Primary: json.RawMessage(`{"containerName":"web","containerPort":80,"targetGroupArn":"arn:aws:elasticloadbalancing:xyz"}`),
+						Canary:  json.RawMessage(`{"containerName":"web","containerPort":80,"targetGroupArn":"arn:aws:elasticloadbalancing:abc"}`),
+					},
+					AutoRollback: true,
+					BlueGreen: &ECSBlueGreen{
+						Controller: ECSBlueGreenControllerNative,
+						Timeout:    Duration(30 * time.Minute),
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/ecs-app-blue-green-code-deploy.yaml",
+			expectedKind:       KindECSApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &ECSDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: ECSDeploymentInput{
+					ServiceDefinitionFile: "/path/to/servicedef.yaml",
+					TaskDefinitionFile:    "/path/to/taskdef.yaml",
+					TargetGroups: ECSTargetGroups{
+						Primary: json.RawMessage(`{"containerName":"web","containerPort":80,"targetGroupArn":"arn:aws:elasticloadbalancing:xyz"}`),
+					},
+					AutoRollback: true,
+					BlueGreen: &ECSBlueGreen{
+						Controller: ECSBlueGreenControllerCodeDeploy,
+						Timeout:    Duration(time.Hour),
+						CodeDeploy: &ECSCodeDeploy{
+							ApplicationName:      "simple",
+							DeploymentGroupName:  "simple-group",
+							DeploymentConfigName: "CodeDeployDefault.ECSCanary10Percent5Minutes",
+						},
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/ecs-app-blue-green-without-canary.yaml",
+			expectedError: fmt.Errorf("canary target group is required to switch the traffic in blue/green deployment"),
+		},
+		{
+			fileName:      "testdata/application/ecs-app-blue-green-with-canary-stage.yaml",
+			expectedError: fmt.Errorf("ECS_CANARY_ROLLOUT stage can not be used in blue/green deployment, use ECS_SYNC stage instead"),
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.fileName, func(t *testing.T) {
@@ -14,6 +14,17 @@
 
 package config
 
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
 // KubernetesDeploymentSpec represents a deployment configuration for Kubernetes application.
 type KubernetesDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -34,6 +45,11 @@ type KubernetesDeploymentSpec struct {
 	Workloads []K8sResourceReference `json:"workloads"`
 	// Which method should be used for traffic routing.
 	TrafficRouting *KubernetesTrafficRouting `json:"trafficRouting"`
+	// Configuration for the preview environments
+	// deployed by K8S_PREVIEW_ROLLOUT stage.
+	Preview *K8sPreview `json:"preview"`
+	// Additional labels and annotations added to all manifests applied by piped.
+	CommonMetadata *K8sCommonMetadata `json:"commonMetadata"`
 }
 
 // Validate returns an error if any wrong configuration value was found.
@@ -41,9 +57,126 @@ func (s *KubernetesDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if a := s.Input.RollbackApproval; a != nil {
+		if err := s.validateRollbackApproval(a); err != nil {
+			return err
+		}
+	}
+	if m := s.Input.MultiCluster; m != nil {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	if r := s.Input.WebhookRetry; r != nil {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	if l := s.Input.Lock; l != nil {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+	}
+	if o := s.Input.KustomizeBuildOptions; o != nil {
+		if err := o.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Input.Namespace == "" {
+		if s.Input.AutoCreateNamespace {
+			return fmt.Errorf("namespace must be specified to use autoCreateNamespace")
+		}
+		if s.Input.EnforceNamespace {
+			return fmt.Errorf("namespace must be specified to use enforceNamespace")
+		}
+	}
+	if filepath.IsAbs(s.Input.KustomizeOverlay) {
+		return fmt.Errorf("kustomizeOverlay must be a relative path to the application directory")
+	}
+	for stage, overlay := range s.Input.KustomizeStageOverlays {
+		if filepath.IsAbs(overlay) {
+			return fmt.Errorf("kustomize overlay for stage %s must be a relative path to the application directory", stage)
+		}
+	}
+	if p := s.Preview; p != nil {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	if m := s.CommonMetadata; m != nil {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	if r := s.TrafficRouting; r != nil {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Pipeline != nil {
+		var lastPrimaryDiffOnly bool
+		for _, stage := range s.Pipeline.Stages {
+			if stage.Name == model.StageK8sPrimaryRollout {
+				lastPrimaryDiffOnly = stage.K8sPrimaryRolloutStageOptions != nil && stage.K8sPrimaryRolloutStageOptions.DiffOnly
+			}
+			if stage.K8sDiffStageOptions != nil {
+				if err := stage.K8sDiffStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.K8sCanaryRolloutStageOptions != nil {
+				if err := stage.K8sCanaryRolloutStageOptions.Validate(s.TrafficRouting); err != nil {
+					return err
+				}
+			}
+			if stage.K8sTrafficRoutingStageOptions != nil {
+				if err := stage.K8sTrafficRoutingStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.K8sValidateStageOptions != nil {
+				if err := stage.K8sValidateStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.K8sVerifyImagesStageOptions != nil {
+				if err := stage.K8sVerifyImagesStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if stage.K8sJobRunStageOptions != nil {
+				if err := stage.K8sJobRunStageOptions.Validate(); err != nil {
+					return err
+				}
+			}
+			if o := stage.K8sPrimaryRolloutStageOptions; o != nil && o.WaitForReady != nil {
+				if err := o.WaitForReady.Validate(); err != nil {
+					return err
+				}
+			}
+			if s.Input.HelmChart == nil && stageHasHelmValues(stage) {
+				return fmt.Errorf("helmValues of %s stage can be used only for the application using a helm chart", stage.Name)
+			}
+		}
+		// The commit becomes the running one once the deployment was completed,
+		// so the previewed manifests must be applied by a later stage.
+		if lastPrimaryDiffOnly {
+			return fmt.Errorf("diffOnly %s stage must be followed by a %s stage applying the manifests", model.StageK8sPrimaryRollout, model.StageK8sPrimaryRollout)
+		}
+	}
 	return nil
 }
 
+func stageHasHelmValues(s PipelineStage) bool {
+	if o := s.K8sCanaryRolloutStageOptions; o != nil && o.HelmValues != nil {
+		return true
+	}
+	if o := s.K8sBaselineRolloutStageOptions; o != nil && o.HelmValues != nil {
+		return true
+	}
+	return false
+}
+
 // KubernetesDeploymentInput represents needed input for triggering a Kubernetes deployment.
 type KubernetesDeploymentInput struct {
 	// List of manifest files in the application directory used to deploy.
@@ -56,6 +189,14 @@ type KubernetesDeploymentInput struct {
 	KustomizeVersion string `json:"kustomizeVersion"`
 	// List of options that should be used by Kustomize commands.
 	KustomizeOptions map[string]string `json:"kustomizeOptions"`
+	// Configurable flags for the "kustomize build" command.
+	KustomizeBuildOptions *InputKustomizeBuildOptions `json:"kustomizeBuildOptions"`
+	// The path to the kustomize overlay directory to build, relative to the application directory.
+	// Empty means the application directory itself.
+	KustomizeOverlay string `json:"kustomizeOverlay"`
+	// Map from a stage name to the kustomize overlay directory used while executing that stage.
+	// The stages not listed here use kustomizeOverlay.
+	KustomizeStageOverlays map[string]string `json:"kustomizeStageOverlays"`
 
 	// Version of helm will be used.
 	HelmVersion string `json:"helmVersion"`
@@ -66,10 +207,83 @@ type KubernetesDeploymentInput struct {
 
 	// The namespace where manifests will be applied.
 	Namespace string `json:"namespace"`
+	// Whether to create the namespace specified in namespace field
+	// before applying manifests when it does not exist.
+	// Default is false.
+	AutoCreateNamespace bool `json:"autoCreateNamespace"`
+	// Whether to override the namespace of all namespaced manifests
+	// with the one specified in namespace field.
+	// Default is false.
+	EnforceNamespace bool `json:"enforceNamespace"`
+	// Configuration for applying manifests by using server-side apply.
+	// Empty means the client-side apply will be used.
+	ServerSideApply *K8sServerSideApply `json:"serverSideApply"`
+	// Configuration for deploying the application to multiple clusters.
+	// Empty means the manifests will be applied to the cluster of
+	// the cloud provider configured for the application.
+	MultiCluster *K8sMultiCluster `json:"multiCluster"`
+	// Configuration for retrying to apply the manifests rejected
+	// because the admission webhooks of the cluster were unavailable.
+	// Empty means retrying each of them for 1 minute.
+	WebhookRetry *K8sWebhookRetry `json:"webhookRetry"`
+	// Configuration for the lock acquired while running the stages changing the cluster
+	// to prevent multiple pipeds sharing the cluster from deploying to the same resources at the same time.
+	// Empty means no lock is acquired.
+	Lock *K8sDeploymentLock `json:"lock"`
+
+	// Whether the resources managed by piped but no longer defined in Git
+	// should be removed while syncing or rolling out PRIMARY variant.
+	// This is applied regardless of the prune option of each stage.
+	Prune bool `json:"prune"`
+	// List of resource kinds that must never be removed while pruning.
+	// Empty means Namespace, PersistentVolume, PersistentVolumeClaim and CustomResourceDefinition.
+	PruneProtectedKinds []string `json:"pruneProtectedKinds"`
 
 	// Automatically reverts all deployment changes on failure.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
+	// Wait for a manual approval before executing the rollback
+	// when the deployment failed at an ANALYSIS stage.
+	// Empty means the rollback will be executed immediately.
+	RollbackApproval *RollbackApproval `json:"rollbackApproval"`
+}
+
+// K8sMultiCluster contains the configurable values for applying manifests
+// to multiple Kubernetes clusters.
+type K8sMultiCluster struct {
+	// List of the names of Kubernetes cloud providers configured in the piped
+	// where the manifests will be applied.
+	CloudProviders []string `json:"cloudProviders"`
+	// Whether to apply the manifests to all clusters in parallel.
+	// Default is false, the clusters will be handled one by one in the listed order.
+	Parallel bool `json:"parallel"`
+}
+
+func (m *K8sMultiCluster) Validate() error {
+	if len(m.CloudProviders) == 0 {
+		return fmt.Errorf("multiCluster.cloudProviders must contain at least one cloud provider")
+	}
+	names := make(map[string]struct{}, len(m.CloudProviders))
+	for _, n := range m.CloudProviders {
+		if n == "" {
+			return fmt.Errorf("multiCluster.cloudProviders must not contain an empty name")
+		}
+		if _, ok := names[n]; ok {
+			return fmt.Errorf("cloud provider %s was listed multiple times in multiCluster.cloudProviders", n)
+		}
+		names[n] = struct{}{}
+	}
+	return nil
+}
+
+// K8sServerSideApply contains the configurable values for applying manifests
+// by using "kubectl apply --server-side".
+type K8sServerSideApply struct {
+	// The name of the field manager used to track field ownership.
+	// Default is "piped".
+	FieldManager string `json:"fieldManager"`
+	// Whether to force the changes against the conflicts with other field managers.
+	ForceConflicts bool `json:"forceConflicts"`
 }
 
 type InputHelmChart struct {
@@ -88,6 +302,106 @@ type InputHelmChart struct {
 	// Whether to skip TLS certificate checks for the repository or not.
 	// This option will automatically set the value of HelmChartRepository.Insecure.
 	Insecure bool `json:"-"`
+	// The address of the OCI registry storing the chart when the specified repository is an OCI one.
+	// This option will automatically set the value of HelmChartRepository.Address.
+	OCIRegistry string `json:"-"`
+}
+
+// InputKustomizeBuildOptions contains the flags passed to the "kustomize build" command.
+type InputKustomizeBuildOptions struct {
+	// Whether to enable the helm chart inflation generator (--enable-helm).
+	// Requires kustomize v4.1.0 or later.
+	EnableHelm bool `json:"enableHelm"`
+	// The restrictor for loading files outside of the kustomization root (--load-restrictor).
+	// Possible values are LoadRestrictionsRootOnly and LoadRestrictionsNone.
+	// Empty means the default restrictor of kustomize.
+	LoadRestrictor string `json:"loadRestrictor"`
+	// Whether to enable the kustomize plugins (--enable-alpha-plugins).
+	EnableAlphaPlugins bool `json:"enableAlphaPlugins"`
+}
+
+func (o *InputKustomizeBuildOptions) Validate() error {
+	switch o.LoadRestrictor {
+	case "", "LoadRestrictionsRootOnly", "LoadRestrictionsNone":
+		return nil
+	default:
+		return fmt.Errorf("unsupported loadRestrictor %q, only LoadRestrictionsRootOnly and LoadRestrictionsNone are supported", o.LoadRestrictor)
+	}
+}
+
+// StageKustomizeOverlay returns the kustomize overlay directory
+// that should be used while executing the given stage.
+func (in *KubernetesDeploymentInput) StageKustomizeOverlay(stage string) string {
+	if overlay, ok := in.KustomizeStageOverlays[stage]; ok {
+		return overlay
+	}
+	return in.KustomizeOverlay
+}
+
+const (
+	defaultK8sWebhookRetryTimeout = time.Minute
+)
+
+// WebhookRetryTimeout returns how long applying a manifest should be retried
+// while the admission webhooks are unavailable. Zero means no retry.
+func (in *KubernetesDeploymentInput) WebhookRetryTimeout() time.Duration {
+	r := in.WebhookRetry
+	if r == nil {
+		return defaultK8sWebhookRetryTimeout
+	}
+	if r.Disabled {
+		return 0
+	}
+	return r.Timeout.Duration()
+}
+
+// K8sWebhookRetry contains the configurable values for retrying to apply
+// the manifests rejected because of unavailable admission webhooks.
+type K8sWebhookRetry struct {
+	// Whether to fail immediately without retrying.
+	Disabled bool `json:"disabled"`
+	// How long applying each manifest is retried before failing.
+	// Default is 1m.
+	Timeout Duration `json:"timeout" default:"1m"`
+}
+
+func (r *K8sWebhookRetry) Validate() error {
+	if !r.Disabled && r.Timeout <= 0 {
+		return fmt.Errorf("webhookRetry.timeout must be positive")
+	}
+	return nil
+}
+
+// K8sDeploymentLock contains the configurable values for the lock
+// backed by a Lease object in the cluster.
+type K8sDeploymentLock struct {
+	// The name of the Lease object used as the lock.
+	// The applications using the same lock exclude each other.
+	// Default is pipecd-lock.
+	Name string `json:"name" default:"pipecd-lock"`
+	// The namespace of the Lease object.
+	// Empty means the namespace specified in the input or the default one.
+	Namespace string `json:"namespace"`
+	// How long the lock is kept without being renewed.
+	// It is the time until the lock can be taken over after its holder went down.
+	// Default is 1m.
+	LeaseDuration Duration `json:"leaseDuration" default:"1m"`
+	// How long to wait for the lock held by another deployment.
+	// Zero means waiting until the stage is timed out or cancelled.
+	WaitTimeout Duration `json:"waitTimeout"`
+}
+
+func (l *K8sDeploymentLock) Validate() error {
+	if l.Name == "" {
+		return fmt.Errorf("lock.name must be specified")
+	}
+	if l.LeaseDuration < Duration(5*time.Second) {
+		return fmt.Errorf("lock.leaseDuration must be at least 5s")
+	}
+	if l.WaitTimeout < 0 {
+		return fmt.Errorf("lock.waitTimeout must not be negative")
+	}
+	return nil
 }
 
 type InputHelmOptions struct {
@@ -98,6 +412,50 @@ type InputHelmOptions struct {
 	ValueFiles []string `json:"valueFiles"`
 	// List of file path for values.
 	SetFiles map[string]string
+	// List of values to set via "--set" flag.
+	SetValues map[string]string `json:"setValues"`
+}
+
+// WithOverrides returns a copy of the helm options
+// with the given overrides merged on top of it.
+// The values of the overrides take precedence over the original ones.
+func (o *InputHelmOptions) WithOverrides(ov *K8sHelmValueOverrides) *InputHelmOptions {
+	merged := &InputHelmOptions{}
+	if o != nil {
+		merged.ReleaseName = o.ReleaseName
+		merged.ValueFiles = append(merged.ValueFiles, o.ValueFiles...)
+		merged.SetFiles = make(map[string]string, len(o.SetFiles))
+		for k, v := range o.SetFiles {
+			merged.SetFiles[k] = v
+		}
+		merged.SetValues = make(map[string]string, len(o.SetValues))
+		for k, v := range o.SetValues {
+			merged.SetValues[k] = v
+		}
+	}
+	if ov == nil {
+		return merged
+	}
+
+	// Helm gives the priority to the last specified value file.
+	merged.ValueFiles = append(merged.ValueFiles, ov.ValueFiles...)
+	if len(ov.SetValues) > 0 && merged.SetValues == nil {
+		merged.SetValues = make(map[string]string, len(ov.SetValues))
+	}
+	for k, v := range ov.SetValues {
+		merged.SetValues[k] = v
+	}
+	return merged
+}
+
+// K8sHelmValueOverrides contains the additional helm values
+// used while rendering the manifests for a specific stage variant.
+type K8sHelmValueOverrides struct {
+	// List of value files should be loaded in addition to the application ones.
+	ValueFiles []string `json:"valueFiles"`
+	// List of values to set via "--set" flag.
+	// These take precedence over the application ones.
+	SetValues map[string]string `json:"setValues"`
 }
 
 type KubernetesTrafficRoutingMethod string
@@ -106,11 +464,39 @@ const (
 	KubernetesTrafficRoutingMethodPodSelector KubernetesTrafficRoutingMethod = "podselector"
 	KubernetesTrafficRoutingMethodIstio       KubernetesTrafficRoutingMethod = "istio"
 	KubernetesTrafficRoutingMethodSMI         KubernetesTrafficRoutingMethod = "smi"
+	KubernetesTrafficRoutingMethodNginx       KubernetesTrafficRoutingMethod = "nginx"
+	KubernetesTrafficRoutingMethodGateway     KubernetesTrafficRoutingMethod = "gateway"
+	KubernetesTrafficRoutingMethodALB         KubernetesTrafficRoutingMethod = "alb"
 )
 
+// IsWeighted reports whether the method can split traffic between variants by percentage.
+func (m KubernetesTrafficRoutingMethod) IsWeighted() bool {
+	switch m {
+	case KubernetesTrafficRoutingMethodIstio,
+		KubernetesTrafficRoutingMethodSMI,
+		KubernetesTrafficRoutingMethodNginx,
+		KubernetesTrafficRoutingMethodGateway,
+		KubernetesTrafficRoutingMethodALB:
+		return true
+	default:
+		return false
+	}
+}
+
 type KubernetesTrafficRouting struct {
-	Method KubernetesTrafficRoutingMethod `json:"method"`
-	Istio  *IstioTrafficRouting           `json:"istio"`
+	Method  KubernetesTrafficRoutingMethod `json:"method"`
+	Istio   *IstioTrafficRouting           `json:"istio"`
+	SMI     *SMITrafficRouting             `json:"smi"`
+	Nginx   *NginxTrafficRouting           `json:"nginx"`
+	Gateway *GatewayTrafficRouting         `json:"gateway"`
+	ALB     *ALBTrafficRouting             `json:"alb"`
+}
+
+func (r *KubernetesTrafficRouting) Validate() error {
+	if r.Method == KubernetesTrafficRoutingMethodALB && (r.ALB == nil || r.ALB.ServicePort == "") {
+		return fmt.Errorf("alb.servicePort is required for alb traffic routing method")
+	}
+	return nil
 }
 
 // DetermineKubernetesTrafficRoutingMethod determines the routing method should be used based on the TrafficRouting config.
@@ -137,6 +523,39 @@ type IstioTrafficRouting struct {
 	VirtualService K8sResourceReference `json:"virtualService"`
 }
 
+type SMITrafficRouting struct {
+	// The reference to the TrafficSplit manifest of Service Mesh Interface.
+	// Empty means the first TrafficSplit resource will be used.
+	TrafficSplit K8sResourceReference `json:"trafficSplit"`
+}
+
+type NginxTrafficRouting struct {
+	// The reference to the Ingress manifest routing traffic to the Service of application.
+	// Empty means the first Ingress resource will be used.
+	Ingress K8sResourceReference `json:"ingress"`
+	// The name of the CANARY Service the canary Ingress routes traffic to.
+	// Empty means the one generated by K8S_CANARY_ROLLOUT stage: <service-name>-canary.
+	CanaryService string `json:"canaryService"`
+}
+
+type GatewayTrafficRouting struct {
+	// The reference to the HTTPRoute manifest of Gateway API.
+	// Empty means the first HTTPRoute resource will be used.
+	HTTPRoute K8sResourceReference `json:"httpRoute"`
+}
+
+type ALBTrafficRouting struct {
+	// The reference to the Ingress manifest managed by AWS Load Balancer Controller.
+	// Empty means the first Ingress resource will be used.
+	Ingress K8sResourceReference `json:"ingress"`
+	// The name of the action the Ingress forwards traffic with.
+	// Its target groups are rewritten via alb.ingress.kubernetes.io/actions.<action> annotation.
+	// Empty means the name of the application Service.
+	Action string `json:"action"`
+	// The port of the Services used in the target groups.
+	ServicePort string `json:"servicePort"`
+}
+
 type K8sResourceReference struct {
 	Kind string `json:"kind"`
 	Name string `json:"name"`
@@ -161,6 +580,36 @@ type K8sPrimaryRolloutStageOptions struct {
 	AddVariantLabelToSelector bool `json:"addVariantLabelToSelector"`
 	// Whether the resources that are no longer defined in Git should be removed or not.
 	Prune bool `json:"prune"`
+	// Whether the changes to the cluster should be previewed by kubectl diff before applying.
+	PreviewDiff bool `json:"previewDiff"`
+	// Whether to only preview the changes by kubectl diff without applying them.
+	// Setting this to true implies previewDiff.
+	DiffOnly bool `json:"diffOnly"`
+	// Configuration for waiting the applied workloads to be ready.
+	// Empty means the stage finishes right after applying.
+	WaitForReady *K8sWaitForReady `json:"waitForReady"`
+}
+
+// K8sWaitForReady contains the configurable values for waiting
+// the rollout of the applied workloads to be completed.
+type K8sWaitForReady struct {
+	// The maximum length of time to wait for all workloads to be ready.
+	// Default is 10m.
+	Timeout Duration `json:"timeout" default:"10m"`
+	// List of the workload kinds to wait for.
+	// Empty means Deployment, StatefulSet and DaemonSet.
+	Kinds []string `json:"kinds"`
+}
+
+func (w *K8sWaitForReady) Validate() error {
+	for _, k := range w.Kinds {
+		switch k {
+		case "Deployment", "StatefulSet", "DaemonSet":
+		default:
+			return fmt.Errorf("unsupported kind %q for waitForReady, only Deployment, StatefulSet and DaemonSet are supported", k)
+		}
+	}
+	return nil
 }
 
 // K8sCanaryRolloutStageOptions contains all configurable values for a K8S_CANARY_ROLLOUT stage.
@@ -177,6 +626,146 @@ type K8sCanaryRolloutStageOptions struct {
 	CreateService bool `json:"createService"`
 	// List of patches used to customize manifests for CANARY variant.
 	Patches []K8sResourcePatch
+	// Additional helm values used to render manifests for CANARY variant.
+	// They are merged on top of the values specified in the application input.
+	HelmValues *K8sHelmValueOverrides `json:"helmValues"`
+	// Where the pods of CANARY variant should be scheduled.
+	// e.g. Running CANARY variant on spot/preemptible nodes.
+	NodePlacement *K8sNodePlacement `json:"nodePlacement"`
+	// Which CPU architectures the pods of CANARY variant should run on
+	// when the cluster mixes nodes of multiple architectures.
+	Architecture *K8sArchitecturePlacement `json:"architecture"`
+	// List of steps to gradually roll out CANARY variant within this stage.
+	// When specified, the replicas field is ignored and each step is executed in order.
+	Steps []K8sCanaryRolloutStep `json:"steps"`
+	// Per-workload configuration of CANARY variant.
+	// The workloads not listed here are rolled out with the replicas of the stage.
+	Workloads []K8sCanaryWorkload `json:"workloads"`
+}
+
+// K8sCanaryWorkload represents the configuration of CANARY variant for a specific workload.
+type K8sCanaryWorkload struct {
+	// The kind of the workload.
+	// Default is Deployment.
+	Kind string `json:"kind"`
+	// The name of the workload.
+	Name string `json:"name"`
+	// How many pods for CANARY variant of this workload.
+	// The same format with the replicas field of the stage is used.
+	// Empty means the replicas of the stage is used.
+	Replicas Replicas `json:"replicas"`
+	// Whether to exclude this workload from CANARY variant.
+	Exclude bool `json:"exclude"`
+}
+
+// K8sCanaryRolloutStep represents a step of gradually rolling out CANARY variant.
+type K8sCanaryRolloutStep struct {
+	// How many pods for CANARY workloads at this step.
+	// The same format with the replicas field of the stage is used.
+	Replicas Replicas `json:"replicas"`
+	// The percentage of traffic should be routed to CANARY variant at this step.
+	// The rest is routed to PRIMARY variant.
+	// Zero means the traffic routing is not changed.
+	Traffic Percentage `json:"traffic"`
+	// How long to wait before moving to the next step.
+	Wait Duration `json:"wait"`
+	// The analysis should be performed after waiting.
+	// The stage fails without moving to the next step if the analysis failed.
+	Analysis *AnalysisStageOptions `json:"analysis"`
+}
+
+func (opts *K8sCanaryRolloutStageOptions) Validate(trafficRouting *KubernetesTrafficRouting) error {
+	for i, w := range opts.Workloads {
+		if w.Name == "" {
+			return fmt.Errorf("name of workload %d in K8S_CANARY_ROLLOUT stage must be specified", i)
+		}
+		if w.Replicas.Number < 0 {
+			return fmt.Errorf("replicas of workload %s in K8S_CANARY_ROLLOUT stage must not be negative", w.Name)
+		}
+		if w.Exclude && w.Replicas.Number != 0 {
+			return fmt.Errorf("replicas of workload %s in K8S_CANARY_ROLLOUT stage cannot be specified with exclude", w.Name)
+		}
+		if len(opts.Steps) > 0 && w.Replicas.Number != 0 {
+			return fmt.Errorf("replicas of workload %s in K8S_CANARY_ROLLOUT stage cannot be specified with steps", w.Name)
+		}
+	}
+
+	if a := opts.Architecture; a != nil {
+		if err := a.Validate(opts.NodePlacement); err != nil {
+			return fmt.Errorf("invalid architecture in K8S_CANARY_ROLLOUT stage: %v", err)
+		}
+	}
+
+	method := DetermineKubernetesTrafficRoutingMethod(trafficRouting)
+	for i, step := range opts.Steps {
+		if step.Replicas.Number <= 0 {
+			return fmt.Errorf("replicas of step %d in K8S_CANARY_ROLLOUT stage must be greater than zero", i)
+		}
+		if t := step.Traffic.Int(); t < 0 || t > 100 {
+			return fmt.Errorf("traffic of step %d in K8S_CANARY_ROLLOUT stage must be between 0 and 100", i)
+		}
+		if step.Traffic.Int() > 0 && !method.IsWeighted() {
+			return fmt.Errorf("traffic of step %d in K8S_CANARY_ROLLOUT stage requires a weighted traffic routing method: istio, smi, nginx, gateway or alb", i)
+		}
+		if step.Wait < 0 {
+			return fmt.Errorf("wait of step %d in K8S_CANARY_ROLLOUT stage must not be negative", i)
+		}
+		if step.Analysis != nil {
+			if err := step.Analysis.Validate(); err != nil {
+				return fmt.Errorf("invalid analysis of step %d in K8S_CANARY_ROLLOUT stage: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// K8sNodePlacement contains the scheduling constraints
+// to be injected into the pod template of workloads.
+type K8sNodePlacement struct {
+	// Labels that the nodes must have to run the pods.
+	// These are merged into the existing nodeSelector of the workloads.
+	NodeSelector map[string]string `json:"nodeSelector"`
+	// Tolerations to be added into the pods.
+	Tolerations []K8sToleration `json:"tolerations"`
+	// The affinity in the same format with Kubernetes PodSpec's affinity field.
+	// This replaces the existing affinity of the workloads.
+	Affinity json.RawMessage `json:"affinity"`
+}
+
+// The well-known node label holding the CPU architecture of the node.
+const k8sArchitectureLabel = "kubernetes.io/arch"
+
+// K8sArchitecturePlacement decides the CPU architectures of the nodes to run the pods on.
+// Exactly one of pin and mirrorPrimary must be specified.
+type K8sArchitecturePlacement struct {
+	// The architecture of the nodes the pods must run on, e.g. amd64 or arm64.
+	Pin string `json:"pin"`
+	// Whether to spread the pods over the architectures
+	// in the same ratio as the running pods of PRIMARY variant.
+	MirrorPrimary bool `json:"mirrorPrimary"`
+}
+
+func (a *K8sArchitecturePlacement) Validate(placement *K8sNodePlacement) error {
+	if (a.Pin == "") == !a.MirrorPrimary {
+		return fmt.Errorf("exactly one of pin and mirrorPrimary must be specified")
+	}
+	if placement != nil {
+		if _, ok := placement.NodeSelector[k8sArchitectureLabel]; ok {
+			return fmt.Errorf("cannot be used with %s in nodeSelector of nodePlacement", k8sArchitectureLabel)
+		}
+	}
+	return nil
+}
+
+// K8sToleration represents a Kubernetes toleration.
+type K8sToleration struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	Effect   string `json:"effect"`
+	// How long the pods tolerate the taint.
+	// Empty means forever.
+	TolerationSeconds *int64 `json:"tolerationSeconds"`
 }
 
 type K8sResourcePatch struct {
@@ -227,6 +816,9 @@ type K8sBaselineRolloutStageOptions struct {
 	Suffix string `json:"suffix"`
 	// Whether the BASELINE service should be created.
 	CreateService bool `json:"createService"`
+	// Additional helm values used to render manifests for BASELINE variant.
+	// They are merged on top of the values specified in the application input.
+	HelmValues *K8sHelmValueOverrides `json:"helmValues"`
 }
 
 // K8sBaselineCleanStageOptions contains all configurable values for a K8S_BASELINE_CLEAN stage.
@@ -244,6 +836,21 @@ type K8sTrafficRoutingStageOptions struct {
 	Canary Percentage `json:"canary"`
 	// The percentage of traffic should be routed to BASELINE variant.
 	Baseline Percentage `json:"baseline"`
+	// Decide the percentage of traffic routed to CANARY variant from the recent request rate
+	// so that the canary variant receives enough requests for the analysis.
+	// When specified, the configured canary percentage is used only when the request rate is unavailable
+	// and BASELINE variant receives the same percentage as CANARY if the baseline percentage was configured.
+	CanarySizing *K8sCanarySizing `json:"canarySizing"`
+}
+
+func (opts *K8sTrafficRoutingStageOptions) Validate() error {
+	if opts.CanarySizing == nil {
+		return nil
+	}
+	if opts.All != "" {
+		return fmt.Errorf("canarySizing can not be used together with all")
+	}
+	return opts.CanarySizing.Validate()
 }
 
 func (opts K8sTrafficRoutingStageOptions) Percentages() (primary, canary, baseline int) {
@@ -260,3 +867,308 @@ func (opts K8sTrafficRoutingStageOptions) Percentages() (primary, canary, baseli
 	}
 	return opts.Primary.Int(), opts.Canary.Int(), opts.Baseline.Int()
 }
+
+// K8sCanarySizing contains configurable values for deciding the percentage of traffic
+// routed to CANARY variant from the request rate queried through an analysis provider.
+type K8sCanarySizing struct {
+	// The unique name of the analysis provider defined in the Piped Configuration.
+	// Required field.
+	Provider string `json:"provider"`
+	// The query returning the total request rate (requests per second) of the application.
+	// Required field.
+	Query string `json:"query"`
+	// How long the past request rate is averaged.
+	// Default is 10m.
+	Window Duration `json:"window" default:"10m"`
+	// How long after which the query times out.
+	// Default is 30s.
+	Timeout Duration `json:"timeout" default:"30s"`
+	// The minimum request rate (requests per second) to be routed to CANARY variant.
+	// Required field.
+	MinCanaryRPS float64 `json:"minCanaryRps"`
+	// The maximum percentage of traffic routed to CANARY variant
+	// even though the minimum request rate is not satisfied.
+	// Default is 50.
+	MaxCanary Percentage `json:"maxCanary" default:"50"`
+}
+
+func (s *K8sCanarySizing) Validate() error {
+	if s.Provider == "" {
+		return fmt.Errorf("canarySizing.provider is required")
+	}
+	if s.Query == "" {
+		return fmt.Errorf("canarySizing.query is required")
+	}
+	if s.Window <= 0 {
+		return fmt.Errorf("canarySizing.window must be greater than zero")
+	}
+	if s.MinCanaryRPS <= 0 {
+		return fmt.Errorf("canarySizing.minCanaryRps must be greater than zero")
+	}
+	if max := s.MaxCanary.Int(); max <= 0 || max > 100 {
+		return fmt.Errorf("canarySizing.maxCanary must be in range (0, 100]")
+	}
+	return nil
+}
+
+type K8sDiffFormat string
+
+const (
+	// K8sDiffFormatUnified renders the diff as a unified text.
+	K8sDiffFormatUnified K8sDiffFormat = "unified"
+	// K8sDiffFormatJSONPatch renders the diff as a list of JSON patch operations.
+	K8sDiffFormatJSONPatch K8sDiffFormat = "json-patch"
+	// K8sDiffFormatMarkdown renders the diff as markdown tables.
+	K8sDiffFormatMarkdown K8sDiffFormat = "markdown"
+)
+
+// K8sDiffStageOptions contains all configurable values for a K8S_DIFF stage.
+type K8sDiffStageOptions struct {
+	// The format used to render the diff.
+	// Can be "unified", "json-patch" or "markdown". Default is "unified".
+	Format K8sDiffFormat `json:"format"`
+	// Maximum number of changed manifests should be shown.
+	// Zero means rendering all.
+	MaxChangedManifests int `json:"maxChangedManifests"`
+}
+
+func (opts *K8sDiffStageOptions) Validate() error {
+	switch opts.Format {
+	case "", K8sDiffFormatUnified, K8sDiffFormatJSONPatch, K8sDiffFormatMarkdown:
+	default:
+		return fmt.Errorf("unsupported format %q for K8S_DIFF stage", opts.Format)
+	}
+	if opts.MaxChangedManifests < 0 {
+		return fmt.Errorf("maxChangedManifests of K8S_DIFF stage must not be negative")
+	}
+	return nil
+}
+
+// K8sValidateStageOptions contains all configurable values for a K8S_VALIDATE stage.
+type K8sValidateStageOptions struct {
+	// Whether to skip validating the manifests against the schemas
+	// and the API versions served by the target cluster.
+	SkipSchemaValidation bool `json:"skipSchemaValidation"`
+	// List of directories containing the OPA/Rego policies
+	// the manifests must comply with.
+	// Relative paths are resolved from the application directory.
+	Policies []string `json:"policies"`
+	// List of the policy namespaces should be checked.
+	// Empty means using the default "main" namespace.
+	PolicyNamespaces []string `json:"policyNamespaces"`
+	// Whether to fail the stage when the policies reported some warnings.
+	FailOnWarning bool `json:"failOnWarning"`
+	// Version of conftest which will be used to check the policies.
+	// Empty means the pre-installed version will be used.
+	ConftestVersion string `json:"conftestVersion"`
+}
+
+func (opts *K8sValidateStageOptions) Validate() error {
+	if opts.SkipSchemaValidation && len(opts.Policies) == 0 {
+		return fmt.Errorf("K8S_VALIDATE stage must have at least one policy when skipping schema validation")
+	}
+	for _, p := range opts.Policies {
+		if p == "" {
+			return fmt.Errorf("policy path of K8S_VALIDATE stage must not be empty")
+		}
+	}
+	return nil
+}
+
+// K8sVerifyImagesStageOptions contains all configurable values for a K8S_VERIFY_IMAGES stage.
+type K8sVerifyImagesStageOptions struct {
+	// List of the public keys the images must be signed with.
+	// Each one is a path to the key file or a KMS URI supported by cosign such as "gcpkms://...".
+	// Relative paths are resolved from the application directory.
+	Keys []string `json:"keys"`
+	// List of the identities the images must be signed by in keyless mode.
+	Identities []K8sImageSignerIdentity `json:"identities"`
+	// List of the predicate types of the attestations every image must have,
+	// e.g. "spdx", "cyclonedx" or "slsaprovenance".
+	Attestations []string `json:"attestations"`
+	// List of the image prefixes should not be verified.
+	ExcludeImages []string `json:"excludeImages"`
+	// Version of cosign which will be used to verify the images.
+	// Empty means the pre-installed version will be used.
+	CosignVersion string `json:"cosignVersion"`
+}
+
+func (opts *K8sVerifyImagesStageOptions) Validate() error {
+	if len(opts.Keys) == 0 && len(opts.Identities) == 0 {
+		return fmt.Errorf("K8S_VERIFY_IMAGES stage must have at least one key or identity")
+	}
+	for _, k := range opts.Keys {
+		if k == "" {
+			return fmt.Errorf("key of K8S_VERIFY_IMAGES stage must not be empty")
+		}
+	}
+	for _, id := range opts.Identities {
+		if err := id.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, a := range opts.Attestations {
+		if a == "" {
+			return fmt.Errorf("attestation type of K8S_VERIFY_IMAGES stage must not be empty")
+		}
+	}
+	return nil
+}
+
+// K8sImageSignerIdentity represents the identity of the keyless signer
+// recorded in the certificate issued by Fulcio.
+type K8sImageSignerIdentity struct {
+	// The OIDC issuer of the identity, e.g. "https://token.actions.githubusercontent.com".
+	Issuer string `json:"issuer"`
+	// The subject of the identity such as an email address or a workflow URI.
+	Subject string `json:"subject"`
+}
+
+func (id K8sImageSignerIdentity) Validate() error {
+	if id.Issuer == "" {
+		return fmt.Errorf("issuer of K8S_VERIFY_IMAGES identity is required")
+	}
+	if id.Subject == "" {
+		return fmt.Errorf("subject of K8S_VERIFY_IMAGES identity is required")
+	}
+	return nil
+}
+
+const (
+	defaultK8sPreviewNamespace = "{{ .App.Name }}-{{ .Key }}"
+)
+
+// K8sPreview contains the configuration for the ephemeral preview environments
+// deployed into the namespaces generated for each branch or pull request.
+type K8sPreview struct {
+	// Template of the namespace where the preview environment is deployed.
+	// Available args are .App.Name, .Branch, .PullRequest, .CommitHash and .Key
+	// where .Key is "pr-<number>" for pull requests, otherwise the branch name.
+	// Default is "{{ .App.Name }}-{{ .Key }}".
+	Namespace string `json:"namespace"`
+	// Template of the URL to access the preview environment.
+	// The same args with namespace and .Namespace are available.
+	URL string `json:"url"`
+	// How long the preview environment is kept since its last rollout.
+	// The expired ones are removed while rolling out other preview environments of the application.
+	// Zero means they are kept until being removed by K8S_PREVIEW_CLEAN stage.
+	TTL Duration `json:"ttl"`
+}
+
+// NamespaceTemplate returns the template of the preview namespace.
+func (p *K8sPreview) NamespaceTemplate() string {
+	if p.Namespace == "" {
+		return defaultK8sPreviewNamespace
+	}
+	return p.Namespace
+}
+
+func (p *K8sPreview) Validate() error {
+	if _, err := template.New("namespace").Parse(p.NamespaceTemplate()); err != nil {
+		return fmt.Errorf("invalid preview namespace template: %v", err)
+	}
+	if _, err := template.New("url").Parse(p.URL); err != nil {
+		return fmt.Errorf("invalid preview url template: %v", err)
+	}
+	if p.TTL < 0 {
+		return fmt.Errorf("preview ttl must not be negative")
+	}
+	return nil
+}
+
+// K8sPreviewRolloutStageOptions contains all configurable values for a K8S_PREVIEW_ROLLOUT stage.
+type K8sPreviewRolloutStageOptions struct {
+}
+
+// K8sPreviewCleanStageOptions contains all configurable values for a K8S_PREVIEW_CLEAN stage.
+type K8sPreviewCleanStageOptions struct {
+}
+
+// K8sJobRunStageOptions contains all configurable values for a K8S_JOB_RUN stage.
+// Exactly one of manifest and container must be specified.
+type K8sJobRunStageOptions struct {
+	// The path to the file containing the Job manifest to run.
+	// It is relative to the application directory and should be placed
+	// where it is not loaded as one of the application manifests, e.g. in a sub directory.
+	Manifest string `json:"manifest"`
+	// The container run by the Job generated by piped.
+	Container *K8sJobContainer `json:"container"`
+	// The name of the generated Job.
+	// Default is "<application name>-job".
+	Name string `json:"name"`
+	// The number of retries before marking the generated Job as failed.
+	// Default is 0, so the stage fails at the first failure.
+	BackoffLimit int `json:"backoffLimit"`
+}
+
+func (opts *K8sJobRunStageOptions) Validate() error {
+	if (opts.Manifest == "") == (opts.Container == nil) {
+		return fmt.Errorf("K8S_JOB_RUN stage must have exactly one of manifest and container")
+	}
+	if filepath.IsAbs(opts.Manifest) {
+		return fmt.Errorf("manifest of K8S_JOB_RUN stage must be a relative path to the application directory")
+	}
+	if c := opts.Container; c != nil && c.Image == "" {
+		return fmt.Errorf("container image of K8S_JOB_RUN stage must not be empty")
+	}
+	if opts.BackoffLimit < 0 {
+		return fmt.Errorf("backoffLimit of K8S_JOB_RUN stage must not be negative")
+	}
+	return nil
+}
+
+// K8sJobContainer represents the container run by the Job of K8S_JOB_RUN stage.
+type K8sJobContainer struct {
+	// The container image to run.
+	Image string `json:"image"`
+	// The entrypoint of the container.
+	// Empty means using the one of the image.
+	Command []string `json:"command"`
+	// The arguments passed to the entrypoint.
+	Args []string `json:"args"`
+	// The environment variables set in the container.
+	Env map[string]string `json:"env"`
+}
+
+const (
+	reservedMetadataKeyPrefix = "pipecd.dev/"
+)
+
+// K8sCommonMetadata contains the labels and annotations
+// added to all manifests applied by piped.
+// Their values are templates which can use the metadata of the deployment:
+// .App.ID, .App.Name, .Deployment.ID, .Commit.Hash, .Commit.Author, .Commit.Branch and .Trigger.Commander.
+type K8sCommonMetadata struct {
+	// Labels added to the manifests.
+	// The rendered values are converted to be valid label values.
+	Labels map[string]string `json:"labels"`
+	// Annotations added to the manifests.
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (m *K8sCommonMetadata) Validate() error {
+	for k, v := range m.Labels {
+		if err := validateCommonMetadata("label", k, v); err != nil {
+			return err
+		}
+	}
+	for k, v := range m.Annotations {
+		if err := validateCommonMetadata("annotation", k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCommonMetadata(kind, key, value string) error {
+	if key == "" {
+		return fmt.Errorf("%s key of commonMetadata must not be empty", kind)
+	}
+	if strings.HasPrefix(key, reservedMetadataKeyPrefix) {
+		return fmt.Errorf("%s %q of commonMetadata must not use the reserved prefix %q", kind, key, reservedMetadataKeyPrefix)
+	}
+	if _, err := template.New(key).Parse(value); err != nil {
+		return fmt.Errorf("invalid template of %s %q in commonMetadata: %v", kind, key, err)
+	}
+	return nil
+}
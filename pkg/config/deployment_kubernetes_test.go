@@ -15,6 +15,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -89,6 +90,1081 @@ func TestKubernetesDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/k8s-app-diff.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sDiff,
+								K8sDiffStageOptions: &K8sDiffStageOptions{
+									Format:              K8sDiffFormatMarkdown,
+									MaxChangedManifests: 5,
+								},
+							},
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name:                       model.StageK8sCanaryClean,
+								K8sCanaryCleanStageOptions: &K8sCanaryCleanStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-canary-steps.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									Steps: []K8sCanaryRolloutStep{
+										{
+											Replicas: Replicas{Number: 10, IsPercentage: true},
+											Traffic:  Percentage{Number: 10},
+											Wait:     Duration(5 * time.Minute),
+										},
+										{
+											Replicas: Replicas{Number: 50, IsPercentage: true},
+											Traffic:  Percentage{Number: 50},
+											Analysis: &AnalysisStageOptions{
+												Duration: Duration(10 * time.Minute),
+											},
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+				TrafficRouting: &KubernetesTrafficRouting{
+					Method: KubernetesTrafficRoutingMethodIstio,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-canary-multiple-workloads.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									Replicas: Replicas{Number: 10, IsPercentage: true},
+									Workloads: []K8sCanaryWorkload{
+										{
+											Name:     "web",
+											Replicas: Replicas{Number: 20, IsPercentage: true},
+										},
+										{
+											Name:    "worker",
+											Exclude: true,
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-nginx-traffic-routing.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									CreateService: true,
+								},
+							},
+							{
+								Name: model.StageK8sTrafficRouting,
+								K8sTrafficRoutingStageOptions: &K8sTrafficRoutingStageOptions{
+									Canary: Percentage{
+										Number: 20,
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageK8sTrafficRouting,
+								K8sTrafficRoutingStageOptions: &K8sTrafficRoutingStageOptions{
+									Primary: Percentage{
+										Number: 100,
+									},
+								},
+							},
+							{
+								Name:                       model.StageK8sCanaryClean,
+								K8sCanaryCleanStageOptions: &K8sCanaryCleanStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+				TrafficRouting: &KubernetesTrafficRouting{
+					Method: KubernetesTrafficRoutingMethodNginx,
+					Nginx: &NginxTrafficRouting{
+						Ingress: K8sResourceReference{
+							Name: "helloworld",
+						},
+						CanaryService: "helloworld-canary",
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-validate.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sValidate,
+								K8sValidateStageOptions: &K8sValidateStageOptions{
+									Policies:         []string{"policy"},
+									PolicyNamespaces: []string{"main", "security"},
+									FailOnWarning:    true,
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-preview.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                          model.StageK8sPreviewRollout,
+								K8sPreviewRolloutStageOptions: &K8sPreviewRolloutStageOptions{},
+							},
+							{
+								Name:                        model.StageK8sPreviewClean,
+								K8sPreviewCleanStageOptions: &K8sPreviewCleanStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+				Preview: &K8sPreview{
+					Namespace: "preview-{{ .Key }}",
+					URL:       "https://{{ .Namespace }}.preview.example.com",
+					TTL:       Duration(72 * time.Hour),
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-common-metadata.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+				CommonMetadata: &K8sCommonMetadata{
+					Labels: map[string]string{
+						"team":          "payment",
+						"commit-author": "{{ .Commit.Author }}",
+					},
+					Annotations: map[string]string{
+						"example.com/cost-center": "1234",
+						"example.com/deployment":  "{{ .Deployment.ID }}",
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-webhook-retry.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+					WebhookRetry: &K8sWebhookRetry{
+						Timeout: Duration(3 * time.Minute),
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-lock.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+					Namespace:    "shared",
+					Lock: &K8sDeploymentLock{
+						Name:          "pipecd-lock",
+						LeaseDuration: Duration(time.Minute),
+						WaitTimeout:   Duration(10 * time.Minute),
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-oci-source.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					OCISource: &DeploymentOCISource{
+						Reference: "ghcr.io/org/manifests:v1.0.0",
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-trigger.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Trigger: &DeploymentTrigger{
+						Includes: []string{"apps/demo/**", "charts/demo/**"},
+						Excludes: []string{"**/*.md"},
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-trigger-schedule.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Trigger: &DeploymentTrigger{
+						Tag:      "v*",
+						Schedule: "CRON_TZ=Asia/Tokyo 0 3 * * *",
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-concurrency-policy.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout:           Duration(6 * time.Hour),
+					ConcurrencyPolicy: ConcurrencyPolicySkipIntermediate,
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-pipeline-on-failure.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+						OnFailure: &PipelineOnFailure{
+							Action: PipelineOnFailureActionPause,
+							Approval: RollbackApproval{
+								Timeout:       Duration(time.Hour),
+								DefaultAction: RollbackApprovalDefaultActionRollback,
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-drift-detection.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					DriftDetection: &DriftDetection{
+						IgnoreFields: []DriftDetectionIgnoreField{
+							{
+								Kind:  "Deployment",
+								Name:  "simple",
+								Paths: []string{"spec.replicas"},
+							},
+							{
+								Kind:  "MutatingWebhookConfiguration",
+								Paths: []string{"webhooks.*.clientConfig.caBundle"},
+							},
+							{
+								Paths: []string{`metadata.annotations.sidecar\.istio\.io/status`},
+							},
+						},
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-drift-detection-without-paths.yaml",
+			expectedError: fmt.Errorf("paths of driftDetection.ignoreFields must be set"),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-post-sync-chain.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					PostSync: &PostSync{
+						Chain: &DeploymentChain{
+							Applications: []DeploymentChainApplication{
+								{
+									Name:   "backend",
+									Labels: map[string]string{"env": "prod"},
+								},
+								{
+									Labels: map[string]string{"team": "payment"},
+								},
+							},
+							Outputs: []string{"ImageTag"},
+						},
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-post-sync-chain-without-matcher.yaml",
+			expectedError: fmt.Errorf("either name or labels of postSync.chain.applications must be set"),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-planner-rules.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+					Planner: DeploymentPlanner{
+						Rules: []DeploymentPlannerRule{
+							{
+								Strategy: PlannerRuleStrategyPipeline,
+								Changes:  PlannerRuleChangesImage,
+							},
+							{
+								Strategy: PlannerRuleStrategyPipeline,
+								Paths:    []string{"migrations/**"},
+							},
+							{
+								Strategy: PlannerRuleStrategyQuickSync,
+								Changes:  PlannerRuleChangesConfig,
+							},
+						},
+					},
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-planner-rules-without-condition.yaml",
+			expectedError: fmt.Errorf("invalid planner.rules[0]: %w", fmt.Errorf("either changes or paths must be set")),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-canary-sizing.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sTrafficRouting,
+								K8sTrafficRoutingStageOptions: &K8sTrafficRoutingStageOptions{
+									Canary: Percentage{Number: 10},
+									CanarySizing: &K8sCanarySizing{
+										Provider:     "prometheus-dev",
+										Query:        `sum(rate(http_requests_total{app="helloworld"}[1m]))`,
+										Window:       Duration(10 * time.Minute),
+										Timeout:      Duration(30 * time.Second),
+										MinCanaryRPS: 100,
+										MaxCanary:    Percentage{Number: 50},
+									},
+								},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-job-run.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sJobRun,
+								K8sJobRunStageOptions: &K8sJobRunStageOptions{
+									Manifest: "jobs/migrate.yaml",
+								},
+							},
+							{
+								Name: model.StageK8sJobRun,
+								K8sJobRunStageOptions: &K8sJobRunStageOptions{
+									Name:         "seed",
+									BackoffLimit: 2,
+									Container: &K8sJobContainer{
+										Image:   "gcr.io/pipecd/helloworld:v0.1.0",
+										Command: []string{"/bin/seed"},
+										Args:    []string{"--env", "dev"},
+										Env: map[string]string{
+											"DB_HOST": "db.example.com",
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-verify-images.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sVerifyImages,
+								K8sVerifyImagesStageOptions: &K8sVerifyImagesStageOptions{
+									Keys: []string{"cosign.pub"},
+									Identities: []K8sImageSignerIdentity{
+										{
+											Issuer:  "https://token.actions.githubusercontent.com",
+											Subject: "https://github.com/pipe-cd/pipe/.github/workflows/release.yaml@refs/heads/master",
+										},
+									},
+									Attestations:  []string{"spdx"},
+									ExcludeImages: []string{"docker.io/library/"},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-canary-on-spot-nodes.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									NodePlacement: &K8sNodePlacement{
+										NodeSelector: map[string]string{
+											"cloud.google.com/gke-spot": "true",
+										},
+										Tolerations: []K8sToleration{
+											{
+												Key:      "cloud.google.com/gke-spot",
+												Operator: "Equal",
+												Value:    "true",
+												Effect:   "NoSchedule",
+											},
+										},
+									},
+								},
+							},
+							{
+								Name: model.StageAnalysis,
+								AnalysisStageOptions: &AnalysisStageOptions{
+									Duration: Duration(10 * time.Minute),
+									PreemptionTolerance: &AnalysisPreemptionTolerance{
+										Variant: "canary",
+										Window:  Duration(5 * time.Minute),
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name:                       model.StageK8sCanaryClean,
+								K8sCanaryCleanStageOptions: &K8sCanaryCleanStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-canary-mirror-architecture.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									Replicas: Replicas{
+										Number:       20,
+										IsPercentage: true,
+									},
+									Architecture: &K8sArchitecturePlacement{
+										MirrorPrimary: true,
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name:                       model.StageK8sCanaryClean,
+								K8sCanaryCleanStageOptions: &K8sCanaryCleanStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-analysis-kubernetes-pod-failures.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageAnalysis,
+								AnalysisStageOptions: &AnalysisStageOptions{
+									Duration: Duration(10 * time.Minute),
+									KubernetesPodFailures: []AnalysisKubernetesPodFailures{
+										{
+											Variant:      "canary",
+											Interval:     Duration(time.Minute),
+											MaxOOMKilled: 1,
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-analysis-kubernetes-pods.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageAnalysis,
+								AnalysisStageOptions: &AnalysisStageOptions{
+									Duration: Duration(10 * time.Minute),
+									KubernetesPods: []AnalysisKubernetesPods{
+										{
+											Variant:            "canary",
+											Interval:           Duration(time.Minute),
+											MinReadyPercentage: Percentage{Number: 100},
+											MaxRestarts:        2,
+										},
+										{
+											Variant:            "primary",
+											Interval:           Duration(30 * time.Second),
+											MinReadyPercentage: Percentage{Number: 80, HasSuffix: true},
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-analysis-consumer-lag.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name:                           model.StageK8sBaselineRollout,
+								K8sBaselineRolloutStageOptions: &K8sBaselineRolloutStageOptions{},
+							},
+							{
+								Name: model.StageAnalysis,
+								AnalysisStageOptions: &AnalysisStageOptions{
+									Duration: Duration(10 * time.Minute),
+									ConsumerLags: []AnalysisConsumerLag{
+										{
+											Provider:         "prometheus-dev",
+											Source:           AnalysisConsumerLagSourceKafka,
+											CanaryConsumer:   "orders-canary",
+											BaselineConsumer: "orders-baseline",
+											Interval:         Duration(time.Minute),
+											MaxLagRatio:      1.5,
+											LagTolerance:     10,
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/k8s-app-analysis-strictness-profiles.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                         model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageAnalysis,
+								AnalysisStageOptions: &AnalysisStageOptions{
+									Duration: Duration(10 * time.Minute),
+									StrictnessProfiles: map[string]AnalysisStrictnessProfile{
+										"prod": {
+											FailureLimit: intPointer(1),
+										},
+										"staging": {
+											FailureLimit: intPointer(5),
+										},
+										"dev": {
+											ReportOnly: true,
+										},
+									},
+								},
+							},
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-analysis-strictness-profiles-invalid.yaml",
+			expectedError: fmt.Errorf("invalid strictness profile for environment prod: %w", fmt.Errorf("failureLimit of analysis strictness profile must not be negative")),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-diff-invalid-format.yaml",
+			expectedError: fmt.Errorf("unsupported format \"html\" for K8S_DIFF stage"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-canary-steps-without-istio.yaml",
+			expectedError: fmt.Errorf("traffic of step 0 in K8S_CANARY_ROLLOUT stage requires a weighted traffic routing method: istio, smi, nginx, gateway or alb"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-canary-multiple-workloads-with-steps.yaml",
+			expectedError: fmt.Errorf("replicas of workload web in K8S_CANARY_ROLLOUT stage cannot be specified with steps"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-canary-architecture-pin-and-mirror.yaml",
+			expectedError: fmt.Errorf("invalid architecture in K8S_CANARY_ROLLOUT stage: exactly one of pin and mirrorPrimary must be specified"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-alb-traffic-routing-without-port.yaml",
+			expectedError: fmt.Errorf("alb.servicePort is required for alb traffic routing method"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-validate-nothing.yaml",
+			expectedError: fmt.Errorf("K8S_VALIDATE stage must have at least one policy when skipping schema validation"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-verify-images-without-signer.yaml",
+			expectedError: fmt.Errorf("K8S_VERIFY_IMAGES stage must have at least one key or identity"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-wait-for-ready-invalid-kind.yaml",
+			expectedError: fmt.Errorf("unsupported kind \"Job\" for waitForReady, only Deployment, StatefulSet and DaemonSet are supported"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-multi-cluster-duplicated.yaml",
+			expectedError: fmt.Errorf("cloud provider cluster-tokyo was listed multiple times in multiCluster.cloudProviders"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-kustomize-invalid-load-restrictor.yaml",
+			expectedError: fmt.Errorf("unsupported loadRestrictor \"LoadRestrictionsAll\", only LoadRestrictionsRootOnly and LoadRestrictionsNone are supported"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-preview-invalid-namespace.yaml",
+			expectedError: fmt.Errorf("invalid preview namespace template: template: namespace:1: unclosed action"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-common-metadata-reserved.yaml",
+			expectedError: fmt.Errorf("label \"pipecd.dev/variant\" of commonMetadata must not use the reserved prefix \"pipecd.dev/\""),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-webhook-retry-invalid-timeout.yaml",
+			expectedError: fmt.Errorf("webhookRetry.timeout must be positive"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-lock-invalid-lease-duration.yaml",
+			expectedError: fmt.Errorf("lock.leaseDuration must be at least 5s"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-oci-source-without-reference.yaml",
+			expectedError: fmt.Errorf("reference of ociSource must be set"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-trigger-tag-with-includes.yaml",
+			expectedError: fmt.Errorf("trigger.tag can not be used together with trigger.includes or trigger.excludes"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-unsupported-concurrency-policy.yaml",
+			expectedError: fmt.Errorf("unsupported concurrencyPolicy \"parallel\""),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-pipeline-unsupported-on-failure.yaml",
+			expectedError: fmt.Errorf("unsupported action \"ignore\" for onFailure"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-pipeline-on-failure-with-rollback-approval.yaml",
+			expectedError: fmt.Errorf("rollbackApproval can not be used together with the pause action of onFailure"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-canary-sizing-without-provider.yaml",
+			expectedError: fmt.Errorf("canarySizing.provider is required"),
+		},
+		{
+			fileName:      "testdata/application/k8s-app-job-run-ambiguous.yaml",
+			expectedError: fmt.Errorf("K8S_JOB_RUN stage must have exactly one of manifest and container"),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-script-run.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageScriptRun,
+								ScriptRunStageOptions: &ScriptRunStageOptions{
+									Run: "./scripts/smoke-test.sh",
+									Env: map[string]string{
+										"ENDPOINT": "https://app.example.com",
+									},
+									WarningExitCodes: []int{2},
+									Tools:            []string{"yq", "kubectl"},
+								},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-script-run-empty.yaml",
+			expectedError: fmt.Errorf("run of SCRIPT_RUN stage must not be empty"),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-notify.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name: model.StageK8sCanaryRollout,
+								K8sCanaryRolloutStageOptions: &K8sCanaryRolloutStageOptions{
+									Replicas: Replicas{
+										Number:       25,
+										IsPercentage: true,
+									},
+								},
+							},
+							{
+								Name: model.StageNotify,
+								NotifyStageOptions: &NotifyStageOptions{
+									Message:   "Canary of {{ .App.Name }} is live at 25%, please verify it.",
+									Receivers: []string{"dev-slack-channel"},
+								},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-notify-empty.yaml",
+			expectedError: fmt.Errorf("message of NOTIFY stage must not be empty"),
+		},
+		{
+			fileName:           "testdata/application/k8s-app-event-publish.yaml",
+			expectedKind:       KindKubernetesApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{
+						Stages: []PipelineStage{
+							{
+								Name:                          model.StageK8sPrimaryRollout,
+								K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{},
+							},
+							{
+								Name: model.StageEventPublish,
+								EventPublishStageOptions: &EventPublishStageOptions{
+									Name: "helloworld-deployed",
+									Data: "{{ .Commit.Hash }}",
+									Labels: map[string]string{
+										"env": "dev",
+									},
+								},
+							},
+						},
+					},
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: KubernetesDeploymentInput{
+					AutoRollback: true,
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:      "testdata/application/k8s-app-event-publish-without-name.yaml",
+			expectedError: fmt.Errorf("name of EVENT_PUBLISH stage must not be empty"),
+		},
 		// {
 		// 	fileName:           "testdata/application/k8s-app-canary.yaml",
 		// 	expectedKind:       KindKubernetesApp,
@@ -136,3 +1212,196 @@ func TestKubernetesDeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestInputHelmOptionsWithOverrides(t *testing.T) {
+	testcases := []struct {
+		name      string
+		opts      *InputHelmOptions
+		overrides *K8sHelmValueOverrides
+		expected  *InputHelmOptions
+	}{
+		{
+			name: "nil options",
+			overrides: &K8sHelmValueOverrides{
+				ValueFiles: []string{"values-canary.yaml"},
+				SetValues: map[string]string{
+					"image.tag": "v2",
+				},
+			},
+			expected: &InputHelmOptions{
+				ValueFiles: []string{"values-canary.yaml"},
+				SetValues: map[string]string{
+					"image.tag": "v2",
+				},
+			},
+		},
+		{
+			name: "merged on top of the original ones",
+			opts: &InputHelmOptions{
+				ReleaseName: "release",
+				ValueFiles:  []string{"values.yaml"},
+				SetFiles: map[string]string{
+					"config": "config.yaml",
+				},
+				SetValues: map[string]string{
+					"image.tag":    "v1",
+					"replicaCount": "3",
+				},
+			},
+			overrides: &K8sHelmValueOverrides{
+				ValueFiles: []string{"values-canary.yaml"},
+				SetValues: map[string]string{
+					"image.tag": "v2",
+				},
+			},
+			expected: &InputHelmOptions{
+				ReleaseName: "release",
+				ValueFiles:  []string{"values.yaml", "values-canary.yaml"},
+				SetFiles: map[string]string{
+					"config": "config.yaml",
+				},
+				SetValues: map[string]string{
+					"image.tag":    "v2",
+					"replicaCount": "3",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.opts.WithOverrides(tc.overrides)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestWebhookRetryTimeout(t *testing.T) {
+	testcases := []struct {
+		name     string
+		retry    *K8sWebhookRetry
+		expected time.Duration
+	}{
+		{
+			name:     "not configured",
+			expected: time.Minute,
+		},
+		{
+			name: "configured timeout",
+			retry: &K8sWebhookRetry{
+				Timeout: Duration(3 * time.Minute),
+			},
+			expected: 3 * time.Minute,
+		},
+		{
+			name: "disabled",
+			retry: &K8sWebhookRetry{
+				Disabled: true,
+				Timeout:  Duration(3 * time.Minute),
+			},
+			expected: 0,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			in := KubernetesDeploymentInput{
+				WebhookRetry: tc.retry,
+			}
+			assert.Equal(t, tc.expected, in.WebhookRetryTimeout())
+		})
+	}
+}
+
+func TestKubernetesDeploymentSpecValidateNamespace(t *testing.T) {
+	testcases := []struct {
+		name    string
+		input   KubernetesDeploymentInput
+		wantErr bool
+	}{
+		{
+			name: "no namespace options",
+		},
+		{
+			name: "auto create with namespace",
+			input: KubernetesDeploymentInput{
+				Namespace:           "preview",
+				AutoCreateNamespace: true,
+				EnforceNamespace:    true,
+			},
+		},
+		{
+			name: "auto create without namespace",
+			input: KubernetesDeploymentInput{
+				AutoCreateNamespace: true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "enforce without namespace",
+			input: KubernetesDeploymentInput{
+				EnforceNamespace: true,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := KubernetesDeploymentSpec{
+				Input: tc.input,
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKubernetesDeploymentSpecValidateDiffOnly(t *testing.T) {
+	primary := func(diffOnly bool) PipelineStage {
+		return PipelineStage{
+			Name:                          model.StageK8sPrimaryRollout,
+			K8sPrimaryRolloutStageOptions: &K8sPrimaryRolloutStageOptions{DiffOnly: diffOnly},
+		}
+	}
+	testcases := []struct {
+		name    string
+		stages  []PipelineStage
+		wantErr bool
+	}{
+		{
+			name:   "no diffOnly",
+			stages: []PipelineStage{primary(false)},
+		},
+		{
+			name: "diffOnly followed by applying",
+			stages: []PipelineStage{
+				primary(true),
+				{Name: model.StageWaitApproval},
+				primary(false),
+			},
+		},
+		{
+			name:    "only diffOnly",
+			stages:  []PipelineStage{primary(true)},
+			wantErr: true,
+		},
+		{
+			name: "diffOnly after applying",
+			stages: []PipelineStage{
+				primary(false),
+				primary(true),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := KubernetesDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Pipeline: &DeploymentPipeline{Stages: tc.stages},
+				},
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
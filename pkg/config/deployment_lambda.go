@@ -14,6 +14,12 @@
 
 package config
 
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
 // LambdaDeploymentSpec represents a deployment configuration for Lambda application.
 type LambdaDeploymentSpec struct {
 	GenericDeploymentSpec
@@ -28,6 +34,16 @@ func (s *LambdaDeploymentSpec) Validate() error {
 	if err := s.GenericDeploymentSpec.Validate(); err != nil {
 		return err
 	}
+	if a := s.Input.RollbackApproval; a != nil {
+		if err := s.validateRollbackApproval(a); err != nil {
+			return err
+		}
+	}
+	if p := s.Input.Package; p != nil {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -35,9 +51,50 @@ type LambdaDeploymentInput struct {
 	// The name of service manifest file placing in application directory.
 	// Default is function.yaml
 	FunctionManifestFile string `json:"functionManifestFile" default:"function.yaml"`
+	// Whether to update only the function configuration without publishing a new version
+	// when the function code (image) was not changed while executing LAMBDA_SYNC stage.
+	// Note that the published versions keep their own configuration, so the changes of
+	// the version-specific settings are applied to the unpublished $LATEST version only.
+	// Default is false.
+	ConfigurationOnly bool `json:"configurationOnly"`
 	// Automatically reverts all changes from all stages when one of them failed.
 	// Default is true.
 	AutoRollback bool `json:"autoRollback" default:"true"`
+	// Wait for a manual approval before executing the rollback
+	// when the deployment failed at an ANALYSIS stage.
+	// Empty means the rollback will be executed immediately.
+	RollbackApproval *RollbackApproval `json:"rollbackApproval"`
+	// Package the source code in the repository into a zip archive
+	// and deploy the function from it instead of the code specified in the function manifest.
+	// Empty means the function is deployed from the image or the zip archive specified in the function manifest.
+	Package *LambdaPackage `json:"package"`
+}
+
+// LambdaPackage contains configurable values for packaging the source code of a Lambda function.
+// The zip archive is uploaded to S3 at <s3KeyPrefix>/<function name>/<commit hash>.zip.
+type LambdaPackage struct {
+	// The path to the directory containing the source code, relative to the application directory.
+	// All files under the directory are zipped as is, so they must be ready to run by the runtime.
+	// Required field.
+	Source string `json:"source"`
+	// The S3 bucket where the zip archive is uploaded.
+	// Required field.
+	S3Bucket string `json:"s3Bucket"`
+	// The prefix of the S3 key of the zip archive.
+	S3KeyPrefix string `json:"s3KeyPrefix"`
+}
+
+func (p *LambdaPackage) Validate() error {
+	if p.Source == "" {
+		return fmt.Errorf("package.source is required")
+	}
+	if filepath.IsAbs(p.Source) || strings.HasPrefix(filepath.Clean(p.Source), "..") {
+		return fmt.Errorf("package.source must be a relative path inside the application directory")
+	}
+	if p.S3Bucket == "" {
+		return fmt.Errorf("package.s3Bucket is required")
+	}
+	return nil
 }
 
 // LambdaSyncStageOptions contains all configurable values for a LAMBDA_SYNC stage.
@@ -45,6 +45,45 @@ func TestLambdaDeploymentConfig(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			fileName:           "testdata/application/lambda-app-rollback-approval.yaml",
+			expectedKind:       KindLambdaApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &LambdaDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: LambdaDeploymentInput{
+					FunctionManifestFile: "function.yaml",
+					AutoRollback:         true,
+					RollbackApproval: &RollbackApproval{
+						Timeout:       Duration(30 * time.Minute),
+						DefaultAction: RollbackApprovalDefaultActionRollback,
+					},
+				},
+			},
+			expectedError: nil,
+		},
+		{
+			fileName:           "testdata/application/lambda-app-package.yaml",
+			expectedKind:       KindLambdaApp,
+			expectedAPIVersion: "pipecd.dev/v1beta1",
+			expectedSpec: &LambdaDeploymentSpec{
+				GenericDeploymentSpec: GenericDeploymentSpec{
+					Timeout: Duration(6 * time.Hour),
+				},
+				Input: LambdaDeploymentInput{
+					FunctionManifestFile: "function.yaml",
+					AutoRollback:         true,
+					Package: &LambdaPackage{
+						Source:      "src",
+						S3Bucket:    "pipecd-lambda-packages",
+						S3KeyPrefix: "simple",
+					},
+				},
+			},
+			expectedError: nil,
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.fileName, func(t *testing.T) {
@@ -58,3 +97,38 @@ func TestLambdaDeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestLambdaPackageValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		pkg     LambdaPackage
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			pkg:     LambdaPackage{Source: "src", S3Bucket: "bucket"},
+			wantErr: false,
+		},
+		{
+			name:    "missing source",
+			pkg:     LambdaPackage{S3Bucket: "bucket"},
+			wantErr: true,
+		},
+		{
+			name:    "source outside the application directory",
+			pkg:     LambdaPackage{Source: "../src", S3Bucket: "bucket"},
+			wantErr: true,
+		},
+		{
+			name:    "missing s3 bucket",
+			pkg:     LambdaPackage{Source: "src"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.pkg.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
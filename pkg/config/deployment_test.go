@@ -16,6 +16,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -71,3 +72,278 @@ func TestHasStage(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateSopsDecryption(t *testing.T) {
+	testcases := []struct {
+		name    string
+		targets []string
+		wantErr bool
+	}{
+		{
+			name:    "valid targets",
+			targets: []string{"secret.yaml", "secrets/db.yaml"},
+			wantErr: false,
+		},
+		{
+			name:    "empty target",
+			targets: []string{""},
+			wantErr: true,
+		},
+		{
+			name:    "absolute path",
+			targets: []string{"/etc/secret.yaml"},
+			wantErr: true,
+		},
+		{
+			name:    "outside application directory",
+			targets: []string{"secrets/../../secret.yaml"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := SopsDecryption{DecryptionTargets: tc.targets}
+			err := d.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestValidateExternalSecrets(t *testing.T) {
+	testcases := []struct {
+		name    string
+		targets []string
+		wantErr bool
+	}{
+		{
+			name:    "valid targets",
+			targets: []string{"deployment.yaml", "secrets/db.yaml"},
+			wantErr: false,
+		},
+		{
+			name:    "empty target",
+			targets: []string{""},
+			wantErr: true,
+		},
+		{
+			name:    "outside application directory",
+			targets: []string{"../secret.yaml"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := ExternalSecrets{Targets: tc.targets}
+			err := e.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestValidateSkipAnalysisMatcher(t *testing.T) {
+	testcases := []struct {
+		name    string
+		matcher SkipAnalysisMatcher
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			matcher: SkipAnalysisMatcher{
+				Pattern:        `\[skip-analysis\]`,
+				AllowedAuthors: []string{"foo"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty pattern",
+			matcher: SkipAnalysisMatcher{
+				AllowedAuthors: []string{"foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid pattern",
+			matcher: SkipAnalysisMatcher{
+				Pattern:        `[skip-analysis`,
+				AllowedAuthors: []string{"foo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no allowed author",
+			matcher: SkipAnalysisMatcher{
+				Pattern: `\[skip-analysis\]`,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.matcher.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestValidateDeploymentTrigger(t *testing.T) {
+	testcases := []struct {
+		name    string
+		trigger DeploymentTrigger
+		wantErr bool
+	}{
+		{
+			name: "valid paths",
+			trigger: DeploymentTrigger{
+				Includes: []string{"apps/demo/**"},
+				Excludes: []string{"**/*.md"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid include pattern",
+			trigger: DeploymentTrigger{
+				Includes: []string{"apps/[demo"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tag with excludes",
+			trigger: DeploymentTrigger{
+				Tag:      "v*",
+				Excludes: []string{"**/*.md"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid schedule with time zone",
+			trigger: DeploymentTrigger{
+				Schedule: "CRON_TZ=Asia/Tokyo 0 3 * * *",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid schedule",
+			trigger: DeploymentTrigger{
+				Schedule: "0 3 * *",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.trigger.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestStageRetries(t *testing.T) {
+	testcases := []struct {
+		name        string
+		retries     StageRetries
+		wantErr     bool
+		wantBackoff []time.Duration
+	}{
+		{
+			name:        "default backoff",
+			retries:     StageRetries{Limit: 3},
+			wantBackoff: []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second},
+		},
+		{
+			name: "backoff is capped by maxBackoff",
+			retries: StageRetries{
+				Limit:      4,
+				Backoff:    Duration(time.Minute),
+				MaxBackoff: Duration(3 * time.Minute),
+			},
+			wantBackoff: []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute},
+		},
+		{
+			name:    "negative limit",
+			retries: StageRetries{Limit: -1},
+			wantErr: true,
+		},
+		{
+			name:    "negative backoff",
+			retries: StageRetries{Limit: 1, Backoff: Duration(-time.Second)},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.retries.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			for i, want := range tc.wantBackoff {
+				assert.Equal(t, want, tc.retries.GetBackoff(i))
+			}
+		})
+	}
+}
+
+func TestPipelineStageOutputs(t *testing.T) {
+	testcases := []struct {
+		name        string
+		stage       PipelineStage
+		wantErr     bool
+		wantOutputs []*model.StageOutput
+	}{
+		{
+			name: "declared outputs",
+			stage: PipelineStage{
+				Name: model.StageScriptRun,
+				Outputs: []StageOutput{
+					{Name: "Endpoint", Required: true},
+					{Name: "replica_count", Type: StageOutputTypeNumber},
+				},
+			},
+			wantOutputs: []*model.StageOutput{
+				{Name: "Endpoint", Type: model.StageOutputType_STAGE_OUTPUT_STRING, Required: true},
+				{Name: "replica_count", Type: model.StageOutputType_STAGE_OUTPUT_NUMBER},
+			},
+		},
+		{
+			name: "built-in outputs",
+			stage: PipelineStage{
+				Name:    model.StageTerraformPlan,
+				Outputs: []StageOutput{{Name: "Summary"}},
+			},
+			wantOutputs: append(model.BuiltinStageOutputs(model.StageTerraformPlan), &model.StageOutput{
+				Name: "Summary",
+				Type: model.StageOutputType_STAGE_OUTPUT_STRING,
+			}),
+		},
+		{
+			name: "invalid name",
+			stage: PipelineStage{
+				Name:    model.StageScriptRun,
+				Outputs: []StageOutput{{Name: "image-tag"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "conflicting with built-in output",
+			stage: PipelineStage{
+				Name:    model.StageTerraformPlan,
+				Outputs: []StageOutput{{Name: "HasChanges"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported type",
+			stage: PipelineStage{
+				Name:    model.StageScriptRun,
+				Outputs: []StageOutput{{Name: "Config", Type: "json"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.stage.validateOutputs()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.wantOutputs, tc.stage.StageOutputs())
+			}
+		})
+	}
+}
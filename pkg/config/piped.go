@@ -19,7 +19,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/pipe-cd/pipe/pkg/model"
 )
@@ -65,10 +72,48 @@ type PipedSpec struct {
 	SealedSecretManagement *SecretManagement `json:"sealedSecretManagement"`
 	// What secret management method should be used.
 	SecretManagement *SecretManagement `json:"secretManagement"`
+	// The key material used to decrypt the files encrypted by sops.
+	Sops *PipedSops `json:"sops"`
+	// The Vault server storing the secrets referenced from the application configuration.
+	Vault *PipedVault `json:"vault"`
 	// Optional settings for event watcher.
 	EventWatcher PipedEventWatcher `json:"eventWatcher"`
+	// Feature flags to enable the new behaviors of executors
+	// for some applications or deployments before they become the default.
+	FeatureFlags []PipedFeatureFlag `json:"featureFlags"`
+	// Settings for running the user-defined scripts by SCRIPT_RUN stage.
+	// The stage is disabled unless it is explicitly enabled here.
+	ScriptRun PipedScriptRun `json:"scriptRun"`
+	// Settings for downloading the tools such as kubectl, helm...
+	// used while executing the deployments.
+	Tools PipedTools `json:"tools"`
+	// Settings for receiving the push events of the repositories by webhook
+	// to trigger the deployments without waiting for the next syncInterval.
+	TriggerWebhook *PipedTriggerWebhook `json:"triggerWebhook"`
+	// List of OCI registries where the application manifests are pulled from.
+	// The registries not listed here are accessed anonymously over HTTPS.
+	OCIRegistries []PipedOCIRegistry `json:"ociRegistries"`
+	// Settings for upgrading piped itself to the version desired by the control plane.
+	// Piped never upgrades itself when this is not specified.
+	Upgrade *PipedUpgrade `json:"upgrade"`
+	// Settings for running multiple replicas sharing this piped ID and key.
+	// The applications are sharded across the live replicas.
+	Sharding *PipedSharding `json:"sharding"`
+	// List of the external processes providing the stages
+	// which are not built into piped.
+	ExecutorPlugins []PipedExecutorPlugin `json:"executorPlugins"`
+	// List of the external logging systems the stage logs are streamed to
+	// in addition to the control plane.
+	LogSinks []PipedLogSink `json:"logSinks"`
+
+	// Guards the fields which can be reloaded while piped is running.
+	// They must be read through the getters.
+	mu sync.RWMutex
 }
 
+// pipedReloadableFields are the JSON names of the fields replaced by Reload.
+var pipedReloadableFields = []string{"repositories", "analysisProviders", "notifications"}
+
 // Validate validates configured data of all fields.
 func (s *PipedSpec) Validate() error {
 	if s.ProjectID == "" {
@@ -102,17 +147,125 @@ func (s *PipedSpec) Validate() error {
 			return err
 		}
 	}
+	if s.Sops != nil {
+		if err := s.Sops.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Vault != nil {
+		if err := s.Vault.Validate(); err != nil {
+			return err
+		}
+	}
 	if err := s.EventWatcher.Validate(); err != nil {
 		return err
 	}
+	if s.TriggerWebhook != nil {
+		if err := s.TriggerWebhook.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.Repositories {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range s.OCIRegistries {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Upgrade != nil {
+		if err := s.Upgrade.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Sharding != nil {
+		if err := s.Sharding.Validate(); err != nil {
+			return err
+		}
+	}
 	for _, p := range s.AnalysisProviders {
 		if err := p.Validate(); err != nil {
 			return err
 		}
 	}
+	plugins := make(map[string]struct{}, len(s.ExecutorPlugins))
+	for _, p := range s.ExecutorPlugins {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if _, ok := plugins[p.Name]; ok {
+			return fmt.Errorf("duplicated executor plugin %s", p.Name)
+		}
+		plugins[p.Name] = struct{}{}
+	}
+	sinks := make(map[string]struct{}, len(s.LogSinks))
+	for _, l := range s.LogSinks {
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		if _, ok := sinks[l.Name]; ok {
+			return fmt.Errorf("duplicated log sink %s", l.Name)
+		}
+		sinks[l.Name] = struct{}{}
+	}
+	for _, p := range s.CloudProviders {
+		if p.KubernetesConfig != nil {
+			if err := p.KubernetesConfig.AppStateInformer.Validate(); err != nil {
+				return fmt.Errorf("invalid config of cloud provider %s: %w", p.Name, err)
+			}
+		}
+		if p.CustomSyncConfig == nil {
+			continue
+		}
+		if err := p.CustomSyncConfig.Validate(); err != nil {
+			return fmt.Errorf("invalid config of cloud provider %s: %w", p.Name, err)
+		}
+	}
+	for _, r := range s.Notifications.Receivers {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("invalid notification receiver %s: %w", r.Name, err)
+		}
+	}
+	for _, r := range s.ChartRepositories {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if r.CredentialsProvider == "" {
+			continue
+		}
+		if _, ok := s.FindRegistryCredentialsProvider(r.CredentialsProvider); !ok {
+			return fmt.Errorf("credentialsProvider %s of chart repository %s must be one of the configured ECS, LAMBDA or CLOUDRUN cloud providers", r.CredentialsProvider, r.Name)
+		}
+	}
+	names := make(map[string]struct{}, len(s.FeatureFlags))
+	for _, f := range s.FeatureFlags {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[f.Name]; ok {
+			return fmt.Errorf("duplicated feature flag %s", f.Name)
+		}
+		names[f.Name] = struct{}{}
+	}
+	if err := s.Tools.Validate(); err != nil {
+		return fmt.Errorf("invalid tools configuration: %w", err)
+	}
 	return nil
 }
 
+// IsFeatureEnabled returns whether the given feature is enabled
+// for the given deployment of the given application.
+func (s *PipedSpec) IsFeatureEnabled(feature, appName, deploymentID string) bool {
+	for _, f := range s.FeatureFlags {
+		if f.Name == feature {
+			return f.enabled(appName, deploymentID)
+		}
+	}
+	return false
+}
+
 // EnableDefaultKubernetesCloudProvider adds the default kubernetes cloud provider if it was not specified.
 func (s *PipedSpec) EnableDefaultKubernetesCloudProvider() {
 	for _, cp := range s.CloudProviders {
@@ -151,10 +304,33 @@ func (s *PipedSpec) FindCloudProvider(name string, t model.CloudProviderType) (P
 	return PipedCloudProvider{}, false
 }
 
+// FindRegistryCredentialsProvider finds and returns a Cloud Provider by name
+// whose credentials can be used to log in to OCI registries.
+func (s *PipedSpec) FindRegistryCredentialsProvider(name string) (PipedCloudProvider, bool) {
+	for _, p := range s.CloudProviders {
+		if p.Name != name {
+			continue
+		}
+		switch p.Type {
+		case model.CloudProviderECS, model.CloudProviderLambda, model.CloudProviderCloudRun:
+			return p, true
+		}
+	}
+	return PipedCloudProvider{}, false
+}
+
+// GetRepositories returns the list of repositories this piped handles.
+func (s *PipedSpec) GetRepositories() []PipedRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Repositories
+}
+
 // GetRepositoryMap returns a map of repositories where key is repo id.
 func (s *PipedSpec) GetRepositoryMap() map[string]PipedRepository {
-	m := make(map[string]PipedRepository, len(s.Repositories))
-	for _, repo := range s.Repositories {
+	repos := s.GetRepositories()
+	m := make(map[string]PipedRepository, len(repos))
+	for _, repo := range repos {
 		m[repo.RepoID] = repo
 	}
 	return m
@@ -162,7 +338,7 @@ func (s *PipedSpec) GetRepositoryMap() map[string]PipedRepository {
 
 // GetRepository finds a repository with the given ID from the configured list.
 func (s *PipedSpec) GetRepository(id string) (PipedRepository, bool) {
-	for _, repo := range s.Repositories {
+	for _, repo := range s.GetRepositories() {
 		if repo.RepoID == id {
 			return repo, true
 		}
@@ -170,9 +346,20 @@ func (s *PipedSpec) GetRepository(id string) (PipedRepository, bool) {
 	return PipedRepository{}, false
 }
 
+// GetNotifications returns the configured notification routes and receivers.
+func (s *PipedSpec) GetNotifications() Notifications {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Notifications
+}
+
 // GetAnalysisProvider finds and returns an Analysis Provider config whose name is the given string.
 func (s *PipedSpec) GetAnalysisProvider(name string) (PipedAnalysisProvider, bool) {
-	for _, p := range s.AnalysisProviders {
+	s.mu.RLock()
+	providers := s.AnalysisProviders
+	s.mu.RUnlock()
+
+	for _, p := range providers {
 		if p.Name == name {
 			return p, true
 		}
@@ -180,6 +367,78 @@ func (s *PipedSpec) GetAnalysisProvider(name string) (PipedAnalysisProvider, boo
 	return PipedAnalysisProvider{}, false
 }
 
+// Reload replaces the repositories, analysis providers and notifications
+// with the ones of the given spec while piped is running,
+// and returns the JSON names of the changed fields.
+func (s *PipedSpec) Reload(n *PipedSpec) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changed []string
+	if !reflect.DeepEqual(s.Repositories, n.Repositories) {
+		s.Repositories = n.Repositories
+		changed = append(changed, "repositories")
+	}
+	if !reflect.DeepEqual(s.AnalysisProviders, n.AnalysisProviders) {
+		s.AnalysisProviders = n.AnalysisProviders
+		changed = append(changed, "analysisProviders")
+	}
+	if !reflect.DeepEqual(s.Notifications, n.Notifications) {
+		s.Notifications = n.Notifications
+		changed = append(changed, "notifications")
+	}
+	return changed
+}
+
+// RequiresRestart reports whether the given spec has any change
+// which can not be applied by Reload and requires restarting piped.
+func (s *PipedSpec) RequiresRestart(n *PipedSpec) (bool, error) {
+	s.mu.RLock()
+	cur, err := json.Marshal(s)
+	repos := s.Repositories
+	s.mu.RUnlock()
+	if err != nil {
+		return false, err
+	}
+	// The credentials of the repositories are given to the git clients only while starting up.
+	if repositoryCredentialsChanged(repos, n.Repositories) {
+		return true, nil
+	}
+	next, err := json.Marshal(n)
+	if err != nil {
+		return false, err
+	}
+
+	var curFields, nextFields map[string]interface{}
+	if err := json.Unmarshal(cur, &curFields); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(next, &nextFields); err != nil {
+		return false, err
+	}
+	for _, f := range pipedReloadableFields {
+		delete(curFields, f)
+		delete(nextFields, f)
+	}
+	return !reflect.DeepEqual(curFields, nextFields), nil
+}
+
+// repositoryCredentialsChanged reports whether any of the next repositories
+// has the credentials different from the current repository of the same ID.
+func repositoryCredentialsChanged(cur, next []PipedRepository) bool {
+	m := make(map[string]PipedRepository, len(cur))
+	for _, r := range cur {
+		m[r.RepoID] = r
+	}
+	for _, r := range next {
+		c := m[r.RepoID]
+		if c.SSHKeyFile != r.SSHKeyFile || !reflect.DeepEqual(c.GitHubApp, r.GitHubApp) {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *PipedSpec) IsInsecureChartRepository(name string) bool {
 	for _, cr := range s.ChartRepositories {
 		if cr.Name == name {
@@ -189,6 +448,17 @@ func (s *PipedSpec) IsInsecureChartRepository(name string) bool {
 	return false
 }
 
+// GetOCIChartRegistry returns the address of the OCI registry for the given chart repository name.
+// An empty string will be returned if the repository was not found or it is not an OCI one.
+func (s *PipedSpec) GetOCIChartRegistry(name string) string {
+	for _, cr := range s.ChartRepositories {
+		if cr.Name == name && cr.IsOCI() {
+			return cr.Address
+		}
+	}
+	return ""
+}
+
 func (s *PipedSpec) GetSecretManagement() *SecretManagement {
 	if s.SealedSecretManagement != nil {
 		return s.SealedSecretManagement
@@ -239,30 +509,566 @@ func (g PipedGit) LoadSSHKey() ([]byte, error) {
 	if g.SSHKeyData != "" && g.SSHKeyFile != "" {
 		return nil, errors.New("only either sshKeyFile or sshKeyData can be set")
 	}
-	if g.SSHKeyData != "" {
-		return base64.StdEncoding.DecodeString(g.SSHKeyData)
+	if g.SSHKeyData != "" {
+		return base64.StdEncoding.DecodeString(g.SSHKeyData)
+	}
+	if g.SSHKeyFile != "" {
+		return os.ReadFile(g.SSHKeyFile)
+	}
+	return nil, errors.New("either sshKeyFile or sshKeyData must be set")
+}
+
+// PipedSops contains the key material used by sops.
+// The cloud KMS keys are accessed by the credentials of the environment piped is running on.
+type PipedSops struct {
+	// The path to the file containing the age keys.
+	AgeKeyFile string `json:"ageKeyFile"`
+	// Base64 encoded string of the age keys.
+	AgeKeyData string `json:"ageKeyData"`
+}
+
+func (s *PipedSops) Validate() error {
+	if s.AgeKeyData != "" && s.AgeKeyFile != "" {
+		return errors.New("only either ageKeyFile or ageKeyData can be set")
+	}
+	return nil
+}
+
+// LoadAgeKey returns the age keys, or nil if nothing was configured.
+func (s *PipedSops) LoadAgeKey() ([]byte, error) {
+	if s.AgeKeyData != "" {
+		return base64.StdEncoding.DecodeString(s.AgeKeyData)
+	}
+	if s.AgeKeyFile != "" {
+		return os.ReadFile(s.AgeKeyFile)
+	}
+	return nil, nil
+}
+
+const (
+	VaultAuthMethodToken      = "TOKEN"
+	VaultAuthMethodKubernetes = "KUBERNETES"
+
+	defaultVaultKubernetesMountPath = "kubernetes"
+	defaultVaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// PipedVault configures the access to the Vault server
+// whose KV version 2 secrets can be referenced from the application configuration.
+type PipedVault struct {
+	// Required: The address of the Vault server.
+	Address string `json:"address"`
+	// The Vault Enterprise namespace.
+	Namespace string `json:"namespace"`
+	// The path to the CA certificate file to verify the Vault server.
+	CACertFile string `json:"caCertFile"`
+	// How piped authenticates with Vault.
+	// Available values: TOKEN, KUBERNETES
+	// Default is TOKEN.
+	AuthMethod string `json:"authMethod"`
+	// The path to the file containing the Vault token.
+	// Required when the auth method is TOKEN.
+	// The file is read at every access to let the token be renewed by Vault Agent.
+	TokenFile string `json:"tokenFile"`
+	// The Vault role to login with the service account of piped.
+	// Required when the auth method is KUBERNETES.
+	KubernetesRole string `json:"kubernetesRole"`
+	// The path the Kubernetes auth method is mounted at.
+	// Default is kubernetes.
+	KubernetesMountPath string `json:"kubernetesMountPath"`
+	// The path to the service account token file.
+	// Default is /var/run/secrets/kubernetes.io/serviceaccount/token.
+	KubernetesTokenFile string `json:"kubernetesTokenFile"`
+}
+
+func (v *PipedVault) Validate() error {
+	if v.Address == "" {
+		return errors.New("address of vault must be set")
+	}
+	switch v.AuthMethod {
+	case "", VaultAuthMethodToken:
+		if v.TokenFile == "" {
+			return errors.New("tokenFile of vault must be set for TOKEN auth method")
+		}
+	case VaultAuthMethodKubernetes:
+		if v.KubernetesRole == "" {
+			return errors.New("kubernetesRole of vault must be set for KUBERNETES auth method")
+		}
+	default:
+		return fmt.Errorf("unsupported auth method of vault: %s", v.AuthMethod)
+	}
+	return nil
+}
+
+func (v *PipedVault) GetKubernetesMountPath() string {
+	if v.KubernetesMountPath != "" {
+		return v.KubernetesMountPath
+	}
+	return defaultVaultKubernetesMountPath
+}
+
+func (v *PipedVault) GetKubernetesTokenFile() string {
+	if v.KubernetesTokenFile != "" {
+		return v.KubernetesTokenFile
+	}
+	return defaultVaultKubernetesTokenFile
+}
+
+const defaultTriggerWebhookPort = 9088
+
+// PipedTriggerWebhook configures the HTTP server receiving the push events
+// from GitHub, GitLab and Bitbucket at "/webhook" path.
+// The repositories are still checked at every syncInterval in case of missing the events.
+type PipedTriggerWebhook struct {
+	// The port number of the server.
+	// Default is 9088.
+	Port int `json:"port"`
+	// The path to the file containing the secret used to verify the payloads.
+	SecretFile string `json:"secretFile"`
+}
+
+func (w *PipedTriggerWebhook) Validate() error {
+	if w.Port < 0 || w.Port > 65535 {
+		return fmt.Errorf("invalid port %d of triggerWebhook", w.Port)
+	}
+	if w.SecretFile == "" {
+		return errors.New("secretFile of triggerWebhook must be set")
+	}
+	return nil
+}
+
+// ListenPort returns the port number of the server.
+func (w *PipedTriggerWebhook) ListenPort() int {
+	if w.Port == 0 {
+		return defaultTriggerWebhookPort
+	}
+	return w.Port
+}
+
+// PipedOCIRegistry contains the settings to access an OCI registry.
+type PipedOCIRegistry struct {
+	// The host of the registry, with the port if needed.
+	// e.g. ghcr.io, localhost:5000
+	Address string `json:"address"`
+	// The username used to authenticate to the registry.
+	Username string `json:"username"`
+	// The path to the file containing the password or the access token.
+	PasswordFile string `json:"passwordFile"`
+	// Whether to connect to the registry over plain HTTP.
+	Insecure bool `json:"insecure"`
+}
+
+func (r *PipedOCIRegistry) Validate() error {
+	if r.Address == "" {
+		return errors.New("address of OCI registry must be set")
+	}
+	if (r.Username == "") != (r.PasswordFile == "") {
+		return fmt.Errorf("both username and passwordFile must be set for OCI registry %s", r.Address)
+	}
+	return nil
+}
+
+const defaultExecutorPluginConnectTimeout = Duration(30 * time.Second)
+
+// PipedExecutorPlugin configures an external process serving the executor plugin gRPC service.
+// The stages listed by the plugin are registered to piped while starting up.
+type PipedExecutorPlugin struct {
+	// The unique name of the plugin.
+	Name string `json:"name"`
+	// The address of the gRPC server of the plugin. e.g. localhost:9095
+	Address string `json:"address"`
+	// The path to the TLS certificate of the gRPC server.
+	// Empty means connecting to the server without TLS.
+	CertFile string `json:"certFile"`
+	// How long to wait for connecting to the plugin and listing its stages.
+	// Default is 30s.
+	ConnectTimeout Duration `json:"connectTimeout"`
+}
+
+func (p *PipedExecutorPlugin) Validate() error {
+	if p.Name == "" {
+		return errors.New("name of executor plugin must be set")
+	}
+	if p.Address == "" {
+		return fmt.Errorf("address of executor plugin %s must be set", p.Name)
+	}
+	if p.ConnectTimeout < 0 {
+		return fmt.Errorf("connectTimeout of executor plugin %s must not be negative", p.Name)
+	}
+	return nil
+}
+
+// GetConnectTimeout returns how long to wait for the plugin while starting up.
+func (p *PipedExecutorPlugin) GetConnectTimeout() time.Duration {
+	if p.ConnectTimeout == 0 {
+		return defaultExecutorPluginConnectTimeout.Duration()
+	}
+	return p.ConnectTimeout.Duration()
+}
+
+const (
+	LogSinkTypeCloudWatchLogs = "CLOUDWATCH_LOGS"
+	LogSinkTypeStackdriver    = "STACKDRIVER"
+	LogSinkTypeLoki           = "LOKI"
+	LogSinkTypeFile           = "FILE"
+
+	defaultStackdriverLogName = "pipecd-stage-logs"
+)
+
+// PipedLogSink streams the stage logs to an external logging system
+// for the long-term retention and searching of the deployment logs.
+type PipedLogSink struct {
+	// The unique name of the sink.
+	Name string `json:"name"`
+	// Which logging system the logs are sent to.
+	// Available values: CLOUDWATCH_LOGS, STACKDRIVER, LOKI, FILE
+	Type           string                       `json:"type"`
+	CloudWatchLogs *LogSinkCloudWatchLogsConfig `json:"cloudWatchLogs"`
+	Stackdriver    *LogSinkStackdriverConfig    `json:"stackdriver"`
+	Loki           *LogSinkLokiConfig           `json:"loki"`
+	File           *LogSinkFileConfig           `json:"file"`
+}
+
+func (s *PipedLogSink) Validate() error {
+	if s.Name == "" {
+		return errors.New("name of log sink must be set")
+	}
+	var err error
+	switch s.Type {
+	case LogSinkTypeCloudWatchLogs:
+		if s.CloudWatchLogs == nil {
+			return fmt.Errorf("log sink %s requires the cloudWatchLogs config", s.Name)
+		}
+		err = s.CloudWatchLogs.Validate()
+	case LogSinkTypeStackdriver:
+		if s.Stackdriver == nil {
+			return fmt.Errorf("log sink %s requires the stackdriver config", s.Name)
+		}
+		err = s.Stackdriver.Validate()
+	case LogSinkTypeLoki:
+		if s.Loki == nil {
+			return fmt.Errorf("log sink %s requires the loki config", s.Name)
+		}
+		err = s.Loki.Validate()
+	case LogSinkTypeFile:
+		if s.File == nil {
+			return fmt.Errorf("log sink %s requires the file config", s.Name)
+		}
+		err = s.File.Validate()
+	default:
+		return fmt.Errorf("unsupported type %q of log sink %s", s.Type, s.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid log sink %s: %w", s.Name, err)
+	}
+	return nil
+}
+
+type LogSinkCloudWatchLogsConfig struct {
+	// Required: The AWS region of the log group.
+	Region string `json:"region"`
+	// Required: The name of the existing log group the logs are sent to.
+	// A log stream named <deployment id>/<stage id> is created for each stage.
+	LogGroup string `json:"logGroup"`
+	// Path to the shared credentials file.
+	CredentialsFile string `json:"credentialsFile"`
+	// AWS Profile to extract credentials from the shared credentials file.
+	// If empty, the environment variable "AWS_PROFILE" is used.
+	// "default" is populated if the environment variable is also not set.
+	Profile string `json:"profile"`
+}
+
+func (c *LogSinkCloudWatchLogsConfig) Validate() error {
+	if c.Region == "" {
+		return errors.New("cloudWatchLogs requires the region")
+	}
+	if c.LogGroup == "" {
+		return errors.New("cloudWatchLogs requires the logGroup")
+	}
+	return nil
+}
+
+type LogSinkStackdriverConfig struct {
+	// Required: The ID of the GCP project the logs are written to.
+	Project string `json:"project"`
+	// The name of the log the entries are written to.
+	// Default is pipecd-stage-logs.
+	LogName string `json:"logName"`
+	// The path to the service account file.
+	// Empty means the application default credentials are used.
+	CredentialsFile string `json:"credentialsFile"`
+}
+
+func (c *LogSinkStackdriverConfig) Validate() error {
+	if c.Project == "" {
+		return errors.New("stackdriver requires the project")
+	}
+	return nil
+}
+
+// GetLogName returns the name of the log the entries are written to.
+func (c *LogSinkStackdriverConfig) GetLogName() string {
+	if c.LogName == "" {
+		return defaultStackdriverLogName
+	}
+	return c.LogName
+}
+
+type LogSinkLokiConfig struct {
+	// Required: The address of Loki. e.g. http://loki:3100
+	Address string `json:"address"`
+	// The tenant ID sent as the X-Scope-OrgID header to the multi-tenant Loki.
+	TenantID string `json:"tenantID"`
+	// The path to the username file for the basic authentication.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file for the basic authentication.
+	PasswordFile string `json:"passwordFile"`
+	// The labels attached to all log streams in addition to the built-in ones.
+	Labels map[string]string `json:"labels"`
+}
+
+func (c *LogSinkLokiConfig) Validate() error {
+	if c.Address == "" {
+		return errors.New("loki requires the address")
+	}
+	if (c.UsernameFile == "") != (c.PasswordFile == "") {
+		return errors.New("loki requires both usernameFile and passwordFile for the basic authentication")
+	}
+	return nil
+}
+
+type LogSinkFileConfig struct {
+	// Required: The path to the file the logs are appended to in the JSON Lines format.
+	Path string `json:"path"`
+}
+
+func (c *LogSinkFileConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("file requires the path")
+	}
+	return nil
+}
+
+const (
+	defaultUpgradeCheckInterval = Duration(time.Minute)
+	defaultUpgradeDrainTimeout  = Duration(time.Hour)
+)
+
+// PipedUpgrade configures how piped upgrades itself when the control plane
+// publishes a desired version different from the running one.
+// Piped stops planning the new deployments, waits for the in-flight ones to be completed,
+// downloads the binary of the desired version and restarts itself with it.
+type PipedUpgrade struct {
+	// Required: The URL template of the piped binary.
+	// {{ .Version }}, {{ .Os }} and {{ .Arch }} are replaced with the desired version
+	// and the platform piped is running on.
+	// e.g. https://github.com/pipe-cd/pipe/releases/download/{{ .Version }}/piped_{{ .Version }}_{{ .Os }}_{{ .Arch }}
+	BinaryURL string `json:"binaryURL"`
+	// Required: The URL template of the file containing the SHA-256 checksum of the binary
+	// in the format of sha256sum command.
+	// The downloaded binary is not executed unless its checksum matches.
+	ChecksumURL string `json:"checksumURL"`
+	// The directory where the downloaded binaries are stored.
+	// It must be writable and allow executing files.
+	// Default is the temporary directory of the system.
+	BinaryDir string `json:"binaryDir"`
+	// How often to check the desired version.
+	// Default is 1m.
+	CheckInterval Duration `json:"checkInterval"`
+	// How long to wait for the in-flight deployments to be completed.
+	// The upgrade is reported as failed if they are still running after this.
+	// Default is 1h.
+	DrainTimeout Duration `json:"drainTimeout"`
+}
+
+func (u *PipedUpgrade) Validate() error {
+	if u.BinaryURL == "" {
+		return errors.New("binaryURL of upgrade must be set")
+	}
+	if u.ChecksumURL == "" {
+		return errors.New("checksumURL of upgrade must be set")
+	}
+	if u.CheckInterval < 0 {
+		return errors.New("checkInterval of upgrade must not be negative")
+	}
+	if u.DrainTimeout < 0 {
+		return errors.New("drainTimeout of upgrade must not be negative")
+	}
+	return nil
+}
+
+// GetCheckInterval returns how often to check the desired version.
+func (u *PipedUpgrade) GetCheckInterval() time.Duration {
+	if u.CheckInterval == 0 {
+		return defaultUpgradeCheckInterval.Duration()
+	}
+	return u.CheckInterval.Duration()
+}
+
+// GetDrainTimeout returns how long to wait for the in-flight deployments to be completed.
+func (u *PipedUpgrade) GetDrainTimeout() time.Duration {
+	if u.DrainTimeout == 0 {
+		return defaultUpgradeDrainTimeout.Duration()
+	}
+	return u.DrainTimeout.Duration()
+}
+
+const (
+	defaultShardingHeartbeatInterval = Duration(10 * time.Second)
+	defaultShardingHeartbeatTimeout  = Duration(time.Minute)
+)
+
+// PipedSharding configures how the applications are sharded across
+// the replicas running with the same piped ID and key.
+// Each replica is identified by the --replica-id flag.
+// The applications not assigned explicitly are distributed by hashing their IDs.
+// The applications of the replica which stopped reporting heartbeats
+// are handed over to the other live replicas.
+type PipedSharding struct {
+	// How often each replica reports that it is still alive.
+	// Default is 10s.
+	HeartbeatInterval Duration `json:"heartbeatInterval"`
+	// How long a replica is considered alive since its last heartbeat.
+	// Default is 1m.
+	HeartbeatTimeout Duration `json:"heartbeatTimeout"`
+	// List of the applications handled by specific replicas while they are alive.
+	Assignments []PipedShardAssignment `json:"assignments"`
+}
+
+type PipedShardAssignment struct {
+	// The ID of the replica.
+	ReplicaID string `json:"replicaID"`
+	// The IDs of the applications handled by the replica.
+	ApplicationIDs []string `json:"applicationIDs"`
+}
+
+func (s *PipedSharding) Validate() error {
+	if s.HeartbeatInterval < 0 {
+		return errors.New("heartbeatInterval of sharding must not be negative")
+	}
+	if s.GetHeartbeatTimeout() <= s.GetHeartbeatInterval() {
+		return errors.New("heartbeatTimeout of sharding must be longer than heartbeatInterval")
+	}
+	assigned := make(map[string]string)
+	for _, a := range s.Assignments {
+		if a.ReplicaID == "" {
+			return errors.New("replicaID of sharding assignment must be set")
+		}
+		for _, id := range a.ApplicationIDs {
+			if r, ok := assigned[id]; ok {
+				return fmt.Errorf("application %s is assigned to both replicas %s and %s", id, r, a.ReplicaID)
+			}
+			assigned[id] = a.ReplicaID
+		}
+	}
+	return nil
+}
+
+// GetHeartbeatInterval returns how often each replica reports that it is still alive.
+func (s *PipedSharding) GetHeartbeatInterval() time.Duration {
+	if s.HeartbeatInterval == 0 {
+		return defaultShardingHeartbeatInterval.Duration()
+	}
+	return s.HeartbeatInterval.Duration()
+}
+
+// GetHeartbeatTimeout returns how long a replica is considered alive since its last heartbeat.
+func (s *PipedSharding) GetHeartbeatTimeout() time.Duration {
+	if s.HeartbeatTimeout == 0 {
+		return defaultShardingHeartbeatTimeout.Duration()
+	}
+	return s.HeartbeatTimeout.Duration()
+}
+
+// AssignedReplica returns the ID of the replica the given application is assigned to.
+func (s *PipedSharding) AssignedReplica(appID string) (string, bool) {
+	for _, a := range s.Assignments {
+		for _, id := range a.ApplicationIDs {
+			if id == appID {
+				return a.ReplicaID, true
+			}
+		}
+	}
+	return "", false
+}
+
+type PipedRepository struct {
+	// Unique identifier for this repository.
+	// This must be unique in the piped scope.
+	RepoID string `json:"repoId"`
+	// Remote address of the repository used to clone the source code.
+	// e.g. git@github.com:org/repo.git
+	Remote string `json:"remote"`
+	// The branch will be handled.
+	Branch string `json:"branch"`
+	// The path to the private ssh key file used only for this repository
+	// such as a deploy key, instead of the one configured in git.
+	SSHKeyFile string `json:"sshKeyFile"`
+	// The GitHub App used to access this repository over HTTPS.
+	GitHubApp *PipedRepositoryGitHubApp `json:"githubApp"`
+}
+
+func (r *PipedRepository) Validate() error {
+	if r.SSHKeyFile != "" && r.GitHubApp != nil {
+		return fmt.Errorf("only either sshKeyFile or githubApp can be set for repository %s", r.RepoID)
+	}
+	if r.GitHubApp == nil {
+		return nil
+	}
+	if !strings.HasPrefix(r.Remote, "https://") {
+		return fmt.Errorf("remote of repository %s must be an HTTPS URL to use githubApp", r.RepoID)
+	}
+	if err := r.GitHubApp.Validate(); err != nil {
+		return fmt.Errorf("invalid githubApp of repository %s: %w", r.RepoID, err)
+	}
+	return nil
+}
+
+// PipedRepositoryGitHubApp represents an installation of a GitHub App.
+// The installation access tokens are issued and refreshed automatically.
+type PipedRepositoryGitHubApp struct {
+	// The ID of the GitHub App.
+	AppID int64 `json:"appId"`
+	// The ID of the installation of the GitHub App
+	// to the organization or the user owning the repository.
+	InstallationID int64 `json:"installationId"`
+	// The path to the private key file of the GitHub App.
+	PrivateKeyFile string `json:"privateKeyFile"`
+	// The URL of GitHub API.
+	// Default is "https://api.github.com".
+	APIURL string `json:"apiUrl"`
+}
+
+func (a *PipedRepositoryGitHubApp) Validate() error {
+	if a.AppID <= 0 {
+		return errors.New("appId must be set")
+	}
+	if a.InstallationID <= 0 {
+		return errors.New("installationId must be set")
 	}
-	if g.SSHKeyFile != "" {
-		return os.ReadFile(g.SSHKeyFile)
+	if a.PrivateKeyFile == "" {
+		return errors.New("privateKeyFile must be set")
 	}
-	return nil, errors.New("either sshKeyFile or sshKeyData must be set")
+	return nil
 }
 
-type PipedRepository struct {
-	// Unique identifier for this repository.
-	// This must be unique in the piped scope.
-	RepoID string `json:"repoId"`
-	// Remote address of the repository used to clone the source code.
-	// e.g. git@github.com:org/repo.git
-	Remote string `json:"remote"`
-	// The branch will be handled.
-	Branch string `json:"branch"`
-}
+type HelmChartRepositoryType string
+
+const (
+	// HTTPHelmChartRepository is a classic Helm chart repository served over HTTP.
+	HTTPHelmChartRepository HelmChartRepositoryType = "HTTP"
+	// OCIHelmChartRepository is an OCI registry storing Helm charts.
+	OCIHelmChartRepository HelmChartRepositoryType = "OCI"
+)
 
 type HelmChartRepository struct {
+	// The repository type. Currently, HTTP and OCI are supported.
+	// Default is HTTP.
+	Type HelmChartRepositoryType `json:"type" default:"HTTP"`
 	// The name of the Helm chart repository.
 	Name string `json:"name"`
 	// The address to the Helm chart repository.
+	// For the OCI type, this is the registry address where charts are stored, e.g. oci://ghcr.io/org/charts
 	Address string `json:"address"`
 	// Username used for the repository backed by HTTP basic authentication.
 	Username string `json:"username"`
@@ -270,17 +1076,54 @@ type HelmChartRepository struct {
 	Password string `json:"password"`
 	// Whether to skip TLS certificate checks for the repository or not.
 	Insecure bool `json:"insecure"`
+	// The name of the cloud provider whose credentials are used to log in to the OCI registry,
+	// instead of the static username and password.
+	// ECS and LAMBDA providers are available for Amazon ECR, and CLOUDRUN provider is for Google Artifact Registry.
+	// The issued token is refreshed periodically before it expires.
+	CredentialsProvider string `json:"credentialsProvider"`
+}
+
+func (r *HelmChartRepository) IsOCI() bool {
+	return r.Type == OCIHelmChartRepository
+}
+
+func (r *HelmChartRepository) Validate() error {
+	if r.Name == "" {
+		return errors.New("name must be set for chart repository")
+	}
+	if r.Address == "" {
+		return fmt.Errorf("address must be set for chart repository %s", r.Name)
+	}
+	switch r.Type {
+	case HTTPHelmChartRepository, OCIHelmChartRepository:
+	default:
+		return fmt.Errorf("unsupported type %q for chart repository %s", r.Type, r.Name)
+	}
+	if r.CredentialsProvider != "" {
+		if !r.IsOCI() {
+			return fmt.Errorf("credentialsProvider is only available for OCI chart repository %s", r.Name)
+		}
+		if r.Username != "" || r.Password != "" {
+			return fmt.Errorf("credentialsProvider cannot be used with username and password for chart repository %s", r.Name)
+		}
+	}
+	return nil
 }
 
 type PipedCloudProvider struct {
 	Name string
 	Type model.CloudProviderType
 
-	KubernetesConfig *CloudProviderKubernetesConfig
-	TerraformConfig  *CloudProviderTerraformConfig
-	CloudRunConfig   *CloudProviderCloudRunConfig
-	LambdaConfig     *CloudProviderLambdaConfig
-	ECSConfig        *CloudProviderECSConfig
+	KubernetesConfig     *CloudProviderKubernetesConfig
+	TerraformConfig      *CloudProviderTerraformConfig
+	CloudRunConfig       *CloudProviderCloudRunConfig
+	LambdaConfig         *CloudProviderLambdaConfig
+	ECSConfig            *CloudProviderECSConfig
+	AppEngineConfig      *CloudProviderAppEngineConfig
+	CloudFormationConfig *CloudProviderCloudFormationConfig
+	NomadConfig          *CloudProviderNomadConfig
+	AzureConfig          *CloudProviderAzureConfig
+	CustomSyncConfig     *CloudProviderCustomSyncConfig
 }
 
 type genericPipedCloudProvider struct {
@@ -324,6 +1167,31 @@ func (p *PipedCloudProvider) UnmarshalJSON(data []byte) error {
 		if len(gp.Config) > 0 {
 			err = json.Unmarshal(gp.Config, p.ECSConfig)
 		}
+	case model.CloudProviderAppEngine:
+		p.AppEngineConfig = &CloudProviderAppEngineConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.AppEngineConfig)
+		}
+	case model.CloudProviderCloudFormation:
+		p.CloudFormationConfig = &CloudProviderCloudFormationConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.CloudFormationConfig)
+		}
+	case model.CloudProviderNomad:
+		p.NomadConfig = &CloudProviderNomadConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.NomadConfig)
+		}
+	case model.CloudProviderAzure:
+		p.AzureConfig = &CloudProviderAzureConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.AzureConfig)
+		}
+	case model.CloudProviderCustomSync:
+		p.CustomSyncConfig = &CloudProviderCustomSyncConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.CustomSyncConfig)
+		}
 	default:
 		err = fmt.Errorf("unsupported cloud provider type: %s", p.Name)
 	}
@@ -349,6 +1217,18 @@ type KubernetesAppStateInformer struct {
 	IncludeResources []KubernetesResourceMatcher `json:"includeResources"`
 	// List of resources that should be ignored from the watching targets.
 	ExcludeResources []KubernetesResourceMatcher `json:"excludeResources"`
+	// List of rules to determine the health status of the resources
+	// such as the custom resources managed by operators.
+	HealthChecks []KubernetesResourceHealthCheck `json:"healthChecks"`
+}
+
+func (i *KubernetesAppStateInformer) Validate() error {
+	for _, c := range i.HealthChecks {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type KubernetesResourceMatcher struct {
@@ -359,6 +1239,48 @@ type KubernetesResourceMatcher struct {
 	Kind string `json:"kind"`
 }
 
+// KubernetesResourceHealthCheck represents the rule to determine
+// the health status of the resources of the specified kind.
+type KubernetesResourceHealthCheck struct {
+	// The APIVersion of the kubernetes resource.
+	APIVersion string `json:"apiVersion"`
+	// The kind name of the kubernetes resource.
+	Kind string `json:"kind"`
+	// List of conditions evaluated in order.
+	// The first matched one decides the health status of the resource.
+	// The resource is considered as not healthy when no condition matched.
+	Conditions []KubernetesResourceHealthCondition `json:"conditions"`
+}
+
+func (c *KubernetesResourceHealthCheck) Validate() error {
+	if c.APIVersion == "" || c.Kind == "" {
+		return fmt.Errorf("both apiVersion and kind of healthChecks must be set")
+	}
+	if len(c.Conditions) == 0 {
+		return fmt.Errorf("conditions of healthCheck for %s/%s must be set", c.APIVersion, c.Kind)
+	}
+	for _, cond := range c.Conditions {
+		if cond.JSONPath == "" {
+			return fmt.Errorf("jsonPath of healthCheck condition for %s/%s must be set", c.APIVersion, c.Kind)
+		}
+	}
+	return nil
+}
+
+type KubernetesResourceHealthCondition struct {
+	// The JSONPath template to extract the value from the resource.
+	// e.g. {.status.phase}, {.status.conditions[?(@.type=="Ready")].status}
+	JSONPath string `json:"jsonPath"`
+	// List of the values matching this condition.
+	// Empty means any non-empty value is matching.
+	Values []string `json:"values"`
+	// Whether the resource is healthy when this condition matched.
+	Healthy bool `json:"healthy"`
+	// The description of the health status shown when this condition matched.
+	// Empty means the extracted value is shown.
+	Description string `json:"description"`
+}
+
 type CloudProviderTerraformConfig struct {
 	// List of variables that will be set directly on terraform commands with "-var" flag.
 	// The variable must be formatted by "key=value" as below:
@@ -411,19 +1333,103 @@ type CloudProviderECSConfig struct {
 	Profile string `json:"profile"`
 }
 
+type CloudProviderAppEngineConfig struct {
+	// The GCP project hosting the App Engine application.
+	Project string `json:"project"`
+	// The path to the service account file for accessing App Engine Admin API.
+	CredentialsFile string `json:"credentialsFile"`
+}
+
+type CloudProviderCloudFormationConfig struct {
+	// The region to send requests to. This parameter is required.
+	// e.g. "us-west-2"
+	// A full list of regions is: https://docs.aws.amazon.com/general/latest/gr/rande.html
+	Region string `json:"region"`
+	// Path to the shared credentials file.
+	CredentialsFile string `json:"credentialsFile"`
+	// The IAM role arn to use when assuming an role.
+	RoleARN string `json:"roleARN"`
+	// Path to the WebIdentity token the SDK should use to assume a role with.
+	TokenFile string `json:"tokenFile"`
+	// AWS Profile to extract credentials from the shared credentials file.
+	// If empty, the environment variable "AWS_PROFILE" is used.
+	// "default" is populated if the environment variable is also not set.
+	Profile string `json:"profile"`
+}
+
+type CloudProviderNomadConfig struct {
+	// The address of the Nomad HTTP API.
+	// Default is http://127.0.0.1:4646
+	Address string `json:"address"`
+	// The region of the Nomad cluster to deploy.
+	// Empty means the region of the agent serving the API is used.
+	Region string `json:"region"`
+	// The path to the file containing the ACL token.
+	TokenFile string `json:"tokenFile"`
+}
+
+type CloudProviderAzureConfig struct {
+	// The ID of the Azure subscription hosting the applications.
+	SubscriptionID string `json:"subscriptionId"`
+	// The ID of the Azure AD tenant of the service principal.
+	TenantID string `json:"tenantId"`
+	// The client ID of the service principal.
+	ClientID string `json:"clientId"`
+	// The path to the file containing the client secret of the service principal.
+	// Empty means the managed identity of the host running piped is used.
+	ClientSecretFile string `json:"clientSecretFile"`
+}
+
+type CloudProviderCustomSyncConfig struct {
+	// The name of the command deploying the applications.
+	// It is resolved from the tools directory of piped.
+	Command string `json:"command"`
+	// The version of the command.
+	// Empty means the pre-installed one without version is used.
+	Version string `json:"version"`
+	// The URL to download the command from when it is not installed yet.
+	// It is a template, where {{ .Version }}, {{ .Os }} and {{ .Arch }} can be used.
+	// Empty means the command must be pre-installed in the tools directory.
+	DownloadURL string `json:"downloadUrl"`
+	// The additional environment variables passed to the command,
+	// such as the credentials of the deployment target.
+	Env map[string]string `json:"env"`
+}
+
+var customSyncCommandRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+func (c *CloudProviderCustomSyncConfig) Validate() error {
+	if !customSyncCommandRegex.MatchString(c.Command) {
+		return fmt.Errorf("command must be a file name consisting of alphanumeric characters, '.', '_' or '-'")
+	}
+	if c.Version != "" && !customSyncCommandRegex.MatchString(c.Version) {
+		return fmt.Errorf("version must consist of alphanumeric characters, '.', '_' or '-'")
+	}
+	return nil
+}
+
 type PipedAnalysisProvider struct {
 	Name string                     `json:"name"`
 	Type model.AnalysisProviderType `json:"type"`
+	// The queries taking longer than this are logged as slow queries.
+	// Empty means no slow query is logged.
+	SlowQueryThreshold Duration `json:"slowQueryThreshold"`
+	// How long the query results are reused by the analyzers sending
+	// the same query to this provider. Empty means no result is cached.
+	CacheTTL Duration `json:"cacheTTL"`
 
 	PrometheusConfig  *AnalysisProviderPrometheusConfig  `json:"prometheus"`
 	DatadogConfig     *AnalysisProviderDatadogConfig     `json:"datadog"`
 	StackdriverConfig *AnalysisProviderStackdriverConfig `json:"stackdriver"`
+	PixieConfig       *AnalysisProviderPixieConfig       `json:"pixie"`
 }
 
 type genericPipedAnalysisProvider struct {
-	Name   string                     `json:"name"`
-	Type   model.AnalysisProviderType `json:"type"`
-	Config json.RawMessage            `json:"config"`
+	Name               string                     `json:"name"`
+	Type               model.AnalysisProviderType `json:"type"`
+	SlowQueryThreshold Duration                   `json:"slowQueryThreshold"`
+	CacheTTL           Duration                   `json:"cacheTTL"`
+	Config             json.RawMessage            `json:"config"`
 }
 
 func (p *PipedAnalysisProvider) UnmarshalJSON(data []byte) error {
@@ -434,6 +1440,8 @@ func (p *PipedAnalysisProvider) UnmarshalJSON(data []byte) error {
 	}
 	p.Name = gp.Name
 	p.Type = gp.Type
+	p.SlowQueryThreshold = gp.SlowQueryThreshold
+	p.CacheTTL = gp.CacheTTL
 
 	switch p.Type {
 	case model.AnalysisProviderPrometheus:
@@ -451,6 +1459,11 @@ func (p *PipedAnalysisProvider) UnmarshalJSON(data []byte) error {
 		if len(gp.Config) > 0 {
 			err = json.Unmarshal(gp.Config, p.StackdriverConfig)
 		}
+	case model.AnalysisProviderPixie:
+		p.PixieConfig = &AnalysisProviderPixieConfig{}
+		if len(gp.Config) > 0 {
+			err = json.Unmarshal(gp.Config, p.PixieConfig)
+		}
 	default:
 		err = fmt.Errorf("unsupported analysis provider type: %s", p.Name)
 	}
@@ -465,6 +1478,8 @@ func (p *PipedAnalysisProvider) Validate() error {
 		return p.DatadogConfig.Validate()
 	case model.AnalysisProviderStackdriver:
 		return p.StackdriverConfig.Validate()
+	case model.AnalysisProviderPixie:
+		return p.PixieConfig.Validate()
 	default:
 		return fmt.Errorf("unknow provider type: %s", p.Type)
 	}
@@ -515,6 +1530,29 @@ func (a *AnalysisProviderStackdriverConfig) Validate() error {
 	return nil
 }
 
+type AnalysisProviderPixieConfig struct {
+	// The address of Pixie Cloud.
+	// Empty means the default one used by the px CLI.
+	CloudAddress string `json:"cloudAddress"`
+	// Required: The ID of the cluster where the PxL scripts are executed.
+	ClusterID string `json:"clusterID"`
+	// Required: The path to the API key file.
+	APIKeyFile string `json:"apiKeyFile"`
+	// The path to the px CLI used to run the PxL scripts.
+	// Defaults to "px" found in PATH.
+	CLIPath string `json:"cliPath"`
+}
+
+func (a *AnalysisProviderPixieConfig) Validate() error {
+	if a.ClusterID == "" {
+		return fmt.Errorf("pixie analysis provider requires the cluster id")
+	}
+	if a.APIKeyFile == "" {
+		return fmt.Errorf("pixie analysis provider requires the api key file")
+	}
+	return nil
+}
+
 type Notifications struct {
 	// List of notification routes.
 	Routes []NotificationRoute `json:"routes"`
@@ -533,20 +1571,214 @@ type NotificationRoute struct {
 	IgnoreApps   []string `json:"ignoreApps"`
 	Envs         []string `json:"envs"`
 	IgnoreEnvs   []string `json:"ignoreEnvs"`
+	// The labels the application must have to match this route.
+	Labels map[string]string `json:"labels"`
 }
 
 type NotificationReceiver struct {
-	Name    string                       `json:"name"`
-	Slack   *NotificationReceiverSlack   `json:"slack"`
-	Webhook *NotificationReceiverWebhook `json:"webhook"`
+	Name      string                         `json:"name"`
+	Slack     *NotificationReceiverSlack     `json:"slack"`
+	Teams     *NotificationReceiverTeams     `json:"teams"`
+	PagerDuty *NotificationReceiverPagerDuty `json:"pagerDuty"`
+	Webhook   *NotificationReceiverWebhook   `json:"webhook"`
+	EventBus  *NotificationReceiverEventBus  `json:"eventBus"`
+}
+
+func (r *NotificationReceiver) Validate() error {
+	if r.Teams != nil {
+		if err := r.Teams.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.PagerDuty != nil {
+		if err := r.PagerDuty.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Webhook != nil {
+		if err := r.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.EventBus != nil {
+		if err := r.EventBus.Validate(); err != nil {
+			return fmt.Errorf("invalid event bus: %w", err)
+		}
+	}
+	return nil
 }
 
 type NotificationReceiverSlack struct {
 	HookURL string `json:"hookURL"`
+	// Whether to add the Approve and Reject buttons to the messages of WAIT_APPROVAL stages.
+	// The incoming webhook must belong to the Slack app whose interactivity
+	// request URL points to the control plane.
+	InteractiveApproval bool `json:"interactiveApproval"`
+}
+
+// NotificationReceiverTeams sends the events to a Microsoft Teams channel
+// through its incoming webhook.
+type NotificationReceiverTeams struct {
+	// Required: The URL of the incoming webhook of the channel.
+	HookURL string `json:"hookURL"`
+}
+
+func (t *NotificationReceiverTeams) Validate() error {
+	if t.HookURL == "" {
+		return fmt.Errorf("teams receiver requires the hook url")
+	}
+	return nil
+}
+
+const (
+	PagerDutySeverityCritical = "critical"
+	PagerDutySeverityError    = "error"
+	PagerDutySeverityWarning  = "warning"
+	PagerDutySeverityInfo     = "info"
+)
+
+// NotificationReceiverPagerDuty opens the PagerDuty incidents for the failed deployments
+// through the Events API v2 and resolves them once the application was deployed successfully.
+type NotificationReceiverPagerDuty struct {
+	// Required: The path to the file containing the integration key of the PagerDuty service.
+	RoutingKeyFile string `json:"routingKeyFile"`
+	// The severity of the opened incidents.
+	// Available values: critical, error, warning, info
+	// Default is error.
+	Severity string `json:"severity"`
+}
+
+func (p *NotificationReceiverPagerDuty) Validate() error {
+	if p.RoutingKeyFile == "" {
+		return fmt.Errorf("pagerDuty receiver requires the routing key file")
+	}
+	switch p.Severity {
+	case "", PagerDutySeverityCritical, PagerDutySeverityError, PagerDutySeverityWarning, PagerDutySeverityInfo:
+		return nil
+	default:
+		return fmt.Errorf("unsupported severity of pagerDuty receiver: %s", p.Severity)
+	}
 }
 
 type NotificationReceiverWebhook struct {
+	// Required: The URL of the endpoint.
 	URL string `json:"url"`
+	// The path to the file containing the key to sign the requests.
+	// The HMAC-SHA256 signature of the request body is set to the X-PipeCD-Signature header.
+	SigningKeyFile string `json:"signingKeyFile"`
+	// The Go template to render the JSON payload instead of the CloudEvents message.
+	// The fields of the CloudEvents message are available in the template.
+	PayloadTemplate string `json:"payloadTemplate"`
+}
+
+func (w *NotificationReceiverWebhook) Validate() error {
+	if w.URL == "" {
+		return fmt.Errorf("webhook receiver requires the url")
+	}
+	return nil
+}
+
+const (
+	EventBusTypeKafka  = "KAFKA"
+	EventBusTypeSNS    = "SNS"
+	EventBusTypePubSub = "PUBSUB"
+)
+
+// NotificationReceiverEventBus publishes the structured events
+// to an external event bus for being consumed by the data platforms.
+type NotificationReceiverEventBus struct {
+	// Which event bus should be used.
+	// Available values: KAFKA, SNS, PUBSUB
+	Type   string                `json:"type"`
+	Kafka  *EventBusKafkaConfig  `json:"kafka"`
+	SNS    *EventBusSNSConfig    `json:"sns"`
+	PubSub *EventBusPubSubConfig `json:"pubsub"`
+}
+
+func (r *NotificationReceiverEventBus) Validate() error {
+	switch r.Type {
+	case EventBusTypeKafka:
+		if r.Kafka == nil {
+			return fmt.Errorf("kafka event bus requires the kafka config")
+		}
+		return r.Kafka.Validate()
+	case EventBusTypeSNS:
+		if r.SNS == nil {
+			return fmt.Errorf("sns event bus requires the sns config")
+		}
+		return r.SNS.Validate()
+	case EventBusTypePubSub:
+		if r.PubSub == nil {
+			return fmt.Errorf("pubsub event bus requires the pubsub config")
+		}
+		return r.PubSub.Validate()
+	default:
+		return fmt.Errorf("unsupported event bus type: %s", r.Type)
+	}
+}
+
+type EventBusKafkaConfig struct {
+	// Required: The address of the Kafka REST Proxy.
+	RESTProxyAddress string `json:"restProxyAddress"`
+	// Required: The topic to publish the events to.
+	Topic string `json:"topic"`
+	// The path to the username file for the basic authentication.
+	UsernameFile string `json:"usernameFile"`
+	// The path to the password file for the basic authentication.
+	PasswordFile string `json:"passwordFile"`
+}
+
+func (c *EventBusKafkaConfig) Validate() error {
+	if c.RESTProxyAddress == "" {
+		return fmt.Errorf("kafka event bus requires the rest proxy address")
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("kafka event bus requires the topic")
+	}
+	return nil
+}
+
+type EventBusSNSConfig struct {
+	// Required: The AWS region of the topic.
+	Region string `json:"region"`
+	// Required: The ARN of the topic to publish the events to.
+	TopicARN string `json:"topicArn"`
+	// Path to the shared credentials file.
+	CredentialsFile string `json:"credentialsFile"`
+	// AWS Profile to extract credentials from the shared credentials file.
+	// If empty, the environment variable "AWS_PROFILE" is used.
+	// "default" is populated if the environment variable is also not set.
+	Profile string `json:"profile"`
+}
+
+func (c *EventBusSNSConfig) Validate() error {
+	if c.Region == "" {
+		return fmt.Errorf("sns event bus requires the region")
+	}
+	if c.TopicARN == "" {
+		return fmt.Errorf("sns event bus requires the topic arn")
+	}
+	return nil
+}
+
+type EventBusPubSubConfig struct {
+	// Required: The ID of the GCP project hosting the topic.
+	Project string `json:"project"`
+	// Required: The ID of the topic to publish the events to.
+	Topic string `json:"topic"`
+	// The path to the service account file.
+	// Empty means the application default credentials are used.
+	CredentialsFile string `json:"credentialsFile"`
+}
+
+func (c *EventBusPubSubConfig) Validate() error {
+	if c.Project == "" {
+		return fmt.Errorf("pubsub event bus requires the project")
+	}
+	if c.Topic == "" {
+		return fmt.Errorf("pubsub event bus requires the topic")
+	}
+	return nil
 }
 
 type SecretManagement struct {
@@ -712,3 +1944,210 @@ type PipedEventWatcherGitRepo struct {
 	// This is prioritized if both includes and this one are given.
 	Excludes []string `json:"excludes"`
 }
+
+const (
+	// FeatureK8sServerSideApply applies the manifests of Kubernetes applications
+	// by using server-side apply even though serverSideApply was not configured in the application.
+	FeatureK8sServerSideApply = "K8S_SERVER_SIDE_APPLY"
+)
+
+var knownFeatures = map[string]struct{}{
+	FeatureK8sServerSideApply: {},
+}
+
+// PipedFeatureFlag enables a feature not enabled by default.
+type PipedFeatureFlag struct {
+	// The name of the feature.
+	Name string `json:"name"`
+	// The names of the applications the feature is enabled for.
+	// Empty means all applications.
+	Applications []string `json:"applications"`
+	// The percentage of the deployments the feature is enabled for.
+	// The same deployment is always decided in the same way.
+	// Empty means all deployments.
+	Percentage *int `json:"percentage"`
+}
+
+func (f *PipedFeatureFlag) Validate() error {
+	if _, ok := knownFeatures[f.Name]; !ok {
+		return fmt.Errorf("unknown feature %q", f.Name)
+	}
+	if f.Percentage != nil && (*f.Percentage < 0 || *f.Percentage > 100) {
+		return fmt.Errorf("percentage of feature %s must be between 0 and 100", f.Name)
+	}
+	return nil
+}
+
+func (f *PipedFeatureFlag) enabled(appName, deploymentID string) bool {
+	if len(f.Applications) > 0 {
+		var found bool
+		for _, a := range f.Applications {
+			if a == appName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Percentage == nil {
+		return true
+	}
+	// The feature name is also hashed to not enable all features for the same deployments.
+	h := fnv.New32a()
+	h.Write([]byte(f.Name + "/" + deploymentID))
+	return int(h.Sum32()%100) < *f.Percentage
+}
+
+// PipedScriptRun configures where and how SCRIPT_RUN stage can run the user-defined scripts.
+type PipedScriptRun struct {
+	// Whether SCRIPT_RUN stage is allowed to run on this piped.
+	Enabled bool `json:"enabled"`
+	// The names of the applications allowed to use SCRIPT_RUN stage.
+	// Empty means all applications.
+	Applications []string `json:"applications"`
+	// The shell used to run the scripts.
+	// Default is /bin/sh.
+	Shell string `json:"shell"`
+	// The names of the environment variables of piped passed through to the scripts.
+	// Only PATH and HOME are passed by default to not leak the credentials of piped.
+	PassEnvs []string `json:"passEnvs"`
+}
+
+// IsAllowed returns whether SCRIPT_RUN stage is allowed to run for the given application.
+func (s *PipedScriptRun) IsAllowed(appName string) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Applications) == 0 {
+		return true
+	}
+	for _, a := range s.Applications {
+		if a == appName {
+			return true
+		}
+	}
+	return false
+}
+
+var sha256Regex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// PipedTools configures how piped downloads the tools used in the deployments.
+type PipedTools struct {
+	// The URL of the HTTP proxy used to download the tools.
+	// Empty means the proxy configured by the HTTPS_PROXY environment variable is used.
+	Proxy string `json:"proxy"`
+	// The locations to download the tools from instead of the default ones
+	// to be usable in the air-gapped environments.
+	Mirrors []PipedToolMirror `json:"mirrors"`
+	// The SHA256 checksums of the files downloaded for the tools.
+	// The tool is not installed if the checksum of the downloaded file does not match.
+	Checksums []PipedToolChecksum `json:"checksums"`
+	// The tools installed while starting piped
+	// to not make the first deployments wait for downloading them.
+	Preinstall []PipedTool `json:"preinstall"`
+	// The user-defined tools which can be used by name in the deployments
+	// such as the tools field of SCRIPT_RUN stage.
+	Definitions []PipedToolDefinition `json:"definitions"`
+}
+
+func (t *PipedTools) Validate() error {
+	if t.Proxy != "" {
+		u, err := url.Parse(t.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("proxy must be an absolute URL")
+		}
+	}
+	for _, m := range t.Mirrors {
+		if m.Name == "" {
+			return fmt.Errorf("name of mirror must be set")
+		}
+		if m.URL == "" {
+			return fmt.Errorf("url of mirror for %s must be set", m.Name)
+		}
+	}
+	for _, c := range t.Checksums {
+		if c.Name == "" || c.Version == "" {
+			return fmt.Errorf("both name and version of checksum must be set")
+		}
+		if !sha256Regex.MatchString(c.SHA256) {
+			return fmt.Errorf("sha256 of %s %s must be a hex-encoded SHA256 checksum", c.Name, c.Version)
+		}
+	}
+	for _, p := range t.Preinstall {
+		if p.Name == "" {
+			return fmt.Errorf("name of pre-installed tool must be set")
+		}
+	}
+	names := make(map[string]struct{}, len(t.Definitions))
+	for _, d := range t.Definitions {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[d.Name]; ok {
+			return fmt.Errorf("duplicated tool definition %s", d.Name)
+		}
+		names[d.Name] = struct{}{}
+	}
+	return nil
+}
+
+// PipedToolMirror represents the location to download a tool from.
+type PipedToolMirror struct {
+	// The name of the tool such as kubectl, kustomize, helm, terraform...
+	Name string `json:"name"`
+	// The URL template of the file to be downloaded.
+	// The version, OS and architecture can be referred as
+	// {{ .Version }}, {{ .Os }} and {{ .Arch }}.
+	URL string `json:"url"`
+}
+
+// PipedToolChecksum represents the expected checksum of a tool version.
+type PipedToolChecksum struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+var toolNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// PipedToolDefinition represents a user-defined tool.
+type PipedToolDefinition struct {
+	// The name of the tool. It is also used as the command name.
+	Name string `json:"name"`
+	// The version of the tool.
+	Version string `json:"version"`
+	// The URL template of the executable file to be downloaded.
+	// The version, OS and architecture can be referred as
+	// {{ .Version }}, {{ .Os }} and {{ .Arch }}.
+	URL string `json:"url"`
+	// The hex-encoded SHA256 checksum of the downloaded file.
+	SHA256 string `json:"sha256"`
+}
+
+func (d *PipedToolDefinition) Validate() error {
+	if !toolNameRegex.MatchString(d.Name) {
+		return fmt.Errorf("name of tool definition must be a file name, but got %q", d.Name)
+	}
+	if d.Version == "" {
+		return fmt.Errorf("version of tool %s must be set", d.Name)
+	}
+	if d.URL == "" {
+		return fmt.Errorf("url of tool %s must be set", d.Name)
+	}
+	if d.SHA256 != "" && !sha256Regex.MatchString(d.SHA256) {
+		return fmt.Errorf("sha256 of tool %s must be a hex-encoded SHA256 checksum", d.Name)
+	}
+	return nil
+}
+
+// PipedTool represents a version of a tool.
+type PipedTool struct {
+	Name string `json:"name"`
+	// Empty means the default version of the tool.
+	Version string `json:"version"`
+}
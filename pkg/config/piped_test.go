@@ -15,6 +15,7 @@
 package config
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -55,23 +56,43 @@ func TestPipedConfig(t *testing.T) {
 						Branch: "master",
 					},
 					{
-						RepoID: "repo2",
-						Remote: "git@github.com:org/repo2.git",
-						Branch: "master",
+						RepoID:     "repo2",
+						Remote:     "git@github.com:org/repo2.git",
+						Branch:     "master",
+						SSHKeyFile: "/etc/piped-secret/repo2-deploy-key",
+					},
+					{
+						RepoID: "repo3",
+						Remote: "https://github.com/org/repo3.git",
+						Branch: "main",
+						GitHubApp: &PipedRepositoryGitHubApp{
+							AppID:          100,
+							InstallationID: 200,
+							PrivateKeyFile: "/etc/piped-secret/github-app.pem",
+						},
 					},
 				},
 				ChartRepositories: []HelmChartRepository{
 					{
+						Type:    HTTPHelmChartRepository,
 						Name:    "fantastic-charts",
 						Address: "https://fantastic-charts.storage.googleapis.com",
 					},
 					{
+						Type:     HTTPHelmChartRepository,
 						Name:     "private-charts",
 						Address:  "https://private-charts.com",
 						Username: "basic-username",
 						Password: "basic-password",
 						Insecure: true,
 					},
+					{
+						Type:     OCIHelmChartRepository,
+						Name:     "oci-charts",
+						Address:  "oci://pipecd.dev/charts",
+						Username: "oci-username",
+						Password: "oci-password",
+					},
 				},
 				CloudProviders: []PipedCloudProvider{
 					{
@@ -94,6 +115,19 @@ func TestPipedConfig(t *testing.T) {
 										Kind:       "Endpoints",
 									},
 								},
+								HealthChecks: []KubernetesResourceHealthCheck{
+									{
+										APIVersion: "networking.gke.io/v1beta1",
+										Kind:       "ManagedCertificate",
+										Conditions: []KubernetesResourceHealthCondition{
+											{
+												JSONPath: "{.status.certificateStatus}",
+												Values:   []string{"Active"},
+												Healthy:  true,
+											},
+										},
+									},
+								},
 							},
 						},
 					},
@@ -131,8 +165,10 @@ func TestPipedConfig(t *testing.T) {
 				},
 				AnalysisProviders: []PipedAnalysisProvider{
 					{
-						Name: "prometheus-dev",
-						Type: model.AnalysisProviderPrometheus,
+						Name:               "prometheus-dev",
+						Type:               model.AnalysisProviderPrometheus,
+						SlowQueryThreshold: Duration(10 * time.Second),
+						CacheTTL:           Duration(30 * time.Second),
 						PrometheusConfig: &AnalysisProviderPrometheusConfig{
 							Address: "https://your-prometheus.dev",
 						},
@@ -153,6 +189,14 @@ func TestPipedConfig(t *testing.T) {
 							ServiceAccountFile: "/etc/piped-secret/gcp-service-account.json",
 						},
 					},
+					{
+						Name: "pixie-dev",
+						Type: model.AnalysisProviderPixie,
+						PixieConfig: &AnalysisProviderPixieConfig{
+							ClusterID:  "7c5b4a1e-2f0d-4d8e-9c39-0b6f2a8e1d42",
+							APIKeyFile: "/etc/piped-secret/pixie-api-key",
+						},
+					},
 				},
 				Notifications: Notifications{
 					Routes: []NotificationRoute{
@@ -171,6 +215,22 @@ func TestPipedConfig(t *testing.T) {
 							Name:     "all-events-to-ci",
 							Receiver: "ci-webhook",
 						},
+						{
+							Name:     "payment-team",
+							Events:   []string{"DEPLOYMENT_WAIT_APPROVAL", "ANALYSIS_FAILED"},
+							Labels:   map[string]string{"team": "payment"},
+							Receiver: "payment-slack-channel",
+						},
+						{
+							Name:     "analytics",
+							Groups:   []string{"DEPLOYMENT", "ANALYSIS"},
+							Receiver: "analytics-kafka",
+						},
+						{
+							Name:     "prod-oncall",
+							Envs:     []string{"prod"},
+							Receiver: "oncall-pagerduty",
+						},
 					},
 					Receivers: []NotificationReceiver{
 						{
@@ -185,10 +245,40 @@ func TestPipedConfig(t *testing.T) {
 								HookURL: "https://slack.com/prod",
 							},
 						},
+						{
+							Name: "payment-slack-channel",
+							Slack: &NotificationReceiverSlack{
+								HookURL: "https://slack.com/payment",
+							},
+						},
+						{
+							Name: "prod-teams-channel",
+							Teams: &NotificationReceiverTeams{
+								HookURL: "https://outlook.office.com/webhook/prod",
+							},
+						},
+						{
+							Name: "oncall-pagerduty",
+							PagerDuty: &NotificationReceiverPagerDuty{
+								RoutingKeyFile: "/etc/piped-secret/pagerduty-routing-key",
+								Severity:       PagerDutySeverityCritical,
+							},
+						},
 						{
 							Name: "ci-webhook",
 							Webhook: &NotificationReceiverWebhook{
-								URL: "https://pipecd.dev/dev-hook",
+								URL:            "https://pipecd.dev/dev-hook",
+								SigningKeyFile: "/etc/piped-secret/webhook-signing-key",
+							},
+						},
+						{
+							Name: "analytics-kafka",
+							EventBus: &NotificationReceiverEventBus{
+								Type: EventBusTypeKafka,
+								Kafka: &EventBusKafkaConfig{
+									RESTProxyAddress: "https://kafka-rest.pipecd.dev",
+									Topic:            "pipecd-events",
+								},
 							},
 						},
 					},
@@ -207,6 +297,14 @@ func TestPipedConfig(t *testing.T) {
 						PublicKeyFile:  "/etc/piped-secret/pair-public-key",
 					},
 				},
+				Sops: &PipedSops{
+					AgeKeyFile: "/etc/piped-secret/sops-age-keys.txt",
+				},
+				Vault: &PipedVault{
+					Address:        "https://vault.pipecd.dev:8200",
+					AuthMethod:     VaultAuthMethodKubernetes,
+					KubernetesRole: "piped",
+				},
 				EventWatcher: PipedEventWatcher{
 					CheckInterval: Duration(10 * time.Minute),
 					GitRepos: []PipedEventWatcherGitRepo{
@@ -217,6 +315,57 @@ func TestPipedConfig(t *testing.T) {
 						},
 					},
 				},
+				ScriptRun: PipedScriptRun{
+					Enabled:      true,
+					Applications: []string{"canary"},
+					PassEnvs:     []string{"AWS_REGION"},
+				},
+				Tools: PipedTools{
+					Proxy: "http://proxy.internal:3128",
+					Mirrors: []PipedToolMirror{
+						{
+							Name: "helm",
+							URL:  "https://mirror.internal/helm/helm-v{{ .Version }}-{{ .Os }}-{{ .Arch }}.tar.gz",
+						},
+					},
+					Checksums: []PipedToolChecksum{
+						{
+							Name:    "helm",
+							Version: "3.5.3",
+							SHA256:  "2170a1a644a9e0b863f00c17b761ce33d4323da64fc74562a3a6df2abbf6cd70",
+						},
+					},
+					Preinstall: []PipedTool{
+						{Name: "kubectl"},
+						{Name: "helm", Version: "3.5.3"},
+					},
+					Definitions: []PipedToolDefinition{
+						{
+							Name:    "yq",
+							Version: "4.9.6",
+							URL:     "https://github.com/mikefarah/yq/releases/download/v{{ .Version }}/yq_{{ .Os }}_{{ .Arch }}",
+						},
+					},
+				},
+				TriggerWebhook: &PipedTriggerWebhook{
+					SecretFile: "/etc/piped-secret/webhook-secret",
+				},
+				OCIRegistries: []PipedOCIRegistry{
+					{
+						Address:      "ghcr.io",
+						Username:     "pipecd",
+						PasswordFile: "/etc/piped-secret/ghcr-token",
+					},
+					{
+						Address:  "localhost:5000",
+						Insecure: true,
+					},
+				},
+				Upgrade: &PipedUpgrade{
+					BinaryURL:     "https://github.com/pipe-cd/pipe/releases/download/{{ .Version }}/piped_{{ .Version }}_{{ .Os }}_{{ .Arch }}",
+					ChecksumURL:   "https://github.com/pipe-cd/pipe/releases/download/{{ .Version }}/piped_{{ .Version }}_{{ .Os }}_{{ .Arch }}.sha256",
+					CheckInterval: Duration(5 * time.Minute),
+				},
 			},
 			expectedError: nil,
 		},
@@ -316,3 +465,1041 @@ func TestPipedEventWatcherValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestNotificationReceiverEventBusValidate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		eventBus NotificationReceiverEventBus
+		wantErr  bool
+	}{
+		{
+			name: "valid kafka",
+			eventBus: NotificationReceiverEventBus{
+				Type: EventBusTypeKafka,
+				Kafka: &EventBusKafkaConfig{
+					RESTProxyAddress: "https://kafka-rest.pipecd.dev",
+					Topic:            "pipecd-events",
+				},
+			},
+		},
+		{
+			name: "missing kafka topic",
+			eventBus: NotificationReceiverEventBus{
+				Type: EventBusTypeKafka,
+				Kafka: &EventBusKafkaConfig{
+					RESTProxyAddress: "https://kafka-rest.pipecd.dev",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid sns",
+			eventBus: NotificationReceiverEventBus{
+				Type: EventBusTypeSNS,
+				SNS: &EventBusSNSConfig{
+					Region:   "us-west-2",
+					TopicARN: "arn:aws:sns:us-west-2:123456789012:pipecd-events",
+				},
+			},
+		},
+		{
+			name: "missing sns config",
+			eventBus: NotificationReceiverEventBus{
+				Type: EventBusTypeSNS,
+				PubSub: &EventBusPubSubConfig{
+					Project: "pipecd",
+					Topic:   "pipecd-events",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid pubsub",
+			eventBus: NotificationReceiverEventBus{
+				Type: EventBusTypePubSub,
+				PubSub: &EventBusPubSubConfig{
+					Project: "pipecd",
+					Topic:   "pipecd-events",
+				},
+			},
+		},
+		{
+			name: "unsupported type",
+			eventBus: NotificationReceiverEventBus{
+				Type: "NATS",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.eventBus.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedVaultValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		vault   PipedVault
+		wantErr bool
+	}{
+		{
+			name: "valid token auth",
+			vault: PipedVault{
+				Address:   "https://vault.pipecd.dev:8200",
+				TokenFile: "/etc/piped-secret/vault-token",
+			},
+		},
+		{
+			name: "valid kubernetes auth",
+			vault: PipedVault{
+				Address:        "https://vault.pipecd.dev:8200",
+				AuthMethod:     VaultAuthMethodKubernetes,
+				KubernetesRole: "piped",
+			},
+		},
+		{
+			name: "missing address",
+			vault: PipedVault{
+				TokenFile: "/etc/piped-secret/vault-token",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing token file",
+			vault: PipedVault{
+				Address: "https://vault.pipecd.dev:8200",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing kubernetes role",
+			vault: PipedVault{
+				Address:    "https://vault.pipecd.dev:8200",
+				AuthMethod: VaultAuthMethodKubernetes,
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported auth method",
+			vault: PipedVault{
+				Address:    "https://vault.pipecd.dev:8200",
+				AuthMethod: "APPROLE",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.vault.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestNotificationReceiverValidate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		receiver NotificationReceiver
+		wantErr  bool
+	}{
+		{
+			name: "valid teams",
+			receiver: NotificationReceiver{
+				Name:  "teams",
+				Teams: &NotificationReceiverTeams{HookURL: "https://outlook.office.com/webhook/prod"},
+			},
+		},
+		{
+			name: "missing teams hook url",
+			receiver: NotificationReceiver{
+				Name:  "teams",
+				Teams: &NotificationReceiverTeams{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid pagerduty with default severity",
+			receiver: NotificationReceiver{
+				Name:      "pagerduty",
+				PagerDuty: &NotificationReceiverPagerDuty{RoutingKeyFile: "/etc/piped-secret/pagerduty-routing-key"},
+			},
+		},
+		{
+			name: "unsupported pagerduty severity",
+			receiver: NotificationReceiver{
+				Name: "pagerduty",
+				PagerDuty: &NotificationReceiverPagerDuty{
+					RoutingKeyFile: "/etc/piped-secret/pagerduty-routing-key",
+					Severity:       "fatal",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing webhook url",
+			receiver: NotificationReceiver{
+				Name:    "webhook",
+				Webhook: &NotificationReceiverWebhook{SigningKeyFile: "/etc/piped-secret/webhook-signing-key"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid event bus",
+			receiver: NotificationReceiver{
+				Name:     "eventbus",
+				EventBus: &NotificationReceiverEventBus{Type: "NATS"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.receiver.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestHelmChartRepositoryCredentialsProviderValidate(t *testing.T) {
+	cloudProviders := []PipedCloudProvider{
+		{
+			Name: "aws",
+			Type: model.CloudProviderECS,
+		},
+		{
+			Name: "kubernetes",
+			Type: model.CloudProviderKubernetes,
+		},
+	}
+	testcases := []struct {
+		name    string
+		repo    HelmChartRepository
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			repo: HelmChartRepository{
+				Type:                OCIHelmChartRepository,
+				Name:                "ecr",
+				Address:             "oci://123456789012.dkr.ecr.us-west-2.amazonaws.com/charts",
+				CredentialsProvider: "aws",
+			},
+			wantErr: false,
+		},
+		{
+			name: "not OCI",
+			repo: HelmChartRepository{
+				Type:                HTTPHelmChartRepository,
+				Name:                "ecr",
+				Address:             "https://charts.example.com",
+				CredentialsProvider: "aws",
+			},
+			wantErr: true,
+		},
+		{
+			name: "with static password",
+			repo: HelmChartRepository{
+				Type:                OCIHelmChartRepository,
+				Name:                "ecr",
+				Address:             "oci://123456789012.dkr.ecr.us-west-2.amazonaws.com/charts",
+				Password:            "password",
+				CredentialsProvider: "aws",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported cloud provider",
+			repo: HelmChartRepository{
+				Type:                OCIHelmChartRepository,
+				Name:                "ecr",
+				Address:             "oci://123456789012.dkr.ecr.us-west-2.amazonaws.com/charts",
+				CredentialsProvider: "kubernetes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown cloud provider",
+			repo: HelmChartRepository{
+				Type:                OCIHelmChartRepository,
+				Name:                "ecr",
+				Address:             "oci://123456789012.dkr.ecr.us-west-2.amazonaws.com/charts",
+				CredentialsProvider: "unknown",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := PipedSpec{
+				ProjectID:         "project",
+				PipedID:           "piped",
+				PipedKeyFile:      "/etc/piped/key",
+				APIAddress:        "api.pipecd.dev:443",
+				WebAddress:        "https://pipecd.dev",
+				SyncInterval:      Duration(time.Minute),
+				CloudProviders:    cloudProviders,
+				ChartRepositories: []HelmChartRepository{tc.repo},
+			}
+			err := spec.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedFeatureFlags(t *testing.T) {
+	zero, half, all := 0, 50, 100
+	testcases := []struct {
+		name         string
+		flags        []PipedFeatureFlag
+		wantErr      bool
+		app          string
+		wantEnabled  bool
+		wantRatioMin float64
+		wantRatioMax float64
+	}{
+		{
+			name:        "not configured",
+			app:         "foo",
+			wantEnabled: false,
+		},
+		{
+			name:        "enabled for all",
+			flags:       []PipedFeatureFlag{{Name: FeatureK8sServerSideApply}},
+			app:         "foo",
+			wantEnabled: true,
+		},
+		{
+			name:        "enabled for the application",
+			flags:       []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Applications: []string{"bar", "foo"}}},
+			app:         "foo",
+			wantEnabled: true,
+		},
+		{
+			name:        "not enabled for other application",
+			flags:       []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Applications: []string{"bar"}}},
+			app:         "foo",
+			wantEnabled: false,
+		},
+		{
+			name:        "zero percentage",
+			flags:       []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Percentage: &zero}},
+			app:         "foo",
+			wantEnabled: false,
+		},
+		{
+			name:        "full percentage",
+			flags:       []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Percentage: &all}},
+			app:         "foo",
+			wantEnabled: true,
+		},
+		{
+			name:         "half of deployments",
+			flags:        []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Percentage: &half}},
+			app:          "foo",
+			wantRatioMin: 0.4,
+			wantRatioMax: 0.6,
+		},
+		{
+			name:    "unknown feature",
+			flags:   []PipedFeatureFlag{{Name: "UNKNOWN"}},
+			wantErr: true,
+		},
+		{
+			name: "invalid percentage",
+			flags: func() []PipedFeatureFlag {
+				p := 101
+				return []PipedFeatureFlag{{Name: FeatureK8sServerSideApply, Percentage: &p}}
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "duplicated feature",
+			flags:   []PipedFeatureFlag{{Name: FeatureK8sServerSideApply}, {Name: FeatureK8sServerSideApply}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &PipedSpec{
+				ProjectID:    "project",
+				PipedID:      "piped",
+				PipedKeyData: "key",
+				APIAddress:   "api",
+				WebAddress:   "web",
+				FeatureFlags: tc.flags,
+			}
+			err := s.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			if tc.wantRatioMax == 0 {
+				assert.Equal(t, tc.wantEnabled, s.IsFeatureEnabled(FeatureK8sServerSideApply, tc.app, "deployment"))
+				return
+			}
+
+			const total = 1000
+			var enabled int
+			for i := 0; i < total; i++ {
+				id := fmt.Sprintf("deployment-%d", i)
+				got := s.IsFeatureEnabled(FeatureK8sServerSideApply, tc.app, id)
+				// The same deployment must be decided in the same way.
+				assert.Equal(t, got, s.IsFeatureEnabled(FeatureK8sServerSideApply, tc.app, id))
+				if got {
+					enabled++
+				}
+			}
+			ratio := float64(enabled) / total
+			assert.True(t, ratio >= tc.wantRatioMin && ratio <= tc.wantRatioMax, "ratio %f", ratio)
+		})
+	}
+}
+
+func TestPipedScriptRunIsAllowed(t *testing.T) {
+	testcases := []struct {
+		name      string
+		scriptRun PipedScriptRun
+		app       string
+		expected  bool
+	}{
+		{
+			name:     "disabled by default",
+			app:      "app",
+			expected: false,
+		},
+		{
+			name:      "enabled for all applications",
+			scriptRun: PipedScriptRun{Enabled: true},
+			app:       "app",
+			expected:  true,
+		},
+		{
+			name: "allowed application",
+			scriptRun: PipedScriptRun{
+				Enabled:      true,
+				Applications: []string{"foo", "app"},
+			},
+			app:      "app",
+			expected: true,
+		},
+		{
+			name: "not allowed application",
+			scriptRun: PipedScriptRun{
+				Enabled:      true,
+				Applications: []string{"foo"},
+			},
+			app:      "app",
+			expected: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.scriptRun.IsAllowed(tc.app))
+		})
+	}
+}
+
+func TestCloudProviderCustomSyncConfigValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		cfg     CloudProviderCustomSyncConfig
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg: CloudProviderCustomSyncConfig{
+				Command:     "deployer",
+				Version:     "1.2.0",
+				DownloadURL: "https://example.com/deployer/{{ .Version }}/deployer_{{ .Os }}_{{ .Arch }}",
+			},
+			wantErr: false,
+		},
+		{
+			name:    "missing command",
+			cfg:     CloudProviderCustomSyncConfig{},
+			wantErr: true,
+		},
+		{
+			name: "command is a path",
+			cfg: CloudProviderCustomSyncConfig{
+				Command: "../bin/deployer",
+			},
+			wantErr: true,
+		},
+		{
+			name: "version containing a slash",
+			cfg: CloudProviderCustomSyncConfig{
+				Command: "deployer",
+				Version: "1.2.0/../x",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedToolsValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		tools   PipedTools
+		wantErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			tools: PipedTools{
+				Proxy:      "http://proxy.internal:3128",
+				Mirrors:    []PipedToolMirror{{Name: "kubectl", URL: "https://mirror.internal/kubectl-{{ .Version }}"}},
+				Checksums:  []PipedToolChecksum{{Name: "kubectl", Version: "1.18.2", SHA256: "6859d4b1ffae8d2e9d3ac3ab1b3a79a9f0d2c4c55b7bd1c8bfcc2ea6c7e3f5e5"}},
+				Preinstall: []PipedTool{{Name: "kubectl"}},
+			},
+		},
+		{
+			name:    "relative proxy",
+			tools:   PipedTools{Proxy: "proxy.internal"},
+			wantErr: true,
+		},
+		{
+			name:    "mirror without url",
+			tools:   PipedTools{Mirrors: []PipedToolMirror{{Name: "kubectl"}}},
+			wantErr: true,
+		},
+		{
+			name:    "checksum without version",
+			tools:   PipedTools{Checksums: []PipedToolChecksum{{Name: "kubectl", SHA256: "6859d4b1ffae8d2e9d3ac3ab1b3a79a9f0d2c4c55b7bd1c8bfcc2ea6c7e3f5e5"}}},
+			wantErr: true,
+		},
+		{
+			name:    "malformed checksum",
+			tools:   PipedTools{Checksums: []PipedToolChecksum{{Name: "kubectl", Version: "1.18.2", SHA256: "6859d4b1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "pre-installed tool without name",
+			tools:   PipedTools{Preinstall: []PipedTool{{Version: "1.18.2"}}},
+			wantErr: true,
+		},
+		{
+			name:    "tool definition with path",
+			tools:   PipedTools{Definitions: []PipedToolDefinition{{Name: "../yq", Version: "4.9.6", URL: "https://example.com/yq"}}},
+			wantErr: true,
+		},
+		{
+			name:    "tool definition without url",
+			tools:   PipedTools{Definitions: []PipedToolDefinition{{Name: "yq", Version: "4.9.6"}}},
+			wantErr: true,
+		},
+		{
+			name: "duplicated tool definitions",
+			tools: PipedTools{Definitions: []PipedToolDefinition{
+				{Name: "yq", Version: "4.9.6", URL: "https://example.com/yq"},
+				{Name: "yq", Version: "4.9.7", URL: "https://example.com/yq"},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.tools.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedOCIRegistryValidate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		registry PipedOCIRegistry
+		wantErr  bool
+	}{
+		{
+			name:     "anonymous",
+			registry: PipedOCIRegistry{Address: "ghcr.io"},
+		},
+		{
+			name:     "with credentials",
+			registry: PipedOCIRegistry{Address: "ghcr.io", Username: "pipecd", PasswordFile: "/etc/token"},
+		},
+		{
+			name:     "missing address",
+			registry: PipedOCIRegistry{Username: "pipecd", PasswordFile: "/etc/token"},
+			wantErr:  true,
+		},
+		{
+			name:     "missing password file",
+			registry: PipedOCIRegistry{Address: "ghcr.io", Username: "pipecd"},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.registry.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedRepositoryValidate(t *testing.T) {
+	githubApp := &PipedRepositoryGitHubApp{
+		AppID:          100,
+		InstallationID: 200,
+		PrivateKeyFile: "/etc/github-app.pem",
+	}
+	testcases := []struct {
+		name    string
+		repo    PipedRepository
+		wantErr bool
+	}{
+		{
+			name: "global credentials",
+			repo: PipedRepository{RepoID: "repo", Remote: "git@github.com:org/repo.git"},
+		},
+		{
+			name: "deploy key",
+			repo: PipedRepository{RepoID: "repo", Remote: "git@github.com:org/repo.git", SSHKeyFile: "/etc/deploy-key"},
+		},
+		{
+			name: "github app",
+			repo: PipedRepository{RepoID: "repo", Remote: "https://github.com/org/repo.git", GitHubApp: githubApp},
+		},
+		{
+			name:    "both deploy key and github app",
+			repo:    PipedRepository{RepoID: "repo", Remote: "https://github.com/org/repo.git", SSHKeyFile: "/etc/deploy-key", GitHubApp: githubApp},
+			wantErr: true,
+		},
+		{
+			name:    "github app with ssh remote",
+			repo:    PipedRepository{RepoID: "repo", Remote: "git@github.com:org/repo.git", GitHubApp: githubApp},
+			wantErr: true,
+		},
+		{
+			name:    "github app without installation",
+			repo:    PipedRepository{RepoID: "repo", Remote: "https://github.com/org/repo.git", GitHubApp: &PipedRepositoryGitHubApp{AppID: 100, PrivateKeyFile: "/etc/github-app.pem"}},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.repo.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedTriggerWebhookValidate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		webhook  PipedTriggerWebhook
+		wantPort int
+		wantErr  bool
+	}{
+		{
+			name:     "default port",
+			webhook:  PipedTriggerWebhook{SecretFile: "/etc/secret"},
+			wantPort: 9088,
+		},
+		{
+			name:     "custom port",
+			webhook:  PipedTriggerWebhook{Port: 8080, SecretFile: "/etc/secret"},
+			wantPort: 8080,
+		},
+		{
+			name:    "missing secret file",
+			webhook: PipedTriggerWebhook{Port: 8080},
+			wantErr: true,
+		},
+		{
+			name:    "invalid port",
+			webhook: PipedTriggerWebhook{Port: 70000, SecretFile: "/etc/secret"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.webhook.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.wantPort, tc.webhook.ListenPort())
+			}
+		})
+	}
+}
+
+func TestPipedUpgradeValidate(t *testing.T) {
+	testcases := []struct {
+		name              string
+		upgrade           PipedUpgrade
+		wantCheckInterval time.Duration
+		wantDrainTimeout  time.Duration
+		wantErr           bool
+	}{
+		{
+			name: "default intervals",
+			upgrade: PipedUpgrade{
+				BinaryURL:   "https://example.com/piped_{{ .Version }}",
+				ChecksumURL: "https://example.com/piped_{{ .Version }}.sha256",
+			},
+			wantCheckInterval: time.Minute,
+			wantDrainTimeout:  time.Hour,
+		},
+		{
+			name: "custom intervals",
+			upgrade: PipedUpgrade{
+				BinaryURL:     "https://example.com/piped_{{ .Version }}",
+				ChecksumURL:   "https://example.com/piped_{{ .Version }}.sha256",
+				CheckInterval: Duration(10 * time.Minute),
+				DrainTimeout:  Duration(30 * time.Minute),
+			},
+			wantCheckInterval: 10 * time.Minute,
+			wantDrainTimeout:  30 * time.Minute,
+		},
+		{
+			name:    "missing binary url",
+			upgrade: PipedUpgrade{},
+			wantErr: true,
+		},
+		{
+			name: "missing checksum url",
+			upgrade: PipedUpgrade{
+				BinaryURL: "https://example.com/piped_{{ .Version }}",
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative drain timeout",
+			upgrade: PipedUpgrade{
+				BinaryURL:    "https://example.com/piped_{{ .Version }}",
+				ChecksumURL:  "https://example.com/piped_{{ .Version }}.sha256",
+				DrainTimeout: Duration(-time.Minute),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.upgrade.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.wantCheckInterval, tc.upgrade.GetCheckInterval())
+				assert.Equal(t, tc.wantDrainTimeout, tc.upgrade.GetDrainTimeout())
+			}
+		})
+	}
+}
+
+func TestPipedShardingValidate(t *testing.T) {
+	testcases := []struct {
+		name     string
+		sharding PipedSharding
+		wantErr  bool
+	}{
+		{
+			name:     "default heartbeat settings",
+			sharding: PipedSharding{},
+		},
+		{
+			name: "valid assignments",
+			sharding: PipedSharding{
+				Assignments: []PipedShardAssignment{
+					{ReplicaID: "piped-0", ApplicationIDs: []string{"app-1", "app-2"}},
+					{ReplicaID: "piped-1", ApplicationIDs: []string{"app-3"}},
+				},
+			},
+		},
+		{
+			name: "timeout is not longer than interval",
+			sharding: PipedSharding{
+				HeartbeatInterval: Duration(time.Minute),
+				HeartbeatTimeout:  Duration(30 * time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing replica id",
+			sharding: PipedSharding{
+				Assignments: []PipedShardAssignment{
+					{ApplicationIDs: []string{"app-1"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "application assigned to multiple replicas",
+			sharding: PipedSharding{
+				Assignments: []PipedShardAssignment{
+					{ReplicaID: "piped-0", ApplicationIDs: []string{"app-1"}},
+					{ReplicaID: "piped-1", ApplicationIDs: []string{"app-1"}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.sharding.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestKubernetesResourceHealthCheckValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		check   KubernetesResourceHealthCheck
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			check: KubernetesResourceHealthCheck{
+				APIVersion: "argoproj.io/v1alpha1",
+				Kind:       "Rollout",
+				Conditions: []KubernetesResourceHealthCondition{
+					{JSONPath: "{.status.phase}", Values: []string{"Healthy"}, Healthy: true},
+				},
+			},
+		},
+		{
+			name: "missing kind",
+			check: KubernetesResourceHealthCheck{
+				APIVersion: "argoproj.io/v1alpha1",
+				Conditions: []KubernetesResourceHealthCondition{
+					{JSONPath: "{.status.phase}"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing conditions",
+			check: KubernetesResourceHealthCheck{
+				APIVersion: "argoproj.io/v1alpha1",
+				Kind:       "Rollout",
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing jsonPath",
+			check: KubernetesResourceHealthCheck{
+				APIVersion: "argoproj.io/v1alpha1",
+				Kind:       "Rollout",
+				Conditions: []KubernetesResourceHealthCondition{
+					{Values: []string{"Healthy"}},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.check.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPipedSpecReload(t *testing.T) {
+	newSpec := func(pipedID string, repos ...string) *PipedSpec {
+		s := &PipedSpec{
+			PipedID:      pipedID,
+			SyncInterval: Duration(time.Minute),
+			AnalysisProviders: []PipedAnalysisProvider{
+				{Name: "prometheus-dev", Type: model.AnalysisProviderPrometheus},
+			},
+		}
+		for _, r := range repos {
+			s.Repositories = append(s.Repositories, PipedRepository{RepoID: r, Branch: "master"})
+		}
+		return s
+	}
+
+	testcases := []struct {
+		name        string
+		next        *PipedSpec
+		wantChanged []string
+		wantRestart bool
+	}{
+		{
+			name: "no change",
+			next: newSpec("piped", "repo-1"),
+		},
+		{
+			name:        "repository added",
+			next:        newSpec("piped", "repo-1", "repo-2"),
+			wantChanged: []string{"repositories"},
+		},
+		{
+			name: "analysis provider and notification changed",
+			next: func() *PipedSpec {
+				s := newSpec("piped", "repo-1")
+				s.AnalysisProviders[0].Name = "prometheus-prod"
+				s.Notifications.Receivers = []NotificationReceiver{{Name: "dev-slack"}}
+				return s
+			}(),
+			wantChanged: []string{"analysisProviders", "notifications"},
+		},
+		{
+			name:        "change requiring restart",
+			next:        newSpec("another-piped", "repo-1"),
+			wantRestart: true,
+		},
+		{
+			name: "repository added with its own credentials",
+			next: func() *PipedSpec {
+				s := newSpec("piped", "repo-1", "repo-2")
+				s.Repositories[1].SSHKeyFile = "/etc/piped-secret/repo-2-key"
+				return s
+			}(),
+			wantChanged: []string{"repositories"},
+			wantRestart: true,
+		},
+		{
+			name: "credentials of repository changed",
+			next: func() *PipedSpec {
+				s := newSpec("piped", "repo-1")
+				s.Repositories[0].GitHubApp = &PipedRepositoryGitHubApp{AppID: 1, InstallationID: 2}
+				return s
+			}(),
+			wantChanged: []string{"repositories"},
+			wantRestart: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newSpec("piped", "repo-1")
+			restart, err := s.RequiresRestart(tc.next)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRestart, restart)
+
+			changed := s.Reload(tc.next)
+			assert.Equal(t, tc.wantChanged, changed)
+			assert.Equal(t, tc.next.GetRepositories(), s.GetRepositories())
+			assert.Equal(t, tc.next.GetNotifications(), s.GetNotifications())
+			assert.Equal(t, "piped", s.PipedID)
+		})
+	}
+}
+
+func TestPipedExecutorPluginValidate(t *testing.T) {
+	testcases := []struct {
+		name               string
+		plugin             PipedExecutorPlugin
+		wantConnectTimeout time.Duration
+		wantErr            bool
+	}{
+		{
+			name:               "default connect timeout",
+			plugin:             PipedExecutorPlugin{Name: "mainframe", Address: "localhost:9095"},
+			wantConnectTimeout: 30 * time.Second,
+		},
+		{
+			name: "custom connect timeout",
+			plugin: PipedExecutorPlugin{
+				Name:           "mainframe",
+				Address:        "localhost:9095",
+				ConnectTimeout: Duration(time.Minute),
+			},
+			wantConnectTimeout: time.Minute,
+		},
+		{
+			name:    "missing name",
+			plugin:  PipedExecutorPlugin{Address: "localhost:9095"},
+			wantErr: true,
+		},
+		{
+			name:    "missing address",
+			plugin:  PipedExecutorPlugin{Name: "mainframe"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.plugin.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err == nil {
+				assert.Equal(t, tc.wantConnectTimeout, tc.plugin.GetConnectTimeout())
+			}
+		})
+	}
+}
+
+func TestPipedLogSinkValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		sink    PipedLogSink
+		wantErr bool
+	}{
+		{
+			name: "valid cloudwatch logs",
+			sink: PipedLogSink{
+				Name: "cloudwatch",
+				Type: LogSinkTypeCloudWatchLogs,
+				CloudWatchLogs: &LogSinkCloudWatchLogsConfig{
+					Region:   "us-west-2",
+					LogGroup: "pipecd",
+				},
+			},
+		},
+		{
+			name: "valid loki",
+			sink: PipedLogSink{
+				Name: "loki",
+				Type: LogSinkTypeLoki,
+				Loki: &LogSinkLokiConfig{Address: "http://loki:3100"},
+			},
+		},
+		{
+			name: "missing name",
+			sink: PipedLogSink{
+				Type: LogSinkTypeFile,
+				File: &LogSinkFileConfig{Path: "/var/log/pipecd.log"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			sink:    PipedLogSink{Name: "elasticsearch", Type: "ELASTICSEARCH"},
+			wantErr: true,
+		},
+		{
+			name:    "missing config of the type",
+			sink:    PipedLogSink{Name: "stackdriver", Type: LogSinkTypeStackdriver},
+			wantErr: true,
+		},
+		{
+			name: "missing log group",
+			sink: PipedLogSink{
+				Name:           "cloudwatch",
+				Type:           LogSinkTypeCloudWatchLogs,
+				CloudWatchLogs: &LogSinkCloudWatchLogsConfig{Region: "us-west-2"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing loki password file",
+			sink: PipedLogSink{
+				Name: "loki",
+				Type: LogSinkTypeLoki,
+				Loki: &LogSinkLokiConfig{
+					Address:      "http://loki:3100",
+					UsernameFile: "/etc/piped-secret/loki-username",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.sink.Validate()
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
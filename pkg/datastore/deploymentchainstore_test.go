@@ -0,0 +1,126 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pipe-cd/pipe/pkg/model"
+)
+
+func TestAddDeploymentChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testcases := []struct {
+		name      string
+		chain     *model.DeploymentChain
+		dsFactory func(*model.DeploymentChain) DataStore
+		wantErr   bool
+	}{
+		{
+			name:      "Invalid deployment chain",
+			chain:     &model.DeploymentChain{},
+			dsFactory: func(c *model.DeploymentChain) DataStore { return nil },
+			wantErr:   true,
+		},
+		{
+			name: "Valid deployment chain",
+			chain: &model.DeploymentChain{
+				Id:                "id",
+				ProjectId:         "project-id",
+				FirstDeploymentId: "deployment-id",
+				Blocks: []*model.ChainBlock{
+					{
+						Nodes: []*model.ChainNode{
+							{
+								ApplicationId:   "app-id",
+								ApplicationName: "app-name",
+								DeploymentId:    "deployment-id",
+								Status:          model.DeploymentStatus_DEPLOYMENT_SUCCESS,
+							},
+						},
+					},
+				},
+				CreatedAt: 1,
+				UpdatedAt: 1,
+			},
+			dsFactory: func(c *model.DeploymentChain) DataStore {
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().Create(gomock.Any(), "DeploymentChain", c.Id, c)
+				return ds
+			},
+			wantErr: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewDeploymentChainStore(tc.dsFactory(tc.chain))
+			err := s.AddDeploymentChain(context.Background(), tc.chain)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
+
+func TestGetDeploymentChain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testcases := []struct {
+		name    string
+		id      string
+		ds      DataStore
+		wantErr bool
+	}{
+		{
+			name: "successful fetch from datastore",
+			id:   "id",
+			ds: func() DataStore {
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().
+					Get(gomock.Any(), "DeploymentChain", "id", &model.DeploymentChain{}).
+					Return(nil)
+				return ds
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "failed fetch from datastore",
+			id:   "id",
+			ds: func() DataStore {
+				ds := NewMockDataStore(ctrl)
+				ds.EXPECT().
+					Get(gomock.Any(), "DeploymentChain", "id", &model.DeploymentChain{}).
+					Return(fmt.Errorf("err"))
+				return ds
+			}(),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewDeploymentChainStore(tc.ds)
+			_, err := s.GetDeploymentChain(context.Background(), tc.id)
+			assert.Equal(t, tc.wantErr, err != nil)
+		})
+	}
+}
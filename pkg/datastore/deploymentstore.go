@@ -68,7 +68,7 @@ var (
 		}
 	}
 
-	StageStatusChangedUpdater = func(stageID string, status model.StageStatus, statusReason string, requires []string, visible bool, retriedCount int32, completedAt int64) func(*model.Deployment) error {
+	StageStatusChangedUpdater = func(stageID string, status model.StageStatus, statusReason string, requires []string, visible bool, retriedCount int32, actualDuration, completedAt int64) func(*model.Deployment) error {
 		return func(d *model.Deployment) error {
 			for _, s := range d.Stages {
 				if s.Id == stageID {
@@ -79,6 +79,9 @@ var (
 					}
 					s.Visible = visible
 					s.RetriedCount = retriedCount
+					if actualDuration > 0 {
+						s.ActualDuration = actualDuration
+					}
 					s.CompletedAt = completedAt
 					return nil
 				}
@@ -199,15 +199,16 @@ func TestDeploymentToCompletedUpdater(t *testing.T) {
 func TestStageStatusChangedUpdater(t *testing.T) {
 	now := time.Now()
 	testcases := []struct {
-		name         string
-		deployment   model.Deployment
-		stageID      string
-		status       model.StageStatus
-		statusDesc   string
-		requires     []string
-		visible      bool
-		retriedCount int32
-		completedAt  int64
+		name           string
+		deployment     model.Deployment
+		stageID        string
+		status         model.StageStatus
+		statusDesc     string
+		requires       []string
+		visible        bool
+		retriedCount   int32
+		actualDuration int64
+		completedAt    int64
 
 		expectedDeployment model.Deployment
 		expectedErr        error
@@ -246,13 +247,14 @@ func TestStageStatusChangedUpdater(t *testing.T) {
 					},
 				},
 			},
-			stageID:      "stage-id1",
-			status:       model.StageStatus_STAGE_SUCCESS,
-			statusDesc:   "updated-status-desc",
-			requires:     []string{"stage-1"},
-			visible:      true,
-			retriedCount: 2,
-			completedAt:  now.Unix(),
+			stageID:        "stage-id1",
+			status:         model.StageStatus_STAGE_SUCCESS,
+			statusDesc:     "updated-status-desc",
+			requires:       []string{"stage-1"},
+			visible:        true,
+			retriedCount:   2,
+			actualDuration: 30,
+			completedAt:    now.Unix(),
 
 			expectedDeployment: model.Deployment{
 				Id:           "deployment-id",
@@ -260,17 +262,18 @@ func TestStageStatusChangedUpdater(t *testing.T) {
 				Status:       model.DeploymentStatus_DEPLOYMENT_RUNNING,
 				Stages: []*model.PipelineStage{
 					{
-						Id:           "stage-id1",
-						Name:         "stage1",
-						Desc:         "desc1",
-						Index:        1,
-						Status:       model.StageStatus_STAGE_SUCCESS,
-						StatusReason: "updated-status-desc",
-						Requires:     []string{"stage-1"},
-						Visible:      true,
-						Metadata:     map[string]string{"meta": "value"},
-						RetriedCount: 2,
-						CompletedAt:  now.Unix(),
+						Id:             "stage-id1",
+						Name:           "stage1",
+						Desc:           "desc1",
+						Index:          1,
+						Status:         model.StageStatus_STAGE_SUCCESS,
+						StatusReason:   "updated-status-desc",
+						Requires:       []string{"stage-1"},
+						Visible:        true,
+						Metadata:       map[string]string{"meta": "value"},
+						RetriedCount:   2,
+						ActualDuration: 30,
+						CompletedAt:    now.Unix(),
 					},
 				},
 			},
@@ -280,7 +283,7 @@ func TestStageStatusChangedUpdater(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			updater := StageStatusChangedUpdater(tc.stageID, tc.status, tc.statusDesc, tc.requires, tc.visible, tc.retriedCount, tc.completedAt)
+			updater := StageStatusChangedUpdater(tc.stageID, tc.status, tc.statusDesc, tc.requires, tc.visible, tc.retriedCount, tc.actualDuration, tc.completedAt)
 			err := updater(&tc.deployment)
 			if err != nil {
 				if tc.expectedErr == nil {
@@ -94,3 +94,11 @@ CREATE INDEX event_key_name_project_id_created_at_desc ON Event (EventKey, Name,
 -- index on `ProjectId` ASC and `EnvIds` ASC
 ALTER TABLE Piped ADD COLUMN EnvIds JSON GENERATED ALWAYS AS (IFNULL(data ->> "$.env_ids", '[]')) VIRTUAL NOT NULL;
 CREATE INDEX piped_project_id_env_ids_asc ON Piped (ProjectId, (CAST(EnvIds AS CHAR(36) ARRAY)));
+
+--
+-- DeploymentChain table indexes
+--
+
+-- index on `Status` ASC and `UpdatedAt` DESC
+ALTER TABLE DeploymentChain ADD COLUMN Status INT GENERATED ALWAYS AS (IFNULL(data->>"$.status", 0)) VIRTUAL NOT NULL;
+CREATE INDEX deployment_chain_status_updated_at_desc ON DeploymentChain (Status, UpdatedAt DESC);
@@ -50,6 +50,19 @@ var (
 			return nil
 		}
 	}
+	PipedDesiredVersionUpdater = func(version string, updatedAt int64) func(piped *model.Piped) error {
+		return func(piped *model.Piped) error {
+			piped.DesiredVersion = version
+			piped.UpdatedAt = updatedAt
+			return nil
+		}
+	}
+	PipedUpgradeStatusUpdater = func(s *model.PipedUpgradeStatus) func(piped *model.Piped) error {
+		return func(piped *model.Piped) error {
+			piped.UpgradeStatus = s
+			return nil
+		}
+	}
 )
 
 type PipedStore interface {
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -26,6 +27,7 @@ type differ struct {
 	ignoreAddingMapKeys           bool
 	equateEmpty                   bool
 	compareNumberAndNumericString bool
+	ignoredPaths                  [][]string
 
 	result *Result
 }
@@ -56,6 +58,18 @@ func WithCompareNumberAndNumericString() Option {
 	}
 }
 
+// WithIgnoredPaths configures differ to ignore the differences at the given paths and all their children.
+// Each path is a dot-separated list of map keys or slice indexes, e.g. spec.template.spec.containers.0.image.
+// "*" matches any key or index at that step and a dot inside a map key can be escaped by backslash,
+// e.g. metadata.annotations.sidecar\.istio\.io/status.
+func WithIgnoredPaths(paths ...string) Option {
+	return func(d *differ) {
+		for _, p := range paths {
+			d.ignoredPaths = append(d.ignoredPaths, splitPath(p))
+		}
+	}
+}
+
 // DiffUnstructureds calculates the diff between two unstructured objects.
 func DiffUnstructureds(x, y unstructured.Unstructured, opts ...Option) (*Result, error) {
 	var (
@@ -77,6 +91,10 @@ func DiffUnstructureds(x, y unstructured.Unstructured, opts ...Option) (*Result,
 }
 
 func (d *differ) diff(path []PathStep, vx, vy reflect.Value) error {
+	if d.isIgnoredPath(path) {
+		return nil
+	}
+
 	if !vx.IsValid() {
 		if d.equateEmpty && isEmptyInterface(vy) {
 			return nil
@@ -161,12 +179,18 @@ func (d *differ) diffSlice(path []PathStep, vx, vy reflect.Value) error {
 
 	for i := minLen; i < vx.Len(); i++ {
 		nextPath := newSlicePath(path, i)
+		if d.isIgnoredPath(nextPath) {
+			continue
+		}
 		nextValueX := vx.Index(i)
 		d.result.addNode(nextPath, nextValueX.Type(), nextValueX.Type(), nextValueX, reflect.Value{})
 	}
 
 	for i := minLen; i < vy.Len(); i++ {
 		nextPath := newSlicePath(path, i)
+		if d.isIgnoredPath(nextPath) {
+			continue
+		}
 		nextValueY := vy.Index(i)
 		d.result.addNode(nextPath, nextValueY.Type(), nextValueY.Type(), reflect.Value{}, nextValueY)
 	}
@@ -174,6 +198,49 @@ func (d *differ) diffSlice(path []PathStep, vx, vy reflect.Value) error {
 	return nil
 }
 
+// isIgnoredPath checks whether the given path matches one of the ignored paths.
+// Since the paths are checked from the root, matching the full length
+// of an ignored path is enough to ignore all of its children as well.
+func (d *differ) isIgnoredPath(path []PathStep) bool {
+	for _, ignored := range d.ignoredPaths {
+		if len(ignored) != len(path) {
+			continue
+		}
+		matched := true
+		for i, s := range ignored {
+			if s != "*" && s != path[i].String() {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits the given path by the dots those are not escaped by backslash.
+func splitPath(path string) []string {
+	var (
+		steps []string
+		b     strings.Builder
+	)
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path) && path[i+1] == '.':
+			b.WriteByte('.')
+			i++
+		case path[i] == '.':
+			steps = append(steps, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(path[i])
+		}
+	}
+	return append(steps, b.String())
+}
+
 func (d *differ) diffMap(path []PathStep, vx, vy reflect.Value) error {
 	if vx.IsNil() || vy.IsNil() {
 		d.result.addNode(path, vx.Type(), vy.Type(), vx, vy)
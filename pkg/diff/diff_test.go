@@ -52,6 +52,38 @@ func TestDiff(t *testing.T) {
 			},
 			diffNum: 0,
 		},
+		{
+			name:     "no diff at ignored paths",
+			yamlFile: "testdata/has_diff.yaml",
+			options: []Option{
+				WithIgnoredPaths(
+					"spec.replicas",
+					"spec.template.metadata.labels",
+					"spec.template.spec.containers.*.image",
+					"spec.template.spec.strategy",
+				),
+			},
+			diffNum: 2,
+			diffString: `  spec:
+    template:
+      spec:
+        containers:
+          - args:
+              #spec.template.spec.containers.0.args.1
+-             - hello
+
+          #spec.template.spec.containers.3
++         - image: new-image
++           livenessProbe:
++             exec:
++               command:
++                 - cat
++                 - /tmp/healthy
++             initialDelaySeconds: 5
++           name: foo
+
+`,
+		},
 		{
 			name:     "has diff",
 			yamlFile: "testdata/has_diff.yaml",
@@ -124,6 +156,31 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestSplitPath(t *testing.T) {
+	testcases := []struct {
+		path     string
+		expected []string
+	}{
+		{
+			path:     "spec",
+			expected: []string{"spec"},
+		},
+		{
+			path:     "spec.template.spec.containers.*.image",
+			expected: []string{"spec", "template", "spec", "containers", "*", "image"},
+		},
+		{
+			path:     `metadata.annotations.sidecar\.istio\.io/status`,
+			expected: []string{"metadata", "annotations", "sidecar.istio.io/status"},
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.expected, splitPath(tc.path))
+		})
+	}
+}
+
 func loadUnstructureds(path string) ([]unstructured.Unstructured, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -121,7 +121,7 @@ func (r *Renderer) Render(ns Nodes) string {
 
 		lastStep := n.Path[pathLen-1]
 		valueX, valueY := n.ValueX, n.ValueY
-		if r.maskPathPrefix != "" && strings.HasPrefix(n.PathString, r.maskPathPrefix) {
+		if r.needMask(n) {
 			valueX = reflect.ValueOf(maskString)
 			valueY = reflect.ValueOf(maskString)
 		}
@@ -222,3 +222,84 @@ func RenderPrimitiveValue(v reflect.Value) string {
 		return v.String()
 	}
 }
+
+// PatchOperation represents a single operation of a JSON patch (RFC 6902)
+// describing how to change a value from the old state to the new state.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// RenderPatchOperations converts the given nodes into a list of JSON patch operations.
+func (r *Renderer) RenderPatchOperations(ns Nodes) []PatchOperation {
+	ops := make([]PatchOperation, 0, len(ns))
+	for _, n := range ns {
+		op := PatchOperation{
+			Path: makeJSONPointer(n.Path),
+		}
+		switch {
+		case !n.ValueX.IsValid():
+			op.Op = "add"
+		case !n.ValueY.IsValid():
+			op.Op = "remove"
+		default:
+			op.Op = "replace"
+		}
+		if op.Op != "remove" {
+			if r.needMask(n) {
+				op.Value = maskString
+			} else if n.ValueY.CanInterface() {
+				op.Value = n.ValueY.Interface()
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// RenderMarkdownTable renders the given nodes as a markdown table
+// containing the path, the old value and the new value of each node.
+func (r *Renderer) RenderMarkdownTable(ns Nodes) string {
+	if len(ns) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("| Path | Old | New |\n")
+	b.WriteString("| --- | --- | --- |\n")
+
+	cell := func(v reflect.Value, mask bool) string {
+		if !v.IsValid() {
+			return ""
+		}
+		if mask {
+			return maskString
+		}
+		s, _ := renderNodeValue(v, "")
+		s = strings.ReplaceAll(s, "|", "\\|")
+		return strings.ReplaceAll(s, "\n", "<br>")
+	}
+
+	for _, n := range ns {
+		mask := r.needMask(n)
+		b.WriteString(fmt.Sprintf("| `%s` | %s | %s |\n", n.PathString, cell(n.ValueX, mask), cell(n.ValueY, mask)))
+	}
+
+	return b.String()
+}
+
+func (r *Renderer) needMask(n Node) bool {
+	return r.maskPathPrefix != "" && strings.HasPrefix(n.PathString, r.maskPathPrefix)
+}
+
+func makeJSONPointer(path []PathStep) string {
+	var b strings.Builder
+	for _, s := range path {
+		step := strings.ReplaceAll(s.String(), "~", "~0")
+		step = strings.ReplaceAll(step, "/", "~1")
+		b.WriteString("/")
+		b.WriteString(step)
+	}
+	return b.String()
+}
@@ -204,3 +204,78 @@ func TestRenderPrimitiveValue(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderPatchOperations(t *testing.T) {
+	ns := Nodes{
+		{
+			Path:       []PathStep{{Type: MapIndexPathStep, MapIndex: "data"}, {Type: MapIndexPathStep, MapIndex: "key"}},
+			PathString: "data.key",
+			ValueX:     reflect.ValueOf("old"),
+			ValueY:     reflect.ValueOf("new"),
+		},
+		{
+			Path:       []PathStep{{Type: MapIndexPathStep, MapIndex: "metadata"}, {Type: MapIndexPathStep, MapIndex: "annotations"}, {Type: MapIndexPathStep, MapIndex: "pipecd.dev/commit"}},
+			PathString: "metadata.annotations.pipecd.dev/commit",
+			ValueY:     reflect.ValueOf("abc"),
+		},
+		{
+			Path:       []PathStep{{Type: MapIndexPathStep, MapIndex: "spec"}, {Type: MapIndexPathStep, MapIndex: "args"}, {Type: SliceIndexPathStep, SliceIndex: 1}},
+			PathString: "spec.args.1",
+			ValueX:     reflect.ValueOf("b"),
+		},
+	}
+
+	testcases := []struct {
+		name     string
+		opts     []RenderOption
+		expected []PatchOperation
+	}{
+		{
+			name: "no mask",
+			expected: []PatchOperation{
+				{Op: "replace", Path: "/data/key", Value: "new"},
+				{Op: "add", Path: "/metadata/annotations/pipecd.dev~1commit", Value: "abc"},
+				{Op: "remove", Path: "/spec/args/1"},
+			},
+		},
+		{
+			name: "mask data",
+			opts: []RenderOption{WithMaskPath("data")},
+			expected: []PatchOperation{
+				{Op: "replace", Path: "/data/key", Value: maskString},
+				{Op: "add", Path: "/metadata/annotations/pipecd.dev~1commit", Value: "abc"},
+				{Op: "remove", Path: "/spec/args/1"},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NewRenderer(tc.opts...).RenderPatchOperations(ns)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestRenderMarkdownTable(t *testing.T) {
+	ns := Nodes{
+		{
+			Path:       []PathStep{{Type: MapIndexPathStep, MapIndex: "spec"}, {Type: MapIndexPathStep, MapIndex: "replicas"}},
+			PathString: "spec.replicas",
+			ValueX:     reflect.ValueOf(2),
+			ValueY:     reflect.ValueOf(3),
+		},
+		{
+			Path:       []PathStep{{Type: MapIndexPathStep, MapIndex: "spec"}, {Type: MapIndexPathStep, MapIndex: "args"}},
+			PathString: "spec.args",
+			ValueY:     reflect.ValueOf([]string{"a|b", "c"}),
+		},
+	}
+	expected := "| Path | Old | New |\n" +
+		"| --- | --- | --- |\n" +
+		"| `spec.replicas` | 2 | 3 |\n" +
+		"| `spec.args` |  | - a\\|b<br>- c |\n"
+
+	assert.Equal(t, "", NewRenderer().RenderMarkdownTable(nil))
+	assert.Equal(t, expected, NewRenderer().RenderMarkdownTable(ns))
+}
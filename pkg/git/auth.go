@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+)
+
+// TokenSource provides the access token used to authenticate to the remote over HTTPS.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// auth holds the credentials used to access the remote of a repository
+// instead of the ones configured globally for the git command.
+type auth struct {
+	sshKeyFile  string
+	tokenSource TokenSource
+}
+
+// envs returns the environment variables to run the git commands accessing the remote.
+// nil is returned if no credentials was configured.
+func (a *auth) envs(ctx context.Context) ([]string, error) {
+	if a == nil {
+		return nil, nil
+	}
+	envs := os.Environ()
+	if a.sshKeyFile != "" {
+		// The host key is not checked as same as the ssh config added by AddSSHConfig.
+		envs = append(envs, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i '%s' -o IdentitiesOnly=yes -o UserKnownHostsFile=/dev/null -o StrictHostKeyChecking=no", a.sshKeyFile))
+	}
+	if a.tokenSource != nil {
+		token, err := a.tokenSource.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get access token: %w", err)
+		}
+		// The token is passed via the environment variables to not be shown in the process list.
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+		envs = append(envs,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+		)
+	}
+	return envs, nil
+}
+
+type Option func(*client)
+
+// WithSSHKeyFile makes the client use the given private key to access the remote of the given repository over SSH.
+func WithSSHKeyFile(repoID, keyFile string) Option {
+	return func(c *client) {
+		c.authFor(repoID).sshKeyFile = keyFile
+	}
+}
+
+// WithTokenSource makes the client use the tokens returned by the given source
+// to access the remote of the given repository over HTTPS.
+func WithTokenSource(repoID string, ts TokenSource) Option {
+	return func(c *client) {
+		c.authFor(repoID).tokenSource = ts
+	}
+}
+
+func (c *client) authFor(repoID string) *auth {
+	a, ok := c.auths[repoID]
+	if !ok {
+		a = &auth{}
+		c.auths[repoID] = a
+	}
+	return a
+}
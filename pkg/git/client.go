@@ -38,6 +38,9 @@ const (
 type Client interface {
 	// Clone clones a specific git repository to the given destination.
 	Clone(ctx context.Context, repoID, remote, branch, destination string) (Repo, error)
+	// Worktree creates a working tree of the given revision at the given destination
+	// by sharing the objects with the local cache.
+	Worktree(ctx context.Context, repoID, remote, revision, destination string) (Repo, error)
 	// Clean removes all cache data.
 	Clean() error
 }
@@ -49,12 +52,14 @@ type client struct {
 	cacheDir  string
 	mu        sync.Mutex
 	repoLocks map[string]*sync.Mutex
-	logger    *zap.Logger
+	// The credentials of the repositories keyed by repository ID.
+	auths  map[string]*auth
+	logger *zap.Logger
 }
 
 // NewClient creates a new CLient instance for cloning git repositories.
 // After using Clean should be called to delete cache data.
-func NewClient(username, email string, logger *zap.Logger) (Client, error) {
+func NewClient(username, email string, logger *zap.Logger, opts ...Option) (Client, error) {
 	gitPath, err := exec.LookPath("git")
 	if err != nil {
 		return nil, fmt.Errorf("unable to find the path of git: %v", err)
@@ -72,14 +77,19 @@ func NewClient(username, email string, logger *zap.Logger) (Client, error) {
 		email = defaultEmail
 	}
 
-	return &client{
+	c := &client{
 		username:  username,
 		email:     email,
 		gitPath:   gitPath,
 		cacheDir:  cacheDir,
 		repoLocks: make(map[string]*sync.Mutex),
+		auths:     make(map[string]*auth),
 		logger:    logger,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 // Clone clones a specific git repository to the given destination.
@@ -96,42 +106,11 @@ func (c *client) Clone(ctx context.Context, repoID, remote, branch, destination
 	c.lockRepo(repoID)
 	defer c.unlockRepo(repoID)
 
-	_, err := os.Stat(repoCachePath)
-	if err != nil && !os.IsNotExist(err) {
+	if err := c.updateCache(ctx, repoID, remote, repoCachePath, logger); err != nil {
 		return nil, err
 	}
 
-	if os.IsNotExist(err) {
-		// Cache miss, clone for the first time.
-		logger.Info(fmt.Sprintf("cloning %s for the first time", repoID))
-		if err := os.MkdirAll(filepath.Dir(repoCachePath), os.ModePerm); err != nil && !os.IsExist(err) {
-			return nil, err
-		}
-		out, err := retryCommand(3, time.Second, logger, func() ([]byte, error) {
-			return c.runGitCommand(ctx, "", "clone", "--mirror", remote, repoCachePath)
-		})
-		if err != nil {
-			logger.Error("failed to clone from remote",
-				zap.String("out", string(out)),
-				zap.Error(err),
-			)
-			return nil, fmt.Errorf("failed to clone from remote: %v", err)
-		}
-	} else {
-		// Cache hit. Do a git fetch to keep updated.
-		c.logger.Info(fmt.Sprintf("fetching %s to update the cache", repoID))
-		out, err := retryCommand(3, time.Second, c.logger, func() ([]byte, error) {
-			return c.runGitCommand(ctx, repoCachePath, "fetch")
-		})
-		if err != nil {
-			logger.Error("failed to fetch from remote",
-				zap.String("out", string(out)),
-				zap.Error(err),
-			)
-			return nil, fmt.Errorf("failed to fetch: %v", err)
-		}
-	}
-
+	var err error
 	if destination != "" {
 		err = os.MkdirAll(destination, os.ModePerm)
 		if err != nil {
@@ -160,6 +139,7 @@ func (c *client) Clone(ctx context.Context, repoID, remote, branch, destination
 	}
 
 	r := NewRepo(destination, c.gitPath, remote, branch)
+	r.auth = c.auths[repoID]
 	if c.username != "" || c.email != "" {
 		if err := r.setUser(ctx, c.username, c.email); err != nil {
 			return nil, fmt.Errorf("failed to set user: %v", err)
@@ -176,11 +156,102 @@ func (c *client) Clone(ctx context.Context, repoID, remote, branch, destination
 	return r, nil
 }
 
+// Worktree creates a working tree of the given revision at the given destination.
+// Unlike Clone, the working tree shares the objects with the local cache of the repository
+// so only the files of the revision are written into the destination.
+// The working tree is detached from any branch, and it is unregistered from the cache
+// after the destination was removed.
+func (c *client) Worktree(ctx context.Context, repoID, remote, revision, destination string) (Repo, error) {
+	var (
+		repoCachePath = filepath.Join(c.cacheDir, repoID)
+		logger        = c.logger.With(
+			zap.String("repo-id", repoID),
+			zap.String("remote", remote),
+			zap.String("repo-cache-path", repoCachePath),
+			zap.String("revision", revision),
+		)
+	)
+
+	c.lockRepo(repoID)
+	defer c.unlockRepo(repoID)
+
+	if err := c.updateCache(ctx, repoID, remote, repoCachePath, logger); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if out, err := c.runGitCommand(ctx, repoCachePath, "worktree", "add", "--detach", destination, revision); err != nil {
+		logger.Error("failed to add worktree",
+			zap.String("out", string(out)),
+			zap.String("repo-path", destination),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to add worktree: %v", err)
+	}
+
+	r := NewRepo(destination, c.gitPath, remote, "")
+	r.auth = c.auths[repoID]
+	return r, nil
+}
+
 // Clean removes all cache data.
 func (c *client) Clean() error {
 	return os.RemoveAll(c.cacheDir)
 }
 
+// updateCache ensures the local cache of the given repository is up to date
+// by cloning it for the first time or fetching the updates.
+// The caller must hold the lock of the repository.
+func (c *client) updateCache(ctx context.Context, repoID, remote, repoCachePath string, logger *zap.Logger) error {
+	_, err := os.Stat(repoCachePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if os.IsNotExist(err) {
+		// Cache miss, clone for the first time.
+		logger.Info(fmt.Sprintf("cloning %s for the first time", repoID))
+		if err := os.MkdirAll(filepath.Dir(repoCachePath), os.ModePerm); err != nil && !os.IsExist(err) {
+			return err
+		}
+		out, err := retryCommand(3, time.Second, logger, func() ([]byte, error) {
+			return c.runRemoteGitCommand(ctx, repoID, "", "clone", "--mirror", remote, repoCachePath)
+		})
+		if err != nil {
+			logger.Error("failed to clone from remote",
+				zap.String("out", string(out)),
+				zap.Error(err),
+			)
+			return fmt.Errorf("failed to clone from remote: %v", err)
+		}
+		return nil
+	}
+
+	// Cache hit. Do a git fetch to keep updated.
+	c.logger.Info(fmt.Sprintf("fetching %s to update the cache", repoID))
+	out, err := retryCommand(3, time.Second, c.logger, func() ([]byte, error) {
+		return c.runRemoteGitCommand(ctx, repoID, repoCachePath, "fetch")
+	})
+	if err != nil {
+		logger.Error("failed to fetch from remote",
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to fetch: %v", err)
+	}
+
+	// Unregister the working trees whose directories were already removed.
+	if out, err := c.runGitCommand(ctx, repoCachePath, "worktree", "prune"); err != nil {
+		logger.Warn("failed to prune worktrees",
+			zap.String("out", string(out)),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
 // getLatestRemoteHashForBranch returns the hash of the latest commit of a remote branch.
 func (c *client) getLatestRemoteHashForBranch(ctx context.Context, remote, branch string) (string, error) {
 	ref := "refs/heads/" + branch
@@ -223,6 +294,19 @@ func (c *client) runGitCommand(ctx context.Context, dir string, args ...string)
 	return cmd.CombinedOutput()
 }
 
+// runRemoteGitCommand runs the git command accessing the remote of the given repository
+// with its credentials if configured.
+func (c *client) runRemoteGitCommand(ctx context.Context, repoID, dir string, args ...string) ([]byte, error) {
+	envs, err := c.auths[repoID].envs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, c.gitPath, args...)
+	cmd.Dir = dir
+	cmd.Env = envs
+	return cmd.CombinedOutput()
+}
+
 // retryCommand retries a command a few times with a constant backoff.
 func retryCommand(retries int, interval time.Duration, logger *zap.Logger, commander func() ([]byte, error)) (out []byte, err error) {
 	for i := 0; i < retries; i++ {
@@ -16,11 +16,13 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -93,6 +95,96 @@ func TestClone(t *testing.T) {
 	assert.Equal(t, "Added note.txt", commits12[0].Message)
 }
 
+func TestCloneWithTokenSource(t *testing.T) {
+	faker, err := newFaker()
+	require.NoError(t, err)
+	defer faker.clean()
+
+	ts := &fakeTokenSource{token: "token"}
+	c, err := NewClient("", "", zap.NewNop(), WithTokenSource("repo-1", ts))
+	require.NoError(t, err)
+	defer c.Clean()
+
+	err = faker.makeRepo("test-clone-token-org", "repo-1")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	remote := filepath.Join(faker.dir, "test-clone-token-org/repo-1")
+
+	repo, err := c.Clone(ctx, "repo-1", remote, "", "")
+	require.NoError(t, err)
+	defer repo.Clean()
+
+	// The cache must be updated by fetching with the credentials of the repository as well.
+	ts.err = errors.New("token expired")
+	_, err = c.Clone(ctx, "repo-1", remote, "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to fetch")
+}
+
+func TestWorktree(t *testing.T) {
+	faker, err := newFaker()
+	require.NoError(t, err)
+	defer faker.clean()
+
+	c, err := NewClient("", "", zap.NewNop())
+	require.NoError(t, err)
+	defer c.Clean()
+
+	err = faker.makeRepo("test-worktree-org", "repo-1")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	remote := filepath.Join(faker.dir, "test-worktree-org/repo-1")
+
+	repo, err := c.Clone(ctx, "repo-1", remote, "", "")
+	require.NoError(t, err)
+	defer repo.Clean()
+	first, err := repo.GetLatestCommit(ctx)
+	require.NoError(t, err)
+
+	// Make sure the worktree of the new revision can be created after fetching the update.
+	commander := gitCommander{
+		gitPath: c.(*client).gitPath,
+		dir:     faker.dir,
+		org:     "test-worktree-org",
+		repo:    "repo-1",
+	}
+	err = commander.addCommit("note.txt", "note.text context")
+	require.NoError(t, err)
+	out, err := exec.Command(commander.gitPath, "-C", remote, "rev-parse", "HEAD").Output()
+	require.NoError(t, err)
+	second := strings.TrimSpace(string(out))
+
+	dir, err := ioutil.TempDir("", "worktree")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wt1, err := c.Worktree(ctx, "repo-1", remote, first.Hash, filepath.Join(dir, "wt-1"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(wt1.GetPath(), "note.txt"))
+	assert.True(t, os.IsNotExist(err))
+	commit, err := wt1.GetLatestCommit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first.Hash, commit.Hash)
+
+	wt2, err := c.Worktree(ctx, "repo-1", remote, second, filepath.Join(dir, "wt-2"))
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(wt2.GetPath(), "note.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "note.text context", string(data))
+
+	// The objects are shared with the cache instead of being copied.
+	info, err := os.Stat(filepath.Join(wt2.GetPath(), ".git"))
+	require.NoError(t, err)
+	assert.False(t, info.IsDir())
+
+	// The removed worktree can be created again at the same destination.
+	require.NoError(t, wt1.Clean())
+	_, err = c.Worktree(ctx, "repo-1", remote, second, filepath.Join(dir, "wt-1"))
+	require.NoError(t, err)
+}
+
 type faker struct {
 	dir     string
 	gitPath string
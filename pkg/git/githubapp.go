@@ -0,0 +1,138 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jwtgo "github.com/golang-jwt/jwt"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+	// The installation token is refreshed this long before it expires
+	// to not be expired while running the git commands.
+	gitHubAppTokenRefreshMargin = 5 * time.Minute
+)
+
+// gitHubAppTokenSource issues the installation access tokens of a GitHub App.
+// The token is reused until it is about to expire.
+type gitHubAppTokenSource struct {
+	appID          int64
+	installationID int64
+	apiURL         string
+	signer         func(jwtgo.Claims) (string, error)
+	httpClient     *http.Client
+	nowFunc        func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewGitHubAppTokenSource returns a TokenSource issuing the access tokens of the given installation of a GitHub App.
+// The API URL of GitHub Enterprise Server should be given as apiURL, empty means github.com.
+func NewGitHubAppTokenSource(appID, installationID int64, privateKey []byte, apiURL string) (TokenSource, error) {
+	key, err := jwtgo.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key of GitHub App: %w", err)
+	}
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	return &gitHubAppTokenSource{
+		appID:          appID,
+		installationID: installationID,
+		apiURL:         strings.TrimSuffix(apiURL, "/"),
+		signer: func(claims jwtgo.Claims) (string, error) {
+			return jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims).SignedString(key)
+		},
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		nowFunc:    time.Now,
+	}, nil
+}
+
+func (s *gitHubAppTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFunc()
+	if s.token != "" && now.Add(gitHubAppTokenRefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	token, expiresAt, err := s.issue(ctx, now)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expiresAt = token, expiresAt
+	return token, nil
+}
+
+// issue requests a new installation access token by authenticating as the GitHub App.
+func (s *gitHubAppTokenSource) issue(ctx context.Context, now time.Time) (string, time.Time, error) {
+	// The issued time is set in the past to allow the clock drift.
+	jwt, err := s.signer(&jwtgo.StandardClaims{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(9 * time.Minute).Unix(),
+		Issuer:    strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign JWT of GitHub App: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.apiURL, s.installationID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("unexpected status code %d while issuing installation token: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse installation token: %w", err)
+	}
+	if out.Token == "" {
+		return "", time.Time{}, fmt.Errorf("no installation token was returned")
+	}
+	return out.Token, out.ExpiresAt, nil
+}
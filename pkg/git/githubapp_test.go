@@ -0,0 +1,99 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwtgo "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubAppTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	issued := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/200/access_tokens" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		claims := &jwtgo.StandardClaims{}
+		_, err := jwtgo.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims, func(*jwtgo.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		// The validation of the time is skipped since the fake clock is used.
+		if ve, ok := err.(*jwtgo.ValidationError); err != nil && (!ok || ve.Errors&^(jwtgo.ValidationErrorExpired|jwtgo.ValidationErrorIssuedAt) != 0) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if claims.Issuer != "100" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		issued++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"token-%d","expires_at":"%s"}`, issued, now.Add(time.Hour).Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	ts, err := NewGitHubAppTokenSource(100, 200, privateKey, server.URL)
+	require.NoError(t, err)
+	s := ts.(*gitHubAppTokenSource)
+	current := now
+	s.nowFunc = func() time.Time { return current }
+
+	ctx := context.Background()
+	token, err := s.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// The token is reused until it is about to expire.
+	current = now.Add(50 * time.Minute)
+	token, err = s.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	current = now.Add(56 * time.Minute)
+	token, err = s.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	// The wrong installation is rejected.
+	s.installationID = 300
+	s.token = ""
+	_, err = s.Token(ctx)
+	assert.Error(t, err)
+
+	_, err = NewGitHubAppTokenSource(100, 200, []byte("invalid"), "")
+	assert.Error(t, err)
+}
@@ -39,6 +39,9 @@ type Repo interface {
 	GetLatestCommit(ctx context.Context) (Commit, error)
 	GetCommitHashForRev(ctx context.Context, rev string) (string, error)
 	ChangedFiles(ctx context.Context, from, to string) ([]string, error)
+	ListTags(ctx context.Context, pattern string) ([]Tag, error)
+	DiffFiles(ctx context.Context, from, to string, paths ...string) ([]FileChange, error)
+	ShowFile(ctx context.Context, commit, path string) ([]byte, error)
 	Checkout(ctx context.Context, commitish string) error
 	CheckoutPullRequest(ctx context.Context, number int, branch string) error
 	Clean() error
@@ -49,11 +52,35 @@ type Repo interface {
 	CommitChanges(ctx context.Context, branch, message string, newBranch bool, changes map[string][]byte) error
 }
 
+// The statuses of the file touched between two commits.
+const (
+	FileAdded    = "A"
+	FileModified = "M"
+	FileDeleted  = "D"
+)
+
+// FileChange represents a file touched between two commits.
+type FileChange struct {
+	// One of FileAdded, FileModified or FileDeleted.
+	Status string
+	// The path to the file relative to the repository root.
+	Path string
+}
+
+// Tag represents a Git tag.
+type Tag struct {
+	Name string
+	// The hash of the commit pointed by the tag.
+	CommitHash string
+}
+
 type repo struct {
 	dir          string
 	gitPath      string
 	remote       string
 	clonedBranch string
+	// The credentials to access the remote, nil means the global ones are used.
+	auth *auth
 }
 
 // NewRepo creates a new Repo instance.
@@ -91,6 +118,7 @@ func (r *repo) Copy(dest string) (Repo, error) {
 		gitPath:      r.gitPath,
 		remote:       r.remote,
 		clonedBranch: r.clonedBranch,
+		auth:         r.auth,
 	}, nil
 }
 
@@ -158,6 +186,96 @@ func (r *repo) ChangedFiles(ctx context.Context, from, to string) ([]string, err
 	return files, nil
 }
 
+// ListTags returns the tags matching the given glob pattern
+// which are reachable from the current HEAD.
+// They are sorted from the most recently created one.
+func (r *repo) ListTags(ctx context.Context, pattern string) ([]Tag, error) {
+	args := []string{
+		"tag",
+		"--list",
+		"--merged", "HEAD",
+		"--sort=-creatordate",
+		// The commit of an annotated tag is shown by *objectname
+		// while the one of a lightweight tag is shown by objectname.
+		"--format=%(refname:strip=2) %(objectname) %(*objectname)",
+	}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	out, err := r.runGitCommand(ctx, args...)
+	if err != nil {
+		return nil, formatCommandError(err, out)
+	}
+
+	var (
+		lines = strings.Split(string(out), "\n")
+		tags  = make([]Tag, 0, len(lines))
+	)
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("unexpected tag output: %s", l)
+		}
+		tags = append(tags, Tag{
+			Name:       fields[0],
+			CommitHash: fields[len(fields)-1],
+		})
+	}
+	return tags, nil
+}
+
+// DiffFiles returns the files touched between two commits along with how they were changed.
+// When some paths are given, only the files under them are returned.
+// A renamed file is returned as a deleted file and an added file.
+func (r *repo) DiffFiles(ctx context.Context, from, to string, paths ...string) ([]FileChange, error) {
+	args := []string{"diff", "--name-status", "--no-renames", from, to}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+	out, err := r.runGitCommand(ctx, args...)
+	if err != nil {
+		return nil, formatCommandError(err, out)
+	}
+
+	var (
+		lines   = strings.Split(string(out), "\n")
+		changes = make([]FileChange, 0, len(lines))
+	)
+	for _, l := range lines {
+		if l == "" {
+			continue
+		}
+		parts := strings.SplitN(l, "\t", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected diff output: %s", l)
+		}
+		// The type changes (T) such as a file replaced by a symlink are considered as modifications.
+		status := parts[0]
+		if status != FileAdded && status != FileDeleted {
+			status = FileModified
+		}
+		changes = append(changes, FileChange{
+			Status: status,
+			Path:   parts[1],
+		})
+	}
+	return changes, nil
+}
+
+// ShowFile returns the content of the given file at the given commit.
+// The path is relative to the repository root.
+func (r *repo) ShowFile(ctx context.Context, commit, path string) ([]byte, error) {
+	out, err := r.runGitCommand(ctx, "show", fmt.Sprintf("%s:%s", commit, filepath.ToSlash(path)))
+	if err != nil {
+		return nil, formatCommandError(err, out)
+	}
+	return out, nil
+}
+
 // Checkout checkouts to a given commitish.
 func (r *repo) Checkout(ctx context.Context, commitish string) error {
 	out, err := r.runGitCommand(ctx, "checkout", commitish)
@@ -178,8 +296,9 @@ func (r *repo) CheckoutPullRequest(ctx context.Context, number int, branch strin
 }
 
 // Pull fetches from and integrate with a local branch.
+// All tags are fetched together, and the moved ones are overwritten.
 func (r *repo) Pull(ctx context.Context, branch string) error {
-	out, err := r.runGitCommand(ctx, "pull", r.remote, branch)
+	out, err := r.runGitCommand(ctx, "pull", "--tags", "--force", r.remote, branch)
 	if err != nil {
 		return formatCommandError(err, out)
 	}
@@ -290,8 +409,13 @@ func (r *repo) setRemote(ctx context.Context, remote string) error {
 }
 
 func (r *repo) runGitCommand(ctx context.Context, args ...string) ([]byte, error) {
+	envs, err := r.auth.envs(ctx)
+	if err != nil {
+		return nil, err
+	}
 	cmd := exec.CommandContext(ctx, r.gitPath, args...)
 	cmd.Dir = r.dir
+	cmd.Env = envs
 	return cmd.CombinedOutput()
 }
 
@@ -104,6 +104,123 @@ func TestChangedFiles(t *testing.T) {
 	assert.Equal(t, expectedChangedFiles, changedFiles)
 }
 
+func TestListTags(t *testing.T) {
+	faker, err := newFaker()
+	require.NoError(t, err)
+	defer faker.clean()
+
+	var (
+		org      = "test-repo-org"
+		repoName = "repo-list-tags"
+		ctx      = context.Background()
+	)
+
+	err = faker.makeRepo(org, repoName)
+	require.NoError(t, err)
+	r := &repo{
+		dir:     faker.repoDir(org, repoName),
+		gitPath: faker.gitPath,
+	}
+
+	firstCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+	_, err = r.runGitCommand(ctx, "tag", "v0.1.0")
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(r.dir, "README.md"), []byte("new content"), os.ModePerm)
+	require.NoError(t, err)
+	err = r.addCommit(ctx, "Updated README")
+	require.NoError(t, err)
+	secondCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+	_, err = r.runGitCommand(ctx, "tag", "-a", "v0.2.0", "-m", "Release v0.2.0")
+	require.NoError(t, err)
+	_, err = r.runGitCommand(ctx, "tag", "staging")
+	require.NoError(t, err)
+
+	tags, err := r.ListTags(ctx, "v*")
+	require.NoError(t, err)
+	require.Equal(t, 2, len(tags))
+	// The tags created at the same second are not ordered by the creation date
+	// so sort them to check regardless of that.
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	assert.Equal(t, []Tag{
+		{Name: "v0.1.0", CommitHash: firstCommitHash},
+		{Name: "v0.2.0", CommitHash: secondCommitHash},
+	}, tags)
+
+	tags, err = r.ListTags(ctx, "release-*")
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(tags))
+}
+
+func TestDiffFiles(t *testing.T) {
+	faker, err := newFaker()
+	require.NoError(t, err)
+	defer faker.clean()
+
+	var (
+		org      = "test-repo-org"
+		repoName = "repo-diff-files"
+		ctx      = context.Background()
+	)
+
+	err = faker.makeRepo(org, repoName)
+	require.NoError(t, err)
+	r := &repo{
+		dir:     faker.repoDir(org, repoName),
+		gitPath: faker.gitPath,
+	}
+
+	err = os.MkdirAll(filepath.Join(r.dir, "app"), os.ModePerm)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(r.dir, "app", "deployment.yaml"), []byte("image: app:v0.1.0"), os.ModePerm)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(r.dir, "app", "old.yaml"), []byte("old"), os.ModePerm)
+	require.NoError(t, err)
+	err = r.addCommit(ctx, "Added app")
+	require.NoError(t, err)
+
+	previousCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(r.dir, "app", "deployment.yaml"), []byte("image: app:v0.2.0"), os.ModePerm)
+	require.NoError(t, err)
+	err = os.Remove(filepath.Join(r.dir, "app", "old.yaml"))
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(r.dir, "app", "new.yaml"), []byte("new"), os.ModePerm)
+	require.NoError(t, err)
+	err = ioutil.WriteFile(filepath.Join(r.dir, "README.md"), []byte("new content"), os.ModePerm)
+	require.NoError(t, err)
+	err = r.addCommit(ctx, "Updated app")
+	require.NoError(t, err)
+
+	headCommitHash, err := r.GetCommitHashForRev(ctx, "HEAD")
+	require.NoError(t, err)
+
+	changes, err := r.DiffFiles(ctx, previousCommitHash, headCommitHash, "app")
+	require.NoError(t, err)
+	expected := []FileChange{
+		{Status: FileModified, Path: "app/deployment.yaml"},
+		{Status: FileAdded, Path: "app/new.yaml"},
+		{Status: FileDeleted, Path: "app/old.yaml"},
+	}
+	assert.Equal(t, expected, changes)
+
+	changes, err = r.DiffFiles(ctx, previousCommitHash, headCommitHash)
+	require.NoError(t, err)
+	assert.Equal(t, 4, len(changes))
+
+	content, err := r.ShowFile(ctx, previousCommitHash, "app/deployment.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "image: app:v0.1.0", string(content))
+
+	_, err = r.ShowFile(ctx, headCommitHash, "app/old.yaml")
+	assert.Error(t, err)
+}
+
 func TestAddCommit(t *testing.T) {
 	faker, err := newFaker()
 	require.NoError(t, err)
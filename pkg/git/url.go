@@ -126,6 +126,24 @@ func MakeFileCreationURL(repoURL, dir, branch, filename, value string) (string,
 	return u.String(), nil
 }
 
+// IsSameRepository reports whether the given URLs point to the same repository
+// regardless of their transports such as SSH and HTTPS.
+func IsSameRepository(a, b string) bool {
+	ua, err := parseGitURL(a)
+	if err != nil {
+		return false
+	}
+	ub, err := parseGitURL(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(ua.Hostname(), ub.Hostname()) && strings.EqualFold(repositoryPath(ua), repositoryPath(ub))
+}
+
+func repositoryPath(u *url.URL) string {
+	return strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+}
+
 var (
 	knownSchemes = map[string]interface{}{
 		"ssh":     struct{}{},
@@ -247,6 +247,52 @@ foo:
 	}
 }
 
+func TestIsSameRepository(t *testing.T) {
+	testcases := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "ssh and https",
+			a:    "git@github.com:org/repo.git",
+			b:    "https://github.com/org/repo",
+			want: true,
+		},
+		{
+			name: "ssh with port and case difference",
+			a:    "ssh://git@GitHub.com:22/Org/Repo.git",
+			b:    "https://github.com/org/repo.git",
+			want: true,
+		},
+		{
+			name: "different repository",
+			a:    "git@github.com:org/repo.git",
+			b:    "https://github.com/org/repo2.git",
+			want: false,
+		},
+		{
+			name: "different host",
+			a:    "git@github.com:org/repo.git",
+			b:    "https://gitlab.com/org/repo.git",
+			want: false,
+		},
+		{
+			name: "invalid url",
+			a:    "git@github.com:org/repo.git",
+			b:    "repo",
+			want: false,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := IsSameRepository(tc.a, tc.b)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
 func TestParseGitURL(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -20,6 +20,7 @@ const (
 	AnalysisProviderPrometheus  AnalysisProviderType = "PROMETHEUS"
 	AnalysisProviderDatadog     AnalysisProviderType = "DATADOG"
 	AnalysisProviderStackdriver AnalysisProviderType = "STACKDRIVER"
+	AnalysisProviderPixie       AnalysisProviderType = "PIXIE"
 )
 
 func (t AnalysisProviderType) String() string {
@@ -17,11 +17,16 @@ package model
 type CloudProviderType string
 
 const (
-	CloudProviderKubernetes CloudProviderType = "KUBERNETES"
-	CloudProviderTerraform  CloudProviderType = "TERRAFORM"
-	CloudProviderCloudRun   CloudProviderType = "CLOUDRUN"
-	CloudProviderLambda     CloudProviderType = "LAMBDA"
-	CloudProviderECS        CloudProviderType = "ECS"
+	CloudProviderKubernetes     CloudProviderType = "KUBERNETES"
+	CloudProviderTerraform      CloudProviderType = "TERRAFORM"
+	CloudProviderCloudRun       CloudProviderType = "CLOUDRUN"
+	CloudProviderLambda         CloudProviderType = "LAMBDA"
+	CloudProviderECS            CloudProviderType = "ECS"
+	CloudProviderAppEngine      CloudProviderType = "APPENGINE"
+	CloudProviderCloudFormation CloudProviderType = "CLOUDFORMATION"
+	CloudProviderNomad          CloudProviderType = "NOMAD"
+	CloudProviderAzure          CloudProviderType = "AZURE"
+	CloudProviderCustomSync     CloudProviderType = "CUSTOM_SYNC"
 )
 
 func (t CloudProviderType) String() string {
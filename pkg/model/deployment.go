@@ -20,6 +20,14 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// MetadataKeyConcurrencyPolicy is the key of the deployment metadata recording
+// the concurrency policy configured for the application when the deployment was triggered.
+const MetadataKeyConcurrencyPolicy = "ConcurrencyPolicy"
+
+// MetadataKeyPausedBy is the key of the deployment metadata recording
+// who paused the deployment. Empty value means the deployment is not paused.
+const MetadataKeyPausedBy = "PausedBy"
+
 var notCompletedDeploymentStatuses = []DeploymentStatus{
 	DeploymentStatus_DEPLOYMENT_PENDING,
 	DeploymentStatus_DEPLOYMENT_PLANNED,
@@ -53,14 +61,30 @@ func IsCompletedStage(status StageStatus) bool {
 	switch status {
 	case StageStatus_STAGE_SUCCESS:
 		return true
+	case StageStatus_STAGE_SUCCESS_WITH_WARNINGS:
+		return true
 	case StageStatus_STAGE_FAILURE:
 		return true
 	case StageStatus_STAGE_CANCELLED:
 		return true
+	case StageStatus_STAGE_SKIPPED:
+		return true
 	}
 	return false
 }
 
+// IsSuccessfulStage checks whether the stage was completed successfully
+// regardless of whether some warnings were reported or not.
+func IsSuccessfulStage(status StageStatus) bool {
+	return status == StageStatus_STAGE_SUCCESS || status == StageStatus_STAGE_SUCCESS_WITH_WARNINGS
+}
+
+// IsPassedStage checks whether the next stages can be executed after the stage,
+// that is, the stage was completed successfully or skipped by a user.
+func IsPassedStage(status StageStatus) bool {
+	return IsSuccessfulStage(status) || status == StageStatus_STAGE_SKIPPED
+}
+
 // CanUpdateDeploymentStatus checks whether the deployment can transit to the given status.
 func CanUpdateDeploymentStatus(cur, next DeploymentStatus) bool {
 	switch next {
@@ -100,10 +124,20 @@ func CanUpdateStageStatus(cur, next StageStatus) bool {
 		return cur <= StageStatus_STAGE_RUNNING
 	case StageStatus_STAGE_CANCELLED:
 		return cur <= StageStatus_STAGE_RUNNING
+	case StageStatus_STAGE_SUCCESS_WITH_WARNINGS:
+		return cur <= StageStatus_STAGE_RUNNING
+	case StageStatus_STAGE_SKIPPED:
+		return cur <= StageStatus_STAGE_RUNNING
 	}
 	return false
 }
 
+// IsPaused checks whether the deployment was paused by a user
+// to hold it before starting the next stage.
+func (d *Deployment) IsPaused() bool {
+	return d.Metadata[MetadataKeyPausedBy] != ""
+}
+
 // StageStatusMap returns the map from id to status of all stages.
 func (d *Deployment) StageStatusMap() map[string]StageStatus {
 	statuses := make(map[string]StageStatus, len(d.Stages))
@@ -156,6 +190,16 @@ func (d *Deployment) CloudProviderType() CloudProviderType {
 		return CloudProviderLambda
 	case ApplicationKind_ECS:
 		return CloudProviderECS
+	case ApplicationKind_APPENGINE:
+		return CloudProviderAppEngine
+	case ApplicationKind_CLOUDFORMATION:
+		return CloudProviderCloudFormation
+	case ApplicationKind_NOMAD:
+		return CloudProviderNomad
+	case ApplicationKind_AZURE_FUNCTIONS:
+		return CloudProviderAzure
+	case ApplicationKind_CUSTOM_SYNC:
+		return CloudProviderCustomSync
 	default:
 		return CloudProviderType(d.Kind.String())
 	}
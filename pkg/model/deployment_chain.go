@@ -0,0 +1,87 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "fmt"
+
+// MetadataKeyChainInputPrefix is the prefix of the deployment metadata keys
+// holding the inputs passed from the previous block of the deployment chain.
+const MetadataKeyChainInputPrefix = "ChainInput."
+
+// IsCompletedDeploymentChain checks whether the deployment chain is at a completion state.
+func IsCompletedDeploymentChain(status DeploymentChainStatus) bool {
+	switch status {
+	case DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS:
+		return true
+	case DeploymentChainStatus_DEPLOYMENT_CHAIN_FAILURE:
+		return true
+	}
+	return false
+}
+
+// ContainsApplication checks whether the given application is deployed by any block of the chain.
+func (c *DeploymentChain) ContainsApplication(appID string) bool {
+	for _, b := range c.Blocks {
+		for _, n := range b.Nodes {
+			if n.ApplicationId == appID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddBlockNodes adds the given nodes to the block at the given index
+// and merges the given inputs into the inputs of that block.
+// The block is created when the index is next to the last one.
+// The applications already contained by the chain are ignored
+// to not deploy an application twice in a chain, which also prevents circular chains.
+// It returns the actually added nodes.
+func (c *DeploymentChain) AddBlockNodes(index int, nodes []*ChainNode, inputs map[string]string) ([]*ChainNode, error) {
+	if index <= 0 || index > len(c.Blocks) {
+		return nil, fmt.Errorf("block %d can not be added to the chain having %d blocks", index, len(c.Blocks))
+	}
+
+	added := make([]*ChainNode, 0, len(nodes))
+	for _, n := range nodes {
+		if c.ContainsApplication(n.ApplicationId) {
+			continue
+		}
+		added = append(added, n)
+	}
+
+	if index == len(c.Blocks) {
+		if len(added) == 0 {
+			return added, nil
+		}
+		c.Blocks = append(c.Blocks, &ChainBlock{})
+	}
+	block := c.Blocks[index]
+	block.Nodes = append(block.Nodes, added...)
+	if len(inputs) > 0 && block.Inputs == nil {
+		block.Inputs = make(map[string]string, len(inputs))
+	}
+	for k, v := range inputs {
+		block.Inputs[k] = v
+	}
+
+	// The chain was completed before the new nodes were added.
+	if len(added) > 0 && c.Status == DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS {
+		c.Status = DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING
+		c.StatusReason = ""
+		c.CompletedAt = 0
+	}
+	return added, nil
+}
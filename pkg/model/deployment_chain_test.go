@@ -0,0 +1,114 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentChainAddBlockNodes(t *testing.T) {
+	newChain := func(status DeploymentChainStatus) *DeploymentChain {
+		return &DeploymentChain{
+			Blocks: []*ChainBlock{
+				{Nodes: []*ChainNode{{ApplicationId: "app-a"}}},
+				{Nodes: []*ChainNode{{ApplicationId: "app-b"}}, Inputs: map[string]string{"key-1": "value-1"}},
+			},
+			Status: status,
+		}
+	}
+	testcases := []struct {
+		name       string
+		chain      *DeploymentChain
+		index      int
+		nodes      []*ChainNode
+		inputs     map[string]string
+		want       int
+		wantErr    bool
+		wantApps   [][]string
+		wantInputs map[string]string
+		wantStatus DeploymentChainStatus
+	}{
+		{
+			name:    "the first block can not be changed",
+			chain:   newChain(DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING),
+			index:   0,
+			nodes:   []*ChainNode{{ApplicationId: "app-c"}},
+			wantErr: true,
+		},
+		{
+			name:    "block is not next to the last one",
+			chain:   newChain(DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING),
+			index:   3,
+			nodes:   []*ChainNode{{ApplicationId: "app-c"}},
+			wantErr: true,
+		},
+		{
+			name:       "add to an existing block",
+			chain:      newChain(DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING),
+			index:      1,
+			nodes:      []*ChainNode{{ApplicationId: "app-c"}},
+			inputs:     map[string]string{"key-2": "value-2"},
+			want:       1,
+			wantApps:   [][]string{{"app-a"}, {"app-b", "app-c"}},
+			wantInputs: map[string]string{"key-1": "value-1", "key-2": "value-2"},
+			wantStatus: DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+		},
+		{
+			name:       "add a new block and reopen the completed chain",
+			chain:      newChain(DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS),
+			index:      2,
+			nodes:      []*ChainNode{{ApplicationId: "app-c"}, {ApplicationId: "app-d"}},
+			inputs:     map[string]string{"key-2": "value-2"},
+			want:       2,
+			wantApps:   [][]string{{"app-a"}, {"app-b"}, {"app-c", "app-d"}},
+			wantInputs: map[string]string{"key-2": "value-2"},
+			wantStatus: DeploymentChainStatus_DEPLOYMENT_CHAIN_RUNNING,
+		},
+		{
+			name:       "applications already in the chain are ignored",
+			chain:      newChain(DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS),
+			index:      2,
+			nodes:      []*ChainNode{{ApplicationId: "app-a"}, {ApplicationId: "app-b"}},
+			want:       0,
+			wantApps:   [][]string{{"app-a"}, {"app-b"}},
+			wantInputs: map[string]string{"key-1": "value-1"},
+			wantStatus: DeploymentChainStatus_DEPLOYMENT_CHAIN_SUCCESS,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.chain.AddBlockNodes(tc.index, tc.nodes, tc.inputs)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, tc.want, len(got))
+
+			apps := make([][]string, 0, len(tc.chain.Blocks))
+			for _, b := range tc.chain.Blocks {
+				ids := make([]string, 0, len(b.Nodes))
+				for _, n := range b.Nodes {
+					ids = append(ids, n.ApplicationId)
+				}
+				apps = append(apps, ids)
+			}
+			assert.Equal(t, tc.wantApps, apps)
+			assert.Equal(t, tc.wantInputs, tc.chain.Blocks[len(tc.chain.Blocks)-1].Inputs)
+			assert.Equal(t, tc.wantStatus, tc.chain.Status)
+		})
+	}
+}
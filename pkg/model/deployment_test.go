@@ -0,0 +1,85 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageStatusWithWarnings(t *testing.T) {
+	testcases := []struct {
+		status     StageStatus
+		completed  bool
+		successful bool
+		passed     bool
+	}{
+		{
+			status: StageStatus_STAGE_NOT_STARTED_YET,
+		},
+		{
+			status: StageStatus_STAGE_RUNNING,
+		},
+		{
+			status:     StageStatus_STAGE_SUCCESS,
+			completed:  true,
+			successful: true,
+			passed:     true,
+		},
+		{
+			status:     StageStatus_STAGE_SUCCESS_WITH_WARNINGS,
+			completed:  true,
+			successful: true,
+			passed:     true,
+		},
+		{
+			status:    StageStatus_STAGE_FAILURE,
+			completed: true,
+		},
+		{
+			status:    StageStatus_STAGE_CANCELLED,
+			completed: true,
+		},
+		{
+			status:    StageStatus_STAGE_SKIPPED,
+			completed: true,
+			passed:    true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.status.String(), func(t *testing.T) {
+			assert.Equal(t, tc.completed, IsCompletedStage(tc.status))
+			assert.Equal(t, tc.successful, IsSuccessfulStage(tc.status))
+			assert.Equal(t, tc.passed, IsPassedStage(tc.status))
+		})
+	}
+
+	assert.True(t, CanUpdateStageStatus(StageStatus_STAGE_RUNNING, StageStatus_STAGE_SUCCESS_WITH_WARNINGS))
+	assert.False(t, CanUpdateStageStatus(StageStatus_STAGE_SUCCESS, StageStatus_STAGE_SUCCESS_WITH_WARNINGS))
+	assert.True(t, CanUpdateStageStatus(StageStatus_STAGE_NOT_STARTED_YET, StageStatus_STAGE_SKIPPED))
+	assert.False(t, CanUpdateStageStatus(StageStatus_STAGE_FAILURE, StageStatus_STAGE_SKIPPED))
+}
+
+func TestDeploymentIsPaused(t *testing.T) {
+	d := &Deployment{}
+	assert.False(t, d.IsPaused())
+
+	d.Metadata = map[string]string{MetadataKeyPausedBy: "user"}
+	assert.True(t, d.IsPaused())
+
+	d.Metadata[MetadataKeyPausedBy] = ""
+	assert.False(t, d.IsPaused())
+}
@@ -0,0 +1,79 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io/ioutil"
+	"strings"
+)
+
+// CompactedMetadataValuePrefix is the prefix of the metadata values
+// compressed by gzip and encoded by base64 to be sent by piped.
+const CompactedMetadataValuePrefix = "gzip+base64:"
+
+// CompactMetadataValue compresses the given metadata value by gzip
+// and encodes it by base64 with CompactedMetadataValuePrefix.
+func CompactMetadataValue(value string) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(value)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return CompactedMetadataValuePrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ExpandMetadataValue restores the value compacted by CompactMetadataValue.
+// The value not having CompactedMetadataValuePrefix is returned as it is.
+func ExpandMetadataValue(value string) (string, error) {
+	if !strings.HasPrefix(value, CompactedMetadataValuePrefix) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, CompactedMetadataValuePrefix))
+	if err != nil {
+		return "", err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	expanded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(expanded), nil
+}
+
+// ExpandMetadata returns a copy of the given metadata whose compacted values are restored.
+// The values failed to be restored are returned as they are.
+func ExpandMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	expanded := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if e, err := ExpandMetadataValue(v); err == nil {
+			v = e
+		}
+		expanded[k] = v
+	}
+	return expanded
+}
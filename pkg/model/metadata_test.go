@@ -0,0 +1,52 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompactMetadataValue(t *testing.T) {
+	value := strings.Repeat("resource-", 1000)
+	compacted, err := CompactMetadataValue(value)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(compacted, CompactedMetadataValuePrefix))
+	assert.Less(t, len(compacted), len(value))
+
+	expanded, err := ExpandMetadataValue(compacted)
+	require.NoError(t, err)
+	assert.Equal(t, value, expanded)
+}
+
+func TestExpandMetadata(t *testing.T) {
+	compacted, err := CompactMetadataValue("compacted value")
+	require.NoError(t, err)
+
+	got := ExpandMetadata(map[string]string{
+		"plain":     "plain value",
+		"compacted": compacted,
+		"broken":    CompactedMetadataValuePrefix + "not-base64",
+	})
+	assert.Equal(t, map[string]string{
+		"plain":     "plain value",
+		"compacted": "compacted value",
+		"broken":    CompactedMetadataValuePrefix + "not-base64",
+	}, got)
+	assert.Nil(t, ExpandMetadata(nil))
+}
@@ -14,6 +14,35 @@
 
 package model
 
+import (
+	"fmt"
+	"strings"
+)
+
+// The identifiers of the interactive Slack messages
+// letting the users approve or reject a WAIT_APPROVAL stage.
+const (
+	SlackApprovalCallbackID    = "wait_approval"
+	SlackApprovalActionApprove = "approve"
+	SlackApprovalActionReject  = "reject"
+)
+
+// MakeSlackApprovalValue returns the value of the approval buttons
+// pointing to the given stage of the given deployment.
+func MakeSlackApprovalValue(deploymentID, stageID string) string {
+	return deploymentID + "/" + stageID
+}
+
+// ParseSlackApprovalValue returns the deployment and the stage
+// pointed by the value of the approval buttons.
+func ParseSlackApprovalValue(value string) (deploymentID, stageID string, err error) {
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("malformed approval value: %s", value)
+	}
+	return parts[0], parts[1], nil
+}
+
 type NotificationEvent struct {
 	Type     NotificationEventType
 	Metadata interface{}
@@ -29,6 +58,8 @@ func (e NotificationEvent) Group() NotificationEventGroup {
 		return NotificationEventGroup_EVENT_APPLICATION_HEALTH
 	case e.Type < 400:
 		return NotificationEventGroup_EVENT_PIPED
+	case e.Type < 500:
+		return NotificationEventGroup_EVENT_ANALYSIS
 	default:
 		return NotificationEventGroup_EVENT_NONE
 	}
@@ -58,6 +89,18 @@ func (e *NotificationEventDeploymentFailed) GetAppName() string {
 	return e.Deployment.ApplicationName
 }
 
+func (e *NotificationEventDeploymentMessage) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
+func (e *NotificationEventDeploymentIncident) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
+func (e *NotificationEventDeploymentWaitApproval) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
 func (e *NotificationEventApplicationSynced) GetAppName() string {
 	return e.Application.Id
 }
@@ -65,3 +108,63 @@ func (e *NotificationEventApplicationSynced) GetAppName() string {
 func (e *NotificationEventApplicationOutOfSync) GetAppName() string {
 	return e.Application.Id
 }
+
+func (e *NotificationEventAnalysisSucceeded) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
+func (e *NotificationEventAnalysisFailed) GetAppName() string {
+	return e.Deployment.ApplicationName
+}
+
+func (e *NotificationEventDeploymentTriggered) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentPlanned) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentApproved) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentRollingBack) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentSucceeded) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentFailed) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentMessage) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentIncident) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventDeploymentWaitApproval) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventApplicationSynced) GetAppLabels() map[string]string {
+	return e.Application.Labels
+}
+
+func (e *NotificationEventApplicationOutOfSync) GetAppLabels() map[string]string {
+	return e.Application.Labels
+}
+
+func (e *NotificationEventAnalysisSucceeded) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
+
+func (e *NotificationEventAnalysisFailed) GetAppLabels() map[string]string {
+	return e.Deployment.Labels
+}
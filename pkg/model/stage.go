@@ -14,6 +14,8 @@
 
 package model
 
+import "strings"
+
 // Stage represents the middle and temporary state of application
 // before reaching its final desired state.
 type Stage string
@@ -27,6 +29,15 @@ const (
 	// StageAnalysis represents the waiting state for analysing
 	// the application status based on metrics, log, http request...
 	StageAnalysis Stage = "ANALYSIS"
+	// StageScriptRun represents the state where
+	// a user-defined script has been run on the host of piped.
+	StageScriptRun Stage = "SCRIPT_RUN"
+	// StageNotify represents the state where
+	// a message has been sent to the notification receivers of piped.
+	StageNotify Stage = "NOTIFY"
+	// StageEventPublish represents the state where
+	// an event has been registered to trigger the event watchers.
+	StageEventPublish Stage = "EVENT_PUBLISH"
 
 	// StageK8sSync represents the state where
 	// all resources should be synced with the Git state.
@@ -49,6 +60,24 @@ const (
 	// StageK8sTrafficRouting represents the state where the traffic to application
 	// should be splitted as the specified percentage to PRIMARY, CANARY, BASELINE variants.
 	StageK8sTrafficRouting Stage = "K8S_TRAFFIC_ROUTING"
+	// StageK8sDiff represents the state where the differences between
+	// the running manifests and the manifests at the target commit have been reported.
+	StageK8sDiff Stage = "K8S_DIFF"
+	// StageK8sValidate represents the state where the manifests at the target commit
+	// have been validated against the schemas of the cluster and the configured policies.
+	StageK8sValidate Stage = "K8S_VALIDATE"
+	// StageK8sVerifyImages represents the state where the signatures and attestations
+	// of all images referenced by the manifests at the target commit have been verified.
+	StageK8sVerifyImages Stage = "K8S_VERIFY_IMAGES"
+	// StageK8sPreviewRollout represents the state where the manifests at the target commit
+	// have been applied into an ephemeral namespace generated for the branch or pull request.
+	StageK8sPreviewRollout Stage = "K8S_PREVIEW_ROLLOUT"
+	// StageK8sPreviewClean represents the state where
+	// the preview environment of the branch or pull request has been removed.
+	StageK8sPreviewClean Stage = "K8S_PREVIEW_CLEAN"
+	// StageK8sJobRun represents the state where a one-shot Job
+	// such as a database migration has been run to completion.
+	StageK8sJobRun Stage = "K8S_JOB_RUN"
 
 	// StageTerraformSync synced infrastructure with all the tf defined in Git.
 	// Firstly, it does plan and if there are any changes detected it applies those changes automatically.
@@ -92,6 +121,50 @@ const (
 	// the CANARY variant resources has been cleaned.
 	StageECSCanaryClean Stage = "ECS_CANARY_CLEAN"
 
+	// StageAppEngineSync does quick sync by deploying the new version
+	// and migrating all traffic to it.
+	StageAppEngineSync Stage = "APPENGINE_SYNC"
+	// StageAppEngineDeployVersion deploys the new version
+	// without routing any traffic to it.
+	StageAppEngineDeployVersion Stage = "APPENGINE_DEPLOY_VERSION"
+	// StageAppEngineMigrateTraffic migrates amount of traffic
+	// from the running version to the new version.
+	StageAppEngineMigrateTraffic Stage = "APPENGINE_MIGRATE_TRAFFIC"
+
+	// StageCloudFormationSync does quick sync by creating a change set
+	// of the stack and executing it immediately.
+	StageCloudFormationSync Stage = "CLOUDFORMATION_SYNC"
+	// StageCloudFormationPlan creates a change set of the stack
+	// to show the changes without applying them.
+	StageCloudFormationPlan Stage = "CLOUDFORMATION_PLAN"
+	// StageCloudFormationApply executes the change set
+	// created by the previous CLOUDFORMATION_PLAN stage.
+	StageCloudFormationApply Stage = "CLOUDFORMATION_APPLY"
+
+	// StageNomadSync does quick sync by running the job
+	// and promoting its canaries if any.
+	StageNomadSync Stage = "NOMAD_SYNC"
+	// StageNomadCanaryRollout runs the job and waits until
+	// the canary allocations created by its update stanza become healthy.
+	StageNomadCanaryRollout Stage = "NOMAD_CANARY_ROLLOUT"
+	// StageNomadPromote promotes the canary allocations
+	// to replace all the running allocations of the job.
+	StageNomadPromote Stage = "NOMAD_PROMOTE"
+
+	// StageAzureFunctionsSync does quick sync by deploying the package
+	// to the staging slot and swapping it with the production slot.
+	StageAzureFunctionsSync Stage = "AZURE_FUNCTIONS_SYNC"
+	// StageAzureFunctionsCanaryRollout deploys the package to the staging slot
+	// without affecting the production slot.
+	StageAzureFunctionsCanaryRollout Stage = "AZURE_FUNCTIONS_CANARY_ROLLOUT"
+	// StageAzureFunctionsPromote swaps the staging slot with the production slot
+	// to make the deployed package receive all traffic.
+	StageAzureFunctionsPromote Stage = "AZURE_FUNCTIONS_PROMOTE"
+
+	// StageCustomSync does quick sync by running the command
+	// provided by the cloud provider to deploy the application.
+	StageCustomSync Stage = "CUSTOM_SYNC"
+
 	// StageRollback represents a state where
 	// the all temporarily created stages will be reverted to
 	// bring back the pre-deploy stage.
@@ -100,6 +173,15 @@ const (
 	StageRollback Stage = "ROLLBACK"
 )
 
+// PluginStagePrefix is the prefix of the names of the stages
+// provided by the executor plugins instead of piped itself.
+const PluginStagePrefix = "PLUGIN_"
+
 func (s Stage) String() string {
 	return string(s)
 }
+
+// IsPlugin returns whether the stage is provided by an executor plugin.
+func (s Stage) IsPlugin() bool {
+	return strings.HasPrefix(string(s), PluginStagePrefix)
+}
@@ -0,0 +1,118 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// StageOutputMetadataKeyPrefix is the prefix of the stage metadata keys
+// holding the values of the outputs published by the stage.
+const StageOutputMetadataKeyPrefix = "Output."
+
+// builtinStageOutputs are the outputs always published by the stages of each kind.
+var builtinStageOutputs = map[Stage][]StageOutput{
+	StageTerraformPlan: {
+		{Name: "HasChanges", Type: StageOutputType_STAGE_OUTPUT_BOOL, Required: true},
+		{Name: "Adds", Type: StageOutputType_STAGE_OUTPUT_NUMBER, Required: true},
+		{Name: "Changes", Type: StageOutputType_STAGE_OUTPUT_NUMBER, Required: true},
+		{Name: "Destroys", Type: StageOutputType_STAGE_OUTPUT_NUMBER, Required: true},
+	},
+}
+
+// BuiltinStageOutputs returns the outputs always published by the given stage.
+func BuiltinStageOutputs(stage Stage) []*StageOutput {
+	outputs, ok := builtinStageOutputs[stage]
+	if !ok {
+		return nil
+	}
+	out := make([]*StageOutput, 0, len(outputs))
+	for i := range outputs {
+		o := outputs[i]
+		out = append(out, &o)
+	}
+	return out
+}
+
+// StageOutputMetadataKey returns the key of the stage metadata holding the value of the given output.
+func StageOutputMetadataKey(name string) string {
+	return StageOutputMetadataKeyPrefix + name
+}
+
+// ParseValue converts the given published value into the Go value of the output type
+// to be used in the templates of the later stages.
+func (o *StageOutput) ParseValue(value string) (interface{}, error) {
+	switch o.Type {
+	case StageOutputType_STAGE_OUTPUT_NUMBER:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value %q of output %s is not a number", value, o.Name)
+		}
+		return v, nil
+	case StageOutputType_STAGE_OUTPUT_BOOL:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("value %q of output %s is not a bool", value, o.Name)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// FindOutput returns the output declared by the stage with the given name.
+func (s *PipelineStage) FindOutput(name string) (*StageOutput, bool) {
+	for _, o := range s.Outputs {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// CheckOutputs checks whether the given stage metadata contains
+// all required outputs of the stage and their values match the output types.
+func (s *PipelineStage) CheckOutputs(metadata map[string]string) error {
+	for _, o := range s.Outputs {
+		v, ok := metadata[StageOutputMetadataKey(o.Name)]
+		if !ok {
+			if o.Required {
+				return fmt.Errorf("required output %s was not published", o.Name)
+			}
+			continue
+		}
+		if _, err := o.ParseValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutputValues returns the values of the outputs published in the given stage metadata
+// keyed by the output names. The outputs having an invalid value are ignored.
+func (s *PipelineStage) OutputValues(metadata map[string]string) map[string]interface{} {
+	values := make(map[string]interface{}, len(s.Outputs))
+	for _, o := range s.Outputs {
+		v, ok := metadata[StageOutputMetadataKey(o.Name)]
+		if !ok {
+			continue
+		}
+		if pv, err := o.ParseValue(v); err == nil {
+			values[o.Name] = pv
+		}
+	}
+	return values
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelineStageCheckOutputs(t *testing.T) {
+	stage := &PipelineStage{
+		Outputs: []*StageOutput{
+			{Name: "Endpoint", Type: StageOutputType_STAGE_OUTPUT_STRING, Required: true},
+			{Name: "Replicas", Type: StageOutputType_STAGE_OUTPUT_NUMBER},
+			{Name: "Migrated", Type: StageOutputType_STAGE_OUTPUT_BOOL},
+		},
+	}
+	testcases := []struct {
+		name       string
+		metadata   map[string]string
+		wantValues map[string]interface{}
+		wantErr    bool
+	}{
+		{
+			name: "all outputs were published",
+			metadata: map[string]string{
+				"Output.Endpoint": "https://example.com",
+				"Output.Replicas": "3",
+				"Output.Migrated": "true",
+				"Other":           "value",
+			},
+			wantValues: map[string]interface{}{
+				"Endpoint": "https://example.com",
+				"Replicas": float64(3),
+				"Migrated": true,
+			},
+		},
+		{
+			name: "optional outputs were not published",
+			metadata: map[string]string{
+				"Output.Endpoint": "https://example.com",
+			},
+			wantValues: map[string]interface{}{
+				"Endpoint": "https://example.com",
+			},
+		},
+		{
+			name: "required output was not published",
+			metadata: map[string]string{
+				"Output.Replicas": "3",
+			},
+			wantValues: map[string]interface{}{
+				"Replicas": float64(3),
+			},
+			wantErr: true,
+		},
+		{
+			name: "value does not match the type",
+			metadata: map[string]string{
+				"Output.Endpoint": "https://example.com",
+				"Output.Replicas": "three",
+			},
+			wantValues: map[string]interface{}{
+				"Endpoint": "https://example.com",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := stage.CheckOutputs(tc.metadata)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantValues, stage.OutputValues(tc.metadata))
+		})
+	}
+}
+
+func TestBuiltinStageOutputs(t *testing.T) {
+	outputs := BuiltinStageOutputs(StageTerraformPlan)
+	assert.Len(t, outputs, 4)
+
+	// The returned outputs must not share the built-in ones.
+	outputs[0].Required = false
+	assert.True(t, BuiltinStageOutputs(StageTerraformPlan)[0].Required)
+
+	assert.Empty(t, BuiltinStageOutputs(StageWait))
+}
@@ -0,0 +1,454 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci provides a client to pull the artifacts stored in OCI registries.
+// It calls the OCI distribution API directly
+// since there is no OCI client in the dependencies.
+package oci
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	// The annotation used by the tools such as ORAS to store the file name of a layer.
+	annotationTitle = "org.opencontainers.image.title"
+
+	// The maximum size of a manifest and a token response.
+	maxMetadataSize = 4 * 1024 * 1024
+)
+
+// Client pulls the artifacts from OCI registries.
+type Client struct {
+	httpClient  *http.Client
+	credentials map[string]credentials
+	insecure    map[string]bool
+	logger      *zap.Logger
+}
+
+type credentials struct {
+	username string
+	password string
+}
+
+type Option func(*Client)
+
+// WithBasicAuth makes the client use the given username and password for the given registry.
+func WithBasicAuth(registry, username, password string) Option {
+	return func(c *Client) {
+		c.credentials[registry] = credentials{
+			username: username,
+			password: password,
+		}
+	}
+}
+
+// WithInsecureRegistry makes the client connect to the given registry over plain HTTP.
+func WithInsecureRegistry(registry string) Option {
+	return func(c *Client) {
+		c.insecure[registry] = true
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		credentials: make(map[string]credentials),
+		insecure:    make(map[string]bool),
+		logger:      zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.logger = c.logger.Named("oci")
+	return c
+}
+
+var defaultClient = NewClient()
+
+// DefaultClient returns the shared client.
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// InitDefaultClient replaces the shared client with the one configured by the given options.
+func InitDefaultClient(opts ...Option) {
+	defaultClient = NewClient(opts...)
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// Pull downloads the artifact of the given reference and writes its files into the given directory.
+// The layers archived by tar are extracted, and the other layers are written
+// as the files named by their title annotations.
+// The digest of the pulled manifest is returned.
+func (c *Client) Pull(ctx context.Context, reference, dest string) (string, error) {
+	ref, err := ParseReference(reference)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference %s: %w", reference, err)
+	}
+	s := &session{
+		client: c,
+		ref:    ref,
+		scheme: "https",
+	}
+	if c.insecure[ref.Registry] {
+		s.scheme = "http"
+	}
+
+	data, digest, err := s.fetchManifest(ctx)
+	if err != nil {
+		return "", err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return "", fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if m.MediaType != "" && m.MediaType != mediaTypeOCIManifest && m.MediaType != mediaTypeDockerManifest {
+		return "", fmt.Errorf("unsupported manifest type %s", m.MediaType)
+	}
+
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return "", err
+	}
+	for _, l := range m.Layers {
+		if err := s.pullLayer(ctx, l, dest); err != nil {
+			return "", fmt.Errorf("failed to pull layer %s: %w", l.Digest, err)
+		}
+	}
+	c.logger.Info("successfully pulled artifact",
+		zap.String("reference", ref.String()),
+		zap.String("digest", digest),
+		zap.Int("layers", len(m.Layers)),
+	)
+	return digest, nil
+}
+
+// session holds the authorization to pull an artifact.
+type session struct {
+	client *Client
+	ref    Reference
+	scheme string
+	// The authorization header value obtained by the last challenge.
+	authorization string
+}
+
+func (s *session) url(kind, version string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/%s/%s", s.scheme, s.ref.Registry, s.ref.Repository, kind, version)
+}
+
+func (s *session) fetchManifest(ctx context.Context) ([]byte, string, error) {
+	resp, err := s.get(ctx, s.url("manifests", s.ref.Version()), mediaTypeOCIManifest+", "+mediaTypeDockerManifest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch manifest of %s: %w", s.ref, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(data)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if s.ref.Digest != "" && s.ref.Digest != digest {
+		return nil, "", fmt.Errorf("digest mismatch of manifest: expected %s but got %s", s.ref.Digest, digest)
+	}
+	return data, digest, nil
+}
+
+func (s *session) pullLayer(ctx context.Context, l descriptor, dest string) error {
+	if !strings.HasPrefix(l.Digest, "sha256:") {
+		return fmt.Errorf("unsupported digest %s", l.Digest)
+	}
+	resp, err := s.get(ctx, s.url("blobs", l.Digest), "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The blob is stored in a temporary file to verify its digest before extracting.
+	f, err := ioutil.TempFile("", "oci-blob")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), resp.Body); err != nil {
+		return err
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != l.Digest {
+		return fmt.Errorf("digest mismatch: expected %s but got %s", l.Digest, got)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch {
+	case strings.HasSuffix(l.MediaType, "tar+gzip"), strings.HasSuffix(l.MediaType, "tar.gzip"):
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return extractTar(gr, dest)
+	case strings.HasSuffix(l.MediaType, ".tar"):
+		return extractTar(f, dest)
+	}
+
+	title := l.Annotations[annotationTitle]
+	if title == "" {
+		return fmt.Errorf("layer of %s type has no %s annotation", l.MediaType, annotationTitle)
+	}
+	path, err := securePath(dest, title)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, f, 0644)
+}
+
+// get sends a GET request to the registry.
+// When the registry requires authorization, the request is sent again
+// with the authorization obtained by answering the challenge.
+func (s *session) get(ctx context.Context, u, accept string) (*http.Response, error) {
+	resp, err := s.do(ctx, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := s.authorize(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = s.do(ctx, u, accept); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, u)
+	}
+	return resp, nil
+}
+
+func (s *session) do(ctx context.Context, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if s.authorization != "" {
+		req.Header.Set("Authorization", s.authorization)
+	}
+	return s.client.httpClient.Do(req)
+}
+
+// authorize answers the given challenge of the registry by the Basic or Bearer scheme.
+// The token for the Bearer scheme is requested with the credentials if configured,
+// otherwise anonymously.
+func (s *session) authorize(ctx context.Context, challenge string) error {
+	cred, hasCred := s.client.credentials[s.ref.Registry]
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCred {
+			return fmt.Errorf("registry %s requires credentials", s.ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(cred.username, cred.password)
+		s.authorization = req.Header.Get("Authorization")
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authorization challenge %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("invalid realm in authorization challenge %q", challenge)
+	}
+	q := realm.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", s.ref.Repository)
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if hasCred {
+		req.SetBasicAuth(cred.username, cred.password)
+	}
+	resp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d while requesting token", resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&token); err != nil {
+		return fmt.Errorf("failed to parse token response: %w", err)
+	}
+	t := token.Token
+	if t == "" {
+		t = token.AccessToken
+	}
+	if t == "" {
+		return fmt.Errorf("no token was returned from %s", realm.Host)
+	}
+	s.authorization = "Bearer " + t
+	return nil
+}
+
+// parseChallenge parses the value of WWW-Authenticate header
+// such as `Bearer realm="https://auth.example.com/token",service="registry.example.com"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if end := strings.Index(rest, ","); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return parts[0], params
+}
+
+// extractTar writes the regular files and directories in the given tar stream into the given directory.
+// The other entries such as symbolic links are ignored to not write outside of the directory.
+func extractTar(r io.Reader, dest string) error {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := securePath(dest, h.Name)
+		if err != nil {
+			return err
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, tr, os.FileMode(h.Mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// securePath returns the path of the given name in the given directory.
+// An error is returned if the name points outside of the directory.
+func securePath(dir, name string) (string, error) {
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if path != filepath.Clean(dir) && !strings.HasPrefix(path, filepath.Clean(dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("file %s is outside of the destination", name)
+	}
+	return path, nil
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
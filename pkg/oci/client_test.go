@@ -0,0 +1,233 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func tarGzip(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+type fakeRegistry struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	// The token required to access the registry if not empty.
+	token string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": f.token})
+		return
+	}
+	if f.token != "" && r.Header.Get("Authorization") != "Bearer "+f.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="fake"`, r.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v2/"), "/", 3)
+	if len(parts) != 3 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var data []byte
+	switch parts[1] {
+	case "manifests":
+		data = f.manifests[parts[0]+":"+parts[2]]
+	case "blobs":
+		data = f.blobs[parts[2]]
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(data)
+}
+
+func TestPull(t *testing.T) {
+	layer := tarGzip(t, map[string]string{
+		"app.pipecd.yaml":       "kind: KubernetesApp",
+		"manifests/deploy.yaml": "kind: Deployment",
+	})
+	file := []byte("kind: Service")
+	m, err := json.Marshal(manifest{
+		MediaType: mediaTypeOCIManifest,
+		Layers: []descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(layer)},
+			{MediaType: "application/yaml", Digest: digestOf(file), Annotations: map[string]string{annotationTitle: "service.yaml"}},
+		},
+	})
+	require.NoError(t, err)
+
+	evil := tarGzip(t, map[string]string{"../evil.yaml": "evil"})
+	evilManifest, err := json.Marshal(manifest{
+		Layers: []descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(evil)},
+		},
+	})
+	require.NoError(t, err)
+
+	reg := &fakeRegistry{
+		manifests: map[string][]byte{
+			"app:v1":             m,
+			"app:" + digestOf(m): m,
+			"evil:v1":            evilManifest,
+		},
+		blobs: map[string][]byte{
+			digestOf(layer): layer,
+			digestOf(file):  file,
+			digestOf(evil):  evil,
+		},
+	}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	testcases := []struct {
+		name      string
+		reference string
+		token     string
+		opts      []Option
+		wantFiles map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "tagged",
+			reference: host + "/app:v1",
+			opts:      []Option{WithInsecureRegistry(host)},
+			wantFiles: map[string]string{
+				"app.pipecd.yaml":       "kind: KubernetesApp",
+				"manifests/deploy.yaml": "kind: Deployment",
+				"service.yaml":          "kind: Service",
+			},
+		},
+		{
+			name:      "digest",
+			reference: host + "/app@" + digestOf(m),
+			opts:      []Option{WithInsecureRegistry(host)},
+			wantFiles: map[string]string{
+				"service.yaml": "kind: Service",
+			},
+		},
+		{
+			name:      "digest mismatch",
+			reference: host + "/app@" + digestOf(file),
+			opts:      []Option{WithInsecureRegistry(host)},
+			wantErr:   true,
+		},
+		{
+			name:      "bearer token",
+			reference: host + "/app:v1",
+			token:     "secret",
+			opts:      []Option{WithInsecureRegistry(host), WithBasicAuth(host, "user", "pass")},
+			wantFiles: map[string]string{
+				"service.yaml": "kind: Service",
+			},
+		},
+		{
+			name:      "wrong credentials",
+			reference: host + "/app:v1",
+			token:     "secret",
+			opts:      []Option{WithInsecureRegistry(host), WithBasicAuth(host, "user", "wrong")},
+			wantErr:   true,
+		},
+		{
+			name:      "file outside of destination",
+			reference: host + "/evil:v1",
+			opts:      []Option{WithInsecureRegistry(host)},
+			wantErr:   true,
+		},
+		{
+			name:      "not found",
+			reference: host + "/app:v2",
+			opts:      []Option{WithInsecureRegistry(host)},
+			wantErr:   true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			reg.token = tc.token
+			dest := t.TempDir()
+			c := NewClient(tc.opts...)
+			digest, err := c.Pull(context.Background(), tc.reference, dest)
+			assert.Equal(t, tc.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, digestOf(m), digest)
+			for name, content := range tc.wantFiles {
+				data, err := ioutil.ReadFile(filepath.Join(dest, name))
+				require.NoError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/app:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/app:pull",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=registry`)
+	assert.Equal(t, "Basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "registry"}, params)
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultTag        = "latest"
+)
+
+// Reference represents a reference to an OCI artifact
+// such as ghcr.io/org/manifests:v1.0.0 or ghcr.io/org/manifests@sha256:...
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses the given string into a Reference.
+// The registry defaults to Docker Hub, and the tag defaults to "latest" when no digest is given.
+func ParseReference(s string) (Reference, error) {
+	s = strings.TrimPrefix(s, "oci://")
+	if s == "" {
+		return Reference{}, fmt.Errorf("empty reference")
+	}
+
+	var ref Reference
+	if i := strings.Index(s, "@"); i >= 0 {
+		ref.Digest = s[i+1:]
+		s = s[:i]
+		if !strings.HasPrefix(ref.Digest, "sha256:") || len(ref.Digest) != len("sha256:")+64 {
+			return Reference{}, fmt.Errorf("invalid digest %q, only sha256 digest is supported", ref.Digest)
+		}
+	}
+	// The colon after the last slash separates the tag, otherwise it is the port of the registry.
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") {
+		ref.Tag = s[i+1:]
+		s = s[:i]
+	}
+
+	ref.Registry = dockerHubRegistry
+	if i := strings.Index(s, "/"); i >= 0 {
+		host := s[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			s = s[i+1:]
+		}
+	}
+	if ref.Registry == dockerHubRegistry && !strings.Contains(s, "/") {
+		s = "library/" + s
+	}
+	if s == "" {
+		return Reference{}, fmt.Errorf("repository must not be empty")
+	}
+	ref.Repository = s
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// Version returns the digest if specified, otherwise the tag
+// to be used to fetch the manifest.
+func (r Reference) Version() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
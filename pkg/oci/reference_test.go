@@ -0,0 +1,74 @@
+// Copyright 2021 The PipeCD Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	testcases := []struct {
+		name    string
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{
+			name: "tagged",
+			ref:  "ghcr.io/org/manifests:v1.0.0",
+			want: Reference{Registry: "ghcr.io", Repository: "org/manifests", Tag: "v1.0.0"},
+		},
+		{
+			name: "digest with oci scheme",
+			ref:  "oci://ghcr.io/org/manifests@" + digest,
+			want: Reference{Registry: "ghcr.io", Repository: "org/manifests", Digest: digest},
+		},
+		{
+			name: "registry with port and no tag",
+			ref:  "localhost:5000/manifests",
+			want: Reference{Registry: "localhost:5000", Repository: "manifests", Tag: "latest"},
+		},
+		{
+			name: "docker hub",
+			ref:  "manifests:v1",
+			want: Reference{Registry: "registry-1.docker.io", Repository: "library/manifests", Tag: "v1"},
+		},
+		{
+			name: "docker hub with namespace",
+			ref:  "org/manifests",
+			want: Reference{Registry: "registry-1.docker.io", Repository: "org/manifests", Tag: "latest"},
+		},
+		{
+			name:    "unsupported digest",
+			ref:     "ghcr.io/org/manifests@md5:abc",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			ref:     "oci://",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseReference(tc.ref)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
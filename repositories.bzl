@@ -65,6 +65,24 @@ def go_repositories():
         version = "v1.0.0",
     )
 
+    go_repository(
+        name = "com_github_aws_aws_sdk_go_v2_service_cloudformation",
+        importpath = "github.com/aws/aws-sdk-go-v2/service/cloudformation",
+        sum = "h1:xKVLmlDAqqAyQgFuXPTvTgSJfUnSEqCxTiIvl9rx/NM=",
+        version = "v1.5.1",
+    )
+    go_repository(
+        name = "com_github_aws_aws_sdk_go_v2_service_cloudwatchlogs",
+        importpath = "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs",
+        sum = "h1:VvOoy2mvIr5kdZaN6Yzj9Z5FbQFnOLQx3VvdAAyMqPU=",
+        version = "v1.4.0",
+    )
+    go_repository(
+        name = "com_github_aws_aws_sdk_go_v2_service_codedeploy",
+        importpath = "github.com/aws/aws-sdk-go-v2/service/codedeploy",
+        sum = "h1:fyDkDvL6zF6SL1CtfWrwabPeF9Yd1W/XM9AmJyKAU0s=",
+        version = "v1.3.1",
+    )
     go_repository(
         name = "com_github_aws_aws_sdk_go_v2_service_ecs",
         importpath = "github.com/aws/aws-sdk-go-v2/service/ecs",
@@ -109,6 +127,18 @@ def go_repositories():
         sum = "h1:p20kkvl+DwV3wYsnLGcmsspBzWGD6EsWKi/W+09Z1NI=",
         version = "v1.2.0",
     )
+    go_repository(
+        name = "com_github_aws_aws_sdk_go_v2_service_secretsmanager",
+        importpath = "github.com/aws/aws-sdk-go-v2/service/secretsmanager",
+        sum = "h1:atHdsCczZyM/y9QIoCQnxudoKk8+ya2EPIplDOofkjw=",
+        version = "v1.3.1",
+    )
+    go_repository(
+        name = "com_github_aws_aws_sdk_go_v2_service_sns",
+        importpath = "github.com/aws/aws-sdk-go-v2/service/sns",
+        sum = "h1:8XqBiTp2/vfIPI2Ytvwy1i6rjA1pVPAItZb6PgdhFC0=",
+        version = "v1.5.0",
+    )
     go_repository(
         name = "com_github_aws_aws_sdk_go_v2_service_sso",
         importpath = "github.com/aws/aws-sdk-go-v2/service/sso",